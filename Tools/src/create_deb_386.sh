@@ -19,12 +19,15 @@ echo "Copying application files"
 
 cp ${BGO_SPACE}/bin/linux_386/amazon-ssm-agent ${BGO_SPACE}/bin/debian_386/debian/usr/bin/
 cp ${BGO_SPACE}/bin/linux_386/ssm-cli ${BGO_SPACE}/bin/debian_386/debian/usr/bin/
+cp ${BGO_SPACE}/bin/linux_386/amazon-ssm-agent-watchdog ${BGO_SPACE}/bin/debian_386/debian/usr/bin/
 cp ${BGO_SPACE}/bin/linux_386/ssm-document-worker ${BGO_SPACE}/bin/debian_386/debian/usr/bin/
-cd ${BGO_SPACE}/bin/debian_386/debian/usr/bin/; strip --strip-unneeded amazon-ssm-agent; strip --strip-unneeded ssm-cli; strip --strip-unneeded ssm-document-worker;cd ~-
+cd ${BGO_SPACE}/bin/debian_386/debian/usr/bin/; strip --strip-unneeded amazon-ssm-agent; strip --strip-unneeded ssm-cli; strip --strip-unneeded ssm-document-worker; strip --strip-unneeded amazon-ssm-agent-watchdog;cd ~-
 cp ${BGO_SPACE}/seelog_unix.xml ${BGO_SPACE}/bin/debian_386/debian/etc/amazon/ssm/seelog.xml.template
 cp ${BGO_SPACE}/amazon-ssm-agent.json.template ${BGO_SPACE}/bin/debian_386/debian/etc/amazon/ssm/
 cp ${BGO_SPACE}/packaging/ubuntu/amazon-ssm-agent.conf ${BGO_SPACE}/bin/debian_386/debian/etc/init/
+cp ${BGO_SPACE}/packaging/ubuntu/amazon-ssm-agent-watchdog.conf ${BGO_SPACE}/bin/debian_386/debian/etc/init/
 cp ${BGO_SPACE}/packaging/ubuntu/amazon-ssm-agent.service ${BGO_SPACE}/bin/debian_386/debian/lib/systemd/system/
+cp ${BGO_SPACE}/packaging/ubuntu/amazon-ssm-agent-watchdog.service ${BGO_SPACE}/bin/debian_386/debian/lib/systemd/system/
 
 echo "Copying debian package config files"
 
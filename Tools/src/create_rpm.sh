@@ -24,13 +24,16 @@ echo "Copying application files"
 cp ${BGO_SPACE}/bin/linux_amd64/amazon-ssm-agent ${BGO_SPACE}/bin/linux_amd64/linux/usr/bin/
 cp ${BGO_SPACE}/bin/linux_amd64/ssm-document-worker ${BGO_SPACE}/bin/linux_amd64/linux/usr/bin/
 cp ${BGO_SPACE}/bin/linux_amd64/ssm-cli ${BGO_SPACE}/bin/linux_amd64/linux/usr/bin/
+cp ${BGO_SPACE}/bin/linux_amd64/amazon-ssm-agent-watchdog ${BGO_SPACE}/bin/linux_amd64/linux/usr/bin/
 cp ${BGO_SPACE}/seelog_unix.xml ${BGO_SPACE}/bin/linux_amd64/linux/etc/amazon/ssm/seelog.xml.template
 cp ${BGO_SPACE}/amazon-ssm-agent.json.template ${BGO_SPACE}/bin/linux_amd64/linux/etc/amazon/ssm/
 cp ${BGO_SPACE}/RELEASENOTES.md ${BGO_SPACE}/bin/linux_amd64/linux/etc/amazon/ssm/
 cp ${BGO_SPACE}/README.md ${BGO_SPACE}/bin/linux_amd64/linux/etc/amazon/ssm/
 cp ${BGO_SPACE}/packaging/linux/amazon-ssm-agent.conf ${BGO_SPACE}/bin/linux_amd64/linux/etc/init/
+cp ${BGO_SPACE}/packaging/linux/amazon-ssm-agent-watchdog.conf ${BGO_SPACE}/bin/linux_amd64/linux/etc/init/
 cp ${BGO_SPACE}/packaging/linux/amazon-ssm-agent.service ${BGO_SPACE}/bin/linux_amd64/linux/etc/systemd/system/
-cd ${BGO_SPACE}/bin/linux_amd64/linux/usr/bin/; strip --strip-unneeded amazon-ssm-agent; strip --strip-unneeded ssm-cli; strip --strip-unneeded ssm-document-worker; cd ~-
+cp ${BGO_SPACE}/packaging/linux/amazon-ssm-agent-watchdog.service ${BGO_SPACE}/bin/linux_amd64/linux/etc/systemd/system/
+cd ${BGO_SPACE}/bin/linux_amd64/linux/usr/bin/; strip --strip-unneeded amazon-ssm-agent; strip --strip-unneeded ssm-cli; strip --strip-unneeded ssm-document-worker; strip --strip-unneeded amazon-ssm-agent-watchdog; cd ~-
 
 echo "Creating the rpm package"
 
@@ -19,15 +19,21 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bootdiag"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/framework/coremanager"
 	"github.com/aws/amazon-ssm-agent/agent/health"
 	"github.com/aws/amazon-ssm-agent/agent/hibernation"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/version"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
 )
 
 const (
@@ -37,12 +43,15 @@ const (
 	registerFlag            = "register"
 	fingerprintFlag         = "fingerprint"
 	similarityThresholdFlag = "similarityThreshold"
+	validateConfigFlag      = "validate-config"
+	encryptValueFlag        = "encrypt-value"
 )
 
 var (
 	instanceIDPtr, regionPtr             *string
 	activationCode, activationID, region string
 	register, clear, force, fpFlag       bool
+	validateConfig, encryptValue         bool
 	similarityThreshold                  int
 	registrationFile                     = filepath.Join(appconfig.DefaultDataStorePath, "registration")
 )
@@ -57,17 +66,150 @@ func start(log logger.T, instanceIDPtr *string, regionPtr *string) (cpm *coreman
 		// this should handle some kind of seg fault errors.
 		if msg := recover(); msg != nil {
 			log.Errorf("Agent crashed with message %v!", msg)
+			if path, err := logger.DumpFlightRecorder("crash"); err == nil {
+				log.Infof("Flight recorder dumped to %v", path)
+			}
 		}
 	}()
 
+	// dump the recent debug events whenever ssm-cli requests it
+	go logger.WatchFlightRecorderRequests(log)
+
+	// report the previous run if it ended without a clean shutdown
+	checkUnexpectedRestart(log)
+
+	// merge the remote configuration over the file before the core modules read it
+	loadRemoteConfig(log)
+	writeEffectiveConfig(log)
+
 	if cpm, err = coremanager.NewCoreManager(instanceIDPtr, regionPtr, log); err != nil {
 		log.Errorf("error occurred when starting core manager: %v", err)
 		return
 	}
 	cpm.Start()
+
+	// apply the changes of the agent configuration file without restarting
+	watchConfig(log)
+	go watchRemoteConfig(log)
 	return
 }
 
+// checkUnexpectedRestart logs the previous run of the agent if it ended without a clean shutdown, the record is
+// reported by the UnexpectedRestarts inventory gatherer.
+func checkUnexpectedRestart(log logger.T) {
+	record, err := bootdiag.Check(log)
+	if err != nil {
+		log.Warnf("Unable to check for an unexpected restart: %v", err)
+	}
+	if record != nil {
+		log.Warnf("The previous agent run, started at %v, ended without a clean shutdown, cause: %v",
+			record.PreviousAgentStartTime.Format(time.RFC3339), record.Cause)
+	}
+}
+
+// loadRemoteConfig fetches the remote configuration of Agent.RemoteConfigParameter, if any, and loads the agent
+// configuration again with it.
+func loadRemoteConfig(log logger.T) {
+	changed, err := appconfig.RefreshRemoteConfig()
+	if err != nil {
+		log.Errorf("Failed to load the remote configuration, using the local configuration: %v", err)
+		return
+	}
+	if !changed {
+		return
+	}
+	if _, err = appconfig.Config(true); err != nil {
+		log.Errorf("Failed to apply the remote configuration: %v", err)
+		return
+	}
+	log.Info("Applied the remote configuration")
+	warnInvalidConfig(log)
+}
+
+// watchRemoteConfig fetches the remote configuration again every Agent.RemoteConfigRefreshMinutes and reloads
+// the agent configuration when it changed.
+func watchRemoteConfig(log logger.T) {
+	for {
+		config, err := appconfig.Config(false)
+		if err != nil {
+			config = appconfig.DefaultConfig()
+		}
+		time.Sleep(time.Duration(config.Agent.RemoteConfigRefreshMinutes) * time.Minute)
+
+		changed, err := appconfig.RefreshRemoteConfig()
+		if err != nil {
+			log.Errorf("Failed to refresh the remote configuration, keeping the current settings: %v", err)
+		} else if changed {
+			reloadConfig(log)
+		}
+	}
+}
+
+// watchConfig reloads the agent configuration whenever its file changes.
+func watchConfig(log logger.T) {
+	configWatcher := &ssmlog.FileWatcher{}
+	configWatcher.Init(log, appconfig.AppConfigPath, func() { reloadConfig(log) })
+	configWatcher.Start()
+}
+
+// reloadConfig applies the reloadable settings of the agent configuration file, see appconfig.ReloadableFields.
+// The other settings take effect when the agent restarts.
+func reloadConfig(log logger.T) {
+	warnInvalidConfig(log)
+	changed, ignored, err := appconfig.Reload()
+	if err != nil {
+		log.Errorf("Failed to reload the agent configuration, keeping the current settings: %v", err)
+		return
+	}
+	writeEffectiveConfig(log)
+	if len(changed) > 0 {
+		log.Infof("Reloaded the agent configuration, changed %v", strings.Join(changed, ", "))
+	}
+	if len(ignored) > 0 {
+		log.Warnf("Changed %v, effective when the agent restarts", strings.Join(ignored, ", "))
+	}
+	for _, field := range changed {
+		if field == "Agent.LogBackend" {
+			ssmlog.ReloadLogger()
+		}
+	}
+}
+
+// logIdentity logs the instance ID and the region of the agent, and the source they come from
+func logIdentity(log logger.T) {
+	instanceID, err := platform.InstanceID()
+	if err != nil {
+		log.Errorf("failed to get the instance ID: %v", err)
+		return
+	}
+	region, err := platform.Region()
+	if err != nil {
+		log.Errorf("failed to get the region of %v: %v", instanceID, err)
+		return
+	}
+	selection, _ := platform.IdentitySource()
+	log.Infof("Instance %v in %v, identity from %v", instanceID, region, selection)
+}
+
+// warnInvalidConfig logs the settings of the configuration file and of the environment the agent ignores or
+// replaces with their defaults
+func warnInvalidConfig(log logger.T) {
+	for _, validationErr := range appconfig.ValidateConfigFile(appconfig.AppConfigPath) {
+		log.Warnf("Invalid setting in %v: %v", appconfig.AppConfigPath, validationErr)
+	}
+	for _, validationErr := range appconfig.ValidateEnvironment() {
+		log.Warnf("Invalid environment override %v", validationErr)
+	}
+}
+
+// writeEffectiveConfig writes the configuration the agent runs with and the sources of its settings, which
+// ssm-cli get-effective-config prints
+func writeEffectiveConfig(log logger.T) {
+	if err := appconfig.WriteEffectiveConfig(appconfig.AppConfigPath); err != nil {
+		log.Warnf("Failed to write the effective configuration: %v", err)
+	}
+}
+
 func blockUntilSignaled(log logger.T) {
 	// Below channel will handle all machine initiated shutdown/reboot requests.
 
@@ -89,6 +231,8 @@ func stop(log logger.T, cpm *coremanager.CoreManager) {
 	log.Info("Stopping agent")
 	log.Flush()
 	cpm.Stop()
+	bootdiag.MarkCleanShutdown(log)
+	watchdog.MarkStopped(log)
 	log.Info("Bye.")
 	log.Flush()
 }
@@ -101,9 +245,23 @@ func run(log logger.T) {
 
 	config, err := appconfig.Config(true)
 	if err != nil {
-		log.Debugf("appconfig could not be loaded - %v", err)
+		log.Errorf("appconfig could not be loaded - %v", err)
 		return
 	}
+	warnInvalidConfig(log)
+
+	// the health check below identifies the instance, so the dev test overrides have to be in place first
+	if *instanceIDPtr != "" {
+		platform.SetInstanceID(*instanceIDPtr)
+	}
+	if *regionPtr != "" {
+		platform.SetRegion(*regionPtr)
+	}
+	logIdentity(log)
+
+	// the watchdog restarts the agent when this heartbeat stops, hibernating included
+	watchdog.StartHeartbeat(log)
+
 	context := context.Default(log, config) // Add instanceID to context
 	//Initializing the health module to send empty health pings to the service.
 	healthModule := health.NewHealthCheck(context)
@@ -26,6 +26,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/fingerprint"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/log/redact"
 	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
 	"github.com/aws/amazon-ssm-agent/agent/ssm/anonauth"
 )
@@ -55,14 +56,32 @@ func parseFlags(log logger.T) {
 	// force flag
 	flag.BoolVar(&force, "y", false, "")
 
+	// configuration validation
+	flag.BoolVar(&validateConfig, validateConfigFlag, false, "")
+
+	// configuration value encryption
+	flag.BoolVar(&encryptValue, encryptValueFlag, false, "")
+
 	flag.Parse()
 
-	if flag.NFlag() > 0 {
+	// the instance id and region overrides are the only flags the agent keeps running with
+	overrides := 0
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "i" || f.Name == "r" {
+			overrides++
+		}
+	})
+
+	if flag.NFlag() > overrides {
 		exitCode := 1
 		if register {
 			exitCode = processRegistration(log)
 		} else if fpFlag {
 			exitCode = processFingerprint(log)
+		} else if validateConfig {
+			exitCode = processConfigValidation(log)
+		} else if encryptValue {
+			exitCode = processValueEncryption(log)
 		} else {
 			flagUsage()
 		}
@@ -80,6 +99,8 @@ func flagUsage() {
 	fmt.Fprintln(os.Stderr, "\t\t-code\tSSM activation code\t(REQUIRED)")
 	fmt.Fprintln(os.Stderr, "\t\t-region\tSSM region       \t(REQUIRED)")
 	fmt.Fprintln(os.Stderr, "\n\t\t-clear\tClears the previously saved SSM registration")
+	fmt.Fprintln(os.Stderr, "\n\t-validate-config [path]\tvalidate the agent configuration file, by default "+appconfig.AppConfigPath)
+	fmt.Fprintln(os.Stderr, "\n\t-encrypt-value\tencrypt the value read from stdin with the key "+appconfig.ConfigKeyPath)
 	fmt.Fprintln(os.Stderr, "\n\t-y\tAnswer yes for all questions")
 }
 
@@ -93,6 +114,8 @@ func processRegistration(log logger.T) (exitCode int) {
 		flagUsage()
 		return 1
 	}
+	// the activation code grants the registration of any instance until it expires
+	redact.AddSecret(activationCode)
 
 	// check if previously registered
 	if !force && registration.InstanceID() != "" {
@@ -128,6 +151,48 @@ func processFingerprint(log logger.T) (exitCode int) {
 	return 0
 }
 
+// processConfigValidation validates the agent configuration file given as argument or the default one, and the
+// environment overrides
+func processConfigValidation(log logger.T) (exitCode int) {
+	path := appconfig.AppConfigPath
+	if flag.NArg() > 0 {
+		path = flag.Arg(0)
+		if _, err := os.Stat(path); err != nil {
+			log.Errorf("Cannot validate the configuration file. %v", err)
+			return 1
+		}
+	}
+	validationErrs := appconfig.ValidateConfigFile(path)
+	for _, validationErr := range validationErrs {
+		log.Errorf("Invalid setting in %v: %v", path, validationErr)
+	}
+	environmentErrs := appconfig.ValidateEnvironment()
+	for _, validationErr := range environmentErrs {
+		log.Errorf("Invalid environment override %v", validationErr)
+	}
+	if len(validationErrs) > 0 || len(environmentErrs) > 0 {
+		return 1
+	}
+	log.Infof("The configuration file %v and the environment overrides are valid", path)
+	return 0
+}
+
+// processValueEncryption prints the encrypted:local: setting of the value read from stdin
+func processValueEncryption(log logger.T) (exitCode int) {
+	value, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		log.Errorf("Cannot read the value to encrypt. %v", err)
+		return 1
+	}
+	encrypted, err := appconfig.EncryptLocal(strings.TrimRight(string(value), "\r\n"))
+	if err != nil {
+		log.Errorf("Cannot encrypt the value. %v", err)
+		return 1
+	}
+	fmt.Println(encrypted)
+	return 0
+}
+
 // registerManagedInstance checks for activation credentials and performs managed instance registration when present
 func registerManagedInstance() (managedInstanceID string, err error) {
 	// try to activate the instance with the activation credentials
@@ -2,7 +2,15 @@
 
 package main
 
-import logger "github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	logger "github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+)
 
 func main() {
 	// initialize logger
@@ -10,9 +18,25 @@ func main() {
 	defer log.Close()
 	defer log.Flush()
 
+	proxyconfig.UseAgentProxy()
+
+	// reload the logger and agent configurations on SIGHUP, e.g. after seelog.xml was edited
+	go reloadOnHangup(log)
+
 	// parse input parameters
 	parseFlags(log)
 
 	// run agent
 	run(log)
 }
+
+// reloadOnHangup reloads the logger from the seelog configuration and runtime log level, and the
+// reloadable settings of the agent configuration, on SIGHUP.
+func reloadOnHangup(log log.T) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		logger.ReloadLogger()
+		reloadConfig(log)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
 	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
 	"golang.org/x/sys/windows/registry"
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/mgr"
@@ -52,6 +53,7 @@ func main() {
 	defer log.Flush()
 
 	proxyconfig.SetProxySettings(log)
+	proxyconfig.UseAgentProxy()
 
 	log.Infof("Proxy environment variables:")
 	for _, name := range []string{"http_proxy", "https_proxy", "no_proxy"} {
@@ -172,6 +174,8 @@ func (a *amazonSSMAgentService) Execute(args []string, r <-chan svc.ChangeReques
 	// notify service controller status is now StartPending
 	s <- svc.Status{State: svc.StartPending}
 
+	watchdog.StartHeartbeat(log)
+
 	// start service, without specifying instance id or region
 	var emptyString string
 	cpm, err := start(a.log, &emptyString, &emptyString)
@@ -181,7 +185,7 @@ func (a *amazonSSMAgentService) Execute(args []string, r <-chan svc.ChangeReques
 	}
 
 	// update service status to Running
-	const acceptCmds = svc.AcceptStop | svc.AcceptShutdown
+	const acceptCmds = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptParamChange
 	s <- svc.Status{State: svc.Running, Accepts: acceptCmds}
 
 loop:
@@ -199,6 +203,11 @@ loop:
 			s <- c.CurrentStatus
 		case svc.Stop, svc.Shutdown:
 			break loop
+		case svc.ParamChange:
+			// reload the logger configuration, runtime log level and agent configuration,
+			// e.g. sc control AmazonSSMAgent paramchange
+			ssmlog.ReloadLogger()
+			reloadConfig(log)
 		default:
 			continue loop
 		}
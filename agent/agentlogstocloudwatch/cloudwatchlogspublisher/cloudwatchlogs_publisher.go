@@ -17,11 +17,14 @@ package cloudwatchlogspublisher
 import (
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogsqueue"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 )
 
 const (
@@ -30,6 +33,10 @@ const (
 	resourceAlreadyExistsException = "ResourceAlreadyExistsException"
 	defaultPollingInterval         = time.Second
 	defaultPollingWaitTime         = 200 * time.Millisecond
+	maxBatchBytes                  = 1048576 // The Max Size of a Batch Supported by the AWS CW Logs Push API
+	maxEventBytes                  = 262144  // The Max Size of an Event Supported by the AWS CW Logs Push API
+	eventOverheadBytes             = 26      // The Size Counted by the AWS CW Logs Push API for each Event on top of its Message
+	maxBatchEvents                 = 10000   // The Max Number of Events in a Batch Supported by the AWS CW Logs Push API
 )
 
 // ICloudWatchPublisher interface for publishing logs to cloudwatchlogs
@@ -152,7 +159,11 @@ func (cloudwatchPublisher *CloudWatchPublisher) Start() {
 		}
 	}
 
-	logStream := cloudwatchPublisher.instanceID
+	// Log to the stream configured, else to a stream named after the instance
+	logStream := cloudwatchlogsqueue.GetLogStream()
+	if logStream == "" {
+		logStream = cloudwatchPublisher.instanceID
+	}
 
 	cloudwatchPublisher.log.Debugf("Cloudwatchlogs Publishing Logs to LogGroup: %v", logGroup)
 	cloudwatchPublisher.log.Debugf("Cloudwatchlogs Publishing Logs to LogStream: %v", logStream)
@@ -203,7 +214,8 @@ func (cloudwatchPublisher *CloudWatchPublisher) startPolling(sequenceToken, sequ
 				cloudwatchPublisher.log.Debugf("Error Dequeueing Messages from Cloudwatchlogs Queue : %v", err)
 			}
 
-			if messages != nil {
+			// Split the messages in batches the PUT Api accepts
+			for _, messages := range splitBatches(messages) {
 				// There are some messages. Call the PUT Api
 				if sequenceToken, err = cloudwatchPublisher.cloudWatchLogsService.PutLogEvents(cloudwatchPublisher.log, messages, cloudwatchPublisher.selfDestination.logGroup, cloudwatchPublisher.selfDestination.logStream, sequenceToken); err != nil {
 					// Error pushing logs even after retries and fixing sequence token
@@ -231,6 +243,37 @@ func (cloudwatchPublisher *CloudWatchPublisher) startPolling(sequenceToken, sequ
 	}()
 }
 
+// splitBatches splits the messages in batches under the maximum size and number of events of a PutLogEvents call,
+// truncating the messages over the maximum size of an event at a rune boundary
+func splitBatches(messages []*cloudwatchlogs.InputLogEvent) (batches [][]*cloudwatchlogs.InputLogEvent) {
+	var batch []*cloudwatchlogs.InputLogEvent
+	batchBytes := 0
+	for _, message := range messages {
+		if message == nil || message.Message == nil {
+			continue
+		}
+		if len(*message.Message)+eventOverheadBytes > maxEventBytes {
+			length := maxEventBytes - eventOverheadBytes
+			for length > 0 && !utf8.RuneStart((*message.Message)[length]) {
+				length--
+			}
+			message.Message = aws.String((*message.Message)[:length])
+		}
+		eventBytes := len(*message.Message) + eventOverheadBytes
+		if batchBytes+eventBytes > maxBatchBytes || len(batch) == maxBatchEvents {
+			batches = append(batches, batch)
+			batch = nil
+			batchBytes = 0
+		}
+		batch = append(batch, message)
+		batchBytes += eventBytes
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
 // getSharingConfigurations gets the sharing configurations structure. Returns nil if configurations incorrect
 func getSharingConfigurations() *destinationConfigurations {
 	sharingDestination := cloudwatchlogsqueue.GetSharingDestination()
@@ -17,10 +17,14 @@ package cloudwatchlogspublisher
 
 import (
 	"errors"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogsqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/cihub/seelog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -95,3 +99,44 @@ func TestGetSharingConfigurationsIncorrect(t *testing.T) {
 	sharingConfigs := getSharingConfigurations()
 	assert.Nil(t, sharingConfigs, "Configurations should be nil as incorrectly formatted")
 }
+
+func TestSplitBatches(t *testing.T) {
+	messages := []*cloudwatchlogs.InputLogEvent{
+		{Message: aws.String(strings.Repeat("a", 200000))},
+		{Message: aws.String(strings.Repeat("b", 500000))},
+		{Message: aws.String(strings.Repeat("c", 200000))},
+		{Message: aws.String(strings.Repeat("d", 200000))},
+		{Message: aws.String(strings.Repeat("e", 200000))},
+	}
+
+	batches := splitBatches(messages)
+
+	assert.Len(t, batches, 2, "Messages over the max batch size should be split")
+	assert.Len(t, batches[0], 4, "First batch should fill up to the max batch size")
+	assert.Len(t, batches[1], 1, "Second batch should hold the remaining message")
+	assert.Equal(t, maxEventBytes-eventOverheadBytes, len(*messages[1].Message), "Message over the max event size should be truncated")
+	assert.Nil(t, splitBatches(nil), "No batch expected without messages")
+}
+
+func TestSplitBatchesTruncatesAtRuneBoundary(t *testing.T) {
+	// the 3 bytes of each rune straddle the maximum size of an event
+	messages := []*cloudwatchlogs.InputLogEvent{{Message: aws.String("a" + strings.Repeat("€", maxEventBytes/3))}}
+
+	splitBatches(messages)
+
+	assert.True(t, utf8.ValidString(*messages[0].Message), "Message should be truncated at a rune boundary")
+	assert.True(t, len(*messages[0].Message)+eventOverheadBytes <= maxEventBytes)
+}
+
+func TestSplitBatchesLimitsTheEventsPerBatch(t *testing.T) {
+	messages := make([]*cloudwatchlogs.InputLogEvent, maxBatchEvents+1)
+	for i := range messages {
+		messages[i] = &cloudwatchlogs.InputLogEvent{Message: aws.String("a")}
+	}
+
+	batches := splitBatches(messages)
+
+	assert.Len(t, batches, 2, "Messages over the max number of events should be split")
+	assert.Len(t, batches[0], maxBatchEvents)
+	assert.Len(t, batches[1], 1)
+}
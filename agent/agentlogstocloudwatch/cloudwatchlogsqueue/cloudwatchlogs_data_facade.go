@@ -37,6 +37,7 @@ const (
 // logDataFacade stores the CloudWatchLogs Destination and Queue being used to store the messages
 type logDataFacade struct {
 	logGroup           string
+	logStream          string
 	logSharingEnabled  bool
 	sharingDestination string
 	messageQueue       *queue.Queue // Access to message queue is restricted from the facade
@@ -91,8 +92,8 @@ func CreateCloudWatchDataInstance(initArgs seelog.CustomReceiverInitArgs) (err e
 
 // setLogDestination updates the logGroup if needed
 func setLogDestination(initArgs seelog.CustomReceiverInitArgs) {
-	logGroup, sharingDestination, logSharingEnabled := parseXMLConfigs(initArgs)
-	if logDataFacadeInstance.logGroup == logGroup && logDataFacadeInstance.logSharingEnabled == logSharingEnabled && logDataFacadeInstance.sharingDestination == sharingDestination {
+	logGroup, logStream, sharingDestination, logSharingEnabled := parseXMLConfigs(initArgs)
+	if logDataFacadeInstance.logGroup == logGroup && logDataFacadeInstance.logStream == logStream && logDataFacadeInstance.logSharingEnabled == logSharingEnabled && logDataFacadeInstance.sharingDestination == sharingDestination {
 		return
 	}
 
@@ -100,6 +101,7 @@ func setLogDestination(initArgs seelog.CustomReceiverInitArgs) {
 	fmt.Println("Log Sharing:", logSharingEnabled)
 
 	logDataFacadeInstance.logGroup = logGroup
+	logDataFacadeInstance.logStream = logStream
 	logDataFacadeInstance.logSharingEnabled = logSharingEnabled
 	logDataFacadeInstance.sharingDestination = sharingDestination
 
@@ -112,8 +114,8 @@ func setLogDestination(initArgs seelog.CustomReceiverInitArgs) {
 	}
 }
 
-// parseXMLConfigs parses the logGroup from seelog config
-func parseXMLConfigs(xmlConfig seelog.CustomReceiverInitArgs) (logGroup, sharingDestination string, logSharingEnabled bool) {
+// parseXMLConfigs parses the logGroup and logStream from seelog config
+func parseXMLConfigs(xmlConfig seelog.CustomReceiverInitArgs) (logGroup, logStream, sharingDestination string, logSharingEnabled bool) {
 	// Getting the log group from seelog config
 	logGroup, ok := xmlConfig.XmlCustomAttrs["log-group"]
 	if !ok {
@@ -121,6 +123,9 @@ func parseXMLConfigs(xmlConfig seelog.CustomReceiverInitArgs) (logGroup, sharing
 		fmt.Println("No Log Group in Config. Will log in default group")
 	}
 
+	// The log stream is optional, the publisher logs to a stream named after the instance id by default
+	logStream = xmlConfig.XmlCustomAttrs["log-stream"]
+
 	var err error
 	logSharingEnabledParam, ok := xmlConfig.XmlCustomAttrs["log-sharing-enabled"]
 	if !ok {
@@ -185,6 +190,11 @@ func GetLogGroup() string {
 	return logDataFacadeInstance.logGroup
 }
 
+// GetLogStream returns the log stream intended for logging, empty if the default stream should be used
+func GetLogStream() string {
+	return logDataFacadeInstance.logStream
+}
+
 // IsLogSharingEnabled returns true if log sharing is enabled
 func IsLogSharingEnabled() bool {
 	return logDataFacadeInstance.logSharingEnabled
@@ -37,6 +37,8 @@ func TestFacade(t *testing.T) {
 
 	once = new(sync.Once)
 	CreateCloudWatchDataInstance(initArgs)
+	assert.Equal(t, "LogGroup", GetLogGroup(), "LogGroup Name Incorrect")
+	assert.Equal(t, "LogStream", GetLogStream(), "LogStream Name Incorrect")
 
 	messages, err := Dequeue(time.Millisecond)
 	assert.NoError(t, err, "Unexpected Error in Dequeueing From Queue")
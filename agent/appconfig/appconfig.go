@@ -20,6 +20,7 @@ import (
 	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
 
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/version"
@@ -29,33 +30,72 @@ import (
 var loadedConfig *SsmagentConfig
 var lock sync.RWMutex
 
+// decryptions is the number of configurations being decrypted
+var decryptions int32
+
 // Config loads the app configuration for amazon-ssm-agent.
 // If reload is true, it loads the config afresh,
 // otherwise it returns a previous loaded version, if any.
-func Config(reload bool) (SsmagentConfig, error) {
+func Config(reload bool) (agentConfig SsmagentConfig, err error) {
+	if !reload && !isLoaded() && atomic.LoadInt32(&decryptions) > 0 {
+		// the first configuration is being decrypted, its ciphertexts aren't returned nor cached meanwhile
+		return UndecryptedConfig(), nil
+	}
 	if reload || !isLoaded() {
-		var agentConfig SsmagentConfig
-		agentConfig = DefaultConfig()
 		path, pathErr := getAppConfigPath()
 		if pathErr != nil {
-			return agentConfig, nil
+			agentConfig = defaultConfigWithEnvironment()
+			if !hasEncryptedSettings(agentConfig) {
+				return agentConfig, nil
+			}
+			return decryptAndCache(agentConfig)
 		}
 
 		// Process config override
 		fmt.Printf("Applying config override from %s.\n", path)
 
-		if err := jsonutil.UnmarshalFile(path, &agentConfig); err != nil {
-			fmt.Println("Failed to unmarshal config override. Fall back to default.")
+		if agentConfig, err = LoadConfigFile(path); err != nil {
+			fmt.Printf("Failed to unmarshal config override: %v. Fall back to default.\n", err)
 			return agentConfig, err
 		}
-		agentConfig.Os.Name = runtime.GOOS
-		agentConfig.Agent.Version = version.Version
-		parser(&agentConfig)
+		if hasEncryptedSettings(agentConfig) {
+			return decryptAndCache(agentConfig)
+		}
 		cache(agentConfig)
 	}
 	return getCached(), nil
 }
 
+// decryptAndCache decrypts the encrypted settings and caches the result. The configuration cached before, if any, is
+// kept meanwhile and when the decryption fails; without one, Config returns UndecryptedConfig meanwhile, as the KMS
+// client loads the configuration itself.
+func decryptAndCache(agentConfig SsmagentConfig) (SsmagentConfig, error) {
+	atomic.AddInt32(&decryptions, 1)
+	defer atomic.AddInt32(&decryptions, -1)
+	if err := decryptSettings(&agentConfig); err != nil {
+		return DefaultConfig(), err
+	}
+	cache(agentConfig)
+	return agentConfig, nil
+}
+
+// LoadConfigFile loads the given config override over the default configuration, then the remote configuration
+// and the environment overrides,
+// applying the same limits as the agent does, without caching the result. When the file can't be parsed, it returns
+// the error with the configuration the agent has without the file.
+func LoadConfigFile(path string) (SsmagentConfig, error) {
+	agentConfig := DefaultConfig()
+	if err := jsonutil.UnmarshalFile(path, &agentConfig); err != nil {
+		return defaultConfigWithEnvironment(), err
+	}
+	applyRemoteConfig(&agentConfig)
+	applyEnvironment(&agentConfig)
+	agentConfig.Os.Name = runtime.GOOS
+	agentConfig.Agent.Version = version.Version
+	parser(&agentConfig)
+	return agentConfig, nil
+}
+
 func isLoaded() bool {
 	lock.RLock()
 	defer lock.RUnlock()
@@ -108,6 +148,8 @@ func DefaultConfig() SsmagentConfig {
 		CommandWorkersLimit: DefaultCommandWorkersLimit,
 		StopTimeoutMillis:   DefaultStopTimeoutMillis,
 		CommandRetryLimit:   DefaultCommandRetryLimit,
+
+		ReplyFlushIntervalMillis: DefaultReplyFlushIntervalMillis,
 	}
 	var ssm = SsmCfg{
 		HealthFrequencyMinutes:                DefaultSsmHealthFrequencyMinutes,
@@ -116,25 +158,60 @@ func DefaultConfig() SsmagentConfig {
 		CustomInventoryDefaultLocation:        DefaultCustomInventoryFolder,
 		AssociationLogsRetentionDurationHours: DefaultAssociationLogsRetentionDurationHours,
 		RunCommandLogsRetentionDurationHours:  DefaultRunCommandLogsRetentionDurationHours,
+		ConnectivityFailureThreshold:          DefaultSsmConnectivityFailureThreshold,
 	}
 	var agent = AgentInfo{
 		Name:                 "amazon-ssm-agent",
 		OrchestrationRootDir: defaultOrchestrationRootDirName,
+		PluginOutputMaxRolls: DefaultPluginOutputMaxRolls,
+		LogBackend:           LogBackendFile,
+		DownloadParallelism:  DefaultDownloadParallelism,
+		DownloadRetryLimit:   DefaultDownloadRetryLimit,
+		MinFreeDiskSpaceMB:   DefaultMinFreeDiskSpaceMB,
+		OrchestrationQuotaMB: DefaultOrchestrationQuotaMB,
+		DownloadQuotaMB:      DefaultDownloadQuotaMB,
+		QuotaMaxAgeDays:      DefaultQuotaMaxAgeDays,
+
+		RemoteConfigRefreshMinutes:           DefaultRemoteConfigRefreshMinutes,
+		RunAsUser:                            DefaultRunAsUser,
+		PartialOutputIntervalSeconds:         DefaultPartialOutputIntervalSeconds,
+		HistoryRetentionDays:                 DefaultHistoryRetentionDays,
+		HistoryMaxEntries:                    DefaultHistoryMaxEntries,
+		ExecutionHookTimeoutSeconds:          DefaultExecutionHookTimeoutSeconds,
+		CloudWatchOutputLogStream:            DefaultCloudWatchOutputLogStream,
+		CloudWatchOutputFlushIntervalSeconds: DefaultCloudWatchOutputFlushIntervalSeconds,
 	}
 	var os = OsInfo{
 		Lang:    "en-US",
 		Version: "1",
 	}
 	var birdwatcher BirdwatcherCfg
+	var executionPolicy = ExecutionPolicyCfg{
+		AllowedUsers: DefaultRunAsUser,
+		MinUmask:     DefaultExecutionPolicyMinUmask,
+	}
+
+	var documentConcurrency = DocumentConcurrencyCfg{
+		MaxConcurrent: map[string]int{DefaultPatchingCategory: 1},
+		Categories:    map[string]string{DefaultPatchingCategory: DefaultPatchingDocuments},
+	}
+
+	var identity = IdentityCfg{
+		ConsumptionOrder: DefaultIdentityConsumptionOrder,
+	}
 
 	var ssmagentCfg = SsmagentConfig{
-		Profile:     credsProfile,
-		Mds:         mds,
-		Ssm:         ssm,
-		Agent:       agent,
-		Os:          os,
-		S3:          s3,
-		Birdwatcher: birdwatcher,
+		Profile:         credsProfile,
+		Mds:             mds,
+		Ssm:             ssm,
+		Agent:           agent,
+		Os:              os,
+		S3:              s3,
+		Birdwatcher:     birdwatcher,
+		ExecutionPolicy: executionPolicy,
+		Identity:        identity,
+
+		DocumentConcurrency: documentConcurrency,
 	}
 
 	return ssmagentCfg
@@ -17,6 +17,7 @@ package appconfig
 
 import (
 	"log"
+	"net/url"
 	"strings"
 )
 
@@ -28,6 +29,76 @@ func parser(config *SsmagentConfig) {
 	config.Agent.Name = getStringValue(config.Agent.Name, DefaultAgentName)
 	config.Agent.OrchestrationRootDir = getStringValue(config.Agent.OrchestrationRootDir, defaultOrchestrationRootDirName)
 	config.Agent.Region = getStringValue(config.Agent.Region, "")
+	config.Agent.PluginOutputMaxSizeMB = getNumericValueAboveMin(
+		config.Agent.PluginOutputMaxSizeMB,
+		0,
+		0)
+	config.Agent.PluginOutputMaxRolls = getNumericValueAboveMin(
+		config.Agent.PluginOutputMaxRolls,
+		0,
+		DefaultPluginOutputMaxRolls)
+	config.Agent.PartialOutputIntervalSeconds = getNumericValue(
+		config.Agent.PartialOutputIntervalSeconds,
+		0,
+		DefaultPartialOutputIntervalSecondsMax,
+		DefaultPartialOutputIntervalSeconds)
+	config.Agent.HistoryRetentionDays = getNumericValue(
+		config.Agent.HistoryRetentionDays,
+		0,
+		DefaultHistoryRetentionDaysMax,
+		DefaultHistoryRetentionDays)
+	config.Agent.HistoryMaxEntries = getNumericValueAboveMin(
+		config.Agent.HistoryMaxEntries,
+		DefaultHistoryMaxEntriesMin,
+		DefaultHistoryMaxEntries)
+	config.Agent.ExecutionHookTimeoutSeconds = getNumericValue(
+		config.Agent.ExecutionHookTimeoutSeconds,
+		DefaultExecutionHookTimeoutSecondsMin,
+		DefaultExecutionHookTimeoutSecondsMax,
+		DefaultExecutionHookTimeoutSeconds)
+	config.Agent.CloudWatchOutputLogStream = getStringValue(config.Agent.CloudWatchOutputLogStream, DefaultCloudWatchOutputLogStream)
+	config.Agent.CloudWatchOutputFlushIntervalSeconds = getNumericValue(
+		config.Agent.CloudWatchOutputFlushIntervalSeconds,
+		DefaultCloudWatchOutputFlushIntervalSecondsMin,
+		DefaultCloudWatchOutputFlushIntervalSecondsMax,
+		DefaultCloudWatchOutputFlushIntervalSeconds)
+	config.Agent.LogBackend = getLogBackend(config.Agent.LogBackend)
+	config.Agent.Proxy = getProxy(config.Agent.Proxy)
+	config.Agent.RemoteConfigRefreshMinutes = getNumericValue(
+		config.Agent.RemoteConfigRefreshMinutes,
+		DefaultRemoteConfigRefreshMinutesMin,
+		DefaultRemoteConfigRefreshMinutesMax,
+		DefaultRemoteConfigRefreshMinutes)
+	config.Agent.DownloadParallelism = getNumericValue(
+		config.Agent.DownloadParallelism,
+		DefaultDownloadParallelismMin,
+		DefaultDownloadParallelismMax,
+		DefaultDownloadParallelism)
+	config.Agent.DownloadRetryLimit = getNumericValue(
+		config.Agent.DownloadRetryLimit,
+		0,
+		DefaultDownloadRetryLimitMax,
+		DefaultDownloadRetryLimit)
+	config.Agent.DownloadBandwidthLimitKBps = getNumericValueAboveMin(
+		config.Agent.DownloadBandwidthLimitKBps,
+		0,
+		0)
+	config.Agent.MinFreeDiskSpaceMB = getNumericValueAboveMin(
+		config.Agent.MinFreeDiskSpaceMB,
+		0,
+		DefaultMinFreeDiskSpaceMB)
+	config.Agent.OrchestrationQuotaMB = getNumericValueAboveMin(
+		config.Agent.OrchestrationQuotaMB,
+		0,
+		DefaultOrchestrationQuotaMB)
+	config.Agent.DownloadQuotaMB = getNumericValueAboveMin(
+		config.Agent.DownloadQuotaMB,
+		0,
+		DefaultDownloadQuotaMB)
+	config.Agent.QuotaMaxAgeDays = getNumericValueAboveMin(
+		config.Agent.QuotaMaxAgeDays,
+		0,
+		DefaultQuotaMaxAgeDays)
 
 	// MDS config
 	config.Mds.CommandWorkersLimit = getNumericValue(
@@ -45,6 +116,11 @@ func parser(config *SsmagentConfig) {
 		DefaultStopTimeoutMillisMin,
 		DefaultStopTimeoutMillisMax,
 		DefaultStopTimeoutMillis)
+	config.Mds.ReplyFlushIntervalMillis = getNumericValue(
+		config.Mds.ReplyFlushIntervalMillis,
+		0,
+		DefaultReplyFlushIntervalMillisMax,
+		DefaultReplyFlushIntervalMillis)
 	config.Mds.Endpoint = getStringValue(config.Mds.Endpoint, "")
 
 	// SSM config
@@ -67,7 +143,31 @@ func parser(config *SsmagentConfig) {
 		config.Ssm.RunCommandLogsRetentionDurationHours,
 		DefaultStateOrchestrationLogsRetentionDurationHoursMin,
 		DefaultRunCommandLogsRetentionDurationHours)
+	config.Ssm.ConnectivityCheckSeconds = getNumericValue(
+		config.Ssm.ConnectivityCheckSeconds,
+		0,
+		DefaultSsmConnectivityCheckSecondsMax,
+		DefaultSsmConnectivityCheckSeconds)
+	config.Ssm.ConnectivityFailureThreshold = getNumericValue(
+		config.Ssm.ConnectivityFailureThreshold,
+		DefaultSsmConnectivityFailureThresholdMin,
+		DefaultSsmConnectivityFailureThresholdMax,
+		DefaultSsmConnectivityFailureThreshold)
 
+	// Execution policy
+	if _, err := ParseUmask(config.ExecutionPolicy.MinUmask); err != nil {
+		config.ExecutionPolicy.MinUmask = DefaultExecutionPolicyMinUmask
+	}
+
+	// Document concurrency, the documents without a valid limit aren't limited
+	for name, limit := range config.DocumentConcurrency.MaxConcurrent {
+		if limit < 1 {
+			delete(config.DocumentConcurrency.MaxConcurrent, name)
+		}
+	}
+
+	// Identity config
+	config.Identity.ConsumptionOrder = getIdentityConsumptionOrder(config.Identity.ConsumptionOrder)
 }
 
 // TODO https://sim.amazon.com/issues/SSM-3439
@@ -91,6 +191,79 @@ func getStringValue(configValue string, defaultValue string) string {
 	return configValue
 }
 
+// getLogBackend returns the supported log backends of the comma separated list, the file backend if there is none
+func getLogBackend(configValue string) string {
+	var backends []string
+	for _, value := range strings.Split(configValue, ",") {
+		backend := strings.ToLower(strings.TrimSpace(value))
+		switch backend {
+		case "":
+			continue
+		case LogBackendFile, LogBackendJournald, LogBackendSyslog, LogBackendEventLog:
+		default:
+			log.Printf("unsupported log backend %v is ignored", value)
+			continue
+		}
+		if !containsString(backends, backend) {
+			backends = append(backends, backend)
+		}
+	}
+	if len(backends) == 0 {
+		return LogBackendFile
+	}
+	return strings.Join(backends, ",")
+}
+
+// getProxy returns the proxy URL, empty if it isn't a valid URL
+func getProxy(configValue string) string {
+	proxy := strings.TrimSpace(configValue)
+	if proxy == "" {
+		return ""
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	if parsed, err := url.Parse(proxy); err != nil || parsed.Host == "" {
+		log.Printf("invalid proxy %v is ignored", configValue)
+		return ""
+	}
+	return proxy
+}
+
+// getIdentityConsumptionOrder returns the supported identity sources of the comma separated list,
+// the default order if there is none
+func getIdentityConsumptionOrder(configValue string) string {
+	var sources []string
+	for _, value := range strings.Split(configValue, ",") {
+		source := strings.ToLower(strings.TrimSpace(value))
+		switch source {
+		case "":
+			continue
+		case IdentitySourceStatic, IdentitySourceOnPrem, IdentitySourceEC2, IdentitySourceECS:
+		default:
+			log.Printf("unsupported identity source %v is ignored", value)
+			continue
+		}
+		if !containsString(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return DefaultIdentityConsumptionOrder
+	}
+	return strings.Join(sources, ",")
+}
+
+// containsString returns true if the value is one of the values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // getNumericValueAboveMin returns the default if config is below minimum
 func getNumericValueAboveMin(configValue int, minValue int, defaultValue int) int {
 	if configValue < minValue {
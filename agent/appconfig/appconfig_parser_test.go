@@ -41,6 +41,20 @@ func TestGetStringValue(t *testing.T) {
 	}
 }
 
+func TestGetLogBackend(t *testing.T) {
+	assert.Equal(t, LogBackendFile, getLogBackend(""))
+	assert.Equal(t, LogBackendFile, getLogBackend("eventvwr"))
+	assert.Equal(t, LogBackendJournald, getLogBackend("Journald"))
+	assert.Equal(t, LogBackendSyslog, getLogBackend("syslog"))
+	assert.Equal(t, "file,eventlog", getLogBackend("File, EventLog, eventlog, kafka"))
+}
+
+func TestGetIdentityConsumptionOrder(t *testing.T) {
+	assert.Equal(t, DefaultIdentityConsumptionOrder, getIdentityConsumptionOrder(""))
+	assert.Equal(t, DefaultIdentityConsumptionOrder, getIdentityConsumptionOrder("azure"))
+	assert.Equal(t, "ec2,onprem", getIdentityConsumptionOrder("EC2, OnPrem, ec2, azure"))
+}
+
 //GetDefaultEndpointTests
 
 type GetDefaultEndPointTest struct {
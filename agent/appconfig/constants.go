@@ -31,11 +31,113 @@ const (
 	DefaultStopTimeoutMillisMin = 10000
 	DefaultStopTimeoutMillisMax = 1000000
 
+	// DefaultReplyFlushIntervalMillis is how long the replies sent after the steps of a command are coalesced by default
+	DefaultReplyFlushIntervalMillis    = 1000
+	DefaultReplyFlushIntervalMillisMax = 60000
+
+	// DefaultPluginOutputMaxRolls represents the default number of rotated plugin output files kept
+	DefaultPluginOutputMaxRolls = 3
+
+	// DefaultPartialOutputIntervalSeconds is how often the output of a running step is reported by default
+	DefaultPartialOutputIntervalSeconds    = 60
+	DefaultPartialOutputIntervalSecondsMax = 3600
+
+	// DefaultHistoryRetentionDays is how long the execution history is kept by default, and
+	// DefaultHistoryMaxEntries the number of executions it keeps
+	DefaultHistoryRetentionDays    = 30
+	DefaultHistoryRetentionDaysMax = 3650
+	DefaultHistoryMaxEntries       = 1000
+	DefaultHistoryMaxEntriesMin    = 1
+
+	// DefaultExecutionHookTimeoutSeconds is how long the pre-execution and post-execution hooks run by default
+	DefaultExecutionHookTimeoutSeconds    = 60
+	DefaultExecutionHookTimeoutSecondsMin = 1
+	DefaultExecutionHookTimeoutSecondsMax = 3600
+
+	// DefaultCloudWatchOutputLogStream is the name of the log streams of the output of the steps by default
+	DefaultCloudWatchOutputLogStream = "{commandId}/{instanceId}/{stepName}/{stream}"
+
+	// DefaultCloudWatchOutputFlushIntervalSeconds is how often the output of the steps is sent to CloudWatch Logs
+	// by default
+	DefaultCloudWatchOutputFlushIntervalSeconds    = 5
+	DefaultCloudWatchOutputFlushIntervalSecondsMin = 1
+	DefaultCloudWatchOutputFlushIntervalSecondsMax = 60
+
+	// DefaultDownloadParallelism is the number of parts of a large file downloaded at the same time by default
+	DefaultDownloadParallelism    = 4
+	DefaultDownloadParallelismMin = 1
+	DefaultDownloadParallelismMax = 16
+
+	// DefaultDownloadRetryLimit is the number of times an interrupted download is resumed by default
+	DefaultDownloadRetryLimit    = 5
+	DefaultDownloadRetryLimitMax = 100
+
+	// DefaultMinFreeDiskSpaceMB is the space left free on the filesystems the agent writes to by default
+	DefaultMinFreeDiskSpaceMB = 100
+
+	// DefaultOrchestrationQuotaMB is the total size of the orchestration directories kept by default
+	DefaultOrchestrationQuotaMB = 1024
+
+	// DefaultDownloadQuotaMB is the total size of the download directory kept by default
+	DefaultDownloadQuotaMB = 1024
+
+	// DefaultQuotaMaxAgeDays is how long the orchestration directories and the downloads are kept by default
+	DefaultQuotaMaxAgeDays = 30
+
+	// LogBackendFile writes the agent logs to the outputs of seelog.xml, the default
+	LogBackendFile = "file"
+
+	// LogBackendJournald sends the agent logs to the systemd journal instead of the file and console outputs of seelog.xml
+	LogBackendJournald = "journald"
+
+	// LogBackendSyslog sends the agent logs to the local syslog daemon instead of the file and console outputs of seelog.xml
+	LogBackendSyslog = "syslog"
+
+	// LogBackendEventLog sends the agent logs to the Windows Event Log instead of the file and console outputs of seelog.xml
+	LogBackendEventLog = "eventlog"
+
+	// IdentitySourceStatic is the Identity.InstanceID and Identity.Region of the configuration
+	IdentitySourceStatic = "static"
+
+	// IdentitySourceOnPrem is the registration of the managed instance, by amazon-ssm-agent -register
+	IdentitySourceOnPrem = "onprem"
+
+	// IdentitySourceEC2 is the EC2 instance metadata service
+	IdentitySourceEC2 = "ec2"
+
+	// IdentitySourceECS is the metadata endpoint of the ECS task the agent runs in
+	IdentitySourceECS = "ecs"
+
+	// DefaultIdentityConsumptionOrder is the order the identity sources are tried in by default
+	DefaultIdentityConsumptionOrder = "static,onprem,ec2,ecs"
+
+	// DefaultRemoteConfigRefreshMinutes is how often the remote configuration is fetched by default
+	DefaultRemoteConfigRefreshMinutes    = 30
+	DefaultRemoteConfigRefreshMinutesMin = 5
+	DefaultRemoteConfigRefreshMinutesMax = 1440
+
+	// DefaultExecutionPolicyMinUmask is the umask the umask of the documents must include by default
+	DefaultExecutionPolicyMinUmask = "022"
+
+	// DefaultPatchingCategory is the category of the documents patching the instance, which run one at a time
+	// by default since concurrent runs corrupt the state of the package managers
+	DefaultPatchingCategory = "Patching"
+	// DefaultPatchingDocuments are the documents of DefaultPatchingCategory
+	DefaultPatchingDocuments = "AWS-RunPatchBaseline,AWS-RunPatchBaselineAssociation,AWS-RunPatchBaselineWithHooks,AWS-InstallWindowsUpdates"
+
 	// SSM defaults
 	DefaultSsmHealthFrequencyMinutes    = 5
 	DefaultSsmHealthFrequencyMinutesMin = 5
 	DefaultSsmHealthFrequencyMinutesMax = 60
 
+	// DefaultSsmConnectivityCheckSeconds disables the connectivity check by default
+	DefaultSsmConnectivityCheckSeconds    = 0
+	DefaultSsmConnectivityCheckSecondsMax = 300
+
+	DefaultSsmConnectivityFailureThreshold    = 3
+	DefaultSsmConnectivityFailureThresholdMin = 1
+	DefaultSsmConnectivityFailureThresholdMax = 10
+
 	DefaultSsmAssociationFrequencyMinutes    = 10
 	DefaultSsmAssociationFrequencyMinutesMin = 5
 	DefaultSsmAssociationFrequencyMinutesMax = 60
@@ -71,6 +173,13 @@ const (
 	ComplianceRootDirName         = "compliance"
 	ComplianceContentHashFileName = "contentHash"
 
+	// AttachmentsCacheRootDirName is the directory caching the files attached to documents
+	AttachmentsCacheRootDirName = "attachments"
+
+	// DocumentsCacheRootDirName is the directory caching the documents received from SSM, which the local
+	// commands can refer to by name
+	DocumentsCacheRootDirName = "documentcache"
+
 	// DefaultDocumentRootDirName is the root directory for storing command states
 	DefaultDocumentRootDirName = "document"
 
@@ -143,6 +252,45 @@ const (
 	// PluginNameRefreshAssociation is the name of refresh association plugin
 	PluginNameRefreshAssociation = "aws:refreshAssociation"
 
+	// PluginNameManageAgentConfig is the name of the plugin that applies agent configuration changes
+	PluginNameManageAgentConfig = "aws:manageAgentConfig"
+
+	// PluginNameQuarantine is the name of the plugin that isolates the instance from the network
+	PluginNameQuarantine = "aws:quarantine"
+
+	// PluginNameParseTestReport is the name of the plugin that reports the outcome of JUnit and TAP test reports
+	PluginNameParseTestReport = "aws:parseTestReport"
+
+	// PluginNameManageRegistry is the name of the plugin that asserts Windows registry values and reports their drift
+	PluginNameManageRegistry = "aws:manageRegistry"
+
+	// PluginNameManageAuthorizedKeys is the name of the plugin that adds and removes expiring SSH authorized keys
+	PluginNameManageAuthorizedKeys = "aws:manageAuthorizedKeys"
+
+	// PluginNameGrantTemporaryAdmin is the name of the plugin that makes a user an administrator for a bounded duration
+	PluginNameGrantTemporaryAdmin = "aws:grantTemporaryAdmin"
+
+	// PluginNameConfigureTimeSync is the name of the plugin that configures the time sources and syncs the clock
+	PluginNameConfigureTimeSync = "aws:configureTimeSync"
+
+	// PluginNameRenameHost is the name of the plugin that renames the host and reports its new name
+	PluginNameRenameHost = "aws:renameHost"
+
+	// PluginNameDiagnoseNetwork is the name of the plugin that reports the network configuration and probes connectivity
+	PluginNameDiagnoseNetwork = "aws:diagnoseNetwork"
+
+	// PluginNameManageDiskSpace is the name of the plugin that reports the disk usage and removes caches, old logs and temp files
+	PluginNameManageDiskSpace = "aws:manageDiskSpace"
+
+	// PluginNameManageProcesses is the name of the plugin that lists, signals, kills or limits processes by pattern
+	PluginNameManageProcesses = "aws:manageProcesses"
+
+	// PluginNameRenderTemplate is the name of the plugin that renders configuration files from Parameter Store values
+	PluginNameRenderTemplate = "aws:renderTemplate"
+
+	// PluginNameAwsRunPythonScript is the name of the plugin that runs Python scripts in virtualenvs with their requirements
+	PluginNameAwsRunPythonScript = "aws:runPythonScript"
+
 	// PluginNameAwsPowerShellModule is the name of the PowerShell Module
 	PluginNameAwsPowerShellModule = "aws:psModule"
 
@@ -152,6 +300,9 @@ const (
 	AppConfigFileName    = "amazon-ssm-agent.json"
 	SeelogConfigFileName = "seelog.xml"
 
+	// ConfigKeyFileName is the file of the local key the encrypted:local: settings are decrypted with
+	ConfigKeyFileName = "amazon-ssm-agent.key"
+
 	// Output truncation limits
 	MaxStdoutLength = 24000
 	MaxStderrLength = 8000
@@ -0,0 +1,22 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd netbsd openbsd
+
+package appconfig
+
+const (
+	// DefaultRunAsUser is empty, the commands of the documents run as the user of the agent unless Agent.RunAsUser
+	// names a user, which the packages don't create on these platforms
+	DefaultRunAsUser = ""
+)
@@ -25,6 +25,9 @@ const (
 	// AppConfigPath is the path of the AppConfig
 	AppConfigPath = DefaultProgramFolder + AppConfigFileName
 
+	// ConfigKeyPath is the path of the local key of the encrypted settings
+	ConfigKeyPath = DefaultProgramFolder + ConfigKeyFileName
+
 	// PackageRoot specifies the directory under which packages will be downloaded and installed
 	PackageRoot = "/var/lib/amazon/ssm/packages"
 
@@ -49,6 +52,10 @@ const (
 	// are moved if the service cannot validate the document (generally impossible via cli)
 	LocalCommandRootInvalid = "/var/lib/amazon/ssm/localcommands/invalid"
 
+	// LocalCommandRootPendingUpload is the directory where the results of the local commands
+	// wait to be uploaded to S3, while the instance is offline
+	LocalCommandRootPendingUpload = "/var/lib/amazon/ssm/localcommands/pendingupload"
+
 	// DownloadRoot specifies the directory under which files will be downloaded
 	DownloadRoot = "/var/log/amazon/ssm/download/"
 
@@ -53,6 +53,9 @@ const (
 	// https://groups.google.com/forum/#!topic/golang-nuts/ggd3ww3ZKcI
 	ExitCodeTrap = " ; exit $LASTEXITCODE"
 
+	// DefaultRunAsUser is empty, the commands of the documents run as the user of the agent on Windows
+	DefaultRunAsUser = ""
+
 	// Exit Code for a command that exits before completion (generally due to timeout or cancel)
 	CommandStoppedPreemptivelyExitCode = -1
 
@@ -78,6 +81,9 @@ var DefaultDocumentWorker string
 // AppConfig Path
 var AppConfigPath string
 
+// ConfigKeyPath is the path of the local key of the encrypted settings, in the data folder only administrators read
+var ConfigKeyPath string
+
 // DefaultDataStorePath represents the directory for storing system data
 var DefaultDataStorePath string
 
@@ -102,6 +108,10 @@ var LocalCommandRootCompleted string
 // are moved if the service cannot validate the document (generally impossible via cli)
 var LocalCommandRootInvalid string
 
+// LocalCommandRootPendingUpload is the directory where the results of the local commands
+// wait to be uploaded to S3, while the instance is offline
+var LocalCommandRootPendingUpload string
+
 // DefaultPluginPath represents the directory for storing plugins in SSM
 var DefaultPluginPath string
 
@@ -167,6 +177,7 @@ func init() {
 	DefaultDocumentWorker = filepath.Join(DefaultProgramFolder, "ssm-document-worker.exe")
 	ManifestCacheDirectory = filepath.Join(EnvProgramFiles, ManifestCacheFolder)
 	AppConfigPath = filepath.Join(DefaultProgramFolder, AppConfigFileName)
+	ConfigKeyPath = filepath.Join(SSMDataPath, ConfigKeyFileName)
 	DefaultDataStorePath = filepath.Join(SSMDataPath, "InstanceData")
 	PackageRoot = filepath.Join(SSMDataPath, "Packages")
 	PackageLockRoot = filepath.Join(SSMDataPath, "Locks\\Packages")
@@ -175,6 +186,7 @@ func init() {
 	LocalCommandRootSubmitted = filepath.Join(LocalCommandRoot, "Submitted")
 	LocalCommandRootCompleted = filepath.Join(LocalCommandRoot, "Completed")
 	LocalCommandRootInvalid = filepath.Join(LocalCommandRoot, "Invalid")
+	LocalCommandRootPendingUpload = filepath.Join(LocalCommandRoot, "PendingUpload")
 	DownloadRoot = filepath.Join(temp, SSMFolder, "Download")
 	UpdaterArtifactsRoot = filepath.Join(temp, SSMFolder, "Update")
 	EC2UpdateArtifactsRoot = filepath.Join(EnvWinDir, EC2ConfigServiceFolder, "Update")
@@ -28,6 +28,11 @@ type MdsCfg struct {
 	CommandWorkersLimit int
 	StopTimeoutMillis   int64
 	CommandRetryLimit   int
+	// ReplyFlushIntervalMillis is how long the replies sent after each step of a command are held and
+	// coalesced before they are sent, 0 sends them at once
+	ReplyFlushIntervalMillis int
+	// CompressReplies sends the replies gzip encoded, for the endpoints that accept compressed requests
+	CompressReplies bool
 }
 
 // SsmCfg represents configuration for Simple system manager (SSM)
@@ -42,6 +47,11 @@ type SsmCfg struct {
 	CustomInventoryDefaultLocation        string
 	AssociationLogsRetentionDurationHours int
 	RunCommandLogsRetentionDurationHours  int
+	// ConnectivityCheckSeconds is how often the agent checks it can reach the SSM endpoint, 0 disables the check
+	ConnectivityCheckSeconds int
+	// ConnectivityFailureThreshold is the number of consecutive failed checks after which the agent reports
+	// itself offline
+	ConnectivityFailureThreshold int
 }
 
 // AgentInfo represents metadata for amazon-ssm-agent
@@ -51,6 +61,68 @@ type AgentInfo struct {
 	Region               string
 	OrchestrationRootDir string
 	DownloadRootDir      string
+	// DownloadParallelism is the number of parts downloaded at the same time for the files large enough to be
+	// split, 1 downloads them in one stream
+	DownloadParallelism int
+	// DownloadRetryLimit is the number of times an interrupted download is resumed before it fails
+	DownloadRetryLimit int
+	// DownloadBandwidthLimitKBps caps the overall rate of the downloads, 0 leaves it unlimited
+	DownloadBandwidthLimitKBps int
+	// MinFreeDiskSpaceMB is the space the downloads, the extractions and the plugin outputs leave free on
+	// their filesystem, they fail before writing otherwise; 0 disables the check
+	MinFreeDiskSpaceMB int
+	// OrchestrationQuotaMB is the total size of the orchestration directories of the documents, the oldest are
+	// removed beyond it; 0 disables the quota
+	OrchestrationQuotaMB int
+	// DownloadQuotaMB is the total size of the download directory, the oldest downloads are removed beyond it;
+	// 0 disables the quota
+	DownloadQuotaMB int
+	// QuotaMaxAgeDays is how long the orchestration directories and the downloads are kept after their last
+	// change, 0 keeps them until the size quotas are reached
+	QuotaMaxAgeDays int
+	// PluginOutputMaxSizeMB is the size the plugin output files are rotated at, 0 disables the rotation
+	PluginOutputMaxSizeMB int
+	// PluginOutputMaxRolls is the number of compressed rotated plugin output files kept
+	PluginOutputMaxRolls int
+	// PartialOutputIntervalSeconds is how often the output of a running step is uploaded to S3 and reported
+	// with the InProgress status of the command, 0 reports it when the step ends only
+	PartialOutputIntervalSeconds int
+	// HistoryRetentionDays is how long the history of the documents the agent ran is kept, 0 doesn't record it;
+	// HistoryMaxEntries is the number of executions it keeps
+	HistoryRetentionDays int
+	HistoryMaxEntries    int
+	// PreExecutionHook and PostExecutionHook are the scripts run before and after each document, with the metadata
+	// of the document in AWS_SSM_ environment variables; empty runs none. ExecutionHookTimeoutSeconds is how long
+	// they may run.
+	PreExecutionHook            string
+	PostExecutionHook           string
+	ExecutionHookTimeoutSeconds int
+	// CloudWatchOutputLogGroup and CloudWatchOutputLogStream name the log group and streams the output of the steps
+	// is streamed to, with placeholders such as {instanceId}, {documentName} and {commandId}; an empty log group
+	// streams none. CloudWatchOutputFlushIntervalSeconds is how often the output is sent.
+	CloudWatchOutputLogGroup             string
+	CloudWatchOutputLogStream            string
+	CloudWatchOutputFlushIntervalSeconds int
+	// LogBackend is where the agent logs are written: file, journald, syslog or eventlog.
+	// Backends listed with file, e.g. file,eventlog, are written to in addition to the seelog.xml outputs; without
+	// file, they replace the file and console outputs of seelog.xml and its other outputs are kept.
+	LogBackend string
+	// RemoteConfigParameter is the Parameter Store parameter holding a JSON configuration merged over the file
+	RemoteConfigParameter string
+	// RemoteConfigRefreshMinutes is how often RemoteConfigParameter is fetched again
+	RemoteConfigRefreshMinutes int
+	// RunAsUser is the user the commands of the documents run as, unless a step sets runAsElevated;
+	// empty runs them as the user of the agent
+	RunAsUser string
+	// Proxy is the URL of the proxy the agent reaches the services and the downloads through, in place of the
+	// HTTP_PROXY and HTTPS_PROXY environment variables, and NoProxy the comma separated hosts, domains and CIDR
+	// blocks reached directly, in place of NO_PROXY; empty uses the environment
+	Proxy   string
+	NoProxy string
+	// JobCategoryWeights are the shares of the document workers given to the documents of each category when
+	// documents are waiting for a worker: RunCommand, Association, Inventory and Default. They override the
+	// default weights of 4, 2, 1 and 2, and are applied when the agent starts.
+	JobCategoryWeights map[string]int
 }
 
 // MfsCfg represents configuration for HummingBird service (MFS)
@@ -80,14 +152,71 @@ type BirdwatcherCfg struct {
 	ForceEnable bool
 }
 
+// ExecutionPolicyCfg is the local policy the executionContext of the documents is checked against.
+// The lists are comma separated, * allows any value and an empty list allows none.
+type ExecutionPolicyCfg struct {
+	// AllowedUsers are the users the documents may run their commands as
+	AllowedUsers string
+	// AllowedGroups are the groups the documents may run their commands as
+	AllowedGroups string
+	// AllowedSELinuxContexts are the SELinux contexts the documents may run their commands in
+	AllowedSELinuxContexts string
+	// AllowedAppArmorProfiles are the AppArmor profiles the documents may confine their commands to
+	AllowedAppArmorProfiles string
+	// AllowedEnvironment are the environment variables of the agent the documents may pass to their commands
+	AllowedEnvironment string
+	// MinUmask is the octal umask the umask of the documents must include, e.g. 022 forbids group and world
+	// writable files
+	MinUmask string
+	// AllowElevation lets the steps set runAsElevated to run their commands as the user of the agent instead of
+	// Agent.RunAsUser, see CheckElevation
+	AllowElevation bool
+}
+
+// DocumentSigningCfg is the policy the signatures of the documents are verified against.
+type DocumentSigningCfg struct {
+	// Enforce refuses the documents that aren't signed by one of PublicKeys, or whose content doesn't match their
+	// signature
+	Enforce bool
+	// PublicKeys are the PEM files of the public keys the documents may be signed with, by key id
+	PublicKeys map[string]string
+}
+
+// DocumentConcurrencyCfg limits the number of executions of the same documents running at the same time, across
+// Run Command and State Manager. The documents without a limit aren't limited.
+type DocumentConcurrencyCfg struct {
+	// MaxConcurrent is the maximum number of concurrent executions, by document name or by category
+	MaxConcurrent map[string]int
+	// Categories are the comma separated names of the documents of each category, * is any document. The documents
+	// of a category share its limit.
+	Categories map[string]string
+}
+
+// IdentityCfg represents configuration related to where the agent gets its instance ID and region from
+type IdentityCfg struct {
+	// ConsumptionOrder is the comma separated list of the sources tried in order: static, onprem, ec2 and ecs.
+	// The instance ID and the region come from the first source providing an instance ID.
+	ConsumptionOrder string
+	// InstanceID is the instance ID of the static source
+	InstanceID string
+	// Region is the region of the static source
+	Region string
+}
+
 // SsmagentConfig stores agent configuration values.
 type SsmagentConfig struct {
-	Profile     CredentialProfile
-	Mds         MdsCfg
-	Ssm         SsmCfg
-	Mfs         MfsCfg
-	Agent       AgentInfo
-	Os          OsInfo
-	S3          S3Cfg
-	Birdwatcher BirdwatcherCfg
+	Profile         CredentialProfile
+	Mds             MdsCfg
+	Ssm             SsmCfg
+	Mfs             MfsCfg
+	Agent           AgentInfo
+	Os              OsInfo
+	S3              S3Cfg
+	Birdwatcher     BirdwatcherCfg
+	ExecutionPolicy ExecutionPolicyCfg
+	DocumentSigning DocumentSigningCfg
+
+	DocumentConcurrency DocumentConcurrencyCfg
+	Identity            IdentityCfg
+	Plugins             PluginsCfg
 }
@@ -0,0 +1,253 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+const (
+	// EffectiveConfigFileName is the file the agent writes its effective configuration to, in the data folder
+	EffectiveConfigFileName = "effective-config.json"
+
+	// SourceDefault is the source of the settings set by no configuration
+	SourceDefault = "default"
+	// SourceFile is the source of the settings set by amazon-ssm-agent.json
+	SourceFile = "file"
+	// SourceRemote is the source of the settings set by the remote configuration of Agent.RemoteConfigParameter
+	SourceRemote = "remote"
+	// SourceEnvironment is the source of the settings set by AMAZON_SSM_AGENT_ environment variables
+	SourceEnvironment = "environment"
+	// SourceAgent is the source of the settings the agent sets itself, Os.Name and Agent.Version
+	SourceAgent = "agent"
+)
+
+// EffectiveConfiguration is the configuration the agent runs with, with the source of each setting.
+type EffectiveConfiguration struct {
+	// ConfigPath is the configuration file merged with the other sources
+	ConfigPath string
+	// Time is when the configuration was merged
+	Time     time.Time
+	Settings []EffectiveSetting
+}
+
+// EffectiveSetting is a setting of the effective configuration.
+type EffectiveSetting struct {
+	// Name is the section and the field of the setting, e.g. Mds.CommandWorkersLimit
+	Name   string
+	Value  interface{}
+	Source string
+	// Note explains a value the agent doesn't use as is, e.g. out of range and replaced by the default
+	Note string `json:",omitempty"`
+}
+
+// effectiveConfigPath returns the path of the effective configuration written by the agent
+var effectiveConfigPath = func() string {
+	return filepath.Join(DefaultDataStorePath, EffectiveConfigFileName)
+}
+
+// EffectiveConfig merges the defaults, the configuration file at path, the remote configuration and the environment
+// overrides as the agent does, and returns every setting with the source of its value. A missing file is skipped.
+// The encrypted settings are returned encrypted.
+func EffectiveConfig(path string) (EffectiveConfiguration, error) {
+	effective := EffectiveConfiguration{ConfigPath: path, Time: time.Now().UTC()}
+
+	// merge the sources without applying the limits, the values the sources set
+	merged := DefaultConfig()
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return effective, err
+	}
+	if err == nil {
+		if err = json.Unmarshal(content, &merged); err != nil {
+			return effective, fmt.Errorf("invalid configuration file %v: %v", path, err)
+		}
+	}
+	applyRemoteConfig(&merged)
+	applyEnvironment(&merged)
+
+	agentConfig := merged
+	agentConfig.Os.Name = runtime.GOOS
+	agentConfig.Agent.Version = version.Version
+	parser(&agentConfig)
+
+	remote.m.RLock()
+	remoteContent := readRemoteConfig()
+	remote.m.RUnlock()
+	fileSettings := jsonSettings(content)
+	remoteSettings := jsonSettings(remoteContent)
+	delete(remoteSettings, "Agent.RemoteConfigParameter")
+	environmentSettings := environmentSettings()
+
+	mergedValue := reflect.ValueOf(merged)
+	configValue := reflect.ValueOf(agentConfig)
+	for i := 0; i < configValue.NumField(); i++ {
+		sectionName := configValue.Type().Field(i).Name
+		section := configValue.Field(i)
+		if section.Kind() != reflect.Struct {
+			effective.Settings = append(effective.Settings, effectiveSetting(sectionName, section, mergedValue.Field(i),
+				fileSettings, remoteSettings, environmentSettings))
+			continue
+		}
+		for j := 0; j < section.NumField(); j++ {
+			name := sectionName + "." + section.Type().Field(j).Name
+			effective.Settings = append(effective.Settings, effectiveSetting(name, section.Field(j),
+				mergedValue.Field(i).Field(j), fileSettings, remoteSettings, environmentSettings))
+		}
+	}
+	return effective, nil
+}
+
+// effectiveSetting returns the setting with the source that set it last, and a note when the agent doesn't use
+// the value of the source
+func effectiveSetting(name string, value reflect.Value, mergedValue reflect.Value,
+	fileSettings, remoteSettings, environmentSettings map[string]bool) EffectiveSetting {
+
+	setting := EffectiveSetting{Name: name, Value: value.Interface(), Source: SourceDefault}
+	switch {
+	case name == "Os.Name" || name == "Agent.Version":
+		setting.Source = SourceAgent
+		return setting
+	case environmentSettings[name]:
+		setting.Source = SourceEnvironment
+	case remoteSettings[name]:
+		setting.Source = SourceRemote
+	case fileSettings[name]:
+		setting.Source = SourceFile
+	}
+	if !reflect.DeepEqual(mergedValue.Interface(), setting.Value) {
+		setting.Note = fmt.Sprintf("the %v value %v is invalid, replaced by the default", setting.Source, mergedValue.Interface())
+	} else if text, ok := setting.Value.(string); ok && IsEncrypted(text) {
+		setting.Note = "encrypted, decrypted by the agent when it loads the configuration"
+	}
+	return setting
+}
+
+// jsonSettings returns the names of the settings set by a configuration in the format of amazon-ssm-agent.json,
+// matching the sections and the fields without case as the JSON decoding does. A section other than a struct, e.g.
+// Plugins, is a single setting.
+func jsonSettings(content []byte) map[string]bool {
+	names := make(map[string]bool)
+	var sections map[string]json.RawMessage
+	if len(content) == 0 || json.Unmarshal(content, &sections) != nil {
+		return names
+	}
+	configType := reflect.TypeOf(SsmagentConfig{})
+	for key, raw := range sections {
+		section, found := fieldByName(configType, key)
+		if !found {
+			continue
+		}
+		if section.Type.Kind() != reflect.Struct {
+			names[section.Name] = true
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(raw, &fields) != nil {
+			continue
+		}
+		for key := range fields {
+			if field, found := fieldByName(section.Type, key); found {
+				names[section.Name+"."+field.Name] = true
+			}
+		}
+	}
+	return names
+}
+
+// environmentSettings returns the names of the settings set by valid environment overrides
+func environmentSettings() map[string]bool {
+	names := make(map[string]bool)
+	variables := environmentVariables()
+	if len(variables) == 0 {
+		return names
+	}
+	configType := reflect.TypeOf(SsmagentConfig{})
+	for i := 0; i < configType.NumField(); i++ {
+		section := configType.Field(i)
+		if section.Type.Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < section.Type.NumField(); j++ {
+			field := section.Type.Field(j)
+			value, found := variables[EnvironmentVariable(section.Name, field.Name)]
+			if found && setField(reflect.New(field.Type).Elem(), value) == nil {
+				names[section.Name+"."+field.Name] = true
+			}
+		}
+	}
+	return names
+}
+
+// WriteEffectiveConfig writes the effective configuration of the configuration file at path to the data folder,
+// for ssm-cli get-effective-config. The settings that aren't reloadable keep the values the agent started with.
+func WriteEffectiveConfig(path string) error {
+	effective, err := EffectiveConfig(path)
+	if err != nil {
+		return err
+	}
+	if running, err := Config(false); err == nil {
+		keepRunningValues(&effective, running)
+	}
+	content, err := json.MarshalIndent(effective, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(effectiveConfigPath()), ReadWriteExecuteAccess); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(effectiveConfigPath(), content, ReadWriteAccess)
+}
+
+// keepRunningValues replaces the changed settings that aren't reloadable by the values of the running configuration
+func keepRunningValues(effective *EffectiveConfiguration, running SsmagentConfig) {
+	runningValue := reflect.ValueOf(running)
+	for i, setting := range effective.Settings {
+		if IsReloadable(setting.Name) || setting.Source == SourceAgent {
+			continue
+		}
+		if text, ok := setting.Value.(string); ok && IsEncrypted(text) {
+			// the running value is decrypted
+			continue
+		}
+		value := runningValue
+		for _, part := range strings.Split(setting.Name, ".") {
+			value = value.FieldByName(part)
+		}
+		if value.IsValid() && !reflect.DeepEqual(value.Interface(), setting.Value) {
+			effective.Settings[i].Note = fmt.Sprintf("changed to %v, takes effect when the agent restarts", setting.Value)
+			effective.Settings[i].Value = value.Interface()
+		}
+	}
+}
+
+// ReadEffectiveConfig returns the effective configuration last written by the agent.
+func ReadEffectiveConfig() (effective EffectiveConfiguration, err error) {
+	content, err := ioutil.ReadFile(effectiveConfigPath())
+	if err != nil {
+		return effective, err
+	}
+	err = json.Unmarshal(content, &effective)
+	return effective, err
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// settingByName returns the setting of the effective configuration with the name
+func settingByName(t *testing.T, effective EffectiveConfiguration, name string) EffectiveSetting {
+	for _, setting := range effective.Settings {
+		if setting.Name == name {
+			return setting
+		}
+	}
+	t.Fatalf("setting %v not found", name)
+	return EffectiveSetting{}
+}
+
+func TestEffectiveConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, AppConfigFileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{
+		"Mds": {"commandWorkersLimit": 10, "StopTimeoutMillis": 30000},
+		"Ssm": {"HealthFrequencyMinutes": 1},
+		"Agent": {"Region": "us-east-1"},
+		"Plugins": {"aws:runShellScript": {"MaxTimeoutSeconds": 3600}}}`), 0600))
+	defer setEnvironment("AMAZON_SSM_AGENT_AGENT_REGION=eu-west-1", "AMAZON_SSM_AGENT_MDS_COMMANDRETRYLIMIT=five")()
+	defer withRemoteSource(DefaultConfig(), nil)()
+	setRemoteConfig([]byte(`{"Mds": {"StopTimeoutMillis": 40000}, "Agent": {"RemoteConfigParameter": "other"}}`))
+
+	effective, err := EffectiveConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, path, effective.ConfigPath)
+	assert.Equal(t, EffectiveSetting{Name: "Mds.CommandWorkersLimit", Value: 10, Source: SourceFile}, settingByName(t, effective, "Mds.CommandWorkersLimit"))
+	assert.Equal(t, EffectiveSetting{Name: "Mds.StopTimeoutMillis", Value: int64(40000), Source: SourceRemote}, settingByName(t, effective, "Mds.StopTimeoutMillis"))
+	assert.Equal(t, EffectiveSetting{Name: "Agent.Region", Value: "eu-west-1", Source: SourceEnvironment}, settingByName(t, effective, "Agent.Region"))
+	assert.Equal(t, SourceDefault, settingByName(t, effective, "Mds.CommandRetryLimit").Source, "the invalid override is ignored")
+	assert.Equal(t, SourceDefault, settingByName(t, effective, "Agent.RemoteConfigParameter").Source, "the remote configuration doesn't set its parameter")
+	assert.Equal(t, SourceAgent, settingByName(t, effective, "Os.Name").Source)
+	assert.Equal(t, SourceFile, settingByName(t, effective, "Plugins").Source)
+
+	health := settingByName(t, effective, "Ssm.HealthFrequencyMinutes")
+	assert.Equal(t, DefaultSsmHealthFrequencyMinutes, health.Value)
+	assert.Equal(t, SourceFile, health.Source)
+	assert.Equal(t, "the file value 1 is invalid, replaced by the default", health.Note)
+}
+
+func TestEffectiveConfigWithoutFile(t *testing.T) {
+	defer setEnvironment("AMAZON_SSM_AGENT_SSM_HEALTHFREQUENCYMINUTES=10")()
+
+	effective, err := EffectiveConfig(filepath.Join(os.TempDir(), "missing", AppConfigFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, SourceEnvironment, settingByName(t, effective, "Ssm.HealthFrequencyMinutes").Source)
+	assert.Equal(t, SourceDefault, settingByName(t, effective, "Mds.CommandWorkersLimit").Source)
+}
+
+func TestWriteEffectiveConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	effectiveConfigPath = func() string { return filepath.Join(dir, "data", EffectiveConfigFileName) }
+	defer func() {
+		effectiveConfigPath = func() string { return filepath.Join(DefaultDataStorePath, EffectiveConfigFileName) }
+	}()
+
+	// the agent started with another endpoint, which isn't reloadable, and reloaded the workers limit
+	running := DefaultConfig()
+	running.Mds.Endpoint = "mds.example.com"
+	running.Mds.CommandWorkersLimit = 8
+	defer withRemoteSource(running, nil)()
+	path := filepath.Join(dir, AppConfigFileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Mds": {"Endpoint": "mds2.example.com", "CommandWorkersLimit": 8}}`), 0600))
+
+	assert.NoError(t, WriteEffectiveConfig(path))
+	effective, err := ReadEffectiveConfig()
+	assert.NoError(t, err)
+	endpoint := settingByName(t, effective, "Mds.Endpoint")
+	assert.Equal(t, "mds.example.com", endpoint.Value)
+	assert.Equal(t, "changed to mds2.example.com, takes effect when the agent restarts", endpoint.Note)
+	assert.Equal(t, float64(8), settingByName(t, effective, "Mds.CommandWorkersLimit").Value)
+	assert.Empty(t, settingByName(t, effective, "Mds.CommandWorkersLimit").Note)
+}
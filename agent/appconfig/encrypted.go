@@ -0,0 +1,215 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/log/redact"
+)
+
+const (
+	// EncryptedPrefix marks the encrypted settings, followed by the scheme and the base64 ciphertext,
+	// e.g. encrypted:kms:AQICAHh...
+	EncryptedPrefix = "encrypted:"
+
+	// EncryptionSchemeKMS settings are decrypted by KMS with the credentials of the agent
+	EncryptionSchemeKMS = "kms"
+
+	// EncryptionSchemeLocal settings are decrypted with AES-256-GCM and the key of ConfigKeyPath
+	EncryptionSchemeLocal = "local"
+
+	// localKeySize is the size of the local key, AES-256
+	localKeySize = 32
+)
+
+// Decrypter returns the plaintext of a ciphertext of its scheme.
+type Decrypter func(ciphertext []byte) ([]byte, error)
+
+// decrypters are the decrypters of the schemes, KMS registers its own where the SDK is available
+var decrypters = map[string]Decrypter{EncryptionSchemeLocal: decryptLocal}
+var decryptersLock sync.RWMutex
+
+// configKeyPath is the path of the local key
+var configKeyPath = func() string { return ConfigKeyPath }
+
+// RegisterDecrypter sets the decrypter of the encrypted:<scheme>: settings.
+func RegisterDecrypter(scheme string, decrypter Decrypter) {
+	decryptersLock.Lock()
+	defer decryptersLock.Unlock()
+	decrypters[scheme] = decrypter
+}
+
+// IsEncrypted returns true if the value of a setting is encrypted.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// EncryptLocal encrypts a value with the local key, for the encrypted:local: settings.
+func EncryptLocal(plaintext string) (string, error) {
+	gcm, err := localCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncryptedPrefix + EncryptionSchemeLocal + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// UndecryptedConfig loads the configuration as Config does, without decrypting nor caching it: its encrypted settings
+// are empty. The clients decrypting the configuration read their own settings from it.
+func UndecryptedConfig() SsmagentConfig {
+	agentConfig := defaultConfigWithEnvironment()
+	if path, err := getAppConfigPath(); err == nil {
+		agentConfig, _ = LoadConfigFile(path)
+	}
+	forEachString(&agentConfig, func(path string, field reflect.Value) error {
+		if IsEncrypted(field.String()) {
+			field.SetString("")
+		}
+		return nil
+	})
+	return agentConfig
+}
+
+// parseEncrypted returns the scheme and the ciphertext of an encrypted setting.
+func parseEncrypted(value string) (scheme string, ciphertext []byte, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(value, EncryptedPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", nil, fmt.Errorf("expected %v<scheme>:<base64 ciphertext>", EncryptedPrefix)
+	}
+	if ciphertext, err = base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1])); err != nil {
+		return "", nil, fmt.Errorf("the ciphertext is not base64: %v", err)
+	}
+	return parts[0], ciphertext, nil
+}
+
+// hasEncryptedSettings returns true if any setting is encrypted.
+func hasEncryptedSettings(config SsmagentConfig) bool {
+	found := false
+	forEachString(&config, func(path string, field reflect.Value) error {
+		found = found || IsEncrypted(field.String())
+		return nil
+	})
+	return found
+}
+
+// decryptSettings replaces the encrypted settings with their plaintext, which is redacted from the logs.
+func decryptSettings(config *SsmagentConfig) error {
+	return forEachString(config, func(path string, field reflect.Value) error {
+		if !IsEncrypted(field.String()) {
+			return nil
+		}
+		plaintext, err := decrypt(field.String())
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %v: %v", path, err)
+		}
+		redact.AddSecret(plaintext)
+		field.SetString(plaintext)
+		return nil
+	})
+}
+
+// decrypt returns the plaintext of an encrypted setting.
+func decrypt(value string) (string, error) {
+	scheme, ciphertext, err := parseEncrypted(value)
+	if err != nil {
+		return "", err
+	}
+	decryptersLock.RLock()
+	decrypter, found := decrypters[scheme]
+	decryptersLock.RUnlock()
+	if !found {
+		return "", fmt.Errorf("unsupported encryption scheme %q", scheme)
+	}
+	plaintext, err := decrypter(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// decryptLocal decrypts a nonce and ciphertext of AES-256-GCM with the local key.
+func decryptLocal(ciphertext []byte) ([]byte, error) {
+	gcm, err := localCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("the ciphertext is too short")
+	}
+	plaintext, err := gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("the ciphertext doesn't match the key of %v", configKeyPath())
+	}
+	return plaintext, nil
+}
+
+// localCipher returns AES-256-GCM with the local key, a base64 encoded 32 bytes key only its owner may read.
+func localCipher() (cipher.AEAD, error) {
+	path := configKeyPath()
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the key: %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+			return nil, fmt.Errorf("%v must only be readable by its owner, its mode is %v", path, info.Mode().Perm())
+		}
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil || len(key) != localKeySize {
+		return nil, fmt.Errorf("%v must contain a base64 encoded %d bytes key", path, localKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// forEachString calls the function with each string setting, stopping at the first error.
+func forEachString(config *SsmagentConfig, function func(path string, field reflect.Value) error) error {
+	configValue := reflect.ValueOf(config).Elem()
+	for i := 0; i < configValue.NumField(); i++ {
+		section := configValue.Field(i)
+		if section.Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < section.NumField(); j++ {
+			if section.Field(j).Kind() != reflect.String {
+				continue
+			}
+			path := configValue.Type().Field(i).Name + "." + section.Type().Field(j).Name
+			if err := function(path, section.Field(j)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
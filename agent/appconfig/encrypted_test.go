@@ -0,0 +1,179 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withLocalKey writes a new local key and returns the function restoring the key path.
+func withLocalKey(t *testing.T, mode os.FileMode) (dir string, restore func()) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	key := make([]byte, localKeySize)
+	_, err = rand.Read(key)
+	assert.NoError(t, err)
+	path := filepath.Join(dir, ConfigKeyFileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), mode))
+	assert.NoError(t, os.Chmod(path, mode))
+	configKeyPath = func() string { return path }
+	return dir, func() {
+		configKeyPath = func() string { return ConfigKeyPath }
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEncryptLocal(t *testing.T) {
+	_, restore := withLocalKey(t, 0600)
+	defer restore()
+
+	value, err := EncryptLocal("proxy-password")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(value, "encrypted:local:"))
+	assert.NotContains(t, value, "proxy-password")
+
+	plaintext, err := decrypt(value)
+	assert.NoError(t, err)
+	assert.Equal(t, "proxy-password", plaintext)
+}
+
+func TestDecryptLocalWrongKey(t *testing.T) {
+	_, restore := withLocalKey(t, 0600)
+	value, err := EncryptLocal("proxy-password")
+	restore()
+	assert.NoError(t, err)
+
+	_, restore = withLocalKey(t, 0600)
+	defer restore()
+	_, err = decrypt(value)
+	assert.Error(t, err)
+}
+
+func TestLocalKeyReadableByOthers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the key permissions are only checked on unix")
+	}
+	_, restore := withLocalKey(t, 0644)
+	defer restore()
+
+	_, err := EncryptLocal("proxy-password")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only be readable by its owner")
+}
+
+func TestDecryptInvalidValues(t *testing.T) {
+	for _, value := range []string{"encrypted:local", "encrypted:local:not base64!", "encrypted:rot13:c2VjcmV0"} {
+		_, err := decrypt(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestDecryptSettings(t *testing.T) {
+	RegisterDecrypter("test", func(ciphertext []byte) ([]byte, error) {
+		if string(ciphertext) == "fail" {
+			return nil, errors.New("access denied")
+		}
+		return []byte(strings.ToUpper(string(ciphertext))), nil
+	})
+	defer delete(decrypters, "test")
+
+	config := DefaultConfig()
+	config.Agent.Region = "us-east-1"
+	config.Mds.Endpoint = "encrypted:test:" + base64.StdEncoding.EncodeToString([]byte("endpoint"))
+	assert.True(t, hasEncryptedSettings(config))
+
+	assert.NoError(t, decryptSettings(&config))
+	assert.Equal(t, "ENDPOINT", config.Mds.Endpoint)
+	assert.Equal(t, "us-east-1", config.Agent.Region)
+	assert.False(t, hasEncryptedSettings(config))
+
+	config.Ssm.Endpoint = "encrypted:test:" + base64.StdEncoding.EncodeToString([]byte("fail"))
+	err := decryptSettings(&config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Ssm.Endpoint")
+}
+
+func TestDecryptAndCache(t *testing.T) {
+	saved := loadedConfig
+	defer func() { loadedConfig = saved }()
+	loadedConfig = nil
+	var duringDecryption SsmagentConfig
+	RegisterDecrypter("test", func(ciphertext []byte) ([]byte, error) {
+		// as the KMS client does
+		duringDecryption, _ = Config(false)
+		if string(ciphertext) == "fail" {
+			return nil, errors.New("access denied")
+		}
+		return []byte(strings.ToUpper(string(ciphertext))), nil
+	})
+	defer delete(decrypters, "test")
+
+	config := DefaultConfig()
+	config.Mds.Endpoint = "encrypted:test:" + base64.StdEncoding.EncodeToString([]byte("endpoint"))
+	decrypted, err := decryptAndCache(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "ENDPOINT", decrypted.Mds.Endpoint)
+	assert.False(t, IsEncrypted(duringDecryption.Mds.Endpoint), "the ciphertexts aren't returned during the decryption")
+	cached, err := Config(false)
+	assert.NoError(t, err)
+	assert.Equal(t, "ENDPOINT", cached.Mds.Endpoint)
+
+	// the configuration cached is kept when a reload fails to decrypt
+	config.Mds.Endpoint = "encrypted:test:" + base64.StdEncoding.EncodeToString([]byte("fail"))
+	_, err = decryptAndCache(config)
+	assert.Error(t, err)
+	assert.Equal(t, "ENDPOINT", duringDecryption.Mds.Endpoint)
+	cached, err = Config(false)
+	assert.NoError(t, err)
+	assert.Equal(t, "ENDPOINT", cached.Mds.Endpoint)
+
+	// without configuration cached, none is until the decryption succeeds
+	loadedConfig = nil
+	_, err = decryptAndCache(config)
+	assert.Error(t, err)
+	assert.False(t, isLoaded())
+}
+
+func TestLoadConfigFileEncrypted(t *testing.T) {
+	dir, restore := withLocalKey(t, 0600)
+	defer restore()
+	value, err := EncryptLocal("https://ssm.example.com")
+	assert.NoError(t, err)
+	path := filepath.Join(dir, AppConfigFileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Ssm": {"Endpoint": "`+value+`"}}`), 0600))
+
+	assert.Empty(t, ValidateConfigFile(path))
+	config, err := LoadConfigFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, value, config.Ssm.Endpoint, "the settings are decrypted once loaded")
+	assert.NoError(t, decryptSettings(&config))
+	assert.Equal(t, "https://ssm.example.com", config.Ssm.Endpoint)
+}
+
+func TestValidateEncrypted(t *testing.T) {
+	errs := ValidateConfig([]byte(`{"Ssm": {"Endpoint": "encrypted:rot13:c2VjcmV0"}}`))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "Ssm.Endpoint", errs[0].Field)
+	assert.Empty(t, ValidateConfig([]byte(`{"Agent": {"LogBackend": "encrypted:kms:c2VjcmV0"}}`)))
+}
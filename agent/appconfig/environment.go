@@ -0,0 +1,159 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+// EnvironmentPrefix is the prefix of the environment variables overriding the settings of the configuration file
+const EnvironmentPrefix = "AMAZON_SSM_AGENT_"
+
+// environ returns the environment of the agent
+var environ = os.Environ
+
+// EnvironmentVariable returns the environment variable overriding a setting, the prefix, the section and the
+// field in upper case separated by underscores, e.g. AMAZON_SSM_AGENT_MDS_STOPTIMEOUTMILLIS for Mds.StopTimeoutMillis.
+func EnvironmentVariable(section string, field string) string {
+	return EnvironmentPrefix + strings.ToUpper(section) + "_" + strings.ToUpper(field)
+}
+
+// ValidateEnvironment checks the environment variables overriding the settings: unknown variables, the types of
+// the values and their ranges.
+func ValidateEnvironment() []ValidationError {
+	config := DefaultConfig()
+	_, errs := applyEnvironment(&config)
+	return errs
+}
+
+// defaultConfigWithEnvironment returns the default configuration with the remote configuration and the environment
+// overrides, the configuration of the instances without configuration file.
+func defaultConfigWithEnvironment() SsmagentConfig {
+	agentConfig := DefaultConfig()
+	remote := applyRemoteConfig(&agentConfig)
+	if applied, _ := applyEnvironment(&agentConfig); applied > 0 || remote {
+		agentConfig.Os.Name = runtime.GOOS
+		agentConfig.Agent.Version = version.Version
+		parser(&agentConfig)
+	}
+	return agentConfig
+}
+
+// applyEnvironment sets the settings overridden by environment variables and returns the number of settings set.
+// The values that can't be parsed and the fileOnlySettings are ignored; the values out of range are set and replaced
+// by the parser.
+func applyEnvironment(config *SsmagentConfig) (applied int, errs []ValidationError) {
+	variables := environmentVariables()
+	if len(variables) == 0 {
+		return 0, nil
+	}
+
+	configValue := reflect.ValueOf(config).Elem()
+	for i := 0; i < configValue.NumField(); i++ {
+		sectionName := configValue.Type().Field(i).Name
+		section := configValue.Field(i)
+		if section.Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < section.NumField(); j++ {
+			fieldName := section.Type().Field(j).Name
+			name := EnvironmentVariable(sectionName, fieldName)
+			value, found := variables[name]
+			if !found {
+				continue
+			}
+			delete(variables, name)
+			if isFileOnlySetting(sectionName, fieldName) {
+				errs = append(errs, ValidationError{Field: name, Message: "only the configuration file sets the setting, it is ignored"})
+				continue
+			}
+			if err := setField(section.Field(j), value); err != nil {
+				errs = append(errs, ValidationError{Field: name, Message: err.Error()})
+				continue
+			}
+			applied++
+			errs = append(errs, validateOverride(name, sectionName+"."+fieldName, section.Field(j))...)
+		}
+	}
+
+	unknown := make([]string, 0, len(variables))
+	for name := range variables {
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, ValidationError{Field: name, Message: "unknown setting, it is ignored"})
+	}
+	return applied, errs
+}
+
+// environmentVariables returns the values of the environment variables with the EnvironmentPrefix, by upper case name
+func environmentVariables() map[string]string {
+	variables := make(map[string]string)
+	for _, variable := range environ() {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) == 2 && strings.HasPrefix(strings.ToUpper(parts[0]), EnvironmentPrefix) {
+			variables[strings.ToUpper(parts[0])] = parts[1]
+		}
+	}
+	return variables
+}
+
+// setField parses the value of an environment variable into the field.
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q; the override is ignored", value)
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int64:
+		parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q; the override is ignored", value)
+		}
+		field.SetInt(parsed)
+	default:
+		return fmt.Errorf("the setting can't be overridden")
+	}
+	return nil
+}
+
+// validateOverride checks the range of an overridden setting, and the supported values of Agent.LogBackend
+// and Identity.ConsumptionOrder.
+func validateOverride(name string, path string, field reflect.Value) []ValidationError {
+	if path == "Agent.LogBackend" {
+		return validateLogBackend(name, field.String())
+	}
+	if path == "Identity.ConsumptionOrder" {
+		return validateIdentityConsumptionOrder(name, field.String())
+	}
+	if valid, found := settingRanges[path]; found {
+		if value := field.Int(); value < valid.min || value > valid.max {
+			return []ValidationError{{Field: name, Message: rangeMessage(valid, value)}}
+		}
+	}
+	return nil
+}
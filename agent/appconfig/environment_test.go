@@ -0,0 +1,101 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setEnvironment(variables ...string) func() {
+	environ = func() []string { return append([]string{"PATH=/usr/bin"}, variables...) }
+	return func() { environ = os.Environ }
+}
+
+func TestEnvironmentVariable(t *testing.T) {
+	assert.Equal(t, "AMAZON_SSM_AGENT_MDS_STOPTIMEOUTMILLIS", EnvironmentVariable("Mds", "StopTimeoutMillis"))
+}
+
+func TestApplyEnvironment(t *testing.T) {
+	defer setEnvironment(
+		"AMAZON_SSM_AGENT_MDS_STOPTIMEOUTMILLIS=30000",
+		"AMAZON_SSM_AGENT_AGENT_REGION=eu-west-1",
+		"amazon_ssm_agent_birdwatcher_forceenable=true",
+		"AMAZON_SSM_AGENT_SSM_HEALTHFREQUENCYMINUTES=1",
+		"AMAZON_SSM_AGENT_MDS_COMMANDWORKERSLIMIT=five",
+		"AMAZON_SSM_AGENT_MDS_ENDPIONT=mds.example.com",
+	)()
+
+	config := DefaultConfig()
+	applied, errs := applyEnvironment(&config)
+	assert.Equal(t, 4, applied)
+	assert.Equal(t, int64(30000), config.Mds.StopTimeoutMillis)
+	assert.Equal(t, "eu-west-1", config.Agent.Region)
+	assert.True(t, config.Birdwatcher.ForceEnable)
+	assert.Equal(t, DefaultCommandWorkersLimit, config.Mds.CommandWorkersLimit)
+	assert.Equal(t, []ValidationError{
+		{Field: "AMAZON_SSM_AGENT_MDS_COMMANDWORKERSLIMIT", Message: `expected an integer, got "five"; the override is ignored`},
+		{Field: "AMAZON_SSM_AGENT_SSM_HEALTHFREQUENCYMINUTES", Message: "must be between 5 and 60, got 1; the default 5 is used instead"},
+		{Field: "AMAZON_SSM_AGENT_MDS_ENDPIONT", Message: "unknown setting, it is ignored"},
+	}, errs)
+	assert.Equal(t, errs, ValidateEnvironment())
+
+	config = defaultConfigWithEnvironment()
+	assert.Equal(t, "eu-west-1", config.Agent.Region)
+	assert.Equal(t, DefaultSsmHealthFrequencyMinutes, config.Ssm.HealthFrequencyMinutes, "the limits apply to the overrides")
+}
+
+func TestApplyEnvironmentFileOnlySettings(t *testing.T) {
+	defer setEnvironment("AMAZON_SSM_AGENT_DOCUMENTSIGNING_ENFORCE=false")()
+
+	config := DefaultConfig()
+	config.DocumentSigning.Enforce = true
+	applied, errs := applyEnvironment(&config)
+	assert.Equal(t, 0, applied)
+	assert.True(t, config.DocumentSigning.Enforce)
+	assert.Equal(t, []ValidationError{
+		{Field: "AMAZON_SSM_AGENT_DOCUMENTSIGNING_ENFORCE", Message: "only the configuration file sets the setting, it is ignored"},
+	}, errs)
+}
+
+func TestLoadConfigFileWithEnvironment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, AppConfigFileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Agent": {"Region": "us-east-1", "LogBackend": "journald"}}`), 0600))
+	defer setEnvironment("AMAZON_SSM_AGENT_AGENT_REGION=eu-west-1")()
+
+	config, err := LoadConfigFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", config.Agent.Region, "the environment overrides the configuration file")
+	assert.Equal(t, LogBackendJournald, config.Agent.LogBackend)
+}
+
+func TestLoadConfigFileInvalidKeepsTheEnvironment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, AppConfigFileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Agent": {"Region": `), 0600))
+	defer setEnvironment("AMAZON_SSM_AGENT_AGENT_REGION=eu-west-1")()
+
+	config, err := LoadConfigFile(path)
+	assert.Error(t, err)
+	assert.Equal(t, "eu-west-1", config.Agent.Region, "the fallback is the configuration without the file")
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AllowsAny is the value of the lists of ExecutionPolicyCfg allowing any value
+const AllowsAny = "*"
+
+// Allowed returns whether the comma separated list of the execution policy allows the value.
+func Allowed(list string, value string) bool {
+	for _, allowed := range strings.Split(list, ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == AllowsAny || (allowed != "" && allowed == value) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckElevation returns an error unless the execution policy allows the steps to set runAsElevated, which runs their
+// commands as the user of the agent instead of Agent.RunAsUser. Elevation is denied by default.
+func CheckElevation(policy ExecutionPolicyCfg) error {
+	if !policy.AllowElevation {
+		return fmt.Errorf("runAsElevated is denied by the execution policy of the agent, which must set ExecutionPolicy.AllowElevation")
+	}
+	return nil
+}
+
+// ParseUmask returns the permission bits of an octal umask, e.g. 022 or 0027.
+func ParseUmask(umask string) (uint32, error) {
+	bits, err := strconv.ParseUint(umask, 8, 32)
+	if err != nil || bits > 0777 {
+		return 0, fmt.Errorf("invalid umask %q, expected an octal number between 000 and 777", umask)
+	}
+	return uint32(bits), nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowed(t *testing.T) {
+	assert.True(t, Allowed("ssm-agent-worker, builder", "builder"))
+	assert.True(t, Allowed("*", "root"))
+	assert.False(t, Allowed("ssm-agent-worker", "root"))
+	assert.False(t, Allowed("", "root"), "an empty list allows none")
+	assert.False(t, Allowed(" , ", ""))
+}
+
+func TestCheckElevation(t *testing.T) {
+	assert.Error(t, CheckElevation(DefaultConfig().ExecutionPolicy), "elevation is denied by default")
+	assert.NoError(t, CheckElevation(ExecutionPolicyCfg{AllowElevation: true}))
+}
+
+func TestParseUmask(t *testing.T) {
+	bits, err := ParseUmask("027")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(027), bits)
+
+	bits, err = ParseUmask("0077")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(077), bits)
+
+	for _, invalid := range []string{"", "abc", "089", "1000"} {
+		_, err = ParseUmask(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestValidateMinUmask(t *testing.T) {
+	assert.Empty(t, ValidateConfig([]byte(`{"ExecutionPolicy": {"MinUmask": "077", "AllowedUsers": "*"}}`)))
+
+	errs := ValidateConfig([]byte(`{"ExecutionPolicy": {"MinUmask": "999"}}`))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "ExecutionPolicy.MinUmask", errs[0].Field)
+}
+
+func TestParserMinUmask(t *testing.T) {
+	config := DefaultConfig()
+	config.ExecutionPolicy.MinUmask = "999"
+	parser(&config)
+	assert.Equal(t, DefaultExecutionPolicyMinUmask, config.ExecutionPolicy.MinUmask)
+}
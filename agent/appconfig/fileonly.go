@@ -0,0 +1,66 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// fileOnlySettings are the settings, whole sections or Section.Field, that only the configuration file sets.
+// The remote configuration and the environment overrides don't change them, as whoever can write the parameter
+// of the remote configuration or the environment of the agent could otherwise weaken the checks of the agent.
+var fileOnlySettings = []string{
+	"DocumentSigning",
+	"Agent.PreExecutionHook",
+	"Agent.PostExecutionHook",
+}
+
+// isFileOnlySetting returns whether Section.Field is only set by the configuration file
+func isFileOnlySetting(section string, field string) bool {
+	for _, setting := range fileOnlySettings {
+		if setting == section || setting == section+"."+field {
+			return true
+		}
+	}
+	return false
+}
+
+// settingValue returns the value of a section or of a Section.Field of the configuration
+func settingValue(config *SsmagentConfig, setting string) reflect.Value {
+	value := reflect.ValueOf(config).Elem()
+	for _, name := range strings.Split(setting, ".") {
+		value = value.FieldByName(name)
+	}
+	return value
+}
+
+// keepFileOnlySettings saves the file-only settings of the configuration and returns the function restoring them.
+// The settings are copied through JSON, as unmarshalling into the configuration reuses its maps and slices.
+func keepFileOnlySettings(config *SsmagentConfig) func() {
+	saved := make([][]byte, len(fileOnlySettings))
+	for i, setting := range fileOnlySettings {
+		saved[i], _ = json.Marshal(settingValue(config, setting).Interface())
+	}
+	return func() {
+		for i, setting := range fileOnlySettings {
+			value := settingValue(config, setting)
+			restored := reflect.New(value.Type())
+			if err := json.Unmarshal(saved[i], restored.Interface()); err == nil {
+				value.Set(restored.Elem())
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// PluginsCfg holds the settings of the plugins by plugin name, e.g.
+// "Plugins": {"aws:runShellScript": {"MaxTimeoutSeconds": 3600}}
+type PluginsCfg map[string]PluginSettings
+
+// PluginSettings are the settings of a plugin. The accessors return the default value of the settings that are
+// missing or of the wrong type.
+type PluginSettings map[string]interface{}
+
+// PluginSettings returns the settings of the plugin, matching the plugin name like the other settings ignoring
+// the case, empty if there are none.
+func (config SsmagentConfig) PluginSettings(plugin string) PluginSettings {
+	if settings, found := config.Plugins[plugin]; found {
+		return settings
+	}
+	for name, settings := range config.Plugins {
+		if strings.EqualFold(name, plugin) {
+			return settings
+		}
+	}
+	return PluginSettings{}
+}
+
+// value returns the setting, ignoring the case of its name.
+func (settings PluginSettings) value(name string) (interface{}, bool) {
+	if value, found := settings[name]; found {
+		return value, value != nil
+	}
+	for key, value := range settings {
+		if strings.EqualFold(key, name) {
+			return value, value != nil
+		}
+	}
+	return nil, false
+}
+
+// String returns a string setting.
+func (settings PluginSettings) String(name string, defaultValue string) string {
+	if value, found := settings.value(name); found {
+		if text, ok := value.(string); ok {
+			return text
+		}
+	}
+	return defaultValue
+}
+
+// Int returns an integer setting, also given as a string.
+func (settings PluginSettings) Int(name string, defaultValue int) int {
+	value, found := settings.value(name)
+	if !found {
+		return defaultValue
+	}
+	switch number := value.(type) {
+	case int:
+		return number
+	case float64:
+		if number == float64(int(number)) {
+			return int(number)
+		}
+	case json.Number:
+		if integer, err := number.Int64(); err == nil {
+			return int(integer)
+		}
+	case string:
+		if integer, err := strconv.Atoi(strings.TrimSpace(number)); err == nil {
+			return integer
+		}
+	}
+	return defaultValue
+}
+
+// Bool returns a boolean setting, also given as a string.
+func (settings PluginSettings) Bool(name string, defaultValue bool) bool {
+	value, found := settings.value(name)
+	if !found {
+		return defaultValue
+	}
+	switch typed := value.(type) {
+	case bool:
+		return typed
+	case string:
+		if parsed, err := strconv.ParseBool(strings.TrimSpace(typed)); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// StringList returns a list of strings setting, also given as a comma separated string.
+func (settings PluginSettings) StringList(name string, defaultValue []string) []string {
+	value, found := settings.value(name)
+	if !found {
+		return defaultValue
+	}
+	switch typed := value.(type) {
+	case []string:
+		return typed
+	case []interface{}:
+		list := make([]string, 0, len(typed))
+		for _, element := range typed {
+			text, ok := element.(string)
+			if !ok {
+				return defaultValue
+			}
+			list = append(list, text)
+		}
+		return list
+	case string:
+		var list []string
+		for _, element := range strings.Split(typed, ",") {
+			if element = strings.TrimSpace(element); element != "" {
+				list = append(list, element)
+			}
+		}
+		return list
+	}
+	return defaultValue
+}
+
+// Decode sets the fields of the struct pointed to by target from the settings, the way the agent configuration
+// file is decoded, for plugins with many settings.
+func (settings PluginSettings) Decode(target interface{}) error {
+	content, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, target)
+}
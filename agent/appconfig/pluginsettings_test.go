@@ -0,0 +1,104 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPluginSettingsAccessors(t *testing.T) {
+	settings := PluginSettings{
+		"Name":      "value",
+		"Timeout":   float64(600),
+		"Limit":     json.Number("12"),
+		"Retries":   "3",
+		"Fraction":  1.5,
+		"Enabled":   true,
+		"Verbose":   "false",
+		"Sources":   []interface{}{"S3", "GitHub"},
+		"Gatherers": "Forensics, UnexpectedRestarts",
+		"Mixed":     []interface{}{"S3", 1},
+	}
+
+	assert.Equal(t, "value", settings.String("name", "default"), "the names ignore the case")
+	assert.Equal(t, "default", settings.String("Missing", "default"))
+	assert.Equal(t, "default", settings.String("Timeout", "default"), "wrong type")
+
+	assert.Equal(t, 600, settings.Int("Timeout", 1))
+	assert.Equal(t, 12, settings.Int("Limit", 1))
+	assert.Equal(t, 3, settings.Int("Retries", 1))
+	assert.Equal(t, 1, settings.Int("Fraction", 1))
+	assert.Equal(t, 1, settings.Int("Name", 1))
+
+	assert.True(t, settings.Bool("Enabled", false))
+	assert.False(t, settings.Bool("Verbose", true))
+	assert.True(t, settings.Bool("Name", true))
+
+	assert.Equal(t, []string{"S3", "GitHub"}, settings.StringList("Sources", nil))
+	assert.Equal(t, []string{"Forensics", "UnexpectedRestarts"}, settings.StringList("Gatherers", nil))
+	assert.Nil(t, settings.StringList("Mixed", nil))
+	assert.Nil(t, settings.StringList("Missing", nil))
+
+	var decoded struct {
+		Timeout int
+		Sources []string
+	}
+	assert.NoError(t, settings.Decode(&decoded))
+	assert.Equal(t, 600, decoded.Timeout)
+	assert.Equal(t, []string{"S3", "GitHub"}, decoded.Sources)
+}
+
+func TestLoadConfigFileWithPluginSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, AppConfigFileName)
+	content := []byte(`{"Plugins": {"aws:runShellScript": {"MaxTimeoutSeconds": 3600}}}`)
+	assert.NoError(t, ioutil.WriteFile(path, content, 0600))
+
+	assert.Empty(t, ValidateConfig(content))
+	config, err := LoadConfigFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 3600, config.PluginSettings("aws:runShellScript").Int("MaxTimeoutSeconds", 0))
+	assert.Equal(t, 3600, config.PluginSettings("AWS:RunShellScript").Int("MaxTimeoutSeconds", 0))
+	assert.Empty(t, config.PluginSettings("aws:downloadContent"))
+	assert.Empty(t, DefaultConfig().PluginSettings("aws:runShellScript"))
+}
+
+func TestValidatePluginSettings(t *testing.T) {
+	errs := ValidateConfig([]byte(`{"Plugins": {"aws:runShellScript": 3600, "aws:downloadContent": {}}}`))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "Plugins.aws:runShellScript", errs[0].Field)
+
+	errs = ValidateConfig([]byte(`{"Plugins": []}`))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "Plugins", errs[0].Field)
+}
+
+func TestReloadPluginSettings(t *testing.T) {
+	current := DefaultConfig()
+	loaded := DefaultConfig()
+	loaded.Plugins = PluginsCfg{"aws:runShellScript": {"MaxTimeoutSeconds": float64(600)}}
+
+	changed, ignored := applyReloadable(&current, loaded)
+	assert.Equal(t, []string{"Plugins"}, changed)
+	assert.Empty(t, ignored)
+	assert.Equal(t, 600, current.PluginSettings("aws:runShellScript").Int("MaxTimeoutSeconds", 0))
+}
@@ -0,0 +1,201 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ReloadableFields are the settings applied by Reload while the agent runs, the other settings take
+// effect when the agent restarts. The log level is not part of the agent configuration: seelog.xml
+// and the runtime log level are watched by the logger. Agent.Proxy replaces the proxy of the environment,
+// which is read once per process.
+var ReloadableFields = []string{
+	"Mds.CommandWorkersLimit",
+	"Mds.StopTimeoutMillis",
+	"Mds.CommandRetryLimit",
+	"Mds.ReplyFlushIntervalMillis",
+	"Mds.CompressReplies",
+	"Ssm.HealthFrequencyMinutes",
+	"Ssm.AssociationFrequencyMinutes",
+	"Ssm.AssociationLogsRetentionDurationHours",
+	"Ssm.RunCommandLogsRetentionDurationHours",
+	"Ssm.ConnectivityCheckSeconds",
+	"Ssm.ConnectivityFailureThreshold",
+	"Agent.PluginOutputMaxSizeMB",
+	"Agent.PluginOutputMaxRolls",
+	"Agent.PartialOutputIntervalSeconds",
+	"Agent.HistoryRetentionDays",
+	"Agent.HistoryMaxEntries",
+	"Agent.PreExecutionHook",
+	"Agent.PostExecutionHook",
+	"Agent.ExecutionHookTimeoutSeconds",
+	"Agent.CloudWatchOutputLogGroup",
+	"Agent.CloudWatchOutputLogStream",
+	"Agent.CloudWatchOutputFlushIntervalSeconds",
+	"Agent.LogBackend",
+	"Agent.RemoteConfigParameter",
+	"Agent.RemoteConfigRefreshMinutes",
+	"Agent.RunAsUser",
+	"Agent.Proxy",
+	"Agent.NoProxy",
+	"Agent.DownloadParallelism",
+	"Agent.DownloadRetryLimit",
+	"Agent.DownloadBandwidthLimitKBps",
+	"Agent.MinFreeDiskSpaceMB",
+	"Agent.OrchestrationQuotaMB",
+	"Agent.DownloadQuotaMB",
+	"Agent.QuotaMaxAgeDays",
+	"Birdwatcher.ForceEnable",
+	"ExecutionPolicy.AllowedUsers",
+	"ExecutionPolicy.AllowedGroups",
+	"ExecutionPolicy.AllowedSELinuxContexts",
+	"ExecutionPolicy.AllowedAppArmorProfiles",
+	"ExecutionPolicy.AllowedEnvironment",
+	"ExecutionPolicy.MinUmask",
+	"ExecutionPolicy.AllowElevation",
+	"DocumentSigning.Enforce",
+	"DocumentSigning.PublicKeys",
+	"DocumentConcurrency.MaxConcurrent",
+	"DocumentConcurrency.Categories",
+	"Plugins",
+}
+
+// reloadState holds the configuration of the last reload and the listeners notified of the reloads
+type reloadState struct {
+	m         sync.RWMutex
+	reloaded  *SsmagentConfig
+	listeners map[string]func(SsmagentConfig)
+}
+
+var reloads = reloadState{listeners: make(map[string]func(SsmagentConfig))}
+
+// reloadConfigPath is the configuration file loaded again by Reload
+var reloadConfigPath = AppConfigPath
+
+// Reload loads the configuration file again and applies its reloadable settings to the loaded configuration.
+// Returns the reloadable settings that changed and the settings that changed but need a restart.
+func Reload() (changed []string, ignored []string, err error) {
+	current, err := Config(false)
+	if err != nil {
+		return nil, nil, err
+	}
+	loaded := defaultConfigWithEnvironment()
+	if _, statErr := os.Stat(reloadConfigPath); statErr == nil {
+		if loaded, err = LoadConfigFile(reloadConfigPath); err != nil {
+			return nil, nil, fmt.Errorf("failed to load %v: %v", reloadConfigPath, err)
+		}
+	}
+	if err = decryptSettings(&loaded); err != nil {
+		return nil, nil, err
+	}
+	// the settings of the running agent rather than of the file
+	loaded.Os.Name = current.Os.Name
+	loaded.Agent.Version = current.Agent.Version
+
+	changed, ignored = applyReloadable(&current, loaded)
+	if len(changed) == 0 {
+		return nil, ignored, nil
+	}
+	cache(current)
+
+	reloads.m.Lock()
+	reloads.reloaded = &current
+	listeners := make([]func(SsmagentConfig), 0, len(reloads.listeners))
+	for _, listener := range reloads.listeners {
+		listeners = append(listeners, listener)
+	}
+	reloads.m.Unlock()
+
+	for _, listener := range listeners {
+		listener(current)
+	}
+	return changed, ignored, nil
+}
+
+// OnReload registers a function called with the configuration after each reload that changed a setting,
+// replacing the function previously registered under the same name. A nil function unregisters it.
+func OnReload(name string, listener func(SsmagentConfig)) {
+	reloads.m.Lock()
+	defer reloads.m.Unlock()
+	if listener == nil {
+		delete(reloads.listeners, name)
+		return
+	}
+	reloads.listeners[name] = listener
+}
+
+// WithReloadedValues returns the configuration with the reloadable settings of the last reload, unchanged
+// if the configuration was never reloaded.
+func WithReloadedValues(config SsmagentConfig) SsmagentConfig {
+	reloads.m.RLock()
+	defer reloads.m.RUnlock()
+	if reloads.reloaded != nil {
+		applyReloadable(&config, *reloads.reloaded)
+	}
+	return config
+}
+
+// applyReloadable copies the reloadable settings of the loaded configuration to the current configuration.
+// Returns the reloadable settings that changed and the other settings that differ.
+func applyReloadable(current *SsmagentConfig, loaded SsmagentConfig) (changed []string, ignored []string) {
+	currentValue := reflect.ValueOf(current).Elem()
+	loadedValue := reflect.ValueOf(loaded)
+	for i := 0; i < currentValue.NumField(); i++ {
+		section := currentValue.Type().Field(i).Name
+		currentSection := currentValue.Field(i)
+		loadedSection := loadedValue.Field(i)
+		if currentSection.Kind() != reflect.Struct {
+			// sections that aren't structs, e.g. Plugins, are a single setting
+			if !reflect.DeepEqual(currentSection.Interface(), loadedSection.Interface()) {
+				if !IsReloadable(section) {
+					ignored = append(ignored, section)
+					continue
+				}
+				currentSection.Set(loadedSection)
+				changed = append(changed, section)
+			}
+			continue
+		}
+		for j := 0; j < currentSection.NumField(); j++ {
+			field := section + "." + currentSection.Type().Field(j).Name
+			if reflect.DeepEqual(currentSection.Field(j).Interface(), loadedSection.Field(j).Interface()) {
+				continue
+			}
+			if !IsReloadable(field) {
+				ignored = append(ignored, field)
+				continue
+			}
+			currentSection.Field(j).Set(loadedSection.Field(j))
+			changed = append(changed, field)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(ignored)
+	return changed, ignored
+}
+
+// IsReloadable returns true if the setting is applied by Reload.
+func IsReloadable(field string) bool {
+	for _, reloadable := range ReloadableFields {
+		if field == reloadable {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defaultPath := reloadConfigPath
+	reloadConfigPath = filepath.Join(dir, AppConfigFileName)
+	defer func() {
+		reloadConfigPath = defaultPath
+		reloads.reloaded = nil
+		OnReload("test", nil)
+	}()
+	cache(DefaultConfig())
+
+	var notified []SsmagentConfig
+	OnReload("test", func(config SsmagentConfig) { notified = append(notified, config) })
+
+	changed, ignored, err := Reload()
+	assert.NoError(t, err)
+	assert.Empty(t, changed, "no configuration file is the default configuration")
+	assert.Empty(t, ignored)
+
+	assert.NoError(t, ioutil.WriteFile(reloadConfigPath, []byte(`{
+		"Mds": {"CommandWorkersLimit": 8, "Endpoint": "mds.example.com"},
+		"Ssm": {"HealthFrequencyMinutes": 10},
+		"Agent": {"Region": "eu-west-1"}
+	}`), 0600))
+	changed, ignored, err = Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Mds.CommandWorkersLimit", "Ssm.HealthFrequencyMinutes"}, changed)
+	assert.Equal(t, []string{"Agent.Region", "Mds.Endpoint"}, ignored)
+
+	config, err := Config(false)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, config.Mds.CommandWorkersLimit)
+	assert.Equal(t, 10, config.Ssm.HealthFrequencyMinutes)
+	assert.Empty(t, config.Mds.Endpoint)
+	assert.Empty(t, config.Agent.Region)
+	assert.Len(t, notified, 1)
+
+	// the configuration a component started with gets the reloaded settings only
+	started := DefaultConfig()
+	started.Agent.Region = "us-east-1"
+	started = WithReloadedValues(started)
+	assert.Equal(t, 8, started.Mds.CommandWorkersLimit)
+	assert.Equal(t, "us-east-1", started.Agent.Region)
+
+	changed, _, err = Reload()
+	assert.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.Len(t, notified, 1, "the listeners are only notified of changes")
+
+	assert.NoError(t, ioutil.WriteFile(reloadConfigPath, []byte(`{"Mds":`), 0600))
+	_, _, err = Reload()
+	assert.Error(t, err)
+	config, _ = Config(false)
+	assert.Equal(t, 8, config.Mds.CommandWorkersLimit, "an invalid file leaves the configuration unchanged")
+}
@@ -0,0 +1,159 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RemoteConfigFileName is the file the agent keeps the remote configuration in, in the data folder, where the
+// workers load it from as well
+const RemoteConfigFileName = "remote-config.json"
+
+// remoteConfigPath returns the path of the remote configuration kept by the agent
+var remoteConfigPath = func() string {
+	return filepath.Join(DefaultDataStorePath, RemoteConfigFileName)
+}
+
+// RemoteSource returns the value of the Parameter Store parameter holding the remote configuration.
+type RemoteSource func(parameter string) (string, error)
+
+// remoteState holds the source of the remote configuration, the last one fetched is kept in remoteConfigPath
+type remoteState struct {
+	m      sync.RWMutex
+	source RemoteSource
+}
+
+var remote remoteState
+
+// RegisterRemoteSource sets the source of the remote configuration, SSM registers its own.
+func RegisterRemoteSource(source RemoteSource) {
+	remote.m.Lock()
+	defer remote.m.Unlock()
+	remote.source = source
+}
+
+// RefreshRemoteConfig fetches the remote configuration of Agent.RemoteConfigParameter again, which the
+// configuration loaded afterwards is merged with, over the file and under the environment overrides.
+// Returns true if the remote configuration changed. An invalid remote configuration is refused and the previous
+// one kept; without Agent.RemoteConfigParameter the remote configuration is dropped.
+func RefreshRemoteConfig() (changed bool, err error) {
+	config, err := Config(false)
+	if err != nil {
+		return false, err
+	}
+	parameter := config.Agent.RemoteConfigParameter
+	if parameter == "" {
+		return setRemoteConfig(nil)
+	}
+
+	remote.m.RLock()
+	source := remote.source
+	remote.m.RUnlock()
+	if source == nil {
+		return false, fmt.Errorf("no source to fetch %v from", parameter)
+	}
+	content, err := source(parameter)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch %v: %v", parameter, err)
+	}
+	if errs := ValidateConfig([]byte(content)); len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for _, validationErr := range errs {
+			messages = append(messages, validationErr.Error())
+		}
+		return false, fmt.Errorf("invalid remote configuration %v, keeping the previous one: %v",
+			parameter, strings.Join(messages, "; "))
+	}
+	return setRemoteConfig([]byte(content))
+}
+
+// setRemoteConfig replaces the remote configuration kept in remoteConfigPath, readable by the agent's user only, and
+// returns true if it changed. A nil content removes it.
+func setRemoteConfig(content []byte) (changed bool, err error) {
+	remote.m.Lock()
+	defer remote.m.Unlock()
+	if bytes.Equal(readRemoteConfig(), content) {
+		return false, nil
+	}
+	path := remoteConfigPath()
+	if content == nil {
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return false, fmt.Errorf("failed to remove the remote configuration: %v", err)
+		}
+		return true, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(path), ReadWriteExecuteAccess); err != nil {
+		return false, fmt.Errorf("failed to keep the remote configuration: %v", err)
+	}
+	// the workers load the configuration meanwhile, replace the file at once
+	temp, err := ioutil.TempFile(filepath.Dir(path), RemoteConfigFileName)
+	if err != nil {
+		return false, fmt.Errorf("failed to keep the remote configuration: %v", err)
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(content)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), ReadWriteAccess)
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to keep the remote configuration: %v", err)
+	}
+	return true, nil
+}
+
+// readRemoteConfig returns the remote configuration kept in remoteConfigPath, nil if there is none.
+func readRemoteConfig() []byte {
+	content, err := ioutil.ReadFile(remoteConfigPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Failed to read the remote configuration: %v\n", err)
+		}
+		return nil
+	}
+	return content
+}
+
+// applyRemoteConfig merges the remote configuration kept by the agent over the configuration, except
+// Agent.RemoteConfigParameter which only the file and the environment set, and the fileOnlySettings. Returns true if
+// there is a remote configuration.
+func applyRemoteConfig(config *SsmagentConfig) bool {
+	remote.m.RLock()
+	content := readRemoteConfig()
+	remote.m.RUnlock()
+	if content == nil {
+		return false
+	}
+	parameter := config.Agent.RemoteConfigParameter
+	restore := keepFileOnlySettings(config)
+	defer restore()
+	if err := json.Unmarshal(content, config); err != nil {
+		fmt.Printf("Failed to apply the remote configuration: %v\n", err)
+	}
+	config.Agent.RemoteConfigParameter = parameter
+	return true
+}
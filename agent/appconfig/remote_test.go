@@ -0,0 +1,139 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withRemoteSource caches the configuration, sets the remote source and keeps the remote configuration in a
+// temporary folder, and returns the function restoring them.
+func withRemoteSource(config SsmagentConfig, source RemoteSource) func() {
+	dir, err := ioutil.TempDir("", "remote")
+	if err != nil {
+		panic(err)
+	}
+	defaultRemoteConfigPath := remoteConfigPath
+	remoteConfigPath = func() string { return filepath.Join(dir, "data", RemoteConfigFileName) }
+	cache(config)
+	RegisterRemoteSource(source)
+	return func() {
+		RegisterRemoteSource(nil)
+		remoteConfigPath = defaultRemoteConfigPath
+		os.RemoveAll(dir)
+		lock.Lock()
+		loadedConfig = nil
+		lock.Unlock()
+	}
+}
+
+func TestRefreshRemoteConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.Agent.RemoteConfigParameter = "/ssm-agent/config"
+	remoteConfig := `{"Mds": {"CommandWorkersLimit": 12}, "Agent": {"RemoteConfigParameter": "/other"}}`
+	var fetched []string
+	defer withRemoteSource(config, func(parameter string) (string, error) {
+		fetched = append(fetched, parameter)
+		return remoteConfig, nil
+	})()
+
+	changed, err := RefreshRemoteConfig()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = RefreshRemoteConfig()
+	assert.NoError(t, err)
+	assert.False(t, changed, "the remote configuration didn't change")
+	assert.Equal(t, []string{"/ssm-agent/config", "/ssm-agent/config"}, fetched)
+	info, err := os.Stat(remoteConfigPath())
+	assert.NoError(t, err, "the remote configuration is kept for the workers")
+	assert.Equal(t, os.FileMode(ReadWriteAccess), info.Mode().Perm())
+
+	merged := DefaultConfig()
+	merged.Agent.RemoteConfigParameter = "/ssm-agent/config"
+	assert.True(t, applyRemoteConfig(&merged))
+	assert.Equal(t, 12, merged.Mds.CommandWorkersLimit)
+	assert.Equal(t, "/ssm-agent/config", merged.Agent.RemoteConfigParameter, "only the file sets the parameter")
+
+	remoteConfig = `{"Mds": {"CommandWorkersLimit": "twelve"}}`
+	changed, err = RefreshRemoteConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Mds.CommandWorkersLimit")
+	assert.False(t, changed, "the invalid remote configuration is refused")
+	merged = DefaultConfig()
+	applyRemoteConfig(&merged)
+	assert.Equal(t, 12, merged.Mds.CommandWorkersLimit, "the previous remote configuration is kept")
+}
+
+func TestRefreshRemoteConfigWithoutParameter(t *testing.T) {
+	defer withRemoteSource(DefaultConfig(), func(parameter string) (string, error) {
+		return "", errors.New("unexpected call")
+	})()
+	setRemoteConfig([]byte(`{"Mds": {"CommandWorkersLimit": 12}}`))
+
+	changed, err := RefreshRemoteConfig()
+	assert.NoError(t, err)
+	assert.True(t, changed, "the remote configuration is dropped")
+	config := DefaultConfig()
+	assert.False(t, applyRemoteConfig(&config))
+	assert.Equal(t, DefaultCommandWorkersLimit, config.Mds.CommandWorkersLimit)
+	_, err = os.Stat(remoteConfigPath())
+	assert.True(t, os.IsNotExist(err), "the remote configuration kept is removed")
+}
+
+func TestRefreshRemoteConfigError(t *testing.T) {
+	config := DefaultConfig()
+	config.Agent.RemoteConfigParameter = "/ssm-agent/config"
+	defer withRemoteSource(config, func(parameter string) (string, error) {
+		return "", errors.New("ParameterNotFound")
+	})()
+
+	changed, err := RefreshRemoteConfig()
+	assert.Error(t, err)
+	assert.False(t, changed)
+}
+
+func TestLoadConfigFileWithRemoteConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, AppConfigFileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Mds": {"CommandWorkersLimit": 4, "CommandRetryLimit": 20}}`), 0600))
+	defer withRemoteSource(DefaultConfig(), nil)()
+	setRemoteConfig([]byte(`{"Mds": {"CommandWorkersLimit": 12, "StopTimeoutMillis": 30000}}`))
+	defer setEnvironment("AMAZON_SSM_AGENT_MDS_STOPTIMEOUTMILLIS=40000")()
+
+	config, err := LoadConfigFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, config.Mds.CommandWorkersLimit, "the remote configuration is merged over the file")
+	assert.Equal(t, 20, config.Mds.CommandRetryLimit, "the settings of the file the remote configuration doesn't set are kept")
+	assert.Equal(t, int64(40000), config.Mds.StopTimeoutMillis, "the environment overrides the remote configuration")
+}
+
+func TestRemoteConfigDoesNotSetFileOnlySettings(t *testing.T) {
+	defer withRemoteSource(DefaultConfig(), nil)()
+	setRemoteConfig([]byte(`{"DocumentSigning": {"Enforce": false, "PublicKeys": {"attacker": "/tmp/attacker.pem"}}, "Mds": {"CommandWorkersLimit": 12}}`))
+
+	config := DefaultConfig()
+	config.DocumentSigning = DocumentSigningCfg{Enforce: true, PublicKeys: map[string]string{"release": "/etc/amazon/ssm/release.pem"}}
+	assert.True(t, applyRemoteConfig(&config))
+	assert.Equal(t, 12, config.Mds.CommandWorkersLimit)
+	assert.True(t, config.DocumentSigning.Enforce)
+	assert.Equal(t, map[string]string{"release": "/etc/amazon/ssm/release.pem"}, config.DocumentSigning.PublicKeys)
+}
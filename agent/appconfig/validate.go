@@ -0,0 +1,388 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ValidationError is a problem of a setting of the agent configuration file.
+type ValidationError struct {
+	// Field is the setting, e.g. Ssm.HealthFrequencyMinutes, empty for the errors of the whole file
+	Field   string
+	Message string
+}
+
+// Error returns the setting and its problem.
+func (e ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// valueRange is the range of a numeric setting and the value the agent uses when it is out of range
+type valueRange struct {
+	min          int64
+	max          int64
+	defaultValue int64
+}
+
+// noMax is the maximum of the settings without one
+const noMax = int64(^uint64(0) >> 1)
+
+// settingRanges are the ranges the parser applies to the numeric settings
+var settingRanges = map[string]valueRange{
+	"Mds.CommandWorkersLimit":                    {DefaultCommandWorkersLimitMin, noMax, DefaultCommandWorkersLimit},
+	"Mds.CommandRetryLimit":                      {DefaultCommandRetryLimitMin, DefaultCommandRetryLimitMax, DefaultCommandRetryLimit},
+	"Mds.StopTimeoutMillis":                      {DefaultStopTimeoutMillisMin, DefaultStopTimeoutMillisMax, DefaultStopTimeoutMillis},
+	"Mds.ReplyFlushIntervalMillis":               {0, DefaultReplyFlushIntervalMillisMax, DefaultReplyFlushIntervalMillis},
+	"Ssm.HealthFrequencyMinutes":                 {DefaultSsmHealthFrequencyMinutesMin, DefaultSsmHealthFrequencyMinutesMax, DefaultSsmHealthFrequencyMinutes},
+	"Ssm.AssociationFrequencyMinutes":            {DefaultSsmAssociationFrequencyMinutesMin, DefaultSsmAssociationFrequencyMinutesMax, DefaultSsmAssociationFrequencyMinutes},
+	"Ssm.AssociationLogsRetentionDurationHours":  {DefaultStateOrchestrationLogsRetentionDurationHoursMin, noMax, DefaultAssociationLogsRetentionDurationHours},
+	"Ssm.RunCommandLogsRetentionDurationHours":   {DefaultStateOrchestrationLogsRetentionDurationHoursMin, noMax, DefaultRunCommandLogsRetentionDurationHours},
+	"Ssm.ConnectivityCheckSeconds":               {0, DefaultSsmConnectivityCheckSecondsMax, DefaultSsmConnectivityCheckSeconds},
+	"Ssm.ConnectivityFailureThreshold":           {DefaultSsmConnectivityFailureThresholdMin, DefaultSsmConnectivityFailureThresholdMax, DefaultSsmConnectivityFailureThreshold},
+	"Agent.PluginOutputMaxSizeMB":                {0, noMax, 0},
+	"Agent.PluginOutputMaxRolls":                 {0, noMax, DefaultPluginOutputMaxRolls},
+	"Agent.PartialOutputIntervalSeconds":         {0, DefaultPartialOutputIntervalSecondsMax, DefaultPartialOutputIntervalSeconds},
+	"Agent.HistoryRetentionDays":                 {0, DefaultHistoryRetentionDaysMax, DefaultHistoryRetentionDays},
+	"Agent.HistoryMaxEntries":                    {DefaultHistoryMaxEntriesMin, noMax, DefaultHistoryMaxEntries},
+	"Agent.ExecutionHookTimeoutSeconds":          {DefaultExecutionHookTimeoutSecondsMin, DefaultExecutionHookTimeoutSecondsMax, DefaultExecutionHookTimeoutSeconds},
+	"Agent.CloudWatchOutputFlushIntervalSeconds": {DefaultCloudWatchOutputFlushIntervalSecondsMin, DefaultCloudWatchOutputFlushIntervalSecondsMax, DefaultCloudWatchOutputFlushIntervalSeconds},
+	"Agent.RemoteConfigRefreshMinutes":           {DefaultRemoteConfigRefreshMinutesMin, DefaultRemoteConfigRefreshMinutesMax, DefaultRemoteConfigRefreshMinutes},
+	"Agent.DownloadParallelism":                  {DefaultDownloadParallelismMin, DefaultDownloadParallelismMax, DefaultDownloadParallelism},
+	"Agent.DownloadRetryLimit":                   {0, DefaultDownloadRetryLimitMax, DefaultDownloadRetryLimit},
+	"Agent.DownloadBandwidthLimitKBps":           {0, noMax, 0},
+	"Agent.MinFreeDiskSpaceMB":                   {0, noMax, DefaultMinFreeDiskSpaceMB},
+	"Agent.OrchestrationQuotaMB":                 {0, noMax, DefaultOrchestrationQuotaMB},
+	"Agent.DownloadQuotaMB":                      {0, noMax, DefaultDownloadQuotaMB},
+	"Agent.QuotaMaxAgeDays":                      {0, noMax, DefaultQuotaMaxAgeDays},
+}
+
+// logBackends are the supported values of the comma separated Agent.LogBackend
+var logBackends = []string{LogBackendFile, LogBackendJournald, LogBackendSyslog, LogBackendEventLog}
+
+// identitySources are the supported values of the comma separated Identity.ConsumptionOrder
+var identitySources = []string{IdentitySourceStatic, IdentitySourceOnPrem, IdentitySourceEC2, IdentitySourceECS}
+
+// ValidateConfigFile checks the agent configuration file against the settings the agent supports.
+// A missing file is valid, the agent runs with the default configuration.
+func ValidateConfigFile(path string) []ValidationError {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return []ValidationError{{Message: fmt.Sprintf("failed to read %v: %v", path, err)}}
+	}
+	return ValidateConfig(content)
+}
+
+// ValidateConfig checks the content of an agent configuration file: its syntax, unknown settings, the types
+// of the values and their ranges. The agent ignores the invalid settings and uses their defaults.
+func ValidateConfig(content []byte) []ValidationError {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var settings map[string]interface{}
+	if err := decoder.Decode(&settings); err != nil {
+		return []ValidationError{{Message: syntaxErrorMessage(content, err)}}
+	}
+
+	var errs []ValidationError
+	configType := reflect.TypeOf(SsmagentConfig{})
+	for _, sectionName := range sortedKeys(settings) {
+		sectionField, found := fieldByName(configType, sectionName)
+		if !found {
+			errs = append(errs, unknownSetting(sectionName, sectionName, fieldNames(configType, "")))
+			continue
+		}
+		if settings[sectionName] == nil {
+			continue
+		}
+		section, ok := settings[sectionName].(map[string]interface{})
+		if !ok {
+			errs = append(errs, ValidationError{Field: sectionField.Name, Message: fmt.Sprintf("expected an object, got %v", describe(settings[sectionName]))})
+			continue
+		}
+		if sectionField.Type.Kind() == reflect.Map {
+			errs = append(errs, validatePlugins(sectionField.Name, section)...)
+			continue
+		}
+		for _, name := range sortedKeys(section) {
+			field, found := fieldByName(sectionField.Type, name)
+			if !found {
+				errs = append(errs, unknownSetting(sectionField.Name+"."+name, name, fieldNames(sectionField.Type, sectionField.Name+".")))
+				continue
+			}
+			errs = append(errs, validateValue(sectionField.Name+"."+field.Name, field.Type.Kind(), section[name])...)
+		}
+	}
+	return errs
+}
+
+// validateValue checks the type of a setting and its range.
+func validateValue(path string, kind reflect.Kind, value interface{}) []ValidationError {
+	if value == nil {
+		return nil
+	}
+	switch kind {
+	case reflect.String:
+		text, ok := value.(string)
+		if !ok {
+			return []ValidationError{{Field: path, Message: fmt.Sprintf("expected a string, got %v", describe(value))}}
+		}
+		if IsEncrypted(text) {
+			return validateEncrypted(path, text)
+		}
+		if path == "Agent.LogBackend" {
+			return validateLogBackend(path, text)
+		}
+		if path == "Identity.ConsumptionOrder" {
+			return validateIdentityConsumptionOrder(path, text)
+		}
+		if path == "ExecutionPolicy.MinUmask" {
+			return validateUmask(path, text)
+		}
+	case reflect.Map:
+		if path == "DocumentConcurrency.MaxConcurrent" {
+			return validateConcurrencyLimits(path, value)
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return []ValidationError{{Field: path, Message: fmt.Sprintf("expected true or false, got %v", describe(value))}}
+		}
+	case reflect.Int, reflect.Int64:
+		number, ok := value.(json.Number)
+		if !ok {
+			return []ValidationError{{Field: path, Message: fmt.Sprintf("expected an integer, got %v", describe(value))}}
+		}
+		integer, err := number.Int64()
+		if err != nil {
+			return []ValidationError{{Field: path, Message: fmt.Sprintf("expected an integer, got %v", number)}}
+		}
+		if valid, found := settingRanges[path]; found && (integer < valid.min || integer > valid.max) {
+			return []ValidationError{{Field: path, Message: rangeMessage(valid, integer)}}
+		}
+	}
+	return nil
+}
+
+// validatePlugins checks the settings of each plugin are an object, the settings themselves are up to the plugins.
+func validatePlugins(path string, plugins map[string]interface{}) (errs []ValidationError) {
+	for _, plugin := range sortedKeys(plugins) {
+		if _, ok := plugins[plugin].(map[string]interface{}); !ok && plugins[plugin] != nil {
+			errs = append(errs, ValidationError{Field: path + "." + plugin, Message: fmt.Sprintf("expected an object, got %v", describe(plugins[plugin]))})
+		}
+	}
+	return errs
+}
+
+// validateEncrypted checks the scheme and the encoding of an encrypted setting, without decrypting it.
+func validateEncrypted(path string, value string) []ValidationError {
+	scheme, _, err := parseEncrypted(value)
+	if err != nil {
+		return []ValidationError{{Field: path, Message: err.Error()}}
+	}
+	if scheme != EncryptionSchemeKMS && scheme != EncryptionSchemeLocal {
+		return []ValidationError{{Field: path, Message: fmt.Sprintf("unsupported encryption scheme %q, expected %v or %v",
+			scheme, EncryptionSchemeKMS, EncryptionSchemeLocal)}}
+	}
+	return nil
+}
+
+// validateLogBackend checks each backend of the comma separated list is supported.
+func validateLogBackend(path string, value string) []ValidationError {
+	var errs []ValidationError
+	for _, backend := range strings.Split(value, ",") {
+		backend = strings.TrimSpace(backend)
+		if backend != "" && !containsString(logBackends, strings.ToLower(backend)) {
+			errs = append(errs, ValidationError{Field: path, Message: fmt.Sprintf("unsupported log backend %q is ignored, expected one of %v",
+				backend, strings.Join(logBackends, ", "))})
+		}
+	}
+	return errs
+}
+
+// validateIdentityConsumptionOrder checks each source of the comma separated list is supported.
+func validateIdentityConsumptionOrder(path string, value string) []ValidationError {
+	var errs []ValidationError
+	for _, source := range strings.Split(value, ",") {
+		source = strings.TrimSpace(source)
+		if source != "" && !containsString(identitySources, strings.ToLower(source)) {
+			errs = append(errs, ValidationError{Field: path, Message: fmt.Sprintf("unsupported identity source %q is ignored, expected one of %v",
+				source, strings.Join(identitySources, ", "))})
+		}
+	}
+	return errs
+}
+
+// validateConcurrencyLimits checks the limits are positive integers.
+func validateConcurrencyLimits(path string, value interface{}) (errs []ValidationError) {
+	limits, ok := value.(map[string]interface{})
+	if !ok {
+		return []ValidationError{{Field: path, Message: fmt.Sprintf("expected an object, got %v", describe(value))}}
+	}
+	for _, name := range sortedKeys(limits) {
+		if number, ok := limits[name].(json.Number); ok {
+			if limit, err := number.Int64(); err == nil && limit >= 1 {
+				continue
+			}
+		}
+		errs = append(errs, ValidationError{Field: path + "." + name, Message: fmt.Sprintf("expected an integer of at least 1, got %v, %v isn't limited",
+			describe(limits[name]), name)})
+	}
+	return errs
+}
+
+// validateUmask checks the umask is an octal number of permission bits.
+func validateUmask(path string, value string) []ValidationError {
+	if _, err := ParseUmask(value); err != nil {
+		return []ValidationError{{Field: path, Message: fmt.Sprintf("%v, the default %v is used", err, DefaultExecutionPolicyMinUmask)}}
+	}
+	return nil
+}
+
+// rangeMessage tells the range of a setting and the value used instead.
+func rangeMessage(valid valueRange, value int64) string {
+	if valid.max == noMax {
+		return fmt.Sprintf("must be at least %d, got %d; the default %d is used instead", valid.min, value, valid.defaultValue)
+	}
+	return fmt.Sprintf("must be between %d and %d, got %d; the default %d is used instead", valid.min, valid.max, value, valid.defaultValue)
+}
+
+// unknownSetting reports a setting the agent ignores, with the closest supported setting if it looks like a typo.
+func unknownSetting(path string, name string, candidates []string) ValidationError {
+	message := "unknown setting, it is ignored"
+	best, bestDistance := "", len(name)/3+1
+	for _, candidate := range candidates {
+		candidateName := candidate[strings.LastIndex(candidate, ".")+1:]
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidateName)); distance <= bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best != "" {
+		message += fmt.Sprintf("; did you mean %v?", best)
+	} else {
+		message += fmt.Sprintf("; expected one of %v", strings.Join(candidates, ", "))
+	}
+	return ValidationError{Field: path, Message: message}
+}
+
+// fieldByName returns the field of the struct matching the name the way encoding/json does, ignoring the case.
+func fieldByName(structType reflect.Type, name string) (reflect.StructField, bool) {
+	if field, found := structType.FieldByName(name); found {
+		return field, true
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		if strings.EqualFold(structType.Field(i).Name, name) {
+			return structType.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// fieldNames returns the names of the fields of the struct, with the prefix.
+func fieldNames(structType reflect.Type, prefix string) []string {
+	names := make([]string, 0, structType.NumField())
+	for i := 0; i < structType.NumField(); i++ {
+		names = append(names, prefix+structType.Field(i).Name)
+	}
+	return names
+}
+
+// sortedKeys returns the keys of the settings in order, so the errors are reported in a stable order.
+func sortedKeys(settings map[string]interface{}) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// describe returns the JSON type and value of a value, for the error messages.
+func describe(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return fmt.Sprintf("the string %q", typed)
+	case json.Number:
+		return fmt.Sprintf("the number %v", typed)
+	case bool:
+		return fmt.Sprintf("%v", typed)
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// syntaxErrorMessage tells the line and column of a JSON syntax error.
+func syntaxErrorMessage(content []byte, err error) string {
+	var offset int64 = -1
+	switch typed := err.(type) {
+	case *json.SyntaxError:
+		offset = typed.Offset
+	case *json.UnmarshalTypeError:
+		offset = typed.Offset
+	}
+	// the offset is after the byte in error
+	offset--
+	if offset < 0 || offset >= int64(len(content)) {
+		return fmt.Sprintf("invalid JSON: %v; the default configuration is used instead", err)
+	}
+	line := 1 + bytes.Count(content[:offset], []byte("\n"))
+	column := offset - int64(bytes.LastIndex(content[:offset], []byte("\n")))
+	return fmt.Sprintf("invalid JSON at line %d, column %d: %v; the default configuration is used instead", line, column, err)
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// minInt returns the smallest of the values.
+func minInt(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
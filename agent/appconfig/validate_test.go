@@ -0,0 +1,94 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []ValidationError
+	}{
+		{"empty", `{}`, nil},
+		{"valid", `{"Mds": {"CommandWorkersLimit": 5}, "agent": {"LogBackend": "file, Journald"}, "Birdwatcher": null}`, nil},
+		{
+			"unknown section",
+			`{"Agnet": {}}`,
+			[]ValidationError{{Field: "Agnet", Message: "unknown setting, it is ignored; did you mean Agent?"}},
+		},
+		{
+			"unknown setting",
+			`{"Ssm": {"HealthFrequencyMinute": 10}}`,
+			[]ValidationError{{Field: "Ssm.HealthFrequencyMinute", Message: "unknown setting, it is ignored; did you mean Ssm.HealthFrequencyMinutes?"}},
+		},
+		{
+			"unknown setting without suggestion",
+			`{"Os": {"Kernel": "4.14"}}`,
+			[]ValidationError{{Field: "Os.Kernel", Message: "unknown setting, it is ignored; expected one of Os.Lang, Os.Name, Os.Version"}},
+		},
+		{
+			"wrong types",
+			`{"Mds": {"CommandWorkersLimit": "5", "StopTimeoutMillis": 20000.5}, "Profile": {"ShareCreds": "true"}, "S3": []}`,
+			[]ValidationError{
+				{Field: "Mds.CommandWorkersLimit", Message: `expected an integer, got the string "5"`},
+				{Field: "Mds.StopTimeoutMillis", Message: "expected an integer, got 20000.5"},
+				{Field: "Profile.ShareCreds", Message: `expected true or false, got the string "true"`},
+				{Field: "S3", Message: "expected an object, got an array"},
+			},
+		},
+		{
+			"out of range",
+			`{"Ssm": {"HealthFrequencyMinutes": 2, "RunCommandLogsRetentionDurationHours": 1}}`,
+			[]ValidationError{
+				{Field: "Ssm.HealthFrequencyMinutes", Message: "must be between 5 and 60, got 2; the default 5 is used instead"},
+				{Field: "Ssm.RunCommandLogsRetentionDurationHours", Message: "must be at least 8, got 1; the default 336 is used instead"},
+			},
+		},
+		{
+			"unsupported log backend",
+			`{"Agent": {"LogBackend": "file,splunk"}}`,
+			[]ValidationError{{Field: "Agent.LogBackend", Message: `unsupported log backend "splunk" is ignored, expected one of file, journald, syslog, eventlog`}},
+		},
+		{
+			"unsupported identity source",
+			`{"Identity": {"ConsumptionOrder": "EC2, azure"}}`,
+			[]ValidationError{{Field: "Identity.ConsumptionOrder", Message: `unsupported identity source "azure" is ignored, expected one of static, onprem, ec2, ecs`}},
+		},
+		{
+			"syntax error",
+			"{\n  \"Mds\": {\n    \"CommandWorkersLimit\": 5,\n  }\n}",
+			[]ValidationError{{Message: "invalid JSON at line 4, column 3: invalid character '}' looking for beginning of object key string; the default configuration is used instead"}},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, ValidateConfig([]byte(testCase.content)))
+		})
+	}
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	assert.Empty(t, ValidateConfigFile(filepath.Join("testdata", "missing.json")), "no configuration file is the default configuration")
+
+	content, err := ioutil.ReadFile(filepath.Join("..", "..", "amazon-ssm-agent.json.template"))
+	assert.NoError(t, err)
+	assert.Empty(t, ValidateConfig(content), "the template is valid")
+}
@@ -96,7 +96,12 @@ func InitializeDocumentState(context context.T,
 		DocumentId:       documentInfo.DocumentID,
 	}
 
-	return docparser.InitializeDocState(context.Log(), contracts.Association, &payload.DocumentContent, documentInfo, parserInfo, payload.Parameters)
+	docState, err := docparser.InitializeDocState(context.Log(), contracts.Association, &payload.DocumentContent, documentInfo, parserInfo, payload.Parameters)
+	if err != nil {
+		return docState, err
+	}
+	docState.AttachmentsContent = payload.AttachmentsContent
+	return docState, nil
 }
 
 // newDocumentInfo initializes new DocumentInfo object
@@ -24,6 +24,7 @@ import (
 	"path"
 	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/association/cache"
 	"github.com/aws/amazon-ssm-agent/agent/association/model"
 	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager"
@@ -53,6 +54,8 @@ const (
 // Processor contains the logic for processing association
 type Processor struct {
 	pollJob            *scheduler.Job
+	pollJobMinutes     int
+	pollJobLock        sync.Mutex
 	assocSvc           service.T
 	complianceUploader complianceUploader.T
 	context            context.T
@@ -109,10 +112,38 @@ func (p *Processor) ModuleExecute(context context.T) {
 		context.Log().Errorf("unable to schedule association processor. %v", err)
 	}
 	p.InitializeAssociationProcessor()
+	p.pollJobLock.Lock()
+	p.pollJobMinutes = associationFrequenceMinutes
 	p.SetPollJob(job)
+	p.pollJobLock.Unlock()
+	appconfig.OnReload(name, p.reschedulePolling)
 }
+
+// reschedulePolling schedules the association polling again if the reloaded configuration changed its frequency
+func (p *Processor) reschedulePolling(config appconfig.SsmagentConfig) {
+	log := p.context.Log()
+	p.pollJobLock.Lock()
+	defer p.pollJobLock.Unlock()
+	associationFrequenceMinutes := config.Ssm.AssociationFrequencyMinutes
+	if p.pollJob == nil || associationFrequenceMinutes == p.pollJobMinutes {
+		return
+	}
+	log.Infof("Association polling frequency changed to %v", associationFrequenceMinutes)
+	assocScheduler.Stop(p.pollJob)
+	job, err := assocScheduler.CreateScheduler(log, p.ProcessAssociation, associationFrequenceMinutes)
+	if err != nil {
+		log.Errorf("unable to reschedule association processor. %v", err)
+	}
+	p.pollJobMinutes = associationFrequenceMinutes
+	p.SetPollJob(job)
+}
+
 func (p *Processor) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	appconfig.OnReload(name, nil)
+	p.pollJobLock.Lock()
 	assocScheduler.Stop(p.pollJob)
+	p.pollJob = nil
+	p.pollJobLock.Unlock()
 	signal.Stop()
 	p.proc.Stop(stopType)
 	return nil
@@ -365,7 +396,7 @@ func (p *Processor) runScheduledAssociation(log log.T) {
 	}
 
 	updatePluginAssociationInstances(*scheduledAssociation.Association.AssociationId, docState)
-	log = p.context.With("[associationId=" + docState.DocumentInformation.AssociationID + "]").Log()
+	log = context.WithTrace(p.context.With("[associationId="+docState.DocumentInformation.AssociationID+"]"), docState.DocumentInformation.TraceParent).Log()
 	instanceID, _ := sys.InstanceID()
 	p.assocSvc.UpdateInstanceAssociationStatus(
 		log,
@@ -397,7 +428,8 @@ func isAssociationTimedOut(assoc *model.InstanceAssociation) bool {
 // parseAssociation parses the association to the document state
 func (p *Processor) parseAssociation(rawData *model.InstanceAssociation) (*contracts.DocumentState, error) {
 	// create separate logger that includes messageID with every log message
-	context := p.context.With("[associationId=" + *rawData.Association.AssociationId + "]")
+	trace := log.NewTraceContext()
+	context := p.context.With("[associationId=" + *rawData.Association.AssociationId + "]").With(trace.ContextTag())
 	log := context.Log()
 	docState := contracts.DocumentState{}
 
@@ -412,6 +444,7 @@ func (p *Processor) parseAssociation(rawData *model.InstanceAssociation) (*contr
 	if docState, err = assocParser.InitializeDocumentState(context, document, rawData); err != nil {
 		return &docState, err
 	}
+	docState.DocumentInformation.TraceParent = trace.TraceParent()
 	var parsedMessageContent string
 	if parsedMessageContent, err = jsonutil.Marshal(document); err != nil {
 		errorMsg := "Encountered error while parsing input - internal error"
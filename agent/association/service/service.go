@@ -24,6 +24,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/association/cache"
 	"github.com/aws/amazon-ssm-agent/agent/association/model"
 	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager"
+	"github.com/aws/amazon-ssm-agent/agent/framework/doccache"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
@@ -308,8 +309,18 @@ func (s *AssociationService) LoadAssociationDetail(log log.T, assoc *model.Insta
 	// TODO: add a retry here
 	// Call getDocument and retrieve the document json string
 	if documentResponse, err = s.ssmSvc.GetDocument(log, *assoc.Association.Name, *assoc.Association.DocumentVersion); err != nil {
+		// the instance may be offline, fall back to the document as it was last fetched
 		log.Errorf("unable to retrieve document, %v", err)
-		return err
+		cached, cacheErr := doccache.Get(aws.StringValue(assoc.Association.InstanceId), *assoc.Association.Name)
+		if cacheErr != nil {
+			return err
+		}
+		log.Infof("using the cached document %v", *assoc.Association.Name)
+		documentResponse = &ssm.GetDocumentOutput{Content: &cached}
+	} else if documentResponse.Content != nil {
+		if err = doccache.Put(log, aws.StringValue(assoc.Association.InstanceId), *assoc.Association.Name, *documentResponse.Content); err != nil {
+			log.Debugf("failed to cache document %v: %v", *assoc.Association.Name, err)
+		}
 	}
 
 	assoc.Document = documentResponse.Content
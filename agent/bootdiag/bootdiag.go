@@ -0,0 +1,259 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package bootdiag detects the agent runs that ended without a clean shutdown, because the instance rebooted
+// unexpectedly or the agent crashed or was killed, and records them with the evidence left by the OS.
+package bootdiag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+const (
+	// CauseReboot is an instance that booted again without the agent stopping, e.g. power loss or kernel panic
+	CauseReboot = "reboot"
+	// CauseAgentOutOfMemory is an agent killed by the out of memory killer
+	CauseAgentOutOfMemory = "agentOutOfMemory"
+	// CauseAgentCrash is an agent that panicked
+	CauseAgentCrash = "agentCrash"
+	// CauseAgentTerminated is an agent that stopped otherwise without a clean shutdown, e.g. killed
+	CauseAgentTerminated = "agentTerminated"
+
+	// kernelEvidence is the evidence source of the kernel messages
+	kernelEvidence = "kernel"
+
+	markerFileName   = "running.json"
+	recordsFileName  = "restarts.json"
+	crashDumpPattern = "flightrecorder-*-crash.log"
+
+	// maxRecords is the number of most recent unexpected restarts kept
+	maxRecords = 10
+	// maxEvidenceLines is the number of most recent lines kept of each evidence source
+	maxEvidenceLines = 50
+	// maxEvidenceLength is the length each evidence source is truncated to, to stay within the inventory limits
+	maxEvidenceLength = 4096
+	// bootTimeTolerance is the difference of boot times still considered the same boot, as they are computed
+	// from the uptime on some platforms
+	bootTimeTolerance = time.Minute
+)
+
+// Record is an agent run that ended without a clean shutdown.
+type Record struct {
+	DetectionTime          time.Time
+	Cause                  string
+	PreviousAgentStartTime time.Time
+	PreviousAgentVersion   string
+	PreviousProcessID      int
+	PreviousBootTime       time.Time
+	BootTime               time.Time
+	CrashDump              string `json:",omitempty"`
+	// Evidence are the last lines of each source of evidence, e.g. the kernel messages or the agent log
+	Evidence map[string]string
+}
+
+// marker is written when the agent starts and removed when it stops cleanly
+type marker struct {
+	StartTime    time.Time
+	AgentVersion string
+	ProcessID    int
+	BootTime     time.Time
+}
+
+// dir is the folder of the marker and the records
+var dir = filepath.Join(appconfig.DefaultDataStorePath, "bootdiagnostics")
+
+// crashDumpDir is where the flight recorder dumps the agent panics
+var crashDumpDir = filepath.Join(logger.DefaultLogDir, logger.FlightRecorderDirName)
+
+// agentLogPath is the agent log, whose last lines are part of the evidence
+var agentLogPath = filepath.Join(logger.DefaultLogDir, logger.LogFile)
+
+// decoupling the platform for easy testability
+var getBootTime = bootTime
+var collectEvidence = osEvidence
+
+// Check is called when the agent starts. It returns the record of the previous run if it ended without a clean
+// shutdown, nil otherwise, and marks the current run as running.
+func Check(log logger.T) (record *Record, err error) {
+	now := time.Now().UTC()
+	boot, err := getBootTime()
+	if err != nil {
+		log.Warnf("Unable to get the boot time - %v", err)
+	}
+	boot = boot.UTC()
+
+	var previous marker
+	if found, err := readJSON(filepath.Join(dir, markerFileName), &previous); err != nil {
+		log.Warnf("Unable to read the marker of the previous run - %v", err)
+	} else if found {
+		record = diagnose(log, previous, boot, now)
+		if err = appendRecord(*record); err != nil {
+			log.Warnf("Unable to save the unexpected restart - %v", err)
+		}
+	}
+
+	current := marker{StartTime: now, AgentVersion: version.Version, ProcessID: os.Getpid(), BootTime: boot}
+	if err = writeJSON(filepath.Join(dir, markerFileName), current); err != nil {
+		return record, fmt.Errorf("unable to mark the agent as running - %v", err)
+	}
+	return record, nil
+}
+
+// MarkCleanShutdown is called when the agent stops cleanly.
+func MarkCleanShutdown(log logger.T) {
+	if err := os.Remove(filepath.Join(dir, markerFileName)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Unable to mark the clean shutdown - %v", err)
+	}
+}
+
+// Records returns the recorded unexpected restarts, the most recent last.
+func Records() (records []Record, err error) {
+	_, err = readJSON(filepath.Join(dir, recordsFileName), &records)
+	return
+}
+
+// diagnose finds the cause of the end of the previous run and collects the evidence.
+func diagnose(log logger.T, previous marker, boot time.Time, now time.Time) *Record {
+	record := &Record{
+		DetectionTime:          now,
+		PreviousAgentStartTime: previous.StartTime,
+		PreviousAgentVersion:   previous.AgentVersion,
+		PreviousProcessID:      previous.ProcessID,
+		PreviousBootTime:       previous.BootTime,
+		BootTime:               boot,
+		Evidence:               make(map[string]string),
+	}
+	rebooted := !boot.IsZero() && !previous.BootTime.IsZero() && absDuration(boot.Sub(previous.BootTime)) > bootTimeTolerance
+
+	for source, lines := range collectEvidence(log, rebooted) {
+		record.Evidence[source] = lastLines(lines)
+	}
+	if lines, err := tail(agentLogPath); err == nil {
+		record.Evidence["agentLog"] = lastLines(lines)
+	}
+
+	switch {
+	case rebooted:
+		record.Cause = CauseReboot
+	case killedOutOfMemory(record.Evidence, previous.ProcessID):
+		record.Cause = CauseAgentOutOfMemory
+	default:
+		record.Cause = CauseAgentTerminated
+		if dump := latestCrashDump(previous.StartTime); dump != "" {
+			record.Cause = CauseAgentCrash
+			record.CrashDump = dump
+		}
+	}
+	return record
+}
+
+// killedOutOfMemory returns true if the kernel messages show the out of memory killer killed the agent.
+func killedOutOfMemory(evidence map[string]string, pid int) bool {
+	for _, line := range strings.Split(evidence[kernelEvidence], "\n") {
+		lower := strings.ToLower(line)
+		if !strings.Contains(lower, "out of memory") && !strings.Contains(lower, "oom-kill") {
+			continue
+		}
+		if strings.Contains(line, "amazon-ssm-agen") || (pid > 0 && strings.Contains(line, fmt.Sprintf("process %d ", pid))) {
+			return true
+		}
+	}
+	return false
+}
+
+// latestCrashDump returns the most recent flight recorder dump of a panic since the given time, if any.
+func latestCrashDump(since time.Time) (latest string) {
+	dumps, _ := filepath.Glob(filepath.Join(crashDumpDir, crashDumpPattern))
+	var latestTime time.Time
+	for _, dump := range dumps {
+		if info, err := os.Stat(dump); err == nil && info.ModTime().After(since) && info.ModTime().After(latestTime) {
+			latest, latestTime = dump, info.ModTime()
+		}
+	}
+	return latest
+}
+
+// appendRecord saves the record after the previous ones, keeping the most recent.
+func appendRecord(record Record) error {
+	records, err := Records()
+	if err != nil {
+		records = nil
+	}
+	records = append(records, record)
+	if len(records) > maxRecords {
+		records = records[len(records)-maxRecords:]
+	}
+	return writeJSON(filepath.Join(dir, recordsFileName), records)
+}
+
+// tail returns the lines at the end of a file.
+func tail(path string) ([]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(string(content), "\n"), "\n"), nil
+}
+
+// lastLines joins the last lines of evidence, truncated to the maximum length from the start.
+func lastLines(lines []string) string {
+	if len(lines) > maxEvidenceLines {
+		lines = lines[len(lines)-maxEvidenceLines:]
+	}
+	joined := strings.Join(lines, "\n")
+	if len(joined) > maxEvidenceLength {
+		joined = joined[len(joined)-maxEvidenceLength:]
+	}
+	return joined
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// readJSON reads a JSON file, returning false if it doesn't exist.
+func readJSON(path string, value interface{}) (found bool, err error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(content, value)
+}
+
+// writeJSON writes a JSON file only readable by the agent.
+func writeJSON(path string, value interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err = fileutil.MakeDirs(filepath.Dir(path)); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0600)
+}
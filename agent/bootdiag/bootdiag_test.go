@@ -0,0 +1,144 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package bootdiag
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// setup redirects the files of the package to a temporary folder and sets the boot time and the evidence.
+func setup(t *testing.T, boot time.Time, evidence map[string][]string) func() {
+	tempDir, err := ioutil.TempDir("", "bootdiag")
+	assert.NoError(t, err)
+	savedDir, savedCrashDumpDir, savedAgentLogPath := dir, crashDumpDir, agentLogPath
+	dir = filepath.Join(tempDir, "bootdiagnostics")
+	crashDumpDir = filepath.Join(tempDir, "flightrecorder")
+	agentLogPath = filepath.Join(tempDir, "amazon-ssm-agent.log")
+	getBootTime = func() (time.Time, error) { return boot, nil }
+	collectEvidence = func(log log.T, rebooted bool) map[string][]string { return evidence }
+	return func() {
+		dir, crashDumpDir, agentLogPath = savedDir, savedCrashDumpDir, savedAgentLogPath
+		getBootTime = bootTime
+		collectEvidence = osEvidence
+		os.RemoveAll(tempDir)
+	}
+}
+
+func TestCheckCleanShutdown(t *testing.T) {
+	defer setup(t, time.Now().Add(-time.Hour), nil)()
+	logger := log.NewMockLog()
+
+	record, err := Check(logger)
+	assert.NoError(t, err)
+	assert.Nil(t, record, "the first run")
+	MarkCleanShutdown(logger)
+
+	record, err = Check(logger)
+	assert.NoError(t, err)
+	assert.Nil(t, record, "the previous run stopped cleanly")
+	records, err := Records()
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestCheckReboot(t *testing.T) {
+	boot := time.Now().Add(-time.Hour)
+	restore := setup(t, boot, map[string][]string{kernelEvidence: {"Kernel panic - not syncing"}})
+	defer restore()
+	logger := log.NewMockLog()
+	assert.NoError(t, ioutil.WriteFile(agentLogPath, []byte("line 1\nline 2\n"), 0600))
+
+	_, err := Check(logger)
+	assert.NoError(t, err)
+	getBootTime = func() (time.Time, error) { return boot.Add(50 * time.Minute), nil }
+
+	record, err := Check(logger)
+	assert.NoError(t, err)
+	assert.NotNil(t, record)
+	assert.Equal(t, CauseReboot, record.Cause)
+	assert.Equal(t, os.Getpid(), record.PreviousProcessID)
+	assert.Equal(t, "Kernel panic - not syncing", record.Evidence[kernelEvidence])
+	assert.Equal(t, "line 1\nline 2", record.Evidence["agentLog"])
+
+	records, err := Records()
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, CauseReboot, records[0].Cause)
+}
+
+func TestCheckAgentCauses(t *testing.T) {
+	boot := time.Now().Add(-time.Hour)
+	defer setup(t, boot, nil)()
+	logger := log.NewMockLog()
+
+	_, err := Check(logger)
+	assert.NoError(t, err)
+	// the boot time computed from the uptime differs slightly
+	getBootTime = func() (time.Time, error) { return boot.Add(time.Second), nil }
+	record, err := Check(logger)
+	assert.NoError(t, err)
+	assert.Equal(t, CauseAgentTerminated, record.Cause)
+
+	assert.NoError(t, os.MkdirAll(crashDumpDir, 0700))
+	dump := filepath.Join(crashDumpDir, "flightrecorder-20261015T101010.000-crash.log")
+	assert.NoError(t, ioutil.WriteFile(dump, []byte("panic"), 0600))
+	assert.NoError(t, os.Chtimes(dump, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	record, err = Check(logger)
+	assert.NoError(t, err)
+	assert.Equal(t, CauseAgentCrash, record.Cause)
+	assert.Equal(t, dump, record.CrashDump)
+
+	collectEvidence = func(log log.T, rebooted bool) map[string][]string {
+		return map[string][]string{kernelEvidence: {"Out of memory: Killed process 4242 (amazon-ssm-agen) total-vm:1GB"}}
+	}
+	record, err = Check(logger)
+	assert.NoError(t, err)
+	assert.Equal(t, CauseAgentOutOfMemory, record.Cause)
+
+	records, err := Records()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestRecordsKeepTheMostRecent(t *testing.T) {
+	defer setup(t, time.Now(), nil)()
+	for i := 0; i < maxRecords+5; i++ {
+		assert.NoError(t, appendRecord(Record{PreviousProcessID: i}))
+	}
+	records, err := Records()
+	assert.NoError(t, err)
+	assert.Len(t, records, maxRecords)
+	assert.Equal(t, maxRecords+4, records[maxRecords-1].PreviousProcessID)
+}
+
+func TestLastLines(t *testing.T) {
+	lines := make([]string, maxEvidenceLines+10)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	lines[len(lines)-1] = "last"
+	joined := lastLines(lines)
+	assert.Len(t, joined, (maxEvidenceLines-1)*len("line\n")+len("last"))
+
+	long := lastLines([]string{string(make([]byte, maxEvidenceLength)), "end"})
+	assert.Len(t, long, maxEvidenceLength)
+	assert.Equal(t, "end", long[len(long)-3:])
+}
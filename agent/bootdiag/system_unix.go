@@ -0,0 +1,112 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package bootdiag
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	logger "github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	journalctlCmd = "journalctl"
+	dmesgCmd      = "dmesg"
+	sysctlCmd     = "sysctl"
+
+	agentUnit = "amazon-ssm-agent"
+)
+
+// procStat is where linux reports its boot time
+var procStat = "/proc/stat"
+
+// bootTimeSec is the seconds since the epoch in the output of sysctl kern.boottime, e.g. { sec = 1697350000, usec = 0 }
+var bootTimeSec = regexp.MustCompile(`sec = (\d+)`)
+
+// decoupling exec.Command for easy testability
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// bootTime returns when the instance booted, from /proc/stat on linux and sysctl elsewhere.
+func bootTime() (time.Time, error) {
+	if content, err := ioutil.ReadFile(procStat); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "btime" {
+				seconds, err := strconv.ParseInt(fields[1], 10, 64)
+				if err != nil {
+					return time.Time{}, err
+				}
+				return time.Unix(seconds, 0), nil
+			}
+		}
+	}
+	output, err := cmdExecutor(sysctlCmd, "-n", "kern.boottime")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%v failed: %v", sysctlCmd, err)
+	}
+	match := bootTimeSec.FindStringSubmatch(string(output))
+	if match == nil {
+		return time.Time{}, fmt.Errorf("unexpected kern.boottime %v", strings.TrimSpace(string(output)))
+	}
+	seconds, _ := strconv.ParseInt(match[1], 10, 64)
+	return time.Unix(seconds, 0), nil
+}
+
+// osEvidence returns the kernel messages and the agent journal of the previous boot if the instance rebooted,
+// of the current boot otherwise. The kernel ring buffer is used when there is no journal.
+func osEvidence(log logger.T, rebooted bool) map[string][]string {
+	evidence := make(map[string][]string)
+	var boot []string
+	if rebooted {
+		boot = []string{"-b", "-1"}
+	}
+	journalArgs := func(args ...string) []string {
+		return append(append(args, boot...), "-n", strconv.Itoa(maxEvidenceLines), "--no-pager", "-o", "short-iso")
+	}
+
+	if output, err := cmdExecutor(journalctlCmd, journalArgs("-k")...); err == nil {
+		evidence[kernelEvidence] = outputLines(output)
+	} else if !rebooted {
+		// the ring buffer only holds the messages of the current boot
+		if output, err := cmdExecutor(dmesgCmd); err == nil {
+			evidence[kernelEvidence] = outputLines(output)
+		} else {
+			log.Debugf("Unable to read the kernel messages - %v", err)
+		}
+	}
+	if output, err := cmdExecutor(journalctlCmd, journalArgs("-u", agentUnit)...); err == nil {
+		evidence["agentJournal"] = outputLines(output)
+	}
+	return evidence
+}
+
+// outputLines splits the output of a command into lines, without the empty ones.
+func outputLines(output []byte) (lines []string) {
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
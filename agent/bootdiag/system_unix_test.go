@@ -0,0 +1,76 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package bootdiag
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootTimeFromProcStat(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "bootdiag")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	defer func(saved string) { procStat = saved }(procStat)
+	procStat = filepath.Join(tempDir, "stat")
+	assert.NoError(t, ioutil.WriteFile(procStat, []byte("cpu  1 2 3\nbtime 1697350000\nprocesses 42\n"), 0600))
+
+	boot, err := bootTime()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1697350000, 0), boot)
+}
+
+func TestBootTimeFromSysctl(t *testing.T) {
+	defer func(saved string) { procStat = saved }(procStat)
+	procStat = filepath.Join(os.TempDir(), "does-not-exist", "stat")
+	defer func() { cmdExecutor = executeCommand }()
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return []byte("{ sec = 1697350000, usec = 12345 } Sun Oct 15 06:06:40 2023\n"), nil
+	}
+
+	boot, err := bootTime()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1697350000, 0), boot)
+}
+
+func TestOSEvidence(t *testing.T) {
+	defer func() { cmdExecutor = executeCommand }()
+	var calls []string
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		calls = append(calls, command+" "+strings.Join(args, " "))
+		if command == journalctlCmd && args[0] == "-k" {
+			return nil, errors.New("no journal")
+		}
+		return []byte(command + " output\n\n"), nil
+	}
+
+	evidence := osEvidence(log.NewMockLog(), false)
+	assert.Equal(t, []string{"dmesg output"}, evidence[kernelEvidence], "the ring buffer without journal")
+	assert.Equal(t, []string{"journalctl output"}, evidence["agentJournal"])
+
+	calls = nil
+	evidence = osEvidence(log.NewMockLog(), true)
+	assert.NotContains(t, evidence, kernelEvidence, "the ring buffer doesn't hold the previous boot")
+	assert.Contains(t, calls[len(calls)-1], "-u amazon-ssm-agent -b -1")
+}
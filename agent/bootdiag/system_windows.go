@@ -0,0 +1,96 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package bootdiag
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	logger "github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// systemEventsScript lists the recent shutdown events: 41 unexpected reboot (Kernel-Power), 6008 unexpected
+	// shutdown, 1074 shutdown initiated by a process and 1001 bug check
+	systemEventsScript = `Get-WinEvent -FilterHashtable @{LogName='System'; Id=41,6008,1074,1001} -MaxEvents %d -ErrorAction SilentlyContinue |
+ForEach-Object { $_.TimeCreated.ToUniversalTime().ToString('yyyy-MM-ddTHH:mm:ssZ') + ' ' + $_.Id + ' ' + $_.ProviderName + ' ' + ($_.Message -replace '\s+', ' ') }`
+
+	// applicationEventsScript lists the recent errors of the application event log about the agent, e.g. the
+	// application errors of a crash
+	applicationEventsScript = `Get-WinEvent -FilterHashtable @{LogName='Application'; Level=1,2} -MaxEvents 500 -ErrorAction SilentlyContinue |
+Where-Object { $_.Message -match 'amazon-ssm-agent' } | Select-Object -First %d |
+ForEach-Object { $_.TimeCreated.ToUniversalTime().ToString('yyyy-MM-ddTHH:mm:ssZ') + ' ' + $_.Id + ' ' + $_.ProviderName + ' ' + ($_.Message -replace '\s+', ' ') }`
+)
+
+var getTickCount64 = syscall.NewLazyDLL("kernel32.dll").NewProc("GetTickCount64")
+
+// decoupling exec.Command for easy testability
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// bootTime returns when the instance booted, from the milliseconds since the boot.
+func bootTime() (time.Time, error) {
+	if err := getTickCount64.Find(); err != nil {
+		return time.Time{}, err
+	}
+	ticks, _, _ := getTickCount64.Call()
+	return time.Now().Add(-time.Duration(ticks) * time.Millisecond), nil
+}
+
+// osEvidence returns the recent shutdown events of the system event log and the errors of the application event
+// log about the agent, the most recent last.
+func osEvidence(log logger.T, rebooted bool) map[string][]string {
+	evidence := make(map[string][]string)
+	scripts := map[string]string{
+		"systemEventLog":      systemEventsScript,
+		"applicationEventLog": applicationEventsScript,
+	}
+	for source, script := range scripts {
+		output, err := cmdExecutor(appconfig.PowerShellPluginCommandName, "-NoProfile", "-NonInteractive", "-Command",
+			fmt.Sprintf(script, maxEvidenceLines))
+		if err != nil {
+			log.Debugf("Unable to read the %v - %v", source, err)
+			continue
+		}
+		evidence[source] = reverse(outputLines(output))
+	}
+	return evidence
+}
+
+// outputLines splits the output of a command into lines, without the empty ones.
+func outputLines(output []byte) (lines []string) {
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// reverse puts the events of Get-WinEvent, listed the most recent first, in chronological order.
+func reverse(lines []string) []string {
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
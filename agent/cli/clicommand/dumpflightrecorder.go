@@ -0,0 +1,143 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	dumpFlightRecorder = "dump-flight-recorder"
+
+	// dumpFlightRecorderTimeout is how long to wait for the agent to pick up the request
+	dumpFlightRecorderTimeout = 30 * time.Second
+)
+
+const dumpFlightRecorderHelp = `NAME:
+    {{.DumpFlightRecorderName}}
+
+DESCRIPTION
+    Requests the running amazon-ssm-agent to write the recent debug events it keeps in memory to disk.
+    The events are captured regardless of the configured log level.
+
+SYNOPSIS
+    {{.DumpFlightRecorderName}}
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.DumpFlightRecorderName}}
+
+    Output:
+
+      {{.DumpFolder}}/flightrecorder-20170101T000000.000-request.log
+
+OUTPUT
+    Path of the file the events were written to
+`
+
+type dumpFlightRecorderHelpParams struct {
+	SsmCliName             string
+	DumpFlightRecorderName string
+	DumpFolder             string
+}
+
+// flightRecorderDir is the folder the agent watches for dump requests and writes the dumps to
+var flightRecorderDir = filepath.Join(log.DefaultLogDir, log.FlightRecorderDirName)
+
+// pollInterval is how often the request is checked for completion
+var pollInterval = time.Second
+
+func init() {
+	cliutil.Register(&DumpFlightRecorderCommand{})
+}
+
+type DumpFlightRecorderCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the dump-flight-recorder cli command
+func (c *DumpFlightRecorderCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	if len(subcommands) > 0 {
+		return fmt.Errorf("%v does not support subcommand %v", dumpFlightRecorder, subcommands), ""
+	}
+	validation := make([]string, 0)
+	for key := range parameters {
+		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	}
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+	return c.requestDump()
+}
+
+// Help prints help for the dump-flight-recorder cli command
+func (c *DumpFlightRecorderCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("DumpFlightRecorderHelp").Parse(dumpFlightRecorderHelp)
+		params := dumpFlightRecorderHelpParams{cliutil.SsmCliName, dumpFlightRecorder, flightRecorderDir}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (DumpFlightRecorderCommand) Name() string {
+	return dumpFlightRecorder
+}
+
+// requestDump asks the agent for a dump and waits until it has been written
+func (DumpFlightRecorderCommand) requestDump() (error, string) {
+	if err := os.MkdirAll(flightRecorderDir, 0750); err != nil {
+		return err, ""
+	}
+	request := filepath.Join(flightRecorderDir, log.FlightRecorderRequestFileName)
+	if err := ioutil.WriteFile(request, []byte{}, 0600); err != nil {
+		return fmt.Errorf("failed to request a flight recorder dump: %v", err), ""
+	}
+
+	// the agent removes the request once it has written the dump
+	for deadline := time.Now().Add(dumpFlightRecorderTimeout); time.Now().Before(deadline); time.Sleep(pollInterval) {
+		if _, err := os.Stat(request); os.IsNotExist(err) {
+			return latestDump()
+		}
+	}
+	os.Remove(request)
+	return fmt.Errorf("amazon-ssm-agent did not respond within %v, make sure it is running", dumpFlightRecorderTimeout), ""
+}
+
+// latestDump returns the path of the most recent dump
+func latestDump() (error, string) {
+	dumps, err := filepath.Glob(filepath.Join(flightRecorderDir, "flightrecorder-*.log"))
+	if err != nil || len(dumps) == 0 {
+		return fmt.Errorf("no flight recorder dump found in %v", flightRecorderDir), ""
+	}
+	sort.Strings(dumps)
+	return nil, dumps[len(dumps)-1]
+}
@@ -0,0 +1,171 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+)
+
+const (
+	getEffectiveConfig        = "get-effective-config"
+	getEffectiveConfigSetting = "setting"
+)
+
+const getEffectiveConfigHelp = `NAME:
+    {{.GetEffectiveConfigName}}
+
+DESCRIPTION
+    Prints the configuration the running amazon-ssm-agent uses: the defaults, merged with {{.ConfigPath}},
+    the remote configuration of Agent.RemoteConfigParameter and the AMAZON_SSM_AGENT_ environment variables.
+    Each setting is followed by the source of its value: default, file, remote, environment or agent.
+    When the agent hasn't written its configuration, the file is merged with the environment of {{.SsmCliName}}
+    instead, without the remote configuration.
+
+SYNOPSIS
+    {{.GetEffectiveConfigName}}
+    [{{.SettingFlag}} <value>]
+
+PARAMETERS
+    {{.SettingFlag}} (string) Only prints the settings starting with the value, e.g. Mds or Ssm.HealthFrequencyMinutes.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.GetEffectiveConfigName}} {{.SettingFlag}} Ssm
+
+    Output:
+
+      Ssm.Endpoint = "" (default)
+      Ssm.HealthFrequencyMinutes = 5 (default) the file value 1 is invalid, replaced by the default
+      Ssm.AssociationFrequencyMinutes = 30 (environment)
+
+OUTPUT
+    The settings in effect and their sources
+`
+
+type getEffectiveConfigHelpParams struct {
+	SsmCliName             string
+	GetEffectiveConfigName string
+	SettingFlag            string
+	ConfigPath             string
+}
+
+func init() {
+	cliutil.Register(&GetEffectiveConfigCommand{})
+}
+
+type GetEffectiveConfigCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-effective-config cli command
+func (c *GetEffectiveConfigCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, prefix := c.validateGetEffectiveConfigInput(subcommands, parameters)
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	header := ""
+	effective, err := appconfig.ReadEffectiveConfig()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read the effective configuration: %v", err), ""
+		}
+		if effective, err = appconfig.EffectiveConfig(appconfig.AppConfigPath); err != nil {
+			return err, ""
+		}
+		header = fmt.Sprintf("amazon-ssm-agent hasn't written its configuration, merged %v with the environment of %v, without the remote configuration\n",
+			effective.ConfigPath, cliutil.SsmCliName)
+	} else {
+		header = fmt.Sprintf("Configuration of amazon-ssm-agent merged with %v at %v\n",
+			effective.ConfigPath, effective.Time.Format(time.RFC3339))
+	}
+	return nil, header + formatEffectiveSettings(effective.Settings, prefix)
+}
+
+// Help prints help for the get-effective-config cli command
+func (c *GetEffectiveConfigCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetEffectiveConfigHelp").Parse(getEffectiveConfigHelp)
+		params := getEffectiveConfigHelpParams{
+			cliutil.SsmCliName,
+			getEffectiveConfig,
+			cliutil.FormatFlag(getEffectiveConfigSetting),
+			appconfig.AppConfigPath,
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetEffectiveConfigCommand) Name() string {
+	return getEffectiveConfig
+}
+
+// formatEffectiveSettings returns a line per setting starting with the prefix, ignoring case
+func formatEffectiveSettings(settings []appconfig.EffectiveSetting, prefix string) string {
+	var lines []string
+	for _, setting := range settings {
+		if !strings.HasPrefix(strings.ToLower(setting.Name), strings.ToLower(prefix)) {
+			continue
+		}
+		value := fmt.Sprintf("%v", setting.Value)
+		if text, ok := setting.Value.(string); ok {
+			value = fmt.Sprintf("%q", text)
+		}
+		line := fmt.Sprintf("%v = %v (%v)", setting.Name, value, setting.Source)
+		if setting.Note != "" {
+			line += " " + setting.Note
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validateGetEffectiveConfigInput checks the subcommands and the optional setting parameter
+func (GetEffectiveConfigCommand) validateGetEffectiveConfigInput(subcommands []string, parameters map[string][]string) (validation []string, prefix string) {
+	validation = make([]string, 0)
+	if len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getEffectiveConfig, subcommands))
+		return validation, ""
+	}
+	if values, found := parameters[getEffectiveConfigSetting]; found {
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(getEffectiveConfigSetting)))
+		} else {
+			prefix = values[0]
+		}
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != getEffectiveConfigSetting {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, prefix
+}
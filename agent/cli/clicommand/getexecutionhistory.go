@@ -0,0 +1,204 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/history"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+const (
+	getExecutionHistory              = "get-execution-history"
+	getExecutionHistoryDocumentName  = "document-name"
+	getExecutionHistoryCommandID     = "command-id"
+	getExecutionHistoryAssociationID = "association-id"
+	getExecutionHistoryStatus        = "status"
+	getExecutionHistorySince         = "since"
+	getExecutionHistoryMaxResults    = "max-results"
+)
+
+const getExecutionHistoryHelp = `NAME:
+    {{.GetExecutionHistoryName}}
+
+DESCRIPTION
+    Prints the executions of documents, commands and associations, recorded by the agent on this instance,
+    from the newest to the oldest, with the status, duration and truncated output of their steps.
+    The executions are kept for Agent.HistoryRetentionDays days, up to Agent.HistoryMaxEntries executions.
+
+SYNOPSIS
+    {{.GetExecutionHistoryName}}
+    [{{.DocumentNameFlag}} <value>]
+    [{{.CommandIDFlag}} <value>]
+    [{{.AssociationIDFlag}} <value>]
+    [{{.StatusFlag}} <value>]
+    [{{.SinceFlag}} <value>]
+    [{{.MaxResultsFlag}} <value>]
+
+PARAMETERS
+    {{.DocumentNameFlag}} (string) Name of the document of the executions.
+
+    {{.CommandIDFlag}} (string) Id of the command of the execution.
+
+    {{.AssociationIDFlag}} (string) Id of the association of the executions.
+
+    {{.StatusFlag}} (string) Status of the executions, Success, Failed, TimedOut, Cancelled...
+
+    {{.SinceFlag}} (string) Executions that ended after this time, RFC3339 or a duration before now (e.g. 24h).
+
+    {{.MaxResultsFlag}} (integer) Number of newest executions printed.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.GetExecutionHistoryName}} {{.StatusFlag}} Failed {{.SinceFlag}} 24h
+
+OUTPUT
+    The executions of the history
+`
+
+type getExecutionHistoryHelpParams struct {
+	SsmCliName              string
+	GetExecutionHistoryName string
+	DocumentNameFlag        string
+	CommandIDFlag           string
+	AssociationIDFlag       string
+	StatusFlag              string
+	SinceFlag               string
+	MaxResultsFlag          string
+}
+
+func init() {
+	cliutil.Register(&GetExecutionHistoryCommand{})
+}
+
+type GetExecutionHistoryCommand struct {
+	helpText string
+}
+
+// Execute validates the input of the get-execution-history cli command and prints the executions of the history
+func (c *GetExecutionHistoryCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateInput(subcommands, parameters)
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	filter := history.Filter{
+		DocumentName:  parameterValue(parameters, getExecutionHistoryDocumentName),
+		CommandID:     parameterValue(parameters, getExecutionHistoryCommandID),
+		AssociationID: parameterValue(parameters, getExecutionHistoryAssociationID),
+		Status:        contracts.ResultStatus(parameterValue(parameters, getExecutionHistoryStatus)),
+	}
+	if since := parameterValue(parameters, getExecutionHistorySince); since != "" {
+		filter.Since, _ = parseSince(since)
+	}
+	if maxResults := parameterValue(parameters, getExecutionHistoryMaxResults); maxResults != "" {
+		filter.MaxResults, _ = strconv.Atoi(maxResults)
+	}
+
+	entries, err := history.Query(filter)
+	if err != nil {
+		return fmt.Errorf("failed to read the execution history: %v", err), ""
+	}
+	if len(entries) == 0 {
+		return nil, "no executions recorded"
+	}
+	result, err := jsonutil.MarshalIndent(entries)
+	return err, result
+}
+
+// parameterValue returns the value of a parameter, empty if it's not set
+func parameterValue(parameters map[string][]string, name string) string {
+	if values, exists := parameters[name]; exists && len(values) == 1 {
+		return values[0]
+	}
+	return ""
+}
+
+// parseSince parses an RFC3339 time, or a duration before now
+func parseSince(since string) (time.Time, error) {
+	if duration, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	return time.Parse(time.RFC3339, since)
+}
+
+// Help prints help for the get-execution-history cli command
+func (c *GetExecutionHistoryCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetExecutionHistoryHelp").Parse(getExecutionHistoryHelp)
+		params := getExecutionHistoryHelpParams{
+			cliutil.SsmCliName,
+			getExecutionHistory,
+			cliutil.FormatFlag(getExecutionHistoryDocumentName),
+			cliutil.FormatFlag(getExecutionHistoryCommandID),
+			cliutil.FormatFlag(getExecutionHistoryAssociationID),
+			cliutil.FormatFlag(getExecutionHistoryStatus),
+			cliutil.FormatFlag(getExecutionHistorySince),
+			cliutil.FormatFlag(getExecutionHistoryMaxResults),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetExecutionHistoryCommand) Name() string {
+	return getExecutionHistory
+}
+
+// validateInput checks the subcommands and parameters for required values, format, and unsupported values
+func (GetExecutionHistoryCommand) validateInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if len(subcommands) > 0 {
+		return append(validation, fmt.Sprintf("%v does not support subcommand %v", getExecutionHistory, subcommands), "")
+	}
+
+	for key, values := range parameters {
+		switch key {
+		case getExecutionHistoryDocumentName, getExecutionHistoryCommandID, getExecutionHistoryAssociationID, getExecutionHistoryStatus:
+		case getExecutionHistorySince:
+			if len(values) == 1 {
+				if _, err := parseSince(values[0]); err != nil {
+					validation = append(validation, fmt.Sprintf("%v value must be an RFC3339 time or a duration", cliutil.FormatFlag(key)))
+				}
+			}
+		case getExecutionHistoryMaxResults:
+			if len(values) == 1 {
+				if maxResults, err := strconv.Atoi(values[0]); err != nil || maxResults < 1 {
+					validation = append(validation, fmt.Sprintf("%v value must be a positive integer", cliutil.FormatFlag(key)))
+				}
+			}
+		default:
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+			continue
+		}
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation
+}
@@ -0,0 +1,150 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/hibernation"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+const (
+	getHibernationStatus     = "get-hibernation-status"
+	getHibernationStatusWake = "wake"
+)
+
+const getHibernationStatusHelp = `NAME:
+    {{.GetHibernationStatusName}}
+
+DESCRIPTION
+    Prints the hibernation status of amazon-ssm-agent. The agent hibernates when it can't reach the health service
+    at startup: it probes the endpoint every minute and pings the health service with an increasing backoff,
+    up to every hour, until it succeeds.
+
+SYNOPSIS
+    {{.GetHibernationStatusName}}
+    [{{.WakeFlag}}]
+
+PARAMETERS
+    {{.WakeFlag}} (boolean) Asks the hibernating agent to ping the health service now.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.GetHibernationStatusName}}
+
+    Output:
+
+      {
+        "State": "Hibernating",
+        "Since": "2017-01-01T00:00:00Z",
+        "LastProbe": "2017-01-01T00:10:00Z",
+        "LastProbeResult": "health ping failed: AccessDeniedException",
+        "HealthPings": 2,
+        "HealthPingIntervalSeconds": 600
+      }
+
+OUTPUT
+    The state of the agent, Hibernating or Active, since when, and the outcome of its last probe
+`
+
+type getHibernationStatusHelpParams struct {
+	SsmCliName               string
+	GetHibernationStatusName string
+	WakeFlag                 string
+}
+
+func init() {
+	cliutil.Register(&GetHibernationStatusCommand{})
+}
+
+type GetHibernationStatusCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-hibernation-status cli command
+func (c *GetHibernationStatusCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, wake := c.validateGetHibernationStatusInput(subcommands, parameters)
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	status, err := hibernation.ReadStatus()
+	if os.IsNotExist(err) {
+		return nil, "amazon-ssm-agent has not hibernated"
+	} else if err != nil {
+		return fmt.Errorf("failed to read the hibernation status: %v", err), ""
+	}
+	result, _ := jsonutil.MarshalIndent(status)
+	if status.Stale(time.Now()) {
+		return nil, result + "\nThe status is stale, amazon-ssm-agent is likely not running"
+	}
+	if !wake {
+		return nil, result
+	}
+	if status.State != hibernation.StateHibernating {
+		return fmt.Errorf("amazon-ssm-agent is not hibernating"), ""
+	}
+	if err = hibernation.RequestWake(); err != nil {
+		return fmt.Errorf("failed to request a health ping: %v", err), ""
+	}
+	return nil, result + "\nRequested a health ping, run the command again to see its result"
+}
+
+// Help prints help for the get-hibernation-status cli command
+func (c *GetHibernationStatusCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetHibernationStatusHelp").Parse(getHibernationStatusHelp)
+		params := getHibernationStatusHelpParams{cliutil.SsmCliName, getHibernationStatus, cliutil.FormatFlag(getHibernationStatusWake)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetHibernationStatusCommand) Name() string {
+	return getHibernationStatus
+}
+
+// validateGetHibernationStatusInput checks the subcommands and the optional wake flag
+func (GetHibernationStatusCommand) validateGetHibernationStatusInput(subcommands []string, parameters map[string][]string) (validation []string, wake bool) {
+	validation = make([]string, 0)
+	if len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getHibernationStatus, subcommands))
+		return validation, false
+	}
+	values, wake := parameters[getHibernationStatusWake]
+	if len(values) > 0 {
+		validation = append(validation, fmt.Sprintf("flag %v should not have any values", cliutil.FormatFlag(getHibernationStatusWake)))
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != getHibernationStatusWake {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, wake
+}
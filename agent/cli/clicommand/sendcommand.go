@@ -29,7 +29,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
-	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/twinj/uuid"
 )
@@ -51,6 +50,9 @@ PARAMETERS
     {{.ContentFlag}} (string) JSON or URL to command document.
     A valid command document is a configuration document with all parameters filled in.
     For information about writing a configuration document, see Configuration Document in the SSM API Reference.
+    The command can also run a document that ran on the instance before, which the agent cached, by name:
+    {"documentName": "name", "parameters": {"name": ["value"]}}.
+    "outputS3BucketName" and "outputS3KeyPrefix" upload the result of the command to S3, once the instance is online.
 
 EXAMPLES
     This example runs a command in a document in S3.
@@ -89,19 +91,33 @@ func (c *SendOfflineCommand) Execute(subcommands []string, parameters map[string
 		return errors.New(strings.Join(validation, "\n")), ""
 	}
 
-	if err, content := c.loadContent(parameters[sendCommandContent][0]); err != nil {
+	err, rawContent := c.loadContent(parameters[sendCommandContent][0])
+	if err != nil {
 		return err, ""
-	} else if err := c.validateContent(content); err != nil {
-		return err, ""
-	} else if contentString, err := jsonutil.Marshal(content); err != nil {
-		return err, ""
-	} else if err, documentName := c.submitCommandDocument(contentString); err != nil {
+	}
+	var reference cachedDocumentReference
+	if err = json.Unmarshal([]byte(rawContent), &reference); err != nil || reference.DocumentName == "" {
+		// the content is a document
+		var content contracts.DocumentContent
+		if err = json.Unmarshal([]byte(rawContent), &content); err != nil {
+			return err, ""
+		} else if err = c.validateContent(content); err != nil {
+			return err, ""
+		}
+	}
+	if err, documentName := c.submitCommandDocument(rawContent); err != nil {
 		return err, ""
 	} else {
 		return nil, c.waitForSubmitStatus(documentName)
 	}
 }
 
+// cachedDocumentReference is a command running a document the instance ran before, cached by the agent,
+// by name with the values of its parameters
+type cachedDocumentReference struct {
+	DocumentName string `json:"documentName"`
+}
+
 // Help prints help for the send-offline-command cli command
 func (c *SendOfflineCommand) Help() string {
 	if len(c.helpText) == 0 {
@@ -149,12 +165,10 @@ func (SendOfflineCommand) validateSendCommandInput(subcommands []string, paramet
 	return validation
 }
 
-// loadContent loads raw json or json obtained from a URL into DocumentContent
-func (SendOfflineCommand) loadContent(rawContent string) (error, contracts.DocumentContent) {
-	var content contracts.DocumentContent
+// loadContent loads raw json or json obtained from a URL
+func (SendOfflineCommand) loadContent(rawContent string) (error, string) {
 	if cliutil.ValidJson(rawContent) {
-		err := json.Unmarshal([]byte(rawContent), &content)
-		return err, content
+		return nil, rawContent
 	}
 	var url = rawContent
 	// TODO:MF: Write a URI loader utility - artifact really doesn't do that job
@@ -164,9 +178,9 @@ func (SendOfflineCommand) loadContent(rawContent string) (error, contracts.Docum
 
 	input := &artifact.DownloadInput{SourceURL: url}
 	if output, err := artifact.Download(log.NewMockLog(), *input); err != nil {
-		return err, content
+		return err, ""
 	} else {
-		err = jsonutil.UnmarshalFile(output.LocalFilePath, &content)
+		content, err := fileutil.ReadAllText(output.LocalFilePath)
 		// TODO:MF: ideally we'd delete the file if we downloaded it - but it might've been a local file and we don't have a good way to tell
 		return err, content
 	}
@@ -0,0 +1,153 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	setLogLevel      = "set-log-level"
+	setLogLevelLevel = "level"
+	setLogLevelReset = "reset"
+)
+
+const setLogLevelHelp = `NAME:
+    {{.SetLogLevelName}}
+
+DESCRIPTION
+    Changes the log level of the running amazon-ssm-agent and its document workers without restarting them.
+    The level overrides the minlevel of seelog.xml until it is reset.
+
+SYNOPSIS
+    {{.SetLogLevelName}}
+    [{{.LevelFlag}} <value>]
+    [{{.ResetFlag}}]
+
+PARAMETERS
+    {{.LevelFlag}} (string) One of {{.Levels}}.
+
+    {{.ResetFlag}} (boolean) Restores the log level of seelog.xml.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.SetLogLevelName}} {{.LevelFlag}} debug
+
+    Output:
+
+      Log level set to debug
+
+OUTPUT
+    The log level in effect
+`
+
+type setLogLevelHelpParams struct {
+	SsmCliName      string
+	SetLogLevelName string
+	LevelFlag       string
+	ResetFlag       string
+	Levels          string
+}
+
+func init() {
+	cliutil.Register(&SetLogLevelCommand{})
+}
+
+type SetLogLevelCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the set-log-level cli command
+func (c *SetLogLevelCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, level, reset := c.validateSetLogLevelInput(subcommands, parameters)
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+	if reset {
+		if err := log.ClearLogLevelOverride(); err != nil {
+			return fmt.Errorf("failed to reset the log level: %v", err), ""
+		}
+		return nil, "Log level reset to the level of seelog.xml"
+	}
+	if err := log.SetLogLevelOverride(level); err != nil {
+		return fmt.Errorf("failed to set the log level: %v", err), ""
+	}
+	return nil, fmt.Sprintf("Log level set to %v", level)
+}
+
+// Help prints help for the set-log-level cli command
+func (c *SetLogLevelCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("SetLogLevelHelp").Parse(setLogLevelHelp)
+		params := setLogLevelHelpParams{
+			cliutil.SsmCliName,
+			setLogLevel,
+			cliutil.FormatFlag(setLogLevelLevel),
+			cliutil.FormatFlag(setLogLevelReset),
+			strings.Join(log.LogLevels, ", "),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (SetLogLevelCommand) Name() string {
+	return setLogLevel
+}
+
+// validateSetLogLevelInput checks that either a valid level or the reset flag is provided
+func (SetLogLevelCommand) validateSetLogLevelInput(subcommands []string, parameters map[string][]string) (validation []string, level string, reset bool) {
+	validation = make([]string, 0)
+
+	if len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", setLogLevel, subcommands))
+		return validation, "", false
+	}
+
+	values, hasLevel := parameters[setLogLevelLevel]
+	_, reset = parameters[setLogLevelReset]
+	if hasLevel == reset {
+		validation = append(validation, fmt.Sprintf("one of %v or %v is required",
+			cliutil.FormatFlag(setLogLevelLevel), cliutil.FormatFlag(setLogLevelReset)))
+	} else if hasLevel {
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(setLogLevelLevel)))
+		} else if level = strings.ToLower(values[0]); !log.IsLogLevel(level) {
+			validation = append(validation, fmt.Sprintf("invalid value %v for parameter %v, expected one of %v",
+				values[0], cliutil.FormatFlag(setLogLevelLevel), strings.Join(log.LogLevels, ", ")))
+		}
+	} else if len(parameters[setLogLevelReset]) > 0 {
+		validation = append(validation, fmt.Sprintf("flag %v should not have any values", cliutil.FormatFlag(setLogLevelReset)))
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != setLogLevelLevel && key != setLogLevelReset {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, level, reset
+}
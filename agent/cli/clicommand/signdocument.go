@@ -0,0 +1,193 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/docparser"
+)
+
+const (
+	signDocument           = "sign-document"
+	signDocumentContent    = "content"
+	signDocumentKeyID      = "key-id"
+	signDocumentPrivateKey = "private-key"
+)
+
+const signDocumentHelp = `NAME:
+    {{.SignDocumentName}}
+
+DESCRIPTION
+    Signs a command document, so that the agents pinning the public key in the DocumentSigning section of their
+    configuration run it. The document must be JSON; a signature it already has is replaced.
+
+SYNOPSIS
+    {{.SignDocumentName}}
+    {{.ContentFlag}} <value>
+    {{.KeyIDFlag}} <value>
+    {{.PrivateKeyFlag}} <value>
+
+PARAMETERS
+    {{.ContentFlag}} (string) JSON or URL to the command document.
+
+    {{.KeyIDFlag}} (string) Name of the key in DocumentSigning.PublicKeys of the agent configuration.
+
+    {{.PrivateKeyFlag}} (string) Path to the PEM file of the RSA, ECDSA or Ed25519 private key.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.SignDocumentName}} {{.ContentFlag}} file:///tmp/document.json {{.KeyIDFlag}} release {{.PrivateKeyFlag}} /etc/keys/release.pem
+
+    Output:
+
+      {
+        "mainSteps": [
+          ...
+        ],
+        "schemaVersion": "2.2",
+        "signature": {
+          "keyId": "release",
+          "value": "MEUCIQDn..."
+        }
+      }
+
+OUTPUT
+    The signed document
+`
+
+type signDocumentHelpParams struct {
+	SsmCliName       string
+	SignDocumentName string
+	ContentFlag      string
+	KeyIDFlag        string
+	PrivateKeyFlag   string
+}
+
+func init() {
+	cliutil.Register(&SignDocumentCommand{})
+}
+
+type SignDocumentCommand struct {
+	helpText string
+}
+
+// Execute validates the input of the sign-document cli command and prints the signed document
+func (c *SignDocumentCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateInput(subcommands, parameters)
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	err, rawContent := SendOfflineCommand{}.loadContent(parameters[signDocumentContent][0])
+	if err != nil {
+		return err, ""
+	}
+	key, err := loadPrivateKey(parameters[signDocumentPrivateKey][0])
+	if err != nil {
+		return fmt.Errorf("failed to load the private key: %v", err), ""
+	}
+	signed, err := docparser.SignDocument([]byte(rawContent), parameters[signDocumentKeyID][0], key)
+	if err != nil {
+		return fmt.Errorf("failed to sign the document: %v", err), ""
+	}
+	return nil, string(signed)
+}
+
+// loadPrivateKey parses a PEM encoded PKCS #8, PKCS #1 or EC private key
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%v isn't a PEM file", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// Help prints help for the sign-document cli command
+func (c *SignDocumentCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("SignDocumentHelp").Parse(signDocumentHelp)
+		params := signDocumentHelpParams{
+			cliutil.SsmCliName,
+			signDocument,
+			cliutil.FormatFlag(signDocumentContent),
+			cliutil.FormatFlag(signDocumentKeyID),
+			cliutil.FormatFlag(signDocumentPrivateKey),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (SignDocumentCommand) Name() string {
+	return signDocument
+}
+
+// validateInput checks the subcommands and parameters for required values, format, and unsupported values
+func (SignDocumentCommand) validateInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if len(subcommands) > 0 {
+		return append(validation, fmt.Sprintf("%v does not support subcommand %v", signDocument, subcommands), "")
+	}
+
+	for _, name := range []string{signDocumentContent, signDocumentKeyID, signDocumentPrivateKey} {
+		if values, exists := parameters[name]; !exists {
+			validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(name)))
+		} else if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(name)))
+		}
+	}
+	if values, exists := parameters[signDocumentContent]; exists && len(values) == 1 && !cliutil.ValidJson(values[0]) && !cliutil.ValidUrl(values[0]) {
+		validation = append(validation, fmt.Sprintf("%v value must be valid json or a URL", cliutil.FormatFlag(signDocumentContent)))
+	}
+
+	for key := range parameters {
+		if key != signDocumentContent && key != signDocumentKeyID && key != signDocumentPrivateKey {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation
+}
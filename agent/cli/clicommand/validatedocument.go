@@ -0,0 +1,198 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/plugin"
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/cihub/seelog"
+)
+
+const (
+	validateDocument           = "validate-document"
+	validateDocumentContent    = "content"
+	validateDocumentParameters = "parameters"
+)
+
+const validateDocumentHelp = `NAME:
+    {{.ValidateDocumentName}}
+
+DESCRIPTION
+    Parses a command document, replaces its parameters and evaluates the preconditions of its steps on this
+    instance, then prints the steps as the agent would run them, without running anything.
+    Each step has an operation: execute, skip, or fail when this version of the agent can't run it.
+
+SYNOPSIS
+    {{.ValidateDocumentName}}
+    {{.ContentFlag}} <value>
+    [{{.ParametersFlag}} <value>]
+
+PARAMETERS
+    {{.ContentFlag}} (string) JSON or URL to the command document.
+
+    {{.ParametersFlag}} (string) JSON object of the values of the parameters of the document, by name.
+    The parameters without a value take their default value.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.ValidateDocumentName}} {{.ContentFlag}} file:///tmp/document.json {{.ParametersFlag}} '{"commands": ["echo hello"]}'
+
+    Output:
+
+      {
+        "steps": [
+          {
+            "name": "runShellScript",
+            "action": "aws:runShellScript",
+            "operation": "execute",
+            "inputs": {
+              "runCommand": [
+                "echo hello"
+              ]
+            }
+          }
+        ]
+      }
+
+OUTPUT
+    The steps of the document, or the reason the document is invalid
+`
+
+type validateDocumentHelpParams struct {
+	SsmCliName           string
+	ValidateDocumentName string
+	ContentFlag          string
+	ParametersFlag       string
+}
+
+// documentPlan is the output of the validate-document cli command
+type documentPlan struct {
+	Steps []runpluginutil.PlannedStep `json:"steps"`
+}
+
+// workerPlugins returns the plugins of the agent the steps are evaluated with
+func workerPlugins(log log.T) runpluginutil.PluginRegistry {
+	config, _ := appconfig.Config(false)
+	return plugin.RegisteredWorkerPlugins(context.Default(log, config))
+}
+
+func init() {
+	cliutil.Register(&ValidateDocumentCommand{})
+}
+
+type ValidateDocumentCommand struct {
+	helpText string
+}
+
+// Execute validates the input of the validate-document cli command and prints the steps of the document
+func (c *ValidateDocumentCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateInput(subcommands, parameters)
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	err, rawContent := SendOfflineCommand{}.loadContent(parameters[validateDocumentContent][0])
+	if err != nil {
+		return err, ""
+	}
+	var content contracts.DocumentContent
+	if err = json.Unmarshal([]byte(rawContent), &content); err != nil {
+		return fmt.Errorf("invalid document: %v", err), ""
+	}
+	params := make(map[string]interface{})
+	if values, exists := parameters[validateDocumentParameters]; exists {
+		if err = json.Unmarshal([]byte(values[0]), &params); err != nil {
+			return fmt.Errorf("invalid parameters: %v", err), ""
+		}
+	}
+
+	// the output is the plan only, the logs of the parsing would mix with it
+	var logger log.T = seelog.Disabled
+	pluginsInfo, err := docparser.ParseDocument(logger, &content, docparser.DocumentParserInfo{}, params)
+	if err != nil {
+		return fmt.Errorf("invalid document: %v", err), ""
+	}
+	plan := documentPlan{Steps: runpluginutil.PlanSteps(logger, pluginsInfo, workerPlugins(logger))}
+	result, err := jsonutil.MarshalIndent(plan)
+	return err, result
+}
+
+// Help prints help for the validate-document cli command
+func (c *ValidateDocumentCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("ValidateDocumentHelp").Parse(validateDocumentHelp)
+		params := validateDocumentHelpParams{
+			cliutil.SsmCliName,
+			validateDocument,
+			cliutil.FormatFlag(validateDocumentContent),
+			cliutil.FormatFlag(validateDocumentParameters),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (ValidateDocumentCommand) Name() string {
+	return validateDocument
+}
+
+// validateInput checks the subcommands and parameters for required values, format, and unsupported values
+func (ValidateDocumentCommand) validateInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if len(subcommands) > 0 {
+		return append(validation, fmt.Sprintf("%v does not support subcommand %v", validateDocument, subcommands), "")
+	}
+
+	if values, exists := parameters[validateDocumentContent]; !exists {
+		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(validateDocumentContent)))
+	} else if len(values) != 1 {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(validateDocumentContent)))
+	} else if !cliutil.ValidJson(values[0]) && !cliutil.ValidUrl(values[0]) {
+		validation = append(validation, fmt.Sprintf("%v value must be valid json or a URL", cliutil.FormatFlag(validateDocumentContent)))
+	}
+
+	if values, exists := parameters[validateDocumentParameters]; exists {
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(validateDocumentParameters)))
+		} else if !cliutil.ValidJson(values[0]) {
+			validation = append(validation, fmt.Sprintf("%v value must be valid json", cliutil.FormatFlag(validateDocumentParameters)))
+		}
+	}
+
+	for key := range parameters {
+		if key != validateDocumentContent && key != validateDocumentParameters {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation
+}
@@ -56,10 +56,31 @@ func (c *defaultContext) Log() log.T {
 	return c.log
 }
 
+// AppConfig returns the configuration of the context, with the settings reloaded while the agent runs.
 func (c *defaultContext) AppConfig() appconfig.SsmagentConfig {
-	return c.appconfig
+	return appconfig.WithReloadedValues(c.appconfig)
 }
 
 func (c *defaultContext) CurrentContext() []string {
 	return c.context
 }
+
+// WithTrace tags the context with a new span of the trace of the traceparent, a new trace is started
+// if the traceparent is missing or invalid, e.g. for the documents persisted by an older agent.
+func WithTrace(ctx T, traceParent string) T {
+	trace, err := log.ParseTraceParent(traceParent)
+	if err != nil {
+		return ctx.With(log.NewTraceContext().ContextTag())
+	}
+	return ctx.With(trace.NewSpan().ContextTag())
+}
+
+// WithSpan tags the context with a new span of the trace it is tagged with.
+// The context is returned unchanged if it isn't traced.
+func WithSpan(ctx T) T {
+	trace, ok := log.TraceContextOf(ctx.CurrentContext())
+	if !ok {
+		return ctx
+	}
+	return ctx.With(trace.NewSpan().ContextTag())
+}
@@ -42,6 +42,9 @@ func prepareRuntimeStatus(log log.T, pluginResult PluginResult) PluginRuntimeSta
 		EndDateTime:    times.ToIso8601UTC(pluginResult.EndDateTime),
 		StandardOutput: pluginResult.StandardOutput,
 		StandardError:  pluginResult.StandardError,
+		Attachments:    pluginResult.Attachments,
+		Attempts:       pluginResult.Attempts,
+		Iterations:     pluginResult.Iterations,
 	}
 
 	if pluginResult.OutputS3BucketName != "" {
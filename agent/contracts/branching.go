@@ -0,0 +1,89 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// BranchContinue runs the next step of the document, the default of onFailure and onSuccess
+	BranchContinue = "Continue"
+	// BranchAbort skips the remaining steps of the document after a failed step, a value of onFailure
+	BranchAbort = "Abort"
+	// BranchExit skips the remaining steps of the document after a successful step, a value of onSuccess
+	BranchExit = "Exit"
+	// BranchStepPrefix precedes the name of the step onFailure or onSuccess jumps to, e.g. step:rollback
+	BranchStepPrefix = "step:"
+)
+
+// StepBranchTarget returns the step a branch of the given step jumps to: the name after BranchStepPrefix for
+// onFailure and onSuccess, the name of nextStep. The steps are named by the name of their config.
+func StepBranchTarget(branch string, isNextStep bool) (name string, ok bool) {
+	if isNextStep {
+		return branch, branch != ""
+	}
+	if strings.HasPrefix(branch, BranchStepPrefix) {
+		return strings.TrimPrefix(branch, BranchStepPrefix), true
+	}
+	return "", false
+}
+
+// ValidateBranching checks the onFailure, onSuccess and nextStep of the steps of a document. The steps can only
+// jump forward, so that each step runs at most once and the document always ends.
+func ValidateBranching(steps []*InstancePluginConfig) error {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		index[step.Name] = i
+	}
+	checkTarget := func(i int, field, branch string, isNextStep bool) error {
+		name, ok := StepBranchTarget(branch, isNextStep)
+		if !ok {
+			return nil
+		}
+		target, found := index[name]
+		if !found {
+			return fmt.Errorf("%v of step %v refers to the unknown step %v", field, steps[i].Name, name)
+		}
+		if target <= i {
+			return fmt.Errorf("%v of step %v refers to step %v, which doesn't come after it", field, steps[i].Name, name)
+		}
+		return nil
+	}
+
+	for i, step := range steps {
+		switch {
+		case step.OnFailure == "", step.OnFailure == BranchContinue, step.OnFailure == BranchAbort, strings.HasPrefix(step.OnFailure, BranchStepPrefix):
+		default:
+			return fmt.Errorf("onFailure of step %v is %v, it must be %v, %v or %v<step name>", step.Name, step.OnFailure, BranchContinue, BranchAbort, BranchStepPrefix)
+		}
+		switch {
+		case step.OnSuccess == "", step.OnSuccess == BranchContinue, step.OnSuccess == BranchExit, strings.HasPrefix(step.OnSuccess, BranchStepPrefix):
+		default:
+			return fmt.Errorf("onSuccess of step %v is %v, it must be %v, %v or %v<step name>", step.Name, step.OnSuccess, BranchContinue, BranchExit, BranchStepPrefix)
+		}
+		if err := checkTarget(i, "onFailure", step.OnFailure, false); err != nil {
+			return err
+		}
+		if err := checkTarget(i, "onSuccess", step.OnSuccess, false); err != nil {
+			return err
+		}
+		if err := checkTarget(i, "nextStep", step.NextStep, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBranching(t *testing.T) {
+	steps := func(install, rollback *InstancePluginConfig) []*InstancePluginConfig {
+		install.Name, rollback.Name = "install", "rollback"
+		return []*InstancePluginConfig{install, {Name: "configure"}, rollback}
+	}
+
+	assert.NoError(t, ValidateBranching(steps(&InstancePluginConfig{OnFailure: "step:rollback", OnSuccess: BranchContinue}, &InstancePluginConfig{})))
+	assert.NoError(t, ValidateBranching(steps(&InstancePluginConfig{OnFailure: BranchAbort, NextStep: "rollback"}, &InstancePluginConfig{OnSuccess: BranchExit})))
+
+	// unknown values and steps, backward jumps
+	assert.Error(t, ValidateBranching(steps(&InstancePluginConfig{OnFailure: "Retry"}, &InstancePluginConfig{})))
+	assert.Error(t, ValidateBranching(steps(&InstancePluginConfig{OnSuccess: BranchAbort}, &InstancePluginConfig{})))
+	assert.Error(t, ValidateBranching(steps(&InstancePluginConfig{OnFailure: "step:missing"}, &InstancePluginConfig{})))
+	assert.Error(t, ValidateBranching(steps(&InstancePluginConfig{}, &InstancePluginConfig{OnFailure: "step:install"})))
+	assert.Error(t, ValidateBranching(steps(&InstancePluginConfig{}, &InstancePluginConfig{NextStep: "rollback"})))
+}
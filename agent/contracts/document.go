@@ -68,6 +68,8 @@ type DocumentInfo struct {
 	DocumentStatus  ResultStatus
 	RunCount        int
 	ProcInfo        OSProcInfo
+	// TraceParent is the W3C traceparent of the trace started when the document was received
+	TraceParent string `json:",omitempty"`
 }
 
 // IOConfiguration represents information relevant to the output sources of a command
@@ -75,8 +77,19 @@ type IOConfiguration struct {
 	OrchestrationDirectory string
 	OutputS3BucketName     string
 	OutputS3KeyPrefix      string
+	// PartialOutput, if set, receives the output of a running step every PartialOutputInterval, while the output
+	// files are uploaded to S3 as they grow. It's not persisted with the document.
+	PartialOutput         PartialOutputFunc `json:"-"`
+	PartialOutputInterval time.Duration     `json:"-"`
+	// CloudWatchLogGroup and CloudWatchLogStream, if set, are where the output of the steps is streamed to, with
+	// {stepName} and {stream} of the log stream replaced for each step and output
+	CloudWatchLogGroup  string `json:",omitempty"`
+	CloudWatchLogStream string `json:",omitempty"`
 }
 
+// PartialOutputFunc receives the standard output and error a step has written so far
+type PartialOutputFunc func(stdout, stderr string)
+
 // DocumentState represents information relevant to a command that gets executed by agent
 type DocumentState struct {
 	DocumentInformation        DocumentInfo
@@ -85,6 +98,8 @@ type DocumentState struct {
 	InstancePluginsInformation []PluginState
 	CancelInformation          CancelCommandInfo
 	IOConfig                   IOConfiguration
+	// AttachmentsContent lists the files attached to the document, fetched before the first step runs
+	AttachmentsContent []AttachmentContent `json:",omitempty"`
 }
 
 // IsRebootRequired returns if reboot is needed
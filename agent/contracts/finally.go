@@ -0,0 +1,53 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package contracts
+
+import (
+	"fmt"
+)
+
+// ValidateFinallySteps checks the finallySteps of a document, which run in order after the main steps whatever
+// happened to them: they can't branch nor be parallel, and their names can't be the names of main steps.
+func ValidateFinallySteps(docContent DocumentContent) error {
+	names := make(map[string]bool, len(docContent.MainSteps))
+	for _, step := range docContent.MainSteps {
+		names[step.Name] = true
+	}
+	for _, step := range docContent.FinallySteps {
+		if names[step.Name] {
+			return fmt.Errorf("finally step %v has the name of another step", step.Name)
+		}
+		names[step.Name] = true
+		if (step.OnFailure != "" && step.OnFailure != BranchContinue) || (step.OnSuccess != "" && step.OnSuccess != BranchContinue) || step.NextStep != "" {
+			return fmt.Errorf("step %v is a finally step, it can't declare onFailure, onSuccess or nextStep", step.Name)
+		}
+		if step.Parallel {
+			return fmt.Errorf("step %v is a finally step, it can't be parallel", step.Name)
+		}
+		if step.Timeout < 0 {
+			return fmt.Errorf("timeoutSeconds of step %v is %v, it can't be negative", step.Name, step.Timeout)
+		}
+	}
+	if err := ValidateRetries(docContent.FinallySteps); err != nil {
+		return err
+	}
+	return ValidateLoops(docContent.FinallySteps)
+}
+
+// AllSteps returns the main steps of the document followed by its finally steps.
+func (docContent DocumentContent) AllSteps() []*InstancePluginConfig {
+	steps := make([]*InstancePluginConfig, 0, len(docContent.MainSteps)+len(docContent.FinallySteps))
+	steps = append(steps, docContent.MainSteps...)
+	return append(steps, docContent.FinallySteps...)
+}
@@ -0,0 +1,40 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFinallySteps(t *testing.T) {
+	doc := DocumentContent{
+		MainSteps:    []*InstancePluginConfig{{Name: "a", OnFailure: BranchAbort}, {Name: "b"}},
+		FinallySteps: []*InstancePluginConfig{{Name: "c", MaxAttempts: 2}, {Name: "d", OnFailure: BranchContinue}},
+	}
+	assert.NoError(t, ValidateFinallySteps(doc))
+	assert.Len(t, doc.AllSteps(), 4)
+
+	doc.FinallySteps[1].Name = "a"
+	assert.Error(t, ValidateFinallySteps(doc))
+
+	doc.FinallySteps[1].Name = "d"
+	doc.FinallySteps[1].OnFailure = BranchAbort
+	assert.Error(t, ValidateFinallySteps(doc))
+
+	doc.FinallySteps[1].OnFailure = ""
+	doc.FinallySteps[1].Parallel = true
+	assert.Error(t, ValidateFinallySteps(doc))
+}
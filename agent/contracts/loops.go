@@ -0,0 +1,126 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxStepIterations is the largest number of iterations of a loop
+	MaxStepIterations = 100
+
+	// DefaultStepIterations is the maxIterations of a loop repeating a step until a condition that doesn't declare it
+	DefaultStepIterations = 10
+
+	// maxLoopDelaySeconds is the largest delaySeconds of a loop
+	maxLoopDelaySeconds = 3600
+)
+
+// StepLoop repeats a step: once for each item of ForEach, a list or a StringList parameter, or until the step ends
+// with the Until conditions, at most MaxIterations times. The step waits DelaySeconds between the iterations.
+// {{ loop.item }} and {{ loop.index }} in the inputs of the step are replaced with the item and the index, from 0,
+// of the iteration.
+type StepLoop struct {
+	ForEach       interface{}    `json:"forEach" yaml:"forEach"`
+	Until         *StepLoopUntil `json:"until" yaml:"until"`
+	MaxIterations int            `json:"maxIterations" yaml:"maxIterations"`
+	DelaySeconds  int            `json:"delaySeconds" yaml:"delaySeconds"`
+}
+
+// StepLoopUntil are the conditions ending a loop, all of them must hold for the iteration of the step:
+// its exit code and a string its standard output contains.
+type StepLoopUntil struct {
+	ExitCode       *int   `json:"exitCode" yaml:"exitCode"`
+	StdoutContains string `json:"stdoutContains" yaml:"stdoutContains"`
+}
+
+// StepIteration is an iteration of a step repeated with a loop, recorded in the result of the step.
+type StepIteration struct {
+	Iteration      int          `json:"iteration"`
+	Item           interface{}  `json:"item,omitempty"`
+	Status         ResultStatus `json:"status"`
+	Code           int          `json:"code"`
+	StartDateTime  string       `json:"startDateTime"`
+	EndDateTime    string       `json:"endDateTime"`
+	StandardOutput string       `json:"standardOutput"`
+	StandardError  string       `json:"standardError"`
+	Error          string       `json:"error,omitempty"`
+}
+
+// Items returns the items of ForEach, nil for a loop repeating until a condition.
+func (l *StepLoop) Items() ([]interface{}, error) {
+	switch items := l.ForEach.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return items, nil
+	case []string:
+		result := make([]interface{}, len(items))
+		for i, item := range items {
+			result[i] = item
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("forEach must be a list, not %v", items)
+	}
+}
+
+// Iterations returns the largest number of iterations of the loop.
+func (l *StepLoop) Iterations() int {
+	if items, _ := l.Items(); items != nil {
+		return len(items)
+	}
+	if l.MaxIterations == 0 {
+		return DefaultStepIterations
+	}
+	return l.MaxIterations
+}
+
+// IsMet checks if an iteration ending with the exit code and the standard output ends the loop.
+func (u *StepLoopUntil) IsMet(exitCode int, stdout string) bool {
+	return (u.ExitCode == nil || *u.ExitCode == exitCode) && strings.Contains(stdout, u.StdoutContains)
+}
+
+// ValidateLoops checks the loops of the steps of a document, once their parameters are replaced.
+func ValidateLoops(steps []*InstancePluginConfig) error {
+	for _, step := range steps {
+		loop := step.Loop
+		if loop == nil {
+			continue
+		}
+		items, err := loop.Items()
+		if err != nil {
+			return fmt.Errorf("loop of step %v: %v", step.Name, err)
+		}
+		if (loop.ForEach == nil) == (loop.Until == nil) {
+			return fmt.Errorf("loop of step %v must declare either forEach or until", step.Name)
+		}
+		if len(items) > MaxStepIterations {
+			return fmt.Errorf("loop of step %v has %v items, at most %v are allowed", step.Name, len(items), MaxStepIterations)
+		}
+		if loop.Until != nil && loop.Until.ExitCode == nil && loop.Until.StdoutContains == "" {
+			return fmt.Errorf("until of step %v must declare exitCode or stdoutContains", step.Name)
+		}
+		if loop.MaxIterations < 0 || loop.MaxIterations > MaxStepIterations {
+			return fmt.Errorf("maxIterations of step %v is %v, it must be between 1 and %v", step.Name, loop.MaxIterations, MaxStepIterations)
+		}
+		if loop.DelaySeconds < 0 || loop.DelaySeconds > maxLoopDelaySeconds {
+			return fmt.Errorf("delaySeconds of step %v is %v, it must be between 0 and %v", step.Name, loop.DelaySeconds, maxLoopDelaySeconds)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLoops(t *testing.T) {
+	zero := 0
+	assert.NoError(t, ValidateLoops([]*InstancePluginConfig{
+		{Name: "a"},
+		{Name: "b", Loop: &StepLoop{ForEach: []string{"git", "nginx"}}},
+		{Name: "c", Loop: &StepLoop{Until: &StepLoopUntil{ExitCode: &zero}, MaxIterations: 20, DelaySeconds: 5}},
+	}))
+	assert.Error(t, ValidateLoops([]*InstancePluginConfig{{Name: "a", Loop: &StepLoop{}}}))
+	assert.Error(t, ValidateLoops([]*InstancePluginConfig{{Name: "a", Loop: &StepLoop{ForEach: "{{ packages }}"}}}))
+	assert.Error(t, ValidateLoops([]*InstancePluginConfig{{Name: "a", Loop: &StepLoop{ForEach: []interface{}{"git"}, Until: &StepLoopUntil{ExitCode: &zero}}}}))
+	assert.Error(t, ValidateLoops([]*InstancePluginConfig{{Name: "a", Loop: &StepLoop{Until: &StepLoopUntil{}}}}))
+	assert.Error(t, ValidateLoops([]*InstancePluginConfig{{Name: "a", Loop: &StepLoop{Until: &StepLoopUntil{ExitCode: &zero}, MaxIterations: MaxStepIterations + 1}}}))
+}
+
+func TestStepLoopIterations(t *testing.T) {
+	assert.Equal(t, 2, (&StepLoop{ForEach: []interface{}{"a", "b"}}).Iterations())
+	assert.Equal(t, DefaultStepIterations, (&StepLoop{Until: &StepLoopUntil{StdoutContains: "ready"}}).Iterations())
+	assert.Equal(t, 3, (&StepLoop{Until: &StepLoopUntil{StdoutContains: "ready"}, MaxIterations: 3}).Iterations())
+}
@@ -178,18 +178,54 @@ type InstancePluginConfig struct {
 	MaxAttempts   int                 `json:"maxAttempts" yaml:"maxAttempts"`
 	Name          string              `json:"name" yaml:"name"` // unique identifier
 	OnFailure     string              `json:"onFailure" yaml:"onFailure"`
+	OnSuccess     string              `json:"onSuccess" yaml:"onSuccess"`
+	NextStep      string              `json:"nextStep" yaml:"nextStep"`
+	Backoff       *StepBackoff        `json:"backoff" yaml:"backoff"`
+	Loop          *StepLoop           `json:"loop" yaml:"loop"`
+	Parallel      bool                `json:"parallel" yaml:"parallel"`
 	Settings      interface{}         `json:"settings" yaml:"settings"`
 	Timeout       int                 `json:"timeoutSeconds" yaml:"timeoutSeconds"`
 	Preconditions map[string][]string `json:"precondition" yaml:"precondition"`
 }
 
+// ExecutionContext is the context a 2.x document runs its commands in, checked against the
+// ExecutionPolicy of the agent configuration. Empty fields keep the context of the agent.
+type ExecutionContext struct {
+	User            string `json:"user,omitempty" yaml:"user"`
+	Group           string `json:"group,omitempty" yaml:"group"`
+	Umask           string `json:"umask,omitempty" yaml:"umask"` // octal, e.g. 027
+	SELinuxContext  string `json:"selinuxContext,omitempty" yaml:"selinuxContext"`
+	AppArmorProfile string `json:"appArmorProfile,omitempty" yaml:"appArmorProfile"`
+	// EnvironmentPassthrough are the environment variables of the agent passed to the commands,
+	// the commands of a document declaring an executionContext only get a minimal environment besides
+	EnvironmentPassthrough []string `json:"environmentPassthrough,omitempty" yaml:"environmentPassthrough"`
+}
+
 // DocumentContent object which represents ssm document content.
 type DocumentContent struct {
-	SchemaVersion string                   `json:"schemaVersion" yaml:"schemaVersion"`
-	Description   string                   `json:"description" yaml:"description"`
-	RuntimeConfig map[string]*PluginConfig `json:"runtimeConfig" yaml:"runtimeConfig"`
-	MainSteps     []*InstancePluginConfig  `json:"mainSteps" yaml:"mainSteps"`
-	Parameters    map[string]*Parameter    `json:"parameters" yaml:"parameters"`
+	SchemaVersion    string                   `json:"schemaVersion" yaml:"schemaVersion"`
+	Description      string                   `json:"description" yaml:"description"`
+	RuntimeConfig    map[string]*PluginConfig `json:"runtimeConfig" yaml:"runtimeConfig"`
+	MainSteps        []*InstancePluginConfig  `json:"mainSteps" yaml:"mainSteps"`
+	FinallySteps     []*InstancePluginConfig  `json:"finallySteps,omitempty" yaml:"finallySteps"`
+	Parameters       map[string]*Parameter    `json:"parameters" yaml:"parameters"`
+	ExecutionContext *ExecutionContext        `json:"executionContext,omitempty" yaml:"executionContext"`
+	MaxParallelSteps int                      `json:"maxParallelSteps,omitempty" yaml:"maxParallelSteps"`
+	ExecutionTimeout int                      `json:"executionTimeout,omitempty" yaml:"executionTimeout"`
+	Signature        *DocumentSignature       `json:"signature,omitempty" yaml:"signature"`
+
+	// raw is the JSON content of the document, see RawContent
+	raw []byte
+}
+
+// AttachmentContent describes a file attached to a document, as returned with the document by SSM.
+// Url is a presigned URL valid for a limited time, the agent caches the file by its hash.
+type AttachmentContent struct {
+	Name     string `json:"Name"`
+	Size     int64  `json:"Size"`
+	Hash     string `json:"Hash"`
+	HashType string `json:"HashType"`
+	Url      string `json:"Url"`
 }
 
 // AdditionalInfo section in agent response
@@ -211,16 +247,19 @@ type AgentInfo struct {
 
 // PluginRuntimeStatus represents plugin runtime status section in agent response
 type PluginRuntimeStatus struct {
-	Status             ResultStatus `json:"status"`
-	Code               int          `json:"code"`
-	Name               string       `json:"name"`
-	Output             string       `json:"output"`
-	StartDateTime      string       `json:"startDateTime"`
-	EndDateTime        string       `json:"endDateTime"`
-	OutputS3BucketName string       `json:"outputS3BucketName"`
-	OutputS3KeyPrefix  string       `json:"outputS3KeyPrefix"`
-	StandardOutput     string       `json:"standardOutput"`
-	StandardError      string       `json:"standardError"`
+	Status             ResultStatus    `json:"status"`
+	Code               int             `json:"code"`
+	Name               string          `json:"name"`
+	Output             string          `json:"output"`
+	StartDateTime      string          `json:"startDateTime"`
+	EndDateTime        string          `json:"endDateTime"`
+	OutputS3BucketName string          `json:"outputS3BucketName"`
+	OutputS3KeyPrefix  string          `json:"outputS3KeyPrefix"`
+	StandardOutput     string          `json:"standardOutput"`
+	StandardError      string          `json:"standardError"`
+	Attachments        []Attachment    `json:"attachments,omitempty"`
+	Attempts           []StepAttempt   `json:"attempts,omitempty"`
+	Iterations         []StepIteration `json:"iterations,omitempty"`
 }
 
 // AgentConfiguration is a struct that stores information about the agent and instance
@@ -239,4 +278,6 @@ type DocumentResult struct {
 	Status          ResultStatus
 	LastPlugin      string
 	NPlugins        int
+	// TraceParent is the W3C traceparent of the span that produced the result
+	TraceParent string `json:",omitempty"`
 }
@@ -0,0 +1,44 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"fmt"
+)
+
+const (
+	// DefaultMaxParallelSteps is the number of parallel steps run at the same time when the document doesn't
+	// set maxParallelSteps
+	DefaultMaxParallelSteps = 4
+	// MaxParallelSteps is the largest maxParallelSteps of a document
+	MaxParallelSteps = 16
+)
+
+// ValidateParallel checks the maxParallelSteps of a document and its parallel steps, which can't branch: they
+// run at the same time as the steps they would jump over.
+func ValidateParallel(docContent DocumentContent) error {
+	if docContent.MaxParallelSteps < 0 || docContent.MaxParallelSteps > MaxParallelSteps {
+		return fmt.Errorf("maxParallelSteps is %v, it must be between 1 and %v", docContent.MaxParallelSteps, MaxParallelSteps)
+	}
+	for _, step := range docContent.MainSteps {
+		if !step.Parallel {
+			continue
+		}
+		if (step.OnFailure != "" && step.OnFailure != BranchContinue) || (step.OnSuccess != "" && step.OnSuccess != BranchContinue) || step.NextStep != "" {
+			return fmt.Errorf("step %v is parallel, it can't declare onFailure, onSuccess or nextStep", step.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateParallel(t *testing.T) {
+	steps := []*InstancePluginConfig{{Name: "a", Parallel: true, OnFailure: BranchContinue}, {Name: "b", Parallel: true}, {Name: "c", OnFailure: "step:d"}, {Name: "d"}}
+	assert.NoError(t, ValidateParallel(DocumentContent{MainSteps: steps, MaxParallelSteps: 2}))
+	assert.Error(t, ValidateParallel(DocumentContent{MainSteps: steps, MaxParallelSteps: MaxParallelSteps + 1}))
+
+	steps[1].NextStep = "d"
+	assert.Error(t, ValidateParallel(DocumentContent{MainSteps: steps}))
+}
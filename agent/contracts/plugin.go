@@ -40,6 +40,19 @@ type PluginResult struct {
 	Error              error        `json:"-"`
 	StandardOutput     string       `json:"standardOutput"`
 	StandardError      string       `json:"standardError"`
+	Attachments        []Attachment `json:"attachments,omitempty"`
+	// Attempts are the attempts of a step retried with maxAttempts, empty for the steps run once
+	Attempts []StepAttempt `json:"attempts,omitempty"`
+	// Iterations are the iterations of a step repeated with a loop, empty for the steps run once
+	Iterations []StepIteration `json:"iterations,omitempty"`
+}
+
+// Attachment represents a result file registered by a plugin and uploaded next to its output.
+type Attachment struct {
+	Name   string `json:"name"`
+	S3Key  string `json:"s3Key,omitempty"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
 }
 
 // IPlugin is interface for authoring a functionality of work.
@@ -78,6 +91,28 @@ type Configuration struct {
 	Preconditions           map[string][]string
 	IsPreconditionEnabled   bool
 	CurrentAssociations     []string
+	// ExecutionContext is the executionContext of the document, nil when it doesn't declare one
+	ExecutionContext *ExecutionContext
+	// OnFailure, OnSuccess and NextStep are the branching of the step, see StepBranchTarget
+	OnFailure string
+	OnSuccess string
+	NextStep  string
+	// MaxAttempts and Backoff are the retries of the step, see StepBackoff
+	MaxAttempts int
+	Backoff     *StepBackoff
+	// Loop repeats the step, nil when it runs once
+	Loop *StepLoop
+	// Parallel runs the step at the same time as the parallel steps next to it, MaxParallelSteps at a time
+	Parallel         bool
+	MaxParallelSteps int
+	// TimeoutSeconds is the budget of the step, ExecutionTimeoutSeconds the one of its document, 0 for none
+	TimeoutSeconds          int
+	ExecutionTimeoutSeconds int
+	// Finally runs the step after the main steps even when they failed, were cancelled or timed out
+	Finally bool
+	// SecureParameters are the decrypted values of the references ssm-secure:<name> the runCommand input of the
+	// script plugins keeps, by reference, for the plugin to pass in the environment of the commands; never saved
+	SecureParameters map[string]string `json:"-"`
 }
 
 // Plugin wraps the plugin configuration and plugin result.
@@ -0,0 +1,79 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// MaxStepAttempts is the largest maxAttempts of a step
+	MaxStepAttempts = 10
+
+	// defaultBackoffInitialSeconds and defaultBackoffMaxSeconds are the backoff of a step that doesn't declare one
+	defaultBackoffInitialSeconds = 5
+	defaultBackoffMaxSeconds     = 60
+)
+
+// StepBackoff is the delay between the attempts of a step with maxAttempts, doubled after each attempt up to
+// MaxSeconds.
+type StepBackoff struct {
+	InitialSeconds int `json:"initialSeconds" yaml:"initialSeconds"`
+	MaxSeconds     int `json:"maxSeconds" yaml:"maxSeconds"`
+}
+
+// StepAttempt is an attempt of a step retried with maxAttempts, recorded in the result of the step.
+type StepAttempt struct {
+	Attempt       int          `json:"attempt"`
+	Status        ResultStatus `json:"status"`
+	Code          int          `json:"code"`
+	StartDateTime string       `json:"startDateTime"`
+	EndDateTime   string       `json:"endDateTime"`
+	Error         string       `json:"error,omitempty"`
+}
+
+// Delay returns how long to wait after the attempt, counted from 1, before the next one. A nil backoff is the
+// default, 5 seconds doubled up to a minute.
+func (b *StepBackoff) Delay(attempt int) time.Duration {
+	initial, max := defaultBackoffInitialSeconds, defaultBackoffMaxSeconds
+	if b != nil {
+		initial, max = b.InitialSeconds, b.MaxSeconds
+		if max < initial {
+			max = initial
+		}
+	}
+	delay := time.Duration(initial) * time.Second
+	for i := 1; i < attempt && delay < time.Duration(max)*time.Second; i++ {
+		delay *= 2
+	}
+	if delay > time.Duration(max)*time.Second {
+		delay = time.Duration(max) * time.Second
+	}
+	return delay
+}
+
+// ValidateRetries checks the maxAttempts and backoff of the steps of a document.
+func ValidateRetries(steps []*InstancePluginConfig) error {
+	for _, step := range steps {
+		if step.MaxAttempts < 0 || step.MaxAttempts > MaxStepAttempts {
+			return fmt.Errorf("maxAttempts of step %v is %v, it must be between 1 and %v", step.Name, step.MaxAttempts, MaxStepAttempts)
+		}
+		if step.Backoff != nil && (step.Backoff.InitialSeconds < 0 || step.Backoff.MaxSeconds < 0) {
+			return fmt.Errorf("backoff of step %v can't be negative", step.Name)
+		}
+	}
+	return nil
+}
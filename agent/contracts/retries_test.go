@@ -0,0 +1,40 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package contracts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepBackoffDelay(t *testing.T) {
+	var defaultBackoff *StepBackoff
+	assert.Equal(t, 5*time.Second, defaultBackoff.Delay(1))
+	assert.Equal(t, 20*time.Second, defaultBackoff.Delay(3))
+	assert.Equal(t, time.Minute, defaultBackoff.Delay(10))
+
+	backoff := &StepBackoff{InitialSeconds: 2, MaxSeconds: 5}
+	assert.Equal(t, 2*time.Second, backoff.Delay(1))
+	assert.Equal(t, 4*time.Second, backoff.Delay(2))
+	assert.Equal(t, 5*time.Second, backoff.Delay(3))
+	assert.Equal(t, time.Duration(0), (&StepBackoff{}).Delay(4))
+}
+
+func TestValidateRetries(t *testing.T) {
+	assert.NoError(t, ValidateRetries([]*InstancePluginConfig{{Name: "a"}, {Name: "b", MaxAttempts: 3, Backoff: &StepBackoff{InitialSeconds: 1}}}))
+	assert.Error(t, ValidateRetries([]*InstancePluginConfig{{Name: "a", MaxAttempts: MaxStepAttempts + 1}}))
+	assert.Error(t, ValidateRetries([]*InstancePluginConfig{{Name: "a", MaxAttempts: 2, Backoff: &StepBackoff{MaxSeconds: -1}}}))
+}
@@ -0,0 +1,44 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package contracts
+
+import (
+	"encoding/json"
+)
+
+// DocumentSignature is the signature of a document, by one of the keys pinned in the DocumentSigning section of the
+// agent configuration.
+type DocumentSignature struct {
+	// KeyID is the name of the key in DocumentSigning.PublicKeys
+	KeyID string `json:"keyId" yaml:"keyId"`
+	// Value is the base64 encoded signature of the canonical form of the document
+	Value string `json:"value" yaml:"value"`
+}
+
+// UnmarshalJSON parses the document and keeps its JSON content, which its signature is verified against.
+func (docContent *DocumentContent) UnmarshalJSON(data []byte) error {
+	// documentContent doesn't have the methods of DocumentContent, json.Unmarshal parses its fields
+	type documentContent DocumentContent
+	if err := json.Unmarshal(data, (*documentContent)(docContent)); err != nil {
+		return err
+	}
+	docContent.raw = append([]byte(nil), data...)
+	return nil
+}
+
+// RawContent returns the JSON the document was parsed from, before its parameters were replaced; nil when it
+// wasn't parsed from JSON.
+func (docContent DocumentContent) RawContent() []byte {
+	return docContent.raw
+}
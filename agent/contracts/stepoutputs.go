@@ -0,0 +1,82 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/parameters"
+)
+
+// SplitStepReference splits a reference to the output of a step, <step>.<output>, the part of
+// {{ steps.<step>.<output> }} after steps. The step is the longest prefix isStep accepts, the names of the steps
+// may contain dots.
+func SplitStepReference(reference string, isStep func(name string) bool) (step, output string, ok bool) {
+	for i := strings.LastIndex(reference, "."); i > 0; i = strings.LastIndex(reference[:i], ".") {
+		if isStep(reference[:i]) {
+			return reference[:i], reference[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// ValidateStepReferences checks the references of the inputs and settings of the steps to the outputs of other
+// steps refer to steps that run before them: earlier steps, outside the parallel block of the step.
+func ValidateStepReferences(steps []*InstancePluginConfig) error {
+	index := make(map[string]int, len(steps))
+	// block is the first step of the parallel block of a step
+	block := make([]int, len(steps))
+	for i, step := range steps {
+		index[step.Name] = i
+		block[i] = i
+		if i > 0 && step.Parallel && steps[i-1].Parallel {
+			block[i] = block[i-1]
+		}
+	}
+
+	for i, step := range steps {
+		isPrevious := func(name string) bool {
+			j, found := index[name]
+			return found && j < block[i]
+		}
+		references := append(parameters.StepReferences(step.Inputs), parameters.StepReferences(step.Settings)...)
+		for _, reference := range references {
+			if _, _, ok := SplitStepReference(reference, isPrevious); !ok {
+				return fmt.Errorf("step %v refers to {{ steps.%v }}, which isn't the output of a step running before it", step.Name, reference)
+			}
+		}
+	}
+	return nil
+}
+
+// NestedStepsOutput is the output of a step running a document, e.g. aws:runDocument: the results of the steps of
+// the document in order, which the next steps refer to with {{ steps.<step>.steps.<nested step>.<output> }}.
+type NestedStepsOutput struct {
+	Steps []NestedStepResult `json:"steps"`
+}
+
+// NestedStepResult is the result of a step of a document run by a step
+type NestedStepResult struct {
+	Name   string       `json:"name"`
+	Action string       `json:"action"`
+	Status ResultStatus `json:"status"`
+	Code   int          `json:"code"`
+	Error  string       `json:"error,omitempty"`
+	Stdout string       `json:"stdout,omitempty"`
+	Stderr string       `json:"stderr,omitempty"`
+	// Output is the output of the nested step, the results of its own steps when it runs a document too
+	Output interface{} `json:"output,omitempty"`
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStepReference(t *testing.T) {
+	isStep := func(name string) bool { return name == "query" || name == "query.v2" }
+	step, output, ok := SplitStepReference("query.v2.json.items.0", isStep)
+	assert.True(t, ok)
+	assert.Equal(t, "query.v2", step)
+	assert.Equal(t, "json.items.0", output)
+	_, _, ok = SplitStepReference("install.stdout", isStep)
+	assert.False(t, ok)
+}
+
+func TestValidateStepReferences(t *testing.T) {
+	steps := []*InstancePluginConfig{
+		{Name: "query", Inputs: map[string]interface{}{"runCommand": []interface{}{"echo {{ parameter }}"}}},
+		{Name: "a", Parallel: true, Inputs: map[string]interface{}{"id": "{{ steps.query.json.id }}"}},
+		{Name: "b", Parallel: true},
+		{Name: "c", Settings: map[string]interface{}{"value": "{{ steps.b.stdout }}"}},
+	}
+	assert.NoError(t, ValidateStepReferences(steps))
+
+	steps[2].Inputs = map[string]interface{}{"id": "{{ steps.a.stdout }}"}
+	assert.Error(t, ValidateStepReferences(steps))
+	steps[2].Inputs = nil
+	steps[0].Inputs = map[string]interface{}{"id": "{{ steps.c.stdout }}"}
+	assert.Error(t, ValidateStepReferences(steps))
+}
@@ -0,0 +1,33 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"fmt"
+)
+
+// ValidateTimeouts checks the executionTimeout of a document and the timeoutSeconds of its steps, in seconds.
+// A step with a timeout longer than the one of the document still ends at the timeout of the document.
+func ValidateTimeouts(docContent DocumentContent) error {
+	if docContent.ExecutionTimeout < 0 {
+		return fmt.Errorf("executionTimeout is %v, it can't be negative", docContent.ExecutionTimeout)
+	}
+	for _, step := range docContent.MainSteps {
+		if step.Timeout < 0 {
+			return fmt.Errorf("timeoutSeconds of step %v is %v, it can't be negative", step.Name, step.Timeout)
+		}
+	}
+	return nil
+}
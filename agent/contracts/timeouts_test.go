@@ -0,0 +1,29 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTimeouts(t *testing.T) {
+	steps := []*InstancePluginConfig{{Name: "install", Timeout: 600}, {Name: "verify"}}
+	assert.NoError(t, ValidateTimeouts(DocumentContent{MainSteps: steps, ExecutionTimeout: 300}))
+	assert.Error(t, ValidateTimeouts(DocumentContent{MainSteps: steps, ExecutionTimeout: -1}))
+
+	steps[1].Timeout = -5
+	assert.Error(t, ValidateTimeouts(DocumentContent{MainSteps: steps}))
+}
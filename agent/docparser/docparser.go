@@ -78,9 +78,15 @@ func ParseDocument(log log.T,
 	if err = validateSchema(docContent.SchemaVersion); err != nil {
 		return
 	}
+	if err = verifySignature(docContent); err != nil {
+		return
+	}
 	if err = getValidatedParameters(log, params, docContent); err != nil {
 		return
 	}
+	if err = validateExecutionContext(docContent); err != nil {
+		return
+	}
 
 	return parseDocumentContent(*docContent, parserInfo)
 }
@@ -174,6 +180,27 @@ func parsePluginStateForV20Schema(
 	if len(docContent.MainSteps) == 0 {
 		return pluginsInfo, fmt.Errorf("Unsupported schema format")
 	}
+	if err = contracts.ValidateBranching(docContent.MainSteps); err != nil {
+		return pluginsInfo, err
+	}
+	if err = contracts.ValidateRetries(docContent.MainSteps); err != nil {
+		return pluginsInfo, err
+	}
+	if err = contracts.ValidateLoops(docContent.MainSteps); err != nil {
+		return pluginsInfo, err
+	}
+	if err = contracts.ValidateParallel(docContent); err != nil {
+		return pluginsInfo, err
+	}
+	if err = contracts.ValidateTimeouts(docContent); err != nil {
+		return pluginsInfo, err
+	}
+	if err = contracts.ValidateFinallySteps(docContent); err != nil {
+		return pluginsInfo, err
+	}
+	if err = contracts.ValidateStepReferences(docContent.AllSteps()); err != nil {
+		return pluginsInfo, err
+	}
 	//initialize plugin states as array
 	pluginsInfo = []contracts.PluginState{}
 
@@ -181,7 +208,8 @@ func parsePluginStateForV20Schema(
 	isPreconditionEnabled := isPreconditionEnabled(docContent.SchemaVersion)
 
 	// getPluginConfigurations converts from PluginConfig (structure from the MDS message) to plugin.Configuration (structure expected by the plugin)
-	for _, instancePluginConfig := range docContent.MainSteps {
+	// the finally steps run after the main steps
+	for i, instancePluginConfig := range docContent.AllSteps() {
 		pluginName := instancePluginConfig.Action
 		config := contracts.Configuration{
 			Settings:                instancePluginConfig.Settings,
@@ -196,6 +224,18 @@ func parsePluginStateForV20Schema(
 			Preconditions:           instancePluginConfig.Preconditions,
 			IsPreconditionEnabled:   isPreconditionEnabled,
 			DefaultWorkingDirectory: defaultWorkingDir,
+			ExecutionContext:        docContent.ExecutionContext,
+			OnFailure:               instancePluginConfig.OnFailure,
+			OnSuccess:               instancePluginConfig.OnSuccess,
+			NextStep:                instancePluginConfig.NextStep,
+			MaxAttempts:             instancePluginConfig.MaxAttempts,
+			Backoff:                 instancePluginConfig.Backoff,
+			Loop:                    instancePluginConfig.Loop,
+			Parallel:                instancePluginConfig.Parallel,
+			MaxParallelSteps:        docContent.MaxParallelSteps,
+			TimeoutSeconds:          instancePluginConfig.Timeout,
+			ExecutionTimeoutSeconds: docContent.ExecutionTimeout,
+			Finally:                 i >= len(docContent.MainSteps),
 		}
 
 		var plugin contracts.PluginState
@@ -276,27 +316,44 @@ func replaceValidatedPluginParameters(
 
 	mainSteps := docContent.MainSteps
 	if mainSteps != nil || len(mainSteps) != 0 {
-		updatedMainSteps := make([]*contracts.InstancePluginConfig, len(mainSteps))
-		for index, instancePluginConfig := range mainSteps {
-			updatedMainSteps[index] = instancePluginConfig
-			updatedMainSteps[index].Settings = parameters.ReplaceParameters(instancePluginConfig.Settings, params, logger)
-			updatedMainSteps[index].Inputs = parameters.ReplaceParameters(instancePluginConfig.Inputs, params, logger)
+		if docContent.MainSteps, err = replaceStepParameters(mainSteps, params, logger); err != nil {
+			return err
+		}
+		docContent.FinallySteps, err = replaceStepParameters(docContent.FinallySteps, params, logger)
+		return err
+	}
+	return nil
+}
 
-			logger.Debug("Resolving SSM parameters")
-			// Resolves SSM parameters
-			if updatedMainSteps[index].Settings, err = parameterstore.Resolve(logger, updatedMainSteps[index].Settings); err != nil {
-				return err
-			}
+// replaceStepParameters replaces parameters with their values, within the inputs and settings of the steps.
+func replaceStepParameters(
+	steps []*contracts.InstancePluginConfig,
+	params map[string]interface{},
+	logger log.T) (updatedSteps []*contracts.InstancePluginConfig, err error) {
+	if steps == nil {
+		return nil, nil
+	}
+	updatedSteps = make([]*contracts.InstancePluginConfig, len(steps))
+	for index, instancePluginConfig := range steps {
+		updatedSteps[index] = instancePluginConfig
+		updatedSteps[index].Settings = parameters.ReplaceParameters(instancePluginConfig.Settings, params, logger)
+		updatedSteps[index].Inputs = parameters.ReplaceParameters(instancePluginConfig.Inputs, params, logger)
+		if loop := instancePluginConfig.Loop; loop != nil {
+			loop.ForEach = parameters.ReplaceParameters(loop.ForEach, params, logger)
+		}
 
-			// Resolves SSM parameters
-			if updatedMainSteps[index].Inputs, err = parameterstore.Resolve(logger, updatedMainSteps[index].Inputs); err != nil {
-				return err
-			}
+		logger.Debug("Resolving SSM parameters")
+		// Resolves SSM parameters
+		if updatedSteps[index].Settings, err = parameterstore.Resolve(logger, updatedSteps[index].Settings); err != nil {
+			return
+		}
+
+		// Resolves SSM parameters
+		if updatedSteps[index].Inputs, err = parameterstore.Resolve(logger, updatedSteps[index].Inputs); err != nil {
+			return
 		}
-		docContent.MainSteps = updatedMainSteps
-		return nil
 	}
-	return nil
+	return
 }
 
 // isPreConditionEnabled checks if precondition support is enabled by checking document schema version
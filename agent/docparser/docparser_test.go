@@ -400,3 +400,28 @@ func loadMessageFromFile(t *testing.T, fileName string) (testDocContent contract
 	}
 	return testDocContent, params
 }
+
+func TestParseDocument_FinallySteps(t *testing.T) {
+	testParserInfo := DocumentParserInfo{
+		OrchestrationDir:  testOrchDir,
+		S3Bucket:          testS3Bucket,
+		S3Prefix:          testS3Prefix,
+		MessageId:         testMessageID,
+		DocumentId:        testDocumentID,
+		DefaultWorkingDir: testWorkingDir,
+	}
+	const finallyDocument = `{"schemaVersion":"2.2","parameters":{"flag":{"type":"String","default":"/tmp/maintenance"}},` +
+		`"mainSteps":[{"action":"aws:runShellScript","name":"deploy","onFailure":"Abort","inputs":{"runCommand":["touch {{ flag }}"]}}],` +
+		`"finallySteps":[{"action":"aws:runShellScript","name":"cleanup","inputs":{"runCommand":["rm -f {{ flag }}"]}}]}`
+
+	var testDocContent contracts.DocumentContent
+	assert.NoError(t, json.Unmarshal([]byte(finallyDocument), &testDocContent))
+
+	pluginsInfo, err := ParseDocument(log.NewMockLog(), &testDocContent, testParserInfo, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(pluginsInfo))
+	assert.False(t, pluginsInfo[0].Configuration.Finally)
+	assert.Equal(t, "cleanup", pluginsInfo[1].Id)
+	assert.True(t, pluginsInfo[1].Configuration.Finally)
+	assert.Equal(t, map[string]interface{}{"runCommand": []interface{}{"rm -f /tmp/maintenance"}}, pluginsInfo[1].Configuration.Properties)
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docparser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+// executionPolicy returns the ExecutionPolicy of the agent configuration
+var executionPolicy = func() (appconfig.ExecutionPolicyCfg, error) {
+	config, err := appconfig.Config(false)
+	return config.ExecutionPolicy, err
+}
+
+// validateExecutionContext checks the executionContext of the document against the ExecutionPolicy of the
+// agent configuration. Only 2.x documents can declare one.
+func validateExecutionContext(docContent *contracts.DocumentContent) error {
+	context := docContent.ExecutionContext
+	if context == nil {
+		return nil
+	}
+	if strings.HasPrefix(docContent.SchemaVersion, "1.") {
+		return fmt.Errorf("executionContext is not supported by documents with schema version %v, use schema version 2.0 or later", docContent.SchemaVersion)
+	}
+	policy, err := executionPolicy()
+	if err != nil {
+		return fmt.Errorf("failed to load the execution policy: %v", err)
+	}
+
+	checks := []struct {
+		name, value, setting, allowed string
+	}{
+		{"user", context.User, "AllowedUsers", policy.AllowedUsers},
+		{"group", context.Group, "AllowedGroups", policy.AllowedGroups},
+		{"SELinux context", context.SELinuxContext, "AllowedSELinuxContexts", policy.AllowedSELinuxContexts},
+		{"AppArmor profile", context.AppArmorProfile, "AllowedAppArmorProfiles", policy.AllowedAppArmorProfiles},
+	}
+	for _, check := range checks {
+		if check.value != "" && !appconfig.Allowed(check.allowed, check.value) {
+			return fmt.Errorf("executionContext: %v %v is not allowed by ExecutionPolicy.%v of the agent configuration", check.name, check.value, check.setting)
+		}
+	}
+	for _, name := range context.EnvironmentPassthrough {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf("executionContext: invalid environment variable name %q", name)
+		}
+		if !appconfig.Allowed(policy.AllowedEnvironment, name) {
+			return fmt.Errorf("executionContext: environment variable %v is not allowed by ExecutionPolicy.AllowedEnvironment of the agent configuration", name)
+		}
+	}
+	if context.Umask != "" {
+		umask, err := appconfig.ParseUmask(context.Umask)
+		if err != nil {
+			return fmt.Errorf("executionContext: %v", err)
+		}
+		minUmask, err := appconfig.ParseUmask(policy.MinUmask)
+		if err != nil {
+			minUmask, _ = appconfig.ParseUmask(appconfig.DefaultExecutionPolicyMinUmask)
+		}
+		if umask&minUmask != minUmask {
+			return fmt.Errorf("executionContext: umask %v doesn't include ExecutionPolicy.MinUmask %v of the agent configuration", context.Umask, policy.MinUmask)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docparser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const documentWithExecutionContext = `{
+	"schemaVersion": "2.2",
+	"executionContext": {
+		"user": "builder",
+		"group": "builders",
+		"umask": "027",
+		"environmentPassthrough": ["HTTP_PROXY"]
+	},
+	"mainSteps": [{"action": "aws:runShellScript", "name": "build", "inputs": {"runCommand": ["make"]}}]
+}`
+
+func withExecutionPolicy(policy appconfig.ExecutionPolicyCfg) func() {
+	saved := executionPolicy
+	executionPolicy = func() (appconfig.ExecutionPolicyCfg, error) { return policy, nil }
+	return func() { executionPolicy = saved }
+}
+
+func TestParseDocument_ExecutionContext(t *testing.T) {
+	defer withExecutionPolicy(appconfig.ExecutionPolicyCfg{
+		AllowedUsers:       "ssm-agent-worker,builder",
+		AllowedGroups:      "*",
+		AllowedEnvironment: "HTTP_PROXY,HTTPS_PROXY",
+		MinUmask:           "022",
+	})()
+
+	var docContent contracts.DocumentContent
+	assert.NoError(t, json.Unmarshal([]byte(documentWithExecutionContext), &docContent))
+	pluginsInfo, err := ParseDocument(log.NewMockLog(), &docContent, DocumentParserInfo{OrchestrationDir: testOrchDir}, nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, pluginsInfo, 1)
+	context := pluginsInfo[0].Configuration.ExecutionContext
+	assert.NotNil(t, context)
+	assert.Equal(t, "builder", context.User)
+	assert.Equal(t, "builders", context.Group)
+	assert.Equal(t, "027", context.Umask)
+	assert.Equal(t, []string{"HTTP_PROXY"}, context.EnvironmentPassthrough)
+}
+
+func TestValidateExecutionContext(t *testing.T) {
+	defer withExecutionPolicy(appconfig.ExecutionPolicyCfg{
+		AllowedUsers:           "ssm-agent-worker",
+		AllowedSELinuxContexts: "system_u:system_r:unconfined_t:s0",
+		AllowedEnvironment:     "HTTP_PROXY",
+		MinUmask:               "022",
+	})()
+
+	valid := []contracts.ExecutionContext{
+		{},
+		{User: "ssm-agent-worker", Umask: "077"},
+		{SELinuxContext: "system_u:system_r:unconfined_t:s0", EnvironmentPassthrough: []string{"HTTP_PROXY"}},
+	}
+	for _, context := range valid {
+		context := context
+		assert.NoError(t, validateExecutionContext(&contracts.DocumentContent{SchemaVersion: "2.2", ExecutionContext: &context}), "%+v", context)
+	}
+
+	invalid := map[string]contracts.ExecutionContext{
+		"user root is not allowed":                   {User: "root"},
+		"group wheel is not allowed":                 {Group: "wheel"},
+		"AppArmor profile unconfined is not allowed": {AppArmorProfile: "unconfined"},
+		"environment variable AWS_SECRET_ACCESS_KEY": {EnvironmentPassthrough: []string{"AWS_SECRET_ACCESS_KEY"}},
+		"invalid environment variable name":          {EnvironmentPassthrough: []string{"PATH=/tmp"}},
+		"doesn't include ExecutionPolicy.MinUmask":   {Umask: "002"},
+		"invalid umask":                              {Umask: "9"},
+	}
+	for message, context := range invalid {
+		context := context
+		err := validateExecutionContext(&contracts.DocumentContent{SchemaVersion: "2.2", ExecutionContext: &context})
+		if assert.Error(t, err, message) {
+			assert.Contains(t, err.Error(), message)
+		}
+	}
+
+	err := validateExecutionContext(&contracts.DocumentContent{SchemaVersion: "1.2", ExecutionContext: &contracts.ExecutionContext{}})
+	assert.Error(t, err, "the 1.x documents cannot declare an executionContext")
+}
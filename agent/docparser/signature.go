@@ -0,0 +1,174 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docparser
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+// signatureField is the field of the document holding its signature, which isn't part of the signed content
+const signatureField = "signature"
+
+// documentSigning returns the DocumentSigning section of the agent configuration
+var documentSigning = func() (appconfig.DocumentSigningCfg, error) {
+	config, err := appconfig.Config(false)
+	return config.DocumentSigning, err
+}
+
+// readPublicKey reads the PEM file of a pinned public key
+var readPublicKey = ioutil.ReadFile
+
+// verifySignature checks the signature of the document against the keys pinned in the DocumentSigning section of
+// the agent configuration. When DocumentSigning.Enforce is set, unsigned documents and documents whose content
+// doesn't match their signature are refused.
+func verifySignature(docContent *contracts.DocumentContent) error {
+	policy, err := documentSigning()
+	if err != nil {
+		return fmt.Errorf("failed to load the document signing policy: %v", err)
+	}
+	if !policy.Enforce {
+		return nil
+	}
+	signature := docContent.Signature
+	if signature == nil || signature.Value == "" {
+		return fmt.Errorf("the document isn't signed, DocumentSigning.Enforce of the agent configuration requires a signature")
+	}
+	raw := docContent.RawContent()
+	if raw == nil {
+		return fmt.Errorf("the signature of the document can't be verified, signed documents must be JSON")
+	}
+	path, found := policy.PublicKeys[signature.KeyID]
+	if !found {
+		return fmt.Errorf("the document is signed by key %v, which isn't in DocumentSigning.PublicKeys of the agent configuration", signature.KeyID)
+	}
+	publicKey, err := loadPublicKey(path)
+	if err != nil {
+		return fmt.Errorf("failed to load the public key %v: %v", signature.KeyID, err)
+	}
+	value, err := base64.StdEncoding.DecodeString(signature.Value)
+	if err != nil {
+		return fmt.Errorf("invalid document signature: %v", err)
+	}
+	content, err := canonicalDocument(raw)
+	if err != nil {
+		return fmt.Errorf("invalid document content: %v", err)
+	}
+	if !verify(publicKey, content, value) {
+		return fmt.Errorf("the signature of the document by key %v doesn't match its content", signature.KeyID)
+	}
+	return nil
+}
+
+// SignDocument signs the JSON content of a document and returns it with its signature.
+func SignDocument(raw []byte, keyID string, key crypto.Signer) (signed []byte, err error) {
+	content, err := canonicalDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+	var value []byte
+	switch key.Public().(type) {
+	case ed25519.PublicKey:
+		value, err = key.Sign(rand.Reader, content, crypto.Hash(0))
+	default:
+		digest := sha256.Sum256(content)
+		value, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	if err = decodeDocument(raw, &document); err != nil {
+		return nil, err
+	}
+	document[signatureField] = contracts.DocumentSignature{
+		KeyID: keyID,
+		Value: base64.StdEncoding.EncodeToString(value),
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// canonicalDocument returns the content of the document that is signed: its JSON without the signature, with its
+// keys sorted and without insignificant whitespace.
+func canonicalDocument(raw []byte) ([]byte, error) {
+	var document map[string]interface{}
+	if err := decodeDocument(raw, &document); err != nil {
+		return nil, err
+	}
+	delete(document, signatureField)
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// decodeDocument decodes the JSON of a document, keeping its numbers as they're written
+func decodeDocument(raw []byte, document *map[string]interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(document); err != nil {
+		return err
+	}
+	if *document == nil {
+		return fmt.Errorf("the document isn't a JSON object")
+	}
+	return nil
+}
+
+// loadPublicKey parses the PEM encoded PKIX public key of a file
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	content, err := readPublicKey(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%v isn't a PEM file", path)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// verify checks a signature of content, RSA PKCS #1 v1.5 and ECDSA signatures are over its SHA-256 digest
+func verify(publicKey crypto.PublicKey, content, signature []byte) bool {
+	digest := sha256.Sum256(content)
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, content, signature)
+	default:
+		return false
+	}
+}
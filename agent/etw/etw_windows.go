@@ -0,0 +1,88 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package etw
+
+import (
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Windows APIs
+var (
+	advapi32             = syscall.NewLazyDLL("advapi32.dll")
+	procEventRegister    = advapi32.NewProc("EventRegister")
+	procEventWriteString = advapi32.NewProc("EventWriteString")
+)
+
+// guid mirrors the layout of the Windows GUID structure
+type guid struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+// providerID is ProviderGUID in its binary form
+var providerID = guid{
+	Data1: 0xB32C5A25,
+	Data2: 0x8F0C,
+	Data3: 0x4A8A,
+	Data4: [8]byte{0x9A, 0x74, 0x36, 0xC0, 0xF1, 0xE1, 0xE2, 0xD7},
+}
+
+var (
+	registerOnce sync.Once
+	regHandle    uint64
+	registered   bool
+)
+
+// register registers the agent provider with ETW, the registration lasts for the lifetime of the process
+func register() {
+	if err := procEventRegister.Find(); err != nil {
+		return
+	}
+	ret, _, _ := procEventRegister.Call(
+		uintptr(unsafe.Pointer(&providerID)),
+		0,
+		0,
+		uintptr(unsafe.Pointer(&regHandle)))
+	registered = ret == 0
+}
+
+// write emits the message as an ETW string event of the agent provider
+func write(level Level, message string) {
+	registerOnce.Do(register)
+	if !registered {
+		return
+	}
+	text, err := syscall.UTF16PtrFromString(message)
+	if err != nil {
+		return
+	}
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		procEventWriteString.Call(uintptr(regHandle), uintptr(level), 0, uintptr(unsafe.Pointer(text)))
+	} else {
+		// 64 bit arguments are passed as two 32 bit halves on 32 bit Windows
+		procEventWriteString.Call(
+			uintptr(regHandle),
+			uintptr(regHandle>>32),
+			uintptr(level),
+			0,
+			0,
+			uintptr(unsafe.Pointer(text)))
+	}
+}
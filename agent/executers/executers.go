@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
@@ -48,6 +49,44 @@ type T interface {
 
 // ShellCommandExecuter is specially added for testing purposes
 type ShellCommandExecuter struct {
+	// RunAsUser is the user the commands run as, the user of the agent when empty
+	RunAsUser string
+	// ExecutionContext is the executionContext of the document the commands belong to, its user is
+	// applied with RunAs
+	ExecutionContext *contracts.ExecutionContext
+	// Environment are the variables added to the environment of the commands, e.g. the values the commands
+	// take other than in their text
+	Environment []string
+}
+
+// RunAs returns the executer running the commands as the given user, the user of the agent when empty.
+// Executers other than ShellCommandExecuter, e.g. the mocks of the tests, are returned unchanged.
+func RunAs(executer T, runAsUser string) T {
+	if shell, ok := executer.(ShellCommandExecuter); ok {
+		shell.RunAsUser = runAsUser
+		return shell
+	}
+	return executer
+}
+
+// InContext returns the executer running the commands in the executionContext of a document, nil for the
+// context of the agent. Executers other than ShellCommandExecuter are returned unchanged.
+func InContext(executer T, executionContext *contracts.ExecutionContext) T {
+	if shell, ok := executer.(ShellCommandExecuter); ok {
+		shell.ExecutionContext = executionContext
+		return shell
+	}
+	return executer
+}
+
+// WithEnvironment returns the executer adding the variables, NAME=value, to the environment of the commands.
+// Executers other than ShellCommandExecuter are returned unchanged.
+func WithEnvironment(executer T, environment []string) T {
+	if shell, ok := executer.(ShellCommandExecuter); ok {
+		shell.Environment = environment
+		return shell
+	}
+	return executer
 }
 
 type timeoutSignal struct {
@@ -70,7 +109,7 @@ type timeoutSignal struct {
 // For byte buffer output, the reader will be a reader over the buffer, which will accumulate the entire output.  Be careful
 // not to use the byte buffer approach for extremely large output (or unknown output) because it could take up a large amount
 // of memory.
-func (ShellCommandExecuter) Execute(
+func (e ShellCommandExecuter) Execute(
 	log log.T,
 	workingDir string,
 	stdoutFilePath string,
@@ -122,7 +161,7 @@ func (ShellCommandExecuter) Execute(
 	// writers as long as it is after the process starts.
 
 	var err error
-	exitCode, err = ExecuteCommand(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments)
+	exitCode, err = executeCommand(log, cancelFlag, e.RunAsUser, e.ExecutionContext, e.Environment, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments)
 	if err != nil {
 		errs = append(errs, err)
 	}
@@ -159,7 +198,7 @@ func (ShellCommandExecuter) Execute(
 }
 
 // NewExecute executes a list of shell commands in the given working directory and provides the stdout and stderr writers.
-func (ShellCommandExecuter) NewExecute(
+func (e ShellCommandExecuter) NewExecute(
 	log log.T,
 	workingDir string,
 	stdoutWriter io.Writer,
@@ -169,7 +208,7 @@ func (ShellCommandExecuter) NewExecute(
 	commandName string,
 	commandArguments []string,
 ) (exitCode int, err error) {
-	exitCode, err = ExecuteCommand(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments)
+	exitCode, err = executeCommand(log, cancelFlag, e.RunAsUser, e.ExecutionContext, e.Environment, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments)
 	return
 }
 
@@ -179,7 +218,7 @@ func (ShellCommandExecuter) NewExecute(
 // even though some errors are reported. For example, if the command got killed while executing,
 // the streams will have whatever data was printed up to the kill point, and the errors will
 // indicate that the process got terminated.
-func (ShellCommandExecuter) StartExe(
+func (e ShellCommandExecuter) StartExe(
 	log log.T,
 	workingDir string,
 	stdoutWriter io.Writer,
@@ -188,7 +227,7 @@ func (ShellCommandExecuter) StartExe(
 	commandName string,
 	commandArguments []string,
 ) (process *os.Process, exitCode int, err error) {
-	process, exitCode, err = StartCommand(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, commandName, commandArguments)
+	process, exitCode, err = startCommand(log, cancelFlag, e.RunAsUser, e.ExecutionContext, e.Environment, workingDir, stdoutWriter, stderrWriter, commandName, commandArguments)
 	return
 }
 
@@ -254,6 +293,23 @@ func ExecuteCommand(log log.T,
 	commandName string,
 	commandArguments []string,
 ) (exitCode int, err error) {
+	return executeCommand(log, cancelFlag, "", nil, nil, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments)
+}
+
+// executeCommand executes the given commands as runAsUser, the user of the agent when empty,
+// in the given executionContext, the context of the agent when nil, with the environment variables added.
+func executeCommand(log log.T,
+	cancelFlag task.CancelFlag,
+	runAsUser string,
+	executionContext *contracts.ExecutionContext,
+	environment []string,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+) (exitCode int, err error) {
 
 	stdoutInterruptable, stopStdout := newWriter(stdoutWriter)
 	stderrInterruptable, stopStderr := newWriter(stderrWriter)
@@ -285,6 +341,19 @@ func ExecuteCommand(log log.T,
 	// configure environment variables
 	prepareEnvironment(command)
 
+	if err = runAs(command, runAsUser); err != nil {
+		log.Errorf("unable to run the command as %v: %v", runAsUser, err)
+		exitCode = 1
+		return
+	}
+
+	if err = applyExecutionContext(command, executionContext); err != nil {
+		log.Errorf("unable to run the command in the execution context of the document: %v", err)
+		exitCode = 1
+		return
+	}
+	command.Env = append(command.Env, environment...)
+
 	log.Debug()
 	log.Debugf("Running in directory %v, command: %v %v", workingDir, commandName, commandArguments)
 	log.Debug()
@@ -336,7 +405,7 @@ func ExecuteCommand(log log.T,
 			// set appropriate exit code based on cancel
 			exitCode = appconfig.CommandStoppedPreemptivelyExitCode
 			err = &exec.ExitError{Stderr: []byte("Cancelled process")}
-			log.Infof("The execution of command was cancelled.")
+			log.Infof("The execution of command was cancelled (%v).", cancelFlag.Reason())
 		}
 	case err = <-done:
 		log.Debug("Process completed.")
@@ -389,6 +458,22 @@ func StartCommand(log log.T,
 	commandName string,
 	commandArguments []string,
 ) (process *os.Process, exitCode int, err error) {
+	return startCommand(log, cancelFlag, "", nil, nil, workingDir, stdoutWriter, stderrWriter, commandName, commandArguments)
+}
+
+// startCommand starts the given commands as runAsUser, the user of the agent when empty,
+// in the given executionContext, the context of the agent when nil, with the environment variables added.
+func startCommand(log log.T,
+	cancelFlag task.CancelFlag,
+	runAsUser string,
+	executionContext *contracts.ExecutionContext,
+	environment []string,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	commandName string,
+	commandArguments []string,
+) (process *os.Process, exitCode int, err error) {
 
 	command := exec.Command(commandName, commandArguments...)
 	command.Dir = workingDir
@@ -402,6 +487,19 @@ func StartCommand(log log.T,
 	// configure environment variables
 	prepareEnvironment(command)
 
+	if err = runAs(command, runAsUser); err != nil {
+		log.Errorf("unable to run the command as %v: %v", runAsUser, err)
+		exitCode = 1
+		return
+	}
+
+	if err = applyExecutionContext(command, executionContext); err != nil {
+		log.Errorf("unable to run the command in the execution context of the document: %v", err)
+		exitCode = 1
+		return
+	}
+	command.Env = append(command.Env, environment...)
+
 	log.Debug()
 	log.Debugf("Running in directory %v, command: %v %v", workingDir, commandName, commandArguments)
 	log.Debug()
@@ -460,6 +558,27 @@ func prepareEnvironment(command *exec.Cmd) {
 	validateEnvironmentVariables(command)
 }
 
+// filterEnvironment keeps the variables of baseEnvironment, the AWS_SSM_ variables set by the agent and the
+// passthrough variables.
+func filterEnvironment(env []string, passthrough []string) []string {
+	keep := append(append([]string{}, baseEnvironment...), passthrough...)
+	filtered := []string{}
+	for _, variable := range env {
+		name := strings.SplitN(variable, "=", 2)[0]
+		if strings.HasPrefix(name, "AWS_SSM_") {
+			filtered = append(filtered, variable)
+			continue
+		}
+		for _, kept := range keep {
+			if sameEnvVariable(name, kept) {
+				filtered = append(filtered, variable)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
 // fmtEnvVariable creates the string to append to the current set of environment variables.
 func fmtEnvVariable(name string, val string) string {
 	return fmt.Sprintf("%s=%s", name, val)
@@ -0,0 +1,27 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build netbsd openbsd
+
+package executers
+
+import (
+	"fmt"
+	"os/user"
+	"runtime"
+)
+
+// setACL fails, the filesystems have no ACLs to give the user access without changing the permissions of others
+func setACL(account *user.User, access aclAccess, paths []string) error {
+	return fmt.Errorf("ACLs aren't supported on %v, set Agent.RunAsUser to an empty string", runtime.GOOS)
+}
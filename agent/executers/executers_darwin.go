@@ -0,0 +1,39 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin
+
+package executers
+
+import (
+	"fmt"
+	"os/user"
+)
+
+// chmodPermissions are the permissions of the ACL entries given by chmod, on macOS read, write, append and execute
+// are also list, add_file, add_subdirectory and search for directories
+var chmodPermissions = map[aclAccess]string{
+	aclTraverse:   "execute",
+	aclDirectory:  "read,write,append,execute,delete_child",
+	aclFile:       "read,write,append",
+	aclExecutable: "read,write,append,execute",
+}
+
+// setACL adds the ACL entry of the user on the paths with chmod, after removing the same entry so that granting
+// access again doesn't add duplicates
+func setACL(account *user.User, access aclAccess, paths []string) error {
+	entry := fmt.Sprintf("%v allow %v", account.Username, chmodPermissions[access])
+	// the removal fails when a path has no such entry
+	runACLCommand(append([]string{"chmod", "-h", "-a", entry}, paths...))
+	return runACLCommand(append([]string{"chmod", "-h", "+a", entry}, paths...))
+}
@@ -0,0 +1,35 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build freebsd linux
+
+package executers
+
+import (
+	"fmt"
+	"os/user"
+)
+
+// setfaclPermissions are the permissions of the ACL entries given by setfacl
+var setfaclPermissions = map[aclAccess]string{
+	aclTraverse:   "x",
+	aclDirectory:  "rwx",
+	aclFile:       "rw",
+	aclExecutable: "rwx",
+}
+
+// setACL adds, or replaces, the ACL entry of the user on the paths with setfacl
+func setACL(account *user.User, access aclAccess, paths []string) error {
+	entry := fmt.Sprintf("u:%v:%v", account.Uid, setfaclPermissions[access])
+	return runACLCommand(append([]string{"setfacl", "-m", entry, "--"}, paths...))
+}
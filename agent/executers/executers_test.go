@@ -21,6 +21,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -132,3 +133,26 @@ func TestQuotePsStringWithQuotes(t *testing.T) {
 	result = QuotePsString("`abc`")
 	assert.Equal(t, "\"``abc``\"", result)
 }
+
+func TestRunAs(t *testing.T) {
+	executer := RunAs(ShellCommandExecuter{}, "ssm-agent-worker")
+	assert.Equal(t, ShellCommandExecuter{RunAsUser: "ssm-agent-worker"}, executer)
+
+	mockExecuter := new(MockCommandExecuter)
+	assert.Equal(t, mockExecuter, RunAs(mockExecuter, "ssm-agent-worker"), "other executers are unchanged")
+}
+
+func TestInContext(t *testing.T) {
+	executionContext := &contracts.ExecutionContext{Umask: "027"}
+	executer := RunAs(InContext(ShellCommandExecuter{}, executionContext), "builder")
+	assert.Equal(t, ShellCommandExecuter{RunAsUser: "builder", ExecutionContext: executionContext}, executer)
+
+	mockExecuter := new(MockCommandExecuter)
+	assert.Equal(t, mockExecuter, InContext(mockExecuter, executionContext), "other executers are unchanged")
+}
+
+func TestFilterEnvironment(t *testing.T) {
+	env := []string{"PATH=/usr/bin", "AWS_SSM_INSTANCE_ID=i-1234", "HTTP_PROXY=http://proxy", "AWS_SECRET_ACCESS_KEY=secret"}
+	assert.Equal(t, []string{"PATH=/usr/bin", "AWS_SSM_INSTANCE_ID=i-1234", "HTTP_PROXY=http://proxy"}, filterEnvironment(env, []string{"HTTP_PROXY"}))
+	assert.Equal(t, []string{"PATH=/usr/bin", "AWS_SSM_INSTANCE_ID=i-1234"}, filterEnvironment(env, nil))
+}
@@ -16,20 +16,225 @@
 package executers
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 )
 
+// baseEnvironment are the variables of the agent kept in the environment of the commands of a document
+// declaring an executionContext, HOME, USER and LOGNAME are those of the user the commands run as
+var baseEnvironment = []string{"PATH", "LANG", "LANGUAGE", "LC_ALL", "LC_CTYPE", "TZ", "HOME", "USER", "LOGNAME"}
+
 func prepareProcess(command *exec.Cmd) {
 	// make the process the leader of its process group
 	// (otherwise we cannot kill it properly)
 	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 }
 
+// lookupUser returns the account of the user the commands run as
+var lookupUser = user.Lookup
+
+// lookupGroup returns the group the commands run as
+var lookupGroup = user.LookupGroup
+
+// lookPath returns the path of the programs applying the SELinux context and the AppArmor profile
+var lookPath = exec.LookPath
+
+// runAs makes the command run as runAsUser, with its groups and its home directory, unless runAsUser is empty.
+func runAs(command *exec.Cmd, runAsUser string) error {
+	if runAsUser == "" {
+		return nil
+	}
+	account, credential, err := lookupCredential(runAsUser)
+	if err != nil {
+		return err
+	}
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Credential = credential
+	command.Env = append(command.Env,
+		fmtEnvVariable("HOME", account.HomeDir),
+		fmtEnvVariable("USER", account.Username),
+		fmtEnvVariable("LOGNAME", account.Username))
+	return nil
+}
+
+// applyExecutionContext sets the group of the command, filters its environment and wraps it to set its umask,
+// its SELinux context and its AppArmor profile. The user of the executionContext is applied by runAs.
+func applyExecutionContext(command *exec.Cmd, executionContext *contracts.ExecutionContext) error {
+	if executionContext == nil {
+		return nil
+	}
+	if executionContext.Group != "" {
+		group, err := lookupGroup(executionContext.Group)
+		if err != nil {
+			return fmt.Errorf("group %v not found: %v", executionContext.Group, err)
+		}
+		gid, err := strconv.ParseUint(group.Gid, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid gid %v of group %v", group.Gid, executionContext.Group)
+		}
+		if command.SysProcAttr == nil {
+			command.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		if command.SysProcAttr.Credential == nil {
+			command.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(os.Getuid())}
+		}
+		command.SysProcAttr.Credential.Gid = uint32(gid)
+	}
+	command.Env = filterEnvironment(command.Env, executionContext.EnvironmentPassthrough)
+
+	// the wrappers run innermost first: aa-exec confines the command, runcon runs aa-exec in the SELinux
+	// context and the shell sets the umask inherited by both
+	args := append([]string{command.Path}, command.Args[1:]...)
+	if executionContext.AppArmorProfile != "" {
+		args = append([]string{"aa-exec", "-p", executionContext.AppArmorProfile, "--"}, args...)
+	}
+	if executionContext.SELinuxContext != "" {
+		args = append([]string{"runcon", executionContext.SELinuxContext}, args...)
+	}
+	if executionContext.Umask != "" {
+		args = append([]string{"/bin/sh", "-c", `umask "$0" && exec "$@"`, executionContext.Umask}, args...)
+	}
+	if len(args) == len(command.Args) {
+		return nil
+	}
+	path, err := lookPath(args[0])
+	if err != nil {
+		return fmt.Errorf("%v is required by the execution context of the document: %v", args[0], err)
+	}
+	command.Path = path
+	command.Args = args
+	return nil
+}
+
+// sameEnvVariable returns whether the names are of the same environment variable, they are case sensitive
+func sameEnvVariable(name string, other string) bool {
+	return name == other
+}
+
+// lookupCredential returns the account of the user and the credential of the processes running as the user
+func lookupCredential(runAsUser string) (*user.User, *syscall.Credential, error) {
+	account, err := lookupUser(runAsUser)
+	if err != nil {
+		return nil, nil, fmt.Errorf("user %v not found, create it or change Agent.RunAsUser: %v", runAsUser, err)
+	}
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid uid %v of user %v", account.Uid, runAsUser)
+	}
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid gid %v of user %v", account.Gid, runAsUser)
+	}
+	credential := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	if groupIds, err := account.GroupIds(); err == nil {
+		for _, groupId := range groupIds {
+			if group, err := strconv.ParseUint(groupId, 10, 32); err == nil {
+				credential.Groups = append(credential.Groups, uint32(group))
+			}
+		}
+	}
+	return account, credential, nil
+}
+
+// aclAccess is the access an ACL entry gives the user the commands run as
+type aclAccess int
+
+const (
+	// aclTraverse lets the user traverse a directory, without listing it
+	aclTraverse aclAccess = iota
+	// aclDirectory lets the user list and modify a directory
+	aclDirectory
+	// aclFile lets the user read and write a file
+	aclFile
+	// aclExecutable lets the user read, write and execute a file
+	aclExecutable
+)
+
+// aclBatchSize is the number of paths of a command setting their ACL
+const aclBatchSize = 100
+
+// runACLCommand runs a command setting ACLs
+var runACLCommand = defaultRunACLCommand
+
+func defaultRunACLCommand(args []string) error {
+	if output, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %v %v", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// GrantAccess gives runAsUser access to the directories, e.g. the orchestration directory holding the script of a
+// plugin, with an ACL entry for the user on each of their files and directories; the links aren't followed. The
+// parents other users can't traverse get an ACL entry letting the user traverse them, so that commands running as
+// runAsUser can reach the directories. The owners and the permissions of the other users don't change.
+func GrantAccess(runAsUser string, dirs ...string) error {
+	if runAsUser == "" {
+		return nil
+	}
+	account, _, err := lookupCredential(runAsUser)
+	if err != nil {
+		return err
+	}
+	paths := map[aclAccess][]string{}
+	traversed := map[string]bool{}
+	for _, dir := range dirs {
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			switch {
+			case info.IsDir():
+				paths[aclDirectory] = append(paths[aclDirectory], path)
+			case info.Mode().IsRegular() && info.Mode()&0100 != 0:
+				paths[aclExecutable] = append(paths[aclExecutable], path)
+			case info.Mode().IsRegular():
+				paths[aclFile] = append(paths[aclFile], path)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to give %v to user %v: %v", dir, runAsUser, err)
+		}
+		for parent := filepath.Dir(dir); ; parent = filepath.Dir(parent) {
+			info, err := os.Stat(parent)
+			if err != nil {
+				return err
+			}
+			if info.Mode()&0001 == 0 && !traversed[parent] {
+				paths[aclTraverse] = append(paths[aclTraverse], parent)
+				traversed[parent] = true
+			}
+			if parent == filepath.Dir(parent) {
+				break
+			}
+		}
+	}
+	for _, access := range []aclAccess{aclTraverse, aclDirectory, aclFile, aclExecutable} {
+		for batch := paths[access]; len(batch) > 0; {
+			count := len(batch)
+			if count > aclBatchSize {
+				count = aclBatchSize
+			}
+			if err = setACL(account, access, batch[:count]); err != nil {
+				return fmt.Errorf("failed to give access to user %v: %v", runAsUser, err)
+			}
+			batch = batch[count:]
+		}
+	}
+	return nil
+}
+
 func killProcess(process *os.Process, signal *timeoutSignal) error {
 	//   NOTE: go only kills the process but not its sub processes.
 	//   The consequence is that command.Wait() does not return, for some reason.
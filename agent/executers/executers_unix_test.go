@@ -0,0 +1,140 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package executers
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunAsCurrentUser(t *testing.T) {
+	current, err := user.Current()
+	assert.NoError(t, err)
+
+	command := exec.Command("test")
+	assert.NoError(t, runAs(command, ""))
+	assert.Nil(t, command.SysProcAttr, "empty runs the command as the agent user")
+
+	prepareProcess(command)
+	assert.NoError(t, runAs(command, current.Username))
+	uid, _ := strconv.ParseUint(current.Uid, 10, 32)
+	assert.Equal(t, uint32(uid), command.SysProcAttr.Credential.Uid)
+	assert.True(t, command.SysProcAttr.Setpgid)
+	assert.Equal(t, current.HomeDir, getEnvVariableValue(command.Env, "HOME"))
+	assert.Equal(t, current.Username, getEnvVariableValue(command.Env, "USER"))
+}
+
+func TestRunAsUnknownUser(t *testing.T) {
+	lookupUser = func(name string) (*user.User, error) { return nil, user.UnknownUserError(name) }
+	defer func() { lookupUser = user.Lookup }()
+
+	err := runAs(exec.Command("test"), "ssm-agent-worker")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "change Agent.RunAsUser")
+	assert.Error(t, GrantAccess("ssm-agent-worker", os.TempDir()))
+}
+
+func TestGrantAccess(t *testing.T) {
+	var commands []string
+	runACLCommand = func(args []string) error {
+		commands = append(commands, strings.Join(args, " "))
+		return nil
+	}
+	defer func() { runACLCommand = defaultRunACLCommand }()
+	current, err := user.Current()
+	assert.NoError(t, err)
+	root, err := ioutil.TempDir("", "executers")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	assert.NoError(t, os.Chmod(root, 0755))
+	parent := filepath.Join(root, "orchestration")
+	dir := filepath.Join(parent, "step")
+	assert.NoError(t, os.MkdirAll(dir, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "_script.sh"), []byte("echo"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "stdout"), []byte("out"), 0600))
+	assert.NoError(t, os.Symlink("/etc/shadow", filepath.Join(dir, "link")))
+
+	assert.NoError(t, GrantAccess("", dir))
+	assert.Empty(t, commands, "nothing changes for the agent user")
+
+	assert.NoError(t, GrantAccess(current.Username, dir))
+	if runtime.GOOS == "linux" {
+		assert.Equal(t, []string{
+			"setfacl -m u:" + current.Uid + ":x -- " + parent,
+			"setfacl -m u:" + current.Uid + ":rwx -- " + dir,
+			"setfacl -m u:" + current.Uid + ":rw -- " + filepath.Join(dir, "stdout"),
+			"setfacl -m u:" + current.Uid + ":rwx -- " + filepath.Join(dir, "_script.sh"),
+		}, commands, "the parents can be traversed, not listed, and the links are skipped")
+	}
+	info, _ := os.Stat(parent)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm(), "the permissions of others don't change")
+}
+
+func TestApplyExecutionContext(t *testing.T) {
+	command := exec.Command("/bin/sh", "-c", "umask; echo $HTTP_PROXY$AWS_SECRET_ACCESS_KEY")
+	command.Env = []string{"PATH=/usr/bin:/bin", "HTTP_PROXY=http://proxy", "AWS_SECRET_ACCESS_KEY=secret"}
+	assert.NoError(t, applyExecutionContext(command, nil))
+	assert.Equal(t, "/bin/sh", command.Args[0], "nil keeps the context of the agent")
+
+	assert.NoError(t, applyExecutionContext(command, &contracts.ExecutionContext{Umask: "027", EnvironmentPassthrough: []string{"HTTP_PROXY"}}))
+	output, err := command.Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "0027\nhttp://proxy\n", string(output))
+}
+
+func TestApplyExecutionContextGroup(t *testing.T) {
+	current, err := user.Current()
+	assert.NoError(t, err)
+	group, err := user.LookupGroupId(current.Gid)
+	assert.NoError(t, err)
+
+	command := exec.Command("test")
+	assert.NoError(t, applyExecutionContext(command, &contracts.ExecutionContext{Group: group.Name}))
+	assert.Equal(t, uint32(os.Getuid()), command.SysProcAttr.Credential.Uid)
+	assert.Equal(t, current.Gid, strconv.FormatUint(uint64(command.SysProcAttr.Credential.Gid), 10))
+
+	lookupGroup = func(name string) (*user.Group, error) { return nil, user.UnknownGroupError(name) }
+	defer func() { lookupGroup = user.LookupGroup }()
+	assert.Error(t, applyExecutionContext(exec.Command("test"), &contracts.ExecutionContext{Group: "builders"}))
+}
+
+func TestApplyExecutionContextProfiles(t *testing.T) {
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = exec.LookPath }()
+
+	command := exec.Command("/bin/sh", "script.sh")
+	assert.NoError(t, applyExecutionContext(command, &contracts.ExecutionContext{
+		SELinuxContext:  "system_u:system_r:unconfined_t:s0",
+		AppArmorProfile: "ssm-documents",
+	}))
+	assert.Equal(t, "/usr/bin/runcon", command.Path)
+	assert.Equal(t, []string{"runcon", "system_u:system_r:unconfined_t:s0", "aa-exec", "-p", "ssm-documents", "--", "/bin/sh", "script.sh"}, command.Args)
+
+	lookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+	err := applyExecutionContext(exec.Command("/bin/sh"), &contracts.ExecutionContext{AppArmorProfile: "ssm-documents"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "aa-exec is required")
+}
@@ -16,8 +16,12 @@
 package executers
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 )
 
 const (
@@ -28,6 +32,43 @@ func prepareProcess(command *exec.Cmd) {
 	// nothing to do on windows
 }
 
+// runAs refuses a runAsUser, the commands run as the user of the agent on Windows
+func runAs(command *exec.Cmd, runAsUser string) error {
+	if runAsUser != "" {
+		return fmt.Errorf("running commands as another user is not supported on Windows")
+	}
+	return nil
+}
+
+// baseEnvironment are the variables of the agent kept in the environment of the commands of a document
+// declaring an executionContext
+var baseEnvironment = []string{"PATH", "PATHEXT", "SystemRoot", "SystemDrive", "windir", "ComSpec", "TEMP", "TMP",
+	"PSModulePath", "ProgramData", "ProgramFiles", "ProgramFiles(x86)", "ALLUSERSPROFILE", "USERPROFILE"}
+
+// applyExecutionContext filters the environment of the command, the other settings of an executionContext
+// are not supported on Windows.
+func applyExecutionContext(command *exec.Cmd, executionContext *contracts.ExecutionContext) error {
+	if executionContext == nil {
+		return nil
+	}
+	if executionContext.Group != "" || executionContext.Umask != "" ||
+		executionContext.SELinuxContext != "" || executionContext.AppArmorProfile != "" {
+		return fmt.Errorf("only the environmentPassthrough of an executionContext is supported on Windows")
+	}
+	command.Env = filterEnvironment(command.Env, executionContext.EnvironmentPassthrough)
+	return nil
+}
+
+// sameEnvVariable returns whether the names are of the same environment variable, they ignore the case
+func sameEnvVariable(name string, other string) bool {
+	return strings.EqualFold(name, other)
+}
+
+// GrantAccess does nothing on Windows, where the commands run as the user of the agent
+func GrantAccess(runAsUser string, dirs ...string) error {
+	return nil
+}
+
 func killProcess(process *os.Process, signal *timeoutSignal) error {
 	// process kill doesn't send proper signal to the process status
 	// Setting the signal to indicate execution was interrupted
@@ -18,6 +18,7 @@ import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/tls"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -31,6 +32,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
@@ -50,15 +52,80 @@ type DownloadInput struct {
 	SourceURL            string
 	DestinationDirectory string
 	SourceChecksums      map[string]string
+	// Headers are added to the http/https requests, e.g. their Authorization
+	Headers http.Header
+	// Redirects controls the redirects the http/https requests follow
+	Redirects RedirectPolicy
+	// TLSConfig is the TLS configuration of the https requests, e.g. trusting a private CA, the default one when nil
+	TLSConfig *tls.Config
 }
 
-// httpDownload attempts to download a file via http/s call
-func httpDownload(log log.T, fileURL string, destFile string) (output DownloadOutput, err error) {
+// RedirectPolicy controls the redirects the http/https downloads follow.
+type RedirectPolicy struct {
+	// Disabled fails the downloads redirected
+	Disabled bool
+	// SameHost fails the downloads redirected to another host
+	SameHost bool
+	// MaxRedirects is the number of redirects followed, defaultMaxRedirects when 0
+	MaxRedirects int
+}
+
+// defaultMaxRedirects is the number of redirects followed by default, as net/http
+const defaultMaxRedirects = 10
+
+// checkRedirect returns the CheckRedirect of the http client following the policy. The Headers of the download,
+// which may hold credentials, aren't sent to other hosts, nor to http URLs after https ones.
+func (policy RedirectPolicy) checkRedirect(headers http.Header) func(r *http.Request, via []*http.Request) error {
+	maxRedirects := policy.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	return func(r *http.Request, via []*http.Request) error {
+		original := via[0].URL
+		switch {
+		case policy.Disabled:
+			return fmt.Errorf("redirected to %v, redirects are disabled", r.URL.Host)
+		case len(via) > maxRedirects:
+			return fmt.Errorf("stopped after %v redirects", maxRedirects)
+		case policy.SameHost && r.URL.Host != original.Host:
+			return fmt.Errorf("redirected to %v, redirects to other hosts are disabled", r.URL.Host)
+		case len(headers) > 0 && original.Scheme == "https" && r.URL.Scheme != "https":
+			return fmt.Errorf("redirected from https to %v, which would send the headers unencrypted", r.URL.Scheme)
+		}
+		if r.URL.Host != original.Host {
+			for name := range headers {
+				r.Header.Del(name)
+			}
+		}
+		r.URL.Opaque = r.URL.Path
+		return nil
+	}
+}
+
+// newHTTPRequest returns a GET request of the URL with the headers of the download
+func newHTTPRequest(fileURL string, headers http.Header) (*http.Request, error) {
+	request, err := http.NewRequest("GET", fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range headers {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	return request, nil
+}
+
+// httpDownload attempts to download a file via http/s call, the file is downloaded again only when its ETag or
+// Last-Modified date changed
+func httpDownload(log log.T, input DownloadInput, destFile string) (output DownloadOutput, err error) {
 	log.Debugf("attempting to download as http/https download %v", destFile)
+	fileURL, checksums := input.SourceURL, input.SourceChecksums
 	eTagFile := destFile + ".etag"
+	lastModifiedFile := destFile + ".lastmodified"
 	var check http.Client
 	var request *http.Request
-	request, err = http.NewRequest("GET", fileURL, nil)
+	request, err = newHTTPRequest(fileURL, input.Headers)
 	if err != nil {
 		return
 	}
@@ -67,20 +134,24 @@ func httpDownload(log log.T, fileURL string, destFile string) (output DownloadOu
 		existingETag, err = fileutil.ReadAllText(eTagFile)
 		request.Header.Add("If-None-Match", existingETag)
 	}
+	if fileutil.Exists(destFile) == true && fileutil.Exists(lastModifiedFile) == true {
+		var lastModified string
+		lastModified, err = fileutil.ReadAllText(lastModifiedFile)
+		request.Header.Add("If-Modified-Since", lastModified)
+	}
 
 	check = http.Client{
-		CheckRedirect: func(r *http.Request, via []*http.Request) error {
-			r.URL.Opaque = r.URL.Path
-			return nil
-		},
+		CheckRedirect: input.Redirects.checkRedirect(input.Headers),
+	}
+	if input.TLSConfig != nil {
+		check.Transport = &http.Transport{Proxy: proxyconfig.Proxy, TLSClientConfig: input.TLSConfig}
 	}
 
 	var resp *http.Response
 	resp, err = check.Do(request)
 	if err != nil {
 		log.Debug("failed to download from http/https, ", err)
-		fileutil.DeleteFile(destFile)
-		fileutil.DeleteFile(eTagFile)
+		discardDownload(destFile)
 		return
 	}
 
@@ -91,31 +162,71 @@ func httpDownload(log log.T, fileURL string, destFile string) (output DownloadOu
 		return output, nil
 	} else if resp.StatusCode != http.StatusOK {
 		log.Debug("failed to download from http/https, ", err)
-		fileutil.DeleteFile(destFile)
-		fileutil.DeleteFile(eTagFile)
+		discardDownload(destFile)
 		err = fmt.Errorf("http request failed. status:%v statuscode:%v", resp.Status, resp.StatusCode)
 		return
 	}
-	defer resp.Body.Close()
 	eTagValue := resp.Header.Get("Etag")
+	file := remoteFile{
+		size:          resp.ContentLength,
+		eTag:          eTagValue,
+		acceptsRanges: resp.Header.Get("Accept-Ranges") == "bytes",
+		checksums:     checksums,
+	}
+	getRange := func(start int64, end int64, eTag string) (io.ReadCloser, error) {
+		rangeRequest, err := newHTTPRequest(fileURL, input.Headers)
+		if err != nil {
+			return nil, err
+		}
+		rangeRequest.Header.Set("Range", fmt.Sprintf("bytes=%v-%v", start, end))
+		rangeRequest.Header.Set("If-Range", eTag)
+		rangeResp, err := check.Do(rangeRequest)
+		if err != nil {
+			return nil, err
+		}
+		switch rangeResp.StatusCode {
+		case http.StatusPartialContent:
+			return rangeResp.Body, nil
+		case http.StatusOK:
+			// the server sends the whole file when it doesn't match If-Range anymore
+			rangeResp.Body.Close()
+			return nil, errSourceChanged
+		default:
+			rangeResp.Body.Close()
+			return nil, fmt.Errorf("http range request failed. status:%v statuscode:%v", rangeResp.Status, rangeResp.StatusCode)
+		}
+	}
+	if output.IsHashMatched, err = downloadBody(log, file, resp.Body, getRange, destFile); err != nil {
+		log.Errorf("failed to write destFile %v, %v ", destFile, err)
+		return
+	}
 	if eTagValue != "" {
 		log.Debug("file eTagValue is ", eTagValue)
-		err = fileutil.WriteAllText(eTagFile, eTagValue)
-		if err != nil {
+		if err = fileutil.WriteAllText(eTagFile, eTagValue); err != nil {
 			log.Errorf("failed to write eTagfile %v, %v ", eTagFile, err)
 			return
 		}
 	}
-	_, err = FileCopy(log, destFile, resp.Body)
-	if err == nil {
-		output.LocalFilePath = destFile
-		output.IsUpdated = true
-	} else {
-		log.Errorf("failed to write destFile %v, %v ", destFile, err)
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		if err = fileutil.WriteAllText(lastModifiedFile, lastModified); err != nil {
+			log.Errorf("failed to write lastModifiedFile %v, %v ", lastModifiedFile, err)
+			return
+		}
 	}
+	output.LocalFilePath = destFile
+	output.IsUpdated = true
 	return
 }
 
+// discardDownload deletes destFile after a failed request, unless it's a partial download to resume
+func discardDownload(destFile string) {
+	fileutil.DeleteFile(destFile + ".etag")
+	fileutil.DeleteFile(destFile + ".lastmodified")
+	if !fileutil.Exists(destFile + stateSuffix) {
+		fileutil.DeleteFile(destFile)
+	}
+}
+
 // awsConfig creates a config and sets region and credential information given an S3 URL
 func awsConfig(log log.T, amazonS3URL s3util.AmazonS3URL) (config *aws.Config, err error) {
 	config = sdkutil.AwsConfig()
@@ -231,7 +342,7 @@ func ListS3Directory(log log.T, amazonS3URL s3util.AmazonS3URL) (folderNames []s
 }
 
 // s3Download attempts to download a file via the aws sdk.
-func s3Download(log log.T, amazonS3URL s3util.AmazonS3URL, destFile string) (output DownloadOutput, err error) {
+func s3Download(log log.T, amazonS3URL s3util.AmazonS3URL, destFile string, checksums map[string]string) (output DownloadOutput, err error) {
 	log.Debugf("attempting to download as s3 download %v", destFile)
 	eTagFile := destFile + ".etag"
 
@@ -258,8 +369,7 @@ func s3Download(log log.T, amazonS3URL s3util.AmazonS3URL, destFile string) (out
 	if err != nil {
 		if req.HTTPResponse == nil || req.HTTPResponse.StatusCode != http.StatusNotModified {
 			log.Debug("failed to download from s3, ", err)
-			fileutil.DeleteFile(destFile)
-			fileutil.DeleteFile(eTagFile)
+			discardDownload(destFile)
 			return
 		}
 
@@ -269,23 +379,41 @@ func s3Download(log log.T, amazonS3URL s3util.AmazonS3URL, destFile string) (out
 		return output, nil
 	}
 
-	if *resp.ETag != "" {
-		log.Debug("files etag is ", *resp.ETag)
-		err = fileutil.WriteAllText(eTagFile, *resp.ETag)
-		if err != nil {
-			log.Errorf("failed to write eTagfile %v, %v ", eTagFile, err)
-			return
+	eTagValue := aws.StringValue(resp.ETag)
+	file := remoteFile{
+		size:          aws.Int64Value(resp.ContentLength),
+		eTag:          eTagValue,
+		acceptsRanges: aws.StringValue(resp.AcceptRanges) == "bytes",
+		checksums:     checksums,
+	}
+	getRange := func(start int64, end int64, eTag string) (io.ReadCloser, error) {
+		rangeReq, rangeResp := s3client.GetObjectRequest(&s3.GetObjectInput{
+			Bucket:  params.Bucket,
+			Key:     params.Key,
+			Range:   aws.String(fmt.Sprintf("bytes=%v-%v", start, end)),
+			IfMatch: aws.String(eTag),
+		})
+		if err := rangeReq.Send(); err != nil {
+			if rangeReq.HTTPResponse != nil && rangeReq.HTTPResponse.StatusCode == http.StatusPreconditionFailed {
+				return nil, errSourceChanged
+			}
+			return nil, err
 		}
+		return rangeResp.Body, nil
 	}
-
-	defer resp.Body.Close()
-	_, err = FileCopy(log, destFile, resp.Body)
-	if err == nil {
-		output.LocalFilePath = destFile
-		output.IsUpdated = true
-	} else {
+	if output.IsHashMatched, err = downloadBody(log, file, resp.Body, getRange, destFile); err != nil {
 		log.Errorf("failed to write destFile %v, %v ", destFile, err)
+		return
+	}
+	if eTagValue != "" {
+		log.Debug("files etag is ", eTagValue)
+		if err = fileutil.WriteAllText(eTagFile, eTagValue); err != nil {
+			log.Errorf("failed to write eTagfile %v, %v ", eTagFile, err)
+			return
+		}
 	}
+	output.LocalFilePath = destFile
+	output.IsUpdated = true
 	return
 }
 
@@ -353,23 +481,25 @@ func Download(log log.T, input DownloadInput) (output DownloadOutput, err error)
 		if amazonS3URL.IsBucketAndKeyPresent() {
 			// source is s3
 			var tempOutput DownloadOutput
-			tempOutput, err = s3Download(log, amazonS3URL, output.LocalFilePath)
-			// if s3 download fails, attempt http/https download as fallback
-			if err != nil {
-				tempOutput, err = httpDownload(log, input.SourceURL, output.LocalFilePath)
+			tempOutput, err = s3Download(log, amazonS3URL, output.LocalFilePath, input.SourceChecksums)
+			// if s3 download fails, attempt http/https download as fallback, unless the content downloaded was wrong
+			if _, mismatch := err.(*ChecksumError); err != nil && !mismatch {
+				tempOutput, err = httpDownload(log, input, output.LocalFilePath)
 			}
 			output = tempOutput
 		} else {
 			// simple http/https download
-			output, err = httpDownload(log, input.SourceURL, output.LocalFilePath)
+			output, err = httpDownload(log, input, output.LocalFilePath)
 		}
 
 		if err != nil {
 			return
 		}
 
+		// the content written was checksummed as it was downloaded, only a file unchanged or downloaded
+		// in parts is read again to verify it
 		isLocalFile, err = fileutil.LocalFileExist(output.LocalFilePath)
-		if isLocalFile == true {
+		if isLocalFile == true && !output.IsHashMatched {
 			output.IsHashMatched, err = VerifyHash(log, input, output)
 		}
 	}
@@ -404,6 +534,8 @@ func VerifyHash(log log.T, input DownloadInput, output DownloadOutput) (bool, er
 		// check the sha256 algorithm by default
 		if hashAlgorithm == "" || strings.EqualFold(hashAlgorithm, "sha256") {
 			computedHashValue, err = Sha256HashValue(log, output.LocalFilePath)
+		} else if strings.EqualFold(hashAlgorithm, HashTypeSha512) {
+			computedHashValue, err = Sha512HashValue(log, output.LocalFilePath)
 		} else if strings.EqualFold(hashAlgorithm, "md5") {
 			computedHashValue, err = Md5HashValue(log, output.LocalFilePath)
 		} else {
@@ -0,0 +1,154 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// Hash types of the checksums of a download
+const (
+	HashTypeSha256 = "sha256"
+	HashTypeSha512 = "sha512"
+	HashTypeMd5    = "md5"
+)
+
+// ChecksumError reports content whose checksum doesn't match the expected one.
+type ChecksumError struct {
+	HashType string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch, the %v of the content is %v, expected %v", e.HashType, e.Actual, e.Expected)
+}
+
+// NewHash returns the hash of hashType, sha256 when hashType is empty.
+func NewHash(hashType string) (hash.Hash, error) {
+	switch strings.ToLower(hashType) {
+	case "", HashTypeSha256:
+		return sha256.New(), nil
+	case HashTypeSha512:
+		return sha512.New(), nil
+	case HashTypeMd5:
+		return md5.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported hash type %v", hashType)
+}
+
+// Checksum returns the hex checksum of hashType of the content read from r.
+func Checksum(r io.Reader, hashType string) (string, error) {
+	hasher, err := NewHash(hashType)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(hasher, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Sha512HashValue gets the sha512 hash value
+func Sha512HashValue(log log.T, filePath string) (value string, err error) {
+	var f *os.File
+	if f, err = os.Open(filePath); err != nil {
+		return
+	}
+	defer f.Close()
+	if value, err = Checksum(f, HashTypeSha512); err == nil {
+		log.Debugf("Hash=%v, FilePath=%v", value, filePath)
+	}
+	return
+}
+
+// VerifyingReader computes the checksums of the content read through it, and verifies them when the content
+// ends: the read reaching the end returns a *ChecksumError instead of io.EOF when a checksum doesn't match.
+// This checksums a download as it's written, without reading the file again.
+type VerifyingReader struct {
+	reader   io.Reader
+	expected map[string]string
+	hashes   map[string]hash.Hash
+	verified bool
+}
+
+// NewVerifyingReader returns a reader of r that verifies the checksums, a map of hash type to hex value
+// like DownloadInput.SourceChecksums. An empty hash type is sha256; the hash types not supported and the
+// empty values are ignored.
+func NewVerifyingReader(r io.Reader, checksums map[string]string) *VerifyingReader {
+	v := &VerifyingReader{reader: r, expected: map[string]string{}, hashes: map[string]hash.Hash{}}
+	for hashType, value := range checksums {
+		if value == "" {
+			continue
+		}
+		if hashType == "" {
+			hashType = HashTypeSha256
+		}
+		hashType = strings.ToLower(hashType)
+		if hasher, err := NewHash(hashType); err == nil {
+			v.expected[hashType] = value
+			v.hashes[hashType] = hasher
+		}
+	}
+	return v
+}
+
+// Read reads from the underlying reader and hashes what it read.
+func (v *VerifyingReader) Read(p []byte) (n int, err error) {
+	n, err = v.reader.Read(p)
+	for _, hasher := range v.hashes {
+		hasher.Write(p[:n])
+	}
+	if err == io.EOF {
+		if verifyErr := v.verify(); verifyErr != nil {
+			err = verifyErr
+		}
+	}
+	return
+}
+
+// Verified returns true when the content was read to the end and matched at least one checksum.
+func (v *VerifyingReader) Verified() bool {
+	return v.verified
+}
+
+// Checksums returns the hex checksums of the content read so far.
+func (v *VerifyingReader) Checksums() map[string]string {
+	checksums := make(map[string]string, len(v.hashes))
+	for hashType, hasher := range v.hashes {
+		checksums[hashType] = hex.EncodeToString(hasher.Sum(nil))
+	}
+	return checksums
+}
+
+// verify compares the checksums of the content with the expected ones
+func (v *VerifyingReader) verify() error {
+	for hashType, actual := range v.Checksums() {
+		if !strings.EqualFold(actual, v.expected[hashType]) {
+			return &ChecksumError{HashType: hashType, Expected: v.expected[hashType], Actual: actual}
+		}
+	}
+	v.verified = len(v.hashes) > 0
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func sha512Hex(content []byte) string {
+	sum := sha512.Sum512(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestChecksum(t *testing.T) {
+	content := []byte("content")
+
+	value, err := Checksum(bytes.NewReader(content), "SHA512")
+	assert.NoError(t, err)
+	assert.Equal(t, sha512Hex(content), value)
+
+	value, err = Checksum(bytes.NewReader(content), "")
+	assert.NoError(t, err)
+	assert.Equal(t, sha256Hex(content), value)
+
+	_, err = Checksum(bytes.NewReader(content), "crc32")
+	assert.Error(t, err)
+}
+
+func TestVerifyingReaderMatches(t *testing.T) {
+	content := randomContent(5000)
+	reader := NewVerifyingReader(bytes.NewReader(content), map[string]string{
+		HashTypeSha256: strings.ToUpper(sha256Hex(content)),
+		"SHA512":       sha512Hex(content),
+		"crc32":        "ignored",
+	})
+
+	read, err := ioutil.ReadAll(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, content, read)
+	assert.True(t, reader.Verified())
+	assert.Equal(t, map[string]string{HashTypeSha256: sha256Hex(content), HashTypeSha512: sha512Hex(content)}, reader.Checksums())
+}
+
+func TestVerifyingReaderMismatch(t *testing.T) {
+	content := randomContent(5000)
+	reader := NewVerifyingReader(bytes.NewReader(content), map[string]string{"": sha256Hex([]byte("other"))})
+
+	_, err := ioutil.ReadAll(reader)
+
+	assert.IsType(t, &ChecksumError{}, err)
+	assert.Equal(t, sha256Hex(content), err.(*ChecksumError).Actual)
+	assert.False(t, reader.Verified())
+}
+
+func TestVerifyingReaderWithoutChecksums(t *testing.T) {
+	reader := NewVerifyingReader(bytes.NewReader([]byte("content")), map[string]string{HashTypeSha256: ""})
+
+	_, err := io.Copy(ioutil.Discard, reader)
+
+	assert.NoError(t, err)
+	assert.False(t, reader.Verified())
+}
+
+func TestHttpDownloadVerifiesContentAsWritten(t *testing.T) {
+	destFile := setupResumableTest(t, downloadSettings{parallelism: 4})
+	server := newRangeServer(randomContent(500))
+	defer server.Close()
+
+	output, err := httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL, SourceChecksums: map[string]string{HashTypeSha512: sha512Hex(server.content)}}, destFile)
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsHashMatched)
+	content, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, server.content, content)
+}
+
+func TestHttpDownloadDiscardsContentNotMatchingChecksum(t *testing.T) {
+	destFile := setupResumableTest(t, downloadSettings{parallelism: 4})
+	server := newRangeServer(randomContent(500))
+	defer server.Close()
+
+	output, err := httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL, SourceChecksums: map[string]string{HashTypeSha256: sha256Hex([]byte("other"))}}, destFile)
+
+	assert.IsType(t, &ChecksumError{}, err)
+	assert.False(t, output.IsHashMatched)
+	assert.False(t, fileutil.Exists(destFile))
+	assert.False(t, fileutil.Exists(destFile+".etag"))
+}
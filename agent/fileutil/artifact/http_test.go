@@ -0,0 +1,107 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpDownloadWithHeadersAndLastModified(t *testing.T) {
+	destFile := setupResumableTest(t, downloadSettings{parallelism: 1})
+	modified := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.ServeContent(w, r, "", modified, bytes.NewReader([]byte("content")))
+	}))
+	defer server.Close()
+	input := DownloadInput{SourceURL: server.URL, Headers: http.Header{"X-Api-Key": {"secret"}}}
+
+	output, err := httpDownload(newTestLog(), input, destFile)
+	assert.NoError(t, err)
+	assert.True(t, output.IsUpdated)
+
+	// the file didn't change since
+	output, err = httpDownload(newTestLog(), input, destFile)
+	assert.NoError(t, err)
+	assert.False(t, output.IsUpdated)
+	assert.Equal(t, destFile, output.LocalFilePath)
+	assert.Equal(t, 2, requests)
+
+	_, err = httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL}, destFile)
+	assert.Error(t, err)
+}
+
+func TestHttpDownloadRedirects(t *testing.T) {
+	var otherHostHeader string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherHostHeader = r.Header.Get("X-Api-Key")
+		w.Write([]byte("content"))
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/other":
+			http.Redirect(w, r, other.URL+"/file", http.StatusFound)
+		case "/same":
+			http.Redirect(w, r, "/file", http.StatusFound)
+		default:
+			w.Write([]byte("content"))
+		}
+	}))
+	defer server.Close()
+	headers := http.Header{"X-Api-Key": {"secret"}}
+
+	// the headers aren't sent to other hosts
+	destFile := setupResumableTest(t, downloadSettings{parallelism: 1})
+	_, err := httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL + "/other", Headers: headers}, destFile)
+	assert.NoError(t, err)
+	assert.Empty(t, otherHostHeader)
+
+	sameHost := RedirectPolicy{SameHost: true}
+	_, err = httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL + "/same", Redirects: sameHost}, destFile)
+	assert.NoError(t, err)
+	_, err = httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL + "/other", Redirects: sameHost}, destFile)
+	assert.Error(t, err)
+
+	disabled := RedirectPolicy{Disabled: true}
+	_, err = httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL + "/same", Redirects: disabled}, destFile)
+	assert.Error(t, err)
+}
+
+func TestCheckRedirect(t *testing.T) {
+	request := func(url string) *http.Request {
+		r, _ := http.NewRequest("GET", url, nil)
+		return r
+	}
+	via := []*http.Request{request("https://example.com/file")}
+
+	checkRedirect := RedirectPolicy{}.checkRedirect(http.Header{"Authorization": {"Basic x"}})
+	assert.Error(t, checkRedirect(request("http://example.com/file"), via), "no downgrade with credentials")
+	assert.NoError(t, RedirectPolicy{}.checkRedirect(nil)(request("http://example.com/file"), via))
+
+	checkRedirect = RedirectPolicy{MaxRedirects: 1}.checkRedirect(nil)
+	assert.NoError(t, checkRedirect(request("https://example.com/next"), via))
+	assert.Error(t, checkRedirect(request("https://example.com/last"), append(via, request("https://example.com/next"))))
+}
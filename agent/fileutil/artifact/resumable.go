@@ -0,0 +1,352 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// stateSuffix names the file kept next to a partial download with the progress of its parts
+	stateSuffix = ".download"
+
+	// copyBufferSize is the size of the reads of the downloads, and so the grain of the bandwidth limit
+	copyBufferSize = 32 * 1024
+
+	// saveStateBytes is how much is downloaded between two saves of the progress
+	saveStateBytes = 4 * 1024 * 1024
+
+	// maxRetryDelay caps the wait before resuming an interrupted part
+	maxRetryDelay = 30 * time.Second
+)
+
+// partSize is the size from which files are downloaded in ranges, and the smallest part they're split in
+var partSize int64 = 8 * 1024 * 1024
+
+// errSourceChanged is returned when the file changes on the server while its parts are downloaded
+var errSourceChanged = errors.New("the file changed on the server during the download")
+
+var sleep = time.Sleep
+
+// limiter caps the overall rate of the downloads of the process
+var limiter = &bandwidthLimiter{}
+
+// downloadSettings are the settings of the agent configuration applied to the downloads
+type downloadSettings struct {
+	parallelism    int
+	retryLimit     int
+	bytesPerSecond int64
+}
+
+var loadDownloadSettings = func() downloadSettings {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		config = appconfig.DefaultConfig()
+	}
+	return downloadSettings{
+		parallelism:    config.Agent.DownloadParallelism,
+		retryLimit:     config.Agent.DownloadRetryLimit,
+		bytesPerSecond: int64(config.Agent.DownloadBandwidthLimitKBps) * 1024,
+	}
+}
+
+// remoteFile describes the file being downloaded, from the response to the request for the whole file
+type remoteFile struct {
+	size          int64
+	eTag          string
+	acceptsRanges bool
+	// checksums expected of the content, see NewVerifyingReader
+	checksums map[string]string
+}
+
+// rangeGetter requests the bytes from start to end included of the file, failing with errSourceChanged
+// if the file doesn't have the eTag anymore
+type rangeGetter func(start int64, end int64, eTag string) (io.ReadCloser, error)
+
+// downloadState is the progress of a download split in parts, saved so an interrupted download resumes
+type downloadState struct {
+	ETag  string
+	Size  int64
+	Parts []downloadPart
+}
+
+// downloadPart is the range [Start, End) of the file, of which Done bytes are downloaded
+type downloadPart struct {
+	Start int64
+	End   int64
+	Done  int64
+}
+
+// download is a download in ranges in progress
+type download struct {
+	log       log.T
+	file      *os.File
+	stateFile string
+	state     downloadState
+	get       rangeGetter
+	unsaved   int64
+	m         sync.Mutex
+}
+
+// downloadBody writes the file to destFile. body is the response to the request for the whole file: it's
+// copied as is when the file is small or the server doesn't serve ranges, otherwise the file is downloaded
+// in parts, in parallel, and the parts interrupted are resumed from where they stopped.
+// The destFile of a failed download in parts is kept, the next download of the same file resumes it.
+// A file copied as is is verified against the file checksums as it's written, verified is true when it matched
+// them; the file downloaded in parts is left to verify once complete.
+func downloadBody(log log.T, file remoteFile, body io.ReadCloser, get rangeGetter, destFile string) (verified bool, err error) {
+	settings := loadDownloadSettings()
+	limiter.setRate(settings.bytesPerSecond)
+	stateFile := destFile + stateSuffix
+
+	if err = fileutil.CheckDiskSpace(destFile, file.size); err != nil {
+		body.Close()
+		return
+	}
+
+	if !file.acceptsRanges || file.eTag == "" || file.size < partSize {
+		defer body.Close()
+		fileutil.DeleteFile(stateFile)
+		reader := NewVerifyingReader(limitedReader{body}, file.checksums)
+		if _, err = FileCopy(log, destFile, reader); err != nil {
+			fileutil.DeleteFile(destFile)
+		}
+		return reader.Verified(), err
+	}
+	body.Close()
+
+	d := &download{log: log, stateFile: stateFile, get: get}
+	if d.file, d.state, err = openDownload(log, destFile, file, settings.parallelism); err != nil {
+		return
+	}
+	defer d.file.Close()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(d.state.Parts))
+	for i := range d.state.Parts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = d.fetch(i, settings.retryLimit)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err = range errs {
+		if err == errSourceChanged {
+			// start over next time
+			d.file.Close()
+			fileutil.DeleteFile(destFile)
+			fileutil.DeleteFile(stateFile)
+			return
+		}
+		if err != nil {
+			d.save()
+			return false, fmt.Errorf("download interrupted, it will resume from %v of %v bytes: %v", d.downloaded(), file.size, err)
+		}
+	}
+
+	if err = d.file.Sync(); err != nil {
+		return
+	}
+	fileutil.DeleteFile(stateFile)
+	log.Infof("%s with %v bytes downloaded in %v parts", destFile, file.size, len(d.state.Parts))
+	return false, nil
+}
+
+// openDownload opens destFile with the progress saved by a previous download of the same file,
+// or prepares a new download split in up to parallelism parts.
+func openDownload(log log.T, destFile string, file remoteFile, parallelism int) (f *os.File, state downloadState, err error) {
+	if content, err := ioutil.ReadFile(destFile + stateSuffix); err == nil && json.Unmarshal(content, &state) == nil {
+		if info, err := os.Stat(destFile); err == nil && info.Size() == file.size && state.ETag == file.eTag && state.Size == file.size {
+			if f, err = os.OpenFile(destFile, os.O_RDWR, 0); err == nil {
+				log.Infof("resuming the download of %v", destFile)
+				return f, state, nil
+			}
+		}
+	}
+
+	state = downloadState{ETag: file.eTag, Size: file.size}
+	parts := int64(parallelism)
+	if parts < 1 {
+		parts = 1
+	}
+	if maxParts := file.size / partSize; parts > maxParts {
+		parts = maxParts
+	}
+	for i := int64(0); i < parts; i++ {
+		state.Parts = append(state.Parts, downloadPart{Start: file.size * i / parts, End: file.size * (i + 1) / parts})
+	}
+
+	if f, err = os.Create(destFile); err != nil {
+		return
+	}
+	if err = f.Truncate(file.size); err != nil {
+		f.Close()
+	}
+	return
+}
+
+// fetch downloads the part, resuming it up to retryLimit times when it's interrupted
+func (d *download) fetch(i int, retryLimit int) (err error) {
+	for attempt := 1; ; attempt++ {
+		if err = d.fetchOnce(i); err == nil || err == errSourceChanged || attempt > retryLimit {
+			return
+		}
+		d.log.Warnf("download of %v interrupted at %v bytes, resuming (%v/%v): %v", d.file.Name(), d.downloaded(), attempt, retryLimit, err)
+		d.save()
+		delay := time.Duration(attempt) * time.Second
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+		sleep(delay)
+	}
+}
+
+// fetchOnce requests the rest of the part and writes it to the file as it arrives
+func (d *download) fetchOnce(i int) error {
+	d.m.Lock()
+	part := d.state.Parts[i]
+	d.m.Unlock()
+	offset := part.Start + part.Done
+	if offset >= part.End {
+		return nil
+	}
+
+	body, err := d.get(offset, part.End-1, d.state.ETag)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	buffer := make([]byte, copyBufferSize)
+	for offset < part.End {
+		chunk := buffer
+		if remaining := part.End - offset; remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+		n, err := body.Read(chunk)
+		if n > 0 {
+			limiter.wait(n)
+			if _, werr := d.file.WriteAt(chunk[:n], offset); werr != nil {
+				return werr
+			}
+			offset += int64(n)
+			d.advance(i, int64(n))
+		}
+		if err == io.EOF && offset < part.End {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
+
+// advance records the bytes written to the part, saving the progress every saveStateBytes
+func (d *download) advance(i int, n int64) {
+	d.m.Lock()
+	d.state.Parts[i].Done += n
+	d.unsaved += n
+	save := d.unsaved >= saveStateBytes
+	d.m.Unlock()
+	if save {
+		d.save()
+	}
+}
+
+// save writes the progress next to the file, after the bytes it counts
+func (d *download) save() {
+	if err := d.file.Sync(); err != nil {
+		d.log.Debugf("failed to sync %v: %v", d.file.Name(), err)
+		return
+	}
+	d.m.Lock()
+	defer d.m.Unlock()
+	d.unsaved = 0
+	content, err := json.Marshal(d.state)
+	if err == nil {
+		err = fileutil.WriteAtomically(d.stateFile, content, appconfig.ReadWriteAccess, false)
+	}
+	if err != nil {
+		d.log.Debugf("failed to save the progress of the download of %v: %v", d.file.Name(), err)
+	}
+}
+
+// downloaded returns the number of bytes downloaded
+func (d *download) downloaded() (total int64) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	for _, part := range d.state.Parts {
+		total += part.Done
+	}
+	return
+}
+
+// bandwidthLimiter spreads the reads of all the downloads over time to stay under a rate
+type bandwidthLimiter struct {
+	bytesPerSecond int64
+	next           time.Time
+	m              sync.Mutex
+}
+
+// setRate changes the rate, 0 removes the limit
+func (l *bandwidthLimiter) setRate(bytesPerSecond int64) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.bytesPerSecond = bytesPerSecond
+}
+
+// wait blocks until n more bytes fit in the rate
+func (l *bandwidthLimiter) wait(n int) {
+	l.m.Lock()
+	if l.bytesPerSecond <= 0 {
+		l.m.Unlock()
+		return
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.bytesPerSecond))
+	delay := l.next.Sub(now)
+	l.m.Unlock()
+	sleep(delay)
+}
+
+// limitedReader reads through the limiter
+type limitedReader struct {
+	r io.Reader
+}
+
+func (r limitedReader) Read(p []byte) (n int, err error) {
+	if len(p) > copyBufferSize {
+		p = p[:copyBufferSize]
+	}
+	n, err = r.r.Read(p)
+	limiter.wait(n)
+	return
+}
@@ -0,0 +1,237 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// rangeServer serves content, cutting off the responses after cutAfter bytes while failures remain
+type rangeServer struct {
+	*httptest.Server
+	content  []byte
+	eTag     string
+	cutAfter int
+	failures int
+	ranges   []string
+	m        sync.Mutex
+}
+
+func newRangeServer(content []byte) *rangeServer {
+	s := &rangeServer{content: content, eTag: `"v1"`}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *rangeServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	content, eTag := s.content, s.eTag
+	cut := s.failures > 0
+	if cut {
+		s.failures--
+	}
+	if r.Header.Get("Range") != "" {
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+	}
+	s.m.Unlock()
+
+	w.Header().Set("ETag", eTag)
+	if cut {
+		w = &cuttingWriter{ResponseWriter: w, left: s.cutAfter}
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+}
+
+// cuttingWriter aborts the response after writing left bytes
+type cuttingWriter struct {
+	http.ResponseWriter
+	left int
+}
+
+func (w *cuttingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.left {
+		w.ResponseWriter.Write(p[:w.left])
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	w.left -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func setupResumableTest(t *testing.T, settings downloadSettings) (destFile string) {
+	dir, err := ioutil.TempDir("", "artifact")
+	assert.NoError(t, err)
+	savedPartSize, savedSleep, savedSettings := partSize, sleep, loadDownloadSettings
+	partSize = 1024
+	sleep = func(time.Duration) {}
+	loadDownloadSettings = func() downloadSettings { return settings }
+	t.Cleanup(func() {
+		partSize, sleep, loadDownloadSettings = savedPartSize, savedSleep, savedSettings
+		os.RemoveAll(dir)
+	})
+	return filepath.Join(dir, "file")
+}
+
+func newTestLog() log.T {
+	logger := log.NewMockLog()
+	logger.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	return logger
+}
+
+func randomContent(size int) []byte {
+	content := make([]byte, size)
+	rand.Read(content)
+	return content
+}
+
+func TestHttpDownloadInParallelParts(t *testing.T) {
+	destFile := setupResumableTest(t, downloadSettings{parallelism: 4})
+	server := newRangeServer(randomContent(10000))
+	defer server.Close()
+
+	output, err := httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL}, destFile)
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsUpdated)
+	content, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, server.content, content)
+	assert.Len(t, server.ranges, 4)
+	assert.False(t, fileutil.Exists(destFile+stateSuffix))
+	eTag, _ := fileutil.ReadAllText(destFile + ".etag")
+	assert.Equal(t, server.eTag, eTag)
+}
+
+func TestHttpDownloadSmallFileInOnePiece(t *testing.T) {
+	destFile := setupResumableTest(t, downloadSettings{parallelism: 4})
+	server := newRangeServer(randomContent(1000))
+	defer server.Close()
+
+	_, err := httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL}, destFile)
+
+	assert.NoError(t, err)
+	content, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, server.content, content)
+	assert.Empty(t, server.ranges)
+}
+
+func TestHttpDownloadResumesInterruptedParts(t *testing.T) {
+	destFile := setupResumableTest(t, downloadSettings{parallelism: 1, retryLimit: 5})
+	server := newRangeServer(randomContent(10000))
+	defer server.Close()
+	// the first request only provides the headers, the next ones are cut
+	server.cutAfter = 3000
+	server.failures = 3
+
+	_, err := httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL}, destFile)
+
+	assert.NoError(t, err)
+	content, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, server.content, content)
+	assert.Equal(t, []string{"bytes=0-9999", "bytes=3000-9999", "bytes=6000-9999"}, server.ranges)
+}
+
+func TestHttpDownloadResumesFailedDownload(t *testing.T) {
+	destFile := setupResumableTest(t, downloadSettings{parallelism: 1, retryLimit: 0})
+	server := newRangeServer(randomContent(10000))
+	defer server.Close()
+	server.cutAfter = 4000
+	server.failures = 2
+
+	_, err := httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL}, destFile)
+	assert.Error(t, err)
+	assert.True(t, fileutil.Exists(destFile))
+	assert.True(t, fileutil.Exists(destFile+stateSuffix))
+	assert.False(t, fileutil.Exists(destFile+".etag"))
+
+	output, err := httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL}, destFile)
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsUpdated)
+	content, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, server.content, content)
+	assert.Equal(t, "bytes=4000-9999", server.ranges[len(server.ranges)-1])
+}
+
+func TestHttpDownloadRestartsWhenFileChanges(t *testing.T) {
+	destFile := setupResumableTest(t, downloadSettings{parallelism: 1, retryLimit: 0})
+	server := newRangeServer(randomContent(10000))
+	defer server.Close()
+	server.cutAfter = 4000
+	server.failures = 2
+
+	_, err := httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL}, destFile)
+	assert.Error(t, err)
+
+	server.content, server.eTag = randomContent(10000), `"v2"`
+	_, err = httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL}, destFile)
+
+	assert.NoError(t, err)
+	content, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, server.content, content)
+	assert.Equal(t, "bytes=0-9999", server.ranges[len(server.ranges)-1])
+}
+
+func TestHttpDownloadChangedDuringDownload(t *testing.T) {
+	destFile := setupResumableTest(t, downloadSettings{parallelism: 1})
+	content := randomContent(10000)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		eTag := `"v1"`
+		if r.Header.Get("Range") != "" {
+			eTag = `"v2"`
+		}
+		w.Header().Set("ETag", eTag)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	_, err := httpDownload(newTestLog(), DownloadInput{SourceURL: server.URL}, destFile)
+
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "changed"))
+	assert.False(t, fileutil.Exists(destFile))
+	assert.False(t, fileutil.Exists(destFile+stateSuffix))
+}
+
+func TestBandwidthLimiter(t *testing.T) {
+	var slept time.Duration
+	savedSleep := sleep
+	sleep = func(d time.Duration) { slept = d }
+	defer func() { sleep = savedSleep }()
+
+	l := &bandwidthLimiter{}
+	l.wait(1024)
+	assert.Equal(t, time.Duration(0), slept)
+
+	l.setRate(1024)
+	for i := 0; i < 4; i++ {
+		l.wait(512)
+	}
+	assert.InDelta(t, float64(2*time.Second), float64(slept), float64(100*time.Millisecond))
+}
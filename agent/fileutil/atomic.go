@@ -0,0 +1,71 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fileutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteAtomically writes the data to a temporary file in the directory of filename, flushes it to disk and
+// renames it to filename, so that a reader, or the agent after a crash, finds either the previous content or
+// the new one, never a partial write. syncDir also flushes the directory, which makes the rename itself
+// survive a power loss; use it for the files the agent cannot start without.
+func WriteAtomically(filename string, data []byte, perm os.FileMode, syncDir bool) error {
+	return writeAtomically(filename, data, perm, syncDir, nil)
+}
+
+// writeAtomically writes the data atomically, prepare is applied to the temporary file before the rename.
+func writeAtomically(filename string, data []byte, perm os.FileMode, syncDir bool, prepare func(path string) error) (err error) {
+	dir := filepath.Dir(filename)
+	tmp, err := ioutil.TempFile(LongPath(dir), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary file for %v: %v", filename, err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set the permissions of %v: %v", filename, err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write %v: %v", filename, err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to flush %v: %v", filename, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %v: %v", filename, err)
+	}
+	if prepare != nil {
+		if err = prepare(tmp.Name()); err != nil {
+			return err
+		}
+	}
+	if err = os.Rename(tmp.Name(), LongPath(filename)); err != nil {
+		return fmt.Errorf("failed to replace %v: %v", filename, err)
+	}
+	if syncDir {
+		if err = syncDirectory(dir); err != nil {
+			return fmt.Errorf("failed to flush the directory of %v: %v", filename, err)
+		}
+	}
+	return nil
+}
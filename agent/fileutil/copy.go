@@ -0,0 +1,235 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package fileutil contains utilities for working with the file system.
+package fileutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// CopyLinkPolicy is what CopyTree and MoveTree do with the symbolic links of the tree
+type CopyLinkPolicy int
+
+const (
+	// CopyLinksPreserve copies the links as links, with the same target
+	CopyLinksPreserve CopyLinkPolicy = iota
+	// CopyLinksFollow copies the files and directories the links point to in place of the links
+	CopyLinksFollow
+	// CopyLinksReject fails on any link
+	CopyLinksReject
+)
+
+// CopyOptions are how CopyTree and MoveTree copy a tree
+type CopyOptions struct {
+	// Links is what happens to the symbolic links of the tree
+	Links CopyLinkPolicy
+	// PreserveOwner keeps the owner and group of the files, which needs root; the copies belong to the process otherwise
+	PreserveOwner bool
+}
+
+// sparseBlockSize is the size of the blocks of zeros left as holes in the copy of a sparse file
+const sparseBlockSize = 4096
+
+// CopyTree copies the file, directory or link src to dst, replacing the files and links at dst and merging the
+// directories. The permission bits, the special bits and the modification times are preserved, as well as the owners
+// with PreserveOwner; the holes of sparse files stay holes. The files at dst are created, never written through an
+// existing link.
+func CopyTree(src, dst string, options CopyOptions) error {
+	info, err := os.Lstat(LongPath(src))
+	if err != nil {
+		return err
+	}
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	absDst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && isUnderDir(absDst, absSrc) {
+		return fmt.Errorf("cannot copy %v into itself, %v", src, dst)
+	}
+	c := copier{options: options}
+	return c.copy(src, dst, info)
+}
+
+// MoveTree moves src to dst. The tree is renamed when the links are preserved and dst is on the same volume;
+// otherwise it's copied with CopyTree, then removed.
+func MoveTree(src, dst string, options CopyOptions) error {
+	if options.Links == CopyLinksPreserve {
+		err := os.Rename(LongPath(src), LongPath(dst))
+		if err == nil || !isCrossDevice(err) {
+			return err
+		}
+	}
+	if err := CopyTree(src, dst, options); err != nil {
+		return err
+	}
+	return os.RemoveAll(LongPath(src))
+}
+
+// copier copies a tree within the limits of the options
+type copier struct {
+	options CopyOptions
+	// dirs are the directories being copied, a followed link back to one of them is a loop
+	dirs []os.FileInfo
+}
+
+func (c *copier) copy(src, dst string, info os.FileInfo) (err error) {
+	if info.Mode()&os.ModeSymlink != 0 {
+		switch c.options.Links {
+		case CopyLinksReject:
+			return fmt.Errorf("%v is a link, the tree may not contain links", src)
+		case CopyLinksPreserve:
+			return c.copyLink(src, dst, info)
+		}
+		if info, err = os.Stat(LongPath(src)); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case info.IsDir():
+		return c.copyDir(src, dst, info)
+	case info.Mode().IsRegular():
+		if err = copyContent(src, dst, info); err != nil {
+			return err
+		}
+		return c.preserve(dst, info)
+	default:
+		return fmt.Errorf("%v isn't a file, a directory or a link", src)
+	}
+}
+
+func (c *copier) copyDir(src, dst string, info os.FileInfo) error {
+	for _, dir := range c.dirs {
+		if os.SameFile(dir, info) {
+			return fmt.Errorf("%v is a loop of links", src)
+		}
+	}
+	c.dirs = append(c.dirs, info)
+	defer func() { c.dirs = c.dirs[:len(c.dirs)-1] }()
+
+	// the directory stays writable until its content is copied
+	if existing, err := os.Lstat(LongPath(dst)); err == nil && !existing.IsDir() {
+		if err = os.Remove(LongPath(dst)); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(LongPath(dst), 0700); err != nil {
+		return err
+	}
+	entries, err := ioutil.ReadDir(LongPath(src))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err = c.copy(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), entry); err != nil {
+			return err
+		}
+	}
+	return c.preserve(dst, info)
+}
+
+func (c *copier) copyLink(src, dst string, info os.FileInfo) error {
+	target, err := os.Readlink(LongPath(src))
+	if err != nil {
+		return err
+	}
+	if err = removeEntry(dst); err != nil {
+		return err
+	}
+	if err = os.Symlink(target, LongPath(dst)); err != nil {
+		return err
+	}
+	if c.options.PreserveOwner {
+		return lchown(dst, info)
+	}
+	return nil
+}
+
+// preserve gives dst the owner, the mode and the modification time of info; the owner first, as changing it
+// clears the setuid and setgid bits
+func (c *copier) preserve(dst string, info os.FileInfo) error {
+	if c.options.PreserveOwner {
+		if err := lchown(dst, info); err != nil {
+			return err
+		}
+	}
+	if err := os.Chmod(LongPath(dst), info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return err
+	}
+	return os.Chtimes(LongPath(dst), info.ModTime(), info.ModTime())
+}
+
+// copyContent copies the content of the file src to a new file dst
+func copyContent(src, dst string, info os.FileInfo) error {
+	in, err := os.Open(LongPath(src))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err = removeEntry(dst); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(LongPath(dst), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if isSparse(info) {
+		err = copySparse(out, in, info.Size())
+	} else {
+		_, err = io.Copy(out, in)
+	}
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copySparse copies in to out, seeking over the blocks of zeros instead of writing them so that they stay holes
+func copySparse(out *os.File, in io.Reader, size int64) error {
+	buf := make([]byte, sparseBlockSize)
+	zeros := make([]byte, sparseBlockSize)
+	for {
+		n, err := io.ReadFull(in, buf)
+		if n > 0 {
+			var writeErr error
+			if bytes.Equal(buf[:n], zeros[:n]) {
+				_, writeErr = out.Seek(int64(n), io.SeekCurrent)
+			} else {
+				_, writeErr = out.Write(buf[:n])
+			}
+			if writeErr != nil {
+				return writeErr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// a hole at the end of the file is only made by its size
+	return out.Truncate(size)
+}
@@ -0,0 +1,144 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// copyTestTree creates a file, a directory and, outside Windows, a link to the file under dir
+func copyTestTree(t *testing.T, dir string) string {
+	src := filepath.Join(dir, "src")
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "sub", "file"), []byte("content"), 0600))
+	assert.NoError(t, os.Chmod(filepath.Join(src, "sub", "file"), 0750))
+	if runtime.GOOS != "windows" {
+		assert.NoError(t, os.Symlink(filepath.Join("sub", "file"), filepath.Join(src, "link")))
+	}
+	return src
+}
+
+func TestCopyTreePreservesModesTimesAndLinks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "copy")
+	defer os.RemoveAll(dir)
+	src := copyTestTree(t, dir)
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	assert.NoError(t, os.Chtimes(filepath.Join(src, "sub", "file"), modTime, modTime))
+	dst := filepath.Join(dir, "dst")
+
+	assert.NoError(t, CopyTree(src, dst, CopyOptions{}))
+	content, _ := ioutil.ReadFile(filepath.Join(dst, "sub", "file"))
+	assert.Equal(t, "content", string(content))
+	info, err := os.Stat(filepath.Join(dst, "sub", "file"))
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(modTime))
+	if runtime.GOOS == "windows" {
+		return
+	}
+	assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+	target, err := os.Readlink(filepath.Join(dst, "link"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("sub", "file"), target)
+}
+
+func TestCopyTreeFollowsOrRejectsLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating links needs a privilege on Windows")
+	}
+	dir, _ := ioutil.TempDir("", "copy")
+	defer os.RemoveAll(dir)
+	src := copyTestTree(t, dir)
+
+	dst := filepath.Join(dir, "followed")
+	assert.NoError(t, CopyTree(src, dst, CopyOptions{Links: CopyLinksFollow}))
+	info, err := os.Lstat(filepath.Join(dst, "link"))
+	assert.NoError(t, err)
+	assert.True(t, info.Mode().IsRegular())
+
+	assert.Error(t, CopyTree(src, filepath.Join(dir, "rejected"), CopyOptions{Links: CopyLinksReject}))
+
+	assert.NoError(t, os.Symlink("..", filepath.Join(src, "sub", "loop")))
+	assert.Error(t, CopyTree(src, filepath.Join(dir, "loop"), CopyOptions{Links: CopyLinksFollow}))
+}
+
+func TestCopyTreeDoesNotWriteThroughLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating links needs a privilege on Windows")
+	}
+	dir, _ := ioutil.TempDir("", "copy")
+	defer os.RemoveAll(dir)
+	src := copyTestTree(t, dir)
+	outside := filepath.Join(dir, "outside")
+	assert.NoError(t, ioutil.WriteFile(outside, []byte("outside"), 0600))
+	dst := filepath.Join(dir, "dst")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dst, "sub"), 0700))
+	assert.NoError(t, os.Symlink(outside, filepath.Join(dst, "sub", "file")))
+
+	assert.NoError(t, CopyTree(src, dst, CopyOptions{}))
+	content, _ := ioutil.ReadFile(outside)
+	assert.Equal(t, "outside", string(content))
+	info, _ := os.Lstat(filepath.Join(dst, "sub", "file"))
+	assert.True(t, info.Mode().IsRegular())
+}
+
+func TestCopyTreeIntoItselfFails(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "copy")
+	defer os.RemoveAll(dir)
+	src := copyTestTree(t, dir)
+
+	assert.Error(t, CopyTree(src, filepath.Join(src, "sub", "copy"), CopyOptions{}))
+}
+
+func TestCopyTreeKeepsHoles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sparse files aren't copied with holes on Windows")
+	}
+	dir, _ := ioutil.TempDir("", "copy")
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "sparse")
+	f, _ := os.Create(src)
+	f.WriteAt([]byte("end"), 1<<20)
+	f.Close()
+	info, _ := os.Stat(src)
+	if !isSparse(info) {
+		t.Skip("the filesystem doesn't support holes")
+	}
+
+	dst := filepath.Join(dir, "copy")
+	assert.NoError(t, CopyTree(src, dst, CopyOptions{}))
+	srcContent, _ := ioutil.ReadFile(src)
+	dstContent, _ := ioutil.ReadFile(dst)
+	assert.Equal(t, srcContent, dstContent)
+	info, _ = os.Stat(dst)
+	assert.True(t, isSparse(info))
+}
+
+func TestMoveTreeCopiesAndRemovesSource(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "copy")
+	defer os.RemoveAll(dir)
+	src := copyTestTree(t, dir)
+	dst := filepath.Join(dir, "dst")
+
+	assert.NoError(t, MoveTree(src, dst, CopyOptions{Links: CopyLinksFollow}))
+	assert.False(t, Exists(src))
+	content, _ := ioutil.ReadFile(filepath.Join(dst, "sub", "file"))
+	assert.Equal(t, "content", string(content))
+}
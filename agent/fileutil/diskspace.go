@@ -0,0 +1,94 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fileutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// megabyte is the unit of Agent.MinFreeDiskSpaceMB
+const megabyte = 1024 * 1024
+
+var getDiskSpaceInfoForPath = GetDiskSpaceInfoForPath
+
+// InsufficientDiskSpaceError is returned when writing to a filesystem would leave less than
+// Agent.MinFreeDiskSpaceMB free
+type InsufficientDiskSpaceError struct {
+	Path           string
+	AvailableBytes int64
+	RequiredBytes  int64
+	ReservedBytes  int64
+}
+
+// Error tells how much space is missing and how to change the reserved space.
+func (e InsufficientDiskSpaceError) Error() string {
+	needed := ""
+	if e.RequiredBytes > 0 {
+		needed = fmt.Sprintf(", %v needed", formatMegabytes(e.RequiredBytes))
+	}
+	return fmt.Sprintf("not enough disk space to write %v: %v available%v and %v kept free (Agent.MinFreeDiskSpaceMB)",
+		e.Path, formatMegabytes(e.AvailableBytes), needed, formatMegabytes(e.ReservedBytes))
+}
+
+// formatMegabytes formats a size in MB, rounded up
+func formatMegabytes(bytes int64) string {
+	return fmt.Sprintf("%v MB", (bytes+megabyte-1)/megabyte)
+}
+
+// CheckDiskSpace fails with an InsufficientDiskSpaceError when writing requiredBytes to path would leave less than
+// Agent.MinFreeDiskSpaceMB free on its filesystem. The path doesn't have to exist yet; requiredBytes is 0 when
+// the size isn't known.
+func CheckDiskSpace(path string, requiredBytes int64) error {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		config = appconfig.DefaultConfig()
+	}
+	reservedBytes := int64(config.Agent.MinFreeDiskSpaceMB) * megabyte
+	if reservedBytes <= 0 {
+		return nil
+	}
+	if requiredBytes < 0 {
+		requiredBytes = 0
+	}
+
+	// the space is the one of the filesystem of the closest existing directory
+	existing := filepath.Clean(path)
+	for {
+		if _, err := os.Stat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	info, err := getDiskSpaceInfoForPath(existing)
+	if err != nil {
+		return fmt.Errorf("failed to get the disk space of %v: %v", existing, err)
+	}
+	if info.AvailBytes-requiredBytes < reservedBytes {
+		return InsufficientDiskSpaceError{
+			Path:           path,
+			AvailableBytes: info.AvailBytes,
+			RequiredBytes:  requiredBytes,
+			ReservedBytes:  reservedBytes,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fileutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "diskspace")
+	defer os.RemoveAll(dir)
+	var checked string
+	defer func() { getDiskSpaceInfoForPath = GetDiskSpaceInfoForPath }()
+	getDiskSpaceInfoForPath = func(path string) (DiskSpaceInfo, error) {
+		checked = path
+		return DiskSpaceInfo{AvailBytes: 150 * megabyte}, nil
+	}
+	path := filepath.Join(dir, "orchestration", "stdout")
+
+	assert.NoError(t, CheckDiskSpace(path, 0))
+	assert.Equal(t, dir, checked, "the closest existing directory is checked")
+	assert.NoError(t, CheckDiskSpace(path, 50*megabyte))
+
+	err := CheckDiskSpace(path, 60*megabyte)
+	assert.Equal(t, InsufficientDiskSpaceError{
+		Path:           path,
+		AvailableBytes: 150 * megabyte,
+		RequiredBytes:  60 * megabyte,
+		ReservedBytes:  100 * megabyte,
+	}, err)
+	assert.Equal(t, fmt.Sprintf("not enough disk space to write %v: 150 MB available, 60 MB needed and 100 MB kept free (Agent.MinFreeDiskSpaceMB)", path), err.Error())
+}
+
+func TestCheckDiskSpaceFailure(t *testing.T) {
+	defer func() { getDiskSpaceInfoForPath = GetDiskSpaceInfoForPath }()
+	getDiskSpaceInfoForPath = func(path string) (DiskSpaceInfo, error) {
+		return DiskSpaceInfo{}, fmt.Errorf("statfs failed")
+	}
+
+	assert.Error(t, CheckDiskSpace(os.TempDir(), 0))
+}
+
+func TestGetDiskSpaceInfoForPath(t *testing.T) {
+	info, err := GetDiskSpaceInfoForPath(os.TempDir())
+	assert.NoError(t, err)
+	assert.True(t, info.TotalBytes > 0)
+	assert.True(t, info.AvailBytes <= info.TotalBytes)
+}
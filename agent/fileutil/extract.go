@@ -0,0 +1,436 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fileutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// SymlinkPolicy is what the extraction does with the symbolic and hard links of an archive
+type SymlinkPolicy int
+
+const (
+	// SymlinksInside keeps the links to a target in the destination and fails on the others
+	SymlinksInside SymlinkPolicy = iota
+	// SymlinksSkip leaves the links out
+	SymlinksSkip
+	// SymlinksReject fails on any link
+	SymlinksReject
+)
+
+// ExtractOptions limits what the extraction of an archive writes
+type ExtractOptions struct {
+	// Symlinks is what happens to the links of the archive
+	Symlinks SymlinkPolicy
+	// MaxBytes is the total size of the extracted files, 0 for no limit
+	MaxBytes int64
+	// MaxEntries is the number of files, directories and links extracted, 0 for no limit
+	MaxEntries int
+}
+
+// DefaultExtractOptions are the limits of Unzip and Uncompress
+var DefaultExtractOptions = ExtractOptions{
+	Symlinks:   SymlinksInside,
+	MaxBytes:   10 << 30,
+	MaxEntries: 100000,
+}
+
+// ErrExtractLimit is returned when an archive exceeds the limits of ExtractOptions
+var ErrExtractLimit = errors.New("the archive exceeds the extraction limits")
+
+var (
+	zipMagic      = []byte("PK")
+	gzipMagic     = []byte{0x1f, 0x8b}
+	zstdMagic     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	xzMagic       = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	tarMagicStart = 257
+	tarMagic      = []byte("ustar")
+)
+
+// compressedExtensions are removed from the name of a compressed file that isn't a tar archive
+var compressedExtensions = []string{".gz", ".zst", ".xz"}
+
+// Extract extracts the zip, tar, tar.gz, tar.zst or tar.xz archive src to the directory dest. A file compressed
+// with gzip, zstd or xz that isn't a tar archive is written to dest, without its extension. The format is
+// detected from the content, not the name. The entries are checked to stay in dest, and their permission
+// bits are preserved; the owners, the special bits and the special files aren't.
+func Extract(src, dest string, options ExtractOptions) (err error) {
+	src = LongPath(src)
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(xzMagic))
+	n, _ := io.ReadFull(file, header)
+	header = header[:n]
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	e := newExtractor(dest, options)
+	if err = os.MkdirAll(LongPath(e.dest), 0700); err != nil {
+		return err
+	}
+	defer func() {
+		if finishErr := e.finish(); err == nil {
+			err = finishErr
+		}
+	}()
+
+	var r io.Reader
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		return e.extractZip(src)
+	case bytes.HasPrefix(header, gzipMagic):
+		gr, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	case bytes.HasPrefix(header, zstdMagic):
+		zr, err := zstd.NewReader(file, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	case bytes.HasPrefix(header, xzMagic):
+		if r, err = xz.NewReader(file); err != nil {
+			return err
+		}
+	default:
+		return e.extractTar(file)
+	}
+
+	br := bufio.NewReader(r)
+	if peek, _ := br.Peek(tarMagicStart + len(tarMagic)); bytes.HasSuffix(peek, tarMagic) && len(peek) == tarMagicStart+len(tarMagic) {
+		return e.extractTar(br)
+	}
+	name := filepath.Base(src)
+	for _, extension := range compressedExtensions {
+		name = strings.TrimSuffix(name, extension)
+	}
+	return e.extractFile(name, 0644, 0, br)
+}
+
+// extractor writes the entries of an archive to dest within the limits of the options
+type extractor struct {
+	dest    string
+	options ExtractOptions
+	bytes   int64
+	entries int
+	// dirs are the permissions of the directories, set once their content is written
+	dirs map[string]os.FileMode
+	// links are the symbolic links, checked once all their targets are written
+	links []string
+}
+
+func newExtractor(dest string, options ExtractOptions) *extractor {
+	return &extractor{dest: filepath.Clean(dest), options: options, dirs: make(map[string]os.FileMode)}
+}
+
+// extractZip extracts the entries of a zip archive
+func (e *extractor) extractZip(src string) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = e.extractDir(f.Name, mode)
+		case mode&os.ModeSymlink != 0:
+			err = e.extractZipLink(f)
+		case mode.IsRegular():
+			err = e.extractZipFile(f)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) extractZipFile(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return e.extractFile(f.Name, f.Mode(), int64(f.UncompressedSize64), rc)
+}
+
+func (e *extractor) extractZipLink(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	// the content of a link is its target
+	target, err := ioutil.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return err
+	}
+	return e.extractLink(f.Name, string(target), false)
+}
+
+// extractTar extracts the entries of a tar archive
+func (e *extractor) extractTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = e.extractDir(hdr.Name, hdr.FileInfo().Mode())
+		case tar.TypeReg, tar.TypeRegA:
+			err = e.extractFile(hdr.Name, hdr.FileInfo().Mode(), hdr.Size, tr)
+		case tar.TypeSymlink:
+			err = e.extractLink(hdr.Name, hdr.Linkname, false)
+		case tar.TypeLink:
+			err = e.extractLink(hdr.Name, hdr.Linkname, true)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// path returns where the entry is extracted, failing when it is outside dest or under a link
+func (e *extractor) path(name string) (string, error) {
+	path := filepath.Join(e.dest, filepath.FromSlash(name))
+	if path != e.dest && !isUnderDir(path, e.dest) {
+		return "", fmt.Errorf("%v attempts to place files outside %v subtree", name, e.dest)
+	}
+	// nothing is written through a link, whatever its target
+	for dir := filepath.Dir(path); dir != e.dest && isUnderDir(dir, e.dest); dir = filepath.Dir(dir) {
+		if info, err := os.Lstat(LongPath(dir)); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%v is under the link %v", name, dir)
+		}
+	}
+	return path, nil
+}
+
+// count counts an entry against the limit
+func (e *extractor) count() error {
+	e.entries++
+	if e.options.MaxEntries > 0 && e.entries > e.options.MaxEntries {
+		return fmt.Errorf("%v: more than %v entries", ErrExtractLimit, e.options.MaxEntries)
+	}
+	return nil
+}
+
+func (e *extractor) extractDir(name string, mode os.FileMode) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	if err = e.count(); err != nil {
+		return err
+	}
+	// the directory stays writable until its content is written
+	if err = os.MkdirAll(LongPath(path), 0700); err != nil {
+		return err
+	}
+	e.dirs[path] = mode.Perm()
+	return nil
+}
+
+// extractFile writes the file of the archive, size is the size it announces, 0 if it doesn't
+func (e *extractor) extractFile(name string, mode os.FileMode, size int64, r io.Reader) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	if err = e.count(); err != nil {
+		return err
+	}
+	if err = CheckDiskSpace(path, size); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(LongPath(filepath.Dir(path)), 0700); err != nil {
+		return err
+	}
+	// a link extracted earlier at the path isn't followed
+	if err = removeEntry(path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(LongPath(path), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if e.options.MaxBytes > 0 {
+		r = io.LimitReader(r, e.options.MaxBytes-e.bytes+1)
+	}
+	written, err := io.Copy(f, r)
+	e.bytes += written
+	if err != nil {
+		return err
+	}
+	if e.options.MaxBytes > 0 && e.bytes > e.options.MaxBytes {
+		return fmt.Errorf("%v: more than %v bytes", ErrExtractLimit, e.options.MaxBytes)
+	}
+	return f.Chmod(mode.Perm())
+}
+
+func (e *extractor) extractLink(name string, target string, hard bool) error {
+	if e.options.Symlinks == SymlinksSkip {
+		return nil
+	}
+	if e.options.Symlinks == SymlinksReject {
+		return fmt.Errorf("%v is a link, the archive may not contain links", name)
+	}
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+
+	// the target of a hard link is a file of the archive, the target of a symbolic link is relative to the link
+	if hard {
+		if target, err = e.path(target); err != nil {
+			return err
+		}
+		if info, err := os.Lstat(LongPath(target)); err != nil || !info.Mode().IsRegular() {
+			return fmt.Errorf("%v links to %v, which isn't a file of the archive", name, target)
+		}
+	} else {
+		resolved := filepath.Join(filepath.Dir(path), filepath.FromSlash(target))
+		if filepath.IsAbs(filepath.FromSlash(target)) || (resolved != e.dest && !isUnderDir(resolved, e.dest)) {
+			return fmt.Errorf("%v links to %v, outside %v subtree", name, target, e.dest)
+		}
+	}
+
+	if err = e.count(); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(LongPath(filepath.Dir(path)), 0700); err != nil {
+		return err
+	}
+	if err = removeEntry(path); err != nil {
+		return err
+	}
+	if hard {
+		return os.Link(LongPath(target), LongPath(path))
+	}
+	e.links = append(e.links, path)
+	return os.Symlink(target, LongPath(path))
+}
+
+// removeEntry removes the file or link at path, if any
+func removeEntry(path string) error {
+	if info, err := os.Lstat(LongPath(path)); err == nil && !info.IsDir() {
+		return os.Remove(LongPath(path))
+	}
+	return nil
+}
+
+// finish checks the symbolic links resolve in dest, and sets the permissions of the directories,
+// the deepest first
+func (e *extractor) finish() error {
+	// the targets of the links may go through other links of the archive, e.g. a link to . followed by ..
+	if len(e.links) > 0 {
+		realDest, err := filepath.EvalSymlinks(e.dest)
+		if err != nil {
+			return err
+		}
+		for _, link := range e.links {
+			target, err := os.Readlink(LongPath(link))
+			if err != nil {
+				return err
+			}
+			dir := filepath.Join(realDest, strings.TrimPrefix(filepath.Dir(link), e.dest))
+			resolved, err := resolveLinks(dir, target, 0)
+			if err != nil || (resolved != realDest && !isUnderDir(resolved, realDest)) {
+				os.Remove(LongPath(link))
+				return fmt.Errorf("%v links to %v, outside %v subtree", link, target, e.dest)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(e.dirs))
+	for path := range e.dirs {
+		paths = append(paths, path)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	for _, path := range paths {
+		if err := os.Chmod(LongPath(path), e.dirs[path]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxLinkDepth is the number of links resolveLinks follows
+const maxLinkDepth = 40
+
+// resolveLinks applies the relative path to the directory dir, which has no link, following the links on the
+// way. The part of the path that doesn't exist is applied as is.
+func resolveLinks(dir string, path string, depth int) (string, error) {
+	if depth > maxLinkDepth {
+		return "", fmt.Errorf("too many levels of links")
+	}
+	for _, component := range strings.Split(filepath.ToSlash(path), "/") {
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			dir = filepath.Dir(dir)
+			continue
+		}
+		next := filepath.Join(dir, component)
+		info, err := os.Lstat(LongPath(next))
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			dir = next
+			continue
+		}
+		target, err := os.Readlink(LongPath(next))
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			dir = filepath.VolumeName(target) + string(filepath.Separator)
+		}
+		if dir, err = resolveLinks(dir, target, depth+1); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package fileutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/ulikunitz/xz"
+)
+
+// archiveEntry is an entry of the archives of the tests
+type archiveEntry struct {
+	name     string
+	mode     int64
+	content  string
+	linkname string
+	hard     bool
+}
+
+var sampleEntries = []archiveEntry{
+	{name: "bin/", mode: 0755},
+	{name: "bin/install", mode: 0755, content: "#!/bin/sh\necho installed\n"},
+	{name: "etc/config.json", mode: 0640, content: `{"enabled": true}`},
+	{name: "bin/setup", linkname: "install"},
+	{name: "lib/install", linkname: "bin/install", hard: true},
+}
+
+func writeTar(w io.Writer, entries []archiveEntry) error {
+	tw := tar.NewWriter(w)
+	for _, entry := range entries {
+		hdr := &tar.Header{Name: entry.name, Mode: entry.mode, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		switch {
+		case strings.HasSuffix(entry.name, "/"):
+			hdr.Typeflag = tar.TypeDir
+		case entry.linkname != "" && entry.hard:
+			hdr.Typeflag, hdr.Linkname, hdr.Mode = tar.TypeLink, entry.linkname, 0644
+		case entry.linkname != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Mode = tar.TypeSymlink, entry.linkname, 0777
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, entries []archiveEntry) error {
+	zw := zip.NewWriter(w)
+	for _, entry := range entries {
+		if entry.hard {
+			continue
+		}
+		hdr := &zip.FileHeader{Name: entry.name}
+		hdr.SetMode(os.FileMode(entry.mode))
+		content := entry.content
+		if entry.linkname != "" {
+			hdr.SetMode(os.ModeSymlink | 0777)
+			content = entry.linkname
+		} else if strings.HasSuffix(entry.name, "/") {
+			hdr.SetMode(os.ModeDir | os.FileMode(entry.mode))
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err = fw.Write([]byte(content)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeArchive writes the entries as an archive of the format to a file of dir
+func writeArchive(t *testing.T, dir string, format string, entries []archiveEntry) string {
+	var buffer bytes.Buffer
+	var err error
+	switch format {
+	case "zip":
+		err = writeZip(&buffer, entries)
+	case "tar":
+		err = writeTar(&buffer, entries)
+	case "tar.gz":
+		gw := gzip.NewWriter(&buffer)
+		if err = writeTar(gw, entries); err == nil {
+			err = gw.Close()
+		}
+	case "tar.zst":
+		var zw *zstd.Encoder
+		if zw, err = zstd.NewWriter(&buffer); err == nil {
+			if err = writeTar(zw, entries); err == nil {
+				err = zw.Close()
+			}
+		}
+	case "tar.xz":
+		var xw *xz.Writer
+		if xw, err = xz.NewWriter(&buffer); err == nil {
+			if err = writeTar(xw, entries); err == nil {
+				err = xw.Close()
+			}
+		}
+	}
+	assert.NoError(t, err)
+	// no extension, the format is detected from the content
+	path := filepath.Join(dir, "package")
+	assert.NoError(t, ioutil.WriteFile(path, buffer.Bytes(), 0600))
+	return path
+}
+
+func TestExtractFormats(t *testing.T) {
+	for _, format := range []string{"zip", "tar", "tar.gz", "tar.zst", "tar.xz"} {
+		t.Run(format, func(t *testing.T) {
+			dir, _ := ioutil.TempDir("", "extract")
+			defer os.RemoveAll(dir)
+			src := writeArchive(t, dir, format, sampleEntries)
+			dest := filepath.Join(dir, "dest")
+
+			assert.NoError(t, Extract(src, dest, DefaultExtractOptions))
+
+			content, err := ioutil.ReadFile(filepath.Join(dest, "bin", "setup"))
+			assert.NoError(t, err)
+			assert.Equal(t, "#!/bin/sh\necho installed\n", string(content))
+			info, err := os.Stat(filepath.Join(dest, "bin", "install"))
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+			info, err = os.Stat(filepath.Join(dest, "etc", "config.json"))
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+			if format != "zip" {
+				content, err = ioutil.ReadFile(filepath.Join(dest, "lib", "install"))
+				assert.NoError(t, err)
+				assert.Equal(t, "#!/bin/sh\necho installed\n", string(content))
+			}
+		})
+	}
+}
+
+func TestExtractCompressedFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "extract")
+	defer os.RemoveAll(dir)
+	var buffer bytes.Buffer
+	xw, _ := xz.NewWriter(&buffer)
+	xw.Write([]byte("not an archive"))
+	xw.Close()
+	src := filepath.Join(dir, "notes.txt.xz")
+	ioutil.WriteFile(src, buffer.Bytes(), 0600)
+
+	assert.NoError(t, Extract(src, filepath.Join(dir, "dest"), DefaultExtractOptions))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "dest", "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "not an archive", string(content))
+}
+
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	testCases := []struct {
+		name    string
+		entries []archiveEntry
+		message string
+	}{
+		{"path traversal", []archiveEntry{{name: "../evil", mode: 0644, content: "x"}}, "outside"},
+		{"link outside", []archiveEntry{{name: "passwd", linkname: "../../etc/passwd"}}, "outside"},
+		{"absolute link", []archiveEntry{{name: "passwd", linkname: "/etc/passwd"}}, "outside"},
+		{"write through a link", []archiveEntry{{name: "sub/", mode: 0755}, {name: "up", linkname: "sub"}, {name: "up/file", mode: 0644, content: "x"}}, "under the link"},
+		{"links through links", []archiveEntry{{name: "self", linkname: "."}, {name: "escape", linkname: "self/.."}}, "outside"},
+		{"hard link outside", []archiveEntry{{name: "passwd", linkname: "../../etc/passwd", hard: true}}, "outside"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir, _ := ioutil.TempDir("", "extract")
+			defer os.RemoveAll(dir)
+			src := writeArchive(t, dir, "tar.gz", testCase.entries)
+			dest := filepath.Join(dir, "dest")
+
+			err := Extract(src, dest, DefaultExtractOptions)
+
+			assert.Error(t, err)
+			if err != nil {
+				assert.Contains(t, err.Error(), testCase.message)
+			}
+			_, err = os.Lstat(filepath.Join(dir, "evil"))
+			assert.True(t, os.IsNotExist(err))
+			_, err = os.Lstat(filepath.Join(dest, "escape"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestExtractSymlinkPolicy(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "extract")
+	defer os.RemoveAll(dir)
+	src := writeArchive(t, dir, "tar", sampleEntries)
+
+	dest := filepath.Join(dir, "skip")
+	assert.NoError(t, Extract(src, dest, ExtractOptions{Symlinks: SymlinksSkip}))
+	_, err := os.Lstat(filepath.Join(dest, "bin", "setup"))
+	assert.True(t, os.IsNotExist(err))
+	assert.True(t, Exists(filepath.Join(dest, "bin", "install")))
+
+	err = Extract(src, filepath.Join(dir, "reject"), ExtractOptions{Symlinks: SymlinksReject})
+	assert.Error(t, err)
+}
+
+func TestExtractLimits(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "extract")
+	defer os.RemoveAll(dir)
+	src := writeArchive(t, dir, "tar.zst", []archiveEntry{
+		{name: "a", mode: 0644, content: strings.Repeat("a", 1000)},
+		{name: "b", mode: 0644, content: strings.Repeat("b", 1000)},
+	})
+
+	err := Extract(src, filepath.Join(dir, "bytes"), ExtractOptions{MaxBytes: 1500})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), ErrExtractLimit.Error())
+	info, _ := os.Stat(filepath.Join(dir, "bytes", "b"))
+	assert.True(t, info.Size() <= 501, "the extraction stops at the limit")
+
+	err = Extract(src, filepath.Join(dir, "entries"), ExtractOptions{MaxEntries: 1})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), ErrExtractLimit.Error())
+
+	assert.NoError(t, Extract(src, filepath.Join(dir, "within"), ExtractOptions{MaxBytes: 2000, MaxEntries: 2}))
+}
@@ -18,6 +18,7 @@ import (
 	"math/rand"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
@@ -32,12 +33,20 @@ type FileLocker interface {
 	Unlock(lockPath string, ownerId string) (hadLock bool, err error)
 }
 
-// TODO: Write platform-specific implementations instead.
+// fileLocker implements FileLocker with the advisory locks of the operating system. The locks of a process
+// that exits are released at once, so the timeout of the locks is not needed.
 type fileLocker struct {
+	mutex sync.Mutex
+	held  map[string]heldLock
+}
+
+type heldLock struct {
+	ownerId string
+	lock    *Lock
 }
 
 func NewFileLocker() FileLocker {
-	return &fileLocker{}
+	return &fileLocker{held: make(map[string]heldLock)}
 }
 
 func GetOwnerIdForProcess() string {
@@ -47,13 +56,43 @@ func GetOwnerIdForProcess() string {
 }
 
 func (fl *fileLocker) Lock(lockPath string, ownerId string, timeoutSeconds int) (locked bool, err error) {
-	locked, err = LockFile(lockPath, ownerId, timeoutSeconds)
-	return
+	fl.mutex.Lock()
+	defer fl.mutex.Unlock()
+
+	if _, ok := fl.held[lockPath]; ok {
+		return false, nil
+	}
+	var lock *Lock
+	if lock, err = TryLock(lockPath); err == ErrLocked {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	// the owner is written for whoever looks at the lock file, the lock does not depend on it
+	if err = lock.file.Truncate(0); err == nil {
+		_, err = lock.file.WriteAt([]byte(ownerId), 0)
+	}
+	if err != nil {
+		lock.Unlock()
+		return false, fmt.Errorf("Error writing to lock file. %v", err)
+	}
+	fl.held[lockPath] = heldLock{ownerId: ownerId, lock: lock}
+	return true, nil
 }
 
 func (fl *fileLocker) Unlock(lockPath string, ownerId string) (hadLock bool, err error) {
-	hadLock, err = UnlockFile(lockPath, ownerId)
-	return
+	fl.mutex.Lock()
+	defer fl.mutex.Unlock()
+
+	held, ok := fl.held[lockPath]
+	if !ok || held.ownerId != ownerId {
+		return false, nil
+	}
+	delete(fl.held, lockPath)
+	if err = held.lock.Unlock(); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 func statLockFile(lockPath string) (exists bool, modificationTime time.Time, err error) {
@@ -125,6 +164,9 @@ func expireIfLockFileTimeout(lockPath string, timeoutSeconds int) (err error) {
 	return nil
 }
 
+// LockFile locks by creating the lock file, and takes over the locks not released within timeoutSeconds.
+// It does not wait for the other owners. TryLock and Acquire lock without timeout, since the operating
+// system releases their locks when the process exits.
 func LockFile(lockPath string, ownerId string, timeoutSeconds int) (locked bool, err error) {
 	// Sleep a little to desynchronize processes
 	time.Sleep(time.Duration(rand.Int63n(1000000)) * time.Microsecond)
@@ -170,6 +212,7 @@ func LockFile(lockPath string, ownerId string, timeoutSeconds int) (locked bool,
 	return true, nil
 }
 
+// UnlockFile releases a lock taken by LockFile, removing the lock file when ownerId owns it.
 func UnlockFile(lockPath string, ownerId string) (hadLock bool, err error) {
 	var contentsRead string
 	if contentsRead, _ = readLockFile(lockPath); contentsRead != ownerId {
@@ -0,0 +1,88 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLocked is returned when the file is locked by another process, or by another Lock of this process.
+var ErrLocked = errors.New("file is locked by another owner")
+
+const (
+	minRetryInterval = 10 * time.Millisecond
+	maxRetryInterval = 200 * time.Millisecond
+)
+
+// Lock is an advisory lock on a file, taken with flock on Unix and LockFileEx on Windows.
+// The operating system releases it when the process exits, so a crashed process never leaves a stale lock.
+// The processes sharing a directory agree on a lock file and take its lock before changing the directory.
+type Lock struct {
+	path string
+	file *os.File
+}
+
+// TryLock locks the file at path, creating it if needed, and returns ErrLocked without waiting when it is locked.
+func TryLock(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open lock file %v. %v", path, err)
+	}
+	if err = lockFile(file); err != nil {
+		file.Close()
+		if err == ErrLocked {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to lock file %v. %v", path, err)
+	}
+	return &Lock{path: path, file: file}, nil
+}
+
+// Acquire locks the file at path, waiting up to timeout for the other owners to release it.
+// It returns ErrLocked when the file is still locked after timeout.
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+	interval := minRetryInterval
+	for {
+		lock, err := TryLock(path)
+		if err != ErrLocked || !time.Now().Before(deadline) {
+			return lock, err
+		}
+		time.Sleep(interval)
+		if interval *= 2; interval > maxRetryInterval {
+			interval = maxRetryInterval
+		}
+	}
+}
+
+// Path returns the path of the lock file.
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// Unlock releases the lock. The lock file stays, removing it would let two owners lock different files.
+func (l *Lock) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
@@ -0,0 +1,139 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package filelock
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const helperLockPathEnv = "FILELOCK_TEST_HOLD_LOCK"
+
+func tempLockPath(t *testing.T) (path string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "filelock")
+	assert.NoError(t, err)
+	return filepath.Join(dir, "test.lock"), func() { os.RemoveAll(dir) }
+}
+
+func TestTryLockExcludesOtherOwners(t *testing.T) {
+	path, cleanup := tempLockPath(t)
+	defer cleanup()
+
+	lock, err := TryLock(path)
+	assert.NoError(t, err)
+	_, err = TryLock(path)
+	assert.Equal(t, ErrLocked, err)
+
+	assert.NoError(t, lock.Unlock())
+	assert.NoError(t, lock.Unlock())
+	lock, err = TryLock(path)
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Unlock())
+}
+
+func TestAcquireWaitsForTheRelease(t *testing.T) {
+	path, cleanup := tempLockPath(t)
+	defer cleanup()
+
+	lock, err := TryLock(path)
+	assert.NoError(t, err)
+	_, err = Acquire(path, 50*time.Millisecond)
+	assert.Equal(t, ErrLocked, err)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		lock.Unlock()
+	}()
+	second, err := Acquire(path, 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, path, second.Path())
+	assert.NoError(t, second.Unlock())
+}
+
+// TestHelperHoldLock is run in a child process by TestLockIsReleasedWhenTheOwnerExits.
+func TestHelperHoldLock(t *testing.T) {
+	path := os.Getenv(helperLockPathEnv)
+	if path == "" {
+		t.Skip("helper process only")
+	}
+	if _, err := TryLock(path); err != nil {
+		os.Exit(2)
+	}
+	os.Stdout.WriteString("locked\n")
+	// hold the lock until the parent kills the process
+	time.Sleep(time.Minute)
+	os.Exit(3)
+}
+
+func TestLockIsReleasedWhenTheOwnerExits(t *testing.T) {
+	path, cleanup := tempLockPath(t)
+	defer cleanup()
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestHelperHoldLock")
+	cmd.Env = append(os.Environ(), helperLockPathEnv+"="+path)
+	stdout, err := cmd.StdoutPipe()
+	assert.NoError(t, err)
+	assert.NoError(t, cmd.Start())
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "locked\n", line)
+
+	_, err = TryLock(path)
+	assert.Equal(t, ErrLocked, err)
+
+	// the process exits without unlocking
+	assert.NoError(t, cmd.Process.Kill())
+	cmd.Wait()
+	lock, err := Acquire(path, 5*time.Second)
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Unlock())
+}
+
+func TestFileLocker(t *testing.T) {
+	path, cleanup := tempLockPath(t)
+	defer cleanup()
+	locker := NewFileLocker()
+
+	locked, err := locker.Lock(path, "owner-1", 1)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+	content, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "owner-1", string(content))
+
+	// another locker of the process, as another process, doesn't get the lock
+	locked, err = NewFileLocker().Lock(path, "owner-2", 1)
+	assert.NoError(t, err)
+	assert.False(t, locked)
+	locked, err = locker.Lock(path, "owner-2", 1)
+	assert.NoError(t, err)
+	assert.False(t, locked)
+
+	hadLock, err := locker.Unlock(path, "owner-2")
+	assert.NoError(t, err)
+	assert.False(t, hadLock)
+	hadLock, err = locker.Unlock(path, "owner-1")
+	assert.NoError(t, err)
+	assert.True(t, hadLock)
+
+	locked, err = NewFileLocker().Lock(path, "owner-2", 1)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+}
@@ -15,7 +15,6 @@
 package fileutil
 
 import (
-	"archive/zip"
 	"bytes"
 	"fmt"
 	"io"
@@ -55,7 +54,7 @@ func DeleteFile(filepath string) (err error) {
 // DeleteDirectory deletes a directory and all its content.
 func DeleteDirectory(dirName string) (err error) {
 
-	return os.RemoveAll(dirName)
+	return os.RemoveAll(LongPath(dirName))
 }
 
 // ReadAllText reads all content from the specified file
@@ -67,7 +66,7 @@ func ReadAllText(filePath string) (text string, err error) {
 	}
 
 	buf := bytes.NewBuffer(nil)
-	f, _ := os.Open(filePath)
+	f, _ := os.Open(LongPath(filePath))
 	defer f.Close()
 	_, err = io.Copy(buf, f)
 	if err != nil {
@@ -80,7 +79,7 @@ func ReadAllText(filePath string) (text string, err error) {
 // AppendToFile appends content to file
 func AppendToFile(fileDirectory string, filename string, content string) (filePath string, err error) {
 	filePath = filepath.Join(fileDirectory, filename)
-	fileWriter, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, appconfig.ReadWriteAccess)
+	fileWriter, err := os.OpenFile(LongPath(filePath), os.O_APPEND|os.O_WRONLY, appconfig.ReadWriteAccess)
 	if err != nil {
 		err = fmt.Errorf("failed to open the file at %v: %v", filePath, err)
 	}
@@ -94,7 +93,7 @@ func AppendToFile(fileDirectory string, filename string, content string) (filePa
 
 // WriteAllText writes all text content to the specified file
 func WriteAllText(filePath string, text string) (err error) {
-	f, _ := os.Create(filePath)
+	f, _ := os.Create(LongPath(filePath))
 	defer f.Close()
 	_, err = f.WriteString(text)
 	return
@@ -195,12 +194,18 @@ func MoveFile(filename, srcPath, dstPath string) (result bool, err error) {
 	return MoveAndRenameFile(srcPath, filename, dstPath, filename)
 }
 
-// MoveAndRenameFile moves a file from the srcPath directory to dstPath directory and gives it a new name
+// MoveAndRenameFile moves a file from the srcPath directory to dstPath directory and gives it a new name.
+// A file on another volume is copied, preserving its links, then removed.
 func MoveAndRenameFile(srcPath, originalName, dstPath, newName string) (result bool, err error) {
 	srcFile := filepath.Join(srcPath, originalName)
 	dstFile := filepath.Join(dstPath, newName)
 
-	if err = fs.Rename(srcFile, dstFile); err != nil {
+	if err = fs.Rename(srcFile, dstFile); err != nil && isCrossDevice(err) {
+		if err = CopyTree(srcFile, dstFile, CopyOptions{}); err == nil {
+			err = DeleteDirectory(srcFile)
+		}
+	}
+	if err != nil {
 		return false, fmt.Errorf("unexpected error encountered while moving the file. Error details - %v", err)
 	}
 	return true, nil
@@ -242,7 +247,7 @@ func GetFileModificationTime(srcPath string) (modificationTime time.Time, err er
 
 // IsDirEmpty returns true if the given directory is empty else it returns false
 func IsDirEmpty(location string) (bool, error) {
-	f, err := os.Open(location)
+	f, err := os.Open(LongPath(location))
 	if err != nil {
 		err = fmt.Errorf("couldn't open path - %v", err)
 		return false, err
@@ -262,7 +267,7 @@ func IsDirEmpty(location string) (bool, error) {
 
 // GetDirectoryNames returns the names of all directories under a give srcPath
 func GetDirectoryNames(srcPath string) (directories []string, err error) {
-	if list, err := ioutil.ReadDir(srcPath); err == nil {
+	if list, err := ioutil.ReadDir(LongPath(srcPath)); err == nil {
 		directories = make([]string, 0)
 		for _, fileinfo := range list {
 			if fileinfo.Mode().IsDir() {
@@ -275,7 +280,7 @@ func GetDirectoryNames(srcPath string) (directories []string, err error) {
 
 // GetFileNames returns the names of all non-directories under a give srcPath
 func GetFileNames(srcPath string) (files []string, err error) {
-	if list, err := ioutil.ReadDir(srcPath); err == nil {
+	if list, err := ioutil.ReadDir(LongPath(srcPath)); err == nil {
 		files = make([]string, 0)
 		for _, fileinfo := range list {
 			if !fileinfo.Mode().IsDir() {
@@ -292,7 +297,7 @@ func ReadDir(location string) ([]os.FileInfo, error) {
 	if location == "" {
 		return files, fmt.Errorf("location cannot be empty")
 	}
-	return ioutil.ReadDir(location)
+	return ioutil.ReadDir(LongPath(location))
 }
 
 // isUnderDir determines if a given path is in or under a given parent directory (after accounting for path traversal)
@@ -300,64 +305,8 @@ func isUnderDir(childPath, parentDirPath string) bool {
 	return strings.HasPrefix(filepath.Clean(childPath)+string(filepath.Separator), filepath.Clean(parentDirPath)+string(filepath.Separator))
 }
 
-// Unzip unzips the installation package (using platform agnostic zip functionality)
-// For platform specific implementation that uses tar.gz on Linux, use Uncompress
+// Unzip extracts the installation package (using platform agnostic zip functionality) with the
+// DefaultExtractOptions. Extract detects the format, so tar archives are extracted as well.
 func Unzip(src, dest string) error {
-	r, err := zip.OpenReader(src)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err := r.Close(); err != nil {
-			return
-		}
-	}()
-
-	os.MkdirAll(dest, appconfig.ReadWriteExecuteAccess)
-	// Closure to address file descriptors issue with all the deferred .Close() methods
-	extractAndWriteFile := func(f *zip.File) error {
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-		defer func() {
-			if err := rc.Close(); err != nil {
-				return
-			}
-		}()
-
-		path := filepath.Join(dest, f.Name)
-
-		if !isUnderDir(path, dest) {
-			return fmt.Errorf("%v attepts to place files outside %v subtree", f.Name, dest)
-		}
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(path, f.Mode())
-		} else {
-			os.MkdirAll(filepath.Dir(path), f.Mode())
-			f, err := os.OpenFile(path, appconfig.FileFlagsCreateOrTruncate, f.Mode())
-			if err != nil {
-				return err
-			}
-			defer func() {
-				if err := f.Close(); err != nil {
-					return
-				}
-			}()
-
-			_, err = io.Copy(f, rc)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	}
-	for _, f := range r.File {
-		err := extractAndWriteFile(f)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return Extract(src, dest, DefaultExtractOptions)
 }
@@ -15,9 +15,11 @@ package fileutil
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -269,3 +271,40 @@ func TestAppendToFile(t *testing.T) {
 	assert.NoError(t, err, "expected no error")
 	fmt.Println(filePath)
 }
+
+func TestWriteAtomically(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutil")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "state.json")
+
+	assert.NoError(t, WriteAtomically(filename, []byte(`{"state": 1}`), 0600, false))
+	assert.NoError(t, WriteAtomically(filename, []byte(`{"state": 2}`), 0640, true))
+	content, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"state": 2}`, string(content))
+	if runtime.GOOS != "windows" {
+		info, _ := os.Stat(filename)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1, "no temporary file is left")
+}
+
+func TestWriteAtomicallyFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutil")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "state.json")
+	assert.NoError(t, WriteAtomically(filename, []byte("previous"), 0600, false))
+
+	err = writeAtomically(filename, []byte("next"), 0600, false, func(path string) error { return fmt.Errorf("interrupted") })
+	assert.Error(t, err)
+	content, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, "previous", string(content), "the previous content is kept")
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1, "the temporary file is removed")
+
+	assert.Error(t, WriteAtomically(filepath.Join(dir, "missing", "state.json"), []byte("next"), 0600, false))
+}
@@ -17,73 +17,87 @@
 package fileutil
 
 import (
-	"archive/tar"
-	"compress/gzip"
-	"fmt"
-	"io"
 	"os"
 	"syscall"
-
-	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 )
 
-// Uncompress untar the installation package
+// Uncompress extracts the tar.gz installation package, or any format Extract supports
 func Uncompress(src, dest string) error {
-	file, err := os.Open(src)
-	if err != nil {
-		return err
+	return Extract(src, dest, DefaultExtractOptions)
+}
+
+// LongPath returns path as is, only Windows limits the length of the paths of its file APIs.
+func LongPath(path string) string {
+	return path
+}
+
+// extendedPath returns path as is, see LongPath
+func extendedPath(path string) string {
+	return path
+}
+
+// openNoFollow makes opening a symbolic link fail
+const openNoFollow = syscall.O_NOFOLLOW
+
+// linkCount returns the number of hard links of the file
+func linkCount(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Nlink)
 	}
-	defer file.Close()
+	return 1
+}
 
-	gr, err := gzip.NewReader(file)
-	if err != nil {
-		return err
+// isSparse returns true when the file takes less blocks than its size, i.e. it has holes
+func isSparse(info os.FileInfo) bool {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(stat.Blocks)*512 < info.Size()
 	}
-	defer gr.Close()
-
-	os.MkdirAll(dest, appconfig.ReadWriteExecuteAccess)
-
-	tr := tar.NewReader(gr)
-	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
-		}
-		itemPath := dest + string(os.PathSeparator) + hdr.Name
-		if !isUnderDir(itemPath, dest) {
-			return fmt.Errorf("%v attepts to place files outside %v subtree", file.Name(), dest)
-		}
-		if hdr.FileInfo().IsDir() {
-			os.MkdirAll(itemPath, hdr.FileInfo().Mode())
-		} else {
-			fw, err := os.OpenFile(itemPath, appconfig.FileFlagsCreateOrTruncate, hdr.FileInfo().Mode())
-			if err != nil {
-				return err
-			}
-			defer fw.Close()
-			_, err = io.Copy(fw, tr)
-			if err != nil {
-				return err
-			}
-		}
+	return false
+}
+
+// lchown gives path the owner and group of info, without following a link
+func lchown(path string, info os.FileInfo) error {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return os.Lchown(path, int(stat.Uid), int(stat.Gid))
 	}
 	return nil
 }
 
+// isCrossDevice returns true when a rename failed because the paths are on different filesystems
+func isCrossDevice(err error) bool {
+	linkErr, ok := err.(*os.LinkError)
+	return ok && linkErr.Err == syscall.EXDEV
+}
+
+// syncDirectory flushes the entries of the directory, e.g. a rename, to disk
+func syncDirectory(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
 // GetDiskSpaceInfo returns DiskSpaceInfo with available, free, and total bytes from system disk space
 func GetDiskSpaceInfo() (diskSpaceInfo DiskSpaceInfo, err error) {
-	var stat syscall.Statfs_t
 	var wd string
 
 	// get a rooted path name
 	if wd, err = os.Getwd(); err != nil {
 		return
 	}
+	return GetDiskSpaceInfoForPath(wd)
+}
+
+// GetDiskSpaceInfoForPath returns DiskSpaceInfo of the filesystem of the existing path
+func GetDiskSpaceInfoForPath(path string) (diskSpaceInfo DiskSpaceInfo, err error) {
+	var stat syscall.Statfs_t
 
 	// get filesystem statistics
-	syscall.Statfs(wd, &stat)
+	if err = syscall.Statfs(path, &stat); err != nil {
+		return
+	}
 
 	// get block size
 	bSize := uint64(stat.Bsize)
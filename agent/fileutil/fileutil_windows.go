@@ -29,25 +29,65 @@ func Uncompress(src, dest string) error {
 	return Unzip(src, dest)
 }
 
+// openNoFollow is 0 on Windows, where Shred checks the file opened is the one found by Lstat instead
+const openNoFollow = 0
+
+// linkCount returns 1 on Windows, where creating a hard link requires write access to the file
+func linkCount(info os.FileInfo) uint64 {
+	return 1
+}
+
+// isSparse returns false on Windows, where the copy of a sparse file would need FSCTL_SET_SPARSE to have holes
+func isSparse(info os.FileInfo) bool {
+	return false
+}
+
+// lchown does nothing on Windows, where the owners are in the security descriptor rather than a uid and gid
+func lchown(path string, info os.FileInfo) error {
+	return nil
+}
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, returned when moving a file to another volume
+const errorNotSameDevice syscall.Errno = 17
+
+// isCrossDevice returns true when a rename failed because the paths are on different volumes
+func isCrossDevice(err error) bool {
+	linkErr, ok := err.(*os.LinkError)
+	return ok && linkErr.Err == errorNotSameDevice
+}
+
+// syncDirectory does nothing on Windows, where directories cannot be flushed and NTFS journals the renames
+func syncDirectory(dir string) error {
+	return nil
+}
+
 // GetDiskSpaceInfo returns available, free, and total bytes respectively from system disk space
 func GetDiskSpaceInfo() (diskSpaceInfo DiskSpaceInfo, err error) {
 	var wd string
-	var availBytes, totalBytes, freeBytes int64
 
 	// Get a rooted path name
 	if wd, err = os.Getwd(); err != nil {
 		return
 	}
+	return GetDiskSpaceInfoForPath(wd)
+}
+
+// GetDiskSpaceInfoForPath returns available, free, and total bytes of the volume of the existing path
+func GetDiskSpaceInfoForPath(path string) (diskSpaceInfo DiskSpaceInfo, err error) {
+	var availBytes, totalBytes, freeBytes int64
 
 	// Load kernel32.dll and find GetDiskFreeSpaceEX function
 	getDiskFreeSpace := syscall.MustLoadDLL("kernel32.dll").MustFindProc("GetDiskFreeSpaceExW")
 
 	// Get the available bytes (for arguments, GetDiskFreeSpace function takes dir name, avail, total, and free respectively)
-	_, _, err = getDiskFreeSpace.Call(
-		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(wd))),
+	ret, _, callErr := getDiskFreeSpace.Call(
+		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(path))),
 		uintptr(unsafe.Pointer(&availBytes)),
 		uintptr(unsafe.Pointer(&totalBytes)),
 		uintptr(unsafe.Pointer(&freeBytes)))
+	if ret == 0 {
+		return diskSpaceInfo, callErr
+	}
 
 	return DiskSpaceInfo{
 		AvailBytes: availBytes,
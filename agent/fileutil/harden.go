@@ -1,42 +1,22 @@
 package fileutil
 
 import (
-	"io/ioutil"
 	"os"
-	"path/filepath"
 )
 
 const (
 	RWPermission = 0600
 )
 
-// HardenedWriteFile calls ioutil.WriteFile and guarantees a hardened permission
-// control. If the file already exists, it hardens the permissions before
-// writing data to it.
+// HardenedWriteFile writes the data atomically, see WriteAtomically, to a file with a hardened permission
+// control. The new file is hardened before it replaces the existing one.
 func HardenedWriteFile(filename string, data []byte) (err error) {
-
-	if _, err = os.Stat(filename); err != nil {
-		if os.IsNotExist(err) {
-			os.Create(filename)
-		} else {
-			return
-		}
-	}
-
-	if err = Harden(filename); err != nil {
-		return
-	}
-
-	if err = ioutil.WriteFile(filename, data, RWPermission); err != nil {
-		return
-	}
-
-	return
+	return writeAtomically(filename, data, RWPermission, true, Harden)
 }
 
 // RecursivelyHarden the files and directory under the specified path.
 func RecursivelyHarden(path string) error {
-	return filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+	return Walk(path, func(p string, fi os.FileInfo, err error) error {
 		return Harden(p)
 	})
 }
@@ -31,6 +31,7 @@ var fullAccessAccessMask = uint32(15) << 28
 
 // Harden the provided path with non-inheriting ACL for admin access only.
 func Harden(path string) (err error) {
+	path = LongPath(path)
 
 	if _, err = os.Stat(path); err != nil {
 		return
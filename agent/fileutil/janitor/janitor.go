@@ -0,0 +1,186 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package janitor keeps the orchestration and download directories of the agent within a size and an age quota,
+// removing their oldest entries and recording the removals in an audit log.
+package janitor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// ReasonAge is the reason of the removal of an entry older than the quota
+	ReasonAge = "age"
+	// ReasonSize is the reason of the removal of an entry of a directory larger than the quota
+	ReasonSize = "size"
+
+	auditFileName = "removals.json"
+	// maxAuditEntries is the number of most recent removals kept in the audit log
+	maxAuditEntries = 1000
+)
+
+// dir is where the audit log is kept
+var dir = filepath.Join(appconfig.DefaultDataStorePath, "janitor")
+
+// Quota limits the total size and the age of the entries of a directory.
+type Quota struct {
+	// MaxBytes is the total size of the entries, 0 for no limit
+	MaxBytes int64
+	// MaxAge is how long an entry is kept after its last change, 0 for no limit
+	MaxAge time.Duration
+	// MinAge protects the entries changed more recently, which may still be in use, whatever the size
+	MinAge time.Duration
+	// Depth is the level under the directory of the entries, 0 or 1 for its own entries; the directories above the
+	// entries are kept, the files above are entries themselves
+	Depth int
+}
+
+// Removal is an entry removed to enforce a quota, recorded in the audit log.
+type Removal struct {
+	Time    time.Time
+	Path    string
+	Bytes   int64
+	ModTime time.Time
+	Reason  string
+}
+
+// entry is a file or directory of a directory under quota; its modification time is the most recent of its tree
+type entry struct {
+	path    string
+	bytes   int64
+	modTime time.Time
+}
+
+// Prune removes the entries of dir beyond the quota, the least recently changed first, and returns the removals.
+// A missing dir has nothing to prune.
+func Prune(log log.T, dir string, quota Quota, now time.Time) (removals []Removal, err error) {
+	if !fileutil.Exists(dir) {
+		return nil, nil
+	}
+	entries, total, err := scan(dir, quota.Depth)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+
+	for _, e := range entries {
+		age := now.Sub(e.modTime)
+		if age < quota.MinAge {
+			break
+		}
+		var reason string
+		switch {
+		case quota.MaxAge > 0 && age > quota.MaxAge:
+			reason = ReasonAge
+		case quota.MaxBytes > 0 && total > quota.MaxBytes:
+			reason = ReasonSize
+		default:
+			// the next entries are more recent and the directory is within its size
+			return removals, nil
+		}
+		if err = fileutil.DeleteDirectory(e.path); err != nil {
+			log.Warnf("Failed to remove %v: %v", e.path, err)
+			continue
+		}
+		log.Infof("Removed %v (%v bytes, last changed %v) to enforce the %v quota of %v", e.path, e.bytes, e.modTime.Format(time.RFC3339), reason, dir)
+		total -= e.bytes
+		removals = append(removals, Removal{Time: now, Path: e.path, Bytes: e.bytes, ModTime: e.modTime, Reason: reason})
+	}
+	return removals, nil
+}
+
+// scan returns the entries of dir, depth levels under it, and their total size
+func scan(dir string, depth int) (entries []entry, total int64, err error) {
+	infos, err := fileutil.ReadDir(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, info := range infos {
+		if depth > 1 && info.IsDir() {
+			children, size, err := scan(filepath.Join(dir, info.Name()), depth-1)
+			if err != nil {
+				return nil, 0, err
+			}
+			entries = append(entries, children...)
+			total += size
+			continue
+		}
+		e := entry{path: filepath.Join(dir, info.Name())}
+		fileutil.Walk(e.path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.Mode().IsRegular() {
+				e.bytes += info.Size()
+			}
+			if info.ModTime().After(e.modTime) {
+				e.modTime = info.ModTime()
+			}
+			return nil
+		})
+		entries = append(entries, e)
+		total += e.bytes
+	}
+	return entries, total, nil
+}
+
+// Audit appends the removals to the audit log, which keeps the most recent ones.
+func Audit(log log.T, removals []Removal) {
+	if len(removals) == 0 {
+		return
+	}
+	all, _ := ReadAudit()
+	all = append(all, removals...)
+	if len(all) > maxAuditEntries {
+		all = all[len(all)-maxAuditEntries:]
+	}
+	lines := make([]string, 0, len(all))
+	for _, removal := range all {
+		line, _ := json.Marshal(removal)
+		lines = append(lines, string(line))
+	}
+	path := filepath.Join(dir, auditFileName)
+	if err := fileutil.MakeDirs(dir); err != nil {
+		log.Warnf("Unable to create %v: %v", dir, err)
+		return
+	}
+	if err := fileutil.WriteAtomically(path, []byte(strings.Join(lines, "\n")+"\n"), appconfig.ReadWriteAccess, false); err != nil {
+		log.Warnf("Unable to write the removals to %v: %v", path, err)
+	}
+}
+
+// ReadAudit returns the most recent removals of the janitor, oldest first.
+func ReadAudit() (removals []Removal, err error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, auditFileName))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		var removal Removal
+		if line != "" && json.Unmarshal([]byte(line), &removal) == nil {
+			removals = append(removals, removal)
+		}
+	}
+	return
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package janitor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupTest(t *testing.T) string {
+	tmp, err := ioutil.TempDir("", "janitor")
+	assert.NoError(t, err)
+	savedDir := dir
+	dir = filepath.Join(tmp, "janitor")
+	t.Cleanup(func() {
+		dir = savedDir
+		os.RemoveAll(tmp)
+	})
+	return tmp
+}
+
+func newTestLog() log.T {
+	logger := log.NewMockLog()
+	logger.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	return logger
+}
+
+// writeEntry creates the directory name under root holding size bytes, last changed at modTime
+func writeEntry(t *testing.T, root, name string, size int, modTime time.Time) string {
+	path := filepath.Join(root, name)
+	assert.NoError(t, os.MkdirAll(path, 0700))
+	file := filepath.Join(path, "stdout")
+	assert.NoError(t, ioutil.WriteFile(file, make([]byte, size), 0600))
+	assert.NoError(t, os.Chtimes(file, modTime, modTime))
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	return path
+}
+
+func TestPruneRemovesOldestBeyondSize(t *testing.T) {
+	root := setupTest(t)
+	now := time.Now()
+	oldest := writeEntry(t, root, "oldest", 100, now.Add(-72*time.Hour))
+	older := writeEntry(t, root, "older", 100, now.Add(-48*time.Hour))
+	recent := writeEntry(t, root, "recent", 100, now.Add(-24*time.Hour))
+	current := writeEntry(t, root, "current", 100, now.Add(-time.Minute))
+
+	removals, err := Prune(newTestLog(), root, Quota{MaxBytes: 50, MinAge: time.Hour}, now)
+	assert.NoError(t, err)
+	assert.Len(t, removals, 3)
+	assert.Equal(t, oldest, removals[0].Path)
+	assert.Equal(t, ReasonSize, removals[0].Reason)
+	assert.Equal(t, int64(100), removals[0].Bytes)
+	assert.False(t, fileExists(older))
+	assert.False(t, fileExists(recent))
+	// changed within MinAge, kept even though the directory is still over its size
+	assert.True(t, fileExists(current))
+}
+
+func TestPruneRemovesOlderThanMaxAge(t *testing.T) {
+	root := setupTest(t)
+	now := time.Now()
+	old := writeEntry(t, root, "old", 100, now.Add(-40*24*time.Hour))
+	recent := writeEntry(t, root, "recent", 100, now.Add(-24*time.Hour))
+
+	removals, err := Prune(newTestLog(), root, Quota{MaxAge: 30 * 24 * time.Hour}, now)
+	assert.NoError(t, err)
+	assert.Len(t, removals, 1)
+	assert.Equal(t, ReasonAge, removals[0].Reason)
+	assert.False(t, fileExists(old))
+	assert.True(t, fileExists(recent))
+
+	// nothing to prune in a missing directory
+	removals, err = Prune(newTestLog(), filepath.Join(root, "missing"), Quota{MaxAge: time.Hour}, now)
+	assert.NoError(t, err)
+	assert.Empty(t, removals)
+}
+
+func TestPruneKeepsTheDirectoriesAboveDepth(t *testing.T) {
+	root := setupTest(t)
+	now := time.Now()
+	artifact := filepath.Join(root, "0123456789abcdef")
+	assert.NoError(t, ioutil.WriteFile(artifact, make([]byte, 100), 0600))
+	assert.NoError(t, os.Chtimes(artifact, now.Add(-72*time.Hour), now.Add(-72*time.Hour)))
+	oldUpdate := writeEntry(t, filepath.Join(root, "update"), "2.0.0", 100, now.Add(-48*time.Hour))
+	newUpdate := writeEntry(t, filepath.Join(root, "update"), "2.1.0", 100, now.Add(-time.Minute))
+
+	removals, err := Prune(newTestLog(), root, Quota{MaxBytes: 150, MinAge: time.Hour, Depth: 2}, now)
+	assert.NoError(t, err)
+	assert.Len(t, removals, 2)
+	assert.Equal(t, artifact, removals[0].Path)
+	assert.Equal(t, oldUpdate, removals[1].Path)
+	// the directory holding the entries is kept with its recent entry
+	assert.True(t, fileExists(newUpdate))
+}
+
+func TestAuditKeepsMostRecentRemovals(t *testing.T) {
+	setupTest(t)
+	removals := make([]Removal, maxAuditEntries)
+	for i := range removals {
+		removals[i] = Removal{Path: "old", Reason: ReasonAge}
+	}
+	Audit(newTestLog(), removals)
+	Audit(newTestLog(), []Removal{{Path: "new", Reason: ReasonSize, Bytes: 10}})
+
+	audit, err := ReadAudit()
+	assert.NoError(t, err)
+	assert.Len(t, audit, maxAuditEntries)
+	assert.Equal(t, "new", audit[len(audit)-1].Path)
+	assert.Equal(t, int64(10), audit[len(audit)-1].Bytes)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package janitor
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/carlescere/scheduler"
+)
+
+const (
+	// janitorName is the name of the core module enforcing the quotas
+	janitorName = "DirectoryJanitor"
+
+	// janitorFrequencyMinutes is how often the quotas are enforced
+	janitorFrequencyMinutes = 60
+
+	// megabyte is the unit of the size quotas
+	megabyte = 1024 * 1024
+
+	// downloadDepth is the level of the entries of the download directory: its files, and the entries of its
+	// directories, e.g. update or http, which are kept
+	downloadDepth = 2
+)
+
+// Janitor is the core module keeping the orchestration and download directories within their quotas.
+type Janitor struct {
+	context context.T
+	job     *scheduler.Job
+	// pruning serializes the runs, the scheduler doesn't wait for the previous one
+	pruning sync.Mutex
+}
+
+// NewJanitor creates a new janitor core module.
+func NewJanitor(context context.T) *Janitor {
+	return &Janitor{
+		context: context.With("[" + janitorName + "]"),
+	}
+}
+
+// prune enforces the quotas of the configuration, which may have been reloaded since the last run
+func (j *Janitor) prune() {
+	j.pruning.Lock()
+	defer j.pruning.Unlock()
+	log := j.context.Log()
+	config := j.context.AppConfig()
+	maxAge := time.Duration(config.Agent.QuotaMaxAgeDays) * 24 * time.Hour
+	// the entries of the running documents are recent, the retention of the orchestration logs has the same minimum
+	minAge := appconfig.DefaultStateOrchestrationLogsRetentionDurationHoursMin * time.Hour
+
+	quotas := map[string]Quota{
+		appconfig.DownloadRoot: {MaxBytes: int64(config.Agent.DownloadQuotaMB) * megabyte, MaxAge: maxAge, MinAge: minAge, Depth: downloadDepth},
+	}
+	if instanceID, err := platform.InstanceID(); err == nil {
+		orchestrationRoot := filepath.Join(appconfig.DefaultDataStorePath, instanceID, appconfig.DefaultDocumentRootDirName, config.Agent.OrchestrationRootDir)
+		quotas[orchestrationRoot] = Quota{MaxBytes: int64(config.Agent.OrchestrationQuotaMB) * megabyte, MaxAge: maxAge, MinAge: minAge}
+	} else {
+		log.Warnf("Unable to get the instance id, the orchestration directories aren't pruned: %v", err)
+	}
+
+	now := time.Now()
+	for dir, quota := range quotas {
+		removals, err := Prune(log, dir, quota, now)
+		if err != nil {
+			log.Warnf("Failed to enforce the quota of %v: %v", dir, err)
+		}
+		Audit(log, removals)
+	}
+}
+
+// ICoreModule implementation
+
+// ModuleName returns the module name
+func (j *Janitor) ModuleName() string {
+	return janitorName
+}
+
+// ModuleExecute enforces the quotas now and every janitorFrequencyMinutes
+func (j *Janitor) ModuleExecute(context context.T) (err error) {
+	// the scheduler runs the job immediately as well
+	if j.job, err = scheduler.Every(janitorFrequencyMinutes).Minutes().Run(j.prune); err != nil {
+		j.context.Log().Errorf("unable to schedule the enforcement of the directory quotas. %v", err)
+	}
+	return
+}
+
+// ModuleRequestStop stops the enforcement of the quotas
+func (j *Janitor) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if j.job != nil {
+		j.context.Log().Info("stopping the enforcement of the directory quotas.")
+		j.job.Quit <- true
+	}
+	return nil
+}
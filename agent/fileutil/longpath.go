@@ -0,0 +1,56 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package fileutil contains utilities for working with the file system.
+package fileutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Walk walks the tree of root like filepath.Walk, through the \\?\ form of root on Windows so that the files
+// deeper than MAX_PATH are found. walkFn gets the paths under root as given; it passes them through LongPath
+// before opening them.
+func Walk(root string, walkFn filepath.WalkFunc) error {
+	longRoot := extendedPath(root)
+	if longRoot == root {
+		return filepath.Walk(root, walkFn)
+	}
+	return filepath.Walk(longRoot, func(path string, info os.FileInfo, err error) error {
+		return walkFn(root+strings.TrimPrefix(path, longRoot), info, err)
+	})
+}
+
+// CopyFile copies src to dst with the permissions perm, replacing dst.
+func CopyFile(src, dst string, perm os.FileMode) (err error) {
+	in, err := os.Open(LongPath(src))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	dst = LongPath(dst)
+	os.Remove(dst)
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
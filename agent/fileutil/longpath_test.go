@@ -0,0 +1,66 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// deepDir creates a directory whose path is longer than MAX_PATH under dir
+func deepDir(t *testing.T, dir string) string {
+	deep := filepath.Join(dir, strings.Repeat("d", 100), strings.Repeat("e", 100), strings.Repeat("f", 100))
+	assert.NoError(t, os.MkdirAll(LongPath(deep), 0700))
+	return deep
+}
+
+func TestWalkReturnsPathsUnderRoot(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "longpath")
+	defer os.RemoveAll(LongPath(dir))
+	deep := deepDir(t, dir)
+	assert.NoError(t, WriteAllText(filepath.Join(deep, "file"), "content"))
+
+	var paths []string
+	assert.NoError(t, Walk(dir, func(path string, info os.FileInfo, err error) error {
+		assert.NoError(t, err)
+		paths = append(paths, path)
+		return nil
+	}))
+	assert.Equal(t, dir, paths[0])
+	assert.Equal(t, filepath.Join(deep, "file"), paths[len(paths)-1])
+	assert.Len(t, paths, 5)
+}
+
+func TestCopyFileReplacesDestination(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "longpath")
+	defer os.RemoveAll(LongPath(dir))
+	deep := deepDir(t, dir)
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(deep, "dst")
+	assert.NoError(t, WriteAllText(src, "new"))
+	assert.NoError(t, WriteAllText(dst, "old content"))
+
+	assert.NoError(t, CopyFile(src, dst, 0600))
+	content, err := ReadAllText(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", content)
+
+	assert.NoError(t, DeleteDirectory(dir))
+	assert.False(t, Exists(dir))
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+// Package fileutil contains utilities for working with the file system.
+package fileutil
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// longPathPrefix turns off the MAX_PATH limit of the Windows file APIs, and their normalization of the path
+	longPathPrefix    = `\\?\`
+	uncLongPathPrefix = `\\?\UNC\`
+	devicePathPrefix  = `\\.\`
+	// maxShortPath is the length from which a path needs the prefix: MAX_PATH minus the 12 characters of an 8.3
+	// file name, the limit of the directories
+	maxShortPath = 248
+)
+
+// LongPath returns the \\?\ form of path when it's too long for the Windows file APIs without it, i.e. 248
+// characters or more once absolute. The form isn't normalized by Windows, so the path is made absolute and cleaned:
+// forward slashes, . and .. are resolved. Shorter paths, device paths, paths already in the form and paths that can't
+// be made absolute are returned as is.
+func LongPath(path string) string {
+	if abs, err := filepath.Abs(path); err != nil || len(abs) < maxShortPath {
+		return path
+	}
+	return extendedPath(path)
+}
+
+// extendedPath returns the \\?\ form of path whatever its length, for the paths under it to be in the form too
+func extendedPath(path string) string {
+	if path == "" || strings.HasPrefix(path, longPathPrefix) || strings.HasPrefix(path, devicePathPrefix) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return uncLongPathPrefix + abs[2:]
+	}
+	return longPathPrefix + abs
+}
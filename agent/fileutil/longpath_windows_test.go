@@ -0,0 +1,38 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package fileutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLongPath(t *testing.T) {
+	long := strings.Repeat("a", 250)
+	assert.Equal(t, `C:\short\path`, LongPath(`C:\short\path`))
+	assert.Equal(t, `\\?\C:\dir\`+long, LongPath(`C:/dir/./sub/../`+long))
+	assert.Equal(t, `\\?\UNC\server\share\`+long, LongPath(`\\server\share\`+long))
+	assert.Equal(t, `\\?\C:\`+long, LongPath(`\\?\C:\`+long))
+	assert.Equal(t, `\\.\pipe\`+long, LongPath(`\\.\pipe\`+long))
+	assert.Equal(t, "", LongPath(""))
+}
+
+func TestExtendedPathPrefixesShortPaths(t *testing.T) {
+	assert.Equal(t, `\\?\C:\short\path`, extendedPath(`C:\short\path`))
+	assert.Equal(t, `\\?\UNC\server\share`, extendedPath(`\\server\share`))
+}
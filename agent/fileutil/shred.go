@@ -0,0 +1,87 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package fileutil contains utilities for working with the file system.
+package fileutil
+
+import (
+	"fmt"
+	"os"
+)
+
+// shredBufferSize is the size of the writes overwriting a file
+const shredBufferSize = 32 * 1024
+
+// Shred overwrites the content of the file with zeros, flushes it to disk and removes the file, so the secrets it
+// held, e.g. the commands of a script or credentials, can't be recovered from the freed blocks. It's best effort:
+// copy on write filesystems, journals and SSDs may keep copies of the content elsewhere. Symbolic links and files
+// with other hard links are removed without being overwritten, as their content is another file's.
+func Shred(path string) error {
+	path = LongPath(path)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().IsRegular() && linkCount(info) <= 1 {
+		err = overwrite(path, info)
+	}
+	if removeErr := os.Remove(path); removeErr != nil {
+		return removeErr
+	}
+	return err
+}
+
+// ShredDirectory shreds the files under dir and removes it.
+func ShredDirectory(dir string) error {
+	var failures []string
+	Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			if err = Shred(path); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+		return nil
+	})
+	if err := os.RemoveAll(LongPath(dir)); err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to overwrite files under %v: %v", dir, failures)
+	}
+	return nil
+}
+
+// overwrite writes zeros over the content of the file, which must still be the file of info
+func overwrite(path string, info os.FileInfo) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|openNoFollow, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if opened, err := f.Stat(); err != nil || !os.SameFile(info, opened) {
+		return fmt.Errorf("%v changed while it was shredded", path)
+	}
+
+	zeros := make([]byte, shredBufferSize)
+	for left := info.Size(); left > 0; {
+		n := int64(len(zeros))
+		if left < n {
+			n = left
+		}
+		if _, err = f.Write(zeros[:n]); err != nil {
+			return err
+		}
+		left -= n
+	}
+	return f.Sync()
+}
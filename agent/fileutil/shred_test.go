@@ -0,0 +1,76 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fileutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShredOverwritesAndRemovesFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "shred")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "_script.sh")
+	secret := bytes.Repeat([]byte("secret"), shredBufferSize/3)
+	assert.NoError(t, ioutil.WriteFile(path, secret, 0600))
+
+	info, _ := os.Lstat(path)
+	assert.NoError(t, overwrite(path, info))
+	content, _ := ioutil.ReadFile(path)
+	assert.Equal(t, make([]byte, len(secret)), content)
+
+	assert.NoError(t, Shred(path))
+	assert.False(t, Exists(path))
+	assert.True(t, os.IsNotExist(Shred(path)))
+}
+
+func TestShredLeavesTheTargetOfLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("links need privileges on Windows")
+	}
+	dir, _ := ioutil.TempDir("", "shred")
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "target")
+	assert.NoError(t, ioutil.WriteFile(target, []byte("keep"), 0600))
+	symlink, hardlink := filepath.Join(dir, "symlink"), filepath.Join(dir, "hardlink")
+	assert.NoError(t, os.Symlink(target, symlink))
+	assert.NoError(t, os.Link(target, hardlink))
+
+	assert.NoError(t, Shred(symlink))
+	assert.NoError(t, Shred(hardlink))
+
+	assert.False(t, Exists(symlink))
+	assert.False(t, Exists(hardlink))
+	content, _ := ioutil.ReadFile(target)
+	assert.Equal(t, "keep", string(content))
+}
+
+func TestShredDirectory(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "shred")
+	defer os.RemoveAll(dir)
+	orchestrationDir := filepath.Join(dir, "orchestration")
+	assert.NoError(t, os.MkdirAll(filepath.Join(orchestrationDir, "step"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(orchestrationDir, "step", "_script.sh"), []byte("secret"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(orchestrationDir, "credentials"), []byte("secret"), 0600))
+
+	assert.NoError(t, ShredDirectory(orchestrationDir))
+
+	assert.False(t, Exists(orchestrationDir))
+}
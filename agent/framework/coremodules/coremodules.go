@@ -17,8 +17,12 @@ package coremodules
 import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/janitor"
 	"github.com/aws/amazon-ssm-agent/agent/health"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/authorizedkeys"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/quarantine"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/temporaryadmin"
 	"github.com/aws/amazon-ssm-agent/agent/runcommand"
 	"github.com/aws/amazon-ssm-agent/agent/startup"
 )
@@ -50,6 +54,18 @@ func loadCoreModules(context context.T) {
 
 	registeredCoreModules = append(registeredCoreModules, startup.NewProcessor(context))
 
+	// removes the expired keys added by aws:manageAuthorizedKeys
+	registeredCoreModules = append(registeredCoreModules, authorizedkeys.NewExpiryCleaner(context))
+
+	// revokes the expired grants of aws:grantTemporaryAdmin
+	registeredCoreModules = append(registeredCoreModules, temporaryadmin.NewExpiryRevoker(context))
+
+	// keeps the addresses allowed by aws:quarantine up to date with the endpoints
+	registeredCoreModules = append(registeredCoreModules, quarantine.NewRefresher(context))
+
+	// keeps the orchestration and download directories within their quotas
+	registeredCoreModules = append(registeredCoreModules, janitor.NewJanitor(context))
+
 	// registering the long running plugin manager as a core module
 	manager.EnsureInitialization(context)
 	if lrpm, err := manager.GetInstance(); err == nil {
@@ -0,0 +1,171 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package docattachments fetches the files attached to documents before their steps run.
+// The files are cached by hash for the instance, so that the documents bundling script libraries
+// keep working offline, and after their presigned URLs expired, once fetched.
+package docattachments
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// downloadsDir is the directory under the orchestration directory where the downloaded resources reside,
+	// the plugins resolve relative working directories and document paths against it
+	downloadsDir = "downloads"
+
+	// attachmentsDir is the directory under downloadsDir holding the attachments of the document
+	attachmentsDir = "attachments"
+
+	// hashTypeSha256 is the only hash type SSM uses for attachments
+	hashTypeSha256 = "sha256"
+
+	// cacheRetention is how long an attachment stays cached after a document last used it
+	cacheRetention = 30 * 24 * time.Hour
+)
+
+// download is replaced in the tests
+var download = artifact.Download
+
+// cacheDirectory returns the directory caching the attachments for the instance, replaced in the tests
+var cacheDirectory = func(instanceID string) string {
+	return filepath.Join(appconfig.DefaultDataStorePath, instanceID, appconfig.AttachmentsCacheRootDirName)
+}
+
+// Directory returns the directory holding the attachments of the document with the given orchestration directory.
+// The steps reach it as the relative working directory "attachments".
+func Directory(orchestrationDir string) string {
+	return filepath.Join(orchestrationDir, downloadsDir, attachmentsDir)
+}
+
+// Fetch places the attachments of a document into Directory(orchestrationDir). The attachments
+// already cached are used as they are, the others are downloaded, verified and cached first.
+func Fetch(log log.T, instanceID string, orchestrationDir string, attachments []contracts.AttachmentContent) (err error) {
+	if len(attachments) == 0 {
+		return nil
+	}
+	for _, attachment := range attachments {
+		if err = validate(attachment); err != nil {
+			return err
+		}
+	}
+
+	cacheDir := cacheDirectory(instanceID)
+	destinationDir := Directory(orchestrationDir)
+	if err = fileutil.MakeDirs(cacheDir); err != nil {
+		return fmt.Errorf("failed to create the attachments cache %v: %v", cacheDir, err)
+	}
+	if err = fileutil.MakeDirs(destinationDir); err != nil {
+		return fmt.Errorf("failed to create the attachments directory %v: %v", destinationDir, err)
+	}
+
+	for _, attachment := range attachments {
+		var cachedFile string
+		if cachedFile, err = cache(log, cacheDir, attachment); err != nil {
+			return fmt.Errorf("failed to fetch attachment %v: %v", attachment.Name, err)
+		}
+		if err = fileutil.CopyFile(cachedFile, filepath.Join(destinationDir, attachment.Name), appconfig.ReadWriteExecuteAccess); err != nil {
+			return fmt.Errorf("failed to place attachment %v: %v", attachment.Name, err)
+		}
+		log.Infof("Attachment %v placed in %v", attachment.Name, destinationDir)
+	}
+
+	prune(log, cacheDir)
+	return nil
+}
+
+// validate checks that the attachment can be placed and verified.
+func validate(attachment contracts.AttachmentContent) error {
+	name := attachment.Name
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || filepath.Base(name) != name {
+		return fmt.Errorf("invalid attachment name %q", name)
+	}
+	if !strings.EqualFold(attachment.HashType, hashTypeSha256) {
+		return fmt.Errorf("attachment %v has unsupported hash type %q", name, attachment.HashType)
+	}
+	if len(attachment.Hash) != 64 || strings.Trim(strings.ToLower(attachment.Hash), "0123456789abcdef") != "" {
+		return fmt.Errorf("attachment %v has invalid hash %q", name, attachment.Hash)
+	}
+	return nil
+}
+
+// cache returns the cached file of the attachment, downloading it when it is not cached or no longer matches its hash.
+func cache(log log.T, cacheDir string, attachment contracts.AttachmentContent) (cachedFile string, err error) {
+	hash := strings.ToLower(attachment.Hash)
+	cachedFile = filepath.Join(cacheDir, hash)
+
+	if fileutil.Exists(cachedFile) {
+		var cachedHash string
+		if cachedHash, err = artifact.Sha256HashValue(log, cachedFile); err == nil && cachedHash == hash {
+			log.Debugf("Using cached attachment %v", attachment.Name)
+			now := time.Now()
+			os.Chtimes(cachedFile, now, now)
+			return cachedFile, nil
+		}
+		log.Warnf("Cached attachment %v does not match its hash, downloading it again", attachment.Name)
+		os.Remove(cachedFile)
+	}
+
+	if attachment.Url == "" {
+		return "", fmt.Errorf("attachment is not cached and has no download URL")
+	}
+	if err = fileutil.CheckDiskSpace(cacheDir, attachment.Size); err != nil {
+		return "", err
+	}
+
+	input := artifact.DownloadInput{
+		SourceURL:            attachment.Url,
+		DestinationDirectory: filepath.Join(cacheDir, downloadsDir),
+		SourceChecksums:      map[string]string{hashTypeSha256: hash},
+	}
+	output, err := download(log, input)
+	if err != nil {
+		return "", err
+	}
+	os.Remove(output.LocalFilePath + ".etag")
+	if !output.IsHashMatched {
+		os.Remove(output.LocalFilePath)
+		return "", fmt.Errorf("downloaded file does not match hash %v", hash)
+	}
+	if err = os.Rename(output.LocalFilePath, cachedFile); err != nil {
+		return "", err
+	}
+	return cachedFile, nil
+}
+
+// prune removes the attachments no document used for cacheRetention.
+func prune(log log.T, cacheDir string) {
+	files, err := fileutil.GetFileNames(cacheDir)
+	if err != nil {
+		log.Debugf("Failed to list the attachments cache: %v", err)
+		return
+	}
+	for _, name := range files {
+		path := filepath.Join(cacheDir, name)
+		if modified, err := fileutil.GetFileModificationTime(path); err == nil && time.Since(modified) > cacheRetention {
+			log.Debugf("Removing attachment %v from the cache", name)
+			os.Remove(path)
+		}
+	}
+}
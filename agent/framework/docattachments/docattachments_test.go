@@ -0,0 +1,141 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docattachments
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubDownloads serves the given content for every URL and counts the downloads.
+func stubDownloads(t *testing.T, content string, count *int) {
+	download = func(log log.T, input artifact.DownloadInput) (output artifact.DownloadOutput, err error) {
+		*count++
+		assert.NoError(t, os.MkdirAll(input.DestinationDirectory, 0700))
+		output.LocalFilePath = filepath.Join(input.DestinationDirectory, "file")
+		assert.NoError(t, ioutil.WriteFile(output.LocalFilePath, []byte(content), 0600))
+		output.IsHashMatched, err = artifact.VerifyHash(log, input, output)
+		return output, err
+	}
+}
+
+func setup(t *testing.T) (root string) {
+	root, err := ioutil.TempDir("", "attachments")
+	assert.NoError(t, err)
+	cacheDirectory = func(instanceID string) string { return filepath.Join(root, instanceID, "attachments") }
+	return root
+}
+
+func attachment(name, content string) contracts.AttachmentContent {
+	return contracts.AttachmentContent{
+		Name:     name,
+		Size:     int64(len(content)),
+		Hash:     fmt.Sprintf("%x", sha256.Sum256([]byte(content))),
+		HashType: "Sha256",
+		Url:      "https://attachments.example.com/" + name,
+	}
+}
+
+func TestFetchDownloadsOnceAndReusesTheCache(t *testing.T) {
+	root := setup(t)
+	defer os.RemoveAll(root)
+	downloads := 0
+	stubDownloads(t, "echo lib", &downloads)
+	lib := attachment("lib.sh", "echo lib")
+
+	first := filepath.Join(root, "orchestration", "cmd-1")
+	assert.NoError(t, Fetch(newTestLog(), "i-1", first, []contracts.AttachmentContent{lib}))
+	content, err := ioutil.ReadFile(filepath.Join(Directory(first), "lib.sh"))
+	assert.NoError(t, err)
+	assert.Equal(t, "echo lib", string(content))
+
+	// the presigned URL expired, the cached file is used
+	lib.Url = ""
+	second := filepath.Join(root, "orchestration", "cmd-2")
+	assert.NoError(t, Fetch(newTestLog(), "i-1", second, []contracts.AttachmentContent{lib}))
+	assert.True(t, fileExists(filepath.Join(Directory(second), "lib.sh")))
+	assert.Equal(t, 1, downloads)
+}
+
+func TestFetchDownloadsAgainWhenTheCacheIsCorrupt(t *testing.T) {
+	root := setup(t)
+	defer os.RemoveAll(root)
+	downloads := 0
+	stubDownloads(t, "echo lib", &downloads)
+	lib := attachment("lib.sh", "echo lib")
+	orchestrationDir := filepath.Join(root, "orchestration", "cmd-1")
+
+	assert.NoError(t, Fetch(newTestLog(), "i-1", orchestrationDir, []contracts.AttachmentContent{lib}))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(cacheDirectory("i-1"), lib.Hash), []byte("tampered"), 0600))
+	assert.NoError(t, Fetch(newTestLog(), "i-1", orchestrationDir, []contracts.AttachmentContent{lib}))
+	assert.Equal(t, 2, downloads)
+}
+
+func TestFetchRejectsContentNotMatchingTheHash(t *testing.T) {
+	root := setup(t)
+	defer os.RemoveAll(root)
+	downloads := 0
+	stubDownloads(t, "echo evil", &downloads)
+
+	err := Fetch(newTestLog(), "i-1", filepath.Join(root, "orchestration"), []contracts.AttachmentContent{attachment("lib.sh", "echo lib")})
+	assert.Error(t, err)
+	files, _ := ioutil.ReadDir(cacheDirectory("i-1"))
+	for _, file := range files {
+		assert.True(t, file.IsDir(), "unexpected cached file %v", file.Name())
+	}
+}
+
+func TestFetchRequiresAURLForUncachedAttachments(t *testing.T) {
+	root := setup(t)
+	defer os.RemoveAll(root)
+	lib := attachment("lib.sh", "echo lib")
+	lib.Url = ""
+
+	err := Fetch(newTestLog(), "i-1", filepath.Join(root, "orchestration"), []contracts.AttachmentContent{lib})
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, validate(attachment("lib.sh", "")))
+	for _, name := range []string{"", ".", "..", "../lib.sh", "dir/lib.sh", `dir\lib.sh`} {
+		assert.Error(t, validate(attachment(name, "")), name)
+	}
+	md5 := attachment("lib.sh", "")
+	md5.HashType = "Md5"
+	assert.Error(t, validate(md5))
+	short := attachment("lib.sh", "")
+	short.Hash = "abc"
+	assert.Error(t, validate(short))
+}
+
+func newTestLog() log.T {
+	logger := log.NewMockLog()
+	logger.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	return logger
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
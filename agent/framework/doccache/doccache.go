@@ -0,0 +1,91 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package doccache caches the documents the agent receives from SSM, by name, for the instance.
+// The local commands can run a cached document by name, and the associations fall back to it,
+// while the instance is offline.
+package doccache
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// cacheDirectory returns the directory caching the documents for the instance, replaced in the tests
+var cacheDirectory = func(instanceID string) string {
+	return filepath.Join(appconfig.DefaultDataStorePath, instanceID, appconfig.DocumentsCacheRootDirName)
+}
+
+// Put caches the JSON content of a document, replacing the previous version of the document.
+// The documents are cached before their parameters are replaced, they don't hold the values of the parameters.
+func Put(log log.T, instanceID string, name string, content string) (err error) {
+	if name == "" {
+		return fmt.Errorf("the document has no name")
+	}
+	cacheDir := cacheDirectory(instanceID)
+	if err = fileutil.MakeDirs(cacheDir); err != nil {
+		return fmt.Errorf("failed to create the documents cache %v: %v", cacheDir, err)
+	}
+	if _, err = fileutil.WriteIntoFileWithPermissions(cachedFile(cacheDir, name), content, appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("failed to cache document %v: %v", name, err)
+	}
+	log.Debugf("Cached document %v", name)
+	return nil
+}
+
+// PutContent caches a parsed document, see Put. The JSON it was parsed from is cached when there's one, so that
+// its signature can still be verified.
+func PutContent(log log.T, instanceID string, name string, content *contracts.DocumentContent) error {
+	if raw := content.RawContent(); raw != nil {
+		return Put(log, instanceID, name, string(raw))
+	}
+	text, err := jsonutil.Marshal(content)
+	if err != nil {
+		return err
+	}
+	return Put(log, instanceID, name, text)
+}
+
+// Get returns the JSON content of a cached document.
+func Get(instanceID string, name string) (string, error) {
+	path := cachedFile(cacheDirectory(instanceID), name)
+	if !fileutil.Exists(path) {
+		return "", fmt.Errorf("document %v isn't cached, it must run once on the instance first", name)
+	}
+	return fileutil.ReadAllText(path)
+}
+
+// GetContent returns a cached document parsed, see Get.
+func GetContent(instanceID string, name string) (content contracts.DocumentContent, err error) {
+	var text string
+	if text, err = Get(instanceID, name); err != nil {
+		return content, err
+	}
+	if err = jsonutil.Unmarshal(text, &content); err != nil {
+		return content, fmt.Errorf("cached document %v is invalid: %v", name, err)
+	}
+	return content, nil
+}
+
+// cachedFile returns the file of a document in the cache; the names of shared documents are ARNs,
+// their colons and slashes are escaped.
+func cachedFile(cacheDir string, name string) string {
+	return filepath.Join(cacheDir, url.QueryEscape(name)+".json")
+}
@@ -0,0 +1,56 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package doccache
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func useTempCache(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "doccache")
+	assert.NoError(t, err)
+	cacheDirectory = func(string) string { return dir }
+	return func() { os.RemoveAll(dir) }
+}
+
+func TestPutAndGetContent(t *testing.T) {
+	defer useTempCache(t)()
+	name := "arn:aws:ssm:us-east-1:123456789012:document/Deploy"
+	content := &contracts.DocumentContent{SchemaVersion: "2.2", Description: "deploy {{ version }}"}
+
+	assert.NoError(t, PutContent(log.NewMockLog(), "i-bar", name, content))
+	cached, err := GetContent("i-bar", name)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.2", cached.SchemaVersion)
+	assert.Equal(t, "deploy {{ version }}", cached.Description)
+
+	assert.NoError(t, Put(log.NewMockLog(), "i-bar", name, `{"schemaVersion": "2.0"}`))
+	cached, err = GetContent("i-bar", name)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0", cached.SchemaVersion)
+}
+
+func TestGetMissingDocument(t *testing.T) {
+	defer useTempCache(t)()
+
+	_, err := Get("i-bar", "AWS-RunShellScript")
+	assert.Error(t, err)
+	assert.Error(t, Put(log.NewMockLog(), "i-bar", "", "{}"))
+}
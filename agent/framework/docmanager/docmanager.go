@@ -23,12 +23,18 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
 const (
 	maxLogFileDeletions int = 100
+
+	// stateLockFileName is the lock file of the document states, shared by the processes of the agent
+	stateLockFileName = ".lock"
+	// stateLockTimeout bounds the wait for the other processes changing the document states
+	stateLockTimeout = 30 * time.Second
 )
 
 type validString func(string) bool
@@ -59,6 +65,7 @@ func NewDocumentFileMgr(dataStorePath, rootDirName, stateLocation string) *Docum
 }
 
 func (d *DocumentFileMgr) MoveDocumentState(log log.T, fileName, instanceID, srcLocationFolder, dstLocationFolder string) {
+	defer d.lockState(log, instanceID)()
 
 	absoluteSource := path.Join(d.dataStorePath,
 		instanceID,
@@ -81,6 +88,7 @@ func (d *DocumentFileMgr) MoveDocumentState(log log.T, fileName, instanceID, src
 }
 
 func (d *DocumentFileMgr) PersistDocumentState(log log.T, fileName, instanceID, locationFolder string, state contracts.DocumentState) {
+	defer d.lockState(log, instanceID)()
 
 	absoluteFileName := path.Join(path.Join(d.dataStorePath,
 		instanceID,
@@ -96,7 +104,8 @@ func (d *DocumentFileMgr) PersistDocumentState(log log.T, fileName, instanceID,
 			log.Debugf("overwriting contents of %v", absoluteFileName)
 		}
 		log.Tracef("persisting interim state %v in file %v", jsonutil.Indent(content), absoluteFileName)
-		if s, err := fileutil.WriteIntoFileWithPermissions(absoluteFileName, jsonutil.Indent(content), os.FileMode(int(appconfig.ReadWriteAccess))); s && err == nil {
+		// an interrupted write would leave a document state the agent cannot resume
+		if err := fileutil.WriteAtomically(absoluteFileName, []byte(jsonutil.Indent(content)), os.FileMode(int(appconfig.ReadWriteAccess)), false); err == nil {
 			log.Debugf("successfully persisted interim state in %v", locationFolder)
 		} else {
 			log.Debugf("persisting interim state in %v failed with error %v", locationFolder, err)
@@ -105,6 +114,7 @@ func (d *DocumentFileMgr) PersistDocumentState(log log.T, fileName, instanceID,
 }
 
 func (d *DocumentFileMgr) GetDocumentState(log log.T, fileName, instanceID, locationFolder string) contracts.DocumentState {
+	defer d.lockState(log, instanceID)()
 
 	absoluteFileName := path.Join(path.Join(d.dataStorePath,
 		instanceID,
@@ -131,6 +141,7 @@ func (d *DocumentFileMgr) GetDocumentState(log log.T, fileName, instanceID, loca
 
 // RemoveData deletes the fileName from locationFolder under defaultLogDir/instanceID
 func (d *DocumentFileMgr) RemoveDocumentState(log log.T, commandID, instanceID, locationFolder string) {
+	defer d.lockState(log, instanceID)()
 
 	absoluteFileName := docStateFileName(commandID, instanceID, locationFolder)
 
@@ -142,6 +153,23 @@ func (d *DocumentFileMgr) RemoveDocumentState(log log.T, commandID, instanceID,
 	}
 }
 
+// lockState takes the lock of the document states of the instance and returns the function releasing it.
+// The agent and the document workers change the states concurrently, the operations go on without the lock
+// when it cannot be taken.
+func (d *DocumentFileMgr) lockState(log log.T, instanceID string) (unlock func()) {
+	stateDir := path.Join(d.dataStorePath, instanceID, d.rootDirName, d.stateLocation)
+	if err := fileutil.MakeDirs(stateDir); err != nil {
+		log.Warnf("failed to create %v, proceeding without the lock of the document states: %v", stateDir, err)
+		return func() {}
+	}
+	lock, err := filelock.Acquire(filepath.Join(stateDir, stateLockFileName), stateLockTimeout)
+	if err != nil {
+		log.Warnf("proceeding without the lock of the document states: %v", err)
+		return func() {}
+	}
+	return func() { lock.Unlock() }
+}
+
 //TODO rework this part
 // DocumentStateDir returns absolute filename where command states are persisted
 func DocumentStateDir(instanceID, locationFolder string) string {
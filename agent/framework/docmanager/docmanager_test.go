@@ -0,0 +1,59 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentStatesWaitForTheLockOfOtherProcesses(t *testing.T) {
+	dataStore, err := ioutil.TempDir("", "docmanager")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dataStore)
+	mgr := NewDocumentFileMgr(dataStore, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState)
+	stateDir := filepath.Join(dataStore, "i-1", appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState)
+	assert.NoError(t, os.MkdirAll(filepath.Join(stateDir, appconfig.DefaultLocationOfPending), 0700))
+
+	// another process holds the lock
+	lock, err := filelock.TryLock(filepath.Join(stateDir, stateLockFileName))
+	assert.NoError(t, err)
+
+	state := contracts.DocumentState{DocumentInformation: contracts.DocumentInfo{DocumentID: "cmd-1"}}
+	persisted := make(chan struct{})
+	go func() {
+		mgr.PersistDocumentState(log.NewMockLog(), "cmd-1", "i-1", appconfig.DefaultLocationOfPending, state)
+		close(persisted)
+	}()
+
+	select {
+	case <-persisted:
+		assert.Fail(t, "the document state was persisted while the lock was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.NoError(t, lock.Unlock())
+	<-persisted
+
+	loaded := mgr.GetDocumentState(log.NewMockLog(), "cmd-1", "i-1", appconfig.DefaultLocationOfPending)
+	assert.Equal(t, "cmd-1", loaded.DocumentInformation.DocumentID)
+}
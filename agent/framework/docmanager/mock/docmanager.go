@@ -0,0 +1,44 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docmanagermock
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/mock"
+)
+
+type DocumentMgrMock struct {
+	mock.Mock
+}
+
+func (m *DocumentMgrMock) MoveDocumentState(log log.T, fileName, instanceID, srcLocationFolder, dstLocationFolder string) {
+	m.Called(log, fileName, instanceID, srcLocationFolder, dstLocationFolder)
+	return
+}
+
+func (m *DocumentMgrMock) PersistDocumentState(log log.T, fileName, instanceID, locationFolder string, state contracts.DocumentState) {
+	m.Called(log, fileName, instanceID, locationFolder, state)
+	return
+}
+
+func (m *DocumentMgrMock) GetDocumentState(log log.T, fileName, instanceID, locationFolder string) contracts.DocumentState {
+	args := m.Called(log, fileName, instanceID, locationFolder)
+	return args.Get(0).(contracts.DocumentState)
+}
+
+func (m *DocumentMgrMock) RemoveDocumentState(log log.T, documentID, instanceID, location string) {
+	m.Called(log, documentID, instanceID, location)
+	return
+}
@@ -0,0 +1,198 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor defines the document processing unit interface
+package processor
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// concurrencyReloadName is the name the limiter is notified of the reloaded configuration under
+const concurrencyReloadName = "DocumentConcurrency"
+
+// documentSlots are the executions running under each limit of DocumentConcurrency, shared by the processors of
+// Run Command and State Manager
+var documentSlots = newConcurrencyLimiter()
+
+// concurrencyLimiter counts the running executions by limit name, and keeps the documents deferred by their limits
+// until slots are released. The deferred documents aren't submitted to the task pools, so that they don't hold
+// the workers the other documents need.
+type concurrencyLimiter struct {
+	m        sync.Mutex
+	running  map[string]int
+	deferred []*deferredDocument
+}
+
+// deferredDocument is a document waiting for slots of its limits before it's submitted.
+type deferredDocument struct {
+	jobID string
+	// owner is the processor the document was submitted to
+	owner interface{}
+	// limits returns the limits of the document in the current configuration
+	limits func() map[string]int
+	// submit submits the document to its task pool, release gives its slots back once it completes
+	submit func(release func())
+}
+
+func newConcurrencyLimiter() *concurrencyLimiter {
+	return &concurrencyLimiter{
+		running: make(map[string]int),
+	}
+}
+
+// acquireOrDefer takes a slot of each limit of the document and returns the function giving them back, or defers
+// the document until the slots are released and returns false.
+func (l *concurrencyLimiter) acquireOrDefer(document *deferredDocument) (release func(), acquired bool) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	limits := document.limits()
+	if !l.tryAcquireLocked(limits) {
+		l.deferred = append(l.deferred, document)
+		return nil, false
+	}
+	return l.releaseFunc(limits), true
+}
+
+// tryAcquireLocked takes a slot of each limit if all of them have one left, the caller holds the lock.
+func (l *concurrencyLimiter) tryAcquireLocked(limits map[string]int) bool {
+	for name, limit := range limits {
+		if l.running[name] >= limit {
+			return false
+		}
+	}
+	for name := range limits {
+		l.running[name]++
+	}
+	return true
+}
+
+// releaseFunc returns the function giving the slots back and submitting the deferred documents that can run.
+func (l *concurrencyLimiter) releaseFunc(limits map[string]int) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.m.Lock()
+			for name := range limits {
+				if l.running[name]--; l.running[name] <= 0 {
+					delete(l.running, name)
+				}
+			}
+			l.m.Unlock()
+			l.drain()
+		})
+	}
+}
+
+// drain submits the deferred documents that can run within their limits, in the order they were deferred. They
+// are submitted from another goroutine, the release may come from a worker of the pool they are submitted to.
+func (l *concurrencyLimiter) drain() {
+	l.m.Lock()
+	var ready []*deferredDocument
+	var releases []func()
+	remaining := l.deferred[:0]
+	for _, document := range l.deferred {
+		if limits := document.limits(); l.tryAcquireLocked(limits) {
+			ready = append(ready, document)
+			releases = append(releases, l.releaseFunc(limits))
+		} else {
+			remaining = append(remaining, document)
+		}
+	}
+	l.deferred = remaining
+	l.m.Unlock()
+	if len(ready) == 0 {
+		return
+	}
+	go func() {
+		for i, document := range ready {
+			document.submit(releases[i])
+		}
+	}()
+}
+
+// isDeferred returns true if the job is deferred by its limits.
+func (l *concurrencyLimiter) isDeferred(jobID string) bool {
+	l.m.Lock()
+	defer l.m.Unlock()
+	for _, document := range l.deferred {
+		if document.jobID == jobID {
+			return true
+		}
+	}
+	return false
+}
+
+// submitDeferred submits the job without waiting for its limits if it's deferred, so that its cancellation is
+// reported the usual way, and returns false if it isn't.
+func (l *concurrencyLimiter) submitDeferred(jobID string) bool {
+	l.m.Lock()
+	var found *deferredDocument
+	for i, document := range l.deferred {
+		if document.jobID == jobID {
+			found = document
+			l.deferred = append(l.deferred[:i], l.deferred[i+1:]...)
+			break
+		}
+	}
+	l.m.Unlock()
+	if found == nil {
+		return false
+	}
+	found.submit(func() {})
+	return true
+}
+
+// discard forgets the documents deferred by the owner, which stay pending for the next start.
+func (l *concurrencyLimiter) discard(owner interface{}) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	remaining := l.deferred[:0]
+	for _, document := range l.deferred {
+		if document.owner != owner {
+			remaining = append(remaining, document)
+		}
+	}
+	l.deferred = remaining
+}
+
+// concurrencyLimits returns the limits of DocumentConcurrency that apply to the document: the limit of its name
+// and the limits of its categories.
+func concurrencyLimits(config appconfig.DocumentConcurrencyCfg, documentName string) map[string]int {
+	// shared documents are run by ARN, their limits are set by name
+	name := documentName[strings.LastIndex(documentName, "/")+1:]
+	limits := make(map[string]int)
+	if limit, found := config.MaxConcurrent[name]; found && limit > 0 {
+		limits[name] = limit
+	}
+	for category, documents := range config.Categories {
+		if limit, found := config.MaxConcurrent[category]; found && limit > 0 && appconfig.Allowed(documents, name) {
+			limits[category] = limit
+		}
+	}
+	return limits
+}
+
+// limitNames returns the sorted names of the limits, for the logs
+func limitNames(limits map[string]int) string {
+	var names []string
+	for name := range limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
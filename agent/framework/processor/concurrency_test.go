@@ -0,0 +1,219 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package processor
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// concurrencyContext returns a context with the default limits of DocumentConcurrency
+func concurrencyContext() *context.Mock {
+	ctx := new(context.Mock)
+	ctx.On("AppConfig").Return(appconfig.DefaultConfig())
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	ctx.On("CurrentContext").Return([]string{})
+	return ctx
+}
+
+func documentNamed(id string, name string) *contracts.DocumentState {
+	docState := &contracts.DocumentState{}
+	docState.DocumentInformation.DocumentID = id
+	docState.DocumentInformation.DocumentName = name
+	return docState
+}
+
+func TestConcurrencyLimits(t *testing.T) {
+	config := appconfig.DocumentConcurrencyCfg{
+		MaxConcurrent: map[string]int{"Patching": 1, "AWS-RunPatchBaseline": 2, "Scripts": 0, "Everything": 10},
+		Categories: map[string]string{
+			"Patching":   "AWS-RunPatchBaseline, AWS-InstallWindowsUpdates",
+			"Scripts":    "AWS-RunShellScript",
+			"Everything": "*",
+			"Unlimited":  "AWS-RunPatchBaseline",
+		},
+	}
+	assert.Equal(t, map[string]int{"Patching": 1, "AWS-RunPatchBaseline": 2, "Everything": 10},
+		concurrencyLimits(config, "arn:aws:ssm:us-east-1:123456789012:document/AWS-RunPatchBaseline"))
+	assert.Equal(t, map[string]int{"Everything": 10}, concurrencyLimits(config, "AWS-RunShellScript"))
+	assert.Empty(t, concurrencyLimits(appconfig.DocumentConcurrencyCfg{}, "AWS-RunShellScript"))
+}
+
+// limitedDocument returns a deferred document whose submissions are sent to submitted
+func limitedDocument(jobID string, limits map[string]int, submitted chan string) *deferredDocument {
+	return &deferredDocument{
+		jobID:  jobID,
+		limits: func() map[string]int { return limits },
+		submit: func(release func()) { submitted <- jobID },
+	}
+}
+
+func TestConcurrencyLimiterDefersUntilTheSlotsAreReleased(t *testing.T) {
+	limiter := newConcurrencyLimiter()
+	submitted := make(chan string, 3)
+	patching := map[string]int{appconfig.DefaultPatchingCategory: 1}
+
+	release, acquired := limiter.acquireOrDefer(limitedDocument("patch-1", patching, submitted))
+	assert.True(t, acquired)
+	_, acquired = limiter.acquireOrDefer(limitedDocument("patch-2", patching, submitted))
+	assert.False(t, acquired)
+	assert.True(t, limiter.isDeferred("patch-2"))
+	// the scripts aren't limited
+	releaseScript, acquired := limiter.acquireOrDefer(limitedDocument("script", map[string]int{}, submitted))
+	assert.True(t, acquired)
+	releaseScript()
+	assert.Empty(t, submitted)
+
+	release()
+	// a release given twice doesn't free the slot of another document
+	release()
+	select {
+	case jobID := <-submitted:
+		assert.Equal(t, "patch-2", jobID)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the second patching document isn't submitted once the first one is complete")
+	}
+	assert.False(t, limiter.isDeferred("patch-2"))
+	assert.Equal(t, 1, limiter.running[appconfig.DefaultPatchingCategory])
+}
+
+func TestConcurrencyLimiterSubmitsTheCanceledDocuments(t *testing.T) {
+	limiter := newConcurrencyLimiter()
+	submitted := make(chan string, 1)
+	patching := map[string]int{appconfig.DefaultPatchingCategory: 1}
+	limiter.acquireOrDefer(limitedDocument("patch-1", patching, submitted))
+	limiter.acquireOrDefer(limitedDocument("patch-2", patching, submitted))
+
+	assert.True(t, limiter.submitDeferred("patch-2"))
+	assert.Equal(t, "patch-2", <-submitted)
+	assert.False(t, limiter.submitDeferred("patch-2"))
+	// the canceled document doesn't take the slot of the running one
+	assert.Equal(t, 1, limiter.running[appconfig.DefaultPatchingCategory])
+}
+
+func TestConcurrencyLimiterDiscardsTheDocumentsOfAStoppedProcessor(t *testing.T) {
+	limiter := newConcurrencyLimiter()
+	submitted := make(chan string, 1)
+	patching := map[string]int{appconfig.DefaultPatchingCategory: 1}
+	release, _ := limiter.acquireOrDefer(limitedDocument("patch-1", patching, submitted))
+	stopped := limitedDocument("patch-2", patching, submitted)
+	stopped.owner = "stopped"
+	limiter.acquireOrDefer(stopped)
+
+	limiter.discard("stopped")
+	release()
+
+	assert.False(t, limiter.isDeferred("patch-2"))
+	select {
+	case <-submitted:
+		assert.Fail(t, "the document of the stopped processor is submitted")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// discardingDocumentMgr drops the document states, the testify mock formats its arguments while the workers log
+type discardingDocumentMgr struct{}
+
+func (discardingDocumentMgr) MoveDocumentState(log.T, string, string, string, string) {}
+
+func (discardingDocumentMgr) PersistDocumentState(log.T, string, string, string, contracts.DocumentState) {
+}
+
+func (discardingDocumentMgr) GetDocumentState(log.T, string, string, string) contracts.DocumentState {
+	return contracts.DocumentState{}
+}
+
+func (discardingDocumentMgr) RemoveDocumentState(log.T, string, string, string) {}
+
+// blockingExecuter runs the patching documents until unblocked, and completes the others right away
+type blockingExecuter struct {
+	started chan string
+	unblock chan struct{}
+}
+
+func (e *blockingExecuter) Run(cancelFlag task.CancelFlag, docStore executer.DocumentStore) chan contracts.DocumentResult {
+	docState := docStore.Load()
+	statusChan := make(chan contracts.DocumentResult)
+	e.started <- docState.DocumentInformation.DocumentID
+	go func() {
+		if len(concurrencyLimits(appconfig.DefaultConfig().DocumentConcurrency, docState.DocumentInformation.DocumentName)) > 0 {
+			<-e.unblock
+		}
+		close(statusChan)
+	}()
+	return statusChan
+}
+
+func TestDeferredDocumentsDontHoldTheWorkers(t *testing.T) {
+	ctx := concurrencyContext()
+	blocking := &blockingExecuter{started: make(chan string, 4), unblock: make(chan struct{})}
+	pool := task.NewPool(ctx.Log(), 2, time.Second, times.DefaultClock)
+	processor := EngineProcessor{
+		context:         ctx,
+		executerCreator: func(context.T) executer.Executer { return blocking },
+		sendCommandPool: pool,
+		documentMgr:     discardingDocumentMgr{},
+		resChan:         make(chan contracts.DocumentResult),
+	}
+	defer documentSlots.discard(&processor)
+	submit := func(id string, name string) {
+		docState := documentNamed(id, name)
+		docState.DocumentInformation.MessageID = id
+		docState.DocumentType = contracts.SendCommand
+		processor.Submit(*docState)
+	}
+
+	// the patching documents waiting for the first one would take the second worker of the pool
+	submit("patch-1", "AWS-RunPatchBaseline")
+	submit("patch-2", "AWS-RunPatchBaseline")
+	submit("patch-3", "AWS-InstallWindowsUpdates")
+	submit("script", "AWS-RunShellScript")
+
+	var started []string
+	for len(started) < 2 {
+		select {
+		case id := <-blocking.started:
+			started = append(started, id)
+		case <-time.After(time.Second):
+			assert.FailNow(t, "the script waits behind the deferred patching documents")
+		}
+	}
+	sort.Strings(started)
+	assert.Equal(t, []string{"patch-1", "script"}, started)
+	assert.True(t, documentSlots.isDeferred("patch-2"))
+	assert.True(t, documentSlots.isDeferred("patch-3"))
+
+	close(blocking.unblock)
+	for _, expected := range []string{"patch-2", "patch-3"} {
+		select {
+		case id := <-blocking.started:
+			assert.Equal(t, expected, id)
+		case <-time.After(time.Second):
+			assert.Fail(t, "the deferred patching documents don't run once the first one is complete")
+		}
+	}
+	pool.ShutdownAndWait(time.Second)
+}
@@ -19,30 +19,59 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 
+	"fmt"
 	"sync"
+	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/framework/docattachments"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
-//TODO currently BasicExecuter.Run() is not idempotent, we should make it so in future
 // BasicExecuter is a thin wrapper over runPlugins().
 type BasicExecuter struct {
 	resChan chan contracts.DocumentResult
 	ctx     context.T
+	plugins runpluginutil.PluginRegistry
 }
 
 var pluginRunner = func(context context.T,
 	docState contracts.DocumentState,
+	plugins runpluginutil.PluginRegistry,
 	resChan chan contracts.PluginResult,
 	cancelFlag task.CancelFlag) (pluginOutputs map[string]*contracts.PluginResult) {
-	return runpluginutil.RunPlugins(context, docState.InstancePluginsInformation, docState.IOConfig, runpluginutil.SSMPluginRegistry, resChan, cancelFlag)
+	return runpluginutil.RunPlugins(context, docState.InstancePluginsInformation, docState.IOConfig, plugins, resChan, cancelFlag)
 
 }
 
+// fetchAttachments is replaced in the tests
+var fetchAttachments = docattachments.Fetch
+
+// failPlugins marks the plugins of the document that have not run yet as failed with the given message.
+func failPlugins(docState *contracts.DocumentState, message string) {
+	now := time.Now()
+	for i := range docState.InstancePluginsInformation {
+		plugin := &docState.InstancePluginsInformation[i]
+		switch plugin.Result.Status {
+		case "", contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
+			plugin.Result = contracts.PluginResult{
+				PluginID:      plugin.Id,
+				PluginName:    plugin.Name,
+				Status:        contracts.ResultStatusFailed,
+				Code:          1,
+				Output:        message,
+				StandardError: message,
+				StartDateTime: now,
+				EndDateTime:   now,
+			}
+		}
+	}
+}
+
 func run(context context.T,
 	docStore executer.DocumentStore,
+	plugins runpluginutil.PluginRegistry,
 	resChan chan contracts.DocumentResult,
 	cancelFlag task.CancelFlag) {
 	defer func() {
@@ -57,6 +86,11 @@ func run(context context.T,
 	nPlugins := len(docState.InstancePluginsInformation)
 	documentName := docState.DocumentInformation.DocumentName
 	documentVersion := docState.DocumentInformation.DocumentVersion
+	// the steps find the attachments of the document in place when they start
+	if err := fetchAttachments(context.Log(), docState.DocumentInformation.InstanceID, docState.IOConfig.OrchestrationDirectory, docState.AttachmentsContent); err != nil {
+		context.Log().Error(err)
+		failPlugins(&docState, fmt.Sprintf("Failed to fetch the attachments of the document: %v", err))
+	}
 	//status channel for plugins update
 	statusChan := make(chan contracts.PluginResult)
 	var wg sync.WaitGroup
@@ -86,10 +120,12 @@ func run(context context.T,
 			}
 			resChan <- docResult
 			contracts.UpdateDocState(&docResult, state)
+			// checkpoint the step, a document resumed after a restart doesn't run the completed steps again
+			docStore.Save(*state)
 		}
 	}(&docState)
 
-	outputs := pluginRunner(context, docState, statusChan, cancelFlag)
+	outputs := pluginRunner(context, docState, plugins, statusChan, cancelFlag)
 	close(statusChan)
 	//make sure the launched go routine has finshed before sending the final response
 	wg.Wait()
@@ -123,6 +159,16 @@ func NewBasicExecuter(context context.T) *BasicExecuter {
 	}
 }
 
+// NewBasicExecuterWithPlugins returns an executer running the documents with the given plugins instead of
+// the plugins of the agent. Programs embedding the document engine use it with their own transport: they
+// parse the documents with docparser, run them and pick the results up from the channel returned by Run.
+func NewBasicExecuterWithPlugins(context context.T, plugins runpluginutil.PluginRegistry) *BasicExecuter {
+	return &BasicExecuter{
+		ctx:     context.With("[BasicExecuter]"),
+		plugins: plugins,
+	}
+}
+
 func (e *BasicExecuter) Run(
 	cancelFlag task.CancelFlag,
 	docStore executer.DocumentStore) chan contracts.DocumentResult {
@@ -134,6 +180,10 @@ func (e *BasicExecuter) Run(
 	e.resChan = make(chan contracts.DocumentResult, nPlugins)
 
 	log.Debug("Running plugins...")
-	go run(e.ctx, docStore, e.resChan, cancelFlag)
+	plugins := e.plugins
+	if plugins == nil {
+		plugins = runpluginutil.SSMPluginRegistry
+	}
+	go run(e.ctx, docStore, plugins, e.resChan, cancelFlag)
 	return e.resChan
 }
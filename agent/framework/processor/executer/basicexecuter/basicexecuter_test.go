@@ -15,11 +15,21 @@
 package basicexecuter
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docattachments"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	executermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/mock"
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
@@ -100,8 +110,13 @@ func testBasicExecuter(t *testing.T, testCase TestCase) {
 	resultState.InstancePluginsInformation[0].Result = *testCase.PluginResults["plugin1"]
 	dataStoreMock.On("Load").Return(state)
 	dataStoreMock.On("Save", resultState).Return()
+	// the step is checkpointed while the document is in progress
+	checkpointState := resultState
+	checkpointState.DocumentInformation.DocumentStatus = contracts.ResultStatusInProgress
+	dataStoreMock.On("Save", checkpointState).Return()
 	pluginRunner = func(context context.T,
 		docState contracts.DocumentState,
+		plugins runpluginutil.PluginRegistry,
 		resChan chan contracts.PluginResult,
 		cancelFlag task.CancelFlag) map[string]*contracts.PluginResult {
 		outputs := make(map[string]*contracts.PluginResult)
@@ -145,3 +160,129 @@ func testBasicExecuter(t *testing.T, testCase TestCase) {
 	dataStoreMock.AssertExpectations(t)
 
 }
+
+// runPlugins is the plugin runner of the executer, the other tests replace it
+var runPlugins = pluginRunner
+
+// greetPlugin is an action of a program embedding the document engine
+type greetPlugin struct{}
+
+func (greetPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	var input struct{ Name string }
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	output.SetOutput("hello " + input.Name)
+	output.MarkAsSucceeded()
+}
+
+func TestRunWithPlugins(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "engine")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	ctx := context.NewMockDefault()
+	pluginRunner = runPlugins
+
+	// the document comes from the transport of the program embedding the engine
+	var document contracts.DocumentContent
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"schemaVersion": "2.2",
+		"mainSteps": [{"action": "vendor:greet", "name": "greet", "inputs": {"name": "{{ name }}"}}],
+		"parameters": {"name": {"type": "String", "default": "appliance"}}
+	}`), &document))
+	docState, err := docparser.InitializeDocState(ctx.Log(), contracts.SendCommandOffline, &document,
+		contracts.DocumentInfo{DocumentID: "greeting"}, docparser.DocumentParserInfo{OrchestrationDir: orchestrationDir}, nil)
+	assert.NoError(t, err)
+
+	plugins := runpluginutil.PluginRegistry{
+		"vendor:greet": runpluginutil.PluginFactory(func(context.T) (runpluginutil.T, error) { return greetPlugin{}, nil }),
+	}
+	docStore := executer.NewMemoryDocumentStore(docState)
+	var final contracts.DocumentResult
+	for result := range NewBasicExecuterWithPlugins(ctx, plugins).Run(task.NewChanneledCancelFlag(), docStore) {
+		final = result
+	}
+
+	assert.Equal(t, contracts.ResultStatusSuccess, final.Status)
+	assert.Equal(t, "hello appliance", final.PluginResults["greet"].Output)
+	assert.Equal(t, contracts.ResultStatusSuccess, docStore.Load().DocumentInformation.DocumentStatus)
+}
+
+func TestRunFailsStepsWhenAttachmentsCannotBeFetched(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "engine")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	ctx := context.NewMockDefault()
+	pluginRunner = runPlugins
+	fetchAttachments = func(log log.T, instanceID, orchestrationDir string, attachments []contracts.AttachmentContent) error {
+		assert.Len(t, attachments, 1)
+		return fmt.Errorf("link expired")
+	}
+	defer func() { fetchAttachments = docattachments.Fetch }()
+
+	var document contracts.DocumentContent
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"schemaVersion": "2.2",
+		"mainSteps": [{"action": "vendor:greet", "name": "greet", "inputs": {"name": "appliance"}}]
+	}`), &document))
+	docState, err := docparser.InitializeDocState(ctx.Log(), contracts.SendCommandOffline, &document,
+		contracts.DocumentInfo{DocumentID: "greeting"}, docparser.DocumentParserInfo{OrchestrationDir: orchestrationDir}, nil)
+	assert.NoError(t, err)
+	docState.AttachmentsContent = []contracts.AttachmentContent{{Name: "lib.sh", HashType: "Sha256"}}
+
+	plugins := runpluginutil.PluginRegistry{
+		"vendor:greet": runpluginutil.PluginFactory(func(context.T) (runpluginutil.T, error) { return greetPlugin{}, nil }),
+	}
+	var final contracts.DocumentResult
+	for result := range NewBasicExecuterWithPlugins(ctx, plugins).Run(task.NewChanneledCancelFlag(), executer.NewMemoryDocumentStore(docState)) {
+		final = result
+	}
+	assert.Equal(t, contracts.ResultStatusFailed, final.Status)
+	assert.Equal(t, contracts.ResultStatusFailed, final.PluginResults["greet"].Status)
+	assert.Contains(t, final.PluginResults["greet"].Output, "link expired")
+}
+
+// recordingStore records the states the executer saves
+type recordingStore struct {
+	executer.DocumentStore
+	saved []contracts.DocumentState
+}
+
+func (s *recordingStore) Save(state contracts.DocumentState) {
+	saved := state
+	saved.InstancePluginsInformation = append([]contracts.PluginState(nil), state.InstancePluginsInformation...)
+	s.saved = append(s.saved, saved)
+	s.DocumentStore.Save(state)
+}
+
+func TestRunCheckpointsEachStep(t *testing.T) {
+	docState := contracts.DocumentState{
+		DocumentInformation:        contracts.DocumentInfo{MessageID: "MessageID"},
+		InstancePluginsInformation: []contracts.PluginState{{Name: "aws:runScript", Id: "plugin1"}, {Name: "aws:runScript", Id: "plugin2"}},
+	}
+	pluginRunner = func(context context.T,
+		docState contracts.DocumentState,
+		plugins runpluginutil.PluginRegistry,
+		resChan chan contracts.PluginResult,
+		cancelFlag task.CancelFlag) map[string]*contracts.PluginResult {
+		outputs := make(map[string]*contracts.PluginResult)
+		for _, pluginState := range docState.InstancePluginsInformation {
+			result := contracts.PluginResult{PluginID: pluginState.Id, PluginName: pluginState.Name, Status: contracts.ResultStatusSuccess}
+			resChan <- result
+			outputs[pluginState.Id] = &result
+		}
+		return outputs
+	}
+	defer func() { pluginRunner = runPlugins }()
+
+	docStore := &recordingStore{DocumentStore: executer.NewMemoryDocumentStore(docState)}
+	for range NewBasicExecuter(context.NewMockDefault()).Run(task.NewChanneledCancelFlag(), docStore) {
+	}
+
+	assert.Len(t, docStore.saved, 3)
+	// the first checkpoint records the first step only, the resumed document runs the second one
+	assert.Equal(t, contracts.ResultStatusSuccess, docStore.saved[0].InstancePluginsInformation[0].Result.Status)
+	assert.Equal(t, contracts.ResultStatus(""), docStore.saved[0].InstancePluginsInformation[1].Result.Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, docStore.saved[2].DocumentInformation.DocumentStatus)
+}
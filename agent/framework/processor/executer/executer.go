@@ -75,3 +75,24 @@ func (f *DocumentFileStore) Save(docState contracts.DocumentState) {
 func (f *DocumentFileStore) Load() contracts.DocumentState {
 	return f.state
 }
+
+// MemoryDocumentStore keeps the document state in memory only, for the programs embedding the document engine
+// that persist the state, if they need to, with their own transport.
+type MemoryDocumentStore struct {
+	state contracts.DocumentState
+}
+
+// NewMemoryDocumentStore returns a store holding the state of a document.
+func NewMemoryDocumentStore(state contracts.DocumentState) *MemoryDocumentStore {
+	return &MemoryDocumentStore{state: state}
+}
+
+// Save keeps the document state.
+func (m *MemoryDocumentStore) Save(docState contracts.DocumentState) {
+	m.state = docState
+}
+
+// Load returns the document state.
+func (m *MemoryDocumentStore) Load() contracts.DocumentState {
+	return m.state
+}
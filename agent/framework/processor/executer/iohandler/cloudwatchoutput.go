@@ -0,0 +1,99 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iohandler
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
+)
+
+const (
+	stepNamePlaceholder = "{stepName}"
+	streamPlaceholder   = "{stream}"
+
+	// maxCloudWatchNameLength is the length of the longest log group and log stream names
+	maxCloudWatchNameLength = 512
+)
+
+var (
+	// invalidLogGroupCharacters and invalidLogStreamCharacters are replaced with _ in the names
+	invalidLogGroupCharacters  = regexp.MustCompile(`[^.\-_/#A-Za-z0-9]`)
+	invalidLogStreamCharacters = regexp.MustCompile(`[:*]`)
+)
+
+// WithCloudWatchOutput sets the log group and stream the output of the steps of the document is streamed to, from
+// the templates Agent.CloudWatchOutputLogGroup and Agent.CloudWatchOutputLogStream of the configuration with the
+// placeholders of the document replaced. {stepName} and {stream} are replaced for each step and output.
+func WithCloudWatchOutput(config appconfig.SsmagentConfig, docState *contracts.DocumentState) {
+	if config.Agent.CloudWatchOutputLogGroup == "" {
+		docState.IOConfig.CloudWatchLogGroup, docState.IOConfig.CloudWatchLogStream = "", ""
+		return
+	}
+	info := docState.DocumentInformation
+	commandID := info.CommandID
+	if commandID == "" {
+		// the run of the association
+		commandID = info.DocumentID
+	}
+	placeholders := strings.NewReplacer(
+		"{instanceId}", info.InstanceID,
+		"{documentName}", info.DocumentName,
+		"{documentVersion}", info.DocumentVersion,
+		"{commandId}", commandID,
+		"{associationId}", info.AssociationID,
+	)
+	docState.IOConfig.CloudWatchLogGroup = cloudWatchName(invalidLogGroupCharacters, placeholders.Replace(config.Agent.CloudWatchOutputLogGroup))
+	docState.IOConfig.CloudWatchLogStream = placeholders.Replace(config.Agent.CloudWatchOutputLogStream)
+}
+
+// cloudWatchOutput returns the module streaming an output of the step to CloudWatch Logs
+func cloudWatchOutput(ioConfig contracts.IOConfiguration, stepName string, stream string) iomodule.CloudWatchLogs {
+	logStream := strings.NewReplacer(stepNamePlaceholder, stepName, streamPlaceholder, stream).Replace(ioConfig.CloudWatchLogStream)
+	return iomodule.CloudWatchLogs{
+		LogGroupName:  ioConfig.CloudWatchLogGroup,
+		LogStreamName: cloudWatchName(invalidLogStreamCharacters, logStream),
+		FlushInterval: cloudWatchFlushInterval(),
+	}
+}
+
+// cloudWatchFlushInterval returns how often the output is sent to CloudWatch Logs
+func cloudWatchFlushInterval() time.Duration {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		return appconfig.DefaultCloudWatchOutputFlushIntervalSeconds * time.Second
+	}
+	return time.Duration(config.Agent.CloudWatchOutputFlushIntervalSeconds) * time.Second
+}
+
+// cloudWatchName replaces the characters CloudWatch Logs doesn't allow in the name and truncates it
+func cloudWatchName(invalidCharacters *regexp.Regexp, name string) string {
+	name = invalidCharacters.ReplaceAllString(name, "_")
+	if len(name) > maxCloudWatchNameLength {
+		name = name[:maxCloudWatchNameLength]
+	}
+	return name
+}
+
+// stepName returns the name of the step from the path of its output
+func stepName(filePath []string) string {
+	if len(filePath) == 0 {
+		return ""
+	}
+	return filePath[len(filePath)-1]
+}
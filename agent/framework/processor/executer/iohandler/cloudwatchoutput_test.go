@@ -0,0 +1,69 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iohandler
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithCloudWatchOutput(t *testing.T) {
+	config := appconfig.SsmagentConfig{}
+	config.Agent.CloudWatchOutputLogGroup = "/ssm/{documentName}"
+	config.Agent.CloudWatchOutputLogStream = appconfig.DefaultCloudWatchOutputLogStream
+	docState := contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{
+			InstanceID:   "i-1234",
+			DocumentName: "AWS:RunShellScript",
+			DocumentID:   "command-1234",
+			CommandID:    "command-1234",
+		},
+	}
+
+	WithCloudWatchOutput(config, &docState)
+	assert.Equal(t, "/ssm/AWS_RunShellScript", docState.IOConfig.CloudWatchLogGroup)
+	assert.Equal(t, "command-1234/i-1234/{stepName}/{stream}", docState.IOConfig.CloudWatchLogStream)
+
+	output := cloudWatchOutput(docState.IOConfig, stepName([]string{"runShellScript"}), "stdout")
+	assert.Equal(t, "/ssm/AWS_RunShellScript", output.LogGroupName)
+	assert.Equal(t, "command-1234/i-1234/runShellScript/stdout", output.LogStreamName)
+}
+
+func TestWithCloudWatchOutputForAssociations(t *testing.T) {
+	config := appconfig.SsmagentConfig{}
+	config.Agent.CloudWatchOutputLogGroup = "ssm-output"
+	config.Agent.CloudWatchOutputLogStream = "{associationId}/{commandId}:{stepName}"
+	docState := contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{
+			AssociationID: "association-1",
+			DocumentID:    "association-1.run-1",
+		},
+	}
+
+	WithCloudWatchOutput(config, &docState)
+	output := cloudWatchOutput(docState.IOConfig, stepName([]string{"step*1"}), "stderr")
+	assert.Equal(t, "association-1/association-1.run-1_step_1", output.LogStreamName)
+}
+
+func TestWithCloudWatchOutputDisabled(t *testing.T) {
+	docState := contracts.DocumentState{}
+	docState.IOConfig.CloudWatchLogGroup = "group"
+
+	WithCloudWatchOutput(appconfig.SsmagentConfig{}, &docState)
+	assert.Empty(t, docState.IOConfig.CloudWatchLogGroup)
+	assert.Empty(t, docState.IOConfig.CloudWatchLogStream)
+}
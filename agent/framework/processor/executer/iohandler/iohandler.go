@@ -18,23 +18,37 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
 )
 
 const (
 	// maximumPluginOutputSize represents the maximum output size that agent supports
 	MaximumPluginOutputSize = 2500
-	// truncateOut represents the string appended when output is truncated
-	truncateOut = "\n---Output truncated---"
-	// truncateError represents the string appended when error is truncated
-	truncateError = "\n---Error truncated----"
+	// truncateOut represents the string inserted where output is truncated
+	truncateOut = "\n---Output truncated---\n"
+	// truncateError represents the string inserted where error is truncated
+	truncateError = "\n---Error truncated---\n"
+	// attachmentsDirName is the folder the attachments are uploaded to, under the plugin output
+	attachmentsDirName = "attachments"
 )
 
+// uploadToS3 uploads an attachment to the output bucket
+var uploadToS3 = func(log log.T, bucketName, s3Key, filePath string) error {
+	return s3util.NewAmazonS3Util(log, bucketName).S3Upload(log, bucketName, s3Key, filePath)
+}
+
 // PluginConfig is used for initializing plugins with default values
 type PluginConfig struct {
 	StdoutFileName        string
@@ -55,6 +69,19 @@ func DefaultOutputConfig() PluginConfig {
 	}
 }
 
+// outputRotation returns the rotation of the plugin output files set in the agent configuration
+func outputRotation() log.RotationConfig {
+	config, err := appconfig.Config(false)
+	if err != nil || config.Agent.PluginOutputMaxSizeMB <= 0 {
+		return log.RotationConfig{}
+	}
+	return log.RotationConfig{
+		MaxSize:  int64(config.Agent.PluginOutputMaxSizeMB) * 1024 * 1024,
+		MaxRolls: config.Agent.PluginOutputMaxRolls,
+		Compress: true,
+	}
+}
+
 // IOHandler Interface defines interface for IOHandler type
 type IOHandler interface {
 	Init(log.T, ...string)
@@ -72,6 +99,7 @@ type IOHandler interface {
 	AppendInfof(format string, params ...interface{})
 	AppendError(message string)
 	AppendErrorf(format string, params ...interface{})
+	AddAttachment(log log.T, filePath string) error
 
 	// getters/setters
 	GetStatus() contracts.ResultStatus
@@ -81,6 +109,7 @@ type IOHandler interface {
 	GetStdoutWriter() multiwriter.DocumentIOMultiWriter
 	GetStderrWriter() multiwriter.DocumentIOMultiWriter
 	GetIOConfig() contracts.IOConfiguration
+	GetAttachments() []contracts.Attachment
 
 	SetStatus(contracts.ResultStatus)
 	SetExitCode(int)
@@ -99,6 +128,13 @@ type DefaultIOHandler struct {
 	ioConfig contracts.IOConfiguration
 	//refreshassociation and invoker write a different output rather than merging stdout and stderr
 	output interface{}
+	// s3KeyPrefix is the prefix the plugin output is uploaded under
+	s3KeyPrefix string
+	// attachments are the result files registered by the plugin
+	attachments []contracts.Attachment
+	// stopPartial stops reporting the output of the running step, partialDone is closed once it stopped
+	stopPartial chan struct{}
+	partialDone chan struct{}
 
 	// List of Writers attached to the IOHandler instance
 	StdoutWriter multiwriter.DocumentIOMultiWriter
@@ -118,6 +154,7 @@ func NewDefaultIOHandler(log log.T, ioConfig contracts.IOConfiguration) *Default
 func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 
 	pluginConfig := DefaultOutputConfig()
+	rotation := outputRotation()
 	// Create path to output location for file and s3
 	fullPath := out.ioConfig.OrchestrationDirectory
 	s3KeyPrefix := out.ioConfig.OutputS3KeyPrefix
@@ -125,6 +162,7 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		fullPath = fileutil.BuildPath(fullPath, element)
 		s3KeyPrefix = fileutil.BuildS3Path(s3KeyPrefix, element)
 	}
+	out.s3KeyPrefix = s3KeyPrefix
 
 	// Initialize file output module
 	stdoutFile := iomodule.File{
@@ -132,6 +170,7 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		OrchestrationDirectory: fullPath,
 		OutputS3BucketName:     out.ioConfig.OutputS3BucketName,
 		OutputS3KeyPrefix:      s3KeyPrefix,
+		Rotation:               rotation,
 	}
 
 	// Initialize console output module
@@ -145,7 +184,11 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 	log.Debug("Initializing the Stdout Multi-writer with file and console listeners")
 	// Get a multi-writer for standard output
 	out.StdoutWriter = multiwriter.NewDocumentIOMultiWriter()
-	out.RegisterOutputSource(log, out.StdoutWriter, stdoutFile, stdoutConsole)
+	stdoutModules := []iomodule.IOModule{stdoutFile, stdoutConsole}
+	if out.ioConfig.CloudWatchLogGroup != "" {
+		stdoutModules = append(stdoutModules, cloudWatchOutput(out.ioConfig, stepName(filePath), "stdout"))
+	}
+	out.RegisterOutputSource(log, out.StdoutWriter, stdoutModules...)
 
 	// Initialize file error module
 	stderrFile := iomodule.File{
@@ -153,6 +196,7 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		OrchestrationDirectory: fullPath,
 		OutputS3BucketName:     out.ioConfig.OutputS3BucketName,
 		OutputS3KeyPrefix:      s3KeyPrefix,
+		Rotation:               rotation,
 	}
 
 	// Initialize console error module
@@ -166,7 +210,13 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 	log.Debug("Initializing the Stderr Multi-writer with file and console listeners")
 	// Get a multi-writer for standard error
 	out.StderrWriter = multiwriter.NewDocumentIOMultiWriter()
-	out.RegisterOutputSource(log, out.StderrWriter, stderrFile, stderrConsole)
+	stderrModules := []iomodule.IOModule{stderrFile, stderrConsole}
+	if out.ioConfig.CloudWatchLogGroup != "" {
+		stderrModules = append(stderrModules, cloudWatchOutput(out.ioConfig, stepName(filePath), "stderr"))
+	}
+	out.RegisterOutputSource(log, out.StderrWriter, stderrModules...)
+
+	out.startPartialOutput(log, fullPath, pluginConfig)
 }
 
 // RegisterOutputSource returns a new output source by creating a multiwriter for the output modules.
@@ -194,6 +244,7 @@ func (out *DefaultIOHandler) RegisterOutputSource(log log.T, multiWriter multiwr
 // Close closes all the attached writers.
 func (out *DefaultIOHandler) Close(log log.T) {
 	log.Debug("IOHandler closing all subscribed writers.")
+	out.stopPartialOutput()
 	if out.StdoutWriter != nil {
 		out.StdoutWriter.Close()
 	}
@@ -205,7 +256,15 @@ func (out *DefaultIOHandler) Close(log log.T) {
 
 // String returns the output by concatenating stdout and stderr
 func (out DefaultIOHandler) String() (response string) {
-	return TruncateOutput(out.stdout, out.stderr, MaximumPluginOutputSize)
+	pluginConfig := DefaultOutputConfig()
+	bucketName := out.ioConfig.OutputS3BucketName
+	if out.s3KeyPrefix == "" {
+		// the output isn't uploaded before Init
+		bucketName = ""
+	}
+	return truncateOutput(out.stdout, out.stderr, MaximumPluginOutputSize,
+		truncateMarker(truncateOut, bucketName, fileutil.BuildS3Path(out.s3KeyPrefix, pluginConfig.StdoutFileName)),
+		truncateMarker(truncateError, bucketName, fileutil.BuildS3Path(out.s3KeyPrefix, pluginConfig.StderrFileName)))
 }
 
 // GetOutput returns the output to be appended to the response
@@ -252,6 +311,11 @@ func (out DefaultIOHandler) GetStderrWriter() multiwriter.DocumentIOMultiWriter
 	return out.StderrWriter
 }
 
+// GetAttachments returns the result files registered by the plugin
+func (out DefaultIOHandler) GetAttachments() []contracts.Attachment {
+	return out.attachments
+}
+
 // SetStatus sets the status
 func (out *DefaultIOHandler) SetStatus(status contracts.ResultStatus) {
 	out.Status = status
@@ -296,6 +360,8 @@ func (out *DefaultIOHandler) Merge(log log.T, mergeOutput *DefaultIOHandler) {
 	stderrBuffer.WriteString(mergeOutput.GetStderr())
 	out.stderr = stderrBuffer.String()
 
+	out.attachments = append(out.attachments, mergeOutput.GetAttachments()...)
+
 	if out.ExitCode == 0 {
 		out.ExitCode = mergeOutput.GetExitCode()
 	}
@@ -394,8 +460,14 @@ func (out *DefaultIOHandler) AppendErrorf(format string, params ...interface{})
 	}
 }
 
-// TruncateOutput truncates the output
+// TruncateOutput truncates the output to the capacity. A stream too long keeps its head and its tail, where the
+// error usually is, with a marker in between.
 func TruncateOutput(stdout string, stderr string, capacity int) (response string) {
+	return truncateOutput(stdout, stderr, capacity, truncateOut, truncateError)
+}
+
+// truncateOutput truncates the output to the capacity with the markers of the streams truncated
+func truncateOutput(stdout string, stderr string, capacity int, outMarker string, errorMarker string) (response string) {
 	outputSize := len(stdout)
 	errorSize := len(stderr)
 
@@ -417,17 +489,88 @@ func TruncateOutput(stdout string, stderr string, capacity int) (response string
 
 	// truncate out and error when both exceed the size
 	if outputSize > availableSpace/2 && errorSize > availableSpace/2 {
-		truncateSize := availableSpace - len(truncateError) - len(truncateOut)
-		return fmt.Sprint(stdout[:truncateSize/2], truncateOut, errorTitle, stderr[:truncateSize/2], truncateError)
+		return fmt.Sprint(truncateMiddle(stdout, availableSpace/2, outMarker), errorTitle, truncateMiddle(stderr, availableSpace/2, errorMarker))
 	}
 
 	// truncate error when output is short
 	if outputSize < availableSpace/2 {
-		truncateSize := availableSpace - len(truncateError)
-		return fmt.Sprint(stdout, errorTitle, stderr[:truncateSize-outputSize], truncateError)
+		return fmt.Sprint(stdout, errorTitle, truncateMiddle(stderr, availableSpace-outputSize, errorMarker))
 	}
 
 	// truncate output when error is short
-	truncateSize := availableSpace - len(truncateOut)
-	return fmt.Sprint(stdout[:truncateSize-errorSize], truncateOut, errorTitle, stderr)
+	return fmt.Sprint(truncateMiddle(stdout, availableSpace-errorSize, outMarker), errorTitle, stderr)
+}
+
+// truncateMiddle truncates the text to the size, keeping its head and its tail around the marker
+func truncateMiddle(text string, size int, marker string) string {
+	keep := size - len(marker)
+	if keep <= 0 {
+		return head(text, size)
+	}
+	headSize := keep / 2
+	return head(text, headSize) + marker + tail(text, keep-headSize)
+}
+
+// head returns the longest prefix of the text of at most size bytes that doesn't split a UTF-8 sequence
+func head(text string, size int) string {
+	for size > 0 && size < len(text) && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	return text[:size]
+}
+
+// tail returns the longest suffix of the text of at most size bytes that doesn't split a UTF-8 sequence
+func tail(text string, size int) string {
+	start := len(text) - size
+	for start > 0 && start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	return text[start:]
+}
+
+// truncateMarker returns the marker of a stream truncated, with the location of its full output if it's uploaded
+func truncateMarker(marker string, bucketName string, key string) string {
+	if bucketName == "" {
+		return marker
+	}
+	return fmt.Sprintf("%v (full output in s3://%v/%v)---\n", strings.TrimSuffix(marker, "---\n"), bucketName, key)
+}
+
+// AddAttachment registers a result file of the plugin. The file is uploaded to the output bucket, if any,
+// and listed with its checksum in the plugin result.
+func (out *DefaultIOHandler) AddAttachment(log log.T, filePath string) error {
+	// the commands may run as another user, a link could make the agent upload a file only it can read
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to attach %v: %v", filePath, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("failed to attach %v: attachment is a directory", filePath)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("failed to attach %v: attachment is not a regular file", filePath)
+	}
+	attachment := contracts.Attachment{
+		Name: filepath.Base(filePath),
+		Size: info.Size(),
+	}
+	for _, existing := range out.attachments {
+		if existing.Name == attachment.Name {
+			return fmt.Errorf("failed to attach %v: an attachment named %v already exists", filePath, attachment.Name)
+		}
+	}
+	if attachment.Sha256, err = artifact.Sha256HashValue(log, filePath); err != nil {
+		return fmt.Errorf("failed to compute the checksum of %v: %v", filePath, err)
+	}
+
+	if out.ioConfig.OutputS3BucketName != "" {
+		s3Key := fileutil.BuildS3Path(out.s3KeyPrefix, attachmentsDirName, attachment.Name)
+		if err = uploadToS3(log, out.ioConfig.OutputS3BucketName, s3Key, filePath); err != nil {
+			return fmt.Errorf("failed to upload %v to s3: %v", filePath, err)
+		}
+		attachment.S3Key = s3Key
+	}
+	log.Debugf("Attached %v with checksum %v", filePath, attachment.Sha256)
+	out.attachments = append(out.attachments, attachment)
+	return nil
 }
@@ -15,7 +15,12 @@ package iohandler
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"sync"
 	"time"
@@ -56,9 +61,9 @@ var testData = []truncateOutputTest{
 	{"sample output", "", sampleSize, "sample output"},
 	{"", "sample error", sampleSize, "\n----------ERROR-------\nsample error"},
 	{"sample output", "sample error", sampleSize, "sample output\n----------ERROR-------\nsample error"},
-	{longMessage, "", sampleSize, "This is a sample text. This is a sample text. This is a sample text. This is \n---Output truncated---"},
-	{"", longMessage, sampleSize, "\n----------ERROR-------\nThis is a sample text. This is a sample text. This is\n---Error truncated----"},
-	{longMessage, longMessage, sampleSize, "This is a sampl\n---Output truncated---\n----------ERROR-------\nThis is a sampl\n---Error truncated----"},
+	{longMessage, "", sampleSize, "This is a sample text. This is a sampl\n---Output truncated---\ns a sample text. This is a sample text"},
+	{"", longMessage, sampleSize, "\n----------ERROR-------\nThis is a sample text. Thi\n---Error truncated---\ntext. This is a sample text"},
+	{longMessage, longMessage, sampleSize, "This is\n---Output truncated---\nle text\n----------ERROR-------\nThis is\n---Error truncated---\nple text"},
 }
 
 func TestTruncateOutput(t *testing.T) {
@@ -68,6 +73,28 @@ func TestTruncateOutput(t *testing.T) {
 	}
 }
 
+func TestTruncateOutputKeepsWholeRunes(t *testing.T) {
+	text := strings.Repeat("é", sampleSize)
+
+	for _, capacity := range []int{sampleSize, sampleSize + 1, 10, 11} {
+		actual := TruncateOutput(text, "", capacity)
+		assert.True(t, utf8.ValidString(actual), "capacity %v", capacity)
+		assert.True(t, len(actual) <= capacity, "capacity %v", capacity)
+	}
+	assert.Equal(t, "éé"+truncateOut+"éé", truncateMiddle(text, len(truncateOut)+9, truncateOut))
+}
+
+func TestStringReportsTheLocationOfTheFullOutput(t *testing.T) {
+	out := NewDefaultIOHandler(logger, contracts.IOConfiguration{OutputS3BucketName: "bucket"})
+	out.s3KeyPrefix = "prefix/runShellScript"
+	out.stdout = longMessage + longMessage + longMessage + "\nfailed to install"
+
+	output := out.String()
+	assert.Len(t, output, MaximumPluginOutputSize)
+	assert.Contains(t, output, "\n---Output truncated (full output in s3://bucket/prefix/runShellScript/stdout)---\n")
+	assert.True(t, strings.HasSuffix(output, "failed to install"))
+}
+
 var logger = log.NewMockLog()
 
 func TestRegisterOutputSource(t *testing.T) {
@@ -174,3 +201,36 @@ func TestAppendFormat(t *testing.T) {
 	assert.Contains(t, output.GetStdout(), testStringFormatted)
 	assert.Contains(t, output.GetStderr(), testStringFormatted)
 }
+
+func TestAddAttachment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "attachments")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	reportPath := filepath.Join(dir, "report.xml")
+	assert.NoError(t, ioutil.WriteFile(reportPath, []byte("report"), 0600))
+
+	var uploadedKey string
+	uploadToS3 = func(log log.T, bucketName, s3Key, filePath string) error {
+		uploadedKey = s3Key
+		return nil
+	}
+	output := NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{OutputS3BucketName: "bucket"})
+	output.s3KeyPrefix = "prefix/aws:runShellScript"
+
+	assert.NoError(t, output.AddAttachment(log.NewMockLog(), reportPath))
+	assert.Error(t, output.AddAttachment(log.NewMockLog(), reportPath), "attachment names are unique")
+	assert.Error(t, output.AddAttachment(log.NewMockLog(), filepath.Join(dir, "missing.xml")))
+	assert.Error(t, output.AddAttachment(log.NewMockLog(), dir))
+	linkPath := filepath.Join(dir, "link.xml")
+	if os.Symlink(reportPath, linkPath) == nil {
+		assert.Error(t, output.AddAttachment(log.NewMockLog(), linkPath), "links are refused")
+	}
+
+	assert.Equal(t, "prefix/aws:runShellScript/attachments/report.xml", uploadedKey)
+	assert.Equal(t, []contracts.Attachment{{
+		Name:   "report.xml",
+		S3Key:  "prefix/aws:runShellScript/attachments/report.xml",
+		Size:   6,
+		Sha256: "845e91831319e89c4d656bdb80c278ac09a7230d61e5dfd2e1b1fbb436ac8917",
+	}}, output.GetAttachments())
+}
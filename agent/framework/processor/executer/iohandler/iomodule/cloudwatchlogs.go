@@ -0,0 +1,200 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+const (
+	// maxEventSize is the size of the longest line sent as one event, the longer lines are split
+	maxEventSize = 256*1024 - eventOverhead
+	// eventOverhead is the size CloudWatch Logs counts for each event on top of its message
+	eventOverhead = 26
+	// maxBatchSize and maxBatchEvents are the limits of PutLogEvents
+	maxBatchSize   = 1024 * 1024
+	maxBatchEvents = 10000
+	// putAttempts is the number of times a batch is sent before it's dropped
+	putAttempts = 3
+	// maxBufferedSize and maxBufferedEvents are the limits of the lines read while the previous ones are sent, the
+	// lines read beyond them are dropped rather than holding the output of the step
+	maxBufferedSize   = 4 * maxBatchSize
+	maxBufferedEvents = maxBatchEvents
+
+	accessDeniedException = "AccessDeniedException"
+)
+
+// newCloudWatchLogsService returns the service the output is sent with
+var newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService {
+	return cloudwatchlogspublisher.NewCloudWatchLogsService()
+}
+
+// putRetryDelay is the delay before a batch is sent again
+var putRetryDelay = time.Second
+
+// CloudWatchLogs streams the output to a log stream of CloudWatch Logs, a line per event, in batches sent every
+// FlushInterval or once they reach the limits of PutLogEvents.
+type CloudWatchLogs struct {
+	LogGroupName  string
+	LogStreamName string
+	FlushInterval time.Duration
+}
+
+// cloudWatchBatch is the events read since the last PutLogEvents
+type cloudWatchBatch struct {
+	events        []*cloudwatchlogs.InputLogEvent
+	size          int
+	sequenceToken *string
+	// stopped is set once the instance isn't allowed to send the output
+	stopped bool
+}
+
+// Read reads the output line by line and sends it to the log stream. The output is read while the lines are sent,
+// the lines read while CloudWatch Logs doesn't keep up are buffered up to maxBufferedSize, then dropped.
+func (c CloudWatchLogs) Read(log log.T, reader *io.PipeReader) {
+	defer reader.Close()
+	service := newCloudWatchLogsService()
+
+	lines := make(chan string, maxBufferedEvents)
+	var buffered int64
+	dropped := 0
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+		scanner.Split(scanEvents)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if atomic.LoadInt64(&buffered)+int64(len(line)) > maxBufferedSize {
+				dropped++
+				continue
+			}
+			select {
+			case lines <- line:
+				atomic.AddInt64(&buffered, int64(len(line)))
+			default:
+				dropped++
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Errorf("Error with the scanner while reading the stream: %v", err)
+		}
+	}()
+
+	// the log group may be created by the administrators, the instance then only needs logs:CreateLogStream and
+	// logs:PutLogEvents, CreateLogGroup fails otherwise
+	service.CreateLogGroup(log, c.LogGroupName)
+	if err := service.CreateLogStream(log, c.LogGroupName, c.LogStreamName); err != nil {
+		log.Errorf("Failed to create the log stream %v of %v, the output isn't sent to CloudWatch Logs: %v", c.LogStreamName, c.LogGroupName, err)
+		// the rest of the output is read and dropped, the reader isn't closed before the step ends
+		for range lines {
+		}
+		return
+	}
+
+	interval := c.FlushInterval
+	if interval <= 0 {
+		interval = appconfig.DefaultCloudWatchOutputFlushIntervalSeconds * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	batch := &cloudWatchBatch{}
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				// the lines are closed once the output is read, dropped is set
+				if dropped > 0 {
+					log.Warnf("Dropped %v lines of output, CloudWatch Logs didn't keep up with the output", dropped)
+					c.add(log, service, batch, fmt.Sprintf("[%v lines of output dropped, CloudWatch Logs didn't keep up]", dropped))
+				}
+				c.flush(log, service, batch)
+				return
+			}
+			atomic.AddInt64(&buffered, -int64(len(line)))
+			c.add(log, service, batch, line)
+		case <-ticker.C:
+			c.flush(log, service, batch)
+		}
+	}
+}
+
+// add adds the line to the batch, which is sent first if the line doesn't fit
+func (c CloudWatchLogs) add(log log.T, service cloudwatchlogsinterface.ICloudWatchLogsService, batch *cloudWatchBatch, line string) {
+	if line == "" {
+		// the events can't be empty
+		line = " "
+	}
+	if len(batch.events) >= maxBatchEvents || batch.size+len(line)+eventOverhead > maxBatchSize {
+		c.flush(log, service, batch)
+	}
+	batch.events = append(batch.events, &cloudwatchlogs.InputLogEvent{
+		Message:   aws.String(line),
+		Timestamp: aws.Int64(time.Now().UnixNano() / int64(time.Millisecond)),
+	})
+	batch.size += len(line) + eventOverhead
+}
+
+// flush sends the events of the batch, the events that can't be sent after putAttempts are dropped
+func (c CloudWatchLogs) flush(log log.T, service cloudwatchlogsinterface.ICloudWatchLogsService, batch *cloudWatchBatch) {
+	if len(batch.events) == 0 || batch.stopped {
+		batch.events, batch.size = nil, 0
+		return
+	}
+	for attempt := 1; ; attempt++ {
+		token, err := service.PutLogEvents(log, batch.events, c.LogGroupName, c.LogStreamName, batch.sequenceToken)
+		if err == nil {
+			batch.sequenceToken = token
+			break
+		}
+		if sdkutil.GetAwsErrorCode(err) == accessDeniedException {
+			log.Errorf("The instance isn't allowed to send the output to %v, the output isn't sent to CloudWatch Logs: %v", c.LogGroupName, err)
+			batch.stopped = true
+			break
+		}
+		if attempt == putAttempts {
+			log.Errorf("Failed to send %v lines of output to the log stream %v: %v", len(batch.events), c.LogStreamName, err)
+			break
+		}
+		time.Sleep(putRetryDelay * time.Duration(attempt))
+	}
+	batch.events, batch.size = nil, 0
+}
+
+// scanEvents splits the output in lines, and the lines longer than an event in several events
+func scanEvents(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 && i < maxEventSize {
+		return i + 1, bytes.TrimSuffix(data[:i], []byte("\r")), nil
+	}
+	if len(data) >= maxEventSize {
+		return maxEventSize, data[:maxEventSize], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// readToCloudWatch runs the module on the output and returns the messages of each PutLogEvents
+func readToCloudWatch(t *testing.T, service *cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock, output string) {
+	origService, origDelay := newCloudWatchLogsService, putRetryDelay
+	newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService { return service }
+	putRetryDelay = time.Millisecond
+	defer func() { newCloudWatchLogsService, putRetryDelay = origService, origDelay }()
+
+	r, w := io.Pipe()
+	done := make(chan bool)
+	go func() {
+		CloudWatchLogs{LogGroupName: "group", LogStreamName: "stream", FlushInterval: time.Hour}.Read(logger, r)
+		close(done)
+	}()
+	w.Write([]byte(output))
+	w.Close()
+	<-done
+}
+
+func messages(events []*cloudwatchlogs.InputLogEvent) []string {
+	result := []string{}
+	for _, event := range events {
+		result = append(result, aws.StringValue(event.Message))
+	}
+	return result
+}
+
+func TestCloudWatchLogsSendsTheLines(t *testing.T) {
+	service := cloudwatchlogspublisher_mock.NewServiceMockDefault()
+	service.On("CreateLogGroup", mock.Anything, "group").Return(nil)
+	service.On("CreateLogStream", mock.Anything, "group", "stream").Return(nil)
+	var sent []string
+	service.On("PutLogEvents", mock.Anything, mock.Anything, "group", "stream", mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, messages(args.Get(1).([]*cloudwatchlogs.InputLogEvent))...)
+	}).Return(aws.String("token"), nil)
+
+	readToCloudWatch(t, service, "first line\r\n\nlast line")
+
+	assert.Equal(t, []string{"first line", " ", "last line"}, sent)
+	service.AssertNumberOfCalls(t, "PutLogEvents", 1)
+}
+
+func TestCloudWatchLogsWithoutLogGroupPermission(t *testing.T) {
+	// the log group created by the administrators
+	service := cloudwatchlogspublisher_mock.NewServiceMockDefault()
+	service.On("CreateLogGroup", mock.Anything, "group").Return(awserr.New(accessDeniedException, "denied", nil))
+	service.On("CreateLogStream", mock.Anything, "group", "stream").Return(nil)
+	service.On("PutLogEvents", mock.Anything, mock.Anything, "group", "stream", mock.Anything).Return(aws.String("token"), nil)
+
+	readToCloudWatch(t, service, "output")
+
+	service.AssertNumberOfCalls(t, "PutLogEvents", 1)
+}
+
+func TestCloudWatchLogsRetriesThenDrops(t *testing.T) {
+	service := cloudwatchlogspublisher_mock.NewServiceMockDefault()
+	service.On("CreateLogGroup", mock.Anything, "group").Return(nil)
+	service.On("CreateLogStream", mock.Anything, "group", "stream").Return(nil)
+	service.On("PutLogEvents", mock.Anything, mock.Anything, "group", "stream", mock.Anything).Return(nil, awserr.New("ServiceUnavailableException", "unavailable", nil))
+
+	readToCloudWatch(t, service, "output")
+
+	service.AssertNumberOfCalls(t, "PutLogEvents", putAttempts)
+}
+
+func TestCloudWatchLogsStopsWhenAccessIsDenied(t *testing.T) {
+	service := cloudwatchlogspublisher_mock.NewServiceMockDefault()
+	service.On("CreateLogGroup", mock.Anything, "group").Return(nil)
+	service.On("CreateLogStream", mock.Anything, "group", "stream").Return(nil)
+	service.On("PutLogEvents", mock.Anything, mock.Anything, "group", "stream", mock.Anything).Return(nil, awserr.New(accessDeniedException, "denied", nil))
+
+	// more events than a batch, so that the output is sent twice
+	readToCloudWatch(t, service, strings.Repeat("line\n", maxBatchEvents+1))
+
+	service.AssertNumberOfCalls(t, "PutLogEvents", 1)
+}
+
+func TestCloudWatchLogsWithoutLogStream(t *testing.T) {
+	service := cloudwatchlogspublisher_mock.NewServiceMockDefault()
+	service.On("CreateLogGroup", mock.Anything, "group").Return(nil)
+	service.On("CreateLogStream", mock.Anything, "group", "stream").Return(awserr.New("ResourceNotFoundException", "not found", nil))
+
+	readToCloudWatch(t, service, "output")
+
+	service.AssertNotCalled(t, "PutLogEvents", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCloudWatchLogsDropsTheLinesItCantKeepUpWith(t *testing.T) {
+	service := cloudwatchlogspublisher_mock.NewServiceMockDefault()
+	service.On("CreateLogGroup", mock.Anything, "group").Return(nil)
+	service.On("CreateLogStream", mock.Anything, "group", "stream").Return(nil)
+	release := make(chan struct{})
+	var sent []string
+	service.On("PutLogEvents", mock.Anything, mock.Anything, "group", "stream", mock.Anything).Run(func(args mock.Arguments) {
+		<-release
+		sent = append(sent, messages(args.Get(1).([]*cloudwatchlogs.InputLogEvent))...)
+	}).Return(aws.String("token"), nil)
+	origService := newCloudWatchLogsService
+	newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService { return service }
+	defer func() { newCloudWatchLogsService = origService }()
+
+	droppedLog := log.NewMockLog()
+	droppedLog.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+
+	r, w := io.Pipe()
+	done := make(chan bool)
+	go func() {
+		CloudWatchLogs{LogGroupName: "group", LogStreamName: "stream", FlushInterval: time.Hour}.Read(droppedLog, r)
+		close(done)
+	}()
+
+	// the output is written while the first batch is sent, the lines beyond the buffer are dropped
+	_, err := w.Write([]byte(strings.Repeat("line\n", maxBatchEvents+1+maxBufferedEvents+10)))
+	assert.NoError(t, err)
+	w.Close()
+	close(release)
+	<-done
+
+	assert.True(t, len(sent) < maxBatchEvents+1+maxBufferedEvents+10)
+	assert.Regexp(t, `^\[\d+ lines of output dropped, CloudWatch Logs didn't keep up\]$`, sent[len(sent)-1])
+}
+
+func TestScanEventsSplitsLongLines(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader(strings.Repeat("a", maxEventSize+10) + "\nb"))
+	scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+	scanner.Split(scanEvents)
+
+	var lengths []int
+	for scanner.Scan() {
+		lengths = append(lengths, len(scanner.Text()))
+	}
+	assert.NoError(t, scanner.Err())
+	assert.Equal(t, []int{maxEventSize, 10, 1}, lengths)
+}
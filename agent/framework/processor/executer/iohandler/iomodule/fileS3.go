@@ -25,12 +25,44 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 )
 
+// uploadCheckSize is how much output is written between the checks for a new part to upload
+var uploadCheckSize = 1024 * 1024
+
+// fileUploader uploads an output file to S3 as it's written
+type fileUploader interface {
+	UploadParts(log log.T) error
+	Complete(log log.T) error
+}
+
+// newFileUpload returns the upload of an output file, which continues the upload of a previous worker if any
+var newFileUpload = func(log log.T, bucketName, s3Key, filePath string) fileUploader {
+	return s3util.NewAmazonS3Util(log, bucketName).NewFileUpload(log, bucketName, s3Key, filePath)
+}
+
+// uploadParts uploads the parts of the output file each time written is signaled, until it's closed, then closes
+// uploaded. The parts not uploaded as the file is written, e.g. after a failure, are uploaded once it's complete.
+func uploadParts(log log.T, upload fileUploader, written <-chan struct{}, uploaded chan<- struct{}) {
+	defer close(uploaded)
+	streaming := true
+	for range written {
+		if !streaming {
+			continue
+		}
+		if err := upload.UploadParts(log); err != nil {
+			log.Warnf("Failed to upload the output to s3 as it's written, uploading it once complete: %v", err)
+			streaming = false
+		}
+	}
+}
+
 // File handles writing to an output file and upload to s3
 type File struct {
 	FileName               string
 	OrchestrationDirectory string
 	OutputS3BucketName     string
 	OutputS3KeyPrefix      string
+	// Rotation rotates the output file once it reaches a maximum size, the rotation is disabled by default
+	Rotation log.RotationConfig
 }
 
 // Read reads from the stream and writes to the output file and s3.
@@ -46,7 +78,7 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 	}
 
 	filePath := filepath.Join(file.OrchestrationDirectory, file.FileName)
-	fileWriter, err := os.OpenFile(filePath, appconfig.FileFlagsCreateOrAppend, appconfig.ReadWriteAccess)
+	fileWriter, rotatingWriter, err := file.open(filePath)
 
 	if err != nil {
 		log.Errorf("Failed to open the file at %v: %v", filePath, err)
@@ -55,6 +87,18 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 
 	defer fileWriter.Close()
 
+	// the output is uploaded in parts as it's written, unless it's rotated, by uploadParts so that the writes of
+	// the plugin never wait for S3: the parts are read back from the file, written is signaled as it grows
+	var upload fileUploader
+	var written chan struct{}
+	var uploaded chan struct{}
+	if file.OutputS3BucketName != "" && rotatingWriter == nil {
+		upload = newFileUpload(log, file.OutputS3BucketName, fileutil.BuildS3Path(file.OutputS3KeyPrefix, file.FileName), filePath)
+		written, uploaded = make(chan struct{}, 1), make(chan struct{})
+		go uploadParts(log, upload, written, uploaded)
+	}
+	unsignaled := 0
+
 	// Read byte by byte and write to file
 	scanner := bufio.NewScanner(reader)
 	scanner.Split(bufio.ScanBytes)
@@ -62,6 +106,18 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 		if _, err = fileWriter.Write([]byte(scanner.Text())); err != nil {
 			log.Errorf("Failed to write the message to stdout: %v", err)
 		}
+		if unsignaled++; written != nil && unsignaled >= uploadCheckSize {
+			unsignaled = 0
+			select {
+			case written <- struct{}{}:
+			default:
+				// the uploader hasn't caught up yet, it checks the file again once it's done
+			}
+		}
+	}
+	if written != nil {
+		close(written)
+		<-uploaded
 	}
 
 	// Check if scanner exited because of an error
@@ -69,17 +125,45 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 		log.Error("Error with the scanner while reading the stream")
 	}
 
-	fi, err := fileWriter.Stat()
+	fi, err := os.Stat(filePath)
 	if err != nil {
 		log.Errorf("Failed to get file size: %v", err)
 		return
 	}
 
+	if upload != nil {
+		if fi.Size() > 0 {
+			if err := upload.Complete(log); err != nil {
+				log.Errorf("Failed to upload the output to s3: %v", err)
+			}
+		}
+		return
+	}
+
 	// Upload output file to S3
 	if file.OutputS3BucketName != "" && fi.Size() > 0 {
 		s3Key := fileutil.BuildS3Path(file.OutputS3KeyPrefix, file.FileName)
 		if err := s3util.NewAmazonS3Util(log, file.OutputS3BucketName).S3Upload(log, file.OutputS3BucketName, s3Key, filePath); err != nil {
 			log.Errorf("Failed to upload the output to s3: %v", err)
 		}
+		// the rotated output is uploaded next to the current one
+		if rotatingWriter != nil {
+			for _, rolledPath := range rotatingWriter.RolledFiles() {
+				s3Key := fileutil.BuildS3Path(file.OutputS3KeyPrefix, filepath.Base(rolledPath))
+				if err := s3util.NewAmazonS3Util(log, file.OutputS3BucketName).S3Upload(log, file.OutputS3BucketName, s3Key, rolledPath); err != nil {
+					log.Errorf("Failed to upload the rotated output to s3: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// open opens the output file for appending, through a rotating writer if the rotation is enabled.
+func (file File) open(filePath string) (io.WriteCloser, *log.RotatingFileWriter, error) {
+	if file.Rotation.MaxSize <= 0 {
+		fileWriter, err := os.OpenFile(filePath, appconfig.FileFlagsCreateOrAppend, appconfig.ReadWriteAccess)
+		return fileWriter, nil, err
 	}
+	rotatingWriter, err := log.NewRotatingFileWriter(filePath, file.Rotation)
+	return rotatingWriter, rotatingWriter, err
 }
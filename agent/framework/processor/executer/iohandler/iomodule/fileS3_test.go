@@ -0,0 +1,82 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowUpload is an upload whose parts take until release is closed to upload
+type slowUpload struct {
+	release   chan struct{}
+	parts     int
+	completed bool
+}
+
+func (u *slowUpload) UploadParts(log log.T) error {
+	<-u.release
+	u.parts++
+	return nil
+}
+
+func (u *slowUpload) Complete(log log.T) error {
+	u.completed = true
+	return nil
+}
+
+func TestFileReadDoesntWaitForTheUpload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileS3")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	origCheckSize, origNewFileUpload := uploadCheckSize, newFileUpload
+	defer func() { uploadCheckSize, newFileUpload = origCheckSize, origNewFileUpload }()
+	uploadCheckSize = 4
+	upload := &slowUpload{release: make(chan struct{})}
+	newFileUpload = func(log log.T, bucketName, s3Key, filePath string) fileUploader { return upload }
+
+	reader, writer := io.Pipe()
+	read := make(chan struct{})
+	go func() {
+		defer close(read)
+		File{FileName: "stdout", OrchestrationDirectory: dir, OutputS3BucketName: "bucket"}.Read(logger, reader)
+	}()
+
+	// the output is written while a part is uploading
+	_, err = io.WriteString(writer, strings.Repeat("output\n", 10))
+	assert.NoError(t, err)
+	writer.Close()
+	var content []byte
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if content, _ = ioutil.ReadFile(filepath.Join(dir, "stdout")); len(content) == 70 {
+			break
+		}
+	}
+	assert.Equal(t, strings.Repeat("output\n", 10), string(content))
+	assert.Equal(t, 0, upload.parts, "the part is still uploading")
+
+	close(upload.release)
+	<-read
+	assert.True(t, upload.parts >= 1)
+	assert.True(t, upload.completed)
+}
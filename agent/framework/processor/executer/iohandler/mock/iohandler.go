@@ -140,6 +140,18 @@ func (m *MockIOHandler) GetIOConfig() contracts.IOConfiguration {
 	return args.Get(0).(contracts.IOConfiguration)
 }
 
+// GetAttachments is a mocked method that just returns what mock tells it to.
+func (m *MockIOHandler) GetAttachments() []contracts.Attachment {
+	args := m.Called()
+	return args.Get(0).([]contracts.Attachment)
+}
+
+// AddAttachment is a mocked method that just returns what mock tells it to.
+func (m *MockIOHandler) AddAttachment(log log.T, filePath string) error {
+	args := m.Called(log, filePath)
+	return args.Error(0)
+}
+
 // SetStatus is a mocked method that acknowledges that the function has been called.
 func (m *MockIOHandler) SetStatus(status contracts.ResultStatus) {
 	m.Called(status)
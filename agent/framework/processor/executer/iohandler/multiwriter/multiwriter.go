@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"io"
 	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/log/redact"
 )
 
 // DocumentIOMultiWriter is a multi-writer with support for close channel.
@@ -35,12 +37,14 @@ type DocumentIOMultiWriter interface {
 type DefaultDocumentIOMultiWriter struct {
 	writers []*io.PipeWriter
 	wg      *sync.WaitGroup
+	// redaction removes the secrets of the process, e.g. decrypted SecureString parameters, from the output
+	redaction redact.Stream
 }
 
 // NewDocumentIOMultiWriter creates a new document multi-writer
 func NewDocumentIOMultiWriter() (b *DefaultDocumentIOMultiWriter) {
 	var w []*io.PipeWriter
-	b = &DefaultDocumentIOMultiWriter{w, new(sync.WaitGroup)}
+	b = &DefaultDocumentIOMultiWriter{writers: w, wg: new(sync.WaitGroup)}
 	return
 }
 
@@ -55,42 +59,41 @@ func (b *DefaultDocumentIOMultiWriter) GetWaitGroup() *sync.WaitGroup {
 	return b.wg
 }
 
-// Write is responsible for writing a byte to all the attached pipes.
+// Write is responsible for writing a byte to all the attached pipes, with the secrets of the process redacted.
 func (b *DefaultDocumentIOMultiWriter) Write(p []byte) (n int, err error) {
 	if len(b.writers) == 0 {
 		return 0, fmt.Errorf("No writers present.")
 	}
 
+	b.writeAll(b.redaction.Redact(p))
+	return len(p), nil
+}
+
+// writeAll writes the redacted output to all the attached pipes.
+func (b *DefaultDocumentIOMultiWriter) writeAll(p []byte) {
+	if len(p) == 0 {
+		return
+	}
 	for i := 0; i < len(b.writers); i++ {
-		n, err = b.writers[i].Write(p)
+		_, err := b.writers[i].Write(p)
 		// TODO: Handler other error types and close the writers after a fixed number of retries
 		if err == io.ErrClosedPipe {
 			// remove the writer as the reader is closed
 			b.writers = append(b.writers[:i], b.writers[i+1:]...)
 			i--
 		}
-		if n != len(p) {
-			err = io.ErrShortWrite
-		}
 	}
-	return len(p), nil
 }
 
 // WriteString is responsible for writing a string to all the attached pipes.
 func (b *DefaultDocumentIOMultiWriter) WriteString(message string) (n int, err error) {
-	if len(b.writers) == 0 {
-		return 0, fmt.Errorf("No writers present.")
-	}
-
-	for _, w := range b.writers {
-		p := []byte(message)
-		n, err = w.Write(p)
-	}
-	return len(message), nil
+	return b.Write([]byte(message))
 }
 
 // Close waits for all the writers to be closed.
 func (b *DefaultDocumentIOMultiWriter) Close() (err error) {
+	// the end of the output held back by the redaction
+	b.writeAll(b.redaction.Flush())
 	for i := 0; i < len(b.writers); i++ {
 		err = b.writers[i].Close()
 	}
@@ -22,6 +22,7 @@ import (
 
 	"sync"
 
+	"github.com/aws/amazon-ssm-agent/agent/log/redact"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -153,3 +154,18 @@ func TestCloseWriter(t *testing.T) {
 	assert.Nil(t, err)
 
 }
+
+// TestWriteRedactsSecrets checks the secrets of the process are redacted, even when they're written byte by byte.
+func TestWriteRedactsSecrets(t *testing.T) {
+	redact.AddSecret("multiwriter-secret")
+	source := "the secret is multiwriter-secret, or multiwriter"
+	mw := NewDocumentIOMultiWriter()
+	r, w := io.Pipe()
+	mw.AddWriter(w)
+	go testReadStream(t, r, "the secret is ****, or multiwriter", mw.wg)
+
+	for i := 0; i < len(source); i++ {
+		mw.Write([]byte{source[i]})
+	}
+	mw.Close()
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iohandler
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// partialOutputFile is an output file of a running step, it's reported again when its size changes
+type partialOutputFile struct {
+	fileName string
+	limit    int
+	size     int64
+}
+
+// startPartialOutput reports the output the step has written so far every PartialOutputInterval of the IO
+// configuration, once it changed, and uploads the output files to S3 under the keys of the final output, which the
+// multipart upload of the output as it's written replaces once the step ends.
+func (out *DefaultIOHandler) startPartialOutput(log log.T, outputDir string, pluginConfig PluginConfig) {
+	report, interval := out.ioConfig.PartialOutput, out.ioConfig.PartialOutputInterval
+	if report == nil || interval <= 0 {
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	out.stopPartial, out.partialDone = stop, done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		stdout := &partialOutputFile{fileName: pluginConfig.StdoutFileName, limit: pluginConfig.MaxStdoutLength}
+		stderr := &partialOutputFile{fileName: pluginConfig.StderrFileName, limit: pluginConfig.MaxStderrLength}
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			stdoutChanged := out.uploadPartialOutput(log, outputDir, stdout)
+			stderrChanged := out.uploadPartialOutput(log, outputDir, stderr)
+			if stdoutChanged || stderrChanged {
+				report(readPartialOutput(outputDir, stdout), readPartialOutput(outputDir, stderr))
+			}
+		}
+	}()
+}
+
+// stopPartialOutput stops reporting the output of the step, before its final output is uploaded
+func (out *DefaultIOHandler) stopPartialOutput() {
+	if out.stopPartial == nil {
+		return
+	}
+	close(out.stopPartial)
+	<-out.partialDone
+	out.stopPartial = nil
+}
+
+// uploadPartialOutput uploads the output file to the output bucket, if any, when its size changed since the
+// last upload. It returns whether it changed.
+func (out *DefaultIOHandler) uploadPartialOutput(log log.T, outputDir string, file *partialOutputFile) bool {
+	filePath := filepath.Join(outputDir, file.fileName)
+	info, err := os.Stat(filePath)
+	if err != nil || info.Size() == file.size {
+		return false
+	}
+	file.size = info.Size()
+	if out.ioConfig.OutputS3BucketName != "" {
+		s3Key := fileutil.BuildS3Path(out.s3KeyPrefix, file.fileName)
+		if err = uploadToS3(log, out.ioConfig.OutputS3BucketName, s3Key, filePath); err != nil {
+			log.Errorf("Failed to upload the partial output to s3: %v", err)
+		}
+	}
+	return true
+}
+
+// readPartialOutput returns the beginning of the output file, up to the limit of the console output
+func readPartialOutput(outputDir string, file *partialOutputFile) string {
+	f, err := os.Open(filepath.Join(outputDir, file.fileName))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	content, _ := ioutil.ReadAll(io.LimitReader(f, int64(file.limit)))
+	return string(content)
+}
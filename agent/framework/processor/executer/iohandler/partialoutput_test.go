@@ -0,0 +1,68 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iohandler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartialOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "partial")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "stdout"), []byte("installing"), 0600))
+
+	uploaded := make(chan string, 10)
+	uploadToS3 = func(log log.T, bucketName, s3Key, filePath string) error {
+		uploaded <- s3Key
+		return nil
+	}
+	reports := make(chan string, 10)
+	output := NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{
+		OutputS3BucketName:    "bucket",
+		PartialOutputInterval: 10 * time.Millisecond,
+		PartialOutput: func(stdout, stderr string) {
+			reports <- stdout
+		},
+	})
+	output.s3KeyPrefix = "prefix/aws:runShellScript"
+	config := DefaultOutputConfig()
+	config.MaxStdoutLength = 4
+	output.startPartialOutput(log.NewMockLog(), dir, config)
+
+	// the output is cut at the limit of the console output
+	assert.Equal(t, "inst", <-reports)
+	assert.Equal(t, "prefix/aws:runShellScript/stdout", <-uploaded)
+
+	// the unchanged output isn't reported again
+	time.Sleep(50 * time.Millisecond)
+	output.stopPartialOutput()
+	assert.Len(t, reports, 0)
+	assert.Len(t, uploaded, 0)
+}
+
+func TestPartialOutputDisabled(t *testing.T) {
+	output := NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	output.startPartialOutput(log.NewMockLog(), "", DefaultOutputConfig())
+	assert.Nil(t, output.stopPartial)
+	output.stopPartialOutput()
+}
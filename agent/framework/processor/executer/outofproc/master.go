@@ -8,12 +8,14 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/etw"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/basicexecuter"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/messaging"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
@@ -25,13 +27,20 @@ const (
 	defaultZombieProcessTimeout = 3 * time.Second
 	//command maximum timeout
 	defaultOrphanProcessTimeout = 172800 * time.Second
+	//number of times a document resumes in a new worker after its worker exited
+	maxWorkerResumes = 1
 )
 
 type OutOfProcExecuter struct {
 	basicexecuter.BasicExecuter
 	docState   *contracts.DocumentState
+	docStore   executer.DocumentStore
 	ctx        context.T
 	cancelFlag task.CancelFlag
+	// workerExited is set when the worker of the document exited before completing it, e.g. while the agent
+	// restarted, the document resumes from its checkpoint in a new worker once the messages the worker left are read
+	workerExited bool
+	resumes      int
 }
 
 var channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
@@ -48,6 +57,9 @@ var processFinder = func(log log.T, procinfo contracts.OSProcInfo) bool {
 	return proc.IsProcessExists(log, procinfo.Pid, procinfo.StartTime)
 }
 
+// completePendingUploads completes the uploads of the output the worker left when it exited
+var completePendingUploads = s3util.CompletePendingUploads
+
 var processCreator = func(name string, argv []string) (proc.OSProcess, error) {
 	return proc.StartProcess(name, argv)
 }
@@ -65,6 +77,7 @@ func (e *OutOfProcExecuter) Run(
 	docStore executer.DocumentStore) chan contracts.DocumentResult {
 	docState := docStore.Load()
 	e.docState = &docState
+	e.docStore = docStore
 	e.cancelFlag = cancelFlag
 	documentID := docState.DocumentInformation.DocumentID
 
@@ -106,16 +119,30 @@ func (e *OutOfProcExecuter) Run(
 //Executer however does hold a timer to the worker to forcefully termniate both of them
 func (e *OutOfProcExecuter) messaging(log log.T, ipc channel.Channel, resChan chan contracts.DocumentResult, cancelFlag task.CancelFlag, stopTimer chan bool) {
 
-	//handoff reply functionalities to data backend.
-	backend := messaging.NewExecuterBackend(resChan, e.docState, cancelFlag)
+	//handoff reply functionalities to data backend, it checkpoints the document state after each step
+	backend := messaging.NewExecuterBackend(resChan, e.docState, cancelFlag, e.docStore)
+	span := etw.StartSpan(etw.StageWorkerMessaging, e.docState.DocumentInformation.DocumentID)
 	//handoff the data backend to messaging worker
-	if err := messaging.Messaging(log, ipc, backend, stopTimer); err != nil {
+	err := messaging.Messaging(log, ipc, backend, stopTimer)
+	if err == nil {
+		span.End(string(e.docState.DocumentInformation.DocumentStatus))
+	} else {
+		span.Fail(err)
 		//the messaging worker encountered error, either ipc run into error or data backend throws error
 		log.Errorf("messaging worker encountered error: %v", err)
 		if e.docState.DocumentInformation.DocumentStatus == contracts.ResultStatusInProgress ||
 			e.docState.DocumentInformation.DocumentStatus == "" ||
 			e.docState.DocumentInformation.DocumentStatus == contracts.ResultStatusNotStarted {
+			if e.workerExited && e.resumes < maxWorkerResumes && !cancelFlag.Canceled() && !cancelFlag.ShutDown() {
+				ipc.Destroy()
+				e.resume(log, resChan, cancelFlag)
+				return
+			}
 			e.docState.DocumentInformation.DocumentStatus = contracts.ResultStatusFailed
+			if e.docState.IOConfig.OutputS3BucketName != "" {
+				// the output the steps wrote before the worker exited is uploaded, rather than lost with the upload
+				completePendingUploads(log, e.docState.IOConfig.OrchestrationDirectory)
+			}
 			log.Info("document failed half way, sending fail message...")
 			resChan <- e.generateUnexpectedFailResult(fmt.Sprintf("document process failed unexpectedly: %s , check [ssm-document-worker] log for crash reason", err))
 		}
@@ -124,6 +151,22 @@ func (e *OutOfProcExecuter) messaging(log log.T, ipc channel.Channel, resChan ch
 	}
 }
 
+// resume runs the document in a new worker, which skips the steps completed before the previous worker exited,
+// e.g. when the instance restarted in the middle of the document.
+func (e *OutOfProcExecuter) resume(log log.T, resChan chan contracts.DocumentResult, cancelFlag task.CancelFlag) {
+	log.Info("the document worker exited before the document completed, resuming it from its checkpoint in a new worker")
+	e.workerExited = false
+	e.resumes++
+	stopTimer := make(chan bool, 1)
+	ipc, err := e.initialize(stopTimer)
+	if err != nil {
+		e.docState.DocumentInformation.DocumentStatus = contracts.ResultStatusFailed
+		resChan <- e.generateUnexpectedFailResult(fmt.Sprintf("failed to resume the document: %v", err))
+		return
+	}
+	e.messaging(log, ipc, resChan, cancelFlag, stopTimer)
+}
+
 func (e *OutOfProcExecuter) generateUnexpectedFailResult(errMsg string) contracts.DocumentResult {
 	var docResult contracts.DocumentResult
 	docResult.MessageID = e.docState.DocumentInformation.MessageID
@@ -162,12 +205,13 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 		} else {
 			log.Infof("process: %v not found, treat as exited", procInfo.Pid)
 			stopTime = defaultZombieProcessTimeout
+			e.workerExited = true
 		}
 		go timeout(stopTimer, stopTime, e.cancelFlag)
 	} else {
 		log.Debug("channel not found, starting a new process...")
 		var process proc.OSProcess
-		if process, err = processCreator(appconfig.DefaultDocumentWorker, proc.FormArgv(documentID)); err != nil {
+		if process, err = processCreator(appconfig.DefaultDocumentWorker, proc.FormArgv(documentID, e.docState.DocumentInformation.InstanceID)); err != nil {
 			log.Errorf("start process: %v error: %v", appconfig.DefaultDocumentWorker, err)
 			//make sure close the channel
 			ipc.Destroy()
@@ -203,6 +247,7 @@ func (e *OutOfProcExecuter) WaitForProcess(stopTimer chan bool, process proc.OSP
 	//}()
 	if err := process.Wait(); err != nil {
 		log.Errorf("process: %v exited unsuccessfully, error message: %v", process.Pid(), err)
+		e.workerExited = true
 	} else {
 		log.Debugf("process: %v exited successfully, trying to stop messaging worker", process.Pid())
 	}
@@ -108,7 +108,7 @@ func TestInitializeNewProcess(t *testing.T) {
 	}
 	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
 		assert.Equal(t, name, appconfig.DefaultDocumentWorker)
-		assert.Equal(t, argv, []string{testDocumentID})
+		assert.Equal(t, argv, []string{testDocumentID, testInstanceID})
 		return testCase.processMock, nil
 	}
 	exe := &OutOfProcExecuter{
@@ -144,7 +144,7 @@ func TestCreateProcessFailed(t *testing.T) {
 	var err = errors.New("failed to create process")
 	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
 		assert.Equal(t, name, appconfig.DefaultDocumentWorker)
-		assert.Equal(t, argv, []string{testDocumentID})
+		assert.Equal(t, argv, []string{testDocumentID, testInstanceID})
 		return nil, err
 	}
 	exe := &OutOfProcExecuter{
@@ -169,7 +169,7 @@ func TestInitializeProcessUnexpectedExited(t *testing.T) {
 	}
 	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
 		assert.Equal(t, name, appconfig.DefaultDocumentWorker)
-		assert.Equal(t, argv, []string{testDocumentID})
+		assert.Equal(t, argv, []string{testDocumentID, testInstanceID})
 		return testCase.processMock, nil
 	}
 	cancel := task.NewChanneledCancelFlag()
@@ -190,6 +190,8 @@ func TestInitializeProcessUnexpectedExited(t *testing.T) {
 	<-stopTimer
 	//assert pid is saved
 	assert.Equal(t, testPid, exe.docState.DocumentInformation.ProcInfo.Pid)
+	//the document resumes in a new worker
+	assert.True(t, exe.workerExited)
 	//set job complete and kill is not called
 	cancel.Set(task.Completed)
 	testCase.processMock.AssertExpectations(t)
@@ -256,6 +258,55 @@ func TestInitializeConnectOldOrphan(t *testing.T) {
 	channelMock.AssertExpectations(t)
 }
 
+func TestInitializeExitedWorkerResumes(t *testing.T) {
+	testCase := CreateTestCase()
+	channelMock := new(channelmock.MockedChannel)
+	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+		return channelMock, nil, true
+	}
+	//the worker exited while the agent was down, e.g. the instance restarted
+	processFinder = func(log log.T, procinfo contracts.OSProcInfo) bool {
+		return false
+	}
+	exe := &OutOfProcExecuter{
+		ctx:        testCase.context,
+		docState:   &testCase.docState,
+		cancelFlag: task.NewChanneledCancelFlag(),
+	}
+	stopTimer := make(chan bool)
+	_, err := exe.initialize(stopTimer)
+	assert.NoError(t, err)
+	//the messages left by the worker are read before the document resumes in a new worker
+	<-stopTimer
+	assert.True(t, exe.workerExited)
+}
+
+func TestResumeFailsWhenWorkerCannotStart(t *testing.T) {
+	testCase := CreateTestCase()
+	channelMock := new(channelmock.MockedChannel)
+	channelMock.On("Destroy").Return(nil)
+	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+		return channelMock, nil, false
+	}
+	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+		return nil, errors.New("failed to create process")
+	}
+	exe := &OutOfProcExecuter{
+		ctx:          testCase.context,
+		docState:     &testCase.docState,
+		cancelFlag:   task.NewChanneledCancelFlag(),
+		workerExited: true,
+	}
+	resChan := make(chan contracts.DocumentResult, 1)
+	exe.resume(logger, resChan, exe.cancelFlag)
+	res := <-resChan
+	assert.False(t, exe.workerExited)
+	assert.Equal(t, 1, exe.resumes)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Equal(t, contracts.ResultStatusFailed, exe.docState.DocumentInformation.DocumentStatus)
+	channelMock.AssertExpectations(t)
+}
+
 //TODO add Run() unittest
 
 //this is needed, since after marshal-unmarshalling thru the data channel, the pointer value changed
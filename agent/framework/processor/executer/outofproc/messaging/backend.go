@@ -7,6 +7,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
@@ -36,19 +37,22 @@ type WorkerBackend struct {
 //Executer backend formulate the run request to the worker, and collect back the responses from worker
 type ExecuterBackend struct {
 	//the shared state object that Executer hand off to data backend
-	docState   *contracts.DocumentState
+	docState *contracts.DocumentState
+	//checkpoint persists the state after each step, the steps completed don't run again when the document resumes
+	checkpoint executer.DocumentStore
 	input      chan string
 	cancelFlag task.CancelFlag
 	output     chan contracts.DocumentResult
 	stopChan   chan int
 }
 
-func NewExecuterBackend(output chan contracts.DocumentResult, docState *contracts.DocumentState, cancelFlag task.CancelFlag) *ExecuterBackend {
+func NewExecuterBackend(output chan contracts.DocumentResult, docState *contracts.DocumentState, cancelFlag task.CancelFlag, checkpoint executer.DocumentStore) *ExecuterBackend {
 	stopChan := make(chan int, defaultBackendChannelSize)
 	inputChan := make(chan string, defaultBackendChannelSize)
 	p := ExecuterBackend{
 		output:     output,
 		docState:   docState,
+		checkpoint: checkpoint,
 		input:      inputChan,
 		cancelFlag: cancelFlag,
 		stopChan:   stopChan,
@@ -62,7 +66,8 @@ func (p *ExecuterBackend) start(docState contracts.DocumentState) {
 	p.input <- startDatagram
 	p.cancelFlag.Wait()
 	if p.cancelFlag.Canceled() {
-		cancelDatagram, _ := CreateDatagram(MessageTypeCancel, "cancel")
+		// the worker sets its own cancel flag with the reason received from the master
+		cancelDatagram, _ := CreateDatagram(MessageTypeCancel, string(p.cancelFlag.Reason()))
 		p.input <- cancelDatagram
 	} else if p.cancelFlag.ShutDown() {
 		p.stopChan <- stopTypeShutdown
@@ -88,6 +93,9 @@ func (p *ExecuterBackend) Process(datagram string) error {
 		var docResult contracts.DocumentResult
 		jsonutil.Unmarshal(content, &docResult)
 		p.formatDocResult(&docResult)
+		if t == MessageTypeReply && p.checkpoint != nil {
+			p.checkpoint.Save(*p.docState)
+		}
 		p.output <- docResult
 		if t == MessageTypeComplete {
 			//get document result, force termniate messaging worker
@@ -136,13 +144,19 @@ func (p *WorkerBackend) Process(datagram string) error {
 		}
 		p.once.Do(func() {
 			statusChan := make(chan contracts.PluginResult)
-			go p.runner(p.ctx, docState, statusChan, p.cancelFlag)
+			go p.runner(context.WithTrace(p.ctx, docState.DocumentInformation.TraceParent), docState, statusChan, p.cancelFlag)
 			go p.pluginListener(statusChan)
 		})
 
 	case MessageTypeCancel:
 		log.Info("requested cancel the command, setting cancel flag...")
-		p.cancelFlag.Set(task.Canceled)
+		var reason string
+		// older masters send a plain "cancel" instead of the reason
+		if err := jsonutil.Unmarshal(content, &reason); err != nil || reason == "cancel" || reason == "" {
+			p.cancelFlag.Set(task.Canceled)
+		} else {
+			p.cancelFlag.SetWithReason(task.Canceled, task.CancelReason(reason))
+		}
 	default:
 		//TODO add extra logic to check whether plugin has started, if not, stop IPC, or add timeout
 		return errors.New("unsupported message type")
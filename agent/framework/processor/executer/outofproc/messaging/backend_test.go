@@ -5,11 +5,13 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	executermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/mock"
 
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 var contextMock = context.NewMockDefault()
@@ -168,6 +170,35 @@ func TestExecuterBackend_ProcessV1(t *testing.T) {
 	cancel.AssertExpectations(t)
 }
 
+func TestExecuterBackend_ProcessCheckpointsReplies(t *testing.T) {
+	testCase := CreateTestCase()
+	outputChan := make(chan contracts.DocumentResult, 10)
+	stopChan := make(chan int, 1)
+	docStore := new(executermocks.MockDocumentStore)
+	var checkpoint contracts.DocumentState
+	docStore.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+		checkpoint = args.Get(0).(contracts.DocumentState)
+	}).Return()
+	backend := ExecuterBackend{
+		cancelFlag: task.NewMockDefault(),
+		output:     outputChan,
+		stopChan:   stopChan,
+		docState:   &testCase.docState,
+		checkpoint: docStore,
+	}
+	assert.NoError(t, backend.Process(testPluginReplyRawJSON))
+	<-outputChan
+	// the completed step is persisted before the document completes
+	docStore.AssertNumberOfCalls(t, "Save", 1)
+	assert.Equal(t, contracts.ResultStatusSuccess, checkpoint.InstancePluginsInformation[0].Result.Status)
+	assert.Equal(t, contracts.ResultStatus(""), checkpoint.InstancePluginsInformation[1].Result.Status)
+
+	// the master persists the completed document itself
+	assert.NoError(t, backend.Process(testDocumentCompleteRawJSON))
+	<-outputChan
+	docStore.AssertNumberOfCalls(t, "Save", 1)
+}
+
 //test the datagram mashalling v1
 func TestExecuterBackend_ProcessUnsupportedVersion(t *testing.T) {
 	testCase := CreateTestCase()
@@ -226,6 +257,19 @@ func TestWorkerBackend_ProcessCancelV1(t *testing.T) {
 
 }
 
+func TestWorkerBackend_ProcessCancelWithReason(t *testing.T) {
+	cancelFlag := new(task.MockCancelFlag)
+	cancelFlag.On("SetWithReason", task.Canceled, task.CancelReasonAgentShutdown).Return(nil)
+	backend := WorkerBackend{
+		ctx:        contextMock,
+		cancelFlag: cancelFlag,
+	}
+	cancelDatagram, err := CreateDatagram(MessageTypeCancel, string(task.CancelReasonAgentShutdown))
+	assert.NoError(t, err)
+	assert.NoError(t, backend.Process(cancelDatagram))
+	cancelFlag.AssertExpectations(t)
+}
+
 func TestWorkerBackendPluginListener(t *testing.T) {
 	testCase := CreateTestCase()
 	statusChan := make(chan contracts.PluginResult)
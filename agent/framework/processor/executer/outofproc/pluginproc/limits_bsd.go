@@ -0,0 +1,30 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build netbsd openbsd
+
+package pluginproc
+
+import (
+	"errors"
+
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+)
+
+// limitProcess fails when the step has limits, they aren't supported on this platform
+func limitProcess(limits runpluginutil.ProcessLimits) error {
+	if limits != (runpluginutil.ProcessLimits{}) {
+		return errors.New("the resource limits aren't supported on this platform")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux
+
+package pluginproc
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"golang.org/x/sys/unix"
+)
+
+// setrlimit sets a resource limit of the process, replaced in the tests
+var setrlimit = unix.Setrlimit
+
+// limitProcess sets the rlimits of the process, which the processes it starts inherit. MaxProcesses limits the
+// processes of the user the process runs as, and doesn't apply to root.
+func limitProcess(limits runpluginutil.ProcessLimits) error {
+	rlimits := []struct {
+		resource int
+		name     string
+		value    uint64
+	}{
+		{unix.RLIMIT_AS, "MaxMemoryMB", uint64(limits.MaxMemoryMB) * 1024 * 1024},
+		{unix.RLIMIT_CPU, "MaxCPUSeconds", uint64(limits.MaxCPUSeconds)},
+		{unix.RLIMIT_NOFILE, "MaxOpenFiles", uint64(limits.MaxOpenFiles)},
+		{unix.RLIMIT_NPROC, "MaxProcesses", uint64(limits.MaxProcesses)},
+	}
+	for _, rlimit := range rlimits {
+		if rlimit.value == 0 {
+			continue
+		}
+		if err := setrlimit(rlimit.resource, newRlimit(rlimit.value)); err != nil {
+			return fmt.Errorf("%v %v: %v", rlimit.name, rlimit.value, err)
+		}
+	}
+	return nil
+}
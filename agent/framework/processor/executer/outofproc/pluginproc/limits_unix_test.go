@@ -0,0 +1,41 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux
+
+package pluginproc
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestLimitProcess(t *testing.T) {
+	origSetrlimit := setrlimit
+	defer func() { setrlimit = origSetrlimit }()
+	rlimits := map[int]uint64{}
+	setrlimit = func(resource int, rlimit *unix.Rlimit) error {
+		rlimits[resource] = uint64(rlimit.Cur)
+		return nil
+	}
+
+	assert.NoError(t, limitProcess(runpluginutil.ProcessLimits{MaxMemoryMB: 512, MaxOpenFiles: 1024}))
+	assert.Equal(t, map[int]uint64{unix.RLIMIT_AS: 512 * 1024 * 1024, unix.RLIMIT_NOFILE: 1024}, rlimits)
+
+	setrlimit = func(resource int, rlimit *unix.Rlimit) error { return unix.EPERM }
+	assert.NoError(t, limitProcess(runpluginutil.ProcessLimits{}))
+	assert.Error(t, limitProcess(runpluginutil.ProcessLimits{MaxProcesses: 10}))
+}
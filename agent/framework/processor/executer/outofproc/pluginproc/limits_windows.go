@@ -0,0 +1,35 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package pluginproc
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/jobobject"
+)
+
+// limitProcess assigns the process to a job object with the limits, which the processes it starts join.
+// MaxCPUSeconds limits the user time of all the processes, MaxOpenFiles isn't supported on windows.
+func limitProcess(limits runpluginutil.ProcessLimits) error {
+	if limits == (runpluginutil.ProcessLimits{}) {
+		return nil
+	}
+	return jobobject.LimitCurrentProcess(
+		uint64(limits.MaxMemoryMB)*1024*1024,
+		time.Duration(limits.MaxCPUSeconds)*time.Second,
+		uint32(limits.MaxProcesses))
+}
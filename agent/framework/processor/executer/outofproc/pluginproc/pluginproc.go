@@ -0,0 +1,207 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package pluginproc runs the steps of the isolated plugins in short-lived worker processes, started by the
+// document worker with their resource limits, so that a plugin crashing or leaking doesn't take the document
+// worker down.
+//
+// The document worker starts itself with the plugin argument and the path of the result file. It writes the
+// step to the standard input of the process, then the cancellations of the document; the process applies its
+// limits, runs the step in process and writes its result to the file.
+package pluginproc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// PluginArg is the first argument of the document worker running a step, the second is the result file
+const PluginArg = "plugin"
+
+// killGracePeriod is how long a cancelled step can take to stop before its process is killed
+const killGracePeriod = 30 * time.Second
+
+// request is the step the process runs
+type request struct {
+	InstanceID    string
+	PluginName    string
+	Configuration contracts.Configuration
+	IOConfig      contracts.IOConfiguration
+	Limits        runpluginutil.ProcessLimits
+}
+
+// cancellation is the cancellation of the document, forwarded to the process
+type cancellation struct {
+	State  task.State
+	Reason task.CancelReason
+}
+
+// response is the result of the step, its error isn't marshaled with it
+type response struct {
+	Result contracts.PluginResult
+	Error  string
+}
+
+// workerCommand returns the command running a step, replaced in the tests
+var workerCommand = func(resultFile string) *exec.Cmd {
+	return exec.Command(appconfig.DefaultDocumentWorker, PluginArg, resultFile)
+}
+
+// runInProcess runs the step in the process, replaced in the tests
+var runInProcess = runpluginutil.RunPlugin
+
+// Run runs the step in a new worker process with the limits, see runpluginutil.ProcessRunner.
+func Run(
+	context context.T,
+	pluginName string,
+	config contracts.Configuration,
+	cancelFlag task.CancelFlag,
+	ioConfig contracts.IOConfiguration,
+	limits runpluginutil.ProcessLimits) (res contracts.PluginResult, err error) {
+
+	log := context.Log()
+	resultFile, err := ioutil.TempFile("", "ssm-plugin-result")
+	if err != nil {
+		return res, fmt.Errorf("failed to create the result file: %v", err)
+	}
+	resultFile.Close()
+	defer os.Remove(resultFile.Name())
+
+	instanceID, _ := platform.InstanceID()
+	step, err := json.Marshal(request{
+		InstanceID:    instanceID,
+		PluginName:    pluginName,
+		Configuration: config,
+		IOConfig:      ioConfig,
+		Limits:        limits,
+	})
+	if err != nil {
+		return res, err
+	}
+
+	command := workerCommand(resultFile.Name())
+	stdin, err := command.StdinPipe()
+	if err != nil {
+		return res, err
+	}
+	if err = command.Start(); err != nil {
+		return res, fmt.Errorf("failed to start the worker process: %v", err)
+	}
+	log.Debugf("Running step %v in worker process %v", config.PluginID, command.Process.Pid)
+	// the process reads its step, then waits for the cancellations until the standard input closes
+	defer stdin.Close()
+	if _, err = stdin.Write(append(step, '\n')); err != nil {
+		log.Errorf("failed to send step %v to its worker process: %v", config.PluginID, err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- command.Wait() }()
+
+	select {
+	case err = <-exited:
+	case <-task.Done(cancelFlag):
+		if state := cancelFlag.State(); state == task.Canceled || state == task.ShutDown {
+			cancel, _ := json.Marshal(cancellation{State: state, Reason: cancelFlag.Reason()})
+			stdin.Write(append(cancel, '\n'))
+		}
+		select {
+		case err = <-exited:
+		case <-time.After(killGracePeriod):
+			log.Errorf("the worker process of step %v didn't stop, killing it", config.PluginID)
+			command.Process.Kill()
+			err = <-exited
+		}
+	}
+
+	content, readErr := ioutil.ReadFile(resultFile.Name())
+	if readErr != nil || len(content) == 0 {
+		if err == nil {
+			err = errors.New("the process exited without the result of the step")
+		}
+		return res, err
+	}
+	var result response
+	if err = json.Unmarshal(content, &result); err != nil {
+		return res, fmt.Errorf("the result of the step is invalid: %v", err)
+	}
+	res = result.Result
+	if result.Error != "" {
+		res.Error = errors.New(result.Error)
+	}
+	return res, nil
+}
+
+// Serve runs the step the document worker writes to the standard input, in the worker process started by Run,
+// and writes its result to the result file.
+func Serve(context context.T, input io.Reader, resultFile string) error {
+	log := context.Log()
+	reader := bufio.NewReader(input)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read the step: %v", err)
+	}
+	var step request
+	if err = json.Unmarshal(line, &step); err != nil {
+		return fmt.Errorf("the step is invalid: %v", err)
+	}
+	platform.SetInstanceID(step.InstanceID)
+	if err = limitProcess(step.Limits); err != nil {
+		return fmt.Errorf("failed to limit the resources of the process: %v", err)
+	}
+
+	cancelFlag := task.NewChanneledCancelFlag()
+	go forwardCancellations(reader, cancelFlag)
+
+	res := runInProcess(context, step.PluginName, step.Configuration, cancelFlag, step.IOConfig)
+	result := response{Result: res}
+	if res.Error != nil {
+		result.Error = res.Error.Error()
+	}
+	content, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	log.Debugf("Step %v ended with status %v", step.Configuration.PluginID, res.Status)
+	return ioutil.WriteFile(resultFile, content, appconfig.ReadWriteAccess)
+}
+
+// forwardCancellations sets the cancel flag of the step with the cancellations of the document. The document
+// worker closing the standard input, e.g. when it crashes, cancels the step.
+func forwardCancellations(reader *bufio.Reader, cancelFlag task.CancelFlag) {
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			cancelFlag.Set(task.Canceled)
+			return
+		}
+		var cancel cancellation
+		if json.Unmarshal(line, &cancel) == nil && (cancel.State == task.Canceled || cancel.State == task.ShutDown) {
+			cancelFlag.SetWithReason(cancel.State, cancel.Reason)
+			return
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pluginproc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWorkerCommand runs TestPluginWorkerHelperProcess as the worker process, in the mode
+func fakeWorkerCommand(mode string) func(string) *exec.Cmd {
+	return func(resultFile string) *exec.Cmd {
+		cmd := exec.Command(os.Args[0], "-test.run=TestPluginWorkerHelperProcess", "--", resultFile)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "HELPER_MODE=" + mode}
+		return cmd
+	}
+}
+
+// TestPluginWorkerHelperProcess is not a real test, it's the worker process of the other tests
+func TestPluginWorkerHelperProcess(*testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	switch os.Getenv("HELPER_MODE") {
+	case "crash":
+		os.Exit(2)
+	case "cancel":
+		runInProcess = func(context context.T, pluginName string, config contracts.Configuration, cancelFlag task.CancelFlag, ioConfig contracts.IOConfiguration) contracts.PluginResult {
+			cancelFlag.Wait()
+			return contracts.PluginResult{Status: contracts.ResultStatusCancelled, Output: string(cancelFlag.Reason())}
+		}
+	default:
+		runInProcess = func(context context.T, pluginName string, config contracts.Configuration, cancelFlag task.CancelFlag, ioConfig contracts.IOConfiguration) contracts.PluginResult {
+			return contracts.PluginResult{Status: contracts.ResultStatusFailed, Code: 3, StandardOutput: pluginName + " " + config.PluginID, Error: assert.AnError}
+		}
+	}
+	resultFile := os.Args[len(os.Args)-1]
+	if err := Serve(context.NewMockDefault(), os.Stdin, resultFile); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func runFakeWorker(mode string, cancelFlag task.CancelFlag) (contracts.PluginResult, error) {
+	origCommand := workerCommand
+	workerCommand = fakeWorkerCommand(mode)
+	defer func() { workerCommand = origCommand }()
+	platform.SetInstanceID("i-1234567890")
+
+	config := contracts.Configuration{PluginID: "install", PluginName: "aws:runShellScript"}
+	return Run(context.NewMockDefault(), "aws:runShellScript", config, cancelFlag, contracts.IOConfiguration{}, runpluginutil.ProcessLimits{})
+}
+
+func TestRun(t *testing.T) {
+	res, err := runFakeWorker("run", task.NewChanneledCancelFlag())
+	assert.NoError(t, err)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Equal(t, 3, res.Code)
+	assert.Equal(t, "aws:runShellScript install", res.StandardOutput)
+	assert.EqualError(t, res.Error, assert.AnError.Error())
+}
+
+func TestRunCrash(t *testing.T) {
+	_, err := runFakeWorker("crash", task.NewChanneledCancelFlag())
+	assert.Error(t, err)
+}
+
+func TestRunCancel(t *testing.T) {
+	cancelFlag := task.NewChanneledCancelFlag()
+	cancelFlag.SetWithReason(task.Canceled, task.CancelReasonTimeout)
+
+	res, err := runFakeWorker("cancel", cancelFlag)
+	assert.NoError(t, err)
+	assert.Equal(t, contracts.ResultStatusCancelled, res.Status)
+	assert.Equal(t, string(task.CancelReasonTimeout), res.Output)
+}
+
+func TestServeInvalidStep(t *testing.T) {
+	resultFile, err := ioutil.TempFile("", "ssm-plugin-result")
+	assert.NoError(t, err)
+	resultFile.Close()
+	defer os.Remove(resultFile.Name())
+
+	assert.Error(t, Serve(context.NewMockDefault(), bytes.NewBufferString("{\n"), resultFile.Name()))
+	assert.Error(t, Serve(context.NewMockDefault(), bytes.NewBufferString(""), resultFile.Name()))
+
+	step, _ := json.Marshal(request{PluginName: "aws:runShellScript"})
+	origRunInProcess := runInProcess
+	defer func() { runInProcess = origRunInProcess }()
+	runInProcess = func(context context.T, pluginName string, config contracts.Configuration, cancelFlag task.CancelFlag, ioConfig contracts.IOConfiguration) contracts.PluginResult {
+		return contracts.PluginResult{Status: contracts.ResultStatusSuccess}
+	}
+	assert.NoError(t, Serve(context.NewMockDefault(), bytes.NewBuffer(append(step, '\n')), resultFile.Name()))
+	content, _ := ioutil.ReadFile(resultFile.Name())
+	assert.Contains(t, string(content), `"status":"Success"`)
+}
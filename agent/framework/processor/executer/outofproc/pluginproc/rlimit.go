@@ -0,0 +1,23 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin linux
+
+package pluginproc
+
+import "golang.org/x/sys/unix"
+
+// newRlimit returns the rlimit setting the soft and hard limits to the value
+func newRlimit(value uint64) *unix.Rlimit {
+	return &unix.Rlimit{Cur: value, Max: value}
+}
@@ -107,6 +107,8 @@ func ParseArgv(argv []string) (string, string, error) {
 
 }
 
-func FormArgv(channelName string) []string {
-	return []string{channelName}
+// FormArgv returns the arguments of the document worker, passing on the instance id so the worker doesn't
+// have to look it up again (and picks up the instance id the agent was started with)
+func FormArgv(channelName string, instanceID string) []string {
+	return []string{channelName, instanceID}
 }
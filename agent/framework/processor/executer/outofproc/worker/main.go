@@ -9,18 +9,21 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/messaging"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/pluginproc"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/plugin"
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
 const (
 	defaultCommandTimeoutMax = 172800 * time.Second
 	defaultWorkerContextName = "[ssm-document-worker]"
+	pluginWorkerContextName  = "[ssm-plugin-worker]"
 )
 
 var pluginRunner = func(
@@ -40,8 +43,7 @@ var pluginRunner = func(
 func initialize(args []string) (context.T, string, error) {
 	// intialize a light weight logger, use the default seelog config logger
 	logger := ssmlog.SSMLogger(false)
-	// initialize appconfig, use default config
-	config := appconfig.DefaultConfig()
+	config := loadConfig(logger)
 	logger.Debugf("parsing args: %v", args)
 	channelName, instanceID, err := proc.ParseArgv(args)
 	//cache the instanceID here in order to avoid throttle by metadata endpoint.
@@ -53,7 +55,38 @@ func initialize(args []string) (context.T, string, error) {
 	return context.Default(logger, config).With(defaultWorkerContextName).With("[" + channelName + "]"), channelName, err
 }
 
+// loadConfig returns the agent configuration, which holds the settings of the plugins, or the default
+// configuration when it can't be loaded
+func loadConfig(logger log.T) appconfig.SsmagentConfig {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		logger.Errorf("failed to load the agent configuration, using the default: %v", err)
+		return appconfig.DefaultConfig()
+	}
+	return config
+}
+
+// runStep runs the step of an isolated plugin the document worker writes to the standard input, in this short
+// lived process started by the document worker
+func runStep(resultFile string) {
+	logger := ssmlog.SSMLogger(false)
+	ctx := context.Default(logger, loadConfig(logger)).With(pluginWorkerContextName)
+	runpluginutil.SSMPluginRegistry = plugin.RegisteredWorkerPlugins(ctx)
+	if err := pluginproc.Serve(ctx, os.Stdin, resultFile); err != nil {
+		logger.Errorf("plugin worker failed: %v", err)
+		logger.Close()
+		os.Exit(1)
+	}
+	logger.Close()
+}
+
 func main() {
+	proxyconfig.UseAgentProxy()
+	if len(os.Args) == 3 && os.Args[1] == pluginproc.PluginArg {
+		runStep(os.Args[2])
+		return
+	}
+
 	var err error
 	var logger log.T
 	args := os.Args
@@ -74,6 +107,8 @@ func main() {
 	}
 	//initialize PluginRegistry
 	runpluginutil.SSMPluginRegistry = plugin.RegisteredWorkerPlugins(ctx)
+	//run the steps of the isolated plugins in their own processes
+	runpluginutil.PluginProcessRunner = pluginproc.Run
 
 	//TODO add command timeout
 	stopTimer := make(chan bool)
@@ -23,13 +23,24 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurecontainers"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/diskspace"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/dockercontainer"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/lrpminvoker"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/manageagentconfig"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/manageprocesses"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/netdiag"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/quarantine"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/refreshassociation"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/renamehost"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/rendertemplate"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/rundocument"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/runpython"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/runscript"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/temporaryadmin"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/testreport"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/timesync"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/updatessmagent"
 )
 
@@ -44,6 +55,7 @@ var allPlugins = map[string]struct{}{
 	appconfig.PluginNameAwsPowerShellModule:    {},
 	appconfig.PluginNameAwsRunPowerShellScript: {},
 	appconfig.PluginNameAwsRunShellScript:      {},
+	appconfig.PluginNameAwsRunPythonScript:     {},
 	appconfig.PluginNameAwsSoftwareInventory:   {},
 	appconfig.PluginNameCloudWatch:             {},
 	appconfig.PluginNameConfigureDocker:        {},
@@ -51,6 +63,18 @@ var allPlugins = map[string]struct{}{
 	appconfig.PluginNameDomainJoin:             {},
 	appconfig.PluginEC2ConfigUpdate:            {},
 	appconfig.PluginNameRefreshAssociation:     {},
+	appconfig.PluginNameManageAgentConfig:      {},
+	appconfig.PluginNameQuarantine:             {},
+	appconfig.PluginNameParseTestReport:        {},
+	appconfig.PluginNameManageRegistry:         {},
+	appconfig.PluginNameManageAuthorizedKeys:   {},
+	appconfig.PluginNameGrantTemporaryAdmin:    {},
+	appconfig.PluginNameConfigureTimeSync:      {},
+	appconfig.PluginNameRenameHost:             {},
+	appconfig.PluginNameDiagnoseNetwork:        {},
+	appconfig.PluginNameManageDiskSpace:        {},
+	appconfig.PluginNameManageProcesses:        {},
+	appconfig.PluginNameRenderTemplate:         {},
 	appconfig.PluginDownloadContent:            {},
 	appconfig.PluginRunDocument:                {},
 }
@@ -81,6 +105,20 @@ func (f RunPowerShellFactory) Create(context context.T) (runpluginutil.T, error)
 	return runscript.NewRunPowerShellPlugin()
 }
 
+type RunShellScriptFactory struct {
+}
+
+func (f RunShellScriptFactory) Create(context context.T) (runpluginutil.T, error) {
+	return runscript.NewRunShellPlugin(context.Log())
+}
+
+type RunPythonScriptFactory struct {
+}
+
+func (f RunPythonScriptFactory) Create(context context.T) (runpluginutil.T, error) {
+	return runpython.NewPlugin()
+}
+
 type UpdateAgentFactory struct {
 }
 
@@ -116,6 +154,76 @@ func (f RefreshAssociationFactory) Create(context context.T) (runpluginutil.T, e
 	return refreshassociation.NewPlugin()
 }
 
+type ManageAgentConfigFactory struct {
+}
+
+func (f ManageAgentConfigFactory) Create(context context.T) (runpluginutil.T, error) {
+	return manageagentconfig.NewPlugin()
+}
+
+type QuarantineFactory struct {
+}
+
+func (f QuarantineFactory) Create(context context.T) (runpluginutil.T, error) {
+	return quarantine.NewPlugin()
+}
+
+type ParseTestReportFactory struct {
+}
+
+func (f ParseTestReportFactory) Create(context context.T) (runpluginutil.T, error) {
+	return testreport.NewPlugin()
+}
+
+type GrantTemporaryAdminFactory struct {
+}
+
+func (f GrantTemporaryAdminFactory) Create(context context.T) (runpluginutil.T, error) {
+	return temporaryadmin.NewPlugin()
+}
+
+type ConfigureTimeSyncFactory struct {
+}
+
+func (f ConfigureTimeSyncFactory) Create(context context.T) (runpluginutil.T, error) {
+	return timesync.NewPlugin()
+}
+
+type RenameHostFactory struct {
+}
+
+func (f RenameHostFactory) Create(context context.T) (runpluginutil.T, error) {
+	return renamehost.NewPlugin()
+}
+
+type DiagnoseNetworkFactory struct {
+}
+
+func (f DiagnoseNetworkFactory) Create(context context.T) (runpluginutil.T, error) {
+	return netdiag.NewPlugin()
+}
+
+type ManageDiskSpaceFactory struct {
+}
+
+func (f ManageDiskSpaceFactory) Create(context context.T) (runpluginutil.T, error) {
+	return diskspace.NewPlugin()
+}
+
+type ManageProcessesFactory struct {
+}
+
+func (f ManageProcessesFactory) Create(context context.T) (runpluginutil.T, error) {
+	return manageprocesses.NewPlugin()
+}
+
+type RenderTemplateFactory struct {
+}
+
+func (f RenderTemplateFactory) Create(context context.T) (runpluginutil.T, error) {
+	return rendertemplate.NewPlugin()
+}
+
 type DownloadContentFactory struct {
 }
 
@@ -168,6 +276,12 @@ func loadPlatformIndependentPlugins(context context.T) runpluginutil.PluginRegis
 	// registering aws:runPowerShellScript plugin
 	workerPlugins[appconfig.PluginNameAwsRunPowerShellScript] = RunPowerShellFactory{}
 
+	// registering aws:runShellScript plugin, it runs with bash on Windows
+	workerPlugins[appconfig.PluginNameAwsRunShellScript] = RunShellScriptFactory{}
+
+	// registering aws:runPythonScript plugin
+	workerPlugins[runpython.Name()] = RunPythonScriptFactory{}
+
 	// registering aws:updateSsmAgent plugin
 	updateAgentPluginName := updatessmagent.Name()
 	workerPlugins[updateAgentPluginName] = UpdateAgentFactory{}
@@ -185,6 +299,46 @@ func loadPlatformIndependentPlugins(context context.T) runpluginutil.PluginRegis
 	refreshAssociationPluginName := refreshassociation.Name()
 	workerPlugins[refreshAssociationPluginName] = RefreshAssociationFactory{}
 
+	// registering aws:manageAgentConfig plugin
+	manageAgentConfigPluginName := manageagentconfig.Name()
+	workerPlugins[manageAgentConfigPluginName] = ManageAgentConfigFactory{}
+
+	// registering aws:quarantine plugin
+	quarantinePluginName := quarantine.Name()
+	workerPlugins[quarantinePluginName] = QuarantineFactory{}
+
+	// registering aws:parseTestReport plugin
+	parseTestReportPluginName := testreport.Name()
+	workerPlugins[parseTestReportPluginName] = ParseTestReportFactory{}
+
+	// registering aws:grantTemporaryAdmin plugin
+	grantTemporaryAdminPluginName := temporaryadmin.Name()
+	workerPlugins[grantTemporaryAdminPluginName] = GrantTemporaryAdminFactory{}
+
+	// registering aws:configureTimeSync plugin
+	configureTimeSyncPluginName := timesync.Name()
+	workerPlugins[configureTimeSyncPluginName] = ConfigureTimeSyncFactory{}
+
+	// registering aws:renameHost plugin
+	renameHostPluginName := renamehost.Name()
+	workerPlugins[renameHostPluginName] = RenameHostFactory{}
+
+	// registering aws:diagnoseNetwork plugin
+	diagnoseNetworkPluginName := netdiag.Name()
+	workerPlugins[diagnoseNetworkPluginName] = DiagnoseNetworkFactory{}
+
+	// registering aws:manageDiskSpace plugin
+	manageDiskSpacePluginName := diskspace.Name()
+	workerPlugins[manageDiskSpacePluginName] = ManageDiskSpaceFactory{}
+
+	// registering aws:manageProcesses plugin
+	manageProcessesPluginName := manageprocesses.Name()
+	workerPlugins[manageProcessesPluginName] = ManageProcessesFactory{}
+
+	// registering aws:renderTemplate plugin
+	renderTemplatePluginName := rendertemplate.Name()
+	workerPlugins[renderTemplatePluginName] = RenderTemplateFactory{}
+
 	// registering aws:configurePackage
 	configurePackagePluginName := configurepackage.Name()
 	workerPlugins[configurePackagePluginName] = ConfigurePackageFactory{}
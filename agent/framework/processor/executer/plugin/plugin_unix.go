@@ -19,20 +19,42 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
-	"github.com/aws/amazon-ssm-agent/agent/plugins/runscript"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/application"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/authorizedkeys"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/domainjoin"
 )
 
-type RunShellScriptFactory struct {
+type ManageAuthorizedKeysFactory struct {
 }
 
-func (f RunShellScriptFactory) Create(context context.T) (runpluginutil.T, error) {
-	return runscript.NewRunShellPlugin(context.Log())
+func (f ManageAuthorizedKeysFactory) Create(context context.T) (runpluginutil.T, error) {
+	return authorizedkeys.NewPlugin()
+}
+
+type ApplicationFactory struct {
+}
+
+func (f ApplicationFactory) Create(context context.T) (runpluginutil.T, error) {
+	return application.NewPlugin()
+}
+
+type DomainJoinFactory struct {
+}
+
+func (f DomainJoinFactory) Create(context context.T) (runpluginutil.T, error) {
+	return domainjoin.NewPlugin()
 }
 
 // loadPlatformDependentPlugins registers platform dependent plugins
 func loadPlatformDependentPlugins(context context.T) runpluginutil.PluginRegistry {
 	var workerPlugins = runpluginutil.PluginRegistry{}
 
-	workerPlugins[appconfig.PluginNameAwsRunShellScript] = RunShellScriptFactory{}
+	workerPlugins[appconfig.PluginNameManageAuthorizedKeys] = ManageAuthorizedKeysFactory{}
+
+	// registering aws:applications plugin
+	workerPlugins[application.Name()] = ApplicationFactory{}
+
+	// registering aws:domainJoin plugin
+	workerPlugins[domainjoin.Name()] = DomainJoinFactory{}
 	return workerPlugins
 }
@@ -0,0 +1,108 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor defines the document processing unit interface
+package processor
+
+import (
+	gocontext "context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+const (
+	preExecutionHook  = "pre"
+	postExecutionHook = "post"
+
+	// maxHookOutputLength is the length the output of a failed hook is truncated to in the logs
+	maxHookOutputLength = 2500
+
+	// hookWaitDelay is how long the agent waits for the output of a hook once it is killed
+	hookWaitDelay = 5 * time.Second
+)
+
+// runHook runs the script of a hook with the environment, and returns its output. The hook and the processes it
+// started are killed on timeout.
+var runHook = func(path string, env []string, timeout time.Duration) (string, error) {
+	if err := checkHookFile(path); err != nil {
+		return "", err
+	}
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), timeout)
+	defer cancel()
+	command := exec.CommandContext(ctx, path)
+	command.Env = append(os.Environ(), env...)
+	killHookOnCancel(command)
+	command.WaitDelay = hookWaitDelay
+	output, err := command.CombinedOutput()
+	if ctx.Err() == gocontext.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+	return string(output), err
+}
+
+// runPreExecutionHook runs Agent.PreExecutionHook before the document starts. A document resumed after a restart
+// or a reboot already ran the hook, and doesn't run it again.
+func runPreExecutionHook(context context.T, docState *contracts.DocumentState) {
+	for _, plugin := range docState.InstancePluginsInformation {
+		if plugin.Result.Status != "" {
+			return
+		}
+	}
+	runExecutionHook(context, preExecutionHook, context.AppConfig().Agent.PreExecutionHook, docState, "")
+}
+
+// runPostExecutionHook runs Agent.PostExecutionHook once the document is complete.
+func runPostExecutionHook(context context.T, docState *contracts.DocumentState, status contracts.ResultStatus) {
+	runExecutionHook(context, postExecutionHook, context.AppConfig().Agent.PostExecutionHook, docState, status)
+}
+
+// runExecutionHook runs the script of a hook with the metadata of the document in its environment. The failures of
+// the hook are logged, they don't change the execution or the status of the document.
+func runExecutionHook(context context.T, hook string, path string, docState *contracts.DocumentState, status contracts.ResultStatus) {
+	if path == "" {
+		return
+	}
+	log := context.Log()
+	info := docState.DocumentInformation
+	env := []string{
+		"AWS_SSM_HOOK=" + hook,
+		"AWS_SSM_INSTANCE_ID=" + info.InstanceID,
+		"AWS_SSM_DOCUMENT_ID=" + info.DocumentID,
+		"AWS_SSM_DOCUMENT_NAME=" + info.DocumentName,
+		"AWS_SSM_DOCUMENT_VERSION=" + info.DocumentVersion,
+		"AWS_SSM_DOCUMENT_TYPE=" + string(docState.DocumentType),
+		"AWS_SSM_COMMAND_ID=" + info.CommandID,
+		"AWS_SSM_ASSOCIATION_ID=" + info.AssociationID,
+		"AWS_SSM_MESSAGE_ID=" + info.MessageID,
+	}
+	if status != "" {
+		env = append(env, "AWS_SSM_DOCUMENT_STATUS="+string(status))
+	}
+	timeout := time.Duration(context.AppConfig().Agent.ExecutionHookTimeoutSeconds) * time.Second
+	log.Infof("Running the %v-execution hook %v of document %v", hook, path, info.DocumentID)
+	output, err := runHook(path, env, timeout)
+	if err != nil {
+		if len(output) > maxHookOutputLength {
+			output = output[:maxHookOutputLength]
+		}
+		log.Errorf("the %v-execution hook %v of document %v failed: %v\n%v", hook, path, info.DocumentID, err, strings.TrimSpace(output))
+		return
+	}
+	log.Debugf("the %v-execution hook of document %v succeeded: %v", hook, info.DocumentID, strings.TrimSpace(output))
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+package processor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// checkHookFile refuses a hook that root doesn't own or that users other than its owner can change, as the agent
+// runs it as root
+func checkHookFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%v is not a regular file", path)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Uid != 0 {
+		return fmt.Errorf("%v is not owned by root", path)
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("%v is writable by its group or by others", path)
+	}
+	return nil
+}
+
+// killHookOnCancel starts the hook in its own process group, and kills the whole group on timeout so that the
+// processes the hook started in the background don't outlive it
+func killHookOnCancel(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	command.Cancel = func() error {
+		return syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// hookContext returns a context running the hook script, which writes its environment to env.txt
+func hookContext(t *testing.T, dir string, script string) *context.Mock {
+	path := filepath.Join(dir, "hook.sh")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700))
+	config := appconfig.SsmagentConfig{}
+	config.Agent.PreExecutionHook = path
+	config.Agent.PostExecutionHook = path
+	config.Agent.ExecutionHookTimeoutSeconds = 1
+	ctx := new(context.Mock)
+	ctx.On("AppConfig").Return(config)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func hookDocState() *contracts.DocumentState {
+	docState := &contracts.DocumentState{DocumentType: contracts.SendCommand}
+	docState.DocumentInformation.DocumentID = "documentID"
+	docState.DocumentInformation.CommandID = "commandID"
+	docState.DocumentInformation.DocumentName = "AWS-RunShellScript"
+	docState.InstancePluginsInformation = []contracts.PluginState{{Id: "step1"}}
+	return docState
+}
+
+func TestExecutionHooksEnvironment(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "hooks")
+	defer os.RemoveAll(dir)
+	envFile := filepath.Join(dir, "env.txt")
+	ctx := hookContext(t, dir, "env | grep ^AWS_SSM_ | sort >> "+envFile+"\n")
+	docState := hookDocState()
+
+	runPreExecutionHook(ctx, docState)
+	runPostExecutionHook(ctx, docState, contracts.ResultStatusFailed)
+
+	env, err := ioutil.ReadFile(envFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(env), "AWS_SSM_HOOK=pre\n")
+	assert.Contains(t, string(env), "AWS_SSM_HOOK=post\n")
+	assert.Contains(t, string(env), "AWS_SSM_DOCUMENT_NAME=AWS-RunShellScript\n")
+	assert.Contains(t, string(env), "AWS_SSM_COMMAND_ID=commandID\n")
+	assert.Contains(t, string(env), "AWS_SSM_DOCUMENT_TYPE=SendCommand\n")
+	assert.Contains(t, string(env), "AWS_SSM_DOCUMENT_STATUS=Failed\n")
+}
+
+func TestPreExecutionHookSkippedForResumedDocuments(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "hooks")
+	defer os.RemoveAll(dir)
+	envFile := filepath.Join(dir, "env.txt")
+	ctx := hookContext(t, dir, "touch "+envFile+"\n")
+	docState := hookDocState()
+	docState.InstancePluginsInformation[0].Result.Status = contracts.ResultStatusSuccess
+
+	runPreExecutionHook(ctx, docState)
+
+	_, err := os.Stat(envFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExecutionHookTimesOut(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "hooks")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hook.sh")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\nexec sleep 10\n"), 0700))
+
+	_, err := runHook(path, nil, 100*time.Millisecond)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
+
+func TestExecutionHookTimeoutKillsBackgroundProcesses(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "hooks")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hook.sh")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\nsleep 10 &\nsleep 10\n"), 0700))
+
+	start := time.Now()
+	_, err := runHook(path, nil, 100*time.Millisecond)
+
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < hookWaitDelay)
+}
+
+func TestExecutionHookWritableByOthersRefused(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "hooks")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hook.sh")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\ntouch "+path+".ran\n"), 0700))
+	assert.NoError(t, os.Chmod(path, 0777))
+
+	_, err := runHook(path, nil, time.Second)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "writable")
+	_, err = os.Stat(path + ".ran")
+	assert.True(t, os.IsNotExist(err))
+}
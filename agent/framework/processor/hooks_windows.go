@@ -0,0 +1,37 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+package processor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// checkHookFile refuses a hook that isn't a regular file. The access to the hook is left to the ACL of its folder.
+func checkHookFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%v is not a regular file", path)
+	}
+	return nil
+}
+
+// killHookOnCancel keeps the default cancellation, which kills the hook process
+func killHookOnCancel(command *exec.Cmd) {}
@@ -26,10 +26,14 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/etw"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc"
+	"github.com/aws/amazon-ssm-agent/agent/history"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/rebooter"
@@ -45,6 +49,8 @@ const (
 	hardStopTimeout = time.Second * 4
 )
 
+var rebootPending = rebooter.RebootPending
+
 type Processor interface {
 	//Start activate the Processor and pick up the left over document in the last run, it returns a channel to caller to gather DocumentResult
 	Start() (chan contracts.DocumentResult, error)
@@ -56,6 +62,8 @@ type Processor interface {
 	Submit(docState contracts.DocumentState)
 	//cancel process the cancel document, with no return value since the command is already tracked in a different thread
 	Cancel(docState contracts.DocumentState)
+	//SetCommandWorkersLimit changes the number of documents run in parallel, the running documents are not interrupted
+	SetCommandWorkersLimit(limit int)
 	//TODO do we need to implement CancelAll?
 	//CancelAll()
 }
@@ -79,13 +87,21 @@ func NewEngineProcessor(ctx context.T, commandWorkerLimit int, cancelWorkerLimit
 	// so we can define the number of workers per each
 	cancelWaitDuration := 10000 * time.Millisecond
 	clock := times.DefaultClock
-	sendCommandTaskPool := task.NewPool(log, commandWorkerLimit, cancelWaitDuration, clock)
+	weights := task.CategoryWeights(ctx.AppConfig().Agent.JobCategoryWeights)
+	sendCommandTaskPool := task.NewPoolWithWeights(log, commandWorkerLimit, cancelWaitDuration, clock, weights)
 	cancelCommandTaskPool := task.NewPool(log, cancelWorkerLimit, cancelWaitDuration, clock)
+	if len(supportedDocs) > 0 {
+		// expose the pool utilization to the health module, named after the primary document type
+		task.RegisterPool(string(supportedDocs[0]), sendCommandTaskPool)
+		task.RegisterPool(string(supportedDocs[0])+"Cancel", cancelCommandTaskPool)
+	}
 	resChan := make(chan contracts.DocumentResult)
 	executerCreator := func(ctx context.T) executer.Executer {
 		return outofproc.NewOutOfProcExecuter(ctx)
 	}
 	documentMgr := docmanager.NewDocumentFileMgr(appconfig.DefaultDataStorePath, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState)
+	// a raised limit of DocumentConcurrency lets the deferred documents run without waiting for a release
+	appconfig.OnReload(concurrencyReloadName, func(appconfig.SsmagentConfig) { documentSlots.drain() })
 	return &EngineProcessor{
 		context:           ctx.With("[EngineProcessor]"),
 		executerCreator:   executerCreator,
@@ -97,6 +113,11 @@ func NewEngineProcessor(ctx context.T, commandWorkerLimit int, cancelWorkerLimit
 	}
 }
 
+// SetCommandWorkersLimit resizes the pool of the documents, the running documents are not interrupted
+func (p *EngineProcessor) SetCommandWorkersLimit(limit int) {
+	p.sendCommandPool.Resize(limit)
+}
+
 func (p *EngineProcessor) Start() (resChan chan contracts.DocumentResult, err error) {
 	context := p.context
 	if context == nil {
@@ -134,52 +155,104 @@ func (p *EngineProcessor) InitialProcessing() (err error) {
 //Submit() is the public interface for sending run document request to processor
 func (p *EngineProcessor) Submit(docState contracts.DocumentState) {
 	log := p.context.Log()
+	//the same document can be submitted twice when it is replayed from disk and redelivered at the same time
+	if p.sendCommandPool.HasJob(jobIDOf(&docState)) || documentSlots.isDeferred(jobIDOf(&docState)) {
+		log.Infof("Document %v is already queued, ignoring duplicate submission", docState.DocumentInformation.DocumentID)
+		return
+	}
 	//queue up the pending document
 	p.documentMgr.PersistDocumentState(log, docState.DocumentInformation.DocumentID, docState.DocumentInformation.InstanceID, appconfig.DefaultLocationOfPending, docState)
 	err := p.submit(&docState)
 	if err != nil {
-		log.Error("Document Submission failed", err)
-		//move the fail-to-submit document to corrupt folder
-		p.documentMgr.MoveDocumentState(log, docState.DocumentInformation.DocumentID, docState.DocumentInformation.InstanceID, appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCorrupt)
+		p.submissionFailed(&docState, err)
 		return
 	}
 	log.Debug("EngineProcessor submit succeeded")
 	return
 }
 
+// submissionFailed moves the document that failed to be submitted to the corrupt folder
+func (p *EngineProcessor) submissionFailed(docState *contracts.DocumentState, err error) {
+	log := p.context.Log()
+	log.Error("Document Submission failed", err)
+	p.documentMgr.MoveDocumentState(log, docState.DocumentInformation.DocumentID, docState.DocumentInformation.InstanceID, appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCorrupt)
+}
+
+// submit submits the document to the pool once it can run within the limits of DocumentConcurrency. A document
+// deferred by its limits waits outside of the pool, and is submitted when the slots it needs are released.
 func (p *EngineProcessor) submit(docState *contracts.DocumentState) error {
 	log := p.context.Log()
-	//TODO this is a hack, in future jobID should be managed by Processing engine itself, instead of inferring from job's internal field
-	var jobID string
-	if docState.IsAssociation() {
-		jobID = docState.DocumentInformation.AssociationID
-	} else {
-		jobID = docState.DocumentInformation.MessageID
+	jobID := jobIDOf(docState)
+	queued := etw.StartSpan(etw.StageDocumentQueued, docState.DocumentInformation.DocumentID)
+	run := func(release func()) error {
+		err := p.sendCommandPool.SubmitWithCategory(log, jobID, jobCategoryOf(docState), func(cancelFlag task.CancelFlag) {
+			defer release()
+			queued.End(string(contracts.ResultStatusInProgress))
+			processCommand(
+				p.context,
+				p.executerCreator,
+				cancelFlag,
+				p.resChan,
+				docState,
+				p.documentMgr)
+		})
+		if err != nil {
+			release()
+		}
+		return err
+	}
+	limits := func() map[string]int {
+		return concurrencyLimits(p.context.AppConfig().DocumentConcurrency, docState.DocumentInformation.DocumentName)
 	}
-	return p.sendCommandPool.Submit(log, jobID, func(cancelFlag task.CancelFlag) {
-		processCommand(
-			p.context,
-			p.executerCreator,
-			cancelFlag,
-			p.resChan,
-			docState,
-			p.documentMgr)
+	release, acquired := documentSlots.acquireOrDefer(&deferredDocument{
+		jobID:  jobID,
+		owner:  p,
+		limits: limits,
+		submit: func(release func()) {
+			log.Infof("Document %v is no longer waiting for %v", docState.DocumentInformation.DocumentID, limitNames(limits()))
+			if err := run(release); err != nil {
+				p.submissionFailed(docState, err)
+			}
+		},
 	})
+	if !acquired {
+		log.Infof("Document %v waits for the running executions of %v to complete", docState.DocumentInformation.DocumentID, limitNames(limits()))
+		return nil
+	}
+	return run(release)
+}
 
+// jobCategoryOf returns the category the document is scheduled under, so that long running
+// background documents such as inventory don't hold up the interactive ones
+func jobCategoryOf(docState *contracts.DocumentState) task.JobCategory {
+	if !docState.IsAssociation() {
+		return task.CategoryRunCommand
+	}
+	for _, plugin := range docState.InstancePluginsInformation {
+		if plugin.Name == appconfig.PluginNameAwsSoftwareInventory {
+			return task.CategoryInventory
+		}
+	}
+	return task.CategoryAssociation
 }
 
-func (p *EngineProcessor) Cancel(docState contracts.DocumentState) {
-	log := p.context.Log()
-	//TODO this is a hack, in future jobID should be managed by Processing engine itself, instead of inferring from job's internal field
-	var jobID string
+//TODO this is a hack, in future jobID should be managed by Processing engine itself, instead of inferring from job's internal field
+func jobIDOf(docState *contracts.DocumentState) string {
 	if docState.IsAssociation() {
-		jobID = docState.DocumentInformation.AssociationID
-	} else {
-		jobID = docState.DocumentInformation.MessageID
+		return docState.DocumentInformation.AssociationID
 	}
+	return docState.DocumentInformation.MessageID
+}
+
+func (p *EngineProcessor) Cancel(docState contracts.DocumentState) {
+	log := p.context.Log()
+	jobID := jobIDOf(&docState)
 	//queue up the pending document
 	p.documentMgr.PersistDocumentState(log, docState.DocumentInformation.DocumentID, docState.DocumentInformation.InstanceID, appconfig.DefaultLocationOfPending, docState)
 	err := p.cancelCommandPool.Submit(log, jobID, func(cancelFlag task.CancelFlag) {
+		// a document deferred by its concurrency limits is submitted to be canceled in the pool, so that its
+		// cancellation is reported the usual way
+		documentSlots.submitDeferred(docState.CancelInformation.CancelMessageID)
 		processCancelCommand(p.context, p.sendCommandPool, &docState, p.documentMgr)
 	})
 	if err != nil {
@@ -198,20 +271,29 @@ func (p *EngineProcessor) Stop(stopType contracts.StopType) {
 		waitTimeout = hardStopTimeout
 	}
 
+	// let the interrupted documents know whether the agent is stopping to reboot the instance
+	reason := task.CancelReasonAgentShutdown
+	if rebootPending() {
+		reason = task.CancelReasonReboot
+	}
+
+	// the deferred documents stay pending for the next start
+	documentSlots.discard(p)
+
 	var wg sync.WaitGroup
 
 	// shutdown the send command pool in a separate go routine
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		p.sendCommandPool.ShutdownAndWait(waitTimeout)
+		p.sendCommandPool.ShutdownAndWaitWithReason(waitTimeout, reason)
 	}()
 
 	// shutdown the cancel command pool in a separate go routine
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		p.cancelCommandPool.ShutdownAndWait(waitTimeout)
+		p.cancelCommandPool.ShutdownAndWaitWithReason(waitTimeout, reason)
 	}()
 
 	// wait for everything to shutdown
@@ -246,6 +328,13 @@ func (p *EngineProcessor) processPendingDocuments(instanceID string) {
 		//inspect document state
 		docState := p.documentMgr.GetDocumentState(log, f.Name(), instanceID, appconfig.DefaultLocationOfPending)
 
+		//a document that already made it to the Current folder is replayed from there
+		if fileutil.Exists(filepath.Join(docmanager.DocumentStateDir(instanceID, appconfig.DefaultLocationOfCurrent), f.Name())) {
+			log.Infof("Document %v is already in progress, removing the pending copy", f.Name())
+			p.documentMgr.RemoveDocumentState(log, f.Name(), instanceID, appconfig.DefaultLocationOfPending)
+			continue
+		}
+
 		if p.isSupportedDocumentType(docState.DocumentType) {
 			log.Infof("Processing pending document %v", docState.DocumentInformation.DocumentID)
 			p.Submit(docState)
@@ -313,6 +402,7 @@ func (p *EngineProcessor) isSupportedDocumentType(documentType contracts.Documen
 }
 
 func processCommand(context context.T, executerCreator ExecuterCreator, cancelFlag task.CancelFlag, resChan chan contracts.DocumentResult, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr) {
+	context = withDocumentTrace(context.With("[documentName="+docState.DocumentInformation.DocumentName+"]"), docState)
 	log := context.Log()
 	//persist the current running document
 	docMgr.MoveDocumentState(log,
@@ -324,8 +414,11 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 	documentID := docState.DocumentInformation.DocumentID
 	instanceID := docState.DocumentInformation.InstanceID
 	messageID := docState.DocumentInformation.MessageID
+	runPreExecutionHook(context, docState)
+	iohandler.WithCloudWatchOutput(context.AppConfig(), docState)
 	e := executerCreator(context)
 	docStore := executer.NewDocumentFileStore(context, instanceID, documentID, appconfig.DefaultLocationOfCurrent, docState, docMgr)
+	span := etw.StartSpan(etw.StageDocumentExecution, documentID)
 	statusChan := e.Run(
 		cancelFlag,
 		&docStore,
@@ -340,6 +433,7 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 
 		}
 		handleCloudwatchPlugin(context, res.PluginResults, documentID)
+		res.TraceParent = docState.DocumentInformation.TraceParent
 		//hand off the message to Service
 		resChan <- res
 		final = &res
@@ -347,14 +441,20 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 	//TODO add shutdown as API call, move cancelFlag out of task pool; cancelFlag to contracts, nobody else above runplugins needs to create cancelFlag.
 	// Shutdown/reboot detection
 	if final == nil || final.LastPlugin != "" {
-		log.Infof("document %v still in progress, shutting down...", messageID)
+		log.Infof("document %v still in progress, shutting down (%v)...", messageID, cancelFlag.Reason())
+		span.End(string(contracts.ResultStatusInProgress))
 		return
-	} else if final.Status == contracts.ResultStatusSuccessAndReboot {
+	}
+	span.End(string(final.Status))
+	if final.Status == contracts.ResultStatusSuccessAndReboot {
 		log.Infof("document %v requested reboot, need to resume", messageID)
 		rebooter.RequestPendingReboot(context.Log())
 		return
 	}
 
+	history.Record(log, context.AppConfig(), history.NewEntry(documentID, docState.DocumentType, *final))
+	runPostExecutionHook(context, docState, final.Status)
+
 	//persist : commands execution in completed folder (terminal state folder)
 	log.Infof("execution of %v is over. Removing interimState from current folder", messageID)
 
@@ -365,6 +465,16 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 
 }
 
+// withDocumentTrace tags the context with a new span of the trace of the document. The span is persisted as the
+// parent of the executer spans so that the worker and plugin logs are correlated with the processor logs.
+func withDocumentTrace(ctx context.T, docState *contracts.DocumentState) context.T {
+	ctx = context.WithTrace(ctx, docState.DocumentInformation.TraceParent)
+	if trace, ok := log.TraceContextOf(ctx.CurrentContext()); ok {
+		docState.DocumentInformation.TraceParent = trace.TraceParent()
+	}
+	return ctx
+}
+
 //TODO CancelCommand is currently treated as a special type of Command by the Processor, but in general Cancel operation should be seen as a probe to existing commands
 func processCancelCommand(context context.T, sendCommandPool task.Pool, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr) {
 
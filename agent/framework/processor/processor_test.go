@@ -22,9 +22,10 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/mock"
-	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/rebooter"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -38,8 +39,9 @@ func TestEngineProcessor_Submit(t *testing.T) {
 	creator := func(ctx context.T) executer.Executer {
 		return executerMock
 	}
-	sendCommandPoolMock.On("Submit", ctx.Log(), "messageID", mock.Anything).Return(nil)
-	docMock := new(DocumentMgrMock)
+	sendCommandPoolMock.On("HasJob", "messageID").Return(false)
+	sendCommandPoolMock.On("SubmitWithCategory", ctx.Log(), "messageID", task.CategoryRunCommand, mock.Anything).Return(nil)
+	docMock := new(docmanagermock.DocumentMgrMock)
 	processor := EngineProcessor{
 		executerCreator: creator,
 		sendCommandPool: sendCommandPoolMock,
@@ -53,6 +55,37 @@ func TestEngineProcessor_Submit(t *testing.T) {
 	sendCommandPoolMock.AssertExpectations(t)
 }
 
+func TestEngineProcessor_SubmitDuplicate(t *testing.T) {
+	sendCommandPoolMock := new(task.MockedPool)
+	ctx := context.NewMockDefault()
+	sendCommandPoolMock.On("HasJob", "messageID").Return(true)
+	docMock := new(docmanagermock.DocumentMgrMock)
+	processor := EngineProcessor{
+		sendCommandPool: sendCommandPoolMock,
+		context:         ctx,
+		documentMgr:     docMock,
+	}
+	docState := contracts.DocumentState{}
+	docState.DocumentInformation.MessageID = "messageID"
+	processor.Submit(docState)
+	sendCommandPoolMock.AssertExpectations(t)
+	sendCommandPoolMock.AssertNotCalled(t, "SubmitWithCategory", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	docMock.AssertNotCalled(t, "PersistDocumentState", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	docMock.AssertNotCalled(t, "MoveDocumentState", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestJobCategoryOf(t *testing.T) {
+	docState := contracts.DocumentState{DocumentType: contracts.SendCommand}
+	assert.Equal(t, task.CategoryRunCommand, jobCategoryOf(&docState))
+
+	docState.DocumentType = contracts.Association
+	docState.InstancePluginsInformation = []contracts.PluginState{{Name: appconfig.PluginNameAwsRunShellScript}}
+	assert.Equal(t, task.CategoryAssociation, jobCategoryOf(&docState))
+
+	docState.InstancePluginsInformation = append(docState.InstancePluginsInformation, contracts.PluginState{Name: appconfig.PluginNameAwsSoftwareInventory})
+	assert.Equal(t, task.CategoryInventory, jobCategoryOf(&docState))
+}
+
 func TestEngineProcessor_Cancel(t *testing.T) {
 	cancelCommandPoolMock := new(task.MockedPool)
 	ctx := context.NewMockDefault()
@@ -61,7 +94,7 @@ func TestEngineProcessor_Cancel(t *testing.T) {
 		return executerMock
 	}
 	cancelCommandPoolMock.On("Submit", ctx.Log(), "cancelMessageID", mock.Anything).Return(nil)
-	docMock := new(DocumentMgrMock)
+	docMock := new(docmanagermock.DocumentMgrMock)
 
 	processor := EngineProcessor{
 		executerCreator:   creator,
@@ -87,8 +120,28 @@ func TestEngineProcessor_Stop(t *testing.T) {
 		context:           ctx,
 		resChan:           resChan,
 	}
-	sendCommandPoolMock.On("ShutdownAndWait", mock.AnythingOfType("time.Duration")).Return(true)
-	cancelCommandPoolMock.On("ShutdownAndWait", mock.AnythingOfType("time.Duration")).Return(true)
+	sendCommandPoolMock.On("ShutdownAndWaitWithReason", mock.AnythingOfType("time.Duration"), task.CancelReasonAgentShutdown).Return(true)
+	cancelCommandPoolMock.On("ShutdownAndWaitWithReason", mock.AnythingOfType("time.Duration"), task.CancelReasonAgentShutdown).Return(true)
+	processor.Stop(contracts.StopTypeSoftStop)
+	sendCommandPoolMock.AssertExpectations(t)
+	cancelCommandPoolMock.AssertExpectations(t)
+}
+
+func TestEngineProcessor_StopForReboot(t *testing.T) {
+	sendCommandPoolMock := new(task.MockedPool)
+	cancelCommandPoolMock := new(task.MockedPool)
+	ctx := context.NewMockDefault()
+	resChan := make(chan contracts.DocumentResult)
+	processor := EngineProcessor{
+		sendCommandPool:   sendCommandPoolMock,
+		cancelCommandPool: cancelCommandPoolMock,
+		context:           ctx,
+		resChan:           resChan,
+	}
+	rebootPending = func() bool { return true }
+	defer func() { rebootPending = rebooter.RebootPending }()
+	sendCommandPoolMock.On("ShutdownAndWaitWithReason", mock.AnythingOfType("time.Duration"), task.CancelReasonReboot).Return(true)
+	cancelCommandPoolMock.On("ShutdownAndWaitWithReason", mock.AnythingOfType("time.Duration"), task.CancelReasonReboot).Return(true)
 	processor.Stop(contracts.StopTypeSoftStop)
 	sendCommandPoolMock.AssertExpectations(t)
 	cancelCommandPoolMock.AssertExpectations(t)
@@ -129,7 +182,7 @@ func TestProcessCommand(t *testing.T) {
 		}
 		close(statusChan)
 	}()
-	docMock := new(DocumentMgrMock)
+	docMock := new(docmanagermock.DocumentMgrMock)
 	docMock.On("MoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
 	docMock.On("RemoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent)
 	processCommand(ctx, creator, cancelFlag, resChan, &docState, docMock)
@@ -163,7 +216,7 @@ func TestProcessCommand_Shutdown(t *testing.T) {
 		//executer shutdown
 		close(statusChan)
 	}()
-	docMock := new(DocumentMgrMock)
+	docMock := new(docmanagermock.DocumentMgrMock)
 	docMock.On("MoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
 	processCommand(ctx, creator, cancelFlag, resChan, &docState, docMock)
 	executerMock.AssertExpectations(t)
@@ -181,7 +234,7 @@ func TestProcessCancelCommand_Success(t *testing.T) {
 	docState := contracts.DocumentState{}
 	docState.CancelInformation.CancelMessageID = "messageID"
 	sendCommandPoolMock.On("Cancel", "messageID").Return(true)
-	docMock := new(DocumentMgrMock)
+	docMock := new(docmanagermock.DocumentMgrMock)
 	docMock.On("MoveDocumentState", mock.Anything, "", "", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
 	docMock.On("RemoveDocumentState", mock.Anything, "", "", appconfig.DefaultLocationOfCurrent, mock.Anything)
 	processCancelCommand(ctx, sendCommandPoolMock, &docState, docMock)
@@ -190,27 +243,3 @@ func TestProcessCancelCommand_Success(t *testing.T) {
 	assert.Equal(t, docState.DocumentInformation.DocumentStatus, contracts.ResultStatusSuccess)
 
 }
-
-type DocumentMgrMock struct {
-	mock.Mock
-}
-
-func (m *DocumentMgrMock) MoveDocumentState(log log.T, fileName, instanceID, srcLocationFolder, dstLocationFolder string) {
-	m.Called(log, fileName, instanceID, srcLocationFolder, dstLocationFolder)
-	return
-}
-
-func (m *DocumentMgrMock) PersistDocumentState(log log.T, fileName, instanceID, locationFolder string, state contracts.DocumentState) {
-	m.Called(log, fileName, instanceID, locationFolder, state)
-	return
-}
-
-func (m *DocumentMgrMock) GetDocumentState(log log.T, fileName, instanceID, locationFolder string) contracts.DocumentState {
-	args := m.Called(log, fileName, instanceID, locationFolder)
-	return args.Get(0).(contracts.DocumentState)
-}
-
-func (m *DocumentMgrMock) RemoveDocumentState(log log.T, documentID, instanceID, location string) {
-	m.Called(log, documentID, instanceID, location)
-	return
-}
@@ -0,0 +1,59 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// nextStepIndex returns the index of the step that runs after the step i, which ended with status: the step its
+// onFailure or onSuccess jumps to, else its nextStep, else the step after it. len(plugins) ends the document.
+// The branching is validated when the document is parsed; an invalid branch continues with the step after i.
+func nextStepIndex(log log.T, plugins []contracts.PluginState, i int, status contracts.ResultStatus) int {
+	config := plugins[i].Configuration
+	branch, isNextStep := "", false
+	switch status {
+	case contracts.ResultStatusFailed, contracts.ResultStatusTimedOut:
+		if config.OnFailure == contracts.BranchAbort {
+			log.Infof("Step %v failed, the remaining steps are skipped", plugins[i].Id)
+			return len(plugins)
+		}
+		branch = config.OnFailure
+	case contracts.ResultStatusSuccess:
+		if config.OnSuccess == contracts.BranchExit {
+			log.Infof("Step %v succeeded, the remaining steps are skipped", plugins[i].Id)
+			return len(plugins)
+		}
+		branch = config.OnSuccess
+	case contracts.ResultStatusCancelled:
+		// the remaining steps are cancelled with the document
+		return i + 1
+	}
+	if _, ok := contracts.StepBranchTarget(branch, false); !ok {
+		branch, isNextStep = config.NextStep, true
+	}
+	name, ok := contracts.StepBranchTarget(branch, isNextStep)
+	if !ok {
+		return i + 1
+	}
+	for j := i + 1; j < len(plugins); j++ {
+		if plugins[j].Id == name {
+			log.Infof("Step %v ended with status %v, continuing with step %v", plugins[i].Id, status, name)
+			return j
+		}
+	}
+	log.Warnf("Step %v branches to %v, which isn't a step after it, continuing with the next step", plugins[i].Id, name)
+	return i + 1
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// branchingPlugin fails the steps whose id starts with fail and records the steps it runs
+type branchingPlugin struct {
+	ran *[]string
+}
+
+func (p branchingPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	*p.ran = append(*p.ran, config.PluginID)
+	if strings.HasPrefix(config.PluginID, "fail") {
+		output.MarkAsFailed(errors.New("step failed"))
+	} else {
+		output.MarkAsSucceeded()
+	}
+}
+
+// runBranchingSteps runs the steps and returns the ids of the steps executed and the results
+func runBranchingSteps(configs ...contracts.Configuration) ([]string, map[string]*contracts.PluginResult) {
+	var ran []string
+	pluginRegistry := PluginRegistry{
+		testPlugin1: PluginFactory(func(context.T) (T, error) { return branchingPlugin{ran: &ran}, nil }),
+	}
+	plugins := make([]contracts.PluginState, len(configs))
+	for i, config := range configs {
+		config.PluginName = testPlugin1
+		plugins[i] = contracts.PluginState{Name: testPlugin1, Id: config.PluginID, Configuration: config}
+	}
+	ch := make(chan contracts.PluginResult, len(plugins))
+	outputs := RunPlugins(context.NewMockDefault(), plugins, contracts.IOConfiguration{}, pluginRegistry, ch, task.NewChanneledCancelFlag())
+	return ran, outputs
+}
+
+func TestRunPluginsBranchesOnFailure(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+
+	ran, outputs := runBranchingSteps(
+		contracts.Configuration{PluginID: "fail-install", OnFailure: "step:rollback"},
+		contracts.Configuration{PluginID: "configure"},
+		contracts.Configuration{PluginID: "rollback", OnSuccess: contracts.BranchExit},
+		contracts.Configuration{PluginID: "report"},
+	)
+
+	assert.Equal(t, []string{"fail-install", "rollback"}, ran)
+	assert.Equal(t, contracts.ResultStatusFailed, outputs["fail-install"].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["configure"].Status)
+	assert.Contains(t, outputs["configure"].Output, "fail-install")
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["rollback"].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["report"].Status)
+}
+
+func TestRunPluginsBranchesOnSuccessAndNextStep(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+
+	ran, _ := runBranchingSteps(
+		contracts.Configuration{PluginID: "install", NextStep: "verify"},
+		contracts.Configuration{PluginID: "rollback"},
+		contracts.Configuration{PluginID: "verify"},
+		contracts.Configuration{PluginID: "fail-check", OnSuccess: "step:done", OnFailure: contracts.BranchContinue},
+		contracts.Configuration{PluginID: "done"},
+	)
+	assert.Equal(t, []string{"install", "verify", "fail-check", "done"}, ran)
+
+	ran, outputs := runBranchingSteps(
+		contracts.Configuration{PluginID: "fail-install", OnFailure: contracts.BranchAbort},
+		contracts.Configuration{PluginID: "configure"},
+	)
+	assert.Equal(t, []string{"fail-install"}, ran)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["configure"].Status)
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// finallyShutdownPollInterval is how often a finally step running after the cancellation of its document checks
+// whether the agent shuts down
+var finallyShutdownPollInterval = time.Second
+
+// finallyIndex returns the index of the first finally step of the document, len(plugins) when it has none.
+func finallyIndex(plugins []contracts.PluginState) int {
+	for i, plugin := range plugins {
+		if plugin.Configuration.Finally {
+			return i
+		}
+	}
+	return len(plugins)
+}
+
+// finallyCancelFlag returns the cancel flag of a finally step: it ignores the cancellation of the document, so that
+// the step runs after the document was cancelled, and is only set when the agent shuts down. stop releases the
+// flag once the step ended.
+func finallyCancelFlag(cancelFlag task.CancelFlag) (flag task.CancelFlag, stop func()) {
+	stepFlag := task.NewChanneledCancelFlag()
+	if cancelFlag.ShutDown() {
+		stepFlag.SetWithReason(task.ShutDown, cancelFlag.Reason())
+		return stepFlag, func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-task.Done(cancelFlag):
+		case <-done:
+			return
+		}
+		// the shutdown of a cancelled document doesn't wake up Wait again
+		ticker := time.NewTicker(finallyShutdownPollInterval)
+		defer ticker.Stop()
+		for {
+			switch cancelFlag.State() {
+			case task.ShutDown:
+				stepFlag.SetWithReason(task.ShutDown, cancelFlag.Reason())
+				return
+			case task.Completed:
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return stepFlag, func() { close(done) }
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPluginsRunsFinallyStepsAfterAbort(t *testing.T) {
+	ran, outputs := runBranchingSteps(
+		contracts.Configuration{PluginID: "fail-deploy", OnFailure: contracts.BranchAbort},
+		contracts.Configuration{PluginID: "verify"},
+		contracts.Configuration{PluginID: "fail-cleanup", Finally: true},
+		contracts.Configuration{PluginID: "enable-monitoring", Finally: true},
+	)
+
+	assert.Equal(t, []string{"fail-deploy", "fail-cleanup", "enable-monitoring"}, ran)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["verify"].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["enable-monitoring"].Status)
+}
+
+func TestRunPluginsRunsFinallyStepsAfterExit(t *testing.T) {
+	ran, _ := runBranchingSteps(
+		contracts.Configuration{PluginID: "check", OnSuccess: contracts.BranchExit},
+		contracts.Configuration{PluginID: "deploy"},
+		contracts.Configuration{PluginID: "cleanup", Finally: true},
+	)
+
+	assert.Equal(t, []string{"check", "cleanup"}, ran)
+}
+
+func TestRunPluginsRunsFinallyStepsAfterTimeouts(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{
+		testPlugin1: PluginFactory(func(context.T) (T, error) { return waitingPlugin{}, nil }),
+	}
+	// the document exceeded its executionTimeout, the finally step still runs until its own timeout
+	plugins := []contracts.PluginState{
+		{Name: testPlugin1, Id: "install", Configuration: contracts.Configuration{PluginID: "install", PluginName: testPlugin1, ExecutionTimeoutSeconds: 1},
+			Result: contracts.PluginResult{Status: contracts.ResultStatusSuccess, StartDateTime: time.Now().Add(-time.Minute)}},
+		{Name: testPlugin1, Id: "verify", Configuration: contracts.Configuration{PluginID: "verify", PluginName: testPlugin1, ExecutionTimeoutSeconds: 1}},
+		{Name: testPlugin1, Id: "cleanup", Configuration: contracts.Configuration{PluginID: "cleanup", PluginName: testPlugin1, ExecutionTimeoutSeconds: 1, TimeoutSeconds: 1, Finally: true}},
+	}
+
+	ch := make(chan contracts.PluginResult, len(plugins))
+	outputs := RunPlugins(context.NewMockDefault(), plugins, contracts.IOConfiguration{}, pluginRegistry, ch, task.NewChanneledCancelFlag())
+
+	assert.Equal(t, contracts.ResultStatusTimedOut, outputs["verify"].Status)
+	assert.Equal(t, contracts.ResultStatusTimedOut, outputs["cleanup"].Status)
+	assert.Contains(t, outputs["cleanup"].Output, "exceeded its timeoutSeconds of 1")
+}
+
+func TestFinallyCancelFlag(t *testing.T) {
+	origInterval := finallyShutdownPollInterval
+	finallyShutdownPollInterval = 10 * time.Millisecond
+	defer func() { finallyShutdownPollInterval = origInterval }()
+
+	cancelFlag := task.NewChanneledCancelFlag()
+	flag, stop := finallyCancelFlag(cancelFlag)
+	defer stop()
+
+	// the cancellation of the document doesn't apply
+	cancelFlag.Set(task.Canceled)
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, flag.Canceled())
+
+	// the shutdown of the agent does
+	cancelFlag.Set(task.ShutDown)
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, flag.ShutDown())
+	assert.Equal(t, task.CancelReasonAgentShutdown, flag.Reason())
+}
+
+func TestFinallyCancelFlagStopReleasesGoroutines(t *testing.T) {
+	cancelFlag := task.NewChanneledCancelFlag()
+	count := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		_, stop := finallyCancelFlag(cancelFlag)
+		stop()
+	}
+	assert.True(t, goroutinesReleased(count))
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// isolatedSetting is the plugin setting running each step of the plugin in its own worker process
+	isolatedSetting = "Isolated"
+	// onCrashSetting is the plugin setting of what happens to a step whose worker process crashes
+	onCrashSetting = "OnCrash"
+	// maxRestartsSetting is the plugin setting of the number of restarts of a step whose worker process crashes
+	maxRestartsSetting = "MaxRestarts"
+
+	// OnCrashFail fails the step whose worker process crashes
+	OnCrashFail = "Fail"
+	// OnCrashRestart runs the step again in a new worker process, up to MaxRestarts times
+	OnCrashRestart = "Restart"
+
+	defaultMaxRestarts = 1
+)
+
+// ProcessLimits are the resource limits of the worker process running a step, and of the processes it starts.
+// 0 doesn't limit the resource. The open files are limited on linux and macOS only.
+type ProcessLimits struct {
+	MaxMemoryMB   int
+	MaxCPUSeconds int
+	MaxOpenFiles  int
+	MaxProcesses  int
+}
+
+// ProcessRunner runs a step in a new worker process with the limits. It returns an error when the process
+// ends without the result of the step, e.g. it crashes or exceeds its limits.
+type ProcessRunner func(
+	context context.T,
+	pluginName string,
+	config contracts.Configuration,
+	cancelFlag task.CancelFlag,
+	ioConfig contracts.IOConfiguration,
+	limits ProcessLimits) (contracts.PluginResult, error)
+
+// PluginProcessRunner runs the steps of the isolated plugins, it's set by the document worker. The steps run
+// in process when it's nil, e.g. in the worker processes themselves or when the agent runs the documents.
+var PluginProcessRunner ProcessRunner
+
+// processLimits returns the limits of the worker processes of the plugin from its settings
+func processLimits(settings appconfig.PluginSettings) ProcessLimits {
+	return ProcessLimits{
+		MaxMemoryMB:   settings.Int("MaxMemoryMB", 0),
+		MaxCPUSeconds: settings.Int("MaxCPUSeconds", 0),
+		MaxOpenFiles:  settings.Int("MaxOpenFiles", 0),
+		MaxProcesses:  settings.Int("MaxProcesses", 0),
+	}
+}
+
+// runPluginIsolated runs the step in its own worker process when the settings of its plugin isolate it, e.g.
+// "Plugins": {"aws:runShellScript": {"Isolated": true, "MaxMemoryMB": 512, "OnCrash": "Restart"}}.
+// A crashing process fails the step, or runs it again with OnCrash Restart. It returns false when the step
+// must run in process.
+func runPluginIsolated(
+	context context.T,
+	pluginName string,
+	config contracts.Configuration,
+	cancelFlag task.CancelFlag,
+	ioConfig contracts.IOConfiguration) (res contracts.PluginResult, isolated bool) {
+
+	if PluginProcessRunner == nil {
+		return res, false
+	}
+	if _, registered := SSMPluginRegistry[pluginName]; !registered {
+		// the plugins of the programs embedding the engine run in their process
+		return res, false
+	}
+	settings := context.AppConfig().PluginSettings(pluginName)
+	if !settings.Bool(isolatedSetting, false) {
+		return res, false
+	}
+
+	log := context.Log()
+	limits := processLimits(settings)
+	restarts := 0
+	if strings.EqualFold(settings.String(onCrashSetting, OnCrashFail), OnCrashRestart) {
+		restarts = settings.Int(maxRestartsSetting, defaultMaxRestarts)
+	}
+	for run := 0; ; run++ {
+		start := time.Now()
+		var err error
+		if res, err = PluginProcessRunner(context, pluginName, config, cancelFlag, ioConfig, limits); err == nil {
+			return res, true
+		}
+		log.Errorf("the worker process of step %v crashed: %v", config.PluginID, err)
+		if run >= restarts || cancelFlag.Canceled() || cancelFlag.ShutDown() {
+			res = contracts.PluginResult{
+				Status:        contracts.ResultStatusFailed,
+				Code:          1,
+				Error:         fmt.Errorf("the worker process of step %v crashed: %v, check the document worker log", config.PluginID, err),
+				StartDateTime: start,
+				EndDateTime:   time.Now(),
+			}
+			return res, true
+		}
+		log.Infof("Restarting step %v in a new worker process", config.PluginID)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// isolatedContext returns a context whose agent configuration holds the plugin settings of testPlugin1
+func isolatedContext(settings appconfig.PluginSettings) context.T {
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(appconfig.SsmagentConfig{Plugins: appconfig.PluginsCfg{testPlugin1: settings}})
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	ctx.On("CurrentContext").Return([]string{})
+	return ctx
+}
+
+// setProcessRunnerMock replaces the runner of the worker processes with one returning the errors in turn, then a result
+func setProcessRunnerMock(errs ...error) (runs *[]ProcessLimits, restore func()) {
+	origRunner, origRegistry := PluginProcessRunner, SSMPluginRegistry
+	runs = &[]ProcessLimits{}
+	PluginProcessRunner = func(context context.T, pluginName string, config contracts.Configuration, cancelFlag task.CancelFlag, ioConfig contracts.IOConfiguration, limits ProcessLimits) (contracts.PluginResult, error) {
+		*runs = append(*runs, limits)
+		if len(*runs) <= len(errs) {
+			return contracts.PluginResult{}, errs[len(*runs)-1]
+		}
+		return contracts.PluginResult{Status: contracts.ResultStatusSuccess, StandardOutput: "isolated"}, nil
+	}
+	SSMPluginRegistry = PluginRegistry{testPlugin1: PluginFactory(func(context.T) (T, error) { return nil, nil })}
+	return runs, func() { PluginProcessRunner, SSMPluginRegistry = origRunner, origRegistry }
+}
+
+func runIsolatedStep(settings appconfig.PluginSettings) (contracts.PluginResult, bool) {
+	config := contracts.Configuration{PluginID: "install", PluginName: testPlugin1}
+	return runPluginIsolated(isolatedContext(settings), testPlugin1, config, task.NewChanneledCancelFlag(), contracts.IOConfiguration{})
+}
+
+func TestRunPluginIsolated(t *testing.T) {
+	runs, restore := setProcessRunnerMock()
+	defer restore()
+
+	res, isolated := runIsolatedStep(appconfig.PluginSettings{"Isolated": true, "MaxMemoryMB": float64(512), "MaxOpenFiles": "1024"})
+	assert.True(t, isolated)
+	assert.Equal(t, "isolated", res.StandardOutput)
+	assert.Equal(t, []ProcessLimits{{MaxMemoryMB: 512, MaxOpenFiles: 1024}}, *runs)
+
+	_, isolated = runIsolatedStep(appconfig.PluginSettings{"MaxMemoryMB": float64(512)})
+	assert.False(t, isolated, "the plugin isn't isolated")
+	assert.Len(t, *runs, 1)
+}
+
+func TestRunPluginIsolatedCrash(t *testing.T) {
+	runs, restore := setProcessRunnerMock(errors.New("exit status 2"))
+	defer restore()
+
+	res, isolated := runIsolatedStep(appconfig.PluginSettings{"Isolated": true})
+	assert.True(t, isolated)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Contains(t, res.Error.Error(), "exit status 2")
+	assert.Len(t, *runs, 1)
+}
+
+func TestRunPluginIsolatedRestart(t *testing.T) {
+	runs, restore := setProcessRunnerMock(errors.New("exit status 2"))
+	defer restore()
+
+	res, _ := runIsolatedStep(appconfig.PluginSettings{"Isolated": true, "OnCrash": "restart"})
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Len(t, *runs, 2)
+
+	*runs = nil
+	res, _ = runIsolatedStep(appconfig.PluginSettings{"Isolated": true, "OnCrash": "Restart", "MaxRestarts": float64(0)})
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Len(t, *runs, 1)
+}
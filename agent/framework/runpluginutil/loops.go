@@ -0,0 +1,132 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/parameters"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+)
+
+// runPluginLoop runs the step once, or repeats it with its loop: once for each item while it succeeds, or until
+// its conditions hold. Each iteration is retried like the step, and writes its output to its own orchestration
+// directory and S3 prefix. The iterations are recorded in the result of the step, which is the result of the last one.
+func runPluginLoop(
+	context context.T,
+	pluginFactory Factory,
+	pluginName string,
+	config contracts.Configuration,
+	cancelFlag task.CancelFlag,
+	ioConfig contracts.IOConfiguration) (res contracts.PluginResult) {
+
+	loop := config.Loop
+	if loop == nil {
+		return runPluginWithRetries(context, pluginFactory, pluginName, config, cancelFlag, ioConfig)
+	}
+
+	log := context.Log()
+	items, _ := loop.Items()
+	iterations := loop.Iterations()
+	met := false
+	var records []contracts.StepIteration
+	for index := 0; index < iterations; index++ {
+		if index > 0 && !waitUnlessCancelled(cancelFlag, time.Duration(loop.DelaySeconds)*time.Second) {
+			log.Infof("Step %v cancelled before iteration %v", config.PluginID, index+1)
+			res.Status = contracts.ResultStatusCancelled
+			break
+		}
+		var item interface{}
+		if items != nil {
+			item = items[index]
+		}
+
+		iterationConfig, iterationIOConfig, err := iterationConfiguration(config, ioConfig, item, index)
+		if err != nil {
+			res = contracts.PluginResult{Status: contracts.ResultStatusFailed, Code: 1, Error: err, StartDateTime: time.Now(), EndDateTime: time.Now()}
+		} else {
+			res = runPluginWithRetries(context, pluginFactory, pluginName, iterationConfig, cancelFlag, iterationIOConfig)
+		}
+		record := contracts.StepIteration{
+			Iteration:      index + 1,
+			Item:           item,
+			Status:         res.Status,
+			Code:           res.Code,
+			StartDateTime:  times.ToIso8601UTC(res.StartDateTime),
+			EndDateTime:    times.ToIso8601UTC(res.EndDateTime),
+			StandardOutput: res.StandardOutput,
+			StandardError:  res.StandardError,
+		}
+		if res.Error != nil {
+			record.Error = res.Error.Error()
+		}
+		records = append(records, record)
+		log.Infof("Iteration %v of %v of step %v ended with status %v", index+1, iterations, config.PluginID, res.Status)
+
+		if res.Status == contracts.ResultStatusCancelled || res.Status.IsReboot() {
+			break
+		}
+		if loop.Until != nil {
+			if met = loop.Until.IsMet(res.Code, res.StandardOutput); met {
+				break
+			}
+		} else if !res.Status.IsSuccess() {
+			break
+		}
+	}
+
+	if loop.Until != nil && !met && res.Status != contracts.ResultStatusCancelled && !res.Status.IsReboot() {
+		res.Status = contracts.ResultStatusFailed
+		res.Error = fmt.Errorf("the until conditions of step %v aren't met after %v iterations", config.PluginID, len(records))
+		log.Error(res.Error)
+	} else if loop.Until != nil && met && res.Status == contracts.ResultStatusFailed {
+		// the conditions expected the step to fail
+		res.Status = contracts.ResultStatusSuccess
+		res.Error = nil
+	}
+	res.Iterations = records
+	return res
+}
+
+// iterationConfiguration returns the configurations of an iteration of a step: {{ loop.item }} and {{ loop.index }}
+// replaced in its properties and settings, and its files and output in iteration-<n> directories and S3 prefixes,
+// so that the output of an iteration isn't appended to the output of the previous ones.
+func iterationConfiguration(
+	config contracts.Configuration,
+	ioConfig contracts.IOConfiguration,
+	item interface{},
+	index int) (contracts.Configuration, contracts.IOConfiguration, error) {
+
+	var err error
+	if config.Properties, err = parameters.ReplaceLoopVariables(config.Properties, item, index); err != nil {
+		return config, ioConfig, err
+	}
+	if config.Settings, err = parameters.ReplaceLoopVariables(config.Settings, item, index); err != nil {
+		return config, ioConfig, err
+	}
+	iterationDir := fmt.Sprintf("iteration-%d", index+1)
+	config.OrchestrationDirectory = filepath.Join(config.OrchestrationDirectory, iterationDir)
+	config.OutputS3KeyPrefix = fileutil.BuildS3Path(config.OutputS3KeyPrefix, iterationDir)
+	ioConfig.OrchestrationDirectory = filepath.Join(ioConfig.OrchestrationDirectory, iterationDir)
+	if ioConfig.OutputS3KeyPrefix != "" {
+		ioConfig.OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, iterationDir)
+	}
+	return config, ioConfig, nil
+}
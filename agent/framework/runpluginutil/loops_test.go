@@ -0,0 +1,108 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// loopPlugin records the configurations it runs with, it exits with the codes in turn
+type loopPlugin struct {
+	configs *[]contracts.Configuration
+	codes   []int
+}
+
+func (p loopPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	*p.configs = append(*p.configs, config)
+	code := 0
+	if run := len(*p.configs) - 1; run < len(p.codes) {
+		code = p.codes[run]
+	}
+	output.AppendInfof("run %d", len(*p.configs))
+	output.SetExitCode(code)
+	if code == 0 {
+		output.MarkAsSucceeded()
+	} else {
+		output.MarkAsFailed(fmt.Errorf("exit code %d", code))
+	}
+}
+
+func runLoopStep(t *testing.T, loop *contracts.StepLoop, codes []int) ([]contracts.Configuration, contracts.PluginResult) {
+	orchestrationDir, err := ioutil.TempDir("", "loops")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	var configs []contracts.Configuration
+	factory := PluginFactory(func(context.T) (T, error) { return loopPlugin{configs: &configs, codes: codes}, nil })
+	config := contracts.Configuration{
+		PluginID:               "install",
+		PluginName:             testPlugin1,
+		Properties:             map[string]interface{}{"runCommand": "yum install -y {{ loop.item }}"},
+		OrchestrationDirectory: "orchestration/install",
+		OutputS3KeyPrefix:      "prefix/install",
+		Loop:                   loop,
+	}
+	res := runPluginLoop(context.NewMockDefault(), factory, testPlugin1, config, task.NewChanneledCancelFlag(), contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir})
+	return configs, res
+}
+
+func TestRunPluginLoopForEach(t *testing.T) {
+	configs, res := runLoopStep(t, &contracts.StepLoop{ForEach: []string{"git", "nginx"}}, nil)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Len(t, configs, 2)
+	assert.Equal(t, map[string]interface{}{"runCommand": "yum install -y nginx"}, configs[1].Properties)
+	assert.Equal(t, "prefix/install/iteration-2", configs[1].OutputS3KeyPrefix)
+	assert.Len(t, res.Iterations, 2)
+	assert.Equal(t, "git", res.Iterations[0].Item)
+	assert.Contains(t, res.Iterations[0].StandardOutput, "run 1")
+	assert.Contains(t, res.StandardOutput, "run 2")
+	assert.NotContains(t, res.StandardOutput, "run 1")
+}
+
+func TestRunPluginLoopForEachStopsAtFailure(t *testing.T) {
+	configs, res := runLoopStep(t, &contracts.StepLoop{ForEach: []interface{}{"git", "nginx", "curl"}}, []int{0, 1})
+
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Len(t, configs, 2)
+	assert.Len(t, res.Iterations, 2)
+	assert.Equal(t, 1, res.Iterations[1].Code)
+}
+
+func TestRunPluginLoopUntil(t *testing.T) {
+	exitCode := 0
+	configs, res := runLoopStep(t, &contracts.StepLoop{Until: &contracts.StepLoopUntil{ExitCode: &exitCode}, MaxIterations: 5}, []int{1, 1, 0})
+
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Len(t, configs, 3)
+	assert.Len(t, res.Iterations, 3)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Iterations[0].Status)
+}
+
+func TestRunPluginLoopUntilNotMet(t *testing.T) {
+	configs, res := runLoopStep(t, &contracts.StepLoop{Until: &contracts.StepLoopUntil{StdoutContains: "ready"}, MaxIterations: 3}, nil)
+
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Error(t, res.Error)
+	assert.Len(t, configs, 3)
+}
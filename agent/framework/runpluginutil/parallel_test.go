@@ -0,0 +1,74 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrentPlugin records the largest number of steps running at the same time
+type concurrentPlugin struct {
+	m       *sync.Mutex
+	running *int
+	max     *int
+}
+
+func (p concurrentPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	p.m.Lock()
+	*p.running++
+	if *p.running > *p.max {
+		*p.max = *p.running
+	}
+	p.m.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	p.m.Lock()
+	*p.running--
+	p.m.Unlock()
+	output.MarkAsSucceeded()
+}
+
+func TestRunPluginsRunsParallelStepsWithinLimit(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	var m sync.Mutex
+	running, max := 0, 0
+	pluginRegistry := PluginRegistry{
+		testPlugin1: PluginFactory(func(context.T) (T, error) {
+			return concurrentPlugin{m: &m, running: &running, max: &max}, nil
+		}),
+	}
+	ids := []string{"install1", "install2", "install3", "install4", "install5", "verify"}
+	plugins := make([]contracts.PluginState, len(ids))
+	for i, id := range ids {
+		config := contracts.Configuration{PluginID: id, PluginName: testPlugin1, Parallel: id != "verify", MaxParallelSteps: 2}
+		plugins[i] = contracts.PluginState{Name: testPlugin1, Id: id, Configuration: config}
+	}
+
+	ch := make(chan contracts.PluginResult, len(plugins))
+	outputs := RunPlugins(context.NewMockDefault(), plugins, contracts.IOConfiguration{}, pluginRegistry, ch, task.NewChanneledCancelFlag())
+
+	assert.Equal(t, 2, max)
+	assert.Len(t, ch, len(plugins))
+	for _, id := range ids {
+		assert.Equal(t, contracts.ResultStatusSuccess, outputs[id].Status)
+	}
+}
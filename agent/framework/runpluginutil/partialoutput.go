@@ -0,0 +1,48 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+)
+
+// withPartialOutput returns the IO configuration of a step sending the output of the step, while it runs, with
+// the InProgress status every Agent.PartialOutputIntervalSeconds, so that the command reports it before the
+// step ends.
+func withPartialOutput(
+	context context.T,
+	ioConfig contracts.IOConfiguration,
+	output contracts.PluginResult,
+	resChan chan contracts.PluginResult) contracts.IOConfiguration {
+
+	seconds := context.AppConfig().Agent.PartialOutputIntervalSeconds
+	if seconds <= 0 {
+		return ioConfig
+	}
+	ioConfig.PartialOutputInterval = time.Duration(seconds) * time.Second
+	ioConfig.PartialOutput = func(stdout, stderr string) {
+		partial := output
+		partial.Status = contracts.ResultStatusInProgress
+		partial.Output = iohandler.TruncateOutput(stdout, stderr, iohandler.MaximumPluginOutputSize)
+		partial.StandardOutput = stdout
+		partial.StandardError = stderr
+		context.Log().Debugf("Sending the partial output of plugin %v", output.PluginID)
+		resChan <- partial
+	}
+	return ioConfig
+}
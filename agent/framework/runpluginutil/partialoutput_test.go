@@ -0,0 +1,54 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWithPartialOutput(t *testing.T) {
+	ctx := new(context.Mock)
+	config := appconfig.SsmagentConfig{}
+	config.Agent.PartialOutputIntervalSeconds = 30
+	ctx.On("AppConfig").Return(config)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+
+	resChan := make(chan contracts.PluginResult, 1)
+	output := contracts.PluginResult{PluginID: "install", PluginName: "aws:runShellScript", Status: contracts.ResultStatusNotStarted}
+	ioConfig := withPartialOutput(ctx, contracts.IOConfiguration{OrchestrationDirectory: "orchestration"}, output, resChan)
+
+	assert.Equal(t, "orchestration", ioConfig.OrchestrationDirectory)
+	assert.Equal(t, 30*time.Second, ioConfig.PartialOutputInterval)
+	ioConfig.PartialOutput("installing", "warning")
+	partial := <-resChan
+	assert.Equal(t, "install", partial.PluginID)
+	assert.Equal(t, contracts.ResultStatusInProgress, partial.Status)
+	assert.Equal(t, "installing", partial.StandardOutput)
+	assert.Equal(t, "warning", partial.StandardError)
+	assert.Equal(t, "installing\n----------ERROR-------\nwarning", partial.Output)
+}
+
+func TestWithPartialOutputDisabled(t *testing.T) {
+	ioConfig := withPartialOutput(context.NewMockDefault(), contracts.IOConfiguration{}, contracts.PluginResult{}, nil)
+	assert.Nil(t, ioConfig.PartialOutput)
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// PlannedStep is a step of a document as it would run on the instance, without running it
+type PlannedStep struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	// Operation is execute, skip or fail, evaluated with the plugins and preconditions of the instance;
+	// Reason tells why the step is skipped or fails
+	Operation string `json:"operation"`
+	Reason    string `json:"reason,omitempty"`
+	// Inputs and Settings have the parameters of the document replaced, the references to the outputs of
+	// the steps before are only replaced when the steps run
+	Inputs        interface{}         `json:"inputs,omitempty"`
+	Settings      interface{}         `json:"settings,omitempty"`
+	Preconditions map[string][]string `json:"precondition,omitempty"`
+
+	OnFailure        string                 `json:"onFailure,omitempty"`
+	OnSuccess        string                 `json:"onSuccess,omitempty"`
+	NextStep         string                 `json:"nextStep,omitempty"`
+	MaxAttempts      int                    `json:"maxAttempts,omitempty"`
+	Backoff          *contracts.StepBackoff `json:"backoff,omitempty"`
+	Loop             *contracts.StepLoop    `json:"loop,omitempty"`
+	Parallel         bool                   `json:"parallel,omitempty"`
+	MaxParallelSteps int                    `json:"maxParallelSteps,omitempty"`
+	TimeoutSeconds   int                    `json:"timeoutSeconds,omitempty"`
+	Finally          bool                   `json:"finally,omitempty"`
+}
+
+// PlanSteps returns the steps of a parsed document as RunPlugins would run them with the plugins of
+// pluginRegistry, without running them. The operations of steps a branch would skip are evaluated anyway.
+func PlanSteps(log log.T, plugins []contracts.PluginState, pluginRegistry PluginRegistry) []PlannedStep {
+	steps := make([]PlannedStep, 0, len(plugins))
+	for _, pluginState := range plugins {
+		config := pluginState.Configuration
+		operation, reason := stepOperation(log, pluginState, pluginRegistry)
+		steps = append(steps, PlannedStep{
+			Name:             pluginState.Id,
+			Action:           pluginState.Name,
+			Operation:        operation,
+			Reason:           reason,
+			Inputs:           config.Properties,
+			Settings:         config.Settings,
+			Preconditions:    config.Preconditions,
+			OnFailure:        config.OnFailure,
+			OnSuccess:        config.OnSuccess,
+			NextStep:         config.NextStep,
+			MaxAttempts:      config.MaxAttempts,
+			Backoff:          config.Backoff,
+			Loop:             config.Loop,
+			Parallel:         config.Parallel,
+			MaxParallelSteps: config.MaxParallelSteps,
+			TimeoutSeconds:   config.TimeoutSeconds,
+			Finally:          config.Finally,
+		})
+	}
+	return steps
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanSteps(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	setPreconditionVariablesMock()
+	defer restorePreconditionVariables()
+
+	ran := false
+	pluginRegistry := PluginRegistry{
+		testPlugin1: PluginFactory(func(context.T) (T, error) {
+			ran = true
+			return nil, nil
+		}),
+	}
+	inputs := map[string]interface{}{"runCommand": []interface{}{"echo hello"}}
+	plugins := []contracts.PluginState{
+		{Id: "run", Name: testPlugin1, Configuration: contracts.Configuration{Properties: inputs, OnFailure: "step:report", TimeoutSeconds: 60}},
+		{Id: "unknown", Name: testUnknownPlugin},
+		{Id: "staging", Name: testPlugin1, Configuration: contracts.Configuration{
+			IsPreconditionEnabled: true,
+			Preconditions:         map[string][]string{"StringEquals": {"tag:Environment", "staging"}},
+		}},
+	}
+
+	steps := PlanSteps(log.NewMockLog(), plugins, pluginRegistry)
+
+	assert.False(t, ran)
+	assert.Len(t, steps, 3)
+	assert.Equal(t, PlannedStep{Name: "run", Action: testPlugin1, Operation: executeStep, Inputs: inputs, OnFailure: "step:report", TimeoutSeconds: 60}, steps[0])
+	assert.Equal(t, failStep, steps[1].Operation)
+	assert.Contains(t, steps[1].Reason, "not supported by this version of ssm agent")
+	assert.Equal(t, skipStep, steps[2].Operation)
+	assert.Contains(t, steps[2].Reason, "skipped")
+}
@@ -0,0 +1,159 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+)
+
+// the variables of the preconditions besides tag:<key> and env:<name>
+const (
+	platformTypeVariable    = "platformType"
+	platformNameVariable    = "platformName"
+	platformVersionVariable = "platformVersion"
+	tagVariablePrefix       = "tag:"
+	envVariablePrefix       = "env:"
+)
+
+// Assign the sources of the variables to global variables to allow unittest to override
+var platformType = platform.PlatformType
+var platformName = platform.PlatformName
+var platformVersion = platform.PlatformVersion
+var instanceTag = platform.InstanceTag
+var lookupEnv = os.LookupEnv
+var fileExists = fileutil.Exists
+
+// Evaluate precondition and return precondition result and unrecognized preconditions (if any)
+// The preconditions are:
+// "StringEquals" and "StringNotEquals": [variable, value], in any order,
+// "VersionGreaterThanOrEqual" and "VersionLessThan": [variable, version], in any order,
+// "FileExists" and "FileNotExists": [path, ...],
+// the variables are platformType, platformName, platformVersion, tag:<key> and env:<name>.
+func evaluatePreconditions(
+	log log.T,
+	preconditions map[string][]string,
+) (bool, []string) {
+
+	var isAllowed = true
+	var unrecognizedPreconditionList []string
+
+	for key, value := range preconditions {
+		var allowed bool
+		var recognized bool
+		switch key {
+		case "StringEquals", "StringNotEquals":
+			var variable, operand string
+			if variable, operand, recognized = splitPreconditionOperands(value); recognized {
+				actual, found := preconditionVariable(log, variable)
+				allowed = found && stringEquals(variable, actual, operand)
+				if key == "StringNotEquals" {
+					allowed = !allowed
+				}
+			}
+		case "VersionGreaterThanOrEqual", "VersionLessThan":
+			var variable, operand string
+			if variable, operand, recognized = splitPreconditionOperands(value); recognized {
+				actual, found := preconditionVariable(log, variable)
+				comparison, err := updateutil.VersionCompare(actual, operand)
+				if !found || err != nil {
+					log.Debugf("Can't compare %v %q to version %v: %v", variable, actual, operand, err)
+				} else if key == "VersionLessThan" {
+					allowed = comparison < 0
+				} else {
+					allowed = comparison >= 0
+				}
+			}
+		case "FileExists", "FileNotExists":
+			recognized = len(value) > 0
+			allowed = true
+			for _, path := range value {
+				allowed = allowed && fileExists(path) == (key == "FileExists")
+			}
+		}
+
+		if !recognized {
+			// mark for unrecognizedPrecondition (which is a form of failure)
+			unrecognizedPreconditionList = append(unrecognizedPreconditionList, fmt.Sprintf("\"%s\": %v", key, value))
+		} else if !allowed {
+			// if precondition doesn't match, mark step for skip
+			log.Debugf("Precondition \"%s\": %v isn't satisfied", key, value)
+			isAllowed = false
+		}
+	}
+
+	return isAllowed, unrecognizedPreconditionList
+}
+
+// splitPreconditionOperands returns the variable and the value of a precondition with 2 operands, in any order,
+// i.e. both "StringEquals": ["platformType", "Windows"] and "StringEquals": ["Windows", "platformType"] are valid;
+// ok is false unless exactly one of the operands is a variable
+func splitPreconditionOperands(operands []string) (variable string, value string, ok bool) {
+	if len(operands) != 2 || isPreconditionVariable(operands[0]) == isPreconditionVariable(operands[1]) {
+		return "", "", false
+	}
+	if isPreconditionVariable(operands[0]) {
+		return operands[0], operands[1], true
+	}
+	return operands[1], operands[0], true
+}
+
+// isPreconditionVariable checks if an operand of a precondition is a variable
+func isPreconditionVariable(operand string) bool {
+	switch operand {
+	case platformTypeVariable, platformNameVariable, platformVersionVariable:
+		return true
+	}
+	return (strings.HasPrefix(operand, tagVariablePrefix) && len(operand) > len(tagVariablePrefix)) ||
+		(strings.HasPrefix(operand, envVariablePrefix) && len(operand) > len(envVariablePrefix))
+}
+
+// preconditionVariable returns the value of a variable on this instance, found is false when the instance has no
+// such tag or environment variable, or the value isn't available
+func preconditionVariable(log log.T, variable string) (value string, found bool) {
+	var err error
+	switch {
+	case variable == platformTypeVariable:
+		value, err = platformType(log)
+	case variable == platformNameVariable:
+		value, err = platformName(log)
+	case variable == platformVersionVariable:
+		value, err = platformVersion(log)
+	case strings.HasPrefix(variable, tagVariablePrefix):
+		value, err = instanceTag(strings.TrimPrefix(variable, tagVariablePrefix))
+	case strings.HasPrefix(variable, envVariablePrefix):
+		value, found = lookupEnv(strings.TrimPrefix(variable, envVariablePrefix))
+		return value, found
+	}
+	if err != nil {
+		log.Debugf("Failed to get %v for the preconditions: %v", variable, err)
+		return "", false
+	}
+	log.Debugf("%v of this instance = %s", variable, value)
+	return value, true
+}
+
+// stringEquals compares the value of a variable, the platform type regardless of its case
+func stringEquals(variable string, actual string, value string) bool {
+	if variable == platformTypeVariable {
+		return strings.EqualFold(actual, value)
+	}
+	return actual == value
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var origPlatformName, origPlatformVersion, origInstanceTag, origLookupEnv, origFileExists = platformName, platformVersion, instanceTag, lookupEnv, fileExists
+
+func setPreconditionVariablesMock() {
+	platformName = func(log.T) (string, error) { return "Amazon Linux", nil }
+	platformVersion = func(log.T) (string, error) { return "2.0.20180622", nil }
+	instanceTag = func(key string) (string, error) {
+		if key == "Environment" {
+			return "production", nil
+		}
+		return "", errors.New("404 - Not Found")
+	}
+	lookupEnv = func(name string) (string, bool) {
+		if name == "DEPLOYMENT" {
+			return "blue", true
+		}
+		return "", false
+	}
+	fileExists = func(path string) bool { return path == "/etc/app.conf" }
+}
+
+func restorePreconditionVariables() {
+	platformName, platformVersion, instanceTag, lookupEnv, fileExists = origPlatformName, origPlatformVersion, origInstanceTag, origLookupEnv, origFileExists
+}
+
+func TestEvaluatePreconditions(t *testing.T) {
+	setPreconditionVariablesMock()
+	defer restorePreconditionVariables()
+
+	testCases := []struct {
+		preconditions map[string][]string
+		allowed       bool
+	}{
+		{map[string][]string{"StringEquals": {"tag:Environment", "production"}}, true},
+		{map[string][]string{"StringEquals": {"staging", "tag:Environment"}}, false},
+		{map[string][]string{"StringEquals": {"tag:Owner", "me"}}, false},
+		{map[string][]string{"StringNotEquals": {"tag:Owner", "me"}}, true},
+		{map[string][]string{"StringEquals": {"env:DEPLOYMENT", "blue"}}, true},
+		{map[string][]string{"StringNotEquals": {"env:DEPLOYMENT", "blue"}}, false},
+		{map[string][]string{"StringEquals": {"platformName", "Amazon Linux"}}, true},
+		{map[string][]string{"FileExists": {"/etc/app.conf"}}, true},
+		{map[string][]string{"FileExists": {"/etc/app.conf", "/etc/other.conf"}}, false},
+		{map[string][]string{"FileNotExists": {"/etc/other.conf"}}, true},
+		{map[string][]string{"VersionGreaterThanOrEqual": {"platformVersion", "2"}, "VersionLessThan": {"platformVersion", "3"}}, true},
+		{map[string][]string{"VersionGreaterThanOrEqual": {"platformVersion", "2.1"}}, false},
+		{map[string][]string{"VersionLessThan": {"env:UNSET", "3"}}, false},
+		{map[string][]string{"StringEquals": {"env:DEPLOYMENT", "blue"}, "FileExists": {"/etc/other.conf"}}, false},
+	}
+
+	for _, testCase := range testCases {
+		allowed, unrecognized := evaluatePreconditions(log.NewMockLog(), testCase.preconditions)
+		assert.Equal(t, testCase.allowed, allowed, "%v", testCase.preconditions)
+		assert.Empty(t, unrecognized, "%v", testCase.preconditions)
+	}
+}
+
+func TestEvaluatePreconditionsUnrecognized(t *testing.T) {
+	setPreconditionVariablesMock()
+	defer restorePreconditionVariables()
+
+	for _, preconditions := range []map[string][]string{
+		{"StringEquals": {"tag:", "production"}},
+		{"StringNotEquals": {"env:DEPLOYMENT", "env:OTHER"}},
+		{"VersionLessThan": {"platformVersion"}},
+		{"FileExists": {}},
+		{"StringLike": {"platformName", "Amazon*"}},
+	} {
+		_, unrecognized := evaluatePreconditions(log.NewMockLog(), preconditions)
+		assert.Len(t, unrecognized, 1, "%v", preconditions)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+)
+
+// cancelPollInterval is how often the wait between the attempts of a step checks the cancellation of the document
+var cancelPollInterval = time.Second
+
+// runPluginWithRetries runs the step up to its maxAttempts times while it fails or times out, waiting for its
+// backoff between the attempts. Only the step is retried, the attempts are recorded in its result.
+func runPluginWithRetries(
+	context context.T,
+	pluginFactory Factory,
+	pluginName string,
+	config contracts.Configuration,
+	cancelFlag task.CancelFlag,
+	ioConfig contracts.IOConfiguration) (res contracts.PluginResult) {
+
+	if config.MaxAttempts <= 1 {
+		return runPlugin(context, pluginFactory, pluginName, config, cancelFlag, ioConfig)
+	}
+
+	log := context.Log()
+	var attempts []contracts.StepAttempt
+	for attempt := 1; ; attempt++ {
+		res = runPlugin(context, pluginFactory, pluginName, config, cancelFlag, ioConfig)
+		record := contracts.StepAttempt{
+			Attempt:       attempt,
+			Status:        res.Status,
+			Code:          res.Code,
+			StartDateTime: times.ToIso8601UTC(res.StartDateTime),
+			EndDateTime:   times.ToIso8601UTC(res.EndDateTime),
+		}
+		if res.Error != nil {
+			record.Error = res.Error.Error()
+		}
+		attempts = append(attempts, record)
+
+		if attempt >= config.MaxAttempts || (res.Status != contracts.ResultStatusFailed && res.Status != contracts.ResultStatusTimedOut) {
+			break
+		}
+		delay := config.Backoff.Delay(attempt)
+		log.Infof("Attempt %v of %v of step %v ended with status %v, retrying in %v", attempt, config.MaxAttempts, config.PluginID, res.Status, delay)
+		if !waitUnlessCancelled(cancelFlag, delay) {
+			log.Infof("Step %v cancelled before attempt %v", config.PluginID, attempt+1)
+			break
+		}
+	}
+	res.Attempts = attempts
+	return
+}
+
+// waitUnlessCancelled waits for delay, it returns false as soon as the document is cancelled or shut down
+func waitUnlessCancelled(cancelFlag task.CancelFlag, delay time.Duration) bool {
+	deadline := time.Now().Add(delay)
+	for {
+		if cancelFlag.Canceled() || cancelFlag.ShutDown() {
+			return false
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return true
+		}
+		if remaining > cancelPollInterval {
+			remaining = cancelPollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
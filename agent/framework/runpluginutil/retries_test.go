@@ -0,0 +1,87 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyPlugin fails its first failures runs
+type flakyPlugin struct {
+	runs     *int
+	failures int
+}
+
+func (p flakyPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	*p.runs++
+	if *p.runs <= p.failures {
+		output.MarkAsFailed(errors.New("transient failure"))
+	} else {
+		output.MarkAsSucceeded()
+	}
+}
+
+func runFlakyStep(failures int, config contracts.Configuration, cancelFlag task.CancelFlag) (int, contracts.PluginResult) {
+	runs := 0
+	factory := PluginFactory(func(context.T) (T, error) { return flakyPlugin{runs: &runs, failures: failures}, nil })
+	config.PluginID, config.PluginName = "flaky", testPlugin1
+	res := runPluginWithRetries(context.NewMockDefault(), factory, testPlugin1, config, cancelFlag, contracts.IOConfiguration{})
+	return runs, res
+}
+
+func TestRunPluginWithRetriesUntilSuccess(t *testing.T) {
+	runs, res := runFlakyStep(2, contracts.Configuration{MaxAttempts: 3, Backoff: &contracts.StepBackoff{}}, task.NewChanneledCancelFlag())
+
+	assert.Equal(t, 3, runs)
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Len(t, res.Attempts, 3)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Attempts[0].Status)
+	assert.Equal(t, "", res.Attempts[0].Error)
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Attempts[2].Status)
+}
+
+func TestRunPluginWithRetriesStopsAtMaxAttempts(t *testing.T) {
+	runs, res := runFlakyStep(5, contracts.Configuration{MaxAttempts: 2, Backoff: &contracts.StepBackoff{}}, task.NewChanneledCancelFlag())
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Len(t, res.Attempts, 2)
+
+	// a step without maxAttempts runs once and records no attempts
+	runs, res = runFlakyStep(5, contracts.Configuration{}, task.NewChanneledCancelFlag())
+	assert.Equal(t, 1, runs)
+	assert.Empty(t, res.Attempts)
+}
+
+func TestRunPluginWithRetriesStopsWhenCancelled(t *testing.T) {
+	savedInterval := cancelPollInterval
+	cancelPollInterval = 10 * time.Millisecond
+	defer func() { cancelPollInterval = savedInterval }()
+	cancelFlag := task.NewChanneledCancelFlag()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancelFlag.Set(task.Canceled)
+	}()
+
+	runs, res := runFlakyStep(5, contracts.Configuration{MaxAttempts: 3, Backoff: &contracts.StepBackoff{InitialSeconds: 60, MaxSeconds: 60}}, cancelFlag)
+	assert.Equal(t, 1, runs)
+	assert.Len(t, res.Attempts, 1)
+}
@@ -15,18 +15,20 @@
 package runpluginutil
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/etw"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
-	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
@@ -35,19 +37,35 @@ const (
 	executeStep string = "execute"
 	skipStep    string = "skip"
 	failStep    string = "fail"
+	// timeOutStep doesn't run a step reached after the executionTimeout of its document
+	timeOutStep string = "timeout"
 )
 
+// T is the interface of the plugins running the steps of the documents. Programs embedding the document
+// engine implement it for their own actions, the plugin reports its result through the output.
 type T interface {
 	Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler)
 }
 
+// Factory creates a plugin for each step it runs.
 type Factory interface {
 	Create(context context.T) (T, error)
 }
 
+// PluginFactory is a function creating a plugin, it lets a function be used as a Factory.
+type PluginFactory func(context context.T) (T, error)
+
+// Create calls the function.
+func (f PluginFactory) Create(context context.T) (T, error) {
+	return f(context)
+}
+
 // PluginRegistry stores a set of plugins (both worker and long running plugins), indexed by ID.
+// The plugins registered under names unknown to the agent are run too, which lets the programs embedding
+// the document engine add their own actions.
 type PluginRegistry map[string]Factory
 
+// SSMPluginRegistry is the registry of the plugins of the agent, used by the executers created without one
 var SSMPluginRegistry PluginRegistry
 
 // allPlugins is the list of all known plugins.
@@ -61,6 +79,7 @@ var allPlugins = map[string]struct{}{
 	appconfig.PluginNameAwsPowerShellModule:    {},
 	appconfig.PluginNameAwsRunPowerShellScript: {},
 	appconfig.PluginNameAwsRunShellScript:      {},
+	appconfig.PluginNameAwsRunPythonScript:     {},
 	appconfig.PluginNameAwsSoftwareInventory:   {},
 	appconfig.PluginNameCloudWatch:             {},
 	appconfig.PluginNameConfigureDocker:        {},
@@ -68,6 +87,18 @@ var allPlugins = map[string]struct{}{
 	appconfig.PluginNameDomainJoin:             {},
 	appconfig.PluginEC2ConfigUpdate:            {},
 	appconfig.PluginNameRefreshAssociation:     {},
+	appconfig.PluginNameManageAgentConfig:      {},
+	appconfig.PluginNameQuarantine:             {},
+	appconfig.PluginNameParseTestReport:        {},
+	appconfig.PluginNameManageRegistry:         {},
+	appconfig.PluginNameManageAuthorizedKeys:   {},
+	appconfig.PluginNameGrantTemporaryAdmin:    {},
+	appconfig.PluginNameConfigureTimeSync:      {},
+	appconfig.PluginNameRenameHost:             {},
+	appconfig.PluginNameDiagnoseNetwork:        {},
+	appconfig.PluginNameManageDiskSpace:        {},
+	appconfig.PluginNameManageProcesses:        {},
+	appconfig.PluginNameRenderTemplate:         {},
 	appconfig.PluginDownloadContent:            {},
 	appconfig.PluginRunDocument:                {},
 }
@@ -90,109 +121,242 @@ func RunPlugins(
 
 	pluginOutputs = make(map[string]*contracts.PluginResult)
 
-	for _, pluginState := range plugins {
-		pluginID := pluginState.Id     // the identifier of the plugin
-		pluginName := pluginState.Name // the name of the plugin
-		pluginOutput := pluginState.Result
-		pluginOutput.PluginID = pluginID
-		pluginOutput.PluginName = pluginName
-		pluginOutputs[pluginID] = &pluginOutput
-		switch pluginOutput.Status {
-		//TODO properly initialize the plugin status
-		case "":
-			context.Log().Debugf("plugin - %v has empty state, initialize as NotStarted",
-				pluginName)
-			pluginOutput.StartDateTime = time.Now()
-			pluginOutput.Status = contracts.ResultStatusNotStarted
-
-		case contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
-			context.Log().Debugf("plugin - %v status %v",
-				pluginName,
-				pluginOutput.Status)
-			pluginOutput.StartDateTime = time.Now()
-
-		case contracts.ResultStatusSuccessAndReboot:
-			context.Log().Debugf("plugin - %v just experienced reboot, reset to InProgress...",
-				pluginName)
-			pluginOutput.Status = contracts.ResultStatusInProgress
-
-		default:
-			context.Log().Debugf("plugin - %v already executed, skipping...",
-				pluginName)
-			continue
+	// the steps before branchTarget that haven't run are skipped, a step branched over them
+	branchTarget, branchedBy := 0, ""
+	// the steps end at the executionTimeout of the document, if any, besides their own timeoutSeconds
+	deadline := documentDeadline(plugins)
+	// the finally steps run whatever happened to the main steps, the branching can't skip them
+	mainSteps := plugins[:finallyIndex(plugins)]
+	for i := 0; i < len(plugins); {
+		// consecutive parallel steps run together
+		end := i + 1
+		if plugins[i].Configuration.Parallel {
+			for end < len(plugins) && plugins[end].Configuration.Parallel {
+				end++
+			}
+		}
+		skipMessage := func(j int) string {
+			if j < branchTarget {
+				return fmt.Sprintf("Step %v skipped by the branching of step %v", plugins[j].Id, branchedBy)
+			}
+			return ""
 		}
 
-		context.Log().Debugf("Executing plugin - %v", pluginName)
-
-		// populate plugin start time and status
-		configuration := pluginState.Configuration
-
-		if ioConfig.OutputS3BucketName != "" {
-			pluginOutputs[pluginID].OutputS3BucketName = ioConfig.OutputS3BucketName
-			if ioConfig.OutputS3KeyPrefix != "" {
-				pluginOutputs[pluginID].OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, pluginName)
+		results := make([]stepResult, end-i)
+		if end == i+1 && plugins[i].Configuration.Finally {
+			// the cancellation and the executionTimeout of the document don't apply to the finally steps
+			finallyFlag, stopFinallyFlag := finallyCancelFlag(cancelFlag)
+			results[0].output, results[0].ran, results[0].reboot = runStep(context, plugins[i], ioConfig, pluginRegistry, resChan, finallyFlag, pluginOutputs, skipMessage(i), time.Time{})
+			stopFinallyFlag()
+		} else if end == i+1 {
+			results[0].output, results[0].ran, results[0].reboot = runStep(context, plugins[i], ioConfig, pluginRegistry, resChan, cancelFlag, pluginOutputs, skipMessage(i), deadline)
+		} else {
+			limit := plugins[i].Configuration.MaxParallelSteps
+			if limit <= 0 {
+				limit = contracts.DefaultMaxParallelSteps
+			}
+			context.Log().Infof("Running steps %v to %v in parallel, %v at a time", plugins[i].Id, plugins[end-1].Id, limit)
+			slots := make(chan struct{}, limit)
+			var wg sync.WaitGroup
+			for j := i; j < end; j++ {
+				wg.Add(1)
+				go func(j int) {
+					defer wg.Done()
+					slots <- struct{}{}
+					defer func() { <-slots }()
+					results[j-i].output, results[j-i].ran, results[j-i].reboot = runStep(context, plugins[j], ioConfig, pluginRegistry, resChan, cancelFlag, pluginOutputs, skipMessage(j), deadline)
+				}(j)
+			}
+			wg.Wait()
+		}
 
+		reboot := false
+		for j, result := range results {
+			pluginOutputs[plugins[i+j].Id] = result.output
+			reboot = reboot || result.reboot
+		}
+		if reboot {
+			// do not execute the the next plugin
+			break
+		}
+		// the parallel steps don't branch
+		if end == i+1 && results[0].ran && i < len(mainSteps) {
+			if target := nextStepIndex(context.Log(), mainSteps, i, results[0].output.Status); target > i+1 {
+				branchTarget, branchedBy = target, plugins[i].Id
 			}
 		}
-		var r contracts.PluginResult
-		pluginHandlerFound := false
+		i = end
+	}
+
+	return
+}
 
-		//check if the said plugin is a worker plugin
-		p, pluginHandlerFound := pluginRegistry[pluginName]
+// stepResult is the result of runStep
+type stepResult struct {
+	output *contracts.PluginResult
+	ran    bool
+	reboot bool
+}
 
-		isKnown, isSupported, _ := isSupportedPlugin(context.Log(), pluginName)
-		operation, logMessage := getStepExecutionOperation(
-			context.Log(),
+// runStep runs a step and sends its result, unless it already ran; skipMessage skips it, a step branched over it.
+// previousOutputs are the results of the steps before it, its inputs may refer to; runStep doesn't modify them.
+// The step times out at the deadline of the document, unless it's zero, or after its own timeout.
+// It returns the result of the step, whether it ran and whether it requested a reboot.
+func runStep(
+	context context.T,
+	pluginState contracts.PluginState,
+	ioConfig contracts.IOConfiguration,
+	pluginRegistry PluginRegistry,
+	resChan chan contracts.PluginResult,
+	cancelFlag task.CancelFlag,
+	previousOutputs map[string]*contracts.PluginResult,
+	skipMessage string,
+	deadline time.Time,
+) (output *contracts.PluginResult, ran bool, reboot bool) {
+	pluginID := pluginState.Id     // the identifier of the plugin
+	pluginName := pluginState.Name // the name of the plugin
+	pluginOutput := pluginState.Result
+	pluginOutput.PluginID = pluginID
+	pluginOutput.PluginName = pluginName
+	output = &pluginOutput
+	switch pluginOutput.Status {
+	//TODO properly initialize the plugin status
+	case "":
+		context.Log().Debugf("plugin - %v has empty state, initialize as NotStarted",
+			pluginName)
+		pluginOutput.StartDateTime = time.Now()
+		pluginOutput.Status = contracts.ResultStatusNotStarted
+
+	case contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
+		context.Log().Debugf("plugin - %v status %v",
 			pluginName,
-			pluginID,
-			isKnown,
-			isSupported,
-			pluginHandlerFound,
-			configuration.IsPreconditionEnabled,
-			configuration.Preconditions)
-
-		switch operation {
-		case executeStep:
-			context.Log().Infof("Running plugin %s", pluginName)
-			r = runPlugin(context, p, pluginName, configuration, cancelFlag, ioConfig)
-			pluginOutputs[pluginID].Code = r.Code
-			pluginOutputs[pluginID].Status = r.Status
-			pluginOutputs[pluginID].Error = r.Error
-			pluginOutputs[pluginID].Output = r.Output
-			pluginOutputs[pluginID].StandardOutput = r.StandardOutput
-			pluginOutputs[pluginID].StandardError = r.StandardError
-
-		case skipStep:
-			context.Log().Info(logMessage)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusSkipped
-			pluginOutputs[pluginID].Code = 0
-			pluginOutputs[pluginID].Output = logMessage
-		case failStep:
-			err := fmt.Errorf(logMessage)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
-			pluginOutputs[pluginID].Error = err
-			context.Log().Error(err)
-		default:
-			err := fmt.Errorf("Unknown error, Operation: %s, Plugin name: %s", operation, pluginName)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
-			pluginOutputs[pluginID].Error = err
-			context.Log().Error(err)
+			pluginOutput.Status)
+		pluginOutput.StartDateTime = time.Now()
+
+	case contracts.ResultStatusSuccessAndReboot:
+		context.Log().Debugf("plugin - %v just experienced reboot, reset to InProgress...",
+			pluginName)
+		pluginOutput.Status = contracts.ResultStatusInProgress
+
+	default:
+		context.Log().Debugf("plugin - %v already executed, skipping...",
+			pluginName)
+		return output, false, false
+	}
+
+	if skipMessage != "" {
+		context.Log().Info(skipMessage)
+		output.Status = contracts.ResultStatusSkipped
+		output.Code = 0
+		output.Output = skipMessage
+		output.EndDateTime = time.Now()
+		resChan <- *output
+		return output, false, false
+	}
+
+	context.Log().Debugf("Executing plugin - %v", pluginName)
+
+	// populate plugin start time and status
+	configuration := pluginState.Configuration
+
+	if ioConfig.OutputS3BucketName != "" {
+		output.OutputS3BucketName = ioConfig.OutputS3BucketName
+		if ioConfig.OutputS3KeyPrefix != "" {
+			output.OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, pluginName)
+
 		}
+	}
+	var r contracts.PluginResult
+	pluginHandlerFound := false
 
-		// set end time.
-		pluginOutputs[pluginID].EndDateTime = time.Now()
-		context.Log().Infof("Sending plugin %v completion message", pluginID)
-		// send to buffer channel, guaranteed to not block since buffer size is plugin number
-		resChan <- *pluginOutputs[pluginID]
+	//check if the said plugin is a worker plugin
+	p, pluginHandlerFound := pluginRegistry[pluginName]
 
-		//TODO handle cancelFlag here
-		if pluginHandlerFound && r.Status == contracts.ResultStatusSuccessAndReboot {
-			// do not execute the the next plugin
-			break
+	operation, logMessage := stepOperation(context.Log(), pluginState, pluginRegistry)
+	if operation == executeStep {
+		var err error
+		if configuration, err = replaceStepOutputs(configuration, previousOutputs); err != nil {
+			operation, logMessage = failStep, fmt.Sprintf("Step %v: %v", pluginID, err)
 		}
 	}
+	if operation == executeStep && !deadline.IsZero() && !time.Now().Before(deadline) {
+		operation = timeOutStep
+		logMessage = fmt.Sprintf("Step %v not run: the document exceeded its executionTimeout of %v seconds", pluginID, configuration.ExecutionTimeoutSeconds)
+	}
 
-	return
+	switch operation {
+	case executeStep:
+		context.Log().Infof("Running plugin %s", pluginName)
+		span := etw.StartSpan(etw.StagePluginExecution, pluginID)
+		stepFlag, stopStepFlag := stepCancelFlag(cancelFlag, time.Duration(configuration.TimeoutSeconds)*time.Second, deadline)
+		r = runPluginLoop(context, p, pluginName, configuration, stepFlag, withPartialOutput(context, ioConfig, *output, resChan))
+		stopStepFlag()
+		if message := timeoutMessage(configuration, cancelFlag, stepFlag); message != "" && r.Status != contracts.ResultStatusSuccess {
+			// tell which of the budgets ran out, the plugin reports the cancellation of the step
+			context.Log().Info(message)
+			r.Status = contracts.ResultStatusTimedOut
+			r.Output = appendOutput(r.Output, message)
+		}
+		span.End(string(r.Status))
+		output.Code = r.Code
+		output.Status = r.Status
+		output.Error = r.Error
+		output.Output = r.Output
+		output.StandardOutput = r.StandardOutput
+		output.StandardError = r.StandardError
+		output.Attachments = r.Attachments
+		output.Attempts = r.Attempts
+		output.Iterations = r.Iterations
+
+	case skipStep:
+		context.Log().Info(logMessage)
+		output.Status = contracts.ResultStatusSkipped
+		output.Code = 0
+		output.Output = logMessage
+	case timeOutStep:
+		context.Log().Info(logMessage)
+		output.Status = contracts.ResultStatusTimedOut
+		output.Code = 1
+		output.Output = logMessage
+	case failStep:
+		err := errors.New(logMessage)
+		output.Status = contracts.ResultStatusFailed
+		output.Error = err
+		context.Log().Error(err)
+	default:
+		err := fmt.Errorf("Unknown error, Operation: %s, Plugin name: %s", operation, pluginName)
+		output.Status = contracts.ResultStatusFailed
+		output.Error = err
+		context.Log().Error(err)
+	}
+
+	// set end time.
+	output.EndDateTime = time.Now()
+	context.Log().Infof("Sending plugin %v completion message", pluginID)
+	// send to buffer channel, guaranteed to not block since buffer size is plugin number
+	resChan <- *output
+
+	//TODO handle cancelFlag here
+	// the next plugin isn't executed after a reboot
+	return output, true, pluginHandlerFound && r.Status == contracts.ResultStatusSuccessAndReboot
+}
+
+// RunPlugin runs a step with the plugin of the agent, in process. The worker processes of the isolated plugins
+// run their step with it.
+func RunPlugin(
+	context context.T,
+	pluginName string,
+	config contracts.Configuration,
+	cancelFlag task.CancelFlag,
+	ioConfig contracts.IOConfiguration) contracts.PluginResult {
+
+	factory, found := SSMPluginRegistry[pluginName]
+	if !found {
+		err := fmt.Errorf("plugin %v isn't registered", pluginName)
+		context.Log().Error(err)
+		return contracts.PluginResult{Status: contracts.ResultStatusFailed, Code: 1, Error: err, StartDateTime: time.Now(), EndDateTime: time.Now()}
+	}
+	return runPlugin(context, factory, pluginName, config, cancelFlag, ioConfig)
 }
 
 func runPlugin(
@@ -204,8 +368,22 @@ func runPlugin(
 	ioConfig contracts.IOConfiguration) (res contracts.PluginResult) {
 	// create a new context that includes plugin ID
 	context = context.With("[pluginName=" + pluginName + "]")
+	if trace, ok := log.TraceContextOf(context.CurrentContext()); ok {
+		context = context.With(trace.NewSpan().ContextTag())
+	}
 
 	log := context.Log()
+	if res, isolated := runPluginIsolated(context, pluginName, config, cancelFlag, ioConfig); isolated {
+		return res
+	}
+	var err error
+	if config, err = replaceSecureParameters(log, config); err != nil {
+		res.Status = contracts.ResultStatusFailed
+		res.Code = 1
+		res.Error = err
+		log.Error(res.Error)
+		return
+	}
 	defer func() {
 		// recover in case the plugin panics
 		// this should handle some kind of seg fault errors.
@@ -256,6 +434,8 @@ func runPlugin(
 	res.Output = output.GetOutput()
 	res.StandardOutput = pluginutil.StringPrefix(output.GetStdout(), pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
 	res.StandardError = pluginutil.StringPrefix(output.GetStderr(), pluginConfig.MaxStderrLength, pluginConfig.OutputTruncatedSuffix)
+	res.Attachments = output.GetAttachments()
+	redactResult(&res)
 	return
 }
 
@@ -287,6 +467,15 @@ func executePlugin(context context.T,
 		defer output.Close(log)
 		output.Init(log, pluginName, propID)
 		p.Execute(context, config, cancelFlag, output)
+		// tell operators why the plugin was interrupted, all cancellations report the same status otherwise
+		switch {
+		case output.GetStatus() == contracts.ResultStatusCancelled && cancelFlag.Reason() != task.CancelReasonNone:
+			output.AppendErrorf("Execution interrupted: %v", cancelFlag.Reason())
+		case output.GetStatus() == contracts.ResultStatusTimedOut && cancelFlag.Reason() == task.CancelReasonNone:
+			// the executer stopped the command at the timeout of the plugin, the flag is left as is since the
+			// next steps share it
+			output.AppendErrorf("Execution interrupted: %v", task.CancelReasonTimeout)
+		}
 	}
 }
 
@@ -297,6 +486,25 @@ func GetPropertyName(rawPluginInput interface{}) (propertyName string, err error
 	return
 }
 
+// stepOperation returns whether the step should be executed, skipped or failed with the plugins of pluginRegistry
+func stepOperation(log log.T, pluginState contracts.PluginState, pluginRegistry PluginRegistry) (string, string) {
+	_, pluginHandlerFound := pluginRegistry[pluginState.Name]
+	isKnown, isSupported, _ := isSupportedPlugin(log, pluginState.Name)
+	if !isKnown && pluginHandlerFound {
+		// a plugin of the program embedding the engine
+		isKnown, isSupported = true, true
+	}
+	return getStepExecutionOperation(
+		log,
+		pluginState.Name,
+		pluginState.Id,
+		isKnown,
+		isSupported,
+		pluginHandlerFound,
+		pluginState.Configuration.IsPreconditionEnabled,
+		pluginState.Configuration.Preconditions)
+}
+
 // Checks plugin compatibility and step precondition and returns if it should be executed, skipped or failed
 func getStepExecutionOperation(
 	log log.T,
@@ -380,50 +588,3 @@ func getStepExecutionOperation(
 		}
 	}
 }
-
-// Evaluate precondition and return precondition result and unrecognized preconditions (if any)
-func evaluatePreconditions(
-	log log.T,
-	preconditions map[string][]string,
-) (bool, []string) {
-
-	var isAllowed = true
-	var unrecognizedPreconditionList []string
-
-	// For current release, we only support "StringEquals" operator and "platformType"
-	// operand, so explicitly checking for those and number of operands must be 2
-	for key, value := range preconditions {
-		switch key {
-		case "StringEquals":
-			// Platform type of OS on the instance
-			instancePlatformType, _ := platform.PlatformType(log)
-			log.Debugf("OS platform type of this instance = %s", instancePlatformType)
-
-			if len(value) != 2 ||
-				(strings.Compare(value[0], "platformType") == 0 && strings.Compare(value[1], "platformType") == 0) ||
-				(strings.Compare(value[0], "platformType") != 0 && strings.Compare(value[1], "platformType") != 0) {
-
-				unrecognizedPreconditionList = append(unrecognizedPreconditionList, fmt.Sprintf("\"%s\": %v", key, value))
-			} else {
-				// Variable and value can be in any order, i.e. both "StringEquals": ["platformType", "Windows"]
-				// and "StringEquals": ["Windows", "platformType"] are valid
-				var platformTypeValue string
-				if strings.Compare(value[0], "platformType") == 0 {
-					platformTypeValue = value[1]
-				} else {
-					platformTypeValue = value[0]
-				}
-
-				if strings.Compare(instancePlatformType, strings.ToLower(platformTypeValue)) != 0 {
-					// if precondition doesn't match for platformType, mark step for skip
-					isAllowed = false
-				}
-			}
-		default:
-			// mark for unrecognizedPrecondition (which is a form of failure)
-			unrecognizedPreconditionList = append(unrecognizedPreconditionList, fmt.Sprintf("\"%s\": %v", key, value))
-		}
-	}
-
-	return isAllowed, unrecognizedPreconditionList
-}
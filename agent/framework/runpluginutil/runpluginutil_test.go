@@ -16,6 +16,8 @@ package runpluginutil
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
@@ -34,6 +36,20 @@ const (
 	testUnsupportedPlugin = "plugin4"
 )
 
+func TestMain(m *testing.M) {
+	// the steps of the tests write their output files under the working directory, keep them out of the sources
+	dir, err := ioutil.TempDir("", "runpluginutil")
+	if err != nil {
+		panic(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 var origIsSupported func(log log.T, pluginName string) (isKnown bool, isSupported bool, message string)
 
 func setIsSupportedMock() {
@@ -1389,9 +1405,12 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 			}
 			pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, cancelFlag, mock.Anything).Return(*pluginResults[name])
 		}
-		pluginFactory := new(PluginFactoryMock)
-		pluginFactory.On("Create", mock.Anything).Return(pluginInstances[name], nil)
-		pluginRegistry[name] = pluginFactory
+		// the registry of the agent only holds the plugins it knows
+		if name != testUnknownPlugin {
+			pluginFactory := new(PluginFactoryMock)
+			pluginFactory.On("Create", mock.Anything).Return(pluginInstances[name], nil)
+			pluginRegistry[name] = pluginFactory
+		}
 
 		pluginConfigs2[index] = pluginConfigs[name]
 	}
@@ -1427,3 +1446,24 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 
 	assert.Equal(t, pluginResults, outputs)
 }
+
+func TestRunPluginsWithEmbedderPlugin(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	ctx := context.NewMockDefault()
+
+	// a plugin unknown to the agent, registered by a program embedding the engine
+	pluginInstance := new(PluginMock)
+	pluginRegistry := PluginRegistry{
+		testUnknownPlugin: PluginFactory(func(context.T) (T, error) { return pluginInstance, nil }),
+	}
+	config := contracts.Configuration{PluginID: testUnknownPlugin, PluginName: testUnknownPlugin}
+	pluginInstance.On("Execute", mock.Anything, config, cancelFlag, mock.Anything).Return()
+
+	ch := make(chan contracts.PluginResult, 1)
+	outputs := RunPlugins(ctx, []contracts.PluginState{{Name: testUnknownPlugin, Id: testUnknownPlugin, Configuration: config}}, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+
+	pluginInstance.AssertExpectations(t)
+	assert.NoError(t, outputs[testUnknownPlugin].Error)
+}
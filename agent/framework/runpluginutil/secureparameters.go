@@ -0,0 +1,158 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/log/redact"
+	"github.com/aws/amazon-ssm-agent/agent/parameters"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
+)
+
+// secureParameterCacheDuration is how long the decrypted SecureString parameters are kept in memory, for the
+// steps, retries and iterations referring to them
+const secureParameterCacheDuration = 5 * time.Minute
+
+// secureParameters is the service decrypting the SecureString parameters of the process, created when the first
+// step refers to one
+var secureParameters struct {
+	once    sync.Once
+	service *ssmparameterresolver.CachingSsmParameterService
+}
+
+// fetchSecureParameters returns the decrypted parameters of the references ssm-secure:<name>
+var fetchSecureParameters = func(log log.T, references []string) (map[string]ssmparameterresolver.SsmParameterInfo, error) {
+	secureParameters.once.Do(func() {
+		service := ssmparameterresolver.NewService()
+		secureParameters.service = ssmparameterresolver.NewCachingService(&service, secureParameterCacheDuration)
+	})
+	return ssmparameterresolver.ResolveParameterReferenceList(secureParameters.service, log, references, ssmparameterresolver.ResolveOptions{})
+}
+
+// scriptPlugins write the runCommand input to a script file, where the decrypted values mustn't be written: its
+// references are kept and the plugins pass the values in the environment of the commands
+var scriptPlugins = map[string]bool{
+	appconfig.PluginNameAwsRunShellScript:      true,
+	appconfig.PluginNameAwsRunPowerShellScript: true,
+}
+
+// runCommandInput is the input of the script plugins holding the commands
+const runCommandInput = "runCommand"
+
+// replaceSecureParameters replaces the references {{ ssm-secure:<name> }} of the properties and settings of a step
+// with the decrypted values of the SecureString parameters, right before the plugin runs, in the process running it.
+// The values are redacted from the logs and the output of the process, and never saved with the document. The
+// runCommand input of the script plugins keeps its references, their values are in config.SecureParameters.
+func replaceSecureParameters(log log.T, config contracts.Configuration) (contracts.Configuration, error) {
+	references := append(parameters.SecureParameterReferences(config.Properties), parameters.SecureParameterReferences(config.Settings)...)
+	if len(references) == 0 {
+		return config, nil
+	}
+	resolved, err := fetchSecureParameters(log, references)
+	if err != nil {
+		return config, fmt.Errorf("failed to resolve the SecureString parameters of step %v: %v", config.PluginID, err)
+	}
+	values := make(map[string]string)
+	for reference, parameter := range resolved {
+		// the cached values were registered when they were fetched, registering them again is a no-op
+		redact.AddSecret(parameter.Value)
+		values[reference] = parameter.Value
+	}
+	log.Debugf("Replaced %v SecureString parameters of step %v", len(values), config.PluginID)
+	if scriptPlugins[config.PluginName] {
+		config.SecureParameters = values
+		config.Properties = replaceExceptRunCommand(config.Properties, values)
+	} else {
+		config.Properties = parameters.ReplaceSecureParameterReferences(config.Properties, values)
+	}
+	config.Settings = parameters.ReplaceSecureParameterReferences(config.Settings, values)
+	return config, nil
+}
+
+// replaceExceptRunCommand replaces the references of the properties of a script plugin, a set of properties or a
+// list of them, but the ones of their runCommand input
+func replaceExceptRunCommand(properties interface{}, values map[string]string) interface{} {
+	switch typed := properties.(type) {
+	case []interface{}:
+		replaced := make([]interface{}, 0, len(typed))
+		for _, item := range typed {
+			replaced = append(replaced, replaceExceptRunCommand(item, values))
+		}
+		return replaced
+	case map[string]interface{}:
+		replaced := make(map[string]interface{}, len(typed))
+		for name, value := range typed {
+			if strings.EqualFold(name, runCommandInput) {
+				replaced[name] = value
+			} else {
+				replaced[name] = parameters.ReplaceSecureParameterReferences(value, values)
+			}
+		}
+		return replaced
+	default:
+		return parameters.ReplaceSecureParameterReferences(properties, values)
+	}
+}
+
+// redactResult redacts the secrets of the process from the output of a step, the output the plugin sets rather
+// than writes, which isn't redacted as it's written
+func redactResult(res *contracts.PluginResult) {
+	res.Output = redactOutput(res.Output)
+	res.StandardOutput = redact.Secrets(res.StandardOutput)
+	res.StandardError = redact.Secrets(res.StandardError)
+}
+
+// redactOutput redacts the secrets of the strings of an output, a string or made of maps, lists and structures
+func redactOutput(output interface{}) interface{} {
+	switch typed := output.(type) {
+	case nil:
+		return nil
+	case string:
+		return redact.Secrets(typed)
+	case []interface{}:
+		redacted := make([]interface{}, 0, len(typed))
+		for _, item := range typed {
+			redacted = append(redacted, redactOutput(item))
+		}
+		return redacted
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(typed))
+		for name, value := range typed {
+			redacted[name] = redactOutput(value)
+		}
+		return redacted
+	}
+	// the structures of the plugins are redacted in their JSON form, which they are reported in, and kept as they
+	// are without secrets
+	if kind := reflect.ValueOf(output).Kind(); kind == reflect.Bool || (kind >= reflect.Int && kind <= reflect.Float64) {
+		return output
+	}
+	var generic interface{}
+	if err := jsonutil.Remarshal(output, &generic); err != nil {
+		return output
+	}
+	if redacted := redactOutput(generic); !reflect.DeepEqual(redacted, generic) {
+		return redacted
+	}
+	return output
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoingPlugin records the properties of the step and sets its command as its output
+type echoingPlugin struct {
+	properties *interface{}
+}
+
+func (p echoingPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	*p.properties = config.Properties
+	output.SetOutput(config.Properties.(map[string]interface{})["command"])
+	output.MarkAsSucceeded()
+}
+
+func runSecureStep(fetch func(log.T, []string) (map[string]ssmparameterresolver.SsmParameterInfo, error)) (interface{}, contracts.PluginResult) {
+	origFetch := fetchSecureParameters
+	fetchSecureParameters = fetch
+	defer func() { fetchSecureParameters = origFetch }()
+
+	var properties interface{}
+	factory := PluginFactory(func(context.T) (T, error) { return echoingPlugin{properties: &properties}, nil })
+	config := contracts.Configuration{
+		PluginID:   "connect",
+		PluginName: testPlugin1,
+		Properties: map[string]interface{}{"command": "mysql -p'{{ ssm-secure:/db/password }}'"},
+	}
+	orchestrationDir, _ := ioutil.TempDir("", "secureparameters")
+	defer os.RemoveAll(orchestrationDir)
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	res := runPlugin(context.NewMockDefault(), factory, testPlugin1, config, task.NewChanneledCancelFlag(), ioConfig)
+	return properties, res
+}
+
+func TestRunPluginReplacesSecureParameters(t *testing.T) {
+	var fetched []string
+	properties, res := runSecureStep(func(log log.T, references []string) (map[string]ssmparameterresolver.SsmParameterInfo, error) {
+		fetched = references
+		return map[string]ssmparameterresolver.SsmParameterInfo{
+			"ssm-secure:/db/password": {Name: "/db/password", Type: "SecureString", Value: "correct-horse-battery"},
+		}, nil
+	})
+
+	assert.Equal(t, []string{"ssm-secure:/db/password"}, fetched)
+	assert.Equal(t, map[string]interface{}{"command": "mysql -p'correct-horse-battery'"}, properties)
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Equal(t, "mysql -p'****'", res.Output)
+}
+
+func TestRunPluginFailsWhenSecureParametersDontResolve(t *testing.T) {
+	properties, res := runSecureStep(func(log log.T, references []string) (map[string]ssmparameterresolver.SsmParameterInfo, error) {
+		return nil, errors.New("access denied")
+	})
+
+	assert.Nil(t, properties)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Contains(t, res.Error.Error(), "access denied")
+}
+
+func TestReplaceSecureParametersKeepsTheRunCommandOfScripts(t *testing.T) {
+	origFetch := fetchSecureParameters
+	fetchSecureParameters = func(log log.T, references []string) (map[string]ssmparameterresolver.SsmParameterInfo, error) {
+		return map[string]ssmparameterresolver.SsmParameterInfo{
+			"ssm-secure:/db/password": {Name: "/db/password", Type: "SecureString", Value: "staple-battery-horse"},
+		}, nil
+	}
+	defer func() { fetchSecureParameters = origFetch }()
+
+	config, err := replaceSecureParameters(log.NewMockLog(), contracts.Configuration{
+		PluginID:   "connect",
+		PluginName: appconfig.PluginNameAwsRunShellScript,
+		Properties: map[string]interface{}{
+			"runCommand":       []interface{}{"mysql -p\"{{ ssm-secure:/db/password }}\""},
+			"workingDirectory": "/tmp/{{ ssm-secure:/db/password }}",
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"runCommand":       []interface{}{"mysql -p\"{{ ssm-secure:/db/password }}\""},
+		"workingDirectory": "/tmp/staple-battery-horse",
+	}, config.Properties)
+	assert.Equal(t, map[string]string{"ssm-secure:/db/password": "staple-battery-horse"}, config.SecureParameters)
+
+	// the outputs the plugins set are redacted whatever their form
+	assert.Equal(t, map[string]interface{}{"status": "connected with ****", "attempts": 2},
+		redactOutput(map[string]interface{}{"status": "connected with staple-battery-horse", "attempts": 2}))
+	type connection struct {
+		Password string
+		Attempts int
+	}
+	assert.Equal(t, map[string]interface{}{"Password": "****", "Attempts": float64(2)},
+		redactOutput(connection{Password: "staple-battery-horse", Attempts: 2}))
+	assert.Equal(t, connection{Attempts: 2}, redactOutput(connection{Attempts: 2}), "kept as is without secrets")
+	assert.Equal(t, 2, redactOutput(2))
+}
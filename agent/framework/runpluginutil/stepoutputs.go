@@ -0,0 +1,141 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/parameters"
+)
+
+// replaceStepOutputs replaces the references {{ steps.<step>.<output> }} of the properties and settings of a step
+// to the outputs of the steps that ran before it, in outputs.
+func replaceStepOutputs(configuration contracts.Configuration, outputs map[string]*contracts.PluginResult) (contracts.Configuration, error) {
+	resolve := func(reference string) (interface{}, error) {
+		return stepOutput(outputs, reference)
+	}
+	var err error
+	if configuration.Properties, err = parameters.ReplaceStepReferences(configuration.Properties, resolve); err != nil {
+		return configuration, err
+	}
+	configuration.Settings, err = parameters.ReplaceStepReferences(configuration.Settings, resolve)
+	return configuration, err
+}
+
+// stepOutput returns the output a reference <step>.<output> refers to:
+// stdout and stderr, without their trailing newlines, exitCode, status, and json, the stdout parsed as JSON,
+// or json.<path>, the value at the path of keys and array indexes separated by dots in it. The outputs of the steps
+// of a document run by the step, e.g. with aws:runDocument, are steps.<nested step>.<output>. The references to a
+// stdout or a stderr truncated in the result of the step fail, rather than resolve to part of the output.
+func stepOutput(outputs map[string]*contracts.PluginResult, reference string) (interface{}, error) {
+	step, name, ok := contracts.SplitStepReference(reference, func(name string) bool {
+		_, found := outputs[name]
+		return found
+	})
+	if !ok {
+		return nil, fmt.Errorf("{{ steps.%v }} doesn't refer to a step that ran before", reference)
+	}
+	result := outputs[step]
+	config := iohandler.DefaultOutputConfig()
+	switch {
+	case name == "stdout" || name == "json" || strings.HasPrefix(name, "json."):
+		if truncated(result.StandardOutput, config.MaxStdoutLength) {
+			return nil, fmt.Errorf("the stdout of step %v is longer than %v characters and was truncated, {{ steps.%v }} can't refer to it", step, config.MaxStdoutLength, reference)
+		}
+	case name == "stderr":
+		if truncated(result.StandardError, config.MaxStderrLength) {
+			return nil, fmt.Errorf("the stderr of step %v is longer than %v characters and was truncated, {{ steps.%v }} can't refer to it", step, config.MaxStderrLength, reference)
+		}
+	}
+	switch {
+	case name == "stdout":
+		return strings.TrimRight(result.StandardOutput, "\r\n"), nil
+	case name == "stderr":
+		return strings.TrimRight(result.StandardError, "\r\n"), nil
+	case name == "exitCode":
+		return result.Code, nil
+	case name == "status":
+		return string(result.Status), nil
+	case strings.HasPrefix(name, "steps."):
+		nested, err := nestedStepOutputs(result)
+		if err != nil {
+			return nil, fmt.Errorf("{{ steps.%v }} refers to the steps of step %v, which didn't run a document", reference, step)
+		}
+		value, err := stepOutput(nested, strings.TrimPrefix(name, "steps."))
+		if err != nil {
+			return nil, fmt.Errorf("in the document run by step %v, %v", step, err)
+		}
+		return value, nil
+	case name == "json" || strings.HasPrefix(name, "json."):
+		var value interface{}
+		if err := json.Unmarshal([]byte(result.StandardOutput), &value); err != nil {
+			return nil, fmt.Errorf("the output of step %v isn't JSON: %v", step, err)
+		}
+		if name == "json" {
+			return value, nil
+		}
+		for _, key := range strings.Split(strings.TrimPrefix(name, "json."), ".") {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				if value, ok = v[key]; !ok {
+					return nil, fmt.Errorf("the JSON output of step %v has no %v", step, name)
+				}
+			case []interface{}:
+				index, err := strconv.Atoi(key)
+				if err != nil || index < 0 || index >= len(v) {
+					return nil, fmt.Errorf("the JSON output of step %v has no %v", step, name)
+				}
+				value = v[index]
+			default:
+				return nil, fmt.Errorf("the JSON output of step %v has no %v", step, name)
+			}
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("{{ steps.%v }} refers to %v, which isn't an output of step %v: stdout, stderr, exitCode, status or json", reference, name, step)
+}
+
+// truncated returns whether the output was truncated to maxLength in the result of its step
+func truncated(output string, maxLength int) bool {
+	return len(output) == maxLength && strings.HasSuffix(output, iohandler.DefaultOutputConfig().OutputTruncatedSuffix)
+}
+
+// nestedStepOutputs returns the results of the steps of the document run by the step, from its output, which is
+// decoded from JSON after the document resumed.
+func nestedStepOutputs(result *contracts.PluginResult) (map[string]*contracts.PluginResult, error) {
+	var output contracts.NestedStepsOutput
+	if err := jsonutil.Remarshal(result.Output, &output); err != nil {
+		return nil, err
+	}
+	if output.Steps == nil {
+		return nil, fmt.Errorf("no nested steps")
+	}
+	outputs := make(map[string]*contracts.PluginResult, len(output.Steps))
+	for _, step := range output.Steps {
+		outputs[step.Name] = &contracts.PluginResult{
+			Status:         step.Status,
+			Code:           step.Code,
+			Output:         step.Output,
+			StandardOutput: step.Stdout,
+			StandardError:  step.Stderr,
+		}
+	}
+	return outputs, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingPlugin records the properties of the steps
+type recordingPlugin struct {
+	m          *sync.Mutex
+	properties map[string]interface{}
+}
+
+func (p recordingPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	p.m.Lock()
+	p.properties[config.PluginID] = config.Properties
+	p.m.Unlock()
+	output.MarkAsSucceeded()
+}
+
+func TestRunPluginsReplacesStepOutputs(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	plugin := recordingPlugin{m: &sync.Mutex{}, properties: map[string]interface{}{}}
+	pluginRegistry := PluginRegistry{
+		testPlugin1: PluginFactory(func(context.T) (T, error) { return plugin, nil }),
+	}
+	steps := []struct {
+		id         string
+		properties interface{}
+	}{
+		{"query.v2", nil},
+		{"use", map[string]interface{}{
+			"ids":     "{{ steps.query.v2.json.instances }}",
+			"command": "stop {{ steps.query.v2.json.instances.1.id }} after {{ steps.query.v2.status }} ({{steps.query.v2.exitCode}})",
+		}},
+		{"missing", map[string]interface{}{"command": "{{ steps.query.v2.json.volumes }}"}},
+	}
+	plugins := make([]contracts.PluginState, len(steps))
+	for i, step := range steps {
+		config := contracts.Configuration{PluginID: step.id, PluginName: testPlugin1, Properties: step.properties}
+		plugins[i] = contracts.PluginState{Name: testPlugin1, Id: step.id, Configuration: config}
+	}
+	// the first step ran before a reboot
+	plugins[0].Result = contracts.PluginResult{
+		Status:         contracts.ResultStatusSuccess,
+		StandardOutput: `{"instances": [{"id": "i-1"}, {"id": "i-2"}]}` + "\n",
+	}
+
+	ch := make(chan contracts.PluginResult, len(plugins))
+	outputs := RunPlugins(context.NewMockDefault(), plugins, contracts.IOConfiguration{}, pluginRegistry, ch, task.NewChanneledCancelFlag())
+
+	assert.Equal(t, map[string]interface{}{
+		"ids":     []interface{}{map[string]interface{}{"id": "i-1"}, map[string]interface{}{"id": "i-2"}},
+		"command": "stop i-2 after Success (0)",
+	}, plugin.properties["use"])
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["use"].Status)
+	assert.Equal(t, contracts.ResultStatusFailed, outputs["missing"].Status)
+	assert.NotContains(t, plugin.properties, "missing")
+}
+
+func TestStepOutput(t *testing.T) {
+	outputs := map[string]*contracts.PluginResult{
+		"install": {Status: contracts.ResultStatusFailed, Code: 2, StandardOutput: "installed\n", StandardError: "warning\n"},
+	}
+	for reference, expected := range map[string]interface{}{
+		"install.stdout":   "installed",
+		"install.stderr":   "warning",
+		"install.exitCode": 2,
+		"install.status":   "Failed",
+	} {
+		value, err := stepOutput(outputs, reference)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value, reference)
+	}
+	for _, reference := range []string{"install.json", "install.output", "verify.stdout"} {
+		_, err := stepOutput(outputs, reference)
+		assert.Error(t, err, reference)
+	}
+}
+
+func TestStepOutputTruncated(t *testing.T) {
+	config := iohandler.DefaultOutputConfig()
+	stdout := pluginutil.StringPrefix(strings.Repeat("x", config.MaxStdoutLength+1), config.MaxStdoutLength, config.OutputTruncatedSuffix)
+	outputs := map[string]*contracts.PluginResult{"install": {Status: contracts.ResultStatusSuccess, StandardOutput: stdout}}
+	for _, reference := range []string{"install.stdout", "install.json", "install.json.version"} {
+		_, err := stepOutput(outputs, reference)
+		assert.Error(t, err, reference)
+		assert.Contains(t, err.Error(), "truncated", reference)
+	}
+	value, err := stepOutput(outputs, "install.status")
+	assert.NoError(t, err)
+	assert.Equal(t, "Success", value)
+}
+
+func TestStepOutputOfNestedSteps(t *testing.T) {
+	nested := contracts.NestedStepsOutput{Steps: []contracts.NestedStepResult{
+		{Name: "configure", Status: contracts.ResultStatusSuccess, Stdout: `{"port": 8080}`},
+		{Name: "runInner", Status: contracts.ResultStatusSuccess, Output: contracts.NestedStepsOutput{Steps: []contracts.NestedStepResult{
+			{Name: "check", Status: contracts.ResultStatusFailed, Code: 3, Stderr: "unhealthy\n"},
+		}}},
+	}}
+	var resumed interface{}
+	assert.NoError(t, jsonutil.Remarshal(nested, &resumed))
+	for _, output := range []interface{}{nested, resumed} {
+		outputs := map[string]*contracts.PluginResult{"runChild": {Status: contracts.ResultStatusSuccess, Output: output}}
+		for reference, expected := range map[string]interface{}{
+			"runChild.steps.configure.json.port":          float64(8080),
+			"runChild.steps.runInner.steps.check.exitCode": 3,
+			"runChild.steps.runInner.steps.check.stderr":   "unhealthy",
+		} {
+			value, err := stepOutput(outputs, reference)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, value, reference)
+		}
+		for _, reference := range []string{"runChild.steps.verify.stdout", "runChild.steps.configure.steps.x.stdout"} {
+			_, err := stepOutput(outputs, reference)
+			assert.Error(t, err, reference)
+		}
+	}
+}
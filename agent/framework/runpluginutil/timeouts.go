@@ -0,0 +1,107 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// documentDeadline returns when the executionTimeout of the document expires, counted from the start of its first
+// step, which is kept when the document resumes after a restart. It's zero when the document has no timeout.
+func documentDeadline(plugins []contracts.PluginState) time.Time {
+	if len(plugins) == 0 || plugins[0].Configuration.ExecutionTimeoutSeconds <= 0 {
+		return time.Time{}
+	}
+	start := time.Now()
+	for _, plugin := range plugins {
+		if plugin.Result.Status != "" && !plugin.Result.StartDateTime.IsZero() && plugin.Result.StartDateTime.Before(start) {
+			start = plugin.Result.StartDateTime
+		}
+	}
+	return start.Add(time.Duration(plugins[0].Configuration.ExecutionTimeoutSeconds) * time.Second)
+}
+
+// stepCancelFlag returns the cancel flag of a step: it's set like the flag of the document, and cancelled with
+// CancelReasonStepTimeout once the step ran for its timeout, or with CancelReasonTimeout at the deadline of the
+// document. A zero timeout or deadline doesn't apply. stop releases the flag once the step ended.
+func stepCancelFlag(cancelFlag task.CancelFlag, timeout time.Duration, deadline time.Time) (flag task.CancelFlag, stop func()) {
+	if timeout <= 0 && deadline.IsZero() {
+		return cancelFlag, func() {}
+	}
+	stepFlag := task.NewChanneledCancelFlag()
+	var timers []*time.Timer
+	var stepTimeout, documentTimeout <-chan time.Time
+	if timeout > 0 {
+		timers = append(timers, time.NewTimer(timeout))
+		stepTimeout = timers[len(timers)-1].C
+	}
+	if !deadline.IsZero() {
+		timers = append(timers, time.NewTimer(time.Until(deadline)))
+		documentTimeout = timers[len(timers)-1].C
+	}
+	cancelled := task.Done(cancelFlag)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-stepTimeout:
+			stepFlag.SetWithReason(task.Canceled, task.CancelReasonStepTimeout)
+		case <-documentTimeout:
+			stepFlag.SetWithReason(task.Canceled, task.CancelReasonTimeout)
+		case <-cancelled:
+			if cancelFlag.Canceled() || cancelFlag.ShutDown() {
+				stepFlag.SetWithReason(cancelFlag.State(), cancelFlag.Reason())
+			}
+		case <-done:
+		}
+	}()
+	return stepFlag, func() {
+		for _, timer := range timers {
+			timer.Stop()
+		}
+		close(done)
+	}
+}
+
+// timeoutMessage returns why the step timed out when its flag was cancelled by one of the timeouts, or an empty
+// string; the step without timeouts runs with the flag of the document.
+func timeoutMessage(config contracts.Configuration, cancelFlag, stepFlag task.CancelFlag) string {
+	if stepFlag == cancelFlag {
+		return ""
+	}
+	switch stepFlag.Reason() {
+	case task.CancelReasonStepTimeout:
+		return fmt.Sprintf("Step %v timed out: it exceeded its timeoutSeconds of %v", config.PluginID, config.TimeoutSeconds)
+	case task.CancelReasonTimeout:
+		return fmt.Sprintf("Step %v timed out: the document exceeded its executionTimeout of %v seconds", config.PluginID, config.ExecutionTimeoutSeconds)
+	}
+	return ""
+}
+
+// appendOutput appends the message to the output of a step, which is a string unless the plugin set its own
+func appendOutput(output interface{}, message string) interface{} {
+	switch out := output.(type) {
+	case nil:
+		return message
+	case string:
+		if out == "" {
+			return message
+		}
+		return out + "\n" + message
+	}
+	return output
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitingPlugin runs until its step is cancelled
+type waitingPlugin struct{}
+
+func (waitingPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if cancelFlag.Canceled() {
+			output.MarkAsCancelled()
+			return
+		}
+	}
+	output.MarkAsSucceeded()
+}
+
+// timingOutPlugin times out as a plugin whose command was stopped by the executer
+type timingOutPlugin struct{}
+
+func (timingOutPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	output.SetStatus(contracts.ResultStatusTimedOut)
+}
+
+func TestRunPluginsReportsThePluginTimeout(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{
+		testPlugin1: PluginFactory(func(context.T) (T, error) { return timingOutPlugin{}, nil }),
+	}
+	config := contracts.Configuration{PluginID: "install", PluginName: testPlugin1}
+	plugins := []contracts.PluginState{{Name: testPlugin1, Id: "install", Configuration: config}}
+	cancelFlag := task.NewChanneledCancelFlag()
+
+	ch := make(chan contracts.PluginResult, len(plugins))
+	outputs := RunPlugins(context.NewMockDefault(), plugins, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+
+	assert.Equal(t, contracts.ResultStatusTimedOut, outputs["install"].Status)
+	assert.Contains(t, outputs["install"].Output, "Execution interrupted: Timeout")
+	assert.False(t, cancelFlag.Canceled(), "the next steps share the flag")
+}
+
+func TestRunPluginsStepTimeout(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{
+		testPlugin1: PluginFactory(func(context.T) (T, error) { return waitingPlugin{}, nil }),
+	}
+	config := contracts.Configuration{PluginID: "install", PluginName: testPlugin1, TimeoutSeconds: 1, ExecutionTimeoutSeconds: 60}
+	plugins := []contracts.PluginState{{Name: testPlugin1, Id: "install", Configuration: config}}
+
+	ch := make(chan contracts.PluginResult, len(plugins))
+	outputs := RunPlugins(context.NewMockDefault(), plugins, contracts.IOConfiguration{}, pluginRegistry, ch, task.NewChanneledCancelFlag())
+
+	assert.Equal(t, contracts.ResultStatusTimedOut, outputs["install"].Status)
+	assert.Contains(t, outputs["install"].Output, "exceeded its timeoutSeconds of 1")
+}
+
+func TestRunPluginsDocumentTimeout(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{
+		testPlugin1: PluginFactory(func(context.T) (T, error) { return waitingPlugin{}, nil }),
+	}
+	// the first step ran before the document resumed, the budget of the document was spent since
+	plugins := []contracts.PluginState{
+		{Name: testPlugin1, Id: "install", Configuration: contracts.Configuration{PluginID: "install", PluginName: testPlugin1, ExecutionTimeoutSeconds: 1},
+			Result: contracts.PluginResult{Status: contracts.ResultStatusSuccess, StartDateTime: time.Now().Add(-time.Minute)}},
+		{Name: testPlugin1, Id: "verify", Configuration: contracts.Configuration{PluginID: "verify", PluginName: testPlugin1, ExecutionTimeoutSeconds: 1, TimeoutSeconds: 600}},
+	}
+
+	ch := make(chan contracts.PluginResult, len(plugins))
+	outputs := RunPlugins(context.NewMockDefault(), plugins, contracts.IOConfiguration{}, pluginRegistry, ch, task.NewChanneledCancelFlag())
+
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["install"].Status)
+	assert.Equal(t, contracts.ResultStatusTimedOut, outputs["verify"].Status)
+	assert.Equal(t, "Step verify not run: the document exceeded its executionTimeout of 1 seconds", outputs["verify"].Output)
+}
+
+func TestStepCancelFlag(t *testing.T) {
+	cancelFlag := task.NewChanneledCancelFlag()
+	assert.Equal(t, cancelFlag, func() task.CancelFlag { flag, _ := stepCancelFlag(cancelFlag, 0, time.Time{}); return flag }())
+
+	stepFlag, stop := stepCancelFlag(cancelFlag, 10*time.Millisecond, time.Now().Add(time.Hour))
+	stepFlag.Wait()
+	stop()
+	assert.True(t, stepFlag.Canceled())
+	assert.Equal(t, task.CancelReasonStepTimeout, stepFlag.Reason())
+	assert.False(t, cancelFlag.Canceled(), "the document goes on")
+
+	stepFlag, stop = stepCancelFlag(cancelFlag, time.Hour, time.Now().Add(10*time.Millisecond))
+	stepFlag.Wait()
+	stop()
+	assert.Equal(t, task.CancelReasonTimeout, stepFlag.Reason())
+
+	stepFlag, stop = stepCancelFlag(cancelFlag, time.Hour, time.Time{})
+	cancelFlag.SetWithReason(task.ShutDown, task.CancelReasonReboot)
+	stepFlag.Wait()
+	stop()
+	assert.True(t, stepFlag.ShutDown())
+	assert.Equal(t, task.CancelReasonReboot, stepFlag.Reason())
+}
+
+// goroutinesReleased waits for the goroutines to go back to count
+func goroutinesReleased(count int) bool {
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		if runtime.NumGoroutine() <= count {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStepCancelFlagStopReleasesGoroutines(t *testing.T) {
+	cancelFlag := task.NewChanneledCancelFlag()
+	count := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		_, stop := stepCancelFlag(cancelFlag, time.Hour, time.Now().Add(time.Hour))
+		stop()
+	}
+	assert.True(t, goroutinesReleased(count))
+}
+
+func TestDocumentDeadline(t *testing.T) {
+	assert.True(t, documentDeadline([]contracts.PluginState{{}}).IsZero())
+
+	start := time.Now().Add(-time.Minute)
+	plugins := []contracts.PluginState{
+		{Configuration: contracts.Configuration{ExecutionTimeoutSeconds: 3600}, Result: contracts.PluginResult{Status: contracts.ResultStatusSuccess, StartDateTime: start}},
+		{Configuration: contracts.Configuration{ExecutionTimeoutSeconds: 3600}},
+	}
+	assert.Equal(t, start.Add(time.Hour), documentDeadline(plugins))
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package health
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
+)
+
+// connectivityDisabledInterval is how often a disabled connectivity check looks whether it was enabled by a reload
+const connectivityDisabledInterval = time.Minute
+
+var (
+	// dialEndpoint opens a connection to the SSM endpoint, the connectivity probe
+	dialEndpoint = dialThroughProxy
+	// proxyFor returns the proxy the agent reaches the endpoint through, nil if none
+	proxyFor = proxyconfig.Proxy
+	// region returns the region of the instance, for the default SSM endpoint
+	region = platform.Region
+	// raiseAlert raises the alerts of the connectivity, in the alerts file of the watchdog
+	raiseAlert = watchdog.RaiseAlert
+)
+
+// connectivityMonitor checks every Ssm.ConnectivityCheckSeconds that the SSM endpoint can be reached, and reports
+// the agent offline after Ssm.ConnectivityFailureThreshold consecutive failures. It detects a lost connectivity
+// within seconds, while the UpdateInstanceInformation heartbeat can't be sent more often than every few minutes.
+type connectivityMonitor struct {
+	failures     int
+	offline      bool
+	offlineSince time.Time
+}
+
+// monitorConnectivity checks the connectivity until stop is closed
+func (h *HealthCheck) monitorConnectivity(stop chan bool) {
+	monitor := &connectivityMonitor{}
+	for {
+		config := h.context.AppConfig()
+		interval := time.Duration(config.Ssm.ConnectivityCheckSeconds) * time.Second
+		if interval <= 0 {
+			monitor.reset()
+			interval = connectivityDisabledInterval
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+		if config = h.context.AppConfig(); config.Ssm.ConnectivityCheckSeconds > 0 {
+			monitor.check(h.context.Log(), config)
+		}
+	}
+}
+
+// check probes the endpoint once and logs the changes of the connectivity
+func (m *connectivityMonitor) check(log log.T, config appconfig.SsmagentConfig) {
+	address, err := EndpointAddress(config)
+	if err != nil {
+		log.Debugf("skipping the connectivity check, %v", err)
+		return
+	}
+	timeout := time.Duration(config.Ssm.ConnectivityCheckSeconds) * time.Second
+	if err = dialEndpoint(address, timeout); err == nil {
+		if m.offline {
+			raiseAlert(log, watchdog.Alert{Time: time.Now().UTC(), Type: watchdog.AlertOnline, Message: fmt.Sprintf(
+				"connectivity to %v restored, the agent was offline for %v", address, time.Since(m.offlineSince))})
+		}
+		m.reset()
+		return
+	}
+
+	m.failures++
+	log.Debugf("connectivity check %d to %v failed: %v", m.failures, address, err)
+	if !m.offline && m.failures >= config.Ssm.ConnectivityFailureThreshold {
+		m.offline = true
+		m.offlineSince = time.Now()
+		raiseAlert(log, watchdog.Alert{Time: m.offlineSince.UTC(), Type: watchdog.AlertOffline, Message: fmt.Sprintf(
+			"agent offline: %v unreachable for %d consecutive checks, %v", address, m.failures, err)})
+	}
+}
+
+// dialThroughProxy opens a connection to the address, through the proxy of the agent if any: the proxy is asked to
+// connect to the address, as it is for the requests of the agent
+func dialThroughProxy(address string, timeout time.Duration) error {
+	proxy, err := proxyFor(&http.Request{URL: &url.URL{Scheme: "https", Host: address}})
+	if err != nil {
+		return fmt.Errorf("invalid proxy: %v", err)
+	}
+	if proxy == nil {
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	proxyAddress := proxy.Host
+	if proxy.Port() == "" {
+		proxyAddress = net.JoinHostPort(proxy.Hostname(), "80")
+	}
+	conn, err := net.DialTimeout("tcp", proxyAddress, timeout)
+	if err != nil {
+		return fmt.Errorf("proxy %v: %v", proxyAddress, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	connect := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: address}, Host: address, Header: http.Header{}}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		request := http.Request{Header: http.Header{}}
+		request.SetBasicAuth(proxy.User.Username(), password)
+		connect.Header.Set("Proxy-Authorization", request.Header.Get("Authorization"))
+	}
+	if err = connect.Write(conn); err != nil {
+		return fmt.Errorf("proxy %v: %v", proxyAddress, err)
+	}
+	response, err := http.ReadResponse(bufio.NewReader(conn), connect)
+	if err != nil {
+		return fmt.Errorf("proxy %v: %v", proxyAddress, err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy %v refused to connect to %v: %v", proxyAddress, address, response.Status)
+	}
+	return nil
+}
+
+// reset forgets the failed checks
+func (m *connectivityMonitor) reset() {
+	m.failures = 0
+	m.offline = false
+}
+
+// EndpointAddress returns the host and port of the SSM endpoint the agent sends its heartbeat to
+func EndpointAddress(config appconfig.SsmagentConfig) (string, error) {
+	host := config.Ssm.Endpoint
+	if host == "" {
+		instanceRegion := config.Agent.Region
+		if instanceRegion == "" {
+			var err error
+			if instanceRegion, err = region(); err != nil {
+				return "", err
+			}
+		}
+		if host = appconfig.GetDefaultEndPoint(instanceRegion, "ssm"); host == "" {
+			host = "ssm." + instanceRegion + ".amazonaws.com"
+		}
+	}
+	if strings.Contains(host, "://") {
+		endpoint, err := url.Parse(host)
+		if err != nil {
+			return "", err
+		}
+		host = endpoint.Host
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "443")
+	}
+	return host, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package health
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubConnectivity replaces the probe with the results of reachable and records the alerts
+func stubConnectivity(reachable *bool) (alerts *[]watchdog.Alert, restore func()) {
+	origDial, origAlert := dialEndpoint, raiseAlert
+	alerts = &[]watchdog.Alert{}
+	dialEndpoint = func(address string, timeout time.Duration) error {
+		if *reachable {
+			return nil
+		}
+		return errors.New("i/o timeout")
+	}
+	raiseAlert = func(log log.T, alert watchdog.Alert) { *alerts = append(*alerts, alert) }
+	return alerts, func() { dialEndpoint, raiseAlert = origDial, origAlert }
+}
+
+func TestConnectivityCheckRaisesTheAlerts(t *testing.T) {
+	reachable := false
+	alerts, restore := stubConnectivity(&reachable)
+	defer restore()
+	config := appconfig.DefaultConfig()
+	config.Ssm.Endpoint = "ssm.us-east-1.amazonaws.com"
+	config.Ssm.ConnectivityCheckSeconds = 1
+	config.Ssm.ConnectivityFailureThreshold = 3
+	monitor := &connectivityMonitor{}
+
+	monitor.check(log.NewMockLog(), config)
+	monitor.check(log.NewMockLog(), config)
+	assert.Empty(t, *alerts, "under the threshold")
+	monitor.check(log.NewMockLog(), config)
+	monitor.check(log.NewMockLog(), config)
+	assert.Len(t, *alerts, 1, "raised once while offline")
+	assert.Equal(t, watchdog.AlertOffline, (*alerts)[0].Type)
+	assert.Contains(t, (*alerts)[0].Message, "ssm.us-east-1.amazonaws.com:443")
+
+	reachable = true
+	monitor.check(log.NewMockLog(), config)
+	monitor.check(log.NewMockLog(), config)
+	assert.Len(t, *alerts, 2)
+	assert.Equal(t, watchdog.AlertOnline, (*alerts)[1].Type)
+	assert.Equal(t, 0, monitor.failures)
+}
+
+func TestEndpointAddress(t *testing.T) {
+	config := appconfig.DefaultConfig()
+	config.Agent.Region = "cn-north-1"
+	address, err := EndpointAddress(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "ssm.cn-north-1.amazonaws.com.cn:443", address)
+
+	config.Ssm.Endpoint = "https://vpce-1234.ssm.us-east-1.vpce.amazonaws.com:8443/"
+	address, err = EndpointAddress(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "vpce-1234.ssm.us-east-1.vpce.amazonaws.com:8443", address)
+}
+
+// fakeProxy answers the CONNECT requests it receives with status, and sends their target and authorization
+func fakeProxy(t *testing.T, status int) (proxyURL *url.URL, requests chan *http.Request) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	requests = make(chan *http.Request, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		requests <- request
+		(&http.Response{StatusCode: status, ProtoMajor: 1, ProtoMinor: 1}).Write(conn)
+	}()
+	return &url.URL{Scheme: "http", User: url.UserPassword("agent", "secret"), Host: listener.Addr().String()}, requests
+}
+
+func TestDialThroughProxy(t *testing.T) {
+	origProxyFor := proxyFor
+	defer func() { proxyFor = origProxyFor }()
+
+	proxyURL, requests := fakeProxy(t, http.StatusOK)
+	proxyFor = func(request *http.Request) (*url.URL, error) { return proxyURL, nil }
+	assert.NoError(t, dialThroughProxy("ssm.us-east-1.amazonaws.com:443", time.Second))
+	request := <-requests
+	assert.Equal(t, http.MethodConnect, request.Method)
+	assert.Equal(t, "ssm.us-east-1.amazonaws.com:443", request.Host)
+	assert.Equal(t, "Basic YWdlbnQ6c2VjcmV0", request.Header.Get("Proxy-Authorization"))
+
+	proxyURL, _ = fakeProxy(t, http.StatusForbidden)
+	err := dialThroughProxy("ssm.us-east-1.amazonaws.com:443", time.Second)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
@@ -16,12 +16,15 @@ package health
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/amazon-ssm-agent/agent/ssm"
+	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 	"github.com/carlescere/scheduler"
 )
@@ -31,13 +34,18 @@ type HealthCheck struct {
 	context               context.T
 	healthCheckStopPolicy *sdkutil.StopPolicy
 	healthJob             *scheduler.Job
+	healthJobMinutes      int
+	healthJobLock         sync.Mutex
 	service               ssm.Service
+	connectivityStop      chan bool
 }
 
 const (
 	name = "HealthCheck"
 	// AgentName is the name of the current agent.
 	AgentName = "amazon-ssm-agent"
+	// AgentStatusActive is the agent status reported by a healthy agent
+	AgentStatusActive = "Active"
 )
 
 var healthModule *HealthCheck
@@ -73,11 +81,31 @@ func NewHealthCheck(context context.T) *HealthCheck {
 
 // schedules recurrent updateHealth calls
 func (h *HealthCheck) scheduleUpdateHealth() {
+	h.healthJobLock.Lock()
+	defer h.healthJobLock.Unlock()
+	h.scheduleUpdateHealthLocked()
+	appconfig.OnReload(name, h.rescheduleUpdateHealth)
+}
+
+// scheduleUpdateHealthLocked schedules recurrent updateHealth calls, the caller holds the job lock
+func (h *HealthCheck) scheduleUpdateHealthLocked() {
 	var err error
-	if h.healthJob, err = scheduler.Every(h.scheduleInMinutes()).Minutes().Run(h.updateHealth); err != nil {
+	h.healthJobMinutes = h.scheduleInMinutes()
+	if h.healthJob, err = scheduler.Every(h.healthJobMinutes).Minutes().Run(h.updateHealth); err != nil {
 		h.context.Log().Errorf("unable to schedule health update. %v", err)
 	}
-	return
+}
+
+// rescheduleUpdateHealth schedules the updateHealth calls again if the reloaded configuration changed their frequency
+func (h *HealthCheck) rescheduleUpdateHealth(config appconfig.SsmagentConfig) {
+	h.healthJobLock.Lock()
+	defer h.healthJobLock.Unlock()
+	if h.healthJob == nil || h.scheduleInMinutes() == h.healthJobMinutes {
+		return
+	}
+	h.context.Log().Infof("rescheduling the health updates every %d minutes.", h.scheduleInMinutes())
+	h.healthJob.Quit <- true
+	h.scheduleUpdateHealthLocked()
 }
 
 // updates SSM with the instance health information
@@ -88,22 +116,52 @@ func (h *HealthCheck) updateHealth() {
 	var err error
 	//TODO when will status become inactive?
 	// If both ssm config and command is inactive => agent is inactive.
-	if _, err = h.service.UpdateInstanceInformation(log, version.Version, "Active", AgentName); err != nil {
+	if _, err = h.service.UpdateInstanceInformation(log, version.Version, AgentStatusActive, AgentName); err != nil {
 		sdkutil.HandleAwsError(log, err, h.healthCheckStopPolicy)
 	}
+	h.reportTaskPoolMetrics()
 	return
 }
 
+// reports the utilization of the agent task pools, to tell a saturated pool from a slow document
+func (h *HealthCheck) reportTaskPoolMetrics() {
+	log := h.context.Log()
+	for _, metrics := range h.TaskPoolMetrics() {
+		log.Infof("task pool %v: %d/%d workers active, %d jobs queued, wait time avg %v max %v, job duration avg %v max %v, %d submitted, %d completed, %d discarded",
+			metrics.Name,
+			metrics.ActiveWorkers,
+			metrics.MaxWorkers,
+			metrics.QueueDepth,
+			metrics.AverageWaitTime,
+			metrics.MaxWaitTime,
+			metrics.AverageJobDuration,
+			metrics.MaxJobDuration,
+			metrics.JobsSubmitted,
+			metrics.JobsCompleted,
+			metrics.JobsDiscarded)
+		if metrics.QueueDepth > 0 && metrics.ActiveWorkers >= metrics.MaxWorkers {
+			log.Warnf("task pool %v is saturated, %d jobs are waiting for a free worker", metrics.Name, metrics.QueueDepth)
+		}
+	}
+}
+
+// TaskPoolMetrics returns the current metrics of all the task pools of the agent
+func (h *HealthCheck) TaskPoolMetrics() []task.PoolMetrics {
+	return task.GetPoolMetrics()
+}
+
 // scheduleInMinutes Run Schedule In Minutes
 func (h *HealthCheck) scheduleInMinutes() int {
-	updateHealthFrequencyMins := 5
+	updateHealthFrequencyMins := appconfig.DefaultSsmHealthFrequencyMinutes
 	config := h.context.AppConfig()
 	log := h.context.Log()
 
-	if 4 < config.Ssm.HealthFrequencyMinutes || config.Ssm.HealthFrequencyMinutes < 61 {
+	// the service throttles instances sending their health more often than the minimum
+	if appconfig.DefaultSsmHealthFrequencyMinutesMin <= config.Ssm.HealthFrequencyMinutes &&
+		config.Ssm.HealthFrequencyMinutes <= appconfig.DefaultSsmHealthFrequencyMinutesMax {
 		updateHealthFrequencyMins = config.Ssm.HealthFrequencyMinutes
 	} else {
-		log.Debug("HealthFrequencyMinutes is outside allowable limits. Limiting to 5 minutes default.")
+		log.Debugf("HealthFrequencyMinutes is outside allowable limits. Limiting to %d minutes default.", updateHealthFrequencyMins)
 	}
 	log.Debugf("%v frequency is every %d minutes.", name, updateHealthFrequencyMins)
 
@@ -127,6 +185,12 @@ func (h *HealthCheck) ModuleExecute(context context.T) (err error) {
 	// First call updateHealth once
 	go h.updateHealth()
 
+	// Check the connectivity between the health updates, when Ssm.ConnectivityCheckSeconds enables it
+	h.healthJobLock.Lock()
+	h.connectivityStop = make(chan bool)
+	go h.monitorConnectivity(h.connectivityStop)
+	h.healthJobLock.Unlock()
+
 	// Wait randomSeconds and schedule recurrent updateHealth calls
 	next := time.Duration(randomSeconds) * time.Second
 	go func(h *HealthCheck) {
@@ -141,9 +205,17 @@ func (h *HealthCheck) ModuleExecute(context context.T) (err error) {
 
 // ModuleRequestStop handles the termination of the health check module job
 func (h *HealthCheck) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	appconfig.OnReload(name, nil)
+	h.healthJobLock.Lock()
+	defer h.healthJobLock.Unlock()
 	if h.healthJob != nil {
 		h.context.Log().Info("stopping update instance health job.")
 		h.healthJob.Quit <- true
+		h.healthJob = nil
+	}
+	if h.connectivityStop != nil {
+		close(h.connectivityStop)
+		h.connectivityStop = nil
 	}
 	return nil
 }
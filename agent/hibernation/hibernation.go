@@ -13,15 +13,20 @@
 
 // Package hibernation is responsible for the agent in hibernate mode.
 // It depends on health pings in an exponential backoff to check if the agent needs
-// to move to active mode.
+// to move to active mode. Each ping is preceded by cheap DNS and TCP probes of the endpoint,
+// which also run between the pings and wake the agent up as soon as the endpoint can be reached.
 package hibernation
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/health"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
 	"github.com/carlescere/scheduler"
 	"github.com/cihub/seelog"
 )
@@ -39,6 +44,13 @@ type Hibernate struct {
 
 	seelogger seelog.LoggerInterface
 	isLogged  bool
+
+	context context.T
+	probe   func(appconfig.SsmagentConfig) error
+
+	// status is written to the status file on each change
+	status     Status
+	statusLock sync.Mutex
 }
 
 // modeChan is a channel that tracks the status of the agent
@@ -50,6 +62,13 @@ const (
 	maxBackOffInterval = 60 * 60 //Minute conversion
 	multiplier         = 2
 	initialPingRate    = 5 * 60 //Seconds
+
+	// probeInterval is how often the endpoint is probed between the health pings
+	probeInterval = time.Minute
+	// wakeCheckInterval is how often a wake request from ssm-cli is looked for
+	wakeCheckInterval = 5 * time.Second
+	// probeProgressLimit is how long a probe may take before the agent is hung
+	probeProgressLimit = probeTimeout + 2*time.Minute
 )
 
 // NewHibernateMode creates an object of type NewHibernateMode
@@ -59,7 +78,7 @@ func NewHibernateMode(healthModule *health.HealthCheck, context context.T) *Hibe
 	logger := log.GetLogger(context.Log(), seelogConfig)
 	logger.Info("Agent enters hibernate mode. Reducing logging...")
 
-	return &Hibernate{
+	m := &Hibernate{
 		healthModule:        healthModule,
 		currentMode:         health.Passive,
 		seelogger:           logger,
@@ -68,15 +87,31 @@ func NewHibernateMode(healthModule *health.HealthCheck, context context.T) *Hibe
 		maxInterval:         maxBackOffInterval,
 		scheduleBackOff:     scheduleBackOffStrategy,
 		schedulePing:        scheduleEmptyHealthPing,
+		context:             context,
+		probe:               probeEndpoint,
 	}
+	m.updateStatus(func(status *Status) {
+		*status = Status{State: StateHibernating, Since: time.Now().UTC(), HealthPingIntervalSeconds: initialPingRate}
+	})
+	return m
 }
 
 // ExecuteHibernation Starts the hibernate mode by blocking agent start and by scheduling health pings
 func ExecuteHibernation(m *Hibernate) health.AgentState {
+	// Probe the endpoint meanwhile, and on the requests of ssm-cli
+	stopProbes := make(chan bool)
+	defer close(stopProbes)
+	go m.watchEndpoint(stopProbes)
+
 	next := time.Duration(initialPingRate) * time.Second
 	// Wait backoff time and then schedule health pings
-	<-time.After(next)
-	m.scheduleBackOff(m)
+	go func() {
+		select {
+		case <-time.After(next):
+			m.scheduleBackOff(m)
+		case <-stopProbes:
+		}
+	}()
 
 loop:
 	// using an infinite loop to block the agent from starting
@@ -87,6 +122,7 @@ loop:
 		case health.Active:
 			//Agent mode is now active. Agent can start. Exit loop
 			m.stopEmptyPing()
+			m.exitHibernation()
 			m.seelogger.Flush()
 			return status //returning status for testing purposes.
 		case health.Passive:
@@ -98,14 +134,107 @@ loop:
 }
 
 func (m *Hibernate) healthCheck() {
+	// skip the API call while the endpoint can't be reached at all
+	if err := m.probe(m.context.AppConfig()); err != nil {
+		m.recordProbe(err.Error())
+		if !m.isLogged {
+			m.seelogger.Errorf("Health ping skipped, %v", err)
+			m.isLogged = true
+		}
+		modeChan <- health.Passive
+		return
+	}
+
 	status, err := health.GetAgentState(m.healthModule)
-	if err != nil && !m.isLogged {
-		m.seelogger.Errorf("Health ping failed with error - %v", err.Error())
-		m.isLogged = true
+	m.updateStatus(func(s *Status) { s.HealthPings++ })
+	if err != nil {
+		m.recordProbe(fmt.Sprintf("health ping failed: %v", err))
+		if !m.isLogged {
+			m.seelogger.Errorf("Health ping failed with error - %v", err.Error())
+			m.isLogged = true
+		}
+	} else {
+		m.recordProbe("health ping succeeded")
 	}
 	modeChan <- status
 }
 
+// watchEndpoint probes the endpoint every probeInterval and pings the health service as soon as the endpoint can
+// be reached again, or when ssm-cli requests it, until stop is closed.
+func (m *Hibernate) watchEndpoint(stop chan bool) {
+	ticker := time.NewTicker(wakeCheckInterval)
+	defer ticker.Stop()
+	defer watchdog.StopWatching(hibernateMode)
+	lastProbe := time.Now()
+	reachable := true
+	for {
+		// the watchdog restarts the agent if the probes hang
+		watchdog.Progress(hibernateMode, probeProgressLimit)
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if wakeRequested() {
+			m.seelogger.Info("Health ping requested by ssm-cli")
+			go m.healthCheck()
+			continue
+		}
+		if time.Since(lastProbe) < probeInterval {
+			continue
+		}
+		lastProbe = time.Now()
+		err := m.probe(m.context.AppConfig())
+		if err != nil {
+			m.recordProbe(err.Error())
+		} else if !reachable {
+			m.seelogger.Info("Endpoint reachable again, pinging the health service")
+			go m.healthCheck()
+		} else {
+			m.recordProbe("endpoint reachable")
+		}
+		reachable = err == nil
+	}
+}
+
+// exitHibernation reports the agent leaves hibernate mode
+func (m *Hibernate) exitHibernation() {
+	m.statusLock.Lock()
+	hibernated := time.Since(m.status.Since)
+	m.statusLock.Unlock()
+	m.seelogger.Infof("Agent exits hibernate mode after %v", hibernated)
+	m.context.Log().Infof("Agent exits hibernate mode after %v, the health service can be reached", hibernated)
+	m.updateStatus(func(status *Status) {
+		status.State = StateActive
+		status.Since = time.Now().UTC()
+	})
+}
+
+// exited returns true once the agent left hibernate mode
+func (m *Hibernate) exited() bool {
+	m.statusLock.Lock()
+	defer m.statusLock.Unlock()
+	return m.status.State == StateActive
+}
+
+// recordProbe records the outcome of a probe or a health ping in the status
+func (m *Hibernate) recordProbe(result string) {
+	m.updateStatus(func(status *Status) {
+		status.LastProbe = time.Now().UTC()
+		status.LastProbeResult = result
+	})
+}
+
+// updateStatus changes the status and writes the status file
+func (m *Hibernate) updateStatus(update func(status *Status)) {
+	m.statusLock.Lock()
+	defer m.statusLock.Unlock()
+	update(&m.status)
+	if err := writeStatus(m.status); err != nil {
+		m.seelogger.Errorf("Unable to write the hibernation status. %v", err)
+	}
+}
+
 func (m *Hibernate) stopEmptyPing() {
 	if m.hibernateJob != nil {
 		m.hibernateJob.Quit <- true
@@ -123,7 +252,7 @@ func scheduleEmptyHealthPing(m *Hibernate) {
 func scheduleBackOffStrategy(m *Hibernate) {
 	// Scheduler to calculate backoffInterval and call this function in that time every backoff return time.
 	// Also stop the current ping scheduler
-	if m.currentPingInterval == m.maxInterval {
+	if m.currentPingInterval == m.maxInterval || m.exited() {
 		return
 	}
 	m.stopEmptyPing()
@@ -133,6 +262,7 @@ func scheduleBackOffStrategy(m *Hibernate) {
 
 	}
 	m.schedulePing(m)
+	m.updateStatus(func(status *Status) { status.HealthPingIntervalSeconds = m.currentPingInterval })
 	backoffInterval := m.currentPingInterval * backOffRate
 
 	next := time.Duration(backoffInterval) * time.Second
@@ -22,6 +22,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/health"
 )
@@ -32,6 +33,8 @@ func TestHibernation_ExecuteHibernation_AgentTurnsActive(t *testing.T) {
 
 	hibernate := NewHibernateMode(healthMock, ctx)
 	hibernate.scheduleBackOff = fakeScheduler
+	// the endpoint can be reached, don't probe it
+	hibernate.probe = func(appconfig.SsmagentConfig) error { return nil }
 	for i := 0; i < 4; i++ {
 		modeChan <- health.Passive
 	}
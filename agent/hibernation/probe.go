@@ -0,0 +1,69 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package hibernation is responsible for the agent in hibernate mode.
+package hibernation
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/health"
+)
+
+// probeTimeout is how long the connection to the endpoint may take
+const probeTimeout = 10 * time.Second
+
+var (
+	lookupHost  = net.LookupHost
+	dialTimeout = net.DialTimeout
+	getenv      = os.Getenv
+)
+
+// probeEndpoint checks the SSM endpoint can be reached before pinging the health service: it resolves its name,
+// then opens a connection to it. Both are much cheaper than the API call and don't count against the API limits.
+// The probe passes when it can't tell, e.g. behind a proxy or without a known region.
+func probeEndpoint(config appconfig.SsmagentConfig) error {
+	if behindProxy() {
+		return nil
+	}
+	address, err := health.EndpointAddress(config)
+	if err != nil {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil
+	}
+	if _, err = lookupHost(host); err != nil {
+		return fmt.Errorf("DNS lookup of %v failed: %v", host, err)
+	}
+	conn, err := dialTimeout("tcp", address, probeTimeout)
+	if err != nil {
+		return fmt.Errorf("connection to %v failed: %v", address, err)
+	}
+	return conn.Close()
+}
+
+// behindProxy returns true if the agent reaches the endpoints through a proxy, the endpoint isn't reachable directly
+func behindProxy() bool {
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		if getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
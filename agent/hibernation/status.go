@@ -0,0 +1,113 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package hibernation is responsible for the agent in hibernate mode.
+package hibernation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	// StatusFileName is the file in the data folder the agent writes its hibernation status to
+	StatusFileName = "hibernation-status.json"
+	// wakeRequestFileName is the file in the data folder requesting a health ping from a hibernating agent
+	wakeRequestFileName = "hibernation-wake"
+
+	// StateHibernating is the state of an agent waiting for the health service to be reachable
+	StateHibernating = "Hibernating"
+	// StateActive is the state of an agent that exited hibernate mode
+	StateActive = "Active"
+)
+
+// Status is the hibernation status of the agent, which tells a hibernating agent from a stopped one.
+type Status struct {
+	State string
+	// Since is when the agent entered the state
+	Since time.Time
+	// LastProbe is when the agent last checked the health service could be reached, and LastProbeResult the outcome
+	LastProbe       time.Time `json:",omitempty"`
+	LastProbeResult string    `json:",omitempty"`
+	// HealthPings is the number of health pings sent since the agent started hibernating
+	HealthPings int
+	// HealthPingIntervalSeconds is the current interval of the backed off health pings
+	HealthPingIntervalSeconds int `json:",omitempty"`
+}
+
+// staleAfter is how long after its last probe a hibernating agent is likely stopped, it probes every probeInterval
+const staleAfter = 3 * probeInterval
+
+var (
+	// statusPath returns the path of the status file
+	statusPath = func() string { return filepath.Join(appconfig.DefaultDataStorePath, StatusFileName) }
+	// wakeRequestPath returns the path of the wake request
+	wakeRequestPath = func() string { return filepath.Join(appconfig.DefaultDataStorePath, wakeRequestFileName) }
+)
+
+// Stale returns true if the agent is hibernating but hasn't probed the endpoint for a while, it is likely stopped.
+func (status Status) Stale(now time.Time) bool {
+	if status.State != StateHibernating {
+		return false
+	}
+	last := status.LastProbe
+	if last.Before(status.Since) {
+		last = status.Since
+	}
+	return now.Sub(last) > staleAfter
+}
+
+// ReadStatus returns the hibernation status written by the agent, an error satisfying os.IsNotExist if the agent
+// never hibernated.
+func ReadStatus() (status Status, err error) {
+	content, err := ioutil.ReadFile(statusPath())
+	if err != nil {
+		return status, err
+	}
+	err = json.Unmarshal(content, &status)
+	return status, err
+}
+
+// RequestWake asks a hibernating agent to check the health service now rather than at its next backed off ping.
+func RequestWake() error {
+	if err := os.MkdirAll(filepath.Dir(wakeRequestPath()), appconfig.ReadWriteExecuteAccess); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(wakeRequestPath(), []byte{}, appconfig.ReadWriteAccess)
+}
+
+// wakeRequested returns true and removes the request if a wake was requested
+func wakeRequested() bool {
+	if _, err := os.Stat(wakeRequestPath()); err != nil {
+		return false
+	}
+	os.Remove(wakeRequestPath())
+	return true
+}
+
+// writeStatus writes the status file
+func writeStatus(status Status) error {
+	content, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(statusPath()), appconfig.ReadWriteExecuteAccess); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(statusPath(), content, appconfig.ReadWriteAccess)
+}
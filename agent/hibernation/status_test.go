@@ -0,0 +1,119 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package hibernation
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/health"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	// keep the status files of the tests out of the data folder
+	dir, err := ioutil.TempDir("", "hibernation")
+	if err != nil {
+		panic(err)
+	}
+	statusPath = func() string { return filepath.Join(dir, StatusFileName) }
+	wakeRequestPath = func() string { return filepath.Join(dir, wakeRequestFileName) }
+	// the health module creates its client with the identity of the instance, don't look it up
+	platform.SetInstanceID("i-1234567890")
+	platform.SetRegion("us-east-1")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestStatusFile(t *testing.T) {
+	ctx := context.NewMockDefault()
+	// the health module isn't used while the probes fail
+	hibernate := NewHibernateMode(nil, ctx)
+
+	status, err := ReadStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, StateHibernating, status.State)
+	assert.Equal(t, initialPingRate, status.HealthPingIntervalSeconds)
+
+	// the health ping is skipped while the endpoint can't be reached
+	hibernate.probe = func(appconfig.SsmagentConfig) error { return errors.New("DNS lookup of ssm failed") }
+	hibernate.healthCheck()
+	assert.Equal(t, health.Passive, <-modeChan)
+	status, err = ReadStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, "DNS lookup of ssm failed", status.LastProbeResult)
+	assert.Equal(t, 0, status.HealthPings)
+
+	hibernate.exitHibernation()
+	status, err = ReadStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, StateActive, status.State)
+	assert.True(t, hibernate.exited())
+}
+
+func TestRequestWake(t *testing.T) {
+	assert.False(t, wakeRequested())
+	assert.NoError(t, RequestWake())
+	assert.True(t, wakeRequested())
+	assert.False(t, wakeRequested(), "the request is removed")
+}
+
+func TestProbeEndpoint(t *testing.T) {
+	defer func() { lookupHost, dialTimeout, getenv = net.LookupHost, net.DialTimeout, os.Getenv }()
+	getenv = func(string) string { return "" }
+	config := appconfig.DefaultConfig()
+	config.Ssm.Endpoint = "ssm.example.com"
+
+	lookupHost = func(host string) ([]string, error) { return nil, errors.New("no such host") }
+	assert.EqualError(t, probeEndpoint(config), "DNS lookup of ssm.example.com failed: no such host")
+
+	lookupHost = func(host string) ([]string, error) { return []string{"192.0.2.1"}, nil }
+	var address string
+	dialTimeout = func(network, addr string, timeout time.Duration) (net.Conn, error) {
+		address = addr
+		return nil, errors.New("connection refused")
+	}
+	assert.EqualError(t, probeEndpoint(config), "connection to ssm.example.com:443 failed: connection refused")
+	assert.Equal(t, "ssm.example.com:443", address)
+
+	getenv = func(name string) string {
+		if name == "https_proxy" {
+			return "http://proxy:3128"
+		}
+		return ""
+	}
+	assert.NoError(t, probeEndpoint(config), "the endpoint isn't probed behind a proxy")
+}
+
+func TestStatusStale(t *testing.T) {
+	since := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	status := Status{State: StateHibernating, Since: since}
+	assert.False(t, status.Stale(since.Add(time.Minute)))
+	assert.True(t, status.Stale(since.Add(time.Hour)))
+
+	status.LastProbe = since.Add(59 * time.Minute)
+	assert.False(t, status.Stale(since.Add(time.Hour)), "the agent probed recently")
+
+	status.State = StateActive
+	assert.False(t, status.Stale(since.Add(24*time.Hour)))
+}
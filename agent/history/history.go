@@ -0,0 +1,259 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package history keeps a local history of the documents the agent ran, commands and associations, for the
+// analysis of past executions without the orchestration folders.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// historyDirName is the folder in the data folder the history is written to, one file per execution
+	historyDirName = "history"
+
+	// maxOutputLength is the length the output of the steps is truncated to in the history
+	maxOutputLength = 2500
+
+	// entryTimeFormat is the format of the end of the executions in the names of the history files, which sort
+	// the files from the oldest to the newest execution
+	entryTimeFormat = "20060102T150405.000000000Z"
+)
+
+// historyDir returns the folder of the history files
+var historyDir = func() string { return filepath.Join(appconfig.DefaultDataStorePath, historyDirName) }
+
+// Entry is an execution of a document in the history.
+type Entry struct {
+	DocumentID      string
+	DocumentName    string
+	DocumentVersion string `json:",omitempty"`
+	DocumentType    contracts.DocumentType
+	// CommandID is the command of the execution of a command, AssociationID the association of an association
+	CommandID     string `json:",omitempty"`
+	AssociationID string `json:",omitempty"`
+	Status        contracts.ResultStatus
+	StartDateTime time.Time
+	EndDateTime   time.Time
+	// DurationSeconds is the time from the start of the first step to the end of the last one
+	DurationSeconds float64
+	Steps           []Step
+}
+
+// Step is a step of an execution in the history, with its output truncated.
+type Step struct {
+	Name            string
+	Action          string
+	Status          contracts.ResultStatus
+	Code            int
+	StartDateTime   time.Time
+	EndDateTime     time.Time
+	DurationSeconds float64
+	Output          string `json:",omitempty"`
+}
+
+// Filter selects the executions of the history, the empty fields select them all.
+type Filter struct {
+	DocumentName  string
+	CommandID     string
+	AssociationID string
+	Status        contracts.ResultStatus
+	// Since selects the executions that ended after it
+	Since time.Time
+	// MaxResults is the number of newest executions returned, 0 returns them all
+	MaxResults int
+}
+
+// NewEntry returns the entry of the final result of a document.
+func NewEntry(documentID string, documentType contracts.DocumentType, result contracts.DocumentResult) Entry {
+	entry := Entry{
+		DocumentID:      documentID,
+		DocumentName:    result.DocumentName,
+		DocumentVersion: result.DocumentVersion,
+		DocumentType:    documentType,
+		AssociationID:   result.AssociationID,
+		Status:          result.Status,
+		Steps:           []Step{},
+	}
+	if result.AssociationID == "" {
+		entry.CommandID = commandID(result.MessageID)
+	}
+	for _, plugin := range result.PluginResults {
+		step := Step{
+			Name:          plugin.PluginID,
+			Action:        plugin.PluginName,
+			Status:        plugin.Status,
+			Code:          plugin.Code,
+			StartDateTime: plugin.StartDateTime,
+			EndDateTime:   plugin.EndDateTime,
+			Output:        truncate(fmt.Sprint(outputOf(plugin))),
+		}
+		if !step.StartDateTime.IsZero() && step.EndDateTime.After(step.StartDateTime) {
+			step.DurationSeconds = step.EndDateTime.Sub(step.StartDateTime).Seconds()
+		}
+		if !step.StartDateTime.IsZero() && (entry.StartDateTime.IsZero() || step.StartDateTime.Before(entry.StartDateTime)) {
+			entry.StartDateTime = step.StartDateTime
+		}
+		if step.EndDateTime.After(entry.EndDateTime) {
+			entry.EndDateTime = step.EndDateTime
+		}
+		entry.Steps = append(entry.Steps, step)
+	}
+	sort.SliceStable(entry.Steps, func(i, j int) bool { return entry.Steps[i].StartDateTime.Before(entry.Steps[j].StartDateTime) })
+	if entry.EndDateTime.IsZero() {
+		entry.EndDateTime = time.Now()
+	}
+	if !entry.StartDateTime.IsZero() {
+		entry.DurationSeconds = entry.EndDateTime.Sub(entry.StartDateTime).Seconds()
+	}
+	return entry
+}
+
+// commandID returns the command id of the message id of a command, aws.ssm.<command id>.<instance id>
+func commandID(messageID string) string {
+	if parts := strings.Split(messageID, "."); len(parts) == 4 {
+		return parts[2]
+	}
+	return messageID
+}
+
+// outputOf returns the output of a step, its console output when the plugin didn't set one
+func outputOf(plugin *contracts.PluginResult) interface{} {
+	if plugin.Output != nil && plugin.Output != "" {
+		return plugin.Output
+	}
+	return plugin.StandardOutput + plugin.StandardError
+}
+
+// truncate truncates the output to maxOutputLength, without splitting a UTF-8 sequence
+func truncate(output string) string {
+	if len(output) <= maxOutputLength {
+		return output
+	}
+	length := maxOutputLength
+	for length > 0 && !utf8.RuneStart(output[length]) {
+		length--
+	}
+	return output[:length]
+}
+
+// Record writes the execution to the history, then removes the executions older than Agent.HistoryRetentionDays
+// and the oldest ones beyond Agent.HistoryMaxEntries. Agent.HistoryRetentionDays 0 doesn't record the executions.
+func Record(log log.T, config appconfig.SsmagentConfig, entry Entry) {
+	if config.Agent.HistoryRetentionDays <= 0 {
+		return
+	}
+	content, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		log.Errorf("failed to record the execution of %v in the history: %v", entry.DocumentID, err)
+		return
+	}
+	if err = os.MkdirAll(historyDir(), appconfig.ReadWriteExecuteAccess); err != nil {
+		log.Errorf("failed to create the history folder: %v", err)
+		return
+	}
+	fileName := fmt.Sprintf("%v_%v.json", entry.EndDateTime.UTC().Format(entryTimeFormat), entry.DocumentID)
+	// a query reading the history, or the agent after a crash, finds the file whole or not at all
+	if err = fileutil.WriteAtomically(filepath.Join(historyDir(), fileName), content, appconfig.ReadWriteAccess, false); err != nil {
+		log.Errorf("failed to record the execution of %v in the history: %v", entry.DocumentID, err)
+		return
+	}
+	prune(log, time.Now().AddDate(0, 0, -config.Agent.HistoryRetentionDays), config.Agent.HistoryMaxEntries)
+}
+
+// prune removes the executions that ended before the cutoff and the oldest ones beyond maxEntries
+func prune(log log.T, cutoff time.Time, maxEntries int) {
+	fileNames := entryFileNames()
+	for i, fileName := range fileNames {
+		if i >= len(fileNames)-maxEntries && !entryEnd(fileName).Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(historyDir(), fileName)); err != nil {
+			log.Warnf("failed to remove %v from the history: %v", fileName, err)
+		}
+	}
+}
+
+// entryFileNames returns the names of the history files, from the oldest to the newest execution
+func entryFileNames() []string {
+	files, err := ioutil.ReadDir(historyDir())
+	if err != nil {
+		return nil
+	}
+	fileNames := []string{}
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			fileNames = append(fileNames, file.Name())
+		}
+	}
+	sort.Strings(fileNames)
+	return fileNames
+}
+
+// entryEnd returns the end of the execution of a history file, the zero time for the files named otherwise
+func entryEnd(fileName string) time.Time {
+	end, _ := time.Parse(entryTimeFormat, strings.SplitN(fileName, "_", 2)[0])
+	return end
+}
+
+// Query returns the executions of the history the filter selects, from the newest to the oldest.
+func Query(filter Filter) ([]Entry, error) {
+	if _, err := os.Stat(historyDir()); os.IsNotExist(err) {
+		return []Entry{}, nil
+	}
+	fileNames := entryFileNames()
+	entries := []Entry{}
+	for i := len(fileNames) - 1; i >= 0; i-- {
+		if filter.MaxResults > 0 && len(entries) >= filter.MaxResults {
+			break
+		}
+		if !filter.Since.IsZero() && entryEnd(fileNames[i]).Before(filter.Since) {
+			break
+		}
+		content, err := ioutil.ReadFile(filepath.Join(historyDir(), fileNames[i]))
+		if err != nil {
+			return nil, err
+		}
+		var entry Entry
+		if err = json.Unmarshal(content, &entry); err != nil {
+			// a file corrupted outside of the agent
+			continue
+		}
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// matches returns true if the filter selects the execution
+func (filter Filter) matches(entry Entry) bool {
+	return (filter.DocumentName == "" || filter.DocumentName == entry.DocumentName) &&
+		(filter.CommandID == "" || filter.CommandID == entry.CommandID) &&
+		(filter.AssociationID == "" || filter.AssociationID == entry.AssociationID) &&
+		(filter.Status == "" || strings.EqualFold(string(filter.Status), string(entry.Status)))
+}
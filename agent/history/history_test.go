@@ -0,0 +1,150 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// useTempHistory keeps the history of a test out of the data folder
+func useTempHistory(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "history")
+	assert.NoError(t, err)
+	origHistoryDir := historyDir
+	historyDir = func() string { return dir }
+	return func() {
+		historyDir = origHistoryDir
+		os.RemoveAll(dir)
+	}
+}
+
+func historyConfig(retentionDays, maxEntries int) appconfig.SsmagentConfig {
+	config := appconfig.SsmagentConfig{}
+	config.Agent.HistoryRetentionDays = retentionDays
+	config.Agent.HistoryMaxEntries = maxEntries
+	return config
+}
+
+func testEntry(documentID, documentName string, status contracts.ResultStatus, end time.Time) Entry {
+	return Entry{DocumentID: documentID, DocumentName: documentName, Status: status, EndDateTime: end, Steps: []Step{}}
+}
+
+func TestNewEntry(t *testing.T) {
+	start := time.Date(2017, 6, 1, 10, 0, 0, 0, time.UTC)
+	result := contracts.DocumentResult{
+		DocumentName: "AWS-RunShellScript",
+		MessageID:    "aws.ssm.c7a1b5f2.i-0123456789",
+		Status:       contracts.ResultStatusFailed,
+		PluginResults: map[string]*contracts.PluginResult{
+			"second": {PluginID: "second", PluginName: "aws:runShellScript", Status: contracts.ResultStatusFailed, Code: 1,
+				StartDateTime: start.Add(time.Minute), EndDateTime: start.Add(3 * time.Minute), StandardError: "not found"},
+			"first": {PluginID: "first", PluginName: "aws:runShellScript", Status: contracts.ResultStatusSuccess,
+				StartDateTime: start, EndDateTime: start.Add(time.Minute), Output: strings.Repeat("x", 3000)},
+		},
+	}
+
+	entry := NewEntry("doc-id", contracts.SendCommand, result)
+
+	assert.Equal(t, "c7a1b5f2", entry.CommandID)
+	assert.Equal(t, contracts.ResultStatusFailed, entry.Status)
+	assert.Equal(t, start, entry.StartDateTime)
+	assert.Equal(t, start.Add(3*time.Minute), entry.EndDateTime)
+	assert.Equal(t, float64(180), entry.DurationSeconds)
+	assert.Len(t, entry.Steps, 2)
+	assert.Equal(t, "first", entry.Steps[0].Name)
+	assert.Len(t, entry.Steps[0].Output, maxOutputLength)
+	assert.Equal(t, "second", entry.Steps[1].Name)
+	assert.Equal(t, "not found", entry.Steps[1].Output)
+	assert.Equal(t, float64(120), entry.Steps[1].DurationSeconds)
+}
+
+func TestRecordAndQuery(t *testing.T) {
+	defer useTempHistory(t)()
+	now := time.Now()
+	config := historyConfig(30, 10)
+	Record(log.NewMockLog(), config, testEntry("a", "AWS-RunShellScript", contracts.ResultStatusSuccess, now.Add(-3*time.Hour)))
+	Record(log.NewMockLog(), config, testEntry("b", "AWS-RunPatchBaseline", contracts.ResultStatusFailed, now.Add(-2*time.Hour)))
+	Record(log.NewMockLog(), config, testEntry("c", "AWS-RunShellScript", contracts.ResultStatusFailed, now.Add(-time.Hour)))
+
+	entries, err := Query(Filter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "b", "a"}, documentIDs(entries))
+
+	entries, _ = Query(Filter{DocumentName: "AWS-RunShellScript"})
+	assert.Equal(t, []string{"c", "a"}, documentIDs(entries))
+
+	entries, _ = Query(Filter{Status: "failed", MaxResults: 1})
+	assert.Equal(t, []string{"c"}, documentIDs(entries))
+
+	entries, _ = Query(Filter{Since: now.Add(-150 * time.Minute)})
+	assert.Equal(t, []string{"c", "b"}, documentIDs(entries))
+}
+
+func TestRecordPrunes(t *testing.T) {
+	defer useTempHistory(t)()
+	now := time.Now()
+	config := historyConfig(1, 2)
+	Record(log.NewMockLog(), config, testEntry("expired", "doc", contracts.ResultStatusSuccess, now.Add(-48*time.Hour)))
+	Record(log.NewMockLog(), config, testEntry("a", "doc", contracts.ResultStatusSuccess, now.Add(-3*time.Hour)))
+	Record(log.NewMockLog(), config, testEntry("b", "doc", contracts.ResultStatusSuccess, now.Add(-2*time.Hour)))
+	Record(log.NewMockLog(), config, testEntry("c", "doc", contracts.ResultStatusSuccess, now.Add(-time.Hour)))
+
+	entries, err := Query(Filter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "b"}, documentIDs(entries))
+}
+
+func TestRecordLeavesNoTemporaryFiles(t *testing.T) {
+	defer useTempHistory(t)()
+	Record(log.NewMockLog(), historyConfig(30, 10), testEntry("a", "doc", contracts.ResultStatusSuccess, time.Now()))
+
+	files, err := ioutil.ReadDir(historyDir())
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), "_a.json"))
+}
+
+func TestRecordDisabled(t *testing.T) {
+	defer useTempHistory(t)()
+	Record(log.NewMockLog(), historyConfig(0, 10), testEntry("a", "doc", contracts.ResultStatusSuccess, time.Now()))
+
+	entries, err := Query(Filter{})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func documentIDs(entries []Entry) []string {
+	ids := []string{}
+	for _, entry := range entries {
+		ids = append(ids, entry.DocumentID)
+	}
+	return ids
+}
+
+func TestTruncateKeepsWholeRunes(t *testing.T) {
+	output := truncate(strings.Repeat("x", maxOutputLength-1) + "é")
+
+	assert.True(t, utf8.ValidString(output))
+	assert.Len(t, output, maxOutputLength-1)
+}
@@ -0,0 +1,183 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package integration runs the amazon-ssm-agent binary against the fake services of package fakeaws.
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/integration/fakeaws"
+)
+
+const (
+	// agentPackage is the import path of the agent binary.
+	agentPackage = "github.com/aws/amazon-ssm-agent/agent"
+
+	// workerPackage is the import path of the document worker binary the agent runs the documents in.
+	workerPackage = "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/worker"
+
+	// region is the region the agent is started in.
+	region = "us-east-1"
+
+	// workerName is the name of the document worker binary.
+	workerName = "ssm-document-worker"
+
+	// stopTimeout is how long Stop waits for the agent to exit before killing it.
+	stopTimeout = 30 * time.Second
+)
+
+// Agent is a running amazon-ssm-agent process.
+type Agent struct {
+	cmd    *exec.Cmd
+	output *syncBuffer
+	exited chan error
+}
+
+// Build compiles the agent and document worker binaries into dir and returns the path of the agent.
+func Build(dir string) (agent string, err error) {
+	agent = executable(dir, "amazon-ssm-agent")
+	if err = build(agent, agentPackage); err != nil {
+		return "", err
+	}
+	return agent, build(executable(dir, workerName), workerPackage)
+}
+
+// InstallWorker puts the document worker built into dir where the agent launches it from,
+// and returns a function putting back the worker that was installed before.
+func InstallWorker(dir string) (restore func() error, err error) {
+	installed := appconfig.DefaultDocumentWorker
+	backup := installed + ".integration"
+	if _, err = os.Stat(installed); err == nil {
+		if err = os.Rename(installed, backup); err != nil {
+			return nil, err
+		}
+	}
+	restore = func() error {
+		if _, err := os.Stat(backup); err != nil {
+			return os.Remove(installed)
+		}
+		return os.Rename(backup, installed)
+	}
+
+	content, err := ioutil.ReadFile(executable(dir, workerName))
+	if err == nil {
+		err = ioutil.WriteFile(installed, content, 0755)
+	}
+	if err != nil {
+		restore()
+		return nil, err
+	}
+	return restore, nil
+}
+
+func build(path string, pkg string) error {
+	if output, err := exec.Command("go", "build", "-o", path, pkg).CombinedOutput(); err != nil {
+		return fmt.Errorf("unable to build %v: %v\n%s", pkg, err, output)
+	}
+	return nil
+}
+
+// executable returns the path of the named executable in dir.
+func executable(dir string, name string) string {
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(dir, name)
+}
+
+// Start runs the agent binary as the instance, talking to the fake services instead of AWS.
+// The agent keeps its state in the usual data directories, so it must run with the privileges of the service.
+func Start(binary string, instanceID string, mds *fakeaws.MDS, ssm *fakeaws.SSM) (*Agent, error) {
+	agent := &Agent{
+		cmd:    exec.Command(binary, "-i", instanceID, "-r", region),
+		output: &syncBuffer{},
+		exited: make(chan error, 1),
+	}
+	agent.cmd.Env = append(os.Environ(),
+		appconfig.EnvironmentPrefix+"MDS_ENDPOINT="+mds.URL,
+		appconfig.EnvironmentPrefix+"SSM_ENDPOINT="+ssm.URL,
+		"AWS_ACCESS_KEY_ID=AKIAFAKEINTEGRATION",
+		"AWS_SECRET_ACCESS_KEY=fake",
+		"AWS_REGION="+region,
+	)
+	agent.cmd.Stdout = agent.output
+	agent.cmd.Stderr = agent.output
+
+	if err := agent.cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() { agent.exited <- agent.cmd.Wait() }()
+	return agent, nil
+}
+
+// Stop interrupts the agent and waits for it to exit, killing it if it takes too long.
+func (agent *Agent) Stop() error {
+	if err := agent.cmd.Process.Signal(os.Interrupt); err != nil {
+		agent.cmd.Process.Kill()
+	}
+	select {
+	case err := <-agent.exited:
+		return err
+	case <-time.After(stopTimeout):
+		agent.cmd.Process.Kill()
+		<-agent.exited
+		return fmt.Errorf("agent did not stop within %v", stopTimeout)
+	}
+}
+
+// Output returns what the agent wrote to stdout and stderr so far.
+func (agent *Agent) Output() string {
+	return agent.output.String()
+}
+
+// Eventually polls the condition until it holds or the timeout expires, and tells whether it held.
+func Eventually(timeout time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if condition() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of the agent's stdout and stderr.
+type syncBuffer struct {
+	buffer bytes.Buffer
+	m      sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.buffer.String()
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fakeaws
+
+import (
+	"testing"
+	"time"
+
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+	"github.com/stretchr/testify/assert"
+)
+
+const instanceID = "i-1234567890abcdef0"
+
+func config(endpoint string) *aws.Config {
+	return &aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Endpoint:    aws.String(endpoint),
+		Region:      aws.String("us-east-1"),
+	}
+}
+
+func TestMDSDeliversCommandsAndRecordsReplies(t *testing.T) {
+	longPollTimeout = 10 * time.Millisecond
+	mds := NewMDS()
+	defer mds.Close()
+	client := ssmmds.New(session.New(config(mds.URL)))
+
+	output, err := client.GetMessages(&ssmmds.GetMessagesInput{
+		Destination:       aws.String(instanceID),
+		MessagesRequestId: aws.String("a6b3c2f4-5e1d-4c7b-9a8f-0e1d2c3b4a59"),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, output.Messages)
+
+	messageID, err := mds.SendCommand(instanceID, messageContracts.SendCommandPayload{CommandID: "c7b0d5f2-1e34-4b8a-9c6d-2f1e0a9b8c7d", DocumentName: "AWS-RunShellScript"})
+	assert.NoError(t, err)
+	output, err = client.GetMessages(&ssmmds.GetMessagesInput{
+		Destination:       aws.String(instanceID),
+		MessagesRequestId: aws.String("a6b3c2f4-5e1d-4c7b-9a8f-0e1d2c3b4a5a"),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, output.Messages, 1)
+	assert.Equal(t, messageID, aws.StringValue(output.Messages[0].MessageId))
+	assert.Equal(t, sendCommandTopic, aws.StringValue(output.Messages[0].Topic))
+
+	_, err = client.AcknowledgeMessage(&ssmmds.AcknowledgeMessageInput{MessageId: aws.String(messageID)})
+	assert.NoError(t, err)
+	_, err = client.SendReply(&ssmmds.SendReplyInput{
+		MessageId: aws.String(messageID),
+		Payload:   aws.String(`{"documentStatus":"Success"}`),
+		ReplyId:   aws.String("b8c9d0e1-f2a3-4b4c-8d5e-6f7a8b9c0d1e"),
+	})
+	assert.NoError(t, err)
+
+	assert.True(t, mds.Acknowledged(messageID))
+	assert.False(t, mds.Failed(messageID))
+	replies, err := mds.Replies(messageID)
+	assert.NoError(t, err)
+	assert.Len(t, replies, 1)
+	assert.Equal(t, messageContracts.SendReplyPayload{DocumentStatus: "Success"}, replies[0].Payload)
+}
+
+func TestSSMServesAssociationsAndRecordsStatuses(t *testing.T) {
+	svc := NewSSM()
+	defer svc.Close()
+	client := ssm.New(session.New(config(svc.URL)))
+
+	svc.AddDocument("Greet", `{"schemaVersion":"2.2","mainSteps":[]}`)
+	associationID := svc.Associate(instanceID, "Greet", nil, "")
+
+	list, err := client.ListInstanceAssociations(&ssm.ListInstanceAssociationsInput{InstanceId: aws.String(instanceID)})
+	assert.NoError(t, err)
+	assert.Len(t, list.Associations, 1)
+	assert.Equal(t, associationID, aws.StringValue(list.Associations[0].AssociationId))
+	assert.Nil(t, list.Associations[0].ScheduleExpression)
+	assert.Equal(t, "Associated", aws.StringValue(list.Associations[0].DetailedStatus))
+
+	document, err := client.GetDocument(&ssm.GetDocumentInput{Name: aws.String("Greet"), DocumentVersion: aws.String("1")})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"schemaVersion":"2.2","mainSteps":[]}`, aws.StringValue(document.Content))
+
+	_, err = client.GetDocument(&ssm.GetDocumentInput{Name: aws.String("Missing")})
+	assert.Error(t, err)
+
+	_, err = client.UpdateInstanceAssociationStatus(&ssm.UpdateInstanceAssociationStatusInput{
+		AssociationId: aws.String(associationID),
+		InstanceId:    aws.String(instanceID),
+		ExecutionResult: &ssm.InstanceAssociationExecutionResult{
+			ExecutionDate:    aws.Time(time.Now()),
+			ExecutionSummary: aws.String("1 out of 1 plugin processed, 1 success"),
+			Status:           aws.String("Success"),
+		},
+	})
+	assert.NoError(t, err)
+
+	statuses, err := svc.AssociationStatuses(associationID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Success"}, statuses)
+
+	list, err = client.ListInstanceAssociations(&ssm.ListInstanceAssociationsInput{InstanceId: aws.String(instanceID)})
+	assert.NoError(t, err)
+	assert.Equal(t, "Success", aws.StringValue(list.Associations[0].DetailedStatus))
+}
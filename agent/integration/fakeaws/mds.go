@@ -0,0 +1,180 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fakeaws
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+)
+
+const (
+	// mdsTargetPrefix is the X-Amz-Target prefix of the ec2messages service.
+	mdsTargetPrefix = "EC2WindowsMessageDeliveryService"
+
+	// sendCommandTopic is the topic of the send command messages handed out by the fake.
+	sendCommandTopic = "aws.ssm.sendCommand.us.east.1.1"
+
+	// cancelCommandTopic is the topic of the cancel command messages handed out by the fake.
+	cancelCommandTopic = "aws.ssm.cancelCommand.us.east.1.1"
+)
+
+// longPollTimeout is how long GetMessages waits for a message before answering with none,
+// so the agent doesn't spin while nothing is queued.
+var longPollTimeout = 2 * time.Second
+
+// Reply is a SendReply received by the fake ec2messages service.
+type Reply struct {
+	MessageID string
+	Payload   messageContracts.SendReplyPayload
+}
+
+// MDS is a fake of the ec2messages service the agent polls for commands.
+type MDS struct {
+	*server
+	queued  map[string][]*ssmmds.Message
+	arrived chan struct{}
+	m       sync.Mutex
+}
+
+// NewMDS starts a fake ec2messages service. Close it when done.
+func NewMDS() *MDS {
+	mds := &MDS{
+		queued:  make(map[string][]*ssmmds.Message),
+		arrived: make(chan struct{}, 1),
+	}
+	mds.server = newServer(mdsTargetPrefix, map[string]handler{
+		"GetMessages": mds.getMessages,
+	})
+	return mds
+}
+
+// SendCommand queues a send command message for the instance and returns its message id.
+func (mds *MDS) SendCommand(instanceID string, payload messageContracts.SendCommandPayload) (messageID string, err error) {
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	messageID = fmt.Sprintf("aws.ssm.%v.%v", payload.CommandID, instanceID)
+	mds.queue(instanceID, messageID, sendCommandTopic, string(content))
+	return messageID, nil
+}
+
+// CancelCommand queues a message cancelling the command sent as cancelMessageID.
+func (mds *MDS) CancelCommand(instanceID string, commandID string, cancelMessageID string) (messageID string, err error) {
+	content, err := json.Marshal(messageContracts.CancelPayload{CancelMessageID: cancelMessageID})
+	if err != nil {
+		return "", err
+	}
+	messageID = fmt.Sprintf("aws.ssm.%v.%v", commandID, instanceID)
+	mds.queue(instanceID, messageID, cancelCommandTopic, string(content))
+	return messageID, nil
+}
+
+func (mds *MDS) queue(instanceID string, messageID string, topic string, payload string) {
+	mds.m.Lock()
+	mds.queued[instanceID] = append(mds.queued[instanceID], &ssmmds.Message{
+		CreatedDate: aws.String(time.Now().UTC().Format("2006-01-02T15:04:05.000Z")),
+		Destination: aws.String(instanceID),
+		MessageId:   aws.String(messageID),
+		Payload:     aws.String(payload),
+		Topic:       aws.String(topic),
+	})
+	mds.m.Unlock()
+
+	select {
+	case mds.arrived <- struct{}{}:
+	default:
+	}
+}
+
+// getMessages hands out the messages queued for the destination, waiting a while for one if there are none.
+func (mds *MDS) getMessages(body []byte) (interface{}, error) {
+	var input ssmmds.GetMessagesInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		return nil, err
+	}
+	destination := aws.StringValue(input.Destination)
+
+	deadline := time.After(longPollTimeout)
+	for {
+		mds.m.Lock()
+		messages := mds.queued[destination]
+		delete(mds.queued, destination)
+		mds.m.Unlock()
+
+		if len(messages) > 0 {
+			return &ssmmds.GetMessagesOutput{
+				Destination:       input.Destination,
+				Messages:          messages,
+				MessagesRequestId: input.MessagesRequestId,
+			}, nil
+		}
+
+		select {
+		case <-mds.arrived:
+		case <-deadline:
+			return &ssmmds.GetMessagesOutput{
+				Destination:       input.Destination,
+				Messages:          []*ssmmds.Message{},
+				MessagesRequestId: input.MessagesRequestId,
+			}, nil
+		}
+	}
+}
+
+// Replies returns the replies the agent sent for the message.
+func (mds *MDS) Replies(messageID string) (replies []Reply, err error) {
+	for _, call := range mds.Calls("SendReply") {
+		var input ssmmds.SendReplyInput
+		if err = call.Decode(&input); err != nil {
+			return
+		}
+		if aws.StringValue(input.MessageId) != messageID {
+			continue
+		}
+		reply := Reply{MessageID: messageID}
+		if err = json.Unmarshal([]byte(aws.StringValue(input.Payload)), &reply.Payload); err != nil {
+			return
+		}
+		replies = append(replies, reply)
+	}
+	return
+}
+
+// Acknowledged tells whether the agent acknowledged the message.
+func (mds *MDS) Acknowledged(messageID string) bool {
+	return mds.received("AcknowledgeMessage", messageID)
+}
+
+// Failed tells whether the agent failed the message.
+func (mds *MDS) Failed(messageID string) bool {
+	return mds.received("FailMessage", messageID)
+}
+
+// received tells whether a call of the operation named the message.
+func (mds *MDS) received(operation string, messageID string) bool {
+	for _, call := range mds.Calls(operation) {
+		var input struct{ MessageId string }
+		if call.Decode(&input) == nil && input.MessageId == messageID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package fakeaws contains in-process fakes of the AWS services the agent talks to,
+// so the agent can be exercised end to end without an AWS account.
+package fakeaws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Call is a request received by a fake service.
+type Call struct {
+	Operation string
+	Body      []byte
+}
+
+// Decode unmarshals the body of the call into v.
+func (c Call) Decode(v interface{}) error {
+	return json.Unmarshal(c.Body, v)
+}
+
+// handler answers one operation of a fake service with the value to encode as the response.
+type handler func(body []byte) (interface{}, error)
+
+// server is an http server speaking the AWS JSON 1.1 protocol used by ec2messages and ssm.
+type server struct {
+	*httptest.Server
+	targetPrefix string
+	handlers     map[string]handler
+	calls        []Call
+	m            sync.Mutex
+}
+
+// newServer starts a server for the service with the given X-Amz-Target prefix.
+// Operations without a handler are answered with an empty object.
+func newServer(targetPrefix string, handlers map[string]handler) *server {
+	s := &server{targetPrefix: targetPrefix, handlers: handlers}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.Header.Get("X-Amz-Target")
+	if !strings.HasPrefix(target, s.targetPrefix+".") {
+		writeError(w, http.StatusBadRequest, "UnknownOperationException", fmt.Sprintf("unknown target %v", target))
+		return
+	}
+	operation := strings.TrimPrefix(target, s.targetPrefix+".")
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "SerializationException", err.Error())
+		return
+	}
+
+	s.m.Lock()
+	s.calls = append(s.calls, Call{Operation: operation, Body: body})
+	h := s.handlers[operation]
+	s.m.Unlock()
+
+	var response interface{} = struct{}{}
+	if h != nil {
+		if response, err = h(body); err != nil {
+			writeError(w, http.StatusBadRequest, "ValidationException", err.Error())
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Calls returns the calls received for the operation, in the order they arrived.
+func (s *server) Calls(operation string) []Call {
+	s.m.Lock()
+	defer s.m.Unlock()
+	var calls []Call
+	for _, call := range s.calls {
+		if call.Operation == operation {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// writeError answers with an error in the shape the AWS sdk expects from JSON 1.1 services.
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"__type": code, "message": message})
+}
@@ -0,0 +1,159 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fakeaws
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/twinj/uuid"
+)
+
+const (
+	// ssmTargetPrefix is the X-Amz-Target prefix of the ssm service.
+	ssmTargetPrefix = "AmazonSSM"
+
+	// associatedStatus is the detailed status of an association that hasn't run yet.
+	associatedStatus = "Associated"
+)
+
+// SSM is a fake of the ssm service serving associations and their documents.
+type SSM struct {
+	*server
+	associations []*ssm.InstanceAssociationSummary
+	documents    map[string]string
+	m            sync.Mutex
+}
+
+// NewSSM starts a fake ssm service. Close it when done.
+func NewSSM() *SSM {
+	svc := &SSM{documents: make(map[string]string)}
+	svc.server = newServer(ssmTargetPrefix, map[string]handler{
+		"ListInstanceAssociations":        svc.listInstanceAssociations,
+		"GetDocument":                     svc.getDocument,
+		"UpdateInstanceAssociationStatus": svc.updateInstanceAssociationStatus,
+	})
+	return svc
+}
+
+// AddDocument makes the document content available by name.
+func (svc *SSM) AddDocument(name string, content string) {
+	svc.m.Lock()
+	defer svc.m.Unlock()
+	svc.documents[name] = content
+}
+
+// Associate associates the named document with the instance and returns the association id.
+// An empty schedule expression makes the association run once.
+func (svc *SSM) Associate(instanceID string, documentName string, parameters map[string][]*string, scheduleExpression string) string {
+	svc.m.Lock()
+	defer svc.m.Unlock()
+
+	uuid.SwitchFormat(uuid.CleanHyphen)
+	associationID := uuid.NewV4().String()
+	checksum, _ := json.Marshal(parameters)
+	association := &ssm.InstanceAssociationSummary{
+		AssociationId:      aws.String(associationID),
+		AssociationVersion: aws.String("1"),
+		Checksum:           aws.String(fmt.Sprintf("%x", sha256.Sum256(append(checksum, scheduleExpression...)))),
+		DetailedStatus:     aws.String(associatedStatus),
+		DocumentVersion:    aws.String("1"),
+		InstanceId:         aws.String(instanceID),
+		Name:               aws.String(documentName),
+		Parameters:         parameters,
+	}
+	if scheduleExpression != "" {
+		association.ScheduleExpression = aws.String(scheduleExpression)
+	}
+	svc.associations = append(svc.associations, association)
+	return associationID
+}
+
+func (svc *SSM) listInstanceAssociations(body []byte) (interface{}, error) {
+	var input ssm.ListInstanceAssociationsInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		return nil, err
+	}
+
+	svc.m.Lock()
+	defer svc.m.Unlock()
+	associations := []*ssm.InstanceAssociationSummary{}
+	for _, association := range svc.associations {
+		if aws.StringValue(association.InstanceId) == aws.StringValue(input.InstanceId) {
+			associations = append(associations, association)
+		}
+	}
+	return &ssm.ListInstanceAssociationsOutput{Associations: associations}, nil
+}
+
+func (svc *SSM) getDocument(body []byte) (interface{}, error) {
+	var input ssm.GetDocumentInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		return nil, err
+	}
+
+	svc.m.Lock()
+	defer svc.m.Unlock()
+	content, found := svc.documents[aws.StringValue(input.Name)]
+	if !found {
+		return nil, fmt.Errorf("document %v does not exist", aws.StringValue(input.Name))
+	}
+	return &ssm.GetDocumentOutput{
+		Content:         aws.String(content),
+		DocumentVersion: input.DocumentVersion,
+		Name:            input.Name,
+	}, nil
+}
+
+// associationStatusInput is the part of UpdateInstanceAssociationStatus the fake looks at;
+// the execution date is a unix timestamp the sdk structures can't decode from json.
+type associationStatusInput struct {
+	AssociationId   string
+	ExecutionResult struct{ Status string }
+}
+
+// updateInstanceAssociationStatus makes the reported status the detailed status of the association, like the service does.
+func (svc *SSM) updateInstanceAssociationStatus(body []byte) (interface{}, error) {
+	var input associationStatusInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		return nil, err
+	}
+
+	svc.m.Lock()
+	defer svc.m.Unlock()
+	for _, association := range svc.associations {
+		if aws.StringValue(association.AssociationId) == input.AssociationId {
+			association.DetailedStatus = aws.String(input.ExecutionResult.Status)
+		}
+	}
+	return struct{}{}, nil
+}
+
+// AssociationStatuses returns the statuses the agent reported for the association, in order.
+func (svc *SSM) AssociationStatuses(associationID string) (statuses []string, err error) {
+	for _, call := range svc.Calls("UpdateInstanceAssociationStatus") {
+		var input associationStatusInput
+		if err = call.Decode(&input); err != nil {
+			return
+		}
+		if input.AssociationId == associationID {
+			statuses = append(statuses, input.ExecutionResult.Status)
+		}
+	}
+	return
+}
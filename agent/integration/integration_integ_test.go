@@ -0,0 +1,202 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build integration && (darwin || freebsd || linux || netbsd || openbsd)
+// +build integration
+// +build darwin freebsd linux netbsd openbsd
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/integration/fakeaws"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/twinj/uuid"
+)
+
+const (
+	// shellDocument runs the commands parameter with aws:runShellScript, as root
+	// since the test machine doesn't have the low-privilege user of the packages.
+	shellDocument = `{
+  "schemaVersion": "2.2",
+  "description": "Run a shell script.",
+  "parameters": {
+    "commands": {"type": "StringList", "description": "The commands to run."}
+  },
+  "mainSteps": [
+    {"action": "aws:runShellScript", "name": "runShellScript", "inputs": {"runCommand": "{{ commands }}", "runAsElevated": true}}
+  ]
+}`
+
+	scenarioTimeout = 2 * time.Minute
+)
+
+var (
+	// instanceID is new for every run, so the agent doesn't resume the documents of a previous run.
+	instanceID string
+
+	mds *fakeaws.MDS
+	ssm *fakeaws.SSM
+
+	// associationID is the run once association of the instance, created before the agent starts
+	// because the agent only polls for associations every few minutes once it's running.
+	associationID string
+)
+
+// TestMain starts one agent for all the scenarios, pointed at the fake services.
+func TestMain(m *testing.M) {
+	if os.Geteuid() != 0 {
+		fmt.Println("skipping the integration scenarios: the agent needs to run as root")
+		return
+	}
+	code, err := run(m)
+	if err != nil {
+		fmt.Println(err)
+		code = 1
+	}
+	os.Exit(code)
+}
+
+// run builds and starts the agent, runs the scenarios and cleans up after them.
+func run(m *testing.M) (code int, err error) {
+	dir, err := ioutil.TempDir("", "integration")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	binary, err := Build(dir)
+	if err != nil {
+		return
+	}
+	restoreWorker, err := InstallWorker(dir)
+	if err != nil {
+		return
+	}
+	defer restoreWorker()
+
+	uuid.SwitchFormat(uuid.CleanHyphen)
+	instanceID = "i-" + strings.Replace(uuid.NewV4().String(), "-", "", -1)[:17]
+	defer os.RemoveAll(filepath.Join(appconfig.DefaultDataStorePath, instanceID))
+
+	mds = fakeaws.NewMDS()
+	defer mds.Close()
+	ssm = fakeaws.NewSSM()
+	defer ssm.Close()
+	ssm.AddDocument("AWS-RunShellScript", shellDocument)
+	associationID = ssm.Associate(instanceID, "AWS-RunShellScript", map[string][]*string{
+		"commands": {aws.String("echo associated")},
+	}, "")
+
+	agent, err := Start(binary, instanceID, mds, ssm)
+	if err != nil {
+		return
+	}
+	code = m.Run()
+	if err = agent.Stop(); err != nil {
+		fmt.Println(err)
+	}
+	if code != 0 {
+		fmt.Println(agent.Output())
+	}
+	return code, nil
+}
+
+// sendShellCommand sends the commands to the agent as AWS-RunShellScript and returns the message id.
+func sendShellCommand(t *testing.T, commands ...string) string {
+	var document contracts.DocumentContent
+	assert.NoError(t, json.Unmarshal([]byte(shellDocument), &document))
+
+	messageID, err := mds.SendCommand(instanceID, messageContracts.SendCommandPayload{
+		CommandID:       uuid.NewV4().String(),
+		DocumentName:    "AWS-RunShellScript",
+		DocumentContent: document,
+		Parameters:      map[string]interface{}{"commands": commands},
+	})
+	assert.NoError(t, err)
+	return messageID
+}
+
+// finalReply waits for the reply reporting the end of the command.
+func finalReply(t *testing.T, messageID string) (reply fakeaws.Reply, found bool) {
+	found = Eventually(scenarioTimeout, func() bool {
+		replies, err := mds.Replies(messageID)
+		assert.NoError(t, err)
+		for _, r := range replies {
+			if r.Payload.DocumentStatus != contracts.ResultStatusInProgress {
+				reply = r
+				return true
+			}
+		}
+		return false
+	})
+	return
+}
+
+func TestRunCommand(t *testing.T) {
+	messageID := sendShellCommand(t, "echo ship_it")
+
+	reply, found := finalReply(t, messageID)
+	if !assert.True(t, found, "no final reply for %v", messageID) {
+		return
+	}
+	assert.Equal(t, contracts.ResultStatusSuccess, reply.Payload.DocumentStatus)
+	assert.Contains(t, reply.Payload.RuntimeStatus["runShellScript"].Output, "ship_it")
+	assert.True(t, mds.Acknowledged(messageID))
+}
+
+func TestFailingCommand(t *testing.T) {
+	messageID := sendShellCommand(t, "echo broken >&2", "exit 3")
+
+	reply, found := finalReply(t, messageID)
+	if !assert.True(t, found, "no final reply for %v", messageID) {
+		return
+	}
+	assert.Equal(t, contracts.ResultStatusFailed, reply.Payload.DocumentStatus)
+	assert.Equal(t, 3, reply.Payload.RuntimeStatus["runShellScript"].Code)
+	assert.Contains(t, reply.Payload.RuntimeStatus["runShellScript"].Output, "broken")
+}
+
+func TestCancelCommand(t *testing.T) {
+	messageID := sendShellCommand(t, "sleep 600")
+	assert.True(t, Eventually(scenarioTimeout, func() bool { return mds.Acknowledged(messageID) }))
+
+	cancelMessageID, err := mds.CancelCommand(instanceID, uuid.NewV4().String(), messageID)
+	assert.NoError(t, err)
+
+	reply, found := finalReply(t, messageID)
+	if !assert.True(t, found, "no final reply for %v", messageID) {
+		return
+	}
+	assert.Equal(t, contracts.ResultStatusCancelled, reply.Payload.DocumentStatus)
+	assert.True(t, mds.Acknowledged(cancelMessageID))
+}
+
+func TestRunOnceAssociation(t *testing.T) {
+	assert.True(t, Eventually(scenarioTimeout, func() bool {
+		statuses, err := ssm.AssociationStatuses(associationID)
+		assert.NoError(t, err)
+		return len(statuses) > 0 && statuses[len(statuses)-1] == contracts.AssociationStatusSuccess
+	}), "association %v did not succeed", associationID)
+}
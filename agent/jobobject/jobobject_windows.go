@@ -19,6 +19,7 @@ package jobobject
 
 import (
 	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
@@ -30,6 +31,9 @@ const (
 	processSetQuotaAccess             = 0x100
 	processTerminateAccess            = 0x1
 	jobObjectLimitkillonClose         = 0x2000
+	jobObjectLimitJobTime             = 0x4
+	jobObjectLimitActiveProcess       = 0x8
+	jobObjectLimitProcessMemory       = 0x100
 )
 
 type (
@@ -132,6 +136,50 @@ func AttachProcessToJobObject(Pid uint32) (err error) {
 	return err
 }
 
+// LimitCurrentProcess assigns the current process to a new job object limiting the memory of each of its
+// processes, the user time of the job and its number of active processes, 0 for no limit. The processes it
+// starts join the job object, and are killed when the current process exits.
+func LimitCurrentProcess(processMemory uint64, userTime time.Duration, activeProcesses uint32) error {
+	job, err := createJobObject(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	var jobinfo JobObjectExtendedLimit
+	jobinfo.BasicLimitInformation.LimitFlags = jobObjectLimitkillonClose
+	if processMemory > 0 {
+		jobinfo.BasicLimitInformation.LimitFlags |= jobObjectLimitProcessMemory
+		jobinfo.ProcessMemoryLimit = uintptr(processMemory)
+	}
+	if userTime > 0 {
+		// in 100-nanosecond ticks
+		jobinfo.BasicLimitInformation.LimitFlags |= jobObjectLimitJobTime
+		jobinfo.BasicLimitInformation.PerJobUserTimeLimit = uint64(userTime / 100)
+	}
+	if activeProcesses > 0 {
+		jobinfo.BasicLimitInformation.LimitFlags |= jobObjectLimitActiveProcess
+		jobinfo.BasicLimitInformation.ActiveProcessLimit = activeProcesses
+	}
+	if err = setInformationJobObject(job, JobObjectExtendedLimitInformation, uintptr(unsafe.Pointer(&jobinfo)), uint32(unsafe.Sizeof(jobinfo))); err != nil {
+		syscall.CloseHandle(job)
+		return err
+	}
+
+	// the handle of the job object stays open until the process exits
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return err
+	}
+	if r1, _, e1 := AssignProcessToJobObject.Call(uintptr(job), uintptr(process)); r1 == 0 {
+		syscall.CloseHandle(job)
+		if e1 != nil {
+			return error(e1)
+		}
+		return syscall.EINVAL
+	}
+	return nil
+}
+
 // Set up a job object for the SSM agent process on Windows. This is to control the lifetime of daemon processes
 // launched via the ConfigureDaemon/RunDaemon plugin.
 // The init function is automatically invoked prior to main function being invoked.
@@ -0,0 +1,54 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Context keys that loggers are tagged with, e.g. context.With("[messageID=" + messageID + "]")
+const (
+	ContextKeyMessageID     = "messageID"
+	ContextKeyCommandID     = "commandID"
+	ContextKeyAssociationID = "associationId"
+	ContextKeyDocumentName  = "documentName"
+	ContextKeyPluginName    = "pluginName"
+	ContextKeyInstanceID    = "instanceID"
+	ContextKeySessionID     = "sessionID"
+	ContextKeyTraceID       = "traceID"
+	ContextKeySpanID        = "spanID"
+)
+
+// contextTagPattern matches the [key=value] context tags of a log message
+var contextTagPattern = regexp.MustCompile(`\[([A-Za-z][A-Za-z0-9]*)=([^\]]*)\]`)
+
+// ContextFields extracts the [key=value] context tags of a formatted log message.
+// The command id is derived from the message id when the message is tagged with one.
+func ContextFields(message string) (fields map[string]string) {
+	fields = make(map[string]string)
+	for _, match := range contextTagPattern.FindAllStringSubmatch(message, -1) {
+		// the innermost context wins, it is the last one in the message
+		fields[match[1]] = match[2]
+	}
+	if messageID, ok := fields[ContextKeyMessageID]; ok {
+		if _, ok = fields[ContextKeyCommandID]; !ok {
+			// message id is in the format of aws.ssm.CommandId.InstanceId
+			if parts := strings.Split(messageID, "."); len(parts) >= 4 && parts[0] == "aws" && parts[1] == "ssm" {
+				fields[ContextKeyCommandID] = parts[len(parts)-2]
+			}
+		}
+	}
+	return fields
+}
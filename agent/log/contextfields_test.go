@@ -0,0 +1,58 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextFields(t *testing.T) {
+	testCases := []struct {
+		message  string
+		expected map[string]string
+	}{
+		{
+			"[instanceID=i-123] [MessagingDeliveryService] [messageID=aws.ssm.2b196342-d7d4-436e-8f09-3883a1116ac3.i-123] [pluginName=aws:runShellScript] running",
+			map[string]string{
+				ContextKeyInstanceID: "i-123",
+				ContextKeyMessageID:  "aws.ssm.2b196342-d7d4-436e-8f09-3883a1116ac3.i-123",
+				ContextKeyCommandID:  "2b196342-d7d4-436e-8f09-3883a1116ac3",
+				ContextKeyPluginName: "aws:runShellScript",
+			},
+		},
+		{
+			"[Association] [associationId=c0a6b8e1] [documentName=AWS-GatherSoftwareInventory] done",
+			map[string]string{
+				ContextKeyAssociationID: "c0a6b8e1",
+				ContextKeyDocumentName:  "AWS-GatherSoftwareInventory",
+			},
+		},
+		{
+			"[messageID=not-an-mds-message] plain",
+			map[string]string{
+				ContextKeyMessageID: "not-an-mds-message",
+			},
+		},
+		{
+			"no context at all [not a tag]",
+			map[string]string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, ContextFields(testCase.message), testCase.message)
+	}
+}
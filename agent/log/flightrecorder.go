@@ -0,0 +1,237 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// FlightRecorderDirName is the folder under the log directory the flight recorder dumps are written to
+	FlightRecorderDirName = "flightrecorder"
+
+	// FlightRecorderRequestFileName is the file ssm-cli creates in the flight recorder folder to request a dump
+	FlightRecorderRequestFileName = "dump.request"
+
+	// flightRecorderCapacity is the number of events kept per subsystem
+	flightRecorderCapacity = 500
+
+	// flightRecorderMaxEventsPerSecond is the number of events a subsystem can record per second,
+	// events above the rate are counted but not captured so a chatty subsystem doesn't cost too much
+	flightRecorderMaxEventsPerSecond = 100
+
+	// flightRecorderErrorDumpInterval is the minimum time between two dumps triggered by errors
+	flightRecorderErrorDumpInterval = 10 * time.Minute
+
+	// flightRecorderMaxDumps is the number of dump files kept on disk
+	flightRecorderMaxDumps = 10
+
+	// flightRecorderRequestPollInterval is how often the agent checks for a dump requested by ssm-cli
+	flightRecorderRequestPollInterval = 10 * time.Second
+
+	// defaultSubsystem is used for the events logged without a named context
+	defaultSubsystem = "agent"
+)
+
+// flightEvent is a log event captured by the flight recorder.
+type flightEvent struct {
+	time    time.Time
+	level   string
+	message string
+}
+
+// flightRing is the ring buffer of the events of a subsystem.
+type flightRing struct {
+	events      []flightEvent
+	next        int
+	full        bool
+	second      int64
+	inSecond    int
+	dropped     int
+	lastDropped int
+}
+
+// add captures the event unless the subsystem exceeded its rate for the current second.
+func (r *flightRing) add(event flightEvent) {
+	if second := event.time.Unix(); second != r.second {
+		r.second = second
+		r.inSecond = 0
+	}
+	if r.inSecond >= flightRecorderMaxEventsPerSecond {
+		r.dropped++
+		return
+	}
+	r.inSecond++
+	if r.dropped > r.lastDropped {
+		// leave a marker where events went missing
+		event.message = fmt.Sprintf("(%v events not captured) %v", r.dropped-r.lastDropped, event.message)
+		r.lastDropped = r.dropped
+	}
+	r.events[r.next] = event
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the captured events from the oldest to the newest.
+func (r *flightRing) snapshot() []flightEvent {
+	if !r.full {
+		return append([]flightEvent(nil), r.events[:r.next]...)
+	}
+	return append(append([]flightEvent(nil), r.events[r.next:]...), r.events[:r.next]...)
+}
+
+// FlightRecorder keeps the recent debug level events of each subsystem in memory, regardless of the configured
+// log level, and writes them to disk when something goes wrong.
+type FlightRecorder struct {
+	m          sync.Mutex
+	dir        string
+	capacity   int
+	subsystems map[string]*flightRing
+	lastDump   time.Time
+}
+
+var flightRecorder = newFlightRecorder(filepath.Join(DefaultLogDir, FlightRecorderDirName), flightRecorderCapacity)
+
+// newFlightRecorder creates a flight recorder that dumps to the given folder.
+func newFlightRecorder(dir string, capacity int) *FlightRecorder {
+	return &FlightRecorder{
+		dir:        dir,
+		capacity:   capacity,
+		subsystems: make(map[string]*flightRing),
+	}
+}
+
+// record captures an event of the given subsystem.
+func (f *FlightRecorder) record(subsystem, level, message string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	ring, found := f.subsystems[subsystem]
+	if !found {
+		ring = &flightRing{events: make([]flightEvent, f.capacity)}
+		f.subsystems[subsystem] = ring
+	}
+	ring.add(flightEvent{time: time.Now(), level: level, message: message})
+}
+
+// dumpOnError writes the events to disk unless an error already triggered a dump recently.
+func (f *FlightRecorder) dumpOnError() {
+	f.m.Lock()
+	if time.Since(f.lastDump) < flightRecorderErrorDumpInterval {
+		f.m.Unlock()
+		return
+	}
+	f.lastDump = time.Now()
+	f.m.Unlock()
+	// don't hold up the caller that is logging the error
+	go f.Dump("error")
+}
+
+// Dump writes the captured events of every subsystem to a new file in the flight recorder folder and
+// returns the path of the file.
+func (f *FlightRecorder) Dump(reason string) (path string, err error) {
+	var buf bytes.Buffer
+	now := time.Now()
+	fmt.Fprintf(&buf, "Flight recorder dump, reason: %v, time: %v\n", reason, now.Format(time.RFC3339))
+
+	f.m.Lock()
+	names := make([]string, 0, len(f.subsystems))
+	for name := range f.subsystems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ring := f.subsystems[name]
+		fmt.Fprintf(&buf, "\n[%v] %v events not captured\n", name, ring.dropped)
+		for _, event := range ring.snapshot() {
+			fmt.Fprintf(&buf, "%v %v %v\n", event.time.Format("2006-01-02 15:04:05.000"), event.level, event.message)
+		}
+	}
+	f.m.Unlock()
+
+	if err = os.MkdirAll(f.dir, 0750); err != nil {
+		return "", err
+	}
+	path = filepath.Join(f.dir, fmt.Sprintf("flightrecorder-%v-%v.log", now.Format("20060102T150405.000"), reason))
+	if err = ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return "", err
+	}
+	f.removeOldDumps()
+	return path, nil
+}
+
+// removeOldDumps keeps the most recent dumps only.
+func (f *FlightRecorder) removeOldDumps() {
+	dumps, err := filepath.Glob(filepath.Join(f.dir, "flightrecorder-*.log"))
+	if err != nil || len(dumps) <= flightRecorderMaxDumps {
+		return
+	}
+	// the timestamp in the name sorts the dumps from the oldest to the newest
+	sort.Strings(dumps)
+	for _, dump := range dumps[:len(dumps)-flightRecorderMaxDumps] {
+		os.Remove(dump)
+	}
+}
+
+// checkDumpRequest dumps the events if ssm-cli requested it, and returns the path of the dump.
+func (f *FlightRecorder) checkDumpRequest() (path string, err error) {
+	request := filepath.Join(f.dir, FlightRecorderRequestFileName)
+	if _, err = os.Stat(request); err != nil {
+		return "", nil
+	}
+	if err = os.Remove(request); err != nil {
+		return "", err
+	}
+	return f.Dump("request")
+}
+
+// DumpFlightRecorder writes the recent events captured by the flight recorder to disk, e.g. when the agent crashes.
+func DumpFlightRecorder(reason string) (path string, err error) {
+	return flightRecorder.Dump(reason)
+}
+
+// WatchFlightRecorderRequests dumps the flight recorder whenever ssm-cli requests it, for the lifetime of the agent.
+func WatchFlightRecorderRequests(log T) {
+	for range time.Tick(flightRecorderRequestPollInterval) {
+		if path, err := flightRecorder.checkDumpRequest(); err != nil {
+			log.Warnf("failed to dump flight recorder: %v", err)
+		} else if path != "" {
+			log.Infof("Flight recorder dumped to %v", path)
+		}
+	}
+}
+
+// subsystemOf returns the subsystem a logger records its events under, which is the first context
+// that isn't a key value pair, e.g. [EngineProcessor].
+func subsystemOf(filter FormatFilter) string {
+	contextFilter, ok := filter.(*ContextFormatFilter)
+	if !ok {
+		return defaultSubsystem
+	}
+	for _, context := range contextFilter.Context {
+		if !strings.Contains(context, "=") {
+			return strings.Trim(context, "[]")
+		}
+	}
+	return defaultSubsystem
+}
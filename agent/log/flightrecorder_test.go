@@ -0,0 +1,117 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlightRingKeepsMostRecentEvents(t *testing.T) {
+	ring := flightRing{events: make([]flightEvent, 3)}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		ring.add(flightEvent{time: start.Add(time.Duration(i) * time.Second), message: fmt.Sprint(i)})
+	}
+
+	events := ring.snapshot()
+
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, "2", events[0].message)
+	assert.Equal(t, "3", events[1].message)
+	assert.Equal(t, "4", events[2].message)
+}
+
+func TestFlightRingThrottlesChattySubsystem(t *testing.T) {
+	ring := flightRing{events: make([]flightEvent, 2*flightRecorderMaxEventsPerSecond)}
+	second := time.Unix(1500000000, 0)
+	for i := 0; i < flightRecorderMaxEventsPerSecond+5; i++ {
+		ring.add(flightEvent{time: second, message: "event"})
+	}
+	ring.add(flightEvent{time: second.Add(time.Second), message: "next"})
+
+	events := ring.snapshot()
+
+	assert.Equal(t, flightRecorderMaxEventsPerSecond+1, len(events))
+	assert.Equal(t, 5, ring.dropped)
+	assert.Equal(t, "(5 events not captured) next", events[len(events)-1].message)
+}
+
+func TestFlightRecorderDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flightrecorder")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	recorder := newFlightRecorder(dir, 10)
+	recorder.record("EngineProcessor", debugLevel, "processing document")
+	recorder.record("MessagingDeliveryService", errorLevel, "failed to poll")
+
+	path, err := recorder.Dump("test")
+
+	assert.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(string(content), "reason: test"))
+	assert.True(t, strings.Contains(string(content), "[EngineProcessor]"))
+	assert.True(t, strings.Contains(string(content), "Debug processing document"))
+	assert.True(t, strings.Contains(string(content), "Error failed to poll"))
+}
+
+func TestFlightRecorderDumpRequest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flightrecorder")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	recorder := newFlightRecorder(dir, 10)
+
+	path, err := recorder.checkDumpRequest()
+	assert.NoError(t, err)
+	assert.Empty(t, path)
+
+	request := filepath.Join(dir, FlightRecorderRequestFileName)
+	assert.NoError(t, ioutil.WriteFile(request, []byte{}, 0600))
+	path, err = recorder.checkDumpRequest()
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, path)
+	_, err = os.Stat(request)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFlightRecorderRemovesOldDumps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flightrecorder")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	recorder := newFlightRecorder(dir, 10)
+	for i := 0; i < flightRecorderMaxDumps+2; i++ {
+		_, err = recorder.Dump(fmt.Sprintf("test%02d", i))
+		assert.NoError(t, err)
+	}
+
+	dumps, _ := filepath.Glob(filepath.Join(dir, "flightrecorder-*.log"))
+
+	assert.Equal(t, flightRecorderMaxDumps, len(dumps))
+}
+
+func TestSubsystemOf(t *testing.T) {
+	assert.Equal(t, "EngineProcessor", subsystemOf(&ContextFormatFilter{Context: []string{"[instanceID=i-123]", "[EngineProcessor]", "[messageID=abc]"}}))
+	assert.Equal(t, defaultSubsystem, subsystemOf(&ContextFormatFilter{Context: []string{"[messageID=abc]"}}))
+	assert.Equal(t, defaultSubsystem, subsystemOf(&ContextFormatFilter{}))
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// LogLevelDirName is the folder under the log directory the runtime log level is kept in
+	LogLevelDirName = "loglevel"
+
+	// LogLevelOverrideFileName is the file holding the log level that overrides the seelog configuration
+	LogLevelOverrideFileName = "override"
+)
+
+// LogLevels are the levels supported by seelog, from the most to the least verbose
+var LogLevels = []string{"trace", "debug", "info", "warn", "error", "critical", "off"}
+
+// LogLevelOverridePath is the file the agent and the document workers watch to change their log level
+// without restarting. It has a folder of its own so that the watchers aren't woken up by the log files.
+var LogLevelOverridePath = filepath.Join(DefaultLogDir, LogLevelDirName, LogLevelOverrideFileName)
+
+// rootMinLevelPattern matches the minlevel attribute of the root element of a seelog configuration
+var rootMinLevelPattern = regexp.MustCompile(`(<seelog\b[^>]*\sminlevel=")([^"]*)(")`)
+
+// rootElementPattern matches the opening of the root element of a seelog configuration
+var rootElementPattern = regexp.MustCompile(`<seelog\b`)
+
+// IsLogLevel returns true if the level is supported by seelog.
+func IsLogLevel(level string) bool {
+	for _, logLevel := range LogLevels {
+		if level == logLevel {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLogLevelOverride sets the log level of the running agent and workers, until it is cleared.
+func SetLogLevelOverride(level string) error {
+	if !IsLogLevel(level) {
+		return fmt.Errorf("log level %v is not one of %v", level, strings.Join(LogLevels, ", "))
+	}
+	if err := os.MkdirAll(filepath.Dir(LogLevelOverridePath), 0750); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(LogLevelOverridePath, []byte(level), 0600)
+}
+
+// ClearLogLevelOverride restores the log level of the seelog configuration. The file is emptied rather
+// than removed so that the watchers are notified.
+func ClearLogLevelOverride() error {
+	if _, err := os.Stat(LogLevelOverridePath); os.IsNotExist(err) {
+		return nil
+	}
+	return ioutil.WriteFile(LogLevelOverridePath, []byte{}, 0600)
+}
+
+// GetLogLevelOverride returns the log level overriding the seelog configuration, empty if there is none.
+func GetLogLevelOverride() string {
+	content, err := ioutil.ReadFile(LogLevelOverridePath)
+	if err != nil {
+		return ""
+	}
+	level := strings.TrimSpace(string(content))
+	if !IsLogLevel(level) {
+		return ""
+	}
+	return level
+}
+
+// WithLogLevel returns the seelog configuration with the minimum level of the root element set to the level.
+func WithLogLevel(seelogConfig []byte, level string) []byte {
+	if rootMinLevelPattern.Match(seelogConfig) {
+		return rootMinLevelPattern.ReplaceAll(seelogConfig, []byte("${1}"+level+"${3}"))
+	}
+	// the root element has no minlevel, add one
+	location := rootElementPattern.FindIndex(seelogConfig)
+	if location == nil {
+		return seelogConfig
+	}
+	updated := make([]byte, 0, len(seelogConfig)+len(level)+12)
+	updated = append(updated, seelogConfig[:location[1]]...)
+	updated = append(updated, []byte(` minlevel="`+level+`"`)...)
+	return append(updated, seelogConfig[location[1]:]...)
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogLevelOverride(t *testing.T) {
+	dir, err := ioutil.TempDir("", "loglevel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defaultPath := LogLevelOverridePath
+	LogLevelOverridePath = filepath.Join(dir, LogLevelDirName, LogLevelOverrideFileName)
+	defer func() { LogLevelOverridePath = defaultPath }()
+
+	assert.Equal(t, "", GetLogLevelOverride())
+	assert.NoError(t, ClearLogLevelOverride())
+	assert.Error(t, SetLogLevelOverride("verbose"))
+
+	assert.NoError(t, SetLogLevelOverride("debug"))
+	assert.Equal(t, "debug", GetLogLevelOverride())
+
+	assert.NoError(t, ClearLogLevelOverride())
+	assert.Equal(t, "", GetLogLevelOverride())
+	_, err = os.Stat(LogLevelOverridePath)
+	assert.NoError(t, err, "the file is kept so that the watchers are notified")
+}
+
+func TestWithLogLevel(t *testing.T) {
+	assert.Equal(t,
+		`<seelog type="adaptive" minlevel="debug"><exceptions><exception filepattern="test*" minlevel="error"/></exceptions></seelog>`,
+		string(WithLogLevel([]byte(`<seelog type="adaptive" minlevel="info"><exceptions><exception filepattern="test*" minlevel="error"/></exceptions></seelog>`), "debug")))
+	assert.Equal(t,
+		`<seelog minlevel="trace" type="sync"></seelog>`,
+		string(WithLogLevel([]byte(`<seelog type="sync"></seelog>`), "trace")))
+	assert.Equal(t, "not a seelog configuration", string(WithLogLevel([]byte("not a seelog configuration"), "debug")))
+}
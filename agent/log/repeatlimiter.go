@@ -0,0 +1,126 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// repeatWindow is how long the repetitions of a message are suppressed after it is written, the message
+	// is summarized with its number of repetitions once the window ends
+	repeatWindow = time.Minute
+
+	// repeatSweepInterval is how often the ended windows are looked for
+	repeatSweepInterval = time.Second
+
+	// maxRepeatEntries is the number of distinct messages tracked at once, the messages above it are written
+	maxRepeatEntries = 1000
+)
+
+// repeats suppresses the repetitions of the messages of all the loggers, e.g. the error of a tight retry loop
+var repeats = newRepeatLimiter(repeatWindow)
+
+// repeatKey identifies a message by its level and text, including its context
+type repeatKey struct {
+	level   string
+	message string
+}
+
+// repeatEntry is the window of a written message
+type repeatEntry struct {
+	since      time.Time
+	suppressed int
+}
+
+// repeatSummary is the number of repetitions of a message suppressed in its window.
+type repeatSummary struct {
+	level      string
+	message    string
+	suppressed int
+	window     time.Duration
+}
+
+// String returns the line the summary is written as
+func (s repeatSummary) String() string {
+	return fmt.Sprintf("[repeated %d times in %v] %v", s.suppressed, s.window, s.message)
+}
+
+// repeatLimiter tracks the messages written within their window.
+type repeatLimiter struct {
+	m         sync.Mutex
+	window    time.Duration
+	entries   map[repeatKey]*repeatEntry
+	lastSweep time.Time
+}
+
+// newRepeatLimiter creates a limiter suppressing the repetitions of a message within the window.
+func newRepeatLimiter(window time.Duration) *repeatLimiter {
+	return &repeatLimiter{
+		window:  window,
+		entries: make(map[repeatKey]*repeatEntry),
+	}
+}
+
+// check returns false if the message repeats a message written within its window, along with the summaries
+// of the messages whose window ended, which are to be written first.
+func (l *repeatLimiter) check(level string, message string, at time.Time) (bool, []repeatSummary) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	var summaries []repeatSummary
+	if at.Sub(l.lastSweep) >= repeatSweepInterval || len(l.entries) >= maxRepeatEntries {
+		summaries = l.sweep(at, false)
+	}
+	key := repeatKey{level: level, message: message}
+	if entry, ok := l.entries[key]; ok {
+		entry.suppressed++
+		return false, summaries
+	}
+	if len(l.entries) < maxRepeatEntries {
+		l.entries[key] = &repeatEntry{since: at}
+	}
+	return true, summaries
+}
+
+// flush ends the windows of all the messages and returns the summaries of the suppressed ones.
+func (l *repeatLimiter) flush(at time.Time) []repeatSummary {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.sweep(at, true)
+}
+
+// sweep forgets the messages whose window ended, or all of them, and returns the summaries of their repetitions.
+func (l *repeatLimiter) sweep(at time.Time, all bool) (summaries []repeatSummary) {
+	l.lastSweep = at
+	for key, entry := range l.entries {
+		elapsed := at.Sub(entry.since)
+		if !all && elapsed < l.window {
+			continue
+		}
+		if entry.suppressed > 0 {
+			window := l.window
+			if elapsed < window {
+				window = elapsed
+			}
+			summaries = append(summaries, repeatSummary{level: key.level, message: key.message, suppressed: entry.suppressed, window: window.Round(time.Second)})
+		}
+		delete(l.entries, key)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].message < summaries[j].message })
+	return summaries
+}
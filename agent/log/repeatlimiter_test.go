@@ -0,0 +1,75 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatLimiter(t *testing.T) {
+	limiter := newRepeatLimiter(time.Minute)
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	allowed, summaries := limiter.check(errorLevel, "failed to reach MDS", start)
+	assert.True(t, allowed)
+	assert.Empty(t, summaries)
+	for i := 1; i <= 1000; i++ {
+		allowed, summaries = limiter.check(errorLevel, "failed to reach MDS", start.Add(time.Duration(i)*10*time.Millisecond))
+		assert.False(t, allowed)
+		assert.Empty(t, summaries)
+	}
+	allowed, _ = limiter.check(warnLevel, "failed to reach MDS", start.Add(20*time.Second))
+	assert.True(t, allowed, "the level is part of the message")
+
+	// the repetitions are summarized once the window ends and the message is written again
+	allowed, summaries = limiter.check(errorLevel, "failed to reach MDS", start.Add(time.Minute))
+	assert.True(t, allowed)
+	assert.Equal(t, []repeatSummary{{level: errorLevel, message: "failed to reach MDS", suppressed: 1000, window: time.Minute}}, summaries)
+	assert.Equal(t, "[repeated 1000 times in 1m0s] failed to reach MDS", summaries[0].String())
+
+	allowed, _ = limiter.check(errorLevel, "failed to reach MDS", start.Add(61*time.Second))
+	assert.False(t, allowed)
+	assert.Equal(t, []repeatSummary{{level: errorLevel, message: "failed to reach MDS", suppressed: 1, window: 2 * time.Second}}, limiter.flush(start.Add(62*time.Second)))
+	assert.Empty(t, limiter.flush(start.Add(63*time.Second)))
+}
+
+func TestWrapperSummarizesRepeatedMessages(t *testing.T) {
+	defaultRepeats := repeats
+	repeats = newRepeatLimiter(time.Minute)
+	defer func() { repeats = defaultRepeats }()
+	base := NewMockLog()
+	logger := &Wrapper{
+		Format:   &ContextFormatFilter{Context: []string{"[MessagingDeliveryService]"}},
+		M:        new(sync.Mutex),
+		Delegate: &DelegateLogger{BaseLoggerInstance: base},
+	}
+
+	for i := 0; i < 100; i++ {
+		err := logger.Errorf("error when calling AWS APIs. error details - %v", "RequestError: send request failed")
+		if i > 0 {
+			assert.EqualError(t, err, "[MessagingDeliveryService] error when calling AWS APIs. error details - RequestError: send request failed")
+		}
+	}
+	logger.Flush()
+
+	base.AssertCalled(t, "Error", []interface{}{"[MessagingDeliveryService] error when calling AWS APIs. error details - RequestError: send request failed"})
+	base.AssertNumberOfCalls(t, "Error", 2)
+	summary := base.Calls[1].Arguments.Get(0).([]interface{})[0].(string)
+	assert.Contains(t, summary, "[repeated 99 times in ")
+	assert.Contains(t, summary, "] [MessagingDeliveryService] error when calling AWS APIs.")
+}
@@ -0,0 +1,203 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// RotationConfig sets when a RotatingFileWriter rotates its file and how many rotated files it keeps.
+type RotationConfig struct {
+	// MaxSize is the size in bytes the file is rotated at, 0 disables the rotation
+	MaxSize int64
+	// MaxRolls is the number of rotated files kept
+	MaxRolls int
+	// MaxTotalSize is the size in bytes the file and its rotated files are kept under, 0 means no limit
+	MaxTotalSize int64
+	// Compress gzips the rotated files
+	Compress bool
+}
+
+// RotatingFileWriter writes to a file that is rotated once it reaches a maximum size. The rotated files are
+// named after the file with a roll number, 1 being the most recent, e.g. amazon-ssm-agent.log.1.gz
+type RotatingFileWriter struct {
+	m      sync.Mutex
+	path   string
+	config RotationConfig
+	file   *os.File
+	size   int64
+}
+
+// NewRotatingFileWriter opens the file for appending, creating it if needed.
+func NewRotatingFileWriter(path string, config RotationConfig) (*RotatingFileWriter, error) {
+	w := &RotatingFileWriter{path: path, config: config}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write writes to the file, rotating it first if the write would make it exceed the maximum size.
+func (w *RotatingFileWriter) Write(p []byte) (n int, err error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.file == nil {
+		return 0, fmt.Errorf("%v is closed", w.path)
+	}
+	if w.config.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.config.MaxSize {
+		if err = w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the file.
+func (w *RotatingFileWriter) Close() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// RolledFiles returns the paths of the rotated files that exist, from the most recent to the oldest.
+func (w *RotatingFileWriter) RolledFiles() (paths []string) {
+	for roll := 1; roll <= w.config.MaxRolls; roll++ {
+		if _, err := os.Stat(w.rollPath(roll)); err == nil {
+			paths = append(paths, w.rollPath(roll))
+		}
+	}
+	return paths
+}
+
+// open opens the file for appending.
+func (w *RotatingFileWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// rotate moves the current file to the first roll, shifting the older rolls, and starts a new file.
+func (w *RotatingFileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	rolled := true
+	if w.config.MaxRolls > 0 {
+		os.Remove(w.rollPath(w.config.MaxRolls))
+		for roll := w.config.MaxRolls - 1; roll >= 1; roll-- {
+			if _, err := os.Stat(w.rollPath(roll)); err == nil {
+				os.Rename(w.rollPath(roll), w.rollPath(roll+1))
+			}
+		}
+		var err error
+		if w.config.Compress {
+			err = compressFile(w.path, w.rollPath(1))
+		} else {
+			err = os.Rename(w.path, w.rollPath(1))
+		}
+		if err != nil {
+			fmt.Printf("failed to roll %v, it's kept: %v\n", w.path, err)
+			rolled = false
+		}
+	}
+	// the content is dropped once rolled, or if no roll is kept
+	if rolled {
+		os.Remove(w.path)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	if !rolled {
+		// the kept file is rolled again once another MaxSize is written, rather than on each write
+		w.size = 0
+	}
+	w.removeOverTotalSize()
+	return nil
+}
+
+// removeOverTotalSize removes the oldest rolls until the file and its rolls fit in the maximum total size.
+func (w *RotatingFileWriter) removeOverTotalSize() {
+	if w.config.MaxTotalSize <= 0 {
+		return
+	}
+	rolls := w.RolledFiles()
+	// leave room for the new file to grow up to the maximum size
+	total := w.config.MaxSize
+	for _, roll := range rolls {
+		if info, err := os.Stat(roll); err == nil {
+			total += info.Size()
+		}
+	}
+	for i := len(rolls) - 1; i >= 0 && total > w.config.MaxTotalSize; i-- {
+		if info, err := os.Stat(rolls[i]); err == nil && os.Remove(rolls[i]) == nil {
+			total -= info.Size()
+		}
+	}
+}
+
+// rollPath returns the path of the given roll.
+func (w *RotatingFileWriter) rollPath(roll int) string {
+	if w.config.Compress {
+		return fmt.Sprintf("%v.%d.gz", w.path, roll)
+	}
+	return fmt.Sprintf("%v.%d", w.path, roll)
+}
+
+// compressFile writes a gzip of the source file to the destination.
+func compressFile(source, destination string) (err error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(destination)
+		}
+	}()
+	gzipWriter := gzip.NewWriter(out)
+	if _, err = io.Copy(gzipWriter, in); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
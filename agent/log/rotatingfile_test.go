@@ -0,0 +1,111 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingFileWriterRotatesBySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotatingfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "agent.log")
+	writer, err := NewRotatingFileWriter(path, RotationConfig{MaxSize: 10, MaxRolls: 2})
+	assert.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err = writer.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	writer.Close()
+
+	current, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "fourth\n", string(current))
+	roll1, _ := ioutil.ReadFile(path + ".1")
+	assert.Equal(t, "third\n", string(roll1))
+	roll2, _ := ioutil.ReadFile(path + ".2")
+	assert.Equal(t, "second\n", string(roll2))
+	// the oldest roll is dropped
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRotatingFileWriterCompresses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotatingfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "agent.log")
+	writer, err := NewRotatingFileWriter(path, RotationConfig{MaxSize: 10, MaxRolls: 2, Compress: true})
+	assert.NoError(t, err)
+
+	writer.Write([]byte("first line\n"))
+	writer.Write([]byte("second line\n"))
+	writer.Close()
+
+	assert.Equal(t, []string{path + ".1.gz"}, writer.RolledFiles())
+	file, err := os.Open(path + ".1.gz")
+	assert.NoError(t, err)
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "first line\n", string(content))
+}
+
+func TestRotatingFileWriterKeepsTotalSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotatingfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "agent.log")
+	writer, err := NewRotatingFileWriter(path, RotationConfig{MaxSize: 100, MaxRolls: 5, MaxTotalSize: 250})
+	assert.NoError(t, err)
+
+	line := strings.Repeat("x", 99) + "\n"
+	for i := 0; i < 5; i++ {
+		writer.Write([]byte(line))
+	}
+	writer.Close()
+
+	// the current file and one roll fit in the total size
+	assert.Equal(t, []string{path + ".1"}, writer.RolledFiles())
+}
+
+func TestRotatingFileWriterKeepsTheFileItCantRoll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotatingfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "agent.log")
+	// a directory in place of the roll makes the compression fail
+	assert.NoError(t, os.MkdirAll(filepath.Join(path+".1.gz", "busy"), 0700))
+	writer, err := NewRotatingFileWriter(path, RotationConfig{MaxSize: 10, MaxRolls: 1, Compress: true})
+	assert.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err = writer.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	writer.Close()
+
+	current, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "first\nsecond\nthird\n", string(current))
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cihub/seelog"
+)
+
+const (
+	// eventLogReceiverName is the name the Event Log receiver is referenced by in seelog.xml
+	eventLogReceiverName = "eventlog_receiver"
+
+	// defaultEventLogSource is the provider the events of the agent are reported by in the Application log
+	defaultEventLogSource = "AmazonSSMAgent"
+
+	// maxEventLogMessageLength is the length the messages are truncated at, the Event Log rejects longer messages
+	maxEventLogMessageLength = 31000
+)
+
+// Event ids of the agent events, Event Log subscriptions can select the errors with EventID >= 300
+const (
+	eventIDInformation uint32 = 100
+	eventIDWarning     uint32 = 200
+	eventIDError       uint32 = 300
+	eventIDCritical    uint32 = 400
+)
+
+// EventLogCustomReceiver implements seelog.CustomReceiver, it reports log messages as events of a dedicated
+// provider of the Windows Event Log. Debug and trace messages are left to the other outputs, e.g.
+// <custom name="eventlog_receiver" formatid="fmtjournal" data-source="AmazonSSMAgent"/>
+type EventLogCustomReceiver struct {
+	eventLog eventLogWriter
+}
+
+// eventLogWriter reports events of the type of its method
+type eventLogWriter interface {
+	Info(eventID uint32, message string) error
+	Warning(eventID uint32, message string) error
+	Error(eventID uint32, message string) error
+	Close() error
+}
+
+// ReceiveMessage reports the message as an event of its level
+func (logReceiver *EventLogCustomReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	if logReceiver.eventLog == nil {
+		return nil
+	}
+	message = strings.TrimRightFunc(message, unicode.IsSpace)
+	if len(message) > maxEventLogMessageLength {
+		length := maxEventLogMessageLength
+		for length > 0 && !utf8.RuneStart(message[length]) {
+			length--
+		}
+		message = message[:length] + "..."
+	}
+	switch level {
+	case seelog.CriticalLvl:
+		return logReceiver.eventLog.Error(eventIDCritical, message)
+	case seelog.ErrorLvl:
+		return logReceiver.eventLog.Error(eventIDError, message)
+	case seelog.WarnLvl:
+		return logReceiver.eventLog.Warning(eventIDWarning, message)
+	case seelog.InfoLvl:
+		return logReceiver.eventLog.Info(eventIDInformation, message)
+	default:
+		return nil
+	}
+}
+
+// AfterParse reads the optional source from the XML args and opens the Event Log
+func (logReceiver *EventLogCustomReceiver) AfterParse(initArgs seelog.CustomReceiverInitArgs) (err error) {
+	source := defaultEventLogSource
+	if value, ok := initArgs.XmlCustomAttrs["source"]; ok && value != "" {
+		source = value
+	}
+	logReceiver.eventLog, err = openEventLog(source)
+	return err
+}
+
+// Flush does nothing, every message is reported as it is received
+func (logReceiver *EventLogCustomReceiver) Flush() {
+}
+
+// Close closes the Event Log
+func (logReceiver *EventLogCustomReceiver) Close() error {
+	if logReceiver.eventLog == nil {
+		return nil
+	}
+	return logReceiver.eventLog.Close()
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeEventLog struct {
+	events []string
+	closed bool
+}
+
+func (l *fakeEventLog) report(eventType string, eventID uint32, message string) error {
+	l.events = append(l.events, fmt.Sprintf("%v %v %v", eventType, eventID, message))
+	return nil
+}
+
+func (l *fakeEventLog) Info(eventID uint32, message string) error {
+	return l.report("info", eventID, message)
+}
+
+func (l *fakeEventLog) Warning(eventID uint32, message string) error {
+	return l.report("warning", eventID, message)
+}
+
+func (l *fakeEventLog) Error(eventID uint32, message string) error {
+	return l.report("error", eventID, message)
+}
+
+func (l *fakeEventLog) Close() error {
+	l.closed = true
+	return nil
+}
+
+func TestEventLogReceiverEvents(t *testing.T) {
+	eventLog := &fakeEventLog{}
+	receiver := EventLogCustomReceiver{eventLog: eventLog}
+
+	assert.NoError(t, receiver.ReceiveMessage("amazon-ssm-agent - v2.2.0.0 - Starting Agent\n", seelog.InfoLvl, nil))
+	assert.NoError(t, receiver.ReceiveMessage("polling for messages", seelog.DebugLvl, nil))
+	assert.NoError(t, receiver.ReceiveMessage("retrying", seelog.WarnLvl, nil))
+	assert.NoError(t, receiver.ReceiveMessage("[commandID=2b196342] plugin failed", seelog.ErrorLvl, nil))
+	assert.NoError(t, receiver.ReceiveMessage("agent crashed", seelog.CriticalLvl, nil))
+
+	assert.Equal(t, []string{
+		"info 100 amazon-ssm-agent - v2.2.0.0 - Starting Agent",
+		"warning 200 retrying",
+		"error 300 [commandID=2b196342] plugin failed",
+		"error 400 agent crashed",
+	}, eventLog.events)
+
+	receiver.Close()
+	assert.True(t, eventLog.closed)
+}
+
+func TestEventLogReceiverTruncatesMessages(t *testing.T) {
+	eventLog := &fakeEventLog{}
+	receiver := EventLogCustomReceiver{eventLog: eventLog}
+
+	assert.NoError(t, receiver.ReceiveMessage(strings.Repeat("x", maxEventLogMessageLength+10), seelog.InfoLvl, nil))
+	assert.Len(t, eventLog.events[0], len("info 100 ")+maxEventLogMessageLength+len("..."))
+}
+
+func TestEventLogReceiverTruncatesMessagesAtRuneBoundary(t *testing.T) {
+	eventLog := &fakeEventLog{}
+	receiver := EventLogCustomReceiver{eventLog: eventLog}
+
+	message := strings.Repeat("x", maxEventLogMessageLength-1) + "é"
+	assert.NoError(t, receiver.ReceiveMessage(message, seelog.InfoLvl, nil))
+	assert.True(t, utf8.ValidString(eventLog.events[0]))
+	assert.Equal(t, "info 100 "+strings.Repeat("x", maxEventLogMessageLength-1)+"...", eventLog.events[0])
+}
+
+func TestEventLogReceiverNotOpened(t *testing.T) {
+	receiver := EventLogCustomReceiver{}
+	assert.NoError(t, receiver.ReceiveMessage("message", seelog.ErrorLvl, nil))
+	assert.NoError(t, receiver.Close())
+}
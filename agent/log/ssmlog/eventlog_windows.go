@@ -0,0 +1,29 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// openEventLog registers the source in the Application log if it isn't already and opens it. The messages
+// of the events are rendered with the message file of EventCreate, which supports the ids of the agent events.
+func openEventLog(source string) (eventLogWriter, error) {
+	// fails if the source is already registered
+	eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info)
+	return eventlog.Open(source)
+}
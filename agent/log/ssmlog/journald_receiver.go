@@ -0,0 +1,142 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// journaldReceiverName is the name the journald receiver is referenced by in seelog.xml
+	journaldReceiverName = "journald_receiver"
+
+	// defaultSyslogIdentifier is the identifier journal entries of the agent are tagged with
+	defaultSyslogIdentifier = "amazon-ssm-agent"
+)
+
+// journaldFieldNames maps the log context keys to the journal fields they are sent as
+var journaldFieldNames = map[string]string{
+	log.ContextKeyMessageID:     "SSM_MESSAGE_ID",
+	log.ContextKeyCommandID:     "SSM_COMMAND_ID",
+	log.ContextKeyAssociationID: "SSM_ASSOCIATION_ID",
+	log.ContextKeyDocumentName:  "SSM_DOCUMENT_NAME",
+	log.ContextKeyPluginName:    "SSM_PLUGIN",
+	log.ContextKeyInstanceID:    "SSM_INSTANCE_ID",
+	log.ContextKeySessionID:     "SSM_SESSION_ID",
+	log.ContextKeyTraceID:       "SSM_TRACE_ID",
+	log.ContextKeySpanID:        "SSM_SPAN_ID",
+}
+
+// JournaldCustomReceiver implements seelog.CustomReceiver, it sends log messages to the systemd journal
+// using the native journal protocol so that the context of a message is available as journal fields,
+// e.g. journalctl SYSLOG_IDENTIFIER=amazon-ssm-agent SSM_COMMAND_ID=<command id>
+type JournaldCustomReceiver struct {
+	identifier string
+	journal    journalWriter
+}
+
+// journalWriter sends a serialized entry to the journal
+type journalWriter interface {
+	Write(entry []byte) error
+	Close() error
+}
+
+// ReceiveMessage sends the message to the journal along with its context fields
+func (logReceiver *JournaldCustomReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	if logReceiver.journal == nil {
+		return nil
+	}
+	return logReceiver.journal.Write(logReceiver.journalEntry(message, level, context))
+}
+
+// AfterParse reads the optional syslog identifier from the XML args and connects to the journal
+func (logReceiver *JournaldCustomReceiver) AfterParse(initArgs seelog.CustomReceiverInitArgs) (err error) {
+	logReceiver.identifier = defaultSyslogIdentifier
+	if identifier, ok := initArgs.XmlCustomAttrs["identifier"]; ok && identifier != "" {
+		logReceiver.identifier = identifier
+	}
+	logReceiver.journal, err = openJournal()
+	return err
+}
+
+// Flush does nothing, every message is sent to the journal as it is received
+func (logReceiver *JournaldCustomReceiver) Flush() {
+}
+
+// Close closes the connection to the journal
+func (logReceiver *JournaldCustomReceiver) Close() error {
+	if logReceiver.journal == nil {
+		return nil
+	}
+	return logReceiver.journal.Close()
+}
+
+// journalEntry serializes the message with its fields according to the journal native protocol
+func (logReceiver *JournaldCustomReceiver) journalEntry(message string, level seelog.LogLevel, context seelog.LogContextInterface) []byte {
+	var entry bytes.Buffer
+	message = strings.TrimRightFunc(message, unicode.IsSpace)
+	appendJournalField(&entry, "MESSAGE", message)
+	appendJournalField(&entry, "PRIORITY", strconv.Itoa(journalPriority(level)))
+	appendJournalField(&entry, "SYSLOG_IDENTIFIER", logReceiver.identifier)
+	if context != nil {
+		appendJournalField(&entry, "CODE_FILE", context.FileName())
+		appendJournalField(&entry, "CODE_LINE", strconv.Itoa(context.Line()))
+		appendJournalField(&entry, "CODE_FUNC", context.Func())
+	}
+	for key, value := range log.ContextFields(message) {
+		if name, ok := journaldFieldNames[key]; ok {
+			appendJournalField(&entry, name, value)
+		}
+	}
+	return entry.Bytes()
+}
+
+// appendJournalField appends a field to the entry, values spanning several lines are length prefixed
+func appendJournalField(entry *bytes.Buffer, name string, value string) {
+	entry.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		entry.WriteByte('=')
+		entry.WriteString(value)
+		entry.WriteByte('\n')
+		return
+	}
+	entry.WriteByte('\n')
+	binary.Write(entry, binary.LittleEndian, uint64(len(value)))
+	entry.WriteString(value)
+	entry.WriteByte('\n')
+}
+
+// journalPriority maps a seelog level to the syslog priority of the journal
+func journalPriority(level seelog.LogLevel) int {
+	switch level {
+	case seelog.CriticalLvl:
+		return 2
+	case seelog.ErrorLvl:
+		return 3
+	case seelog.WarnLvl:
+		return 4
+	case seelog.InfoLvl:
+		return 6
+	default:
+		return 7
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeJournal struct {
+	entries [][]byte
+	closed  bool
+}
+
+func (j *fakeJournal) Write(entry []byte) error {
+	j.entries = append(j.entries, entry)
+	return nil
+}
+
+func (j *fakeJournal) Close() error {
+	j.closed = true
+	return nil
+}
+
+func TestJournaldReceiverStructuredFields(t *testing.T) {
+	journal := &fakeJournal{}
+	receiver := JournaldCustomReceiver{identifier: defaultSyslogIdentifier, journal: journal}
+
+	err := receiver.ReceiveMessage("[messageID=aws.ssm.2b196342-d7d4-436e-8f09-3883a1116ac3.i-123] [documentName=AWS-RunShellScript] [pluginName=aws:runShellScript] Running plugin\n", seelog.ErrorLvl, nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, journal.entries, 1)
+	entry := string(journal.entries[0])
+	assert.Contains(t, entry, "MESSAGE=[messageID=aws.ssm.2b196342-d7d4-436e-8f09-3883a1116ac3.i-123] [documentName=AWS-RunShellScript] [pluginName=aws:runShellScript] Running plugin\n")
+	assert.Contains(t, entry, "PRIORITY=3\n")
+	assert.Contains(t, entry, "SYSLOG_IDENTIFIER=amazon-ssm-agent\n")
+	assert.Contains(t, entry, "SSM_COMMAND_ID=2b196342-d7d4-436e-8f09-3883a1116ac3\n")
+	assert.Contains(t, entry, "SSM_DOCUMENT_NAME=AWS-RunShellScript\n")
+	assert.Contains(t, entry, "SSM_PLUGIN=aws:runShellScript\n")
+
+	receiver.Close()
+	assert.True(t, journal.closed)
+}
+
+func TestJournaldReceiverMultilineMessage(t *testing.T) {
+	var entry bytes.Buffer
+	appendJournalField(&entry, "MESSAGE", "line1\nline2")
+
+	var expected bytes.Buffer
+	expected.WriteString("MESSAGE\n")
+	binary.Write(&expected, binary.LittleEndian, uint64(11))
+	expected.WriteString("line1\nline2\n")
+	assert.Equal(t, expected.Bytes(), entry.Bytes())
+}
+
+func TestJournaldReceiverNotConnected(t *testing.T) {
+	receiver := JournaldCustomReceiver{}
+	assert.NoError(t, receiver.ReceiveMessage("message", seelog.InfoLvl, nil))
+	assert.NoError(t, receiver.Close())
+}
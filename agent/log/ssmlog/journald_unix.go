@@ -0,0 +1,53 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"net"
+	"sync"
+)
+
+// journalSocketPath is the datagram socket journald listens to for native protocol entries
+var journalSocketPath = "/run/systemd/journal/socket"
+
+// journalSocket sends entries to journald over its datagram socket
+type journalSocket struct {
+	conn *net.UnixConn
+	m    sync.Mutex
+}
+
+// openJournal connects to the journald socket
+func openJournal() (journalWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journalSocket{conn: conn}, nil
+}
+
+// Write sends one entry to the journal
+func (j *journalSocket) Write(entry []byte) error {
+	j.m.Lock()
+	defer j.m.Unlock()
+	_, err := j.conn.Write(entry)
+	return err
+}
+
+// Close closes the journald socket
+func (j *journalSocket) Close() error {
+	return j.conn.Close()
+}
@@ -0,0 +1,26 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"errors"
+)
+
+// openJournal fails, there is no systemd journal on windows
+func openJournal() (journalWriter, error) {
+	return nil, errors.New("journald is not supported on windows")
+}
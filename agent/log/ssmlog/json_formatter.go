@@ -0,0 +1,99 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/cihub/seelog"
+)
+
+// jsonFormatterName is the name the JSON formatter is referenced by in seelog.xml,
+// e.g. <format id="fmtjson" format="%SsmJson%n"/>
+const jsonFormatterName = "SsmJson"
+
+// jsonFieldNames maps the log context keys to the fields of the JSON events
+var jsonFieldNames = map[string]string{
+	log.ContextKeyMessageID:     "messageId",
+	log.ContextKeyCommandID:     "commandId",
+	log.ContextKeyAssociationID: "associationId",
+	log.ContextKeyDocumentName:  "documentName",
+	log.ContextKeyPluginName:    "plugin",
+	log.ContextKeyInstanceID:    "instanceId",
+	log.ContextKeySessionID:     "sessionId",
+	log.ContextKeyTraceID:       "traceId",
+	log.ContextKeySpanID:        "spanId",
+}
+
+// leadingContextPattern matches the context tags loggers prefix their messages with, e.g. [EngineProcessor] [messageID=...]
+var leadingContextPattern = regexp.MustCompile(`^(\[[^\]]*\]\s*)*`)
+
+func init() {
+	if err := seelog.RegisterCustomFormatter(jsonFormatterName, createJSONFormatter); err != nil {
+		fmt.Printf("failed to register the %v log formatter: %v\n", jsonFormatterName, err)
+	}
+}
+
+// createJSONFormatter creates the formatter that writes each log message as a JSON object,
+// so log pipelines don't have to parse the text format
+func createJSONFormatter(param string) seelog.FormatterFunc {
+	return func(message string, level seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+		return jsonEvent(message, level, context)
+	}
+}
+
+// jsonEvent serializes the message with its level, time, module and context fields.
+// The documentId is the command id or association id the message is correlated with.
+func jsonEvent(message string, level seelog.LogLevel, context seelog.LogContextInterface) string {
+	message = strings.TrimRightFunc(message, unicode.IsSpace)
+	event := map[string]string{
+		"level": level.String(),
+	}
+	timestamp := time.Now()
+	if context != nil {
+		timestamp = context.CallTime()
+		event["source"] = fmt.Sprintf("%v:%v", context.FileName(), context.Line())
+	}
+	event["timestamp"] = timestamp.UTC().Format(time.RFC3339Nano)
+
+	contextTags := leadingContextPattern.FindString(message)
+	event["message"] = message[len(contextTags):]
+	for _, tag := range strings.Fields(contextTags) {
+		if !strings.Contains(tag, "=") {
+			event["module"] = strings.Trim(tag, "[]")
+			break
+		}
+	}
+	for key, value := range log.ContextFields(contextTags) {
+		if name, ok := jsonFieldNames[key]; ok {
+			event[name] = value
+		}
+	}
+	if commandID, ok := event["commandId"]; ok {
+		event["documentId"] = commandID
+	} else if associationID, ok := event["associationId"]; ok {
+		event["documentId"] = associationID
+	}
+
+	// a map of strings always marshals
+	serialized, _ := json.Marshal(event)
+	return string(serialized)
+}
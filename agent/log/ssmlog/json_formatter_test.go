@@ -0,0 +1,57 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONEventFields(t *testing.T) {
+	serialized := jsonEvent("[EngineProcessor] [messageID=aws.ssm.2b196342-d7d4-436e-8f09-3883a1116ac3.i-123] [documentName=AWS-RunShellScript] Running \"plugin\"\n", seelog.InfoLvl, nil)
+
+	var event map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(serialized), &event))
+	assert.Equal(t, "info", event["level"])
+	assert.Equal(t, "EngineProcessor", event["module"])
+	assert.Equal(t, "Running \"plugin\"", event["message"])
+	assert.Equal(t, "2b196342-d7d4-436e-8f09-3883a1116ac3", event["commandId"])
+	assert.Equal(t, "2b196342-d7d4-436e-8f09-3883a1116ac3", event["documentId"])
+	assert.Equal(t, "AWS-RunShellScript", event["documentName"])
+	assert.NotEmpty(t, event["timestamp"])
+}
+
+func TestJSONEventMessageWithBrackets(t *testing.T) {
+	serialized := jsonEvent("[associationId=a-123] document [x] failed\nsecond line", seelog.ErrorLvl, nil)
+
+	var event map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(serialized), &event))
+	assert.Equal(t, "document [x] failed\nsecond line", event["message"])
+	assert.Equal(t, "a-123", event["documentId"])
+	_, hasModule := event["module"]
+	assert.False(t, hasModule)
+}
+
+func TestJSONFormatterInSeelogConfig(t *testing.T) {
+	config := `<seelog type="sync"><outputs formatid="fmtjson"><console/></outputs>
+<formats><format id="fmtjson" format="%SsmJson%n"/></formats></seelog>`
+
+	_, err := seelog.LoggerFromConfigAsString(config)
+
+	assert.NoError(t, err)
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// logBackendFormatID is the format of the messages sent to the log backend, the backend records their time,
+// level and caller on its own
+const logBackendFormatID = "fmtlogbackend"
+
+// logBackendReceivers maps the log backends of appconfig to the receivers the agent logs are sent to
+var logBackendReceivers = map[string]string{
+	appconfig.LogBackendJournald: journaldReceiverName,
+	appconfig.LogBackendSyslog:   syslogReceiverName,
+	appconfig.LogBackendEventLog: eventLogReceiverName,
+}
+
+// outputsPattern matches the outputs element of a seelog configuration
+var outputsPattern = regexp.MustCompile(`(?s)<outputs\b.*?</outputs>`)
+
+// outputsEndPattern matches the closing of the outputs element of a seelog configuration
+var outputsEndPattern = regexp.MustCompile(`</outputs>`)
+
+// localOutputPatterns match the receivers of a seelog configuration writing to the files or the console, which the
+// log backends replace unless the file backend is kept
+var localOutputPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?s)<buffered\b[^>]*>.*?</buffered>`),
+	regexp.MustCompile(`(?s)<(rollingfile|file|console)\b[^>]*?/>`),
+	regexp.MustCompile(`(?s)<(rollingfile|file|console)\b[^>]*>.*?</(rollingfile|file|console)>`),
+}
+
+// emptyFilterPattern matches a filter of a seelog configuration left without receivers
+var emptyFilterPattern = regexp.MustCompile(`(?s)<filter\b[^>]*>\s*</filter>`)
+
+// formatsEndPattern matches the closing of the formats element of a seelog configuration
+var formatsEndPattern = regexp.MustCompile(`</formats>`)
+
+// rootEndPattern matches the closing of the root element of a seelog configuration
+var rootEndPattern = regexp.MustCompile(`</seelog>`)
+
+// logBackend returns the log backend configured in appconfig
+func logBackend() string {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		return appconfig.LogBackendFile
+	}
+	return config.Agent.LogBackend
+}
+
+// withLogBackend returns the seelog configuration with the receivers of the log backends, a comma separated list,
+// added to its outputs. Unless the list has the file backend, the outputs writing to the files and the console are
+// removed; the other outputs, e.g. custom receivers, are kept.
+func withLogBackend(seelogConfig []byte, backend string) []byte {
+	var receivers []string
+	keepLocalOutputs := false
+	for _, name := range strings.Split(backend, ",") {
+		if name == appconfig.LogBackendFile {
+			keepLocalOutputs = true
+		} else if receiver, ok := logBackendReceivers[name]; ok {
+			receivers = append(receivers, receiver)
+		}
+	}
+	if len(receivers) == 0 || !outputsPattern.Match(seelogConfig) {
+		return seelogConfig
+	}
+
+	updated := seelogConfig
+	if !keepLocalOutputs {
+		outputs := outputsPattern.Find(updated)
+		for _, pattern := range append(localOutputPatterns, emptyFilterPattern) {
+			outputs = pattern.ReplaceAllLiteral(outputs, nil)
+		}
+		updated = outputsPattern.ReplaceAllLiteral(updated, outputs)
+	}
+	for _, receiver := range receivers {
+		location := outputsEndPattern.FindIndex(updated)
+		updated = insertAt(updated, location[0], `<custom name="`+receiver+`" formatid="`+logBackendFormatID+`"/>`)
+	}
+
+	format := `<format id="` + logBackendFormatID + `" format="%Msg"/>`
+	if location := formatsEndPattern.FindIndex(updated); location != nil {
+		return insertAt(updated, location[0], format)
+	}
+	// the configuration has no formats, add them
+	if location := rootEndPattern.FindIndex(updated); location != nil {
+		return insertAt(updated, location[0], "<formats>"+format+"</formats>")
+	}
+	return updated
+}
+
+// insertAt inserts the text in the seelog configuration at the index
+func insertAt(seelogConfig []byte, index int, text string) []byte {
+	updated := make([]byte, 0, len(seelogConfig)+len(text))
+	updated = append(updated, seelogConfig[:index]...)
+	updated = append(updated, []byte(text)...)
+	return append(updated, seelogConfig[index:]...)
+}
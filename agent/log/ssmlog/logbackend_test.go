@@ -0,0 +1,65 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLogBackend(t *testing.T) {
+	seelogConfig := []byte(`<seelog minlevel="info"><outputs formatid="fmtinfo"><console/>` +
+		`<filter levels="error"><rollingfile type="size" filename="errors.log" maxsize="100" maxrolls="5"/></filter></outputs>` +
+		`<formats><format id="fmtinfo" format="%Msg%n"/></formats></seelog>`)
+
+	assert.Equal(t,
+		`<seelog minlevel="info"><outputs formatid="fmtinfo"><custom name="journald_receiver" formatid="fmtlogbackend"/></outputs>`+
+			`<formats><format id="fmtinfo" format="%Msg%n"/><format id="fmtlogbackend" format="%Msg"/></formats></seelog>`,
+		string(withLogBackend(seelogConfig, appconfig.LogBackendJournald)))
+	assert.Equal(t, string(seelogConfig), string(withLogBackend(seelogConfig, appconfig.LogBackendFile)))
+
+	assert.Equal(t,
+		`<seelog><outputs><custom name="syslog_receiver" formatid="fmtlogbackend"/></outputs><formats><format id="fmtlogbackend" format="%Msg"/></formats></seelog>`,
+		string(withLogBackend([]byte(`<seelog><outputs><console/></outputs></seelog>`), appconfig.LogBackendSyslog)))
+
+	// the receivers are added to the outputs along with the file backend
+	assert.Equal(t,
+		`<seelog><outputs><console/><custom name="eventlog_receiver" formatid="fmtlogbackend"/></outputs><formats><format id="fmtlogbackend" format="%Msg"/></formats></seelog>`,
+		string(withLogBackend([]byte(`<seelog><outputs><console/></outputs></seelog>`), "file,eventlog")))
+}
+
+func TestWithLogBackendKeepsTheOtherOutputs(t *testing.T) {
+	seelogConfig := []byte(`<seelog><outputs><buffered size="100"><file path="agent.log"/></buffered>` +
+		`<filter levels="error"><console/><conn net="tcp" addr="logs.example.com:514"/></filter>` +
+		`<custom name="audit"/></outputs></seelog>`)
+
+	assert.Equal(t,
+		`<seelog><outputs><filter levels="error"><conn net="tcp" addr="logs.example.com:514"/></filter>`+
+			`<custom name="audit"/><custom name="journald_receiver" formatid="fmtlogbackend"/></outputs>`+
+			`<formats><format id="fmtlogbackend" format="%Msg"/></formats></seelog>`,
+		string(withLogBackend(seelogConfig, appconfig.LogBackendJournald)))
+}
+
+func TestWithLogBackendDefaultConfig(t *testing.T) {
+	seelogConfig := string(withLogBackend(log.DefaultConfig(), appconfig.LogBackendJournald))
+	assert.Contains(t, seelogConfig, `<custom name="journald_receiver" formatid="fmtlogbackend"/>`)
+	assert.Contains(t, seelogConfig, `<format id="fmtlogbackend" format="%Msg"/>`)
+	assert.NotContains(t, seelogConfig, "rollingfile")
+	assert.NotContains(t, seelogConfig, "<filter")
+	assert.Contains(t, seelogConfig, `minlevel="info"`)
+}
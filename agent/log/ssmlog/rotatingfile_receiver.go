@@ -0,0 +1,105 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// rotatingFileReceiverName is the name the rotating file receiver is referenced by in seelog.xml
+	rotatingFileReceiverName = "rotatingfile_receiver"
+
+	defaultRotationMaxSize  = 30000000
+	defaultRotationMaxRolls = 5
+)
+
+// RotatingFileCustomReceiver implements seelog.CustomReceiver, it writes the log messages to a file that is
+// rotated by size, optionally gzipping the rotated files and keeping them under a total size, e.g.
+// <custom name="rotatingfile_receiver" formatid="fmtinfo" data-filename="/var/log/amazon/ssm/amazon-ssm-agent.log"
+// data-maxsize="30000000" data-maxrolls="5" data-maxtotalsize="60000000" data-compress="true"/>
+type RotatingFileCustomReceiver struct {
+	writer *log.RotatingFileWriter
+}
+
+// ReceiveMessage writes the formatted message to the file
+func (logReceiver *RotatingFileCustomReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	if logReceiver.writer == nil {
+		return nil
+	}
+	_, err := logReceiver.writer.Write([]byte(message))
+	return err
+}
+
+// AfterParse reads the file name and the rotation settings from the XML args and opens the file
+func (logReceiver *RotatingFileCustomReceiver) AfterParse(initArgs seelog.CustomReceiverInitArgs) (err error) {
+	path, config, err := parseRotationArgs(initArgs.XmlCustomAttrs)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	logReceiver.writer, err = log.NewRotatingFileWriter(path, config)
+	return err
+}
+
+// Flush does nothing, every message is written to the file as it is received
+func (logReceiver *RotatingFileCustomReceiver) Flush() {
+}
+
+// Close closes the file
+func (logReceiver *RotatingFileCustomReceiver) Close() error {
+	if logReceiver.writer == nil {
+		return nil
+	}
+	return logReceiver.writer.Close()
+}
+
+// parseRotationArgs reads the data- attributes of the receiver
+func parseRotationArgs(attrs map[string]string) (path string, config log.RotationConfig, err error) {
+	path = attrs["filename"]
+	if path == "" {
+		return "", config, fmt.Errorf("%v requires a data-filename", rotatingFileReceiverName)
+	}
+	config = log.RotationConfig{MaxSize: defaultRotationMaxSize, MaxRolls: defaultRotationMaxRolls}
+	if value, ok := attrs["maxsize"]; ok {
+		if config.MaxSize, err = strconv.ParseInt(value, 10, 64); err != nil || config.MaxSize <= 0 {
+			return "", config, fmt.Errorf("invalid data-maxsize %v", value)
+		}
+	}
+	if value, ok := attrs["maxrolls"]; ok {
+		if config.MaxRolls, err = strconv.Atoi(value); err != nil || config.MaxRolls < 0 {
+			return "", config, fmt.Errorf("invalid data-maxrolls %v", value)
+		}
+	}
+	if value, ok := attrs["maxtotalsize"]; ok {
+		if config.MaxTotalSize, err = strconv.ParseInt(value, 10, 64); err != nil || config.MaxTotalSize < 0 {
+			return "", config, fmt.Errorf("invalid data-maxtotalsize %v", value)
+		}
+	}
+	if value, ok := attrs["compress"]; ok {
+		if config.Compress, err = strconv.ParseBool(value); err != nil {
+			return "", config, fmt.Errorf("invalid data-compress %v", value)
+		}
+	}
+	return path, config, nil
+}
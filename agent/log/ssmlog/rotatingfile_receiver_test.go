@@ -0,0 +1,61 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRotationArgs(t *testing.T) {
+	path, config, err := parseRotationArgs(map[string]string{
+		"filename":     "/var/log/amazon/ssm/amazon-ssm-agent.log",
+		"maxsize":      "1000",
+		"maxtotalsize": "5000",
+		"compress":     "true",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/log/amazon/ssm/amazon-ssm-agent.log", path)
+	assert.Equal(t, log.RotationConfig{MaxSize: 1000, MaxRolls: defaultRotationMaxRolls, MaxTotalSize: 5000, Compress: true}, config)
+
+	_, _, err = parseRotationArgs(map[string]string{})
+	assert.Error(t, err)
+	_, _, err = parseRotationArgs(map[string]string{"filename": "agent.log", "maxsize": "big"})
+	assert.Error(t, err)
+}
+
+func TestRotatingFileReceiver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotatingfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs", "agent.log")
+	receiver := RotatingFileCustomReceiver{}
+
+	err = receiver.AfterParse(seelog.CustomReceiverInitArgs{XmlCustomAttrs: map[string]string{"filename": path}})
+	assert.NoError(t, err)
+	assert.NoError(t, receiver.ReceiveMessage("message\n", seelog.InfoLvl, nil))
+	assert.NoError(t, receiver.Close())
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "message\n", string(content))
+}
@@ -16,9 +16,11 @@ package ssmlog
 
 import (
 	"fmt"
-
+	"os"
+	"path/filepath"
 	"sync"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/cihub/seelog"
 )
@@ -47,7 +49,7 @@ func SSMLogger(useWatcher bool) log.T {
 // initLogger initializes a new logger based on current configurations and starts file watcher on the configurations file
 func initLogger(useWatcher bool) (logger log.T) {
 	// Read the current configurations or get the default configurations
-	logConfigBytes := log.GetLogConfigBytes()
+	logConfigBytes := logConfigBytes()
 	// Initialize the base seelog logger
 	baseLogger, _ := initBaseLoggerFromBytes(logConfigBytes)
 	// Create the wrapper logger
@@ -56,9 +58,25 @@ func initLogger(useWatcher bool) (logger log.T) {
 		// Start the config file watcher
 		startWatcher(logger)
 	}
+	// The runtime log level applies to the agent and to the document workers
+	startLogLevelWatcher(logger)
 	return
 }
 
+// logConfigBytes returns the current configurations with the log backend and the runtime log level applied, if any
+func logConfigBytes() []byte {
+	logConfigBytes := log.GetLogConfigBytes()
+	if backend := logBackend(); backend != appconfig.LogBackendFile {
+		fmt.Println("Applying log backend:", backend)
+		logConfigBytes = withLogBackend(logConfigBytes, backend)
+	}
+	if level := log.GetLogLevelOverride(); level != "" {
+		fmt.Println("Applying runtime log level:", level)
+		logConfigBytes = log.WithLogLevel(logConfigBytes, level)
+	}
+	return logConfigBytes
+}
+
 // withContext creates a wrapper logger on the base logger passed with context is passed
 func withContext(logger seelog.LoggerInterface, context ...string) (contextLogger log.T) {
 	loggerInstance.BaseLoggerInstance = logger
@@ -134,6 +152,32 @@ func startWatcher(logger log.T) {
 	fileWatcher.Start()
 }
 
+// startLogLevelWatcher starts the file watcher on the runtime log level file
+func startLogLevelWatcher(logger log.T) {
+	defer func() {
+		// In case the creation of watcher panics, let the current logger continue
+		if msg := recover(); msg != nil {
+			logger.Errorf("Log Level File Watcher Initilization Failed. Runtime log level changes will be ignored: %v", msg)
+		}
+	}()
+	// The watcher needs the folder of the file to exist
+	if err := os.MkdirAll(filepath.Dir(log.LogLevelOverridePath), 0750); err != nil {
+		logger.Errorf("Failed to create the runtime log level folder. Runtime log level changes will be ignored: %v", err)
+		return
+	}
+	fileWatcher := &FileWatcher{}
+	fileWatcher.Init(logger, log.LogLevelOverridePath, replaceLogger)
+	fileWatcher.Start()
+}
+
+// ReloadLogger replaces the current logger with a new logger initialized from the current configurations file
+// and runtime log level, if a logger has been loaded
+func ReloadLogger() {
+	if isLoaded() {
+		replaceLogger()
+	}
+}
+
 // ReplaceLogger replaces the current logger with a new logger initialized from the current configurations file
 func replaceLogger() {
 	fmt.Println("Replacing Logger")
@@ -142,7 +186,7 @@ func replaceLogger() {
 	logger := getCached()
 
 	//Create new logger
-	logConfigBytes := log.GetLogConfigBytes()
+	logConfigBytes := logConfigBytes()
 	baseLogger, err := initBaseLoggerFromBytes(logConfigBytes)
 
 	// If err in creating logger, do not replace logger
@@ -176,6 +220,10 @@ func initBaseLoggerFromBytes(seelogConfig []byte) (seelogger seelog.LoggerInterf
 	fmt.Println("Initializing new seelog logger")
 	logReceiver := &CloudWatchCustomReceiver{}
 	seelog.RegisterReceiver("cloudwatch_receiver", logReceiver)
+	seelog.RegisterReceiver(journaldReceiverName, &JournaldCustomReceiver{})
+	seelog.RegisterReceiver(syslogReceiverName, &SyslogCustomReceiver{})
+	seelog.RegisterReceiver(eventLogReceiverName, &EventLogCustomReceiver{})
+	seelog.RegisterReceiver(rotatingFileReceiverName, &RotatingFileCustomReceiver{})
 	seelogger, err = seelog.LoggerFromConfigAsBytes(seelogConfig)
 	if err != nil {
 		fmt.Println("Error parsing logger config. Creating logger from default config:", err)
@@ -0,0 +1,93 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/cihub/seelog"
+)
+
+const (
+	// syslogReceiverName is the name the syslog receiver is referenced by in seelog.xml
+	syslogReceiverName = "syslog_receiver"
+
+	// defaultSyslogFacility is the facility the messages of the agent are sent with
+	defaultSyslogFacility = "daemon"
+)
+
+// SyslogCustomReceiver implements seelog.CustomReceiver, it sends log messages to the local syslog daemon
+// with the syslog priority of their level. The context of a message is kept as its [key=value] tags, e.g.
+// <custom name="syslog_receiver" formatid="fmtjournal" data-identifier="amazon-ssm-agent" data-facility="local0"/>
+type SyslogCustomReceiver struct {
+	syslog syslogWriter
+}
+
+// syslogWriter sends a message to syslog with the priority of its method
+type syslogWriter interface {
+	Crit(message string) error
+	Err(message string) error
+	Warning(message string) error
+	Info(message string) error
+	Debug(message string) error
+	Close() error
+}
+
+// ReceiveMessage sends the message to syslog with the priority of its level
+func (logReceiver *SyslogCustomReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	if logReceiver.syslog == nil {
+		return nil
+	}
+	message = strings.TrimRightFunc(message, unicode.IsSpace)
+	switch level {
+	case seelog.CriticalLvl:
+		return logReceiver.syslog.Crit(message)
+	case seelog.ErrorLvl:
+		return logReceiver.syslog.Err(message)
+	case seelog.WarnLvl:
+		return logReceiver.syslog.Warning(message)
+	case seelog.InfoLvl:
+		return logReceiver.syslog.Info(message)
+	default:
+		return logReceiver.syslog.Debug(message)
+	}
+}
+
+// AfterParse reads the optional identifier and facility from the XML args and connects to syslog
+func (logReceiver *SyslogCustomReceiver) AfterParse(initArgs seelog.CustomReceiverInitArgs) (err error) {
+	identifier := defaultSyslogIdentifier
+	if value, ok := initArgs.XmlCustomAttrs["identifier"]; ok && value != "" {
+		identifier = value
+	}
+	facility := defaultSyslogFacility
+	if value, ok := initArgs.XmlCustomAttrs["facility"]; ok && value != "" {
+		facility = strings.ToLower(value)
+	}
+	logReceiver.syslog, err = openSyslog(facility, identifier)
+	return err
+}
+
+// Flush does nothing, every message is sent to syslog as it is received
+func (logReceiver *SyslogCustomReceiver) Flush() {
+}
+
+// Close closes the connection to syslog
+func (logReceiver *SyslogCustomReceiver) Close() error {
+	if logReceiver.syslog == nil {
+		return nil
+	}
+	return logReceiver.syslog.Close()
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"testing"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSyslog struct {
+	messages []string
+	closed   bool
+}
+
+func (s *fakeSyslog) send(priority string, message string) error {
+	s.messages = append(s.messages, priority+" "+message)
+	return nil
+}
+
+func (s *fakeSyslog) Crit(message string) error    { return s.send("crit", message) }
+func (s *fakeSyslog) Err(message string) error     { return s.send("err", message) }
+func (s *fakeSyslog) Warning(message string) error { return s.send("warning", message) }
+func (s *fakeSyslog) Info(message string) error    { return s.send("info", message) }
+func (s *fakeSyslog) Debug(message string) error   { return s.send("debug", message) }
+
+func (s *fakeSyslog) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestSyslogReceiverPriorities(t *testing.T) {
+	syslog := &fakeSyslog{}
+	receiver := SyslogCustomReceiver{syslog: syslog}
+
+	assert.NoError(t, receiver.ReceiveMessage("[commandID=2b196342] Running plugin\n", seelog.ErrorLvl, nil))
+	assert.NoError(t, receiver.ReceiveMessage("disk is almost full", seelog.WarnLvl, nil))
+	assert.NoError(t, receiver.ReceiveMessage("agent crashed", seelog.CriticalLvl, nil))
+	assert.NoError(t, receiver.ReceiveMessage("started", seelog.InfoLvl, nil))
+	assert.NoError(t, receiver.ReceiveMessage("polling", seelog.TraceLvl, nil))
+
+	assert.Equal(t, []string{
+		"err [commandID=2b196342] Running plugin",
+		"warning disk is almost full",
+		"crit agent crashed",
+		"info started",
+		"debug polling",
+	}, syslog.messages)
+
+	receiver.Close()
+	assert.True(t, syslog.closed)
+}
+
+func TestSyslogReceiverUnsupportedFacility(t *testing.T) {
+	receiver := SyslogCustomReceiver{}
+	err := receiver.AfterParse(seelog.CustomReceiverInitArgs{XmlCustomAttrs: map[string]string{"facility": "kern"}})
+	assert.Error(t, err)
+	assert.NoError(t, receiver.ReceiveMessage("message", seelog.InfoLvl, nil))
+	assert.NoError(t, receiver.Close())
+}
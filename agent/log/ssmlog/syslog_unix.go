@@ -0,0 +1,45 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// syslogFacilities maps the facility names of the XML args to the syslog facilities
+var syslogFacilities = map[string]syslog.Priority{
+	"daemon": syslog.LOG_DAEMON,
+	"user":   syslog.LOG_USER,
+	"local0": syslog.LOG_LOCAL0,
+	"local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4,
+	"local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6,
+	"local7": syslog.LOG_LOCAL7,
+}
+
+// openSyslog connects to the local syslog daemon
+func openSyslog(facility string, identifier string) (syslogWriter, error) {
+	priority, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unsupported syslog facility %v", facility)
+	}
+	return syslog.New(priority|syslog.LOG_INFO, identifier)
+}
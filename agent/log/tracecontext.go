@@ -0,0 +1,101 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+const (
+	// traceParentVersion is the version of the W3C trace context the traceparent is formatted with
+	traceParentVersion = "00"
+
+	// traceParentSampled is the trace flags of the traceparent, the agent traces are always recorded
+	traceParentSampled = "01"
+)
+
+// traceParentPattern matches a W3C traceparent, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+var traceParentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+// TraceContext identifies the processing of a message across the core agent, the document workers and the
+// plugins. The ids are OpenTelemetry compatible: the trace id is generated when the message is received and
+// each subsystem handling it logs with a span of its own.
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// NewTraceContext returns the root span of a new trace.
+func NewTraceContext() TraceContext {
+	return TraceContext{TraceID: randomID(16), SpanID: randomID(8)}
+}
+
+// NewSpan returns a new span of the trace.
+func (t TraceContext) NewSpan() TraceContext {
+	return TraceContext{TraceID: t.TraceID, SpanID: randomID(8)}
+}
+
+// TraceParent formats the trace context as a W3C traceparent, the format it is persisted and propagated in.
+func (t TraceContext) TraceParent() string {
+	return fmt.Sprintf("%v-%v-%v-%v", traceParentVersion, t.TraceID, t.SpanID, traceParentSampled)
+}
+
+// ContextTag returns the context tags loggers are tagged with to log the trace and span ids.
+func (t TraceContext) ContextTag() string {
+	return fmt.Sprintf("[%v=%v] [%v=%v]", ContextKeyTraceID, t.TraceID, ContextKeySpanID, t.SpanID)
+}
+
+// ParseTraceParent parses a W3C traceparent.
+func ParseTraceParent(traceParent string) (TraceContext, error) {
+	match := traceParentPattern.FindStringSubmatch(traceParent)
+	if match == nil || match[2] == zeroID(16) || match[3] == zeroID(8) {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %v", traceParent)
+	}
+	return TraceContext{TraceID: match[2], SpanID: match[3]}, nil
+}
+
+// TraceContextOf returns the innermost trace context a logger context is tagged with.
+func TraceContextOf(context []string) (TraceContext, bool) {
+	var trace TraceContext
+	for _, tag := range context {
+		fields := ContextFields(tag)
+		if traceID, ok := fields[ContextKeyTraceID]; ok {
+			trace = TraceContext{TraceID: traceID, SpanID: fields[ContextKeySpanID]}
+		}
+	}
+	return trace, trace.TraceID != ""
+}
+
+// randomID returns a random hex id of the number of bytes.
+func randomID(size int) string {
+	id := make([]byte, size)
+	for {
+		if _, err := rand.Read(id); err != nil {
+			// the ids only correlate the logs, a failing random source shouldn't fail the processing
+			return zeroID(size-1) + "01"
+		}
+		// the all zero id is invalid
+		if encoded := hex.EncodeToString(id); encoded != zeroID(size) {
+			return encoded
+		}
+	}
+}
+
+// zeroID returns the all zero hex id of the number of bytes.
+func zeroID(size int) string {
+	return fmt.Sprintf("%0*d", size*2, 0)
+}
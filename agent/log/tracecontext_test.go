@@ -0,0 +1,66 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceContext(t *testing.T) {
+	trace := NewTraceContext()
+	assert.Len(t, trace.TraceID, 32)
+	assert.Len(t, trace.SpanID, 16)
+
+	span := trace.NewSpan()
+	assert.Equal(t, trace.TraceID, span.TraceID)
+	assert.NotEqual(t, trace.SpanID, span.SpanID)
+
+	parsed, err := ParseTraceParent(span.TraceParent())
+	assert.NoError(t, err)
+	assert.Equal(t, span, parsed)
+}
+
+func TestParseTraceParent(t *testing.T) {
+	trace, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NoError(t, err)
+	assert.Equal(t, TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}, trace)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+	} {
+		_, err = ParseTraceParent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTraceContextOf(t *testing.T) {
+	_, ok := TraceContextOf([]string{"[EngineProcessor]", "[messageID=abc]"})
+	assert.False(t, ok)
+
+	root := NewTraceContext()
+	span := root.NewSpan()
+	trace, ok := TraceContextOf([]string{"[EngineProcessor]", root.ContextTag(), "[pluginName=aws:runShellScript]", span.ContextTag()})
+	assert.True(t, ok)
+	assert.Equal(t, span, trace)
+
+	fields := ContextFields("[messageID=abc] " + span.ContextTag() + " running")
+	assert.Equal(t, span.TraceID, fields[ContextKeyTraceID])
+	assert.Equal(t, span.SpanID, fields[ContextKeySpanID])
+}
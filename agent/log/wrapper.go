@@ -14,7 +14,21 @@
 package log
 
 import (
+	"errors"
+	"fmt"
 	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log/redact"
+)
+
+const (
+	traceLevel    = "Trace"
+	debugLevel    = "Debug"
+	infoLevel     = "Info"
+	warnLevel     = "Warn"
+	errorLevel    = "Error"
+	criticalLevel = "Critical"
 )
 
 // DelegateLogger holds the base logger for logging
@@ -39,127 +53,239 @@ type FormatFilter interface {
 	Filterf(format string, params ...interface{}) (newFormat string, newParams []interface{})
 }
 
+// renderf formats the message with its context and redacts the secrets it contains before it reaches
+// any receiver, so that debug logging doesn't leak decrypted parameters or credentials.
+func (w *Wrapper) renderf(format string, params ...interface{}) string {
+	format, params = w.Format.Filterf(format, params...)
+	return redact.String(fmt.Sprintf(format, params...))
+}
+
+// render formats the operands with their context and redacts the secrets they contain.
+func (w *Wrapper) render(v ...interface{}) string {
+	return redact.String(fmt.Sprint(w.Format.Filter(v...)...))
+}
+
+// limit returns false if the message repeats a message written within the repeat window. The messages whose
+// window ended are summarized with their number of repetitions first.
+func (w *Wrapper) limit(level string, message string) bool {
+	allowed, summaries := repeats.check(level, message, time.Now())
+	w.writeSummaries(summaries)
+	return allowed
+}
+
+// writeSummaries writes the summaries of the suppressed repetitions at the level of their message.
+func (w *Wrapper) writeSummaries(summaries []repeatSummary) {
+	if len(summaries) == 0 {
+		return
+	}
+	w.M.Lock()
+	defer w.M.Unlock()
+	for _, summary := range summaries {
+		w.delegate(summary.level, summary.String())
+	}
+}
+
+// delegate writes the message to the base logger at the level.
+func (w *Wrapper) delegate(level string, message string) {
+	base := w.Delegate.BaseLoggerInstance
+	switch level {
+	case traceLevel:
+		base.Trace(message)
+	case debugLevel:
+		base.Debug(message)
+	case infoLevel:
+		base.Info(message)
+	case warnLevel:
+		base.Warn(message)
+	case errorLevel:
+		base.Error(message)
+	default:
+		base.Critical(message)
+	}
+}
+
+// capture records the message in the flight recorder, errors trigger a dump of the recent events.
+func (w *Wrapper) capture(level string, message string) {
+	flightRecorder.record(subsystemOf(w.Format), level, message)
+	if level == errorLevel || level == criticalLevel {
+		flightRecorder.dumpOnError()
+	}
+}
+
 // Tracef formats message according to format specifier
 // and writes to log with level = Trace.
 func (w *Wrapper) Tracef(format string, params ...interface{}) {
-	format, params = w.Format.Filterf(format, params...)
+	message := w.renderf(format, params...)
+	if !w.limit(traceLevel, message) {
+		return
+	}
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	w.Delegate.BaseLoggerInstance.Tracef(format, params...)
+	w.Delegate.BaseLoggerInstance.Trace(message)
 }
 
 // Debugf formats message according to format specifier
 // and writes to log with level = Debug.
 func (w *Wrapper) Debugf(format string, params ...interface{}) {
-	format, params = w.Format.Filterf(format, params...)
+	message := w.renderf(format, params...)
+	if !w.limit(debugLevel, message) {
+		return
+	}
+	w.capture(debugLevel, message)
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	w.Delegate.BaseLoggerInstance.Debugf(format, params...)
+	w.Delegate.BaseLoggerInstance.Debug(message)
 }
 
 // Infof formats message according to format specifier
 // and writes to log with level = Info.
 func (w *Wrapper) Infof(format string, params ...interface{}) {
-	format, params = w.Format.Filterf(format, params...)
+	message := w.renderf(format, params...)
+	if !w.limit(infoLevel, message) {
+		return
+	}
+	w.capture(infoLevel, message)
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	w.Delegate.BaseLoggerInstance.Infof(format, params...)
+	w.Delegate.BaseLoggerInstance.Info(message)
 }
 
 // Warnf formats message according to format specifier
 // and writes to log with level = Warn.
 func (w *Wrapper) Warnf(format string, params ...interface{}) error {
-	format, params = w.Format.Filterf(format, params...)
+	message := w.renderf(format, params...)
+	if !w.limit(warnLevel, message) {
+		// the callers may return the error of a suppressed message, like that of a logged one
+		return errors.New(message)
+	}
+	w.capture(warnLevel, message)
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	return w.Delegate.BaseLoggerInstance.Warnf(format, params...)
+	return w.Delegate.BaseLoggerInstance.Warn(message)
 }
 
 // Errorf formats message according to format specifier
 // and writes to log with level = Error.
 func (w *Wrapper) Errorf(format string, params ...interface{}) error {
-	format, params = w.Format.Filterf(format, params...)
+	message := w.renderf(format, params...)
+	if !w.limit(errorLevel, message) {
+		// the callers may return the error of a suppressed message, like that of a logged one
+		return errors.New(message)
+	}
+	w.capture(errorLevel, message)
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	return w.Delegate.BaseLoggerInstance.Errorf(format, params...)
+	return w.Delegate.BaseLoggerInstance.Error(message)
 }
 
 // Criticalf formats message according to format specifier
 // and writes to log with level = Critical.
 func (w *Wrapper) Criticalf(format string, params ...interface{}) error {
-	format, params = w.Format.Filterf(format, params...)
+	message := w.renderf(format, params...)
+	if !w.limit(criticalLevel, message) {
+		// the callers may return the error of a suppressed message, like that of a logged one
+		return errors.New(message)
+	}
+	w.capture(criticalLevel, message)
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	return w.Delegate.BaseLoggerInstance.Criticalf(format, params...)
+	return w.Delegate.BaseLoggerInstance.Critical(message)
 }
 
 // Trace formats message using the default formats for its operands
 // and writes to log with level = Trace
 func (w *Wrapper) Trace(v ...interface{}) {
-	v = w.Format.Filter(v...)
+	message := w.render(v...)
+	if !w.limit(traceLevel, message) {
+		return
+	}
 	w.M.Lock()
 	defer w.M.Unlock()
-	w.Delegate.BaseLoggerInstance.Trace(v...)
+	w.Delegate.BaseLoggerInstance.Trace(message)
 }
 
 // Debug formats message using the default formats for its operands
 // and writes to log with level = Debug
 func (w *Wrapper) Debug(v ...interface{}) {
-	v = w.Format.Filter(v...)
+	message := w.render(v...)
+	if !w.limit(debugLevel, message) {
+		return
+	}
+	w.capture(debugLevel, message)
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	w.Delegate.BaseLoggerInstance.Debug(v...)
+	w.Delegate.BaseLoggerInstance.Debug(message)
 }
 
 // Info formats message using the default formats for its operands
 // and writes to log with level = Info
 func (w *Wrapper) Info(v ...interface{}) {
-	v = w.Format.Filter(v...)
+	message := w.render(v...)
+	if !w.limit(infoLevel, message) {
+		return
+	}
+	w.capture(infoLevel, message)
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	w.Delegate.BaseLoggerInstance.Info(v...)
+	w.Delegate.BaseLoggerInstance.Info(message)
 }
 
 // Warn formats message using the default formats for its operands
 // and writes to log with level = Warn
 func (w *Wrapper) Warn(v ...interface{}) error {
-	v = w.Format.Filter(v...)
+	message := w.render(v...)
+	if !w.limit(warnLevel, message) {
+		// the callers may return the error of a suppressed message, like that of a logged one
+		return errors.New(message)
+	}
+	w.capture(warnLevel, message)
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	return w.Delegate.BaseLoggerInstance.Warn(v...)
+	return w.Delegate.BaseLoggerInstance.Warn(message)
 }
 
 // Error formats message using the default formats for its operands
 // and writes to log with level = Error
 func (w *Wrapper) Error(v ...interface{}) error {
-	v = w.Format.Filter(v...)
+	message := w.render(v...)
+	if !w.limit(errorLevel, message) {
+		// the callers may return the error of a suppressed message, like that of a logged one
+		return errors.New(message)
+	}
+	w.capture(errorLevel, message)
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	return w.Delegate.BaseLoggerInstance.Error(v...)
+	return w.Delegate.BaseLoggerInstance.Error(message)
 }
 
 // Critical formats message using the default formats for its operands
 // and writes to log with level = Critical
 func (w *Wrapper) Critical(v ...interface{}) error {
-	v = w.Format.Filter(v...)
+	message := w.render(v...)
+	if !w.limit(criticalLevel, message) {
+		// the callers may return the error of a suppressed message, like that of a logged one
+		return errors.New(message)
+	}
+	w.capture(criticalLevel, message)
 
 	w.M.Lock()
 	defer w.M.Unlock()
-	return w.Delegate.BaseLoggerInstance.Critical(v...)
+	return w.Delegate.BaseLoggerInstance.Critical(message)
 }
 
-// Flush flushes all the messages in the logger.
+// Flush flushes all the messages in the logger, along with the summaries of the suppressed repetitions.
 func (w *Wrapper) Flush() {
+	w.writeSummaries(repeats.flush(time.Now()))
 	w.M.Lock()
 	defer w.M.Unlock()
 	w.Delegate.BaseLoggerInstance.Flush()
@@ -123,6 +123,8 @@ func EnsureInitialization(context context.T) {
 		clock := times.DefaultClock
 		startPluginPool := task.NewPool(log, NumberOfLongRunningPluginWorkers, cancelWaitDuration, clock)
 		stopPluginPool := task.NewPool(log, NumberOfCancelWorkers, cancelWaitDuration, clock)
+		task.RegisterPool(Name+"Start", startPluginPool)
+		task.RegisterPool(Name+"Stop", stopPluginPool)
 
 		fileSysUtil := &longrunning.FileSysUtilImpl{}
 
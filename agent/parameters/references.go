@@ -0,0 +1,153 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parameters
+
+import (
+	"regexp"
+)
+
+// stepReferenceRegex matches the references to the outputs of the previous steps of a document,
+// {{ steps.<step>.<output> }}; the parameters are replaced when the document is parsed, the references when the
+// step referring to them runs
+var stepReferenceRegex = regexp.MustCompile(`{{\s*steps\.([^{}\s]+)\s*}}`)
+
+// ReplaceStepReferences traverses the input like ReplaceParameters and replaces the references
+// {{ steps.<reference> }} with the values resolve returns for them. A string that is only a reference is replaced
+// with the value, which need not be a string; in longer strings the values are marshaled like the parameters.
+func ReplaceStepReferences(input interface{}, resolve func(reference string) (interface{}, error)) (output interface{}, err error) {
+	output = mapStrings(input, func(s string) interface{} {
+		if err != nil {
+			return s
+		}
+		if match := stepReferenceRegex.FindStringSubmatch(s); match != nil && match[0] == s {
+			var value interface{}
+			value, err = resolve(match[1])
+			return value
+		}
+		return stepReferenceRegex.ReplaceAllStringFunc(s, func(reference string) string {
+			if err != nil {
+				return reference
+			}
+			var value interface{}
+			if value, err = resolve(stepReferenceRegex.FindStringSubmatch(reference)[1]); err != nil {
+				return reference
+			}
+			var valueString string
+			valueString, err = convertToString(value)
+			return valueString
+		})
+	})
+	return output, err
+}
+
+// loopVariableRegex matches the variables of the iterations of a step repeated with a loop, {{ loop.item }} and
+// {{ loop.index }}, replaced when each iteration runs
+var loopVariableRegex = regexp.MustCompile(`{{\s*loop\.(item|index)\s*}}`)
+
+// ReplaceLoopVariables traverses the input like ReplaceParameters and replaces {{ loop.item }} and {{ loop.index }}
+// with the item and the index of the iteration, like ReplaceStepReferences.
+func ReplaceLoopVariables(input interface{}, item interface{}, index int) (output interface{}, err error) {
+	variables := map[string]interface{}{"item": item, "index": index}
+	output = mapStrings(input, func(s string) interface{} {
+		if match := loopVariableRegex.FindStringSubmatch(s); match != nil && match[0] == s {
+			return variables[match[1]]
+		}
+		return loopVariableRegex.ReplaceAllStringFunc(s, func(variable string) string {
+			value, convertErr := convertToString(variables[loopVariableRegex.FindStringSubmatch(variable)[1]])
+			if convertErr != nil {
+				err = convertErr
+			}
+			return value
+		})
+	})
+	return output, err
+}
+
+// StepReferences returns the references {{ steps.<reference> }} in the strings of the input.
+func StepReferences(input interface{}) (references []string) {
+	mapStrings(input, func(s string) interface{} {
+		for _, match := range stepReferenceRegex.FindAllStringSubmatch(s, -1) {
+			references = append(references, match[1])
+		}
+		return s
+	})
+	return references
+}
+
+// secureParameterReferenceRegex matches the references to SecureString parameters, {{ ssm-secure:<name> }}, which
+// the parsing of the document leaves for the agent to replace with the decrypted values when the step runs, so that
+// the values aren't saved with the document
+var secureParameterReferenceRegex = regexp.MustCompile(`{{\s*(ssm-secure:[/\w.:-]+)\s*}}`)
+
+// SecureParameterReferences returns the references ssm-secure:<name> of {{ ssm-secure:<name> }} in the strings of
+// the input.
+func SecureParameterReferences(input interface{}) (references []string) {
+	mapStrings(input, func(s string) interface{} {
+		for _, match := range secureParameterReferenceRegex.FindAllStringSubmatch(s, -1) {
+			references = append(references, match[1])
+		}
+		return s
+	})
+	return references
+}
+
+// ReplaceSecureParameterReferences traverses the input like ReplaceParameters and replaces the references
+// {{ ssm-secure:<name> }} with their values, by reference ssm-secure:<name>.
+func ReplaceSecureParameterReferences(input interface{}, values map[string]string) interface{} {
+	return mapStrings(input, func(s string) interface{} {
+		return secureParameterReferenceRegex.ReplaceAllStringFunc(s, func(reference string) string {
+			if value, found := values[secureParameterReferenceRegex.FindStringSubmatch(reference)[1]]; found {
+				return value
+			}
+			return reference
+		})
+	})
+}
+
+// mapStrings returns a copy of the input, made of the composite types of json.Unmarshal and yaml.Unmarshal,
+// with its strings replaced by replace
+func mapStrings(input interface{}, replace func(string) interface{}) interface{} {
+	switch input := input.(type) {
+	case string:
+		return replace(input)
+	case []interface{}:
+		out := make([]interface{}, len(input))
+		for i, v := range input {
+			out[i] = mapStrings(v, replace)
+		}
+		return out
+	case []map[string]interface{}:
+		out := make([]map[string]interface{}, len(input))
+		for i, v := range input {
+			out[i] = mapStrings(v, replace).(map[string]interface{})
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for k, v := range input {
+			out[k] = mapStrings(v, replace)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{})
+		for k, v := range input {
+			if k, ok := k.(string); ok {
+				out[k] = mapStrings(v, replace)
+			}
+		}
+		return out
+	default:
+		return input
+	}
+}
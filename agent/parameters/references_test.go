@@ -0,0 +1,81 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parameters
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceStepReferences(t *testing.T) {
+	values := map[string]interface{}{"install.exitCode": 3, "install.stdout": "v1.2", "query.json.ids": []interface{}{"a", "b"}}
+	resolve := func(reference string) (interface{}, error) {
+		if value, ok := values[reference]; ok {
+			return value, nil
+		}
+		return nil, errors.New("unknown reference " + reference)
+	}
+	input := map[string]interface{}{
+		"runCommand": []interface{}{"echo {{ steps.install.stdout }} exited with {{steps.install.exitCode}}", "{{ parameter }}"},
+		"ids":        "{{ steps.query.json.ids }}",
+		"timeout":    60,
+	}
+
+	output, err := ReplaceStepReferences(input, resolve)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"runCommand": []interface{}{"echo v1.2 exited with 3", "{{ parameter }}"},
+		"ids":        []interface{}{"a", "b"},
+		"timeout":    60,
+	}, output)
+	references := StepReferences(input)
+	sort.Strings(references)
+	assert.Equal(t, []string{"install.exitCode", "install.stdout", "query.json.ids"}, references)
+
+	_, err = ReplaceStepReferences([]interface{}{"{{ steps.missing.stdout }}"}, resolve)
+	assert.Error(t, err)
+}
+
+func TestReplaceLoopVariables(t *testing.T) {
+	input := map[string]interface{}{
+		"runCommand": []interface{}{"yum install -y {{ loop.item }} # {{loop.index}}", "{{ steps.install.stdout }}"},
+		"package":    "{{ loop.item }}",
+	}
+
+	output, err := ReplaceLoopVariables(input, map[string]interface{}{"name": "git"}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"runCommand": []interface{}{`yum install -y {"name":"git"} # 2`, "{{ steps.install.stdout }}"},
+		"package":    map[string]interface{}{"name": "git"},
+	}, output)
+}
+
+func TestReplaceSecureParameterReferences(t *testing.T) {
+	input := map[string]interface{}{
+		"runCommand": []interface{}{"mysql -p'{{ ssm-secure:/db/password }}' -e '{{ssm-secure:query:2}}'", "{{ ssm:/db/host }}"},
+		"timeout":    60,
+	}
+
+	references := SecureParameterReferences(input)
+	sort.Strings(references)
+	assert.Equal(t, []string{"ssm-secure:/db/password", "ssm-secure:query:2"}, references)
+	output := ReplaceSecureParameterReferences(input, map[string]string{"ssm-secure:/db/password": "hunter2"})
+	assert.Equal(t, map[string]interface{}{
+		"runCommand": []interface{}{"mysql -p'hunter2' -e '{{ssm-secure:query:2}}'", "{{ ssm:/db/host }}"},
+		"timeout":    60,
+	}, output)
+}
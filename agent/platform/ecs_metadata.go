@@ -0,0 +1,115 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// ECSContainerMetadataURIV4 is the environment variable ECS sets to the metadata endpoint of the container
+	ECSContainerMetadataURIV4 = "ECS_CONTAINER_METADATA_URI_V4"
+	// ECSContainerMetadataURI is the version 3 of the metadata endpoint
+	ECSContainerMetadataURI = "ECS_CONTAINER_METADATA_URI"
+	// ECSMetadataRequestTimeout specifies the timeout of the requests to the metadata endpoint
+	ECSMetadataRequestTimeout = time.Duration(2 * time.Second)
+)
+
+// ECSTaskMetadata stores the values fetched from the task metadata endpoint
+type ECSTaskMetadata struct {
+	Cluster string `json:"Cluster"`
+	TaskARN string `json:"TaskARN"`
+}
+
+// ECSContainerMetadata stores the values fetched from the container metadata endpoint
+type ECSContainerMetadata struct {
+	DockerID string `json:"DockerId"`
+}
+
+// ECSMetadataClient is used to make requests to the metadata endpoint of the ECS container
+type ECSMetadataClient struct {
+	client httpClient
+}
+
+// NewECSMetadataClient creates new ECSMetadataClient
+func NewECSMetadataClient() *ECSMetadataClient {
+	return &ECSMetadataClient{client: &http.Client{Timeout: ECSMetadataRequestTimeout}}
+}
+
+// endpoint returns the metadata endpoint of the container, empty outside of ECS
+func (c ECSMetadataClient) endpoint() string {
+	if uri := os.Getenv(ECSContainerMetadataURIV4); uri != "" {
+		return uri
+	}
+	return os.Getenv(ECSContainerMetadataURI)
+}
+
+// read decodes the resource of the metadata endpoint
+func (c ECSMetadataClient) read(path string, v interface{}) error {
+	resp, err := c.client.Get(c.endpoint() + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ECS metadata request failed. status:%v", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// InstanceID returns the instance ID of the container, ecs:<cluster>_<task ID>_<container runtime ID>,
+// empty outside of ECS
+func (c ECSMetadataClient) InstanceID() (string, error) {
+	if c.endpoint() == "" {
+		return "", nil
+	}
+	var task ECSTaskMetadata
+	if err := c.read("/task", &task); err != nil {
+		return "", err
+	}
+	var container ECSContainerMetadata
+	if err := c.read("", &container); err != nil {
+		return "", err
+	}
+	if task.Cluster == "" || task.TaskARN == "" || container.DockerID == "" {
+		return "", fmt.Errorf("the ECS metadata is incomplete")
+	}
+	return fmt.Sprintf("ecs:%v_%v_%v", lastSegment(task.Cluster), lastSegment(task.TaskARN), container.DockerID), nil
+}
+
+// Region returns the region of the task, from its ARN, empty outside of ECS
+func (c ECSMetadataClient) Region() (string, error) {
+	if c.endpoint() == "" {
+		return "", nil
+	}
+	var task ECSTaskMetadata
+	if err := c.read("/task", &task); err != nil {
+		return "", err
+	}
+	// arn:aws:ecs:<region>:<account>:task/<cluster>/<task ID>
+	if parts := strings.Split(task.TaskARN, ":"); len(parts) > 3 && parts[3] != "" {
+		return parts[3], nil
+	}
+	return "", fmt.Errorf("unexpected task ARN %q", task.TaskARN)
+}
+
+// lastSegment returns the name at the end of an ARN, e.g. the cluster of arn:aws:ecs:us-east-1:1:cluster/default
+func lastSegment(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
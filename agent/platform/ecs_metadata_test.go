@@ -0,0 +1,58 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package platform
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECSMetadataClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/container":
+			fmt.Fprint(w, `{"DockerId": "cd189a933e5849daa93386466019ab50-2495160603"}`)
+		case "/v4/container/task":
+			fmt.Fprint(w, `{"Cluster": "arn:aws:ecs:us-west-2:111122223333:cluster/default",
+				"TaskARN": "arn:aws:ecs:us-west-2:111122223333:task/default/cd189a933e5849daa93386466019ab50"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer os.Unsetenv(ECSContainerMetadataURIV4)
+	client := NewECSMetadataClient()
+
+	os.Unsetenv(ECSContainerMetadataURIV4)
+	instanceID, err := client.InstanceID()
+	assert.NoError(t, err)
+	assert.Empty(t, instanceID, "outside of ECS")
+
+	os.Setenv(ECSContainerMetadataURIV4, server.URL+"/v4/container")
+	instanceID, err = client.InstanceID()
+	assert.NoError(t, err)
+	assert.Equal(t, "ecs:default_cd189a933e5849daa93386466019ab50_cd189a933e5849daa93386466019ab50-2495160603", instanceID)
+	region, err := client.Region()
+	assert.NoError(t, err)
+	assert.Equal(t, "us-west-2", region)
+
+	os.Setenv(ECSContainerMetadataURIV4, server.URL+"/v3")
+	_, err = client.InstanceID()
+	assert.Error(t, err)
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package platform
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// IdentitySourceOverride is the source of an instance ID set with SetInstanceID
+const IdentitySourceOverride = "override"
+
+// identitySource provides an instance ID and a region, both empty when it doesn't apply to the machine
+type identitySource interface {
+	InstanceID() (string, error)
+	Region() (string, error)
+}
+
+// identitySources are the sources of Identity.ConsumptionOrder
+var identitySources = map[string]identitySource{
+	appconfig.IdentitySourceStatic: staticIdentity{},
+	appconfig.IdentitySourceOnPrem: onPremIdentity{},
+	appconfig.IdentitySourceEC2:    ec2Identity{},
+	appconfig.IdentitySourceECS:    ecsIdentity{},
+}
+
+// IdentitySelection tells where the instance ID and the region of the agent come from
+type IdentitySelection struct {
+	// Source is the first source of Identity.ConsumptionOrder providing an instance ID
+	Source string
+	// Skipped are the sources tried before it, with why they were skipped
+	Skipped []string
+}
+
+// String describes the selection for the logs
+func (s IdentitySelection) String() string {
+	if len(s.Skipped) == 0 {
+		return s.Source
+	}
+	return fmt.Sprintf("%v, skipped %v", s.Source, strings.Join(s.Skipped, "; "))
+}
+
+var cachedSelection *IdentitySelection
+var selectionLock sync.Mutex
+
+// IdentitySource returns the source the instance ID and the region come from
+func IdentitySource() (IdentitySelection, error) {
+	selectionLock.Lock()
+	selection := cachedSelection
+	selectionLock.Unlock()
+	if selection != nil {
+		return *selection, nil
+	}
+	if _, err := InstanceID(); err != nil {
+		return IdentitySelection{}, err
+	}
+	selectionLock.Lock()
+	defer selectionLock.Unlock()
+	if cachedSelection == nil {
+		return IdentitySelection{}, fmt.Errorf("no identity source provided the instance ID")
+	}
+	return *cachedSelection, nil
+}
+
+// setIdentitySource records the source of the instance ID
+func setIdentitySource(selection IdentitySelection) {
+	selectionLock.Lock()
+	defer selectionLock.Unlock()
+	cachedSelection = &selection
+}
+
+// selectIdentity returns the first source of Identity.ConsumptionOrder providing an instance ID,
+// and the error of the last source failing if none does
+func selectIdentity() (selection IdentitySelection, source identitySource, instanceID string, err error) {
+	for _, name := range strings.Split(identityConfig().ConsumptionOrder, ",") {
+		source, found := identitySources[name]
+		if !found {
+			continue
+		}
+		id, sourceErr := source.InstanceID()
+		if sourceErr == nil && id != "" {
+			selection.Source = name
+			return selection, source, id, nil
+		}
+		if sourceErr != nil {
+			err = sourceErr
+			selection.Skipped = append(selection.Skipped, fmt.Sprintf("%v: %v", name, sourceErr))
+		} else {
+			selection.Skipped = append(selection.Skipped, fmt.Sprintf("%v: no instance ID", name))
+		}
+	}
+	return selection, nil, "", err
+}
+
+// staticIdentity is the identity set in the configuration
+type staticIdentity struct{}
+
+func (staticIdentity) InstanceID() (string, error) { return identityConfig().InstanceID, nil }
+
+func (staticIdentity) Region() (string, error) { return identityConfig().Region, nil }
+
+// onPremIdentity is the registration of the managed instance
+type onPremIdentity struct{}
+
+func (onPremIdentity) InstanceID() (string, error) { return managedInstance.InstanceID(), nil }
+
+func (onPremIdentity) Region() (string, error) { return managedInstance.Region(), nil }
+
+// ec2Identity is the EC2 instance metadata, and the dynamic data for the region
+type ec2Identity struct{}
+
+func (ec2Identity) InstanceID() (string, error) { return metadata.GetMetadata("instance-id") }
+
+func (ec2Identity) Region() (region string, err error) {
+	if region, err = metadata.Region(); region != "" && err == nil {
+		return
+	}
+	return dynamicData.Region()
+}
+
+// ecsIdentity is the metadata of the ECS task
+type ecsIdentity struct{}
+
+func (ecsIdentity) InstanceID() (string, error) { return ecsMetadata.InstanceID() }
+
+func (ecsIdentity) Region() (string, error) { return ecsMetadata.Region() }
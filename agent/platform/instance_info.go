@@ -46,6 +46,7 @@ func SetInstanceID(instanceID string) error {
 		return fmt.Errorf("invalid instanceID")
 	}
 	cachedInstanceID = instanceID
+	setIdentitySource(IdentitySelection{Source: IdentitySourceOverride})
 	return nil
 }
 
@@ -135,20 +136,25 @@ func IsManagedInstance() (bool, error) {
 	return false, nil
 }
 
-// fetchInstanceID fetches the instance id with the following preference order.
-// 1. managed instance registration
-// 2. EC2 Instance Metadata
-func fetchInstanceID() (string, error) {
-	var err error
-	var instanceID string
-
-	// trying to get instance id from managed instance registration
-	if instanceID = managedInstance.InstanceID(); instanceID != "" {
-		return instanceID, nil
+// InstanceTag returns the value of a tag of the EC2 instance from the instance metadata,
+// which only has the tags when the instance allows it (InstanceMetadataTags enabled)
+func InstanceTag(key string) (string, error) {
+	if managed, err := IsManagedInstance(); err != nil || managed {
+		return "", fmt.Errorf("the tags of the instance aren't available: not an EC2 instance")
 	}
+	return metadata.GetMetadata("tags/instance/" + key)
+}
 
-	// trying to get instance id from ec2 metadata
-	if instanceID, err = metadata.GetMetadata("instance-id"); instanceID != "" && err == nil {
+// fetchInstanceID fetches the instance id from the first source of Identity.ConsumptionOrder providing one,
+// by default:
+// 1. static configuration
+// 2. managed instance registration
+// 3. EC2 Instance Metadata
+// 4. ECS Task Metadata
+func fetchInstanceID() (string, error) {
+	selection, _, instanceID, err := selectIdentity()
+	if instanceID != "" {
+		setIdentitySource(selection)
 		return instanceID, nil
 	}
 
@@ -172,27 +178,32 @@ func fetchInstanceType() (string, error) {
 	return "", fmt.Errorf(errorMessage, "instance Type", err)
 }
 
-// fetchRegion fetches the region with the following preference order.
-// 1. managed instance registration
-// 2. EC2 Instance Metadata
-// 3. EC2 Instance Dynamic Data
+// fetchRegion fetches the region from the source providing the instance id, so both describe the same
+// machine. When no source provides an instance id, the region comes from the first source of
+// Identity.ConsumptionOrder providing one; the EC2 source falls back to the dynamic data.
 func fetchRegion() (string, error) {
 	var err error
 	var region string
 
-	// trying to get region from managed instance registration
-	if region = managedInstance.Region(); region != "" {
-		return region, nil
-	}
-
-	// trying to get region from metadata
-	if region, err = metadata.Region(); region != "" && err == nil {
-		return region, nil
-	}
-
-	// trying to get region from dynamic data
-	if region, err = dynamicData.Region(); region != "" && err == nil {
-		return region, nil
+	if _, source, _, _ := selectIdentity(); source != nil {
+		if region, err = source.Region(); region != "" && err == nil {
+			return region, nil
+		}
+		return "", fmt.Errorf(errorMessage, "region", err)
+	}
+
+	for _, name := range strings.Split(identityConfig().ConsumptionOrder, ",") {
+		source, found := identitySources[name]
+		if !found {
+			continue
+		}
+		var sourceErr error
+		if region, sourceErr = source.Region(); region != "" && sourceErr == nil {
+			return region, nil
+		}
+		if sourceErr != nil {
+			err = sourceErr
+		}
 	}
 
 	// return combined error messages
@@ -15,12 +15,22 @@
 package platform
 
 import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
+// dependency for the Identity section of the configuration
+var identityConfig = func() appconfig.IdentityCfg {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		config = appconfig.DefaultConfig()
+	}
+	return config.Identity
+}
+
 // dependency for managed instance registration
 var managedInstance instanceRegistration = instanceInfo{}
 
@@ -83,3 +93,11 @@ func (d instanceDynamicData) Region() (string, error) {
 	}
 	return "", err
 }
+
+// dependency for the metadata of the ECS task
+var ecsMetadata ecsMetadataClient = NewECSMetadataClient()
+
+type ecsMetadataClient interface {
+	InstanceID() (string, error)
+	Region() (string, error)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -54,6 +55,28 @@ type dynamicDataStub struct {
 
 func (d dynamicDataStub) Region() (string, error) { return d.region, d.err }
 
+// ecs stub
+type ecsStub struct {
+	instanceID string
+	region     string
+	err        error
+}
+
+func (e ecsStub) InstanceID() (string, error) { return e.instanceID, e.err }
+
+func (e ecsStub) Region() (string, error) { return e.region, e.err }
+
+// identityConfigStub returns the identity configuration of the tests
+func identityConfigStub(config appconfig.IdentityCfg) func() appconfig.IdentityCfg {
+	return func() appconfig.IdentityCfg { return config }
+}
+
+func init() {
+	// the tests don't depend on the configuration and the environment of the machine
+	identityConfig = identityConfigStub(appconfig.IdentityCfg{ConsumptionOrder: appconfig.DefaultIdentityConsumptionOrder})
+	ecsMetadata = ecsStub{}
+}
+
 // Examples
 
 func ExampleInstanceID() {
@@ -183,3 +206,72 @@ func TestFetchRegion(t *testing.T) {
 		assert.Equal(t, test.expectedRegionError, actualError, "%s %s, %s", test.inputMetadata.message, test.inputRegistration.message, test.inputDynamicData.message)
 	}
 }
+
+func TestFetchIdentityInConsumptionOrder(t *testing.T) {
+	defer func() {
+		identityConfig = identityConfigStub(appconfig.IdentityCfg{ConsumptionOrder: appconfig.DefaultIdentityConsumptionOrder})
+	}()
+	identityConfig = identityConfigStub(appconfig.IdentityCfg{ConsumptionOrder: "ec2,onprem"})
+	metadata = validMetadata
+	managedInstance = validRegistration
+
+	instanceID, err := fetchInstanceID()
+	assert.NoError(t, err)
+	assert.Equal(t, sampleInstanceID, instanceID)
+	selection, _ := IdentitySource()
+	assert.Equal(t, IdentitySelection{Source: appconfig.IdentitySourceEC2}, selection)
+
+	region, err := fetchRegion()
+	assert.NoError(t, err)
+	assert.Equal(t, sampleInstanceRegion, region)
+}
+
+func TestFetchRegionFromTheSourceOfTheInstanceID(t *testing.T) {
+	metadata = validMetadata
+	managedInstance = registrationStub{instanceID: sampleManagedInstID}
+	dynamicData = validDynamicData
+
+	region, err := fetchRegion()
+	assert.Equal(t, "", region, "the region of another source is a different machine")
+	assert.Error(t, err)
+}
+
+func TestFetchStaticIdentity(t *testing.T) {
+	defer func() {
+		identityConfig = identityConfigStub(appconfig.IdentityCfg{ConsumptionOrder: appconfig.DefaultIdentityConsumptionOrder})
+	}()
+	identityConfig = identityConfigStub(appconfig.IdentityCfg{
+		ConsumptionOrder: appconfig.DefaultIdentityConsumptionOrder,
+		InstanceID:       "mi-0123456789abcdef0",
+		Region:           "eu-west-1",
+	})
+	metadata = validMetadata
+	managedInstance = validRegistration
+
+	instanceID, err := fetchInstanceID()
+	assert.NoError(t, err)
+	assert.Equal(t, "mi-0123456789abcdef0", instanceID)
+	region, err := fetchRegion()
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", region)
+}
+
+func TestSelectIdentityTellsTheSkippedSources(t *testing.T) {
+	defer func() { ecsMetadata = ecsStub{} }()
+	metadata = inValidMetadata
+	managedInstance = inValidRegistration
+	ecsMetadata = ecsStub{instanceID: "ecs:default_task_container", region: "ap-southeast-2"}
+
+	selection, _, instanceID, err := selectIdentity()
+	assert.NoError(t, err)
+	assert.Equal(t, "ecs:default_task_container", instanceID)
+	assert.Equal(t, IdentitySelection{
+		Source:  appconfig.IdentitySourceECS,
+		Skipped: []string{"static: no instance ID", "onprem: no instance ID", "ec2: " + sampleInstanceError},
+	}, selection)
+	assert.Equal(t, "ecs, skipped static: no instance ID; onprem: no instance ID; ec2: metadata error occurred", selection.String())
+
+	region, err := fetchRegion()
+	assert.NoError(t, err)
+	assert.Equal(t, "ap-southeast-2", region)
+}
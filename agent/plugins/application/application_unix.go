@@ -0,0 +1,178 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package application implements the application plugin.
+// On Linux, the applications are the packages of the package manager of the distribution.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+package application
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// defaultApplicationExecutionTimeoutInSeconds represents default timeout time for execution of applications in seconds
+	defaultApplicationExecutionTimeoutInSeconds = 3600
+
+	// defaultQueryTimeoutInSeconds is the timeout of the check of an installed package
+	defaultQueryTimeoutInSeconds = 60
+)
+
+// Plugin is the type for the applications plugin.
+type Plugin struct {
+	// ExecuteCommand is an object that can execute commands.
+	CommandExecuter executers.T
+}
+
+// ApplicationPluginInput represents one package installed, repaired or uninstalled by the Applications plugin.
+type ApplicationPluginInput struct {
+	contracts.PluginInput
+	ID     string
+	Action string
+	// Name is the name of the package
+	Name string
+	// Version pins the version of the package installed, the latest version of the repositories when empty
+	Version string
+	// RefreshRepositories refreshes the metadata of the repositories before the package is installed or repaired
+	RefreshRepositories bool
+	// Parameters are more arguments of the package manager
+	Parameters string
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	plugin.CommandExecuter = executers.ShellCommandExecuter{}
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameAwsApplications
+}
+
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else {
+		p.runCommandsRawInput(log, config.Properties, config.DefaultWorkingDirectory, cancelFlag, output)
+	}
+	return
+}
+
+// runCommandsRawInput runs the package manager for one package.
+// The input is in the default json unmarshal format (e.g. map[string]interface{}).
+func (p *Plugin) runCommandsRawInput(log log.T, rawPluginInput interface{}, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	var pluginInput ApplicationPluginInput
+	err := jsonutil.Remarshal(rawPluginInput, &pluginInput)
+	log.Debugf("Plugin input %v", pluginInput)
+	if err != nil {
+		errorString := fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", rawPluginInput, err)
+		output.MarkAsFailed(errorString)
+		return
+	}
+	p.runCommands(log, pluginInput, defaultWorkingDirectory, cancelFlag, output)
+}
+
+// runCommands runs the package manager for one package and sets the status of the output from its exit code.
+func (p *Plugin) runCommands(log log.T, pluginInput ApplicationPluginInput, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	if err := validateArgument("Name", pluginInput.Name, true); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	if err := validateArgument("Version", pluginInput.Version, false); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	manager, err := findPackageManager()
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	params := []string{}
+	if pluginInput.Parameters != "" {
+		log.Debugf("Got Parameters \"%v\"", pluginInput.Parameters)
+		params = processParams(log, pluginInput.Parameters)
+	}
+	commandArguments, err := manager.actionArguments(pluginInput.Action, pluginInput.Name, pluginInput.Version, params)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	if pluginInput.Action == UNINSTALL {
+		// Uninstall will skip, if the package is not currently installed.
+		// This is needed to support idempotent behavior.
+		exitCode, _ := p.CommandExecuter.NewExecute(log, defaultWorkingDirectory, ioutil.Discard, ioutil.Discard, cancelFlag, defaultQueryTimeoutInSeconds, manager.queryCommand, manager.queryArguments(pluginInput.Name))
+		if exitCode != appconfig.SuccessExitCode {
+			output.AppendInfof("%v is not installed", pluginInput.Name)
+			output.SetStatus(contracts.ResultStatusSuccess)
+			return
+		}
+	} else if pluginInput.RefreshRepositories {
+		exitCode, err := p.CommandExecuter.NewExecute(log, defaultWorkingDirectory, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, defaultApplicationExecutionTimeoutInSeconds, manager.command, manager.refresh)
+		if exitCode != appconfig.SuccessExitCode || err != nil {
+			output.SetExitCode(exitCode)
+			output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+			output.MarkAsFailed(fmt.Errorf("failed to refresh the repositories of %v: %v", manager.command, err))
+			return
+		}
+	}
+
+	// Execute Command
+	log.Debugf("running %v %v", manager.command, commandArguments)
+	exitCode, err := p.CommandExecuter.NewExecute(log, defaultWorkingDirectory, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, defaultApplicationExecutionTimeoutInSeconds, manager.command, commandArguments)
+
+	// Set output status
+	output.SetExitCode(exitCode)
+	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to run %v: %v", manager.command, err))
+		return
+	}
+}
+
+// validateArgument checks a name or a version given to the package manager can't be taken for one of its options
+func validateArgument(field string, value string, required bool) error {
+	if value == "" {
+		if required {
+			return fmt.Errorf("%v is required", field)
+		}
+		return nil
+	}
+	if strings.HasPrefix(value, "-") || strings.ContainsAny(value, " \t\r\n") {
+		return fmt.Errorf("%v %q is not a valid package %v", field, value, strings.ToLower(field))
+	}
+	return nil
+}
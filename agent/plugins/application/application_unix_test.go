@@ -0,0 +1,170 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubLookPath finds the commands given
+func stubLookPath(installed ...string) func(string) (string, error) {
+	return func(command string) (string, error) {
+		for _, name := range installed {
+			if name == command {
+				return "/usr/bin/" + command, nil
+			}
+		}
+		return "", errors.New("executable file not found in $PATH")
+	}
+}
+
+func TestFindPackageManager(t *testing.T) {
+	lookPathOrig := lookPath
+	defer func() { lookPath = lookPathOrig }()
+
+	for _, installed := range []string{"dnf", "yum", "zypper", "apk", "apt-get"} {
+		lookPath = stubLookPath(installed)
+		manager, err := findPackageManager()
+		assert.NoError(t, err)
+		assert.Equal(t, installed, manager.command)
+	}
+
+	// modern RHEL and Fedora have both
+	lookPath = stubLookPath("yum", "dnf")
+	manager, _ := findPackageManager()
+	assert.Equal(t, "dnf", manager.command)
+
+	lookPath = stubLookPath()
+	_, err := findPackageManager()
+	assert.Error(t, err)
+}
+
+func TestActionArguments(t *testing.T) {
+	lookPathOrig := lookPath
+	defer func() { lookPath = lookPathOrig }()
+
+	testCases := []struct {
+		manager  string
+		action   string
+		version  string
+		expected []string
+	}{
+		{"dnf", INSTALL, "1.20.1", []string{"install", "-y", "nginx-1.20.1"}},
+		{"dnf", REPAIR, "1.20.1", []string{"reinstall", "-y", "nginx"}},
+		{"zypper", INSTALL, "1.20.1", []string{"--non-interactive", "install", "--oldpackage", "nginx=1.20.1"}},
+		{"zypper", UNINSTALL, "", []string{"--non-interactive", "remove", "nginx"}},
+		{"apk", INSTALL, "", []string{"add", "nginx"}},
+		{"apk", INSTALL, "1.20.1-r0", []string{"add", "nginx=1.20.1-r0"}},
+		{"apk", REPAIR, "", []string{"fix", "nginx"}},
+	}
+	for _, testCase := range testCases {
+		lookPath = stubLookPath(testCase.manager)
+		manager, _ := findPackageManager()
+		args, err := manager.actionArguments(testCase.action, "nginx", testCase.version, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, args)
+	}
+
+	args, _ := manager("apt-get").actionArguments(INSTALL, "nginx", "", []string{"--no-install-recommends"})
+	assert.Equal(t, []string{"install", "-y", "-q", "--allow-downgrades", "--no-install-recommends", "nginx"}, args)
+	_, err := manager("apk").actionArguments("Upgrade", "nginx", "", nil)
+	assert.Error(t, err)
+}
+
+// manager returns the package manager of a command
+func manager(command string) packageManager {
+	for _, manager := range packageManagers {
+		if manager.command == command {
+			return manager
+		}
+	}
+	return packageManager{}
+}
+
+// runCommands runs the plugin with a package manager
+func runCommands(installed string, input ApplicationPluginInput, mockExecuter *executers.MockCommandExecuter) iohandler.IOHandler {
+	lookPathOrig := lookPath
+	defer func() { lookPath = lookPathOrig }()
+	lookPath = stubLookPath(installed)
+
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	p := &Plugin{CommandExecuter: mockExecuter}
+	p.runCommands(log.NewMockLog(), input, "", task.NewChanneledCancelFlag(), output)
+	return output
+}
+
+func TestRunCommandsRefreshesRepositories(t *testing.T) {
+	mockExecuter := &executers.MockCommandExecuter{}
+	mockExecuter.On("NewExecute", mock.Anything, "", mock.Anything, mock.Anything, mock.Anything, defaultApplicationExecutionTimeoutInSeconds, "apk", []string{"update"}).Return(0, nil).Once()
+	mockExecuter.On("NewExecute", mock.Anything, "", mock.Anything, mock.Anything, mock.Anything, defaultApplicationExecutionTimeoutInSeconds, "apk", []string{"add", "nginx=1.20.1-r0"}).Return(0, nil).Once()
+
+	output := runCommands("apk", ApplicationPluginInput{Action: INSTALL, Name: "nginx", Version: "1.20.1-r0", RefreshRepositories: true}, mockExecuter)
+	mockExecuter.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+}
+
+func TestRunCommandsFailedRefresh(t *testing.T) {
+	mockExecuter := &executers.MockCommandExecuter{}
+	mockExecuter.On("NewExecute", mock.Anything, "", mock.Anything, mock.Anything, mock.Anything, defaultApplicationExecutionTimeoutInSeconds, "zypper", []string{"--non-interactive", "refresh"}).Return(4, errors.New("exit status 4")).Once()
+
+	output := runCommands("zypper", ApplicationPluginInput{Action: REPAIR, Name: "nginx", RefreshRepositories: true}, mockExecuter)
+	mockExecuter.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, 4, output.GetExitCode())
+}
+
+func TestRunCommandsUninstall(t *testing.T) {
+	mockExecuter := &executers.MockCommandExecuter{}
+	mockExecuter.On("NewExecute", mock.Anything, "", mock.Anything, mock.Anything, mock.Anything, defaultQueryTimeoutInSeconds, "rpm", []string{"-q", "nginx"}).Return(0, nil).Once()
+	mockExecuter.On("NewExecute", mock.Anything, "", mock.Anything, mock.Anything, mock.Anything, defaultApplicationExecutionTimeoutInSeconds, "dnf", []string{"remove", "-y", "nginx"}).Return(0, nil).Once()
+
+	output := runCommands("dnf", ApplicationPluginInput{Action: UNINSTALL, Name: "nginx"}, mockExecuter)
+	mockExecuter.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+}
+
+func TestRunCommandsUninstallNotInstalled(t *testing.T) {
+	mockExecuter := &executers.MockCommandExecuter{}
+	mockExecuter.On("NewExecute", mock.Anything, "", mock.Anything, mock.Anything, mock.Anything, defaultQueryTimeoutInSeconds, "apk", []string{"info", "-e", "nginx"}).Return(1, errors.New("exit status 1")).Once()
+
+	output := runCommands("apk", ApplicationPluginInput{Action: UNINSTALL, Name: "nginx"}, mockExecuter)
+	mockExecuter.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Contains(t, output.GetStdout(), "nginx is not installed")
+}
+
+func TestRunCommandsInvalidInput(t *testing.T) {
+	for _, input := range []ApplicationPluginInput{
+		{Action: INSTALL},
+		{Action: INSTALL, Name: "--from=http://example.com/nginx.rpm"},
+		{Action: INSTALL, Name: "nginx", Version: "1.20 --nogpgcheck"},
+		{Action: "Upgrade", Name: "nginx"},
+	} {
+		mockExecuter := &executers.MockCommandExecuter{}
+		output := runCommands("dnf", input, mockExecuter)
+		mockExecuter.AssertNotCalled(t, "NewExecute")
+		assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package application
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// The actions of the plugin, on every platform
+const (
+	INSTALL = "Install"
+
+	UNINSTALL = "Uninstall"
+
+	REPAIR = "Repair"
+)
+
+// processParams smartly divides the input parameter string into valid string blocks
+func processParams(log log.T, str string) []string {
+
+	// Sample transformation:
+	// str = "/v value "some path" myproperty=value"
+	// result: []string{"/v", "value", "some path", "myproperty=value"}
+
+	// contains the last split location of the string
+	lastbit := 0
+
+	params := []string{}
+
+	// true if first quote was encountered else false
+	quoteInit := false
+
+	// Iterate through each character in str
+	for i, c := range str {
+
+		// Look for quotes or spaces
+		// By default we split a string using space as a delimiter
+		// If a quote(") is encountered then wait for the next quote irrespective of any spaces in between
+		if c == '"' {
+			if quoteInit {
+				quoteInit = false
+				params = append(params, str[lastbit:i+1])
+				lastbit = i + 1
+			} else {
+				quoteInit = true
+				lastbit = i
+			}
+		} else if c == ' ' && !quoteInit {
+			if lastbit != i {
+				params = append(params, str[lastbit:i])
+			}
+			lastbit = i + 1
+		}
+	}
+
+	// This handles the last word in str
+	if lastbit < len(str) {
+		params = append(params, str[lastbit:])
+	}
+
+	log.Debug("Parameters after processing...")
+	for _, param := range params {
+		log.Debug(param)
+	}
+
+	return params
+}
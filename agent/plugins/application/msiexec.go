@@ -27,14 +27,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
-const (
-	INSTALL = "Install"
-
-	UNINSTALL = "Uninstall"
-
-	REPAIR = "Repair"
-)
-
 const (
 	ErrorUnknownProduct = 1605
 
@@ -105,57 +97,6 @@ func setMsiExecStatus(log log.T, pluginInput ApplicationPluginInput, cancelFlag
 	log.Debugf("stderr: %v", out.GetStderr())
 }
 
-// processParams smartly divides the input parameter string into valid string blocks
-func processParams(log log.T, str string) []string {
-
-	// Sample transformation:
-	// str = "/v value "some path" myproperty=value"
-	// result: []string{"/v", "value", "some path", "myproperty=value"}
-
-	// contains the last split location of the string
-	lastbit := 0
-
-	params := []string{}
-
-	// true if first quote was encountered else false
-	quoteInit := false
-
-	// Iterate through each character in str
-	for i, c := range str {
-
-		// Look for quotes or spaces
-		// By default we split a string using space as a delimiter
-		// If a quote(") is encountered then wait for the next quote irrespective of any spaces in between
-		if c == '"' {
-			if quoteInit {
-				quoteInit = false
-				params = append(params, str[lastbit:i+1])
-				lastbit = i + 1
-			} else {
-				quoteInit = true
-				lastbit = i
-			}
-		} else if c == ' ' && !quoteInit {
-			if lastbit != i {
-				params = append(params, str[lastbit:i])
-			}
-			lastbit = i + 1
-		}
-	}
-
-	// This handles the last word in str
-	if lastbit < len(str) {
-		params = append(params, str[lastbit:])
-	}
-
-	log.Debug("Parameters after processing...")
-	for _, param := range params {
-		log.Debug(param)
-	}
-
-	return params
-}
-
 // Using https://msdn.microsoft.com/en-us/library/aa376931(v=vs.85).aspx as a reference, this returns
 // description of error codes regarding msi-exec.
 // getExitCodeDescription returns the description for errorCode
@@ -0,0 +1,139 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package application implements the application plugin.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+package application
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// lookPath looks up the package managers in the PATH of the agent
+var lookPath = exec.LookPath
+
+// packageManager installs, repairs and uninstalls the packages of a distribution
+type packageManager struct {
+	// command is the package manager
+	command string
+	// refresh are the arguments refreshing the metadata of the repositories
+	refresh []string
+	// install, repair and uninstall are the arguments of the actions, followed by the package
+	install   []string
+	repair    []string
+	uninstall []string
+	// queryCommand and query check a package is installed, with exit code 0
+	queryCommand string
+	query        []string
+	// versionSeparator joins the name and the version of a package pinned to a version
+	versionSeparator string
+}
+
+// packageManagers are the package managers, in the order they are looked up: dnf comes before yum, which modern
+// RHEL and Fedora keep as an alias of dnf.
+var packageManagers = []packageManager{
+	{
+		// dnf installs the version given even when a later one is installed
+		command:          "dnf",
+		refresh:          []string{"makecache", "--refresh"},
+		install:          []string{"install", "-y"},
+		repair:           []string{"reinstall", "-y"},
+		uninstall:        []string{"remove", "-y"},
+		queryCommand:     "rpm",
+		query:            []string{"-q"},
+		versionSeparator: "-",
+	},
+	{
+		// yum doesn't downgrade a package to the version given
+		command:          "yum",
+		refresh:          []string{"makecache"},
+		install:          []string{"install", "-y"},
+		repair:           []string{"reinstall", "-y"},
+		uninstall:        []string{"remove", "-y"},
+		queryCommand:     "rpm",
+		query:            []string{"-q"},
+		versionSeparator: "-",
+	},
+	{
+		// zypper installs the version given even when a later one is installed, with --oldpackage
+		command:          "zypper",
+		refresh:          []string{"--non-interactive", "refresh"},
+		install:          []string{"--non-interactive", "install", "--oldpackage"},
+		repair:           []string{"--non-interactive", "install", "--force"},
+		uninstall:        []string{"--non-interactive", "remove"},
+		queryCommand:     "rpm",
+		query:            []string{"-q"},
+		versionSeparator: "=",
+	},
+	{
+		// apk records the version given in /etc/apk/world, which keeps the package at it on later upgrades until
+		// it is installed again without version
+		command:          "apk",
+		refresh:          []string{"update"},
+		install:          []string{"add"},
+		repair:           []string{"fix"},
+		uninstall:        []string{"del"},
+		queryCommand:     "apk",
+		query:            []string{"info", "-e"},
+		versionSeparator: "=",
+	},
+	{
+		command:          "apt-get",
+		refresh:          []string{"update", "-q"},
+		install:          []string{"install", "-y", "-q", "--allow-downgrades"},
+		repair:           []string{"install", "-y", "-q", "--reinstall"},
+		uninstall:        []string{"remove", "-y", "-q"},
+		queryCommand:     "dpkg",
+		query:            []string{"-s"},
+		versionSeparator: "=",
+	},
+}
+
+// findPackageManager returns the first package manager found in the PATH of the agent
+func findPackageManager() (packageManager, error) {
+	for _, manager := range packageManagers {
+		if _, err := lookPath(manager.command); err == nil {
+			return manager, nil
+		}
+	}
+	return packageManager{}, fmt.Errorf("none of the supported package managers (dnf, yum, zypper, apk, apt-get) is installed")
+}
+
+// actionArguments returns the arguments of the package manager running an action on a package. The version pins
+// the version of the package installed, and is ignored by the other actions.
+func (m packageManager) actionArguments(action string, name string, version string, params []string) ([]string, error) {
+	var args []string
+	switch action {
+	case INSTALL:
+		args = m.install
+		if version != "" {
+			name = name + m.versionSeparator + version
+		}
+	case REPAIR:
+		args = m.repair
+	case UNINSTALL:
+		args = m.uninstall
+	default:
+		return nil, fmt.Errorf("Action is set to unsupported value: %v", action)
+	}
+	arguments := append(append(append([]string{}, args...), params...), name)
+	return arguments, nil
+}
+
+// queryArguments returns the arguments of the query command checking a package is installed
+func (m packageManager) queryArguments(name string) []string {
+	return append(append([]string{}, m.query...), name)
+}
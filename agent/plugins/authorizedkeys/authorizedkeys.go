@@ -0,0 +1,382 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package authorizedkeys implements the aws:manageAuthorizedKeys plugin, which adds and removes the SSH
+// public keys of a user, optionally until an expiry time after which the agent removes them.
+package authorizedkeys
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// ActionAdd adds the keys, replacing the entries of the same keys
+	ActionAdd = "Add"
+
+	// ActionRemove removes the keys, or all the keys added by the plugin if none is given
+	ActionRemove = "Remove"
+
+	// authorizedKeysAccess is the mode of the authorized_keys files
+	authorizedKeysAccess = os.FileMode(0600)
+
+	// sshDirAccess is the mode of the .ssh folders created by the plugin
+	sshDirAccess = os.FileMode(0700)
+
+	// lockFileName is the lock of the authorized_keys files and the state, taken by the document workers adding
+	// and removing keys and by the agent removing the expired keys
+	lockFileName = ".lock"
+
+	// lockTimeout bounds the wait for the other processes changing the keys
+	lockTimeout = 30 * time.Second
+)
+
+// stateDir is the folder the authorized_keys files with expiring keys are recorded in, so that the agent
+// can remove the keys once expired
+var stateDir = filepath.Join(appconfig.DefaultDataStorePath, "authorizedkeys")
+
+// lookupUser returns the user the keys are managed for
+var lookupUser = user.Lookup
+
+// now returns the current time, the expiry of the keys is relative to it
+var now = time.Now
+
+// Plugin is the type for the aws:manageAuthorizedKeys plugin.
+type Plugin struct {
+}
+
+// AuthorizedKeysPluginInput represents the keys to add or remove.
+type AuthorizedKeysPluginInput struct {
+	contracts.PluginInput
+	ID     string
+	Action string
+	User   string
+	// PublicKeys are the keys as in a .pub file, e.g. ssh-ed25519 AAAAC3Nza... alice@laptop
+	PublicKeys []string
+	// ExpiresAt is the RFC 3339 time the added keys expire at, ValidFor is the duration they are valid for, e.g. 4h.
+	// The keys don't expire if neither is set.
+	ExpiresAt string
+	ValidFor  string
+}
+
+// State records the authorized_keys files holding expiring keys.
+type State struct {
+	Files []string
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameManageAuthorizedKeys
+}
+
+// Execute adds or removes the keys of the user.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input AuthorizedKeysPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if input.User == "" {
+		output.MarkAsFailed(fmt.Errorf("User is required"))
+		return
+	}
+	keys := make([]keyLine, 0, len(input.PublicKeys))
+	for _, publicKey := range input.PublicKeys {
+		key, err := parsePublicKey(publicKey)
+		if err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		keys = append(keys, key)
+	}
+	u, err := lookupUser(input.User)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to find user %v: %v", input.User, err))
+		return
+	}
+	path := filepath.Join(u.HomeDir, ".ssh", "authorized_keys")
+	owner, err := ownerOfUser(u)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to get the ids of user %v: %v", u.Username, err))
+		return
+	}
+	lock, err := lockKeys()
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	defer lock.Unlock()
+
+	switch input.Action {
+	case ActionAdd:
+		if len(keys) == 0 {
+			output.MarkAsFailed(fmt.Errorf("PublicKeys is required to add keys"))
+			return
+		}
+		expiry, err := expiryOf(input)
+		if err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		if err = addKeys(log, owner, path, keys, expiry); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		if expiry.IsZero() {
+			output.AppendInfof("%v keys authorized for %v", len(keys), input.User)
+		} else {
+			output.AppendInfof("%v keys authorized for %v until %v", len(keys), input.User, expiry.UTC().Format(time.RFC3339))
+		}
+	case ActionRemove:
+		removed, err := removeKeys(log, owner, path, keys)
+		if err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		output.AppendInfof("%v keys removed for %v", removed, input.User)
+	default:
+		output.MarkAsFailed(fmt.Errorf("Action must be %v or %v, got %v", ActionAdd, ActionRemove, input.Action))
+		return
+	}
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// expiryOf returns the time the added keys expire at, zero if they don't expire.
+func expiryOf(input AuthorizedKeysPluginInput) (expiry time.Time, err error) {
+	switch {
+	case input.ExpiresAt != "" && input.ValidFor != "":
+		return expiry, fmt.Errorf("only one of ExpiresAt or ValidFor can be set")
+	case input.ExpiresAt != "":
+		if expiry, err = time.Parse(time.RFC3339, input.ExpiresAt); err != nil {
+			return expiry, fmt.Errorf("invalid ExpiresAt %v, expected an RFC 3339 time: %v", input.ExpiresAt, err)
+		}
+	case input.ValidFor != "":
+		validFor, err := time.ParseDuration(input.ValidFor)
+		if err != nil || validFor <= 0 {
+			return expiry, fmt.Errorf("invalid ValidFor %v, expected a positive duration, e.g. 4h", input.ValidFor)
+		}
+		expiry = now().Add(validFor)
+	default:
+		return expiry, nil
+	}
+	if !expiry.After(now()) {
+		return expiry, fmt.Errorf("the keys would expire in the past at %v", expiry.UTC().Format(time.RFC3339))
+	}
+	// the expiry-time option has a precision of a second
+	return expiry.Truncate(time.Second), nil
+}
+
+// addKeys adds the keys to the authorized_keys file of the user, replacing the entries of the same keys.
+func addKeys(log log.T, owner *fileOwner, path string, keys []keyLine, expiry time.Time) error {
+	lines, err := readLines(owner, path)
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %v", path, err)
+	}
+	lines, _ = withoutKeys(lines, func(entry keyLine) bool { return containsKey(keys, entry) })
+	for _, key := range keys {
+		key.Comment = strings.TrimSpace(key.Comment + " " + managedMarker)
+		if !expiry.IsZero() {
+			key.Options = expiryOptionOf(expiry)
+		}
+		lines = append(lines, key.String())
+	}
+	if err = writeLines(owner, path, lines); err != nil {
+		return fmt.Errorf("failed to write %v: %v", path, err)
+	}
+	log.Infof("added %v keys to %v", len(keys), path)
+	if !expiry.IsZero() {
+		return recordFile(path)
+	}
+	return nil
+}
+
+// removeKeys removes the keys from an authorized_keys file, or all the keys added by the plugin if none is given.
+func removeKeys(log log.T, owner *fileOwner, path string, keys []keyLine) (int, error) {
+	lines, err := readLines(owner, path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %v: %v", path, err)
+	}
+	lines, removed := withoutKeys(lines, func(entry keyLine) bool {
+		if len(keys) == 0 {
+			return entry.managed()
+		}
+		return containsKey(keys, entry)
+	})
+	if removed == 0 {
+		return 0, nil
+	}
+	if err = writeLines(owner, path, lines); err != nil {
+		return 0, fmt.Errorf("failed to write %v: %v", path, err)
+	}
+	log.Infof("removed %v keys from %v", removed, path)
+	return removed, nil
+}
+
+// RemoveExpiredKeys removes the expired keys added by the plugin from the recorded authorized_keys files.
+// The files left without expiring keys are no longer recorded.
+func RemoveExpiredKeys(log log.T) error {
+	lock, err := lockKeys()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	state, err := GetState()
+	if err != nil {
+		return err
+	}
+	at := now()
+	var files []string
+	for _, path := range state.Files {
+		owner, err := ownerOfKeysFile(path)
+		var lines []string
+		if err == nil {
+			lines, err = readLines(owner, path)
+		}
+		if err != nil {
+			log.Errorf("failed to read %v: %v", path, err)
+			files = append(files, path)
+			continue
+		}
+		remaining, removed := withoutKeys(lines, func(entry keyLine) bool { return entry.expired(at) })
+		if removed > 0 {
+			if err = writeLines(owner, path, remaining); err != nil {
+				log.Errorf("failed to remove the expired keys of %v: %v", path, err)
+				files = append(files, path)
+				continue
+			}
+			log.Infof("removed %v expired keys from %v", removed, path)
+		}
+		for _, line := range remaining {
+			if entry, ok := parseKeyLine(line); ok && entry.managed() {
+				if _, expires := entry.expiry(); expires {
+					files = append(files, path)
+					break
+				}
+			}
+		}
+	}
+	// the files are kept in order, the state is unchanged if none was dropped
+	if len(files) == len(state.Files) {
+		return nil
+	}
+	return saveState(State{Files: files})
+}
+
+// withoutKeys returns the lines without the entries matching, and the number of entries removed.
+func withoutKeys(lines []string, matches func(entry keyLine) bool) (kept []string, removed int) {
+	for _, line := range lines {
+		if entry, ok := parseKeyLine(line); ok && matches(entry) {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept, removed
+}
+
+// containsKey returns true if the entry is one of the keys, the options and comments are ignored.
+func containsKey(keys []keyLine, entry keyLine) bool {
+	for _, key := range keys {
+		if key.KeyType == entry.KeyType && key.Key == entry.Key {
+			return true
+		}
+	}
+	return false
+}
+
+// lockKeys takes the lock of the authorized_keys files and the state, the caller unlocks it.
+func lockKeys() (*filelock.Lock, error) {
+	if err := fileutil.MakeDirs(stateDir); err != nil {
+		return nil, err
+	}
+	lock, err := filelock.Acquire(filepath.Join(stateDir, lockFileName), lockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock the authorized keys: %v", err)
+	}
+	return lock, nil
+}
+
+// recordFile records an authorized_keys file holding expiring keys.
+func recordFile(path string) error {
+	state, err := GetState()
+	if err != nil {
+		return err
+	}
+	for _, file := range state.Files {
+		if file == path {
+			return nil
+		}
+	}
+	state.Files = append(state.Files, path)
+	sort.Strings(state.Files)
+	return saveState(state)
+}
+
+// GetState returns the authorized_keys files holding expiring keys.
+func GetState() (state State, err error) {
+	path := filepath.Join(stateDir, "state.json")
+	if !fileutil.Exists(path) {
+		return State{}, nil
+	}
+	if err = jsonutil.UnmarshalFile(path, &state); err != nil {
+		return State{}, fmt.Errorf("failed to read authorized keys state: %v", err)
+	}
+	return state, nil
+}
+
+// saveState persists the authorized_keys files holding expiring keys.
+func saveState(state State) error {
+	if err := fileutil.MakeDirs(stateDir); err != nil {
+		return err
+	}
+	content, err := jsonutil.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if _, err = fileutil.WriteIntoFileWithPermissions(filepath.Join(stateDir, "state.json"), content, appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("failed to save authorized keys state: %v", err)
+	}
+	return nil
+}
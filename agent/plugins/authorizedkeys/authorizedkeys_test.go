@@ -0,0 +1,159 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package authorizedkeys
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	aliceKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGl0J5Ckz0I9zq3UX4xQn6M1u1kM0yT8o7T5h9u3w4bA alice@laptop"
+	bobKey   = "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTY= bob"
+	adminKey = `from="10.0.0.0/8,192.168.0.1" ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ== admin key`
+)
+
+func TestParseKeyLine(t *testing.T) {
+	entry, ok := parseKeyLine(adminKey)
+	assert.True(t, ok)
+	assert.Equal(t, keyLine{Options: `from="10.0.0.0/8,192.168.0.1"`, KeyType: "ssh-rsa", Key: "AAAAB3NzaC1yc2EAAAADAQABAAABAQ==", Comment: "admin key"}, entry)
+	assert.Equal(t, adminKey, entry.String())
+	assert.False(t, entry.managed())
+
+	for _, line := range []string{"", "# comment", "not a key", "ssh-rsa"} {
+		_, ok = parseKeyLine(line)
+		assert.False(t, ok, line)
+	}
+
+	_, err := parsePublicKey(adminKey)
+	assert.Error(t, err, "options aren't allowed")
+	_, err = parsePublicKey("ssh-ed25519 not-base64")
+	assert.Error(t, err)
+}
+
+func TestExpiry(t *testing.T) {
+	entry, _ := parseKeyLine(`no-pty,expiry-time="20261016120000" ssh-ed25519 AAAA ssm-managed`)
+	expiry, ok := entry.expiry()
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local), expiry)
+	assert.False(t, entry.expired(expiry.Add(-time.Second)))
+	assert.True(t, entry.expired(expiry))
+
+	entry, _ = parseKeyLine(`expiry-time="20261016Z" ssh-ed25519 AAAA`)
+	expiry, ok = entry.expiry()
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), expiry)
+	assert.False(t, entry.expired(expiry.Add(time.Hour)), "keys not added by the plugin are left alone")
+
+	added := time.Date(2026, 10, 16, 12, 30, 15, 0, time.Local)
+	entry, _ = parseKeyLine(expiryOptionOf(added) + " ssh-ed25519 AAAA ssm-managed")
+	expiry, _ = entry.expiry()
+	assert.True(t, added.Equal(expiry))
+}
+
+func TestExecuteAddAndExpire(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authorizedkeys")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defaultStateDir := stateDir
+	stateDir = filepath.Join(dir, "state")
+	defer func() { stateDir = defaultStateDir }()
+	current, err := user.Current()
+	assert.NoError(t, err)
+	home := *current
+	home.HomeDir = filepath.Join(dir, "home")
+	lookupUser = func(string) (*user.User, error) { return &home, nil }
+	defer func() { lookupUser = user.Lookup }()
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+	path := filepath.Join(home.HomeDir, ".ssh", "authorized_keys")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), sshDirAccess))
+	assert.NoError(t, ioutil.WriteFile(path, []byte(adminKey+"\n"), authorizedKeysAccess))
+
+	execute := func(properties map[string]interface{}) iohandler.IOHandler {
+		output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+		plugin, _ := NewPlugin()
+		plugin.Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, task.NewChanneledCancelFlag(), output)
+		return output
+	}
+
+	output := execute(map[string]interface{}{"Action": "Add", "User": "alice", "PublicKeys": []string{aliceKey}, "ValidFor": "4h"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	output = execute(map[string]interface{}{"Action": "Add", "User": "alice", "PublicKeys": []string{bobKey}})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+
+	content, _ := ioutil.ReadFile(path)
+	assert.Equal(t, adminKey+"\n"+
+		`expiry-time="20261016160000" `+aliceKey+" ssm-managed\n"+
+		bobKey+" ssm-managed\n", string(content))
+	state, _ := GetState()
+	assert.Equal(t, []string{path}, state.Files)
+
+	// adding a key again replaces it
+	output = execute(map[string]interface{}{"Action": "Add", "User": "alice", "PublicKeys": []string{aliceKey}, "ValidFor": "8h"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	content, _ = ioutil.ReadFile(path)
+	assert.Equal(t, 1, strings.Count(string(content), "AAAAC3NzaC1lZDI1NTE5"))
+
+	now = func() time.Time { return start.Add(7 * time.Hour) }
+	assert.NoError(t, RemoveExpiredKeys(log.NewMockLog()))
+	content, _ = ioutil.ReadFile(path)
+	assert.True(t, strings.Contains(string(content), "alice@laptop"), "the key isn't expired yet")
+
+	now = func() time.Time { return start.Add(8 * time.Hour) }
+	assert.NoError(t, RemoveExpiredKeys(log.NewMockLog()))
+	content, _ = ioutil.ReadFile(path)
+	assert.Equal(t, adminKey+"\n"+bobKey+" ssm-managed\n", string(content))
+	state, _ = GetState()
+	assert.Empty(t, state.Files, "no expiring key is left")
+
+	// removing without keys removes the keys added by the plugin only
+	output = execute(map[string]interface{}{"Action": "Remove", "User": "alice"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	content, _ = ioutil.ReadFile(path)
+	assert.Equal(t, adminKey+"\n", string(content))
+	info, _ := os.Stat(path)
+	assert.Equal(t, authorizedKeysAccess, info.Mode().Perm())
+}
+
+func TestExecuteInvalidInput(t *testing.T) {
+	lookupUser = func(string) (*user.User, error) { return &user.User{HomeDir: os.TempDir()}, nil }
+	defer func() { lookupUser = user.Lookup }()
+
+	for _, properties := range []map[string]interface{}{
+		{"Action": "Add", "PublicKeys": []string{aliceKey}},
+		{"Action": "Add", "User": "alice"},
+		{"Action": "Add", "User": "alice", "PublicKeys": []string{aliceKey}, "ValidFor": "-1h"},
+		{"Action": "Add", "User": "alice", "PublicKeys": []string{aliceKey}, "ExpiresAt": "2000-01-01T00:00:00Z"},
+		{"Action": "Add", "User": "alice", "PublicKeys": []string{aliceKey}, "ExpiresAt": "2100-01-01T00:00:00Z", "ValidFor": "1h"},
+		{"Action": "Rotate", "User": "alice"},
+	} {
+		output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+		(&Plugin{}).Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, task.NewChanneledCancelFlag(), output)
+		assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus(), "%v", properties)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package authorizedkeys
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/carlescere/scheduler"
+)
+
+const (
+	// cleanerName is the name of the core module removing the expired keys
+	cleanerName = "AuthorizedKeysExpiry"
+
+	// cleanerFrequencyMinutes is how often the expired keys are removed, sshd already refuses them
+	// through the expiry-time option in the meantime
+	cleanerFrequencyMinutes = 1
+)
+
+// ExpiryCleaner is the core module removing the expired keys added by the aws:manageAuthorizedKeys plugin.
+type ExpiryCleaner struct {
+	context context.T
+	job     *scheduler.Job
+}
+
+// NewExpiryCleaner creates a new expiry cleaner core module.
+func NewExpiryCleaner(context context.T) *ExpiryCleaner {
+	return &ExpiryCleaner{
+		context: context.With("[" + cleanerName + "]"),
+	}
+}
+
+// removeExpiredKeys removes the expired keys of all the recorded files.
+func (c *ExpiryCleaner) removeExpiredKeys() {
+	if err := RemoveExpiredKeys(c.context.Log()); err != nil {
+		c.context.Log().Errorf("failed to remove the expired authorized keys: %v", err)
+	}
+}
+
+// ICoreModule implementation
+
+// ModuleName returns the module name
+func (c *ExpiryCleaner) ModuleName() string {
+	return cleanerName
+}
+
+// ModuleExecute removes the keys expired while the agent was stopped and schedules the removal of the others
+func (c *ExpiryCleaner) ModuleExecute(context context.T) (err error) {
+	go c.removeExpiredKeys()
+	if c.job, err = scheduler.Every(cleanerFrequencyMinutes).Minutes().Run(c.removeExpiredKeys); err != nil {
+		c.context.Log().Errorf("unable to schedule the removal of the expired authorized keys. %v", err)
+	}
+	return
+}
+
+// ModuleRequestStop stops the removal of the expired keys
+func (c *ExpiryCleaner) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if c.job != nil {
+		c.context.Log().Info("stopping the removal of the expired authorized keys.")
+		c.job.Quit <- true
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package authorizedkeys
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// managedMarker ends the comment of the keys added by the plugin, only those keys expire
+	managedMarker = "ssm-managed"
+
+	// expiryOption is the OpenSSH option sshd refuses the key with once the time has passed
+	expiryOption = "expiry-time"
+
+	// expiryTimeFormat is the format of the expiry-time option, sshd interprets it in the local time zone
+	expiryTimeFormat = "20060102150405"
+)
+
+// keyTypePrefixes are the prefixes of the OpenSSH public key types
+var keyTypePrefixes = []string{"ssh-", "ecdsa-sha2-", "sk-ssh-", "sk-ecdsa-"}
+
+// keyLine is an entry of an authorized_keys file.
+type keyLine struct {
+	Options string
+	KeyType string
+	Key     string
+	Comment string
+}
+
+// parseKeyLine parses an authorized_keys entry, false for the blank lines, the comments and the lines it can't parse.
+func parseKeyLine(line string) (entry keyLine, ok bool) {
+	field, rest := splitField(line)
+	if !isKeyType(field) {
+		entry.Options = field
+		field, rest = splitField(rest)
+	}
+	if !isKeyType(field) {
+		return keyLine{}, false
+	}
+	entry.KeyType = field
+	entry.Key, entry.Comment = splitField(rest)
+	if entry.Key == "" {
+		return keyLine{}, false
+	}
+	return entry, true
+}
+
+// parsePublicKey parses a public key as pasted from a .pub file, options aren't allowed.
+func parsePublicKey(publicKey string) (keyLine, error) {
+	entry, ok := parseKeyLine(strings.TrimSpace(publicKey))
+	if !ok || entry.Options != "" {
+		return keyLine{}, fmt.Errorf("invalid public key %v, expected <type> <base64 key> [comment]", publicKey)
+	}
+	if _, err := base64.StdEncoding.DecodeString(entry.Key); err != nil {
+		return keyLine{}, fmt.Errorf("invalid public key %v: %v", publicKey, err)
+	}
+	return entry, nil
+}
+
+// String formats the entry as an authorized_keys line.
+func (k keyLine) String() string {
+	fields := []string{k.KeyType, k.Key}
+	if k.Options != "" {
+		fields = append([]string{k.Options}, fields...)
+	}
+	if k.Comment != "" {
+		fields = append(fields, k.Comment)
+	}
+	return strings.Join(fields, " ")
+}
+
+// managed returns true if the key was added by the plugin.
+func (k keyLine) managed() bool {
+	return k.Comment == managedMarker || strings.HasSuffix(k.Comment, " "+managedMarker)
+}
+
+// expiry returns the expiry-time option of the key, false if the key doesn't expire.
+func (k keyLine) expiry() (time.Time, bool) {
+	for _, option := range splitOptions(k.Options) {
+		if !strings.HasPrefix(strings.ToLower(option), expiryOption+"=") {
+			continue
+		}
+		value := strings.Trim(option[len(expiryOption)+1:], "\"")
+		location := time.Local
+		if strings.HasSuffix(value, "Z") {
+			value, location = strings.TrimSuffix(value, "Z"), time.UTC
+		}
+		// the minutes and seconds are optional
+		for _, format := range []string{expiryTimeFormat, expiryTimeFormat[:12], expiryTimeFormat[:8]} {
+			if len(value) != len(format) {
+				continue
+			}
+			if expiry, err := time.ParseInLocation(format, value, location); err == nil {
+				return expiry, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// expired returns true if the key was added by the plugin and its expiry time has passed.
+func (k keyLine) expired(at time.Time) bool {
+	if !k.managed() {
+		return false
+	}
+	expiry, ok := k.expiry()
+	return ok && !at.Before(expiry)
+}
+
+// expiryOptionOf formats the expiry-time option.
+func expiryOptionOf(expiry time.Time) string {
+	return fmt.Sprintf("%v=\"%v\"", expiryOption, expiry.Local().Format(expiryTimeFormat))
+}
+
+// isKeyType returns true if the field is an OpenSSH public key type.
+func isKeyType(field string) bool {
+	for _, prefix := range keyTypePrefixes {
+		if strings.HasPrefix(field, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitField returns the first whitespace separated field of the line, whitespaces in quotes don't separate fields.
+func splitField(line string) (field, rest string) {
+	line = strings.TrimLeft(line, " \t")
+	inQuotes := false
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if (c == ' ' || c == '\t') && !inQuotes {
+			return line[:i], strings.TrimLeft(line[i:], " \t")
+		}
+	}
+	return line, ""
+}
+
+// splitOptions splits the comma separated options, commas in quotes don't separate options.
+func splitOptions(options string) (split []string) {
+	inQuotes := false
+	start := 0
+	for i, c := range options {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ',' && !inQuotes {
+			split = append(split, options[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(options) {
+		split = append(split, options[start:])
+	}
+	return split
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package authorizedkeys
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const (
+	// readKeysScript prints the authorized_keys file $1, nothing when it's missing
+	readKeysScript = `if [ -e "$1" ]; then exec cat -- "$1"; fi`
+
+	// writeKeysScript replaces the authorized_keys file $2 in the folder $1 with the standard input, through a
+	// temporary file renamed over the file so that sshd never reads a partial file
+	writeKeysScript = `umask 077
+mkdir -p -- "$1" || exit 1
+tmp=$(mktemp "$1/.authorized_keys.XXXXXX") || exit 1
+if cat > "$tmp" && chmod 600 "$tmp" && mv -f -- "$tmp" "$2"; then exit 0; fi
+rm -f -- "$tmp"
+exit 1`
+)
+
+// readLines reads an authorized_keys file as its owner, a missing file has no lines. The home folder of the user
+// is theirs, running as the user keeps the links they create there from reaching the files of others.
+func readLines(owner *fileOwner, path string) ([]string, error) {
+	content, err := runAsOwner(owner, nil, readKeysScript, path)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimRight(string(content), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// writeLines replaces an authorized_keys file as its owner, creating its folder if needed.
+func writeLines(owner *fileOwner, path string, lines []string) error {
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+	_, err := runAsOwner(owner, strings.NewReader(content), writeKeysScript, filepath.Dir(path), path)
+	return err
+}
+
+// runAsOwner runs the shell script as the owner, with a minimal environment, and returns its output.
+func runAsOwner(owner *fileOwner, stdin *strings.Reader, script string, args ...string) ([]byte, error) {
+	command := exec.Command("/bin/sh", append([]string{"-c", script, "sh"}, args...)...)
+	command.Env = []string{"PATH=/usr/bin:/bin", "LC_ALL=C"}
+	command.Dir = "/"
+	if owner.uid != os.Getuid() {
+		command.SysProcAttr = &syscall.SysProcAttr{
+			Credential: &syscall.Credential{Uid: uint32(owner.uid), Gid: uint32(owner.gid)},
+		}
+	}
+	if stdin != nil {
+		command.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	output, err := command.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v %v", err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
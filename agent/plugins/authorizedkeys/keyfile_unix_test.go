@@ -0,0 +1,62 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package authorizedkeys
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteLinesAsOwner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authorizedkeys")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	home := filepath.Join(dir, "home")
+	assert.NoError(t, os.Mkdir(home, 0755))
+	path := filepath.Join(home, ".ssh", "authorized_keys")
+
+	owner, err := ownerOfKeysFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.Getuid(), owner.uid)
+	lines, err := readLines(owner, path)
+	assert.NoError(t, err)
+	assert.Empty(t, lines, "a missing file has no lines")
+
+	assert.NoError(t, writeLines(owner, path, []string{aliceKey, bobKey}))
+	lines, err = readLines(owner, path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{aliceKey, bobKey}, lines)
+	info, _ := os.Stat(filepath.Dir(path))
+	assert.Equal(t, sshDirAccess, info.Mode().Perm())
+	info, _ = os.Stat(path)
+	assert.Equal(t, authorizedKeysAccess, info.Mode().Perm())
+	entries, _ := ioutil.ReadDir(filepath.Dir(path))
+	assert.Len(t, entries, 1, "the temporary file is renamed")
+}
+
+func TestOwnerOfKeysFileRefusesLinkedHome(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authorizedkeys")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Symlink(os.TempDir(), filepath.Join(dir, "home")))
+
+	_, err = ownerOfKeysFile(filepath.Join(dir, "home", ".ssh", "authorized_keys"))
+	assert.Error(t, err)
+}
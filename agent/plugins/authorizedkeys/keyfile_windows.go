@@ -0,0 +1,64 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package authorizedkeys
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readLines reads an authorized_keys file, a missing file has no lines.
+func readLines(owner *fileOwner, path string) ([]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	text := strings.TrimRight(string(content), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// writeLines replaces an authorized_keys file, creating its folder if needed. The lines are written to a temporary
+// file renamed over the file so that sshd never reads a partial file.
+func writeLines(owner *fileOwner, path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), sshDirAccess); err != nil {
+		return err
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(path), ".authorized_keys")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+	if _, err = temp.WriteString(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package authorizedkeys
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner is the user the authorized_keys files and folders are read and written as, sshd refuses the files
+// that other users can write to.
+type fileOwner struct {
+	uid int
+	gid int
+}
+
+// ownerOfUser returns the owner of the files of the user.
+func ownerOfUser(u *user.User) (*fileOwner, error) {
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return nil, err
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return nil, err
+	}
+	return &fileOwner{uid: uid, gid: gid}, nil
+}
+
+// ownerOfKeysFile returns the owner of the home folder holding the authorized_keys file, the user the file is
+// read and written as. The home folder must not be a link.
+func ownerOfKeysFile(path string) (*fileOwner, error) {
+	home := filepath.Dir(filepath.Dir(path))
+	info, err := os.Lstat(home)
+	if err != nil {
+		return nil, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || !ok {
+		return nil, fmt.Errorf("%v is not the home folder of a user", home)
+	}
+	return &fileOwner{uid: int(stat.Uid), gid: int(stat.Gid)}, nil
+}
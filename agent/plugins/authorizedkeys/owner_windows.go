@@ -0,0 +1,33 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package authorizedkeys
+
+import (
+	"os/user"
+)
+
+// fileOwner is a no-op on Windows, the authorized_keys files inherit the permissions of the profile of the user.
+type fileOwner struct{}
+
+// ownerOfUser returns no owner, the files inherit the permissions of their folder.
+func ownerOfUser(u *user.User) (*fileOwner, error) {
+	return nil, nil
+}
+
+// ownerOfKeysFile returns no owner, the files inherit the permissions of their folder.
+func ownerOfKeysFile(path string) (*fileOwner, error) {
+	return nil, nil
+}
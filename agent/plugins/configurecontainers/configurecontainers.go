@@ -15,6 +15,7 @@ package configurecontainers
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -31,6 +32,11 @@ const (
 	//Action values
 	INSTALL   = "Install"
 	UNINSTALL = "Uninstall"
+
+	//Runtime values
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+	RuntimePodman     = "podman"
 )
 
 // Plugin is the type for the plugin.
@@ -44,6 +50,8 @@ type ConfigureContainerPluginInput struct {
 	contracts.PluginInput
 	ID     string
 	Action string
+	// Runtime is the container runtime installed or uninstalled, docker by default
+	Runtime string
 }
 
 // NewPlugin returns a new instance of the plugin.
@@ -103,6 +111,16 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput ConfigureCo
 		return
 	}
 
+	switch strings.ToLower(pluginInput.Runtime) {
+	case "", RuntimeDocker:
+		pluginInput.Runtime = RuntimeDocker
+	case RuntimeContainerd, RuntimePodman:
+		pluginInput.Runtime = strings.ToLower(pluginInput.Runtime)
+	default:
+		output.MarkAsFailed(fmt.Errorf("configure Runtime is set to unsupported value: %v", pluginInput.Runtime))
+		return
+	}
+
 	log.Info("********************************starting configure Docker plugin**************************************")
 	switch pluginInput.Action {
 	case INSTALL:
@@ -22,11 +22,11 @@ import (
 )
 
 func runInstallCommands(log log.T, pluginInput ConfigureContainerPluginInput, orchestrationDirectory string, out iohandler.IOHandler) {
-	linuxcontainerutil.RunInstallCommands(log, orchestrationDirectory, out)
+	linuxcontainerutil.RunInstallCommands(log, pluginInput.Runtime, orchestrationDirectory, out)
 	return
 }
 
 func runUninstallCommands(log log.T, pluginInput ConfigureContainerPluginInput, orchestrationDirectory string, out iohandler.IOHandler) {
-	linuxcontainerutil.RunUninstallCommands(log, orchestrationDirectory, out)
+	linuxcontainerutil.RunUninstallCommands(log, pluginInput.Runtime, orchestrationDirectory, out)
 	return
 }
@@ -16,15 +16,25 @@
 package configurecontainers
 
 import (
+	"fmt"
+
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurecontainers/windowscontainerutil"
 )
 
 func runInstallCommands(log log.T, pluginInput ConfigureContainerPluginInput, orchestrationDirectory string, out iohandler.IOHandler) {
+	if pluginInput.Runtime != RuntimeDocker {
+		out.MarkAsFailed(fmt.Errorf("%v is only supported on Linux", pluginInput.Runtime))
+		return
+	}
 	windowscontainerutil.RunInstallCommands(log, orchestrationDirectory, out)
 }
 
 func runUninstallCommands(log log.T, pluginInput ConfigureContainerPluginInput, orchestrationDirectory string, out iohandler.IOHandler) {
+	if pluginInput.Runtime != RuntimeDocker {
+		out.MarkAsFailed(fmt.Errorf("%v is only supported on Linux", pluginInput.Runtime))
+		return
+	}
 	windowscontainerutil.RunUninstallCommands(log, orchestrationDirectory, out)
 }
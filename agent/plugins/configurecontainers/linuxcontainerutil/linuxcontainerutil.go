@@ -21,7 +21,8 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 )
 
-func RunInstallCommands(log log.T, orchestrationDirectory string, out iohandler.IOHandler) {
+// RunInstallCommands installs the container runtime, docker, containerd or podman
+func RunInstallCommands(log log.T, runtime string, orchestrationDirectory string, out iohandler.IOHandler) {
 	var err error
 	var context *updateutil.InstanceContext
 	context, err = dep.GetInstanceContext(log)
@@ -30,6 +31,10 @@ func RunInstallCommands(log log.T, orchestrationDirectory string, out iohandler.
 		out.MarkAsFailed(fmt.Errorf("Error determining Linux variant: %v", err))
 		return
 	}
+	if runtime != docker {
+		runRuntimeCommands(log, runtimeInstallCommands, runtime, context.Platform, "Installation complete", out)
+		return
+	}
 	if context.Platform == updateutil.PlatformUbuntu {
 		log.Error("Ubuntu platform is not currently supported", err)
 		out.MarkAsFailed(fmt.Errorf("Ubuntu platform is not currently supported: %v", err))
@@ -157,7 +162,8 @@ func runRedhatLinuxPlatformInstallCommands(log log.T, orchestrationDirectory str
 	return
 }
 
-func RunUninstallCommands(log log.T, orchestrationDirectory string, out iohandler.IOHandler) {
+// RunUninstallCommands uninstalls the container runtime, docker, containerd or podman
+func RunUninstallCommands(log log.T, runtime string, orchestrationDirectory string, out iohandler.IOHandler) {
 	var err error
 	var context *updateutil.InstanceContext
 	context, err = dep.GetInstanceContext(log)
@@ -166,6 +172,10 @@ func RunUninstallCommands(log log.T, orchestrationDirectory string, out iohandle
 		out.MarkAsFailed(fmt.Errorf("Error determining Linux variant: %v", err))
 		return
 	}
+	if runtime != docker {
+		runRuntimeCommands(log, runtimeUninstallCommands, runtime, context.Platform, "Uninstall complete", out)
+		return
+	}
 	if context.Platform == updateutil.PlatformUbuntu {
 		log.Error("Ubuntu platform is not currently supported", err)
 		out.MarkAsFailed(fmt.Errorf("Ubuntu platform is not currently supported: %v", err))
@@ -13,6 +13,7 @@
 package linuxcontainerutil
 
 import (
+	"errors"
 	"runtime"
 	"testing"
 
@@ -70,7 +71,7 @@ func TestInstall(t *testing.T) {
 	defer func() { dep = depOrig }()
 
 	output := iohandler.DefaultIOHandler{}
-	RunInstallCommands(loggerMock, "", &output)
+	RunInstallCommands(loggerMock, "docker", "", &output)
 
 	assert.Equal(t, output.GetExitCode(), 0)
 	assert.Contains(t, output.GetStdout(), "Installation complete")
@@ -85,7 +86,7 @@ func TestInstallUnsupportedPlatform(t *testing.T) {
 	defer func() { dep = depOrig }()
 
 	output := iohandler.DefaultIOHandler{}
-	RunInstallCommands(loggerMock, "", &output)
+	RunInstallCommands(loggerMock, "docker", "", &output)
 
 	assert.Equal(t, output.GetExitCode(), 1)
 	assert.Equal(t, output.GetStdout(), "")
@@ -101,7 +102,7 @@ func TestUnInstall(t *testing.T) {
 	defer func() { dep = depOrig }()
 
 	output := iohandler.DefaultIOHandler{}
-	RunUninstallCommands(loggerMock, "", &output)
+	RunUninstallCommands(loggerMock, "docker", "", &output)
 
 	assert.Equal(t, output.GetExitCode(), 0)
 	assert.Contains(t, output.GetStderr(), "")
@@ -109,3 +110,53 @@ func TestUnInstall(t *testing.T) {
 	containerMock.AssertCalled(t, "GetInstanceContext", mock.Anything)
 	containerMock.AssertNumberOfCalls(t, "UpdateUtilExeCommandOutput", 1)
 }
+
+func TestInstallPodmanOnUbuntu(t *testing.T) {
+	depOrig := dep
+	containerMock := unsupportedPlatformMock()
+	dep = containerMock
+	defer func() { dep = depOrig }()
+
+	output := iohandler.DefaultIOHandler{}
+	RunInstallCommands(loggerMock, "podman", "", &output)
+
+	assert.Equal(t, output.GetExitCode(), 0)
+	assert.Contains(t, output.GetStdout(), "Installing podman through apt")
+	assert.Contains(t, output.GetStdout(), "Installation complete")
+	containerMock.AssertCalled(t, "UpdateUtilExeCommandOutput", mock.Anything, "apt-get", []string{"install", "-y", "podman"},
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	containerMock.AssertNumberOfCalls(t, "UpdateUtilExeCommandOutput", 2)
+}
+
+func TestInstallContainerdFails(t *testing.T) {
+	depOrig := dep
+	containerMock := DepMock{}
+	containerMock.On("GetInstanceContext", mock.Anything).Return(&updateutil.InstanceContext{Platform: updateutil.PlatformLinux}, nil)
+	containerMock.On("UpdateUtilExeCommandOutput", mock.Anything, "yum", mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", errors.New("No package containerd available"))
+	dep = &containerMock
+	defer func() { dep = depOrig }()
+
+	output := iohandler.DefaultIOHandler{}
+	RunInstallCommands(loggerMock, "containerd", "", &output)
+
+	// the service isn't started
+	assert.Equal(t, output.GetExitCode(), 1)
+	assert.Contains(t, output.GetStderr(), "Error running yum install -y containerd: No package containerd available")
+	containerMock.AssertNumberOfCalls(t, "UpdateUtilExeCommandOutput", 1)
+}
+
+func TestUninstallPodmanUnsupportedPlatform(t *testing.T) {
+	depOrig := dep
+	containerMock := DepMock{}
+	containerMock.On("GetInstanceContext", mock.Anything).Return(&updateutil.InstanceContext{Platform: updateutil.PlatformSuseOS}, nil)
+	dep = &containerMock
+	defer func() { dep = depOrig }()
+
+	output := iohandler.DefaultIOHandler{}
+	RunUninstallCommands(loggerMock, "podman", "", &output)
+
+	assert.Equal(t, output.GetExitCode(), 1)
+	assert.Contains(t, output.GetStderr(), "podman is not supported on the sles platform")
+	containerMock.AssertNumberOfCalls(t, "UpdateUtilExeCommandOutput", 0)
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+package linuxcontainerutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+)
+
+const (
+	docker     = "docker"
+	containerd = "containerd"
+	podman     = "podman"
+)
+
+// runtimeCommand is a command installing or uninstalling a container runtime, with the message output before it runs
+type runtimeCommand struct {
+	message    string
+	cmd        string
+	parameters []string
+}
+
+// runtimeInstallCommands are the commands installing containerd and podman, by runtime and platform
+var runtimeInstallCommands = map[string]map[string][]runtimeCommand{
+	containerd: {
+		updateutil.PlatformLinux: {
+			{"Installing containerd through yum", "yum", []string{"install", "-y", "containerd"}},
+			{"Starting containerd service", "systemctl", []string{"enable", "--now", "containerd"}},
+		},
+		updateutil.PlatformRedHat: {
+			{"Installing yum-utils", "yum", []string{"install", "-y", "yum-utils"}},
+			{"Add docker repo", "yum-config-manager", []string{"--add-repo", "https://download.docker.com/linux/centos/docker-ce.repo"}},
+			{"Installing containerd through yum", "yum", []string{"install", "-y", "containerd.io"}},
+			{"Starting containerd service", "systemctl", []string{"enable", "--now", "containerd"}},
+		},
+		updateutil.PlatformUbuntu: {
+			{"Update apt package index", "apt-get", []string{"update"}},
+			{"Installing containerd through apt", "apt-get", []string{"install", "-y", "containerd"}},
+			{"Starting containerd service", "systemctl", []string{"enable", "--now", "containerd"}},
+		},
+	},
+	podman: {
+		updateutil.PlatformLinux: {
+			{"Installing podman through yum", "yum", []string{"install", "-y", "podman"}},
+		},
+		updateutil.PlatformRedHat: {
+			{"Installing podman through yum", "yum", []string{"install", "-y", "podman"}},
+		},
+		updateutil.PlatformUbuntu: {
+			{"Update apt package index", "apt-get", []string{"update"}},
+			{"Installing podman through apt", "apt-get", []string{"install", "-y", "podman"}},
+		},
+	},
+}
+
+// runtimeUninstallCommands are the commands uninstalling containerd and podman, by runtime and platform
+var runtimeUninstallCommands = map[string]map[string][]runtimeCommand{
+	containerd: {
+		updateutil.PlatformLinux: {
+			{"Removing containerd through yum", "yum", []string{"remove", "-y", "containerd"}},
+		},
+		updateutil.PlatformRedHat: {
+			{"Removing containerd through yum", "yum", []string{"remove", "-y", "containerd.io"}},
+		},
+		updateutil.PlatformUbuntu: {
+			{"Removing containerd through apt", "apt-get", []string{"remove", "-y", "containerd"}},
+		},
+	},
+	podman: {
+		updateutil.PlatformLinux: {
+			{"Removing podman through yum", "yum", []string{"remove", "-y", "podman"}},
+		},
+		updateutil.PlatformRedHat: {
+			{"Removing podman through yum", "yum", []string{"remove", "-y", "podman"}},
+		},
+		updateutil.PlatformUbuntu: {
+			{"Removing podman through apt", "apt-get", []string{"remove", "-y", "podman"}},
+		},
+	},
+}
+
+// runRuntimeCommands runs the commands installing or uninstalling containerd or podman on the platform
+func runRuntimeCommands(log log.T, commands map[string]map[string][]runtimeCommand, runtime string, platform string, completeMessage string, out iohandler.IOHandler) {
+	platformCommands, ok := commands[runtime][platform]
+	if !ok {
+		log.Errorf("%v is not supported on %v", runtime, platform)
+		out.MarkAsFailed(fmt.Errorf("%v is not supported on the %v platform", runtime, platform))
+		return
+	}
+	for _, command := range platformCommands {
+		out.AppendInfo(command.message)
+		commandLine := command.cmd + " " + strings.Join(command.parameters, " ")
+		output, err := dep.UpdateUtilExeCommandOutput(120, log, command.cmd, command.parameters, "", "", "", "", false)
+		if err != nil {
+			log.Errorf("Error running %v: %v", commandLine, err)
+			out.MarkAsFailed(fmt.Errorf("Error running %v: %v", commandLine, err))
+			return
+		}
+		log.Debugf("%v: %v", commandLine, output)
+	}
+	out.AppendInfo(completeMessage)
+	out.MarkAsSucceeded()
+}
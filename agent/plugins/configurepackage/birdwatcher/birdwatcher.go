@@ -133,6 +133,33 @@ func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName stri
 	return downloadFile(tracer, file)
 }
 
+// DownloadPatch downloads the patch of the platform matching artifact from the artifact of another version, when the
+// package lists one in its patches in the manifest, and returns it with the SHA-256 of the artifact in the manifest
+func (ds *PackageService) DownloadPatch(tracer trace.Tracer, packageName string, fromVersion string, version string) (string, string, error) {
+	manifest, err := readManifestFromCache(ds.manifestCache, packageName, version)
+	if err != nil {
+		if manifest, _, err = downloadManifest(ds, packageName, version); err != nil {
+			return "", "", fmt.Errorf("failed to download the manifest: %v", err)
+		}
+	}
+
+	pkginfo, err := ds.extractPackageInfo(tracer, manifest)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find platform: %v", err)
+	}
+	file, patch := manifest.Files[pkginfo.File], manifest.Files[pkginfo.Patches[fromVersion]]
+	if file == nil || patch == nil {
+		return "", "", fmt.Errorf("the manifest has no patch of %v from %v", pkginfo.File, fromVersion)
+	}
+	checksum := file.Checksums["sha256"]
+	if checksum == "" {
+		return "", "", fmt.Errorf("the manifest has no SHA-256 of %v", pkginfo.File)
+	}
+
+	patchPath, err := downloadFile(tracer, patch)
+	return patchPath, checksum, err
+}
+
 // ReportResult sents back the result of the install/upgrade/uninstall run back to Birdwatcher
 func (ds *PackageService) ReportResult(tracer trace.Tracer, result packageservice.PackageResult) error {
 	log := tracer.CurrentTrace().Logger
@@ -774,3 +774,86 @@ func TestDownloadArtifact(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadPatch(t *testing.T) {
+	manifestStr := `
+	{
+		"packages": {
+			"platformName": {
+				"platformVersion": {
+					"architecture": {
+						"file": "test.zip",
+						"patches": {"1.0.0": "test-1.0.0.zst", "0.9.0": "missing.zst"}
+					}
+				}
+			}
+		},
+		"files": {
+			"test.zip": {
+				"downloadLocation": "https://example.com/agent",
+				"checksums": {"sha256": "asdf"}
+			},
+			"test-1.0.0.zst": {
+				"downloadLocation": "https://example.com/agent-patch",
+				"checksums": {"sha256": "qwer"}
+			}
+		}
+	}
+	`
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+
+	data := []struct {
+		name        string
+		fromVersion string
+		expectedErr bool
+	}{
+		{
+			"successful download",
+			"1.0.0",
+			false,
+		},
+		{
+			"no patch from the version",
+			"1.1.0",
+			true,
+		},
+		{
+			"patch missing from the files",
+			"0.9.0",
+			true,
+		},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			cache := packageservice.ManifestCacheMemNew()
+			cache.WriteManifest("packageName", "1234", []byte(manifestStr))
+
+			mockedCollector := envdetect.CollectorMock{}
+			mockedCollector.On("CollectData", mock.Anything).Return(&envdetect.Environment{
+				&osdetect.OperatingSystem{"platformName", "platformVersion", "", "architecture", "", ""},
+				&ec2infradetect.Ec2Infrastructure{"instanceID", "region", "", "availabilityZone", "instanceType"},
+			}, nil).Once()
+
+			ds := &PackageService{manifestCache: cache, collector: &mockedCollector}
+			network := networkMock{downloadOutput: artifact.DownloadOutput{LocalFilePath: "agent.zst"}}
+			networkdep = &network
+
+			result, checksum, err := ds.DownloadPatch(tracer, "packageName", testdata.fromVersion, "1234")
+
+			if testdata.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "agent.zst", result)
+				assert.Equal(t, "asdf", checksum)
+				// the patch is verified by its own checksums when downloaded
+				assert.Equal(t, artifact.DownloadInput{
+					SourceURL:       "https://example.com/agent-patch",
+					SourceChecksums: map[string]string{"sha256": "qwer"},
+				}, network.downloadInput)
+			}
+		})
+	}
+}
@@ -24,6 +24,9 @@ type File struct {
 // PackageInfo contains references to Files matching the current platform/version/arch
 type PackageInfo struct {
 	File string `json:"file"`
+	// Patches references the patches of File from the File of other versions, by version, see
+	// packageservice.ApplyPatch
+	Patches map[string]string `json:"patches,omitempty"`
 }
 
 // Manifest contains references to all SSM packages for a given agent version
@@ -26,6 +26,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/customrepo"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/installer"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
@@ -39,11 +40,27 @@ const (
 	InstallAction = "Install"
 	// UninstallAction represents the json command to uninstall package
 	UninstallAction = "Uninstall"
+
+	// the settings of the private package repository used when the input doesn't set one
+	repositoryURLSetting      = "RepositoryURL"
+	repositoryEndpointSetting = "RepositoryEndpoint"
+	repositoryCABundleSetting = "RepositoryCABundle"
+	repositoryUsernameSetting = "RepositoryUsername"
+	repositoryPasswordSetting = "RepositoryPassword"
+
+	// deltaUpdatesSetting keeps the archives of the packages to patch them to the next versions
+	deltaUpdatesSetting = "DeltaUpdates"
 )
 
+// deltaUpdates tells whether the packages are patched from the archive of the installed version when possible
+var deltaUpdates = func() bool {
+	appCfg, err := appconfig.Config(false)
+	return err == nil && appCfg.PluginSettings(Name()).Bool(deltaUpdatesSetting, false)
+}
+
 // Plugin is the type for the configurepackage plugin.
 type Plugin struct {
-	packageServiceSelector func(tracer trace.Tracer, serviceEndpoint string, localrepo localpackages.Repository) packageservice.PackageService
+	packageServiceSelector func(tracer trace.Tracer, input *ConfigurePackagePluginInput, localrepo localpackages.Repository) (packageservice.PackageService, error)
 	localRepository        localpackages.Repository
 }
 
@@ -55,6 +72,13 @@ type ConfigurePackagePluginInput struct {
 	Action     string `json:"action"`
 	Source     string `json:"source"`
 	Repository string `json:"repository"`
+	// RepositoryURL is a private package repository, https://host/path or s3://bucket/prefix, used instead of the
+	// package service of the region with the other Repository fields, see customrepo.Repository
+	RepositoryURL      string `json:"repositoryUrl"`
+	RepositoryEndpoint string `json:"repositoryEndpoint"`
+	RepositoryCABundle string `json:"repositoryCaBundle"`
+	RepositoryUsername string `json:"repositoryUsername"`
+	RepositoryPassword string `json:"repositoryPassword"`
 }
 
 // NewPlugin returns a new instance of the plugin.
@@ -108,7 +132,12 @@ func prepareConfigurePackage(
 		if !(installedVersion == "" || installState == localpackages.None) && (installedVersion != version || !isSameAsCache) {
 			uninst, err = ensurePackage(tracer, repository, packageService, packageArn, installedVersion, isSameAsCache, config)
 			if err != nil {
-				trace.WithError(err)
+				if installState != localpackages.Failed {
+					// the installed version may no longer be available, the upgrade goes on but can't be rolled back
+					trace.AppendInfof("Warning: failed to keep %v %v to roll back to, upgrading to %v without rollback: %v", input.Name, installedVersion, version, err)
+				} else {
+					trace.WithError(err)
+				}
 			}
 		}
 		trace.End()
@@ -172,7 +201,7 @@ func ensurePackage(
 		(currentVersion == version && (currentState == localpackages.Failed || !isSameAsCache)) {
 		pkgTrace.AppendDebugf("Current %v Target %v State %v", currentVersion, version, currentState).End()
 		pkgTrace.AppendDebugf("Refreshing package content for %v %v", packageName, version).End()
-		if err = repository.RefreshPackage(tracer, packageName, version, packageService.PackageServiceName(), buildDownloadDelegate(tracer, packageService, packageName, version)); err != nil {
+		if err = repository.RefreshPackage(tracer, packageName, version, packageService.PackageServiceName(), buildDownloadDelegate(tracer, repository, packageService, packageName, version)); err != nil {
 			pkgTrace.WithError(err).End()
 			return nil, err
 		}
@@ -187,14 +216,23 @@ func ensurePackage(
 	return repository.GetInstaller(tracer, config, packageName, version), nil
 }
 
-// buildDownloadDelegate constructs the delegate used by the repository to download a package from the service
-func buildDownloadDelegate(tracer trace.Tracer, packageService packageservice.PackageService, packageName string, version string) func(trace.Tracer, string) error {
+// buildDownloadDelegate constructs the delegate used by the repository to download a package from the service. With
+// delta updates, the package is patched from the archive kept for the installed version when the service has a patch,
+// and its archive is kept instead of deleted.
+func buildDownloadDelegate(tracer trace.Tracer, repository localpackages.Repository, packageService packageservice.PackageService, packageName string, version string) func(trace.Tracer, string) error {
 	return func(tracer trace.Tracer, targetDirectory string) error {
 		trace := tracer.BeginSection("download artifact")
-		filePath, err := packageService.DownloadArtifact(tracer, packageName, version)
-		if err != nil {
-			trace.WithError(err).End()
-			return err
+		keepArchive := deltaUpdates()
+		var filePath string
+		if keepArchive {
+			filePath = patchArtifact(tracer, repository, packageService, packageName, version)
+		}
+		if filePath == "" {
+			var err error
+			if filePath, err = packageService.DownloadArtifact(tracer, packageName, version); err != nil {
+				trace.WithError(err).End()
+				return err
+			}
 		}
 
 		// TODO: Consider putting uncompress into the ssminstaller new and not deleting it (since the zip is the repository-validatable artifact)
@@ -203,6 +241,15 @@ func buildDownloadDelegate(tracer trace.Tracer, packageService packageservice.Pa
 			return fmt.Errorf("failed to extract package installer package %v from %v, %v", filePath, targetDirectory, uncompressErr.Error())
 		}
 
+		if keepArchive {
+			keepErr := repository.KeepArchive(tracer, packageName, version, filePath)
+			if keepErr == nil {
+				trace.End()
+				return nil
+			}
+			trace.AppendErrorf("failed to keep the archive of %v %v, the next version will be downloaded whole, %v", packageName, version, keepErr)
+		}
+
 		// NOTE: this could be considered a warning - it likely points to a real problem, but if uncompress succeeded, we could continue
 		// delete compressed package after using
 		if cleanupErr := filesysdep.RemoveAll(filePath); cleanupErr != nil {
@@ -215,6 +262,39 @@ func buildDownloadDelegate(tracer trace.Tracer, packageService packageservice.Pa
 	}
 }
 
+// patchArtifact returns the archive of a version of a package patched from the archive kept for the installed version,
+// or an empty path when there is no archive or patch to patch it from
+func patchArtifact(tracer trace.Tracer, repository localpackages.Repository, packageService packageservice.PackageService, packageName string, version string) string {
+	patchDownloader, ok := packageService.(packageservice.PatchDownloader)
+	if !ok {
+		return ""
+	}
+	installedVersion := repository.GetInstalledVersion(tracer, packageName)
+	if installedVersion == "" || installedVersion == version {
+		return ""
+	}
+	basePath := repository.GetArchive(tracer, packageName, installedVersion)
+	if basePath == "" {
+		return ""
+	}
+
+	patchtrace := tracer.BeginSection(fmt.Sprintf("patch %v %v to %v", packageName, installedVersion, version))
+	patchPath, checksum, err := patchDownloader.DownloadPatch(tracer, packageName, installedVersion, version)
+	if err != nil {
+		patchtrace.AppendInfof("no patch from %v, downloading the whole package: %v", installedVersion, err).End()
+		return ""
+	}
+	defer filesysdep.RemoveAll(patchPath)
+
+	filePath := patchPath + ".zip"
+	if err = packageservice.ApplyPatch(basePath, patchPath, filePath, checksum); err != nil {
+		patchtrace.AppendErrorf("failed to apply the patch from %v, downloading the whole package: %v", installedVersion, err).End()
+		return ""
+	}
+	patchtrace.End()
+	return filePath
+}
+
 // getVersionToInstall decides which version to install and whether there is an existing version (that is not in the process of installing)
 func getVersionToInstall(
 	tracer trace.Tracer,
@@ -351,17 +431,46 @@ func checkAlreadyInstalled(
 }
 
 // selectService chooses the implementation of PackageService to use for a given execution of the plugin
-func selectService(tracer trace.Tracer, serviceEndpoint string, localrepo localpackages.Repository) packageservice.PackageService {
-	region, _ := platform.Region()
+func selectService(tracer trace.Tracer, input *ConfigurePackagePluginInput, localrepo localpackages.Repository) (packageservice.PackageService, error) {
 	appCfg, err := appconfig.Config(false)
+	if repository := customRepository(input, appCfg.PluginSettings(Name())); repository != nil {
+		tracer.CurrentTrace().AppendInfof("Using the package repository %v", repository.URL)
+		return customrepo.New(*repository)
+	}
 
+	serviceEndpoint := input.Repository
+	region, _ := platform.Region()
 	if (err == nil && appCfg.Birdwatcher.ForceEnable) || !ssms3.UseSSMS3Service(tracer, serviceEndpoint, region) {
 		tracer.CurrentTrace().AppendInfof("S3 repository is not marked active in %v %v", region, serviceEndpoint)
-		return birdwatcher.New(serviceEndpoint, localrepo)
+		return birdwatcher.New(serviceEndpoint, localrepo), nil
 	}
 
 	tracer.CurrentTrace().AppendInfof("S3 repository is marked active")
-	return ssms3.New(serviceEndpoint, region)
+	return ssms3.New(serviceEndpoint, region), nil
+}
+
+// customRepository returns the private package repository set by the input, or else by the settings of the plugin,
+// or nil when neither sets one
+func customRepository(input *ConfigurePackagePluginInput, settings appconfig.PluginSettings) *customrepo.Repository {
+	if input.RepositoryURL != "" {
+		return &customrepo.Repository{
+			URL:      input.RepositoryURL,
+			Endpoint: input.RepositoryEndpoint,
+			CABundle: input.RepositoryCABundle,
+			Username: input.RepositoryUsername,
+			Password: input.RepositoryPassword,
+		}
+	}
+	if url := settings.String(repositoryURLSetting, ""); url != "" {
+		return &customrepo.Repository{
+			URL:      url,
+			Endpoint: settings.String(repositoryEndpointSetting, ""),
+			CABundle: settings.String(repositoryCABundleSetting, ""),
+			Username: settings.String(repositoryUsernameSetting, ""),
+			Password: settings.String(repositoryPasswordSetting, ""),
+		}
+	}
+	return nil
 }
 
 // Execute runs the plugin operation and returns output
@@ -385,8 +494,10 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 	} else if input, err := parseAndValidateInput(config.Properties); err != nil {
 		tracer.CurrentTrace().WithError(err).End()
 		out.MarkAsFailed(nil, nil)
+	} else if packageService, err := p.packageServiceSelector(tracer, input, p.localRepository); err != nil {
+		tracer.CurrentTrace().WithError(err).End()
+		out.MarkAsFailed(nil, nil)
 	} else {
-		packageService := p.packageServiceSelector(tracer, input.Repository, p.localRepository)
 		//Return failure if the manifest cannot be accessed
 		//Return failure if the package version is installed, but the manifest is no longer available
 		packageArn, manifestVersion, isSameAsCache, err := getPackageArnAndVersion(tracer, packageService, input)
@@ -430,6 +541,9 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 						uninst,
 						installState,
 						&out)
+					if attempts := describeAttempts(out.Attempts); attempts != "" {
+						out.AppendInfo(log, attempts)
+					}
 				}
 			}
 
@@ -17,6 +17,7 @@ package configurepackage
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
@@ -26,7 +27,8 @@ import (
 )
 
 // TODO: consider passing in the timeout and cancel channels - does cancel trigger rollback?
-// executeConfigurePackage performs install and uninstall actions, with rollback support and recovery after reboots
+// executeConfigurePackage performs install and uninstall actions, with rollback support and recovery after reboots.
+// The installs, validations and uninstalls it runs are recorded in the Attempts of the output.
 func executeConfigurePackage(
 	tracer trace.Tracer,
 	context context.T,
@@ -34,7 +36,7 @@ func executeConfigurePackage(
 	inst installer.Installer,
 	uninst installer.Installer,
 	initialInstallState localpackages.InstallState,
-	output contracts.PluginOutputter) {
+	output *trace.PluginOutputTrace) {
 
 	trace := tracer.BeginSection(fmt.Sprintf("execute configure - state: %s", initialInstallState))
 	defer trace.End()
@@ -75,7 +77,7 @@ func executeInstall(
 	inst installer.Installer,
 	uninst installer.Installer,
 	isRollback bool,
-	output contracts.PluginOutputter) {
+	output *trace.PluginOutputTrace) {
 
 	installtrace := tracer.BeginSection(fmt.Sprintf("install %s/%s - rollback: %t", inst.PackageName(), inst.Version(), isRollback))
 	defer installtrace.End()
@@ -87,12 +89,14 @@ func executeInstall(
 	}
 
 	result := inst.Install(tracer, context)
+	output.RecordAttempt("install", inst.Version(), isRollback, result)
 
 	installtrace.WithExitcode(int64(result.GetExitCode()))
 
 	if result.GetStatus() == contracts.ResultStatusSuccess {
 		validatetrace := tracer.BeginSection(fmt.Sprintf("validate %s/%s - rollback: %t", inst.PackageName(), inst.Version(), isRollback))
 		result = inst.Validate(tracer, context)
+		output.RecordAttempt("validate", inst.Version(), isRollback, result)
 		validatetrace.WithExitcode(int64(result.GetExitCode()))
 	}
 	if result.GetStatus().IsReboot() {
@@ -102,9 +106,14 @@ func executeInstall(
 	}
 	if !result.GetStatus().IsSuccess() {
 		installtrace.AppendErrorf("Failed to install package; install status %v", result.GetStatus())
-		if isRollback || uninst == nil {
+		// a reinstall of the same version replaced the content of the installed version, it can't be rolled back to
+		if isRollback || uninst == nil || uninst.Version() == inst.Version() {
+			if !isRollback {
+				// there is no version to roll back to, remove what the failed install left instead
+				cleanupFailedInstall(tracer, context, inst, output)
+			}
 			output.MarkAsFailed(nil, nil)
-			// TODO: Remove from repository if this isn't the last successfully installed version?  Run uninstall to clean up?
+			// TODO: Remove from repository if this isn't the last successfully installed version?
 			setNewInstallState(tracer, repository, inst, localpackages.Failed)
 			return
 		}
@@ -135,7 +144,7 @@ func executeUninstall(
 	inst installer.Installer,
 	uninst installer.Installer,
 	isRollback bool,
-	output contracts.PluginOutputter) {
+	output *trace.PluginOutputTrace) {
 
 	installtrace := tracer.BeginSection(fmt.Sprintf("uninstall %s/%s - rollback: %t", uninst.PackageName(), uninst.Version(), isRollback))
 	defer installtrace.End()
@@ -151,6 +160,7 @@ func executeUninstall(
 	}
 
 	result := uninst.Uninstall(tracer, context)
+	output.RecordAttempt("uninstall", uninst.Version(), isRollback, result)
 	installtrace.WithExitcode(int64(result.GetExitCode()))
 
 	if !result.GetStatus().IsSuccess() {
@@ -178,6 +188,38 @@ func executeUninstall(
 	output.MarkAsSucceeded()
 }
 
+// cleanupFailedInstall uninstalls a package version that failed to install when there is no version to roll back to,
+// so that the failed install doesn't leave the package half-installed
+func cleanupFailedInstall(tracer trace.Tracer, context context.T, inst installer.Installer, output *trace.PluginOutputTrace) {
+	cleanuptrace := tracer.BeginSection(fmt.Sprintf("uninstall %s/%s after failed install", inst.PackageName(), inst.Version()))
+	defer cleanuptrace.End()
+
+	result := inst.Uninstall(tracer, context)
+	output.RecordAttempt("uninstall", inst.Version(), true, result)
+	cleanuptrace.WithExitcode(int64(result.GetExitCode()))
+
+	if result.GetStatus().IsReboot() {
+		cleanuptrace.AppendInfof("Uninstall of %v %v requested a reboot, which is not done after a failed install", inst.PackageName(), inst.Version())
+	} else if !result.GetStatus().IsSuccess() {
+		cleanuptrace.AppendErrorf("Failed to uninstall %v %v after the failed install; uninstall status %v", inst.PackageName(), inst.Version(), result.GetStatus())
+	}
+}
+
+// describeAttempts lists the installs, validations and uninstalls run by the plugin when it rolled back, or returns an
+// empty string
+func describeAttempts(attempts []trace.Attempt) string {
+	rolledBack := false
+	descriptions := make([]string, len(attempts))
+	for i, attempt := range attempts {
+		rolledBack = rolledBack || attempt.Rollback
+		descriptions[i] = attempt.String()
+	}
+	if !rolledBack {
+		return ""
+	}
+	return "Attempts: " + strings.Join(descriptions, "; ")
+}
+
 // cleanupAfterUninstall removes packages that are no longer needed in the repository
 func cleanupAfterUninstall(tracer trace.Tracer, repository localpackages.Repository, uninst installer.Installer, output contracts.PluginOutputter) {
 	trace := tracer.BeginSection(fmt.Sprintf("cleanup %s/%s", uninst.PackageName(), uninst.Version()))
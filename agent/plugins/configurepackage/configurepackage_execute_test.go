@@ -17,10 +17,12 @@ package configurepackage
 import (
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -95,6 +97,7 @@ func TestUninstall(t *testing.T) {
 
 func TestInstall_FailedInstall(t *testing.T) {
 	installerMock := installerFailedMock("SsmTest", "0.0.1")
+	installerMock.On("Uninstall", mock.Anything).Return(pluginOutputWithStatus(contracts.ResultStatusSuccess)).Once()
 	repoMock := &repository_mock.MockedRepository{}
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Installing).Return(nil)
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Failed).Return(nil)
@@ -106,10 +109,13 @@ func TestInstall_FailedInstall(t *testing.T) {
 
 	installerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, "Attempts: install 0.0.1: Failed (exit code 0); rollback uninstall 0.0.1: Success (exit code 0)", describeAttempts(output.Attempts))
 }
 
 func TestInstall_FailedValidate(t *testing.T) {
 	installerMock := installerInvalidMock("SsmTest", "0.0.1")
+	installerMock.On("Uninstall", mock.Anything).Return(pluginOutputWithStatus(contracts.ResultStatusSuccess)).Once()
 	repoMock := &repository_mock.MockedRepository{}
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Installing).Return(nil)
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Failed).Return(nil)
@@ -157,6 +163,32 @@ func TestRollback(t *testing.T) {
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, "Attempts: uninstall 0.0.1: Success (exit code 0); install 0.0.2: Failed (exit code 0); "+
+		"rollback uninstall 0.0.2: Success (exit code 0); rollback install 0.0.1: Success (exit code 0); "+
+		"rollback validate 0.0.1: Success (exit code 0)", describeAttempts(output.Attempts))
+}
+
+func TestReinstallFailedNotRolledBack(t *testing.T) {
+	// the reinstall replaced the content of the installed version, there is nothing to roll back to
+	uninstallerMock := uninstallerSuccessMock("SsmTest", "0.0.1")
+	installerMock := installerFailedWithRollbackMock("SsmTest", "0.0.1")
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Upgrading).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Installing).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Failed).Return(nil)
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, output)
+
+	installerMock.AssertExpectations(t)
+	uninstallerMock.AssertExpectations(t)
+	repoMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, "Attempts: uninstall 0.0.1: Success (exit code 0); install 0.0.1: Failed (exit code 0); "+
+		"rollback uninstall 0.0.1: Success (exit code 0)", describeAttempts(output.Attempts))
 }
 
 func TestRollbackFailed(t *testing.T) {
@@ -16,13 +16,23 @@
 package configurepackage
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -180,6 +190,43 @@ func TestPrepareUpgrade(t *testing.T) {
 	installerMock.AssertExpectations(t)
 }
 
+func TestPrepareUpgradeWithoutInstalledVersion(t *testing.T) {
+	// file stubs are needed for ensurePackage because it handles the unzip
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+
+	pluginInformation := createStubPluginInputInstallLatest()
+	installerMock := installerNotCalledMock()
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("GetInstalledVersion", mock.Anything, mock.Anything).Return("0.0.1")
+	repoMock.On("GetInstallState", mock.Anything, mock.Anything).Return(localpackages.Installed, "0.0.1")
+	repoMock.On("ValidatePackage", mock.Anything, mock.Anything, "0.0.2").Return(nil)
+	repoMock.On("ValidatePackage", mock.Anything, mock.Anything, "0.0.1").Return(errors.New("missing"))
+	repoMock.On("RefreshPackage", mock.Anything, mock.Anything, "0.0.1", mock.Anything, mock.Anything).Return(errors.New("no longer available"))
+	repoMock.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, "0.0.2").Return(installerMock)
+	serviceMock := serviceUpgradeMock()
+	serviceMock.On("PackageServiceName").Return("mock")
+	tracer := trace.NewTracer(log.NewMockLog())
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	inst, uninst, _, _ := prepareConfigurePackage(
+		tracer,
+		buildConfigSimple(pluginInformation),
+		repoMock,
+		serviceMock,
+		pluginInformation,
+		"packageArn",
+		"0.0.2",
+		false,
+		output)
+
+	// the upgrade goes on without rollback
+	assert.NotNil(t, inst)
+	assert.Nil(t, uninst)
+	assert.NotEqual(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, tracer.ToPluginOutput().GetStdout(), "upgrading to 0.0.2 without rollback")
+}
+
 func TestPrepareUninstall(t *testing.T) {
 	// file stubs are needed for ensurePackage because it handles the unzip
 	stubs := setSuccessStubs()
@@ -513,6 +560,130 @@ func buildConfig(pluginInformation *ConfigurePackagePluginInput, orchestrationDi
 	return config
 }
 
+// writePackagePatch writes the archive of the version 0.0.1 of a package and its patch to the version 0.0.2
+func writePackagePatch(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "0.0.1.zip")
+	base := []byte("package 0.0.1")
+	assert.NoError(t, ioutil.WriteFile(basePath, base, 0600))
+	var patch bytes.Buffer
+	encoder, _ := zstd.NewWriter(&patch, zstd.WithEncoderDictRaw(0, base))
+	encoder.Write([]byte("package 0.0.2"))
+	encoder.Close()
+	patchPath := filepath.Join(dir, "patch")
+	assert.NoError(t, ioutil.WriteFile(patchPath, patch.Bytes(), 0600))
+	return basePath, patchPath
+}
+
+func TestDownloadDelegatePatch(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+	savedDeltaUpdates := deltaUpdates
+	defer func() { deltaUpdates = savedDeltaUpdates }()
+	deltaUpdates = func() bool { return true }
+	basePath, patchPath := writePackagePatch(t)
+	hash := sha256.Sum256([]byte("package 0.0.2"))
+	checksum := hex.EncodeToString(hash[:])
+
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("GetInstalledVersion", mock.Anything, "packageArn").Return("0.0.1")
+	repoMock.On("GetArchive", mock.Anything, "packageArn", "0.0.1").Return(basePath)
+	repoMock.On("KeepArchive", mock.Anything, "packageArn", "0.0.2", patchPath+".zip").Return(nil)
+	serviceMock := serviceUpgradeMock()
+	serviceMock.On("DownloadPatch", mock.Anything, "packageArn", "0.0.1", "0.0.2").Return(patchPath, checksum, nil)
+
+	tracer := trace.NewTracer(log.NewMockLog())
+	err := buildDownloadDelegate(tracer, repoMock, serviceMock, "packageArn", "0.0.2")(tracer, "/packages/packageArn/0.0.2")
+	assert.NoError(t, err)
+	repoMock.AssertExpectations(t)
+	serviceMock.AssertNotCalled(t, "DownloadArtifact", mock.Anything, mock.Anything, mock.Anything)
+	patched, err := ioutil.ReadFile(patchPath + ".zip")
+	assert.NoError(t, err)
+	assert.Equal(t, "package 0.0.2", string(patched))
+}
+
+func TestDownloadDelegatePatchChecksumMismatch(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+	savedDeltaUpdates := deltaUpdates
+	defer func() { deltaUpdates = savedDeltaUpdates }()
+	deltaUpdates = func() bool { return true }
+	basePath, patchPath := writePackagePatch(t)
+	hash := sha256.Sum256([]byte("package 0.0.3"))
+
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("GetInstalledVersion", mock.Anything, "packageArn").Return("0.0.1")
+	repoMock.On("GetArchive", mock.Anything, "packageArn", "0.0.1").Return(basePath)
+	repoMock.On("KeepArchive", mock.Anything, "packageArn", "0.0.2", "/temp/0.0.2").Return(nil)
+	serviceMock := serviceUpgradeMock()
+	serviceMock.On("DownloadPatch", mock.Anything, "packageArn", "0.0.1", "0.0.2").Return(patchPath, hex.EncodeToString(hash[:]), nil)
+
+	// the patched archive isn't the one in the manifest, the whole package is downloaded
+	tracer := trace.NewTracer(log.NewMockLog())
+	err := buildDownloadDelegate(tracer, repoMock, serviceMock, "packageArn", "0.0.2")(tracer, "/packages/packageArn/0.0.2")
+	assert.NoError(t, err)
+	repoMock.AssertExpectations(t)
+	serviceMock.AssertCalled(t, "DownloadArtifact", mock.Anything, "packageArn", "0.0.2")
+	assert.False(t, fileutil.Exists(patchPath+".zip"))
+}
+
+func TestDownloadDelegateWithoutPatch(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+	savedDeltaUpdates := deltaUpdates
+	defer func() { deltaUpdates = savedDeltaUpdates }()
+	deltaUpdates = func() bool { return true }
+
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("GetInstalledVersion", mock.Anything, "packageArn").Return("0.0.1")
+	repoMock.On("GetArchive", mock.Anything, "packageArn", "0.0.1").Return("/packages/packageArn/0.0.1/.package.zip")
+	repoMock.On("KeepArchive", mock.Anything, "packageArn", "0.0.2", "/temp/0.0.2").Return(nil)
+	serviceMock := serviceUpgradeMock()
+	serviceMock.On("DownloadPatch", mock.Anything, "packageArn", "0.0.1", "0.0.2").Return("", "", errors.New("not found"))
+
+	// the whole package is downloaded
+	tracer := trace.NewTracer(log.NewMockLog())
+	err := buildDownloadDelegate(tracer, repoMock, serviceMock, "packageArn", "0.0.2")(tracer, "/packages/packageArn/0.0.2")
+	assert.NoError(t, err)
+	repoMock.AssertExpectations(t)
+	serviceMock.AssertCalled(t, "DownloadArtifact", mock.Anything, "packageArn", "0.0.2")
+}
+
+func TestDownloadDelegateWithoutDeltaUpdates(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+	savedDeltaUpdates := deltaUpdates
+	defer func() { deltaUpdates = savedDeltaUpdates }()
+	deltaUpdates = func() bool { return false }
+
+	repoMock := &repository_mock.MockedRepository{}
+	serviceMock := serviceUpgradeMock()
+
+	// the archive isn't kept
+	tracer := trace.NewTracer(log.NewMockLog())
+	err := buildDownloadDelegate(tracer, repoMock, serviceMock, "packageArn", "0.0.2")(tracer, "/packages/packageArn/0.0.2")
+	assert.NoError(t, err)
+	repoMock.AssertExpectations(t)
+	serviceMock.AssertCalled(t, "DownloadArtifact", mock.Anything, "packageArn", "0.0.2")
+}
+
+func TestCustomRepository(t *testing.T) {
+	settings := appconfig.PluginSettings{"RepositoryURL": "s3://packages", "RepositoryEndpoint": "https://minio.example.com"}
+
+	// without repository in the input nor in the settings, the package service of the region is used
+	assert.Nil(t, customRepository(&ConfigurePackagePluginInput{}, appconfig.PluginSettings{}))
+
+	repository := customRepository(&ConfigurePackagePluginInput{}, settings)
+	assert.Equal(t, "s3://packages", repository.URL)
+	assert.Equal(t, "https://minio.example.com", repository.Endpoint)
+
+	// the repository of the input replaces the one of the settings entirely
+	repository = customRepository(&ConfigurePackagePluginInput{RepositoryURL: "https://packages.example.com", RepositoryUsername: "agent"}, settings)
+	assert.Equal(t, "https://packages.example.com", repository.URL)
+	assert.Equal(t, "agent", repository.Username)
+	assert.Empty(t, repository.Endpoint)
+}
+
 func TestValidateInput(t *testing.T) {
 	input := ConfigurePackagePluginInput{}
 
@@ -190,9 +190,9 @@ func installerNotCalledMock() *installerMock.Mock {
 	return &installerMock.Mock{}
 }
 
-func selectMockService(service packageservice.PackageService) func(tracer trace.Tracer, repository string, localrepo localpackages.Repository) packageservice.PackageService {
-	return func(tracer trace.Tracer, repository string, localrepo localpackages.Repository) packageservice.PackageService {
-		return service
+func selectMockService(service packageservice.PackageService) func(tracer trace.Tracer, input *ConfigurePackagePluginInput, localrepo localpackages.Repository) (packageservice.PackageService, error) {
+	return func(tracer trace.Tracer, input *ConfigurePackagePluginInput, localrepo localpackages.Repository) (packageservice.PackageService, error) {
+		return service, nil
 	}
 }
 
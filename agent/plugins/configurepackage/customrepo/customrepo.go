@@ -0,0 +1,199 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package customrepo implements the package service of the private package repositories, served over HTTPS or by an
+// S3-compatible storage instead of the regional package service, e.g. in air-gapped environments.
+package customrepo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+// latestFile is the file of an HTTPS repository holding the latest version of a package for a platform and arch
+const latestFile = "latest"
+
+// Repository is a private package repository. The packages are at
+// <URL>/<PackageName>/<Platform>/<Arch>/<PackageVersion>/<PackageName>.zip
+type Repository struct {
+	// URL is the root of the repository, https://host/path or s3://bucket/prefix
+	URL string
+	// Endpoint is the endpoint of the S3-compatible storage of an s3:// repository, e.g. https://minio.example.com,
+	// the S3 endpoint of the region when empty
+	Endpoint string
+	// CABundle is the path of a PEM file of the certificates of CAs trusted for the repository, besides those of the
+	// system
+	CABundle string
+	// Username and Password authenticate to the repository: with basic authentication over HTTPS, as the access key
+	// ID and secret access key of an s3:// repository, which uses the credentials of the agent otherwise
+	Username string
+	Password string
+}
+
+// store reads the packages of a repository
+type store interface {
+	// latestVersion returns the latest version of the package in the directory of its platform and arch
+	latestVersion(log log.T, dir string) (string, error)
+	// download downloads a file of the repository and returns its local path
+	download(log log.T, key string) (string, error)
+}
+
+// PackageService gets the packages from a private repository
+type PackageService struct {
+	repository Repository
+	store      store
+}
+
+// New returns the package service of the repository
+func New(repository Repository) (*PackageService, error) {
+	root, err := url.Parse(repository.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid package repository %v: %v", repository.URL, err)
+	}
+	tlsConfig, err := loadTLSConfig(repository.CABundle)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &PackageService{repository: repository}
+	switch root.Scheme {
+	case "https":
+		headers := http.Header{}
+		if repository.Username != "" || repository.Password != "" {
+			credentials := base64.StdEncoding.EncodeToString([]byte(repository.Username + ":" + repository.Password))
+			headers.Set("Authorization", "Basic "+credentials)
+		}
+		service.store = httpsStore{root: root, headers: headers, tlsConfig: tlsConfig}
+	case "s3":
+		if root.Host == "" {
+			return nil, fmt.Errorf("package repository %v has no bucket", repository.URL)
+		}
+		if service.store, err = newS3Store(root, repository, tlsConfig); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("package repository %v is neither an https:// nor an s3:// URL", repository.URL)
+	}
+	return service, nil
+}
+
+// loadTLSConfig returns the TLS configuration trusting the CAs of the bundle besides those of the system, or nil
+// without bundle
+func loadTLSConfig(caBundle string) (*tls.Config, error) {
+	if caBundle == "" {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CA bundle of the package repository: %v", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("the CA bundle %v of the package repository has no PEM certificate", caBundle)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+// PackageServiceName returns the name of the package service
+func (ds *PackageService) PackageServiceName() string {
+	return packageservice.PackageServiceName_customrepo
+}
+
+// DownloadManifest looks up the latest version of a package for this platform and arch in the repository; the
+// packages don't have a manifest
+func (ds *PackageService) DownloadManifest(tracer trace.Tracer, packageName string, version string) (string, string, bool, error) {
+	// the packages of the repository are not reinstalled every time, as those of the ssms3 service
+	isSameAsCache := true
+	if !packageservice.IsLatest(version) {
+		return packageName, version, isSameAsCache, nil
+	}
+
+	versiontrace := tracer.BeginSection(fmt.Sprintf("looking up latest version of %v in %v", packageName, ds.repository.URL))
+	latest, err := ds.store.latestVersion(versiontrace.Logger, packageDir(packageName))
+	if err != nil {
+		versiontrace.WithError(err).End()
+		return packageName, "", isSameAsCache, err
+	}
+	if latest == "" {
+		err = fmt.Errorf("no latest version found for package %v on platform %v in %v", packageName, appconfig.PackagePlatform, ds.repository.URL)
+		versiontrace.WithError(err).End()
+		return packageName, "", isSameAsCache, err
+	}
+	versiontrace.AppendInfof("latest version: %v", latest).End()
+	return packageName, latest, isSameAsCache, nil
+}
+
+// DownloadArtifact downloads the package of a version and returns its local path
+func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName string, version string) (string, error) {
+	return ds.downloadFile(tracer, artifactKey(packageName, version))
+}
+
+// DownloadPatch downloads the patch of the package of a version from the package of another version, and the SHA-256
+// of the package published next to it
+func (ds *PackageService) DownloadPatch(tracer trace.Tracer, packageName string, fromVersion string, version string) (string, string, error) {
+	checksumPath, err := ds.downloadFile(tracer, packageservice.ChecksumPath(artifactKey(packageName, version)))
+	if err != nil {
+		return "", "", err
+	}
+	checksum, err := packageservice.ReadChecksumFile(checksumPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read the checksum of %v %v: %v", packageName, version, err)
+	}
+	patchPath, err := ds.downloadFile(tracer, path.Join(packageDir(packageName), version, packageservice.PatchPath(fromVersion)))
+	return patchPath, checksum, err
+}
+
+// downloadFile downloads a file of the repository and returns its local path
+func (ds *PackageService) downloadFile(tracer trace.Tracer, key string) (string, error) {
+	downloadtrace := tracer.BeginSection(fmt.Sprintf("download %v from %v", key, ds.repository.URL))
+	filePath, err := ds.store.download(downloadtrace.Logger, key)
+	if err != nil {
+		err = fmt.Errorf("failed to download installation package %v from %v, %v", key, ds.repository.URL, err)
+		downloadtrace.WithError(err).End()
+		return "", err
+	}
+	downloadtrace.End()
+	return filePath, nil
+}
+
+// ReportResult doesn't report the results, the repository is only read
+func (*PackageService) ReportResult(tracer trace.Tracer, result packageservice.PackageResult) error {
+	// NOP
+	return nil
+}
+
+// artifactKey returns the key of the package of a version
+func artifactKey(packageName string, version string) string {
+	return path.Join(packageDir(packageName), version, packageName+".zip")
+}
+
+// packageDir returns the directory of the versions of a package for this platform and arch
+func packageDir(packageName string) string {
+	return path.Join(packageName, appconfig.PackagePlatform, runtime.GOARCH)
+}
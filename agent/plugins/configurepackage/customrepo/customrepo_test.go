@@ -0,0 +1,199 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package customrepo
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+var packagePath = fmt.Sprintf("/pkg/%v/%v", appconfig.PackagePlatform, runtime.GOARCH)
+
+// writeCABundle writes the certificate of the TLS server as a CA bundle
+func writeCABundle(t *testing.T, server *httptest.Server) string {
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, ioutil.WriteFile(bundle, content, 0600))
+	return bundle
+}
+
+func TestHTTPSRepository(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "agent" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/packages"+packagePath+"/latest" {
+			fmt.Fprintln(w, "1.2.0")
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	var downloaded artifact.DownloadInput
+	savedDownload := download
+	defer func() { download = savedDownload }()
+	download = func(log log.T, input artifact.DownloadInput) (artifact.DownloadOutput, error) {
+		downloaded = input
+		return artifact.DownloadOutput{LocalFilePath: "/var/downloads/pkg.zip"}, nil
+	}
+
+	service, err := New(Repository{URL: server.URL + "/packages", CABundle: writeCABundle(t, server), Username: "agent", Password: "secret"})
+	assert.NoError(t, err)
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test")
+
+	name, version, isSameAsCache, err := service.DownloadManifest(tracer, "pkg", "latest")
+	assert.NoError(t, err)
+	assert.Equal(t, "pkg", name)
+	assert.Equal(t, "1.2.0", version)
+	assert.True(t, isSameAsCache)
+
+	filePath, err := service.DownloadArtifact(tracer, "pkg", "1.2.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/downloads/pkg.zip", filePath)
+	assert.Equal(t, server.URL+"/packages"+packagePath+"/1.2.0/pkg.zip", downloaded.SourceURL)
+	assert.NotNil(t, downloaded.TLSConfig)
+	assert.True(t, downloaded.Redirects.SameHost)
+	assert.True(t, strings.HasPrefix(downloaded.Headers.Get("Authorization"), "Basic "))
+}
+
+func TestDownloadPatch(t *testing.T) {
+	checksum := strings.Repeat("ab", 32)
+	var downloaded []string
+	savedDownload := download
+	defer func() { download = savedDownload }()
+	download = func(log log.T, input artifact.DownloadInput) (artifact.DownloadOutput, error) {
+		downloaded = append(downloaded, input.SourceURL)
+		localPath := filepath.Join(t.TempDir(), "download")
+		if strings.HasSuffix(input.SourceURL, ".sha256") {
+			assert.NoError(t, ioutil.WriteFile(localPath, []byte(checksum+"  pkg.zip\n"), 0600))
+		}
+		return artifact.DownloadOutput{LocalFilePath: localPath}, nil
+	}
+
+	service, err := New(Repository{URL: "https://packages.example.com"})
+	assert.NoError(t, err)
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test")
+
+	patchPath, patchChecksum, err := service.DownloadPatch(tracer, "pkg", "1.1.0", "1.2.0")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, patchPath)
+	assert.Equal(t, checksum, patchChecksum)
+	assert.Equal(t, []string{
+		"https://packages.example.com" + packagePath + "/1.2.0/pkg.zip.sha256",
+		"https://packages.example.com" + packagePath + "/1.2.0/patches/1.1.0.zst",
+	}, downloaded)
+}
+
+func TestHTTPSRepositoryWithoutCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "1.2.0")
+	}))
+	defer server.Close()
+
+	// the certificate of the server isn't trusted
+	service, err := New(Repository{URL: server.URL})
+	assert.NoError(t, err)
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test")
+	_, _, _, err = service.DownloadManifest(tracer, "pkg", "latest")
+	assert.Error(t, err)
+
+	// a given version is downloaded without looking up the latest
+	_, version, _, err := service.DownloadManifest(tracer, "pkg", "1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", version)
+}
+
+func TestS3CompatibleRepository(t *testing.T) {
+	prefix := "packages" + packagePath + "/"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKID/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.URL.Path == "/bucket" && r.URL.Query().Get("prefix") == prefix:
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>bucket</Name><Prefix>%[1]v</Prefix>
+<Delimiter>/</Delimiter><IsTruncated>false</IsTruncated><CommonPrefixes><Prefix>%[1]v1.2.0/</Prefix></CommonPrefixes>
+<CommonPrefixes><Prefix>%[1]v1.10.0/</Prefix></CommonPrefixes><CommonPrefixes><Prefix>%[1]vtest/</Prefix></CommonPrefixes>
+</ListBucketResult>`, prefix)
+		case r.URL.Path == "/bucket/"+prefix+"1.10.0/pkg.zip":
+			fmt.Fprint(w, "package")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	savedConfig, savedRoot := awsConfig, downloadRoot
+	defer func() { awsConfig, downloadRoot = savedConfig, savedRoot }()
+	awsConfig = func() *aws.Config { return &aws.Config{} }
+	downloadRoot = t.TempDir()
+
+	service, err := New(Repository{URL: "s3://bucket/packages/", Endpoint: server.URL, Username: "AKID", Password: "secret"})
+	assert.NoError(t, err)
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test")
+
+	_, version, _, err := service.DownloadManifest(tracer, "pkg", "latest")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.10.0", version)
+
+	filePath, err := service.DownloadArtifact(tracer, "pkg", version)
+	assert.NoError(t, err)
+	content, err := ioutil.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "package", string(content))
+
+	_, err = service.DownloadArtifact(tracer, "pkg", "2.0.0")
+	assert.Error(t, err)
+}
+
+func TestNewInvalidRepository(t *testing.T) {
+	savedConfig := awsConfig
+	defer func() { awsConfig = savedConfig }()
+	awsConfig = func() *aws.Config { return &aws.Config{} }
+
+	missingBundle := filepath.Join(os.TempDir(), "missing-ca-bundle.pem")
+	for _, repository := range []Repository{
+		{URL: "http://packages.example.com"},
+		{URL: "s3:///packages"},
+		{URL: "s3://bucket", Endpoint: "minio"},
+		{URL: "https://packages.example.com", CABundle: missingBundle},
+	} {
+		_, err := New(repository)
+		assert.Error(t, err, repository.URL)
+	}
+}
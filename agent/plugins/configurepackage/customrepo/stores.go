@@ -0,0 +1,212 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package customrepo
+
+import (
+	"crypto/sha1"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/ssms3"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// maxLatestFileSize is the size of the largest latest file read
+const maxLatestFileSize = 1024
+
+// download downloads the https files, resuming the downloads interrupted
+var download = artifact.Download
+
+// awsConfig returns the configuration of the agent, with its region and credentials
+var awsConfig = sdkutil.AwsConfig
+
+// downloadRoot is the directory the packages of the s3:// repositories are downloaded to
+var downloadRoot = appconfig.DownloadRoot
+
+// httpsStore reads the packages of a repository served over HTTPS
+type httpsStore struct {
+	root      *url.URL
+	headers   http.Header
+	tlsConfig *tls.Config
+}
+
+// url returns the URL of a file of the repository
+func (s httpsStore) url(key string) string {
+	fileURL := *s.root
+	fileURL.Path = path.Join(fileURL.Path, key)
+	return fileURL.String()
+}
+
+// client returns the client of the requests to the repository, which doesn't send the credentials to other hosts
+func (s httpsStore) client() *http.Client {
+	client := &http.Client{
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if r.URL.Host != s.root.Host || r.URL.Scheme != "https" {
+				return fmt.Errorf("the package repository redirected to %v, another host", r.URL.Host)
+			}
+			return nil
+		},
+	}
+	if s.tlsConfig != nil {
+		client.Transport = &http.Transport{Proxy: proxyconfig.Proxy, TLSClientConfig: s.tlsConfig}
+	}
+	return client
+}
+
+func (s httpsStore) latestVersion(log log.T, dir string) (string, error) {
+	request, err := http.NewRequest(http.MethodGet, s.url(path.Join(dir, latestFile)), nil)
+	if err != nil {
+		return "", err
+	}
+	for name, values := range s.headers {
+		request.Header[name] = values
+	}
+	response, err := s.client().Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to read %v: %v", request.URL, response.Status)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(response.Body, maxLatestFileSize))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func (s httpsStore) download(log log.T, key string) (string, error) {
+	output, err := download(log, artifact.DownloadInput{
+		SourceURL: s.url(key),
+		Headers:   s.headers,
+		Redirects: artifact.RedirectPolicy{SameHost: true},
+		TLSConfig: s.tlsConfig,
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.LocalFilePath == "" {
+		return "", fmt.Errorf("%v wasn't downloaded", key)
+	}
+	return output.LocalFilePath, nil
+}
+
+// s3Store reads the packages of a repository in a bucket of S3 or of an S3-compatible storage
+type s3Store struct {
+	bucket string
+	prefix string
+	client *s3.S3
+}
+
+// newS3Store returns the store of the repository s3://bucket/prefix
+func newS3Store(root *url.URL, repository Repository, tlsConfig *tls.Config) (*s3Store, error) {
+	config := awsConfig()
+	if repository.Endpoint != "" {
+		endpoint, err := url.Parse(repository.Endpoint)
+		if err != nil || endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint %v of the package repository", repository.Endpoint)
+		}
+		// the S3-compatible storages don't serve the buckets as host names
+		config.Endpoint = aws.String(repository.Endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+		if aws.StringValue(config.Region) == "" {
+			config.Region = aws.String("us-east-1")
+		}
+	}
+	if repository.Username != "" || repository.Password != "" {
+		config.Credentials = credentials.NewStaticCredentials(repository.Username, repository.Password, "")
+	}
+	if tlsConfig != nil {
+		config.HTTPClient = &http.Client{Transport: &http.Transport{Proxy: proxyconfig.Proxy, TLSClientConfig: tlsConfig}}
+	}
+	return &s3Store{
+		bucket: root.Host,
+		prefix: strings.Trim(root.Path, "/"),
+		client: s3.New(session.New(config)),
+	}, nil
+}
+
+// key returns the key of a file of the repository
+func (s *s3Store) key(name string) string {
+	return strings.TrimPrefix(path.Join(s.prefix, name), "/")
+}
+
+func (s *s3Store) latestVersion(log log.T, dir string) (string, error) {
+	prefix := s.key(dir) + "/"
+	var versions []string
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, folder := range page.CommonPrefixes {
+			versions = append(versions, strings.TrimSuffix(strings.TrimPrefix(aws.StringValue(folder.Prefix), prefix), "/"))
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	return ssms3.GetLatestVersion(versions, ""), nil
+}
+
+func (s *s3Store) download(log log.T, key string) (string, error) {
+	object, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer object.Body.Close()
+
+	if err = fileutil.MakeDirs(downloadRoot); err != nil {
+		return "", err
+	}
+	localPath := filepath.Join(downloadRoot, fmt.Sprintf("%x", sha1.Sum([]byte("s3://"+s.bucket+"/"+s.key(key)))))
+	file, err := os.Create(localPath)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(file, object.Body); err == nil {
+		err = file.Close()
+	} else {
+		file.Close()
+	}
+	if err != nil {
+		os.Remove(localPath)
+		return "", err
+	}
+	log.Debugf("downloaded s3://%v/%v to %v", s.bucket, s.key(key), localPath)
+	return localPath, nil
+}
@@ -35,6 +35,9 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 )
 
+// archiveFileName is the name of the archive kept in the directory of a version of a package
+const archiveFileName = ".package.zip"
+
 // DownloadDelegate is a function that downloads a package to a directory provided by the repository
 type DownloadDelegate func(tracer trace.Tracer, targetDirectory string) error
 
@@ -69,6 +72,9 @@ type Repository interface {
 	GetInventoryData(log log.T) []model.ApplicationData
 	GetInstaller(tracer trace.Tracer, configuration contracts.Configuration, packageArn string, version string) installer.Installer
 
+	KeepArchive(tracer trace.Tracer, packageArn string, version string, archivePath string) error
+	GetArchive(tracer trace.Tracer, packageArn string, version string) string
+
 	LockPackage(tracer trace.Tracer, packageArn string, action string) error
 	UnlockPackage(tracer trace.Tracer, packageArn string)
 
@@ -102,14 +108,15 @@ type PackageInstallState struct {
 
 // PackageManifest represents json structure of package's online configuration file.
 type PackageManifest struct {
-	Name            string `json:"name"`
-	Platform        string `json:"platform"`
-	Architecture    string `json:"architecture"`
-	Version         string `json:"version"`
-	AppName         string `json:"appname"`         // optional inventory attribute
-	AppPublisher    string `json:"apppublisher"`    // optional inventory attribute
-	AppReferenceURL string `json:"appreferenceurl"` // optional inventory attribute
-	AppType         string `json:"apptype"`         // optional inventory attribute
+	Name            string             `json:"name"`
+	Platform        string             `json:"platform"`
+	Architecture    string             `json:"architecture"`
+	Version         string             `json:"version"`
+	AppName         string             `json:"appname"`         // optional inventory attribute
+	AppPublisher    string             `json:"apppublisher"`    // optional inventory attribute
+	AppReferenceURL string             `json:"appreferenceurl"` // optional inventory attribute
+	AppType         string             `json:"apptype"`         // optional inventory attribute
+	Hooks           ssminstaller.Hooks `json:"hooks"`           // optional scripts run around the install
 }
 
 type localRepository struct {
@@ -143,11 +150,33 @@ func (repo *localRepository) GetInstaller(tracer trace.Tracer,
 
 	// Give each version an independent orchestration directory to support install and uninstall for two versions during rollback
 	configuration.OrchestrationDirectory = filepath.Join(configuration.OrchestrationDirectory, normalizeDirectory(version))
+
+	// the manifest was validated with the package, a package without valid manifest has no hooks
+	var hooks ssminstaller.Hooks
+	if manifest, err := repo.openPackageManifest(tracer, repo.filesysdep, packageArn, version); err == nil {
+		hooks = manifest.Hooks
+	}
 	return ssminstaller.New(packageArn,
 		version,
 		repo.getPackageVersionPath(tracer, packageArn, version),
 		configuration,
-		&envdetect.CollectorImp{})
+		&envdetect.CollectorImp{},
+		hooks)
+}
+
+// KeepArchive moves the archive a version of a package was extracted from into the repository, as the base of the
+// patches of the next versions
+func (repo *localRepository) KeepArchive(tracer trace.Tracer, packageArn string, version string, archivePath string) error {
+	return repo.filesysdep.Move(archivePath, repo.getArchivePath(tracer, packageArn, version))
+}
+
+// GetArchive returns the path of the archive kept for a version of a package, empty if there is none
+func (repo *localRepository) GetArchive(tracer trace.Tracer, packageArn string, version string) string {
+	archivePath := repo.getArchivePath(tracer, packageArn, version)
+	if !repo.filesysdep.Exists(archivePath) {
+		return ""
+	}
+	return archivePath
 }
 
 // GetInstalledVersion returns the version of the last successfully installed package
@@ -177,12 +206,29 @@ func (repo *localRepository) checkPackageIsSupported(tracer trace.Tracer, packag
 	return err
 }
 
+// checkPackageHooks returns an error if a hook declared by the manifest is invalid or missing from the package
+func (repo *localRepository) checkPackageHooks(hooks ssminstaller.Hooks, packageVersionPath string) error {
+	for _, script := range []string{hooks.PreInstall, hooks.PostInstall, hooks.Validate} {
+		if script == "" {
+			continue
+		}
+		if err := ssminstaller.ValidateHook(script); err != nil {
+			return err
+		}
+		if !repo.filesysdep.Exists(filepath.Join(packageVersionPath, filepath.FromSlash(script))) {
+			return fmt.Errorf("hook %v is missing from the package", script)
+		}
+	}
+	return nil
+}
+
 // ValidatePackage returns an error if the given package version artifacts are missing, incomplete, or corrupt
 func (repo *localRepository) ValidatePackage(tracer trace.Tracer, packageArn string, version string) error {
 	// Find and parse manifest
 	trace := tracer.BeginSection("Validate Package")
 
-	if _, err := repo.openPackageManifest(tracer, repo.filesysdep, packageArn, version); err != nil {
+	manifest, err := repo.openPackageManifest(tracer, repo.filesysdep, packageArn, version)
+	if err != nil {
 		trace.WithError(err).End()
 		return fmt.Errorf("Package manifest is invalid: %v", err)
 	}
@@ -192,6 +238,11 @@ func (repo *localRepository) ValidatePackage(tracer trace.Tracer, packageArn str
 	packageVersionPath := repo.getPackageVersionPath(tracer, packageArn, version)
 	trace.AppendDebugf("package version path for package %v version %v is %v", packageArn, version, packageVersionPath)
 
+	if err := repo.checkPackageHooks(manifest.Hooks, packageVersionPath); err != nil {
+		trace.WithError(err).End()
+		return fmt.Errorf("Package manifest is invalid: %v", err)
+	}
+
 	files, errFiles := repo.filesysdep.GetFileNames(packageVersionPath)
 	if errFiles != nil {
 		trace.WithError(errFiles)
@@ -398,6 +449,11 @@ func (repo *localRepository) getPackageVersionPath(tracer trace.Tracer, packageA
 	return filepath.Join(repo.getPackageRoot(packageArn), normalizeDirectory(version))
 }
 
+// getArchivePath is a helper function that builds the path to the archive kept for a given version of a package
+func (repo *localRepository) getArchivePath(tracer trace.Tracer, packageArn string, version string) string {
+	return filepath.Join(repo.getPackageVersionPath(tracer, packageArn, version), archiveFileName)
+}
+
 // getManifestPath is a helper function that builds the path to the manifest file for a given version of a package
 func (repo *localRepository) getManifestPath(tracer trace.Tracer, packageArn string, version string, manifestName string) string {
 	return filepath.Join(repo.getPackageVersionPath(tracer, packageArn, version), fmt.Sprintf("%v.json", manifestName))
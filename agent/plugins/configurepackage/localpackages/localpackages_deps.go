@@ -18,6 +18,7 @@ package localpackages
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 )
@@ -31,6 +32,7 @@ type FileSysDep interface {
 	RemoveAll(path string) error
 	ReadFile(filename string) ([]byte, error)
 	WriteFile(filename string, content string) error
+	Move(src string, dst string) error
 }
 
 type fileSysDepImp struct{}
@@ -62,3 +64,8 @@ func (fileSysDepImp) ReadFile(filename string) ([]byte, error) {
 func (fileSysDepImp) WriteFile(filename string, content string) error {
 	return fileutil.WriteAllText(filename, content)
 }
+
+func (fileSysDepImp) Move(src string, dst string) error {
+	_, err := fileutil.MoveAndRenameFile(filepath.Dir(src), filepath.Base(src), filepath.Dir(dst), filepath.Base(dst))
+	return err
+}
@@ -28,6 +28,10 @@ var fileLocker = filelock.NewFileLocker()
 
 func TestPackageLock(t *testing.T) {
 	os.Remove("lockpath")
+	// the lock files stay after the unlock
+	defer os.Remove("lockpath-Foo")
+	defer os.Remove("lockpath-Bar")
+	defer os.Remove("lockpath-Foobar")
 
 	// lock Foo for Install
 	err := lockPackage(fileLocker, "lockpath-Foo", "Foo", "Install")
@@ -47,6 +47,24 @@ func TestGetInstaller(t *testing.T) {
 	assert.NotNil(t, inst)
 }
 
+func TestArchive(t *testing.T) {
+	archivePath := path.Join(testRepoRoot, testPackage, "1.0.0", ".package.zip")
+	// Setup mock with expectations
+	mockFileSys := MockedFileSys{}
+	mockFileSys.On("Move", "/downloads/package.zip", archivePath).Return(nil).Once()
+	mockFileSys.On("Exists", archivePath).Return(true).Once()
+	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "0.0.1", ".package.zip")).Return(false).Once()
+
+	// Instantiate repository with mock
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot}
+
+	// Call and validate mock expectations and return value
+	assert.NoError(t, repo.KeepArchive(tracerMock, testPackage, "1.0.0", "/downloads/package.zip"))
+	assert.Equal(t, archivePath, repo.GetArchive(tracerMock, testPackage, "1.0.0"))
+	assert.Empty(t, repo.GetArchive(tracerMock, testPackage, "0.0.1"))
+	mockFileSys.AssertExpectations(t)
+}
+
 func TestGetInstallState(t *testing.T) {
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
@@ -230,6 +248,25 @@ func TestValidatePackage_NoManifest(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestValidatePackageMissingHook(t *testing.T) {
+	version := "0.0.1"
+	manifest := []byte(`{"name": "SsmTest", "version": "0.0.1", "hooks": {"preinstall": "hooks/pre.sh", "postinstall": "post.sh"}}`)
+	// Setup mock with expectations
+	mockFileSys := MockedFileSys{}
+	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(true).Once()
+	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(manifest, nil).Once()
+	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "hooks", "pre.sh")).Return(true).Once()
+	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "post.sh")).Return(false).Once()
+
+	// Instantiate repository with mock
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot, fileLocker: &filelock.FileLockerNoop{}}
+
+	// Call and validate mock expectations and return value
+	err := repo.ValidatePackage(tracerMock, testPackage, version)
+	mockFileSys.AssertExpectations(t)
+	assert.EqualError(t, err, "Package manifest is invalid: hook post.sh is missing from the package")
+}
+
 func TestValidatePackageNoContent(t *testing.T) {
 	version := "0.0.1"
 	// Setup mock with expectations
@@ -790,3 +827,8 @@ func (fileMock *MockedFileSys) WriteFile(filename string, content string) error
 	fileMock.ContentWritten += content
 	return args.Error(0)
 }
+
+func (fileMock *MockedFileSys) Move(src string, dst string) error {
+	args := fileMock.Called(src, dst)
+	return args.Error(0)
+}
@@ -87,6 +87,16 @@ func (repoMock *MockedRepository) GetInstaller(tracer trace.Tracer,
 	return args.Get(0).(installer.Installer)
 }
 
+func (repoMock *MockedRepository) KeepArchive(tracer trace.Tracer, packageName string, version string, archivePath string) error {
+	args := repoMock.Called(tracer, packageName, version, archivePath)
+	return args.Error(0)
+}
+
+func (repoMock *MockedRepository) GetArchive(tracer trace.Tracer, packageName string, version string) string {
+	args := repoMock.Called(tracer, packageName, version)
+	return args.String(0)
+}
+
 func (repoMock *MockedRepository) ReadManifest(packageName string, packageVersion string) ([]byte, error) {
 	args := repoMock.Called(packageName, packageVersion)
 	return args.Get(0).([]byte), args.Error(1)
@@ -39,6 +39,11 @@ func (ds *Mock) DownloadArtifact(tracer trace.Tracer, packageName string, versio
 	return args.String(0), args.Error(1)
 }
 
+func (ds *Mock) DownloadPatch(tracer trace.Tracer, packageName string, fromVersion string, version string) (string, string, error) {
+	args := ds.Called(tracer, packageName, fromVersion, version)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
 func (ds *Mock) ReportResult(tracer trace.Tracer, result packageservice.PackageResult) error {
 	args := ds.Called(tracer, result)
 	return args.Error(0)
@@ -48,9 +48,17 @@ type PackageService interface {
 	ReportResult(tracer trace.Tracer, result PackageResult) error
 }
 
+// PatchDownloader is implemented by the package services that can download a version of a package as a patch of the
+// archive of another version, see ApplyPatch. DownloadPatch returns the path of the patch and the SHA-256 of the archive
+// of the version, which verifies the archive patched.
+type PatchDownloader interface {
+	DownloadPatch(tracer trace.Tracer, packageName string, fromVersion string, version string) (string, string, error)
+}
+
 const (
 	PackageServiceName_ssms3       = "ssms3"
 	PackageServiceName_birdwatcher = "birdwatcher"
+	PackageServiceName_customrepo  = "customrepo"
 )
 
 // ByTiming implements sort.Interface for []*packageservice.Trace based on the
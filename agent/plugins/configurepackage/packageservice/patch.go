@@ -0,0 +1,131 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package packageservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxPatchWindow is the largest window of the patches applied, the default limit of zstd when decoding: the archives
+// larger than that are downloaded whole instead of patched
+const maxPatchWindow = 1 << 27
+
+// checksumSuffix is the suffix of the file with the SHA-256 of an archive, published next to the archive
+const checksumSuffix = ".sha256"
+
+// PatchPath returns the path of the patch from another version, relative to the directory of the archive of a version
+func PatchPath(fromVersion string) string {
+	return path.Join("patches", fromVersion+".zst")
+}
+
+// ChecksumPath returns the path of the file with the SHA-256 of an archive, which verifies the archive patched
+func ChecksumPath(archivePath string) string {
+	return archivePath + checksumSuffix
+}
+
+// ParseChecksum returns the SHA-256 of a checksum file, in the format of sha256sum or the bare hex digest
+func ParseChecksum(content []byte) (string, error) {
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("the checksum file is empty")
+	}
+	if digest, err := hex.DecodeString(fields[0]); err != nil || len(digest) != sha256.Size {
+		return "", fmt.Errorf("%q is not a SHA-256", fields[0])
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// ReadChecksumFile returns the SHA-256 of a checksum file downloaded, see ParseChecksum, and removes the file
+func ReadChecksumFile(filePath string) (string, error) {
+	content, err := ioutil.ReadFile(filePath)
+	os.Remove(filePath)
+	if err != nil {
+		return "", err
+	}
+	return ParseChecksum(content)
+}
+
+// ApplyPatch writes to targetPath the archive recreated by a patch from the base archive, and verifies it against the
+// SHA-256 of the archive. The patches are made with zstd --patch-from=<base archive> <archive> -o <patch>: zstd frames
+// using the base archive as raw dictionary. The base archive is mapped in memory rather than read, and the archive is
+// streamed to targetPath.
+func ApplyPatch(basePath string, patchPath string, targetPath string, checksum string) (err error) {
+	if checksum == "" {
+		return fmt.Errorf("no SHA-256 of the archive to verify the patch")
+	}
+	base, err := os.Open(basePath)
+	if err != nil {
+		return err
+	}
+	defer base.Close()
+	info, err := base.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxPatchWindow {
+		return fmt.Errorf("the archive of %v bytes is too large to patch, the largest is %v bytes", info.Size(), maxPatchWindow)
+	}
+	var dictionary []byte
+	if info.Size() > 0 {
+		var unmap func() error
+		if dictionary, unmap, err = mapFile(base, int(info.Size())); err != nil {
+			return fmt.Errorf("failed to map the archive %v: %v", basePath, err)
+		}
+		defer unmap()
+	}
+
+	patch, err := os.Open(patchPath)
+	if err != nil {
+		return err
+	}
+	defer patch.Close()
+
+	decoder, err := zstd.NewReader(patch,
+		zstd.WithDecoderDictRaw(0, dictionary),
+		zstd.WithDecoderMaxWindow(maxPatchWindow),
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderLowmem(true))
+	if err != nil {
+		return err
+	}
+	defer decoder.Close()
+
+	target, err := os.Create(targetPath)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(target, hash), decoder); err == nil {
+		err = target.Close()
+	} else {
+		target.Close()
+	}
+	if err == nil && !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), checksum) {
+		err = fmt.Errorf("the SHA-256 of the patched archive is %x instead of %v", hash.Sum(nil), checksum)
+	}
+	if err != nil {
+		os.Remove(targetPath)
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package packageservice
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePatch writes the patch from base to content, as zstd --patch-from does
+func writePatch(t *testing.T, patchPath string, base []byte, content []byte) {
+	var patch bytes.Buffer
+	encoder, err := zstd.NewWriter(&patch, zstd.WithEncoderDictRaw(0, base))
+	assert.NoError(t, err)
+	_, err = encoder.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, encoder.Close())
+	assert.NoError(t, ioutil.WriteFile(patchPath, patch.Bytes(), 0600))
+}
+
+// checksumOf returns the SHA-256 of content, as in the checksum files
+func checksumOf(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+func TestApplyPatch(t *testing.T) {
+	dir := t.TempDir()
+	base := bytes.Repeat([]byte("version 1.0.0 of the package "), 1000)
+	content := append(append([]byte{}, base[:10000]...), []byte("version 1.1.0 of the package")...)
+	basePath := filepath.Join(dir, "base.zip")
+	assert.NoError(t, ioutil.WriteFile(basePath, base, 0600))
+	writePatch(t, filepath.Join(dir, "patch.zst"), base, content)
+
+	assert.NoError(t, ApplyPatch(basePath, filepath.Join(dir, "patch.zst"), filepath.Join(dir, "target.zip"), checksumOf(content)))
+	patched, err := ioutil.ReadFile(filepath.Join(dir, "target.zip"))
+	assert.NoError(t, err)
+	assert.Equal(t, content, patched)
+}
+
+func TestApplyPatchCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "base.zip")
+	assert.NoError(t, ioutil.WriteFile(basePath, []byte("version 1.0.0"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "patch.zst"), []byte("not a patch"), 0600))
+
+	assert.Error(t, ApplyPatch(basePath, filepath.Join(dir, "patch.zst"), filepath.Join(dir, "target.zip"), checksumOf(nil)))
+	assert.False(t, fileutil.Exists(filepath.Join(dir, "target.zip")))
+
+	// the patch of another base is rejected by its checksum
+	writePatch(t, filepath.Join(dir, "patch.zst"), []byte("version 0.9.0 of the package"), []byte("version 1.1.0 of the package"))
+	assert.Error(t, ApplyPatch(basePath, filepath.Join(dir, "patch.zst"), filepath.Join(dir, "target.zip"), checksumOf(nil)))
+	assert.False(t, fileutil.Exists(filepath.Join(dir, "target.zip")))
+}
+
+func TestApplyPatchChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	base := []byte("version 1.0.0 of the package")
+	content := []byte("version 1.1.0 of the package")
+	basePath := filepath.Join(dir, "base.zip")
+	assert.NoError(t, ioutil.WriteFile(basePath, base, 0600))
+	writePatch(t, filepath.Join(dir, "patch.zst"), base, content)
+
+	// a valid patch recreating another archive than the one published
+	assert.Error(t, ApplyPatch(basePath, filepath.Join(dir, "patch.zst"), filepath.Join(dir, "target.zip"), checksumOf(base)))
+	assert.False(t, fileutil.Exists(filepath.Join(dir, "target.zip")))
+	assert.Error(t, ApplyPatch(basePath, filepath.Join(dir, "patch.zst"), filepath.Join(dir, "target.zip"), ""))
+	assert.False(t, fileutil.Exists(filepath.Join(dir, "target.zip")))
+}
+
+func TestParseChecksum(t *testing.T) {
+	checksum := checksumOf([]byte("package"))
+	parsed, err := ParseChecksum([]byte(checksum + "  nginx.zip\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, checksum, parsed)
+	parsed, err = ParseChecksum([]byte(strings.ToUpper(checksum)))
+	assert.NoError(t, err)
+	assert.Equal(t, checksum, parsed)
+
+	_, err = ParseChecksum([]byte(""))
+	assert.Error(t, err)
+	_, err = ParseChecksum([]byte("d41d8cd98f00b204e9800998ecf8427e  nginx.zip"))
+	assert.Error(t, err)
+}
+
+func TestPatchPath(t *testing.T) {
+	assert.Equal(t, "patches/1.0.0.zst", PatchPath("1.0.0"))
+	assert.Equal(t, "nginx/1.1.0/nginx.zip.sha256", ChecksumPath("nginx/1.1.0/nginx.zip"))
+}
@@ -0,0 +1,30 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+package packageservice
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the size bytes of a file in memory, read only
+func mapFile(file *os.File, size int) (content []byte, unmap func() error, err error) {
+	if content, err = syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED); err != nil {
+		return nil, nil, os.NewSyscallError("mmap", err)
+	}
+	return content, func() error { return syscall.Munmap(content) }, nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+package packageservice
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mapFile maps the size bytes of a file in memory, read only
+func mapFile(file *os.File, size int) (content []byte, unmap func() error, err error) {
+	mapping, err := syscall.CreateFileMapping(syscall.Handle(file.Fd()), nil, syscall.PAGE_READONLY, 0, 0, nil)
+	if err != nil {
+		return nil, nil, os.NewSyscallError("CreateFileMapping", err)
+	}
+	defer syscall.CloseHandle(mapping)
+	view, err := syscall.MapViewOfFile(mapping, syscall.FILE_MAP_READ, 0, 0, uintptr(size))
+	if err != nil {
+		return nil, nil, os.NewSyscallError("MapViewOfFile", err)
+	}
+	// the view is mapped outside of the Go heap, its address is reinterpreted rather than converted from uintptr
+	content = unsafe.Slice(*(**byte)(unsafe.Pointer(&view)), size)
+	return content, func() error { return syscall.UnmapViewOfFile(view) }, nil
+}
@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -38,8 +40,20 @@ type Installer struct {
 	packagePath        string
 	config             contracts.Configuration // TODO:MF: See if we can use a smaller struct that has just the things we need
 	envdetectCollector envdetect.Collector
+	hooks              Hooks
 }
 
+// Hooks are the scripts the manifest of a package declares to run before and after its install and to validate it,
+// relative to the directory of the package. The .sh hooks run with sh, the .ps1 hooks with PowerShell.
+type Hooks struct {
+	PreInstall  string `json:"preinstall"`
+	PostInstall string `json:"postinstall"`
+	Validate    string `json:"validate"`
+}
+
+// hookPathPattern restricts the paths of the hooks to those the commands running them don't need to quote
+var hookPathPattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+(/[A-Za-z0-9_.\-]+)*\.(sh|ps1)$`)
+
 type ActionType uint8
 
 const (
@@ -51,13 +65,16 @@ type Action struct {
 	actionName string
 	filepath   string
 	actionType ActionType
+	// script is the path of the script relative to the package directory
+	script string
 }
 
 func New(packageName string,
 	version string,
 	packagePath string,
 	configuration contracts.Configuration,
-	envdetectCollector envdetect.Collector) *Installer {
+	envdetectCollector envdetect.Collector,
+	hooks Hooks) *Installer {
 	return &Installer{
 		filesysdep:         &fileSysDepImp{},
 		execdep:            &execDepImp{},
@@ -66,19 +83,57 @@ func New(packageName string,
 		packagePath:        packagePath,
 		config:             configuration,
 		envdetectCollector: envdetectCollector,
+		hooks:              hooks,
 	}
 }
 
+// Install runs the preinstall hook, the install action and the postinstall hook, stopping at the first that fails
 func (inst *Installer) Install(tracer trace.Tracer, context context.T) contracts.PluginOutputter {
-	return inst.executeAction(tracer, context, "install")
+	return executeInOrder(
+		func() contracts.PluginOutputter {
+			return inst.executeHook(tracer, context, "preinstall", inst.hooks.PreInstall)
+		},
+		func() contracts.PluginOutputter { return inst.executeAction(tracer, context, "install") },
+		func() contracts.PluginOutputter {
+			return inst.executeHook(tracer, context, "postinstall", inst.hooks.PostInstall)
+		})
 }
 
 func (inst *Installer) Uninstall(tracer trace.Tracer, context context.T) contracts.PluginOutputter {
 	return inst.executeAction(tracer, context, "uninstall")
 }
 
+// Validate runs the validate action and then the validate hook
 func (inst *Installer) Validate(tracer trace.Tracer, context context.T) contracts.PluginOutputter {
-	return inst.executeAction(tracer, context, "validate")
+	return executeInOrder(
+		func() contracts.PluginOutputter { return inst.executeAction(tracer, context, "validate") },
+		func() contracts.PluginOutputter {
+			return inst.executeHook(tracer, context, "validate", inst.hooks.Validate)
+		})
+}
+
+// ValidateHook returns an error if the path of a hook is not a .sh or .ps1 script in the package directory
+func ValidateHook(script string) error {
+	if !hookPathPattern.MatchString(script) {
+		return fmt.Errorf("hook %v is not the relative path of a .sh or .ps1 script", script)
+	}
+	for _, element := range strings.Split(script, "/") {
+		if element == "." || element == ".." {
+			return fmt.Errorf("hook %v is outside of the package", script)
+		}
+	}
+	return nil
+}
+
+// executeInOrder runs the steps one after the other until one doesn't succeed, a reboot included, and returns the
+// output of the last step run
+func executeInOrder(steps ...func() contracts.PluginOutputter) (output contracts.PluginOutputter) {
+	for _, step := range steps {
+		if output = step(); output.GetStatus() != contracts.ResultStatusSuccess {
+			break
+		}
+	}
+	return output
 }
 
 func (inst *Installer) Version() string {
@@ -109,6 +164,59 @@ func (inst *Installer) executeAction(tracer trace.Tracer, context context.T, act
 	return output
 }
 
+// executeHook runs a hook of the package, and succeeds without a hook. Its output is added to the output of the step
+// as that of the <hookName>-hook action.
+func (inst *Installer) executeHook(tracer trace.Tracer, context context.T, hookName string, script string) contracts.PluginOutputter {
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+	output.SetStatus(contracts.ResultStatusSuccess)
+	if script == "" {
+		return output
+	}
+
+	actionName := hookName + "-hook"
+	exectrace := tracer.BeginSection(fmt.Sprintf("execute hook: %s %s", hookName, script))
+	pluginsInfo, err := inst.readHook(context, actionName, script)
+	if err != nil {
+		exectrace.WithError(err)
+		output.MarkAsFailed(nil, nil)
+	} else {
+		exectrace.AppendInfof("Initiating %v %v %v hook", inst.packageName, inst.version, hookName)
+		inst.executeDocument(tracer, context, actionName, pluginsInfo, output)
+	}
+
+	exectrace.End()
+	return output
+}
+
+// readHook turns a hook into a set of SSM Document Plugins to execute
+func (inst *Installer) readHook(context context.T, actionName string, script string) (pluginsInfo []contracts.PluginState, err error) {
+	if err = ValidateHook(script); err != nil {
+		return nil, err
+	}
+	action := &Action{
+		actionName: actionName,
+		filepath:   filepath.Join(inst.packagePath, filepath.FromSlash(script)),
+		actionType: ACTION_TYPE_SH,
+		script:     script,
+	}
+	if strings.HasSuffix(script, ".ps1") {
+		action.actionType = ACTION_TYPE_PS1
+	}
+	if !inst.filesysdep.Exists(action.filepath) {
+		return nil, fmt.Errorf("hook %v is missing from the package", script)
+	}
+
+	workingDir := inst.packagePath
+	envVars, err := inst.getEnvVars(actionName, context)
+	if err != nil {
+		return nil, err
+	}
+	if action.actionType == ACTION_TYPE_SH {
+		return inst.readShAction(context, action, workingDir, envVars)
+	}
+	return inst.readPs1Action(context, action, workingDir, envVars)
+}
+
 // getActionPath is a helper function that builds the path to an action document file
 func (inst *Installer) getActionPath(actionName string, extension string) string {
 	return filepath.Join(inst.packagePath, fmt.Sprintf("%v.%v", actionName, extension))
@@ -159,14 +267,14 @@ func (inst *Installer) readShAction(context context.T, action *Action, workingDi
 	}
 
 	runCommand := []interface{}{}
-	runCommand = append(runCommand, fmt.Sprintf("echo Running sh %v.sh", action.actionName))
+	runCommand = append(runCommand, fmt.Sprintf("echo Running sh %v", action.script))
 
 	for k, v := range envVars {
 		v = executers.QuoteShString(v)
 		runCommand = append(runCommand, fmt.Sprintf("export %v=%v", k, v))
 	}
 
-	runCommand = append(runCommand, fmt.Sprintf("sh %v.sh", action.actionName))
+	runCommand = append(runCommand, fmt.Sprintf("sh %v", action.script))
 
 	return inst.readScriptAction(action, workingDir, "runShellScript", runCommand)
 }
@@ -178,14 +286,14 @@ func (inst *Installer) readPs1Action(context context.T, action *Action, workingD
 	}
 
 	runCommand := []interface{}{}
-	runCommand = append(runCommand, fmt.Sprintf("echo Running %v.ps1", action.actionName))
+	runCommand = append(runCommand, fmt.Sprintf("echo Running %v", action.script))
 
 	for k, v := range envVars {
 		v = executers.QuotePsString(v)
 		runCommand = append(runCommand, fmt.Sprintf("$env:%v = %v", k, v))
 	}
 
-	runCommand = append(runCommand, fmt.Sprintf(".\\%v.ps1; exit $LASTEXITCODE", action.actionName))
+	runCommand = append(runCommand, fmt.Sprintf(".\\%v; exit $LASTEXITCODE", filepath.FromSlash(action.script)))
 
 	return inst.readScriptAction(action, workingDir, "runPowerShellScript", runCommand)
 }
@@ -205,12 +313,14 @@ func (inst *Installer) resolveAction(tracer trace.Tracer, actionName string) (ex
 		actionTemp.actionName = actionName
 		actionTemp.actionType = ACTION_TYPE_SH
 		actionTemp.filepath = actionPathSh
+		actionTemp.script = actionName + ".sh"
 	}
 	if actionPathExistsPs1 {
 		countExists += 1
 		actionTemp.actionName = actionName
 		actionTemp.actionType = ACTION_TYPE_PS1
 		actionTemp.filepath = actionPathPs1
+		actionTemp.script = actionName + ".ps1"
 	}
 
 	if countExists > 1 {
@@ -250,6 +250,105 @@ func TestUninstall_Success(t *testing.T) {
 	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
 }
 
+func TestInstall_Hooks(t *testing.T) {
+	// Setup mocks with expectations
+	mockFileSys := MockedFileSys{}
+	mockFileSys.On("Exists", path.Join(testPackagePath, "hooks", "pre.sh")).Return(true).Once()
+	mockReadAction(t, &mockFileSys, path.Join(testPackagePath, "install"), []byte("echo sh"), []byte{}, false)
+	mockFileSys.On("Exists", path.Join(testPackagePath, "post.sh")).Return(true).Once()
+
+	mockExec := MockedExec{}
+	for _, script := range []string{"hooks/pre.sh", "install.sh", "post.sh"} {
+		script := script
+		mockExec.On("ExecuteDocument", mock.Anything, mock.MatchedBy(func(pluginsInfo []contracts.PluginState) bool {
+			runCommand := pluginsInfo[0].Configuration.Properties.(map[string]interface{})["runCommand"].([]interface{})
+			return runCommand[len(runCommand)-1] == "sh "+script
+		}), mock.Anything, mock.Anything).Return(map[string]*contracts.PluginResult{"Foo": {Status: contracts.ResultStatusSuccess, StandardOutput: "ran " + script}}).Once()
+	}
+
+	mockEnvdetectCollector := &envdetect.CollectorMock{}
+	mockEnvdetectCollector.On("CollectData", mock.Anything).Return(&environmentStub, nil).Times(3)
+
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	// Instantiate installer with mock
+	inst := Installer{filesysdep: &mockFileSys,
+		execdep:            &mockExec,
+		packagePath:        testPackagePath,
+		envdetectCollector: mockEnvdetectCollector,
+		hooks:              Hooks{PreInstall: "hooks/pre.sh", PostInstall: "post.sh"}}
+
+	// Call and validate mock expectations and return value
+	output := inst.Install(tracer, contextMock)
+	mockFileSys.AssertExpectations(t)
+	mockExec.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Contains(t, output.GetStdout(), "preinstall-hook output: ran hooks/pre.sh")
+	assert.Contains(t, output.GetStdout(), "postinstall-hook output: ran post.sh")
+}
+
+func TestInstall_PreInstallHookFails(t *testing.T) {
+	// Setup mocks with expectations
+	mockFileSys := MockedFileSys{}
+	mockFileSys.On("Exists", path.Join(testPackagePath, "pre.ps1")).Return(true).Once()
+
+	mockExec := MockedExec{}
+	mockExec.On("ExecuteDocument", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(map[string]*contracts.PluginResult{"Foo": {Status: contracts.ResultStatusFailed, Code: 1, StandardError: "not ready"}}).Once()
+
+	mockEnvdetectCollector := &envdetect.CollectorMock{}
+	mockEnvdetectCollector.On("CollectData", mock.Anything).Return(&environmentStub, nil).Once()
+
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	// Instantiate installer with mock
+	inst := Installer{filesysdep: &mockFileSys,
+		execdep:            &mockExec,
+		packagePath:        testPackagePath,
+		envdetectCollector: mockEnvdetectCollector,
+		hooks:              Hooks{PreInstall: "pre.ps1"}}
+
+	// Call and validate mock expectations and return value, the install action isn't run
+	output := inst.Install(tracer, contextMock)
+	mockFileSys.AssertExpectations(t)
+	mockExec.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "preinstall-hook errors: not ready")
+}
+
+func TestValidate_MissingHook(t *testing.T) {
+	// Setup mocks with expectations
+	mockFileSys := MockedFileSys{}
+	mockReadAction(t, &mockFileSys, path.Join(testPackagePath, "validate"), []byte{}, []byte{}, false)
+	mockFileSys.On("Exists", path.Join(testPackagePath, "check.sh")).Return(false).Once()
+	mockExec := MockedExec{}
+
+	mockEnvdetectCollector := &envdetect.CollectorMock{}
+
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	// Instantiate installer with mock
+	inst := Installer{filesysdep: &mockFileSys,
+		execdep:            &mockExec,
+		packagePath:        testPackagePath,
+		envdetectCollector: mockEnvdetectCollector,
+		hooks:              Hooks{Validate: "check.sh"}}
+
+	// Call and validate mock expectations and return value
+	output := inst.Validate(tracer, contextMock)
+	mockFileSys.AssertExpectations(t)
+	mockExec.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+}
+
+func TestValidateHook(t *testing.T) {
+	for _, script := range []string{"pre.sh", "hooks/post-install.ps1", "hooks/v1.2/check_app.sh"} {
+		assert.NoError(t, ValidateHook(script), script)
+	}
+	for _, script := range []string{"", "pre.bat", "/etc/pre.sh", "../pre.sh", "hooks/../../pre.sh", "./pre.sh", "pre install.sh", "pre.sh; reboot", "hooks\\pre.ps1"} {
+		assert.Error(t, ValidateHook(script), script)
+	}
+}
+
 // Load specified file from file system
 func loadFile(t *testing.T, fileName string) (result []byte) {
 	var err error
@@ -143,6 +143,21 @@ func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName stri
 	return downloadPackageFromS3(tracer, s3Location)
 }
 
+// DownloadPatch downloads the patch of the archive of a version from the archive of another version, and the SHA-256
+// of the archive published next to it
+func (ds *PackageService) DownloadPatch(tracer trace.Tracer, packageName string, fromVersion string, version string) (string, string, error) {
+	checksumPath, err := downloadPackageFromS3(tracer, packageservice.ChecksumPath(getS3Location(packageName, version, ds.packageURL)))
+	if err != nil {
+		return "", "", err
+	}
+	checksum, err := packageservice.ReadChecksumFile(checksumPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read the checksum of %v %v: %v", packageName, version, err)
+	}
+	patchPath, err := downloadPackageFromS3(tracer, getS3PatchLocation(packageName, fromVersion, version, ds.packageURL))
+	return patchPath, checksum, err
+}
+
 func (*PackageService) ReportResult(tracer trace.Tracer, result packageservice.PackageResult) error {
 	// NOP
 	return nil
@@ -183,6 +198,15 @@ func getS3Location(packageName string, version string, url string) string {
 	return s3Location
 }
 
+// getS3PatchLocation constructs the s3 url of the patch of a version of the package from another version
+func getS3PatchLocation(packageName string, fromVersion string, version string, url string) string {
+	s3Location := url + "/{PackageVersion}/" + packageservice.PatchPath(fromVersion)
+
+	s3Location = strings.Replace(s3Location, updateutil.PackageNameHolder, packageName, -1)
+	s3Location = strings.Replace(s3Location, updateutil.PackageVersionHolder, version, -1)
+	return s3Location
+}
+
 // getS3Url returns the s3 location containing all versions of a package
 func getS3Url(packageURL string, packageName string) *url.URL {
 	// s3 uri format based on agreed convention
@@ -193,8 +217,8 @@ func getS3Url(packageURL string, packageName string) *url.URL {
 	return s3Url
 }
 
-// getLatestVersion returns the latest version given a list of version strings (that match PatternVersion)
-func getLatestVersion(versions []string, except string) string {
+// GetLatestVersion returns the latest version given a list of version strings (that match PatternVersion)
+func GetLatestVersion(versions []string, except string) string {
 	var latestVersion string
 	var latestMajor, latestMinor, latestBuild = -1, -1, -1
 	for _, version := range versions {
@@ -232,7 +256,7 @@ func getLatestS3Version(tracer trace.Tracer, packageURL string, name string) (st
 		return "", err
 	}
 
-	latestVersion := getLatestVersion(folders[:], "")
+	latestVersion := GetLatestVersion(folders[:], "")
 	versiontrace.AppendInfof("latest version: %s", latestVersion).End()
 	return latestVersion, nil
 }
@@ -17,7 +17,10 @@ package ssms3
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
@@ -30,25 +33,25 @@ import (
 
 func TestGetLatestVersion_NumericSort(t *testing.T) {
 	versions := [3]string{"1.0.0", "2.0.0", "10.0.0"}
-	latest := getLatestVersion(versions[:], "")
+	latest := GetLatestVersion(versions[:], "")
 	assert.Equal(t, "10.0.0", latest)
 }
 
 func TestGetLatestVersion_OnlyOneValid(t *testing.T) {
 	versions := [3]string{"0.0.0", "1.0", "1.0.0.0"}
-	latest := getLatestVersion(versions[:], "")
+	latest := GetLatestVersion(versions[:], "")
 	assert.Equal(t, "0.0.0", latest)
 }
 
 func TestGetLatestVersion_NoneValid(t *testing.T) {
 	versions := [3]string{"Foo", "1.0", "1.0.0.0"}
-	latest := getLatestVersion(versions[:], "")
+	latest := GetLatestVersion(versions[:], "")
 	assert.Equal(t, "", latest)
 }
 
 func TestGetLatestVersion_None(t *testing.T) {
 	versions := make([]string, 0)
-	latest := getLatestVersion(versions[:], "")
+	latest := GetLatestVersion(versions[:], "")
 	assert.Equal(t, "", latest)
 }
 
@@ -99,31 +102,31 @@ func TestEndpointUsEast1Gamma(t *testing.T) {
 
 func TestGetLatestVersion_RandomStringsAreNotValid(t *testing.T) {
 	versions := []string{"foo", "bar", "asdf", "1234567890", "foo.bar.abc", "-10.-10.-10", "1234567890.asdf.1234567890", "123.abcd"}
-	latest := getLatestVersion(versions[:], "")
+	latest := GetLatestVersion(versions[:], "")
 	assert.Equal(t, "", latest)
 }
 
 func TestGetLatestVersion_DifferentLengthMajorMinorBuildVersion(t *testing.T) {
 	versions := []string{"123.0.126789", "12.3455.67", "65535.8765432.6543"}
-	latest := getLatestVersion(versions[:], "")
+	latest := GetLatestVersion(versions[:], "")
 	assert.Equal(t, "65535.8765432.6543", latest)
 }
 
 func TestGetLatestVersion_ZeroStartingMajorMinorBuildVersion(t *testing.T) {
 	versions := []string{"01.1.1", "0.02.1", "0.1.02", "03.03.03"}
-	latest := getLatestVersion(versions[:], "")
+	latest := GetLatestVersion(versions[:], "")
 	assert.Equal(t, "03.03.03", latest)
 }
 
 func TestGetLatestVersion_OnlyMajorMinorVersionBuildFormatsAreValid(t *testing.T) {
 	versions := []string{"1.0.0", "0.0", "1", "", "4.5.6.7.8.9.1.2.3"}
-	latest := getLatestVersion(versions[:], "")
+	latest := GetLatestVersion(versions[:], "")
 	assert.Equal(t, "1.0.0", latest)
 }
 
 func TestGetLatestVersion_NegativeVersionsAreNotValid(t *testing.T) {
 	versions := []string{"-1.-1.-1", "-2.0.1", "0.1.-1", "1.-2.0"}
-	latest := getLatestVersion(versions[:], "")
+	latest := GetLatestVersion(versions[:], "")
 	assert.Equal(t, "", latest)
 }
 
@@ -227,3 +230,31 @@ func TestUseSSMS3Service_False(t *testing.T) {
 
 	assert.False(t, UseSSMS3Service(tracer, "beta", "eu-central-1"))
 }
+
+func TestGetS3PatchLocation(t *testing.T) {
+	location := getS3PatchLocation("Test", "1.0.0", "1.1.0", "https://s3.amazonaws.com/amazon-ssm-packages-beta/BirdwatcherPackages/{PackageName}/windows/amd64")
+	assert.Equal(t, "https://s3.amazonaws.com/amazon-ssm-packages-beta/BirdwatcherPackages/Test/windows/amd64/1.1.0/patches/1.0.0.zst", location)
+}
+
+func TestDownloadPatch(t *testing.T) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	checksumPath := filepath.Join(t.TempDir(), "Test.zip.sha256")
+	checksum := strings.Repeat("ab", 32)
+	assert.NoError(t, ioutil.WriteFile(checksumPath, []byte(checksum+"  Test.zip\n"), 0600))
+	packageURL := "https://s3.amazonaws.com/amazon-ssm-packages-beta/BirdwatcherPackages/{PackageName}/windows/amd64"
+
+	mockObj := new(SSMS3Mock)
+	mockObj.On("Download", mock.Anything, artifact.DownloadInput{SourceURL: getS3Location("Test", "1.1.0", packageURL) + ".sha256"}).Return(artifact.DownloadOutput{LocalFilePath: checksumPath}, nil)
+	mockObj.On("Download", mock.Anything, artifact.DownloadInput{SourceURL: getS3PatchLocation("Test", "1.0.0", "1.1.0", packageURL)}).Return(artifact.DownloadOutput{LocalFilePath: "patchPath"}, nil)
+
+	networkdep = mockObj
+
+	ds := &PackageService{packageURL: packageURL}
+	patchPath, patchChecksum, err := ds.DownloadPatch(tracer, "Test", "1.0.0", "1.1.0")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "patchPath", patchPath)
+	assert.Equal(t, checksum, patchChecksum)
+	mockObj.AssertExpectations(t)
+}
@@ -14,6 +14,8 @@
 package trace
 
 import (
+	"fmt"
+
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -21,10 +23,40 @@ import (
 
 type PluginOutputTrace struct {
 	Tracer   Tracer
+	Attempts []Attempt
 	exitCode int
 	status   contracts.ResultStatus
 }
 
+// Attempt is an install, validation or uninstall of a package version run by the plugin
+type Attempt struct {
+	Action   string
+	Version  string
+	Rollback bool
+	Status   contracts.ResultStatus
+	ExitCode int
+}
+
+// String describes the attempt, e.g. "rollback install 1.0: Success (exit code 0)"
+func (a Attempt) String() string {
+	action := a.Action
+	if a.Rollback {
+		action = "rollback " + action
+	}
+	return fmt.Sprintf("%v %v: %v (exit code %v)", action, a.Version, a.Status, a.ExitCode)
+}
+
+// RecordAttempt records the result of an install, validation or uninstall of a package version
+func (po *PluginOutputTrace) RecordAttempt(action string, version string, rollback bool, result contracts.PluginOutputter) {
+	po.Attempts = append(po.Attempts, Attempt{
+		Action:   action,
+		Version:  version,
+		Rollback: rollback,
+		Status:   result.GetStatus(),
+		ExitCode: result.GetExitCode(),
+	})
+}
+
 // Getter/Setter
 
 func (po *PluginOutputTrace) GetStatus() contracts.ResultStatus { return po.status }
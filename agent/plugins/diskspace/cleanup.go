@@ -0,0 +1,80 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diskspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// cleanupTarget is a directory a cleanup removes files from
+type cleanupTarget struct {
+	// dir is walked recursively, without following the symbolic links
+	dir string
+	// pattern matches the names of the files removed, all files if nil
+	pattern *regexp.Regexp
+}
+
+// runCleanup removes the regular files of the targets of the cleanup older than minAge, or only counts them in a
+// dry run. The directories are kept, and the targets that don't exist on the instance are skipped.
+func runCleanup(name string, minAge time.Duration, dryRun bool, cancelFlag task.CancelFlag) (result CleanupResult, err error) {
+	result.Name = name
+	cutoff := now().Add(-minAge)
+	walked, failed := 0, 0
+	for _, target := range cleanupTargets(name) {
+		if _, err := os.Lstat(target.dir); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(target.dir, func(path string, info os.FileInfo, err error) error {
+			if walked++; walked%cancelCheckInterval == 0 && (cancelFlag.Canceled() || cancelFlag.ShutDown()) {
+				return errCanceled
+			}
+			if err != nil {
+				if failed++; failed <= maxReportedErrors {
+					result.Errors = append(result.Errors, err.Error())
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() || !info.ModTime().Before(cutoff) {
+				return nil
+			}
+			if target.pattern != nil && !target.pattern.MatchString(info.Name()) {
+				return nil
+			}
+			if !dryRun {
+				if err := os.Remove(path); err != nil {
+					if failed++; failed <= maxReportedErrors {
+						result.Errors = append(result.Errors, err.Error())
+					}
+					return nil
+				}
+			}
+			result.Files++
+			result.ReclaimedBytes += info.Size()
+			return nil
+		})
+		if err == errCanceled {
+			return result, err
+		}
+	}
+	if failed > maxReportedErrors {
+		result.Errors = append(result.Errors, fmt.Sprintf("%d more errors", failed-maxReportedErrors))
+	}
+	return result, nil
+}
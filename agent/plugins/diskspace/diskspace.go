@@ -0,0 +1,285 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package diskspace implements the aws:manageDiskSpace plugin, which reports the largest directories and files
+// under the given paths and optionally removes the package caches, rotated logs and temporary files, for
+// low disk space remediation.
+package diskspace
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// CleanupPackageCache removes the packages downloaded by the package managers
+	CleanupPackageCache = "packageCache"
+
+	// CleanupOldLogs removes the rotated and compressed system logs, the current logs are kept
+	CleanupOldLogs = "oldLogs"
+
+	// CleanupTempFiles removes the files of the system temporary directories
+	CleanupTempFiles = "tempFiles"
+
+	// defaultTopCount is the number of largest directories and files reported unless set
+	defaultTopCount = 10
+
+	// maxTopCount is the maximum number of largest directories and files reported
+	maxTopCount = 100
+
+	// defaultMaxDepth is the depth of the directories reported under each path unless set
+	defaultMaxDepth = 3
+
+	// maxMaxDepth is the maximum depth of the directories reported
+	maxMaxDepth = 10
+
+	// defaultMinAgeDays is the age of the files the cleanups remove unless set
+	defaultMinAgeDays = 7
+
+	// maxReportedErrors is the maximum number of errors reported by the analysis and by each cleanup
+	maxReportedErrors = 10
+)
+
+// cleanups are the supported cleanups
+var cleanups = []string{CleanupPackageCache, CleanupOldLogs, CleanupTempFiles}
+
+// volumeInfo returns the space of the volume of a path
+var volumeInfo = systemVolumeInfo
+
+// cleanupTargets returns the directories and files a cleanup removes
+var cleanupTargets = systemCleanupTargets
+
+// defaultPaths returns the paths analyzed unless set
+var defaultPaths = systemDefaultPaths
+
+// now returns the current time, the files are aged against it
+var now = time.Now
+
+// Plugin is the type for the aws:manageDiskSpace plugin.
+type Plugin struct {
+}
+
+// DiskSpacePluginInput represents the paths to analyze and the cleanups to run.
+type DiskSpacePluginInput struct {
+	contracts.PluginInput
+	ID string
+	// Paths are the absolute paths analyzed, the root of the system volume by default
+	Paths []string
+	// TopCount is the number of largest directories and files reported, 10 by default
+	TopCount int
+	// MaxDepth is the depth of the directories reported under each path, 3 by default
+	MaxDepth int
+	// Cleanups are the cleanups to run, none by default
+	Cleanups []string
+	// MinAgeDays is the age in days of the files the cleanups remove, 7 by default
+	MinAgeDays int
+	// DryRun reports the files the cleanups would remove and the space they would reclaim without removing them
+	DryRun bool
+}
+
+// DiskSpaceOutput is the outcome of the analysis and the cleanups, set as the output of the plugin.
+type DiskSpaceOutput struct {
+	// Volumes are the volumes of the paths, after the cleanups
+	Volumes        []Volume        `json:"volumes"`
+	TopDirectories []PathUsage     `json:"topDirectories"`
+	TopFiles       []PathUsage     `json:"topFiles"`
+	Cleanups       []CleanupResult `json:"cleanups,omitempty"`
+	DryRun         bool            `json:"dryRun"`
+	ReclaimedBytes int64           `json:"reclaimedBytes"`
+	Errors         []string        `json:"errors,omitempty"`
+}
+
+// Volume is the space of the volume of an analyzed path.
+type Volume struct {
+	Path           string  `json:"path"`
+	TotalBytes     int64   `json:"totalBytes"`
+	AvailableBytes int64   `json:"availableBytes"`
+	UsedPercent    float64 `json:"usedPercent"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// PathUsage is the space used by a directory, including its subdirectories, or by a file.
+type PathUsage struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+}
+
+// CleanupResult is the outcome of a cleanup, the space it reclaimed or would reclaim in a dry run.
+type CleanupResult struct {
+	Name           string   `json:"name"`
+	Files          int      `json:"files"`
+	ReclaimedBytes int64    `json:"reclaimedBytes"`
+	Errors         []string `json:"errors,omitempty"`
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameManageDiskSpace
+}
+
+// Execute analyzes the disk usage and runs the cleanups.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input DiskSpacePluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if err := validate(&input); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	result := DiskSpaceOutput{DryRun: input.DryRun}
+	usage, err := analyze(input.Paths, input.MaxDepth, input.TopCount, cancelFlag)
+	if err == errCanceled {
+		markInterrupted(cancelFlag, output)
+		return
+	}
+	result.TopDirectories, result.TopFiles, result.Errors = usage.topDirectories, usage.topFiles, usage.errors
+	for _, directory := range result.TopDirectories {
+		output.AppendInfof("%v\t%v", formatBytes(directory.Bytes), directory.Path)
+	}
+
+	minAge := time.Duration(input.MinAgeDays) * 24 * time.Hour
+	for _, name := range input.Cleanups {
+		cleanup, err := runCleanup(name, minAge, input.DryRun, cancelFlag)
+		if err == errCanceled {
+			markInterrupted(cancelFlag, output)
+			return
+		}
+		result.Cleanups = append(result.Cleanups, cleanup)
+		result.ReclaimedBytes += cleanup.ReclaimedBytes
+		log.Infof("cleanup %v: %d files, %d bytes, dry run %v", name, cleanup.Files, cleanup.ReclaimedBytes, input.DryRun)
+		if input.DryRun {
+			output.AppendInfof("%v: would remove %d files, reclaiming %v", name, cleanup.Files, formatBytes(cleanup.ReclaimedBytes))
+		} else {
+			output.AppendInfof("%v: removed %d files, reclaimed %v", name, cleanup.Files, formatBytes(cleanup.ReclaimedBytes))
+		}
+		for _, cleanupErr := range cleanup.Errors {
+			output.AppendErrorf("%v: %v", name, cleanupErr)
+		}
+	}
+
+	for _, path := range input.Paths {
+		volume := Volume{Path: path}
+		if total, available, err := volumeInfo(path); err != nil {
+			volume.Error = err.Error()
+		} else if total > 0 {
+			volume.TotalBytes, volume.AvailableBytes = total, available
+			volume.UsedPercent = float64(total-available) * 100 / float64(total)
+			output.AppendInfof("%v: %v available of %v", path, formatBytes(available), formatBytes(total))
+		}
+		result.Volumes = append(result.Volumes, volume)
+	}
+
+	if content, err := jsonutil.Marshal(result); err == nil {
+		output.SetOutput(content)
+	}
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// validate checks the input and sets its defaults.
+func validate(input *DiskSpacePluginInput) error {
+	if len(input.Paths) == 0 {
+		input.Paths = defaultPaths()
+	}
+	for i, path := range input.Paths {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("Paths must be absolute, got %q", path)
+		}
+		input.Paths[i] = filepath.Clean(path)
+	}
+	if input.TopCount == 0 {
+		input.TopCount = defaultTopCount
+	}
+	if input.TopCount < 0 || input.TopCount > maxTopCount {
+		return fmt.Errorf("TopCount must be between 1 and %d, got %d", maxTopCount, input.TopCount)
+	}
+	if input.MaxDepth == 0 {
+		input.MaxDepth = defaultMaxDepth
+	}
+	if input.MaxDepth < 0 || input.MaxDepth > maxMaxDepth {
+		return fmt.Errorf("MaxDepth must be between 1 and %d, got %d", maxMaxDepth, input.MaxDepth)
+	}
+	for _, name := range input.Cleanups {
+		if !isCleanup(name) {
+			return fmt.Errorf("cleanups must be among %v, got %q", strings.Join(cleanups, ", "), name)
+		}
+	}
+	if input.MinAgeDays == 0 {
+		input.MinAgeDays = defaultMinAgeDays
+	}
+	if input.MinAgeDays < 0 {
+		return fmt.Errorf("MinAgeDays must be at least 1, got %d", input.MinAgeDays)
+	}
+	return nil
+}
+
+// isCleanup returns true if the cleanup is supported.
+func isCleanup(name string) bool {
+	for _, supported := range cleanups {
+		if name == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// markInterrupted marks the plugin as shut down or cancelled.
+func markInterrupted(cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else {
+		output.MarkAsCancelled()
+	}
+}
+
+// formatBytes returns the size in the largest unit it is at least one of.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	divisor, exponent := int64(unit), 0
+	for n := size / unit; n >= unit && exponent < 4; n /= unit {
+		divisor *= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(divisor), "KMGTP"[exponent])
+}
@@ -0,0 +1,171 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diskspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFile creates a file of the size, modified at the time, and its directories.
+func writeFile(t *testing.T, path string, size int, modified time.Time) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	assert.NoError(t, ioutil.WriteFile(path, []byte(strings.Repeat("x", size)), 0600))
+	assert.NoError(t, os.Chtimes(path, modified, modified))
+}
+
+func TestValidate(t *testing.T) {
+	root, _ := filepath.Abs(string(filepath.Separator))
+	input := DiskSpacePluginInput{Paths: []string{filepath.Join(root, "var", "log", "..")}, Cleanups: []string{CleanupOldLogs}}
+	assert.NoError(t, validate(&input))
+	assert.Equal(t, []string{filepath.Join(root, "var")}, input.Paths)
+	assert.Equal(t, defaultTopCount, input.TopCount)
+	assert.Equal(t, defaultMaxDepth, input.MaxDepth)
+	assert.Equal(t, defaultMinAgeDays, input.MinAgeDays)
+
+	input = DiskSpacePluginInput{}
+	assert.NoError(t, validate(&input))
+	assert.Equal(t, systemDefaultPaths(), input.Paths)
+
+	invalid := []DiskSpacePluginInput{
+		{Paths: []string{"var/log"}},
+		{TopCount: maxTopCount + 1},
+		{MaxDepth: -1},
+		{Cleanups: []string{"everything"}},
+		{MinAgeDays: -1},
+	}
+	for _, input := range invalid {
+		assert.Error(t, validate(&input), "%+v", input)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	root, err := ioutil.TempDir("", "diskspace")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	modified := time.Now()
+	writeFile(t, filepath.Join(root, "a", "b", "c", "d", "big"), 400, modified)
+	writeFile(t, filepath.Join(root, "a", "small"), 50, modified)
+	writeFile(t, filepath.Join(root, "e", "medium"), 200, modified)
+	writeFile(t, filepath.Join(root, "top"), 10, modified)
+
+	usage, err := analyze([]string{root}, 2, 3, task.NewChanneledCancelFlag())
+	assert.NoError(t, err)
+	assert.Empty(t, usage.errors)
+	assert.Equal(t, []PathUsage{
+		{Path: filepath.Join(root, "a"), Bytes: 450},
+		{Path: filepath.Join(root, "a", "b"), Bytes: 400},
+		{Path: filepath.Join(root, "e"), Bytes: 200},
+	}, usage.topDirectories, "the directories deeper than the max depth are not reported")
+	assert.Equal(t, []PathUsage{
+		{Path: filepath.Join(root, "a", "b", "c", "d", "big"), Bytes: 400},
+		{Path: filepath.Join(root, "e", "medium"), Bytes: 200},
+		{Path: filepath.Join(root, "a", "small"), Bytes: 50},
+	}, usage.topFiles)
+
+	usage, err = analyze([]string{filepath.Join(root, "missing")}, 2, 3, task.NewChanneledCancelFlag())
+	assert.NoError(t, err)
+	assert.Len(t, usage.errors, 1)
+}
+
+func TestRunCleanup(t *testing.T) {
+	root, err := ioutil.TempDir("", "diskspace")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	cleanupTargets = func(name string) []cleanupTarget {
+		return []cleanupTarget{
+			{dir: filepath.Join(root, "log"), pattern: regexp.MustCompile(`\.gz$`)},
+			{dir: filepath.Join(root, "missing")},
+		}
+	}
+	defer func() { cleanupTargets = systemCleanupTargets }()
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	writeFile(t, filepath.Join(root, "log", "messages"), 100, old)
+	writeFile(t, filepath.Join(root, "log", "messages.1.gz"), 200, old)
+	writeFile(t, filepath.Join(root, "log", "app", "app.log.gz"), 300, old)
+	writeFile(t, filepath.Join(root, "log", "app", "recent.gz"), 400, time.Now())
+
+	result, err := runCleanup(CleanupOldLogs, 7*24*time.Hour, true, task.NewChanneledCancelFlag())
+	assert.NoError(t, err)
+	assert.Equal(t, CleanupResult{Name: CleanupOldLogs, Files: 2, ReclaimedBytes: 500}, result)
+	assert.True(t, fileutil.Exists(filepath.Join(root, "log", "messages.1.gz")), "a dry run removes nothing")
+
+	result, err = runCleanup(CleanupOldLogs, 7*24*time.Hour, false, task.NewChanneledCancelFlag())
+	assert.NoError(t, err)
+	assert.Equal(t, CleanupResult{Name: CleanupOldLogs, Files: 2, ReclaimedBytes: 500}, result)
+	_, err = os.Stat(filepath.Join(root, "log", "messages.1.gz"))
+	assert.True(t, os.IsNotExist(err))
+	assert.True(t, fileutil.Exists(filepath.Join(root, "log", "messages")), "the files not matching the pattern are kept")
+	assert.True(t, fileutil.Exists(filepath.Join(root, "log", "app", "recent.gz")), "the recent files are kept")
+}
+
+func TestExecute(t *testing.T) {
+	root, err := ioutil.TempDir("", "diskspace")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	writeFile(t, filepath.Join(root, "tmp", "old"), 100, time.Now().Add(-30*24*time.Hour))
+	cleanupTargets = func(name string) []cleanupTarget { return []cleanupTarget{{dir: filepath.Join(root, "tmp")}} }
+	volumeInfo = func(path string) (int64, int64, error) { return 1000, 250, nil }
+	defer func() {
+		cleanupTargets = systemCleanupTargets
+		volumeInfo = systemVolumeInfo
+	}()
+
+	execute := func(properties map[string]interface{}) *iohandler.DefaultIOHandler {
+		output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+		plugin, _ := NewPlugin()
+		plugin.Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, task.NewChanneledCancelFlag(), output)
+		return output
+	}
+
+	output := execute(map[string]interface{}{"Paths": []string{root}, "Cleanups": []string{CleanupTempFiles}, "DryRun": true})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	var result DiskSpaceOutput
+	assert.NoError(t, jsonutil.Unmarshal(output.GetOutput().(string), &result))
+	assert.Equal(t, []Volume{{Path: root, TotalBytes: 1000, AvailableBytes: 250, UsedPercent: 75}}, result.Volumes)
+	assert.Equal(t, []PathUsage{{Path: filepath.Join(root, "tmp"), Bytes: 100}}, result.TopDirectories)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, int64(100), result.ReclaimedBytes)
+	assert.Contains(t, output.GetStdout(), "would remove 1 files, reclaiming 100 B")
+	assert.True(t, fileutil.Exists(filepath.Join(root, "tmp", "old")))
+
+	output = execute(map[string]interface{}{"Paths": []string{root}, "Cleanups": []string{CleanupTempFiles}})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Contains(t, output.GetStdout(), "removed 1 files, reclaimed 100 B")
+	_, err = os.Stat(filepath.Join(root, "tmp", "old"))
+	assert.True(t, os.IsNotExist(err))
+
+	output = execute(map[string]interface{}{"Paths": []string{"relative"}})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "2.0 GiB", formatBytes(2<<30))
+}
@@ -0,0 +1,65 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package diskspace
+
+import (
+	"os"
+	"regexp"
+	"syscall"
+)
+
+// rotatedLogPattern matches the logs rotated by logrotate and newsyslog: numbered, compressed or dated
+var rotatedLogPattern = regexp.MustCompile(`\.([0-9]+|gz|bz2|xz|zst|old)$|-[0-9]{8}$`)
+
+// systemDefaultPaths returns the root of the file system.
+func systemDefaultPaths() []string {
+	return []string{"/"}
+}
+
+// systemVolumeInfo returns the total and available bytes of the file system of the path.
+func systemVolumeInfo(path string) (total int64, available int64, err error) {
+	var stat syscall.Statfs_t
+	if err = syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	blockSize := uint64(stat.Bsize)
+	return int64(uint64(stat.Blocks) * blockSize), int64(uint64(stat.Bavail) * blockSize), nil
+}
+
+// sameVolume returns true if the directory is on the file system of the root.
+func sameVolume(root os.FileInfo, info os.FileInfo) bool {
+	rootStat, rootOk := root.Sys().(*syscall.Stat_t)
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return !rootOk || !ok || rootStat.Dev == stat.Dev
+}
+
+// systemCleanupTargets returns the directories of the package managers, system logs and temporary files.
+func systemCleanupTargets(name string) []cleanupTarget {
+	switch name {
+	case CleanupPackageCache:
+		return []cleanupTarget{
+			{dir: "/var/cache/apt/archives", pattern: regexp.MustCompile(`\.deb$`)},
+			{dir: "/var/cache/yum", pattern: regexp.MustCompile(`\.rpm$`)},
+			{dir: "/var/cache/dnf", pattern: regexp.MustCompile(`\.d?rpm$`)},
+			{dir: "/var/cache/zypp/packages", pattern: regexp.MustCompile(`\.rpm$`)},
+		}
+	case CleanupOldLogs:
+		return []cleanupTarget{{dir: "/var/log", pattern: rotatedLogPattern}}
+	case CleanupTempFiles:
+		return []cleanupTarget{{dir: "/tmp"}, {dir: "/var/tmp"}}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package diskspace
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// getDiskFreeSpace is GetDiskFreeSpaceExW of kernel32.dll
+var getDiskFreeSpace = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// systemDefaultPaths returns the root of the system drive.
+func systemDefaultPaths() []string {
+	return []string{os.Getenv("SystemDrive") + `\`}
+}
+
+// systemVolumeInfo returns the total and available bytes of the volume of the path.
+func systemVolumeInfo(path string) (total int64, available int64, err error) {
+	var free int64
+	ret, _, err := getDiskFreeSpace.Call(
+		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(path))),
+		uintptr(unsafe.Pointer(&available)),
+		uintptr(unsafe.Pointer(&total)),
+		uintptr(unsafe.Pointer(&free)))
+	if ret == 0 {
+		return 0, 0, err
+	}
+	return total, available, nil
+}
+
+// sameVolume returns true, the walk doesn't follow the junctions and volumes are mounted as drives.
+func sameVolume(root os.FileInfo, info os.FileInfo) bool {
+	return true
+}
+
+// systemCleanupTargets returns the directories of the Windows Update downloads, Windows logs and temporary files.
+func systemCleanupTargets(name string) []cleanupTarget {
+	windowsDir := os.Getenv("WINDIR")
+	switch name {
+	case CleanupPackageCache:
+		return []cleanupTarget{{dir: filepath.Join(windowsDir, "SoftwareDistribution", "Download")}}
+	case CleanupOldLogs:
+		return []cleanupTarget{{dir: filepath.Join(windowsDir, "Logs"), pattern: regexp.MustCompile(`(?i)\.(cab|old|bak)$`)}}
+	case CleanupTempFiles:
+		targets := []cleanupTarget{{dir: filepath.Join(windowsDir, "Temp")}}
+		if temp := os.TempDir(); !sameDir(temp, targets[0].dir) {
+			targets = append(targets, cleanupTarget{dir: temp})
+		}
+		return targets
+	}
+	return nil
+}
+
+// sameDir returns true if the paths are the same directory, ignoring the case.
+func sameDir(a, b string) bool {
+	return strings.EqualFold(filepath.Clean(a), filepath.Clean(b))
+}
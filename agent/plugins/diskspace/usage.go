@@ -0,0 +1,136 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diskspace
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// cancelCheckInterval is the number of files walked between the checks of the cancel flag
+const cancelCheckInterval = 1000
+
+// errCanceled stops the walks when the plugin is canceled
+var errCanceled = errors.New("canceled")
+
+// diskUsage is the outcome of the analysis
+type diskUsage struct {
+	topDirectories []PathUsage
+	topFiles       []PathUsage
+	errors         []string
+}
+
+// analyze walks the paths, without crossing into other volumes, and returns the largest directories up to maxDepth
+// under each path and the largest files. The unreadable directories are reported as errors and skipped.
+func analyze(paths []string, maxDepth int, topCount int, cancelFlag task.CancelFlag) (usage diskUsage, err error) {
+	directories := make(map[string]int64)
+	files := &usageHeap{}
+	walked, failed := 0, 0
+	for _, root := range paths {
+		rootInfo, err := os.Lstat(root)
+		if err != nil {
+			usage.errors = append(usage.errors, err.Error())
+			continue
+		}
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if walked++; walked%cancelCheckInterval == 0 && (cancelFlag.Canceled() || cancelFlag.ShutDown()) {
+				return errCanceled
+			}
+			if err != nil {
+				if failed++; failed <= maxReportedErrors {
+					usage.errors = append(usage.errors, err.Error())
+				}
+				return nil
+			}
+			if info.IsDir() {
+				if path != root && !sameVolume(rootInfo, info) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			addToAncestors(directories, root, path, maxDepth, info.Size())
+			heap.Push(files, PathUsage{Path: path, Bytes: info.Size()})
+			if files.Len() > topCount {
+				heap.Pop(files)
+			}
+			return nil
+		})
+		if err == errCanceled {
+			return usage, err
+		}
+	}
+	if failed > maxReportedErrors {
+		usage.errors = append(usage.errors, fmt.Sprintf("%d more paths could not be read", failed-maxReportedErrors))
+	}
+
+	for path, size := range directories {
+		usage.topDirectories = append(usage.topDirectories, PathUsage{Path: path, Bytes: size})
+	}
+	usage.topDirectories = largest(usage.topDirectories, topCount)
+	usage.topFiles = largest(*files, topCount)
+	return usage, nil
+}
+
+// addToAncestors adds the size of a file to its directories under the root, up to maxDepth.
+func addToAncestors(directories map[string]int64, root string, path string, maxDepth int, size int64) {
+	relative, err := filepath.Rel(root, filepath.Dir(path))
+	if err != nil || relative == "." {
+		return
+	}
+	elements := strings.Split(relative, string(filepath.Separator))
+	directory := root
+	for depth := 0; depth < len(elements) && depth < maxDepth; depth++ {
+		directory = filepath.Join(directory, elements[depth])
+		directories[directory] += size
+	}
+}
+
+// largest returns the count largest usages, the largest first.
+func largest(usages []PathUsage, count int) []PathUsage {
+	sorted := append([]PathUsage{}, usages...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Bytes != sorted[j].Bytes {
+			return sorted[i].Bytes > sorted[j].Bytes
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+	if len(sorted) > count {
+		sorted = sorted[:count]
+	}
+	return sorted
+}
+
+// usageHeap is a min-heap of usages, keeping the largest files as the smallest are popped
+type usageHeap []PathUsage
+
+func (h usageHeap) Len() int            { return len(h) }
+func (h usageHeap) Less(i, j int) bool  { return h[i].Bytes < h[j].Bytes }
+func (h usageHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *usageHeap) Push(x interface{}) { *h = append(*h, x.(PathUsage)) }
+func (h *usageHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
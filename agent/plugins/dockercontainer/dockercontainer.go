@@ -16,8 +16,10 @@ package dockercontainer
 import (
 	"errors"
 	"fmt"
+	"os/exec"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
@@ -55,6 +57,19 @@ const (
 var dockerExecCommand = "docker.exe"
 var duration_Seconds time.Duration = 30 * time.Second
 
+// runtimeCommands are the command line tools of the container runtimes, in the order they are looked for
+var runtimeCommands = []struct {
+	runtime string
+	command string
+}{
+	{"docker", "docker"},
+	{"containerd", "nerdctl"},
+	{"podman", "podman"},
+}
+
+// lookPath finds the command line tools of the container runtimes
+var lookPath = exec.LookPath
+
 // Plugin is the type for the plugin.
 type Plugin struct {
 	// ExecuteCommand is an object that can execute commands.
@@ -77,6 +92,9 @@ type DockerContainerPluginInput struct {
 	Env              string
 	User             string
 	Publish          string
+	// Runtime is the container runtime the action is run with, docker, containerd or podman, the first installed by
+	// default
+	Runtime string
 }
 
 // NewPlugin returns a new instance of the plugin.
@@ -139,7 +157,11 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput DockerConta
 		output.MarkAsFailed(err)
 		return
 	}
-	var commandName string = "docker"
+	commandName, err := runtimeCommand(pluginInput.Runtime)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("Validation error, %v", err))
+		return
+	}
 	var commandArguments []string
 	switch pluginInput.Action {
 	case CREATE, RUN:
@@ -305,6 +327,26 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput DockerConta
 	return
 }
 
+// runtimeCommand returns the command line tool of the runtime, or of the first runtime installed when it's not set.
+// The containerd actions are run with nerdctl, whose commands are those of docker.
+func runtimeCommand(runtime string) (string, error) {
+	if runtime != "" {
+		for _, runtimeCommand := range runtimeCommands {
+			if strings.EqualFold(runtime, runtimeCommand.runtime) {
+				return runtimeCommand.command, nil
+			}
+		}
+		return "", fmt.Errorf("Runtime is set to unsupported value: %v", runtime)
+	}
+	for _, runtimeCommand := range runtimeCommands {
+		if _, err := lookPath(runtimeCommand.command); err == nil {
+			return runtimeCommand.command, nil
+		}
+	}
+	// none is installed, docker fails as it did before the other runtimes were supported
+	return "docker", nil
+}
+
 func validateInputs(pluginInput DockerContainerPluginInput) (err error) {
 	validContainerName := regexp.MustCompile(`^[a-zA-Z0-9_\-\\\/]*$`)
 	if !validContainerName.MatchString(pluginInput.Container) {
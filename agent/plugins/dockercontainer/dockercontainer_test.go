@@ -0,0 +1,64 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockercontainer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubLookPath finds the commands given
+func stubLookPath(installed ...string) func(string) (string, error) {
+	return func(command string) (string, error) {
+		for _, name := range installed {
+			if name == command {
+				return "/usr/bin/" + command, nil
+			}
+		}
+		return "", errors.New("executable file not found in $PATH")
+	}
+}
+
+func TestRuntimeCommand(t *testing.T) {
+	lookPathOrig := lookPath
+	defer func() { lookPath = lookPathOrig }()
+	lookPath = stubLookPath("podman")
+
+	for runtime, expected := range map[string]string{"docker": "docker", "Containerd": "nerdctl", "podman": "podman"} {
+		command, err := runtimeCommand(runtime)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, command)
+	}
+	_, err := runtimeCommand("crio")
+	assert.Error(t, err)
+}
+
+func TestRuntimeCommandDetected(t *testing.T) {
+	lookPathOrig := lookPath
+	defer func() { lookPath = lookPathOrig }()
+
+	lookPath = stubLookPath("podman", "nerdctl")
+	command, _ := runtimeCommand("")
+	assert.Equal(t, "nerdctl", command)
+
+	lookPath = stubLookPath("docker", "podman")
+	command, _ = runtimeCommand("")
+	assert.Equal(t, "docker", command)
+
+	lookPath = stubLookPath()
+	command, _ = runtimeCommand("")
+	assert.Equal(t, "docker", command)
+}
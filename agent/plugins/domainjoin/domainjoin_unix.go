@@ -0,0 +1,294 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+// Package domainjoin implements the domainjoin plugin.
+// On Linux, the instance joins the directory with realmd, adcli and SSSD, with the credentials of the seamless
+// domain join secret of the directory.
+package domainjoin
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+)
+
+const (
+	// ExecutionTimeoutInSeconds is the timeout of each command of the domain join, package installs included
+	ExecutionTimeoutInSeconds = 600
+
+	// seamlessDomainJoinSecret is the Parameter Store reference of the Secrets Manager secret holding the account
+	// joining instances to a directory, with the ID of the directory
+	seamlessDomainJoinSecret = "ssm-secure:/aws/reference/secretsmanager/aws/directory-services/%v/seamless-domain-join"
+)
+
+// Makes command as variables, so that we can mock this for unit tests
+var getInstanceContext = instanceContext
+var getJoinCredentials = joinCredentials
+var lookPath = exec.LookPath
+var runCommand = runDomainCommand
+
+// Plugin is the type for the domain join plugin.
+type Plugin struct {
+}
+
+// DomainJoinPluginInput represents one set of commands executed by the Domain join plugin.
+type DomainJoinPluginInput struct {
+	contracts.PluginInput
+	DirectoryId    string
+	DirectoryName  string
+	DirectoryOU    string
+	DnsIpAddresses []string
+}
+
+// joinCredential is the account of the seamless domain join secret
+type joinCredential struct {
+	Username string `json:"awsSeamlessDomainUsername"`
+	Password string `json:"awsSeamlessDomainPassword"`
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the plugin name
+func Name() string {
+	return appconfig.PluginNameDomainJoin
+}
+
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	var properties map[string]interface{}
+	if properties = pluginutil.LoadParametersAsMap(log, config.Properties, output); output.GetExitCode() != 0 {
+		return
+	}
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else {
+		p.runCommandsRawInput(log, properties, output)
+
+		if output.GetStatus() == contracts.ResultStatusFailed {
+			output.AppendInfo("Domain join failed.")
+		} else if output.GetStatus() == contracts.ResultStatusSuccess {
+			output.AppendInfo("Domain join succeeded.")
+		}
+	}
+
+	return
+}
+
+// runCommandsRawInput joins the domain of the input.
+// The input is in the default json unmarshal format (e.g. map[string]interface{}).
+func (p *Plugin) runCommandsRawInput(log log.T, rawPluginInput map[string]interface{}, output iohandler.IOHandler) {
+	var pluginInput DomainJoinPluginInput
+	err := jsonutil.Remarshal(rawPluginInput, &pluginInput)
+	log.Debugf("Plugin input %v", pluginInput)
+
+	if err != nil {
+		errorString := fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", rawPluginInput, err)
+		output.MarkAsFailed(errorString)
+		return
+	}
+	p.runCommands(log, pluginInput, output)
+}
+
+// runCommands installs realmd, adcli and SSSD, joins the domain unless already joined, and configures PAM.
+func (p *Plugin) runCommands(log log.T, pluginInput DomainJoinPluginInput, out iohandler.IOHandler) {
+	if err := validateInput(pluginInput); err != nil {
+		out.MarkAsFailed(fmt.Errorf("Failed to build domain join command because : %v", err.Error()))
+		return
+	}
+
+	instance, err := getInstanceContext(log)
+	if err != nil {
+		out.MarkAsFailed(fmt.Errorf("Error determining Linux variant: %v", err))
+		return
+	}
+	platform, ok := joinPlatforms[instance.Platform]
+	if !ok {
+		out.MarkAsFailed(fmt.Errorf("domain join is not supported on the %v platform", instance.Platform))
+		return
+	}
+	if len(pluginInput.DnsIpAddresses) != 0 {
+		out.AppendInfo("DnsIpAddresses are not applied on Linux, the instance resolves the directory with its DNS servers")
+	}
+
+	out.SetStatus(contracts.ResultStatusInProgress)
+	if joined(log, pluginInput.DirectoryName) {
+		out.AppendInfof("The instance is already joined to %v", pluginInput.DirectoryName)
+		out.MarkAsSucceeded()
+		return
+	}
+	for _, command := range platform.install {
+		if err = runOutputCommand(log, command, out); err != nil {
+			out.MarkAsFailed(err)
+			return
+		}
+	}
+
+	credential, err := getJoinCredentials(log, pluginInput.DirectoryId)
+	if err != nil {
+		out.MarkAsFailed(fmt.Errorf("failed to get the seamless domain join credentials of %v: %v", pluginInput.DirectoryId, err))
+		return
+	}
+	out.AppendInfof("Joining %v as %v", pluginInput.DirectoryName, credential.Username)
+	// realm reads the password from its standard input
+	if err = runCommand(log, credential.Password+"\n", out.GetStdoutWriter(), out.GetStderrWriter(), "realm", joinArguments(pluginInput, credential.Username)...); err != nil {
+		out.MarkAsFailed(fmt.Errorf("Error running realm join: %v", err))
+		return
+	}
+
+	if err = configurePam(log, platform, out); err != nil {
+		out.MarkAsFailed(err)
+		return
+	}
+	for _, service := range platform.services {
+		command := domainCommand{fmt.Sprintf("Starting %v service", service), "systemctl", []string{"enable", "--now", service}}
+		if err = runOutputCommand(log, command, out); err != nil {
+			out.MarkAsFailed(err)
+			return
+		}
+	}
+	out.MarkAsSucceeded()
+}
+
+// validateInput checks the directory is given, and that its name and OU can't be taken for options of realm
+func validateInput(pluginInput DomainJoinPluginInput) error {
+	if len(pluginInput.DirectoryId) == 0 {
+		return fmt.Errorf("directoryId is required")
+	}
+	if len(pluginInput.DirectoryName) == 0 {
+		return fmt.Errorf("directoryName is required")
+	}
+	if strings.HasPrefix(pluginInput.DirectoryName, "-") || strings.ContainsAny(pluginInput.DirectoryName, " \t\r\n") {
+		return fmt.Errorf("directoryName %q is not a valid domain name", pluginInput.DirectoryName)
+	}
+	if strings.HasPrefix(pluginInput.DirectoryOU, "-") {
+		return fmt.Errorf("directoryOU %q is not a valid OU", pluginInput.DirectoryOU)
+	}
+	return nil
+}
+
+// joinArguments returns the arguments of realm joining the domain, in the OU given
+func joinArguments(pluginInput DomainJoinPluginInput, username string) []string {
+	args := []string{"join", "--verbose", "--membership-software=adcli", "--client-software=sssd", "--user=" + username}
+	if len(pluginInput.DirectoryOU) != 0 {
+		args = append(args, "--computer-ou="+pluginInput.DirectoryOU)
+	}
+	return append(args, pluginInput.DirectoryName)
+}
+
+// joined returns whether realm is installed and lists the domain as joined
+func joined(log log.T, directoryName string) bool {
+	if _, err := lookPath("realm"); err != nil {
+		return false
+	}
+	var realms bytes.Buffer
+	if err := runCommand(log, "", &realms, ioutil.Discard, "realm", "list", "--name-only"); err != nil {
+		log.Debugf("realm list failed: %v", err)
+		return false
+	}
+	for _, realm := range strings.Fields(realms.String()) {
+		if strings.EqualFold(realm, directoryName) {
+			return true
+		}
+	}
+	return false
+}
+
+// configurePam runs the first PAM command of the platform found
+func configurePam(log log.T, platform joinPlatform, out iohandler.IOHandler) error {
+	var commands []string
+	for _, command := range platform.pam {
+		if _, err := lookPath(command.cmd); err == nil {
+			return runOutputCommand(log, command, out)
+		}
+		commands = append(commands, command.cmd)
+	}
+	return fmt.Errorf("none of %v is installed to configure PAM", strings.Join(commands, ", "))
+}
+
+// runOutputCommand outputs the message of a command and runs it
+func runOutputCommand(log log.T, command domainCommand, out iohandler.IOHandler) error {
+	out.AppendInfo(command.message)
+	if err := runCommand(log, "", out.GetStdoutWriter(), out.GetStderrWriter(), command.cmd, command.parameters...); err != nil {
+		commandLine := command.cmd + " " + strings.Join(command.parameters, " ")
+		log.Errorf("Error running %v: %v", commandLine, err)
+		return fmt.Errorf("Error running %v: %v", commandLine, err)
+	}
+	return nil
+}
+
+// runDomainCommand runs a command with the text given on its standard input
+func runDomainCommand(log log.T, stdin string, stdout io.Writer, stderr io.Writer, name string, args ...string) error {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), ExecutionTimeoutInSeconds*time.Second)
+	defer cancel()
+	log.Debugf("running %v %v", name, args)
+	command := exec.CommandContext(ctx, name, args...)
+	command.Stdin = strings.NewReader(stdin)
+	command.Stdout = stdout
+	command.Stderr = stderr
+	return command.Run()
+}
+
+// instanceContext returns the platform of the instance
+func instanceContext(log log.T) (*updateutil.InstanceContext, error) {
+	util := updateutil.Utility{}
+	return util.CreateInstanceContext(log)
+}
+
+// joinCredentials returns the account of the seamless domain join secret of the directory
+func joinCredentials(log log.T, directoryID string) (credential joinCredential, err error) {
+	reference := fmt.Sprintf(seamlessDomainJoinSecret, directoryID)
+	service := ssmparameterresolver.NewService()
+	resolved, err := ssmparameterresolver.ResolveParameterReferenceList(&service, log, []string{reference}, ssmparameterresolver.ResolveOptions{})
+	if err != nil {
+		return credential, err
+	}
+	secret, found := resolved[reference]
+	if !found {
+		return credential, fmt.Errorf("%v not found", reference)
+	}
+	if err = json.Unmarshal([]byte(secret.Value), &credential); err != nil {
+		return credential, fmt.Errorf("the secret is not the JSON of awsSeamlessDomainUsername and awsSeamlessDomainPassword")
+	}
+	if credential.Username == "" || credential.Password == "" {
+		return credential, fmt.Errorf("the secret has no awsSeamlessDomainUsername or awsSeamlessDomainPassword")
+	}
+	return credential, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+package domainjoin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testDirectoryName = "corp.example.com"
+	testDirectoryId   = "d-0123456789"
+)
+
+// fakeHost records the commands run and answers them like a host with the commands installed
+type fakeHost struct {
+	platform  string
+	installed []string
+	realms    string
+	failing   string
+	commands  []string
+	stdins    []string
+}
+
+func (h *fakeHost) stub(t *testing.T) func() {
+	savedContext, savedCredentials, savedLookPath, savedRunCommand := getInstanceContext, getJoinCredentials, lookPath, runCommand
+	getInstanceContext = func(log log.T) (*updateutil.InstanceContext, error) {
+		return &updateutil.InstanceContext{Platform: h.platform}, nil
+	}
+	getJoinCredentials = func(log log.T, directoryID string) (joinCredential, error) {
+		assert.Equal(t, testDirectoryId, directoryID)
+		return joinCredential{Username: "awsSeamlessDomainJoinUser", Password: "secret"}, nil
+	}
+	lookPath = func(command string) (string, error) {
+		for _, name := range h.installed {
+			if name == command {
+				return "/usr/bin/" + command, nil
+			}
+		}
+		return "", errors.New("executable file not found in $PATH")
+	}
+	runCommand = func(log log.T, stdin string, stdout io.Writer, stderr io.Writer, name string, args ...string) error {
+		commandLine := strings.Join(append([]string{name}, args...), " ")
+		h.commands = append(h.commands, commandLine)
+		h.stdins = append(h.stdins, stdin)
+		if h.failing != "" && strings.HasPrefix(commandLine, h.failing) {
+			return fmt.Errorf("exit status 1")
+		}
+		if commandLine == "realm list --name-only" {
+			fmt.Fprint(stdout, h.realms)
+		}
+		return nil
+	}
+	return func() {
+		getInstanceContext, getJoinCredentials, lookPath, runCommand = savedContext, savedCredentials, savedLookPath, savedRunCommand
+	}
+}
+
+func joinDomain(input DomainJoinPluginInput) iohandler.IOHandler {
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	p := new(Plugin)
+	p.runCommands(log.NewMockLog(), input, output)
+	return output
+}
+
+func TestJoinRedHat(t *testing.T) {
+	host := &fakeHost{platform: updateutil.PlatformRedHat, installed: []string{"authselect", "authconfig"}}
+	defer host.stub(t)()
+
+	output := joinDomain(DomainJoinPluginInput{DirectoryId: testDirectoryId, DirectoryName: testDirectoryName, DirectoryOU: "OU=Servers,DC=corp,DC=example,DC=com"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Equal(t, []string{
+		"yum install -y realmd sssd sssd-tools adcli krb5-workstation oddjob oddjob-mkhomedir samba-common-tools",
+		"realm join --verbose --membership-software=adcli --client-software=sssd --user=awsSeamlessDomainJoinUser --computer-ou=OU=Servers,DC=corp,DC=example,DC=com corp.example.com",
+		"authselect select sssd with-mkhomedir --force",
+		"systemctl enable --now oddjobd",
+		"systemctl enable --now sssd",
+	}, host.commands)
+	assert.Equal(t, "secret\n", host.stdins[1])
+	assert.NotContains(t, output.GetStdout(), "secret")
+}
+
+func TestJoinPamCommands(t *testing.T) {
+	testCases := []struct {
+		platform  string
+		installed []string
+		pam       string
+	}{
+		{updateutil.PlatformLinux, []string{"authconfig"}, "authconfig --enablesssd --enablesssdauth --enablemkhomedir --update"},
+		{updateutil.PlatformUbuntu, []string{"pam-auth-update"}, "pam-auth-update --enable mkhomedir"},
+		{updateutil.PlatformSuseOS, []string{"pam-config"}, "pam-config --add --sss --mkhomedir"},
+	}
+	for _, testCase := range testCases {
+		host := &fakeHost{platform: testCase.platform, installed: testCase.installed}
+		restore := host.stub(t)
+		output := joinDomain(DomainJoinPluginInput{DirectoryId: testDirectoryId, DirectoryName: testDirectoryName})
+		restore()
+		assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), testCase.platform)
+		assert.Contains(t, host.commands, testCase.pam, testCase.platform)
+	}
+}
+
+func TestJoinAlreadyJoined(t *testing.T) {
+	host := &fakeHost{platform: updateutil.PlatformUbuntu, installed: []string{"realm"}, realms: "CORP.EXAMPLE.COM\n"}
+	defer host.stub(t)()
+
+	output := joinDomain(DomainJoinPluginInput{DirectoryId: testDirectoryId, DirectoryName: testDirectoryName})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Equal(t, []string{"realm list --name-only"}, host.commands)
+	assert.Contains(t, output.GetStdout(), "already joined")
+}
+
+func TestJoinFailed(t *testing.T) {
+	host := &fakeHost{platform: updateutil.PlatformSuseOS, installed: []string{"pam-config"}, failing: "realm join"}
+	defer host.stub(t)()
+
+	output := joinDomain(DomainJoinPluginInput{DirectoryId: testDirectoryId, DirectoryName: testDirectoryName})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.NotContains(t, host.commands, "pam-config --add --sss --mkhomedir")
+}
+
+func TestJoinInvalidInput(t *testing.T) {
+	host := &fakeHost{platform: updateutil.PlatformRedHat}
+	defer host.stub(t)()
+
+	for _, input := range []DomainJoinPluginInput{
+		{DirectoryName: testDirectoryName},
+		{DirectoryId: testDirectoryId},
+		{DirectoryId: testDirectoryId, DirectoryName: "--install=/"},
+		{DirectoryId: testDirectoryId, DirectoryName: testDirectoryName, DirectoryOU: "--unattended"},
+	} {
+		output := joinDomain(input)
+		assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	}
+	assert.Empty(t, host.commands)
+
+	host.platform = updateutil.PlatformRaspbian
+	output := joinDomain(DomainJoinPluginInput{DirectoryId: testDirectoryId, DirectoryName: testDirectoryName})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Empty(t, host.commands)
+}
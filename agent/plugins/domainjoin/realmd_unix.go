@@ -0,0 +1,84 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+package domainjoin
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+)
+
+// domainCommand is a command of the domain join, with the message output before it runs
+type domainCommand struct {
+	message    string
+	cmd        string
+	parameters []string
+}
+
+// joinPlatform is how a Linux platform joins a domain with realmd, adcli and SSSD
+type joinPlatform struct {
+	// install installs realmd, adcli, SSSD and their PAM modules
+	install []domainCommand
+	// pam are the commands enabling SSSD and the creation of the home directories of the domain users in PAM, the
+	// first found runs
+	pam []domainCommand
+	// services are enabled and started once the instance joined
+	services []string
+}
+
+var (
+	// rpmPackages are the packages of the domain join on Amazon Linux, Red Hat and CentOS
+	rpmPackages = []string{"realmd", "sssd", "sssd-tools", "adcli", "krb5-workstation", "oddjob", "oddjob-mkhomedir", "samba-common-tools"}
+
+	// rpmPam configures PAM with authselect since RHEL 8 and Amazon Linux 2023, and authconfig before
+	rpmPam = []domainCommand{
+		{"Enabling SSSD in PAM with authselect", "authselect", []string{"select", "sssd", "with-mkhomedir", "--force"}},
+		{"Enabling SSSD in PAM with authconfig", "authconfig", []string{"--enablesssd", "--enablesssdauth", "--enablemkhomedir", "--update"}},
+	}
+
+	rpmPlatform = joinPlatform{
+		install:  []domainCommand{{"Installing realmd, adcli and SSSD through yum", "yum", append([]string{"install", "-y"}, rpmPackages...)}},
+		pam:      rpmPam,
+		services: []string{"oddjobd", "sssd"},
+	}
+)
+
+// joinPlatforms are the platforms joining domains, by platform name
+var joinPlatforms = map[string]joinPlatform{
+	updateutil.PlatformLinux:  rpmPlatform,
+	updateutil.PlatformRedHat: rpmPlatform,
+	updateutil.PlatformCentOS: rpmPlatform,
+	updateutil.PlatformUbuntu: {
+		install: []domainCommand{
+			{"Update apt package index", "apt-get", []string{"update"}},
+			// krb5-user asks for the default realm unless noninteractive
+			{"Installing realmd, adcli and SSSD through apt", "env", []string{"DEBIAN_FRONTEND=noninteractive", "apt-get", "install", "-y",
+				"realmd", "sssd", "sssd-tools", "adcli", "krb5-user", "libnss-sss", "libpam-sss", "samba-common-bin"}},
+		},
+		pam: []domainCommand{
+			{"Enabling the creation of home directories in PAM", "pam-auth-update", []string{"--enable", "mkhomedir"}},
+		},
+		services: []string{"sssd"},
+	},
+	updateutil.PlatformSuseOS: {
+		install: []domainCommand{
+			{"Installing realmd, adcli and SSSD through zypper", "zypper", []string{"--non-interactive", "install",
+				"realmd", "sssd", "sssd-tools", "sssd-ad", "adcli", "krb5-client", "samba-client"}},
+		},
+		pam: []domainCommand{
+			{"Enabling SSSD in PAM with pam-config", "pam-config", []string{"--add", "--sss", "--mkhomedir"}},
+		},
+		services: []string{"sssd"},
+	},
+}
@@ -0,0 +1,179 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License..
+package downloadcontent
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
+
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// requireChecksumSetting is the plugin setting failing the downloads without checksum
+const requireChecksumSetting = "RequireChecksum"
+
+// sha256Pattern matches a hexadecimal SHA-256
+var sha256Pattern = regexp.MustCompile("^[0-9a-fA-F]{64}$")
+
+// validateChecksums ensures the checksums are SHA-256, and that the download has one when the agent configuration
+// requires it.
+func validateChecksums(input *DownloadContentPlugin, required bool) error {
+	if input.Checksum != "" && len(input.Checksums) > 0 {
+		return fmt.Errorf("only one of Checksum and Checksums can be specified")
+	}
+	if required && input.Checksum == "" && len(input.Checksums) == 0 {
+		return fmt.Errorf("the agent configuration requires the SHA-256 Checksum, or Checksums, of the content downloaded")
+	}
+	checksums := []string{input.Checksum}
+	if input.Checksum == "" {
+		checksums = checksums[:0]
+	}
+	for _, checksum := range input.Checksums {
+		checksums = append(checksums, checksum)
+	}
+	for _, checksum := range checksums {
+		if !sha256Pattern.MatchString(checksum) {
+			return fmt.Errorf("checksum %v must be a hexadecimal SHA-256", checksum)
+		}
+	}
+	return nil
+}
+
+// verifyChecksums verifies the SHA-256 of every file downloaded: the file downloaded matches Checksum, or each file
+// matches the entry of Checksums of its path relative to the destination.
+func verifyChecksums(log log.T, input *DownloadContentPlugin, destinationPath string, result *remoteresource.DownloadResult) error {
+	if input.Checksum == "" && len(input.Checksums) == 0 {
+		return nil
+	}
+	files, err := downloadedFiles(result)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no file was downloaded to verify its checksum")
+	}
+	if input.Checksum != "" && len(files) > 1 {
+		return fmt.Errorf("Checksum verifies a single file but %v were downloaded, specify Checksums by path", len(files))
+	}
+
+	for _, file := range files {
+		expected := input.Checksum
+		if expected == "" {
+			relative, err := filepath.Rel(destinationPath, file)
+			if err != nil {
+				return err
+			}
+			var ok bool
+			if expected, ok = input.Checksums[filepath.ToSlash(relative)]; !ok {
+				return fmt.Errorf("Checksums has no checksum for %v", filepath.ToSlash(relative))
+			}
+		}
+		actual, err := artifact.Sha256HashValue(log, file)
+		if err != nil || actual == "" {
+			return fmt.Errorf("failed to compute the checksum of %v: %v", file, err)
+		}
+		if !strings.EqualFold(actual, expected) {
+			return fmt.Errorf("the SHA-256 of %v is %v, expected %v", file, actual, expected)
+		}
+	}
+	return nil
+}
+
+// downloadedFiles returns the files downloaded, with the files of the directories downloaded except the metadata
+// of their git repository
+func downloadedFiles(result *remoteresource.DownloadResult) (files []string, err error) {
+	if result == nil {
+		return nil, nil
+	}
+	files = append(files, result.Files...)
+	for _, dir := range result.Directories {
+		err = fileutil.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if info.Mode().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// stagingPrefix is the prefix of the directories the content is verified in before it's moved to its destination
+const stagingPrefix = "staging"
+
+// stagedDownload downloads the content to a staging directory under the orchestration directory and verifies its
+// checksums there, then moves it to the destination; the destination is left as it was when the verification fails.
+func (p *Plugin) stagedDownload(log log.T, remoteResource remoteresource.RemoteResource, input *DownloadContentPlugin, orchestrationDir, destinationPath string) error {
+	if err := fileutil.MakeDirs(orchestrationDir); err != nil {
+		return fmt.Errorf("failed to create the orchestration directory %v: %v", orchestrationDir, err)
+	}
+	staging, err := ioutil.TempDir(orchestrationDir, stagingPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to create the staging directory: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(fileutil.LongPath(staging)); err != nil {
+			log.Warnf("failed to remove the staging directory %v: %v", staging, err)
+		}
+	}()
+
+	// the content is staged the way the resource would have written it to the destination: in the staging
+	// directory when the destination is an existing directory, as a file or directory named content otherwise
+	existingDir := false
+	if info, err := os.Stat(fileutil.LongPath(destinationPath)); err == nil && info.IsDir() {
+		existingDir = true
+	}
+	stagedPath := staging
+	if !existingDir {
+		stagedPath = filepath.Join(staging, "content")
+		if os.IsPathSeparator(destinationPath[len(destinationPath)-1]) {
+			stagedPath += string(os.PathSeparator)
+		}
+	}
+
+	err, result := remoteResource.DownloadRemoteResource(log, p.filesys, stagedPath)
+	if err != nil {
+		return err
+	}
+	if err = verifyChecksums(log, input, stagedPath, result); err != nil {
+		return err
+	}
+
+	if existingDir {
+		err = fileutil.CopyTree(staging, destinationPath, fileutil.CopyOptions{})
+	} else if err = fileutil.MakeDirs(filepath.Dir(filepath.Clean(destinationPath))); err == nil {
+		err = fileutil.MoveTree(filepath.Clean(stagedPath), filepath.Clean(destinationPath), fileutil.CopyOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move the content verified to %v: %v", destinationPath, err)
+	}
+	return nil
+}
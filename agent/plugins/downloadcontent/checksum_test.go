@@ -0,0 +1,132 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License..
+package downloadcontent
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
+	"github.com/stretchr/testify/assert"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func sha256Hex(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestValidateChecksums(t *testing.T) {
+	assert.NoError(t, validateChecksums(&DownloadContentPlugin{}, false))
+	assert.Error(t, validateChecksums(&DownloadContentPlugin{}, true), "the agent configuration requires a checksum")
+	assert.NoError(t, validateChecksums(&DownloadContentPlugin{Checksum: sha256Hex("content")}, true))
+	assert.NoError(t, validateChecksums(&DownloadContentPlugin{Checksums: map[string]string{"file": sha256Hex("content")}}, true))
+	assert.Error(t, validateChecksums(&DownloadContentPlugin{Checksum: "d41d8cd98f00b204e9800998ecf8427e"}, false), "MD5")
+	assert.Error(t, validateChecksums(&DownloadContentPlugin{
+		Checksum:  sha256Hex("content"),
+		Checksums: map[string]string{"file": sha256Hex("content")},
+	}, false))
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloadcontent")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	repository := filepath.Join(dir, "repo")
+	os.MkdirAll(filepath.Join(repository, ".git"), 0700)
+	os.MkdirAll(filepath.Join(repository, "scripts"), 0700)
+	ioutil.WriteFile(filepath.Join(repository, ".git", "HEAD"), []byte("ref"), 0600)
+	ioutil.WriteFile(filepath.Join(repository, "scripts", "install.sh"), []byte("install"), 0600)
+	file := filepath.Join(dir, "app.tar.gz")
+	ioutil.WriteFile(file, []byte("content"), 0600)
+
+	fileResult := &remoteresource.DownloadResult{Files: []string{file}}
+	assert.NoError(t, verifyChecksums(logger, &DownloadContentPlugin{}, dir, fileResult))
+	assert.NoError(t, verifyChecksums(logger, &DownloadContentPlugin{Checksum: sha256Hex("content")}, dir, fileResult))
+	assert.Error(t, verifyChecksums(logger, &DownloadContentPlugin{Checksum: sha256Hex("other")}, dir, fileResult))
+	assert.Error(t, verifyChecksums(logger, &DownloadContentPlugin{Checksum: sha256Hex("content")}, dir, &remoteresource.DownloadResult{}))
+
+	// every file of the directories is verified, but the git metadata
+	dirResult := &remoteresource.DownloadResult{Directories: []string{repository}}
+	checksums := map[string]string{"repo/scripts/install.sh": sha256Hex("install")}
+	assert.NoError(t, verifyChecksums(logger, &DownloadContentPlugin{Checksums: checksums}, dir, dirResult))
+	assert.Error(t, verifyChecksums(logger, &DownloadContentPlugin{Checksum: sha256Hex("install")}, dir,
+		&remoteresource.DownloadResult{Files: []string{file}, Directories: []string{repository}}), "a single checksum for several files")
+	assert.Error(t, verifyChecksums(logger, &DownloadContentPlugin{Checksums: checksums}, dir,
+		&remoteresource.DownloadResult{Files: []string{file}, Directories: []string{repository}}), "no checksum for a file")
+}
+
+// fileResource writes a file named app.sh to the destination, or in the destination when it's a directory
+type fileResource struct {
+	content string
+}
+
+func (r fileResource) DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destination string) (error, *remoteresource.DownloadResult) {
+	if info, err := os.Stat(destination); err == nil && info.IsDir() || os.IsPathSeparator(destination[len(destination)-1]) {
+		destination = filepath.Join(destination, "app.sh")
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), 0700); err != nil {
+		return err, nil
+	}
+	if err := ioutil.WriteFile(destination, []byte(r.content), 0600); err != nil {
+		return err, nil
+	}
+	return nil, &remoteresource.DownloadResult{Files: []string{destination}}
+}
+
+func (r fileResource) ValidateLocationInfo() (bool, error) {
+	return true, nil
+}
+
+func TestStagedDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloadcontent")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	orchestrationDir := filepath.Join(dir, "orchestration")
+	p := &Plugin{}
+	input := &DownloadContentPlugin{Checksum: sha256Hex("verified")}
+
+	// a file destination is replaced only by content verified
+	destination := filepath.Join(dir, "scripts", "app.sh")
+	assert.NoError(t, p.stagedDownload(logger, fileResource{content: "verified"}, input, orchestrationDir, destination))
+	content, _ := ioutil.ReadFile(destination)
+	assert.Equal(t, "verified", string(content))
+	assert.Error(t, p.stagedDownload(logger, fileResource{content: "tampered"}, input, orchestrationDir, destination))
+	content, _ = ioutil.ReadFile(destination)
+	assert.Equal(t, "verified", string(content), "the destination is left as it was")
+
+	// the content is moved in an existing directory, along its other files
+	ioutil.WriteFile(filepath.Join(dir, "scripts", "other.sh"), []byte("other"), 0600)
+	os.Remove(destination)
+	assert.NoError(t, p.stagedDownload(logger, fileResource{content: "verified"}, input, orchestrationDir, filepath.Join(dir, "scripts")))
+	content, _ = ioutil.ReadFile(destination)
+	assert.Equal(t, "verified", string(content))
+	_, err = os.Stat(filepath.Join(dir, "scripts", "other.sh"))
+	assert.NoError(t, err)
+
+	// a destination ending with a separator is a directory
+	assert.NoError(t, p.stagedDownload(logger, fileResource{content: "verified"}, input, orchestrationDir, filepath.Join(dir, "new")+string(os.PathSeparator)))
+	_, err = os.Stat(filepath.Join(dir, "new", "app.sh"))
+	assert.NoError(t, err)
+
+	// the staging directories are removed
+	entries, err := ioutil.ReadDir(orchestrationDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -19,13 +19,17 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/gitresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/gitresource/privategithub"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/httpresource"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/ociresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/s3resource"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/sshgitresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/ssmdocresource"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 
@@ -40,11 +44,17 @@ import (
 
 const (
 	GitHub      = "GitHub"      //Github represents the source type "GitHub" from where the resource can be downloaded
+	Git         = "Git"         //Git represents the source type "Git", repositories cloned over SSH
+	HTTP        = "HTTP"        //HTTP represents the source type "HTTP", files downloaded from HTTP and HTTPS servers
+	OCI         = "OCI"         //OCI represents the source type "OCI", artifacts pulled from OCI registries, ECR included
 	S3          = "S3"          //S3 represents the source type "S3" from where the resource is being downloaded
 	SSMDocument = "SSMDocument" //SSMDocument represents the source type as SSM Document
 
 	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
 
+	// allowedSourceTypesSetting is the plugin setting of the source types the instance downloads from, all by default
+	allowedSourceTypesSetting = "AllowedSourceTypes"
+
 	FailExitCode = 1
 	PassExitCode = 0
 )
@@ -70,6 +80,10 @@ type DownloadContentPlugin struct {
 	SourceType      string `json:"sourceType"`
 	SourceInfo      string `json:"sourceInfo"`
 	DestinationPath string `json:"destinationPath"`
+	// Checksum is the SHA-256 of the file downloaded
+	Checksum string `json:"checksum"`
+	// Checksums are the SHA-256 of the files downloaded, by path relative to the destination
+	Checksums map[string]string `json:"checksums"`
 	// TODO: 08/25/2017 meloniam@ Change the type of SourceInfo and documentParameters to map[string]interface{}
 	// TODO: https://amazon.awsapps.com/workdocs/index.html#/document/7d56a42ea5b040a7c33548d77dc98040f0fb380bbbfb2fd580c861225e2ee1c7
 }
@@ -82,6 +96,12 @@ func newRemoteResource(log log.T, SourceType string, SourceInfo string) (resourc
 		// TODO: https://amazon.awsapps.com/workdocs/index.html#/document/7d56a42ea5b040a7c33548d77dc98040f0fb380bbbfb2fd580c861225e2ee1c7
 		token := privategithub.NewTokenInfoImpl()
 		return gitresource.NewGitResource(log, SourceInfo, token)
+	case Git:
+		return sshgitresource.NewSSHGitResource(SourceInfo, privategithub.NewTokenInfoImpl())
+	case HTTP:
+		return httpresource.NewHTTPResource(SourceInfo, privategithub.NewTokenInfoImpl())
+	case OCI:
+		return ociresource.NewOCIResource(SourceInfo, privategithub.NewTokenInfoImpl())
 	case S3:
 		return s3resource.NewS3Resource(log, SourceInfo)
 	case SSMDocument:
@@ -108,13 +128,17 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 		output.MarkAsCancelled()
 	} else if input, err := parseAndValidateInput(config.Properties); err != nil {
 		output.MarkAsFailed(err)
+	} else if err = checkAllowedSourceType(context.AppConfig().PluginSettings(appconfig.PluginDownloadContent), input.SourceType); err != nil {
+		output.MarkAsFailed(err)
+	} else if err = validateChecksums(input, context.AppConfig().PluginSettings(appconfig.PluginDownloadContent).Bool(requireChecksumSetting, false)); err != nil {
+		output.MarkAsFailed(err)
 	} else {
-		p.runCopyContent(log, input, config, output)
+		p.runCopyContent(log, input, config, cancelFlag, output)
 	}
 }
 
 // runCopyContent figures out the type of source, downloads the resource, saves it on disk and returns information required for it
-func (p *Plugin) runCopyContent(log log.T, input *DownloadContentPlugin, config contracts.Configuration, output iohandler.IOHandler) {
+func (p *Plugin) runCopyContent(log log.T, input *DownloadContentPlugin, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 
 	//Run aws:downloadContent plugin
 	log.Debug("Inside run downloadcontent function")
@@ -126,6 +150,12 @@ func (p *Plugin) runCopyContent(log log.T, input *DownloadContentPlugin, config
 		output.MarkAsFailed(err)
 		return
 	}
+	if cancellable, ok := remoteResource.(remoteresource.CancellableResource); ok {
+		cancellable.SetCancelFlag(cancelFlag)
+	}
+	if checksumResource, ok := remoteResource.(remoteresource.ChecksumResource); ok && input.Checksum != "" {
+		checksumResource.SetChecksum(input.Checksum)
+	}
 	var destinationPath string
 
 	// If path is absolute, then download to the path,
@@ -146,7 +176,12 @@ func (p *Plugin) runCopyContent(log log.T, input *DownloadContentPlugin, config
 		return
 	}
 	log.Debug("Downloading resource")
-	if err, _ = remoteResource.DownloadRemoteResource(log, p.filesys, destinationPath); err != nil {
+	if input.Checksum != "" || len(input.Checksums) > 0 {
+		err = p.stagedDownload(log, remoteResource, input, config.OrchestrationDirectory, destinationPath)
+	} else {
+		err, _ = remoteResource.DownloadRemoteResource(log, p.filesys, destinationPath)
+	}
+	if err != nil {
 		output.MarkAsFailed(err)
 		return
 	}
@@ -188,7 +223,7 @@ func validateInput(input *DownloadContentPlugin) (valid bool, err error) {
 		return false, errors.New("SourceType must be specified")
 	}
 	//ensure all entries are valid
-	if input.SourceType != GitHub && input.SourceType != S3 && input.SourceType != SSMDocument {
+	if input.SourceType != GitHub && input.SourceType != Git && input.SourceType != HTTP && input.SourceType != OCI && input.SourceType != S3 && input.SourceType != SSMDocument {
 		return false, errors.New("Unsupported source type")
 	}
 	// ensure non-empty source info
@@ -198,15 +233,30 @@ func validateInput(input *DownloadContentPlugin) (valid bool, err error) {
 	return true, nil
 }
 
+// checkAllowedSourceType ensures the agent configuration allows downloading from the source type.
+func checkAllowedSourceType(settings appconfig.PluginSettings, sourceType string) error {
+	allowed := settings.StringList(allowedSourceTypesSetting, nil)
+	if allowed == nil {
+		return nil
+	}
+	for _, allowedType := range allowed {
+		if strings.EqualFold(allowedType, sourceType) {
+			return nil
+		}
+	}
+	return fmt.Errorf("SourceType %v is not allowed by the agent configuration, allowed source types: %v",
+		sourceType, strings.Join(allowed, ", "))
+}
+
 // SetFilePermissions applies execute permissions to the folder
 func SetFilePermissions(log log.T, workingDir string) error {
 
 	var permissionsWalk = func(path string, info os.FileInfo, e error) (err error) {
 		log.Info("Changing permissions for ", path)
-		return os.Chmod(path, appconfig.ReadWriteExecuteAccess)
+		return os.Chmod(fileutil.LongPath(path), appconfig.ReadWriteExecuteAccess)
 	}
 
-	err := filepath.Walk(workingDir, permissionsWalk)
+	err := fileutil.Walk(workingDir, permissionsWalk)
 	if err != nil {
 		log.Errorf("Error while changing the permissions of files - %v", err.Error())
 	}
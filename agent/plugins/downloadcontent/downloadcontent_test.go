@@ -16,6 +16,7 @@
 package downloadcontent
 
 import (
+	"os"
 	"testing"
 
 	"time"
@@ -27,6 +28,7 @@ import (
 	filemock "github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
 	resourcemock "github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource/mock"
 	"github.com/aws/amazon-ssm-agent/agent/task"
@@ -39,6 +41,13 @@ var copyContentFileMock = filemock.FileSystemMock{}
 var copyContentResourceMock = resourcemock.RemoteResourceMock{}
 var contextMock = context.NewMockDefault()
 
+func TestMain(m *testing.M) {
+	// the remote resources create their clients with the identity of the instance, don't look it up
+	platform.SetInstanceID("i-1234567890")
+	platform.SetRegion("us-east-1")
+	os.Exit(m.Run())
+}
+
 func TestNewRemoteResource_InvalidLocationType(t *testing.T) {
 
 	var mockLocationInfo string
@@ -107,7 +116,7 @@ func TestNewPlugin_RunCopyContent(t *testing.T) {
 	mockIOHandler.On("MarkAsSucceeded").Return()
 
 	SetPermission = stubChmod
-	p.runCopyContent(logger, &input, config, mockIOHandler)
+	p.runCopyContent(logger, &input, config, task.NewChanneledCancelFlag(), mockIOHandler)
 
 	copyContentResourceMock.AssertExpectations(t)
 	fileMock.AssertExpectations(t)
@@ -133,7 +142,7 @@ func TestNewPlugin_RunCopyContent_absPathDestinationDir(t *testing.T) {
 	mockIOHandler.On("MarkAsSucceeded").Return()
 
 	SetPermission = stubChmod
-	p.runCopyContent(logger, &input, config, mockIOHandler)
+	p.runCopyContent(logger, &input, config, task.NewChanneledCancelFlag(), mockIOHandler)
 
 	copyContentResourceMock.AssertExpectations(t)
 	fileMock.AssertExpectations(t)
@@ -158,7 +167,7 @@ func TestNewPlugin_RunCopyContent_relativeDirDestinationPath(t *testing.T) {
 	mockIOHandler.On("MarkAsSucceeded").Return()
 
 	SetPermission = stubChmod
-	p.runCopyContent(logger, &input, config, mockIOHandler)
+	p.runCopyContent(logger, &input, config, task.NewChanneledCancelFlag(), mockIOHandler)
 
 	copyContentResourceMock.AssertExpectations(t)
 	fileMock.AssertExpectations(t)
@@ -188,7 +197,7 @@ func Test_RunCopyContentBadLocationInfo(t *testing.T) {
 	}
 	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
 
-	p.runCopyContent(logger, &input, config, mockIOHandler)
+	p.runCopyContent(logger, &input, config, task.NewChanneledCancelFlag(), mockIOHandler)
 
 	fileMock.AssertExpectations(t)
 	mockIOHandler.AssertExpectations(t)
@@ -365,6 +374,19 @@ func TestValidateInput_UnsupportedLocationType(t *testing.T) {
 	assert.Contains(t, err.Error(), "Unsupported source type")
 }
 
+func TestValidateInput_Git(t *testing.T) {
+
+	input := DownloadContentPlugin{
+		SourceType: Git,
+		SourceInfo: `{"repository": "ssh://git@git.example.com/org/repo.git"}`,
+	}
+
+	result, err := validateInput(&input)
+
+	assert.True(t, result)
+	assert.NoError(t, err)
+}
+
 func TestValidateInput_UnknownSourceType(t *testing.T) {
 
 	input := DownloadContentPlugin{}
@@ -475,3 +497,14 @@ func createMockCancelFlag() task.CancelFlag {
 func stubChmod(log log.T, workingDir string) error {
 	return nil
 }
+
+func TestCheckAllowedSourceType(t *testing.T) {
+	assert.NoError(t, checkAllowedSourceType(nil, GitHub), "all source types are allowed by default")
+
+	settings := map[string]interface{}{"AllowedSourceTypes": []interface{}{"s3", "SSMDocument"}}
+	assert.NoError(t, checkAllowedSourceType(settings, S3))
+	assert.NoError(t, checkAllowedSourceType(settings, SSMDocument))
+	err := checkAllowedSourceType(settings, GitHub)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not allowed by the agent configuration")
+}
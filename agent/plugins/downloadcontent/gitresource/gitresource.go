@@ -147,6 +147,7 @@ func (git *GitResource) download(log log.T, filesys filemanager.FileSystem, info
 			log.Errorf("Error obtaining file content from GitHub file - %v, %v", fileMetadata.GetPath(), err)
 			return err
 		}
+		result.Files = append(result.Files, destinationDir)
 	} else {
 		return fmt.Errorf("Could not download from GitHub repository")
 	}
@@ -31,6 +31,10 @@ const (
 	ssmSecurePrefix = "ssm-secure:"
 )
 
+// Regex to check the pattern of the secure parameter required.
+// pattern must be equal to {{ ssm-secure:parameter-name }}
+var ssmSecureStringPattern = regexp.MustCompile("{{\\s*(" + ssmSecurePrefix + "[\\w-/]+)\\s*}}")
+
 type PrivateGithubAccess interface {
 	GetOAuthClient(log log.T, token string) (*http.Client, error)
 }
@@ -53,17 +57,47 @@ func (t TokenInfoImpl) GetOAuthClient(log log.T, tokenInfo string) (client *http
 		return nil, err
 	}
 
-	var tokenVal ssmparameterresolver.SsmParameterInfo
-	var tokenMap map[string]ssmparameterresolver.SsmParameterInfo
+	tokenVal, err := t.resolveParameter(log, tokenInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validating to check if the parameter obtained is a secure string
+	if tokenVal.Type != parameterstore.ParamTypeSecureString {
+		return nil, fmt.Errorf("token-parameter-name %v must be of secure string type, Current type - %v", tokenVal.Name, tokenVal.Type)
+	}
+	return t.gitoauthclient.GetGithubOauthClient(tokenVal.Value), nil
+}
+
+// GetSecureParameter returns the value of the SecureString parameter referenced as {{ ssm-secure:parameter-name }},
+// for the sources authenticating with other credentials than a GitHub token.
+// NOTE: Do not log the value
+func (t TokenInfoImpl) GetSecureParameter(log log.T, reference string) (string, error) {
+	if !ssmSecureStringPattern.MatchString(reference) {
+		return "", fmt.Errorf("Format of the ssm parameter %v is incorrect. Please specify parameter as '{{ ssm-secure:parameter-name }}'", reference)
+	}
+	parameter, err := t.resolveParameter(log, reference)
+	if err != nil {
+		return "", err
+	}
+	if parameter.Type != parameterstore.ParamTypeSecureString {
+		return "", fmt.Errorf("parameter %v must be of secure string type, Current type - %v", parameter.Name, parameter.Type)
+	}
+	return parameter.Value, nil
+}
+
+// resolveParameter gets the parameter referenced from parameter store
+func (t TokenInfoImpl) resolveParameter(log log.T, reference string) (parameter ssmparameterresolver.SsmParameterInfo, err error) {
+	var parameterMap map[string]ssmparameterresolver.SsmParameterInfo
 	var parameterReferences []string
 
 	// Regex to extract the contents of the parameter from within {{ }} to get parameter value
 	// for. e.g. {{ ssm-secure:parameter-name }} will extract ssm-secure:parameter-name
-	subParam := regexp.MustCompile(`\{\{(.*?)\}\}`).FindStringSubmatch(tokenInfo)
+	subParam := regexp.MustCompile(`\{\{(.*?)\}\}`).FindStringSubmatch(reference)
 	if len(subParam) > 1 {
 		parameterReferences = []string{subParam[1]}
 	} else {
-		return client, errors.New("Something went wrong when trying to extract ssm-secure parameter")
+		return parameter, errors.New("Something went wrong when trying to extract ssm-secure parameter")
 	}
 
 	resolverOptions := ssmparameterresolver.ResolveOptions{
@@ -72,25 +106,20 @@ func (t TokenInfoImpl) GetOAuthClient(log log.T, tokenInfo string) (client *http
 
 	// Get the parameter value from parameter store.
 	// NOTE: Do not log the parameter value
-	if tokenMap, err = t.SsmParameter(log, &t.paramAccess, parameterReferences, resolverOptions); err != nil {
-		return nil, fmt.Errorf("Could not resolve ssm parameter - %v. Error - %v", parameterReferences, err)
+	if parameterMap, err = t.SsmParameter(log, &t.paramAccess, parameterReferences, resolverOptions); err != nil {
+		return parameter, fmt.Errorf("Could not resolve ssm parameter - %v. Error - %v", parameterReferences, err)
 	}
 
-	// Parameter output must be of size 1. Any other number of tokens returned can lead to undesired behavior
-	if len(tokenMap) != 1 {
-		return nil, fmt.Errorf("Invalid number of tokens returned - %v", len(tokenMap))
+	// Parameter output must be of size 1. Any other number of parameters returned can lead to undesired behavior
+	if len(parameterMap) != 1 {
+		return parameter, fmt.Errorf("Invalid number of tokens returned - %v", len(parameterMap))
 	}
 
-	//Extracting single value of token contained within tokenMap
-	for _, token := range tokenMap {
-		tokenVal = token
+	//Extracting single value of parameter contained within parameterMap
+	for _, value := range parameterMap {
+		parameter = value
 	}
-
-	// Validating to check if the parameter obtained is a secure string
-	if tokenVal.Type != parameterstore.ParamTypeSecureString {
-		return nil, fmt.Errorf("token-parameter-name %v must be of secure string type, Current type - %v", tokenVal.Name, tokenVal.Type)
-	}
-	return t.gitoauthclient.GetGithubOauthClient(tokenVal.Value), nil
+	return parameter, nil
 }
 
 func getSSMParameter(log log.T, paramService ssmparameterresolver.ISsmParameterService, parameterReferences []string,
@@ -102,9 +131,6 @@ func getSSMParameter(log log.T, paramService ssmparameterresolver.ISsmParameterS
 // validateTokenParameter validates the format of tokenInfo
 func validateTokenParameter(tokenInfo string) (valid bool, err error) {
 
-	// Regex to check the pattern of the secure parameter required.
-	// pattern must be equal to {{ ssm-secure:parameter-name }}
-	var ssmSecureStringPattern = regexp.MustCompile("{{\\s*(" + ssmSecurePrefix + "[\\w-/]+)\\s*}}")
 	if ssmSecureStringPattern.Match([]byte(tokenInfo)) {
 		return true, nil
 	}
@@ -122,3 +122,20 @@ func getMockedSecureParam(log log.T, paramService ssmparameterresolver.ISsmParam
 
 	return info, nil
 }
+
+func TestTokenInfoImpl_GetSecureParameter(t *testing.T) {
+	tokenInfo := TokenInfoImpl{
+		SsmParameter: getMockedSecureParam,
+	}
+
+	value, err := tokenInfo.GetSecureParameter(logMock, `{{ ssm-secure:dummysecureparam }}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "lskjksjgshfg1234jdskjhgvs", value)
+
+	_, err = tokenInfo.GetSecureParameter(logMock, `dummysecureparam`)
+	assert.Error(t, err)
+
+	tokenInfo.SsmParameter = getMockedParam
+	_, err = tokenInfo.GetSecureParameter(logMock, `{{ ssm-secure:dummysecureparam }}`)
+	assert.EqualError(t, err, "parameter dummysecureparam must be of secure string type, Current type - String")
+}
@@ -0,0 +1,230 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Package httpresource implements the methods to access resources from HTTP and HTTPS servers
+package httpresource
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
+
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// RedirectsFollow follows the redirects, the default
+	RedirectsFollow = "follow"
+	// RedirectsSameHost follows the redirects to the host of the URL only
+	RedirectsSameHost = "sameHost"
+	// RedirectsNone fails the downloads redirected
+	RedirectsNone = "none"
+
+	// defaultFileName is the name of the file downloaded from a URL without file name
+	defaultFileName = "download"
+)
+
+// cacheRoot is the directory of the files downloaded, which are downloaded again only when they changed
+var cacheRoot = filepath.Join(appconfig.DownloadRoot, "http")
+
+// download downloads the file, unless the file in the destination directory is up to date
+var download = artifact.Download
+
+// secureParameters resolves the SecureString parameters holding the credentials
+type secureParameters interface {
+	GetSecureParameter(log log.T, reference string) (string, error)
+}
+
+// HTTPResource is a struct for the remote resource of type HTTP
+type HTTPResource struct {
+	Info     HTTPInfo
+	secrets  secureParameters
+	checksum string
+}
+
+// HTTPInfo represents the sourceInfo type sent by runcommand
+type HTTPInfo struct {
+	URL string `json:"url"`
+	// Headers are sent with the requests, a value can reference a SecureString parameter as
+	// {{ ssm-secure:parameter-name }}
+	Headers map[string]string `json:"headers"`
+	// Username and Password are sent as basic authentication, Password references a SecureString parameter
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Redirects is RedirectsFollow, RedirectsSameHost or RedirectsNone
+	Redirects    string `json:"redirects"`
+	MaxRedirects int    `json:"maxRedirects"`
+}
+
+// NewHTTPResource is a constructor of type HTTPResource
+func NewHTTPResource(info string, secrets secureParameters) (*HTTPResource, error) {
+	var httpInfo HTTPInfo
+	if err := jsonutil.Unmarshal(info, &httpInfo); err != nil {
+		return nil, fmt.Errorf("Source Info could not be unmarshalled for source type HTTP. Please check JSON format of sourceInfo - %v", err.Error())
+	}
+	httpInfo.URL = strings.TrimSpace(httpInfo.URL)
+	return &HTTPResource{
+		Info:    httpInfo,
+		secrets: secrets,
+	}, nil
+}
+
+// SetChecksum makes the download fail when the SHA-256 of the file doesn't match, see remoteresource.ChecksumResource
+func (h *HTTPResource) SetChecksum(checksum string) {
+	h.checksum = checksum
+}
+
+// DownloadRemoteResource downloads the file to the destination, or in the destination when it's a directory. The
+// file is kept in the download cache and downloaded again only when its ETag or Last-Modified date changed.
+func (h *HTTPResource) DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destinationPath string) (err error, result *remoteresource.DownloadResult) {
+	if destinationPath == "" {
+		destinationPath = appconfig.DownloadRoot
+	}
+	headers, err := h.headers(log)
+	if err != nil {
+		return err, nil
+	}
+
+	input := artifact.DownloadInput{
+		SourceURL:            h.Info.URL,
+		DestinationDirectory: filepath.Join(cacheRoot, h.cacheKey()),
+		Headers:              headers,
+		Redirects: artifact.RedirectPolicy{
+			Disabled:     h.Info.Redirects == RedirectsNone,
+			SameHost:     h.Info.Redirects == RedirectsSameHost,
+			MaxRedirects: h.Info.MaxRedirects,
+		},
+	}
+	if h.checksum != "" {
+		input.SourceChecksums = map[string]string{artifact.HashTypeSha256: h.checksum}
+	}
+	log.Infof("Downloading %v", h.Info.URL)
+	output, err := download(log, input)
+	if err != nil {
+		return fmt.Errorf("failed to download %v: %v", h.Info.URL, err), nil
+	}
+	if !output.IsUpdated {
+		log.Infof("%v didn't change since it was downloaded", h.Info.URL)
+	}
+
+	destinationFile := destinationPath
+	if filesys.Exists(destinationPath) && filesys.IsDirectory(destinationPath) || os.IsPathSeparator(destinationPath[len(destinationPath)-1]) {
+		destinationFile = filepath.Join(destinationPath, h.fileName())
+	}
+	if err = filesys.MakeDirs(filepath.Dir(destinationFile)); err != nil {
+		return err, nil
+	}
+	if err = fileutil.CopyFile(output.LocalFilePath, destinationFile, appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("failed to copy the download to %v: %v", destinationFile, err), nil
+	}
+	return nil, &remoteresource.DownloadResult{Files: []string{destinationFile}}
+}
+
+// headers returns the headers of the requests, with the values of the parameters they reference.
+// NOTE: Do not log the headers
+func (h *HTTPResource) headers(log log.T) (http.Header, error) {
+	headers := http.Header{}
+	for name, value := range h.Info.Headers {
+		if strings.Contains(value, "{{") {
+			secret, err := h.secrets.GetSecureParameter(log, value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the value of the header %v: %v", name, err)
+			}
+			value = secret
+		}
+		headers.Set(name, value)
+	}
+	if h.Info.Username != "" {
+		password, err := h.secrets.GetSecureParameter(log, h.Info.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the password of %v: %v", h.Info.Username, err)
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(h.Info.Username + ":" + password))
+		headers.Set("Authorization", "Basic "+credentials)
+	}
+	return headers, nil
+}
+
+// cacheKey identifies the cache of the downloads with the same credentials, so that the downloads with other
+// credentials don't get the files the server sent for them
+func (h *HTTPResource) cacheKey() string {
+	lines := []string{h.Info.Username, h.Info.Password}
+	for name, value := range h.Info.Headers {
+		lines = append(lines, http.CanonicalHeaderKey(name)+": "+value)
+	}
+	sort.Strings(lines[2:])
+	hash := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(hash[:8])
+}
+
+// fileName returns the name of the file of the URL
+func (h *HTTPResource) fileName() string {
+	fileURL, _ := url.Parse(h.Info.URL)
+	if name := path.Base(fileURL.Path); name != "." && name != "/" {
+		return name
+	}
+	return defaultFileName
+}
+
+// ValidateLocationInfo ensures that the required parameters of SourceInfo are specified
+func (h *HTTPResource) ValidateLocationInfo() (valid bool, err error) {
+	if h.Info.URL == "" {
+		return false, errors.New("URL for HTTP SourceType must be specified")
+	}
+	fileURL, err := url.Parse(h.Info.URL)
+	if err != nil || (fileURL.Scheme != "http" && fileURL.Scheme != "https") || fileURL.Host == "" {
+		return false, fmt.Errorf("URL %v for HTTP SourceType must be an http:// or https:// URL", h.Info.URL)
+	}
+	if fileURL.User != nil {
+		return false, errors.New("the URL for HTTP SourceType can't hold credentials, specify Username and Password")
+	}
+	hasCredentials := h.Info.Username != "" || h.Info.Password != "" || len(h.Info.Headers) > 0
+	if hasCredentials && fileURL.Scheme != "https" {
+		return false, errors.New("Headers, Username and Password for HTTP SourceType are only sent to https:// URLs")
+	}
+	if (h.Info.Username == "") != (h.Info.Password == "") {
+		return false, errors.New("Username and Password for HTTP SourceType must be specified together")
+	}
+	if strings.Contains(h.Info.Username, ":") {
+		return false, errors.New("Username for HTTP SourceType can't contain ':'")
+	}
+	for name := range h.Info.Headers {
+		if name == "" || strings.ContainsAny(name, ": \t\r\n") {
+			return false, fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	switch h.Info.Redirects {
+	case "", RedirectsFollow, RedirectsSameHost, RedirectsNone:
+	default:
+		return false, fmt.Errorf("Redirects for HTTP SourceType must be %v, %v or %v", RedirectsFollow, RedirectsSameHost, RedirectsNone)
+	}
+	if h.Info.MaxRedirects < 0 {
+		return false, errors.New("MaxRedirects for HTTP SourceType must be positive")
+	}
+	return true, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Package ociresource implements the methods to access artifacts published to OCI registries
+package ociresource
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
+
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// titleAnnotation is the annotation of the layers naming their file, as set by oras
+	titleAnnotation = "org.opencontainers.image.title"
+
+	defaultTag = "latest"
+)
+
+var (
+	// repositoryPattern matches the repositories of the OCI distribution specification
+	repositoryPattern = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*$`)
+	tagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	digestPattern     = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+)
+
+// secureParameters resolves the SecureString parameter holding the password of the registry
+type secureParameters interface {
+	GetSecureParameter(log log.T, reference string) (string, error)
+}
+
+// OCIResource is a struct for the remote resource of type OCI
+type OCIResource struct {
+	Info    OCIInfo
+	secrets secureParameters
+}
+
+// OCIInfo represents the sourceInfo type sent by runcommand
+type OCIInfo struct {
+	// Reference names the artifact, as registry/repository:tag or registry/repository@sha256:digest
+	Reference string `json:"reference"`
+	// Username and Password authenticate with the registries other than ECR, Password references a SecureString
+	// parameter as {{ ssm-secure:parameter-name }}
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// reference is a parsed artifact reference
+type reference struct {
+	registry   string
+	repository string
+	// tag or digest of the manifest
+	tag    string
+	digest string
+}
+
+// NewOCIResource is a constructor of type OCIResource
+func NewOCIResource(info string, secrets secureParameters) (*OCIResource, error) {
+	var ociInfo OCIInfo
+	if err := jsonutil.Unmarshal(info, &ociInfo); err != nil {
+		return nil, fmt.Errorf("Source Info could not be unmarshalled for source type OCI. Please check JSON format of sourceInfo - %v", err.Error())
+	}
+	ociInfo.Reference = strings.TrimSpace(ociInfo.Reference)
+	return &OCIResource{
+		Info:    ociInfo,
+		secrets: secrets,
+	}, nil
+}
+
+// parseReference parses registry/repository:tag and registry/repository@sha256:digest
+func parseReference(value string) (ref reference, err error) {
+	slash := strings.Index(value, "/")
+	if slash <= 0 {
+		return ref, fmt.Errorf("reference %v must start with the registry, e.g. 123456789012.dkr.ecr.us-east-1.amazonaws.com/repository:tag", value)
+	}
+	ref.registry, value = value[:slash], value[slash+1:]
+	if at := strings.Index(value, "@"); at >= 0 {
+		value, ref.digest = value[:at], value[at+1:]
+		if !digestPattern.MatchString(ref.digest) {
+			return ref, fmt.Errorf("digest %v must be sha256: followed by the hexadecimal SHA-256", ref.digest)
+		}
+	} else if colon := strings.LastIndex(value, ":"); colon >= 0 {
+		value, ref.tag = value[:colon], value[colon+1:]
+		if !tagPattern.MatchString(ref.tag) {
+			return ref, fmt.Errorf("invalid tag %v", ref.tag)
+		}
+	} else {
+		ref.tag = defaultTag
+	}
+	ref.repository = value
+	if !repositoryPattern.MatchString(ref.repository) {
+		return ref, fmt.Errorf("invalid repository %v", ref.repository)
+	}
+	return ref, nil
+}
+
+// DownloadRemoteResource downloads the layers of the artifact, each to the file of its title. The artifact of a
+// single layer is downloaded to the destination unless it's a directory.
+func (oci *OCIResource) DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destinationPath string) (err error, result *remoteresource.DownloadResult) {
+	if destinationPath == "" {
+		destinationPath = appconfig.DownloadRoot
+	}
+	ref, err := parseReference(oci.Info.Reference)
+	if err != nil {
+		return err, nil
+	}
+	client := &registryClient{registry: ref.registry, repository: ref.repository, username: oci.Info.Username}
+	if oci.Info.Username != "" {
+		if client.password, err = oci.secrets.GetSecureParameter(log, oci.Info.Password); err != nil {
+			return fmt.Errorf("failed to get the password of %v: %v", oci.Info.Username, err), nil
+		}
+	}
+
+	log.Infof("Downloading the manifest of %v", oci.Info.Reference)
+	manifest, err := client.manifest(log, ref)
+	if err != nil {
+		return err, nil
+	}
+	if len(manifest.Layers) == 0 {
+		return fmt.Errorf("the artifact %v has no layers", oci.Info.Reference), nil
+	}
+
+	isDirectory := len(manifest.Layers) > 1 || os.IsPathSeparator(destinationPath[len(destinationPath)-1]) ||
+		filesys.Exists(destinationPath) && filesys.IsDirectory(destinationPath)
+	directory := destinationPath
+	if !isDirectory {
+		directory = filepath.Dir(destinationPath)
+	}
+	if err = filesys.MakeDirs(directory); err != nil {
+		return fmt.Errorf("failed to create the directory %v: %v", directory, err), nil
+	}
+
+	result = &remoteresource.DownloadResult{}
+	names := map[string]bool{}
+	for _, layer := range manifest.Layers {
+		name := layerFileName(layer)
+		if names[name] {
+			return fmt.Errorf("several layers of the artifact are named %v", name), nil
+		}
+		names[name] = true
+
+		file := destinationPath
+		if isDirectory {
+			file = filepath.Join(directory, name)
+		}
+		log.Infof("Downloading the layer %v to %v", layer.Digest, file)
+		if err = client.downloadBlob(log, layer, file); err != nil {
+			return err, nil
+		}
+		result.Files = append(result.Files, file)
+	}
+	return nil, result
+}
+
+// layerFileName returns the file name of the title annotation of the layer, the hexadecimal digest without title
+func layerFileName(layer descriptor) string {
+	name := layer.Annotations[titleAnnotation]
+	// the title names a file of the destination, not a path
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return strings.TrimPrefix(layer.Digest, "sha256:")
+	}
+	return name
+}
+
+// ValidateLocationInfo ensures that the required parameters of SourceInfo are specified
+func (oci *OCIResource) ValidateLocationInfo() (valid bool, err error) {
+	if oci.Info.Reference == "" {
+		return false, errors.New("Reference for OCI SourceType must be specified")
+	}
+	if _, err = parseReference(oci.Info.Reference); err != nil {
+		return false, err
+	}
+	if (oci.Info.Username == "") != (oci.Info.Password == "") {
+		return false, errors.New("Username and Password for OCI SourceType must be specified together")
+	}
+	return true, nil
+}
@@ -0,0 +1,235 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package ociresource
+
+import (
+	filemock "github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var logMock = log.NewMockLog()
+
+type secretsMock struct{}
+
+func (secretsMock) GetSecureParameter(log log.T, reference string) (string, error) {
+	if reference == "{{ ssm-secure:registry-password }}" {
+		return "password", nil
+	}
+	return "", errors.New("parameter not found")
+}
+
+// registryServer serves the manifest and the blobs of an artifact, to the clients with the token of its token service
+type registryServer struct {
+	*httptest.Server
+	manifest []byte
+	blobs    map[string][]byte
+	// authorization is the Authorization the registry expects, the token of the token service when empty
+	authorization string
+	tokenRequests []*http.Request
+}
+
+func newRegistryServer(t *testing.T, layers map[string]string) *registryServer {
+	s := &registryServer{blobs: map[string][]byte{}}
+	m := manifest{MediaType: mediaTypeOCIManifest}
+	for title, content := range layers {
+		digest := sha256Digest([]byte(content))
+		s.blobs[digest] = []byte(content)
+		m.Layers = append(m.Layers, descriptor{
+			MediaType:   "application/vnd.example.config.v1",
+			Digest:      digest,
+			Size:        int64(len(content)),
+			Annotations: map[string]string{titleAnnotation: title},
+		})
+	}
+	s.manifest, _ = json.Marshal(m)
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serve))
+
+	savedClient := httpClient
+	httpClient = s.Client()
+	t.Cleanup(func() {
+		httpClient = savedClient
+		s.Close()
+	})
+	return s
+}
+
+func (s *registryServer) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		s.tokenRequests = append(s.tokenRequests, r)
+		fmt.Fprint(w, `{"token": "pull-token"}`)
+		return
+	}
+	expected := s.authorization
+	if expected == "" {
+		expected = "Bearer pull-token"
+	}
+	if r.Header.Get("Authorization") != expected {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%v/token",service="registry.example.com"`, s.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v2/config/bundles/manifests/"):
+		w.Header().Set("Content-Type", mediaTypeOCIManifest)
+		w.Write(s.manifest)
+	case strings.HasPrefix(r.URL.Path, "/v2/config/bundles/blobs/"):
+		blob, ok := s.blobs[strings.TrimPrefix(r.URL.Path, "/v2/config/bundles/blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write(blob)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *registryServer) host() string {
+	return strings.TrimPrefix(s.URL, "https://")
+}
+
+func newDirectoryMock(dir string) *filemock.FileSystemMock {
+	fileMock := &filemock.FileSystemMock{}
+	fileMock.On("Exists", dir).Return(true)
+	fileMock.On("IsDirectory", dir).Return(true)
+	fileMock.On("MakeDirs", dir).Return(nil)
+	return fileMock
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "ociresource")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestDownloadArtifactWithToken(t *testing.T) {
+	server := newRegistryServer(t, map[string]string{"app.json": `{"debug": false}`, "../../etc/passwd": "root"})
+	dir := tempDir(t)
+
+	resource, err := NewOCIResource(fmt.Sprintf(`{"reference": "%v/config/bundles:v3", "username": "deploy",
+		"password": "{{ ssm-secure:registry-password }}"}`, server.host()), secretsMock{})
+	assert.NoError(t, err)
+	valid, err := resource.ValidateLocationInfo()
+	assert.True(t, valid)
+	assert.NoError(t, err)
+
+	err, result := resource.DownloadRemoteResource(logMock, newDirectoryMock(dir), dir)
+	assert.NoError(t, err)
+	assert.Len(t, result.Files, 2)
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "app.json"))
+	assert.Equal(t, `{"debug": false}`, string(content))
+	// the layer titled with a path is named after its digest
+	content, _ = ioutil.ReadFile(filepath.Join(dir, strings.TrimPrefix(sha256Digest([]byte("root")), "sha256:")))
+	assert.Equal(t, "root", string(content))
+
+	if assert.Len(t, server.tokenRequests, 1) {
+		request := server.tokenRequests[0]
+		assert.Equal(t, "repository:config/bundles:pull", request.URL.Query().Get("scope"))
+		assert.Equal(t, "registry.example.com", request.URL.Query().Get("service"))
+		username, password, _ := request.BasicAuth()
+		assert.Equal(t, "deploy", username)
+		assert.Equal(t, "password", password)
+	}
+}
+
+func TestDownloadArtifactFromECR(t *testing.T) {
+	server := newRegistryServer(t, map[string]string{"app.json": "{}"})
+	server.authorization = "Basic QVdTOnRva2Vu"
+	// the requests to the ECR registry are sent to the server
+	transport := server.Client().Transport
+	httpClient = &http.Client{Transport: roundTripper(func(r *http.Request) (*http.Response, error) {
+		r.URL.Host = server.host()
+		return transport.RoundTrip(r)
+	})}
+	savedECRAuthorization := ecrAuthorization
+	defer func() { ecrAuthorization = savedECRAuthorization }()
+	ecrAuthorization = func(region string, registryID string) (string, error) {
+		assert.Equal(t, "us-east-1", region)
+		assert.Equal(t, "123456789012", registryID)
+		return "Basic QVdTOnRva2Vu", nil
+	}
+
+	dir := tempDir(t)
+	destination := filepath.Join(dir, "settings.json")
+	fileMock := &filemock.FileSystemMock{}
+	fileMock.On("Exists", destination).Return(false)
+	fileMock.On("MakeDirs", dir).Return(nil)
+	resource, _ := NewOCIResource(`{"reference": "123456789012.dkr.ecr.us-east-1.amazonaws.com/config/bundles@`+
+		sha256Digest(server.manifest)+`"}`, secretsMock{})
+
+	err, result := resource.DownloadRemoteResource(logMock, fileMock, destination)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{destination}, result.Files)
+	assert.Empty(t, server.tokenRequests)
+}
+
+type roundTripper func(r *http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestDownloadArtifactVerifiesDigests(t *testing.T) {
+	server := newRegistryServer(t, map[string]string{"app.json": "{}"})
+	dir := tempDir(t)
+
+	// the manifest isn't the one of the digest
+	other := sha256Digest([]byte("other"))
+	resource, _ := NewOCIResource(fmt.Sprintf(`{"reference": "%v/config/bundles@%v"}`, server.host(), other), secretsMock{})
+	err, _ := resource.DownloadRemoteResource(logMock, newDirectoryMock(dir), dir)
+	assert.Error(t, err)
+
+	// the blob isn't the one of its digest
+	for digest := range server.blobs {
+		server.blobs[digest] = []byte("tampered")
+	}
+	resource, _ = NewOCIResource(fmt.Sprintf(`{"reference": "%v/config/bundles"}`, server.host()), secretsMock{})
+	err, _ = resource.DownloadRemoteResource(logMock, newDirectoryMock(dir), dir)
+	assert.Error(t, err)
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files)
+}
+
+func TestParseReference(t *testing.T) {
+	ref, err := parseReference("123456789012.dkr.ecr.us-east-1.amazonaws.com/config/bundles:v3")
+	assert.NoError(t, err)
+	assert.Equal(t, reference{registry: "123456789012.dkr.ecr.us-east-1.amazonaws.com", repository: "config/bundles", tag: "v3"}, ref)
+
+	ref, err = parseReference("registry.example.com:5000/bundles")
+	assert.NoError(t, err)
+	assert.Equal(t, reference{registry: "registry.example.com:5000", repository: "bundles", tag: defaultTag}, ref)
+
+	digest := sha256Digest([]byte("manifest"))
+	ref, err = parseReference("registry.example.com/bundles@" + digest)
+	assert.NoError(t, err)
+	assert.Equal(t, reference{registry: "registry.example.com", repository: "bundles", digest: digest}, ref)
+
+	for _, invalid := range []string{"bundles:v3", "/bundles", "registry.example.com/Bundles", "registry.example.com/bundles:-v3",
+		"registry.example.com/bundles@sha256:1234", "registry.example.com/bundles@md5:" + strings.Repeat("0", 32)} {
+		_, err = parseReference(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
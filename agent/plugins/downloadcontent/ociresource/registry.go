@@ -0,0 +1,275 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package ociresource
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// maxManifestSize is the size of the largest manifest read
+	maxManifestSize = 4 * 1024 * 1024
+)
+
+var (
+	// ecrRegistryPattern matches the ECR registries, e.g. 123456789012.dkr.ecr.us-east-1.amazonaws.com
+	ecrRegistryPattern = regexp.MustCompile(`^([0-9]{12})\.dkr\.ecr(-fips)?\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+	// challengeParameterPattern matches the parameters of a WWW-Authenticate challenge
+	challengeParameterPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+// httpClient sends the requests to the registries
+var httpClient = http.DefaultClient
+
+// ecrAuthorization returns the basic authorization of an ECR registry for the credentials of the instance
+var ecrAuthorization = func(region string, registryID string) (string, error) {
+	awsConfig := sdkutil.AwsConfig()
+	awsConfig.Region = aws.String(region)
+	output, err := ecr.New(session.New(awsConfig)).GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{
+		RegistryIds: []*string{aws.String(registryID)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(output.AuthorizationData) == 0 {
+		return "", errors.New("ECR returned no authorization token")
+	}
+	return "Basic " + aws.StringValue(output.AuthorizationData[0].AuthorizationToken), nil
+}
+
+// descriptor describes a blob of the OCI image specification
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// manifest is the manifest of an artifact
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// registryClient sends the requests of the OCI distribution API for a repository
+type registryClient struct {
+	registry   string
+	repository string
+	username   string
+	password   string
+	// authorization is the Authorization header of the requests once authenticated
+	authorization string
+}
+
+// manifest gets and verifies the manifest of the reference
+func (c *registryClient) manifest(log log.T, ref reference) (m manifest, err error) {
+	name := ref.digest
+	if name == "" {
+		name = ref.tag
+	}
+	response, err := c.get(log, "manifests/"+name, mediaTypeOCIManifest+", "+mediaTypeDockerManifest)
+	if err != nil {
+		return m, err
+	}
+	defer response.Body.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(response.Body, maxManifestSize))
+	if err != nil {
+		return m, fmt.Errorf("failed to read the manifest of %v: %v", name, err)
+	}
+	if ref.digest != "" {
+		if digest := sha256Digest(content); digest != ref.digest {
+			return m, fmt.Errorf("the digest of the manifest is %v, expected %v", digest, ref.digest)
+		}
+	}
+	if err = json.Unmarshal(content, &m); err != nil {
+		return m, fmt.Errorf("invalid manifest of %v: %v", name, err)
+	}
+	if m.MediaType == "" {
+		m.MediaType = response.Header.Get("Content-Type")
+	}
+	if m.MediaType != mediaTypeOCIManifest && m.MediaType != mediaTypeDockerManifest {
+		return m, fmt.Errorf("%v is a %v, not an artifact manifest", name, m.MediaType)
+	}
+	for _, layer := range m.Layers {
+		if !digestPattern.MatchString(layer.Digest) {
+			return m, fmt.Errorf("unsupported digest %v of a layer, only sha256 digests are supported", layer.Digest)
+		}
+	}
+	return m, nil
+}
+
+// downloadBlob downloads the blob to the file, which is only written once its digest is verified
+func (c *registryClient) downloadBlob(log log.T, blob descriptor, path string) error {
+	response, err := c.get(log, "blobs/"+blob.Digest, "")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	temp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".download")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(temp, hash), response.Body)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %v: %v", blob.Digest, err)
+	}
+	if digest := "sha256:" + hex.EncodeToString(hash.Sum(nil)); digest != blob.Digest || size != blob.Size {
+		return fmt.Errorf("the blob downloaded for %v doesn't match it, its digest is %v and its size %v", blob.Digest, digest, size)
+	}
+	os.Remove(path)
+	return os.Rename(temp.Name(), path)
+}
+
+// get sends a request of the API of the repository, authenticating when the registry asks for it
+func (c *registryClient) get(log log.T, path string, accept string) (*http.Response, error) {
+	if c.authorization == "" {
+		if matches := ecrRegistryPattern.FindStringSubmatch(c.registry); matches != nil {
+			var err error
+			if c.authorization, err = ecrAuthorization(matches[3], matches[1]); err != nil {
+				return nil, fmt.Errorf("failed to get an authorization token of %v: %v", c.registry, err)
+			}
+		}
+	}
+
+	requestURL := fmt.Sprintf("https://%v/v2/%v/%v", c.registry, c.repository, path)
+	response, err := c.send(requestURL, accept)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusUnauthorized && c.authorization == "" {
+		challenge := response.Header.Get("WWW-Authenticate")
+		response.Body.Close()
+		if err = c.authenticate(log, challenge); err != nil {
+			return nil, err
+		}
+		if response, err = c.send(requestURL, accept); err != nil {
+			return nil, err
+		}
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("GET %v failed: %v", requestURL, response.Status)
+	}
+	return response, nil
+}
+
+func (c *registryClient) send(requestURL string, accept string) (*http.Response, error) {
+	request, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	if c.authorization != "" {
+		request.Header.Set("Authorization", c.authorization)
+	}
+	return httpClient.Do(request)
+}
+
+// authenticate answers the challenge of the registry, getting a bearer token from its token service, with the
+// credentials when there are some
+func (c *registryClient) authenticate(log log.T, challenge string) error {
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	switch scheme {
+	case "basic":
+		if c.username == "" {
+			return fmt.Errorf("the registry %v requires a Username and Password", c.registry)
+		}
+		request, _ := http.NewRequest("GET", "https://"+c.registry, nil)
+		request.SetBasicAuth(c.username, c.password)
+		c.authorization = request.Header.Get("Authorization")
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("the registry %v asks for the unsupported authentication %q", c.registry, challenge)
+	}
+
+	parameters := map[string]string{}
+	for _, match := range challengeParameterPattern.FindAllStringSubmatch(challenge, -1) {
+		parameters[strings.ToLower(match[1])] = match[2]
+	}
+	realm, err := url.Parse(parameters["realm"])
+	if err != nil || realm.Scheme != "https" {
+		return fmt.Errorf("the registry %v asks for a token from the invalid realm %q", c.registry, parameters["realm"])
+	}
+	query := realm.Query()
+	if parameters["service"] != "" {
+		query.Set("service", parameters["service"])
+	}
+	query.Set("scope", "repository:"+c.repository+":pull")
+	realm.RawQuery = query.Encode()
+
+	log.Debugf("Getting a token from %v", realm.Host)
+	request, err := http.NewRequest("GET", realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to get a token of %v: %v", c.registry, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get a token of %v: %v", c.registry, response.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(io.LimitReader(response.Body, maxManifestSize)).Decode(&token); err != nil {
+		return fmt.Errorf("invalid token of %v: %v", c.registry, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	c.authorization = "Bearer " + token.Token
+	return nil
+}
+
+func sha256Digest(content []byte) string {
+	hash := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
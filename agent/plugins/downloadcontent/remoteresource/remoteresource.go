@@ -18,6 +18,7 @@ package remoteresource
 import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
 const (
@@ -35,3 +36,14 @@ type RemoteResource interface {
 	DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destinationDir string) (err error, result *DownloadResult)
 	ValidateLocationInfo() (bool, error)
 }
+
+// CancellableResource is implemented by the remote resources whose downloads stop when the step is cancelled
+type CancellableResource interface {
+	SetCancelFlag(cancelFlag task.CancelFlag)
+}
+
+// ChecksumResource is implemented by the remote resources that verify the SHA-256 of a single file while they
+// download it
+type ChecksumResource interface {
+	SetChecksum(checksum string)
+}
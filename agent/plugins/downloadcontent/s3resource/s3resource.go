@@ -38,6 +38,7 @@ import (
 type S3Resource struct {
 	Info     S3Info
 	s3Object s3util.AmazonS3URL
+	checksum string
 }
 
 // S3Info represents the sourceInfo type sent by runcommand
@@ -73,6 +74,11 @@ func parseSourceInfo(sourceInfo string) (s3Info S3Info, err error) {
 	return
 }
 
+// SetChecksum makes the download of a file fail when its SHA-256 doesn't match, see remoteresource.ChecksumResource
+func (s3 *S3Resource) SetChecksum(checksum string) {
+	s3.checksum = checksum
+}
+
 // DownloadRemoteResource calls download to pull down files or directory from s3
 func (s3 *S3Resource) DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destPath string) (err error, result *remoteresource.DownloadResult) {
 	var fileURL *url.URL
@@ -163,6 +169,9 @@ func (s3 *S3Resource) DownloadRemoteResource(log log.T, filesys filemanager.File
 				}
 			}
 			input.DestinationDirectory = localFilePath
+			if !isDirTypeDownloaded && s3.checksum != "" {
+				input.SourceChecksums = map[string]string{artifact.HashTypeSha256: s3.checksum}
+			}
 			downloadOutput, err := dep.Download(log, input)
 			if err != nil {
 				return err, nil
@@ -173,6 +182,7 @@ func (s3 *S3Resource) DownloadRemoteResource(log log.T, filesys filemanager.File
 						"possible that the content was not downloaded because the path provided is wrong. %v", err),
 					nil
 			}
+			result.Files = append(result.Files, filepath.Join(filepath.Dir(downloadOutput.LocalFilePath), destinationFile))
 		}
 	}
 	return nil, result
@@ -20,9 +20,11 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/stretchr/testify/assert"
 
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -30,6 +32,13 @@ import (
 
 var logMock = log.NewMockLog()
 
+func TestMain(m *testing.M) {
+	// the bucket region defaults to the region of the instance, don't look it up
+	platform.SetInstanceID("i-1234567890")
+	platform.SetRegion("us-east-1")
+	os.Exit(m.Run())
+}
+
 func TestS3Resource_ValidateLocationInfoPath(t *testing.T) {
 
 	locationInfo := `{
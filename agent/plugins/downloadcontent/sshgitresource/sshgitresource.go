@@ -0,0 +1,328 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Package sshgitresource implements the methods to access resources from git repositories over SSH
+package sshgitresource
+
+import (
+	gocontext "context"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultDepth is the depth of the clones of the sources that don't set one
+	defaultDepth = 1
+
+	keyFileName        = "id"
+	knownHostsFileName = "known_hosts"
+
+	// gitTimeout is how long a git command of the clone runs before it is killed
+	gitTimeout = time.Hour
+	// gitWaitDelay is how long the output of a killed git command is waited for, ssh may hold it open
+	gitWaitDelay = 5 * time.Second
+)
+
+// commitIDPattern matches the full SHA-1 or SHA-256 name of a commit
+var commitIDPattern = regexp.MustCompile("^([0-9a-f]{40}|[0-9a-f]{64})$")
+
+// credentialsRoot is the directory of the private keys and known hosts of the clones, which only the agent can read
+var credentialsRoot = filepath.Join(appconfig.DefaultDataStorePath, "sshgit")
+
+// runGit runs git in dir, the environment adds to the environment of the agent. git is killed when the step is
+// cancelled or after gitTimeout.
+var runGit = func(log log.T, cancelFlag task.CancelFlag, dir string, env []string, args ...string) error {
+	log.Debugf("Running git %v", strings.Join(args, " "))
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), gitTimeout)
+	defer cancel()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-task.Done(cancelFlag):
+			cancel()
+		case <-done:
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.WaitDelay = gitWaitDelay
+	output, err := cmd.CombinedOutput()
+	switch {
+	case err == nil:
+		return nil
+	case cancelFlag.Canceled() || cancelFlag.ShutDown():
+		return fmt.Errorf("git %v was interrupted: %v", args[0], cancelFlag.Reason())
+	case ctx.Err() == gocontext.DeadlineExceeded:
+		return fmt.Errorf("git %v timed out after %v", args[0], gitTimeout)
+	}
+	return fmt.Errorf("git %v failed: %v\n%v", args[0], err, strings.TrimSpace(string(output)))
+}
+
+// secureParameters resolves the SecureString parameter holding the private key
+type secureParameters interface {
+	GetSecureParameter(log log.T, reference string) (string, error)
+}
+
+// SSHGitResource is a struct for the remote resource of type Git
+type SSHGitResource struct {
+	Info       SSHGitInfo
+	secrets    secureParameters
+	cancelFlag task.CancelFlag
+}
+
+// SSHGitInfo represents the sourceInfo type sent by runcommand
+type SSHGitInfo struct {
+	// Repository is the ssh:// URL of the repository
+	Repository string `json:"repository"`
+	// PrivateSSHKey references the SecureString parameter holding the deploy key, as {{ ssm-secure:parameter-name }}
+	PrivateSSHKey string `json:"privateSSHKey"`
+	// HostKey holds the public keys of the server, one per line, e.g. "ssh-ed25519 AAAAC3Nza...", no other key
+	// is trusted
+	HostKey string `json:"hostKey"`
+	// Branch, Tag or CommitID pins the content downloaded, the default branch of the repository by default
+	Branch   string `json:"branch"`
+	Tag      string `json:"tag"`
+	CommitID string `json:"commitID"`
+	// Depth is the number of commits of the history downloaded
+	Depth int `json:"depth"`
+}
+
+// NewSSHGitResource is a constructor of type SSHGitResource
+func NewSSHGitResource(info string, secrets secureParameters) (*SSHGitResource, error) {
+	var gitInfo SSHGitInfo
+	if err := jsonutil.Unmarshal(info, &gitInfo); err != nil {
+		return nil, fmt.Errorf("Source Info could not be unmarshalled for source type Git. Please check JSON format of sourceInfo - %v", err.Error())
+	}
+	return &SSHGitResource{
+		Info:       gitInfo,
+		secrets:    secrets,
+		cancelFlag: task.NewChanneledCancelFlag(),
+	}, nil
+}
+
+// SetCancelFlag makes the clone stop when the step is cancelled, see remoteresource.CancellableResource
+func (git *SSHGitResource) SetCancelFlag(cancelFlag task.CancelFlag) {
+	git.cancelFlag = cancelFlag
+}
+
+// DownloadRemoteResource clones the repository in the destination, or in a directory named after the repository when
+// the destination is an existing directory.
+func (git *SSHGitResource) DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destinationPath string) (err error, result *remoteresource.DownloadResult) {
+	if destinationPath == "" {
+		destinationPath = appconfig.DownloadRoot
+	}
+	if filesys.Exists(destinationPath) {
+		if !filesys.IsDirectory(destinationPath) {
+			return fmt.Errorf("the destination %v already exists and isn't a directory", destinationPath), nil
+		}
+		repository, _ := url.Parse(git.Info.Repository)
+		destinationPath = filepath.Join(destinationPath, strings.TrimSuffix(path.Base(repository.Path), ".git"))
+	}
+	if err = filesys.MakeDirs(filepath.Dir(destinationPath)); err != nil {
+		return fmt.Errorf("failed to create the directory of %v: %v", destinationPath, err), nil
+	}
+
+	key, err := git.secrets.GetSecureParameter(log, git.Info.PrivateSSHKey)
+	if err != nil {
+		return err, nil
+	}
+	env, cleanup, err := git.sshEnvironment(key)
+	if err != nil {
+		return fmt.Errorf("failed to prepare the SSH credentials: %v", err), nil
+	}
+	defer cleanup()
+
+	// the repository is cloned next to the destination and moved to it once complete, so that a failed clone
+	// neither leaves part of the repository nor removes what was at the destination
+	staging, err := ioutil.TempDir(filepath.Dir(destinationPath), "."+filepath.Base(destinationPath)+".")
+	if err != nil {
+		return fmt.Errorf("failed to create the directory of the clone: %v", err), nil
+	}
+	defer os.RemoveAll(staging)
+
+	log.Infof("Cloning %v to %v", git.Info.Repository, destinationPath)
+	if err = git.clone(log, env, staging); err != nil {
+		return err, nil
+	}
+	if err = os.Rename(staging, destinationPath); err != nil {
+		return fmt.Errorf("failed to move the clone to %v: %v", destinationPath, err), nil
+	}
+	return nil, &remoteresource.DownloadResult{Directories: []string{destinationPath}}
+}
+
+// clone clones the commit, branch or tag of the repository, with depth commits of history.
+func (git *SSHGitResource) clone(log log.T, env []string, destinationPath string) error {
+	depth := "--depth=" + strconv.Itoa(git.depth())
+	if git.Info.CommitID == "" {
+		args := []string{"clone", "--quiet", depth}
+		if ref := git.Info.Branch + git.Info.Tag; ref != "" {
+			args = append(args, "--branch="+ref)
+		}
+		return runGit(log, git.cancelFlag, "", env, append(args, "--", git.Info.Repository, destinationPath)...)
+	}
+
+	if err := runGit(log, git.cancelFlag, "", env, "init", "--quiet", "--", destinationPath); err != nil {
+		return err
+	}
+	if err := runGit(log, git.cancelFlag, destinationPath, env, "remote", "add", "origin", git.Info.Repository); err != nil {
+		return err
+	}
+	if err := runGit(log, git.cancelFlag, destinationPath, env, "fetch", "--quiet", depth, "origin", git.Info.CommitID); err != nil {
+		if git.cancelFlag.Canceled() || git.cancelFlag.ShutDown() {
+			return err
+		}
+		// the servers that don't allow fetching a commit by name send the whole repository
+		log.Infof("Fetching the whole repository, fetching the commit failed: %v", err)
+		if err = runGit(log, git.cancelFlag, destinationPath, env, "fetch", "--quiet", "origin"); err != nil {
+			return err
+		}
+	}
+	return runGit(log, git.cancelFlag, destinationPath, env, "checkout", "--quiet", "--detach", git.Info.CommitID)
+}
+
+// sshEnvironment writes the private key and the known hosts to files only the agent can read, and returns the
+// environment running ssh with them and the function removing them.
+func (git *SSHGitResource) sshEnvironment(key string) (env []string, cleanup func(), err error) {
+	if err = fileutil.MakeDirs(credentialsRoot); err != nil {
+		return nil, nil, err
+	}
+	dir, err := ioutil.TempDir(credentialsRoot, "clone")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup = func() { fileutil.ShredDirectory(dir) }
+
+	keyPath := filepath.Join(dir, keyFileName)
+	knownHostsPath := filepath.Join(dir, knownHostsFileName)
+	// ssh rejects the keys without final new line
+	key = strings.TrimSpace(key) + "\n"
+	if err = ioutil.WriteFile(keyPath, []byte(key), appconfig.ReadWriteAccess); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if err = ioutil.WriteFile(knownHostsPath, []byte(git.knownHosts()), appconfig.ReadWriteAccess); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	sshCommand := fmt.Sprintf("ssh -i %v -o IdentitiesOnly=yes -o BatchMode=yes -o StrictHostKeyChecking=yes "+
+		"-o UserKnownHostsFile=%v -o GlobalKnownHostsFile=/dev/null", quote(keyPath), quote(knownHostsPath))
+	return []string{"GIT_SSH_COMMAND=" + sshCommand, "GIT_TERMINAL_PROMPT=0"}, cleanup, nil
+}
+
+// knownHosts returns the known_hosts file trusting the host keys for the server of the repository
+func (git *SSHGitResource) knownHosts() string {
+	repository, _ := url.Parse(git.Info.Repository)
+	host := repository.Hostname()
+	if port := repository.Port(); port != "" && port != "22" {
+		host = fmt.Sprintf("[%v]:%v", host, port)
+	}
+	var lines []string
+	for _, hostKey := range hostKeys(git.Info.HostKey) {
+		lines = append(lines, host+" "+hostKey)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (git *SSHGitResource) depth() int {
+	if git.Info.Depth == 0 {
+		return defaultDepth
+	}
+	return git.Info.Depth
+}
+
+// ValidateLocationInfo ensures that the required parameters of SourceInfo are specified
+func (git *SSHGitResource) ValidateLocationInfo() (valid bool, err error) {
+	repository, err := url.Parse(git.Info.Repository)
+	if git.Info.Repository == "" {
+		return false, errors.New("Repository for Git SourceType must be specified")
+	} else if err != nil || repository.Scheme != "ssh" || repository.Hostname() == "" || strings.Trim(repository.Path, "/") == "" {
+		return false, fmt.Errorf("Repository %v for Git SourceType must be an ssh:// URL, e.g. ssh://git@example.com/org/repo.git", git.Info.Repository)
+	}
+	if git.Info.PrivateSSHKey == "" {
+		return false, errors.New("PrivateSSHKey for Git SourceType must be specified")
+	}
+
+	keys := hostKeys(git.Info.HostKey)
+	if len(keys) == 0 {
+		return false, errors.New("HostKey for Git SourceType must be specified")
+	}
+	for _, key := range keys {
+		fields := strings.Fields(key)
+		if len(fields) < 2 {
+			return false, fmt.Errorf("HostKey %v must be a key type followed by the base64 key, e.g. ssh-ed25519 AAAAC3Nza...", key)
+		}
+		if _, err := base64.StdEncoding.DecodeString(fields[1]); err != nil {
+			return false, fmt.Errorf("HostKey %v isn't base64 encoded", key)
+		}
+	}
+
+	pins := 0
+	for _, pin := range []string{git.Info.Branch, git.Info.Tag, git.Info.CommitID} {
+		if pin != "" {
+			pins++
+		}
+		if strings.HasPrefix(pin, "-") {
+			return false, fmt.Errorf("invalid branch, tag or commit %v", pin)
+		}
+	}
+	if pins > 1 {
+		return false, errors.New("only one of Branch, Tag and CommitID can be specified")
+	}
+	if git.Info.CommitID != "" && !commitIDPattern.MatchString(git.Info.CommitID) {
+		return false, fmt.Errorf("CommitID %v must be the full hexadecimal name of a commit", git.Info.CommitID)
+	}
+	if git.Info.Depth < 0 {
+		return false, errors.New("Depth for Git SourceType must be positive")
+	}
+	return true, nil
+}
+
+// hostKeys returns the lines of the host keys
+func hostKeys(hostKey string) []string {
+	var keys []string
+	for _, line := range strings.Split(hostKey, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			keys = append(keys, line)
+		}
+	}
+	return keys
+}
+
+// quote quotes a path for the shell git runs ssh with
+func quote(path string) string {
+	return "'" + strings.Replace(filepath.ToSlash(path), "'", `'\''`, -1) + "'"
+}
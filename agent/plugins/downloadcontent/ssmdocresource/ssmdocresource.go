@@ -95,6 +95,7 @@ func (ssmdoc *SSMDocResource) DownloadRemoteResource(log log.T, filesys filemana
 		log.Errorf("Error saving file - %v", err)
 		return err, nil
 	}
+	result.Files = append(result.Files, destinationFilePath)
 
 	return nil, result
 }
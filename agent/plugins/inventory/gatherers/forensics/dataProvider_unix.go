@@ -0,0 +1,161 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+// Package forensics contains a gatherer that snapshots the running processes, their open files and
+// the recent logins, for incident response triage.
+package forensics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	psCmd = "ps"
+	// psFormat lists the processes, lstart is 5 fields long, e.g. Mon Jan  2 15:04:05 2006
+	psFormat     = "pid=,ppid=,user=,lstart=,args="
+	lstartLayout = "Mon Jan 2 15:04:05 2006"
+
+	lastCmd = "last"
+)
+
+// procRoot is where the open files of the processes are listed, on platforms that have it
+var procRoot = "/proc"
+
+// decoupling exec.Command for easy testability
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).CombinedOutput()
+}
+
+// collectProcessData lists the running processes with ps.
+func collectProcessData(context context.T) (data []model.ProcessData, err error) {
+	var output []byte
+	if output, err = cmdExecutor(psCmd, "-eo", psFormat); err != nil {
+		return nil, fmt.Errorf("%v failed: %v %v", psCmd, err, string(output))
+	}
+	return parseProcesses(string(output)), nil
+}
+
+// parseProcesses parses the output of ps, the lines that can't be parsed are skipped.
+func parseProcesses(output string) (data []model.ProcessData) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+		process := model.ProcessData{
+			ProcessId:       fields[0],
+			ParentProcessId: fields[1],
+			User:            fields[2],
+		}
+		if startTime, err := time.ParseInLocation(lstartLayout, strings.Join(fields[3:8], " "), time.Local); err == nil {
+			process.StartTime = startTime.UTC().Format(time.RFC3339)
+		}
+		if len(fields) > 8 {
+			process.CommandLine = strings.Join(fields[8:], " ")
+			process.Name = filepath.Base(fields[8])
+		}
+		data = append(data, process)
+	}
+	return data
+}
+
+// collectOpenFileData lists the files the processes have open, where the platform exposes them in /proc.
+func collectOpenFileData(context context.T, processes []model.ProcessData) (data []model.OpenFileData, err error) {
+	if _, err = os.Stat(procRoot); err != nil {
+		context.Log().Debugf("%v is not available, open files are not collected", procRoot)
+		return nil, nil
+	}
+	for _, process := range processes {
+		if _, err := strconv.Atoi(process.ProcessId); err != nil {
+			continue
+		}
+		fdDir := filepath.Join(procRoot, process.ProcessId, "fd")
+		fds, err := ioutil.ReadDir(fdDir)
+		if err != nil {
+			// the process has exited or isn't accessible
+			continue
+		}
+		count := 0
+		for _, fd := range fds {
+			path, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			// only report files, not sockets, pipes and the like
+			if err != nil || !strings.HasPrefix(path, "/") {
+				continue
+			}
+			data = append(data, model.OpenFileData{ProcessId: process.ProcessId, Path: path})
+			if count++; count >= maxOpenFilesPerProcess {
+				break
+			}
+		}
+		if len(data) >= maxOpenFiles {
+			break
+		}
+	}
+	return data, nil
+}
+
+// collectLoginData lists the most recent logins with last.
+func collectLoginData(context context.T) (data []model.LoginData, err error) {
+	var output []byte
+	if output, err = cmdExecutor(lastCmd, "-n", strconv.Itoa(maxLogins)); err != nil {
+		return nil, fmt.Errorf("%v failed: %v %v", lastCmd, err, string(output))
+	}
+	return parseLogins(string(output)), nil
+}
+
+// parseLogins parses the output of last, e.g.
+// ec2-user pts/0        10.0.0.1         Mon Oct  2 10:00   still logged in
+func parseLogins(output string) (data []model.LoginData) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		// skip the blank lines and the trailer, e.g. wtmp begins Mon Oct  2 10:00:00 2017
+		if len(fields) < 4 || strings.HasSuffix(fields[0], "tmp") {
+			continue
+		}
+		login := model.LoginData{
+			User:     fields[0],
+			Terminal: fields[1],
+			Details:  strings.Join(fields[2:], " "),
+		}
+		// the source is omitted for local logins, the time starts with a day of the week
+		if _, err := time.Parse("Mon", fields[2]); err != nil {
+			login.Source = fields[2]
+			login.Time = strings.Join(fields[3:min(len(fields), 7)], " ")
+		} else {
+			login.Time = strings.Join(fields[2:min(len(fields), 6)], " ")
+		}
+		data = append(data, login)
+	}
+	return data
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
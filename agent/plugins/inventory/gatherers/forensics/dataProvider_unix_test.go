@@ -0,0 +1,82 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package forensics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const psOutput = `    1     0 root     Mon Oct  2 10:00:00 2017 /sbin/init splash
+  812     1 ec2-user Mon Oct  2 10:05:30 2017 /usr/bin/python3 -m http.server  8080
+  bad line
+`
+
+const lastOutput = `ec2-user pts/0        10.0.0.1         Mon Oct  2 10:00   still logged in
+root     tty1                          Mon Oct  2 09:00 - 09:30  (00:30)
+reboot   system boot  4.14.77-81.59.am Mon Oct  2 08:59   still running
+
+wtmp begins Mon Oct  2 08:59:00 2017
+`
+
+func TestParseProcesses(t *testing.T) {
+	processes := parseProcesses(psOutput)
+
+	assert.Equal(t, 2, len(processes))
+	assert.Equal(t, "812", processes[1].ProcessId)
+	assert.Equal(t, "1", processes[1].ParentProcessId)
+	assert.Equal(t, "ec2-user", processes[1].User)
+	assert.Equal(t, "python3", processes[1].Name)
+	assert.Equal(t, "/usr/bin/python3 -m http.server 8080", processes[1].CommandLine)
+	assert.NotEmpty(t, processes[1].StartTime)
+}
+
+func TestParseLogins(t *testing.T) {
+	logins := parseLogins(lastOutput)
+
+	assert.Equal(t, 3, len(logins))
+	assert.Equal(t, model.LoginData{
+		User:     "ec2-user",
+		Terminal: "pts/0",
+		Source:   "10.0.0.1",
+		Time:     "Mon Oct 2 10:00",
+		Details:  "10.0.0.1 Mon Oct 2 10:00 still logged in",
+	}, logins[0])
+	assert.Equal(t, "", logins[1].Source)
+	assert.Equal(t, "Mon Oct 2 09:00", logins[1].Time)
+}
+
+func TestCollectOpenFileData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	procRoot = dir
+	fdDir := filepath.Join(dir, "42", "fd")
+	assert.NoError(t, os.MkdirAll(fdDir, 0700))
+	assert.NoError(t, os.Symlink("/var/log/secure", filepath.Join(fdDir, "3")))
+	assert.NoError(t, os.Symlink("socket:[1234]", filepath.Join(fdDir, "4")))
+
+	openFiles, err := collectOpenFileData(context.NewMockDefault(), []model.ProcessData{{ProcessId: "42"}, {ProcessId: "43"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.OpenFileData{{ProcessId: "42", Path: "/var/log/secure"}}, openFiles)
+}
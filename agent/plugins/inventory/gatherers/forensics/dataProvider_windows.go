@@ -0,0 +1,131 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+// Package forensics contains a gatherer that snapshots the running processes, their open files and
+// the recent logins, for incident response triage.
+package forensics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/twinj/uuid"
+)
+
+const (
+	PowershellCmd = "powershell"
+)
+
+var (
+	startMarker   = "<start" + randomString(8) + ">"
+	endMarker     = "<end" + randomString(8) + ">"
+	processScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$jsonObj = @()
+foreach($p in Get-CimInstance Win32_Process) {
+$Name = $p.Name
+$CommandLine = $p.CommandLine
+$StartTime = ""
+if ($p.CreationDate) { $StartTime = $p.CreationDate.ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ") }
+$owner = Invoke-CimMethod -InputObject $p -MethodName GetOwner -ErrorAction SilentlyContinue
+$User = ""
+if ($owner -and $owner.User) { $User = $owner.Domain + "\" + $owner.User }
+$jsonObj += @"
+{"ProcessId": "$($p.ProcessId)", "ParentProcessId": "$($p.ParentProcessId)", "Name": "` + mark(`$Name`) + `", "User": "` + mark(`$User`) + `",
+"StartTime": "$StartTime", "CommandLine": "` + mark(`$CommandLine`) + `"}
+"@
+}
+$result = $jsonObj -join ","
+$result = "[" + $result + "]"
+[Console]::WriteLine($result)
+`
+	// loginScript reads the successful logon events of the security event log, the properties are
+	// TargetDomainName (6), TargetUserName (5), LogonType (8), WorkstationName (11) and IpAddress (18)
+	loginScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$jsonObj = @()
+foreach($e in Get-WinEvent -FilterHashtable @{LogName='Security';Id=4624} -MaxEvents ` + strconv.Itoa(maxLogins) + ` -ErrorAction SilentlyContinue) {
+$User = $e.Properties[6].Value + "\" + $e.Properties[5].Value
+$Terminal = $e.Properties[11].Value
+$Source = $e.Properties[18].Value
+$Time = $e.TimeCreated.ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ")
+$jsonObj += @"
+{"User": "` + mark(`$User`) + `", "Terminal": "` + mark(`$Terminal`) + `", "Source": "` + mark(`$Source`) + `", "Time": "$Time",
+"Details": "LogonType $($e.Properties[8].Value)"}
+"@
+}
+$result = $jsonObj -join ","
+$result = "[" + $result + "]"
+[Console]::WriteLine($result)
+`
+)
+
+func randomString(length int) string {
+	return uuid.NewV4().String()[:length]
+}
+
+func mark(s string) string {
+	return startMarker + s + endMarker
+}
+
+// decoupling exec.Command for easy testability
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).CombinedOutput()
+}
+
+// collectDataFromPowershell runs the script and parses its JSON output into data
+func collectDataFromPowershell(log log.T, script string, data interface{}) (err error) {
+	var output []byte
+	var cleanOutput string
+	if output, err = cmdExecutor(PowershellCmd, script); err != nil {
+		log.Debugf("Command Stderr: %v", string(output))
+		return fmt.Errorf("Command failed with error: %v", string(output))
+	}
+	cleanOutput, err = pluginutil.ReplaceMarkedFields(pluginutil.CleanupNewLines(string(output)), startMarker, endMarker, pluginutil.CleanupJSONField)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal([]byte(cleanOutput), data); err != nil {
+		return fmt.Errorf("Unable to parse command output - %v", err.Error())
+	}
+	return nil
+}
+
+// collectProcessData lists the running processes with their owner.
+func collectProcessData(context context.T) (data []model.ProcessData, err error) {
+	err = collectDataFromPowershell(context.Log(), processScript, &data)
+	return
+}
+
+// collectOpenFileData doesn't collect anything, Windows doesn't list the handles of other processes
+// without additional tools.
+func collectOpenFileData(context context.T, processes []model.ProcessData) (data []model.OpenFileData, err error) {
+	context.Log().Debug("open files are not collected on Windows")
+	return nil, nil
+}
+
+// collectLoginData lists the most recent successful logons.
+func collectLoginData(context context.T) (data []model.LoginData, err error) {
+	err = collectDataFromPowershell(context.Log(), loginScript, &data)
+	return
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package forensics contains a gatherer that snapshots the running processes, their open files and
+// the recent logins, for incident response triage. It only runs when enabled in the inventory policy.
+package forensics
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// GathererName captures name of forensics gatherer
+	GathererName = "ForensicsSnapshot"
+	// ProcessTypeName is the inventory type of the running processes
+	ProcessTypeName = "Custom:ForensicsProcess"
+	// OpenFileTypeName is the inventory type of the files opened by the running processes
+	OpenFileTypeName = "Custom:ForensicsOpenFile"
+	// LoginTypeName is the inventory type of the recent logins
+	LoginTypeName = "Custom:ForensicsLogin"
+	// SchemaVersionOfForensicsGatherer represents schema version of forensics gatherer
+	SchemaVersionOfForensicsGatherer = "1.0"
+
+	// maxCommandLineLength is the length command lines are truncated to
+	maxCommandLineLength = 1024
+	// maxOpenFilesPerProcess is the number of open files reported per process
+	maxOpenFilesPerProcess = 50
+	// maxOpenFiles is the number of open files reported in total, to stay within the inventory size limits
+	maxOpenFiles = 5000
+	// maxLogins is the number of most recent logins reported
+	maxLogins = 100
+)
+
+type T struct{}
+
+// Gatherer returns new forensics gatherer
+func Gatherer(context context.T) *T {
+	return new(T)
+}
+
+var collectProcesses = collectProcessData
+var collectOpenFiles = collectOpenFileData
+var collectLogins = collectLoginData
+
+// Name returns name of forensics gatherer
+func (t *T) Name() string {
+	return GathererName
+}
+
+// Run executes forensics gatherer and returns one inventory item per snapshot type
+func (t *T) Run(context context.T, configuration model.Config) (items []model.Item, err error) {
+	log := context.Log()
+
+	//CaptureTime must comply with format: 2016-07-30T18:15:37Z to comply with regex at SSM.
+	captureTime := time.Now().UTC().Format(time.RFC3339)
+
+	var processes []model.ProcessData
+	if processes, err = collectProcesses(context); err != nil {
+		return nil, err
+	}
+	for i := range processes {
+		processes[i].CommandLine = truncate(processes[i].CommandLine, maxCommandLineLength)
+	}
+
+	var openFiles []model.OpenFileData
+	if openFiles, err = collectOpenFiles(context, processes); err != nil {
+		// open files are best effort, the process list is still worth reporting
+		log.Errorf("Unable to collect open files - %v", err)
+		err = nil
+	}
+	if len(openFiles) > maxOpenFiles {
+		log.Infof("%v open files found, reporting the first %v", len(openFiles), maxOpenFiles)
+		openFiles = openFiles[:maxOpenFiles]
+	}
+
+	var logins []model.LoginData
+	if logins, err = collectLogins(context); err != nil {
+		log.Errorf("Unable to collect logins - %v", err)
+		err = nil
+	}
+	if len(logins) > maxLogins {
+		logins = logins[:maxLogins]
+	}
+
+	log.Infof("Forensics snapshot: %v processes, %v open files, %v logins", len(processes), len(openFiles), len(logins))
+	items = append(items,
+		model.Item{Name: ProcessTypeName, SchemaVersion: SchemaVersionOfForensicsGatherer, Content: processes, CaptureTime: captureTime},
+		model.Item{Name: OpenFileTypeName, SchemaVersion: SchemaVersionOfForensicsGatherer, Content: openFiles, CaptureTime: captureTime},
+		model.Item{Name: LoginTypeName, SchemaVersion: SchemaVersionOfForensicsGatherer, Content: logins, CaptureTime: captureTime})
+	return
+}
+
+// RequestStop stops the execution of forensics gatherer.
+func (t *T) RequestStop(stopType contracts.StopType) error {
+	var err error
+	return err
+}
+
+// truncate shortens s to at most length bytes, without splitting a UTF-8 sequence.
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	for length > 0 && !utf8.RuneStart(s[length]) {
+		length--
+	}
+	return s[:length]
+}
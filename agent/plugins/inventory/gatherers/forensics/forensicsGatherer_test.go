@@ -0,0 +1,96 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package forensics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testProcesses = []model.ProcessData{
+	{
+		ProcessId:       "1",
+		ParentProcessId: "0",
+		Name:            "init",
+		User:            "root",
+		StartTime:       "2017-10-02T10:00:00Z",
+		CommandLine:     "/sbin/init " + strings.Repeat("a", 2*maxCommandLineLength),
+	},
+}
+
+var testLogins = []model.LoginData{
+	{
+		User:     "ec2-user",
+		Terminal: "pts/0",
+		Source:   "10.0.0.1",
+		Time:     "Mon Oct 2 10:00",
+	},
+}
+
+func testCollectProcesses(context context.T) ([]model.ProcessData, error) {
+	return testProcesses, nil
+}
+
+func testCollectOpenFiles(context context.T, processes []model.ProcessData) ([]model.OpenFileData, error) {
+	return nil, fmt.Errorf("access denied")
+}
+
+func testCollectLogins(context context.T) ([]model.LoginData, error) {
+	return testLogins, nil
+}
+
+func TestGatherer(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectProcesses = testCollectProcesses
+	collectOpenFiles = testCollectOpenFiles
+	collectLogins = testCollectLogins
+
+	items, err := gatherer.Run(contextMock, model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(items))
+	assert.Equal(t, ProcessTypeName, items[0].Name)
+	assert.Equal(t, SchemaVersionOfForensicsGatherer, items[0].SchemaVersion)
+	processes := items[0].Content.([]model.ProcessData)
+	assert.Equal(t, maxCommandLineLength, len(processes[0].CommandLine))
+	// the open files are best effort
+	assert.Equal(t, OpenFileTypeName, items[1].Name)
+	assert.Empty(t, items[1].Content)
+	assert.Equal(t, LoginTypeName, items[2].Name)
+	assert.Equal(t, testLogins, items[2].Content)
+}
+
+func TestGathererFailsWithoutProcesses(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectProcesses = func(context context.T) ([]model.ProcessData, error) {
+		return nil, fmt.Errorf("ps not found")
+	}
+
+	_, err := gatherer.Run(contextMock, model.Config{})
+
+	assert.Error(t, err)
+}
+
+func TestTruncateKeepsWholeRunes(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 5))
+	assert.Equal(t, "ab", truncate("abé", 3))
+	assert.Equal(t, "abé", truncate("abéd", 4))
+}
@@ -21,11 +21,13 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/forensics"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/unexpectedrestart"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/windowsUpdate"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 )
@@ -60,10 +62,12 @@ func InitializeGatherers(context context.T) (SupportedGatherer, InstalledGathere
 		network.GathererName:                     network.Gatherer(context),
 		windowsUpdate.GathererName:               windowsUpdate.Gatherer(context),
 		file.GathererName:                        file.Gatherer(context),
+		forensics.GathererName:                   forensics.Gatherer(context),
 		instancedetailedinformation.GathererName: instancedetailedinformation.Gatherer(context),
 		role.GathererName:                        role.Gatherer(context),
 		service.GathererName:                     service.Gatherer(context),
 		registry.GathererName:                    registry.Gatherer(context),
+		unexpectedrestart.GathererName:           unexpectedrestart.Gatherer(context),
 	}
 
 	for key := range installedGatherer {
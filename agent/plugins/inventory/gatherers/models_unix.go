@@ -21,8 +21,10 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/forensics"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/unexpectedrestart"
 )
 
 var supportedGathererNames = []string{
@@ -31,5 +33,7 @@ var supportedGathererNames = []string{
 	custom.GathererName,
 	network.GathererName,
 	file.GathererName,
+	forensics.GathererName,
 	instancedetailedinformation.GathererName,
+	unexpectedrestart.GathererName,
 }
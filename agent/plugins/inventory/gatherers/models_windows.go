@@ -22,11 +22,13 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/forensics"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/unexpectedrestart"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/windowsUpdate"
 )
 
@@ -37,8 +39,10 @@ var supportedGathererNames = []string{
 	network.GathererName,
 	windowsUpdate.GathererName,
 	file.GathererName,
+	forensics.GathererName,
 	instancedetailedinformation.GathererName,
 	role.GathererName,
 	service.GathererName,
 	registry.GathererName,
+	unexpectedrestart.GathererName,
 }
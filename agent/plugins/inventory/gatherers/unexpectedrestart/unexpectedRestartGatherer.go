@@ -0,0 +1,127 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package unexpectedrestart contains a gatherer that reports the agent runs that ended without a clean shutdown,
+// detected by the agent when it starts. It only runs when enabled in the inventory policy.
+package unexpectedrestart
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/bootdiag"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// GathererName captures name of unexpected restart gatherer
+	GathererName = "UnexpectedRestarts"
+	// TypeName is the inventory type of the unexpected restarts
+	TypeName = "Custom:UnexpectedRestart"
+	// SchemaVersionOfUnexpectedRestartGatherer represents schema version of unexpected restart gatherer
+	SchemaVersionOfUnexpectedRestartGatherer = "1.0"
+
+	// maxEvidenceLength is the length of the evidence of a restart, shared by its sources
+	maxEvidenceLength = 4096
+)
+
+type T struct{}
+
+// Gatherer returns new unexpected restart gatherer
+func Gatherer(context context.T) *T {
+	return new(T)
+}
+
+var getRecords = bootdiag.Records
+
+// Name returns name of unexpected restart gatherer
+func (t *T) Name() string {
+	return GathererName
+}
+
+// Run executes unexpected restart gatherer and returns the recorded unexpected restarts
+func (t *T) Run(context context.T, configuration model.Config) (items []model.Item, err error) {
+	var records []bootdiag.Record
+	if records, err = getRecords(); err != nil {
+		return nil, err
+	}
+	data := make([]model.UnexpectedRestartData, 0, len(records))
+	for _, record := range records {
+		data = append(data, model.UnexpectedRestartData{
+			DetectionTime:          formatTime(record.DetectionTime),
+			Cause:                  record.Cause,
+			PreviousAgentStartTime: formatTime(record.PreviousAgentStartTime),
+			PreviousAgentVersion:   record.PreviousAgentVersion,
+			PreviousBootTime:       formatTime(record.PreviousBootTime),
+			BootTime:               formatTime(record.BootTime),
+			CrashDump:              record.CrashDump,
+			Evidence:               formatEvidence(record.Evidence),
+		})
+	}
+	context.Log().Infof("%v unexpected restarts recorded", len(data))
+
+	//CaptureTime must comply with format: 2016-07-30T18:15:37Z to comply with regex at SSM.
+	captureTime := time.Now().UTC().Format(time.RFC3339)
+	items = append(items, model.Item{
+		Name:          TypeName,
+		SchemaVersion: SchemaVersionOfUnexpectedRestartGatherer,
+		Content:       data,
+		CaptureTime:   captureTime,
+	})
+	return
+}
+
+// RequestStop stops the execution of unexpected restart gatherer.
+func (t *T) RequestStop(stopType contracts.StopType) error {
+	var err error
+	return err
+}
+
+// formatTime formats the times in the format of the inventory, empty if unknown.
+func formatTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.UTC().Format(time.RFC3339)
+}
+
+// formatEvidence joins the last lines of each source of evidence under its name, sharing the maximum length.
+func formatEvidence(evidence map[string]string) string {
+	if len(evidence) == 0 {
+		return ""
+	}
+	sources := make([]string, 0, len(evidence))
+	for source := range evidence {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	length := maxEvidenceLength / len(sources)
+	sections := make([]string, 0, len(sources))
+	for _, source := range sources {
+		section := fmt.Sprintf("[%v]\n", source)
+		lines := evidence[source]
+		if available := length - len(section); len(lines) > available {
+			if available < 0 {
+				available = 0
+			}
+			lines = lines[len(lines)-available:]
+		}
+		sections = append(sections, section+lines)
+	}
+	return strings.Join(sections, "\n")
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package unexpectedrestart
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/bootdiag"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatherer(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	start := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	getRecords = func() ([]bootdiag.Record, error) {
+		return []bootdiag.Record{{
+			DetectionTime:          start.Add(time.Hour),
+			Cause:                  bootdiag.CauseReboot,
+			PreviousAgentStartTime: start,
+			PreviousAgentVersion:   "2.2.0.0",
+			BootTime:               start.Add(59 * time.Minute),
+			Evidence:               map[string]string{"kernel": "Kernel panic", "agentLog": "last line"},
+		}}, nil
+	}
+	defer func() { getRecords = bootdiag.Records }()
+
+	items, err := gatherer.Run(contextMock, model.Config{})
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, TypeName, items[0].Name)
+	assert.Equal(t, SchemaVersionOfUnexpectedRestartGatherer, items[0].SchemaVersion)
+	assert.Equal(t, []model.UnexpectedRestartData{{
+		DetectionTime:          "2026-10-15T11:00:00Z",
+		Cause:                  bootdiag.CauseReboot,
+		PreviousAgentStartTime: "2026-10-15T10:00:00Z",
+		PreviousAgentVersion:   "2.2.0.0",
+		BootTime:               "2026-10-15T10:59:00Z",
+		Evidence:               "[agentLog]\nlast line\n[kernel]\nKernel panic",
+	}}, items[0].Content)
+}
+
+func TestGathererError(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	getRecords = func() ([]bootdiag.Record, error) { return nil, errors.New("invalid records") }
+	defer func() { getRecords = bootdiag.Records }()
+
+	_, err := Gatherer(contextMock).Run(contextMock, model.Config{})
+	assert.Error(t, err)
+}
+
+func TestFormatEvidenceLength(t *testing.T) {
+	evidence := formatEvidence(map[string]string{
+		"kernel":   strings.Repeat("k", maxEvidenceLength),
+		"agentLog": strings.Repeat("a", maxEvidenceLength) + "end",
+	})
+	assert.True(t, len(evidence) <= maxEvidenceLength+1)
+	assert.Contains(t, evidence, "end\n[kernel]")
+}
@@ -34,11 +34,13 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/forensics"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/unexpectedrestart"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/windowsUpdate"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
@@ -57,6 +59,9 @@ const (
 	errorMsgForInabilityToSendDataToSSM       = "Unable to upload inventory data to SSM"
 	msgWhenNoDataToReturnForInventoryPlugin   = "Inventory policy has been successfully applied but there is no inventory data to upload to SSM"
 	successfulMsgForInventoryPlugin           = "Inventory policy has been successfully applied and collected inventory data has been uploaded to SSM"
+
+	// disabledGatherersSetting is the plugin setting of the gatherers the instance never runs
+	disabledGatherersSetting = "DisabledGatherers"
 )
 
 // PluginInput represents configuration which is applied to inventory plugin during execution.
@@ -71,6 +76,8 @@ type PluginInput struct {
 	WindowsRegistry             string
 	WindowsUpdates              string
 	InstanceDetailedInformation string
+	Forensics                   string
+	UnexpectedRestarts          string
 	CustomInventory             string
 	CustomInventoryDirectory    string
 }
@@ -326,6 +333,8 @@ func (p *Plugin) ValidateInventoryInput(context context.T, input PluginInput) (c
 		network.GathererName:                     input.NetworkConfig,
 		windowsUpdate.GathererName:               input.WindowsUpdates,
 		instancedetailedinformation.GathererName: input.InstanceDetailedInformation,
+		forensics.GathererName:                   input.Forensics,
+		unexpectedrestart.GathererName:           input.UnexpectedRestarts,
 	}
 
 	predefinedGatherersWithFilters := map[string]string{
@@ -363,6 +372,16 @@ func (p *Plugin) ValidateInventoryInput(context context.T, input PluginInput) (c
 		configuredGatherers[gatherer] = cfg
 	}
 
+	// the gatherers disabled in the agent configuration don't run, whatever the policy
+	settings := context.AppConfig().PluginSettings(appconfig.PluginNameAwsSoftwareInventory)
+	for _, name := range settings.StringList(disabledGatherersSetting, nil) {
+		for configuredGatherer := range configuredGatherers {
+			if strings.EqualFold(configuredGatherer.Name(), name) {
+				log.Infof("Gatherer %v is disabled by the agent configuration", configuredGatherer.Name())
+				delete(configuredGatherers, configuredGatherer)
+			}
+		}
+	}
 	return
 }
 
@@ -19,11 +19,16 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/forensics"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 // MockInventoryPlugin returns mock inventory plugin
@@ -199,3 +204,27 @@ func TestPlugin_IsMulitpleAssociationPresent(t *testing.T) {
 	assert.True(t, status)
 	assert.Equal(t, "testAssociationID2", other)
 }
+
+func TestValidateInventoryInputDisabledGatherers(t *testing.T) {
+	names := []string{application.GathererName, forensics.GathererName}
+	p, _ := MockInventoryPlugin(names, names)
+	for _, name := range names {
+		p.installedGatherers[name].(*gatherers.Mock).On("Name").Return(name)
+		p.supportedGatherers[name] = p.installedGatherers[name]
+	}
+
+	config := appconfig.SsmagentConfig{Plugins: appconfig.PluginsCfg{
+		appconfig.PluginNameAwsSoftwareInventory: {"DisabledGatherers": []interface{}{"forensicssnapshot"}},
+	}}
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+
+	configured, err := p.ValidateInventoryInput(ctx, PluginInput{Applications: model.Enabled, Forensics: model.Enabled})
+	assert.NoError(t, err)
+	assert.Len(t, configured, 1)
+	for gatherer := range configured {
+		assert.Equal(t, application.GathererName, gatherer.Name())
+	}
+}
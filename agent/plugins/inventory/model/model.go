@@ -167,6 +167,43 @@ type InstanceDetailedInformation struct {
 	OSServicePack         string
 }
 
+// ProcessData captures all attributes present in Custom:ForensicsProcess inventory type
+type ProcessData struct {
+	ProcessId       string
+	ParentProcessId string
+	Name            string
+	User            string
+	StartTime       string
+	CommandLine     string
+}
+
+// OpenFileData captures all attributes present in Custom:ForensicsOpenFile inventory type
+type OpenFileData struct {
+	ProcessId string
+	Path      string
+}
+
+// LoginData captures all attributes present in Custom:ForensicsLogin inventory type
+type LoginData struct {
+	User     string
+	Terminal string
+	Source   string
+	Time     string
+	Details  string
+}
+
+// UnexpectedRestartData captures all attributes present in Custom:UnexpectedRestart inventory type
+type UnexpectedRestartData struct {
+	DetectionTime          string
+	Cause                  string
+	PreviousAgentStartTime string
+	PreviousAgentVersion   string
+	PreviousBootTime       string
+	BootTime               string
+	CrashDump              string
+	Evidence               string
+}
+
 // Config captures all various properties (including optional) that can be supplied to a gatherer.
 // NOTE: Not all properties will be applicable to all gatherers.
 // E.g: Applications gatherer uses Collection, Files use Filters, Custom uses Collection & Location.
@@ -0,0 +1,318 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package manageagentconfig implements the aws:manageAgentConfig plugin, which applies a selected set of
+// agent configuration changes sent through a document, so they don't have to be distributed as files.
+package manageagentconfig
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// logLevels are the log levels supported by seelog
+var logLevels = []string{"trace", "debug", "info", "warn", "error", "critical"}
+
+// minLevelPattern matches the minlevel attribute of the root element of the seelog configuration
+var minLevelPattern = regexp.MustCompile(`(<seelog\b[^>]*\sminlevel=")([^"]*)(")`)
+
+// appConfigPath and seelogConfigPath are the files the plugin updates
+var appConfigPath = appconfig.AppConfigPath
+var seelogConfigPath = log.DefaultSeelogConfigFilePath
+
+// Plugin is the type for the aws:manageAgentConfig plugin.
+type Plugin struct {
+}
+
+// ManageAgentConfigPluginInput represents the configuration changes requested by the document.
+// Settings that are omitted are left unchanged.
+type ManageAgentConfigPluginInput struct {
+	contracts.PluginInput
+	ID                          string
+	LogLevel                    string
+	HealthFrequencyMinutes      *int
+	AssociationFrequencyMinutes *int
+	CommandWorkersLimit         *int
+	BirdwatcherForceEnable      *bool
+}
+
+// configFile is the content of a configuration file before it was changed.
+type configFile struct {
+	path    string
+	content []byte
+	existed bool
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameManageAgentConfig
+}
+
+// Execute validates the requested configuration changes and applies them. The configuration files are
+// restored if the changed configuration doesn't load back as requested.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input ManageAgentConfigPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if err := validate(input); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	changes, err := apply(log, input)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	output.AppendInfo(strings.Join(changes, "\n"))
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// validate checks the requested values against the limits the agent applies to its configuration.
+func validate(input ManageAgentConfigPluginInput) error {
+	var errs []string
+	if input.LogLevel == "" && input.HealthFrequencyMinutes == nil && input.AssociationFrequencyMinutes == nil &&
+		input.CommandWorkersLimit == nil && input.BirdwatcherForceEnable == nil {
+		return fmt.Errorf("no configuration change requested")
+	}
+	if input.LogLevel != "" && !isLogLevel(input.LogLevel) {
+		errs = append(errs, fmt.Sprintf("LogLevel %v is not one of %v", input.LogLevel, strings.Join(logLevels, ", ")))
+	}
+	if value := input.HealthFrequencyMinutes; value != nil &&
+		(*value < appconfig.DefaultSsmHealthFrequencyMinutesMin || *value > appconfig.DefaultSsmHealthFrequencyMinutesMax) {
+		errs = append(errs, fmt.Sprintf("HealthFrequencyMinutes must be between %v and %v",
+			appconfig.DefaultSsmHealthFrequencyMinutesMin, appconfig.DefaultSsmHealthFrequencyMinutesMax))
+	}
+	if value := input.AssociationFrequencyMinutes; value != nil &&
+		(*value < appconfig.DefaultSsmAssociationFrequencyMinutesMin || *value > appconfig.DefaultSsmAssociationFrequencyMinutesMax) {
+		errs = append(errs, fmt.Sprintf("AssociationFrequencyMinutes must be between %v and %v",
+			appconfig.DefaultSsmAssociationFrequencyMinutesMin, appconfig.DefaultSsmAssociationFrequencyMinutesMax))
+	}
+	if value := input.CommandWorkersLimit; value != nil && *value < appconfig.DefaultCommandWorkersLimitMin {
+		errs = append(errs, fmt.Sprintf("CommandWorkersLimit must be at least %v", appconfig.DefaultCommandWorkersLimitMin))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid agent configuration: %v", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// apply writes the requested changes and rolls them back if they don't load back as requested.
+// Returns a description of the applied changes.
+func apply(log log.T, input ManageAgentConfigPluginInput) (changes []string, err error) {
+	var backups []configFile
+	defer func() {
+		if err != nil {
+			for _, backup := range backups {
+				if restoreErr := restore(backup); restoreErr != nil {
+					log.Errorf("failed to restore %v: %v", backup.path, restoreErr)
+				}
+			}
+		}
+	}()
+
+	if input.LogLevel != "" {
+		var backup configFile
+		if backup, err = read(seelogConfigPath); err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+		if err = setLogLevel(backup, input.LogLevel); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("LogLevel set to %v, effective immediately", input.LogLevel))
+	}
+
+	if input.HealthFrequencyMinutes != nil || input.AssociationFrequencyMinutes != nil ||
+		input.CommandWorkersLimit != nil || input.BirdwatcherForceEnable != nil {
+		var backup configFile
+		if backup, err = read(appConfigPath); err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+		var applied []string
+		if applied, err = setAppConfig(backup, input); err != nil {
+			return nil, err
+		}
+		changes = append(changes, applied...)
+	}
+	return changes, nil
+}
+
+// setLogLevel changes the minimum level of the seelog configuration, the agent watches the file and
+// reloads it on change. Falls back to the default seelog configuration if there is none on disk.
+func setLogLevel(current configFile, level string) error {
+	content := current.content
+	if !current.existed {
+		content = log.DefaultConfig()
+	}
+	if !minLevelPattern.Match(content) {
+		return fmt.Errorf("%v has no minlevel to update", current.path)
+	}
+	content = minLevelPattern.ReplaceAll(content, []byte("${1}"+level+"${3}"))
+	if err := write(current.path, content); err != nil {
+		return err
+	}
+
+	var seelogConfig struct {
+		MinLevel string `xml:"minlevel,attr"`
+	}
+	if err := xml.Unmarshal(content, &seelogConfig); err != nil {
+		return fmt.Errorf("updated %v is not valid: %v", current.path, err)
+	}
+	if seelogConfig.MinLevel != level {
+		return fmt.Errorf("updated %v has log level %v instead of %v", current.path, seelogConfig.MinLevel, level)
+	}
+	return nil
+}
+
+// setAppConfig changes the requested settings of the agent configuration, keeping the settings that
+// aren't managed by the plugin, and verifies the agent loads them as requested.
+func setAppConfig(current configFile, input ManageAgentConfigPluginInput) (changes []string, err error) {
+	settings := make(map[string]interface{})
+	if current.existed {
+		if err = json.Unmarshal(current.content, &settings); err != nil {
+			return nil, fmt.Errorf("failed to parse %v: %v", current.path, err)
+		}
+	}
+	var overridden []string
+	set := func(section, name string, value interface{}) {
+		if variable := appconfig.EnvironmentVariable(section, name); os.Getenv(variable) != "" {
+			overridden = append(overridden, variable)
+		}
+		values, ok := settings[section].(map[string]interface{})
+		if !ok {
+			values = make(map[string]interface{})
+			settings[section] = values
+		}
+		values[name] = value
+		// the agent reloads its configuration file on change
+		effective := "effective when the agent restarts"
+		if appconfig.IsReloadable(section + "." + name) {
+			effective = "effective immediately"
+		}
+		changes = append(changes, fmt.Sprintf("%v.%v set to %v, %v", section, name, value, effective))
+	}
+	if input.HealthFrequencyMinutes != nil {
+		set("Ssm", "HealthFrequencyMinutes", *input.HealthFrequencyMinutes)
+	}
+	if input.AssociationFrequencyMinutes != nil {
+		set("Ssm", "AssociationFrequencyMinutes", *input.AssociationFrequencyMinutes)
+	}
+	if input.CommandWorkersLimit != nil {
+		set("Mds", "CommandWorkersLimit", *input.CommandWorkersLimit)
+	}
+	if input.BirdwatcherForceEnable != nil {
+		set("Birdwatcher", "ForceEnable", *input.BirdwatcherForceEnable)
+	}
+	if len(overridden) > 0 {
+		return nil, fmt.Errorf("%v set in the environment of the agent, overriding the configuration file", strings.Join(overridden, ", "))
+	}
+
+	var content string
+	if content, err = jsonutil.MarshalIndent(settings); err != nil {
+		return nil, err
+	}
+	if err = write(current.path, []byte(content)); err != nil {
+		return nil, err
+	}
+
+	var loaded appconfig.SsmagentConfig
+	if loaded, err = appconfig.LoadConfigFile(current.path); err != nil {
+		return nil, fmt.Errorf("updated %v is not valid: %v", current.path, err)
+	}
+	if (input.HealthFrequencyMinutes != nil && loaded.Ssm.HealthFrequencyMinutes != *input.HealthFrequencyMinutes) ||
+		(input.AssociationFrequencyMinutes != nil && loaded.Ssm.AssociationFrequencyMinutes != *input.AssociationFrequencyMinutes) ||
+		(input.CommandWorkersLimit != nil && loaded.Mds.CommandWorkersLimit != *input.CommandWorkersLimit) ||
+		(input.BirdwatcherForceEnable != nil && loaded.Birdwatcher.ForceEnable != *input.BirdwatcherForceEnable) {
+		return nil, fmt.Errorf("updated %v doesn't load as requested", current.path)
+	}
+	return changes, nil
+}
+
+// read returns the current content of a configuration file, if any.
+func read(path string) (configFile, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return configFile{path: path}, nil
+	} else if err != nil {
+		return configFile{}, fmt.Errorf("failed to read %v: %v", path, err)
+	}
+	return configFile{path: path, content: content, existed: true}, nil
+}
+
+// write replaces a configuration file with a rename so the agent never reads a partially written file.
+func write(path string, content []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("failed to write %v: %v", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %v: %v", path, err)
+	}
+	return nil
+}
+
+// restore puts back a configuration file the way it was before the plugin changed it.
+func restore(backup configFile) error {
+	if !backup.existed {
+		if err := os.Remove(backup.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return write(backup.path, backup.content)
+}
+
+// isLogLevel returns true if the level is supported by seelog.
+func isLogLevel(level string) bool {
+	for _, logLevel := range logLevels {
+		if level == logLevel {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package manageagentconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+func setupConfigFiles(t *testing.T) (dir string) {
+	dir, err := ioutil.TempDir("", "manageagentconfig")
+	assert.NoError(t, err)
+	appConfigPath = filepath.Join(dir, appconfig.AppConfigFileName)
+	seelogConfigPath = filepath.Join(dir, appconfig.SeelogConfigFileName)
+	return dir
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, validate(ManageAgentConfigPluginInput{LogLevel: "debug"}))
+	assert.NoError(t, validate(ManageAgentConfigPluginInput{HealthFrequencyMinutes: intPtr(appconfig.DefaultSsmHealthFrequencyMinutesMin)}))
+
+	assert.Error(t, validate(ManageAgentConfigPluginInput{}))
+	assert.Error(t, validate(ManageAgentConfigPluginInput{LogLevel: "verbose"}))
+	assert.Error(t, validate(ManageAgentConfigPluginInput{HealthFrequencyMinutes: intPtr(appconfig.DefaultSsmHealthFrequencyMinutesMax + 1)}))
+	assert.Error(t, validate(ManageAgentConfigPluginInput{AssociationFrequencyMinutes: intPtr(0)}))
+	assert.Error(t, validate(ManageAgentConfigPluginInput{CommandWorkersLimit: intPtr(0)}))
+}
+
+func TestApplyKeepsUnmanagedSettings(t *testing.T) {
+	dir := setupConfigFiles(t)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(appConfigPath, []byte(`{"Agent": {"Region": "us-east-1"}, "Ssm": {"Endpoint": "ssm.example.com"}}`), 0600))
+	forceEnable := true
+
+	changes, err := apply(logger, ManageAgentConfigPluginInput{
+		LogLevel:               "debug",
+		HealthFrequencyMinutes: intPtr(10),
+		BirdwatcherForceEnable: &forceEnable,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(changes))
+	loaded, err := appconfig.LoadConfigFile(appConfigPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, loaded.Ssm.HealthFrequencyMinutes)
+	assert.Equal(t, "ssm.example.com", loaded.Ssm.Endpoint)
+	assert.Equal(t, "us-east-1", loaded.Agent.Region)
+	assert.True(t, loaded.Birdwatcher.ForceEnable)
+	seelogConfig, err := ioutil.ReadFile(seelogConfigPath)
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(string(seelogConfig), `critmsgcount="500" minlevel="debug"`))
+	// the exceptions keep their own level
+	assert.True(t, strings.Contains(string(seelogConfig), `<exception filepattern="test*" minlevel="error"/>`))
+}
+
+func TestApplyRollsBackOnFailure(t *testing.T) {
+	dir := setupConfigFiles(t)
+	defer os.RemoveAll(dir)
+	invalidAppConfig := []byte(`{"Ssm": `)
+	assert.NoError(t, ioutil.WriteFile(appConfigPath, invalidAppConfig, 0600))
+
+	_, err := apply(logger, ManageAgentConfigPluginInput{LogLevel: "debug", CommandWorkersLimit: intPtr(10)})
+
+	assert.Error(t, err)
+	// the seelog configuration didn't exist before, it is removed
+	_, err = os.Stat(seelogConfigPath)
+	assert.True(t, os.IsNotExist(err))
+	content, err := ioutil.ReadFile(appConfigPath)
+	assert.NoError(t, err)
+	assert.Equal(t, invalidAppConfig, content)
+}
+
+func TestApplyRefusesSettingsOverriddenByEnvironment(t *testing.T) {
+	dir := setupConfigFiles(t)
+	defer os.RemoveAll(dir)
+	variable := appconfig.EnvironmentVariable("Mds", "CommandWorkersLimit")
+	os.Setenv(variable, "3")
+	defer os.Unsetenv(variable)
+
+	_, err := apply(logger, ManageAgentConfigPluginInput{CommandWorkersLimit: intPtr(10)})
+
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "AMAZON_SSM_AGENT_MDS_COMMANDWORKERSLIMIT"))
+	_, err = os.Stat(appConfigPath)
+	assert.True(t, os.IsNotExist(err), "the configuration file is left unchanged")
+}
+
+func TestSetLogLevelWithoutMinLevel(t *testing.T) {
+	dir := setupConfigFiles(t)
+	defer os.RemoveAll(dir)
+	seelogConfig := []byte(`<seelog type="sync"></seelog>`)
+	assert.NoError(t, ioutil.WriteFile(seelogConfigPath, seelogConfig, 0600))
+
+	_, err := apply(logger, ManageAgentConfigPluginInput{LogLevel: "debug"})
+
+	assert.Error(t, err)
+	content, err := ioutil.ReadFile(seelogConfigPath)
+	assert.NoError(t, err)
+	assert.Equal(t, seelogConfig, content)
+}
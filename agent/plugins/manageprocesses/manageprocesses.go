@@ -0,0 +1,348 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package manageprocesses implements the aws:manageProcesses plugin, which lists, signals, kills or lowers the
+// priority of the processes matching name and command line patterns, with the same semantics on all platforms.
+// The critical system processes and the agent are never acted on.
+package manageprocesses
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// ActionList lists the matching processes
+	ActionList = "list"
+
+	// ActionSignal sends a signal to the matching processes
+	ActionSignal = "signal"
+
+	// ActionKill terminates the matching processes, forcibly once the grace period is over
+	ActionKill = "kill"
+
+	// ActionLimit lowers the scheduling priority of the matching processes
+	ActionLimit = "limit"
+
+	// PriorityBelowNormal is nice 10, or the BelowNormal priority class on Windows
+	PriorityBelowNormal = "belowNormal"
+
+	// PriorityIdle is nice 19, or the Idle priority class on Windows
+	PriorityIdle = "idle"
+
+	// defaultMaxMatches is the number of processes a signal, kill or limit acts on at most unless set
+	defaultMaxMatches = 10
+
+	// maxMaxMatches is the maximum of MaxMatches
+	maxMaxMatches = 1000
+
+	// defaultGracePeriodSeconds is how long kill waits for the processes to exit before killing them forcibly
+	defaultGracePeriodSeconds = 10
+
+	// maxGracePeriodSeconds is the maximum of GracePeriodSeconds
+	maxGracePeriodSeconds = 300
+)
+
+// actions and priorities are the supported actions and priorities
+var actions = []string{ActionList, ActionSignal, ActionKill, ActionLimit}
+var priorities = []string{PriorityBelowNormal, PriorityIdle}
+
+// agentProcessNames are the processes of the agent, protected on all platforms
+var agentProcessNames = []string{"amazon-ssm-agent", "ssm-agent-worker", "ssm-document-worker", "ssm-session-worker", "ssm-session-logger"}
+
+// listProcesses, signalProcess, killProcess and lowerPriority act on the processes of the instance
+var listProcesses = systemListProcesses
+var signalProcess = systemSignalProcess
+var killProcess = systemKillProcess
+var lowerPriority = systemLowerPriority
+
+// Plugin is the type for the aws:manageProcesses plugin.
+type Plugin struct {
+}
+
+// ManageProcessesPluginInput represents the processes to act on and the action.
+type ManageProcessesPluginInput struct {
+	contracts.PluginInput
+	ID string
+	// Action is list, signal, kill or limit
+	Action string
+	// NamePattern is a regular expression matching the executable name of the processes
+	NamePattern string
+	// CommandLinePattern is a regular expression matching the command line of the processes
+	CommandLinePattern string
+	// User only matches the processes of this user if set
+	User string
+	// Signal is the signal sent by the signal action, e.g. HUP or SIGUSR1, not supported on Windows
+	Signal string
+	// GracePeriodSeconds is how long kill waits for the processes to exit before killing them forcibly, 10 by default
+	GracePeriodSeconds int
+	// Priority is the priority set by the limit action, belowNormal by default or idle
+	Priority string
+	// MaxMatches fails the plugin without acting if more processes match, 10 by default
+	MaxMatches int
+
+	namePattern        *regexp.Regexp
+	commandLinePattern *regexp.Regexp
+}
+
+// Process is a running process.
+type Process struct {
+	PID         int    `json:"pid"`
+	ParentPID   int    `json:"parentPid"`
+	User        string `json:"user,omitempty"`
+	Name        string `json:"name"`
+	CommandLine string `json:"commandLine,omitempty"`
+}
+
+// ProcessResult is a matching process and the outcome of the action on it.
+type ProcessResult struct {
+	Process
+	// Protected is the reason the process was not acted on
+	Protected string `json:"protected,omitempty"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ManageProcessesOutput is the outcome of the action, set as the output of the plugin.
+type ManageProcessesOutput struct {
+	Action    string          `json:"action"`
+	Processes []ProcessResult `json:"processes"`
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameManageProcesses
+}
+
+// Execute finds the matching processes and acts on them.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input ManageProcessesPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if err := validate(&input); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	processes, err := listProcesses()
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to list the processes: %v", err))
+		return
+	}
+	result := ManageProcessesOutput{Action: input.Action, Processes: match(processes, input)}
+	targets := 0
+	for _, process := range result.Processes {
+		if process.Protected == "" {
+			targets++
+		}
+	}
+	if input.Action != ActionList && targets > input.MaxMatches {
+		output.MarkAsFailed(fmt.Errorf("%d processes match, more than MaxMatches %d; nothing was done", targets, input.MaxMatches))
+		return
+	}
+
+	failed := 0
+	for i := range result.Processes {
+		process := &result.Processes[i]
+		if cancelFlag.ShutDown() {
+			output.MarkAsShutdown()
+			return
+		} else if cancelFlag.Canceled() {
+			output.MarkAsCancelled()
+			return
+		}
+		if process.Protected != "" {
+			output.AppendInfof("%d %v: skipped, %v", process.PID, process.Name, process.Protected)
+			continue
+		}
+		if process.Result, err = act(input, process.PID); err != nil {
+			failed++
+			process.Error = err.Error()
+			output.AppendErrorf("%d %v: %v", process.PID, process.Name, err)
+			continue
+		}
+		log.Infof("%v %d %v: %v", input.Action, process.PID, process.Name, process.Result)
+		output.AppendInfof("%d %v: %v", process.PID, process.Name, process.Result)
+	}
+	if len(result.Processes) == 0 {
+		output.AppendInfo("no process matches")
+	}
+
+	if content, err := jsonutil.Marshal(result); err == nil {
+		output.SetOutput(content)
+	}
+	if failed > 0 {
+		output.MarkAsFailed(fmt.Errorf("%v failed for %d of %d processes", input.Action, failed, targets))
+		return
+	}
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// validate checks the input, compiles the patterns and sets the defaults.
+func validate(input *ManageProcessesPluginInput) (err error) {
+	if input.Action == "" {
+		input.Action = ActionList
+	}
+	if !containsFold(actions, input.Action) {
+		return fmt.Errorf("Action must be one of %v, got %q", strings.Join(actions, ", "), input.Action)
+	}
+	input.Action = strings.ToLower(input.Action)
+	if input.Action != ActionList && input.NamePattern == "" && input.CommandLinePattern == "" {
+		return fmt.Errorf("NamePattern or CommandLinePattern is required to %v processes", input.Action)
+	}
+	if input.NamePattern != "" {
+		if input.namePattern, err = regexp.Compile(input.NamePattern); err != nil {
+			return fmt.Errorf("invalid NamePattern: %v", err)
+		}
+	}
+	if input.CommandLinePattern != "" {
+		if input.commandLinePattern, err = regexp.Compile(input.CommandLinePattern); err != nil {
+			return fmt.Errorf("invalid CommandLinePattern: %v", err)
+		}
+	}
+	if input.Action == ActionSignal {
+		if len(signals) == 0 {
+			return fmt.Errorf("the signal action is not supported on this platform, use kill")
+		}
+		input.Signal = strings.TrimPrefix(strings.ToUpper(input.Signal), "SIG")
+		if _, found := signals[input.Signal]; !found {
+			return fmt.Errorf("Signal must be one of %v, got %q", strings.Join(signalNames(), ", "), input.Signal)
+		}
+	}
+	if input.GracePeriodSeconds == 0 {
+		input.GracePeriodSeconds = defaultGracePeriodSeconds
+	}
+	if input.GracePeriodSeconds < 0 || input.GracePeriodSeconds > maxGracePeriodSeconds {
+		return fmt.Errorf("GracePeriodSeconds must be between 1 and %d, got %d", maxGracePeriodSeconds, input.GracePeriodSeconds)
+	}
+	if input.Priority == "" {
+		input.Priority = PriorityBelowNormal
+	}
+	for _, priority := range priorities {
+		if strings.EqualFold(priority, input.Priority) {
+			input.Priority = priority
+		}
+	}
+	if !containsFold(priorities, input.Priority) {
+		return fmt.Errorf("Priority must be one of %v, got %q", strings.Join(priorities, ", "), input.Priority)
+	}
+	if input.MaxMatches == 0 {
+		input.MaxMatches = defaultMaxMatches
+	}
+	if input.MaxMatches < 0 || input.MaxMatches > maxMaxMatches {
+		return fmt.Errorf("MaxMatches must be between 1 and %d, got %d", maxMaxMatches, input.MaxMatches)
+	}
+	return nil
+}
+
+// match returns the processes matching the patterns and the user, with the reason the protected ones are
+// not acted on.
+func match(processes []Process, input ManageProcessesPluginInput) (matched []ProcessResult) {
+	for _, process := range processes {
+		if input.namePattern != nil && !input.namePattern.MatchString(process.Name) {
+			continue
+		}
+		if input.commandLinePattern != nil && !input.commandLinePattern.MatchString(process.CommandLine) {
+			continue
+		}
+		if input.User != "" && !strings.EqualFold(input.User, process.User) {
+			continue
+		}
+		matched = append(matched, ProcessResult{Process: process, Protected: protection(process)})
+	}
+	return matched
+}
+
+// protection returns the reason a process must not be acted on, empty if it can be.
+func protection(process Process) string {
+	if process.PID == os.Getpid() || process.PID == os.Getppid() {
+		return "process of the agent"
+	}
+	name := strings.TrimSuffix(strings.ToLower(process.Name), ".exe")
+	if containsFold(agentProcessNames, name) {
+		return "process of the agent"
+	}
+	if isSystemProcess(process) || containsFold(criticalProcessNames, name) {
+		return "critical system process"
+	}
+	return ""
+}
+
+// act runs the action on a process and returns its outcome.
+func act(input ManageProcessesPluginInput, pid int) (string, error) {
+	switch input.Action {
+	case ActionSignal:
+		if err := signalProcess(pid, input.Signal); err != nil {
+			return "", err
+		}
+		return "sent SIG" + input.Signal, nil
+	case ActionKill:
+		return killProcess(pid, time.Duration(input.GracePeriodSeconds)*time.Second)
+	case ActionLimit:
+		if err := lowerPriority(pid, input.Priority); err != nil {
+			return "", err
+		}
+		return "priority set to " + input.Priority, nil
+	}
+	return "running", nil
+}
+
+// signalNames returns the names of the supported signals, in order.
+func signalNames() []string {
+	names := make([]string, 0, len(signals))
+	for name := range signals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// containsFold returns true if the value is one of the values, ignoring the case.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
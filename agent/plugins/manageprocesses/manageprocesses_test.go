@@ -0,0 +1,129 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package manageprocesses
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	input := ManageProcessesPluginInput{}
+	assert.NoError(t, validate(&input))
+	assert.Equal(t, ActionList, input.Action)
+	assert.Equal(t, defaultMaxMatches, input.MaxMatches)
+	assert.Equal(t, defaultGracePeriodSeconds, input.GracePeriodSeconds)
+
+	input = ManageProcessesPluginInput{Action: "Limit", NamePattern: "^java$", Priority: "IDLE"}
+	assert.NoError(t, validate(&input))
+	assert.Equal(t, ActionLimit, input.Action)
+	assert.Equal(t, PriorityIdle, input.Priority)
+	assert.True(t, input.namePattern.MatchString("java"))
+
+	invalid := []ManageProcessesPluginInput{
+		{Action: "suspend", NamePattern: "java"},
+		{Action: "kill"},
+		{Action: "kill", NamePattern: "("},
+		{Action: "list", CommandLinePattern: "["},
+		{Action: "limit", NamePattern: "java", Priority: "realtime"},
+		{Action: "kill", NamePattern: "java", GracePeriodSeconds: maxGracePeriodSeconds + 1},
+		{Action: "kill", NamePattern: "java", MaxMatches: -1},
+	}
+	for _, input := range invalid {
+		assert.Error(t, validate(&input), "%+v", input)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	processes := []Process{
+		{PID: 1, Name: "init", CommandLine: "/sbin/init"},
+		{PID: 100, ParentPID: 1, User: "app", Name: "java", CommandLine: "/usr/bin/java -jar app.jar"},
+		{PID: 101, ParentPID: 1, User: "root", Name: "java", CommandLine: "/usr/bin/java -jar admin.jar"},
+		{PID: 102, ParentPID: 1, User: "root", Name: "amazon-ssm-agent", CommandLine: "/usr/bin/amazon-ssm-agent"},
+		{PID: os.Getpid(), ParentPID: 1, User: "root", Name: "worker", CommandLine: "worker"},
+	}
+
+	input := ManageProcessesPluginInput{Action: ActionKill, NamePattern: "java", CommandLinePattern: `app\.jar`}
+	assert.NoError(t, validate(&input))
+	assert.Equal(t, []ProcessResult{{Process: processes[1]}}, match(processes, input))
+
+	input = ManageProcessesPluginInput{User: "ROOT"}
+	assert.NoError(t, validate(&input))
+	matched := match(processes, input)
+	assert.Len(t, matched, 3)
+	assert.Empty(t, matched[0].Protected)
+	assert.Equal(t, "process of the agent", matched[1].Protected)
+	assert.Equal(t, "process of the agent", matched[2].Protected)
+
+	input = ManageProcessesPluginInput{}
+	assert.NoError(t, validate(&input))
+	assert.Equal(t, "critical system process", match(processes, input)[0].Protected)
+}
+
+func TestExecute(t *testing.T) {
+	processes := []Process{
+		{PID: 1, Name: "init", CommandLine: "/sbin/init"},
+		{PID: 100, ParentPID: 1, Name: "java", CommandLine: "java -jar app.jar"},
+		{PID: 101, ParentPID: 1, Name: "java", CommandLine: "java -jar worker.jar"},
+	}
+	var killed []int
+	listProcesses = func() ([]Process, error) { return processes, nil }
+	killProcess = func(pid int, gracePeriod time.Duration) (string, error) {
+		if pid == 101 {
+			return "", fmt.Errorf("operation not permitted")
+		}
+		killed = append(killed, pid)
+		return "terminated", nil
+	}
+	defer func() {
+		listProcesses = systemListProcesses
+		killProcess = systemKillProcess
+	}()
+
+	execute := func(properties map[string]interface{}) *iohandler.DefaultIOHandler {
+		output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+		plugin, _ := NewPlugin()
+		plugin.Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, task.NewChanneledCancelFlag(), output)
+		return output
+	}
+
+	output := execute(map[string]interface{}{"Action": "kill", "NamePattern": "^(init|java)$", "MaxMatches": 1})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "2 processes match, more than MaxMatches 1")
+	assert.Empty(t, killed, "nothing is killed when too many processes match")
+
+	output = execute(map[string]interface{}{"Action": "kill", "NamePattern": "^(init|java)$"})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, []int{100}, killed, "init is protected")
+	var result ManageProcessesOutput
+	assert.NoError(t, jsonutil.Unmarshal(output.GetOutput().(string), &result))
+	assert.Equal(t, "critical system process", result.Processes[0].Protected)
+	assert.Equal(t, "terminated", result.Processes[1].Result)
+	assert.Equal(t, "operation not permitted", result.Processes[2].Error)
+	assert.Contains(t, output.GetStderr(), "kill failed for 1 of 2 processes")
+
+	output = execute(map[string]interface{}{"NamePattern": "python"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Contains(t, output.GetStdout(), "no process matches")
+}
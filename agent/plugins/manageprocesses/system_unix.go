@@ -0,0 +1,137 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package manageprocesses
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// psFormat lists the processes without header, the command line last as it contains spaces
+const psFormat = "pid=,ppid=,user=,args="
+
+// signals are the signals the signal action sends
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+	"STOP": syscall.SIGSTOP,
+	"CONT": syscall.SIGCONT,
+}
+
+// niceValues are the nice values of the priorities
+var niceValues = map[string]int{
+	PriorityBelowNormal: 10,
+	PriorityIdle:        19,
+}
+
+// criticalProcessNames are the processes the system can't run without or can't be reached without
+var criticalProcessNames = []string{
+	"init", "systemd", "systemd-journald", "systemd-logind", "systemd-udevd", "systemd-networkd", "systemd-resolved",
+	"udevd", "dbus-daemon", "dbus-broker", "sshd", "launchd", "kernel_task",
+}
+
+// pollInterval is how often kill checks whether a process has exited during the grace period
+var pollInterval = 100 * time.Millisecond
+
+// runPs lists the processes
+var runPs = func() ([]byte, error) {
+	return exec.Command("ps", "-eo", psFormat).Output()
+}
+
+// systemListProcesses lists the processes with ps.
+func systemListProcesses() ([]Process, error) {
+	output, err := runPs()
+	if err != nil {
+		return nil, err
+	}
+	return parseProcesses(string(output)), nil
+}
+
+// parseProcesses parses the output of ps, the lines that can't be parsed are skipped.
+func parseProcesses(output string) (processes []Process) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		parentPID, _ := strconv.Atoi(fields[1])
+		process := Process{PID: pid, ParentPID: parentPID, User: fields[2], CommandLine: strings.Join(fields[3:], " ")}
+		if strings.HasPrefix(process.CommandLine, "[") {
+			// kernel threads have no executable
+			process.Name = strings.Trim(process.CommandLine, "[]")
+		} else {
+			process.Name = filepath.Base(fields[3])
+		}
+		processes = append(processes, process)
+	}
+	return processes
+}
+
+// isSystemProcess returns true for init and the kernel threads.
+func isSystemProcess(process Process) bool {
+	if process.PID <= 1 {
+		return true
+	}
+	if runtime.GOOS == "linux" && (process.PID == 2 || process.ParentPID == 2) {
+		return true
+	}
+	return strings.HasPrefix(process.CommandLine, "[") && strings.HasSuffix(process.CommandLine, "]")
+}
+
+// systemSignalProcess sends the signal to the process.
+func systemSignalProcess(pid int, signal string) error {
+	return syscall.Kill(pid, signals[signal])
+}
+
+// systemKillProcess sends SIGTERM to the process, and SIGKILL if it is still running after the grace period.
+func systemKillProcess(pid int, gracePeriod time.Duration) (string, error) {
+	if err := syscall.Kill(pid, syscall.SIGTERM); err == syscall.ESRCH {
+		return "already exited", nil
+	} else if err != nil {
+		return "", err
+	}
+	for deadline := time.Now().Add(gracePeriod); time.Now().Before(deadline); time.Sleep(pollInterval) {
+		if syscall.Kill(pid, 0) == syscall.ESRCH {
+			return "terminated", nil
+		}
+	}
+	if err := syscall.Kill(pid, syscall.SIGKILL); err == syscall.ESRCH {
+		return "terminated", nil
+	} else if err != nil {
+		return "", fmt.Errorf("still running after %v: %v", gracePeriod, err)
+	}
+	return fmt.Sprintf("killed, still running after %v", gracePeriod), nil
+}
+
+// systemLowerPriority sets the nice value of the priority.
+func systemLowerPriority(pid int, priority string) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, niceValues[priority])
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package manageprocesses
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProcesses(t *testing.T) {
+	output := `    1     0 root     /sbin/init splash
+    2     0 root     [kthreadd]
+ 1234     1 app      /usr/bin/java -jar /opt/app/app.jar
+ ps: invalid line
+`
+	processes := parseProcesses(output)
+	assert.Equal(t, []Process{
+		{PID: 1, ParentPID: 0, User: "root", Name: "init", CommandLine: "/sbin/init splash"},
+		{PID: 2, ParentPID: 0, User: "root", Name: "kthreadd", CommandLine: "[kthreadd]"},
+		{PID: 1234, ParentPID: 1, User: "app", Name: "java", CommandLine: "/usr/bin/java -jar /opt/app/app.jar"},
+	}, processes)
+	assert.True(t, isSystemProcess(processes[1]))
+	assert.False(t, isSystemProcess(processes[2]))
+}
+
+func TestSystemKillProcess(t *testing.T) {
+	start := func(script string) int {
+		command := exec.Command("sh", "-c", script)
+		assert.NoError(t, command.Start())
+		// reap the child so it doesn't linger as a zombie once killed
+		go command.Wait()
+		return command.Process.Pid
+	}
+
+	result, err := systemKillProcess(start("sleep 60"), time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "terminated", result)
+
+	pid := start(`trap "" TERM; while true; do sleep 1; done`)
+	time.Sleep(200 * time.Millisecond)
+	result, err = systemKillProcess(pid, 300*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "killed, still running after 300ms", result)
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package manageprocesses
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// processScript lists the processes with their owner, as an array even if there is a single one
+const processScript = `ConvertTo-Json -Compress -InputObject @(Get-CimInstance Win32_Process | ForEach-Object {` +
+	`$owner = Invoke-CimMethod -InputObject $_ -MethodName GetOwner -ErrorAction SilentlyContinue;` +
+	`@{PID=$_.ProcessId; ParentPID=$_.ParentProcessId; Name=$_.Name; CommandLine=$_.CommandLine;` +
+	`User=$(if ($owner -and $owner.User) { $owner.Domain + '\' + $owner.User } else { '' })}})`
+
+// signals is empty, Windows has no signals
+var signals = map[string]syscall.Signal{}
+
+// priorityClasses are the priority classes of the priorities
+var priorityClasses = map[string]string{
+	PriorityBelowNormal: "BelowNormal",
+	PriorityIdle:        "Idle",
+}
+
+// criticalProcessNames are the processes Windows can't run without, lower case without .exe
+var criticalProcessNames = []string{
+	"system", "idle", "registry", "secure system", "memory compression", "smss", "csrss", "wininit", "winlogon",
+	"services", "lsass", "lsaiso", "svchost", "fontdrvhost", "dwm",
+}
+
+// runPowerShell runs a script and returns its output
+var runPowerShell = func(script string) ([]byte, error) {
+	output, err := exec.Command(appconfig.PowerShellPluginCommandName, "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%v %v", err, strings.TrimSpace(string(output)))
+	}
+	return output, nil
+}
+
+// systemListProcesses lists the processes with Win32_Process.
+func systemListProcesses() ([]Process, error) {
+	output, err := runPowerShell(processScript)
+	if err != nil {
+		return nil, err
+	}
+	var processes []Process
+	if err = json.Unmarshal(output, &processes); err != nil {
+		return nil, fmt.Errorf("failed to parse the processes: %v", err)
+	}
+	return processes, nil
+}
+
+// isSystemProcess returns true for the System Idle Process and the System process.
+func isSystemProcess(process Process) bool {
+	return process.PID == 0 || process.PID == 4
+}
+
+// systemSignalProcess fails, validate rejects the signal action on Windows.
+func systemSignalProcess(pid int, signal string) error {
+	return fmt.Errorf("signals are not supported on Windows")
+}
+
+// systemKillProcess terminates the process immediately, Windows has no way to ask any process to exit.
+func systemKillProcess(pid int, gracePeriod time.Duration) (string, error) {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return "", err
+	}
+	defer process.Release()
+	if err = process.Kill(); err != nil {
+		return "", err
+	}
+	return "terminated", nil
+}
+
+// systemLowerPriority sets the priority class of the priority.
+func systemLowerPriority(pid int, priority string) error {
+	_, err := runPowerShell(fmt.Sprintf("(Get-Process -Id %d -ErrorAction Stop).PriorityClass = '%v'", pid, priorityClasses[priority]))
+	return err
+}
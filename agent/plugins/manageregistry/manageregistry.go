@@ -0,0 +1,344 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package manageregistry implements the aws:manageRegistry plugin, which asserts the values of Windows
+// registry keys, reports their drift and exports their values before and after the run.
+package manageregistry
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// EnsurePresent sets the value if it is missing or different
+	EnsurePresent = "Present"
+
+	// EnsureAbsent deletes the value if it exists
+	EnsureAbsent = "Absent"
+
+	// ActionNone is the action of the values that didn't drift
+	ActionNone = "None"
+
+	// ActionSet is the action of the values set by the plugin
+	ActionSet = "Set"
+
+	// ActionDeleted is the action of the values deleted by the plugin
+	ActionDeleted = "Deleted"
+
+	// ActionDriftDetected is the action of the values that drifted when the plugin only detects the drift
+	ActionDriftDetected = "DriftDetected"
+)
+
+// Registry value types
+const (
+	TypeString       = "REG_SZ"
+	TypeExpandString = "REG_EXPAND_SZ"
+	TypeMultiString  = "REG_MULTI_SZ"
+	TypeDWord        = "REG_DWORD"
+	TypeQWord        = "REG_QWORD"
+	TypeBinary       = "REG_BINARY"
+)
+
+// multiStringDivider separates the strings of the data of a REG_MULTI_SZ value
+const multiStringDivider = "\n"
+
+// registryRoots maps the abbreviated root keys to their full names
+var registryRoots = map[string]string{
+	"HKLM":                "HKEY_LOCAL_MACHINE",
+	"HKCU":                "HKEY_CURRENT_USER",
+	"HKU":                 "HKEY_USERS",
+	"HKCR":                "HKEY_CLASSES_ROOT",
+	"HKCC":                "HKEY_CURRENT_CONFIG",
+	"HKEY_LOCAL_MACHINE":  "HKEY_LOCAL_MACHINE",
+	"HKEY_CURRENT_USER":   "HKEY_CURRENT_USER",
+	"HKEY_USERS":          "HKEY_USERS",
+	"HKEY_CLASSES_ROOT":   "HKEY_CLASSES_ROOT",
+	"HKEY_CURRENT_CONFIG": "HKEY_CURRENT_CONFIG",
+}
+
+// Value is a typed registry value. Data is formatted as a string: decimal for REG_DWORD and REG_QWORD,
+// lowercase hex for REG_BINARY and one line per string for REG_MULTI_SZ.
+type Value struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+// registryAccess reads and writes the registry values, a missing key or value is read as nil.
+type registryAccess interface {
+	GetValue(root, path, name string) (*Value, error)
+	SetValue(root, path, name string, value Value) error
+	DeleteValue(root, path, name string) error
+}
+
+// Plugin is the type for the aws:manageRegistry plugin.
+type Plugin struct {
+	registry registryAccess
+}
+
+// RegistryEntry is a registry value asserted by the document.
+type RegistryEntry struct {
+	// Key is the path of the key including its root, e.g. HKLM\SOFTWARE\Amazon
+	Key string
+	// Name is the name of the value, the default value of the key if empty
+	Name string
+	// Type is the registry type of the value, REG_SZ by default
+	Type string
+	// Value is the data of the value, Values are the strings of a REG_MULTI_SZ value
+	Value  string
+	Values []string
+	// Ensure is Present or Absent, Present by default
+	Ensure string
+}
+
+// ManageRegistryPluginInput represents the registry values to assert.
+type ManageRegistryPluginInput struct {
+	contracts.PluginInput
+	ID      string
+	Entries []RegistryEntry
+	// DetectOnly reports the drift without changing the registry, the plugin fails if any value drifted
+	DetectOnly bool
+}
+
+// EntryResult is the outcome of an entry, with the value before and after the run.
+type EntryResult struct {
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Ensure string `json:"ensure"`
+	Before *Value `json:"before"`
+	After  *Value `json:"after"`
+	Drift  bool   `json:"drift"`
+	Action string `json:"action"`
+}
+
+// ManageRegistryOutput is the structured outcome of the entries, set as the output of the plugin.
+type ManageRegistryOutput struct {
+	Drifted int           `json:"drifted"`
+	Entries []EntryResult `json:"entries"`
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	plugin.registry = newRegistryAccess()
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameManageRegistry
+}
+
+// Execute asserts the registry values and reports their drift.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input ManageRegistryPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if len(input.Entries) == 0 {
+		output.MarkAsFailed(fmt.Errorf("Entries is required"))
+		return
+	}
+	entries := make([]RegistryEntry, len(input.Entries))
+	desired := make([]*Value, len(input.Entries))
+	for i, entry := range input.Entries {
+		var err error
+		if entries[i], desired[i], err = validate(entry); err != nil {
+			output.MarkAsFailed(fmt.Errorf("Entry %v: %v", i+1, err))
+			return
+		}
+	}
+
+	result := ManageRegistryOutput{Entries: []EntryResult{}}
+	var failed error
+	for i, entry := range entries {
+		entryResult, err := p.apply(log, entry, desired[i], input.DetectOnly)
+		result.Entries = append(result.Entries, entryResult)
+		if entryResult.Drift {
+			result.Drifted++
+		}
+		if err != nil {
+			output.AppendErrorf("%v: %v", valuePath(entry), err)
+			// the remaining values are still asserted, they are independent of each other
+			failed = err
+			continue
+		}
+		if entryResult.Action != ActionNone {
+			output.AppendInfof("%v: %v", valuePath(entry), entryResult.Action)
+		}
+	}
+	if content, err := jsonutil.Marshal(result); err == nil {
+		output.SetOutput(content)
+	}
+	output.AppendInfof("%v of %v registry values drifted", result.Drifted, len(entries))
+
+	if failed != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to assert the registry values"))
+		return
+	}
+	if input.DetectOnly && result.Drifted > 0 {
+		output.MarkAsFailed(fmt.Errorf("%v registry values drifted", result.Drifted))
+		return
+	}
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// apply reads the value, compares it with the desired value and fixes it unless only detecting the drift.
+func (p *Plugin) apply(log log.T, entry RegistryEntry, desired *Value, detectOnly bool) (result EntryResult, err error) {
+	result = EntryResult{Key: entry.Key, Name: entry.Name, Ensure: entry.Ensure, Action: ActionNone}
+	root, path := splitKey(entry.Key)
+	if result.Before, err = p.registry.GetValue(root, path, entry.Name); err != nil {
+		return result, fmt.Errorf("failed to read the value: %v", err)
+	}
+	result.After = result.Before
+	result.Drift = !equal(result.Before, desired)
+	if !result.Drift {
+		return result, nil
+	}
+	if detectOnly {
+		result.Action = ActionDriftDetected
+		return result, nil
+	}
+
+	if desired == nil {
+		log.Infof("deleting registry value %v", valuePath(entry))
+		if err = p.registry.DeleteValue(root, path, entry.Name); err != nil {
+			return result, fmt.Errorf("failed to delete the value: %v", err)
+		}
+		result.Action = ActionDeleted
+	} else {
+		log.Infof("setting registry value %v to %v %v", valuePath(entry), desired.Type, desired.Data)
+		if err = p.registry.SetValue(root, path, entry.Name, *desired); err != nil {
+			return result, fmt.Errorf("failed to set the value: %v", err)
+		}
+		result.Action = ActionSet
+	}
+	// export what the registry holds now rather than what was requested
+	if result.After, err = p.registry.GetValue(root, path, entry.Name); err != nil {
+		return result, fmt.Errorf("failed to read the value back: %v", err)
+	}
+	return result, nil
+}
+
+// validate checks an entry, sets its defaults and returns its desired value, nil if it should be absent.
+func validate(entry RegistryEntry) (RegistryEntry, *Value, error) {
+	root, path := splitKey(entry.Key)
+	if root == "" {
+		return entry, nil, fmt.Errorf("Key must start with one of HKLM, HKCU, HKU, HKCR or HKCC, got %v", entry.Key)
+	}
+	if path == "" {
+		return entry, nil, fmt.Errorf("Key must be a sub key of %v", root)
+	}
+	switch {
+	case entry.Ensure == "" || strings.EqualFold(entry.Ensure, EnsurePresent):
+		entry.Ensure = EnsurePresent
+	case strings.EqualFold(entry.Ensure, EnsureAbsent):
+		entry.Ensure = EnsureAbsent
+		return entry, nil, nil
+	default:
+		return entry, nil, fmt.Errorf("Ensure must be %v or %v, got %v", EnsurePresent, EnsureAbsent, entry.Ensure)
+	}
+
+	if entry.Type == "" {
+		entry.Type = TypeString
+	}
+	entry.Type = strings.ToUpper(entry.Type)
+	data := entry.Value
+	if entry.Type == TypeMultiString && len(entry.Values) > 0 {
+		data = strings.Join(entry.Values, multiStringDivider)
+	} else if len(entry.Values) > 0 {
+		return entry, nil, fmt.Errorf("Values is only supported by %v", TypeMultiString)
+	}
+	normalized, err := Normalize(entry.Type, data)
+	if err != nil {
+		return entry, nil, err
+	}
+	return entry, &Value{Type: entry.Type, Data: normalized}, nil
+}
+
+// Normalize formats the data of a value so that equal values compare equal, e.g. 0x10 and 16 for REG_DWORD.
+func Normalize(valueType, data string) (string, error) {
+	switch valueType {
+	case TypeString, TypeExpandString, TypeMultiString:
+		return data, nil
+	case TypeDWord, TypeQWord:
+		bitSize := 32
+		if valueType == TypeQWord {
+			bitSize = 64
+		}
+		number, err := strconv.ParseUint(strings.TrimSpace(data), 0, bitSize)
+		if err != nil {
+			return "", fmt.Errorf("invalid %v value %v, expected an unsigned %v bit number", valueType, data, bitSize)
+		}
+		return strconv.FormatUint(number, 10), nil
+	case TypeBinary:
+		// reg.exe and regedit export binary values as comma separated bytes, e.g. 01,ff
+		digits := strings.NewReplacer(",", "", " ", "", "0x", "").Replace(strings.ToLower(data))
+		if _, err := hex.DecodeString(digits); err != nil {
+			return "", fmt.Errorf("invalid %v value %v, expected hex bytes", valueType, data)
+		}
+		return digits, nil
+	default:
+		return "", fmt.Errorf("Type must be one of %v, %v, %v, %v, %v or %v, got %v",
+			TypeString, TypeExpandString, TypeMultiString, TypeDWord, TypeQWord, TypeBinary, valueType)
+	}
+}
+
+// splitKey splits a key into its full root name and its path, the root is empty if it isn't supported.
+func splitKey(key string) (root, path string) {
+	parts := strings.SplitN(strings.Replace(key, "/", `\`, -1), `\`, 2)
+	root = registryRoots[strings.ToUpper(strings.TrimSuffix(parts[0], ":"))]
+	if len(parts) == 2 {
+		path = strings.Trim(parts[1], `\`)
+	}
+	return root, path
+}
+
+// equal returns true if the actual value is the desired value, both are nil when the value is absent.
+func equal(actual, desired *Value) bool {
+	if actual == nil || desired == nil {
+		return actual == nil && desired == nil
+	}
+	return *actual == *desired
+}
+
+// valuePath formats the entry for the output, the default value of a key is shown as (Default) like regedit does.
+func valuePath(entry RegistryEntry) string {
+	name := entry.Name
+	if name == "" {
+		name = "(Default)"
+	}
+	return entry.Key + `\` + name
+}
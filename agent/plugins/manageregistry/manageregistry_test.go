@@ -0,0 +1,146 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package manageregistry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRegistry keeps the values in memory, indexed by root\path\name
+type fakeRegistry map[string]Value
+
+func (r fakeRegistry) GetValue(root, path, name string) (*Value, error) {
+	if value, ok := r[root+`\`+path+`\`+name]; ok {
+		return &value, nil
+	}
+	return nil, nil
+}
+
+func (r fakeRegistry) SetValue(root, path, name string, value Value) error {
+	r[root+`\`+path+`\`+name] = value
+	return nil
+}
+
+func (r fakeRegistry) DeleteValue(root, path, name string) error {
+	delete(r, root+`\`+path+`\`+name)
+	return nil
+}
+
+func TestNormalize(t *testing.T) {
+	for _, test := range []struct {
+		valueType, data, normalized string
+	}{
+		{TypeDWord, "0x10", "16"},
+		{TypeDWord, " 16 ", "16"},
+		{TypeQWord, "18446744073709551615", "18446744073709551615"},
+		{TypeBinary, "01,FF, 0a", "01ff0a"},
+		{TypeString, " spaces are kept ", " spaces are kept "},
+	} {
+		normalized, err := Normalize(test.valueType, test.data)
+		assert.NoError(t, err)
+		assert.Equal(t, test.normalized, normalized)
+	}
+
+	for _, test := range []struct{ valueType, data string }{
+		{TypeDWord, "4294967296"},
+		{TypeDWord, "-1"},
+		{TypeBinary, "0g"},
+		{"REG_LINK", "target"},
+	} {
+		_, err := Normalize(test.valueType, test.data)
+		assert.Error(t, err, test.valueType+" "+test.data)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	entry, desired, err := validate(RegistryEntry{Key: `hklm\SOFTWARE\Amazon\`, Name: "Paths", Type: "reg_multi_sz", Values: []string{"a", "b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, EnsurePresent, entry.Ensure)
+	assert.Equal(t, &Value{Type: TypeMultiString, Data: "a\nb"}, desired)
+
+	_, desired, err = validate(RegistryEntry{Key: "HKEY_CURRENT_USER/Console", Name: "QuickEdit", Ensure: "absent"})
+	assert.NoError(t, err)
+	assert.Nil(t, desired)
+
+	_, err = validateError(RegistryEntry{Key: `HKXX\SOFTWARE`})
+	assert.Error(t, err)
+	_, err = validateError(RegistryEntry{Key: "HKLM"})
+	assert.Error(t, err)
+	_, err = validateError(RegistryEntry{Key: `HKLM\SOFTWARE`, Ensure: "Exists"})
+	assert.Error(t, err)
+	_, err = validateError(RegistryEntry{Key: `HKLM\SOFTWARE`, Type: TypeString, Values: []string{"a"}})
+	assert.Error(t, err)
+}
+
+func validateError(entry RegistryEntry) (RegistryEntry, error) {
+	entry, _, err := validate(entry)
+	return entry, err
+}
+
+func TestExecuteFixesDrift(t *testing.T) {
+	registry := fakeRegistry{
+		`HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Audit\Enabled`: {Type: TypeDWord, Data: "0"},
+		`HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Audit\Legacy`:  {Type: TypeString, Data: "yes"},
+		`HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Audit\Level`:   {Type: TypeDWord, Data: "3"},
+	}
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	config := contracts.Configuration{
+		Properties: map[string]interface{}{
+			"Entries": []interface{}{
+				map[string]interface{}{"Key": `HKLM\SOFTWARE\Policies\Audit`, "Name": "Enabled", "Type": "REG_DWORD", "Value": "1"},
+				map[string]interface{}{"Key": `HKLM\SOFTWARE\Policies\Audit`, "Name": "Legacy", "Ensure": "Absent"},
+				map[string]interface{}{"Key": `HKLM\SOFTWARE\Policies\Audit`, "Name": "Level", "Type": "REG_DWORD", "Value": "0x3"},
+			},
+		},
+	}
+
+	(&Plugin{registry: registry}).Execute(context.NewMockDefault(), config, task.NewChanneledCancelFlag(), output)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Equal(t, Value{Type: TypeDWord, Data: "1"}, registry[`HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Audit\Enabled`])
+	_, ok := registry[`HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Audit\Legacy`]
+	assert.False(t, ok)
+	assert.True(t, strings.Contains(output.GetStdout(), "2 of 3 registry values drifted"))
+	result := output.GetOutput().(string)
+	assert.True(t, strings.Contains(result, `"before":{"type":"REG_DWORD","data":"0"},"after":{"type":"REG_DWORD","data":"1"},"drift":true,"action":"Set"`), result)
+	assert.True(t, strings.Contains(result, `"before":{"type":"REG_SZ","data":"yes"},"after":null,"drift":true,"action":"Deleted"`), result)
+	assert.True(t, strings.Contains(result, `"drift":false,"action":"None"`), result)
+}
+
+func TestExecuteDetectOnly(t *testing.T) {
+	registry := fakeRegistry{}
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	config := contracts.Configuration{
+		Properties: map[string]interface{}{
+			"DetectOnly": true,
+			"Entries": []interface{}{
+				map[string]interface{}{"Key": `HKCU\Console`, "Name": "QuickEdit", "Type": "REG_DWORD", "Value": "1"},
+			},
+		},
+	}
+
+	(&Plugin{registry: registry}).Execute(context.NewMockDefault(), config, task.NewChanneledCancelFlag(), output)
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Empty(t, registry, "the registry is left untouched")
+	assert.True(t, strings.Contains(output.GetOutput().(string), `"before":null,"after":null,"drift":true,"action":"DriftDetected"`))
+}
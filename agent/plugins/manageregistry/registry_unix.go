@@ -0,0 +1,40 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package manageregistry
+
+import (
+	"fmt"
+)
+
+// unsupportedRegistry fails the entries, the registry only exists on Windows
+type unsupportedRegistry struct{}
+
+// newRegistryAccess returns the registry of the platform.
+func newRegistryAccess() registryAccess {
+	return unsupportedRegistry{}
+}
+
+func (unsupportedRegistry) GetValue(root, path, name string) (*Value, error) {
+	return nil, fmt.Errorf("%v is only supported on Windows", Name())
+}
+
+func (unsupportedRegistry) SetValue(root, path, name string, value Value) error {
+	return fmt.Errorf("%v is only supported on Windows", Name())
+}
+
+func (unsupportedRegistry) DeleteValue(root, path, name string) error {
+	return fmt.Errorf("%v is only supported on Windows", Name())
+}
@@ -0,0 +1,150 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package manageregistry
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// rootKeys maps the full root names to the predefined keys
+var rootKeys = map[string]registry.Key{
+	"HKEY_LOCAL_MACHINE":  registry.LOCAL_MACHINE,
+	"HKEY_CURRENT_USER":   registry.CURRENT_USER,
+	"HKEY_USERS":          registry.USERS,
+	"HKEY_CLASSES_ROOT":   registry.CLASSES_ROOT,
+	"HKEY_CURRENT_CONFIG": registry.CURRENT_CONFIG,
+}
+
+// valueTypes maps the registry value types to their names
+var valueTypes = map[uint32]string{
+	registry.SZ:        TypeString,
+	registry.EXPAND_SZ: TypeExpandString,
+	registry.MULTI_SZ:  TypeMultiString,
+	registry.DWORD:     TypeDWord,
+	registry.QWORD:     TypeQWord,
+	registry.BINARY:    TypeBinary,
+}
+
+// windowsRegistry accesses the registry of the instance
+type windowsRegistry struct{}
+
+// newRegistryAccess returns the registry of the platform.
+func newRegistryAccess() registryAccess {
+	return windowsRegistry{}
+}
+
+// GetValue reads a value, nil if the key or the value doesn't exist.
+func (windowsRegistry) GetValue(root, path, name string) (*Value, error) {
+	key, err := registry.OpenKey(rootKeys[root], path, registry.QUERY_VALUE)
+	if err == registry.ErrNotExist {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	_, valueType, err := key.GetValue(name, nil)
+	if err == registry.ErrNotExist {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	typeName, ok := valueTypes[valueType]
+	if !ok {
+		// values of other types never match the desired values and are overwritten
+		return &Value{Type: fmt.Sprintf("type %v", valueType)}, nil
+	}
+
+	value := Value{Type: typeName}
+	switch valueType {
+	case registry.SZ, registry.EXPAND_SZ:
+		value.Data, _, err = key.GetStringValue(name)
+	case registry.MULTI_SZ:
+		var values []string
+		values, _, err = key.GetStringsValue(name)
+		value.Data = strings.Join(values, multiStringDivider)
+	case registry.DWORD, registry.QWORD:
+		var number uint64
+		number, _, err = key.GetIntegerValue(name)
+		value.Data = strconv.FormatUint(number, 10)
+	case registry.BINARY:
+		var data []byte
+		data, _, err = key.GetBinaryValue(name)
+		value.Data = hex.EncodeToString(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// SetValue writes a value, the key is created if it doesn't exist.
+func (windowsRegistry) SetValue(root, path, name string, value Value) error {
+	key, _, err := registry.CreateKey(rootKeys[root], path, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	switch value.Type {
+	case TypeString:
+		return key.SetStringValue(name, value.Data)
+	case TypeExpandString:
+		return key.SetExpandStringValue(name, value.Data)
+	case TypeMultiString:
+		return key.SetStringsValue(name, strings.Split(value.Data, multiStringDivider))
+	case TypeDWord:
+		number, err := strconv.ParseUint(value.Data, 10, 32)
+		if err != nil {
+			return err
+		}
+		return key.SetDWordValue(name, uint32(number))
+	case TypeQWord:
+		number, err := strconv.ParseUint(value.Data, 10, 64)
+		if err != nil {
+			return err
+		}
+		return key.SetQWordValue(name, number)
+	case TypeBinary:
+		data, err := hex.DecodeString(value.Data)
+		if err != nil {
+			return err
+		}
+		return key.SetBinaryValue(name, data)
+	}
+	return fmt.Errorf("unsupported value type %v", value.Type)
+}
+
+// DeleteValue deletes a value, a missing key or value is already deleted.
+func (windowsRegistry) DeleteValue(root, path, name string) error {
+	key, err := registry.OpenKey(rootKeys[root], path, registry.SET_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	if err = key.DeleteValue(name); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package netdiag implements the aws:diagnoseNetwork plugin, which reports the network interfaces, routes and
+// DNS configuration of the instance and probes the connectivity to the given targets, for network triage.
+package netdiag
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// ProbePing probes a host with an ICMP echo request
+	ProbePing = "ping"
+
+	// ProbeTCP probes a host:port with a TCP connection
+	ProbeTCP = "tcp"
+
+	// ProbeTLS probes a host:port with a TLS handshake, verifying the certificate of the server
+	ProbeTLS = "tls"
+
+	// ProbeHTTP probes a URL with a GET request
+	ProbeHTTP = "http"
+
+	// defaultTimeoutSeconds is the timeout of each probe unless set
+	defaultTimeoutSeconds = 5
+
+	// maxTimeoutSeconds is the maximum timeout of each probe
+	maxTimeoutSeconds = 60
+
+	// maxProbes is the maximum number of probes of a run
+	maxProbes = 50
+)
+
+// probeTypes are the supported probes
+var probeTypes = []string{ProbePing, ProbeTCP, ProbeTLS, ProbeHTTP}
+
+// interfaces, routes and dnsConfig return the network configuration of the instance
+var interfaces = netInterfaces
+var routes = systemRoutes
+var dnsConfig = systemDNSConfig
+
+// Plugin is the type for the aws:diagnoseNetwork plugin.
+type Plugin struct {
+}
+
+// NetworkDiagnosticsPluginInput represents the probes to run.
+type NetworkDiagnosticsPluginInput struct {
+	contracts.PluginInput
+	ID string
+	// Probes are the connectivity probes, run in order
+	Probes []Probe
+	// MTUTarget is the host the path MTU is discovered to, the discovery is skipped if empty
+	MTUTarget string
+	// TimeoutSeconds is the timeout of each probe, 5 seconds by default
+	TimeoutSeconds int
+	// FailOnProbeFailure fails the plugin if any of the probes fails, the results are reported either way
+	FailOnProbeFailure bool
+}
+
+// Probe is a connectivity probe: a host for ping, a host:port for tcp and tls, a URL for http.
+type Probe struct {
+	Type   string
+	Target string
+}
+
+// NetworkDiagnosticsOutput is the outcome of the diagnostics, set as the output of the plugin.
+type NetworkDiagnosticsOutput struct {
+	Interfaces []Interface   `json:"interfaces"`
+	Routes     []Route       `json:"routes"`
+	DNS        DNSConfig     `json:"dns"`
+	Probes     []ProbeResult `json:"probes,omitempty"`
+	MTU        *MTUDiscovery `json:"mtu,omitempty"`
+	Errors     []string      `json:"errors,omitempty"`
+}
+
+// Interface is the configuration of a network interface.
+type Interface struct {
+	Name         string   `json:"name"`
+	Index        int      `json:"index"`
+	MTU          int      `json:"mtu"`
+	HardwareAddr string   `json:"hardwareAddr,omitempty"`
+	Flags        []string `json:"flags,omitempty"`
+	Addresses    []string `json:"addresses,omitempty"`
+}
+
+// Route is an entry of the routing tables.
+type Route struct {
+	Destination string `json:"destination"`
+	Gateway     string `json:"gateway,omitempty"`
+	Interface   string `json:"interface,omitempty"`
+	Metric      int    `json:"metric"`
+}
+
+// DNSConfig is the effective DNS configuration of the instance.
+type DNSConfig struct {
+	Servers []string `json:"servers"`
+	Search  []string `json:"search,omitempty"`
+}
+
+// ProbeResult is the outcome of a probe.
+type ProbeResult struct {
+	Type          string   `json:"type"`
+	Target        string   `json:"target"`
+	Success       bool     `json:"success"`
+	LatencyMillis *float64 `json:"latencyMillis,omitempty"`
+	// Address is the address the target resolved to
+	Address string `json:"address,omitempty"`
+	// Detail is the TLS version and certificate, or the HTTP status
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// MTUDiscovery is the outcome of the path MTU discovery.
+type MTUDiscovery struct {
+	Target  string `json:"target"`
+	PathMTU int    `json:"pathMtu,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameDiagnoseNetwork
+}
+
+// Execute gathers the network configuration and runs the probes.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input NetworkDiagnosticsPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if err := validate(&input); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	timeout := time.Duration(input.TimeoutSeconds) * time.Second
+
+	result := gatherConfiguration(log)
+	output.AppendInfof("%d interfaces, %d routes, DNS servers %v", len(result.Interfaces), len(result.Routes), strings.Join(result.DNS.Servers, ", "))
+
+	failed := 0
+	for _, probe := range input.Probes {
+		if cancelFlag.ShutDown() {
+			output.MarkAsShutdown()
+			return
+		} else if cancelFlag.Canceled() {
+			output.MarkAsCancelled()
+			return
+		}
+		probeResult := runProbe(probe, timeout)
+		result.Probes = append(result.Probes, probeResult)
+		if probeResult.Success {
+			output.AppendInfof("%v %v: ok %v", probe.Type, probe.Target, probeResult.Detail)
+		} else {
+			failed++
+			output.AppendErrorf("%v %v: %v", probe.Type, probe.Target, probeResult.Error)
+		}
+	}
+
+	if input.MTUTarget != "" {
+		mtu := discoverPathMTU(input.MTUTarget, timeout)
+		result.MTU = &mtu
+		if mtu.Error != "" {
+			output.AppendErrorf("path MTU to %v: %v", mtu.Target, mtu.Error)
+		} else {
+			output.AppendInfof("path MTU to %v: %d", mtu.Target, mtu.PathMTU)
+		}
+	}
+
+	if content, err := jsonutil.Marshal(result); err == nil {
+		output.SetOutput(content)
+	}
+	if input.FailOnProbeFailure && failed > 0 {
+		output.MarkAsFailed(fmt.Errorf("%d of %d probes failed", failed, len(input.Probes)))
+		return
+	}
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// validate checks the input and sets its defaults.
+func validate(input *NetworkDiagnosticsPluginInput) error {
+	if len(input.Probes) > maxProbes {
+		return fmt.Errorf("at most %d probes are supported, got %d", maxProbes, len(input.Probes))
+	}
+	for i := range input.Probes {
+		probe := &input.Probes[i]
+		probe.Type = strings.ToLower(probe.Type)
+		if !isProbeType(probe.Type) {
+			return fmt.Errorf("probe type must be one of %v, got %q", strings.Join(probeTypes, ", "), probe.Type)
+		}
+		if err := validateTarget(probe.Type, probe.Target); err != nil {
+			return err
+		}
+	}
+	if input.MTUTarget != "" {
+		if err := validateTarget(ProbePing, input.MTUTarget); err != nil {
+			return fmt.Errorf("invalid MTUTarget: %v", err)
+		}
+	}
+	if input.TimeoutSeconds == 0 {
+		input.TimeoutSeconds = defaultTimeoutSeconds
+	}
+	if input.TimeoutSeconds < 0 || input.TimeoutSeconds > maxTimeoutSeconds {
+		return fmt.Errorf("TimeoutSeconds must be between 1 and %d, got %d", maxTimeoutSeconds, input.TimeoutSeconds)
+	}
+	return nil
+}
+
+// isProbeType returns true if the probe type is supported.
+func isProbeType(probeType string) bool {
+	for _, supported := range probeTypes {
+		if probeType == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// gatherConfiguration returns the interfaces, routes and DNS configuration, the parts that can't be read
+// are reported as errors rather than failing the diagnostics.
+func gatherConfiguration(log log.T) NetworkDiagnosticsOutput {
+	var result NetworkDiagnosticsOutput
+	var err error
+	if result.Interfaces, err = interfaces(); err != nil {
+		log.Errorf("failed to list the network interfaces: %v", err)
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to list the network interfaces: %v", err))
+	}
+	if result.Routes, err = routes(); err != nil {
+		log.Errorf("failed to list the routes: %v", err)
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to list the routes: %v", err))
+	}
+	if result.DNS, err = dnsConfig(); err != nil {
+		log.Errorf("failed to read the DNS configuration: %v", err)
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to read the DNS configuration: %v", err))
+	}
+	return result
+}
+
+// netInterfaces returns the configuration of the network interfaces.
+func netInterfaces() ([]Interface, error) {
+	netInterfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var result []Interface
+	for _, netInterface := range netInterfaces {
+		item := Interface{
+			Name:         netInterface.Name,
+			Index:        netInterface.Index,
+			MTU:          netInterface.MTU,
+			HardwareAddr: netInterface.HardwareAddr.String(),
+		}
+		if flags := netInterface.Flags.String(); flags != "0" {
+			item.Flags = strings.Split(flags, "|")
+		}
+		if addresses, err := netInterface.Addrs(); err == nil {
+			for _, address := range addresses {
+				item.Addresses = append(item.Addresses, address.String())
+			}
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package netdiag
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	input := NetworkDiagnosticsPluginInput{Probes: []Probe{
+		{Type: "PING", Target: "10.0.0.1"},
+		{Type: "tcp", Target: "ssm.us-east-1.amazonaws.com:443"},
+		{Type: "tls", Target: "[fd00::1]:443"},
+		{Type: "http", Target: "http://169.254.169.254/latest/meta-data/"},
+	}}
+	assert.NoError(t, validate(&input))
+	assert.Equal(t, ProbePing, input.Probes[0].Type)
+	assert.Equal(t, defaultTimeoutSeconds, input.TimeoutSeconds)
+
+	invalid := []NetworkDiagnosticsPluginInput{
+		{Probes: []Probe{{Type: "udp", Target: "10.0.0.1:53"}}},
+		{Probes: []Probe{{Type: "ping", Target: "-f 10.0.0.1"}}},
+		{Probes: []Probe{{Type: "tcp", Target: "10.0.0.1"}}},
+		{Probes: []Probe{{Type: "tcp", Target: "10.0.0.1:0"}}},
+		{Probes: []Probe{{Type: "http", Target: "ftp://10.0.0.1/"}}},
+		{MTUTarget: "--help"},
+		{TimeoutSeconds: maxTimeoutSeconds + 1},
+	}
+	for _, input := range invalid {
+		assert.Error(t, validate(&input), "%+v", input)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closed := listener.Addr().String()
+	listener.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+	tlsTarget := strings.TrimPrefix(tlsServer.URL, "https://")
+
+	result := runProbe(Probe{Type: ProbeTCP, Target: strings.TrimPrefix(server.URL, "http://")}, time.Second)
+	assert.True(t, result.Success, result.Error)
+	assert.NotNil(t, result.LatencyMillis)
+	assert.Equal(t, strings.TrimPrefix(server.URL, "http://"), result.Address)
+
+	result = runProbe(Probe{Type: ProbeTCP, Target: closed}, time.Second)
+	assert.False(t, result.Success)
+	assert.NotEmpty(t, result.Error)
+
+	result = runProbe(Probe{Type: ProbeHTTP, Target: server.URL}, time.Second)
+	assert.True(t, result.Success, "any response tells the target is reachable")
+	assert.Equal(t, "403 Forbidden", result.Detail)
+
+	result = runProbe(Probe{Type: ProbeTLS, Target: tlsTarget}, time.Second)
+	assert.False(t, result.Success, "the certificate of the test server is not trusted")
+	assert.Contains(t, result.Error, "certificate")
+}
+
+func TestProbePing(t *testing.T) {
+	runPing = func(args ...string) (string, error) {
+		if args[len(args)-1] == "10.0.0.9" {
+			return "PING 10.0.0.9 (10.0.0.9) 56(84) bytes of data.\n\n1 packets transmitted, 0 received, 100% packet loss, time 0ms\n", fmt.Errorf("exit status 1")
+		}
+		return "PING instance.internal (10.0.0.1) 56(84) bytes of data.\n64 bytes from 10.0.0.1: icmp_seq=1 ttl=64 time=0.045 ms\n", nil
+	}
+	defer func() { runPing = defaultRunPing }()
+
+	result := runProbe(Probe{Type: ProbePing, Target: "instance.internal"}, time.Second)
+	assert.True(t, result.Success, result.Error)
+	assert.Equal(t, "10.0.0.1", result.Address)
+	assert.Equal(t, 0.045, *result.LatencyMillis)
+
+	result = runProbe(Probe{Type: ProbePing, Target: "10.0.0.9"}, time.Second)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "100% packet loss")
+}
+
+func TestDiscoverPathMTU(t *testing.T) {
+	pathMTU := 1500
+	var pinged int
+	runPing = func(args ...string) (string, error) {
+		pinged++
+		for i, arg := range args {
+			if arg == "-s" || arg == "-l" {
+				payload, _ := strconv.Atoi(args[i+1])
+				if payload+ipv4Overhead > pathMTU {
+					return "message too long", fmt.Errorf("exit status 1")
+				}
+			}
+		}
+		return "time=1 ms TTL=64", nil
+	}
+	defer func() { runPing = defaultRunPing }()
+
+	assert.Equal(t, MTUDiscovery{Target: "10.0.0.1", PathMTU: 1500}, discoverPathMTU("10.0.0.1", time.Second))
+	assert.True(t, pinged < 20, "binary search, pinged %d times", pinged)
+
+	pathMTU = 9001
+	assert.Equal(t, MTUDiscovery{Target: "10.0.0.1", PathMTU: 9001}, discoverPathMTU("10.0.0.1", time.Second))
+
+	pathMTU = 0
+	assert.NotEmpty(t, discoverPathMTU("10.0.0.1", time.Second).Error)
+}
+
+func TestExecute(t *testing.T) {
+	interfaces = func() ([]Interface, error) {
+		return []Interface{{Name: "eth0", Index: 2, MTU: 9001, Addresses: []string{"10.0.0.5/24"}}}, nil
+	}
+	routes = func() ([]Route, error) { return nil, fmt.Errorf("netstat not found") }
+	dnsConfig = func() (DNSConfig, error) { return DNSConfig{Servers: []string{"10.0.0.2"}}, nil }
+	defer func() {
+		interfaces = netInterfaces
+		routes = systemRoutes
+		dnsConfig = systemDNSConfig
+	}()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	execute := func(properties map[string]interface{}) *iohandler.DefaultIOHandler {
+		output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+		plugin, _ := NewPlugin()
+		plugin.Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, task.NewChanneledCancelFlag(), output)
+		return output
+	}
+
+	probes := []map[string]string{{"Type": "tcp", "Target": listener.Addr().String()}, {"Type": "tcp", "Target": "127.0.0.1:1"}}
+	output := execute(map[string]interface{}{"Probes": probes})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), "failed probes are part of the results")
+	var result NetworkDiagnosticsOutput
+	assert.NoError(t, jsonutil.Unmarshal(output.GetOutput().(string), &result))
+	assert.Equal(t, "eth0", result.Interfaces[0].Name)
+	assert.Equal(t, []string{"10.0.0.2"}, result.DNS.Servers)
+	assert.Equal(t, []string{"failed to list the routes: netstat not found"}, result.Errors)
+	assert.Len(t, result.Probes, 2)
+	assert.True(t, result.Probes[0].Success)
+	assert.False(t, result.Probes[1].Success)
+
+	output = execute(map[string]interface{}{"Probes": probes, "FailOnProbeFailure": true})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "1 of 2 probes failed")
+
+	output = execute(map[string]interface{}{"Probes": []map[string]string{{"Type": "dns", "Target": "example.com"}}})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+}
@@ -0,0 +1,228 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package netdiag
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ipv4Overhead is the size of the IPv4 and ICMP headers added to the payload of an echo request
+	ipv4Overhead = 28
+
+	// minPathMTU is the MTU every IPv4 host must accept
+	minPathMTU = 576
+
+	// maxPathMTU is the largest MTU probed, the jumbo frames MTU of EC2
+	maxPathMTU = 9001
+)
+
+// hostPattern matches the host names and addresses the probes accept
+var hostPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9.:_-]*$`)
+
+// pingLatencyPattern matches the round trip time in the output of ping, e.g. time=0.045 ms or time<1ms
+var pingLatencyPattern = regexp.MustCompile(`time[=<]\s*([0-9.]+)\s*ms`)
+
+// pingAddressPattern matches the address the target resolved to in the output of ping
+var pingAddressPattern = regexp.MustCompile(`[(\[]([0-9A-Fa-f.:]+)[)\]]`)
+
+// tlsVersions are the names of the TLS versions
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// runPing runs ping with the arguments and returns its output
+var runPing = defaultRunPing
+
+// defaultRunPing runs the ping of the system
+func defaultRunPing(args ...string) (string, error) {
+	output, err := exec.Command("ping", args...).CombinedOutput()
+	return string(output), err
+}
+
+// validateTarget checks the target of a probe: a host for ping, a host:port for tcp and tls, a URL for http.
+func validateTarget(probeType, target string) error {
+	switch probeType {
+	case ProbePing:
+		if !hostPattern.MatchString(target) {
+			return fmt.Errorf("invalid %v target %q, expected a host name or an address", probeType, target)
+		}
+	case ProbeTCP, ProbeTLS:
+		host, port, err := net.SplitHostPort(target)
+		if err != nil || !hostPattern.MatchString(host) {
+			return fmt.Errorf("invalid %v target %q, expected host:port", probeType, target)
+		}
+		if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+			return fmt.Errorf("invalid %v target %q, the port must be between 1 and 65535", probeType, target)
+		}
+	case ProbeHTTP:
+		parsed, err := url.Parse(target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid %v target %q, expected an http or https URL", probeType, target)
+		}
+	}
+	return nil
+}
+
+// runProbe runs a probe, its failure is part of the result.
+func runProbe(probe Probe, timeout time.Duration) ProbeResult {
+	result := ProbeResult{Type: probe.Type, Target: probe.Target}
+	start := time.Now()
+	var err error
+	switch probe.Type {
+	case ProbePing:
+		err = probePing(&result, timeout)
+	case ProbeTCP:
+		err = probeTCP(&result, timeout)
+	case ProbeTLS:
+		err = probeTLS(&result, timeout)
+	case ProbeHTTP:
+		err = probeHTTP(&result, timeout)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Success = true
+	if result.LatencyMillis == nil {
+		result.LatencyMillis = millisSince(start)
+	}
+	return result
+}
+
+// probePing sends an echo request, the latency is the round trip time reported by ping.
+func probePing(result *ProbeResult, timeout time.Duration) error {
+	output, err := runPing(pingArgs(result.Target, timeout)...)
+	if match := pingAddressPattern.FindStringSubmatch(output); match != nil {
+		result.Address = match[1]
+	}
+	if err != nil {
+		return fmt.Errorf("no reply: %v %v", err, lastLine(output))
+	} else if !pingReplied(output) {
+		return fmt.Errorf("no reply: %v", lastLine(output))
+	}
+	if match := pingLatencyPattern.FindStringSubmatch(output); match != nil {
+		if latency, err := strconv.ParseFloat(match[1], 64); err == nil {
+			result.LatencyMillis = &latency
+		}
+	}
+	return nil
+}
+
+// probeTCP opens a TCP connection, the latency is the time to connect.
+func probeTCP(result *ProbeResult, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", result.Target, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	result.Address = conn.RemoteAddr().String()
+	return nil
+}
+
+// probeTLS completes a TLS handshake and verifies the certificate of the server, the latency includes the handshake.
+func probeTLS(result *ProbeResult, timeout time.Duration) error {
+	host, _, _ := net.SplitHostPort(result.Target)
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", result.Target, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	result.Address = conn.RemoteAddr().String()
+	state := conn.ConnectionState()
+	version, found := tlsVersions[state.Version]
+	if !found {
+		version = fmt.Sprintf("TLS 0x%04x", state.Version)
+	}
+	result.Detail = version
+	if len(state.PeerCertificates) > 0 {
+		certificate := state.PeerCertificates[0]
+		result.Detail = fmt.Sprintf("%v, certificate %v expires %v", version, certificate.Subject.CommonName,
+			certificate.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// probeHTTP sends a GET request without following redirects, any response is a success.
+// The latency is the time to the response headers.
+func probeHTTP(result *ProbeResult, timeout time.Duration) error {
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	start := time.Now()
+	response, err := client.Get(result.Target)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	result.LatencyMillis = millisSince(start)
+	result.Detail = response.Status
+	return nil
+}
+
+// discoverPathMTU finds the largest packet that reaches the target without being fragmented, with a binary
+// search on the size of echo requests that must not be fragmented.
+func discoverPathMTU(target string, timeout time.Duration) MTUDiscovery {
+	result := MTUDiscovery{Target: target}
+	reaches := func(mtu int) bool {
+		output, err := runPing(mtuPingArgs(target, mtu-ipv4Overhead, timeout)...)
+		return err == nil && pingReplied(output)
+	}
+	if !reaches(minPathMTU) {
+		result.Error = fmt.Sprintf("no reply to a %d bytes packet", minPathMTU)
+		return result
+	}
+	low, high := minPathMTU, maxPathMTU+1
+	if reaches(maxPathMTU) {
+		low = maxPathMTU
+	}
+	// low is known to get through, high isn't
+	for high-low > 1 {
+		middle := (low + high) / 2
+		if reaches(middle) {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	result.PathMTU = low
+	return result
+}
+
+// millisSince returns the time elapsed since start in milliseconds.
+func millisSince(start time.Time) *float64 {
+	millis := float64(time.Since(start)) / float64(time.Millisecond)
+	return &millis
+}
+
+// lastLine returns the last non empty line of an output.
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
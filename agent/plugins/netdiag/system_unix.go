@@ -0,0 +1,206 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package netdiag
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procRoutePath, procIPv6RoutePath and resolvConfPath are the files the configuration is read from
+var procRoutePath = "/proc/net/route"
+var procIPv6RoutePath = "/proc/net/ipv6_route"
+var resolvConfPath = "/etc/resolv.conf"
+
+// systemRoutes returns the routing tables, from /proc on Linux and from netstat on the other systems.
+func systemRoutes() ([]Route, error) {
+	content, err := ioutil.ReadFile(procRoutePath)
+	if os.IsNotExist(err) {
+		output, err := exec.Command("netstat", "-rn").CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("netstat -rn failed: %v %v", err, strings.TrimSpace(string(output)))
+		}
+		return parseNetstatRoutes(string(output)), nil
+	} else if err != nil {
+		return nil, err
+	}
+	result := parseProcRoutes(string(content))
+	// IPv6 may be disabled
+	if content, err = ioutil.ReadFile(procIPv6RoutePath); err == nil {
+		result = append(result, parseProcIPv6Routes(string(content))...)
+	}
+	return result, nil
+}
+
+// parseProcRoutes parses /proc/net/route, whose addresses are hexadecimal in host byte order.
+func parseProcRoutes(content string) []Route {
+	var result []Route
+	for _, line := range strings.Split(content, "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+		destination, gateway, mask := procIPv4(fields[1]), procIPv4(fields[2]), procIPv4(fields[7])
+		if destination == nil || gateway == nil || mask == nil {
+			continue
+		}
+		ones, _ := net.IPMask(mask).Size()
+		route := Route{Destination: fmt.Sprintf("%v/%d", destination, ones), Interface: fields[0]}
+		if !gateway.Equal(net.IPv4zero.To4()) {
+			route.Gateway = gateway.String()
+		}
+		route.Metric, _ = strconv.Atoi(fields[6])
+		result = append(result, route)
+	}
+	return result
+}
+
+// procIPv4 decodes an address of /proc/net/route.
+func procIPv4(field string) net.IP {
+	value, err := strconv.ParseUint(field, 16, 32)
+	if err != nil {
+		return nil
+	}
+	ip := make(net.IP, net.IPv4len)
+	binary.LittleEndian.PutUint32(ip, uint32(value))
+	return ip
+}
+
+// parseProcIPv6Routes parses /proc/net/ipv6_route, the local and loopback routes are left out.
+func parseProcIPv6Routes(content string) []Route {
+	var result []Route
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[9] == "lo" {
+			continue
+		}
+		destination, err := hex.DecodeString(fields[0])
+		if err != nil || len(destination) != net.IPv6len {
+			continue
+		}
+		gateway, err := hex.DecodeString(fields[4])
+		if err != nil || len(gateway) != net.IPv6len {
+			continue
+		}
+		prefix, _ := strconv.ParseUint(fields[1], 16, 8)
+		metric, _ := strconv.ParseUint(fields[5], 16, 32)
+		route := Route{
+			Destination: fmt.Sprintf("%v/%d", net.IP(destination), prefix),
+			Interface:   fields[9],
+			Metric:      int(metric),
+		}
+		if !net.IP(gateway).Equal(net.IPv6zero) {
+			route.Gateway = net.IP(gateway).String()
+		}
+		result = append(result, route)
+	}
+	return result
+}
+
+// parseNetstatRoutes parses the routes listed by netstat -rn, whose columns depend on the system.
+func parseNetstatRoutes(output string) []Route {
+	var result []Route
+	interfaceColumn := -1
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "Destination" {
+			interfaceColumn = -1
+			for i, field := range fields {
+				if field == "Netif" || field == "Interface" || field == "Iface" {
+					interfaceColumn = i
+				}
+			}
+			continue
+		}
+		if interfaceColumn < 0 || len(fields) <= interfaceColumn {
+			continue
+		}
+		route := Route{Destination: fields[0], Interface: fields[interfaceColumn]}
+		if !strings.HasPrefix(fields[1], "link#") {
+			route.Gateway = fields[1]
+		}
+		result = append(result, route)
+	}
+	return result
+}
+
+// systemDNSConfig returns the name servers and search domains of resolv.conf.
+func systemDNSConfig() (DNSConfig, error) {
+	content, err := ioutil.ReadFile(resolvConfPath)
+	if err != nil {
+		return DNSConfig{}, err
+	}
+	return parseResolvConf(string(content)), nil
+}
+
+// parseResolvConf parses the nameserver, search and domain directives of resolv.conf.
+func parseResolvConf(content string) DNSConfig {
+	var result DNSConfig
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			result.Servers = append(result.Servers, fields[1])
+		case "search", "domain":
+			// the last of them wins
+			result.Search = fields[1:]
+		}
+	}
+	return result
+}
+
+// pingArgs returns the arguments of ping for a single echo request.
+func pingArgs(target string, timeout time.Duration) []string {
+	return []string{"-c", "1", "-W", pingWait(timeout), target}
+}
+
+// mtuPingArgs returns the arguments of ping for a single echo request that must not be fragmented.
+func mtuPingArgs(target string, payload int, timeout time.Duration) []string {
+	dontFragment := []string{"-D"}
+	if runtime.GOOS == "linux" {
+		dontFragment = []string{"-M", "do"}
+	}
+	args := append([]string{"-c", "1", "-W", pingWait(timeout), "-s", strconv.Itoa(payload)}, dontFragment...)
+	return append(args, target)
+}
+
+// pingWait returns the wait time of ping, in seconds on Linux and in milliseconds on the BSDs.
+func pingWait(timeout time.Duration) string {
+	if runtime.GOOS == "linux" {
+		return strconv.Itoa(int(timeout / time.Second))
+	}
+	return strconv.Itoa(int(timeout / time.Millisecond))
+}
+
+// pingReplied returns true if the target replied, ping exits with an error otherwise.
+func pingReplied(output string) bool {
+	return true
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package netdiag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProcRoutes(t *testing.T) {
+	content := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t00000000\t0100000A\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+		"eth0\t0000000A\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n"
+	assert.Equal(t, []Route{
+		{Destination: "0.0.0.0/0", Gateway: "10.0.0.1", Interface: "eth0", Metric: 100},
+		{Destination: "10.0.0.0/24", Interface: "eth0", Metric: 100},
+	}, parseProcRoutes(content))
+
+	ipv6 := "00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003 eth0\n" +
+		"00000000000000000000000000000001 80 00000000000000000000000000000000 00 00000000000000000000000000000000 00000000 00000002 00000000 80200001 lo\n"
+	assert.Equal(t, []Route{{Destination: "::/0", Gateway: "fe80::1", Interface: "eth0", Metric: 1024}}, parseProcIPv6Routes(ipv6))
+}
+
+func TestParseNetstatRoutes(t *testing.T) {
+	output := `Routing tables
+
+Internet:
+Destination        Gateway            Flags        Netif Expire
+default            192.168.1.1        UGScg          en0
+127                127.0.0.1          UCS            lo0
+192.168.1          link#6             UCS            en0      !
+`
+	assert.Equal(t, []Route{
+		{Destination: "default", Gateway: "192.168.1.1", Interface: "en0"},
+		{Destination: "127", Gateway: "127.0.0.1", Interface: "lo0"},
+		{Destination: "192.168.1", Interface: "en0"},
+	}, parseNetstatRoutes(output))
+}
+
+func TestParseResolvConf(t *testing.T) {
+	content := "# generated by NetworkManager\nsearch ec2.internal corp.example.com\nnameserver 10.0.0.2\nnameserver fd00::2\noptions timeout:2\n"
+	assert.Equal(t, DNSConfig{Servers: []string{"10.0.0.2", "fd00::2"}, Search: []string{"ec2.internal", "corp.example.com"}}, parseResolvConf(content))
+}
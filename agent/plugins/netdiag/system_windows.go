@@ -0,0 +1,102 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package netdiag
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	// routesScript lists the routes, as an array even if there is a single one
+	routesScript = `ConvertTo-Json -Compress -InputObject @(Get-NetRoute -ErrorAction Stop | ` +
+		`Select-Object DestinationPrefix,NextHop,InterfaceAlias,RouteMetric)`
+
+	// dnsScript lists the DNS servers of the interfaces and the suffix search list
+	dnsScript = `ConvertTo-Json -Compress -InputObject @{` +
+		`Servers=@(Get-DnsClientServerAddress -ErrorAction Stop | ForEach-Object { $_.ServerAddresses } | Select-Object -Unique);` +
+		`Search=@((Get-DnsClientGlobalSetting -ErrorAction Stop).SuffixSearchList)}`
+)
+
+// runPowerShell runs a script and returns its output
+var runPowerShell = func(script string) ([]byte, error) {
+	output, err := exec.Command(appconfig.PowerShellPluginCommandName, "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%v %v", err, strings.TrimSpace(string(output)))
+	}
+	return output, nil
+}
+
+// systemRoutes returns the routing tables listed by Get-NetRoute.
+func systemRoutes() ([]Route, error) {
+	output, err := runPowerShell(routesScript)
+	if err != nil {
+		return nil, err
+	}
+	var netRoutes []struct {
+		DestinationPrefix string
+		NextHop           string
+		InterfaceAlias    string
+		RouteMetric       int
+	}
+	if err = json.Unmarshal(output, &netRoutes); err != nil {
+		return nil, fmt.Errorf("failed to parse the routes: %v", err)
+	}
+	var result []Route
+	for _, netRoute := range netRoutes {
+		route := Route{Destination: netRoute.DestinationPrefix, Interface: netRoute.InterfaceAlias, Metric: netRoute.RouteMetric}
+		if netRoute.NextHop != "0.0.0.0" && netRoute.NextHop != "::" {
+			route.Gateway = netRoute.NextHop
+		}
+		result = append(result, route)
+	}
+	return result, nil
+}
+
+// systemDNSConfig returns the DNS servers of the interfaces and the suffix search list.
+func systemDNSConfig() (DNSConfig, error) {
+	output, err := runPowerShell(dnsScript)
+	if err != nil {
+		return DNSConfig{}, err
+	}
+	var result DNSConfig
+	if err = json.Unmarshal(output, &result); err != nil {
+		return DNSConfig{}, fmt.Errorf("failed to parse the DNS configuration: %v", err)
+	}
+	return result, nil
+}
+
+// pingArgs returns the arguments of ping for a single echo request.
+func pingArgs(target string, timeout time.Duration) []string {
+	return []string{"-n", "1", "-w", strconv.Itoa(int(timeout / time.Millisecond)), target}
+}
+
+// mtuPingArgs returns the arguments of ping for a single echo request that must not be fragmented.
+func mtuPingArgs(target string, payload int, timeout time.Duration) []string {
+	return []string{"-n", "1", "-w", strconv.Itoa(int(timeout / time.Millisecond)), "-f", "-l", strconv.Itoa(payload), target}
+}
+
+// pingReplied returns true if the target replied, ping also exits with 0 when a router reports the target
+// as unreachable.
+func pingReplied(output string) bool {
+	return strings.Contains(strings.ToUpper(output), "TTL=")
+}
@@ -17,6 +17,7 @@ package pluginutil
 import (
 	"io"
 	"io/ioutil"
+	"os"
 	"strconv"
 	"strings"
 
@@ -89,6 +90,14 @@ func CreateScriptFile(log log.T, scriptPath string, runCommand []string, byteOrd
 	return
 }
 
+// ShredScriptFile overwrites and removes the script file once its commands ran, the commands may hold secrets such
+// as the values of secure string parameters.
+func ShredScriptFile(log log.T, scriptPath string) {
+	if err := fileutil.Shred(scriptPath); err != nil && !os.IsNotExist(err) {
+		log.Warnf("failed to shred the script file %v, err %v", scriptPath, err)
+	}
+}
+
 // DownloadFileFromSource downloads file from source
 func DownloadFileFromSource(log log.T, source string, sourceHash string, sourceHashType string) (artifact.DownloadOutput, error) {
 	// download source and verify its integrity
@@ -125,6 +125,7 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput PSModulePlu
 		output.MarkAsFailed(fmt.Errorf("failed to create script file. %v", err))
 		return
 	}
+	defer pluginutil.ShredScriptFile(log, scriptPath)
 
 	if pluginInput.Source != "" {
 		//change hash type to be default sha256
@@ -0,0 +1,399 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package quarantine implements the aws:quarantine plugin, which isolates the instance from the network
+// with host firewall rules that only let the agent reach the SSM endpoints, and releases it.
+package quarantine
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// ActionIsolate applies the quarantine, running it again refreshes the allowed addresses
+	ActionIsolate = "Isolate"
+
+	// ActionRelease removes the quarantine
+	ActionRelease = "Release"
+
+	// metadataAddress and metadataIPv6Address are the instance metadata service the agent gets its credentials and
+	// identity from
+	metadataAddress     = "169.254.169.254"
+	metadataIPv6Address = "fd00:ec2::254"
+
+	// lockFileName is the lock of the quarantine, taken by the document workers isolating and releasing the instance
+	// and by the agent refreshing the allowed addresses
+	lockFileName = ".lock"
+
+	// lockTimeout bounds the wait for the other processes changing the quarantine
+	lockTimeout = 2 * time.Minute
+
+	// inventoryTypeName is the custom inventory type the quarantine state is reported with
+	inventoryTypeName = "Custom:Quarantine"
+
+	// inventoryFileName is the file of the quarantine state in the custom inventory folder
+	inventoryFileName = "Quarantine.json"
+)
+
+// amazonResolvers are the addresses of the Amazon DNS server of the VPC, reachable from the instances of any subnet
+var amazonResolvers = []string{"169.254.169.253", "fd00:ec2::253"}
+
+// stateDir is the folder the quarantine state is persisted in, so that it survives agent restarts
+var stateDir = filepath.Join(appconfig.DefaultDataStorePath, "quarantine")
+
+// lookupIP resolves the endpoints to allow
+var lookupIP = net.LookupIP
+
+// region returns the region of the SSM endpoints
+var region = platform.Region
+
+// customInventoryDir returns the custom inventory folder the inventory plugin reads by default
+var customInventoryDir = func() (string, error) {
+	instanceID, err := platform.InstanceID()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appconfig.DefaultDataStorePath, instanceID, appconfig.InventoryRootDirName,
+		appconfig.CustomInventoryRootDirName), nil
+}
+
+// runCommand runs a firewall command and returns its combined output
+var runCommand = func(name string, args ...string) (string, error) {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("%v %v failed: %v %v", name, strings.Join(args, " "), err, string(output))
+	}
+	return string(output), nil
+}
+
+// Plugin is the type for the aws:quarantine plugin.
+type Plugin struct {
+}
+
+// QuarantinePluginInput represents the action requested by the document.
+type QuarantinePluginInput struct {
+	contracts.PluginInput
+	ID     string
+	Action string
+	// AllowedEndpoints are host names or addresses allowed on top of the SSM endpoints, e.g. the S3 endpoint
+	// the command output is uploaded to
+	AllowedEndpoints []string
+}
+
+// State is the quarantine state of the instance.
+type State struct {
+	Quarantined      bool
+	Since            time.Time
+	AllowedAddresses []string
+	// AllowedEndpoints are the endpoints allowed by the document, resolved again with the SSM endpoints when the
+	// allowed addresses are refreshed
+	AllowedEndpoints []string `json:",omitempty"`
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameQuarantine
+}
+
+// Execute isolates or releases the instance.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input QuarantinePluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+
+	switch input.Action {
+	case ActionIsolate:
+		addresses, err := allowedAddresses(log, context.AppConfig(), input.AllowedEndpoints)
+		if err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		if err = isolate(log, input.AllowedEndpoints, addresses); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		output.AppendInfof("Instance quarantined, allowed addresses: %v", strings.Join(addresses, ", "))
+	case ActionRelease:
+		if err := release(log); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		output.AppendInfo("Instance released from quarantine")
+	default:
+		output.MarkAsFailed(fmt.Errorf("Action must be %v or %v, got %v", ActionIsolate, ActionRelease, input.Action))
+		return
+	}
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// isolate applies the firewall rules and records the quarantine.
+func isolate(log log.T, endpoints []string, addresses []string) error {
+	lock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	state, err := GetState()
+	if err != nil {
+		return err
+	}
+	if state.Quarantined {
+		// start over from the original rules so the allowed addresses are refreshed
+		log.Info("Instance is already quarantined, refreshing the allowed addresses")
+		if err = removeRules(log); err != nil {
+			return err
+		}
+	}
+	if err = applyRules(log, addresses); err != nil {
+		log.Errorf("failed to quarantine the instance, removing the rules applied so far: %v", err)
+		if removeErr := removeRules(log); removeErr != nil {
+			log.Errorf("failed to remove the quarantine rules: %v", removeErr)
+		}
+		return err
+	}
+	if !state.Quarantined {
+		state.Since = time.Now().UTC()
+	}
+	state.Quarantined = true
+	state.AllowedAddresses = addresses
+	state.AllowedEndpoints = endpoints
+	return saveState(log, state)
+}
+
+// release removes the firewall rules and records the release.
+func release(log log.T) error {
+	lock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	state, err := GetState()
+	if err != nil {
+		return err
+	}
+	if !state.Quarantined {
+		log.Info("Instance is not quarantined")
+		return nil
+	}
+	if err = removeRules(log); err != nil {
+		return err
+	}
+	return saveState(log, State{})
+}
+
+// Refresh resolves the endpoints of a quarantined instance again and updates the allowed addresses, the addresses
+// of the endpoints change over time and the agent would lose the Release command with its connectivity.
+func Refresh(log log.T, config appconfig.SsmagentConfig) error {
+	lock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	state, err := GetState()
+	if err != nil || !state.Quarantined {
+		return err
+	}
+	addresses, err := allowedAddresses(log, config, state.AllowedEndpoints)
+	if err != nil {
+		return err
+	}
+	if strings.Join(addresses, ",") == strings.Join(state.AllowedAddresses, ",") {
+		return nil
+	}
+	log.Infof("quarantine allowed addresses changed from %v to %v", state.AllowedAddresses, addresses)
+	if err = updateRules(log, state.AllowedAddresses, addresses); err != nil {
+		return err
+	}
+	state.AllowedAddresses = addresses
+	return saveState(log, state)
+}
+
+// allowedAddresses resolves the addresses the agent needs to reach while quarantined.
+func allowedAddresses(log log.T, config appconfig.SsmagentConfig, extraEndpoints []string) ([]string, error) {
+	instanceRegion, err := region()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the region of the instance: %v", err)
+	}
+	endpoints := append([]string{
+		endpointOf(config.Ssm.Endpoint, instanceRegion, "ssm"),
+		endpointOf(config.Mds.Endpoint, instanceRegion, "ec2messages"),
+	}, extraEndpoints...)
+
+	unique := map[string]struct{}{metadataAddress: {}, metadataIPv6Address: {}}
+	for _, endpoint := range endpoints {
+		ips, err := lookupIP(hostOf(endpoint))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %v: %v", endpoint, err)
+		}
+		for _, ip := range ips {
+			unique[ip.String()] = struct{}{}
+		}
+	}
+	addresses := make([]string, 0, len(unique))
+	for address := range unique {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	log.Debugf("quarantine allowed addresses %v", addresses)
+	return addresses, nil
+}
+
+// endpointOf returns the endpoint the agent uses for the service.
+func endpointOf(configured, region, service string) string {
+	if configured != "" {
+		return configured
+	}
+	if endpoint := appconfig.GetDefaultEndPoint(region, service); endpoint != "" {
+		return endpoint
+	}
+	return fmt.Sprintf("%v.%v.amazonaws.com", service, region)
+}
+
+// hostOf strips the scheme, port and path an endpoint may be configured with.
+func hostOf(endpoint string) string {
+	if i := strings.Index(endpoint, "://"); i >= 0 {
+		endpoint = endpoint[i+3:]
+	}
+	if i := strings.Index(endpoint, "/"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	if host, _, err := net.SplitHostPort(endpoint); err == nil {
+		return host
+	}
+	return endpoint
+}
+
+// difference returns the addresses that aren't in other.
+func difference(addresses []string, other []string) (result []string) {
+	in := make(map[string]struct{}, len(other))
+	for _, address := range other {
+		in[address] = struct{}{}
+	}
+	for _, address := range addresses {
+		if _, found := in[address]; !found {
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+// GetState returns the quarantine state of the instance.
+func GetState() (state State, err error) {
+	path := filepath.Join(stateDir, "state.json")
+	if !fileutil.Exists(path) {
+		return State{}, nil
+	}
+	if err = jsonutil.UnmarshalFile(path, &state); err != nil {
+		return State{}, fmt.Errorf("failed to read quarantine state: %v", err)
+	}
+	return state, nil
+}
+
+// lockState takes the lock of the quarantine, the caller unlocks it.
+func lockState() (*filelock.Lock, error) {
+	if err := fileutil.MakeDirs(stateDir); err != nil {
+		return nil, err
+	}
+	lock, err := filelock.Acquire(filepath.Join(stateDir, lockFileName), lockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock the quarantine state: %v", err)
+	}
+	return lock, nil
+}
+
+// saveState persists the quarantine state and reports it in the inventory.
+func saveState(log log.T, state State) error {
+	if err := fileutil.MakeDirs(stateDir); err != nil {
+		return err
+	}
+	content, err := jsonutil.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if _, err = fileutil.WriteIntoFileWithPermissions(filepath.Join(stateDir, "state.json"), content, appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("failed to save quarantine state: %v", err)
+	}
+	reportState(log, state)
+	return nil
+}
+
+// reportState writes the quarantine state to the custom inventory folder, the inventory plugin reports it with
+// the other custom inventory items; a release is reported rather than removed so the inventory isn't left stale.
+func reportState(log log.T, state State) {
+	dir, err := customInventoryDir()
+	if err != nil {
+		log.Warnf("failed to report the quarantine state in the inventory: %v", err)
+		return
+	}
+	since := ""
+	if state.Quarantined {
+		since = state.Since.Format(time.RFC3339)
+	}
+	// the custom inventory attributes are strings
+	item := map[string]interface{}{
+		"SchemaVersion": "1.0",
+		"TypeName":      inventoryTypeName,
+		"Content": map[string]string{
+			"Quarantined":      fmt.Sprint(state.Quarantined),
+			"Since":            since,
+			"AllowedAddresses": strings.Join(state.AllowedAddresses, ","),
+			"AllowedEndpoints": strings.Join(state.AllowedEndpoints, ","),
+		},
+	}
+	content, err := jsonutil.Marshal(item)
+	if err == nil {
+		err = fileutil.MakeDirs(dir)
+	}
+	if err == nil {
+		err = fileutil.WriteAtomically(filepath.Join(dir, inventoryFileName), []byte(content), appconfig.ReadWriteAccess, false)
+	}
+	if err != nil {
+		log.Warnf("failed to report the quarantine state in the inventory: %v", err)
+	}
+}
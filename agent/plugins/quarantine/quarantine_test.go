@@ -0,0 +1,122 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package quarantine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+// setupFirewall records the firewall commands instead of running them, failing the ones containing failOn
+func setupFirewall(t *testing.T, failOn string) (dir string, commands *[]string) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	assert.NoError(t, err)
+	stateDir = dir
+	customInventoryDir = func() (string, error) { return filepath.Join(dir, "custom"), nil }
+	commands = &[]string{}
+	runCommand = func(name string, args ...string) (string, error) {
+		command := name + " " + strings.Join(args, " ")
+		*commands = append(*commands, command)
+		if failOn != "" && strings.Contains(command, failOn) {
+			return "", fmt.Errorf("%v failed", command)
+		}
+		return "", nil
+	}
+	return dir, commands
+}
+
+func TestIsolateAndRelease(t *testing.T) {
+	dir, commands := setupFirewall(t, "")
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, isolate(logger, []string{"s3.amazonaws.com"}, []string{"10.0.0.1", metadataAddress}))
+
+	state, err := GetState()
+	assert.NoError(t, err)
+	assert.True(t, state.Quarantined)
+	assert.False(t, state.Since.IsZero())
+	assert.Equal(t, []string{"10.0.0.1", metadataAddress}, state.AllowedAddresses)
+	assert.Equal(t, []string{"s3.amazonaws.com"}, state.AllowedEndpoints)
+	assert.True(t, strings.Contains(strings.Join(*commands, "\n"), "10.0.0.1"))
+	assert.Equal(t, "true", inventoryAttributes(t, dir)["Quarantined"])
+
+	assert.NoError(t, release(logger))
+
+	state, err = GetState()
+	assert.NoError(t, err)
+	assert.False(t, state.Quarantined)
+	assert.Equal(t, "false", inventoryAttributes(t, dir)["Quarantined"])
+}
+
+// inventoryAttributes returns the attributes of the quarantine state reported in the custom inventory
+func inventoryAttributes(t *testing.T, dir string) map[string]string {
+	var item struct {
+		TypeName string
+		Content  map[string]string
+	}
+	assert.NoError(t, jsonutil.UnmarshalFile(filepath.Join(dir, "custom", inventoryFileName), &item))
+	assert.Equal(t, inventoryTypeName, item.TypeName)
+	return item.Content
+}
+
+func TestRefreshWhenNotQuarantined(t *testing.T) {
+	dir, commands := setupFirewall(t, "")
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, Refresh(logger, appconfig.SsmagentConfig{}))
+	assert.Empty(t, *commands)
+}
+
+func TestIsolateFailureRemovesRules(t *testing.T) {
+	dir, commands := setupFirewall(t, "10.0.0.1")
+	defer os.RemoveAll(dir)
+
+	assert.Error(t, isolate(logger, nil, []string{"10.0.0.1"}))
+
+	state, err := GetState()
+	assert.NoError(t, err)
+	assert.False(t, state.Quarantined)
+	assert.NotEmpty(t, *commands)
+}
+
+func TestReleaseWhenNotQuarantined(t *testing.T) {
+	dir, commands := setupFirewall(t, "")
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, release(logger))
+	assert.Empty(t, *commands)
+}
+
+func TestHostOf(t *testing.T) {
+	assert.Equal(t, "ssm.us-east-1.amazonaws.com", hostOf("ssm.us-east-1.amazonaws.com"))
+	assert.Equal(t, "ssm.example.com", hostOf("https://ssm.example.com:443/path"))
+	assert.Equal(t, "ssm.example.com", hostOf("ssm.example.com:8443"))
+}
+
+func TestEndpointOf(t *testing.T) {
+	assert.Equal(t, "ssm.us-east-1.amazonaws.com", endpointOf("", "us-east-1", "ssm"))
+	assert.Equal(t, "ec2messages.cn-north-1.amazonaws.com.cn", endpointOf("", "cn-north-1", "ec2messages"))
+	assert.Equal(t, "ssm.example.com", endpointOf("ssm.example.com", "us-east-1", "ssm"))
+}
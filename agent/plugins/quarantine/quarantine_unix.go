@@ -0,0 +1,238 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package quarantine
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	iptables  = "iptables"
+	ip6tables = "ip6tables"
+
+	// inputChain and outputChain hold the quarantine rules, they are jumped to first from the built-in
+	// chains so the existing rules are left untouched and come back into effect on release
+	inputChain  = "SSM-QUARANTINE-IN"
+	outputChain = "SSM-QUARANTINE-OUT"
+)
+
+// resolvConfPaths list the DNS resolvers of the instance, the second one the resolvers systemd-resolved forwards to
+var resolvConfPaths = []string{"/etc/resolv.conf", "/run/systemd/resolve/resolv.conf"}
+
+// neighborDiscovery are the ICMPv6 messages IPv6 needs to reach the allowed addresses
+var neighborDiscovery = []string{"router-solicitation", "router-advertisement", "neighbour-solicitation", "neighbour-advertisement"}
+
+// lookPath finds the firewall commands
+var lookPath = exec.LookPath
+
+// hasIPv6 returns whether an interface of the instance other than the loopback has an IPv6 address
+var hasIPv6 = func() bool {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		// the IPv6 traffic can't be ruled out
+		return true
+	}
+	for _, address := range addresses {
+		if ipNet, ok := address.(*net.IPNet); ok && ipNet.IP.To4() == nil && !ipNet.IP.IsLoopback() {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRules drops all the traffic of IPv4 and IPv6 but the connections to the allowed addresses and their replies,
+// and the DNS queries to the resolvers of the instance. The connections established with other addresses are dropped
+// as well.
+func applyRules(log log.T, addresses []string) error {
+	resolvers := dnsResolvers(log)
+	ipv4Addresses, ipv6Addresses := byFamily(addresses)
+	ipv4Resolvers, ipv6Resolvers := byFamily(resolvers)
+	if err := runRules(quarantineRules(ipv4Addresses, ipv4Resolvers, false), iptables); err != nil {
+		return err
+	}
+	if _, err := lookPath(ip6tables); err != nil {
+		if hasIPv6() {
+			return fmt.Errorf("%v is needed to isolate the IPv6 traffic of the instance: %v", ip6tables, err)
+		}
+		log.Infof("%v isn't installed and the instance has no IPv6 address, only the IPv4 traffic is filtered", ip6tables)
+	} else if err = runRules(quarantineRules(ipv6Addresses, ipv6Resolvers, true), ip6tables); err != nil {
+		return err
+	}
+	log.Infof("quarantine rules applied to %v and %v, DNS resolvers %v", inputChain, outputChain, resolvers)
+	return nil
+}
+
+// quarantineRules returns the rules of a firewall command, iptables or ip6tables, allowing the addresses and the
+// DNS resolvers, and the neighbor discovery of IPv6
+func quarantineRules(addresses []string, resolvers []string, ipv6 bool) [][]string {
+	input := [][]string{{"-A", inputChain, "-i", "lo", "-j", "ACCEPT"}}
+	output := [][]string{{"-A", outputChain, "-o", "lo", "-j", "ACCEPT"}}
+	if ipv6 {
+		for _, message := range neighborDiscovery {
+			input = append(input, []string{"-A", inputChain, "-p", "ipv6-icmp", "--icmpv6-type", message, "-j", "ACCEPT"})
+			output = append(output, []string{"-A", outputChain, "-p", "ipv6-icmp", "--icmpv6-type", message, "-j", "ACCEPT"})
+		}
+	}
+	// DNS stays open to the resolvers so the agent can follow the endpoints when the quarantine is refreshed
+	for _, resolver := range resolvers {
+		for _, protocol := range []string{"udp", "tcp"} {
+			input = append(input, []string{"-A", inputChain, "-s", resolver, "-p", protocol, "--sport", "53",
+				"-m", "state", "--state", "ESTABLISHED", "-j", "ACCEPT"})
+			output = append(output, []string{"-A", outputChain, "-d", resolver, "-p", protocol, "--dport", "53", "-j", "ACCEPT"})
+		}
+	}
+	for _, address := range addresses {
+		inputRule, outputRule := allowRules(address)
+		input = append(input, append([]string{"-A", inputChain}, inputRule...))
+		output = append(output, append([]string{"-A", outputChain}, outputRule...))
+	}
+
+	rules := [][]string{{"-N", inputChain}}
+	rules = append(rules, input...)
+	rules = append(rules, []string{"-A", inputChain, "-j", "DROP"}, []string{"-N", outputChain})
+	rules = append(rules, output...)
+	return append(rules,
+		[]string{"-A", outputChain, "-j", "DROP"},
+		[]string{"-I", "INPUT", "1", "-j", inputChain},
+		[]string{"-I", "OUTPUT", "1", "-j", outputChain})
+}
+
+// allowRules returns the rules of the input and output chains allowing the connections to an address and their replies
+func allowRules(address string) (input []string, output []string) {
+	return []string{"-s", address, "-m", "state", "--state", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+		[]string{"-d", address, "-j", "ACCEPT"}
+}
+
+// updateRules allows the connections to the addresses added and removes the rules of the addresses removed, in the
+// chains in place so that the quarantine isn't lifted while the addresses are updated
+func updateRules(log log.T, previous []string, addresses []string) error {
+	addedIPv4, addedIPv6 := byFamily(difference(addresses, previous))
+	removedIPv4, removedIPv6 := byFamily(difference(previous, addresses))
+	if err := runRules(updatedRules(addedIPv4, removedIPv4), iptables); err != nil {
+		return err
+	}
+	if _, err := lookPath(ip6tables); err != nil {
+		log.Debugf("%v isn't installed, only the IPv4 addresses are updated", ip6tables)
+	} else if err = runRules(updatedRules(addedIPv6, removedIPv6), ip6tables); err != nil {
+		return err
+	}
+	log.Infof("quarantine allowed addresses updated to %v", addresses)
+	return nil
+}
+
+// updatedRules returns the rules inserting the allow rules of the added addresses ahead of the rules dropping the
+// rest of the traffic, and deleting those of the removed addresses
+func updatedRules(added []string, removed []string) (rules [][]string) {
+	for _, address := range added {
+		input, output := allowRules(address)
+		rules = append(rules, append([]string{"-I", inputChain, "1"}, input...), append([]string{"-I", outputChain, "1"}, output...))
+	}
+	for _, address := range removed {
+		input, output := allowRules(address)
+		rules = append(rules, append([]string{"-D", inputChain}, input...), append([]string{"-D", outputChain}, output...))
+	}
+	return rules
+}
+
+// runRules runs the rules with the firewall command
+func runRules(rules [][]string, command string) error {
+	for _, rule := range rules {
+		if _, err := runCommand(command, rule...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// byFamily splits the addresses into the IPv4 and the IPv6 ones
+func byFamily(addresses []string) (ipv4 []string, ipv6 []string) {
+	for _, address := range addresses {
+		if ip := net.ParseIP(address); ip == nil {
+			continue
+		} else if ip.To4() != nil {
+			ipv4 = append(ipv4, address)
+		} else {
+			ipv6 = append(ipv6, address)
+		}
+	}
+	return ipv4, ipv6
+}
+
+// dnsResolvers returns the Amazon DNS server and the resolvers the instance is configured with, which are the
+// resolver of the VPC unless the instance uses its own; the local ones are reached through the loopback
+func dnsResolvers(log log.T) []string {
+	unique := make(map[string]struct{})
+	for _, resolver := range amazonResolvers {
+		unique[resolver] = struct{}{}
+	}
+	for _, path := range resolvConfPaths {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Debugf("no DNS resolvers in %v: %v", path, err)
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 || fields[0] != "nameserver" {
+				continue
+			}
+			// the zone of the link-local addresses isn't part of the address
+			address := strings.SplitN(fields[1], "%", 2)[0]
+			if ip := net.ParseIP(address); ip != nil && !ip.IsLoopback() {
+				unique[ip.String()] = struct{}{}
+			}
+		}
+		file.Close()
+	}
+	resolvers := make([]string, 0, len(unique))
+	for resolver := range unique {
+		resolvers = append(resolvers, resolver)
+	}
+	sort.Strings(resolvers)
+	return resolvers
+}
+
+// removeRules removes the quarantine chains of IPv4 and IPv6, if any.
+func removeRules(log log.T) error {
+	for _, command := range []string{iptables, ip6tables} {
+		for builtin, chain := range map[string]string{"INPUT": inputChain, "OUTPUT": outputChain} {
+			if _, err := runCommand(command, "-n", "-L", chain); err != nil {
+				log.Debugf("chain %v of %v doesn't exist", chain, command)
+				continue
+			}
+			if _, err := runCommand(command, "-D", builtin, "-j", chain); err != nil {
+				log.Debugf("chain %v of %v isn't referenced from %v", chain, command, builtin)
+			}
+			if _, err := runCommand(command, "-F", chain); err != nil {
+				return err
+			}
+			if _, err := runCommand(command, "-X", chain); err != nil {
+				return err
+			}
+		}
+	}
+	log.Info("quarantine rules removed")
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package quarantine
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	// the firewall of the host running the tests is never looked at
+	lookPath = func(file string) (string, error) { return "/sbin/" + file, nil }
+	hasIPv6 = func() bool { return true }
+	resolvConfPaths = nil
+	os.Exit(m.Run())
+}
+
+func TestApplyRules(t *testing.T) {
+	dir, commands := setupFirewall(t, "")
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, applyRules(logger, []string{"10.0.0.1", "2600:1f18::1"}))
+
+	ipv4 := strings.Join(filterCommands(*commands, iptables+" "), "\n")
+	ipv6 := strings.Join(filterCommands(*commands, ip6tables+" "), "\n")
+	assert.Contains(t, ipv4, "-A SSM-QUARANTINE-OUT -d 10.0.0.1 -j ACCEPT")
+	assert.Contains(t, ipv4, "-A SSM-QUARANTINE-IN -s 10.0.0.1 -m state --state ESTABLISHED,RELATED -j ACCEPT")
+	assert.Contains(t, ipv4, "-A SSM-QUARANTINE-OUT -d 169.254.169.253 -p udp --dport 53 -j ACCEPT")
+	assert.NotContains(t, ipv4, "2600:1f18::1")
+	assert.Contains(t, ipv6, "-A SSM-QUARANTINE-OUT -d 2600:1f18::1 -j ACCEPT")
+	assert.Contains(t, ipv6, "-A SSM-QUARANTINE-OUT -d fd00:ec2::253 -p tcp --dport 53 -j ACCEPT")
+	assert.Contains(t, ipv6, "--icmpv6-type neighbour-solicitation")
+	for _, command := range *commands {
+		// the connections established with other addresses are dropped, and so are the DNS queries to other resolvers
+		if strings.Contains(command, "ESTABLISHED") {
+			assert.Contains(t, command, " -s ")
+		}
+		if strings.Contains(command, "53") {
+			assert.Regexp(t, " -[sd] ", command)
+		}
+	}
+}
+
+func TestApplyRulesWithoutIp6tables(t *testing.T) {
+	dir, commands := setupFirewall(t, "")
+	defer os.RemoveAll(dir)
+	defer func(origLookPath func(string) (string, error), origHasIPv6 func() bool) {
+		lookPath, hasIPv6 = origLookPath, origHasIPv6
+	}(lookPath, hasIPv6)
+	lookPath = func(file string) (string, error) { return "", errors.New("not found") }
+
+	assert.Error(t, applyRules(logger, []string{"10.0.0.1"}), "the IPv6 traffic can't be isolated")
+
+	// without IPv6 only the IPv4 traffic is filtered
+	hasIPv6 = func() bool { return false }
+	*commands = nil
+	assert.NoError(t, applyRules(logger, []string{"10.0.0.1"}))
+	assert.Empty(t, filterCommands(*commands, ip6tables+" "))
+	assert.NotEmpty(t, filterCommands(*commands, iptables+" "))
+}
+
+func TestRefreshUpdatesTheRulesWhenTheEndpointsMove(t *testing.T) {
+	dir, commands := setupFirewall(t, "")
+	defer os.RemoveAll(dir)
+	defer func(origLookupIP func(string) ([]net.IP, error), origRegion func() (string, error)) {
+		lookupIP, region = origLookupIP, origRegion
+	}(lookupIP, region)
+	region = func() (string, error) { return "us-east-1", nil }
+	resolved := map[string]string{
+		"ssm.us-east-1.amazonaws.com": "10.0.0.1", "ec2messages.us-east-1.amazonaws.com": "10.0.0.2", "s3.amazonaws.com": "2600:1f18::1",
+	}
+	lookupIP = func(host string) ([]net.IP, error) { return []net.IP{net.ParseIP(resolved[host])}, nil }
+	addresses, err := allowedAddresses(logger, appconfig.SsmagentConfig{}, []string{"s3.amazonaws.com"})
+	assert.NoError(t, err)
+	assert.NoError(t, isolate(logger, []string{"s3.amazonaws.com"}, addresses))
+
+	// nothing moved
+	*commands = nil
+	assert.NoError(t, Refresh(logger, appconfig.SsmagentConfig{}))
+	assert.Empty(t, *commands)
+
+	resolved["ssm.us-east-1.amazonaws.com"] = "10.0.0.3"
+	resolved["s3.amazonaws.com"] = "2600:1f18::2"
+	assert.NoError(t, Refresh(logger, appconfig.SsmagentConfig{}))
+
+	assert.Equal(t, []string{
+		"iptables -I SSM-QUARANTINE-IN 1 -s 10.0.0.3 -m state --state ESTABLISHED,RELATED -j ACCEPT",
+		"iptables -I SSM-QUARANTINE-OUT 1 -d 10.0.0.3 -j ACCEPT",
+		"iptables -D SSM-QUARANTINE-IN -s 10.0.0.1 -m state --state ESTABLISHED,RELATED -j ACCEPT",
+		"iptables -D SSM-QUARANTINE-OUT -d 10.0.0.1 -j ACCEPT",
+		"ip6tables -I SSM-QUARANTINE-IN 1 -s 2600:1f18::2 -m state --state ESTABLISHED,RELATED -j ACCEPT",
+		"ip6tables -I SSM-QUARANTINE-OUT 1 -d 2600:1f18::2 -j ACCEPT",
+		"ip6tables -D SSM-QUARANTINE-IN -s 2600:1f18::1 -m state --state ESTABLISHED,RELATED -j ACCEPT",
+		"ip6tables -D SSM-QUARANTINE-OUT -d 2600:1f18::1 -j ACCEPT",
+	}, *commands)
+	state, err := GetState()
+	assert.NoError(t, err)
+	assert.Contains(t, state.AllowedAddresses, "10.0.0.3")
+	assert.NotContains(t, state.AllowedAddresses, "10.0.0.1")
+	assert.Contains(t, inventoryAttributes(t, dir)["AllowedAddresses"], "10.0.0.3")
+}
+
+func TestRemoveRulesOfBothFamilies(t *testing.T) {
+	dir, commands := setupFirewall(t, "")
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, removeRules(logger))
+	assert.Contains(t, *commands, "iptables -X SSM-QUARANTINE-IN")
+	assert.Contains(t, *commands, "ip6tables -X SSM-QUARANTINE-OUT")
+}
+
+func TestDNSResolvers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	resolvConf := filepath.Join(dir, "resolv.conf")
+	assert.NoError(t, ioutil.WriteFile(resolvConf, []byte("# generated\nnameserver 127.0.0.53\nnameserver 10.0.0.2\nnameserver fe80::1%eth0\nsearch ec2.internal\n"), 0600))
+	defer func(origPaths []string) { resolvConfPaths = origPaths }(resolvConfPaths)
+	resolvConfPaths = []string{resolvConf, filepath.Join(dir, "missing")}
+
+	assert.Equal(t, []string{"10.0.0.2", "169.254.169.253", "fd00:ec2::253", "fe80::1"}, dnsResolvers(logger))
+}
+
+// filterCommands returns the commands starting with prefix
+func filterCommands(commands []string, prefix string) (filtered []string) {
+	for _, command := range commands {
+		if strings.HasPrefix(command, prefix) {
+			filtered = append(filtered, command)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package quarantine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	netsh = "netsh"
+
+	// ruleName is the name of the firewall rule allowing the traffic of the agent
+	ruleName = "SSM-Quarantine"
+
+	// dnsRuleName is the name of the firewall rules allowing the DNS queries to the resolvers
+	dnsRuleName = "SSM-Quarantine-DNS"
+
+	// dnsServers is the keyword of the firewall for the DNS servers the instance is configured with
+	dnsServers = "dns"
+)
+
+// backupPath is the export of the firewall configuration taken before the quarantine, it is imported back on release
+var backupPath = filepath.Join(stateDir, "firewall.wfw")
+
+// applyRules disables the existing firewall rules, blocks all the traffic by default and only allows the DNS
+// queries to the resolvers of the instance and the connections to the allowed addresses.
+func applyRules(log log.T, addresses []string) error {
+	if err := fileutil.MakeDirs(stateDir); err != nil {
+		return err
+	}
+	if !fileutil.Exists(backupPath) {
+		if _, err := runCommand(netsh, "advfirewall", "export", backupPath); err != nil {
+			return err
+		}
+	}
+	resolvers := "remoteip=" + strings.Join(append([]string{dnsServers}, amazonResolvers...), ",")
+	rules := [][]string{
+		{"advfirewall", "firewall", "set", "rule", "name=all", "new", "enable=no"},
+		{"advfirewall", "firewall", "add", "rule", "name=" + dnsRuleName, "dir=out", "action=allow", "protocol=UDP", "remoteport=53", resolvers},
+		{"advfirewall", "firewall", "add", "rule", "name=" + dnsRuleName, "dir=out", "action=allow", "protocol=TCP", "remoteport=53", resolvers},
+		{"advfirewall", "firewall", "add", "rule", "name=" + ruleName, "dir=out", "action=allow", "remoteip=" + strings.Join(addresses, ",")},
+		{"advfirewall", "set", "allprofiles", "state", "on"},
+		{"advfirewall", "set", "allprofiles", "firewallpolicy", "blockinbound,blockoutbound"},
+	}
+	for _, rule := range rules {
+		if _, err := runCommand(netsh, rule...); err != nil {
+			return err
+		}
+	}
+	log.Info("quarantine firewall policy applied")
+	return nil
+}
+
+// updateRules replaces the addresses of the rule allowing the traffic of the agent, the rule stays in place so the
+// quarantine isn't lifted while the addresses are updated.
+func updateRules(log log.T, previous []string, addresses []string) error {
+	if _, err := runCommand(netsh, "advfirewall", "firewall", "set", "rule", "name="+ruleName, "new", "remoteip="+strings.Join(addresses, ",")); err != nil {
+		return err
+	}
+	log.Infof("quarantine allowed addresses updated to %v", addresses)
+	return nil
+}
+
+// removeRules imports back the firewall configuration exported before the quarantine.
+func removeRules(log log.T) error {
+	if !fileutil.Exists(backupPath) {
+		log.Debug("no firewall configuration to restore")
+		return nil
+	}
+	if _, err := runCommand(netsh, "advfirewall", "import", backupPath); err != nil {
+		return err
+	}
+	if err := os.Remove(backupPath); err != nil {
+		return err
+	}
+	log.Info("firewall configuration restored")
+	return nil
+}
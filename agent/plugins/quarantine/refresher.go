@@ -0,0 +1,75 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package quarantine
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/carlescere/scheduler"
+)
+
+const (
+	// refresherName is the name of the core module refreshing the allowed addresses
+	refresherName = "QuarantineRefresh"
+
+	// refresherFrequencyMinutes is how often the endpoints of a quarantined instance are resolved again
+	refresherFrequencyMinutes = 5
+)
+
+// Refresher is the core module keeping the addresses allowed by the aws:quarantine plugin up to date with the
+// addresses of the endpoints.
+type Refresher struct {
+	context context.T
+	job     *scheduler.Job
+}
+
+// NewRefresher creates a new refresher core module.
+func NewRefresher(context context.T) *Refresher {
+	return &Refresher{
+		context: context.With("[" + refresherName + "]"),
+	}
+}
+
+// refresh updates the allowed addresses if the instance is quarantined.
+func (r *Refresher) refresh() {
+	if err := Refresh(r.context.Log(), r.context.AppConfig()); err != nil {
+		r.context.Log().Errorf("failed to refresh the quarantine allowed addresses: %v", err)
+	}
+}
+
+// ICoreModule implementation
+
+// ModuleName returns the module name
+func (r *Refresher) ModuleName() string {
+	return refresherName
+}
+
+// ModuleExecute refreshes the addresses, which may have changed while the agent was stopped, and schedules the
+// next refreshes
+func (r *Refresher) ModuleExecute(context context.T) (err error) {
+	go r.refresh()
+	if r.job, err = scheduler.Every(refresherFrequencyMinutes).Minutes().Run(r.refresh); err != nil {
+		r.context.Log().Errorf("unable to schedule the refresh of the quarantine allowed addresses. %v", err)
+	}
+	return
+}
+
+// ModuleRequestStop stops the refreshes of the allowed addresses
+func (r *Refresher) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if r.job != nil {
+		r.context.Log().Info("stopping the refresh of the quarantine allowed addresses.")
+		r.job.Quit <- true
+	}
+	return nil
+}
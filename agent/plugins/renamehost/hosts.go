@@ -0,0 +1,61 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package renamehost
+
+import (
+	"net"
+	"strings"
+)
+
+// withHostName returns the lines of a hosts file with the previous host name, and its short name, replaced
+// by the new name in the entries of the loopback addresses. Returns false if there was none.
+func withHostName(lines []string, previous string, name string) ([]string, bool) {
+	previousShort := strings.SplitN(previous, ".", 2)[0]
+	short := strings.SplitN(name, ".", 2)[0]
+	changed := false
+	updated := make([]string, len(lines))
+	for i, line := range lines {
+		updated[i] = line
+		entry := line
+		comment := ""
+		if index := strings.Index(line, "#"); index >= 0 {
+			entry, comment = line[:index], line[index:]
+		}
+		fields := strings.Fields(entry)
+		if len(fields) < 2 {
+			continue
+		}
+		if ip := net.ParseIP(fields[0]); ip == nil || !ip.IsLoopback() {
+			continue
+		}
+		replaced := false
+		for j := 1; j < len(fields); j++ {
+			if strings.EqualFold(fields[j], previous) {
+				fields[j] = name
+				replaced = true
+			} else if previousShort != previous && strings.EqualFold(fields[j], previousShort) {
+				fields[j] = short
+				replaced = true
+			}
+		}
+		if replaced {
+			updated[i] = strings.Join(fields, " ")
+			if comment != "" {
+				updated[i] += " " + comment
+			}
+			changed = true
+		}
+	}
+	return updated, changed
+}
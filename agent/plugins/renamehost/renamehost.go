@@ -0,0 +1,176 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package renamehost implements the aws:renameHost plugin, which renames the host, reboots it if the new name
+// requires it and reports the new name to SSM right away instead of at the next health update.
+package renamehost
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/health"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	ssmsvc "github.com/aws/amazon-ssm-agent/agent/ssm"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+// maxHostNameLength is the longest host name allowed by RFC 1123
+const maxHostNameLength = 253
+
+// labelPattern matches a label of a host name, letters, digits and hyphens not at either end
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+// hostNames returns the current host name and the name the host is renamed to at the next reboot, if any
+var hostNames = currentHostNames
+
+// rename renames the host, returns true if a reboot is required for the new name to apply
+var rename = renameHost
+
+// reportInstanceInformation reports the instance information with the new host name to SSM
+var reportInstanceInformation = func(log log.T) error {
+	_, err := ssmsvc.NewService().UpdateInstanceInformation(log, version.Version, health.AgentStatusActive, health.AgentName)
+	return err
+}
+
+// Plugin is the type for the aws:renameHost plugin.
+type Plugin struct {
+}
+
+// RenameHostPluginInput represents the new name of the host.
+type RenameHostPluginInput struct {
+	contracts.PluginInput
+	ID       string
+	HostName string
+	// AllowReboot reboots the host if the new name only applies after a reboot, the plugin completes
+	// once the host is back with its new name. The name applies at the next reboot otherwise.
+	AllowReboot bool
+}
+
+// RenameHostOutput is the outcome of the rename, set as the output of the plugin.
+type RenameHostOutput struct {
+	PreviousHostName string `json:"previousHostName"`
+	HostName         string `json:"hostName"`
+	Renamed          bool   `json:"renamed"`
+	RebootRequired   bool   `json:"rebootRequired"`
+	Reported         bool   `json:"reported"`
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameRenameHost
+}
+
+// Execute renames the host and reports its new name. The plugin is executed again once the host is back
+// from the reboot it requested, the host then already has its new name.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input RenameHostPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if err := validateHostName(input.HostName); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	current, pending, err := hostNames()
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to get the host name: %v", err))
+		return
+	}
+
+	result := RenameHostOutput{PreviousHostName: current, HostName: input.HostName}
+	switch {
+	case strings.EqualFold(current, input.HostName):
+		output.AppendInfof("host name is %v", current)
+	case strings.EqualFold(pending, input.HostName):
+		// renamed by a previous run that didn't reboot
+		result.RebootRequired = true
+	default:
+		if result.RebootRequired, err = rename(log, input.HostName); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to rename the host to %v: %v", input.HostName, err))
+			return
+		}
+		result.Renamed = true
+		output.AppendInfof("host renamed from %v to %v", current, input.HostName)
+	}
+
+	if result.RebootRequired {
+		setOutput(output, result)
+		if input.AllowReboot {
+			output.AppendInfof("rebooting for the host name %v to apply", input.HostName)
+			output.MarkAsSuccessWithReboot()
+			return
+		}
+		output.AppendInfof("the host name %v applies at the next reboot", input.HostName)
+		output.SetStatus(contracts.ResultStatusSuccess)
+		return
+	}
+
+	// the new name is reported right away, the console would show the previous one until the next health update
+	if err = reportInstanceInformation(log); err != nil {
+		log.Errorf("failed to report the instance information: %v", err)
+		output.AppendErrorf("failed to report the host name %v, it is reported with the next health update: %v", input.HostName, err)
+	} else {
+		result.Reported = true
+	}
+	setOutput(output, result)
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// setOutput sets the outcome as the output of the plugin
+func setOutput(output iohandler.IOHandler, result RenameHostOutput) {
+	if content, err := jsonutil.Marshal(result); err == nil {
+		output.SetOutput(content)
+	}
+}
+
+// validateDNSName checks the name is a valid RFC 1123 host name
+func validateDNSName(name string) error {
+	if name == "" {
+		return fmt.Errorf("HostName is required")
+	}
+	if len(name) > maxHostNameLength {
+		return fmt.Errorf("host name %v is longer than %v characters", name, maxHostNameLength)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !labelPattern.MatchString(label) {
+			return fmt.Errorf("invalid host name %q, labels are up to 63 letters, digits and hyphens not starting or ending with a hyphen", name)
+		}
+	}
+	return nil
+}
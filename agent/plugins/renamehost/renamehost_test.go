@@ -0,0 +1,128 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package renamehost
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeHost is a host whose renames apply at the next reboot if rebootRequired is set
+type fakeHost struct {
+	current        string
+	pending        string
+	rebootRequired bool
+	renames        int
+	reports        int
+}
+
+func (h *fakeHost) stub() func() {
+	defaultReport := reportInstanceInformation
+	hostNames = func() (string, string, error) { return h.current, h.pending, nil }
+	rename = func(log log.T, name string) (bool, error) {
+		h.renames++
+		if h.rebootRequired {
+			h.pending = name
+		} else {
+			h.current = name
+		}
+		return h.rebootRequired, nil
+	}
+	reportInstanceInformation = func(log log.T) error {
+		h.reports++
+		return nil
+	}
+	return func() { hostNames, rename, reportInstanceInformation = currentHostNames, renameHost, defaultReport }
+}
+
+func execute(properties map[string]interface{}) *iohandler.DefaultIOHandler {
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	plugin, _ := NewPlugin()
+	plugin.Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, task.NewChanneledCancelFlag(), output)
+	return output
+}
+
+func TestExecuteRenameWithoutReboot(t *testing.T) {
+	host := &fakeHost{current: "ip-10-0-0-1"}
+	defer host.stub()()
+
+	output := execute(map[string]interface{}{"HostName": "web-1.example.com"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, "web-1.example.com", host.current)
+	assert.Equal(t, 1, host.reports, "the new name is reported right away")
+	assert.True(t, strings.Contains(output.GetOutput().(string), `"previousHostName":"ip-10-0-0-1","hostName":"web-1.example.com","renamed":true,"rebootRequired":false,"reported":true`))
+
+	// renaming to the current name only reports it
+	output = execute(map[string]interface{}{"HostName": "WEB-1.example.com"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, 1, host.renames)
+	assert.Equal(t, 2, host.reports)
+}
+
+func TestExecuteRenameWithReboot(t *testing.T) {
+	host := &fakeHost{current: "EC2AMAZ-ABC123", rebootRequired: true}
+	defer host.stub()()
+
+	output := execute(map[string]interface{}{"HostName": "web-1"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, 0, host.reports, "the name isn't reported before it applies")
+
+	// the rename is pending, the second run reboots without renaming again
+	output = execute(map[string]interface{}{"HostName": "web-1", "AllowReboot": true})
+	assert.Equal(t, contracts.ResultStatusSuccessAndReboot, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, 1, host.renames)
+
+	// the plugin runs again once the host is back with its new name
+	host.current, host.pending = host.pending, ""
+	output = execute(map[string]interface{}{"HostName": "web-1", "AllowReboot": true})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, 1, host.renames)
+	assert.Equal(t, 1, host.reports)
+}
+
+func TestExecuteInvalidHostName(t *testing.T) {
+	host := &fakeHost{current: "ip-10-0-0-1"}
+	defer host.stub()()
+
+	for _, name := range []string{"", "-web", "web_1", "web 1", strings.Repeat("a", 64)} {
+		output := execute(map[string]interface{}{"HostName": name})
+		assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus(), name)
+	}
+	assert.Equal(t, 0, host.renames)
+}
+
+func TestWithHostName(t *testing.T) {
+	hosts := []string{
+		"127.0.0.1 localhost",
+		"127.0.1.1 ip-10-0-0-1.ec2.internal ip-10-0-0-1 # added by cloud-init",
+		"10.0.0.1 ip-10-0-0-1",
+	}
+	updated, changed := withHostName(hosts, "ip-10-0-0-1.ec2.internal", "web-1.example.com")
+	assert.True(t, changed)
+	assert.Equal(t, []string{
+		"127.0.0.1 localhost",
+		"127.0.1.1 web-1.example.com web-1 # added by cloud-init",
+		"10.0.0.1 ip-10-0-0-1",
+	}, updated)
+
+	_, changed = withHostName(hosts, "db-1", "web-1")
+	assert.False(t, changed)
+}
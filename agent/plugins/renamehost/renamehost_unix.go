@@ -0,0 +1,100 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package renamehost
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// hostsPath is the hosts file mapping the host name to a loopback address on some distributions
+var hostsPath = "/etc/hosts"
+
+// hostNamePath is the file the host name is read from at boot without hostnamectl
+var hostNamePath = "/etc/hostname"
+
+// cloudConfigDir is the folder of the cloud-init drop-in configurations
+var cloudConfigDir = "/etc/cloud/cloud.cfg.d"
+
+// preserveHostNameConfig keeps cloud-init from setting the host name of the instance metadata at boot
+const preserveHostNameConfig = "# set by aws:renameHost, the host was renamed\npreserve_hostname: true\n"
+
+// validateHostName checks the name is a valid host name.
+func validateHostName(name string) error {
+	return validateDNSName(name)
+}
+
+// currentHostNames returns the host name, the new name applies without reboot on unix.
+func currentHostNames() (current string, pending string, err error) {
+	current, err = os.Hostname()
+	return current, "", err
+}
+
+// renameHost sets the host name with hostnamectl, or /etc/hostname and hostname without systemd. The name
+// mapped in /etc/hosts is replaced and cloud-init is configured to keep the name at boot.
+func renameHost(log log.T, name string) (bool, error) {
+	previous, err := os.Hostname()
+	if err != nil {
+		return false, err
+	}
+	if _, lookErr := exec.LookPath("hostnamectl"); lookErr == nil {
+		if output, err := exec.Command("hostnamectl", "set-hostname", name).CombinedOutput(); err != nil {
+			return false, fmt.Errorf("hostnamectl failed: %v %v", err, strings.TrimSpace(string(output)))
+		}
+	} else {
+		if err = ioutil.WriteFile(hostNamePath, []byte(name+"\n"), 0644); err != nil {
+			return false, err
+		}
+		if output, err := exec.Command("hostname", name).CombinedOutput(); err != nil {
+			return false, fmt.Errorf("hostname failed: %v %v", err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	if err = replaceHostsName(previous, name); err != nil {
+		log.Errorf("failed to replace %v with %v in %v: %v", previous, name, hostsPath, err)
+	}
+	if fileutil.Exists(cloudConfigDir) {
+		path := filepath.Join(cloudConfigDir, "99-ssm-preserve-hostname.cfg")
+		if err = ioutil.WriteFile(path, []byte(preserveHostNameConfig), 0644); err != nil {
+			log.Errorf("failed to keep cloud-init from resetting the host name: %v", err)
+		}
+	}
+	return false, nil
+}
+
+// replaceHostsName replaces the previous host name with the new one in the hosts file
+func replaceHostsName(previous string, name string) error {
+	info, err := os.Stat(hostsPath)
+	if err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(hostsPath)
+	if err != nil {
+		return err
+	}
+	lines, changed := withHostName(strings.Split(string(content), "\n"), previous, name)
+	if !changed {
+		return nil
+	}
+	return ioutil.WriteFile(hostsPath, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package renamehost
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// maxComputerNameLength is the longest NetBIOS computer name
+	maxComputerNameLength = 15
+
+	// activeComputerNameKey holds the name the computer runs with
+	activeComputerNameKey = `SYSTEM\CurrentControlSet\Control\ComputerName\ActiveComputerName`
+
+	// computerNameKey holds the name the computer runs with after the next reboot
+	computerNameKey = `SYSTEM\CurrentControlSet\Control\ComputerName\ComputerName`
+)
+
+// validateHostName checks the name is a valid computer name, a single label of at most 15 characters.
+func validateHostName(name string) error {
+	if err := validateDNSName(name); err != nil {
+		return err
+	}
+	if strings.Contains(name, ".") || len(name) > maxComputerNameLength {
+		return fmt.Errorf("invalid computer name %v, it must be a single label of at most %v characters", name, maxComputerNameLength)
+	}
+	return nil
+}
+
+// currentHostNames returns the active computer name and the name it is renamed to at the next reboot.
+func currentHostNames() (current string, pending string, err error) {
+	if current, err = computerName(activeComputerNameKey); err != nil {
+		return "", "", err
+	}
+	if pending, err = computerName(computerNameKey); err != nil {
+		return "", "", err
+	}
+	if strings.EqualFold(current, pending) {
+		pending = ""
+	}
+	return current, pending, nil
+}
+
+// renameHost renames the computer with Rename-Computer, the new name applies after a reboot.
+func renameHost(log log.T, name string) (bool, error) {
+	script := fmt.Sprintf("Rename-Computer -NewName '%v' -Force -ErrorAction Stop", name)
+	output, err := exec.Command(appconfig.PowerShellPluginCommandName, "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("%v %v", err, strings.TrimSpace(string(output)))
+	}
+	log.Infof("computer renamed to %v, pending reboot", name)
+	return true, nil
+}
+
+// computerName reads the computer name of the registry key
+func computerName(path string) (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer key.Close()
+	name, _, err := key.GetStringValue("ComputerName")
+	return name, err
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package rendertemplate
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// supportsOwnership and supportsPermissions are true, the rendered files get an owner, a group and a mode
+const supportsOwnership = true
+const supportsPermissions = true
+
+// lookupOwnership returns the uid of the owner and the gid of the group, -1 for those not set.
+func lookupOwnership(owner string, group string) (uid int, gid int, err error) {
+	uid, gid = -1, -1
+	if owner != "" {
+		account, err := user.Lookup(owner)
+		if err != nil {
+			return -1, -1, fmt.Errorf("unknown Owner %v: %v", owner, err)
+		}
+		if uid, err = strconv.Atoi(account.Uid); err != nil {
+			return -1, -1, err
+		}
+	}
+	if group != "" {
+		account, err := user.LookupGroup(group)
+		if err != nil {
+			return -1, -1, fmt.Errorf("unknown Group %v: %v", group, err)
+		}
+		if gid, err = strconv.Atoi(account.Gid); err != nil {
+			return -1, -1, err
+		}
+	}
+	return uid, gid, nil
+}
+
+// ownedBy returns true if the file has the uid and the gid, those set to -1 aren't compared.
+func ownedBy(info os.FileInfo, uid int, gid int) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+	return (uid == -1 || int(stat.Uid) == uid) && (gid == -1 || int(stat.Gid) == gid)
+}
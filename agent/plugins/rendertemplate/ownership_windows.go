@@ -0,0 +1,34 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package rendertemplate
+
+import (
+	"os"
+)
+
+// supportsOwnership and supportsPermissions are false, the rendered files inherit the ACL of their directory
+const supportsOwnership = false
+const supportsPermissions = false
+
+// lookupOwnership returns -1, validate rejects Owner and Group on Windows.
+func lookupOwnership(owner string, group string) (uid int, gid int, err error) {
+	return -1, -1, nil
+}
+
+// ownedBy returns true, Windows files have no uid and gid.
+func ownedBy(info os.FileInfo, uid int, gid int) bool {
+	return true
+}
@@ -0,0 +1,354 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package rendertemplate implements the aws:renderTemplate plugin, which renders a Go template with values
+// of Parameter Store, Secrets Manager and the document parameters, and writes the result atomically to a
+// configuration file, reporting whether it changed.
+package rendertemplate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// ssmPrefix and ssmSecurePrefix are the prefixes of the parameter references of the resolver
+	ssmPrefix       = "ssm:"
+	ssmSecurePrefix = "ssm-secure:"
+
+	// secretsManagerPath is the Parameter Store path of the Secrets Manager secrets
+	secretsManagerPath = "/aws/reference/secretsmanager/"
+
+	// sha256HashType is the type of SourceHash
+	sha256HashType = "sha256"
+
+	// defaultMode is the mode of a new destination file unless set
+	defaultMode = 0600
+)
+
+// resolveParameter returns the value of a parameter reference, e.g. ssm:/app/port
+var resolveParameter = resolveParameterReference
+
+// downloadTemplate returns the local path of the template downloaded from its source
+var downloadTemplate = defaultDownloadTemplate
+
+// defaultDownloadTemplate downloads the template from S3 or HTTPS and verifies its hash.
+func defaultDownloadTemplate(log log.T, source string, sourceHash string) (string, error) {
+	output, err := pluginutil.DownloadFileFromSource(log, source, sourceHash, sha256HashType)
+	if err != nil {
+		return "", err
+	}
+	if !output.IsHashMatched || output.LocalFilePath == "" {
+		return "", fmt.Errorf("the hash of %v doesn't match SourceHash", source)
+	}
+	return output.LocalFilePath, nil
+}
+
+// Plugin is the type for the aws:renderTemplate plugin.
+type Plugin struct {
+}
+
+// RenderTemplatePluginInput represents the template, its values and the destination file.
+type RenderTemplatePluginInput struct {
+	contracts.PluginInput
+	ID string
+	// Template is the text of the template, if not downloaded from Source
+	Template string
+	// Source is the S3 or HTTPS URL of the template, if not inline
+	Source string
+	// SourceHash is the SHA-256 of the template downloaded from Source, not verified if empty
+	SourceHash string
+	// Parameters are the values available to the template as .Parameters, typically document parameters
+	Parameters map[string]interface{}
+	// Destination is the absolute path of the rendered file
+	Destination string
+	// Mode is the octal mode of the rendered file, by default the mode of the existing file or 0600, not used on Windows
+	Mode string
+	// Owner and Group own the rendered file if set, not supported on Windows
+	Owner string
+	Group string
+
+	mode os.FileMode
+}
+
+// RenderTemplateOutput is the outcome of the rendering, set as the output of the plugin.
+type RenderTemplateOutput struct {
+	Destination string `json:"destination"`
+	// Changed is true if the content, the mode or the owner of the destination changed
+	Changed bool   `json:"changed"`
+	SHA256  string `json:"sha256"`
+	Bytes   int    `json:"bytes"`
+}
+
+// templateData is the data the templates are executed with
+type templateData struct {
+	Parameters map[string]interface{}
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameRenderTemplate
+}
+
+// Execute renders the template and writes the destination file if its content changed.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started", Name())
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input RenderTemplatePluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties;\nerror %v", err))
+		return
+	}
+	if err := validate(&input); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	text := input.Template
+	if input.Source != "" {
+		path, err := downloadTemplate(log, input.Source, input.SourceHash)
+		if err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to download the template from %v: %v", input.Source, err))
+			return
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to read the template: %v", err))
+			return
+		}
+		text = string(content)
+	}
+
+	rendered, err := render(log, text, input.Parameters)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to render the template: %v", err))
+		return
+	}
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	changed, err := update(input, rendered)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to write %v: %v", input.Destination, err))
+		return
+	}
+	hash := sha256.Sum256(rendered)
+	result := RenderTemplateOutput{Destination: input.Destination, Changed: changed, SHA256: hex.EncodeToString(hash[:]), Bytes: len(rendered)}
+	if changed {
+		output.AppendInfof("%v updated", input.Destination)
+	} else {
+		output.AppendInfof("%v unchanged", input.Destination)
+	}
+	if content, err := jsonutil.Marshal(result); err == nil {
+		output.SetOutput(content)
+	}
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// validate checks the input and parses the mode.
+func validate(input *RenderTemplatePluginInput) error {
+	if (input.Template == "") == (input.Source == "") {
+		return fmt.Errorf("either Template or Source is required")
+	}
+	if input.Destination == "" || !filepath.IsAbs(input.Destination) {
+		return fmt.Errorf("Destination must be an absolute path, got %q", input.Destination)
+	}
+	input.Destination = filepath.Clean(input.Destination)
+	if input.Mode != "" {
+		mode, err := strconv.ParseUint(input.Mode, 8, 32)
+		if err != nil || mode > 0777 {
+			return fmt.Errorf("Mode must be octal permissions such as 0640, got %q", input.Mode)
+		}
+		input.mode = os.FileMode(mode)
+	}
+	if (input.Owner != "" || input.Group != "") && !supportsOwnership {
+		return fmt.Errorf("Owner and Group are not supported on this platform")
+	}
+	return nil
+}
+
+// render executes the template, resolving the values of Parameter Store and Secrets Manager it references
+// once each. A missing key of the parameters fails the rendering rather than rendering "<no value>".
+func render(log log.T, text string, parameters map[string]interface{}) ([]byte, error) {
+	resolved := make(map[string]string)
+	lookup := func(reference string) (string, error) {
+		if value, found := resolved[reference]; found {
+			return value, nil
+		}
+		value, err := resolveParameter(log, reference)
+		if err != nil {
+			return "", err
+		}
+		resolved[reference] = value
+		return value, nil
+	}
+	functions := template.FuncMap{
+		// ssm returns the value of a String or StringList parameter
+		"ssm": func(name string) (string, error) { return lookup(ssmPrefix + name) },
+		// ssmSecure returns the decrypted value of a SecureString parameter
+		"ssmSecure": func(name string) (string, error) { return lookup(ssmSecurePrefix + name) },
+		// secret returns the value of a Secrets Manager secret
+		"secret": func(id string) (string, error) { return lookup(ssmSecurePrefix + secretsManagerPath + id) },
+		"split":  strings.Split,
+		"join":   strings.Join,
+		"trim":   strings.TrimSpace,
+	}
+	parsed, err := template.New("template").Option("missingkey=error").Funcs(functions).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if parameters == nil {
+		parameters = make(map[string]interface{})
+	}
+	var buffer bytes.Buffer
+	if err = parsed.Execute(&buffer, templateData{Parameters: parameters}); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// resolveParameterReference returns the value of a parameter reference from Parameter Store, the resolver
+// redacts the values of the secure references from the logs.
+func resolveParameterReference(log log.T, reference string) (string, error) {
+	service := ssmparameterresolver.NewService()
+	resolved, err := ssmparameterresolver.ResolveParameterReferenceList(&service, log, []string{reference}, ssmparameterresolver.ResolveOptions{})
+	if err != nil {
+		return "", err
+	}
+	parameter, found := resolved[reference]
+	if !found {
+		return "", fmt.Errorf("%v not found", reference)
+	}
+	return parameter.Value, nil
+}
+
+// update writes the rendered content to the destination if it differs, or only sets the mode and the owner if
+// they differ, and returns whether the destination changed.
+func update(input RenderTemplatePluginInput, content []byte) (changed bool, err error) {
+	uid, gid, err := lookupOwnership(input.Owner, input.Group)
+	if err != nil {
+		return false, err
+	}
+	mode := input.mode
+	info, err := os.Stat(input.Destination)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if info == nil {
+		if mode == 0 {
+			mode = defaultMode
+		}
+		return true, writeAtomically(input.Destination, content, mode, uid, gid)
+	}
+	if mode == 0 {
+		mode = info.Mode().Perm()
+	}
+
+	current, err := ioutil.ReadFile(input.Destination)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(current, content) {
+		return true, writeAtomically(input.Destination, content, mode, uid, gid)
+	}
+	if supportsPermissions && info.Mode().Perm() != mode {
+		if err = os.Chmod(input.Destination, mode); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	if !ownedBy(info, uid, gid) {
+		if err = os.Chown(input.Destination, uid, gid); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// writeAtomically writes the content to a temporary file of the directory of the destination, then renames it
+// over the destination, so the readers of the destination never see a partially written file.
+func writeAtomically(destination string, content []byte, mode os.FileMode, uid int, gid int) (err error) {
+	dir := filepath.Dir(destination)
+	if err = fileutil.MakeDirsWithExecuteAccess(dir); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(destination)+".")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(content); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if supportsPermissions {
+		if err = os.Chmod(tmp.Name(), mode); err != nil {
+			return err
+		}
+	}
+	if uid != -1 || gid != -1 {
+		if err = os.Chown(tmp.Name(), uid, gid); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), destination)
+}
@@ -0,0 +1,171 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rendertemplate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeParameters replaces Parameter Store with the values and returns the references resolved
+func fakeParameters(values map[string]string) (resolved *[]string, restore func()) {
+	resolved = &[]string{}
+	resolveParameter = func(log log.T, reference string) (string, error) {
+		*resolved = append(*resolved, reference)
+		if value, found := values[reference]; found {
+			return value, nil
+		}
+		return "", fmt.Errorf("The following parameter(s) cannot be resolved: %v", reference)
+	}
+	return resolved, func() { resolveParameter = resolveParameterReference }
+}
+
+func TestValidate(t *testing.T) {
+	destination, _ := filepath.Abs(filepath.Join("etc", "app.conf"))
+	input := RenderTemplatePluginInput{Template: "port={{.Parameters.port}}", Destination: destination, Mode: "0640"}
+	assert.NoError(t, validate(&input))
+	assert.Equal(t, os.FileMode(0640), input.mode)
+
+	invalid := []RenderTemplatePluginInput{
+		{Destination: destination},
+		{Template: "a", Source: "https://example.com/app.conf.tmpl", Destination: destination},
+		{Template: "a", Destination: "app.conf"},
+		{Template: "a", Destination: destination, Mode: "rw-r--r--"},
+		{Template: "a", Destination: destination, Mode: "1777"},
+	}
+	for _, input := range invalid {
+		assert.Error(t, validate(&input), "%+v", input)
+	}
+}
+
+func TestRender(t *testing.T) {
+	resolved, restore := fakeParameters(map[string]string{
+		"ssm:/app/hosts":                                      "a.example.com,b.example.com",
+		"ssm-secure:/app/password":                            "s3cr3t",
+		"ssm-secure:/aws/reference/secretsmanager/app/apikey": "k3y",
+	})
+	defer restore()
+
+	rendered, err := render(log.NewMockLog(), `hosts={{ join (split (ssm "/app/hosts") ",") " " }}
+password={{ ssmSecure "/app/password" }}
+apikey={{ secret "app/apikey" }}
+port={{ .Parameters.port }}
+again={{ ssm "/app/hosts" }}`, map[string]interface{}{"port": "8080"})
+	assert.NoError(t, err)
+	assert.Equal(t, `hosts=a.example.com b.example.com
+password=s3cr3t
+apikey=k3y
+port=8080
+again=a.example.com,b.example.com`, string(rendered))
+	assert.Equal(t, []string{"ssm:/app/hosts", "ssm-secure:/app/password", "ssm-secure:/aws/reference/secretsmanager/app/apikey"}, *resolved,
+		"each parameter is resolved once")
+
+	_, err = render(log.NewMockLog(), `{{ .Parameters.prot }}`, map[string]interface{}{"port": "8080"})
+	assert.Error(t, err, "a missing key fails the rendering")
+	_, err = render(log.NewMockLog(), `{{ ssm "/app/missing" }}`, nil)
+	assert.Error(t, err)
+	_, err = render(log.NewMockLog(), `{{ if }}`, nil)
+	assert.Error(t, err)
+}
+
+func TestUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rendertemplate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	input := RenderTemplatePluginInput{Destination: filepath.Join(dir, "conf.d", "app.conf")}
+
+	changed, err := update(input, []byte("port=8080\n"))
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	content, err := ioutil.ReadFile(input.Destination)
+	assert.NoError(t, err)
+	assert.Equal(t, "port=8080\n", string(content))
+
+	changed, err = update(input, []byte("port=8080\n"))
+	assert.NoError(t, err)
+	assert.False(t, changed, "the same content leaves the file unchanged")
+
+	changed, err = update(input, []byte("port=9090\n"))
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	files, _ := ioutil.ReadDir(filepath.Dir(input.Destination))
+	assert.Len(t, files, 1, "the temporary file is renamed over the destination")
+
+	if runtime.GOOS != "windows" {
+		info, _ := os.Stat(input.Destination)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "readable by the owner only by default")
+		input.mode = 0644
+		changed, err = update(input, []byte("port=9090\n"))
+		assert.NoError(t, err)
+		assert.True(t, changed, "a different mode is a change")
+		info, _ = os.Stat(input.Destination)
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	}
+}
+
+func TestExecute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rendertemplate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	_, restore := fakeParameters(map[string]string{"ssm:/app/port": "8080"})
+	defer restore()
+	downloadTemplate = func(log log.T, source string, sourceHash string) (string, error) {
+		path := filepath.Join(dir, "app.conf.tmpl")
+		return path, ioutil.WriteFile(path, []byte(`port={{ ssm "/app/port" }} env={{ .Parameters.env }}`), 0600)
+	}
+	defer func() { downloadTemplate = defaultDownloadTemplate }()
+
+	execute := func(properties map[string]interface{}) *iohandler.DefaultIOHandler {
+		output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+		plugin, _ := NewPlugin()
+		plugin.Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, task.NewChanneledCancelFlag(), output)
+		return output
+	}
+	properties := map[string]interface{}{
+		"Source":      "https://s3.amazonaws.com/bucket/app.conf.tmpl",
+		"Destination": filepath.Join(dir, "app.conf"),
+		"Parameters":  map[string]interface{}{"env": "prod"},
+	}
+
+	output := execute(properties)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	var result RenderTemplateOutput
+	assert.NoError(t, jsonutil.Unmarshal(output.GetOutput().(string), &result))
+	assert.True(t, result.Changed)
+	assert.Equal(t, len("port=8080 env=prod"), result.Bytes)
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "app.conf"))
+	assert.Equal(t, "port=8080 env=prod", string(content))
+
+	output = execute(properties)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.NoError(t, jsonutil.Unmarshal(output.GetOutput().(string), &result))
+	assert.False(t, result.Changed)
+
+	output = execute(map[string]interface{}{"Template": `{{ ssm "/app/missing" }}`, "Destination": filepath.Join(dir, "other.conf")})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	_, err = os.Stat(filepath.Join(dir, "other.conf"))
+	assert.True(t, os.IsNotExist(err), "nothing is written when the rendering fails")
+}
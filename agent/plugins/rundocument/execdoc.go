@@ -34,7 +34,7 @@ type ExecDocument interface {
 		s3Bucket string, s3KeyPrefix string, messageID string, documentID string, defaultWorkingDirectory string,
 		params map[string]interface{}) (pluginsInfo []contracts.PluginState, err error)
 	ExecuteDocument(context context.T, pluginInput []contracts.PluginState, documentID string,
-		documentCreatedDate string) (chan contracts.DocumentResult, error)
+		documentCreatedDate string, cancelFlag task.CancelFlag) (chan contracts.DocumentResult, error)
 }
 
 type ExecDocumentImpl struct {
@@ -68,9 +68,10 @@ func (exec ExecDocumentImpl) ParseDocument(log log.T, documentRaw []byte, orches
 	return
 }
 
-// ExecuteDocument is responsible to execute the sub-documents that are created or downloaded by the executeCommand plugin
+// ExecuteDocument is responsible to execute the sub-documents that are created or downloaded by the executeCommand plugin,
+// they are cancelled with cancelFlag.
 func (exec ExecDocumentImpl) ExecuteDocument(context context.T, pluginInput []contracts.PluginState, documentID string,
-	documentCreatedDate string) (resultChannels chan contracts.DocumentResult, err error) {
+	documentCreatedDate string, cancelFlag task.CancelFlag) (resultChannels chan contracts.DocumentResult, err error) {
 	log := context.Log()
 	log.Info("Running sub-document")
 
@@ -88,7 +89,6 @@ func (exec ExecDocumentImpl) ExecuteDocument(context context.T, pluginInput []co
 	}
 	docStore := executer.NewDocumentFileStore(context, documentID, instanceID, appconfig.DefaultLocationOfCurrent,
 		&docState, docmanager.NewDocumentFileMgr(appconfig.DefaultDataStorePath, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState))
-	cancelFlag := task.NewChanneledCancelFlag()
 	resultChannels = exec.DocExecutor.Run(cancelFlag, &docStore)
 
 	return resultChannels, nil
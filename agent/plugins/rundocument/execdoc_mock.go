@@ -19,6 +19,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -35,7 +36,7 @@ func (e ExecMock) ParseDocument(log log.T, documentRaw []byte, orchestrationDir
 	return args.Get(0).([]contracts.PluginState), args.Error(1)
 }
 
-func (e ExecMock) ExecuteDocument(context context.T, pluginInput []contracts.PluginState, documentID string, documentCreatedDate string) (chan contracts.DocumentResult, error) {
-	args := e.Called(context, pluginInput, documentID, documentCreatedDate)
+func (e ExecMock) ExecuteDocument(context context.T, pluginInput []contracts.PluginState, documentID string, documentCreatedDate string, cancelFlag task.CancelFlag) (chan contracts.DocumentResult, error) {
+	args := e.Called(context, pluginInput, documentID, documentCreatedDate, cancelFlag)
 	return args.Get(0).(chan contracts.DocumentResult), args.Error(1)
 }
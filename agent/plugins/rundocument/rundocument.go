@@ -25,6 +25,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
 	ssmsvc "github.com/aws/amazon-ssm-agent/agent/ssm"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/aws/amazon-ssm-agent/agent/times"
@@ -34,6 +35,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"strings"
@@ -49,6 +51,9 @@ const (
 
 	SSMDocumentType = "SSMDocument"
 	LocalPathType   = "LocalPath"
+	S3Type          = "S3"
+
+	sha256HashType = "sha256"
 
 	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
 
@@ -76,6 +81,26 @@ type RunDocumentPluginInput struct {
 	DocumentType       string      `json:"documentType"`
 	DocumentPath       string      `json:"documentPath"`
 	DocumentParameters interface{} `json:"documentParameters"`
+	DocumentHash       string      `json:"documentHash"`
+}
+
+// RunDocumentPluginOutput is the output of the plugin, the results of the steps of the sub-document in order, which
+// the next steps of the document refer to with {{ steps.<step>.steps.<sub-document step>.<output> }}
+type RunDocumentPluginOutput = contracts.NestedStepsOutput
+
+// StepResult is the result of a step of the sub-document
+type StepResult = contracts.NestedStepResult
+
+// downloadDocument downloads a document from S3 or HTTPS, verifying its sha256 hash when given
+var downloadDocument = func(log log.T, source string, sourceHash string) (string, error) {
+	output, err := pluginutil.DownloadFileFromSource(log, source, sourceHash, sha256HashType)
+	if err != nil {
+		return "", err
+	}
+	if !output.IsHashMatched || output.LocalFilePath == "" {
+		return "", fmt.Errorf("the hash of %v doesn't match documentHash", source)
+	}
+	return output.LocalFilePath, nil
 }
 
 // ExecutePluginDepth is the struct that is sent through to the sub-documents to maintain the depth of execution
@@ -106,12 +131,12 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 	} else if input, err := parseAndValidateInput(config.Properties); err != nil {
 		output.MarkAsFailed(err)
 	} else {
-		p.runDocument(context, input, config, output)
+		p.runDocument(context, input, config, cancelFlag, output)
 	}
 }
 
 // runCopyContent figures out the type of location, downloads the resource, saves it on disk and returns information required for it
-func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, config contracts.Configuration, output iohandler.IOHandler) {
+func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 
 	log := context.Log()
 	//Run aws:runDocument plugin
@@ -141,6 +166,12 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 	if input.DocumentType == SSMDocumentType {
 		if documentPath, err = p.downloadDocumentFromSSM(log, config, input); err != nil {
 			output.MarkAsFailed(err)
+			return
+		}
+	} else if input.DocumentType == S3Type {
+		if documentPath, err = downloadDocument(log, input.DocumentPath, input.DocumentHash); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to download the document %v - %v", input.DocumentPath, err))
+			return
 		}
 	} else {
 		if filepath.IsAbs(input.DocumentPath) {
@@ -163,8 +194,11 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 
 	var resultsChannel chan contracts.DocumentResult
 	var pluginOutput map[string]*contracts.PluginResult
-	if resultsChannel, err = p.execDoc.ExecuteDocument(context, pluginsInfo, config.BookKeepingFileName, times.ToIso8601UTC(time.Now())); err != nil {
+	documentFlag, stopDocumentFlag := subDocumentCancelFlag(cancelFlag)
+	defer stopDocumentFlag()
+	if resultsChannel, err = p.execDoc.ExecuteDocument(context, pluginsInfo, config.BookKeepingFileName, times.ToIso8601UTC(time.Now()), documentFlag); err != nil {
 		output.MarkAsFailed(fmt.Errorf("There was an error while running documents - %v", err.Error()))
+		return
 	}
 
 	for res := range resultsChannel {
@@ -175,8 +209,24 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 	}
 	if pluginOutput == nil {
 		output.MarkAsFailed(errors.New("No output obtained from executing document"))
+		return
 	}
-	for _, pluginOut := range pluginOutput {
+	var results RunDocumentPluginOutput
+	for _, pluginOut := range orderedResults(pluginsInfo, pluginOutput) {
+		step := StepResult{
+			Name:   pluginOut.PluginID,
+			Action: pluginOut.PluginName,
+			Status: pluginOut.Status,
+			Code:   pluginOut.Code,
+			Stdout: pluginOut.StandardOutput,
+			Stderr: pluginOut.StandardError,
+			Output: pluginOut.Output,
+		}
+		if pluginOut.Error != nil {
+			step.Error = pluginOut.Error.Error()
+		}
+		results.Steps = append(results.Steps, step)
+
 		if pluginOut.StandardOutput != "" {
 			// separating the append so that the output is on a new line
 			output.AppendInfof("%v", pluginOut.StandardOutput)
@@ -190,6 +240,64 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 		}
 		output.SetStatus(contracts.MergeResultStatus(output.GetStatus(), pluginOut.Status))
 	}
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	}
+	output.SetOutput(results)
+}
+
+// subDocumentCancelFlag returns the cancel flag of the sub-document, set when the flag of the step is until stop is
+// called. The timeouts of the step cancel the sub-document with CancelReasonParentTimeout, so that its steps don't
+// report them as their own timeouts.
+func subDocumentCancelFlag(cancelFlag task.CancelFlag) (flag task.CancelFlag, stop func()) {
+	documentFlag := task.NewChanneledCancelFlag()
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-task.Done(cancelFlag):
+			// both are ready when the flag is set after stop but before the goroutine selected
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if !cancelFlag.Canceled() && !cancelFlag.ShutDown() {
+				return
+			}
+			reason := cancelFlag.Reason()
+			if reason == task.CancelReasonStepTimeout || reason == task.CancelReasonTimeout {
+				reason = task.CancelReasonParentTimeout
+			}
+			documentFlag.SetWithReason(cancelFlag.State(), reason)
+		case <-done:
+		}
+	}()
+	return documentFlag, func() { close(done) }
+}
+
+// orderedResults returns the results of the steps in the order of the document
+func orderedResults(pluginsInfo []contracts.PluginState, pluginOutput map[string]*contracts.PluginResult) []*contracts.PluginResult {
+	var results []*contracts.PluginResult
+	ordered := make(map[string]bool)
+	for _, pluginInfo := range pluginsInfo {
+		if pluginOut, found := pluginOutput[pluginInfo.Id]; found && !ordered[pluginInfo.Id] {
+			results = append(results, pluginOut)
+			ordered[pluginInfo.Id] = true
+		}
+	}
+	var remaining []string
+	for id := range pluginOutput {
+		if !ordered[id] {
+			remaining = append(remaining, id)
+		}
+	}
+	sort.Strings(remaining)
+	for _, id := range remaining {
+		results = append(results, pluginOutput[id])
+	}
+	return results
 }
 
 func (p *Plugin) downloadDocumentFromSSM(log log.T, config contracts.Configuration, input *RunDocumentPluginInput) (string, error) {
@@ -280,9 +388,9 @@ func parseAndValidateInput(rawPluginInput interface{}) (*RunDocumentPluginInput,
 func validateInput(input *RunDocumentPluginInput) (valid bool, err error) {
 	// ensure non-empty location type
 	if input.DocumentType == "" {
-		return false, errors.New("Document Type must be specified to either by SSMDocument or LocalPath.")
+		return false, errors.New("Document Type must be specified to either by SSMDocument, LocalPath or S3.")
 	}
-	if input.DocumentType != SSMDocumentType && input.DocumentType != LocalPathType {
+	if input.DocumentType != SSMDocumentType && input.DocumentType != LocalPathType && input.DocumentType != S3Type {
 		return false, errors.New("Document type specified in invalid")
 	}
 	if input.DocumentPath == "" {
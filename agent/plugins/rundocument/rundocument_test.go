@@ -17,6 +17,7 @@ package rundocument
 
 import (
 	"fmt"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -95,7 +96,7 @@ func TestExecDocumentImpl_ExecuteDocumentFailure(t *testing.T) {
 	exec := ExecDocumentImpl{
 		DocExecutor: execMock,
 	}
-	_, err := exec.ExecuteDocument(contextMock, pluginInput, documentId, "time")
+	_, err := exec.ExecuteDocument(contextMock, pluginInput, documentId, "time", task.NewChanneledCancelFlag())
 
 	assert.NoError(t, err)
 }
@@ -125,7 +126,7 @@ func TestExecDocumentImpl_ExecuteDocumentSuccess(t *testing.T) {
 	exec := ExecDocumentImpl{
 		DocExecutor: execMock,
 	}
-	_, err := exec.ExecuteDocument(contextMock, pluginInput, documentId, "time")
+	_, err := exec.ExecuteDocument(contextMock, pluginInput, documentId, "time", task.NewChanneledCancelFlag())
 
 	assert.NoError(t, err)
 }
@@ -318,9 +319,10 @@ func TestPlugin_RunDocument(t *testing.T) {
 
 	fileMock.On("ReadFile", "/var/tmp/docLocation/docname.json").Return(content, nil)
 	execMock.On("ParseDocument", contextMock.Log(), []byte(content), conf.OrchestrationDirectory, conf.OutputS3BucketName, conf.OutputS3KeyPrefix, conf.MessageId, conf.PluginID, conf.DefaultWorkingDirectory, parameters).Return(plugins, nil)
-	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything).Return(resChan, nil)
+	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything, mock.Anything).Return(resChan, nil)
 	mockIOHandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
 	mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+	mockIOHandler.On("SetOutput", mock.Anything).Return()
 
 	p := Plugin{
 		filesys: fileMock,
@@ -378,9 +380,10 @@ func TestPlugin_RunDocumentFromSSMDocument(t *testing.T) {
 	fileMock.On("WriteFile", "orch/downloads/RunShellScript.json", content).Return(nil)
 	fileMock.On("ReadFile", "orch/downloads/RunShellScript.json").Return(content, nil)
 	execMock.On("ParseDocument", contextMock.Log(), []byte(content), conf.OrchestrationDirectory, conf.OutputS3BucketName, conf.OutputS3KeyPrefix, conf.MessageId, conf.PluginID, conf.DefaultWorkingDirectory, parameters).Return(plugins, nil)
-	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything).Return(resChan, nil)
+	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything, mock.Anything).Return(resChan, nil)
 	mockIOHandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
 	mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+	mockIOHandler.On("SetOutput", mock.Anything).Return()
 
 	var input RunDocumentPluginInput
 	input.DocumentType = "SSMDocument"
@@ -393,7 +396,7 @@ func TestPlugin_RunDocumentFromSSMDocument(t *testing.T) {
 		execDoc: execMock,
 	}
 
-	p.runDocument(contextMock, &input, conf, mockIOHandler)
+	p.runDocument(contextMock, &input, conf, task.NewChanneledCancelFlag(), mockIOHandler)
 
 	execMock.AssertExpectations(t)
 	fileMock.AssertExpectations(t)
@@ -436,9 +439,10 @@ func TestPlugin_RunDocumentFromAbsLocalPath(t *testing.T) {
 
 	fileMock.On("ReadFile", "/var/tmp/document/docName.json").Return(content, nil)
 	execMock.On("ParseDocument", contextMock.Log(), []byte(content), conf.OrchestrationDirectory, conf.OutputS3BucketName, conf.OutputS3KeyPrefix, conf.MessageId, conf.PluginID, conf.DefaultWorkingDirectory, parameters).Return(plugins, nil)
-	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything).Return(resChan, nil)
+	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything, mock.Anything).Return(resChan, nil)
 	mockIOHandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
 	mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+	mockIOHandler.On("SetOutput", mock.Anything).Return()
 
 	var input RunDocumentPluginInput
 	input.DocumentType = "LocalPath"
@@ -450,13 +454,85 @@ func TestPlugin_RunDocumentFromAbsLocalPath(t *testing.T) {
 		execDoc: execMock,
 	}
 
-	p.runDocument(contextMock, &input, conf, mockIOHandler)
+	p.runDocument(contextMock, &input, conf, task.NewChanneledCancelFlag(), mockIOHandler)
 
 	execMock.AssertExpectations(t)
 	fileMock.AssertExpectations(t)
 	mockIOHandler.AssertExpectations(t)
 }
 
+func TestPlugin_RunDocumentFromS3(t *testing.T) {
+	execMock := NewExecMock()
+	fileMock := filemock.FileSystemMock{}
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	conf := createStubConfiguration("orch", "bucket", "prefix", "1234-1234-1234", "directory")
+
+	plugins := []contracts.PluginState{{Id: "second", Name: "aws:runShellScript"}, {Id: "first", Name: "aws:runShellScript"}}
+	pluginResults := map[string]*contracts.PluginResult{
+		"first":  {PluginID: "first", PluginName: "aws:runShellScript", Status: contracts.ResultStatusSuccess},
+		"second": {PluginID: "second", PluginName: "aws:runShellScript", Status: contracts.ResultStatusFailed, Code: 2, Error: fmt.Errorf("exit status 2")},
+	}
+	resChan := make(chan contracts.DocumentResult, 1)
+	resChan <- contracts.DocumentResult{Status: contracts.ResultStatusFailed, PluginResults: pluginResults}
+	close(resChan)
+
+	savedDownload := downloadDocument
+	defer func() { downloadDocument = savedDownload }()
+	downloadDocument = func(log log.T, source string, sourceHash string) (string, error) {
+		assert.Equal(t, "https://s3.amazonaws.com/bucket/document.yaml", source)
+		assert.Equal(t, "abcdef", sourceHash)
+		return "/var/tmp/downloads/document.yaml", nil
+	}
+
+	content := "content"
+	parameters := make(map[string]interface{})
+	fileMock.On("ReadFile", "/var/tmp/downloads/document.yaml").Return(content, nil)
+	execMock.On("ParseDocument", contextMock.Log(), []byte(content), conf.OrchestrationDirectory, conf.OutputS3BucketName, conf.OutputS3KeyPrefix, conf.MessageId, conf.PluginID, conf.DefaultWorkingDirectory, parameters).Return(plugins, nil)
+	execMock.On("ExecuteDocument", contextMock, mock.Anything, conf.BookKeepingFileName, mock.Anything, mock.Anything).Return(resChan, nil)
+	mockIOHandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
+	mockIOHandler.On("SetStatus", mock.Anything).Return()
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+	mockIOHandler.On("SetOutput", mock.Anything).Return()
+
+	input := RunDocumentPluginInput{
+		DocumentType: S3Type,
+		DocumentPath: "https://s3.amazonaws.com/bucket/document.yaml",
+		DocumentHash: "abcdef",
+	}
+	p := Plugin{
+		filesys: fileMock,
+		execDoc: execMock,
+	}
+	p.runDocument(contextMock, &input, conf, task.NewChanneledCancelFlag(), mockIOHandler)
+
+	mockIOHandler.AssertCalled(t, "SetOutput", RunDocumentPluginOutput{Steps: []StepResult{
+		{Name: "second", Action: "aws:runShellScript", Status: contracts.ResultStatusFailed, Code: 2, Error: "exit status 2"},
+		{Name: "first", Action: "aws:runShellScript", Status: contracts.ResultStatusSuccess},
+	}})
+	execMock.AssertExpectations(t)
+	fileMock.AssertExpectations(t)
+}
+
+func TestPlugin_RunDocumentFromS3DownloadFailure(t *testing.T) {
+	execMock := NewExecMock()
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	conf := createStubConfiguration("orch", "bucket", "prefix", "1234-1234-1234", "directory")
+
+	savedDownload := downloadDocument
+	defer func() { downloadDocument = savedDownload }()
+	downloadDocument = func(log log.T, source string, sourceHash string) (string, error) {
+		return "", fmt.Errorf("the hash of %v doesn't match documentHash", source)
+	}
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	input := RunDocumentPluginInput{DocumentType: S3Type, DocumentPath: "https://s3.amazonaws.com/bucket/document.json"}
+	p := Plugin{execDoc: execMock}
+	p.runDocument(contextMock, &input, conf, task.NewChanneledCancelFlag(), mockIOHandler)
+
+	mockIOHandler.AssertNumberOfCalls(t, "MarkAsFailed", 1)
+	execMock.AssertNotCalled(t, "ParseDocument", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestName(t *testing.T) {
 	assert.Equal(t, "aws:runDocument", Name())
 }
@@ -516,7 +592,7 @@ func TestValidateInput_NoDocumentType(t *testing.T) {
 
 	assert.False(t, result)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "Document Type must be specified to either by SSMDocument or LocalPath.")
+	assert.Contains(t, err.Error(), "Document Type must be specified to either by SSMDocument, LocalPath or S3.")
 
 }
 func TestValidateInput_UnknownDocumentType(t *testing.T) {
@@ -608,12 +684,85 @@ type MockDefaultPlugin struct {
 	mock.Mock
 }
 
+func TestSubDocumentCancelFlag(t *testing.T) {
+	for reason, expected := range map[task.CancelReason]task.CancelReason{
+		task.CancelReasonUserRequest: task.CancelReasonUserRequest,
+		task.CancelReasonStepTimeout: task.CancelReasonParentTimeout,
+		task.CancelReasonTimeout:     task.CancelReasonParentTimeout,
+	} {
+		cancelFlag := task.NewChanneledCancelFlag()
+		documentFlag, stop := subDocumentCancelFlag(cancelFlag)
+		cancelFlag.SetWithReason(task.Canceled, reason)
+		assert.Equal(t, task.Canceled, documentFlag.Wait())
+		assert.Equal(t, expected, documentFlag.Reason())
+		stop()
+	}
+
+	cancelFlag := task.NewChanneledCancelFlag()
+	documentFlag, stop := subDocumentCancelFlag(cancelFlag)
+	stop()
+	cancelFlag.Set(task.ShutDown)
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, documentFlag.ShutDown())
+}
+
+func TestSubDocumentCancelFlagStopReleasesGoroutines(t *testing.T) {
+	cancelFlag := task.NewChanneledCancelFlag()
+	count := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		_, stop := subDocumentCancelFlag(cancelFlag)
+		stop()
+	}
+	for start := time.Now(); runtime.NumGoroutine() > count && time.Since(start) < time.Second; {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, runtime.NumGoroutine() <= count)
+}
+
+func TestPlugin_RunDocumentCancelled(t *testing.T) {
+	execMock := NewExecMock()
+	fileMock := filemock.FileSystemMock{}
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	conf := createStubConfiguration("orch", "bucket", "prefix", "1234-1234-1234", "directory")
+	plugins := []contracts.PluginState{{Id: "child", Name: "aws:runShellScript"}}
+
+	cancelFlag := task.NewChanneledCancelFlag()
+	resChan := make(chan contracts.DocumentResult, 1)
+	fileMock.On("ReadFile", "/var/tmp/docLocation/docname.json").Return("content", nil)
+	execMock.On("ParseDocument", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(plugins, nil)
+	execMock.On("ExecuteDocument", contextMock, mock.Anything, conf.BookKeepingFileName, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		// the sub-document stops its step once the step of the parent document is cancelled
+		cancelFlag.SetWithReason(task.Canceled, task.CancelReasonStepTimeout)
+		documentFlag := args.Get(4).(task.CancelFlag)
+		documentFlag.Wait()
+		assert.Equal(t, task.CancelReasonParentTimeout, documentFlag.Reason())
+		resChan <- contracts.DocumentResult{Status: contracts.ResultStatusCancelled, PluginResults: map[string]*contracts.PluginResult{
+			"child": {PluginID: "child", PluginName: "aws:runShellScript", Status: contracts.ResultStatusCancelled, StandardOutput: "partial"},
+		}}
+		close(resChan)
+	}).Return(resChan, nil)
+	mockIOHandler.On("AppendInfof", "%v", []interface{}{"partial"}).Return()
+	mockIOHandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
+	mockIOHandler.On("SetStatus", contracts.ResultStatusCancelled).Return()
+	mockIOHandler.On("MarkAsCancelled").Return()
+	mockIOHandler.On("SetOutput", mock.Anything).Return()
+
+	input := RunDocumentPluginInput{DocumentType: LocalPathType, DocumentPath: "/var/tmp/docLocation/docname.json"}
+	p := Plugin{filesys: fileMock, execDoc: execMock}
+	p.runDocument(contextMock, &input, conf, cancelFlag, mockIOHandler)
+
+	mockIOHandler.AssertCalled(t, "MarkAsCancelled")
+	mockIOHandler.AssertCalled(t, "SetOutput", RunDocumentPluginOutput{Steps: []StepResult{
+		{Name: "child", Action: "aws:runShellScript", Status: contracts.ResultStatusCancelled, Stdout: "partial"},
+	}})
+}
+
 func createMockCancelFlag() task.CancelFlag {
 	mockCancelFlag := new(task.MockCancelFlag)
 	// Setup mocks
 	mockCancelFlag.On("Canceled").Return(false)
 	mockCancelFlag.On("ShutDown").Return(false)
-	mockCancelFlag.On("Wait").Return(false).After(100 * time.Millisecond)
+	mockCancelFlag.On("Wait").Return(task.Completed).After(100 * time.Millisecond)
 
 	return mockCancelFlag
 }
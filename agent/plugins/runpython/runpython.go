@@ -0,0 +1,228 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpython implements the aws:runPythonScript plugin, which runs an inline or downloaded Python script
+// in a cached virtualenv holding the pip requirements the step declares.
+package runpython
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// scriptName is the file the script is written to in the orchestration directory
+	scriptName = "_script.py"
+
+	// sha256HashType is the type of SourceHash
+	sha256HashType = "sha256"
+
+	// pythonSetting is the plugin setting of the Python the virtualenvs are created with
+	pythonSetting = "Python"
+	// maxAgeSetting is the plugin setting of the days a virtualenv is kept without being used
+	maxAgeSetting = "VirtualenvMaxAgeDays"
+	// defaultMaxAgeDays is the default of maxAgeSetting
+	defaultMaxAgeDays = 30
+)
+
+// downloadScript returns the local path of the script downloaded from its source
+var downloadScript = func(log log.T, source string, sourceHash string) (string, error) {
+	output, err := pluginutil.DownloadFileFromSource(log, source, sourceHash, sha256HashType)
+	if err != nil {
+		return "", err
+	}
+	if !output.IsHashMatched || output.LocalFilePath == "" {
+		return "", fmt.Errorf("the hash of %v doesn't match SourceHash", source)
+	}
+	return output.LocalFilePath, nil
+}
+
+// Plugin is the type for the aws:runPythonScript plugin.
+type Plugin struct {
+	// CommandExecuter runs the script, and creates the virtualenvs
+	CommandExecuter executers.T
+}
+
+// RunPythonPluginInput represents the script of a step and its requirements.
+type RunPythonPluginInput struct {
+	contracts.PluginInput
+	ID string
+	// Script are the lines of the script, if not downloaded from Source
+	Script []string
+	// Source is the S3 or HTTPS URL of the script, if not inline
+	Source string
+	// SourceHash is the SHA-256 of the script downloaded from Source, not verified if empty
+	SourceHash string
+	// Requirements are the lines of the pip requirements file of the script, the script runs with the Python of
+	// the plugin settings without a virtualenv when there are none
+	Requirements []string
+	// Arguments are given to the script
+	Arguments        []string
+	WorkingDirectory string
+	TimeoutSeconds   interface{}
+	// RunAsElevated runs the script as the user of the agent instead of Agent.RunAsUser
+	RunAsElevated bool
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	return &Plugin{CommandExecuter: executers.ShellCommandExecuter{}}, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameAwsRunPythonScript
+}
+
+// Execute prepares the virtualenv of the requirements of the step and runs the script with it.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started", Name())
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input RunPythonPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties;\nerror %v", err))
+		return
+	}
+	if len(input.Script) == 0 == (input.Source == "") {
+		output.MarkAsFailed(fmt.Errorf("either Script or Source is required"))
+		return
+	}
+	appConfig := context.AppConfig()
+	settings := appConfig.PluginSettings(Name())
+	runAsUser, err := runAsUser(log, appConfig.Agent.RunAsUser, appConfig.ExecutionPolicy, config.ExecutionContext, input.RunAsElevated)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	executer := executers.RunAs(executers.InContext(p.CommandExecuter, config.ExecutionContext), runAsUser)
+	timeout := pluginutil.ValidateExecutionTimeout(log, input.TimeoutSeconds)
+
+	orchestrationDir := config.OrchestrationDirectory
+	if err := fileutil.MakeDirsWithExecuteAccess(orchestrationDir); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to create orchestrationDir directory, %v", orchestrationDir))
+		return
+	}
+	scriptPath := filepath.Join(orchestrationDir, scriptName)
+	if err := writeScript(log, input, scriptPath); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	defer pluginutil.ShredScriptFile(log, scriptPath)
+
+	python, err := lookPython(settings.String(pythonSetting, defaultPython))
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	if len(input.Requirements) > 0 {
+		venv := virtualenv{
+			root:     filepath.Join(virtualenvsRoot, userDir(runAsUser)),
+			python:   python,
+			executer: executer,
+			runAs:    runAsUser,
+			maxAge:   time.Duration(settings.Int(maxAgeSetting, defaultMaxAgeDays)) * 24 * time.Hour,
+		}
+		if python, err = venv.prepare(log, input.Requirements, cancelFlag, timeout); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to prepare the virtualenv: %v", err))
+			return
+		}
+	}
+	if err = executers.GrantAccess(runAsUser, orchestrationDir); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to prepare the script to run as %v: %v", runAsUser, err))
+		return
+	}
+
+	workingDir := input.WorkingDirectory
+	if workingDir == "" {
+		workingDir = config.DefaultWorkingDirectory
+	}
+	exitCode, err := executer.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, timeout,
+		python, append([]string{scriptPath}, input.Arguments...))
+	output.SetExitCode(exitCode)
+	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+	if err != nil {
+		status := output.GetStatus()
+		if status != contracts.ResultStatusCancelled && status != contracts.ResultStatusTimedOut {
+			output.MarkAsFailed(fmt.Errorf("failed to run the script: %v", err))
+		}
+	}
+}
+
+// writeScript writes the inline or downloaded script of the step.
+func writeScript(log log.T, input RunPythonPluginInput, scriptPath string) error {
+	lines := input.Script
+	if input.Source != "" {
+		path, err := downloadScript(log, input.Source, input.SourceHash)
+		if err != nil {
+			return fmt.Errorf("failed to download the script from %v: %v", input.Source, err)
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read the script: %v", err)
+		}
+		lines = []string{strings.TrimSuffix(string(content), "\n")}
+	}
+	if err := pluginutil.CreateScriptFile(log, scriptPath, lines, fileutil.ByteOrderMarkSkip); err != nil {
+		return fmt.Errorf("failed to create script file. %v", err)
+	}
+	return nil
+}
+
+// runAsUser returns the user the script runs as, as aws:runShellScript does: the user of the executionContext of
+// the document, else the user of the agent when the step sets runAsElevated and the local policy allows it, else
+// Agent.RunAsUser.
+func runAsUser(log log.T, agentRunAsUser string, policy appconfig.ExecutionPolicyCfg, executionContext *contracts.ExecutionContext, runAsElevated bool) (string, error) {
+	if executionContext != nil && executionContext.User != "" {
+		return executionContext.User, nil
+	}
+	if runAsElevated && agentRunAsUser != "" {
+		if err := appconfig.CheckElevation(policy); err != nil {
+			return "", err
+		}
+		log.Infof("running the script as the agent user instead of %v, the step sets runAsElevated", agentRunAsUser)
+		return "", nil
+	}
+	return agentRunAsUser, nil
+}
+
+// userDir is the directory of the virtualenvs of a user, who owns them
+func userDir(runAsUser string) string {
+	if runAsUser == "" {
+		// user names can't start with a dot
+		return ".agent"
+	}
+	return runAsUser
+}
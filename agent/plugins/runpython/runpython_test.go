@@ -0,0 +1,225 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpython
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	multiwritermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var logger = log.NewMockLog()
+
+// withTempVirtualenvs runs the test with the virtualenvs in a temporary directory and python3 installed
+func withTempVirtualenvs(t *testing.T, test func(root string)) {
+	root, err := ioutil.TempDir("", "virtualenvs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	savedRoot, savedLookPath := virtualenvsRoot, lookPath
+	defer func() { virtualenvsRoot, lookPath = savedRoot, savedLookPath }()
+	virtualenvsRoot = root
+	lookPath = func(name string) (string, error) {
+		if name == defaultPython {
+			return "/usr/bin/" + name, nil
+		}
+		return "", fmt.Errorf("executable file not found in $PATH")
+	}
+	test(root)
+}
+
+// expectVirtualenvCreation sets the expectations of the commands creating a virtualenv, which create its directory
+func expectVirtualenvCreation(executer *executers.MockCommandExecuter) {
+	executer.On("NewExecute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		"/usr/bin/"+defaultPython, mock.MatchedBy(func(args []string) bool { return len(args) == 3 && args[1] == "venv" })).
+		Run(func(args mock.Arguments) { os.MkdirAll(args.Get(7).([]string)[2], 0700) }).Return(0, nil)
+	executer.On("NewExecute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.MatchedBy(func(args []string) bool { return len(args) > 2 && args[1] == "pip" })).Return(0, nil)
+}
+
+func TestVirtualenvIsCachedByRequirements(t *testing.T) {
+	withTempVirtualenvs(t, func(root string) {
+		executer := new(executers.MockCommandExecuter)
+		expectVirtualenvCreation(executer)
+		venv := virtualenv{root: root, python: "/usr/bin/" + defaultPython, executer: executer}
+
+		python, err := venv.prepare(logger, []string{"requests==2.18.4", "boto3"}, task.NewChanneledCancelFlag(), 60)
+		assert.NoError(t, err)
+		dir := filepath.Join(root, virtualenvKey(venv.python, []string{"boto3", "requests==2.18.4"}))
+		assert.Equal(t, virtualenvPython(dir), python)
+		assert.True(t, filepath.IsAbs(python))
+		_, err = os.Stat(dir + lockSuffix)
+		assert.NoError(t, err)
+
+		// the same requirements in another order use the virtualenv
+		cached, err := venv.prepare(logger, []string{"boto3", " requests==2.18.4"}, task.NewChanneledCancelFlag(), 60)
+		assert.NoError(t, err)
+		assert.Equal(t, python, cached)
+		executer.AssertNumberOfCalls(t, "NewExecute", 2)
+	})
+}
+
+func TestVirtualenvFailedInstallIsRetried(t *testing.T) {
+	withTempVirtualenvs(t, func(root string) {
+		executer := new(executers.MockCommandExecuter)
+		executer.On("NewExecute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			"/usr/bin/"+defaultPython, mock.Anything).Run(func(args mock.Arguments) {
+			os.MkdirAll(args.Get(7).([]string)[2], 0700)
+		}).Return(0, nil)
+		executer.On("NewExecute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything).Return(1, nil)
+		venv := virtualenv{root: root, python: "/usr/bin/" + defaultPython, executer: executer}
+
+		_, err := venv.prepare(logger, []string{"missing-package"}, task.NewChanneledCancelFlag(), 60)
+		assert.Error(t, err)
+		_, err = venv.prepare(logger, []string{"missing-package"}, task.NewChanneledCancelFlag(), 60)
+		assert.Error(t, err)
+		executer.AssertNumberOfCalls(t, "NewExecute", 4)
+	})
+}
+
+func TestVirtualenvPrune(t *testing.T) {
+	withTempVirtualenvs(t, func(root string) {
+		unused := filepath.Join(root, "unused")
+		used := filepath.Join(root, "used")
+		for _, dir := range []string{unused, used} {
+			os.MkdirAll(dir, 0700)
+			ioutil.WriteFile(filepath.Join(dir, readyFile), nil, 0600)
+		}
+		old := time.Now().Add(-48 * time.Hour)
+		os.Chtimes(filepath.Join(unused, readyFile), old, old)
+		os.Chtimes(unused, old, old)
+
+		virtualenv{root: root, maxAge: 24 * time.Hour}.prune(logger)
+		_, err := os.Stat(unused)
+		assert.True(t, os.IsNotExist(err))
+		_, err = os.Stat(used)
+		assert.NoError(t, err)
+	})
+}
+
+func TestVirtualenvPruneSkipsLockedVirtualenvs(t *testing.T) {
+	withTempVirtualenvs(t, func(root string) {
+		locked := filepath.Join(root, "locked")
+		os.MkdirAll(locked, 0700)
+		old := time.Now().Add(-48 * time.Hour)
+		os.Chtimes(locked, old, old)
+		lock, err := filelock.TryLock(locked + lockSuffix)
+		assert.NoError(t, err)
+		defer lock.Unlock()
+
+		virtualenv{root: root, maxAge: 24 * time.Hour}.prune(logger)
+		_, err = os.Stat(locked)
+		assert.NoError(t, err)
+	})
+}
+
+func TestPipArguments(t *testing.T) {
+	assert.Equal(t, []string{"-m", "pip", "install", "--disable-pip-version-check", "--no-input",
+		"--index-url", "https://pypi.example.com/simple", "requests >= 2.0; python_version < '3.8'", "boto3"},
+		pipArguments([]string{"--index-url https://pypi.example.com/simple", "# pinned", "requests >= 2.0; python_version < '3.8'", "", " boto3"}))
+}
+
+func TestExecuteRequiresScriptOrSource(t *testing.T) {
+	for _, properties := range []map[string]interface{}{
+		{},
+		{"script": []string{"print(1)"}, "source": "https://example.com/script.py"},
+	} {
+		cancelFlag := task.NewChanneledCancelFlag()
+		ioHandler := new(iohandlermocks.MockIOHandler)
+		ioHandler.On("MarkAsFailed", fmt.Errorf("either Script or Source is required")).Return()
+		p, _ := NewPlugin()
+		p.Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, cancelFlag, ioHandler)
+		ioHandler.AssertExpectations(t)
+	}
+}
+
+func TestExecuteWithRequirements(t *testing.T) {
+	withTempVirtualenvs(t, func(root string) {
+		orchestrationDir, err := ioutil.TempDir("", "orchestration")
+		assert.NoError(t, err)
+		defer os.RemoveAll(orchestrationDir)
+
+		executer := new(executers.MockCommandExecuter)
+		expectVirtualenvCreation(executer)
+		scriptPath := filepath.Join(orchestrationDir, scriptName)
+		executer.On("NewExecute", mock.Anything, "/tmp", mock.Anything, mock.Anything, mock.Anything, 3600,
+			mock.MatchedBy(func(python string) bool {
+				return filepath.Dir(filepath.Dir(filepath.Dir(python))) == filepath.Join(root, ".agent")
+			}),
+			[]string{scriptPath, "--verbose"}).Run(func(args mock.Arguments) {
+			script, _ := ioutil.ReadFile(scriptPath)
+			assert.Equal(t, "import requests\nprint(requests.__version__)\n", string(script))
+		}).Return(0, nil)
+
+		ioHandler := new(iohandlermocks.MockIOHandler)
+		ioHandler.On("GetStdoutWriter").Return(new(multiwritermock.MockDocumentIOMultiWriter))
+		ioHandler.On("GetStderrWriter").Return(new(multiwritermock.MockDocumentIOMultiWriter))
+		ioHandler.On("SetExitCode", 0).Return()
+		ioHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+
+		p := &Plugin{CommandExecuter: executer}
+		p.Execute(context.NewMockDefault(), contracts.Configuration{
+			Properties: map[string]interface{}{
+				"script":           []string{"import requests", "print(requests.__version__)"},
+				"requirements":     []string{"requests"},
+				"arguments":        []string{"--verbose"},
+				"workingDirectory": "/tmp",
+			},
+			OrchestrationDirectory: orchestrationDir,
+		}, task.NewChanneledCancelFlag(), ioHandler)
+
+		ioHandler.AssertExpectations(t)
+		executer.AssertNumberOfCalls(t, "NewExecute", 3)
+		_, err = os.Stat(scriptPath)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestRunAsUser(t *testing.T) {
+	allowed := appconfig.ExecutionPolicyCfg{AllowElevation: true}
+	user, err := runAsUser(logger, "ssm-user", allowed, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "ssm-user", user)
+	user, err = runAsUser(logger, "ssm-user", allowed, nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "", user)
+	user, err = runAsUser(logger, "ssm-user", appconfig.ExecutionPolicyCfg{}, &contracts.ExecutionContext{User: "deploy"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy", user)
+
+	// the local policy denies elevation by default
+	_, err = runAsUser(logger, "ssm-user", appconfig.DefaultConfig().ExecutionPolicy, nil, true)
+	assert.Error(t, err)
+	user, err = runAsUser(logger, "", appconfig.DefaultConfig().ExecutionPolicy, nil, true)
+	assert.NoError(t, err, "there is nothing to elevate from without Agent.RunAsUser")
+	assert.Equal(t, "", user)
+	assert.Equal(t, ".agent", userDir(""))
+	assert.Equal(t, "deploy", userDir("deploy"))
+}
@@ -0,0 +1,194 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpython
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// lockSuffix names the lock file of a virtualenv, next to its directory in the root of the virtualenvs
+	lockSuffix = ".lock"
+	// readyFile marks the virtualenvs whose requirements are installed, its modification time is their last use
+	readyFile = ".ready"
+
+	// maxPipOutputLength is the length the output of a failed pip install is truncated to
+	maxPipOutputLength = 2500
+)
+
+// virtualenvsRoot is the directory of the cached virtualenvs, by user and requirements
+var virtualenvsRoot = filepath.Join(appconfig.DefaultDataStorePath, "runpython", "virtualenvs")
+
+// lookPath finds the Python of the plugin settings in the PATH of the agent
+var lookPath = exec.LookPath
+
+// virtualenv is the cache of the virtualenvs of a user
+type virtualenv struct {
+	// root is the directory of the virtualenvs of the user
+	root string
+	// python creates the virtualenvs
+	python string
+	// executer runs the commands creating the virtualenvs as the user, who owns them
+	executer executers.T
+	runAs    string
+	// maxAge is how long a virtualenv is kept without being used, they are kept forever when 0
+	maxAge time.Duration
+}
+
+// lookPython returns the path of the Python the scripts run with.
+func lookPython(python string) (string, error) {
+	path, err := lookPath(python)
+	if err != nil {
+		return "", fmt.Errorf("Python %v is not installed: %v", python, err)
+	}
+	return path, nil
+}
+
+// virtualenvKey identifies the virtualenv of the requirements, which doesn't depend on their order
+func virtualenvKey(python string, requirements []string) string {
+	lines := make([]string, 0, len(requirements))
+	for _, requirement := range requirements {
+		if requirement = strings.TrimSpace(requirement); requirement != "" {
+			lines = append(lines, requirement)
+		}
+	}
+	sort.Strings(lines)
+	hash := sha256.Sum256([]byte(python + "\n" + strings.Join(lines, "\n")))
+	return hex.EncodeToString(hash[:16])
+}
+
+// pipArguments returns the arguments of pip installing the requirements. A requirement is an argument, the options
+// of a requirements file, such as --index-url, are split into their arguments, and the comments are skipped.
+func pipArguments(requirements []string) []string {
+	args := []string{"-m", "pip", "install", "--disable-pip-version-check", "--no-input"}
+	for _, requirement := range requirements {
+		requirement = strings.TrimSpace(requirement)
+		switch {
+		case requirement == "" || strings.HasPrefix(requirement, "#"):
+		case strings.HasPrefix(requirement, "-"):
+			args = append(args, strings.Fields(requirement)...)
+		default:
+			args = append(args, requirement)
+		}
+	}
+	return args
+}
+
+// prepare returns the Python of the virtualenv of the requirements, creating it and installing the requirements
+// unless a previous step did. The virtualenvs of the user unused for maxAge are removed. The lock file of the
+// virtualenv serializes its preparation between the document workers, and keeps it from being pruned meanwhile.
+func (v virtualenv) prepare(log log.T, requirements []string, cancelFlag task.CancelFlag, timeout int) (string, error) {
+	if err := fileutil.MakeDirsWithExecuteAccess(v.root); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(v.root, virtualenvKey(v.python, requirements))
+	lock, err := filelock.Acquire(dir+lockSuffix, time.Duration(timeout)*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("failed to lock the virtualenv %v: %v", dir, err)
+	}
+	defer lock.Unlock()
+
+	python := virtualenvPython(dir)
+	ready := filepath.Join(dir, readyFile)
+	if fileutil.Exists(ready) {
+		now := time.Now()
+		os.Chtimes(ready, now, now)
+		log.Debugf("Using the virtualenv %v", dir)
+		return python, nil
+	}
+
+	if err := executers.GrantAccess(v.runAs, v.root); err != nil {
+		return "", err
+	}
+	v.prune(log)
+	// a virtualenv without readyFile failed to install its requirements, it's created again
+	os.RemoveAll(dir)
+
+	log.Infof("Creating the virtualenv %v", dir)
+	if err := v.run(log, cancelFlag, timeout, v.python, "-m", "venv", dir); err != nil {
+		return "", err
+	}
+	// the requirements are given to pip, which runs as the user, as arguments rather than in a file of the agent
+	if err := v.run(log, cancelFlag, timeout, python, pipArguments(requirements)...); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(ready, nil, appconfig.ReadWriteAccess); err != nil {
+		return "", err
+	}
+	return python, nil
+}
+
+// run runs a command preparing the virtualenv, its output is part of the error when it fails
+func (v virtualenv) run(log log.T, cancelFlag task.CancelFlag, timeout int, command string, args ...string) error {
+	var output bytes.Buffer
+	exitCode, err := v.executer.NewExecute(log, v.root, &output, &output, cancelFlag, timeout, command, args)
+	if err == nil && exitCode == 0 {
+		return nil
+	}
+	text := output.String()
+	if len(text) > maxPipOutputLength {
+		text = "..." + text[len(text)-maxPipOutputLength:]
+	}
+	if err == nil {
+		err = fmt.Errorf("exit code %v", exitCode)
+	}
+	return fmt.Errorf("%v %v failed: %v\n%v", filepath.Base(command), strings.Join(args, " "), err, text)
+}
+
+// prune removes the virtualenvs of the user unused for maxAge, except those locked by a step preparing them
+func (v virtualenv) prune(log log.T) {
+	if v.maxAge <= 0 {
+		return
+	}
+	entries, err := ioutil.ReadDir(v.root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		dir := filepath.Join(v.root, entry.Name())
+		lastUse := entry.ModTime()
+		if ready, err := os.Stat(filepath.Join(dir, readyFile)); err == nil {
+			lastUse = ready.ModTime()
+		}
+		if !entry.IsDir() || time.Since(lastUse) < v.maxAge {
+			continue
+		}
+		lock, err := filelock.TryLock(dir + lockSuffix)
+		if err != nil {
+			continue
+		}
+		log.Infof("Removing the virtualenv %v, unused since %v", dir, lastUse)
+		if err = os.RemoveAll(dir); err != nil {
+			log.Warnf("failed to remove the virtualenv %v: %v", dir, err)
+		}
+		lock.Unlock()
+	}
+}
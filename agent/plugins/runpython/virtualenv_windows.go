@@ -0,0 +1,29 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package runpython
+
+import (
+	"path/filepath"
+)
+
+// defaultPython is the Python the scripts run with unless the plugin settings name another one, the installer of
+// Python for Windows doesn't install python3
+const defaultPython = "python"
+
+// virtualenvPython returns the Python of a virtualenv
+func virtualenvPython(dir string) string {
+	return filepath.Join(dir, "Scripts", "python.exe")
+}
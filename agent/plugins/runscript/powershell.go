@@ -0,0 +1,271 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runscript implements the runscript plugin.
+package runscript
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/versionutil"
+)
+
+const (
+	// editionDesktop is Windows PowerShell, powershell.exe up to 5.1
+	editionDesktop = "Desktop"
+	// editionCore is PowerShell 6 and later, pwsh
+	editionCore = "Core"
+
+	// preferPwshSetting is the plugin setting running the scripts with pwsh when it's installed, unless the step
+	// pins the edition
+	preferPwshSetting = "PreferPwsh"
+	// executionPolicySetting is the plugin setting of the execution policy of the steps that don't set one, the
+	// steps may only set a stricter one
+	executionPolicySetting = "ExecutionPolicy"
+	// languageModeSetting is the plugin setting of the language mode of the steps, ConstrainedLanguage can't be
+	// loosened by the steps
+	languageModeSetting = "LanguageMode"
+
+	languageModeFull        = "FullLanguage"
+	languageModeConstrained = "ConstrainedLanguage"
+
+	// constrainedCommand sets the language mode and runs the script, exiting like -File does: with the exit code of
+	// the script, else 1 when its last command failed. $LASTEXITCODE alone is that of the last native command, 0
+	// when the script only ran cmdlets.
+	constrainedCommand = "$ExecutionContext.SessionState.LanguageMode = '%v'; $LASTEXITCODE = 0; & '%v'; " +
+		"if (-not $?) { if ($LASTEXITCODE) { exit $LASTEXITCODE }; exit 1 }; exit $LASTEXITCODE"
+)
+
+// executionPolicies are the execution policies the scripts may run with, from the strictest
+var executionPolicies = []string{"AllSigned", "RemoteSigned", "Unrestricted", "Bypass"}
+
+// languageModes are the language modes the scripts may run in, from the strictest
+var languageModes = []string{languageModeConstrained, languageModeFull}
+
+// executionPoliciesEnforced is whether PowerShell enforces the execution policies, it only does on Windows
+var executionPoliciesEnforced = runtime.GOOS == "windows"
+
+// powerShellInstallation is a PowerShell the scripts may run with
+type powerShellInstallation struct {
+	command string
+	edition string
+}
+
+// powerShellExists returns whether the command of an installation exists
+var powerShellExists = fileutil.Exists
+
+// versionTimeout is how long PowerShell may take to report its version before it's killed
+var versionTimeout = 30 * time.Second
+
+// powerShellVersions caches the versions of the installations, by command
+var powerShellVersions sync.Map
+
+// powerShellVersion returns the version of an installation, e.g. 7.3.4 or 5.1.19041.3031
+var powerShellVersion = func(command string) (string, error) {
+	if version, found := powerShellVersions.Load(command); found {
+		return version.(string), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, command, "-NoProfile", "-NonInteractive", "-Command", "$PSVersionTable.PSVersion.ToString()").Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("%v didn't report its version within %v", command, versionTimeout)
+	}
+	if err != nil {
+		return "", err
+	}
+	version := strings.TrimSpace(string(output))
+	powerShellVersions.Store(command, version)
+	return version, nil
+}
+
+// selectPowerShell returns the command of the first installed PowerShell of the edition whose version is in the
+// range. Without an edition, Windows PowerShell comes first unless preferCore is set.
+func selectPowerShell(log log.T, edition string, versionRange string, preferCore bool) (string, error) {
+	switch {
+	case edition == "":
+	case strings.EqualFold(edition, editionDesktop):
+		edition = editionDesktop
+	case strings.EqualFold(edition, editionCore):
+		edition = editionCore
+	default:
+		return "", fmt.Errorf("unsupported PowerShell edition %q, expected %v or %v", edition, editionDesktop, editionCore)
+	}
+	if _, err := inVersionRange("0", versionRange); err != nil {
+		return "", err
+	}
+
+	var candidates []powerShellInstallation
+	for _, installation := range powerShellInstallations() {
+		if (edition == "" || installation.edition == edition) && powerShellExists(installation.command) {
+			candidates = append(candidates, installation)
+		}
+	}
+	if edition == "" && preferCore {
+		// the sort is stable, the installations of each edition keep their order
+		var core, desktop []powerShellInstallation
+		for _, candidate := range candidates {
+			if candidate.edition == editionCore {
+				core = append(core, candidate)
+			} else {
+				desktop = append(desktop, candidate)
+			}
+		}
+		candidates = append(core, desktop...)
+	}
+
+	for _, candidate := range candidates {
+		if versionRange == "" {
+			return candidate.command, nil
+		}
+		version, err := powerShellVersion(candidate.command)
+		if err != nil {
+			log.Warnf("failed to get the version of %v: %v", candidate.command, err)
+			continue
+		}
+		if matches, _ := inVersionRange(version, versionRange); matches {
+			log.Debugf("Running the PowerShell scripts with %v %v", candidate.command, version)
+			return candidate.command, nil
+		}
+	}
+	return "", fmt.Errorf("no PowerShell matching %v is installed", describePowerShell(edition, versionRange))
+}
+
+// describePowerShell describes the PowerShell a step asks for
+func describePowerShell(edition string, versionRange string) string {
+	description := "edition " + edition
+	if edition == "" {
+		description = "any edition"
+	}
+	if versionRange != "" {
+		description += " and version " + versionRange
+	}
+	return description
+}
+
+// inVersionRange returns whether the version matches all the comma separated constraints of the range, e.g.
+// ">=7.2, <8". A constraint without operator matches the versions it's a prefix of, e.g. 7 matches 7.3.4.
+func inVersionRange(version string, versionRange string) (bool, error) {
+	matches := true
+	for _, constraint := range strings.Split(versionRange, ",") {
+		constraint = strings.TrimSpace(constraint)
+		if constraint == "" {
+			continue
+		}
+		operator := strings.TrimRight(constraint, "0123456789.")
+		bound := strings.TrimSpace(constraint[len(operator):])
+		operator = strings.TrimSpace(operator)
+		if bound == "" || strings.Trim(bound, ".") != bound || strings.Contains(bound, "..") {
+			return false, fmt.Errorf("invalid PowerShell version %q", constraint)
+		}
+		comparison := versionutil.Compare(version, bound, false)
+		switch operator {
+		case "":
+			matches = matches && (version == bound || strings.HasPrefix(version, bound+"."))
+		case "=", "==":
+			matches = matches && comparison == 0
+		case ">=":
+			matches = matches && comparison >= 0
+		case ">":
+			matches = matches && comparison > 0
+		case "<=":
+			matches = matches && comparison <= 0
+		case "<":
+			matches = matches && comparison < 0
+		default:
+			return false, fmt.Errorf("invalid PowerShell version %q, expected a version after =, >=, >, <= or <", constraint)
+		}
+	}
+	return matches, nil
+}
+
+// powerShellArguments returns the arguments running the script with the execution policy and in the language mode
+// of the step, else of the plugin settings; nil when neither sets them and the default arguments apply. The
+// language mode is set before the script runs. It is best effort: without a WDAC or AppLocker policy enforcing
+// it, the script can't switch back from ConstrainedLanguage in its session, but can start another PowerShell.
+func powerShellArguments(arguments []string, scriptPath string, pluginInput RunScriptPluginInput, settings appconfig.PluginSettings) ([]string, error) {
+	policy, err := strictestChoice("execution policy", executionPolicies, pluginInput.ExecutionPolicy, settings.String(executionPolicySetting, ""), executionPolicySetting)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := strictestChoice("language mode", languageModes, pluginInput.LanguageMode, settings.String(languageModeSetting, ""), languageModeSetting)
+	if err != nil {
+		return nil, err
+	}
+	if policy == "" && mode == "" {
+		return nil, nil
+	}
+	if policy != "" && !executionPoliciesEnforced && policy != "Unrestricted" && policy != "Bypass" {
+		return nil, fmt.Errorf("execution policy %v can't be enforced, PowerShell only enforces the execution policies on Windows", policy)
+	}
+
+	var result []string
+	for i := 0; i < len(arguments); i++ {
+		switch {
+		case strings.EqualFold(arguments[i], "-ExecutionPolicy") && i+1 < len(arguments):
+			if policy == "" {
+				result = append(result, arguments[i], arguments[i+1])
+			}
+			i++
+		case arguments[i] == "" || strings.EqualFold(arguments[i], "-f") || strings.EqualFold(arguments[i], "-File"):
+		default:
+			result = append(result, arguments[i])
+		}
+	}
+	if policy != "" {
+		result = append(result, "-ExecutionPolicy", policy)
+	}
+	if mode == languageModeConstrained {
+		command := fmt.Sprintf(constrainedCommand, languageModeConstrained, strings.Replace(scriptPath, "'", "''", -1))
+		return append(result, "-Command", command), nil
+	}
+	return append(result, "-File", scriptPath), nil
+}
+
+// strictestChoice returns the choice of the step, else of the plugin setting, checking the step doesn't loosen the
+// plugin setting. The choices are ordered from the strictest.
+func strictestChoice(name string, choices []string, step string, setting string, settingName string) (string, error) {
+	stepIndex, settingIndex := -1, -1
+	for i, choice := range choices {
+		if strings.EqualFold(step, choice) {
+			stepIndex = i
+		}
+		if strings.EqualFold(setting, choice) {
+			settingIndex = i
+		}
+	}
+	if step != "" && stepIndex < 0 {
+		return "", fmt.Errorf("unsupported %v %q, expected one of %v", name, step, strings.Join(choices, ", "))
+	}
+	if setting != "" && settingIndex < 0 {
+		return "", fmt.Errorf("unsupported %v %q in the %v plugin setting, expected one of %v", name, setting, settingName, strings.Join(choices, ", "))
+	}
+	switch {
+	case stepIndex < 0 && settingIndex < 0:
+		return "", nil
+	case stepIndex < 0:
+		return choices[settingIndex], nil
+	case settingIndex >= 0 && stepIndex > settingIndex:
+		return "", fmt.Errorf("%v %v is less strict than %v of the %v plugin setting", name, choices[stepIndex], choices[settingIndex], settingName)
+	}
+	return choices[stepIndex], nil
+}
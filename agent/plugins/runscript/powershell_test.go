@@ -0,0 +1,152 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withPowerShells replaces the installations with Windows PowerShell 5.1 and pwsh 6.2 and 7.3
+func withPowerShells() func() {
+	savedInstallations, savedExists, savedVersion := powerShellInstallations, powerShellExists, powerShellVersion
+	powerShellInstallations = func() []powerShellInstallation {
+		return []powerShellInstallation{
+			{command: "powershell.exe", edition: editionDesktop},
+			{command: "pwsh6", edition: editionCore},
+			{command: "missing", edition: editionCore},
+			{command: "pwsh7", edition: editionCore},
+		}
+	}
+	powerShellExists = func(command string) bool { return command != "missing" }
+	versions := map[string]string{"powershell.exe": "5.1.19041.3031", "pwsh6": "6.2.7", "pwsh7": "7.3.4"}
+	powerShellVersion = func(command string) (string, error) { return versions[command], nil }
+	return func() { powerShellInstallations, powerShellExists, powerShellVersion = savedInstallations, savedExists, savedVersion }
+}
+
+func TestSelectPowerShell(t *testing.T) {
+	defer withPowerShells()()
+
+	for _, test := range []struct {
+		edition, versionRange string
+		preferCore            bool
+		expected              string
+	}{
+		{"", "", false, "powershell.exe"},
+		{"", "", true, "pwsh6"},
+		{"core", "", false, "pwsh6"},
+		{"Desktop", "", true, "powershell.exe"},
+		{"", "7", false, "pwsh7"},
+		{"", ">=5.1, <6", true, "powershell.exe"},
+		{"Core", ">= 6.2.7, < 7.3.4", false, "pwsh6"},
+		{"", ">7.3", false, "pwsh7"},
+	} {
+		command, err := selectPowerShell(logger, test.edition, test.versionRange, test.preferCore)
+		assert.NoError(t, err, "%+v", test)
+		assert.Equal(t, test.expected, command, "%+v", test)
+	}
+}
+
+func TestSelectPowerShellFailures(t *testing.T) {
+	defer withPowerShells()()
+
+	_, err := selectPowerShell(logger, "Desktop", ">=7", false)
+	assert.EqualError(t, err, "no PowerShell matching edition Desktop and version >=7 is installed")
+	_, err = selectPowerShell(logger, "Nano", "", false)
+	assert.Error(t, err)
+	_, err = selectPowerShell(logger, "", "~7.2", false)
+	assert.Error(t, err)
+	_, err = selectPowerShell(logger, "", "7.2-preview", false)
+	assert.Error(t, err)
+}
+
+func TestPowerShellArguments(t *testing.T) {
+	saved := executionPoliciesEnforced
+	executionPoliciesEnforced = true
+	defer func() { executionPoliciesEnforced = saved }()
+	windowsArgs := []string{"-InputFormat", "None", "-Noninteractive", "-NoProfile", "-ExecutionPolicy", "unrestricted", "-f"}
+
+	args, err := powerShellArguments(windowsArgs, `C:\run\_script.ps1`, RunScriptPluginInput{}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, args, "the default arguments apply")
+
+	args, err = powerShellArguments(windowsArgs, `C:\run\_script.ps1`, RunScriptPluginInput{ExecutionPolicy: "allsigned"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-InputFormat", "None", "-Noninteractive", "-NoProfile", "-ExecutionPolicy", "AllSigned", "-File", `C:\run\_script.ps1`}, args)
+
+	args, err = powerShellArguments(windowsArgs, `C:\o'brien\_script.ps1`, RunScriptPluginInput{LanguageMode: "ConstrainedLanguage"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-InputFormat", "None", "-Noninteractive", "-NoProfile", "-ExecutionPolicy", "unrestricted", "-Command",
+		`$ExecutionContext.SessionState.LanguageMode = 'ConstrainedLanguage'; $LASTEXITCODE = 0; & 'C:\o''brien\_script.ps1'; `+
+			`if (-not $?) { if ($LASTEXITCODE) { exit $LASTEXITCODE }; exit 1 }; exit $LASTEXITCODE`}, args)
+}
+
+func TestPowerShellArgumentsFromPluginSettings(t *testing.T) {
+	saved := executionPoliciesEnforced
+	executionPoliciesEnforced = true
+	defer func() { executionPoliciesEnforced = saved }()
+	settings := map[string]interface{}{"ExecutionPolicy": "RemoteSigned", "LanguageMode": "ConstrainedLanguage"}
+
+	args, err := powerShellArguments([]string{""}, "/run/_script.ps1", RunScriptPluginInput{ExecutionPolicy: "AllSigned"}, settings)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-ExecutionPolicy", "AllSigned", "-Command",
+		"$ExecutionContext.SessionState.LanguageMode = 'ConstrainedLanguage'; $LASTEXITCODE = 0; & '/run/_script.ps1'; " +
+			"if (-not $?) { if ($LASTEXITCODE) { exit $LASTEXITCODE }; exit 1 }; exit $LASTEXITCODE"}, args)
+
+	_, err = powerShellArguments(nil, "/run/_script.ps1", RunScriptPluginInput{ExecutionPolicy: "Bypass"}, settings)
+	assert.EqualError(t, err, "execution policy Bypass is less strict than RemoteSigned of the ExecutionPolicy plugin setting")
+	_, err = powerShellArguments(nil, "/run/_script.ps1", RunScriptPluginInput{LanguageMode: "FullLanguage"}, settings)
+	assert.EqualError(t, err, "language mode FullLanguage is less strict than ConstrainedLanguage of the LanguageMode plugin setting")
+	_, err = powerShellArguments(nil, "/run/_script.ps1", RunScriptPluginInput{LanguageMode: "NoLanguage"}, nil)
+	assert.Error(t, err)
+}
+
+func TestPowerShellArgumentsWithoutExecutionPolicies(t *testing.T) {
+	saved := executionPoliciesEnforced
+	executionPoliciesEnforced = false
+	defer func() { executionPoliciesEnforced = saved }()
+
+	_, err := powerShellArguments([]string{""}, "/run/_script.ps1", RunScriptPluginInput{ExecutionPolicy: "AllSigned"}, nil)
+	assert.EqualError(t, err, "execution policy AllSigned can't be enforced, PowerShell only enforces the execution policies on Windows")
+	args, err := powerShellArguments([]string{""}, "/run/_script.ps1", RunScriptPluginInput{ExecutionPolicy: "Bypass"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-ExecutionPolicy", "Bypass", "-File", "/run/_script.ps1"}, args)
+}
+
+func TestPowerShellVersionTimesOut(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake PowerShell is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "powershell")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	command := filepath.Join(dir, "pwsh")
+	assert.NoError(t, ioutil.WriteFile(command, []byte("#!/bin/sh\nexec sleep 10\n"), 0700))
+	savedTimeout := versionTimeout
+	versionTimeout = 100 * time.Millisecond
+	defer func() { versionTimeout = savedTimeout }()
+
+	start := time.Now()
+	_, err = powerShellVersion(command)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "didn't report its version")
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
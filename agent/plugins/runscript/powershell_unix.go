@@ -0,0 +1,28 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package runscript
+
+// powerShellInstallations are the places pwsh is installed in, only PowerShell 6 and later run on linux and macOS
+var powerShellInstallations = func() []powerShellInstallation {
+	return []powerShellInstallation{
+		{command: "/usr/bin/pwsh", edition: editionCore},
+		{command: "/usr/local/bin/pwsh", edition: editionCore},
+		{command: "/opt/microsoft/powershell/7/pwsh", edition: editionCore},
+		{command: "/snap/bin/pwsh", edition: editionCore},
+		// the packages of PowerShell 6.0 installed it as powershell
+		{command: "/usr/bin/powershell", edition: editionCore},
+	}
+}
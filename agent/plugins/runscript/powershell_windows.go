@@ -0,0 +1,31 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package runscript
+
+import (
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// powerShellInstallations are Windows PowerShell, then the places the installers of pwsh install it in
+var powerShellInstallations = func() []powerShellInstallation {
+	return []powerShellInstallation{
+		{command: appconfig.PowerShellPluginCommandName, edition: editionDesktop},
+		{command: filepath.Join(appconfig.EnvProgramFiles, "PowerShell", "7", "pwsh.exe"), edition: editionCore},
+		{command: filepath.Join(appconfig.EnvProgramFiles, "PowerShell", "6", "pwsh.exe"), edition: editionCore},
+	}
+}
@@ -35,14 +35,21 @@ type runPowerShellPlugin struct {
 func NewRunPowerShellPlugin() (*runPowerShellPlugin, error) {
 	psplugin := runPowerShellPlugin{
 		Plugin{
-			Name:            appconfig.PluginNameAwsRunPowerShellScript,
-			ScriptName:      powerShellScriptName,
-			ShellCommand:    appconfig.PowerShellPluginCommandName,
-			ShellArguments:  strings.Split(appconfig.PowerShellPluginCommandArgs, " "),
-			ByteOrderMark:   fileutil.ByteOrderMarkEmit,
-			CommandExecuter: executers.ShellCommandExecuter{},
+			Name:                appconfig.PluginNameAwsRunPowerShellScript,
+			ScriptName:          powerShellScriptName,
+			ShellCommand:        appconfig.PowerShellPluginCommandName,
+			ShellArguments:      strings.Split(appconfig.PowerShellPluginCommandArgs, " "),
+			ByteOrderMark:       fileutil.ByteOrderMarkEmit,
+			CommandExecuter:     executers.ShellCommandExecuter{},
+			PowerShell:          selectPowerShell,
+			EnvironmentVariable: powerShellVariable,
 		},
 	}
 
 	return &psplugin, nil
 }
+
+// powerShellVariable returns the reference of PowerShell to the environment variable name
+func powerShellVariable(name string, interpreter string) string {
+	return "$env:" + name
+}
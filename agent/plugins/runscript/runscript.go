@@ -28,12 +28,22 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/parameters"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
 const (
 	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
+
+	// defaultTimeoutSetting is the plugin setting of the timeout of the documents that don't set one
+	defaultTimeoutSetting = "DefaultTimeoutSeconds"
+	// maxTimeoutSetting is the plugin setting of the maximum timeout of the documents
+	maxTimeoutSetting = "MaxTimeoutSeconds"
+
+	// secureParameterVariablePrefix is the prefix of the environment variables holding the SecureString parameters
+	// of the commands, numbered from 1
+	secureParameterVariablePrefix = "AWS_SSM_SECURE_PARAMETER_"
 )
 
 // Plugin is the type for the runscript plugin.
@@ -45,7 +55,28 @@ type Plugin struct {
 	ScriptName     string
 	ShellCommand   string
 	ShellArguments []string
-	ByteOrderMark  fileutil.ByteOrderMark
+	// ScriptPath converts the path of the script to the one the shell takes, nil when it takes the path as is
+	ScriptPath    func(path string) string
+	ByteOrderMark fileutil.ByteOrderMark
+	// Settings are the settings of the plugin in the agent configuration, see executionTimeout
+	Settings appconfig.PluginSettings
+	// RunAsUser is Agent.RunAsUser of the agent configuration, the user the commands run as
+	RunAsUser string
+	// LocalPolicy is the ExecutionPolicy of the agent configuration, which allows runAsElevated or not
+	LocalPolicy appconfig.ExecutionPolicyCfg
+	// ExecutionContext is the executionContext of the document, nil when it doesn't declare one
+	ExecutionContext *contracts.ExecutionContext
+	// Interpreter returns the command running the script of a step with the given interpreter input and the
+	// shebang of the script, nil when the plugin doesn't take an interpreter
+	Interpreter func(name string) (command string, shebang string, err error)
+	// PowerShell selects the PowerShell running the script of a step, nil when the plugin doesn't run PowerShell
+	PowerShell func(log log.T, edition string, versionRange string, preferCore bool) (command string, err error)
+	// EnvironmentVariable returns the reference of the script to an environment variable, in the language of the
+	// interpreter input of the step, empty for the shell of the plugin
+	EnvironmentVariable func(name string, interpreter string) string
+	// SecureParameters are the decrypted values of the references ssm-secure:<name> of the commands, passed in
+	// the environment of the commands rather than written to the script
+	SecureParameters map[string]string
 }
 
 // RunScriptPluginInput represents one set of commands executed by the RunScript plugin.
@@ -55,6 +86,21 @@ type RunScriptPluginInput struct {
 	ID               string
 	WorkingDirectory string
 	TimeoutSeconds   interface{}
+	// Attachments are the paths or patterns, relative to the working directory, of the result files the
+	// commands produce, they are uploaded with the output and listed with their checksum in the result
+	Attachments []string
+	// RunAsElevated runs the commands as the user of the agent instead of Agent.RunAsUser
+	RunAsElevated bool
+	// Interpreter runs the commands with bash, sh, ksh, zsh or python3 instead of the default shell
+	Interpreter string
+	// PowerShellEdition is the edition of PowerShell the commands run with, Desktop or Core
+	PowerShellEdition string
+	// PowerShellVersion is the range of the versions of PowerShell the commands run with, e.g. ">=7.2, <8"
+	PowerShellVersion string
+	// ExecutionPolicy is the execution policy of PowerShell the script runs with, e.g. AllSigned
+	ExecutionPolicy string
+	// LanguageMode is the language mode of PowerShell the script runs in, ConstrainedLanguage or FullLanguage
+	LanguageMode string
 }
 
 // Execute runs multiple sets of commands and returns their outputs.
@@ -63,6 +109,12 @@ func (p *Plugin) Execute(context context.T, config contracts.Configuration, canc
 	log := context.Log()
 	log.Infof("%v started with configuration %v", p.Name, config)
 	log.Debugf("DefaultWorkingDirectory %v", config.DefaultWorkingDirectory)
+	appConfig := context.AppConfig()
+	p.Settings = appConfig.PluginSettings(p.Name)
+	p.RunAsUser = appConfig.Agent.RunAsUser
+	p.LocalPolicy = appConfig.ExecutionPolicy
+	p.ExecutionContext = config.ExecutionContext
+	p.SecureParameters = config.SecureParameters
 
 	if cancelFlag.ShutDown() {
 		output.MarkAsShutdown()
@@ -86,10 +138,44 @@ func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput
 	p.runCommands(log, pluginID, pluginInput, orchestrationDirectory, defaultWorkingDirectory, cancelFlag, output)
 }
 
+// executionTimeout returns the timeout of the commands, DefaultTimeoutSeconds of the plugin settings when the
+// document doesn't set one, capped at MaxTimeoutSeconds of the plugin settings.
+func (p *Plugin) executionTimeout(log log.T, timeoutSeconds interface{}) int {
+	if timeoutSeconds == nil {
+		if defaultTimeout := p.Settings.Int(defaultTimeoutSetting, 0); defaultTimeout > 0 {
+			timeoutSeconds = defaultTimeout
+		}
+	}
+	timeout := pluginutil.ValidateExecutionTimeout(log, timeoutSeconds)
+	if maxTimeout := p.Settings.Int(maxTimeoutSetting, 0); maxTimeout > 0 && timeout > maxTimeout {
+		log.Infof("'TimeoutSeconds' %v exceeds the %v of the agent configuration, using %v", timeout, maxTimeoutSetting, maxTimeout)
+		timeout = maxTimeout
+	}
+	return timeout
+}
+
+// runAsUser returns the user the commands run as: the user of the executionContext of the document, else
+// empty for the user of the agent when the document sets runAsElevated and the local policy allows it.
+func (p *Plugin) runAsUser(log log.T, pluginInput RunScriptPluginInput) (string, error) {
+	if p.ExecutionContext != nil && p.ExecutionContext.User != "" {
+		return p.ExecutionContext.User, nil
+	}
+	if pluginInput.RunAsElevated && p.RunAsUser != "" {
+		if err := appconfig.CheckElevation(p.LocalPolicy); err != nil {
+			return "", err
+		}
+		log.Infof("running the commands as the agent user instead of %v, the document sets runAsElevated", p.RunAsUser)
+		return "", nil
+	}
+	return p.RunAsUser, nil
+}
+
 // runCommands executes one set of commands and returns their output.
 func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPluginInput, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 	var err error
 	var workingDir string
+	// the directories of the agent the commands use, given to the user they run as
+	var agentDirs []string
 
 	if filepath.IsAbs(pluginInput.WorkingDirectory) {
 		workingDir = pluginInput.WorkingDirectory
@@ -99,6 +185,8 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 		workingDir = filepath.Join(orchestrationDir, downloadsDir, pluginInput.WorkingDirectory)
 		if !fileutil.Exists(workingDir) {
 			workingDir = defaultWorkingDirectory
+		} else {
+			agentDirs = append(agentDirs, workingDir)
 		}
 	}
 
@@ -112,25 +200,89 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 		return
 	}
 
+	// Resolve the interpreter before writing the script, it names the interpreter in its shebang
+	commandName := p.ShellCommand
+	commandArguments := p.ShellArguments
+	runCommand := pluginInput.RunCommand
+	if pluginInput.Interpreter != "" {
+		if p.Interpreter == nil {
+			output.MarkAsFailed(fmt.Errorf("%v doesn't support the interpreter input", p.Name))
+			return
+		}
+		var shebang string
+		if commandName, shebang, err = p.Interpreter(pluginInput.Interpreter); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		commandArguments = nil
+		runCommand = withShebang(runCommand, shebang)
+	}
+	pinnedPowerShell := pluginInput.PowerShellEdition != "" || pluginInput.PowerShellVersion != ""
+	if (pinnedPowerShell || pluginInput.ExecutionPolicy != "" || pluginInput.LanguageMode != "") && p.PowerShell == nil {
+		output.MarkAsFailed(fmt.Errorf("%v doesn't support the powerShellEdition, powerShellVersion, executionPolicy and languageMode inputs", p.Name))
+		return
+	}
+	preferPwsh := p.Settings.Bool(preferPwshSetting, false)
+	if p.PowerShell != nil && (pinnedPowerShell || preferPwsh) {
+		if commandName, err = p.PowerShell(log, pluginInput.PowerShellEdition, pluginInput.PowerShellVersion, preferPwsh); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+	}
+
+	// the script file mustn't hold the values of the SecureString parameters, the commands read them from
+	// their environment
+	runCommand, environment := p.secureEnvironment(runCommand, pluginInput.Interpreter)
+
 	// Create script file path
 	scriptPath := filepath.Join(orchestrationDir, p.ScriptName)
 	log.Debugf("Writing commands %v to file %v", pluginInput, scriptPath)
 
 	// Create script file
-	if err = pluginutil.CreateScriptFile(log, scriptPath, pluginInput.RunCommand, p.ByteOrderMark); err != nil {
+	if err = pluginutil.CreateScriptFile(log, scriptPath, runCommand, p.ByteOrderMark); err != nil {
 		output.MarkAsFailed(fmt.Errorf("failed to create script file. %v", err))
 		return
 	}
+	defer pluginutil.ShredScriptFile(log, scriptPath)
 
 	// Set execution time
-	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
+	executionTimeout := p.executionTimeout(log, pluginInput.TimeoutSeconds)
+
+	// Run the commands as the low-privilege user unless the document requests the privileges of the agent
+	runAsUser, err := p.runAsUser(log, pluginInput)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	if err = executers.GrantAccess(runAsUser, append(agentDirs, orchestrationDir)...); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to prepare the commands to run as %v: %v", runAsUser, err))
+		return
+	}
+	executer := executers.RunAs(executers.InContext(p.CommandExecuter, p.ExecutionContext), runAsUser)
+	executer = executers.WithEnvironment(executer, environment)
 
 	// Construct Command Name and Arguments
-	commandName := p.ShellCommand
-	commandArguments := append(p.ShellArguments, scriptPath, appconfig.ExitCodeTrap)
+	shellScriptPath := scriptPath
+	if p.ScriptPath != nil {
+		shellScriptPath = p.ScriptPath(scriptPath)
+	}
+	var powerShellArgs []string
+	if p.PowerShell != nil {
+		if powerShellArgs, err = powerShellArguments(commandArguments, shellScriptPath, pluginInput, p.Settings); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+	}
+	if pluginInput.Interpreter != "" {
+		commandArguments = []string{shellScriptPath}
+	} else if powerShellArgs != nil {
+		commandArguments = powerShellArgs
+	} else {
+		commandArguments = append(commandArguments, shellScriptPath, appconfig.ExitCodeTrap)
+	}
 
 	// Execute Command
-	exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
+	exitCode, err := executer.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
 
 	// Set output status
 	output.SetExitCode(exitCode)
@@ -144,4 +296,62 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 			output.MarkAsFailed(fmt.Errorf("failed to run commands: %v", err))
 		}
 	}
+
+	attachResults(log, workingDir, pluginInput.Attachments, output)
+}
+
+// secureEnvironment replaces the references {{ ssm-secure:<name> }} of the commands with references to environment
+// variables holding their decrypted values, and returns the commands and the variables, NAME=value.
+func (p *Plugin) secureEnvironment(runCommand []string, interpreter string) (commands []string, environment []string) {
+	if len(p.SecureParameters) == 0 || p.EnvironmentVariable == nil {
+		return runCommand, nil
+	}
+	variables := make(map[string]string)
+	for _, command := range runCommand {
+		for _, reference := range parameters.SecureParameterReferences(command) {
+			value, found := p.SecureParameters[reference]
+			if _, named := variables[reference]; !found || named {
+				continue
+			}
+			name := fmt.Sprintf("%v%v", secureParameterVariablePrefix, len(variables)+1)
+			variables[reference] = p.EnvironmentVariable(name, interpreter)
+			environment = append(environment, name+"="+value)
+		}
+	}
+	for _, command := range runCommand {
+		commands = append(commands, parameters.ReplaceSecureParameterReferences(command, variables).(string))
+	}
+	return commands, environment
+}
+
+// withShebang returns the commands starting with the shebang, in place of the one of the commands if they have one
+func withShebang(runCommand []string, shebang string) []string {
+	if len(runCommand) > 0 && strings.HasPrefix(runCommand[0], "#!") {
+		runCommand = runCommand[1:]
+	}
+	return append([]string{shebang}, runCommand...)
+}
+
+// attachResults registers the result files matching the attachment patterns. A missing result file is
+// reported in the error output but doesn't change the status set by the commands.
+func attachResults(log log.T, workingDir string, patterns []string, output iohandler.IOHandler) {
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(workingDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			output.AppendErrorf("invalid attachment pattern %v: %v", pattern, err)
+			continue
+		}
+		if len(matches) == 0 {
+			output.AppendErrorf("no result file matches the attachment %v", pattern)
+			continue
+		}
+		for _, match := range matches {
+			if err = output.AddAttachment(log, match); err != nil {
+				output.AppendError(err.Error())
+			}
+		}
+	}
 }
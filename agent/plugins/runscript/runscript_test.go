@@ -15,11 +15,14 @@ package runscript
 
 import (
 	"fmt"
+	"io/ioutil"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
@@ -343,3 +346,137 @@ func setCancelFlagExpectations(mockCancelFlag *task.MockCancelFlag, times int) {
 	mockCancelFlag.On("Canceled").Return(false).Times(times)
 	mockCancelFlag.On("ShutDown").Return(false).Times(times)
 }
+
+func TestExecutionTimeoutFromPluginSettings(t *testing.T) {
+	logger := log.NewMockLog()
+	p := Plugin{}
+	assert.Equal(t, 3600, p.executionTimeout(logger, nil), "the default of the agent without plugin settings")
+	assert.Equal(t, 7200, p.executionTimeout(logger, "7200"))
+
+	p.Settings = map[string]interface{}{"DefaultTimeoutSeconds": float64(600), "MaxTimeoutSeconds": float64(1800)}
+	assert.Equal(t, 600, p.executionTimeout(logger, nil), "the default of the plugin settings")
+	assert.Equal(t, 900, p.executionTimeout(logger, 900))
+	assert.Equal(t, 1800, p.executionTimeout(logger, "7200"), "capped by the plugin settings")
+}
+
+func TestRunAsUser(t *testing.T) {
+	logger := log.NewMockLog()
+	p := Plugin{RunAsUser: "ssm-agent-worker", LocalPolicy: appconfig.ExecutionPolicyCfg{AllowElevation: true}}
+	runAsUser, err := p.runAsUser(logger, RunScriptPluginInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ssm-agent-worker", runAsUser)
+	runAsUser, err = p.runAsUser(logger, RunScriptPluginInput{RunAsElevated: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "", runAsUser, "the document requests the agent user")
+
+	p.ExecutionContext = &contracts.ExecutionContext{User: "builder"}
+	runAsUser, err = p.runAsUser(logger, RunScriptPluginInput{RunAsElevated: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "builder", runAsUser, "the executionContext of the document sets the user")
+
+	p.ExecutionContext = &contracts.ExecutionContext{Umask: "027"}
+	p.RunAsUser = ""
+	runAsUser, err = p.runAsUser(logger, RunScriptPluginInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", runAsUser)
+}
+
+func TestRunAsElevatedDeniedByDefault(t *testing.T) {
+	logger := log.NewMockLog()
+	p := Plugin{RunAsUser: "ssm-agent-worker", LocalPolicy: appconfig.DefaultConfig().ExecutionPolicy, ScriptName: "_script.sh", ShellCommand: "sh"}
+	_, err := p.runAsUser(logger, RunScriptPluginInput{RunAsElevated: true})
+	assert.Error(t, err, "the local policy doesn't allow elevation")
+
+	// the commands fail without running
+	output := iohandler.NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+	p.runCommands(logger, "pluginID", RunScriptPluginInput{RunCommand: []string{"id"}, RunAsElevated: true}, t.TempDir(), t.TempDir(), task.NewChanneledCancelFlag(), output)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "ExecutionPolicy.AllowElevation")
+}
+
+func TestShellScriptPath(t *testing.T) {
+	assert.Nil(t, shell{command: "sh"}.scriptPath(), "sh takes the paths as is")
+
+	wsl := shell{command: `C:\Windows\System32\bash.exe`, driveRoot: "/mnt/"}.scriptPath()
+	assert.Equal(t, "/mnt/c/ProgramData/Amazon/SSM/run/_script.sh", wsl(`C:\ProgramData\Amazon\SSM\run\_script.sh`))
+	assert.Equal(t, "/cygdrive/d/run/_script.sh", unixPath(`D:\run\_script.sh`, "/cygdrive/"))
+	assert.Equal(t, "/c/run/_script.sh", unixPath(`C:\run\_script.sh`, "/"))
+	assert.Equal(t, "run/_script.sh", unixPath(`run\_script.sh`, "/"), "relative paths keep no drive")
+}
+
+func TestRunCommandsWithInterpreter(t *testing.T) {
+	testCase := generateTestCaseOk("0")
+	testCase.Input.RunCommand = []string{"#!/bin/sh", "[[ -n $HOME ]] && echo bash"}
+	testCase.Input.Interpreter = "bash"
+
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		p.ByteOrderMark = fileutil.ByteOrderMarkSkip
+		p.Interpreter = func(name string) (string, string, error) {
+			return "/bin/" + name, "#!/bin/" + name, nil
+		}
+		var script string
+		mockExecuter.On("NewExecute", mock.Anything, testCase.Input.WorkingDirectory, testCase.Output.StdoutWriter, testCase.Output.StderrWriter,
+			mockCancelFlag, mock.Anything, "/bin/bash", mock.MatchedBy(func(args []string) bool { return len(args) == 1 })).Run(func(args mock.Arguments) {
+			content, _ := ioutil.ReadFile(args.Get(7).([]string)[0])
+			script = string(content)
+		}).Return(0, nil)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+
+		p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+		assert.Equal(t, "#!/bin/bash\n[[ -n $HOME ]] && echo bash\n", script)
+	}
+
+	testExecution(t, executeTester)
+}
+
+func TestRunCommandsWithSecureParameters(t *testing.T) {
+	testCase := generateTestCaseOk("0")
+	testCase.Input.RunCommand = []string{"mysql -p\"{{ ssm-secure:/db/password }}\" -u {{ssm-secure:/db/user}}", "echo {{ ssm-secure:/db/password }}"}
+
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		p.ByteOrderMark = fileutil.ByteOrderMarkSkip
+		p.EnvironmentVariable = shellVariable
+		p.SecureParameters = map[string]string{"ssm-secure:/db/password": "staple-battery-horse", "ssm-secure:/db/user": "admin"}
+		var script string
+		mockExecuter.On("NewExecute", mock.Anything, testCase.Input.WorkingDirectory, testCase.Output.StdoutWriter, testCase.Output.StderrWriter,
+			mockCancelFlag, mock.Anything, "sh", mock.Anything).Run(func(args mock.Arguments) {
+			content, _ := ioutil.ReadFile(args.Get(7).([]string)[1])
+			script = string(content)
+		}).Return(0, nil)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+
+		p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+		assert.Equal(t, "mysql -p\"${AWS_SSM_SECURE_PARAMETER_1}\" -u ${AWS_SSM_SECURE_PARAMETER_2}\necho ${AWS_SSM_SECURE_PARAMETER_1}\n", script)
+	}
+
+	testExecution(t, executeTester)
+}
+
+func TestSecureEnvironment(t *testing.T) {
+	p := Plugin{EnvironmentVariable: powerShellVariable}
+	commands := []string{"Connect -Password \"{{ ssm-secure:/db/password }}\""}
+	withoutValues, environment := p.secureEnvironment(commands, "")
+	assert.Equal(t, commands, withoutValues, "the references without values are kept")
+	assert.Empty(t, environment)
+
+	p.SecureParameters = map[string]string{"ssm-secure:/db/password": "staple-battery-horse"}
+	withoutValues, environment = p.secureEnvironment(commands, "")
+	assert.Equal(t, []string{"Connect -Password \"$env:AWS_SSM_SECURE_PARAMETER_1\""}, withoutValues)
+	assert.Equal(t, []string{"AWS_SSM_SECURE_PARAMETER_1=staple-battery-horse"}, environment)
+
+	assert.Equal(t, "${AWS_SSM_SECURE_PARAMETER_1}", shellVariable("AWS_SSM_SECURE_PARAMETER_1", "bash"))
+	assert.Equal(t, "__import__('os').environ['AWS_SSM_SECURE_PARAMETER_1']", shellVariable("AWS_SSM_SECURE_PARAMETER_1", "python3"))
+}
+
+func TestRunCommandsWithUnsupportedInterpreter(t *testing.T) {
+	testCase := generateTestCaseOk("0")
+	testCase.Input.Interpreter = "bash"
+
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		mockIOHandler.On("MarkAsFailed", fmt.Errorf("aws:runShellScript doesn't support the interpreter input")).Return()
+
+		p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+	}
+
+	testExecution(t, executeTester)
+}
@@ -12,10 +12,13 @@
 // permissions and limitations under the License.
 
 // Package runscript implements the RunScript plugin.
-// RunShellScript contains implementation of the plugin that runs shell scripts on linux
+// RunShellScript contains implementation of the plugin that runs shell scripts on linux, or with bash on windows
 package runscript
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
@@ -27,22 +30,79 @@ type runShellPlugin struct {
 	Plugin
 }
 
+// shell is the shell running the scripts of the plugin
+type shell struct {
+	command string
+	// driveRoot is the directory the shell mounts the Windows drives in, e.g. /mnt/ for /mnt/c, empty on linux
+	driveRoot string
+}
+
 var shellScriptName = "_script.sh"
 var shellCommand = "sh"
 var shellArgs = []string{"-c"}
 
+// interpreters are the values of the interpreter input of the steps
+var interpreters = []string{"bash", "sh", "ksh", "zsh", "python3"}
+
 // NewRunShellPlugin returns a new instance of the SHPlugin.
+// It fails on windows when none of the bash installations it supports is found.
 func NewRunShellPlugin(log log.T) (*runShellPlugin, error) {
+	sh, err := findShell(log)
+	if err != nil {
+		return nil, err
+	}
+
 	shplugin := runShellPlugin{
 		Plugin{
-			Name:            appconfig.PluginNameAwsRunShellScript,
-			ScriptName:      shellScriptName,
-			ShellCommand:    shellCommand,
-			ShellArguments:  shellArgs,
-			ByteOrderMark:   fileutil.ByteOrderMarkSkip,
-			CommandExecuter: executers.ShellCommandExecuter{},
+			Name:                appconfig.PluginNameAwsRunShellScript,
+			ScriptName:          shellScriptName,
+			ShellCommand:        sh.command,
+			ShellArguments:      shellArgs,
+			ScriptPath:          sh.scriptPath(),
+			ByteOrderMark:       fileutil.ByteOrderMarkSkip,
+			CommandExecuter:     executers.ShellCommandExecuter{},
+			Interpreter:         sh.interpreter,
+			EnvironmentVariable: shellVariable,
 		},
 	}
 
 	return &shplugin, nil
 }
+
+// shellVariable returns the reference to the environment variable name of the shell, or of python3
+func shellVariable(name string, interpreter string) string {
+	if interpreter == "python3" {
+		return fmt.Sprintf("__import__('os').environ['%v']", name)
+	}
+	return "${" + name + "}"
+}
+
+// scriptPath returns the conversion of the paths of the scripts for the shell, nil when it takes them as is
+func (sh shell) scriptPath() func(string) string {
+	if sh.driveRoot == "" {
+		return nil
+	}
+	return func(path string) string {
+		return unixPath(path, sh.driveRoot)
+	}
+}
+
+// unixPath converts a windows path, e.g. C:\ProgramData\_script.sh, to the path of the file for a shell mounting
+// the drives in driveRoot, e.g. /mnt/c/ProgramData/_script.sh
+func unixPath(path string, driveRoot string) string {
+	path = strings.Replace(path, `\`, "/", -1)
+	if len(path) >= 2 && path[1] == ':' {
+		path = driveRoot + strings.ToLower(path[:1]) + path[2:]
+	}
+	return path
+}
+
+// supportedInterpreter returns an error unless the interpreter input of a step is one of interpreters
+func supportedInterpreter(name string) error {
+	for _, interpreter := range interpreters {
+		if name == interpreter {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported interpreter %q, expected one of %v", name, strings.Join(interpreters, ", "))
+}
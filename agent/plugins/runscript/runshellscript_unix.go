@@ -0,0 +1,42 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package runscript
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// lookPath finds the command of an interpreter in the PATH of the agent
+var lookPath = exec.LookPath
+
+// findShell returns sh, which runs the scripts on linux
+func findShell(log log.T) (shell, error) {
+	return shell{command: shellCommand}, nil
+}
+
+// interpreter returns the installed command of the interpreter, which the shebang of the script names
+func (sh shell) interpreter(name string) (command string, shebang string, err error) {
+	if err = supportedInterpreter(name); err != nil {
+		return "", "", err
+	}
+	if command, err = lookPath(name); err != nil {
+		return "", "", fmt.Errorf("interpreter %v is not installed: %v", name, err)
+	}
+	return command, "#!" + command, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package runscript
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpreter(t *testing.T) {
+	saved := lookPath
+	defer func() { lookPath = saved }()
+	lookPath = func(name string) (string, error) {
+		if name == "bash" {
+			return "/bin/bash", nil
+		}
+		return "", fmt.Errorf("executable file not found in $PATH")
+	}
+
+	command, shebang, err := shell{command: "sh"}.interpreter("bash")
+	assert.NoError(t, err)
+	assert.Equal(t, "/bin/bash", command)
+	assert.Equal(t, "#!/bin/bash", shebang)
+
+	_, _, err = shell{command: "sh"}.interpreter("zsh")
+	assert.EqualError(t, err, "interpreter zsh is not installed: executable file not found in $PATH")
+
+	_, _, err = shell{command: "sh"}.interpreter("perl")
+	assert.EqualError(t, err, `unsupported interpreter "perl", expected one of bash, sh, ksh, zsh, python3`)
+}
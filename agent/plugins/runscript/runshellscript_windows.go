@@ -0,0 +1,61 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package runscript
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// bashInstallations returns the bash installations the plugin runs the scripts with on windows, in order of
+// preference: Git for Windows, Cygwin, then the bash of the Windows Subsystem for Linux.
+var bashInstallations = func() []shell {
+	return []shell{
+		{command: filepath.Join(appconfig.EnvProgramFiles, "Git", "bin", "bash.exe"), driveRoot: "/"},
+		{command: filepath.Join(os.Getenv("ProgramFiles(x86)"), "Git", "bin", "bash.exe"), driveRoot: "/"},
+		{command: filepath.Join(os.Getenv("SystemDrive")+`\`, "cygwin64", "bin", "bash.exe"), driveRoot: "/cygdrive/"},
+		{command: filepath.Join(os.Getenv("SystemDrive")+`\`, "cygwin", "bin", "bash.exe"), driveRoot: "/cygdrive/"},
+		{command: filepath.Join(os.Getenv("SystemRoot"), "System32", "bash.exe"), driveRoot: "/mnt/"},
+	}
+}
+
+// findShell returns the first bash installed, the documents written for linux run with it
+func findShell(log log.T) (shell, error) {
+	for _, bash := range bashInstallations() {
+		if fileutil.Exists(bash.command) {
+			log.Debugf("Running the shell scripts with %v", bash.command)
+			return bash, nil
+		}
+	}
+	return shell{}, fmt.Errorf("%v needs bash on Windows, install Git for Windows, Cygwin or the Windows Subsystem for Linux",
+		appconfig.PluginNameAwsRunShellScript)
+}
+
+// interpreter returns the bash the scripts run with, the other interpreters aren't supported on Windows
+func (sh shell) interpreter(name string) (command string, shebang string, err error) {
+	if err = supportedInterpreter(name); err != nil {
+		return "", "", err
+	}
+	if name != "bash" && name != "sh" {
+		return "", "", fmt.Errorf("interpreter %v is not supported on Windows, use bash or sh", name)
+	}
+	return sh.command, "#!/usr/bin/env bash", nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package temporaryadmin
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+const (
+	// sudoersFilePrefix is the prefix of the sudoers drop-in files of the grants
+	sudoersFilePrefix = "ssm-temporary-admin-"
+
+	// sudoersFileAccess is the mode sudo requires of the drop-in files
+	sudoersFileAccess = os.FileMode(0440)
+)
+
+// userNamePattern matches the user names that can be elevated, the local users only as sudo has no domains
+var userNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+
+// sudoersDir is the folder sudo includes the drop-in files of the grants from
+var sudoersDir = "/etc/sudoers.d"
+
+// visudoPath validates the drop-in files before they are installed, the validation is skipped without it
+var visudoPath = "/usr/sbin/visudo"
+
+// sudoersAdminGroup elevates the users with a sudoers drop-in file per grant, so that revoking a grant
+// leaves the sudo and wheel memberships of the user alone
+type sudoersAdminGroup struct{}
+
+// newAdminGroup returns the administrators of the platform.
+func newAdminGroup() adminGroup {
+	return sudoersAdminGroup{}
+}
+
+// IsAdmin returns true for root, the other users can be elevated whatever their groups
+func (sudoersAdminGroup) IsAdmin(userName string) (bool, error) {
+	u, err := lookupUser(userName)
+	if err != nil {
+		return false, err
+	}
+	return u.Uid == "0", nil
+}
+
+// Grant installs the drop-in file of the user, replacing the one of a previous grant
+func (sudoersAdminGroup) Grant(userName string, expiry time.Time) error {
+	if !fileutil.Exists(sudoersDir) {
+		return fmt.Errorf("%v doesn't exist, sudo is required to grant administrator rights", sudoersDir)
+	}
+	content := fmt.Sprintf("# Granted by %v until %v, the amazon-ssm-agent removes this file once expired\n%v ALL=(ALL) NOPASSWD: ALL\n",
+		Name(), expiry.UTC().Format(time.RFC3339), userName)
+	path := sudoersPath(userName)
+	// sudo ignores the files with a dot in their name, the file isn't included until it is renamed
+	temp := path + ".tmp"
+	if err := ioutil.WriteFile(temp, []byte(content), sudoersFileAccess); err != nil {
+		return err
+	}
+	defer os.Remove(temp)
+	if fileutil.Exists(visudoPath) {
+		if output, err := exec.Command(visudoPath, "-c", "-f", temp).CombinedOutput(); err != nil {
+			return fmt.Errorf("invalid sudoers file: %v %v", err, strings.TrimSpace(string(output)))
+		}
+	}
+	return os.Rename(temp, path)
+}
+
+// Revoke removes the drop-in file of the user, and the one the previous agents named after the user
+func (sudoersAdminGroup) Revoke(userName string) error {
+	if err := os.Remove(sudoersPath(userName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// the legacy names of a.b and a_b are the same, the file is only removed if it elevates the user
+	legacy := legacySudoersPath(userName)
+	content, err := ioutil.ReadFile(legacy)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !strings.Contains(string(content), "\n"+userName+" ALL=") {
+		return nil
+	}
+	if err := os.Remove(legacy); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// sudoersPath returns the drop-in file of the grant of the user, named after the hex of the user name so that
+// distinct users never share a file and the name holds none of the dots sudo ignores the files of
+func sudoersPath(userName string) string {
+	return filepath.Join(sudoersDir, sudoersFilePrefix+hex.EncodeToString([]byte(userName)))
+}
+
+// legacySudoersPath returns the drop-in file the previous agents installed, with the dots of the user name replaced
+func legacySudoersPath(userName string) string {
+	return filepath.Join(sudoersDir, sudoersFilePrefix+strings.Replace(userName, ".", "_", -1))
+}
@@ -0,0 +1,64 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package temporaryadmin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSudoersPathIsDistinctPerUser(t *testing.T) {
+	assert.NotEqual(t, sudoersPath("a.b"), sudoersPath("a_b"))
+	assert.NotContains(t, filepath.Base(sudoersPath("a.b")), ".")
+}
+
+func TestUserNamePatternRefusesDomainUsers(t *testing.T) {
+	assert.True(t, userNamePattern.MatchString("a.b"))
+	assert.False(t, userNamePattern.MatchString(`DOMAIN\alice`))
+}
+
+func TestGrantAndRevokeDistinctUsers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sudoers")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defaultSudoersDir, defaultVisudoPath := sudoersDir, visudoPath
+	sudoersDir, visudoPath = dir, filepath.Join(dir, "visudo")
+	defer func() { sudoersDir, visudoPath = defaultSudoersDir, defaultVisudoPath }()
+
+	group := sudoersAdminGroup{}
+	expiry := time.Now().Add(time.Hour)
+	assert.NoError(t, group.Grant("a.b", expiry))
+	assert.NoError(t, group.Grant("a_b", expiry))
+	assert.NoError(t, group.Revoke("a.b"))
+	_, err = os.Stat(sudoersPath("a.b"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(sudoersPath("a_b"))
+	assert.NoError(t, err)
+
+	// the legacy file of a_b isn't removed when a.b is revoked
+	assert.NoError(t, ioutil.WriteFile(legacySudoersPath("a_b"), []byte("# Granted\na_b ALL=(ALL) NOPASSWD: ALL\n"), sudoersFileAccess))
+	assert.NoError(t, group.Revoke("a.b"))
+	_, err = os.Stat(legacySudoersPath("a_b"))
+	assert.NoError(t, err)
+	assert.NoError(t, group.Revoke("a_b"))
+	_, err = os.Stat(legacySudoersPath("a_b"))
+	assert.True(t, os.IsNotExist(err))
+}
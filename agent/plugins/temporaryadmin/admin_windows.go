@@ -0,0 +1,84 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package temporaryadmin
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// userNamePattern matches the user names that can be elevated, optionally qualified with a domain
+var userNamePattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*\\)?[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+
+// administratorsSID is the well-known SID of the local Administrators group, whose name is localized
+const administratorsSID = "S-1-5-32-544"
+
+// localAdministrators elevates the users with the membership of the local Administrators group
+type localAdministrators struct{}
+
+// newAdminGroup returns the administrators of the platform.
+func newAdminGroup() adminGroup {
+	return localAdministrators{}
+}
+
+// IsAdmin returns true if the user is a member of the Administrators group
+func (localAdministrators) IsAdmin(userName string) (bool, error) {
+	output, err := runPowerShell(fmt.Sprintf("[bool](%v)", memberQuery(userName)))
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(output, "true"), nil
+}
+
+// Grant adds the user to the Administrators group unless it is already a member from a previous grant
+func (localAdministrators) Grant(userName string, expiry time.Time) error {
+	_, err := runPowerShell(fmt.Sprintf("if (-not (%v)) { Add-LocalGroupMember -SID %v -Member %v -ErrorAction Stop }",
+		memberQuery(userName), administratorsSID, quote(userName)))
+	return err
+}
+
+// Revoke removes the user from the Administrators group
+func (localAdministrators) Revoke(userName string) error {
+	_, err := runPowerShell(fmt.Sprintf("if (%v) { Remove-LocalGroupMember -SID %v -Member %v -ErrorAction Stop }",
+		memberQuery(userName), administratorsSID, quote(userName)))
+	return err
+}
+
+// memberQuery returns the PowerShell expression listing the user among the Administrators, local users are
+// listed qualified with the computer name
+func memberQuery(userName string) string {
+	return fmt.Sprintf("Get-LocalGroupMember -SID %v | Where-Object { $_.Name -eq %v -or $_.Name -eq ($env:COMPUTERNAME + '\\' + %v) }",
+		administratorsSID, quote(userName), quote(userName))
+}
+
+// quote returns the value as a PowerShell single quoted string
+func quote(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
+
+// runPowerShell runs the script and returns its trimmed output
+func runPowerShell(script string) (string, error) {
+	output, err := exec.Command(appconfig.PowerShellPluginCommandName, "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v: %v", err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
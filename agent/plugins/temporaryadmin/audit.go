@@ -0,0 +1,75 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package temporaryadmin
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// EventGranted is audited when a user is made an administrator or a grant is extended
+	EventGranted = "Granted"
+
+	// EventRevoked is audited when a grant is revoked by a document
+	EventRevoked = "Revoked"
+
+	// EventExpired is audited when the agent revokes an expired grant
+	EventExpired = "Expired"
+)
+
+// auditPath is the file the audit events are appended to, one JSON object per line
+var auditPath = filepath.Join(log.DefaultLogDir, "audit", "temporaryadmin.log")
+
+// AuditEvent records a change of the administrator rights granted by the plugin.
+type AuditEvent struct {
+	Time      string `json:"time"`
+	Event     string `json:"event"`
+	User      string `json:"user"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	CommandID string `json:"commandId,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// audit appends the event to the audit file and logs it, a failure to write the file doesn't undo the change.
+func audit(log log.T, event AuditEvent) {
+	event.Time = now().UTC().Format(time.RFC3339)
+	line, err := jsonutil.Marshal(event)
+	if err != nil {
+		log.Errorf("failed to audit %v: %v", event, err)
+		return
+	}
+	log.Infof("audit: %v", line)
+	if err = appendLine(auditPath, line); err != nil {
+		log.Errorf("failed to write the audit event to %v: %v", auditPath, err)
+	}
+}
+
+// appendLine appends a line to the file, creating it readable by its owner only.
+func appendLine(path string, line string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(line + "\n")
+	return err
+}
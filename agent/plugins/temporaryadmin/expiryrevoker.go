@@ -0,0 +1,73 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package temporaryadmin
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/carlescere/scheduler"
+)
+
+const (
+	// revokerName is the name of the core module revoking the expired grants
+	revokerName = "TemporaryAdminExpiry"
+
+	// revokerFrequencyMinutes is how often the expired grants are revoked
+	revokerFrequencyMinutes = 1
+)
+
+// ExpiryRevoker is the core module revoking the expired grants of the aws:grantTemporaryAdmin plugin.
+type ExpiryRevoker struct {
+	context context.T
+	job     *scheduler.Job
+}
+
+// NewExpiryRevoker creates a new expiry revoker core module.
+func NewExpiryRevoker(context context.T) *ExpiryRevoker {
+	return &ExpiryRevoker{
+		context: context.With("[" + revokerName + "]"),
+	}
+}
+
+// revokeExpiredGrants revokes the expired grants of all the users.
+func (r *ExpiryRevoker) revokeExpiredGrants() {
+	if err := RevokeExpiredGrants(r.context.Log()); err != nil {
+		r.context.Log().Errorf("failed to revoke the expired temporary administrator rights: %v", err)
+	}
+}
+
+// ICoreModule implementation
+
+// ModuleName returns the module name
+func (r *ExpiryRevoker) ModuleName() string {
+	return revokerName
+}
+
+// ModuleExecute revokes the grants expired while the agent was stopped and schedules the revocation of the others
+func (r *ExpiryRevoker) ModuleExecute(context context.T) (err error) {
+	go r.revokeExpiredGrants()
+	if r.job, err = scheduler.Every(revokerFrequencyMinutes).Minutes().Run(r.revokeExpiredGrants); err != nil {
+		r.context.Log().Errorf("unable to schedule the revocation of the expired temporary administrator rights. %v", err)
+	}
+	return
+}
+
+// ModuleRequestStop stops the revocation of the expired grants
+func (r *ExpiryRevoker) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if r.job != nil {
+		r.context.Log().Info("stopping the revocation of the expired temporary administrator rights.")
+		r.job.Quit <- true
+	}
+	return nil
+}
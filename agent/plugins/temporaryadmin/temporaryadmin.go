@@ -0,0 +1,318 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package temporaryadmin implements the aws:grantTemporaryAdmin plugin, which makes a local user an
+// administrator for a bounded duration after which the agent revokes the elevation, for just-in-time access.
+package temporaryadmin
+
+import (
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// ActionGrant makes the user an administrator until the grant expires, extending a previous grant
+	ActionGrant = "Grant"
+
+	// ActionRevoke revokes the grant of the user before it expires
+	ActionRevoke = "Revoke"
+
+	// MaxValidFor is the longest duration an elevation can be granted for
+	MaxValidFor = 12 * time.Hour
+
+	// lockFileName is the lock of the state, taken by the document workers granting and revoking and by the agent
+	// revoking the expired grants
+	lockFileName = ".lock"
+
+	// lockTimeout bounds the wait for the other processes changing the grants
+	lockTimeout = 30 * time.Second
+)
+
+// stateDir is the folder the grants are recorded in, so that the agent revokes them once expired
+var stateDir = filepath.Join(appconfig.DefaultDataStorePath, "temporaryadmin")
+
+// lookupUser returns the user to elevate
+var lookupUser = user.Lookup
+
+// now returns the current time, the expiry of the grants is relative to it
+var now = time.Now
+
+// admins makes the users administrators of the platform
+var admins = newAdminGroup()
+
+// adminGroup grants and revokes the administrator rights of the users
+type adminGroup interface {
+	// IsAdmin returns true if the user is an administrator without a grant of the plugin
+	IsAdmin(userName string) (bool, error)
+	Grant(userName string, expiry time.Time) error
+	Revoke(userName string) error
+}
+
+// Plugin is the type for the aws:grantTemporaryAdmin plugin.
+type Plugin struct {
+}
+
+// TemporaryAdminPluginInput represents the user to elevate and for how long.
+type TemporaryAdminPluginInput struct {
+	contracts.PluginInput
+	ID     string
+	Action string
+	User   string
+	// ValidFor is how long the user stays an administrator, e.g. 1h, at most 12h
+	ValidFor string
+	// Reason is recorded in the audit events, e.g. the ticket the access was requested for
+	Reason string
+}
+
+// Grant is the elevation of a user until it expires.
+type Grant struct {
+	User      string
+	ExpiresAt time.Time
+}
+
+// State records the grants the agent revokes once expired.
+type State struct {
+	Grants []Grant
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameGrantTemporaryAdmin
+}
+
+// Execute grants or revokes the administrator rights of the user.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input TemporaryAdminPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if !userNamePattern.MatchString(input.User) {
+		output.MarkAsFailed(fmt.Errorf("User must be a local user name, got %q", input.User))
+		return
+	}
+	if _, err := lookupUser(input.User); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to find user %v: %v", input.User, err))
+		return
+	}
+	commandID := commandIDOf(context)
+
+	switch input.Action {
+	case ActionGrant, "":
+		validFor, err := time.ParseDuration(input.ValidFor)
+		if err != nil || validFor <= 0 || validFor > MaxValidFor {
+			output.MarkAsFailed(fmt.Errorf("ValidFor must be a positive duration of at most %v, e.g. 1h, got %q", MaxValidFor, input.ValidFor))
+			return
+		}
+		expiry := now().Add(validFor).Truncate(time.Second)
+		if err = grant(log, input.User, expiry, commandID, input.Reason); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		output.AppendInfof("%v is an administrator until %v", input.User, expiry.UTC().Format(time.RFC3339))
+	case ActionRevoke:
+		revoked, err := revoke(log, input.User, commandID, input.Reason)
+		if err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		if revoked {
+			output.AppendInfof("administrator rights of %v revoked", input.User)
+		} else {
+			output.AppendInfof("%v has no administrator rights granted by %v", input.User, Name())
+		}
+	default:
+		output.MarkAsFailed(fmt.Errorf("Action must be %v or %v, got %v", ActionGrant, ActionRevoke, input.Action))
+		return
+	}
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// grant makes the user an administrator until the expiry, the grant is recorded before the user is elevated
+// so that the agent revokes it even if the elevation partially failed.
+func grant(log log.T, userName string, expiry time.Time, commandID string, reason string) error {
+	lock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	state, err := GetState()
+	if err != nil {
+		return err
+	}
+	if index := state.indexOf(userName); index < 0 {
+		isAdmin, err := admins.IsAdmin(userName)
+		if err != nil {
+			return fmt.Errorf("failed to check the administrator rights of %v: %v", userName, err)
+		}
+		if isAdmin {
+			return fmt.Errorf("%v is already an administrator, it can't be granted temporary administrator rights", userName)
+		}
+		state.Grants = append(state.Grants, Grant{User: userName, ExpiresAt: expiry})
+	} else if expiry.After(state.Grants[index].ExpiresAt) {
+		state.Grants[index].ExpiresAt = expiry
+	} else {
+		expiry = state.Grants[index].ExpiresAt
+	}
+	sort.Slice(state.Grants, func(i, j int) bool { return state.Grants[i].User < state.Grants[j].User })
+	if err = saveState(state); err != nil {
+		return err
+	}
+	if err = admins.Grant(userName, expiry); err != nil {
+		return fmt.Errorf("failed to make %v an administrator: %v", userName, err)
+	}
+	audit(log, AuditEvent{Event: EventGranted, User: userName, ExpiresAt: expiry.UTC().Format(time.RFC3339), CommandID: commandID, Reason: reason})
+	return nil
+}
+
+// revoke revokes the grant of the user, returns false if the user has no grant.
+func revoke(log log.T, userName string, commandID string, reason string) (bool, error) {
+	lock, err := lockState()
+	if err != nil {
+		return false, err
+	}
+	defer lock.Unlock()
+	state, err := GetState()
+	if err != nil {
+		return false, err
+	}
+	index := state.indexOf(userName)
+	if index < 0 {
+		return false, nil
+	}
+	if err = admins.Revoke(userName); err != nil {
+		return false, fmt.Errorf("failed to revoke the administrator rights of %v: %v", userName, err)
+	}
+	state.Grants = append(state.Grants[:index], state.Grants[index+1:]...)
+	if err = saveState(state); err != nil {
+		return true, err
+	}
+	audit(log, AuditEvent{Event: EventRevoked, User: userName, CommandID: commandID, Reason: reason})
+	return true, nil
+}
+
+// RevokeExpiredGrants revokes the expired grants, the grants failing to be revoked are retried on the next call.
+func RevokeExpiredGrants(log log.T) error {
+	lock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	state, err := GetState()
+	if err != nil {
+		return err
+	}
+	at := now()
+	var grants []Grant
+	for _, grant := range state.Grants {
+		if at.Before(grant.ExpiresAt) {
+			grants = append(grants, grant)
+			continue
+		}
+		if err = admins.Revoke(grant.User); err != nil {
+			log.Errorf("failed to revoke the expired administrator rights of %v: %v", grant.User, err)
+			grants = append(grants, grant)
+			continue
+		}
+		audit(log, AuditEvent{Event: EventExpired, User: grant.User, ExpiresAt: grant.ExpiresAt.UTC().Format(time.RFC3339)})
+	}
+	if len(grants) == len(state.Grants) {
+		return nil
+	}
+	return saveState(State{Grants: grants})
+}
+
+// indexOf returns the index of the grant of the user, -1 if the user has none.
+func (state State) indexOf(userName string) int {
+	for i, grant := range state.Grants {
+		if strings.EqualFold(grant.User, userName) {
+			return i
+		}
+	}
+	return -1
+}
+
+// commandIDOf returns the id of the command the plugin runs for, empty for an association.
+func commandIDOf(context context.T) string {
+	return log.ContextFields(strings.Join(context.CurrentContext(), " "))[log.ContextKeyCommandID]
+}
+
+// lockState takes the lock of the grants, the caller unlocks it.
+func lockState() (*filelock.Lock, error) {
+	if err := fileutil.MakeDirs(stateDir); err != nil {
+		return nil, err
+	}
+	lock, err := filelock.Acquire(filepath.Join(stateDir, lockFileName), lockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock the temporary admin state: %v", err)
+	}
+	return lock, nil
+}
+
+// GetState returns the grants of the plugin.
+func GetState() (state State, err error) {
+	path := filepath.Join(stateDir, "state.json")
+	if !fileutil.Exists(path) {
+		return State{}, nil
+	}
+	if err = jsonutil.UnmarshalFile(path, &state); err != nil {
+		return State{}, fmt.Errorf("failed to read temporary admin state: %v", err)
+	}
+	return state, nil
+}
+
+// saveState persists the grants of the plugin.
+func saveState(state State) error {
+	if err := fileutil.MakeDirs(stateDir); err != nil {
+		return err
+	}
+	content, err := jsonutil.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if _, err = fileutil.WriteIntoFileWithPermissions(filepath.Join(stateDir, "state.json"), content, appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("failed to save temporary admin state: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package temporaryadmin
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAdminGroup records the elevated users and their expiry
+type fakeAdminGroup struct {
+	admins  map[string]bool
+	granted map[string]time.Time
+}
+
+func (g *fakeAdminGroup) IsAdmin(userName string) (bool, error) {
+	return g.admins[userName], nil
+}
+
+func (g *fakeAdminGroup) Grant(userName string, expiry time.Time) error {
+	g.granted[userName] = expiry
+	return nil
+}
+
+func (g *fakeAdminGroup) Revoke(userName string) error {
+	delete(g.granted, userName)
+	return nil
+}
+
+func TestExecuteGrantRevokeAndExpire(t *testing.T) {
+	dir, err := ioutil.TempDir("", "temporaryadmin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defaultStateDir, defaultAuditPath, defaultAdmins := stateDir, auditPath, admins
+	stateDir = filepath.Join(dir, "state")
+	auditPath = filepath.Join(dir, "audit", "temporaryadmin.log")
+	group := &fakeAdminGroup{admins: map[string]bool{"root": true}, granted: map[string]time.Time{}}
+	admins = group
+	defer func() { stateDir, auditPath, admins = defaultStateDir, defaultAuditPath, defaultAdmins }()
+	lookupUser = func(name string) (*user.User, error) { return &user.User{Username: name}, nil }
+	defer func() { lookupUser = user.Lookup }()
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	execute := func(properties map[string]interface{}) iohandler.IOHandler {
+		output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+		plugin, _ := NewPlugin()
+		plugin.Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, task.NewChanneledCancelFlag(), output)
+		return output
+	}
+
+	output := execute(map[string]interface{}{"User": "alice", "ValidFor": "1h", "Reason": "INC-42"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	output = execute(map[string]interface{}{"Action": "Grant", "User": "bob", "ValidFor": "2h"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, map[string]time.Time{"alice": start.Add(time.Hour), "bob": start.Add(2 * time.Hour)}, group.granted)
+
+	// granting again extends the grant, it is never shortened
+	output = execute(map[string]interface{}{"User": "alice", "ValidFor": "3h"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	output = execute(map[string]interface{}{"User": "alice", "ValidFor": "30m"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	state, _ := GetState()
+	assert.Equal(t, []Grant{{User: "alice", ExpiresAt: start.Add(3 * time.Hour)}, {User: "bob", ExpiresAt: start.Add(2 * time.Hour)}}, state.Grants)
+
+	now = func() time.Time { return start.Add(2 * time.Hour) }
+	assert.NoError(t, RevokeExpiredGrants(log.NewMockLog()))
+	assert.Equal(t, map[string]time.Time{"alice": start.Add(3 * time.Hour)}, group.granted)
+
+	output = execute(map[string]interface{}{"Action": "Revoke", "User": "alice"})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Empty(t, group.granted)
+	state, _ = GetState()
+	assert.Empty(t, state.Grants)
+
+	content, _ := ioutil.ReadFile(auditPath)
+	events := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, events, 6)
+	assert.Equal(t, `{"time":"2026-10-16T12:00:00Z","event":"Granted","user":"alice","expiresAt":"2026-10-16T13:00:00Z","reason":"INC-42"}`, events[0])
+	assert.Equal(t, `{"time":"2026-10-16T14:00:00Z","event":"Expired","user":"bob","expiresAt":"2026-10-16T14:00:00Z"}`, events[4])
+	assert.Equal(t, `{"time":"2026-10-16T14:00:00Z","event":"Revoked","user":"alice"}`, events[5])
+}
+
+func TestConcurrentGrantsAreAllRecorded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "temporaryadmin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defaultStateDir, defaultAuditPath, defaultAdmins := stateDir, auditPath, admins
+	stateDir = filepath.Join(dir, "state")
+	auditPath = filepath.Join(dir, "audit", "temporaryadmin.log")
+	group := &fakeAdminGroup{admins: map[string]bool{}, granted: map[string]time.Time{}}
+	admins = group
+	defer func() { stateDir, auditPath, admins = defaultStateDir, defaultAuditPath, defaultAdmins }()
+
+	// the workers and the agent change the state under its lock, no grant is lost
+	users := []string{"alice", "bob", "carol", "dave", "erin", "frank"}
+	var wg sync.WaitGroup
+	for _, userName := range users {
+		wg.Add(1)
+		go func(userName string) {
+			defer wg.Done()
+			assert.NoError(t, grant(log.NewMockLog(), userName, time.Now().Add(time.Hour), "", ""))
+		}(userName)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, RevokeExpiredGrants(log.NewMockLog()))
+	}()
+	wg.Wait()
+
+	state, err := GetState()
+	assert.NoError(t, err)
+	assert.Len(t, state.Grants, len(users))
+	assert.Len(t, group.granted, len(users))
+}
+
+func TestExecuteInvalidInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "temporaryadmin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defaultStateDir, defaultAdmins := stateDir, admins
+	stateDir = filepath.Join(dir, "state")
+	group := &fakeAdminGroup{admins: map[string]bool{"root": true}, granted: map[string]time.Time{}}
+	admins = group
+	defer func() { stateDir, admins = defaultStateDir, defaultAdmins }()
+	lookupUser = func(name string) (*user.User, error) { return &user.User{Username: name}, nil }
+	defer func() { lookupUser = user.Lookup }()
+
+	for _, properties := range []map[string]interface{}{
+		{"User": "alice"},
+		{"User": "alice", "ValidFor": "13h"},
+		{"User": "alice", "ValidFor": "-1h"},
+		{"User": "alice ALL=(ALL) ALL", "ValidFor": "1h"},
+		{"User": "alice", "ValidFor": "1h", "Action": "Elevate"},
+		{"User": "root", "ValidFor": "1h"},
+	} {
+		output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+		plugin, _ := NewPlugin()
+		plugin.Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, task.NewChanneledCancelFlag(), output)
+		assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus(), properties)
+	}
+	assert.Empty(t, group.granted)
+}
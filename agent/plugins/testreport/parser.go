@@ -0,0 +1,206 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testreport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// FormatJUnit is the JUnit XML report format
+	FormatJUnit = "JUnit"
+
+	// FormatTAP is the Test Anything Protocol report format
+	FormatTAP = "TAP"
+
+	// StatusPassed is the status of a passed test
+	StatusPassed = "Passed"
+
+	// StatusFailed is the status of a failed or errored test
+	StatusFailed = "Failed"
+
+	// StatusSkipped is the status of a skipped test
+	StatusSkipped = "Skipped"
+)
+
+// TestCase is the outcome of a test of a report.
+type TestCase struct {
+	Suite   string `json:"suite,omitempty"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the outcome of the tests of one or more reports.
+type Report struct {
+	Total   int        `json:"total"`
+	Passed  int        `json:"passed"`
+	Failed  int        `json:"failed"`
+	Skipped int        `json:"skipped"`
+	Cases   []TestCase `json:"-"`
+}
+
+// add adds a test to the report.
+func (r *Report) add(testCase TestCase) {
+	r.Total++
+	switch testCase.Status {
+	case StatusPassed:
+		r.Passed++
+	case StatusFailed:
+		r.Failed++
+	case StatusSkipped:
+		r.Skipped++
+	}
+	r.Cases = append(r.Cases, testCase)
+}
+
+// junitSuite is a testsuite element, suites may be nested in a testsuites element or in each other
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []junitCase  `xml:"testcase"`
+}
+
+// junitCase is a testcase element
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failures  []junitResult `xml:"failure"`
+	Errors    []junitResult `xml:"error"`
+	Skipped   *junitResult  `xml:"skipped"`
+}
+
+// junitResult is a failure, error or skipped element
+type junitResult struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+// text returns the message of the result, or its content if there is no message
+func (r junitResult) text() string {
+	if r.Message != "" {
+		return r.Message
+	}
+	return strings.TrimSpace(r.Content)
+}
+
+// parseJUnit parses a JUnit XML report, with either a testsuites or a testsuite root element.
+func parseJUnit(content []byte, report *Report) error {
+	var root struct {
+		XMLName xml.Name
+		junitSuite
+	}
+	if err := xml.Unmarshal(content, &root); err != nil {
+		return fmt.Errorf("invalid JUnit report: %v", err)
+	}
+	if root.XMLName.Local != "testsuites" && root.XMLName.Local != "testsuite" {
+		return fmt.Errorf("invalid JUnit report: unexpected root element %v", root.XMLName.Local)
+	}
+	addJUnitSuite(root.junitSuite, report)
+	return nil
+}
+
+// addJUnitSuite adds the tests of a suite and of its nested suites to the report.
+func addJUnitSuite(suite junitSuite, report *Report) {
+	for _, junit := range suite.Cases {
+		testCase := TestCase{Suite: suite.Name, Name: junit.Name, Status: StatusPassed}
+		if junit.ClassName != "" {
+			testCase.Suite = junit.ClassName
+		}
+		if len(junit.Failures) > 0 {
+			testCase.Status = StatusFailed
+			testCase.Message = junit.Failures[0].text()
+		} else if len(junit.Errors) > 0 {
+			testCase.Status = StatusFailed
+			testCase.Message = junit.Errors[0].text()
+		} else if junit.Skipped != nil {
+			testCase.Status = StatusSkipped
+			testCase.Message = junit.Skipped.text()
+		}
+		report.add(testCase)
+	}
+	for _, nested := range suite.Suites {
+		addJUnitSuite(nested, report)
+	}
+}
+
+// tapLinePattern matches a TAP test line, e.g. "not ok 2 - disk is encrypted # TODO"
+var tapLinePattern = regexp.MustCompile(`^(not ok|ok)\b\s*(\d+)?\s*(?:-\s*)?([^#]*?)\s*(?:#\s*(.*))?$`)
+
+// parseTAP parses a Test Anything Protocol report. The diagnostic lines following a failed test are its message.
+func parseTAP(content []byte, report *Report) error {
+	found := false
+	var failed *TestCase
+	var diagnostics []string
+	flush := func() {
+		if failed != nil {
+			failed.Message = strings.Join(diagnostics, "\n")
+			report.add(*failed)
+		}
+		failed = nil
+		diagnostics = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "#") && failed != nil {
+			diagnostics = append(diagnostics, strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			continue
+		}
+		match := tapLinePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		flush()
+		found = true
+		testCase := TestCase{Name: match[3], Status: StatusPassed}
+		if testCase.Name == "" {
+			testCase.Name = fmt.Sprintf("test %v", match[2])
+		}
+		directive := strings.ToUpper(match[4])
+		switch {
+		case strings.HasPrefix(directive, "SKIP"), strings.HasPrefix(directive, "TODO"):
+			// TODO tests are expected to fail and don't count as failures
+			testCase.Status = StatusSkipped
+			testCase.Message = strings.TrimSpace(match[4])
+		case match[1] == "not ok":
+			testCase.Status = StatusFailed
+			failed = &testCase
+			continue
+		}
+		report.add(testCase)
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("invalid TAP report: %v", err)
+	}
+	if !found {
+		return fmt.Errorf("invalid TAP report: no test line found")
+	}
+	return nil
+}
+
+// detectFormat guesses the format of a report from its content.
+func detectFormat(content []byte) string {
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "<") {
+		return FormatJUnit
+	}
+	return FormatTAP
+}
@@ -0,0 +1,303 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package testreport implements the aws:parseTestReport plugin, which reports the outcome of the tests of
+// JUnit XML or TAP reports produced by the previous steps of a document, optionally as compliance items.
+package testreport
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	ssmsvc "github.com/aws/amazon-ssm-agent/agent/ssm"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+const (
+	// customComplianceTypePrefix is the prefix SSM requires for the compliance types not owned by AWS
+	customComplianceTypePrefix = "Custom:"
+
+	// complianceExecutionType is the execution type the compliance items are reported with
+	complianceExecutionType = "Command"
+
+	// maxComplianceItems is the maximum number of compliance items of a PutComplianceItems call
+	maxComplianceItems = 10000
+
+	// maxReportedFailures is the maximum number of failed tests listed in the plugin output
+	maxReportedFailures = 50
+
+	// maxMessageLength is the length the test messages are truncated at
+	maxMessageLength = 512
+)
+
+// complianceSeverities are the severities supported by SSM compliance
+var complianceSeverities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "INFORMATIONAL", "UNSPECIFIED"}
+
+// instanceID returns the id of the instance the compliance items are reported for
+var instanceID = platform.InstanceID
+
+// Plugin is the type for the aws:parseTestReport plugin.
+type Plugin struct {
+	ssmSvc ssmsvc.Service
+}
+
+// TestReportPluginInput represents the reports to parse and how to report their outcome.
+type TestReportPluginInput struct {
+	contracts.PluginInput
+	ID string
+	// ReportPath is the path or pattern of the reports, relative to the working directory
+	ReportPath       string
+	WorkingDirectory string
+	// Format is JUnit or TAP, detected from the content if omitted
+	Format string
+	// ComplianceType reports each test as a compliance item of the type if set, e.g. Custom:Validation
+	ComplianceType string
+	// ComplianceSeverity is the severity of the compliance items, UNSPECIFIED by default
+	ComplianceSeverity string
+}
+
+// TestReportOutput is the structured outcome of the tests, set as the output of the plugin.
+type TestReportOutput struct {
+	Report
+	Reports  []string   `json:"reports"`
+	Failures []TestCase `json:"failures,omitempty"`
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameParseTestReport
+}
+
+// Execute parses the reports and fails if any of their tests failed.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	p.ssmSvc = ssmsvc.NewService()
+	p.execute(context, config, cancelFlag, output)
+}
+
+func (p *Plugin) execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input TestReportPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if err := validate(&input); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	reportPaths, err := findReports(input, config.DefaultWorkingDirectory)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	var report Report
+	for _, reportPath := range reportPaths {
+		if err = parseReport(reportPath, input.Format, &report); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+	}
+
+	result := TestReportOutput{Report: report, Reports: reportPaths}
+	output.AppendInfof("%v tests: %v passed, %v failed, %v skipped", report.Total, report.Passed, report.Failed, report.Skipped)
+	for _, testCase := range report.Cases {
+		if testCase.Status != StatusFailed {
+			continue
+		}
+		output.AppendErrorf("%v failed: %v", testName(testCase), testCase.Message)
+		if len(result.Failures) < maxReportedFailures {
+			testCase.Message = truncate(testCase.Message)
+			result.Failures = append(result.Failures, testCase)
+		}
+	}
+	if content, err := jsonutil.Marshal(result); err == nil {
+		output.SetOutput(content)
+	}
+
+	if input.ComplianceType != "" {
+		if err = p.putComplianceItems(log, input, report); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		output.AppendInfof("Test outcomes reported as %v compliance", input.ComplianceType)
+	}
+
+	if report.Failed > 0 {
+		output.MarkAsFailed(nil)
+		return
+	}
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// validate checks the input and sets its defaults.
+func validate(input *TestReportPluginInput) error {
+	if input.ReportPath == "" {
+		return fmt.Errorf("ReportPath is required")
+	}
+	switch strings.ToLower(input.Format) {
+	case "":
+	case strings.ToLower(FormatJUnit):
+		input.Format = FormatJUnit
+	case strings.ToLower(FormatTAP):
+		input.Format = FormatTAP
+	default:
+		return fmt.Errorf("Format must be %v or %v, got %v", FormatJUnit, FormatTAP, input.Format)
+	}
+	if input.ComplianceType != "" && !strings.HasPrefix(input.ComplianceType, customComplianceTypePrefix) {
+		input.ComplianceType = customComplianceTypePrefix + input.ComplianceType
+	}
+	if input.ComplianceSeverity == "" {
+		input.ComplianceSeverity = "UNSPECIFIED"
+	}
+	input.ComplianceSeverity = strings.ToUpper(input.ComplianceSeverity)
+	for _, severity := range complianceSeverities {
+		if input.ComplianceSeverity == severity {
+			return nil
+		}
+	}
+	return fmt.Errorf("ComplianceSeverity must be one of %v, got %v", strings.Join(complianceSeverities, ", "), input.ComplianceSeverity)
+}
+
+// findReports returns the reports matching the report path.
+func findReports(input TestReportPluginInput, defaultWorkingDirectory string) ([]string, error) {
+	pattern := input.ReportPath
+	if !filepath.IsAbs(pattern) {
+		workingDir := input.WorkingDirectory
+		if workingDir == "" {
+			workingDir = defaultWorkingDirectory
+		}
+		pattern = filepath.Join(workingDir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ReportPath %v: %v", pattern, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no test report matches %v", pattern)
+	}
+	return matches, nil
+}
+
+// parseReport adds the tests of a report to the outcome.
+func parseReport(reportPath, format string, report *Report) error {
+	content, err := ioutil.ReadFile(reportPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the test report %v: %v", reportPath, err)
+	}
+	if format == "" {
+		format = detectFormat(content)
+	}
+	if format == FormatJUnit {
+		err = parseJUnit(content, report)
+	} else {
+		err = parseTAP(content, report)
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %v", reportPath, err)
+	}
+	return nil
+}
+
+// putComplianceItems reports the passed and failed tests as compliance items, the skipped tests are left out.
+func (p *Plugin) putComplianceItems(log log.T, input TestReportPluginInput, report Report) error {
+	instance, err := instanceID()
+	if err != nil {
+		return fmt.Errorf("failed to get the instance id: %v", err)
+	}
+	var items []*ssm.ComplianceItemEntry
+	for _, testCase := range report.Cases {
+		status := "COMPLIANT"
+		if testCase.Status == StatusSkipped {
+			continue
+		} else if testCase.Status == StatusFailed {
+			status = "NON_COMPLIANT"
+		}
+		if len(items) == maxComplianceItems {
+			log.Errorf("Only the first %v tests are reported as compliance items", maxComplianceItems)
+			break
+		}
+		items = append(items, &ssm.ComplianceItemEntry{
+			Id:       aws.String(testName(testCase)),
+			Title:    aws.String(testCase.Name),
+			Status:   aws.String(status),
+			Severity: aws.String(input.ComplianceSeverity),
+			Details: map[string]*string{
+				"Message": aws.String(truncate(testCase.Message)),
+			},
+		})
+	}
+
+	content, err := jsonutil.Marshal(items)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum([]byte(content))
+	executionTime := time.Now()
+	if _, err = p.ssmSvc.PutComplianceItems(log, &executionTime, complianceExecutionType, "", instance,
+		input.ComplianceType, base64.StdEncoding.EncodeToString(sum[:]), items); err != nil {
+		return fmt.Errorf("failed to report the tests as %v compliance: %v", input.ComplianceType, err)
+	}
+	return nil
+}
+
+// testName returns the name of a test qualified with its suite.
+func testName(testCase TestCase) string {
+	if testCase.Suite == "" {
+		return testCase.Name
+	}
+	return testCase.Suite + "." + testCase.Name
+}
+
+// truncate shortens a test message to the maximum length, without splitting a UTF-8 sequence.
+func truncate(message string) string {
+	if len(message) <= maxMessageLength {
+		return message
+	}
+	length := maxMessageLength
+	for length > 0 && !utf8.RuneStart(message[length]) {
+		length--
+	}
+	return message[:length] + "..."
+}
@@ -0,0 +1,147 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testreport
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	ssmsvc "github.com/aws/amazon-ssm-agent/agent/ssm"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const junitReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="hardening">
+    <testcase classname="ssh" name="root login disabled"/>
+    <testcase classname="ssh" name="password authentication disabled">
+      <failure message="PasswordAuthentication is yes">sshd_config line 56</failure>
+    </testcase>
+    <testcase name="firewall enabled"><error>iptables not found</error></testcase>
+    <testcase name="selinux enforcing"><skipped/></testcase>
+  </testsuite>
+</testsuites>`
+
+const tapReport = `TAP version 13
+1..4
+ok 1 - disk is encrypted
+not ok 2 - ntp is synchronized
+# offset is 12s
+# expected under 1s
+ok 3 - swap disabled # SKIP no swap
+not ok 4 - kernel is patched # TODO known issue
+`
+
+func TestParseJUnit(t *testing.T) {
+	var report Report
+	assert.NoError(t, parseJUnit([]byte(junitReport), &report))
+
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 1, report.Passed)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, TestCase{Suite: "ssh", Name: "password authentication disabled", Status: StatusFailed, Message: "PasswordAuthentication is yes"}, report.Cases[1])
+	assert.Equal(t, TestCase{Suite: "hardening", Name: "firewall enabled", Status: StatusFailed, Message: "iptables not found"}, report.Cases[2])
+
+	assert.Error(t, parseJUnit([]byte(`<html></html>`), &Report{}))
+	assert.Error(t, parseJUnit([]byte(`<testsuite>`), &Report{}))
+}
+
+func TestParseTAP(t *testing.T) {
+	var report Report
+	assert.NoError(t, parseTAP([]byte(tapReport), &report))
+
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 1, report.Passed)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, TestCase{Name: "ntp is synchronized", Status: StatusFailed, Message: "offset is 12s\nexpected under 1s"}, report.Cases[1])
+
+	assert.Error(t, parseTAP([]byte("no tests were run"), &Report{}))
+}
+
+func TestValidate(t *testing.T) {
+	input := TestReportPluginInput{ReportPath: "report.xml", Format: "junit", ComplianceType: "Validation"}
+	assert.NoError(t, validate(&input))
+	assert.Equal(t, FormatJUnit, input.Format)
+	assert.Equal(t, "Custom:Validation", input.ComplianceType)
+	assert.Equal(t, "UNSPECIFIED", input.ComplianceSeverity)
+
+	assert.Error(t, validate(&TestReportPluginInput{}))
+	assert.Error(t, validate(&TestReportPluginInput{ReportPath: "report.xml", Format: "xunit"}))
+	assert.Error(t, validate(&TestReportPluginInput{ReportPath: "report.xml", ComplianceSeverity: "urgent"}))
+}
+
+func TestExecuteReportsFailuresAsCompliance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testreport")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "hardening.xml"), []byte(junitReport), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "runtime.tap"), []byte(tapReport), 0600))
+	instanceID = func() (string, error) { return "i-1234567890abcdef0", nil }
+
+	ssmMock := ssmsvc.NewMockDefault()
+	ssmMock.On("PutComplianceItems", mock.Anything, mock.Anything, complianceExecutionType, "", "i-1234567890abcdef0",
+		"Custom:Validation", mock.Anything, mock.Anything).Return(&ssm.PutComplianceItemsOutput{}, nil)
+	plugin := &Plugin{ssmSvc: ssmMock}
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	config := contracts.Configuration{
+		Properties:              map[string]interface{}{"ReportPath": "*", "ComplianceType": "Validation"},
+		DefaultWorkingDirectory: dir,
+	}
+
+	plugin.execute(context.NewMockDefault(), config, task.NewChanneledCancelFlag(), output)
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.True(t, strings.Contains(output.GetStdout(), "8 tests: 2 passed, 3 failed, 3 skipped"))
+	assert.True(t, strings.Contains(output.GetStderr(), "ssh.password authentication disabled failed: PasswordAuthentication is yes"))
+	assert.True(t, strings.Contains(output.GetOutput().(string), `"failed":3`))
+	items := ssmMock.Calls[0].Arguments.Get(7).([]*ssm.ComplianceItemEntry)
+	assert.Len(t, items, 5, "skipped tests are not reported")
+	assert.Equal(t, "NON_COMPLIANT", *items[1].Status)
+}
+
+func TestExecuteWithoutReport(t *testing.T) {
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	config := contracts.Configuration{
+		Properties:              map[string]interface{}{"ReportPath": "missing.xml"},
+		DefaultWorkingDirectory: os.TempDir(),
+	}
+
+	(&Plugin{}).execute(context.NewMockDefault(), config, task.NewChanneledCancelFlag(), output)
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.True(t, strings.Contains(output.GetStderr(), "no test report matches"))
+}
+
+func TestTruncateKeepsWholeRunes(t *testing.T) {
+	message := strings.Repeat("a", maxMessageLength-1) + "é"
+
+	truncated := truncate(message)
+
+	assert.True(t, utf8.ValidString(truncated))
+	assert.Equal(t, strings.Repeat("a", maxMessageLength-1)+"...", truncated)
+	assert.Equal(t, "short", truncate("short"))
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package timesync
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// sntpPacketLength is the length of the SNTP requests and responses
+	sntpPacketLength = 48
+
+	// sntpTimeout is how long a server is waited for
+	sntpTimeout = 5 * time.Second
+
+	// ntpEpochOffset is the number of seconds between the NTP epoch, 1900, and the Unix epoch
+	ntpEpochOffset = 2208988800
+)
+
+// sntpPort is the port the time servers are queried on
+var sntpPort = "123"
+
+// querySNTPOffset measures the offset of the clock from the server with an SNTP request (RFC 4330),
+// positive if the clock is behind the server.
+func querySNTPOffset(server string) (time.Duration, error) {
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(server, sntpPort), sntpTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(sntpTimeout)); err != nil {
+		return 0, err
+	}
+
+	request := make([]byte, sntpPacketLength)
+	// no leap second warning, version 4, client mode
+	request[0] = 0<<6 | 4<<3 | 3
+	originate := time.Now()
+	binary.BigEndian.PutUint64(request[40:], toNTPTime(originate))
+	if _, err = conn.Write(request); err != nil {
+		return 0, err
+	}
+	response := make([]byte, sntpPacketLength)
+	length, err := conn.Read(response)
+	destination := time.Now()
+	if err != nil {
+		return 0, err
+	}
+	if length < sntpPacketLength {
+		return 0, fmt.Errorf("short SNTP response of %v bytes", length)
+	}
+	if mode := response[0] & 0x7; mode != 4 {
+		return 0, fmt.Errorf("unexpected SNTP mode %v", mode)
+	}
+	if stratum := response[1]; stratum == 0 || stratum > 15 {
+		return 0, fmt.Errorf("server is not synchronized, stratum %v", stratum)
+	}
+	if binary.BigEndian.Uint64(response[24:]) != binary.BigEndian.Uint64(request[40:]) {
+		return 0, fmt.Errorf("SNTP response doesn't match the request")
+	}
+	receive := fromNTPTime(binary.BigEndian.Uint64(response[32:]))
+	transmit := fromNTPTime(binary.BigEndian.Uint64(response[40:]))
+	return (receive.Sub(originate) + transmit.Sub(destination)) / 2, nil
+}
+
+// toNTPTime converts a time to a 64 bits NTP timestamp, seconds since 1900 and fraction of a second
+func toNTPTime(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// fromNTPTime converts a 64 bits NTP timestamp to a time
+func fromNTPTime(timestamp uint64) time.Time {
+	seconds := int64(timestamp>>32) - ntpEpochOffset
+	nanoseconds := int64((timestamp & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(seconds, nanoseconds)
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package timesync
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// commandTimeoutSeconds bounds each command of the time service, chronyc waitsync alone waits up to 30s
+const commandTimeoutSeconds = 60
+
+// runCommand runs a command of the time service, stopped when it times out or the step is canceled, and
+// returns its trimmed output
+var runCommand = func(log log.T, cancelFlag task.CancelFlag, name string, arg ...string) (string, error) {
+	var output bytes.Buffer
+	_, err := executers.ShellCommandExecuter{}.NewExecute(log, "", &output, &output, cancelFlag, commandTimeoutSeconds, name, arg)
+	if err != nil {
+		return "", fmt.Errorf("%v %v failed: %v %v", name, strings.Join(arg, " "), err, strings.TrimSpace(output.String()))
+	}
+	return strings.TrimSpace(output.String()), nil
+}
+
+// serversComment precedes the time sources set by the plugin in the configuration files
+const serversComment = "# time sources set by aws:configureTimeSync"
+
+// withServers returns the lines of a chrony or ntpd configuration with the server and pool directives replaced
+// by the servers, in place of the first of them, and false if the servers were already configured.
+func withServers(lines []string, servers []string) ([]string, bool) {
+	var configured []string
+	var updated []string
+	insertAt := -1
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if strings.TrimSpace(line) == serversComment {
+			continue
+		}
+		if len(fields) >= 2 && (fields[0] == "server" || fields[0] == "pool") {
+			if fields[0] == "server" {
+				configured = append(configured, fields[1])
+			} else {
+				// a pool is never one of the servers set by the plugin
+				configured = append(configured, "")
+			}
+			if insertAt < 0 {
+				insertAt = len(updated)
+			}
+			continue
+		}
+		updated = append(updated, line)
+	}
+	if equalServers(configured, servers) {
+		return lines, false
+	}
+	directives := []string{serversComment}
+	for _, server := range servers {
+		directives = append(directives, "server "+server+" iburst")
+	}
+	if insertAt < 0 {
+		return append(updated, directives...), true
+	}
+	result := append([]string{}, updated[:insertAt]...)
+	result = append(result, directives...)
+	return append(result, updated[insertAt:]...), true
+}
+
+// equalServers returns true if the configured servers are the servers, in order
+func equalServers(configured []string, servers []string) bool {
+	if len(configured) != len(servers) {
+		return false
+	}
+	for i := range servers {
+		if !strings.EqualFold(configured[i], servers[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package timesync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// chronyConfigs are the configuration files of chrony on the Red Hat and Debian families, with their service
+var chronyConfigs = []struct {
+	path    string
+	service string
+}{
+	{"/etc/chrony.conf", "chronyd"},
+	{"/etc/chrony/chrony.conf", "chrony"},
+}
+
+// ntpConfigPath is the configuration file of ntpd
+var ntpConfigPath = "/etc/ntp.conf"
+
+// ntpServices are the names of the ntpd service on the Red Hat and Debian families
+var ntpServices = []string{"ntpd", "ntp"}
+
+// detectTimeService returns chrony if installed, ntpd otherwise.
+func detectTimeService() (timeService, error) {
+	for _, config := range chronyConfigs {
+		if fileutil.Exists(config.path) {
+			return chrony{configPath: config.path, service: config.service}, nil
+		}
+	}
+	if fileutil.Exists(ntpConfigPath) {
+		return ntpd{configPath: ntpConfigPath}, nil
+	}
+	return nil, fmt.Errorf("neither chrony nor ntpd is installed")
+}
+
+// chrony configures and syncs chronyd
+type chrony struct {
+	configPath string
+	service    string
+}
+
+func (c chrony) Name() string {
+	return "chrony"
+}
+
+// Configure sets the servers of chrony.conf and restarts chronyd if they changed
+func (c chrony) Configure(log log.T, cancelFlag task.CancelFlag, servers []string) (bool, error) {
+	changed, err := configureServers(log, c.configPath, servers)
+	if err != nil || !changed {
+		return changed, err
+	}
+	return true, controlService(log, cancelFlag, "restart", c.service)
+}
+
+// Sync waits up to 30s for chronyd to select a source and steps the clock to its time
+func (c chrony) Sync(log log.T, cancelFlag task.CancelFlag) error {
+	if _, err := runCommand(log, cancelFlag, "chronyc", "waitsync", "30", "0", "0", "1"); err != nil {
+		return err
+	}
+	output, err := runCommand(log, cancelFlag, "chronyc", "makestep")
+	log.Infof("chronyc makestep: %v", output)
+	return err
+}
+
+// ntpd configures and syncs ntpd
+type ntpd struct {
+	configPath string
+}
+
+func (n ntpd) Name() string {
+	return "ntpd"
+}
+
+// Configure sets the servers of ntp.conf and restarts ntpd if they changed
+func (n ntpd) Configure(log log.T, cancelFlag task.CancelFlag, servers []string) (bool, error) {
+	changed, err := configureServers(log, n.configPath, servers)
+	if err != nil || !changed {
+		return changed, err
+	}
+	return true, controlService(log, cancelFlag, "restart", ntpServices...)
+}
+
+// Sync stops ntpd to set the clock once with ntpd -gq, ntpd is started again whether the clock was set or not
+func (n ntpd) Sync(log log.T, cancelFlag task.CancelFlag) error {
+	if err := controlService(log, cancelFlag, "stop", ntpServices...); err != nil {
+		return err
+	}
+	output, syncErr := runCommand(log, cancelFlag, "ntpd", "-gq")
+	log.Infof("ntpd -gq: %v", output)
+	if err := controlService(log, cancelFlag, "start", ntpServices...); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// configureServers replaces the servers of a configuration file, returns false if they were already configured
+func configureServers(log log.T, path string, servers []string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	lines, changed := withServers(strings.Split(strings.TrimRight(string(content), "\n"), "\n"), servers)
+	if !changed {
+		return false, nil
+	}
+	if err = ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	log.Infof("time sources of %v set to %v", path, strings.Join(servers, ", "))
+	return true, nil
+}
+
+// controlService runs the action on the first of the services that exists, with systemd or with sysvinit
+func controlService(log log.T, cancelFlag task.CancelFlag, action string, services ...string) (err error) {
+	for _, service := range services {
+		if _, err = runCommand(log, cancelFlag, "systemctl", action, service); err == nil {
+			return nil
+		}
+		if _, err = runCommand(log, cancelFlag, "service", service, action); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package timesync
+
+import (
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"golang.org/x/sys/windows/registry"
+)
+
+// w32timeParametersKey is the registry key of the configuration of the Windows Time service
+const w32timeParametersKey = `SYSTEM\CurrentControlSet\Services\W32Time\Parameters`
+
+// detectTimeService returns the Windows Time service.
+func detectTimeService() (timeService, error) {
+	return w32time{}, nil
+}
+
+// w32time configures and syncs the Windows Time service
+type w32time struct{}
+
+func (w w32time) Name() string {
+	return "w32time"
+}
+
+// Configure sets the manual peer list of w32time, the servers are queried in client mode
+func (w w32time) Configure(log log.T, cancelFlag task.CancelFlag, servers []string) (bool, error) {
+	peers := make([]string, len(servers))
+	for i, server := range servers {
+		peers[i] = server + ",0x8"
+	}
+	peerList := strings.Join(peers, " ")
+	if current, syncType, err := w.currentPeers(); err == nil && strings.EqualFold(current, peerList) && syncType == "NTP" {
+		return false, nil
+	}
+	// the service has to run for the configuration to be updated
+	runCommand(log, cancelFlag, "net", "start", "w32time")
+	if _, err := runCommand(log, cancelFlag, "w32tm", "/config", "/manualpeerlist:"+peerList, "/syncfromflags:manual", "/update"); err != nil {
+		return false, err
+	}
+	log.Infof("w32time peers set to %v", peerList)
+	return true, nil
+}
+
+// Sync makes w32time sync the clock with its peers immediately
+func (w w32time) Sync(log log.T, cancelFlag task.CancelFlag) error {
+	runCommand(log, cancelFlag, "net", "start", "w32time")
+	output, err := runCommand(log, cancelFlag, "w32tm", "/resync", "/force")
+	log.Infof("w32tm /resync: %v", output)
+	return err
+}
+
+// currentPeers returns the configured peer list and the sync type of w32time
+func (w w32time) currentPeers() (peers string, syncType string, err error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, w32timeParametersKey, registry.QUERY_VALUE)
+	if err != nil {
+		return "", "", err
+	}
+	defer key.Close()
+	if peers, _, err = key.GetStringValue("NtpServer"); err != nil {
+		return "", "", err
+	}
+	if syncType, _, err = key.GetStringValue("Type"); err != nil {
+		return "", "", err
+	}
+	return peers, syncType, nil
+}
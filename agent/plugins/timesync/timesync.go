@@ -0,0 +1,198 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package timesync implements the aws:configureTimeSync plugin, which configures the time sources of
+// chrony, ntpd or the Windows Time service, forces a sync and reports the clock offset before and after.
+package timesync
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// DefaultReferenceServer is the Amazon Time Sync Service, the offset is measured against it unless
+// time sources are given
+const DefaultReferenceServer = "169.254.169.123"
+
+// serverPattern matches the host names and addresses of the time sources
+var serverPattern = regexp.MustCompile(`^[A-Za-z0-9.:_-]+$`)
+
+// detect returns the time service of the instance
+var detect = detectTimeService
+
+// measureOffset returns the offset of the clock from the server, positive if the clock is behind
+var measureOffset = querySNTPOffset
+
+// timeService configures and syncs the time service of the platform
+type timeService interface {
+	// Name returns the name of the time service, e.g. chrony
+	Name() string
+	// Configure sets the time sources, returns false if they were already set
+	Configure(log log.T, cancelFlag task.CancelFlag, servers []string) (bool, error)
+	// Sync steps the clock to the time of its sources
+	Sync(log log.T, cancelFlag task.CancelFlag) error
+}
+
+// Plugin is the type for the aws:configureTimeSync plugin.
+type Plugin struct {
+}
+
+// TimeSyncPluginInput represents the time sources to configure and whether to sync the clock.
+type TimeSyncPluginInput struct {
+	contracts.PluginInput
+	ID string
+	// Servers are the time sources, the configured ones are kept if empty
+	Servers []string
+	// ForceSync steps the clock to the time of the sources instead of letting the service slew it
+	ForceSync bool
+	// ReferenceServer is the server the offset is measured against, the first server by default
+	ReferenceServer string
+	// MaxOffsetMillis fails the plugin if the offset after the run is larger, 0 disables the check
+	MaxOffsetMillis float64
+}
+
+// TimeSyncOutput is the outcome of the run, set as the output of the plugin.
+type TimeSyncOutput struct {
+	Service            string   `json:"service"`
+	Servers            []string `json:"servers,omitempty"`
+	Configured         bool     `json:"configured"`
+	Synced             bool     `json:"synced"`
+	ReferenceServer    string   `json:"referenceServer"`
+	OffsetBeforeMillis *float64 `json:"offsetBeforeMillis"`
+	OffsetAfterMillis  *float64 `json:"offsetAfterMillis"`
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameConfigureTimeSync
+}
+
+// Execute configures the time sources, syncs the clock and reports its offset.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var input TimeSyncPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if err := validate(&input); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	service, err := detect()
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	result := TimeSyncOutput{Service: service.Name(), Servers: input.Servers, ReferenceServer: input.ReferenceServer}
+	result.OffsetBeforeMillis = offsetOf(log, input.ReferenceServer, output)
+
+	if len(input.Servers) > 0 {
+		if result.Configured, err = service.Configure(log, cancelFlag, input.Servers); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to configure the time sources of %v: %v", service.Name(), err))
+			return
+		}
+		if result.Configured {
+			output.AppendInfof("%v time sources set to %v", service.Name(), strings.Join(input.Servers, ", "))
+		} else {
+			output.AppendInfof("%v time sources are already %v", service.Name(), strings.Join(input.Servers, ", "))
+		}
+	}
+	if input.ForceSync {
+		if err = service.Sync(log, cancelFlag); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to sync the clock with %v: %v", service.Name(), err))
+			return
+		}
+		result.Synced = true
+		output.AppendInfof("clock synced by %v", service.Name())
+	}
+
+	result.OffsetAfterMillis = offsetOf(log, input.ReferenceServer, output)
+	if content, err := jsonutil.Marshal(result); err == nil {
+		output.SetOutput(content)
+	}
+
+	if input.MaxOffsetMillis > 0 {
+		if result.OffsetAfterMillis == nil {
+			output.MarkAsFailed(fmt.Errorf("the offset from %v couldn't be measured", input.ReferenceServer))
+			return
+		}
+		if math.Abs(*result.OffsetAfterMillis) > input.MaxOffsetMillis {
+			output.MarkAsFailed(fmt.Errorf("the clock is %.3fms off %v, more than %vms", *result.OffsetAfterMillis, input.ReferenceServer, input.MaxOffsetMillis))
+			return
+		}
+	}
+	output.SetStatus(contracts.ResultStatusSuccess)
+}
+
+// validate checks the input and sets its defaults.
+func validate(input *TimeSyncPluginInput) error {
+	for _, server := range input.Servers {
+		if !serverPattern.MatchString(server) {
+			return fmt.Errorf("invalid time server %q, expected a host name or an address", server)
+		}
+	}
+	if input.ReferenceServer == "" {
+		input.ReferenceServer = DefaultReferenceServer
+		if len(input.Servers) > 0 {
+			input.ReferenceServer = input.Servers[0]
+		}
+	} else if !serverPattern.MatchString(input.ReferenceServer) {
+		return fmt.Errorf("invalid reference server %q, expected a host name or an address", input.ReferenceServer)
+	}
+	if input.MaxOffsetMillis < 0 {
+		return fmt.Errorf("MaxOffsetMillis must be positive, got %v", input.MaxOffsetMillis)
+	}
+	return nil
+}
+
+// offsetOf measures the offset of the clock in milliseconds, nil if the server can't be reached.
+func offsetOf(log log.T, server string, output iohandler.IOHandler) *float64 {
+	offset, err := measureOffset(server)
+	if err != nil {
+		log.Errorf("failed to measure the offset from %v: %v", server, err)
+		output.AppendErrorf("failed to measure the offset from %v: %v", server, err)
+		return nil
+	}
+	millis := float64(offset) / float64(time.Millisecond)
+	output.AppendInfof("clock offset from %v: %.3fms", server, millis)
+	return &millis
+}
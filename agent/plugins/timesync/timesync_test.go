@@ -0,0 +1,157 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package timesync
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTimeService records the servers and syncs, the clock is synced to the offset of the sync
+type fakeTimeService struct {
+	servers []string
+	synced  bool
+}
+
+func (s *fakeTimeService) Name() string {
+	return "fake"
+}
+
+func (s *fakeTimeService) Configure(log log.T, cancelFlag task.CancelFlag, servers []string) (bool, error) {
+	if equalServers(s.servers, servers) {
+		return false, nil
+	}
+	s.servers = servers
+	return true, nil
+}
+
+func (s *fakeTimeService) Sync(log log.T, cancelFlag task.CancelFlag) error {
+	s.synced = true
+	return nil
+}
+
+func TestWithServers(t *testing.T) {
+	config := []string{
+		"# Use public servers from the pool.ntp.org project.",
+		"pool 2.amazon.pool.ntp.org iburst",
+		"server 10.0.0.1",
+		"driftfile /var/lib/chrony/drift",
+	}
+	updated, changed := withServers(config, []string{"169.254.169.123", "10.0.0.2"})
+	assert.True(t, changed)
+	expected := []string{
+		"# Use public servers from the pool.ntp.org project.",
+		serversComment,
+		"server 169.254.169.123 iburst",
+		"server 10.0.0.2 iburst",
+		"driftfile /var/lib/chrony/drift",
+	}
+	assert.Equal(t, expected, updated)
+
+	updated, changed = withServers(expected, []string{"169.254.169.123", "10.0.0.2"})
+	assert.False(t, changed)
+	assert.Equal(t, expected, updated)
+
+	updated, changed = withServers([]string{"driftfile /var/lib/ntp/drift"}, []string{"10.0.0.1"})
+	assert.True(t, changed)
+	assert.Equal(t, []string{"driftfile /var/lib/ntp/drift", serversComment, "server 10.0.0.1 iburst"}, updated)
+}
+
+func TestQuerySNTPOffset(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+	defaultPort := sntpPort
+	_, sntpPort, _ = net.SplitHostPort(conn.LocalAddr().String())
+	defer func() { sntpPort = defaultPort }()
+
+	// the server is 2s ahead of the clock
+	go func() {
+		request := make([]byte, sntpPacketLength)
+		_, client, err := conn.ReadFrom(request)
+		if err != nil {
+			return
+		}
+		serverTime := time.Now().Add(2 * time.Second)
+		response := make([]byte, sntpPacketLength)
+		response[0] = 4<<3 | 4
+		response[1] = 2
+		copy(response[24:32], request[40:48])
+		binary.BigEndian.PutUint64(response[32:], toNTPTime(serverTime))
+		binary.BigEndian.PutUint64(response[40:], toNTPTime(serverTime))
+		conn.WriteTo(response, client)
+	}()
+
+	offset, err := querySNTPOffset("127.0.0.1")
+	assert.NoError(t, err)
+	assert.InDelta(t, float64(2*time.Second), float64(offset), float64(100*time.Millisecond))
+}
+
+func TestNTPTime(t *testing.T) {
+	at := time.Date(2026, 10, 16, 12, 0, 0, 500000000, time.UTC)
+	assert.True(t, at.Equal(fromNTPTime(toNTPTime(at))))
+}
+
+func TestExecute(t *testing.T) {
+	service := &fakeTimeService{}
+	detect = func() (timeService, error) { return service, nil }
+	defer func() { detect = detectTimeService }()
+	offsets := map[bool]time.Duration{false: 1500 * time.Millisecond, true: 2 * time.Millisecond}
+	var measured []string
+	measureOffset = func(server string) (time.Duration, error) {
+		measured = append(measured, server)
+		return offsets[service.synced], nil
+	}
+	defer func() { measureOffset = querySNTPOffset }()
+
+	execute := func(properties map[string]interface{}) *iohandler.DefaultIOHandler {
+		output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+		plugin, _ := NewPlugin()
+		plugin.Execute(context.NewMockDefault(), contracts.Configuration{Properties: properties}, task.NewChanneledCancelFlag(), output)
+		return output
+	}
+
+	output := execute(map[string]interface{}{"Servers": []string{"10.0.0.1", "10.0.0.2"}, "ForceSync": true, "MaxOffsetMillis": 10})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, service.servers)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.1"}, measured)
+	var result TimeSyncOutput
+	assert.NoError(t, jsonutil.Unmarshal(output.GetOutput().(string), &result))
+	assert.True(t, result.Configured)
+	assert.True(t, result.Synced)
+	assert.Equal(t, 1500.0, *result.OffsetBeforeMillis)
+	assert.Equal(t, 2.0, *result.OffsetAfterMillis)
+
+	// the clock is checked against the Amazon Time Sync Service without servers
+	service.synced = false
+	measured = nil
+	output = execute(map[string]interface{}{"MaxOffsetMillis": 1000})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, []string{DefaultReferenceServer, DefaultReferenceServer}, measured)
+	assert.True(t, strings.Contains(output.GetStderr(), "1500.000ms"), output.GetStderr())
+
+	output = execute(map[string]interface{}{"Servers": []string{"10.0.0.1\nserver evil"}})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+}
@@ -0,0 +1,106 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proxyconfig
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// Proxy returns the proxy of the request, nil if the request is sent directly: Agent.Proxy and Agent.NoProxy of
+// the agent configuration, else the proxy environment variables. Unlike http.ProxyFromEnvironment, they are read
+// for each request, so that the proxy of a reloaded configuration applies to the next requests.
+func Proxy(request *http.Request) (*url.URL, error) {
+	proxy, noProxy := proxySettings(request.URL.Scheme)
+	if proxy == "" || bypassed(noProxy, request.URL.Host) {
+		return nil, nil
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	return url.Parse(proxy)
+}
+
+// UseAgentProxy makes the default HTTP transport, which the AWS SDK clients use, send the requests through Proxy.
+func UseAgentProxy() {
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.Proxy = Proxy
+	}
+}
+
+// proxySettings returns the proxy of the scheme and the hosts reached directly, of the agent configuration if it
+// sets a proxy, of the environment otherwise
+func proxySettings(scheme string) (proxy string, noProxy string) {
+	if config, err := appconfig.Config(false); err == nil && config.Agent.Proxy != "" {
+		return config.Agent.Proxy, config.Agent.NoProxy
+	}
+	if scheme == "https" {
+		proxy = environment("HTTPS_PROXY", "https_proxy")
+	} else {
+		proxy = environment("HTTP_PROXY", "http_proxy")
+	}
+	return proxy, environment("NO_PROXY", "no_proxy")
+}
+
+// environment returns the value of the first of the variables that is set
+func environment(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// bypassed returns true if the host is reached directly: localhost, or a host matching an entry of the comma
+// separated list, * matching any host. An entry is a host or a domain, which matches its sub-domains, or a CIDR block.
+func bypassed(noProxy string, host string) bool {
+	hostname := strings.ToLower(host)
+	if name, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = name
+	}
+	hostname = strings.Trim(hostname, "[]")
+	ip := net.ParseIP(hostname)
+	if hostname == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+	for _, entry := range strings.Split(strings.ToLower(noProxy), ",") {
+		entry = strings.TrimSpace(entry)
+		if name, _, err := net.SplitHostPort(entry); err == nil {
+			entry = name
+		}
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		}
+		if _, block, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && block.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		domain := strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proxyconfig
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBypassed(t *testing.T) {
+	noProxy := "internal.example.com, .corp.example.com,10.0.0.0/8,proxy.example.com:3128"
+
+	assert.True(t, bypassed(noProxy, "localhost:8080"))
+	assert.True(t, bypassed(noProxy, "127.0.0.1"))
+	assert.True(t, bypassed(noProxy, "internal.example.com:443"))
+	assert.True(t, bypassed(noProxy, "git.internal.example.com"))
+	assert.True(t, bypassed(noProxy, "host.corp.example.com"))
+	assert.True(t, bypassed(noProxy, "10.1.2.3:443"))
+	assert.True(t, bypassed(noProxy, "proxy.example.com"))
+	assert.False(t, bypassed(noProxy, "ssm.us-east-1.amazonaws.com:443"))
+	assert.False(t, bypassed(noProxy, "notinternal.example.com"))
+	assert.True(t, bypassed("*", "ssm.us-east-1.amazonaws.com"))
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy"} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+	request, _ := http.NewRequest("GET", "https://ssm.us-east-1.amazonaws.com/", nil)
+
+	proxy, err := Proxy(request)
+	assert.NoError(t, err)
+	assert.Nil(t, proxy)
+
+	// the environment is read for each request
+	os.Setenv("HTTPS_PROXY", "proxy.example.com:3128")
+	proxy, err = Proxy(request)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://proxy.example.com:3128", proxy.String())
+
+	os.Setenv("NO_PROXY", "amazonaws.com")
+	proxy, err = Proxy(request)
+	assert.NoError(t, err)
+	assert.Nil(t, proxy)
+}
@@ -14,7 +14,11 @@
 // Package rebooter provides utilities used to reboot a machine.
 package rebooter
 
-import "github.com/aws/amazon-ssm-agent/agent/log"
+import (
+	"sync/atomic"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
 
 type RebootType string
 
@@ -25,6 +29,9 @@ const (
 
 var ch = make(chan RebootType)
 
+// rebootPending is set to 1 once a reboot request has been accepted
+var rebootPending int32
+
 func GetChannel() chan RebootType {
 	return ch
 }
@@ -43,6 +50,7 @@ func RequestPendingReboot(log log.T) bool {
 	//non-blocking send
 	select {
 	case ch <- RebootRequestTypeReboot:
+		atomic.StoreInt32(&rebootPending, 1)
 		log.Info("successfully requested a reboot")
 		return true
 	default:
@@ -51,3 +59,8 @@ func RequestPendingReboot(log log.T) bool {
 	}
 
 }
+
+// RebootPending returns true if a reboot has been requested and the agent is stopping to reboot the machine
+func RebootPending() bool {
+	return atomic.LoadInt32(&rebootPending) == 1
+}
@@ -25,10 +25,12 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	mdsService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
 	"github.com/aws/aws-sdk-go/service/ssmmds"
 	"github.com/carlescere/scheduler"
 )
@@ -47,6 +49,8 @@ var once sync.Once
 var loadDocStateFromSendCommand = parseSendCommandMessage
 var loadDocStateFromCancelCommand = parseCancelCommandMessage
 
+var pendingDocMgr docmanager.DocumentMgr = docmanager.NewDocumentFileMgr(appconfig.DefaultDataStorePath, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState)
+
 // Name returns the module name
 func (s *RunCommandService) ModuleName() string {
 	return s.name
@@ -78,14 +82,27 @@ func (s *RunCommandService) ModuleExecute(context context.T) (err error) {
 	if s.pollAssociations {
 		s.assocProcessor.ModuleExecute(context)
 	}
+	// the offline service always runs one document at a time
+	if s.name == mdsName {
+		appconfig.OnReload(s.name, s.resizeWorkers)
+	}
 	return
 }
 
+// resizeWorkers applies the command workers limit of the reloaded configuration
+func (s *RunCommandService) resizeWorkers(config appconfig.SsmagentConfig) {
+	s.processor.SetCommandWorkersLimit(config.Mds.CommandWorkersLimit)
+}
+
 func (s *RunCommandService) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	appconfig.OnReload(s.name, nil)
 	//first stop the message poller
 	s.stop()
+	watchdog.StopWatching(s.name)
 	//second stop the message processor
 	s.processor.Stop(stopType)
+	//then send the replies held for the steps that ran
+	s.flushReplies()
 
 	//TODO move this out once we have association moved to a different core module
 	if s.assocProcessor != nil {
@@ -116,6 +133,14 @@ func (s *RunCommandService) listenReply(resultChan chan contracts.DocumentResult
 		}
 		s.sendResponse(res.MessageID, res)
 	}
+	s.flushReplies()
+}
+
+// flushReplies sends the replies held by the service.
+func (s *RunCommandService) flushReplies() {
+	if s.replies != nil {
+		s.replies.flush()
+	}
 }
 
 // isRunCommandLogFile checks whether the file name format satisfies the format for RunCommand generated log files
@@ -150,8 +175,9 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 		err      error
 	)
 
-	// create separate logger that includes messageID with every log message
-	context := s.context.With("[messageID=" + *msg.MessageId + "]")
+	// create separate logger that includes messageID and the trace started for the message with every log message
+	trace := log.NewTraceContext()
+	context := s.context.With("[messageID=" + *msg.MessageId + "]").With(trace.ContextTag())
 	log := context.Log()
 	log.Debug("Processing message")
 
@@ -180,8 +206,22 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 		}
 		return
 	}
+
+	docState.DocumentInformation.TraceParent = trace.TraceParent()
+
+	// queue the document on disk before acknowledging the message, MDS won't deliver it again once acknowledged
+	// and the processor replays the pending documents on startup if the agent stops before picking it up
+	isSendCommand := docState.DocumentType == contracts.SendCommand || docState.DocumentType == contracts.SendCommandOffline
+	if isSendCommand {
+		pendingDocMgr.PersistDocumentState(log, docState.DocumentInformation.DocumentID, docState.DocumentInformation.InstanceID, appconfig.DefaultLocationOfPending, *docState)
+	}
+
 	if err = s.service.AcknowledgeMessage(log, *msg.MessageId); err != nil {
 		sdkutil.HandleAwsError(log, err, s.processorStopPolicy)
+		if isSendCommand {
+			// the message will be delivered again, drop the queued copy so that it does not run twice
+			pendingDocMgr.RemoveDocumentState(log, docState.DocumentInformation.DocumentID, docState.DocumentInformation.InstanceID, appconfig.DefaultLocationOfPending)
+		}
 		return
 	}
 
@@ -0,0 +1,59 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// minCompressedSize is the size of the smallest request body compressed, smaller bodies gain little
+const minCompressedSize = 1024
+
+// compressReplies returns whether the replies are sent gzip encoded
+var compressReplies = func() bool {
+	config, err := appconfig.Config(false)
+	return err == nil && config.Mds.CompressReplies
+}
+
+// gzipBody compresses the body of the request once it's built, so that the compressed body is signed.
+func gzipBody(r *request.Request) {
+	if r.Error != nil || r.Body == nil {
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		r.Error = err
+		return
+	}
+	if len(body) < minCompressedSize {
+		r.SetBufferBody(body)
+		return
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err = writer.Write(body); err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		r.Error = err
+		return
+	}
+	r.SetBufferBody(compressed.Bytes())
+	r.HTTPRequest.Header.Set("Content-Encoding", "gzip")
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package service
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSendReplyRequest(payload string) *request.Request {
+	sdk := ssmmds.New(session.New(&aws.Config{Region: aws.String("us-east-1"), Credentials: credentials.AnonymousCredentials}))
+	req, _ := sdk.SendReplyRequest(&ssmmds.SendReplyInput{
+		MessageId: aws.String("aws.ssm.message-1.i-1234"),
+		Payload:   aws.String(payload),
+		ReplyId:   aws.String("reply-0000-0000-0001"),
+	})
+	req.Handlers.Build.PushBack(gzipBody)
+	return req
+}
+
+func TestGzipBodyCompressesLargeReplies(t *testing.T) {
+	payload := strings.Repeat("output ", 1000)
+	req := newSendReplyRequest(payload)
+	assert.NoError(t, req.Build())
+	assert.Equal(t, "gzip", req.HTTPRequest.Header.Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(req.Body)
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), payload)
+}
+
+func TestGzipBodyKeepsSmallReplies(t *testing.T) {
+	req := newSendReplyRequest("output")
+	assert.NoError(t, req.Build())
+	assert.Empty(t, req.HTTPRequest.Header.Get("Content-Encoding"))
+
+	body, err := ioutil.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"Payload":"output"`)
+}
@@ -16,8 +16,11 @@ package service
 
 import (
 	"fmt"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"errors"
@@ -25,20 +28,52 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/doccache"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/times"
 	"github.com/aws/aws-sdk-go/service/ssmmds"
 	"github.com/twinj/uuid"
 )
 
+// uploadRetryInterval is how often the results waiting to be uploaded are retried, while the instance is offline
+const uploadRetryInterval = time.Minute
+
+// getCachedDocument and uploadResult are replaced in the tests
+var getCachedDocument = doccache.GetContent
+var uploadResult = func(log log.T, bucketName string, objectKey string, filePath string) error {
+	return s3util.NewAmazonS3Util(log, bucketName).S3Upload(log, bucketName, objectKey, filePath)
+}
+
 type offlineService struct {
 	TopicPrefix         string
 	newCommandDir       string
 	submittedCommandDir string
 	commandResultDir    string
 	invalidCommandDir   string
+	pendingUploadDir    string
+	uploadLock          sync.Mutex
+	lastUploadAttempt   time.Time
+}
+
+// localCommand holds the settings of a local command document besides its content. A local command can run
+// a document cached by name with the given parameters, instead of holding the content of the document.
+type localCommand struct {
+	DocumentName       string                 `json:"documentName"`
+	Parameters         map[string]interface{} `json:"parameters"`
+	OutputS3BucketName string                 `json:"outputS3BucketName"`
+	OutputS3KeyPrefix  string                 `json:"outputS3KeyPrefix"`
+}
+
+// pendingUpload is a result of a local command to upload to S3, once the command is complete
+// and the instance is online
+type pendingUpload struct {
+	BucketName string `json:"bucketName"`
+	KeyPrefix  string `json:"keyPrefix"`
+	InstanceID string `json:"instanceId"`
+	Complete   bool   `json:"complete"`
 }
 
 // NewOfflineService initializes a service that looks for work in a local command folder
@@ -57,12 +92,14 @@ func NewOfflineService(log log.T, topicPrefix string) (Service, error) {
 		submittedCommandDir: appconfig.LocalCommandRootSubmitted,
 		invalidCommandDir:   appconfig.LocalCommandRootInvalid,
 		commandResultDir:    appconfig.LocalCommandRootCompleted,
+		pendingUploadDir:    appconfig.LocalCommandRootPendingUpload,
 	}, err
 }
 
 // GetMessages looks for new local command documents on the filesystem and parses them into messages
 func (ols *offlineService) GetMessages(log log.T, instanceID string) (messages *ssmmds.GetMessagesOutput, err error) {
 	messages = &ssmmds.GetMessagesOutput{}
+	ols.uploadResults(log, false)
 
 	// Look for unprocessed locally submitted documents
 	var docName, docPath string
@@ -83,9 +120,18 @@ func (ols *offlineService) GetMessages(log log.T, instanceID string) (messages *
 		commandID := uuid.NewV4().String()
 		messageID := fmt.Sprintf("aws.ssm.%v.%v", commandID, instanceID)
 
-		// Parse file
+		// Parse file, a document or a reference to a cached document
+		var command localCommand
 		var content contracts.DocumentContent
-		if errContent := jsonutil.UnmarshalFile(docPath, &content); errContent != nil {
+		errContent := jsonutil.UnmarshalFile(docPath, &command)
+		if errContent == nil && command.DocumentName != "" {
+			content, errContent = getCachedDocument(instanceID, command.DocumentName)
+		} else if errContent == nil {
+			// the parameters of a document are its parameter definitions
+			command.Parameters = nil
+			errContent = jsonutil.UnmarshalFile(docPath, &content)
+		}
+		if errContent != nil {
 			log.Errorf("Error parsing command document %v:\n%v", docName, errContent)
 			if errMove := moveCommandDocument(ols.newCommandDir, ols.invalidCommandDir, docName, commandID); errMove != nil {
 				log.Errorf("Command %v was invalid but failed to move to invalid folder: %v", commandID, errMove.Error())
@@ -96,7 +142,10 @@ func (ols *offlineService) GetMessages(log log.T, instanceID string) (messages *
 		log.Debugf("Local command content:\n%v", debugContent)
 
 		// Turn it into a message
-		payload := &messageContracts.SendCommandPayload{DocumentContent: content, CommandID: commandID, DocumentName: docName}
+		payload := &messageContracts.SendCommandPayload{DocumentContent: content, CommandID: commandID, DocumentName: docName, Parameters: command.Parameters}
+		if command.DocumentName != "" {
+			payload.DocumentName = command.DocumentName
+		}
 		var payloadstr string
 		if payloadstr, err = jsonutil.Marshal(payload); err != nil {
 			log.Errorf("Error marshalling message for command document %v with message ID %v:\n%v", docName, messageID, err)
@@ -120,6 +169,14 @@ func (ols *offlineService) GetMessages(log log.T, instanceID string) (messages *
 			continue // If doc failed to move, we will not return this message - we don't want to reprocess it or make it impossible to know which command ID it was given
 		}
 
+		// The steps don't upload their output, the result of the command is uploaded once complete
+		if command.OutputS3BucketName != "" {
+			upload := pendingUpload{BucketName: command.OutputS3BucketName, KeyPrefix: command.OutputS3KeyPrefix, InstanceID: instanceID}
+			if errUpload := ols.savePendingUpload(commandID, upload); errUpload != nil {
+				log.Errorf("Failed to queue the upload of the result of command %v: %v", commandID, errUpload)
+			}
+		}
+
 		messages.Messages = append(messages.Messages, message)
 	}
 
@@ -161,10 +218,87 @@ func (ols *offlineService) SendReply(log log.T, messageID string, payload string
 	}
 	if err := fileutil.WriteAllText(filepath.Join(ols.commandResultDir, commandID), payload); err != nil {
 		log.Errorf("failed to write command %v result: %v", commandID, err)
+		return nil
+	}
+
+	var reply messageContracts.SendReplyPayload
+	if err := jsonutil.Unmarshal(payload, &reply); err != nil || !isComplete(reply.DocumentStatus) {
+		return nil
 	}
+	var upload pendingUpload
+	if err := jsonutil.UnmarshalFile(filepath.Join(ols.pendingUploadDir, commandID), &upload); err != nil {
+		// the result of the command isn't uploaded
+		return nil
+	}
+	upload.Complete = true
+	if err := ols.savePendingUpload(commandID, upload); err != nil {
+		log.Errorf("failed to queue the upload of command %v result: %v", commandID, err)
+	}
+	ols.uploadResults(log, true)
 	return nil
 }
 
+// isComplete checks if a document status is final, the documents requesting a reboot resume after it
+func isComplete(status contracts.ResultStatus) bool {
+	switch status {
+	case contracts.ResultStatusSuccess, contracts.ResultStatusFailed, contracts.ResultStatusCancelled,
+		contracts.ResultStatusTimedOut, contracts.ResultStatusSkipped:
+		return true
+	default:
+		return false
+	}
+}
+
+// savePendingUpload saves the upload of the result of a command in the queue
+func (ols *offlineService) savePendingUpload(commandID string, upload pendingUpload) error {
+	if err := fileutil.MakeDirs(ols.pendingUploadDir); err != nil {
+		return err
+	}
+	content, err := jsonutil.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAllText(filepath.Join(ols.pendingUploadDir, commandID), content)
+}
+
+// uploadResults uploads the results of the complete commands in the queue, in the order they completed, at most
+// every uploadRetryInterval unless forced. The uploads stop at the first failure, the instance is likely offline, and
+// resume once it is online again; the results stay in the queue until they are uploaded, across restarts.
+func (ols *offlineService) uploadResults(log log.T, force bool) {
+	ols.uploadLock.Lock()
+	defer ols.uploadLock.Unlock()
+	if !force && time.Since(ols.lastUploadAttempt) < uploadRetryInterval {
+		return
+	}
+	ols.lastUploadAttempt = time.Now()
+
+	files, err := fileutil.ReadDir(ols.pendingUploadDir)
+	if err != nil {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	for _, file := range files {
+		commandID := file.Name()
+		pendingPath := filepath.Join(ols.pendingUploadDir, commandID)
+		var upload pendingUpload
+		if err = jsonutil.UnmarshalFile(pendingPath, &upload); err != nil {
+			log.Errorf("Removing the invalid upload of command %v result: %v", commandID, err)
+			fileutil.DeleteFile(pendingPath)
+			continue
+		}
+		if !upload.Complete {
+			continue
+		}
+		key := path.Join(upload.KeyPrefix, commandID, upload.InstanceID, "result.json")
+		if err = uploadResult(log, upload.BucketName, key, filepath.Join(ols.commandResultDir, commandID)); err != nil {
+			log.Infof("Failed to upload command %v result to %v, retrying in %v: %v", commandID, upload.BucketName, uploadRetryInterval, err)
+			return
+		}
+		log.Infof("Uploaded command %v result to s3://%v/%v", commandID, upload.BucketName, key)
+		fileutil.DeleteFile(pendingPath)
+	}
+}
+
 func (ols *offlineService) FailMessage(log log.T, messageID string, failureType FailureType) error {
 	return nil
 }
@@ -15,11 +15,16 @@
 package service
 
 import (
+	"errors"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,6 +35,7 @@ const (
 	submittedCommands = "testdata/new/submitted"
 	invalidCommands   = "testdata/new/invalid"
 	completeDir       = "testdata/new/completed"
+	pendingUploads    = "testdata/new/pendingupload"
 )
 
 func TestValid(t *testing.T) {
@@ -87,6 +93,70 @@ func TestOfflineService_SendReply(t *testing.T) {
 	assert.Equal(t, 1, FileCount(completeDir))
 }
 
+func TestCachedDocumentAndPendingUpload(t *testing.T) {
+	service := GetTestService()
+	defer CleanTestDirs()
+	getCachedDocument = func(instanceID string, name string) (contracts.DocumentContent, error) {
+		if name == "Deploy" {
+			return contracts.DocumentContent{SchemaVersion: "2.2"}, nil
+		}
+		return contracts.DocumentContent{}, errors.New("not cached")
+	}
+	var uploads []string
+	online := false
+	uploadResult = func(log log.T, bucketName string, objectKey string, filePath string) error {
+		if !online {
+			return errors.New("no route to host")
+		}
+		uploads = append(uploads, bucketName+"/"+objectKey)
+		return nil
+	}
+	err := SubmitTestDoc("cachedcommand.json")
+	assert.Nil(t, err)
+
+	messages, err := service.GetMessages(logger, "i-bar")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages.Messages))
+	var payload messageContracts.SendCommandPayload
+	assert.Nil(t, jsonutil.Unmarshal(*messages.Messages[0].Payload, &payload))
+	assert.Equal(t, "Deploy", payload.DocumentName)
+	assert.Equal(t, "2.2", payload.DocumentContent.SchemaVersion)
+	assert.Equal(t, []interface{}{"1.2"}, payload.Parameters["version"])
+	assert.Equal(t, "", payload.OutputS3BucketName)
+	assert.Equal(t, 1, FileCount(pendingUploads))
+
+	messageID := *messages.Messages[0].MessageId
+	commandID, _ := messageContracts.GetCommandID(messageID)
+	service.SendReply(logger, messageID, `{"documentStatus": "InProgress"}`)
+	service.SendReply(logger, messageID, `{"documentStatus": "Success"}`)
+	assert.Empty(t, uploads)
+	assert.Equal(t, 1, FileCount(pendingUploads))
+
+	// the instance is online again
+	online = true
+	service.(*offlineService).lastUploadAttempt = time.Time{}
+	_, err = service.GetMessages(logger, "i-bar")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"results-bucket/offline/" + commandID + "/i-bar/result.json"}, uploads)
+	assert.Equal(t, 0, FileCount(pendingUploads))
+}
+
+func TestUncachedDocumentIsInvalid(t *testing.T) {
+	service := GetTestService()
+	defer CleanTestDirs()
+	getCachedDocument = func(instanceID string, name string) (contracts.DocumentContent, error) {
+		return contracts.DocumentContent{}, errors.New("not cached")
+	}
+	err := SubmitTestDoc("cachedcommand.json")
+	assert.Nil(t, err)
+
+	messages, err := service.GetMessages(logger, "i-bar")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(messages.Messages))
+	assert.Equal(t, 1, FileCount(invalidCommands))
+}
+
 func GetTestService() Service {
 	CleanTestDirs()
 	return &offlineService{
@@ -95,6 +165,7 @@ func GetTestService() Service {
 		submittedCommandDir: submittedCommands,
 		invalidCommandDir:   invalidCommands,
 		commandResultDir:    completeDir,
+		pendingUploadDir:    pendingUploads,
 	}
 }
 
@@ -106,28 +177,27 @@ func SubmitTestDoc(name string) error {
 	}
 }
 
+// placeholder keeps the test directories in git, CleanTestDirs and FileCount ignore it
+const placeholder = "dummy"
+
 func CleanTestDirs() {
-	var files []string
-	files, _ = fileutil.GetFileNames(submittedCommands)
-	for _, file := range files {
-		fileutil.DeleteFile(filepath.Join(submittedCommands, file))
-	}
-	files, _ = fileutil.GetFileNames(invalidCommands)
-	for _, file := range files {
-		fileutil.DeleteFile(filepath.Join(invalidCommands, file))
-	}
-	files, _ = fileutil.GetFileNames(newCommands)
-	for _, file := range files {
-		fileutil.DeleteFile(filepath.Join(newCommands, file))
-	}
-	files, _ = fileutil.GetFileNames(completeDir)
-	for _, file := range files {
-		fileutil.DeleteFile(filepath.Join(completeDir, file))
+	for _, dir := range []string{submittedCommands, invalidCommands, newCommands, completeDir, pendingUploads} {
+		files, _ := fileutil.GetFileNames(dir)
+		for _, file := range files {
+			if file != placeholder {
+				fileutil.DeleteFile(filepath.Join(dir, file))
+			}
+		}
 	}
 }
 
 func FileCount(path string) int {
-	var files []string
-	files, _ = fileutil.GetFileNames(path)
-	return len(files)
+	files, _ := fileutil.GetFileNames(path)
+	count := 0
+	for _, file := range files {
+		if file != placeholder {
+			count++
+		}
+	}
+	return count
 }
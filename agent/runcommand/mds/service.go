@@ -25,6 +25,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -93,7 +94,7 @@ func NewService(region string, endpoint string, creds *credentials.Credentials,
 
 	// capture Transport so we can use it to cancel requests
 	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy: proxyconfig.Proxy,
 		Dial: (&net.Dialer{
 			Timeout:   connectionTimeout,
 			KeepAlive: 0,
@@ -212,6 +213,9 @@ func (mds *sdkService) SendReply(log log.T, messageID string, payload string) (e
 	}
 	log.Debug("Calling SendReply with params", params)
 	req, resp := mds.sdk.SendReplyRequest(params)
+	if compressReplies() {
+		req.Handlers.Build.PushBack(gzipBody)
+	}
 	if err = mds.sendRequest(req); err != nil {
 		err = fmt.Errorf("SendReply Error: %v", err)
 		log.Debug(err)
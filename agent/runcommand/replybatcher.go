@@ -0,0 +1,169 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runcommand implements runcommand core processing module
+package runcommand
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	mdsService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+)
+
+// replySender sends a reply for a message to the service.
+type replySender func(log log.T, messageID string, payload messageContracts.SendReplyPayload)
+
+// replyBatcher coalesces the replies sent after each step of a command. A reply holds the status of all the
+// steps of the command, so the reply pending for a message is replaced by the next one and only the latest
+// is sent when the flush interval elapses. The final reply of a command and the document level replies are
+// sent at once, after the reply pending for the same message, so that the service gets the replies of a
+// message in order. The replies are sent outside of the mutex, in the order they're taken, so that a slow
+// service never holds the replies of the next steps.
+type replyBatcher struct {
+	mutex    sync.Mutex
+	send     replySender
+	interval func() time.Duration
+	pending  map[string]pendingReply
+	// order holds the IDs of the messages with a pending reply, in the order their replies became pending
+	order []string
+	timer *time.Timer
+	// nextTicket is the turn of the next replies taken, sendingTicket the turn of the replies being sent; turn is
+	// signaled when the turn changes
+	nextTicket    uint64
+	sendingTicket uint64
+	turn          *sync.Cond
+}
+
+type pendingReply struct {
+	log       log.T
+	messageID string
+	payload   messageContracts.SendReplyPayload
+}
+
+// serviceReplySender returns the sender of the replies to the service.
+func serviceReplySender(service mdsService.Service, stopPolicy *sdkutil.StopPolicy) replySender {
+	return func(log log.T, messageID string, payload messageContracts.SendReplyPayload) {
+		processSendReply(log, messageID, service, payload, stopPolicy)
+	}
+}
+
+func newReplyBatcher(send replySender, interval func() time.Duration) *replyBatcher {
+	b := &replyBatcher{
+		send:     send,
+		interval: interval,
+		pending:  make(map[string]pendingReply),
+	}
+	b.turn = sync.NewCond(&b.mutex)
+	return b
+}
+
+// sendStepReply holds the reply sent after a step of a command until the next flush, replacing the reply
+// pending for the message. It sends the reply at once when the flush interval is 0.
+func (b *replyBatcher) sendStepReply(log log.T, messageID string, payload messageContracts.SendReplyPayload) {
+	b.mutex.Lock()
+
+	interval := b.interval()
+	if interval <= 0 {
+		b.take(messageID)
+		b.sendInTurn(pendingReply{log: log, messageID: messageID, payload: payload})
+		return
+	}
+	if _, ok := b.pending[messageID]; !ok {
+		b.order = append(b.order, messageID)
+	}
+	b.pending[messageID] = pendingReply{log: log, messageID: messageID, payload: payload}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(interval, b.flush)
+	}
+	b.mutex.Unlock()
+}
+
+// sendFinalReply sends the reply of a completed command, which supersedes the reply pending for the message.
+func (b *replyBatcher) sendFinalReply(log log.T, messageID string, payload messageContracts.SendReplyPayload) {
+	b.mutex.Lock()
+
+	b.take(messageID)
+	b.sendInTurn(pendingReply{log: log, messageID: messageID, payload: payload})
+}
+
+// sendDocumentReply sends a document level reply after the reply pending for the message.
+func (b *replyBatcher) sendDocumentReply(log log.T, messageID string, payload messageContracts.SendReplyPayload) {
+	b.mutex.Lock()
+
+	var replies []pendingReply
+	if reply, ok := b.take(messageID); ok {
+		replies = append(replies, reply)
+	}
+	b.sendInTurn(append(replies, pendingReply{log: log, messageID: messageID, payload: payload})...)
+}
+
+// flush sends the pending replies.
+func (b *replyBatcher) flush() {
+	b.mutex.Lock()
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	replies := make([]pendingReply, 0, len(b.order))
+	for _, messageID := range b.order {
+		replies = append(replies, b.pending[messageID])
+		delete(b.pending, messageID)
+	}
+	b.order = nil
+	b.sendInTurn(replies...)
+}
+
+// sendInTurn sends the replies taken once the replies taken before are sent, and unlocks the mutex locked by the
+// caller; the replies are sent unlocked, the mutex is only locked again to pass the turn.
+func (b *replyBatcher) sendInTurn(replies ...pendingReply) {
+	if len(replies) == 0 {
+		b.mutex.Unlock()
+		return
+	}
+	ticket := b.nextTicket
+	b.nextTicket++
+	for b.sendingTicket != ticket {
+		b.turn.Wait()
+	}
+	b.mutex.Unlock()
+
+	defer func() {
+		b.mutex.Lock()
+		b.sendingTicket++
+		b.turn.Broadcast()
+		b.mutex.Unlock()
+	}()
+	for _, reply := range replies {
+		b.send(reply.log, reply.messageID, reply.payload)
+	}
+}
+
+// take removes the reply pending for the message and returns it.
+func (b *replyBatcher) take(messageID string) (reply pendingReply, ok bool) {
+	if reply, ok = b.pending[messageID]; !ok {
+		return
+	}
+	delete(b.pending, messageID)
+	for i, id := range b.order {
+		if id == messageID {
+			b.order = append(b.order[:i], b.order[i+1:]...)
+			break
+		}
+	}
+	return
+}
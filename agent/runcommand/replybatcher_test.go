@@ -0,0 +1,147 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runcommand
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// sentReplies records the replies sent, as message ID and trace output of the payload.
+type sentReplies struct {
+	mutex   sync.Mutex
+	replies []string
+}
+
+func (r *sentReplies) send(log log.T, messageID string, payload messageContracts.SendReplyPayload) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.replies = append(r.replies, messageID+":"+payload.DocumentTraceOutput)
+}
+
+func (r *sentReplies) get() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.replies...)
+}
+
+func reply(trace string) messageContracts.SendReplyPayload {
+	return messageContracts.SendReplyPayload{DocumentStatus: contracts.ResultStatusInProgress, DocumentTraceOutput: trace}
+}
+
+func fixedInterval(interval time.Duration) func() time.Duration {
+	return func() time.Duration { return interval }
+}
+
+func TestReplyBatcherCoalescesStepReplies(t *testing.T) {
+	sent := &sentReplies{}
+	b := newReplyBatcher(sent.send, fixedInterval(50*time.Millisecond))
+	logger := log.NewMockLog()
+
+	for _, step := range []string{"step1", "step2", "step3"} {
+		b.sendStepReply(logger, "msg-1", reply(step))
+	}
+	b.sendStepReply(logger, "msg-2", reply("step1"))
+	assert.Empty(t, sent.get())
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, []string{"msg-1:step3", "msg-2:step1"}, sent.get())
+}
+
+func TestReplyBatcherFinalReplySupersedesPendingReply(t *testing.T) {
+	sent := &sentReplies{}
+	b := newReplyBatcher(sent.send, fixedInterval(time.Hour))
+	logger := log.NewMockLog()
+
+	b.sendStepReply(logger, "msg-1", reply("step1"))
+	b.sendStepReply(logger, "msg-2", reply("step1"))
+	b.sendFinalReply(logger, "msg-1", reply("done"))
+	assert.Equal(t, []string{"msg-1:done"}, sent.get())
+
+	b.flush()
+	assert.Equal(t, []string{"msg-1:done", "msg-2:step1"}, sent.get())
+}
+
+func TestReplyBatcherSendsPendingReplyBeforeDocumentReply(t *testing.T) {
+	sent := &sentReplies{}
+	b := newReplyBatcher(sent.send, fixedInterval(time.Hour))
+	logger := log.NewMockLog()
+
+	b.sendStepReply(logger, "msg-1", reply("step1"))
+	b.sendDocumentReply(logger, "msg-1", reply("cancelling"))
+	b.flush()
+	assert.Equal(t, []string{"msg-1:step1", "msg-1:cancelling"}, sent.get())
+}
+
+func TestReplyBatcherWithoutInterval(t *testing.T) {
+	sent := &sentReplies{}
+	b := newReplyBatcher(sent.send, fixedInterval(0))
+	logger := log.NewMockLog()
+
+	b.sendStepReply(logger, "msg-1", reply("step1"))
+	b.sendStepReply(logger, "msg-1", reply("step2"))
+	assert.Equal(t, []string{"msg-1:step1", "msg-1:step2"}, sent.get())
+}
+
+func TestReplyBatcherHoldsNoStepReplyWhileSending(t *testing.T) {
+	sent := &sentReplies{}
+	release := make(chan struct{})
+	slowSend := func(log log.T, messageID string, payload messageContracts.SendReplyPayload) {
+		if payload.DocumentTraceOutput == "step1" {
+			<-release
+		}
+		sent.send(log, messageID, payload)
+	}
+	b := newReplyBatcher(slowSend, fixedInterval(time.Hour))
+	logger := log.NewMockLog()
+
+	b.sendStepReply(logger, "msg-1", reply("step1"))
+	flushed := make(chan struct{})
+	go func() {
+		b.flush()
+		close(flushed)
+	}()
+	for {
+		// the flush took the reply and is sending it
+		b.mutex.Lock()
+		taken := len(b.pending) == 0
+		b.mutex.Unlock()
+		if taken {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// the replies of the next steps are held while the service is slow, and the final reply is sent after step1
+	b.sendStepReply(logger, "msg-1", reply("step2"))
+	b.sendStepReply(logger, "msg-2", reply("step1"))
+	final := make(chan struct{})
+	go func() {
+		b.sendFinalReply(logger, "msg-1", reply("done"))
+		close(final)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, sent.get())
+
+	close(release)
+	<-flushed
+	<-final
+	assert.Equal(t, []string{"msg-1:step1", "msg-1:done"}, sent.get())
+}
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
 	"github.com/carlescere/scheduler"
 )
 
@@ -46,6 +47,8 @@ func (s *RunCommandService) loop() {
 	// this is extra insurance to prevent any race condition
 	pollStartTime := time.Now()
 	updateLastPollTime(s.name, pollStartTime)
+	// the scheduler polls again after pollMessageFrequencyMinutes at the latest
+	watchdog.Progress(s.name, pollProgressLimit)
 
 	log := s.context.Log()
 	if s.processorStopPolicy != nil {
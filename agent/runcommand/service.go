@@ -63,6 +63,9 @@ const (
 	// note: the connection timeout for MDSPoll should be less than this.
 	pollMessageFrequencyMinutes = 15
 
+	// pollProgressLimit is how long the polling may take to start again before the agent is hung
+	pollProgressLimit = (pollMessageFrequencyMinutes + 5) * time.Minute
+
 	// the default stoppolicy error threshold. After 10 consecutive errors the plugin will stop for 15 minutes.
 	stopPolicyErrorThreshold = 10
 )
@@ -83,6 +86,7 @@ type RunCommandService struct {
 	service              mdsService.Service
 	sendDocLevelResponse SendDocumentLevelResponse
 	sendResponse         SendResponse
+	replies              *replyBatcher
 	orchestrationRootDir string
 	messagePollJob       *scheduler.Job
 	//TODO move association poller out, we surely have to
@@ -145,16 +149,30 @@ func NewService(ctx context.T, serviceName string, service mdsService.Service, c
 	// create a stop policy where we will stop after 10 consecutive errors and if time period expires.
 	stopPolicy := newStopPolicy(serviceName)
 
+	// the replies sent after the steps of the commands are coalesced for Mds.ReplyFlushIntervalMillis
+	replies := newReplyBatcher(serviceReplySender(service, stopPolicy), func() time.Duration {
+		return time.Duration(ctx.AppConfig().Mds.ReplyFlushIntervalMillis) * time.Millisecond
+	})
+
 	// SendDocLevelResponse is used to send document level update
 	// Specify a new status of the document
 	sendDocLevelResponse := func(messageID string, resultStatus contracts.ResultStatus, documentTraceOutput string) {
 		payloadDoc := prepareReplyPayloadToUpdateDocumentStatus(agentInfo, resultStatus, documentTraceOutput)
-		processSendReply(log, messageID, service, payloadDoc, stopPolicy)
+		replies.sendDocumentReply(log, messageID, payloadDoc)
 	}
 
 	sendResponse := func(messageID string, res contracts.DocumentResult) {
 		pluginID := res.LastPlugin
-		processSendReply(log, messageID, service, FormatPayload(log, pluginID, agentInfo, res.PluginResults), stopPolicy)
+		log := log
+		if res.TraceParent != "" {
+			log = context.WithTrace(ctx, res.TraceParent).Log()
+		}
+		payload := FormatPayload(log, pluginID, agentInfo, res.PluginResults)
+		if pluginID == "" {
+			replies.sendFinalReply(log, messageID, payload)
+		} else {
+			replies.sendStepReply(log, messageID, payload)
+		}
 	}
 
 	var assocProc *associationProcessor.Processor
@@ -170,6 +188,7 @@ func NewService(ctx context.T, serviceName string, service mdsService.Service, c
 		service:              service,
 		sendDocLevelResponse: sendDocLevelResponse,
 		sendResponse:         sendResponse,
+		replies:              replies,
 		orchestrationRootDir: orchestrationRootDir,
 		processorStopPolicy:  stopPolicy,
 		assocProcessor:       assocProc,
@@ -16,6 +16,7 @@ package runcommand
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -23,15 +24,18 @@ import (
 	"encoding/json"
 	"path"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/docparser"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/mock"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/runcommand/mock"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/amazon-ssm-agent/agent/times"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ssmmds"
@@ -59,6 +63,8 @@ type TestCaseProcessMessage struct {
 
 	ProcessMock *processormock.MockedProcessor
 
+	DocMgrMock *docmanagermock.DocumentMgrMock
+
 	IsDocLevelResponseSent *bool
 }
 
@@ -73,6 +79,7 @@ func TestProcessMessageWithSendCommandTopicPrefix(t *testing.T) {
 	svc, tc := prepareTestProcessMessage(topic)
 
 	// set the expectations
+	tc.DocMgrMock.On("PersistDocumentState", mock.Anything, mock.Anything, mock.Anything, appconfig.DefaultLocationOfPending, isTracedDocState(contracts.SendCommand)).Return()
 	tc.MdsMock.On("AcknowledgeMessage", mock.Anything, *tc.Message.MessageId).Return(nil)
 	loadDocStateFromSendCommand = func(context context.T,
 		msg *ssmmds.Message,
@@ -80,7 +87,7 @@ func TestProcessMessageWithSendCommandTopicPrefix(t *testing.T) {
 		return &fakeDocState, nil
 	}
 
-	tc.ProcessMock.On("Submit", fakeDocState).Return(nil)
+	tc.ProcessMock.On("Submit", isTracedDocState(contracts.SendCommand)).Return(nil)
 	// execute processMessage
 	svc.processMessage(&tc.Message)
 
@@ -88,10 +95,48 @@ func TestProcessMessageWithSendCommandTopicPrefix(t *testing.T) {
 	tc.ContextMock.AssertCalled(t, "Log")
 	tc.MdsMock.AssertExpectations(t)
 	tc.ProcessMock.AssertExpectations(t)
+	tc.DocMgrMock.AssertExpectations(t)
 
 	assert.True(t, *tc.IsDocLevelResponseSent)
 }
 
+// TestProcessMessageAcknowledgeFailed tests processMessage drops the queued document when the message can't be acknowledged
+func TestProcessMessageAcknowledgeFailed(t *testing.T) {
+	var fakeDocState = contracts.DocumentState{
+		DocumentType: contracts.SendCommand,
+	}
+	// prepare processor and test case fields
+	svc, tc := prepareTestProcessMessage(testTopicSend)
+	svc.processorStopPolicy = sdkutil.NewStopPolicy("test", 10)
+
+	// set the expectations
+	tc.DocMgrMock.On("PersistDocumentState", mock.Anything, mock.Anything, mock.Anything, appconfig.DefaultLocationOfPending, isTracedDocState(contracts.SendCommand)).Return()
+	tc.DocMgrMock.On("RemoveDocumentState", mock.Anything, mock.Anything, mock.Anything, appconfig.DefaultLocationOfPending).Return()
+	tc.MdsMock.On("AcknowledgeMessage", mock.Anything, *tc.Message.MessageId).Return(fmt.Errorf("throttled"))
+	loadDocStateFromSendCommand = func(context context.T,
+		msg *ssmmds.Message,
+		messagesOrchestrationRootDir string) (*contracts.DocumentState, error) {
+		return &fakeDocState, nil
+	}
+
+	// execute processMessage
+	svc.processMessage(&tc.Message)
+
+	// check expectations
+	tc.MdsMock.AssertExpectations(t)
+	tc.DocMgrMock.AssertExpectations(t)
+	tc.ProcessMock.AssertNotCalled(t, "Submit", mock.Anything)
+
+	assert.False(t, *tc.IsDocLevelResponseSent)
+}
+
+// isTracedDocState matches the document states of the type that carry the trace started for the message
+func isTracedDocState(documentType contracts.DocumentType) interface{} {
+	return mock.MatchedBy(func(docState contracts.DocumentState) bool {
+		return docState.DocumentType == documentType && docState.DocumentInformation.TraceParent != ""
+	})
+}
+
 // TestProcessMessageWithCancelCommandTopicPrefix tests processMessage with CancelCommand topic prefix
 func TestProcessMessageWithCancelCommandTopicPrefix(t *testing.T) {
 	// CancelCommand topic prefix
@@ -104,7 +149,7 @@ func TestProcessMessageWithCancelCommandTopicPrefix(t *testing.T) {
 
 	// set the expectations
 	tc.MdsMock.On("AcknowledgeMessage", mock.Anything, *tc.Message.MessageId).Return(nil)
-	tc.ProcessMock.On("Cancel", fakeCancelDocState).Return(nil)
+	tc.ProcessMock.On("Cancel", isTracedDocState(contracts.CancelCommand)).Return(nil)
 	loadDocStateFromCancelCommand = func(context context.T, msg *ssmmds.Message, messagesOrchestrationRootDir string) (*contracts.DocumentState, error) {
 		return &fakeCancelDocState, nil
 	}
@@ -202,6 +247,10 @@ func prepareTestProcessMessage(testTopic string) (svc RunCommandService, testCas
 	// create mocked processor
 	processorMock := new(processormock.MockedProcessor)
 
+	// create mocked document manager for the documents queued before acknowledging
+	docMgrMock := new(docmanagermock.DocumentMgrMock)
+	pendingDocMgr = docMgrMock
+
 	svc = RunCommandService{
 		context:              contextMock,
 		config:               agentConfig,
@@ -216,6 +265,7 @@ func prepareTestProcessMessage(testTopic string) (svc RunCommandService, testCas
 		Message:                message,
 		MdsMock:                mdsMock,
 		ProcessMock:            processorMock,
+		DocMgrMock:             docMgrMock,
 		IsDocLevelResponseSent: &isDocLevelResponseSent,
 	}
 
@@ -26,6 +26,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/framework/doccache"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
@@ -146,6 +147,12 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 	} else {
 		documentType = contracts.SendCommand
 	}
+	if documentType == contracts.SendCommand && parsedMessage.DocumentName != "" {
+		// the local commands can run the document by name once it ran here
+		if err = doccache.PutContent(log, *msg.Destination, parsedMessage.DocumentName, &parsedMessage.DocumentContent); err != nil {
+			log.Debugf("Failed to cache document %v: %v", parsedMessage.DocumentName, err)
+		}
+	}
 	documentInfo := newDocumentInfo(*msg, parsedMessage)
 	parserInfo := docparser.DocumentParserInfo{
 		OrchestrationDir: messageOrchestrationDirectory,
@@ -160,6 +167,7 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 	if err != nil {
 		return nil, err
 	}
+	docState.AttachmentsContent = parsedMessage.AttachmentsContent
 	parsedMessageContent, _ := jsonutil.Marshal(parsedMessage)
 
 	var parsedContentJson *gabs.Container
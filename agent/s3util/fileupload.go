@@ -0,0 +1,263 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package s3util contains methods for interacting with S3.
+package s3util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// UploadStateSuffix is appended to the path of a file being uploaded for the state of its upload
+const UploadStateSuffix = ".s3upload"
+
+// uploadPartSize is the size of the first parts of the files uploaded as they grow, doubled every
+// partSizeDoublingParts parts so that the parts cover the largest objects of S3
+var uploadPartSize = 8 * 1024 * 1024
+
+// partSizeDoublingParts is the number of parts uploaded before their size doubles, 8 MB parts reach 4 GB in the last
+// thousand of the 10,000 parts of an upload and cover about 8 TB
+const partSizeDoublingParts = 1000
+
+// maxUploadPartSize is the maximum size of a part of S3, the last part included
+const maxUploadPartSize = 5 * 1024 * 1024 * 1024
+
+// UploadedPart is a part of a file upload.
+type UploadedPart struct {
+	Number int64
+	ETag   string
+}
+
+// FileUpload uploads a file to S3 while it's being written, in parts of uploadPartSize read from the file as it
+// grows, so that the file is never held in memory. Its state is saved next to the file after each part, a process
+// resuming the file, or cleaning up after the process writing it crashed, continues the upload where it stopped.
+type FileUpload struct {
+	Bucket   string
+	Key      string
+	FilePath string
+	UploadID string
+	Parts    []UploadedPart
+	// Offset is the size of the file uploaded in parts
+	Offset int64
+
+	s3 s3iface.S3API
+}
+
+// NewFileUpload returns the upload of the file to the object, continuing the upload of the file started by a
+// previous process if any.
+func (u *AmazonS3Util) NewFileUpload(log log.T, bucketName string, objectKey string, filePath string) *FileUpload {
+	upload := &FileUpload{Bucket: bucketName, Key: objectKey, FilePath: filePath}
+	if saved, err := loadFileUpload(filePath + UploadStateSuffix); err == nil && saved.Bucket == bucketName && saved.Key == objectKey {
+		log.Infof("Resuming the upload of %v to s3://%v/%v after %v parts", filePath, bucketName, objectKey, len(saved.Parts))
+		upload = saved
+	}
+	upload.s3 = u.myUploader.S3
+	return upload
+}
+
+// loadFileUpload reads the state of an upload
+func loadFileUpload(statePath string) (*FileUpload, error) {
+	content, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		return nil, err
+	}
+	var upload FileUpload
+	if err = json.Unmarshal(content, &upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// save writes the state of the upload next to the file
+func (upload *FileUpload) save() error {
+	content, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(upload.FilePath+UploadStateSuffix, content, appconfig.ReadWriteAccess)
+}
+
+// UploadParts uploads the parts of the file written since the last part.
+func (upload *FileUpload) UploadParts(log log.T) error {
+	info, err := os.Stat(upload.FilePath)
+	if err != nil {
+		return err
+	}
+	// the last part is left for Complete, whatever the size of the rest of the file
+	for info.Size()-upload.Offset >= upload.partSize() && len(upload.Parts) < s3manager.MaxUploadParts-1 {
+		partSize := upload.partSize()
+		if upload.UploadID == "" {
+			created, err := upload.s3.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+				Bucket:      aws.String(upload.Bucket),
+				Key:         aws.String(upload.Key),
+				ContentType: aws.String("text/plain"),
+			})
+			if err != nil {
+				return err
+			}
+			upload.UploadID = aws.StringValue(created.UploadId)
+			log.Infof("Uploading %v to s3://%v/%v as it's written", upload.FilePath, upload.Bucket, upload.Key)
+		}
+		if err = upload.uploadPart(upload.Offset, partSize); err != nil {
+			return err
+		}
+		upload.Offset += partSize
+		if err = upload.save(); err != nil {
+			log.Warnf("failed to save the state of the upload of %v: %v", upload.FilePath, err)
+		}
+	}
+	return nil
+}
+
+// partSize returns the size of the next part
+func (upload *FileUpload) partSize() int64 {
+	return int64(uploadPartSize) << uint(len(upload.Parts)/partSizeDoublingParts)
+}
+
+// uploadPart uploads the part of the file at the offset as the next part
+func (upload *FileUpload) uploadPart(offset int64, size int64) error {
+	file, err := os.Open(upload.FilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	number := int64(len(upload.Parts) + 1)
+	uploaded, err := upload.s3.UploadPart(&s3.UploadPartInput{
+		Bucket:     aws.String(upload.Bucket),
+		Key:        aws.String(upload.Key),
+		UploadId:   aws.String(upload.UploadID),
+		PartNumber: aws.Int64(number),
+		Body:       io.NewSectionReader(file, offset, size),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload part %v of %v: %v", number, upload.FilePath, err)
+	}
+	upload.Parts = append(upload.Parts, UploadedPart{Number: number, ETag: aws.StringValue(uploaded.ETag)})
+	return nil
+}
+
+// Complete uploads the rest of the file and completes the upload, or uploads the file in one request if it's
+// smaller than a part. The upload is aborted if it can't be completed.
+func (upload *FileUpload) Complete(log log.T) (err error) {
+	defer os.Remove(upload.FilePath + UploadStateSuffix)
+	if err = upload.UploadParts(log); err != nil {
+		upload.abort(log)
+		return err
+	}
+	info, err := os.Stat(upload.FilePath)
+	if err != nil {
+		upload.abort(log)
+		return err
+	}
+
+	if upload.UploadID == "" {
+		file, err := os.Open(upload.FilePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		log.Infof("Uploading %v to s3://%v/%v", upload.FilePath, upload.Bucket, upload.Key)
+		if _, err = upload.s3.PutObject(&s3.PutObjectInput{
+			Bucket:      aws.String(upload.Bucket),
+			Key:         aws.String(upload.Key),
+			Body:        file,
+			ContentType: aws.String("text/plain"),
+		}); err != nil {
+			return err
+		}
+	} else {
+		if info.Size() > upload.Offset {
+			if info.Size()-upload.Offset > maxUploadPartSize {
+				upload.abort(log)
+				return fmt.Errorf("%v exceeds the maximum size of an object of S3", upload.FilePath)
+			}
+			if err = upload.uploadPart(upload.Offset, info.Size()-upload.Offset); err != nil {
+				upload.abort(log)
+				return err
+			}
+			upload.Offset = info.Size()
+		}
+		parts := []*s3.CompletedPart{}
+		for _, part := range upload.Parts {
+			parts = append(parts, &s3.CompletedPart{PartNumber: aws.Int64(part.Number), ETag: aws.String(part.ETag)})
+		}
+		if _, err = upload.s3.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(upload.Bucket),
+			Key:             aws.String(upload.Key),
+			UploadId:        aws.String(upload.UploadID),
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+		}); err != nil {
+			upload.abort(log)
+			return err
+		}
+	}
+	log.Infof("Successfully uploaded %v to s3://%v/%v", upload.FilePath, upload.Bucket, upload.Key)
+	if _, aclErr := upload.s3.PutObjectAcl(&s3.PutObjectAclInput{
+		Bucket: aws.String(upload.Bucket),
+		Key:    aws.String(upload.Key),
+		ACL:    aws.String("bucket-owner-full-control"),
+	}); aclErr != nil {
+		// gracefully ignore the error, since the S3 putAcl policy may not be set
+		log.Debugf("PutAcl: bucket-owner-full-control failed, error: %v", aclErr)
+	}
+	return nil
+}
+
+// abort aborts the multipart upload, so that its parts aren't kept by S3
+func (upload *FileUpload) abort(log log.T) {
+	if upload.UploadID == "" {
+		return
+	}
+	if _, err := upload.s3.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(upload.Bucket),
+		Key:      aws.String(upload.Key),
+		UploadId: aws.String(upload.UploadID),
+	}); err != nil {
+		log.Warnf("failed to abort the upload of %v: %v", upload.FilePath, err)
+	}
+}
+
+// CompletePendingUploads completes the uploads of the files under the folder left by a process that exited
+// before completing them, with the output the files have.
+func CompletePendingUploads(log log.T, dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, UploadStateSuffix) {
+			return nil
+		}
+		saved, err := loadFileUpload(path)
+		if err != nil {
+			log.Warnf("failed to read the pending upload %v: %v", path, err)
+			os.Remove(path)
+			return nil
+		}
+		log.Infof("Completing the pending upload of %v", saved.FilePath)
+		upload := NewAmazonS3Util(log, saved.Bucket).NewFileUpload(log, saved.Bucket, saved.Key, saved.FilePath)
+		if err = upload.Complete(log); err != nil {
+			log.Errorf("Failed to complete the upload of %v to s3: %v", saved.FilePath, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,161 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package s3util
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 records the parts and objects uploaded
+type fakeS3 struct {
+	s3iface.S3API
+	parts     []string
+	completed []int64
+	objects   map[string]string
+	aborted   bool
+	failPart  bool
+}
+
+func (f *fakeS3) CreateMultipartUpload(input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+}
+
+func (f *fakeS3) UploadPart(input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	if f.failPart {
+		return nil, errors.New("connection reset")
+	}
+	content, _ := ioutil.ReadAll(input.Body)
+	f.parts = append(f.parts, string(content))
+	return &s3.UploadPartOutput{ETag: aws.String(fmt.Sprintf("etag-%v", *input.PartNumber))}, nil
+}
+
+func (f *fakeS3) CompleteMultipartUpload(input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	for _, part := range input.MultipartUpload.Parts {
+		f.completed = append(f.completed, *part.PartNumber)
+	}
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (f *fakeS3) AbortMultipartUpload(input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	f.aborted = true
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (f *fakeS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	content, _ := ioutil.ReadAll(input.Body)
+	f.objects[*input.Key] = string(content)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) PutObjectAcl(input *s3.PutObjectAclInput) (*s3.PutObjectAclOutput, error) {
+	return &s3.PutObjectAclOutput{}, nil
+}
+
+func newTestFileUpload(t *testing.T, content string) (*FileUpload, *fakeS3, func()) {
+	dir, err := ioutil.TempDir("", "fileupload")
+	assert.NoError(t, err)
+	filePath := filepath.Join(dir, "stdout")
+	assert.NoError(t, ioutil.WriteFile(filePath, []byte(content), 0600))
+	origPartSize := uploadPartSize
+	uploadPartSize = 4
+	fake := &fakeS3{objects: map[string]string{}}
+	upload := &FileUpload{Bucket: "bucket", Key: "prefix/stdout", FilePath: filePath, s3: fake}
+	return upload, fake, func() {
+		uploadPartSize = origPartSize
+		os.RemoveAll(dir)
+	}
+}
+
+func TestFileUploadUploadsPartsAsTheFileGrows(t *testing.T) {
+	upload, fake, cleanup := newTestFileUpload(t, "abcdefghij")
+	defer cleanup()
+
+	assert.NoError(t, upload.UploadParts(log.NewMockLog()))
+	assert.Equal(t, []string{"abcd", "efgh"}, fake.parts)
+	assert.Equal(t, int64(8), upload.Offset)
+
+	// the state is saved after each part, for the process continuing the upload
+	saved, err := loadFileUpload(upload.FilePath + UploadStateSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, "upload-1", saved.UploadID)
+	assert.Len(t, saved.Parts, 2)
+
+	assert.NoError(t, upload.Complete(log.NewMockLog()))
+	assert.Equal(t, []string{"abcd", "efgh", "ij"}, fake.parts)
+	assert.Equal(t, []int64{1, 2, 3}, fake.completed)
+	_, err = os.Stat(upload.FilePath + UploadStateSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileUploadPutsSmallFiles(t *testing.T) {
+	upload, fake, cleanup := newTestFileUpload(t, "abc")
+	defer cleanup()
+
+	assert.NoError(t, upload.Complete(log.NewMockLog()))
+	assert.Empty(t, fake.parts)
+	assert.Equal(t, "abc", fake.objects["prefix/stdout"])
+}
+
+func TestFileUploadAbortsOnFailure(t *testing.T) {
+	upload, fake, cleanup := newTestFileUpload(t, "abcdefghij")
+	defer cleanup()
+	assert.NoError(t, upload.UploadParts(log.NewMockLog()))
+
+	fake.failPart = true
+	assert.Error(t, upload.Complete(log.NewMockLog()))
+	assert.True(t, fake.aborted)
+	assert.Empty(t, fake.completed)
+}
+
+func TestNewFileUploadResumes(t *testing.T) {
+	upload, fake, cleanup := newTestFileUpload(t, "abcdefghij")
+	defer cleanup()
+	assert.NoError(t, upload.UploadParts(log.NewMockLog()))
+
+	// a new process continues the upload after the parts already uploaded
+	util := &AmazonS3Util{myUploader: &s3manager.Uploader{S3: fake}}
+	resumed := util.NewFileUpload(log.NewMockLog(), "bucket", "prefix/stdout", upload.FilePath)
+	assert.Equal(t, int64(8), resumed.Offset)
+	assert.NoError(t, resumed.Complete(log.NewMockLog()))
+	assert.Equal(t, []string{"abcd", "efgh", "ij"}, fake.parts)
+	assert.Equal(t, []int64{1, 2, 3}, fake.completed)
+}
+
+func TestFileUploadPartSizeDoubles(t *testing.T) {
+	upload, fake, cleanup := newTestFileUpload(t, "abcdefghij")
+	defer cleanup()
+
+	// past the first thousand parts the parts are twice as large
+	upload.UploadID = "upload-1"
+	upload.Parts = make([]UploadedPart, partSizeDoublingParts)
+	assert.NoError(t, upload.UploadParts(log.NewMockLog()))
+	assert.Equal(t, []string{"abcdefgh"}, fake.parts)
+	assert.Equal(t, int64(8), upload.Offset)
+
+	upload.Parts = make([]UploadedPart, 3*partSizeDoublingParts)
+	assert.Equal(t, int64(32), upload.partSize())
+	assert.Equal(t, int64(4<<9), (&FileUpload{Parts: make([]UploadedPart, s3manager.MaxUploadParts-1)}).partSize())
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sdkutil
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+const (
+	// kmsDecryptAttempts is the number of times a ciphertext is decrypted before giving up, as KMS or the credentials
+	// of the agent may not be reachable yet when the agent starts
+	kmsDecryptAttempts = 6
+
+	// kmsRetryInitialDelay is the delay before the second attempt, doubled after each attempt
+	kmsRetryInitialDelay = 2 * time.Second
+)
+
+// kmsPermanentErrors are the errors of KMS the next attempts would fail with too
+var kmsPermanentErrors = map[string]bool{
+	"AccessDeniedException":               true,
+	kms.ErrCodeDisabledException:          true,
+	kms.ErrCodeInvalidCiphertextException: true,
+	kms.ErrCodeInvalidGrantTokenException: true,
+	kms.ErrCodeInvalidKeyUsageException:   true,
+	kms.ErrCodeNotFoundException:          true,
+}
+
+// kmsRetrySleep waits between the attempts
+var kmsRetrySleep = time.Sleep
+
+func init() {
+	appconfig.RegisterDecrypter(appconfig.EncryptionSchemeKMS, decryptKMS)
+}
+
+// newKMSClient returns the KMS client decrypting the encrypted:kms: settings. Its region comes from a load of the
+// configuration of its own, as the configuration cached may not be decrypted yet.
+var newKMSClient = func() kmsiface.KMSAPI {
+	awsConfig := AwsConfig()
+	if region := appconfig.UndecryptedConfig().Agent.Region; region != "" {
+		awsConfig.Region = aws.String(region)
+	}
+	return kms.New(session.New(awsConfig))
+}
+
+// decryptKMS decrypts a ciphertext blob of the KMS Encrypt API with the credentials of the agent, retrying with an
+// exponential backoff unless KMS refuses the ciphertext.
+func decryptKMS(ciphertext []byte) ([]byte, error) {
+	delay := kmsRetryInitialDelay
+	for attempt := 1; ; attempt++ {
+		output, err := newKMSClient().Decrypt(&kms.DecryptInput{CiphertextBlob: ciphertext})
+		if err == nil {
+			return output.Plaintext, nil
+		}
+		if awsErr, ok := err.(awserr.Error); ok && kmsPermanentErrors[awsErr.Code()] {
+			return nil, err
+		}
+		if attempt == kmsDecryptAttempts {
+			return nil, fmt.Errorf("%v, after %v attempts", err, attempt)
+		}
+		fmt.Printf("Failed to decrypt a setting with KMS, retrying in %v: %v\n", delay, err)
+		kmsRetrySleep(delay)
+		delay *= 2
+	}
+}
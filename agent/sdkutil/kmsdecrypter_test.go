@@ -0,0 +1,94 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sdkutil
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/stretchr/testify/assert"
+)
+
+type kmsMock struct {
+	kmsiface.KMSAPI
+	input  *kms.DecryptInput
+	output *kms.DecryptOutput
+	err    error
+	// failures is the number of attempts failing with err before the output is returned, all of them when 0
+	failures int
+	calls    int
+}
+
+func (m *kmsMock) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	m.input = input
+	m.calls++
+	if m.err != nil && (m.failures == 0 || m.calls <= m.failures) {
+		return nil, m.err
+	}
+	return m.output, nil
+}
+
+// withKMSMock runs the test with the KMS mock, recording the delays between the attempts in slept
+func withKMSMock(mock *kmsMock, slept *[]time.Duration, test func()) {
+	saved, savedSleep := newKMSClient, kmsRetrySleep
+	defer func() { newKMSClient, kmsRetrySleep = saved, savedSleep }()
+	newKMSClient = func() kmsiface.KMSAPI { return mock }
+	kmsRetrySleep = func(delay time.Duration) { *slept = append(*slept, delay) }
+	test()
+}
+
+func TestDecryptKMS(t *testing.T) {
+	mock := &kmsMock{output: &kms.DecryptOutput{Plaintext: []byte("secret")}}
+	var slept []time.Duration
+	withKMSMock(mock, &slept, func() {
+		plaintext, err := decryptKMS([]byte("blob"))
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", string(plaintext))
+		assert.Equal(t, []byte("blob"), mock.input.CiphertextBlob)
+	})
+}
+
+func TestDecryptKMSError(t *testing.T) {
+	mock := &kmsMock{err: awserr.New("AccessDeniedException", "not authorized to perform kms:Decrypt", nil)}
+	var slept []time.Duration
+	withKMSMock(mock, &slept, func() {
+		_, err := decryptKMS([]byte("blob"))
+		assert.Error(t, err)
+		assert.Equal(t, 1, mock.calls, "the errors of the ciphertext or the key aren't retried")
+	})
+}
+
+func TestDecryptKMSRetries(t *testing.T) {
+	var slept []time.Duration
+	mock := &kmsMock{err: errors.New("dial tcp: connection refused"), failures: 2, output: &kms.DecryptOutput{Plaintext: []byte("secret")}}
+	withKMSMock(mock, &slept, func() {
+		plaintext, err := decryptKMS([]byte("blob"))
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", string(plaintext))
+		assert.Equal(t, 3, mock.calls)
+	})
+
+	slept = nil
+	mock = &kmsMock{err: errors.New("dial tcp: connection refused")}
+	withKMSMock(mock, &slept, func() {
+		_, err := decryptKMS([]byte("blob"))
+		assert.Error(t, err)
+		assert.Equal(t, kmsDecryptAttempts, mock.calls)
+		assert.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second}, slept)
+	})
+}
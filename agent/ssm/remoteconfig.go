@@ -0,0 +1,38 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ssm
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func init() {
+	appconfig.RegisterRemoteSource(getRemoteConfig)
+}
+
+// getRemoteConfig returns the decrypted value of the parameter holding the remote agent configuration.
+func getRemoteConfig(parameter string) (string, error) {
+	response, err := NewService().GetDecryptedParameters(log.Logger(), []string{parameter})
+	if err != nil {
+		return "", err
+	}
+	if len(response.Parameters) == 0 {
+		return "", fmt.Errorf("parameter %v not found", parameter)
+	}
+	return aws.StringValue(response.Parameters[0].Value), nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmparameterresolver contains types and methods for resolving SSM Parameter references.
+package ssmparameterresolver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// cachedParameter is a parameter of the cache, until it expires
+type cachedParameter struct {
+	info    SsmParameterInfo
+	expires time.Time
+}
+
+// CachingSsmParameterService keeps the parameters a service returns in memory for a short time, so that the steps,
+// retries and iterations of a document referring to the same parameters don't fetch and decrypt them each time.
+// The cache is never written to disk.
+type CachingSsmParameterService struct {
+	ISsmParameterService
+	service ISsmParameterService
+	ttl     time.Duration
+	mutex   sync.Mutex
+	cache   map[string]cachedParameter
+}
+
+// NewCachingService returns a service caching the parameters of the service for ttl.
+func NewCachingService(service ISsmParameterService, ttl time.Duration) *CachingSsmParameterService {
+	return &CachingSsmParameterService{
+		service: service,
+		ttl:     ttl,
+		cache:   make(map[string]cachedParameter),
+	}
+}
+
+func (s *CachingSsmParameterService) getParameters(
+	log log.T,
+	parameterReferences []string) (map[string]SsmParameterInfo, error) {
+
+	result := make(map[string]SsmParameterInfo)
+	missing := []string{}
+	now := time.Now()
+	s.mutex.Lock()
+	for reference, parameter := range s.cache {
+		if !now.Before(parameter.expires) {
+			delete(s.cache, reference)
+		}
+	}
+	for _, reference := range parameterReferences {
+		if parameter, found := s.cache[reference]; found {
+			result[reference] = parameter.info
+		} else {
+			missing = append(missing, reference)
+		}
+	}
+	s.mutex.Unlock()
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	log.Debugf("Fetching %v parameters not cached", len(missing))
+	fetched, err := s.service.getParameters(log, missing)
+	if err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for reference, info := range fetched {
+		s.cache[reference] = cachedParameter{info: info, expires: now.Add(s.ttl)}
+		result[reference] = info
+	}
+	return result, nil
+}
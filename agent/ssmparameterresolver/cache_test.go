@@ -0,0 +1,67 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmparameterresolver contains types and methods for resolving SSM Parameter references.
+package ssmparameterresolver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingService records the references each call fetches
+type countingService struct {
+	ServiceMockedObjectWithRecords
+	fetched [][]string
+}
+
+func (m *countingService) getParameters(log log.T, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	m.fetched = append(m.fetched, append([]string{}, parameterReferences...))
+	return m.ServiceMockedObjectWithRecords.getParameters(log, parameterReferences)
+}
+
+func TestCachingServiceFetchesOnlyMissingParameters(t *testing.T) {
+	records := map[string]SsmParameterInfo{
+		"ssm-secure:db-password": {Name: "db-password", Type: secureStringType, Value: "value_db-password"},
+		"ssm-secure:api-key":     {Name: "api-key", Type: secureStringType, Value: "value_api-key"},
+	}
+	service := &countingService{ServiceMockedObjectWithRecords: newServiceMockedObjectWithExtraRecords(records)}
+	cache := NewCachingService(service, time.Minute)
+
+	first, err := ResolveParameterReferenceList(cache, log.NewMockLog(), []string{"ssm-secure:db-password"}, ResolveOptions{})
+	assert.NoError(t, err)
+	second, err := ResolveParameterReferenceList(cache, log.NewMockLog(), []string{"ssm-secure:db-password", "ssm-secure:api-key"}, ResolveOptions{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, records["ssm-secure:db-password"], first["ssm-secure:db-password"])
+	assert.Equal(t, records, second)
+	assert.Equal(t, [][]string{{"ssm-secure:db-password"}, {"ssm-secure:api-key"}}, service.fetched)
+}
+
+func TestCachingServiceExpires(t *testing.T) {
+	records := map[string]SsmParameterInfo{
+		"ssm-secure:db-password": {Name: "db-password", Type: secureStringType, Value: "value_db-password"},
+	}
+	service := &countingService{ServiceMockedObjectWithRecords: newServiceMockedObjectWithExtraRecords(records)}
+	cache := NewCachingService(service, 0)
+
+	for i := 0; i < 2; i++ {
+		_, err := ResolveParameterReferenceList(cache, log.NewMockLog(), []string{"ssm-secure:db-password"}, ResolveOptions{})
+		assert.NoError(t, err)
+	}
+
+	assert.Len(t, service.fetched, 2)
+}
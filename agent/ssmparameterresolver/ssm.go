@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/log/redact"
 	"github.com/aws/amazon-ssm-agent/agent/ssm"
 )
 
@@ -68,6 +69,10 @@ func (s *SsmParameterService) getParameters(
 	resolvedParametersMap := map[string]SsmParameterInfo{}
 	for i := 0; i < len(parametersOutput.Parameters); i++ {
 		param := parametersOutput.Parameters[i]
+		if *param.Type == secureStringType {
+			// the decrypted value must never reach the logs of the process
+			redact.AddSecret(*param.Value)
+		}
 		resolvedParametersMap[ref2NameMapper[*param.Name]] = SsmParameterInfo{
 			Name:  *param.Name,
 			Type:  *param.Type,
@@ -32,6 +32,44 @@ const (
 	ShutDown State = 3
 )
 
+// CancelReason describes why cancellation or ShutDown has been requested for a job.
+type CancelReason string
+
+const (
+	// CancelReasonNone indicates a job for which no cancellation has been requested.
+	CancelReasonNone CancelReason = ""
+
+	// CancelReasonUserRequest indicates a job canceled on request of the user, e.g. by a CancelCommand.
+	CancelReasonUserRequest CancelReason = "UserRequest"
+
+	// CancelReasonTimeout indicates a job canceled because it exceeded its execution timeout.
+	CancelReasonTimeout CancelReason = "Timeout"
+
+	// CancelReasonStepTimeout indicates a step canceled because it exceeded its own timeout.
+	CancelReasonStepTimeout CancelReason = "StepTimeout"
+
+	// CancelReasonParentTimeout indicates a sub-document canceled because the step running it timed out.
+	CancelReasonParentTimeout CancelReason = "ParentTimeout"
+
+	// CancelReasonAgentShutdown indicates a job interrupted because the agent is stopping.
+	CancelReasonAgentShutdown CancelReason = "AgentShutdown"
+
+	// CancelReasonReboot indicates a job interrupted because the agent is stopping to reboot the instance.
+	CancelReasonReboot CancelReason = "Reboot"
+)
+
+// defaultCancelReason returns the reason assumed when a flag is set without one.
+func defaultCancelReason(state State) CancelReason {
+	switch state {
+	case Canceled:
+		return CancelReasonUserRequest
+	case ShutDown:
+		return CancelReasonAgentShutdown
+	default:
+		return CancelReasonNone
+	}
+}
+
 // CancelFlag is an object that is passed to any job submitted to a task in order to
 // communicated job cancellation. Job cancellation has to be cooperative.
 type CancelFlag interface {
@@ -40,8 +78,16 @@ type CancelFlag interface {
 	Canceled() bool
 
 	// Set sets the state of this flag and wakes up waiting callers.
+	// The reason is defaulted based on the state, see SetWithReason.
 	Set(state State)
 
+	// SetWithReason sets the state of this flag along with the reason it was set, and wakes up waiting callers.
+	SetWithReason(state State, reason CancelReason)
+
+	// Reason returns why cancellation or ShutDown has been requested, CancelReasonNone otherwise.
+	// Executers and plugins can include it in the result of a job that has been interrupted.
+	Reason() CancelReason
+
 	// ShutDown returns true if a ShutDown has been requested, false otherwise.
 	// This method should be called periodically in the job.
 	ShutDown() bool
@@ -63,6 +109,7 @@ type CancelFlag interface {
 // ChanneledCancelFlag is a default implementation of the task.CancelFlag interface.
 type ChanneledCancelFlag struct {
 	state  State
+	reason CancelReason
 	ch     chan struct{}
 	closed bool
 	m      sync.RWMutex
@@ -95,17 +142,49 @@ func (t *ChanneledCancelFlag) State() State {
 	return t.state
 }
 
+// Reason returns why this flag has been set to Cancel or ShutDown state.
+func (t *ChanneledCancelFlag) Reason() CancelReason {
+	t.m.RLock()
+	defer t.m.RUnlock()
+	return t.reason
+}
+
 // Wait blocks until the flag is set to either Cancel or Completed state. Returns the state.
 func (t *ChanneledCancelFlag) Wait() (state State) {
 	<-t.ch
 	return t.State()
 }
 
+// Done returns a channel closed once the flag is set, which can be selected on along with other channels.
+func (t *ChanneledCancelFlag) Done() <-chan struct{} {
+	return t.ch
+}
+
+// Done returns a channel closed once the flag is set, see Wait. The flags without a Done method, e.g. the mocks,
+// are waited for by a goroutine, which returns once they are set.
+func Done(flag CancelFlag) <-chan struct{} {
+	if channeled, ok := flag.(interface{ Done() <-chan struct{} }); ok {
+		return channeled.Done()
+	}
+	done := make(chan struct{})
+	go func() {
+		flag.Wait()
+		close(done)
+	}()
+	return done
+}
+
 // Set sets the state of this flag and wakes up waiting callers.
 func (t *ChanneledCancelFlag) Set(state State) {
+	t.SetWithReason(state, defaultCancelReason(state))
+}
+
+// SetWithReason sets the state of this flag along with the reason and wakes up waiting callers.
+func (t *ChanneledCancelFlag) SetWithReason(state State, reason CancelReason) {
 	t.m.Lock()
 	defer t.m.Unlock()
 	t.state = state
+	t.reason = reason
 
 	// close channel to wake up routines that are waiting
 	if !t.closed {
@@ -38,6 +38,25 @@ func TestCanceled(t *testing.T) {
 
 }
 
+// TestCancelReason tests that the reason is defaulted from the state unless it is given explicitly
+func TestCancelReason(t *testing.T) {
+	cancelFlag := NewChanneledCancelFlag()
+	assert.Equal(t, CancelReasonNone, cancelFlag.Reason())
+
+	cancelFlag.Set(Canceled)
+	assert.Equal(t, CancelReasonUserRequest, cancelFlag.Reason())
+
+	cancelFlag.Set(ShutDown)
+	assert.Equal(t, CancelReasonAgentShutdown, cancelFlag.Reason())
+
+	cancelFlag.SetWithReason(ShutDown, CancelReasonReboot)
+	assert.True(t, cancelFlag.ShutDown())
+	assert.Equal(t, CancelReasonReboot, cancelFlag.Reason())
+
+	cancelFlag.Set(Completed)
+	assert.Equal(t, CancelReasonNone, cancelFlag.Reason())
+}
+
 // TestWait tests that the Wait method blocks the caller and returns the
 // correct state once unblocked
 func TestWait(t *testing.T) {
@@ -67,3 +86,18 @@ func testWait(t *testing.T, state State) {
 	assert.Equal(t, state, <-ch)
 	assert.Equal(t, flag.Canceled(), state == Canceled)
 }
+
+// TestDone tests that the channel of Done is closed once the flag is set
+func TestDone(t *testing.T) {
+	flag := NewChanneledCancelFlag()
+	done := Done(flag)
+	select {
+	case <-done:
+		assert.Fail(t, "the flag is not set")
+	default:
+	}
+
+	flag.Set(Completed)
+	<-done
+	assert.Equal(t, Completed, flag.State())
+}
@@ -0,0 +1,182 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sync"
+)
+
+// JobCategory is the kind of work a job does. Queued jobs are handed to the workers
+// in proportion to the weight of their category, so that a burst of jobs of one
+// category can't starve the others.
+type JobCategory string
+
+const (
+	// CategoryDefault is the category of the jobs submitted without one.
+	CategoryDefault JobCategory = "Default"
+
+	// CategoryRunCommand is the category of interactive Run Command documents.
+	CategoryRunCommand JobCategory = "RunCommand"
+
+	// CategoryAssociation is the category of State Manager association documents.
+	CategoryAssociation JobCategory = "Association"
+
+	// CategoryInventory is the category of inventory gathering documents.
+	CategoryInventory JobCategory = "Inventory"
+)
+
+// DefaultCategoryWeights favors the interactive categories over the background ones.
+// A category that isn't listed has a weight of 1.
+var DefaultCategoryWeights = map[JobCategory]int{
+	CategoryDefault:     2,
+	CategoryRunCommand:  4,
+	CategoryAssociation: 2,
+	CategoryInventory:   1,
+}
+
+// maxQueuedJobsPerCategory is the number of jobs of a category waiting for a worker, the submitters of that
+// category block beyond it like they did when the jobs were handed to the workers directly.
+const maxQueuedJobsPerCategory = 10
+
+// CategoryWeights returns the default weights overridden by the given ones, by category name.
+func CategoryWeights(overrides map[string]int) map[JobCategory]int {
+	weights := make(map[JobCategory]int, len(DefaultCategoryWeights)+len(overrides))
+	for category, weight := range DefaultCategoryWeights {
+		weights[category] = weight
+	}
+	for category, weight := range overrides {
+		weights[JobCategory(category)] = weight
+	}
+	return weights
+}
+
+// fairQueue holds the jobs waiting for a worker, one bounded FIFO per category.
+// It implements stride scheduling: every category has a pass that advances by the
+// inverse of its weight each time one of its jobs is dispatched, and the next job
+// is taken from the waiting category with the lowest pass.
+type fairQueue struct {
+	m sync.Mutex
+	// ready is signaled when a job is queued, space when a job is taken
+	ready   *sync.Cond
+	space   *sync.Cond
+	limit   int
+	weights map[JobCategory]int
+	queues  map[JobCategory][]JobToken
+	pass    map[JobCategory]float64
+	// virtualTime is the pass of the last dispatched job, idle categories catch up to it
+	// so they don't build up credit while they have nothing to run
+	virtualTime float64
+	closed      bool
+	// retiring is the number of workers to let go, they are handed a retirement before any job
+	retiring int
+}
+
+// newFairQueue creates a queue scheduling the categories according to the given weights, holding up to limit
+// jobs of each category.
+func newFairQueue(weights map[JobCategory]int, limit int) *fairQueue {
+	q := &fairQueue{
+		limit:   limit,
+		weights: weights,
+		queues:  make(map[JobCategory][]JobToken),
+		pass:    make(map[JobCategory]float64),
+	}
+	q.ready = sync.NewCond(&q.m)
+	q.space = sync.NewCond(&q.m)
+	return q
+}
+
+// weight returns the weight of the given category.
+func (q *fairQueue) weight(category JobCategory) int {
+	if weight, found := q.weights[category]; found && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// push queues a job, blocking while its category is full. Returns an error if the queue has been closed.
+func (q *fairQueue) push(token JobToken) error {
+	q.m.Lock()
+	defer q.m.Unlock()
+	category := token.category
+	for !q.closed && len(q.queues[category]) >= q.limit {
+		q.space.Wait()
+	}
+	if q.closed {
+		return fmt.Errorf("pool is shut down, job %v rejected", token.id)
+	}
+	if len(q.queues[category]) == 0 && q.pass[category] < q.virtualTime {
+		q.pass[category] = q.virtualTime
+	}
+	q.queues[category] = append(q.queues[category], token)
+	q.ready.Signal()
+	return nil
+}
+
+// pop blocks until a job is available and returns the next job to run according to the weights.
+// Returns false once the queue has been closed and all the remaining jobs have been taken.
+func (q *fairQueue) pop() (token JobToken, ok bool) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	for {
+		if q.retiring > 0 {
+			q.retiring--
+			return JobToken{retire: true}, true
+		}
+		if category, found := q.nextCategory(); found {
+			token = q.queues[category][0]
+			q.queues[category] = q.queues[category][1:]
+			q.virtualTime = q.pass[category]
+			q.pass[category] += 1 / float64(q.weight(category))
+			// the submitters of other categories may be waiting too, wake them all to recheck
+			q.space.Broadcast()
+			return token, true
+		}
+		if q.closed {
+			return JobToken{}, false
+		}
+		q.ready.Wait()
+	}
+}
+
+// nextCategory returns the waiting category with the lowest pass, ties are broken by name.
+func (q *fairQueue) nextCategory() (next JobCategory, found bool) {
+	for category, jobs := range q.queues {
+		if len(jobs) == 0 {
+			continue
+		}
+		if !found || q.pass[category] < q.pass[next] || (q.pass[category] == q.pass[next] && category < next) {
+			next = category
+			found = true
+		}
+	}
+	return
+}
+
+// close wakes up the waiting workers and rejects the waiting submitters, the jobs already queued can still be taken.
+func (q *fairQueue) close() {
+	q.m.Lock()
+	defer q.m.Unlock()
+	q.closed = true
+	q.ready.Broadcast()
+	q.space.Broadcast()
+}
+
+// retire lets n of the workers go, the workers busy with a job are let go once they are done with it.
+func (q *fairQueue) retire(n int) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	q.retiring += n
+	q.ready.Broadcast()
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pushJobs(t *testing.T, q *fairQueue, category JobCategory, n int) {
+	for i := 0; i < n; i++ {
+		assert.NoError(t, q.push(JobToken{id: fmt.Sprintf("%v-%d", category, i), category: category}))
+	}
+}
+
+func popCategories(q *fairQueue, n int) (categories []JobCategory) {
+	for i := 0; i < n; i++ {
+		token, _ := q.pop()
+		categories = append(categories, token.category)
+	}
+	return
+}
+
+func TestFairQueueWeights(t *testing.T) {
+	q := newFairQueue(map[JobCategory]int{CategoryRunCommand: 3, CategoryInventory: 1}, maxQueuedJobsPerCategory)
+	pushJobs(t, q, CategoryInventory, 10)
+	pushJobs(t, q, CategoryRunCommand, 10)
+
+	counts := make(map[JobCategory]int)
+	for _, category := range popCategories(q, 8) {
+		counts[category]++
+	}
+
+	assert.Equal(t, 6, counts[CategoryRunCommand])
+	assert.Equal(t, 2, counts[CategoryInventory])
+}
+
+func TestFairQueueKeepsOrderWithinCategory(t *testing.T) {
+	q := newFairQueue(DefaultCategoryWeights, maxQueuedJobsPerCategory)
+	pushJobs(t, q, CategoryAssociation, 3)
+
+	for i := 0; i < 3; i++ {
+		token, ok := q.pop()
+		assert.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("%v-%d", CategoryAssociation, i), token.id)
+	}
+}
+
+func TestFairQueueIdleCategoryDoesNotBuildCredit(t *testing.T) {
+	q := newFairQueue(map[JobCategory]int{CategoryRunCommand: 1, CategoryInventory: 1}, maxQueuedJobsPerCategory)
+	pushJobs(t, q, CategoryInventory, 10)
+	popCategories(q, 5)
+
+	// a category that was idle while the other one ran gets its share from now on, not a burst
+	pushJobs(t, q, CategoryRunCommand, 10)
+
+	assert.Equal(t, []JobCategory{CategoryRunCommand, CategoryInventory, CategoryRunCommand, CategoryInventory}, popCategories(q, 4))
+}
+
+func TestFairQueueClose(t *testing.T) {
+	q := newFairQueue(DefaultCategoryWeights, maxQueuedJobsPerCategory)
+	pushJobs(t, q, CategoryDefault, 1)
+	q.close()
+
+	assert.Error(t, q.push(JobToken{id: "rejected"}))
+	_, ok := q.pop()
+	assert.True(t, ok)
+	_, ok = q.pop()
+	assert.False(t, ok)
+}
+
+func TestFairQueueBlocksWhenTheCategoryIsFull(t *testing.T) {
+	q := newFairQueue(DefaultCategoryWeights, 2)
+	pushJobs(t, q, CategoryInventory, 2)
+
+	pushed := make(chan error, 1)
+	go func() { pushed <- q.push(JobToken{id: "blocked", category: CategoryInventory}) }()
+	// the other categories aren't held up by the full one
+	pushJobs(t, q, CategoryRunCommand, 1)
+	select {
+	case <-pushed:
+		assert.Fail(t, "the job was queued beyond the limit of its category")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	q.pop()
+	q.pop()
+	assert.NoError(t, <-pushed)
+}
+
+func TestFairQueueCloseRejectsTheBlockedJobs(t *testing.T) {
+	q := newFairQueue(DefaultCategoryWeights, 1)
+	pushJobs(t, q, CategoryInventory, 1)
+
+	pushed := make(chan error, 1)
+	go func() { pushed <- q.push(JobToken{id: "blocked", category: CategoryInventory}) }()
+	q.close()
+
+	assert.Error(t, <-pushed)
+}
+
+func TestCategoryWeights(t *testing.T) {
+	weights := CategoryWeights(map[string]int{"Inventory": 3, "Custom": 2})
+
+	assert.Equal(t, 3, weights[CategoryInventory])
+	assert.Equal(t, 2, weights[JobCategory("Custom")])
+	assert.Equal(t, DefaultCategoryWeights[CategoryRunCommand], weights[CategoryRunCommand])
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// PoolMetrics is a point in time snapshot of the utilization of a pool.
+type PoolMetrics struct {
+	// Name is the name the pool has been registered with
+	Name string
+	// MaxWorkers is the number of workers of the pool
+	MaxWorkers int
+	// ActiveWorkers is the number of workers currently running a job
+	ActiveWorkers int
+	// QueueDepth is the number of submitted jobs waiting for a free worker
+	QueueDepth int
+	// JobsSubmitted is the total number of jobs submitted to the pool
+	JobsSubmitted int64
+	// JobsCompleted is the total number of jobs that finished running
+	JobsCompleted int64
+	// JobsDiscarded is the total number of jobs canceled before they were started
+	JobsDiscarded int64
+	// AverageWaitTime is the average time a started job waited for a free worker
+	AverageWaitTime time.Duration
+	// MaxWaitTime is the longest time a started job waited for a free worker
+	MaxWaitTime time.Duration
+	// AverageJobDuration is the average running time of completed jobs
+	AverageJobDuration time.Duration
+	// MaxJobDuration is the longest running time of completed jobs
+	MaxJobDuration time.Duration
+}
+
+// poolMetrics accumulates the utilization counters of a pool.
+type poolMetrics struct {
+	m             sync.Mutex
+	active        int
+	submitted     int64
+	started       int64
+	completed     int64
+	discarded     int64
+	totalWait     time.Duration
+	maxWait       time.Duration
+	totalDuration time.Duration
+	maxDuration   time.Duration
+}
+
+// jobSubmitted records a job entering the queue.
+func (pm *poolMetrics) jobSubmitted() {
+	pm.m.Lock()
+	defer pm.m.Unlock()
+	pm.submitted++
+}
+
+// jobDiscarded records a queued job that was canceled before a worker picked it up.
+func (pm *poolMetrics) jobDiscarded() {
+	pm.m.Lock()
+	defer pm.m.Unlock()
+	pm.discarded++
+}
+
+// jobStarted records a worker picking up a job after it waited in the queue for the given time.
+func (pm *poolMetrics) jobStarted(wait time.Duration) {
+	pm.m.Lock()
+	defer pm.m.Unlock()
+	pm.started++
+	pm.active++
+	pm.totalWait += wait
+	if wait > pm.maxWait {
+		pm.maxWait = wait
+	}
+}
+
+// jobFinished records a worker finishing a job that ran for the given time.
+func (pm *poolMetrics) jobFinished(duration time.Duration) {
+	pm.m.Lock()
+	defer pm.m.Unlock()
+	pm.active--
+	pm.completed++
+	pm.totalDuration += duration
+	if duration > pm.maxDuration {
+		pm.maxDuration = duration
+	}
+}
+
+// snapshot returns the current values of the counters.
+func (pm *poolMetrics) snapshot(maxWorkers int) (metrics PoolMetrics) {
+	pm.m.Lock()
+	defer pm.m.Unlock()
+	metrics = PoolMetrics{
+		MaxWorkers:     maxWorkers,
+		ActiveWorkers:  pm.active,
+		QueueDepth:     int(pm.submitted - pm.started - pm.discarded),
+		JobsSubmitted:  pm.submitted,
+		JobsCompleted:  pm.completed,
+		JobsDiscarded:  pm.discarded,
+		MaxWaitTime:    pm.maxWait,
+		MaxJobDuration: pm.maxDuration,
+	}
+	if pm.started > 0 {
+		metrics.AverageWaitTime = pm.totalWait / time.Duration(pm.started)
+	}
+	if pm.completed > 0 {
+		metrics.AverageJobDuration = pm.totalDuration / time.Duration(pm.completed)
+	}
+	return
+}
+
+var registeredPools = make(map[string]Pool)
+var registryLock sync.RWMutex
+
+// RegisterPool makes the metrics of the given pool available through GetPoolMetrics under the given name.
+// Registering a pool under a name that is already in use replaces the previous pool.
+func RegisterPool(name string, pool Pool) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registeredPools[name] = pool
+}
+
+// UnregisterPool removes the pool registered under the given name.
+func UnregisterPool(name string) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	delete(registeredPools, name)
+}
+
+// GetPoolMetrics returns a snapshot of the metrics of all registered pools, sorted by name.
+func GetPoolMetrics() []PoolMetrics {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	metrics := make([]PoolMetrics, 0, len(registeredPools))
+	for name, pool := range registeredPools {
+		snapshot := pool.Metrics()
+		snapshot.Name = name
+		metrics = append(metrics, snapshot)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+	return metrics
+}
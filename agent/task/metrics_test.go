@@ -0,0 +1,83 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolMetricsSnapshot(t *testing.T) {
+	var pm poolMetrics
+	pm.jobSubmitted()
+	pm.jobSubmitted()
+	pm.jobSubmitted()
+	pm.jobSubmitted()
+	pm.jobStarted(2 * time.Second)
+	pm.jobStarted(4 * time.Second)
+	pm.jobDiscarded()
+	pm.jobFinished(10 * time.Second)
+
+	metrics := pm.snapshot(5)
+
+	assert.Equal(t, 5, metrics.MaxWorkers)
+	assert.Equal(t, 1, metrics.ActiveWorkers)
+	assert.Equal(t, 1, metrics.QueueDepth)
+	assert.Equal(t, int64(4), metrics.JobsSubmitted)
+	assert.Equal(t, int64(1), metrics.JobsCompleted)
+	assert.Equal(t, int64(1), metrics.JobsDiscarded)
+	assert.Equal(t, 3*time.Second, metrics.AverageWaitTime)
+	assert.Equal(t, 4*time.Second, metrics.MaxWaitTime)
+	assert.Equal(t, 10*time.Second, metrics.AverageJobDuration)
+	assert.Equal(t, 10*time.Second, metrics.MaxJobDuration)
+}
+
+func TestPoolMetrics(t *testing.T) {
+	clock := times.NewMockedClock()
+	waitTimeout := 100 * time.Millisecond
+	clock.On("After", waitTimeout).Return(clock.AfterChannel)
+	shutdownTimeout := 10000 * time.Millisecond
+	clock.On("After", shutdownTimeout).Return(clock.AfterChannel)
+	clock.On("After", shutdownTimeout+waitTimeout).Return(clock.AfterChannel)
+
+	pool := NewPool(logger, 1, waitTimeout, clock)
+	RegisterPool("test", pool)
+	defer UnregisterPool("test")
+
+	started := make(chan bool)
+	release := make(chan bool)
+	err := pool.Submit(logger, "job", func(CancelFlag) {
+		started <- true
+		<-release
+	})
+	assert.Nil(t, err)
+	<-started
+
+	metrics := GetPoolMetrics()
+	assert.Equal(t, 1, len(metrics))
+	assert.Equal(t, "test", metrics[0].Name)
+	assert.Equal(t, 1, metrics[0].ActiveWorkers)
+	assert.Equal(t, 0, metrics[0].QueueDepth)
+	assert.Equal(t, int64(1), metrics[0].JobsSubmitted)
+
+	close(release)
+	assert.True(t, pool.ShutdownAndWait(shutdownTimeout))
+
+	snapshot := pool.Metrics()
+	assert.Equal(t, 0, snapshot.ActiveWorkers)
+	assert.Equal(t, int64(1), snapshot.JobsCompleted)
+}
@@ -28,6 +28,9 @@ type Pool interface {
 	// Returns an error if a job with the same name already exists.
 	Submit(log log.T, jobID string, job Job) error
 
+	// SubmitWithCategory is the same as Submit, the job is scheduled fairly with the jobs of the other categories.
+	SubmitWithCategory(log log.T, jobID string, category JobCategory, job Job) error
+
 	// Cancel cancels the given job. Jobs that have not started yet will never be started.
 	// Jobs that are running will have their CancelFlag set to the Canceled state.
 	// It is the responsibility of the job to terminate within a reasonable time.
@@ -43,14 +46,25 @@ type Pool interface {
 	// workers terminated before the timeout or false if the timeout expired.
 	ShutdownAndWait(timeout time.Duration) (finished bool)
 
+	// ShutdownAndWaitWithReason is the same as ShutdownAndWait, the CancelFlag of the
+	// interrupted jobs carries the given reason instead of CancelReasonAgentShutdown.
+	ShutdownAndWaitWithReason(timeout time.Duration, reason CancelReason) (finished bool)
+
 	// HasJob returns if jobStore has specified job
 	HasJob(jobID string) bool
+
+	// Metrics returns a snapshot of the queue depth, worker utilization and job timings of the pool.
+	Metrics() PoolMetrics
+
+	// Resize changes the number of workers of the pool. The running jobs are never interrupted,
+	// the workers in excess exit once they are done with their current job.
+	Resize(maxParallel int)
 }
 
 // pool implements a task pool where all jobs are managed by a root task
 type pool struct {
 	log            log.T
-	jobQueue       chan JobToken
+	jobQueue       *fairQueue
 	nWorkers       int
 	doneWorker     chan struct{}
 	isShutdown     bool
@@ -58,6 +72,9 @@ type pool struct {
 	mut            sync.Mutex
 	jobStore       *JobStore
 	cancelDuration time.Duration
+	metrics        poolMetrics
+	jobProcessor   func(JobToken)
+	jobDiscarded   func()
 }
 
 // JobToken embeds a job and its associated info
@@ -66,15 +83,25 @@ type JobToken struct {
 	job        Job
 	cancelFlag *ChanneledCancelFlag
 	log        log.T
+	submitTime time.Time
+	category   JobCategory
+	// retire tells the worker popping the token to exit
+	retire bool
 }
 
 // NewPool creates a new task pool and launches maxParallel workers.
 // The cancelWaitDuration parameter defines how long to wait for a job
 // to complete a cancellation request.
 func NewPool(log log.T, maxParallel int, cancelWaitDuration time.Duration, clock times.Clock) Pool {
+	return NewPoolWithWeights(log, maxParallel, cancelWaitDuration, clock, DefaultCategoryWeights)
+}
+
+// NewPoolWithWeights creates a new task pool like NewPool, the queued jobs are handed to the workers
+// according to the given weights of their categories.
+func NewPoolWithWeights(log log.T, maxParallel int, cancelWaitDuration time.Duration, clock times.Clock, weights map[JobCategory]int) Pool {
 	p := &pool{
 		log:            log,
-		jobQueue:       make(chan JobToken),
+		jobQueue:       newFairQueue(weights, maxQueuedJobsPerCategory),
 		nWorkers:       maxParallel,
 		doneWorker:     make(chan struct{}),
 		clock:          clock,
@@ -86,27 +113,35 @@ func NewPool(log log.T, maxParallel int, cancelWaitDuration time.Duration, clock
 	// defines the job processing function.
 	processor := func(j JobToken) {
 		defer p.jobStore.DeleteJob(j.id)
+		startTime := time.Now()
+		p.metrics.jobStarted(startTime.Sub(j.submitTime))
+		defer func() { p.metrics.jobFinished(time.Since(startTime)) }()
 		process(j.log, j.job, j.cancelFlag, cancelWaitDuration, p.clock)
 	}
 
 	// start the workers
-	p.start(processor)
+	p.start(processor, p.metrics.jobDiscarded)
 
 	return p
 }
 
 // Shutdown cancels all the jobs in this pool and shuts down the workers.
 func (p *pool) Shutdown() {
+	p.shutdown(CancelReasonAgentShutdown)
+}
+
+// shutdown shuts down all the jobs with the given reason and shuts down the workers.
+func (p *pool) shutdown(reason CancelReason) {
 	// ShutDown and delete all jobs
-	p.ShutDownAll()
+	p.ShutDownAll(reason)
 
 	p.mut.Lock()
 	defer p.mut.Unlock()
 	if !p.isShutdown {
-		// close the channel to makes all workers terminate once the pending
+		// close the queue to makes all workers terminate once the pending
 		// jobs have been consumed (the pending jobs are in the Canceled state
 		// so they will simply be discarded)
-		close(p.jobQueue)
+		p.jobQueue.close()
 		p.isShutdown = true
 	}
 }
@@ -115,11 +150,19 @@ func (p *pool) Shutdown() {
 // or until the timeout has elapsed, whichever comes first. Returns true if all
 // workers terminated before the timeout or false if the timeout expired.
 func (p *pool) ShutdownAndWait(timeout time.Duration) (finished bool) {
-	p.Shutdown()
+	return p.ShutdownAndWaitWithReason(timeout, CancelReasonAgentShutdown)
+}
+
+// ShutdownAndWaitWithReason calls Shutdown with the given reason then waits until all the workers have exited
+// or until the timeout has elapsed, whichever comes first.
+func (p *pool) ShutdownAndWaitWithReason(timeout time.Duration, reason CancelReason) (finished bool) {
+	p.shutdown(reason)
 
 	timeoutTimer := p.clock.After(timeout)
 	exitTimer := p.clock.After(timeout + p.cancelDuration)
+	p.mut.Lock()
 	workersRunning := p.nWorkers
+	p.mut.Unlock()
 	for workersRunning > 0 {
 		select {
 		case <-p.doneWorker:
@@ -133,7 +176,7 @@ func (p *pool) ShutdownAndWait(timeout time.Duration) (finished bool) {
 		case <-timeoutTimer:
 			p.log.Debugf("Pool shutdown timed out with %d workers still running, start cancelling jobs...", workersRunning)
 			// wait for the worker pool to react to the cancel flag and fail the ongoing jobs
-			p.CancelAll()
+			p.CancelAll(reason)
 		case <-exitTimer:
 			p.log.Debugf("Pool eventual timeout with %d workers still running ", workersRunning)
 			return false
@@ -143,43 +186,89 @@ func (p *pool) ShutdownAndWait(timeout time.Duration) (finished bool) {
 }
 
 // start starts the workers of this pool
-func (p *pool) start(jobProcessor func(JobToken)) {
-	for i := 0; i < p.nWorkers; i++ {
+func (p *pool) start(jobProcessor func(JobToken), jobDiscarded func()) {
+	p.jobProcessor = jobProcessor
+	p.jobDiscarded = jobDiscarded
+	p.startWorkers(0, p.nWorkers)
+}
+
+// startWorkers starts the workers numbered from first up to, not including, last
+func (p *pool) startWorkers(first, last int) {
+	for i := first; i < last; i++ {
 		workerName := fmt.Sprintf("worker-%d", i)
 		go func() {
-			defer p.workerDone()
-			worker(workerName, p.jobQueue, jobProcessor)
+			// the retired workers are no longer counted by the pool
+			if retired := worker(workerName, p.jobQueue, p.jobProcessor, p.jobDiscarded); !retired {
+				p.workerDone()
+			}
 		}()
 	}
 }
 
+// Resize changes the number of workers of this pool, the workers in excess exit once they are done with their current job.
+func (p *pool) Resize(maxParallel int) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if p.isShutdown || maxParallel < 1 || maxParallel == p.nWorkers {
+		return
+	}
+	p.log.Infof("Resizing the pool from %d to %d workers", p.nWorkers, maxParallel)
+	if maxParallel > p.nWorkers {
+		p.startWorkers(p.nWorkers, maxParallel)
+	} else {
+		p.jobQueue.retire(p.nWorkers - maxParallel)
+	}
+	p.nWorkers = maxParallel
+}
+
 // workerDone signals that a worker has terminated.
 func (p *pool) workerDone() {
 	p.doneWorker <- struct{}{}
 }
 
-// worker processes jobs from a channel.
-func worker(workerName string, queue chan JobToken, processor func(JobToken)) {
-	for token := range queue {
-		if !token.cancelFlag.Canceled() {
+// worker processes jobs from the queue, jobs canceled or shut down while queued are discarded.
+// Returns true if the worker was retired rather than shut down.
+func worker(workerName string, queue *fairQueue, processor func(JobToken), discarded func()) (retired bool) {
+	for {
+		token, ok := queue.pop()
+		if !ok {
+			return false
+		}
+		if token.retire {
+			return true
+		}
+		if !token.cancelFlag.Canceled() && !token.cancelFlag.ShutDown() {
 			processor(token)
+		} else {
+			discarded()
 		}
 	}
 }
 
 // Submit adds a job to the execution queue of this pool.
 func (p *pool) Submit(log log.T, jobID string, job Job) (err error) {
+	return p.SubmitWithCategory(log, jobID, CategoryDefault, job)
+}
+
+// SubmitWithCategory adds a job of the given category to the execution queue of this pool.
+func (p *pool) SubmitWithCategory(log log.T, jobID string, category JobCategory, job Job) (err error) {
 	token := JobToken{
 		id:         jobID,
 		job:        job,
 		cancelFlag: NewChanneledCancelFlag(),
 		log:        log,
+		submitTime: time.Now(),
+		category:   category,
 	}
 	err = p.jobStore.AddJob(jobID, &token)
 	if err != nil {
 		return
 	}
-	p.jobQueue <- token
+	p.metrics.jobSubmitted()
+	if err = p.jobQueue.push(token); err != nil {
+		p.jobStore.DeleteJob(jobID)
+		p.metrics.jobDiscarded()
+	}
 	return
 }
 
@@ -189,6 +278,14 @@ func (p *pool) HasJob(jobID string) bool {
 	return found
 }
 
+// Metrics returns a snapshot of the queue depth, worker utilization and job timings of this pool.
+func (p *pool) Metrics() PoolMetrics {
+	p.mut.Lock()
+	nWorkers := p.nWorkers
+	p.mut.Unlock()
+	return p.metrics.snapshot(nWorkers)
+}
+
 // Cancel cancels the job with the given id.
 func (p *pool) Cancel(jobID string) (canceled bool) {
 	jobToken, found := p.jobStore.GetJob(jobID)
@@ -199,28 +296,28 @@ func (p *pool) Cancel(jobID string) (canceled bool) {
 	// delete job to avoid multiple cancelations
 	p.jobStore.DeleteJob(jobID)
 
-	jobToken.cancelFlag.Set(Canceled)
+	jobToken.cancelFlag.SetWithReason(Canceled, CancelReasonUserRequest)
 	return true
 }
 
-// CancelAll cancels all the running jobs.
-func (p *pool) CancelAll() {
+// CancelAll cancels all the running jobs with the given reason.
+func (p *pool) CancelAll(reason CancelReason) {
 	// remove jobs from task and save them to a local variable
 	jobs := p.jobStore.DeleteAllJobs()
 
 	// cancel each job
 	for _, token := range jobs {
-		token.cancelFlag.Set(Canceled)
+		token.cancelFlag.SetWithReason(Canceled, reason)
 	}
 }
 
-// ShutdownAll cancels all the running jobs.
-func (p *pool) ShutDownAll() {
+// ShutdownAll cancels all the running jobs with the given reason.
+func (p *pool) ShutDownAll(reason CancelReason) {
 	// remove jobs from task and save them to a local variable
 	jobs := p.jobStore.DeleteAllJobs()
 
 	// cancel each job
 	for _, token := range jobs {
-		token.cancelFlag.Set(ShutDown)
+		token.cancelFlag.SetWithReason(ShutDown, reason)
 	}
 }
@@ -85,6 +85,29 @@ func testPool(t *testing.T, nWorkers int, nJobs int, shouldCancel bool) {
 	clock.AssertCalled(t, "After", shutdownTimeout+waitTimeout)
 }
 
+func TestPoolShutdownWithReason(t *testing.T) {
+	clock := times.NewMockedClock()
+	waitTimeout := 100 * time.Millisecond
+	clock.On("After", waitTimeout).Return(clock.AfterChannel)
+	shutdownTimeout := 10000 * time.Millisecond
+	clock.On("After", shutdownTimeout).Return(clock.AfterChannel)
+	clock.On("After", shutdownTimeout+waitTimeout).Return(clock.AfterChannel)
+
+	pool := NewPool(logger, 1, waitTimeout, clock)
+	started := make(chan bool)
+	reason := make(chan CancelReason, 1)
+	err := pool.Submit(logger, "job", func(cancelFlag CancelFlag) {
+		started <- true
+		cancelFlag.Wait()
+		reason <- cancelFlag.Reason()
+	})
+	assert.Nil(t, err)
+	<-started
+
+	assert.True(t, pool.ShutdownAndWaitWithReason(shutdownTimeout, CancelReasonReboot))
+	assert.Equal(t, CancelReasonReboot, <-reason)
+}
+
 func exercisePool(t *testing.T, pool Pool, jobID string, shouldCancel bool) {
 	// submit job
 	jobState := make(chan bool)
@@ -111,6 +134,7 @@ func exercisePool(t *testing.T, pool Pool, jobID string, shouldCancel bool) {
 		// cancel job
 		assert.True(t, pool.Cancel(jobID))
 		assert.True(t, flag.Canceled())
+		assert.Equal(t, CancelReasonUserRequest, flag.Reason())
 
 		// check that thejob was immediately removed
 		assert.False(t, pool.Cancel(jobID))
@@ -119,3 +143,34 @@ func exercisePool(t *testing.T, pool Pool, jobID string, shouldCancel bool) {
 	// see that job completes
 	assert.True(t, <-jobState)
 }
+
+func TestPoolResize(t *testing.T) {
+	clock := times.NewMockedClock()
+	waitTimeout := 100 * time.Millisecond
+	clock.On("After", waitTimeout).Return(clock.AfterChannel)
+	shutdownTimeout := 10000 * time.Millisecond
+	clock.On("After", shutdownTimeout).Return(clock.AfterChannel)
+	clock.On("After", shutdownTimeout+waitTimeout).Return(clock.AfterChannel)
+
+	pool := NewPool(logger, 1, waitTimeout, clock)
+	pool.Resize(2)
+	assert.Equal(t, 2, pool.Metrics().MaxWorkers)
+
+	started := make(chan bool)
+	release := make(chan bool)
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, pool.Submit(logger, fmt.Sprintf("job-%d", i), func(cancelFlag CancelFlag) {
+			started <- true
+			<-release
+		}))
+	}
+	<-started
+	<-started
+
+	// the running jobs complete, one of the workers exits once done
+	pool.Resize(1)
+	assert.Equal(t, 1, pool.Metrics().MaxWorkers)
+	close(release)
+
+	assert.True(t, pool.ShutdownAndWait(shutdownTimeout))
+}
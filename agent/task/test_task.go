@@ -33,6 +33,11 @@ func (mockPool *MockedPool) Submit(log log.T, jobID string, job Job) error {
 	return mockPool.Called(log, jobID, job).Error(0)
 }
 
+// SubmitWithCategory mocks the method with the same name.
+func (mockPool *MockedPool) SubmitWithCategory(log log.T, jobID string, category JobCategory, job Job) error {
+	return mockPool.Called(log, jobID, category, job).Error(0)
+}
+
 // Cancel mocks the method with the same name.
 func (mockPool *MockedPool) Cancel(jobID string) bool {
 	return mockPool.Called(jobID).Bool(0)
@@ -49,12 +54,28 @@ func (mockPool *MockedPool) ShutdownAndWait(timeout time.Duration) (finished boo
 	return args.Bool(0)
 }
 
-// ShutdownAndWait mocks the method with the same name.
+// ShutdownAndWaitWithReason mocks the method with the same name.
+func (mockPool *MockedPool) ShutdownAndWaitWithReason(timeout time.Duration, reason CancelReason) (finished bool) {
+	args := mockPool.Called(timeout, reason)
+	return args.Bool(0)
+}
+
+// HasJob mocks the method with the same name.
 func (mockPool *MockedPool) HasJob(jobID string) bool {
 	args := mockPool.Called(jobID)
 	return args.Bool(0)
 }
 
+// Metrics mocks the method with the same name.
+func (mockPool *MockedPool) Metrics() PoolMetrics {
+	return mockPool.Called().Get(0).(PoolMetrics)
+}
+
+// Resize mocks the method with the same name.
+func (mockPool *MockedPool) Resize(maxParallel int) {
+	mockPool.Called(maxParallel)
+}
+
 // MockCancelFlag mocks a cancel flag.
 type MockCancelFlag struct {
 	mock.Mock
@@ -85,6 +106,14 @@ func (flag *MockCancelFlag) Set(state State) {
 	flag.Called(state)
 }
 
+func (flag *MockCancelFlag) SetWithReason(state State, reason CancelReason) {
+	flag.Called(state, reason)
+}
+
+func (flag *MockCancelFlag) Reason() CancelReason {
+	return flag.Called().Get(0).(CancelReason)
+}
+
 func (flag *MockCancelFlag) State() State {
 	return flag.Called().Get(0).(State)
 }
@@ -31,8 +31,11 @@ var minimumSupportedVersions map[string]string
 var once sync.Once
 
 var (
-	downloadArtifact = artifact.Download
-	uncompress       = fileutil.Uncompress
+	downloadArtifact       = artifact.Download
+	uncompress             = fileutil.Uncompress
+	retireWorkerBinary     = updateutil.RetireWorkerBinary
+	restoreWorkerBinary    = updateutil.RestoreWorkerBinary
+	cleanupRetiredBinaries = updateutil.CleanupRetiredWorkerBinaries
 )
 
 // NewUpdater creates an instance of Updater and other services it requires
@@ -145,6 +148,9 @@ func prepareInstallationPackages(mgr *updateManager, log log.T, context *UpdateC
 	var instanceContext *updateutil.InstanceContext
 	updateDownload := ""
 
+	// remove document workers retired by previous updates whose documents have completed since
+	cleanupRetiredBinaries(log)
+
 	if instanceContext, err = mgr.util.CreateInstanceContext(log); err != nil {
 		return mgr.failed(context, log, updateutil.ErrorEnvironmentIssue, err.Error(), false)
 	}
@@ -210,6 +216,13 @@ func proceedUpdate(mgr *updateManager, log log.T, context *UpdateContext) (err e
 		context.Current.SourceVersion,
 		context.Current.TargetVersion)
 
+	// Document workers keep running on the source version while the agent is swapped,
+	// make sure the installer is able to lay down the new worker binary next to them
+	retiredWorker, err := retireWorkerBinary(log, context.Current.SourceVersion)
+	if err != nil {
+		context.Current.AppendError(log, "%v", err)
+	}
+
 	// Uninstall only when the target version is lower than the source version
 	if context.Current.RequiresUninstall {
 		if err = mgr.uninstall(mgr, log, context.Current.SourceVersion, context); err != nil {
@@ -218,6 +231,9 @@ func proceedUpdate(mgr *updateManager, log log.T, context *UpdateContext) (err e
 				"failed to uninstall %v %v",
 				context.Current.PackageName,
 				context.Current.SourceVersion)
+			if err = restoreWorkerBinary(log, retiredWorker); err != nil {
+				context.Current.AppendError(log, "%v", err)
+			}
 			return mgr.failed(context, log, updateutil.ErrorUninstallFailed, message, true)
 		}
 	}
@@ -231,6 +247,10 @@ func proceedUpdate(mgr *updateManager, log log.T, context *UpdateContext) (err e
 			context.Current.TargetVersion)
 		context.Current.AppendError(log, message)
 
+		if err = restoreWorkerBinary(log, retiredWorker); err != nil {
+			context.Current.AppendError(log, "%v", err)
+		}
+
 		context.Current.AppendInfo(
 			log,
 			"Initiating rollback %v to %v",
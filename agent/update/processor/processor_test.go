@@ -344,6 +344,41 @@ func TestProceedUpdateFailInstall(t *testing.T) {
 	assert.Empty(t, context.Histories)
 }
 
+func TestProceedUpdateFailInstallRestoresRetiredWorker(t *testing.T) {
+	// setup
+	updater := createDefaultUpdaterStub()
+	context := createUpdateContext(Staged)
+	restoredPath := ""
+
+	retireWorkerBinary = func(log log.T, version string) (string, error) {
+		return "ssm-document-worker." + version + ".retired", nil
+	}
+	restoreWorkerBinary = func(log log.T, retiredPath string) error {
+		restoredPath = retiredPath
+		return nil
+	}
+	defer func() {
+		retireWorkerBinary = updateutil.RetireWorkerBinary
+		restoreWorkerBinary = updateutil.RestoreWorkerBinary
+	}()
+
+	// stub install for updater
+	updater.mgr.install = func(mgr *updateManager, log log.T, version string, context *UpdateContext) (err error) {
+		return fmt.Errorf("install failed")
+	}
+	updater.mgr.rollback = func(mgr *updateManager, log log.T, context *UpdateContext) (err error) {
+		return nil
+	}
+
+	// action
+	err := proceedUpdate(updater.mgr, logger, context)
+
+	// assert
+	assert.NoError(t, err)
+	assert.Equal(t, "ssm-document-worker."+context.Current.SourceVersion+".retired", restoredPath)
+	assert.Equal(t, context.Current.State, Rollback)
+}
+
 func TestVerifyInstallation(t *testing.T) {
 	// setup
 	control := &stubControl{serviceIsRunning: true}
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
 	ssmlog "github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
@@ -135,6 +136,14 @@ func main() {
 
 	log.Infof("Update root is: %v", detail.UpdateRoot)
 
+	// a second updater would interleave its changes with the ones of this updater
+	lock, err := filelock.TryLock(updateutil.UpdateLockFilePath(detail.UpdateRoot))
+	if err != nil {
+		log.Errorf("another update is in progress, please retry later: %v", err)
+		return
+	}
+	defer lock.Unlock()
+
 	// Load UpdateContext from local storage, set current update with the new UpdateDetail
 	context, err := updater.InitializeUpdate(log, detail)
 	if err != nil {
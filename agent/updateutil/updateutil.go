@@ -49,6 +49,9 @@ const (
 	// UpdateContextFileName represents Update context json file
 	UpdateContextFileName = "updatecontext.json"
 
+	// UpdateLockFileName represents the lock file held by the running updater
+	UpdateLockFileName = "update.lock"
+
 	// UpdatePluginResultFileName represents Update plugin result file name
 	UpdatePluginResultFileName = "updatepluginresult.json"
 
@@ -577,6 +580,11 @@ func UpdateContextFilePath(updateRoot string) (filePath string) {
 	return filepath.Join(updateRoot, UpdateContextFileName)
 }
 
+// UpdateLockFilePath returns the path of the lock file held by the running updater
+func UpdateLockFilePath(updateRoot string) (filePath string) {
+	return filepath.Join(updateRoot, UpdateLockFileName)
+}
+
 // UpdateOutputDirectory returns output directory
 func UpdateOutputDirectory(updateRoot string) string {
 	return filepath.Join(updateRoot, DefaultOutputFolder)
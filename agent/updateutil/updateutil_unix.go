@@ -75,6 +75,10 @@ const (
 	UnInstaller = "uninstall.sh"
 )
 
+// running executables are unlinked and replaced by the package manager, so a worker
+// keeps its binary image while the new version is installed
+var workerBinaryLockedWhileRunning = false
+
 func prepareProcess(command *exec.Cmd) {
 	// make the process the leader of its process group
 	// (otherwise we cannot kill it properly)
@@ -79,6 +79,9 @@ const (
 	UnInstaller = "uninstall.ps1"
 )
 
+// the installer cannot overwrite an executable that a running worker was started from
+var workerBinaryLockedWhileRunning = true
+
 var getPlatformSku = platform.PlatformSku
 
 func prepareProcess(command *exec.Cmd) {
@@ -0,0 +1,97 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updateutil contains updater specific utilities.
+package updateutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// retiredWorkerSuffix is appended to a document worker binary that has been moved aside during update
+const retiredWorkerSuffix = ".retired"
+
+var documentWorkerPath = appconfig.DefaultDocumentWorker
+var renameFile = os.Rename
+var removeFile = os.Remove
+var globFiles = filepath.Glob
+
+// RetireWorkerBinary moves the document worker binary of the given version out of the way of the installer.
+// Document workers are detached from the agent and keep running their documents to completion
+// while the agent is updated; on platforms where a running executable cannot be replaced in place,
+// the binary they were started from is renamed so that the installer can lay down the new one
+// without stopping them. Returns the path of the retired binary, or empty if nothing was moved.
+func RetireWorkerBinary(log log.T, version string) (retiredPath string, err error) {
+	if !workerBinaryLockedWhileRunning {
+		log.Debugf("running document workers do not lock %v, nothing to retire", documentWorkerPath)
+		return "", nil
+	}
+	if _, err = os.Stat(documentWorkerPath); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	retiredPath = retiredWorkerBinaryPath(version)
+	// a leftover from an earlier attempt at the same version is not in use, remove it first
+	removeFile(retiredPath)
+	if err = renameFile(documentWorkerPath, retiredPath); err != nil {
+		return "", fmt.Errorf("failed to move document worker %v aside, %v", documentWorkerPath, err)
+	}
+	log.Infof("moved document worker %v to %v, running documents will complete on version %v", documentWorkerPath, retiredPath, version)
+	return retiredPath, nil
+}
+
+// RestoreWorkerBinary moves a retired document worker binary back in place when no replacement was installed.
+func RestoreWorkerBinary(log log.T, retiredPath string) (err error) {
+	if retiredPath == "" {
+		return nil
+	}
+	if _, err = os.Stat(documentWorkerPath); err == nil {
+		// the installer already laid down a worker, keep it
+		return nil
+	}
+	if err = renameFile(retiredPath, documentWorkerPath); err != nil {
+		return fmt.Errorf("failed to restore document worker %v, %v", retiredPath, err)
+	}
+	log.Infof("restored document worker %v", documentWorkerPath)
+	return nil
+}
+
+// CleanupRetiredWorkerBinaries removes retired document worker binaries that are no longer used by any process.
+// Binaries that are still running cannot be removed and are left for the next cleanup.
+func CleanupRetiredWorkerBinaries(log log.T) {
+	retired, err := globFiles(documentWorkerPath + ".*" + retiredWorkerSuffix)
+	if err != nil {
+		log.Debugf("failed to list retired document workers, %v", err)
+		return
+	}
+	for _, path := range retired {
+		if err = removeFile(path); err != nil {
+			log.Debugf("retired document worker %v is still in use, %v", path, err)
+			continue
+		}
+		log.Debugf("removed retired document worker %v", path)
+	}
+}
+
+// retiredWorkerBinaryPath returns the path a document worker binary of the given version is retired to
+func retiredWorkerBinaryPath(version string) string {
+	return documentWorkerPath + "." + version + retiredWorkerSuffix
+}
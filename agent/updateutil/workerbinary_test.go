@@ -0,0 +1,116 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updateutil contains updater specific utilities.
+package updateutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupWorkerBinary(t *testing.T, locked bool) (dir string) {
+	dir, err := ioutil.TempDir("", "workerbinary")
+	assert.NoError(t, err)
+	documentWorkerPath = filepath.Join(dir, "ssm-document-worker")
+	workerBinaryLockedWhileRunning = locked
+	assert.NoError(t, ioutil.WriteFile(documentWorkerPath, []byte("worker"), appconfig.ReadWriteAccess))
+	return dir
+}
+
+func teardownWorkerBinary(dir string, locked bool) {
+	documentWorkerPath = appconfig.DefaultDocumentWorker
+	workerBinaryLockedWhileRunning = locked
+	os.RemoveAll(dir)
+}
+
+func TestRetireWorkerBinary(t *testing.T) {
+	locked := workerBinaryLockedWhileRunning
+	dir := setupWorkerBinary(t, true)
+	defer teardownWorkerBinary(dir, locked)
+
+	retiredPath, err := RetireWorkerBinary(logger, "2.2.0.0")
+
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "ssm-document-worker.2.2.0.0.retired"), retiredPath)
+	_, err = os.Stat(documentWorkerPath)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(retiredPath)
+	assert.NoError(t, err)
+}
+
+func TestRetireWorkerBinaryNotLocked(t *testing.T) {
+	locked := workerBinaryLockedWhileRunning
+	dir := setupWorkerBinary(t, false)
+	defer teardownWorkerBinary(dir, locked)
+
+	retiredPath, err := RetireWorkerBinary(logger, "2.2.0.0")
+
+	assert.NoError(t, err)
+	assert.Empty(t, retiredPath)
+	_, err = os.Stat(documentWorkerPath)
+	assert.NoError(t, err)
+}
+
+func TestRestoreWorkerBinary(t *testing.T) {
+	locked := workerBinaryLockedWhileRunning
+	dir := setupWorkerBinary(t, true)
+	defer teardownWorkerBinary(dir, locked)
+
+	retiredPath, err := RetireWorkerBinary(logger, "2.2.0.0")
+	assert.NoError(t, err)
+
+	err = RestoreWorkerBinary(logger, retiredPath)
+
+	assert.NoError(t, err)
+	_, err = os.Stat(documentWorkerPath)
+	assert.NoError(t, err)
+	_, err = os.Stat(retiredPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRestoreWorkerBinaryKeepsInstalledWorker(t *testing.T) {
+	locked := workerBinaryLockedWhileRunning
+	dir := setupWorkerBinary(t, true)
+	defer teardownWorkerBinary(dir, locked)
+
+	retiredPath, err := RetireWorkerBinary(logger, "2.2.0.0")
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(documentWorkerPath, []byte("new worker"), appconfig.ReadWriteAccess))
+
+	err = RestoreWorkerBinary(logger, retiredPath)
+
+	assert.NoError(t, err)
+	content, err := ioutil.ReadFile(documentWorkerPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "new worker", string(content))
+}
+
+func TestCleanupRetiredWorkerBinaries(t *testing.T) {
+	locked := workerBinaryLockedWhileRunning
+	dir := setupWorkerBinary(t, true)
+	defer teardownWorkerBinary(dir, locked)
+
+	retiredPath, err := RetireWorkerBinary(logger, "2.2.0.0")
+	assert.NoError(t, err)
+
+	CleanupRetiredWorkerBinaries(logger)
+
+	_, err = os.Stat(retiredPath)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -15,7 +15,6 @@ package fsvault
 
 import (
 	"io/ioutil"
-	"os"
 
 	"encoding/json"
 
@@ -39,7 +38,7 @@ func (fsvFileSystem) Exists(path string) bool              { return fileutil.Exi
 func (fsvFileSystem) MakeDirs(path string) error           { return fileutil.MakeDirs(path) }
 func (fsvFileSystem) RecursivelyHarden(path string) error  { return fileutil.RecursivelyHarden(path) }
 func (fsvFileSystem) ReadFile(path string) ([]byte, error) { return ioutil.ReadFile(path) }
-func (fsvFileSystem) Remove(path string) error             { return os.Remove(path) }
+func (fsvFileSystem) Remove(path string) error             { return fileutil.Shred(path) }
 func (fsvFileSystem) HardenedWriteFile(path string, data []byte) error {
 	return fileutil.HardenedWriteFile(path, data)
 }
@@ -0,0 +1,39 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package main represents the entry point of the ssm agent watchdog, which restarts the agent when it stops
+// writing its heartbeat.
+package main
+
+import (
+	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	ssmlog "github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
+)
+
+const defaultLogFileName = "amazon-ssm-agent-watchdog.log"
+
+var log logger.T
+
+func init() {
+	log = ssmlog.GetUpdaterLogger(logger.DefaultLogDir, defaultLogFileName)
+}
+
+// watch runs the watchdog until stop is closed
+func watch(stop <-chan struct{}) {
+	log.Infof("Starting the agent watchdog %v", version.Version)
+	watchdog.NewWatchdog(log).Run(stop)
+	log.Info("Stopped the agent watchdog")
+	log.Flush()
+}
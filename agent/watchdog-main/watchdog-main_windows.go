@@ -0,0 +1,68 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+// Package main represents the entry point of the ssm agent watchdog, which restarts the agent when it stops
+// writing its heartbeat.
+package main
+
+import (
+	"golang.org/x/sys/windows/svc"
+)
+
+const serviceName = "AmazonSSMAgentWatchdog"
+
+type watchdogService struct{}
+
+func main() {
+	defer log.Close()
+	defer log.Flush()
+
+	isIntSess, err := svc.IsAnInteractiveSession()
+	if err != nil {
+		log.Errorf("Failed to determine if we are running in an interactive session: %v", err)
+		return
+	}
+	if isIntSess {
+		watch(make(chan struct{}))
+		return
+	}
+	svc.Run(serviceName, &watchdogService{})
+}
+
+// Execute the watchdog as Windows service.  Implement golang.org/x/sys/windows/svc#Handler.
+func (w *watchdogService) Execute(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+	s <- svc.Status{State: svc.StartPending}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		watch(stop)
+		close(done)
+	}()
+	s <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+loop:
+	for c := range r {
+		switch c.Cmd {
+		case svc.Interrogate:
+			s <- c.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			break loop
+		}
+	}
+	s <- svc.Status{State: svc.StopPending}
+	close(stop)
+	<-done
+	return false, 0
+}
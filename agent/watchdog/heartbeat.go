@@ -0,0 +1,202 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package watchdog keeps the agent running: the agent writes a heartbeat, and the watchdog, a separate process,
+// restarts the agent when the heartbeat stops, whatever the restart policy of the service manager.
+package watchdog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+const (
+	// StateRunning is the state of an agent writing its heartbeat
+	StateRunning = "Running"
+	// StateStopped is the state of an agent that shut down cleanly, the watchdog leaves it stopped
+	StateStopped = "Stopped"
+
+	heartbeatFileName = "heartbeat.json"
+)
+
+// Heartbeat is written by the agent every heartbeatInterval while it runs.
+type Heartbeat struct {
+	State string
+	Pid   int
+	// Time is when the agent last wrote the heartbeat, StartTime when the agent started
+	Time      time.Time
+	StartTime time.Time
+	Version   string
+}
+
+// dir is the folder of the heartbeat and the alerts
+var dir = filepath.Join(appconfig.DefaultDataStorePath, "watchdog")
+
+// heartbeatInterval is how often the agent writes its heartbeat
+var heartbeatInterval = 30 * time.Second
+
+// staleAfter is how long after its last heartbeat a running agent is considered dead or hung
+func staleAfter() time.Duration {
+	return 3 * heartbeatInterval
+}
+
+// heartbeat is the heartbeat of this agent process
+var heartbeat struct {
+	Heartbeat
+	started bool
+	m       sync.Mutex
+}
+
+// loop is a loop of the agent the heartbeat depends on
+type loop struct {
+	// last is when the loop last made progress, it makes progress at least every limit
+	last  time.Time
+	limit time.Duration
+}
+
+// loops are the loops watched, by name
+var loops = struct {
+	byName map[string]loop
+	m      sync.Mutex
+}{byName: map[string]loop{}}
+
+// Progress records the loop name made progress, it makes progress again within limit. The agent stops writing its
+// heartbeat while a loop doesn't, so the watchdog restarts the agent when its main loops hang.
+func Progress(name string, limit time.Duration) {
+	loops.m.Lock()
+	defer loops.m.Unlock()
+	loops.byName[name] = loop{last: time.Now(), limit: limit}
+}
+
+// StopWatching stops watching the loop name, which ended.
+func StopWatching(name string) {
+	loops.m.Lock()
+	defer loops.m.Unlock()
+	delete(loops.byName, name)
+}
+
+// hungLoops returns the loops that haven't made progress within their limit, with when they last did
+func hungLoops(now time.Time) (hung []string) {
+	loops.m.Lock()
+	defer loops.m.Unlock()
+	for name, loop := range loops.byName {
+		if now.Sub(loop.last) > loop.limit {
+			hung = append(hung, fmt.Sprintf("%v since %v", name, loop.last.Format(time.RFC3339)))
+		}
+	}
+	sort.Strings(hung)
+	return hung
+}
+
+// StartHeartbeat writes the heartbeat of the agent every heartbeatInterval while its loops make progress, until
+// MarkStopped.
+func StartHeartbeat(log log.T) {
+	heartbeat.m.Lock()
+	defer heartbeat.m.Unlock()
+	if heartbeat.started {
+		return
+	}
+	now := time.Now()
+	heartbeat.started = true
+	heartbeat.Heartbeat = Heartbeat{State: StateRunning, Pid: os.Getpid(), StartTime: now, Version: version.Version}
+	beat(log, now)
+
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if !tick(log, now) {
+				return
+			}
+		}
+	}()
+}
+
+// tick writes the heartbeat unless a loop hangs, and returns false once the agent stopped
+func tick(log log.T, now time.Time) (running bool) {
+	heartbeat.m.Lock()
+	defer heartbeat.m.Unlock()
+	if heartbeat.State != StateRunning {
+		return false
+	}
+	if hung := hungLoops(now); len(hung) > 0 {
+		log.Warnf("No progress of %v, not writing the watchdog heartbeat", strings.Join(hung, ", "))
+		return true
+	}
+	beat(log, now)
+	return true
+}
+
+// MarkStopped records the clean shutdown of the agent, so the watchdog doesn't restart it.
+func MarkStopped(log log.T) {
+	heartbeat.m.Lock()
+	defer heartbeat.m.Unlock()
+	if !heartbeat.started {
+		return
+	}
+	heartbeat.State = StateStopped
+	beat(log, time.Now())
+}
+
+// beat writes the heartbeat, the caller holds the heartbeat lock
+func beat(log log.T, now time.Time) {
+	heartbeat.Time = now
+	if err := writeJSON(filepath.Join(dir, heartbeatFileName), heartbeat.Heartbeat); err != nil {
+		log.Warnf("Unable to write the watchdog heartbeat: %v", err)
+	}
+}
+
+// ReadHeartbeat returns the heartbeat written by the agent, an error satisfying os.IsNotExist if the agent
+// never wrote one.
+func ReadHeartbeat() (heartbeat Heartbeat, err error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, heartbeatFileName))
+	if err != nil {
+		return heartbeat, err
+	}
+	err = json.Unmarshal(content, &heartbeat)
+	return
+}
+
+// Stale returns true if the agent is running but hasn't written its heartbeat for a while, it's dead or hung.
+func (h Heartbeat) Stale(now time.Time) bool {
+	return h.State == StateRunning && now.Sub(h.Time) > staleAfter()
+}
+
+// writeJSON writes value to path through a temporary file, so the watchdog never reads a partial file
+func writeJSON(path string, value interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, content)
+}
+
+// writeFile writes the content to path through a temporary file, so the readers never see a partial file
+func writeFile(path string, content []byte) error {
+	if err := fileutil.MakeDirs(filepath.Dir(path)); err != nil {
+		return err
+	}
+	return fileutil.WriteAtomically(path, content, appconfig.ReadWriteAccess, false)
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package watchdog
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// agentBinary is the agent started directly when no service manager restarts it
+var agentBinary = "/usr/bin/amazon-ssm-agent"
+
+// restartCommands are the ways of the service managers to restart the agent, tried in order
+var restartCommands = [][]string{
+	{"systemctl", "restart", "amazon-ssm-agent"},
+	{"initctl", "restart", "amazon-ssm-agent"},
+	{"initctl", "start", "amazon-ssm-agent"},
+	{"launchctl", "kickstart", "-k", "system/com.amazon.aws.ssm"},
+	{"service", "amazon-ssm-agent", "restart"},
+}
+
+// runCommand runs the command, returning its combined output
+var runCommand = func(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// startProcess starts the agent binary in its own session, so it outlives the watchdog
+var startProcess = func(path string) error {
+	cmd := exec.Command(path)
+	cmd.Dir = filepath.Dir(path)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// restartAgent restarts the agent with the first service manager that succeeds, or kills the hung agent
+// and starts the binary directly when none does, e.g. the service isn't registered.
+func restartAgent(log log.T, heartbeat Heartbeat) error {
+	var failures []string
+	for _, command := range restartCommands {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+		output, err := runCommand(command[0], command[1:]...)
+		if err == nil {
+			log.Infof("Restarted the agent with %v", strings.Join(command, " "))
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("%v: %v %s", strings.Join(command, " "), err, strings.TrimSpace(string(output))))
+	}
+	log.Warnf("No service manager restarted the agent, starting %v: %v", agentBinary, strings.Join(failures, "; "))
+
+	if isAgentProcess(heartbeat.Pid) {
+		if process, err := os.FindProcess(heartbeat.Pid); err == nil {
+			process.Kill()
+			time.Sleep(time.Second)
+		}
+	}
+	return startProcess(agentBinary)
+}
+
+// isAgentProcess returns true if the process pid runs the agent binary, false when it can't be told
+func isAgentProcess(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%v/exe", pid))
+	if err != nil {
+		// without procfs, e.g. on macOS and FreeBSD, ps tells the command of the process
+		output, psErr := runCommand("ps", "-o", "comm=", "-p", strconv.Itoa(pid))
+		if psErr != nil {
+			return false
+		}
+		exe = strings.TrimSpace(string(output))
+	}
+	return filepath.Base(exe) == filepath.Base(agentBinary)
+}
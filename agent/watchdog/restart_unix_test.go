@@ -0,0 +1,42 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package watchdog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsAgentProcessWithoutProcfs(t *testing.T) {
+	defaultRunCommand := runCommand
+	defer func() { runCommand = defaultRunCommand }()
+	var ran []string
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		ran = append([]string{name}, args...)
+		if args[len(args)-1] == "999999998" {
+			return nil, errors.New("exit status 1")
+		}
+		return []byte(agentBinary + "\n"), nil
+	}
+
+	// the process has no /proc entry, ps tells its command
+	assert.True(t, isAgentProcess(999999999))
+	assert.Equal(t, []string{"ps", "-o", "comm=", "-p", "999999999"}, ran)
+	assert.False(t, isAgentProcess(999999998), "the process is gone")
+	assert.False(t, isAgentProcess(0))
+}
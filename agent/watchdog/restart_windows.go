@@ -0,0 +1,75 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package watchdog
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	agentServiceName = "AmazonSSMAgent"
+	agentImageName   = "amazon-ssm-agent.exe"
+	// stopTimeout is how long the agent service is given to stop before its process is killed
+	stopTimeout = 30 * time.Second
+)
+
+// restartAgent stops the agent service, killing the process of a hung agent, and starts it again, whatever
+// the recovery options of the service.
+func restartAgent(log log.T, heartbeat Heartbeat) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager: %v", err)
+	}
+	defer m.Disconnect()
+	service, err := m.OpenService(agentServiceName)
+	if err != nil {
+		return fmt.Errorf("failed to open the %v service: %v", agentServiceName, err)
+	}
+	defer service.Close()
+
+	if status, err := service.Query(); err == nil && status.State != svc.Stopped {
+		service.Control(svc.Stop)
+		if !waitForState(service, svc.Stopped, stopTimeout) && heartbeat.Pid > 0 {
+			// the filters make sure the pid of the heartbeat still is the agent
+			output, err := exec.Command("taskkill", "/F",
+				"/FI", fmt.Sprintf("PID eq %v", heartbeat.Pid),
+				"/FI", fmt.Sprintf("IMAGENAME eq %v", agentImageName)).CombinedOutput()
+			log.Infof("Killed the hung agent: %v %s", err, output)
+			waitForState(service, svc.Stopped, stopTimeout)
+		}
+	}
+	if err = service.Start(); err != nil {
+		return fmt.Errorf("failed to start the %v service: %v", agentServiceName, err)
+	}
+	log.Infof("Restarted the %v service", agentServiceName)
+	return nil
+}
+
+// waitForState returns true when the service reaches state before timeout
+func waitForState(service *mgr.Service, state svc.State, timeout time.Duration) bool {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(time.Second) {
+		if status, err := service.Query(); err == nil && status.State == state {
+			return true
+		}
+	}
+	return false
+}
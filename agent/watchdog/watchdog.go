@@ -0,0 +1,198 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package watchdog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// AlertRestart is an agent restarted because its heartbeat stopped
+	AlertRestart = "Restart"
+	// AlertRestartFailed is a restart of the agent that failed
+	AlertRestartFailed = "RestartFailed"
+	// AlertCrashLoop is an agent that keeps stopping after it's restarted
+	AlertCrashLoop = "CrashLoop"
+	// AlertOffline is an agent that can't reach the SSM endpoint, raised by the connectivity check of the agent
+	AlertOffline = "Offline"
+	// AlertOnline is an agent that reaches the SSM endpoint again after an AlertOffline
+	AlertOnline = "Online"
+
+	alertsFileName = "alerts.json"
+	// alertsLockFileName is locked while the alerts file is changed, the agent and the watchdog both raise alerts
+	alertsLockFileName = "alerts.lock"
+	alertsLockTimeout  = 5 * time.Second
+
+	// checkInterval is how often the watchdog reads the heartbeat
+	checkInterval = 30 * time.Second
+	// minBackoff is the time left to the agent to write its heartbeat after a restart, it doubles with each
+	// restart up to maxBackoff while the agent doesn't stay up for stableAfter
+	minBackoff  = 2 * time.Minute
+	maxBackoff  = 30 * time.Minute
+	stableAfter = 10 * time.Minute
+	// crashLoopRestarts restarts within crashLoopWindow are a crash loop
+	crashLoopRestarts = 5
+	crashLoopWindow   = time.Hour
+	// maxAlerts is the number of most recent alerts kept
+	maxAlerts = 50
+)
+
+// Alert is an event of the agent or the watchdog worth the attention of the administrator, appended to the
+// alerts file.
+type Alert struct {
+	Time    time.Time
+	Type    string
+	Message string
+}
+
+// Watchdog restarts the agent when its heartbeat stops, backing off while the agent keeps failing.
+type Watchdog struct {
+	log     log.T
+	restart func(log log.T, heartbeat Heartbeat) error
+	now     func() time.Time
+
+	// restarts are the times of the restarts within crashLoopWindow
+	restarts    []time.Time
+	backoff     time.Duration
+	nextRestart time.Time
+}
+
+// NewWatchdog returns a watchdog restarting the agent with the service manager of the platform.
+func NewWatchdog(log log.T) *Watchdog {
+	return &Watchdog{log: log, restart: restartAgent, now: time.Now}
+}
+
+// Run checks the heartbeat of the agent every checkInterval until stop is closed.
+func (w *Watchdog) Run(stop <-chan struct{}) {
+	w.log.Infof("Watching the agent heartbeat in %v", dir)
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		w.check()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check restarts the agent if its heartbeat stopped, unless it was restarted too recently
+func (w *Watchdog) check() {
+	now := w.now()
+	heartbeat, err := ReadHeartbeat()
+	if err != nil {
+		if os.IsNotExist(err) {
+			w.log.Debugf("The agent hasn't written a heartbeat yet")
+		} else {
+			w.log.Warnf("Unable to read the agent heartbeat: %v", err)
+		}
+		return
+	}
+
+	if !heartbeat.Stale(now) {
+		if heartbeat.State == StateRunning && w.backoff > 0 && now.Sub(heartbeat.StartTime) > stableAfter {
+			w.log.Infof("The agent has been up since %v, resetting the restart backoff", heartbeat.StartTime)
+			w.backoff = 0
+		}
+		return
+	}
+	if now.Before(w.nextRestart) {
+		w.log.Debugf("The agent heartbeat is stale, next restart at %v", w.nextRestart)
+		return
+	}
+
+	w.alert(Alert{Time: now, Type: AlertRestart, Message: fmt.Sprintf(
+		"the agent %v (pid %v) hasn't written its heartbeat since %v, restarting it",
+		heartbeat.Version, heartbeat.Pid, heartbeat.Time.Format(time.RFC3339))})
+	if err = w.restart(w.log, heartbeat); err != nil {
+		w.alert(Alert{Time: now, Type: AlertRestartFailed, Message: fmt.Sprintf("failed to restart the agent: %v", err)})
+	}
+
+	w.restarts = append(w.restarts, now)
+	for len(w.restarts) > 0 && now.Sub(w.restarts[0]) > crashLoopWindow {
+		w.restarts = w.restarts[1:]
+	}
+	if len(w.restarts) >= crashLoopRestarts {
+		w.alert(Alert{Time: now, Type: AlertCrashLoop, Message: fmt.Sprintf(
+			"the agent was restarted %v times within %v, check the agent log", len(w.restarts), crashLoopWindow)})
+	}
+
+	if w.backoff *= 2; w.backoff < minBackoff {
+		w.backoff = minBackoff
+	} else if w.backoff > maxBackoff {
+		w.backoff = maxBackoff
+	}
+	w.nextRestart = now.Add(w.backoff)
+}
+
+// alert logs the alert and appends it to the alerts file
+func (w *Watchdog) alert(alert Alert) {
+	RaiseAlert(w.log, alert)
+}
+
+// RaiseAlert logs the alert and appends it to the alerts file, which keeps the maxAlerts most recent ones.
+func RaiseAlert(log log.T, alert Alert) {
+	log.Errorf("%v: %v", alert.Type, alert.Message)
+	if err := fileutil.MakeDirs(dir); err != nil {
+		log.Warnf("Unable to write the alert to %v: %v", dir, err)
+		return
+	}
+	lock, err := filelock.Acquire(filepath.Join(dir, alertsLockFileName), alertsLockTimeout)
+	if err != nil {
+		log.Warnf("Unable to write the alert, the alerts are locked: %v", err)
+		return
+	}
+	defer lock.Unlock()
+
+	alerts, _ := ReadAlerts()
+	alerts = append(alerts, alert)
+	if len(alerts) > maxAlerts {
+		alerts = alerts[len(alerts)-maxAlerts:]
+	}
+	lines := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		line, _ := json.Marshal(alert)
+		lines = append(lines, string(line))
+	}
+	path := filepath.Join(dir, alertsFileName)
+	if err := writeFile(path, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+		log.Warnf("Unable to write the alert to %v: %v", path, err)
+	}
+}
+
+// ReadAlerts returns the most recent alerts of the watchdog, oldest first.
+func ReadAlerts() (alerts []Alert, err error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, alertsFileName))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		var alert Alert
+		if line != "" && json.Unmarshal([]byte(line), &alert) == nil {
+			alerts = append(alerts, alert)
+		}
+	}
+	return
+}
@@ -0,0 +1,207 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package watchdog
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupTest(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "watchdog")
+	assert.NoError(t, err)
+	savedDir := dir
+	dir = tmp
+	t.Cleanup(func() {
+		dir = savedDir
+		os.RemoveAll(tmp)
+	})
+}
+
+func newTestLog() log.T {
+	logger := log.NewMockLog()
+	logger.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	return logger
+}
+
+// testWatchdog is a watchdog with a clock the test advances and the restarts recorded
+type testWatchdog struct {
+	*Watchdog
+	clock    time.Time
+	restarts int
+	failure  error
+}
+
+func newTestWatchdog() *testWatchdog {
+	w := &testWatchdog{clock: time.Now()}
+	w.Watchdog = &Watchdog{
+		log: newTestLog(),
+		restart: func(log log.T, heartbeat Heartbeat) error {
+			w.restarts++
+			return w.failure
+		},
+		now: func() time.Time { return w.clock },
+	}
+	return w
+}
+
+func writeHeartbeat(t *testing.T, heartbeat Heartbeat) {
+	assert.NoError(t, writeJSON(dir+"/"+heartbeatFileName, heartbeat))
+}
+
+func alertTypes() (types []string) {
+	alerts, _ := ReadAlerts()
+	for _, alert := range alerts {
+		types = append(types, alert.Type)
+	}
+	return
+}
+
+func TestHeartbeatStale(t *testing.T) {
+	now := time.Now()
+	assert.False(t, Heartbeat{State: StateRunning, Time: now.Add(-staleAfter() / 2)}.Stale(now))
+	assert.True(t, Heartbeat{State: StateRunning, Time: now.Add(-2 * staleAfter())}.Stale(now))
+	assert.False(t, Heartbeat{State: StateStopped, Time: now.Add(-2 * staleAfter())}.Stale(now))
+}
+
+func TestStartHeartbeatAndMarkStopped(t *testing.T) {
+	setupTest(t)
+	logger := newTestLog()
+
+	StartHeartbeat(logger)
+	beat, err := ReadHeartbeat()
+	assert.NoError(t, err)
+	assert.Equal(t, StateRunning, beat.State)
+	assert.Equal(t, os.Getpid(), beat.Pid)
+
+	MarkStopped(logger)
+	beat, err = ReadHeartbeat()
+	assert.NoError(t, err)
+	assert.Equal(t, StateStopped, beat.State)
+}
+
+func TestTickSkipsHeartbeatWhileALoopHangs(t *testing.T) {
+	setupTest(t)
+	logger := newTestLog()
+	heartbeat.m.Lock()
+	heartbeat.Heartbeat = Heartbeat{State: StateRunning, Pid: os.Getpid()}
+	heartbeat.m.Unlock()
+	defer StopWatching("poller")
+
+	start := time.Now()
+	Progress("poller", time.Minute)
+	assert.True(t, tick(logger, start.Add(30*time.Second)))
+	beat, err := ReadHeartbeat()
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(30*time.Second).Unix(), beat.Time.Unix())
+
+	// the poller stopped making progress, the heartbeat goes stale
+	assert.True(t, tick(logger, start.Add(2*time.Minute)))
+	beat, err = ReadHeartbeat()
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(30*time.Second).Unix(), beat.Time.Unix())
+
+	// the loop ended
+	StopWatching("poller")
+	assert.True(t, tick(logger, start.Add(3*time.Minute)))
+	beat, err = ReadHeartbeat()
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(3*time.Minute).Unix(), beat.Time.Unix())
+}
+
+func TestCheckLeavesHealthyAgent(t *testing.T) {
+	setupTest(t)
+	w := newTestWatchdog()
+	writeHeartbeat(t, Heartbeat{State: StateRunning, Time: w.clock})
+
+	w.check()
+
+	assert.Equal(t, 0, w.restarts)
+}
+
+func TestCheckWithoutHeartbeat(t *testing.T) {
+	setupTest(t)
+	w := newTestWatchdog()
+
+	w.check()
+
+	assert.Equal(t, 0, w.restarts)
+}
+
+func TestCheckRestartsStaleAgentWithBackoff(t *testing.T) {
+	setupTest(t)
+	w := newTestWatchdog()
+	writeHeartbeat(t, Heartbeat{State: StateRunning, Time: w.clock.Add(-time.Hour)})
+
+	w.check()
+	assert.Equal(t, 1, w.restarts)
+	assert.Equal(t, []string{AlertRestart}, alertTypes())
+
+	// the agent is given minBackoff to come back
+	w.clock = w.clock.Add(minBackoff - time.Second)
+	w.check()
+	assert.Equal(t, 1, w.restarts)
+
+	w.clock = w.clock.Add(time.Second)
+	w.check()
+	assert.Equal(t, 2, w.restarts)
+	assert.Equal(t, 2*minBackoff, w.backoff)
+}
+
+func TestCheckAlertsCrashLoop(t *testing.T) {
+	setupTest(t)
+	w := newTestWatchdog()
+	w.failure = errors.New("failed")
+	writeHeartbeat(t, Heartbeat{State: StateRunning, Time: w.clock.Add(-time.Hour)})
+
+	for i := 0; i < crashLoopRestarts; i++ {
+		w.check()
+		w.clock = w.nextRestart
+	}
+
+	assert.Equal(t, crashLoopRestarts, w.restarts)
+	assert.Equal(t, maxBackoff, w.backoff)
+	types := alertTypes()
+	assert.Contains(t, types, AlertRestartFailed)
+	assert.Equal(t, AlertCrashLoop, types[len(types)-1])
+}
+
+func TestCheckResetsBackoffOfStableAgent(t *testing.T) {
+	setupTest(t)
+	w := newTestWatchdog()
+	w.backoff = maxBackoff
+	writeHeartbeat(t, Heartbeat{State: StateRunning, Time: w.clock, StartTime: w.clock.Add(-2 * stableAfter)})
+
+	w.check()
+
+	assert.Equal(t, time.Duration(0), w.backoff)
+	assert.Equal(t, 0, w.restarts)
+}
+
+func TestCheckLeavesStoppedAgent(t *testing.T) {
+	setupTest(t)
+	w := newTestWatchdog()
+	writeHeartbeat(t, Heartbeat{State: StateStopped, Time: w.clock.Add(-time.Hour)})
+
+	w.check()
+
+	assert.Equal(t, 0, w.restarts)
+}
@@ -45,6 +45,7 @@ ln -s `pwd` vendor/src/github.com/aws/amazon-ssm-agent
 go build -ldflags "-s -w" -o bin/amazon-ssm-agent -v agent/agent.go agent/agent_unix.go agent/agent_parser.go
 go build -ldflags "-s -w" -o bin/ssm-document-worker -v agent/framework/processor/executer/outofproc/worker/main.go
 go build -ldflags "-s -w" -o bin/ssm-cli -v agent/cli-main/cli-main.go
+go build -ldflags "-s -w" -o bin/amazon-ssm-agent-watchdog -v agent/watchdog-main/watchdog-main.go agent/watchdog-main/watchdog-main_unix.go
 
 %install
 
@@ -56,16 +57,18 @@ mkdir -p %{buildroot}%{_sysconfdir}/amazon/ssm/ \
          %{buildroot}%{_localstatedir}/log/amazon/ssm/
 
 cp {README.md,RELEASENOTES.md} %{buildroot}%{_sysconfdir}/amazon/ssm/
-cp bin/{amazon-ssm-agent,ssm-document-worker,ssm-cli} %{buildroot}%{_prefix}/bin/
+cp bin/{amazon-ssm-agent,ssm-document-worker,ssm-cli,amazon-ssm-agent-watchdog} %{buildroot}%{_prefix}/bin/
 %if 0%{?amzn} >= 2
 cp packaging/linux/amazon-ssm-agent.service %{buildroot}%{_unitdir}/
+cp packaging/linux/amazon-ssm-agent-watchdog.service %{buildroot}%{_unitdir}/
 %else 
 cp packaging/linux/amazon-ssm-agent.conf %{buildroot}%{_sysconfdir}/init/
+cp packaging/linux/amazon-ssm-agent-watchdog.conf %{buildroot}%{_sysconfdir}/init/
 %endif
 cp amazon-ssm-agent.json.template %{buildroot}%{_sysconfdir}/amazon/ssm/amazon-ssm-agent.json.template
 cp seelog_unix.xml %{buildroot}%{_sysconfdir}/amazon/ssm/seelog.xml.template
 
-strip --strip-unneeded %{buildroot}%{_prefix}/bin/{amazon-ssm-agent,ssm-document-worker,ssm-cli}
+strip --strip-unneeded %{buildroot}%{_prefix}/bin/{amazon-ssm-agent,ssm-document-worker,ssm-cli,amazon-ssm-agent-watchdog}
 
 %files
 %defattr(-,root,root,-)
@@ -75,12 +78,15 @@ strip --strip-unneeded %{buildroot}%{_prefix}/bin/{amazon-ssm-agent,ssm-document
 %{_sysconfdir}/amazon/ssm/RELEASENOTES.md
 %if 0%{?amzn} >= 2
 %{_unitdir}/amazon-ssm-agent.service
+%{_unitdir}/amazon-ssm-agent-watchdog.service
 %else
 %{_sysconfdir}/init/amazon-ssm-agent.conf
+%{_sysconfdir}/init/amazon-ssm-agent-watchdog.conf
 %endif
 %{_prefix}/bin/amazon-ssm-agent
 %{_prefix}/bin/ssm-document-worker
 %{_prefix}/bin/ssm-cli
+%{_prefix}/bin/amazon-ssm-agent-watchdog
 %{_localstatedir}/lib/amazon/ssm/
 
 %ghost %{_localstatedir}/log/amazon/ssm/
@@ -91,13 +97,15 @@ strip --strip-unneeded %{buildroot}%{_prefix}/bin/{amazon-ssm-agent,ssm-document
 
 %if 0%{?amzn} < 2
 %config(noreplace) %{_sysconfdir}/init/amazon-ssm-agent.conf
+%config(noreplace) %{_sysconfdir}/init/amazon-ssm-agent-watchdog.conf
 %endif
 
 %preun
 %if 0%{?amzn} >= 2
-%systemd_preun %{name}.service
+%systemd_preun %{name}.service %{name}-watchdog.service
 %else
 if [ $1 -eq 0 ] ; then
+    /sbin/stop amazon-ssm-agent-watchdog &> /dev/null || :
     /sbin/stop amazon-ssm-agent &> /dev/null || :
     sleep 1
 fi
@@ -105,7 +113,7 @@ fi
 
 %post
 %if 0%{?amzn} >= 2
-%systemd_post %{name}.service
+%systemd_post %{name}.service %{name}-watchdog.service
 %else
 if [ $1 -eq 2 ] ; then
     if [[ $(/sbin/status amazon-ssm-agent) =~ "amazon-ssm-agent start" ]] ; then
@@ -117,5 +125,5 @@ fi
 
 %postun
 %if 0%{?amzn} >= 2
-%systemd_postun_with_restart %{name}.service
+%systemd_postun_with_restart %{name}.service %{name}-watchdog.service
 %endif
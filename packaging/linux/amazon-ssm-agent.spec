@@ -9,6 +9,7 @@ URL          : http://docs.aws.amazon.com/ssm/latest/APIReference/Welcome.html
 
 Packager     : Amazon.com, Inc. <http://aws.amazon.com>
 Vendor       : Amazon.com
+Requires     : acl
 
 %description
 This package provides Amazon SSM Agent for managing EC2 Instances using SSM APIs
@@ -19,6 +20,7 @@ This package provides Amazon SSM Agent for managing EC2 Instances using SSM APIs
 /etc/amazon/ssm/seelog.xml.template
 /usr/bin/amazon-ssm-agent
 /usr/bin/ssm-cli
+/usr/bin/amazon-ssm-agent-watchdog
 /usr/bin/ssm-document-worker
 /var/lib/amazon/ssm/
 %doc /etc/amazon/ssm/RELEASENOTES.md
@@ -26,6 +28,8 @@ This package provides Amazon SSM Agent for managing EC2 Instances using SSM APIs
 
 %config(noreplace) /etc/init/amazon-ssm-agent.conf
 %config(noreplace) /etc/systemd/system/amazon-ssm-agent.service
+%config(noreplace) /etc/init/amazon-ssm-agent-watchdog.conf
+%config(noreplace) /etc/systemd/system/amazon-ssm-agent-watchdog.service
 
 # The scriptlets in %pre and %post are run before and after a package is installed.
 # The scriptlets %preun and %postun are run before and after a package is uninstalled.
@@ -38,26 +42,36 @@ This package provides Amazon SSM Agent for managing EC2 Instances using SSM APIs
 # Upgrade:       %pre, %posttrans
 
 %pre
-# Stop the agent before the upgrade
+# Stop the watchdog and the agent before the upgrade
 if [ $1 -ge 2 ]; then
     /sbin/init --version &> stdout.txt
     if [[ `cat stdout.txt` =~ upstart ]]; then
+        /sbin/stop amazon-ssm-agent-watchdog || true
         /sbin/stop amazon-ssm-agent
     elif [[ `systemctl` =~ -\.mount ]]; then
+        systemctl stop amazon-ssm-agent-watchdog
         systemctl stop amazon-ssm-agent
         systemctl daemon-reload
     fi
     rm stdout.txt
 fi
 
+# Create the low-privilege user the commands of the documents run as
+if ! id ssm-agent-worker > /dev/null 2>&1; then
+    useradd --system --no-create-home --home-dir / --shell /sbin/nologin ssm-agent-worker
+fi
+
 %preun
-# Stop the agent after uninstall
+# Stop the watchdog and the agent after uninstall
 if [ $1 -eq 0 ] ; then
     /sbin/init --version &> stdout.txt
     if [[ `cat stdout.txt` =~ upstart ]]; then
+        /sbin/stop amazon-ssm-agent-watchdog || true
         /sbin/stop amazon-ssm-agent
         sleep 1
     elif [[ `systemctl` =~ -\.mount ]]; then
+        systemctl stop amazon-ssm-agent-watchdog
+        systemctl disable amazon-ssm-agent-watchdog
         systemctl stop amazon-ssm-agent
         systemctl disable amazon-ssm-agent
         systemctl daemon-reload
@@ -66,14 +80,17 @@ if [ $1 -eq 0 ] ; then
 fi
 
 %posttrans
-# Start the agent after initial install or upgrade
+# Start the agent and the watchdog after initial install or upgrade
 if [ $1 -ge 0 ]; then
     /sbin/init --version &> stdout.txt
     if [[ `cat stdout.txt` =~ upstart ]]; then
         /sbin/start amazon-ssm-agent
+        /sbin/start amazon-ssm-agent-watchdog
     elif [[ `systemctl` =~ -\.mount ]]; then
         systemctl enable amazon-ssm-agent
         systemctl start amazon-ssm-agent
+        systemctl enable amazon-ssm-agent-watchdog
+        systemctl start amazon-ssm-agent-watchdog
         systemctl daemon-reload
     fi
     rm stdout.txt